@@ -6,18 +6,24 @@ SPDX-License-Identifier: Apache-2.0
 package startcmd
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"net/http"
+	"os"
 	"strings"
 
 	"github.com/gorilla/mux"
 	"github.com/spf13/cobra"
 
 	"github.com/hyperledger/aries-framework-go/pkg/common/log"
+	httptransport "github.com/hyperledger/aries-framework-go/pkg/didcomm/transport/http"
 	"github.com/hyperledger/aries-framework-go/pkg/framework/aries"
 	"github.com/hyperledger/aries-framework-go/pkg/framework/aries/defaults"
 	"github.com/hyperledger/aries-framework-go/pkg/restapi"
+	"github.com/hyperledger/aries-framework-go/pkg/restapi/auth"
 )
 
 const (
@@ -39,6 +45,13 @@ const (
 	// AgentInboundHostFlagUsage is the usage text for the agent inbound host command line argument.
 	AgentInboundHostFlagUsage = "Inbound Host Name:Port"
 
+	// AgentAdminHostFlagName is the flag name for the agent admin host command line argument.
+	AgentAdminHostFlagName = "admin-host"
+
+	// AgentAdminHostFlagUsage is the usage text for the agent admin host command line argument.
+	AgentAdminHostFlagUsage = "Admin Host Name:Port serving the " + httptransport.DefaultHealthPath + " and " +
+		httptransport.DefaultReadyPath + " health check endpoints, the endpoints aren't served if not set"
+
 	// AgentDBPathFlagName is the flag name for the database path command line argument.
 	AgentDBPathFlagName = "db-path"
 
@@ -48,6 +61,43 @@ const (
 	// AgentDBPathFlagUsage is the flag usage text for the database path command line argument.
 	AgentDBPathFlagUsage = "Path to database"
 
+	// AgentAPIKeyFlagName is the flag name for the API key command line argument.
+	AgentAPIKeyFlagName = "api-key"
+
+	// AgentAPIKeyEnvKey is the environment variable of the API key, used if the API key flag isn't set so the key
+	// isn't visible in the process list.
+	AgentAPIKeyEnvKey = "ARIESD_API_KEY"
+
+	// AgentAPIKeyFlagUsage is the flag usage text for the API key command line argument.
+	AgentAPIKeyFlagUsage = "API key required in the " + auth.APIKeyHeader + " header of the REST API requests." +
+		" Alternatively, this can be set with the following environment variable: " + AgentAPIKeyEnvKey
+
+	// AgentTLSCertFileFlagName is the flag name for the TLS certificate command line argument.
+	AgentTLSCertFileFlagName = "tls-cert-file"
+
+	// AgentTLSCertFileFlagUsage is the flag usage text for the TLS certificate command line argument.
+	AgentTLSCertFileFlagUsage = "PEM file of the TLS certificate of the REST API," +
+		" the REST API is served over HTTP if not set"
+
+	// AgentTLSKeyFileFlagName is the flag name for the TLS key command line argument.
+	AgentTLSKeyFileFlagName = "tls-key-file"
+
+	// AgentTLSKeyFileFlagUsage is the flag usage text for the TLS key command line argument.
+	AgentTLSKeyFileFlagUsage = "PEM file of the private key of the TLS certificate of the REST API"
+
+	// AgentTLSClientCAFileFlagName is the flag name for the TLS client CA command line argument.
+	AgentTLSClientCAFileFlagName = "tls-client-ca-file"
+
+	// AgentTLSClientCAFileFlagUsage is the flag usage text for the TLS client CA command line argument.
+	AgentTLSClientCAFileFlagUsage = "PEM file of the CAs verifying the client certificates of the REST API requests"
+
+	// AgentTLSClientSubjectFlagName is the flag name for the TLS client subject command line argument.
+	AgentTLSClientSubjectFlagName = "tls-client-subject"
+
+	// AgentTLSClientSubjectFlagUsage is the flag usage text for the TLS client subject command line argument.
+	AgentTLSClientSubjectFlagUsage = "Subject common name of the client certificates authorized to call the REST API," +
+		" can be repeated"
+
 	// MissingHostErrorMessage is the error message shown when the user provides a blank host argument.
 	MissingHostErrorMessage = "Unable to start aries agentd, host not provided"
 
@@ -59,6 +109,7 @@ var logger = log.New("aries-framework/agentd")
 
 type server interface {
 	ListenAndServe(host string, router *mux.Router) error
+	ListenAndServeTLS(host string, tlsConfig *tls.Config, router *mux.Router) error
 }
 
 // HTTPServer represents an actual server implementation.
@@ -69,6 +120,28 @@ func (s *HTTPServer) ListenAndServe(host string, router *mux.Router) error {
 	return http.ListenAndServe(host, router)
 }
 
+// ListenAndServeTLS starts the server over TLS using the standard Go HTTP server implementation, the certificates
+// are loaded in the TLS config.
+func (s *HTTPServer) ListenAndServeTLS(host string, tlsConfig *tls.Config, router *mux.Router) error {
+	srv := &http.Server{Addr: host, Handler: router, TLSConfig: tlsConfig}
+
+	return srv.ListenAndServeTLS("", "")
+}
+
+// agentParameters are the parameters of the agent started by the start command
+type agentParameters struct {
+	server         server
+	host           string
+	inboundHost    string
+	adminHost      string
+	dbPath         string
+	apiKey         string
+	tlsCertFile    string
+	tlsKeyFile     string
+	tlsClientCA    string
+	clientSubjects []string
+}
+
 // Cmd returns the Cobra start command.
 func Cmd(server server) (*cobra.Command, error) {
 	startCmd := &cobra.Command{
@@ -87,12 +160,29 @@ func Cmd(server server) (*cobra.Command, error) {
 				return fmt.Errorf("agent inbound host flag not found: %s", err)
 			}
 
+			adminHost, err := cmd.Flags().GetString(AgentAdminHostFlagName)
+			if err != nil {
+				return fmt.Errorf("agent admin host flag not found: %s", err)
+			}
+
 			dbPath, err := cmd.Flags().GetString(AgentDBPathFlagName)
 			if err != nil {
 				return fmt.Errorf("agent DB path flag not found: %s", err)
 			}
 
-			err = startAgent(server, host, inboundHost, dbPath)
+			parameters := &agentParameters{
+				server:      server,
+				host:        host,
+				inboundHost: inboundHost,
+				adminHost:   adminHost,
+				dbPath:      dbPath,
+			}
+
+			if err = setSecurityParameters(cmd, parameters); err != nil {
+				return err
+			}
+
+			err = startAgent(parameters)
 			if err != nil {
 				return fmt.Errorf("unable to start agent: %s", err)
 			}
@@ -100,42 +190,99 @@ func Cmd(server server) (*cobra.Command, error) {
 			return nil
 		},
 	}
+	if err := createFlags(startCmd); err != nil {
+		return nil, err
+	}
+
+	return startCmd, nil
+}
+
+func createFlags(startCmd *cobra.Command) error {
 	startCmd.Flags().StringP(AgentHostFlagName, AgentHostFlagShorthand, "", AgentHostFlagUsage)
 	err := startCmd.MarkFlagRequired(AgentHostFlagName)
 	if err != nil {
-		return nil, fmt.Errorf("tried to mark host flag as required but it was not found: %s", err)
+		return fmt.Errorf("tried to mark host flag as required but it was not found: %s", err)
 	}
 
 	startCmd.Flags().StringP(AgentInboundHostFlagName, AgentInboundHostFlagShorthand, "", AgentInboundHostFlagUsage)
 	err = startCmd.MarkFlagRequired(AgentInboundHostFlagName)
 	if err != nil {
-		return nil, fmt.Errorf("tried to mark inbound host flag as required but it was not found: %s", err)
+		return fmt.Errorf("tried to mark inbound host flag as required but it was not found: %s", err)
 	}
 	startCmd.Flags().StringP(AgentDBPathFlagName, AgentDBPathFlagShorthand, "", AgentDBPathFlagUsage)
 	err = startCmd.MarkFlagRequired(AgentDBPathFlagName)
 	if err != nil {
-		return nil, fmt.Errorf("tried to mark DB path flag as required but it was not found: %s", err)
+		return fmt.Errorf("tried to mark DB path flag as required but it was not found: %s", err)
 	}
 
-	return startCmd, nil
+	startCmd.Flags().String(AgentAdminHostFlagName, "", AgentAdminHostFlagUsage)
+	startCmd.Flags().String(AgentAPIKeyFlagName, "", AgentAPIKeyFlagUsage)
+	startCmd.Flags().String(AgentTLSCertFileFlagName, "", AgentTLSCertFileFlagUsage)
+	startCmd.Flags().String(AgentTLSKeyFileFlagName, "", AgentTLSKeyFileFlagUsage)
+	startCmd.Flags().String(AgentTLSClientCAFileFlagName, "", AgentTLSClientCAFileFlagUsage)
+	startCmd.Flags().StringArray(AgentTLSClientSubjectFlagName, nil, AgentTLSClientSubjectFlagUsage)
+
+	return nil
 }
 
-func startAgent(server server, host, inboundHost, dbPath string) error {
+// setSecurityParameters sets the API key and the TLS parameters of the REST API from the flags
+func setSecurityParameters(cmd *cobra.Command, parameters *agentParameters) error {
+	var err error
+
+	parameters.apiKey, err = cmd.Flags().GetString(AgentAPIKeyFlagName)
+	if err != nil {
+		return fmt.Errorf("agent API key flag not found: %s", err)
+	}
+
+	if parameters.apiKey == "" {
+		parameters.apiKey = os.Getenv(AgentAPIKeyEnvKey)
+	}
+
+	parameters.tlsCertFile, err = cmd.Flags().GetString(AgentTLSCertFileFlagName)
+	if err != nil {
+		return fmt.Errorf("agent TLS certificate file flag not found: %s", err)
+	}
+
+	parameters.tlsKeyFile, err = cmd.Flags().GetString(AgentTLSKeyFileFlagName)
+	if err != nil {
+		return fmt.Errorf("agent TLS key file flag not found: %s", err)
+	}
+
+	parameters.tlsClientCA, err = cmd.Flags().GetString(AgentTLSClientCAFileFlagName)
+	if err != nil {
+		return fmt.Errorf("agent TLS client CA file flag not found: %s", err)
+	}
+
+	parameters.clientSubjects, err = cmd.Flags().GetStringArray(AgentTLSClientSubjectFlagName)
+	if err != nil {
+		return fmt.Errorf("agent TLS client subject flag not found: %s", err)
+	}
+
+	return nil
+}
+
+func startAgent(parameters *agentParameters) error {
+	host := parameters.host
+
 	if host == "" {
 		return errors.New(strings.ToLower(MissingHostErrorMessage))
 	}
 
-	if inboundHost == "" {
+	if parameters.inboundHost == "" {
 		return errors.New(strings.ToLower(MissingInboundHostErrorMessage))
 	}
-	var opts []aries.Option
-	opts = append(opts, defaults.WithInboundHTTPAddr(inboundHost))
 
-	if dbPath != "" {
-		opts = append(opts, defaults.WithStorePath(dbPath))
+	tlsConfig, err := serverTLSConfig(parameters)
+	if err != nil {
+		return fmt.Errorf("failed to start aries agentd on port [%s], failed to load TLS config : %w", host, err)
 	}
 
-	framework, err := aries.New(opts...)
+	restOpts, err := restAuthOpts(parameters)
+	if err != nil {
+		return fmt.Errorf("failed to start aries agentd on port [%s], failed to create REST API auth : %w", host, err)
+	}
+
+	framework, err := aries.New(frameworkOpts(parameters)...)
 	if err != nil {
 		return fmt.Errorf("failed to start aries agentd on port [%s], failed to initialize framework :  %w", host, err)
 	}
@@ -146,10 +293,17 @@ func startAgent(server server, host, inboundHost, dbPath string) error {
 	}
 
 	// get all HTTP REST API handlers available for controller A PI
-	restService, err := restapi.New(ctx)
+	restService, err := restapi.New(ctx, restOpts...)
 	if err != nil {
 		return fmt.Errorf("failed to start aries agentd on port [%s], failed to get rest service api :  %w", host, err)
 	}
+
+	defer func() {
+		if closeErr := restService.Close(); closeErr != nil {
+			logger.Errorf("failed to close rest service api : %s", closeErr)
+		}
+	}()
+
 	handlers := restService.GetOperations()
 
 	// register handlers
@@ -161,10 +315,113 @@ func startAgent(server server, host, inboundHost, dbPath string) error {
 	logger.Infof("Starting aries agentd on host [%s]", host)
 
 	// start server on given port and serve using given handlers
-	err = server.ListenAndServe(host, router)
+	err = serve(parameters, tlsConfig, router)
 	if err != nil {
 		return fmt.Errorf("failed to start aries agentd on port [%s], cause:  %w", host, err)
 	}
 
 	return nil
 }
+
+// serve serves the REST API over TLS if configured, over HTTP otherwise
+func serve(parameters *agentParameters, tlsConfig *tls.Config, router *mux.Router) error {
+	if tlsConfig != nil {
+		return parameters.server.ListenAndServeTLS(parameters.host, tlsConfig, router)
+	}
+
+	return parameters.server.ListenAndServe(parameters.host, router)
+}
+
+func frameworkOpts(parameters *agentParameters) []aries.Option {
+	var inboundOpts []httptransport.InboundOpt
+	// the orchestrators probe the agent on the health check endpoints of the admin address, if set
+	if parameters.adminHost != "" {
+		inboundOpts = append(inboundOpts, httptransport.WithHealthCheck(parameters.adminHost))
+	}
+
+	opts := []aries.Option{defaults.WithInboundHTTPAddr(parameters.inboundHost, inboundOpts...)}
+
+	if parameters.dbPath != "" {
+		opts = append(opts, defaults.WithStorePath(parameters.dbPath))
+	}
+
+	return opts
+}
+
+// serverTLSConfig returns the TLS config of the REST API, nil if the REST API is served over HTTP
+func serverTLSConfig(parameters *agentParameters) (*tls.Config, error) {
+	if parameters.tlsCertFile == "" && parameters.tlsKeyFile == "" {
+		if parameters.tlsClientCA != "" {
+			return nil, errors.New("TLS client CA requires the TLS certificate and key")
+		}
+
+		return nil, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(parameters.tlsCertFile, parameters.tlsKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("load TLS certificate: %w", err)
+	}
+
+	tlsConfig := &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+
+	if parameters.tlsClientCA == "" {
+		return tlsConfig, nil
+	}
+
+	pem, err := ioutil.ReadFile(parameters.tlsClientCA)
+	if err != nil {
+		return nil, fmt.Errorf("read TLS client CA: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, errors.New("no certificate found in TLS client CA")
+	}
+
+	// the clients without certificate can still authenticate with the API key
+	tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	tlsConfig.ClientCAs = pool
+
+	return tlsConfig, nil
+}
+
+// restAuthOpts returns the options authenticating the REST API requests with the API key and the client
+// certificates
+func restAuthOpts(parameters *agentParameters) ([]restapi.Opt, error) {
+	var authenticators []auth.Authenticator
+
+	if parameters.apiKey != "" {
+		if parameters.tlsCertFile == "" {
+			logger.Warnf("the REST API is served over HTTP, the API key is sent in cleartext")
+		}
+
+		authenticators = append(authenticators,
+			auth.NewAPIKey(map[string]*auth.Principal{parameters.apiKey: {ID: "agentd-admin"}}))
+	}
+
+	if (len(parameters.clientSubjects) == 0) != (parameters.tlsClientCA == "") {
+		return nil, errors.New("TLS client subjects and TLS client CA must be set together")
+	}
+
+	if len(parameters.clientSubjects) != 0 {
+		subjects := make(map[string]*auth.Principal, len(parameters.clientSubjects))
+		for _, subject := range parameters.clientSubjects {
+			subjects[subject] = &auth.Principal{ID: subject}
+		}
+
+		authenticators = append(authenticators, auth.NewMTLS(subjects))
+	}
+
+	// the REST API is open to any caller without API key nor client subjects, e.g. when bound to localhost
+	if len(authenticators) == 0 {
+		return nil, nil
+	}
+
+	middleware, err := auth.New(authenticators)
+	if err != nil {
+		return nil, err
+	}
+
+	return []restapi.Opt{restapi.WithAuth(middleware)}, nil
+}
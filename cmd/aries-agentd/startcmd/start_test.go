@@ -6,14 +6,23 @@ SPDX-License-Identifier: Apache-2.0
 package startcmd
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/json"
+	"encoding/pem"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"math/big"
 	"net"
 	"net/http"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -24,12 +33,19 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
-type mockServer struct{}
+type mockServer struct {
+	tlsConfig *tls.Config
+}
 
 func (s *mockServer) ListenAndServe(host string, router *mux.Router) error {
 	return nil
 }
 
+func (s *mockServer) ListenAndServeTLS(host string, tlsConfig *tls.Config, router *mux.Router) error {
+	s.tlsConfig = tlsConfig
+	return nil
+}
+
 func randomURL() string {
 	return fmt.Sprintf("localhost:%d", mustGetRandomPort(3))
 }
@@ -73,6 +89,63 @@ func TestStartCmdContents(t *testing.T) {
 	checkFlagPropertiesCorrect(t, startCmd, AgentInboundHostFlagName,
 		AgentInboundHostFlagShorthand, AgentInboundHostFlagUsage)
 	checkFlagPropertiesCorrect(t, startCmd, AgentDBPathFlagName, AgentDBPathFlagShorthand, AgentDBPathFlagUsage)
+
+	adminHostFlag := startCmd.Flag(AgentAdminHostFlagName)
+	require.NotNil(t, adminHostFlag)
+	require.Equal(t, AgentAdminHostFlagUsage, adminHostFlag.Usage)
+	require.Empty(t, adminHostFlag.Annotations)
+
+	optionalFlags := map[string]string{
+		AgentAPIKeyFlagName:           AgentAPIKeyFlagUsage,
+		AgentTLSCertFileFlagName:      AgentTLSCertFileFlagUsage,
+		AgentTLSKeyFileFlagName:       AgentTLSKeyFileFlagUsage,
+		AgentTLSClientCAFileFlagName:  AgentTLSClientCAFileFlagUsage,
+		AgentTLSClientSubjectFlagName: AgentTLSClientSubjectFlagUsage,
+	}
+
+	for name, usage := range optionalFlags {
+		flag := startCmd.Flag(name)
+		require.NotNil(t, flag)
+		require.Equal(t, usage, flag.Usage)
+		require.Empty(t, flag.Annotations)
+	}
+}
+
+func TestStartCmdWithAPIKeyEnv(t *testing.T) {
+	require.NoError(t, os.Setenv(AgentAPIKeyEnvKey, "env-secret"))
+
+	defer func() {
+		require.NoError(t, os.Unsetenv(AgentAPIKeyEnvKey))
+	}()
+
+	parse := func(args ...string) *agentParameters {
+		startCmd, err := Cmd(&mockServer{})
+		require.NoError(t, err)
+		require.NoError(t, startCmd.ParseFlags(args))
+
+		parameters := &agentParameters{}
+		require.NoError(t, setSecurityParameters(startCmd, parameters))
+
+		return parameters
+	}
+
+	t.Run("test API key from the environment", func(t *testing.T) {
+		require.Equal(t, "env-secret", parse().apiKey)
+	})
+
+	t.Run("test API key flag takes precedence", func(t *testing.T) {
+		require.Equal(t, "flag-secret", parse("--"+AgentAPIKeyFlagName, "flag-secret").apiKey)
+	})
+
+	t.Run("test TLS flags", func(t *testing.T) {
+		parameters := parse("--"+AgentTLSCertFileFlagName, "cert.pem", "--"+AgentTLSKeyFileFlagName, "key.pem",
+			"--"+AgentTLSClientCAFileFlagName, "ca.pem",
+			"--"+AgentTLSClientSubjectFlagName, "alice", "--"+AgentTLSClientSubjectFlagName, "bob")
+		require.Equal(t, "cert.pem", parameters.tlsCertFile)
+		require.Equal(t, "key.pem", parameters.tlsKeyFile)
+		require.Equal(t, "ca.pem", parameters.tlsClientCA)
+		require.Equal(t, []string{"alice", "bob"}, parameters.clientSubjects)
+	})
 }
 
 func checkFlagPropertiesCorrect(t *testing.T, cmd *cobra.Command, flagName, flagShorthand, flagUsage string) {
@@ -102,7 +175,9 @@ func TestStartAriesDRequests(t *testing.T) {
 	testInboundHostURL := randomURL()
 
 	go func() {
-		err := startAgent(&HTTPServer{}, testHostURL, testInboundHostURL, path)
+		err := startAgent(&agentParameters{
+			server: &HTTPServer{}, host: testHostURL, inboundHost: testInboundHostURL, dbPath: path,
+		})
 		require.NoError(t, err)
 	}()
 
@@ -111,6 +186,263 @@ func TestStartAriesDRequests(t *testing.T) {
 	validateRequests(t, testHostURL, testInboundHostURL)
 }
 
+func TestStartAriesDWithAPIKey(t *testing.T) {
+	path, cleanup := generateTempDir(t)
+	defer cleanup()
+
+	testHostURL := randomURL()
+	testInboundHostURL := randomURL()
+
+	go func() {
+		err := startAgent(&agentParameters{
+			server: &HTTPServer{}, host: testHostURL, inboundHost: testInboundHostURL, dbPath: path, apiKey: "secret",
+		})
+		require.NoError(t, err)
+	}()
+
+	waitForServerToStart(t, testHostURL, testInboundHostURL)
+
+	get := func(apiKey string) int {
+		req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("http://%s/features", testHostURL), nil)
+		require.NoError(t, err)
+
+		if apiKey != "" {
+			req.Header.Set("X-API-Key", apiKey)
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+
+		return resp.StatusCode
+	}
+
+	require.Equal(t, http.StatusUnauthorized, get(""))
+	require.Equal(t, http.StatusUnauthorized, get("guess"))
+	require.Equal(t, http.StatusOK, get("secret"))
+}
+
+func TestStartAriesDWithTLS(t *testing.T) {
+	path, cleanup := generateTempDir(t)
+	defer cleanup()
+
+	certs := generateTLSFiles(t, path)
+
+	testHostURL := randomURL()
+	testInboundHostURL := randomURL()
+
+	go func() {
+		err := startAgent(&agentParameters{
+			server: &HTTPServer{}, host: testHostURL, inboundHost: testInboundHostURL, dbPath: path,
+			apiKey: "secret", tlsCertFile: certs.serverCert, tlsKeyFile: certs.serverKey, tlsClientCA: certs.ca,
+			clientSubjects: []string{"admin"},
+		})
+		require.NoError(t, err)
+	}()
+
+	waitForServerToStart(t, testHostURL, testInboundHostURL)
+
+	get := func(clientCert *tls.Certificate, apiKey string) int {
+		tlsConfig := &tls.Config{RootCAs: certs.pool} // nolint: gosec
+		if clientCert != nil {
+			tlsConfig.Certificates = []tls.Certificate{*clientCert}
+		}
+
+		req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("https://%s/features", testHostURL), nil)
+		require.NoError(t, err)
+
+		if apiKey != "" {
+			req.Header.Set("X-API-Key", apiKey)
+		}
+
+		resp, err := (&http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}).Do(req)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+
+		return resp.StatusCode
+	}
+
+	require.Equal(t, http.StatusUnauthorized, get(nil, ""))
+	require.Equal(t, http.StatusOK, get(nil, "secret"))
+	require.Equal(t, http.StatusOK, get(&certs.admin, ""))
+	require.Equal(t, http.StatusUnauthorized, get(&certs.guest, ""))
+}
+
+func TestStartAriesDWithInvalidTLS(t *testing.T) {
+	path, cleanup := generateTempDir(t)
+	defer cleanup()
+
+	certs := generateTLSFiles(t, path)
+
+	start := func(parameters *agentParameters) error {
+		parameters.server = &mockServer{}
+		parameters.host = randomURL()
+		parameters.inboundHost = randomURL()
+
+		return startAgent(parameters)
+	}
+
+	t.Run("test TLS config", func(t *testing.T) {
+		server := &mockServer{}
+		err := startAgent(&agentParameters{
+			server: server, host: randomURL(), inboundHost: randomURL(), dbPath: path,
+			tlsCertFile: certs.serverCert, tlsKeyFile: certs.serverKey, tlsClientCA: certs.ca,
+			clientSubjects: []string{"admin"},
+		})
+		require.NoError(t, err)
+		require.NotNil(t, server.tlsConfig)
+		require.Len(t, server.tlsConfig.Certificates, 1)
+		require.Equal(t, tls.VerifyClientCertIfGiven, server.tlsConfig.ClientAuth)
+		require.NotNil(t, server.tlsConfig.ClientCAs)
+	})
+
+	t.Run("test missing TLS key", func(t *testing.T) {
+		err := start(&agentParameters{tlsCertFile: certs.serverCert})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "load TLS certificate")
+	})
+
+	t.Run("test client CA without TLS certificate", func(t *testing.T) {
+		err := start(&agentParameters{tlsClientCA: certs.ca})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "TLS client CA requires the TLS certificate and key")
+	})
+
+	t.Run("test invalid client CA", func(t *testing.T) {
+		err := start(&agentParameters{tlsCertFile: certs.serverCert, tlsKeyFile: certs.serverKey,
+			tlsClientCA: filepath.Join(path, "missing.pem")})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "read TLS client CA")
+
+		err = start(&agentParameters{tlsCertFile: certs.serverCert, tlsKeyFile: certs.serverKey,
+			tlsClientCA: certs.serverKey})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "no certificate found in TLS client CA")
+	})
+
+	t.Run("test client subjects without client CA", func(t *testing.T) {
+		err := start(&agentParameters{tlsCertFile: certs.serverCert, tlsKeyFile: certs.serverKey,
+			clientSubjects: []string{"admin"}})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "TLS client subjects and TLS client CA must be set together")
+	})
+}
+
+type tlsFiles struct {
+	ca         string
+	serverCert string
+	serverKey  string
+	pool       *x509.CertPool
+	admin      tls.Certificate
+	guest      tls.Certificate
+}
+
+// generateTLSFiles writes a CA and a localhost server certificate to the directory and returns them with the
+// client certificates of the admin and guest subjects
+func generateTLSFiles(t *testing.T, dir string) *tlsFiles {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "agentd CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+
+	caCert, err := x509.ParseCertificate(caDER)
+	require.NoError(t, err)
+
+	issue := func(serial int64, template *x509.Certificate) (certPEM, keyPEM []byte) {
+		key, e := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, e)
+
+		template.SerialNumber = big.NewInt(serial)
+		template.NotBefore = caTemplate.NotBefore
+		template.NotAfter = caTemplate.NotAfter
+
+		der, e := x509.CreateCertificate(rand.Reader, template, caCert, &key.PublicKey, caKey)
+		require.NoError(t, e)
+
+		keyDER, e := x509.MarshalECPrivateKey(key)
+		require.NoError(t, e)
+
+		return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+			pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	}
+
+	client := func(serial int64, subject string) tls.Certificate {
+		certPEM, keyPEM := issue(serial, &x509.Certificate{
+			Subject:     pkix.Name{CommonName: subject},
+			ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		})
+
+		cert, e := tls.X509KeyPair(certPEM, keyPEM)
+		require.NoError(t, e)
+
+		return cert
+	}
+
+	serverCert, serverKey := issue(2, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "localhost"},
+		DNSNames:    []string{"localhost"},
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	})
+
+	files := &tlsFiles{
+		ca:         filepath.Join(dir, "ca.pem"),
+		serverCert: filepath.Join(dir, "server.pem"),
+		serverKey:  filepath.Join(dir, "server-key.pem"),
+		pool:       x509.NewCertPool(),
+		admin:      client(3, "admin"),
+		guest:      client(4, "guest"),
+	}
+
+	files.pool.AddCert(caCert)
+
+	require.NoError(t, ioutil.WriteFile(files.ca,
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER}), 0600))
+	require.NoError(t, ioutil.WriteFile(files.serverCert, serverCert, 0600))
+	require.NoError(t, ioutil.WriteFile(files.serverKey, serverKey, 0600))
+
+	return files
+}
+
+func TestStartAriesDWithAdminHost(t *testing.T) {
+	path, cleanup := generateTempDir(t)
+	defer cleanup()
+
+	testHostURL := randomURL()
+	testInboundHostURL := randomURL()
+	testAdminHostURL := randomURL()
+
+	go func() {
+		err := startAgent(&agentParameters{
+			server: &HTTPServer{}, host: testHostURL, inboundHost: testInboundHostURL, adminHost: testAdminHostURL,
+			dbPath: path,
+		})
+		require.NoError(t, err)
+	}()
+
+	waitForServerToStart(t, testHostURL, testInboundHostURL)
+
+	if err := listenFor(testAdminHostURL, 2*time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := http.Get(fmt.Sprintf("http://%s/healthz", testAdminHostURL)) // nolint: gosec
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.NoError(t, resp.Body.Close())
+}
+
 func listenFor(host string, d time.Duration) error {
 	timeout := time.After(d)
 	for {
@@ -218,7 +550,7 @@ func TestStartCmdWithMissingHostArg(t *testing.T) {
 }
 
 func TestStartAgentWithBlankHost(t *testing.T) {
-	err := startAgent(&mockServer{}, "", randomURL(), "")
+	err := startAgent(&agentParameters{server: &mockServer{}, inboundHost: randomURL()})
 
 	require.NotNil(t, err)
 	require.Equal(t, strings.ToLower(MissingHostErrorMessage), err.Error())
@@ -237,7 +569,7 @@ func TestStartCmdWithoutInboundHostArg(t *testing.T) {
 }
 
 func TestStartAgentWithBlankInboundHost(t *testing.T) {
-	err := startAgent(&mockServer{}, randomURL(), "", "")
+	err := startAgent(&agentParameters{server: &mockServer{}, host: randomURL()})
 
 	require.NotNil(t, err)
 	require.Equal(t, strings.ToLower(MissingInboundHostErrorMessage), err.Error())
@@ -287,7 +619,7 @@ func TestStartMultipleAgentsWithSameHost(t *testing.T) {
 	path1, cleanup1 := generateTempDir(t)
 	defer cleanup1()
 	go func() {
-		err := startAgent(&HTTPServer{}, host, inboundHost, path1)
+		err := startAgent(&agentParameters{server: &HTTPServer{}, host: host, inboundHost: inboundHost, dbPath: path1})
 		require.NoError(t, err)
 	}()
 
@@ -295,7 +627,7 @@ func TestStartMultipleAgentsWithSameHost(t *testing.T) {
 
 	path2, cleanup2 := generateTempDir(t)
 	defer cleanup2()
-	err := startAgent(&HTTPServer{}, host, inboundHost2, path2)
+	err := startAgent(&agentParameters{server: &HTTPServer{}, host: host, inboundHost: inboundHost2, dbPath: path2})
 
 	require.NotNil(t, err)
 	addressAlreadyInUseErrorMessage := "failed to start aries agentd on port [" + host +
@@ -312,13 +644,13 @@ func TestStartMultipleAgentsWithSameDBPath(t *testing.T) {
 	defer cleanup()
 
 	go func() {
-		err := startAgent(&HTTPServer{}, host1, inboundHost1, path)
+		err := startAgent(&agentParameters{server: &HTTPServer{}, host: host1, inboundHost: inboundHost1, dbPath: path})
 		require.NoError(t, err)
 	}()
 
 	waitForServerToStart(t, host1, inboundHost1)
 
-	err := startAgent(&HTTPServer{}, host2, inboundHost2, path)
+	err := startAgent(&agentParameters{server: &HTTPServer{}, host: host2, inboundHost: inboundHost2, dbPath: path})
 
 	require.NotNil(t, err)
 	require.Contains(t, err.Error(), "storage initialization failed")
@@ -0,0 +1,103 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// The demo program runs an issuer, a holder and a verifier agent through the invitation, DID exchange,
+// credential issuance and proof flows over the in-memory transports of the demo package.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/hyperledger/aries-framework-go/examples/demo"
+	"github.com/hyperledger/aries-framework-go/pkg/common/log"
+)
+
+func main() {
+	// the INFO logs of the framework would hide the steps of the demo
+	log.SetLevel("", log.WARNING)
+
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "demo failed: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	network := demo.NewNetwork()
+
+	issuer, err := network.NewIssuer("university")
+	if err != nil {
+		return err
+	}
+
+	defer closeAgent(issuer)
+
+	holder, err := network.NewHolder("alice")
+	if err != nil {
+		return err
+	}
+
+	defer closeAgent(holder)
+
+	verifier, err := network.NewVerifier("employer", "employer.example.com")
+	if err != nil {
+		return err
+	}
+
+	defer closeAgent(verifier)
+
+	if _, _, err = holder.Connect(issuer.Agent); err != nil {
+		return fmt.Errorf("connect holder to issuer: %w", err)
+	}
+
+	fmt.Println("holder connected to issuer")
+
+	vc, err := issuer.IssueDegree(holder.DID(), "Alice", "BachelorDegree", "Bachelor of Science and Arts")
+	if err != nil {
+		return err
+	}
+
+	if err = holder.Store(vc); err != nil {
+		return err
+	}
+
+	fmt.Printf("issuer %s issued degree to holder %s\n", issuer.DID(), holder.DID())
+
+	if _, _, err = holder.Connect(verifier.Agent); err != nil {
+		return fmt.Errorf("connect holder to verifier: %w", err)
+	}
+
+	fmt.Println("holder connected to verifier")
+
+	request, err := verifier.RequestPresentation()
+	if err != nil {
+		return err
+	}
+
+	vpBytes, err := holder.Present(request)
+	if err != nil {
+		return err
+	}
+
+	credentials, err := verifier.Verify(vpBytes, request)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range credentials {
+		fmt.Printf("verifier accepted %v credential of %s issued by %s\n", c.Types(), holder.DID(), c.Issuer.ID)
+	}
+
+	return nil
+}
+
+func closeAgent(agent io.Closer) {
+	if err := agent.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+	}
+}
@@ -0,0 +1,205 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package demo
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/client/didexchange"
+	"github.com/hyperledger/aries-framework-go/pkg/common/log"
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/service"
+	didexsvc "github.com/hyperledger/aries-framework-go/pkg/didcomm/protocol/didexchange"
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/transport/nearfield"
+	"github.com/hyperledger/aries-framework-go/pkg/framework/aries"
+	"github.com/hyperledger/aries-framework-go/pkg/framework/context"
+	"github.com/hyperledger/aries-framework-go/pkg/storage/leveldb"
+)
+
+const (
+	stateCompleted = "completed"
+
+	// connectionTimeout is the maximum duration of the DID exchange of a connection
+	connectionTimeout = 10 * time.Second
+
+	// completedBufferSize is the number of completed connections buffered until Connect waits for them
+	completedBufferSize = 10
+)
+
+var logger = log.New("aries-framework/examples/demo")
+
+// Agent is an agent of the demo network, it accepts the DID exchange requests automatically
+type Agent struct {
+	Name        string
+	DIDExchange *didexchange.Client
+
+	framework *aries.Aries
+	ctx       *context.Provider
+	storePath string
+	completed chan string
+	actions   chan service.DIDCommAction
+	msgs      chan service.StateMsg
+}
+
+// NewAgent starts the agent listening on the name as near-field address
+func (n *Network) NewAgent(name string) (*Agent, error) {
+	storePath, err := ioutil.TempDir("", "demo-"+name)
+	if err != nil {
+		return nil, fmt.Errorf("create store of agent %s: %w", name, err)
+	}
+
+	a := &Agent{
+		Name:      name,
+		storePath: storePath,
+		completed: make(chan string, completedBufferSize),
+		actions:   make(chan service.DIDCommAction),
+		msgs:      make(chan service.StateMsg),
+	}
+
+	if err = a.start(n); err != nil {
+		if e := a.Close(); e != nil {
+			logger.Warnf("close agent %s: %s", name, e)
+		}
+
+		return nil, err
+	}
+
+	return a, nil
+}
+
+func (a *Agent) start(n *Network) error {
+	store, err := leveldb.NewProvider(a.storePath)
+	if err != nil {
+		return fmt.Errorf("open store of agent %s: %w", a.Name, err)
+	}
+
+	listener, err := n.medium.Listen(a.Name)
+	if err != nil {
+		return fmt.Errorf("listen for agent %s: %w", a.Name, err)
+	}
+
+	a.framework, err = aries.New(
+		aries.WithStoreProvider(store),
+		aries.WithInboundTransport(nearfield.NewInbound(listener)),
+		aries.WithTransportProviderFactory(n),
+		aries.WithVDR(n.registry),
+	)
+	if err != nil {
+		return fmt.Errorf("create framework of agent %s: %w", a.Name, err)
+	}
+
+	if a.ctx, err = a.framework.Context(); err != nil {
+		return fmt.Errorf("get context of agent %s: %w", a.Name, err)
+	}
+
+	if a.DIDExchange, err = didexchange.New(a.ctx); err != nil {
+		return fmt.Errorf("create DID exchange client of agent %s: %w", a.Name, err)
+	}
+
+	if err = a.DIDExchange.RegisterActionEvent(a.actions); err != nil {
+		return fmt.Errorf("register action events of agent %s: %w", a.Name, err)
+	}
+
+	go func() {
+		if e := service.AutoExecuteActionEvent(a.actions); e != nil {
+			logger.Errorf("execute action events of agent %s: %s", a.Name, e)
+		}
+	}()
+
+	if err = a.DIDExchange.RegisterMsgEvent(a.msgs); err != nil {
+		return fmt.Errorf("register message events of agent %s: %w", a.Name, err)
+	}
+
+	go a.notifyCompleted()
+
+	return nil
+}
+
+// notifyCompleted reports the IDs of the connections completing their DID exchange
+func (a *Agent) notifyCompleted() {
+	for msg := range a.msgs {
+		if msg.Type != service.PostState || msg.StateID != stateCompleted {
+			continue
+		}
+
+		event, ok := msg.Properties.(didexsvc.Event)
+		if !ok {
+			continue
+		}
+
+		select {
+		case a.completed <- event.ConnectionID():
+		default:
+			logger.Warnf("agent %s dropped the completion of connection %s", a.Name, event.ConnectionID())
+		}
+	}
+}
+
+// Connect invites the agent to a connection with the other agent and waits for the DID exchange to complete on
+// both sides, the connection IDs of the agent and of the other agent are returned
+func (a *Agent) Connect(other *Agent) (string, string, error) {
+	invitation, err := other.DIDExchange.CreateInvitation(other.Name)
+	if err != nil {
+		return "", "", fmt.Errorf("create invitation: %w", err)
+	}
+
+	if err = a.DIDExchange.HandleInvitation(invitation); err != nil {
+		return "", "", fmt.Errorf("handle invitation: %w", err)
+	}
+
+	connectionID, err := a.waitForConnection()
+	if err != nil {
+		return "", "", err
+	}
+
+	otherConnectionID, err := other.waitForConnection()
+	if err != nil {
+		return "", "", err
+	}
+
+	return connectionID, otherConnectionID, nil
+}
+
+func (a *Agent) waitForConnection() (string, error) {
+	select {
+	case connectionID := <-a.completed:
+		return connectionID, nil
+	case <-time.After(connectionTimeout):
+		return "", fmt.Errorf("timeout waiting for the connection of agent %s", a.Name)
+	}
+}
+
+// Close stops the agent and removes its store, the first error is returned
+func (a *Agent) Close() error {
+	var err error
+
+	if a.DIDExchange != nil {
+		err = a.DIDExchange.Close()
+
+		// the client doesn't send events once closed
+		close(a.actions)
+		close(a.msgs)
+	}
+
+	if a.framework != nil {
+		if e := a.framework.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+
+	if e := os.RemoveAll(a.storePath); e != nil && err == nil {
+		err = e
+	}
+
+	if err != nil {
+		return fmt.Errorf("close agent %s: %w", a.Name, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,122 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package demo
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDemo(t *testing.T) {
+	network := NewNetwork()
+
+	issuer, err := network.NewIssuer("university")
+	require.NoError(t, err)
+
+	defer func() {
+		require.NoError(t, issuer.Close())
+	}()
+
+	holder, err := network.NewHolder("alice")
+	require.NoError(t, err)
+
+	defer func() {
+		require.NoError(t, holder.Close())
+	}()
+
+	verifier, err := network.NewVerifier("employer", "example.com")
+	require.NoError(t, err)
+
+	defer func() {
+		require.NoError(t, verifier.Close())
+	}()
+
+	t.Run("test invitation and exchange", func(t *testing.T) {
+		holderConnection, issuerConnection, err := holder.Connect(issuer.Agent)
+		require.NoError(t, err)
+
+		connection, err := holder.DIDExchange.GetConnection(holderConnection)
+		require.NoError(t, err)
+		require.Equal(t, stateCompleted, connection.State)
+
+		connection, err = issuer.DIDExchange.GetConnection(issuerConnection)
+		require.NoError(t, err)
+		require.Equal(t, stateCompleted, connection.State)
+
+		_, _, err = holder.Connect(verifier.Agent)
+		require.NoError(t, err)
+	})
+
+	t.Run("test issuance and proof", func(t *testing.T) {
+		vc, err := issuer.IssueDegree(holder.DID(), "Alice", "BachelorDegree", "Bachelor of Science and Arts")
+		require.NoError(t, err)
+		require.NoError(t, holder.Store(vc))
+
+		request, err := verifier.RequestPresentation()
+		require.NoError(t, err)
+
+		vpBytes, err := holder.Present(request)
+		require.NoError(t, err)
+
+		credentials, err := verifier.Verify(vpBytes, request)
+		require.NoError(t, err)
+		require.Len(t, credentials, 1)
+		require.Equal(t, issuer.DID(), credentials[0].Issuer.ID)
+		require.Equal(t, "Alice", credentials[0].Subject.(map[string]interface{})["name"])
+
+		// the presentation can't be replayed
+		_, err = verifier.Verify(vpBytes, request)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "challenge")
+	})
+
+	t.Run("test tampered presentation", func(t *testing.T) {
+		request, err := verifier.RequestPresentation()
+		require.NoError(t, err)
+
+		vpBytes, err := holder.Present(request)
+		require.NoError(t, err)
+
+		var vp map[string]interface{}
+		require.NoError(t, json.Unmarshal(vpBytes, &vp))
+
+		vc := vp["verifiableCredential"].([]interface{})[0].(map[string]interface{})
+		vc["credentialSubject"].(map[string]interface{})["degree"].(map[string]interface{})["type"] = "MasterDegree"
+
+		tampered, err := json.Marshal(vp)
+		require.NoError(t, err)
+
+		_, err = verifier.Verify(tampered, request)
+		require.Error(t, err)
+	})
+
+	t.Run("test credential of another subject", func(t *testing.T) {
+		vc, err := issuer.IssueDegree("did:mem:bob", "Bob", "BachelorDegree", "Bachelor of Science and Arts")
+		require.NoError(t, err)
+
+		err = holder.Store(vc)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "is issued to did:mem:bob")
+	})
+}
+
+func TestNetwork_NewAgent(t *testing.T) {
+	network := NewNetwork()
+
+	agent, err := network.NewAgent("agent")
+	require.NoError(t, err)
+
+	defer func() {
+		require.NoError(t, agent.Close())
+	}()
+
+	_, err = network.NewAgent("agent")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "listen for agent agent")
+}
@@ -0,0 +1,11 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package demo wires an issuer, a holder and a verifier agent through the in-memory near-field medium and an
+// in-memory verifiable data registry, and walks them through the invitation, DID exchange, credential issuance
+// and proof flows. The credentials and presentations are handed over by the caller as the framework has no
+// issue-credential and present-proof protocols yet.
+package demo
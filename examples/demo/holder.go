@@ -0,0 +1,76 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package demo
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+)
+
+// Holder is the agent of the subject of the credentials, it keeps the credentials issued to its public DID and
+// presents them to the verifiers
+type Holder struct {
+	*Agent
+	identity    *identity
+	credentials []*verifiable.Credential
+}
+
+// NewHolder starts the holder agent and registers its public DID in the network
+func (n *Network) NewHolder(name string) (*Holder, error) {
+	id, err := n.newIdentity()
+	if err != nil {
+		return nil, fmt.Errorf("create identity of holder %s: %w", name, err)
+	}
+
+	agent, err := n.NewAgent(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Holder{Agent: agent, identity: id}, nil
+}
+
+// DID returns the public DID of the holder
+func (h *Holder) DID() string {
+	return h.identity.DID
+}
+
+// Store checks the credential is issued to the holder and keeps it
+func (h *Holder) Store(vc *verifiable.Credential) error {
+	subjectID, err := vc.SubjectID()
+	if err != nil {
+		return fmt.Errorf("store credential: %w", err)
+	}
+
+	if subjectID != h.identity.DID {
+		return fmt.Errorf("credential %s is issued to %s", vc.ID, subjectID)
+	}
+
+	h.credentials = append(h.credentials, vc)
+
+	return nil
+}
+
+// Present returns the presentation of the stored credentials signed by the holder for the request of the verifier
+func (h *Holder) Present(request *verifiable.PresentationRequest) ([]byte, error) {
+	vp, err := verifiable.NewPresentationOf(h.identity.DID, h.credentials...)
+	if err != nil {
+		return nil, fmt.Errorf("create presentation: %w", err)
+	}
+
+	err = vp.AddLinkedDataProof(&verifiable.LinkedDataProofContext{
+		SignatureType: verifiable.Ed25519Signature2018,
+		Creator:       h.identity.KeyID,
+		Signer:        h.identity,
+	}, request)
+	if err != nil {
+		return nil, fmt.Errorf("sign presentation: %w", err)
+	}
+
+	return vp.MarshalJSON()
+}
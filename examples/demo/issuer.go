@@ -0,0 +1,75 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package demo
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+)
+
+// degreeTemplate is the template of the credentials issued by the university of the demo
+func degreeTemplate(issuerID string) *verifiable.IssuanceTemplate {
+	return &verifiable.IssuanceTemplate{
+		Context: []string{"https://www.w3.org/2018/credentials/examples/v1"},
+		Types:   []string{"UniversityDegreeCredential"},
+		Issuer:  verifiable.Issuer{ID: issuerID, Name: "Example University"},
+		SubjectFields: map[string]string{
+			"name":       "name",
+			"degreeType": "degree.type",
+			"degreeName": "degree.name",
+		},
+		RequiredFields: []string{"name", "degreeType"},
+		Validity:       4 * 365 * 24 * time.Hour,
+	}
+}
+
+// Issuer is the agent of a university issuing degrees to the holders under its public DID
+type Issuer struct {
+	*Agent
+	identity *identity
+	template *verifiable.IssuanceTemplate
+}
+
+// NewIssuer starts the issuer agent and registers its public DID in the network
+func (n *Network) NewIssuer(name string) (*Issuer, error) {
+	id, err := n.newIdentity()
+	if err != nil {
+		return nil, fmt.Errorf("create identity of issuer %s: %w", name, err)
+	}
+
+	agent, err := n.NewAgent(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Issuer{Agent: agent, identity: id, template: degreeTemplate(id.DID)}, nil
+}
+
+// DID returns the public DID of the issuer
+func (i *Issuer) DID() string {
+	return i.identity.DID
+}
+
+// IssueDegree issues the degree credential signed by the issuer to the subject
+func (i *Issuer) IssueDegree(subjectID, name, degreeType, degreeName string) (*verifiable.Credential, error) {
+	vc, err := i.template.Issue(subjectID, map[string]interface{}{
+		"name":       name,
+		"degreeType": degreeType,
+		"degreeName": degreeName,
+	}, verifiable.WithNoCustomSchemaCheck())
+	if err != nil {
+		return nil, fmt.Errorf("issue degree: %w", err)
+	}
+
+	if err = vc.GenerateProof(i.identity, &verifiable.ProofOptions{Creator: i.identity.KeyID}); err != nil {
+		return nil, fmt.Errorf("sign degree: %w", err)
+	}
+
+	return vc, nil
+}
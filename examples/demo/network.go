@@ -0,0 +1,91 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package demo
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/transport"
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/transport/nearfield"
+	"github.com/hyperledger/aries-framework-go/pkg/didmethod/mem"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	"github.com/hyperledger/aries-framework-go/pkg/framework/didresolver"
+)
+
+// maxFrameSize is the MTU of the links of the in-memory medium
+const maxFrameSize = 512
+
+// Network connects the agents of the demo: the agents exchange their DIDComm envelopes over the in-memory
+// near-field medium and register their public DIDs in the in-memory verifiable data registry
+type Network struct {
+	medium   *nearfield.Medium
+	registry *mem.VDR
+	resolver *didresolver.DIDResolver
+}
+
+// NewNetwork creates the in-memory network of the agents
+func NewNetwork() *Network {
+	registry := mem.New()
+
+	return &Network{
+		medium:   nearfield.NewMedium(maxFrameSize),
+		registry: registry,
+		resolver: didresolver.New(didresolver.WithDidMethod(registry)),
+	}
+}
+
+// Resolver returns the resolver of the DIDs registered in the network
+func (n *Network) Resolver() *didresolver.DIDResolver {
+	return n.resolver
+}
+
+// CreateOutboundTransport returns the outbound transport of the agents, it implements
+// api.TransportProviderFactory
+func (n *Network) CreateOutboundTransport() (transport.OutboundTransport, error) {
+	return nearfield.NewOutbound(n.medium), nil
+}
+
+// identity is a public DID registered in the network with its signing key
+type identity struct {
+	DID   string
+	KeyID string
+	priv  ed25519.PrivateKey
+}
+
+// newIdentity creates a signing key and registers the DID of the key in the network
+func (n *Network) newIdentity() (*identity, error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("generate key: %w", err)
+	}
+
+	doc, err := n.registry.Create(&did.Doc{Context: []string{"https://w3id.org/did/v1"}})
+	if err != nil {
+		return nil, fmt.Errorf("register DID: %w", err)
+	}
+
+	doc.PublicKey = []did.PublicKey{{
+		ID:         doc.ID + "#key-1",
+		Type:       "Ed25519VerificationKey2018",
+		Controller: doc.ID,
+		Value:      pub,
+	}}
+	doc.AssertionMethod = []did.VerificationMethod{{PublicKey: doc.PublicKey[0], Referenced: true}}
+
+	if _, err = n.registry.Update(doc); err != nil {
+		return nil, fmt.Errorf("register DID key: %w", err)
+	}
+
+	return &identity{DID: doc.ID, KeyID: doc.PublicKey[0].ID, priv: priv}, nil
+}
+
+// Sign signs the document with the key of the identity
+func (i *identity) Sign(doc []byte) ([]byte, error) {
+	return ed25519.Sign(i.priv, doc), nil
+}
@@ -0,0 +1,71 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package demo
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+)
+
+// challengeTTL is the validity period of the presentation requests of the verifier
+const challengeTTL = 5 * time.Minute
+
+// Verifier is the agent of a relying party requesting the presentation of credentials
+type Verifier struct {
+	*Agent
+	domain     string
+	resolver   verifiable.DIDResolver
+	challenges *verifiable.ChallengeStore
+}
+
+// NewVerifier starts the verifier agent, the presentations are requested for the domain of the verifier
+func (n *Network) NewVerifier(name, domain string) (*Verifier, error) {
+	agent, err := n.NewAgent(name)
+	if err != nil {
+		return nil, err
+	}
+
+	challenges, err := verifiable.NewChallengeStore(agent.ctx.StorageProvider(), challengeTTL)
+	if err != nil {
+		if e := agent.Close(); e != nil {
+			logger.Warnf("close agent %s: %s", name, e)
+		}
+
+		return nil, fmt.Errorf("create challenge store of verifier %s: %w", name, err)
+	}
+
+	return &Verifier{Agent: agent, domain: domain, resolver: n.resolver, challenges: challenges}, nil
+}
+
+// RequestPresentation returns a presentation request with a new challenge
+func (v *Verifier) RequestPresentation() (*verifiable.PresentationRequest, error) {
+	return v.challenges.NewRequest(v.domain)
+}
+
+// Verify checks the presentation answers the request and returns its credentials. The proofs of the presentation
+// and of its credentials are verified against the public DIDs of the network, the presentation must be signed
+// by its holder and the holder must be the subject of the credentials. A presentation is accepted once.
+func (v *Verifier) Verify(vpBytes []byte, request *verifiable.PresentationRequest) ([]*verifiable.Credential, error) {
+	credentialOpts := []verifiable.CredentialOpt{
+		verifiable.WithNoCustomSchemaCheck(),
+		verifiable.WithProofChecker(verifiable.NewDIDProofChecker(v.resolver)),
+	}
+
+	vp, err := verifiable.NewPresentation(vpBytes,
+		verifiable.WithPresentationProofCheck(verifiable.NewDIDKeyResolver(v.resolver)),
+		verifiable.WithPresentationRequest(request),
+		verifiable.WithChallengeStore(v.challenges),
+		verifiable.WithHolderBinding(),
+		verifiable.WithSubjectHolderCheck(credentialOpts...))
+	if err != nil {
+		return nil, fmt.Errorf("verify presentation: %w", err)
+	}
+
+	return vp.DecodeCredentials(credentialOpts...)
+}
@@ -0,0 +1,38 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package cache defines the cache abstraction shared by the components caching data, like the DID resolution,
+// the credential schemas or the connection records, so the deployment picks where the data is cached:
+// in memory (see cache/mem) or in a cache server shared by the agent instances (see cache/redis).
+package cache
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrNotFound is returned when the key isn't cached or its entry has expired
+var ErrNotFound = errors.New("not found in cache")
+
+// Cache stores values by key for a limited time
+type Cache interface {
+	// Get returns the value cached for the key or ErrNotFound
+	Get(key string) ([]byte, error)
+	// Set caches the value for the key, the entry expires after the ttl unless the ttl is zero
+	Set(key string, value []byte, ttl time.Duration) error
+	// Delete removes the entry of the key, deleting a key not cached isn't an error
+	Delete(key string) error
+}
+
+// Provider opens the caches of the name spaces
+type Provider interface {
+	// OpenCache returns the cache of the name space, the caches opened for the same name space share the entries
+	OpenCache(name string) (Cache, error)
+	// CloseCache closes the cache of the name space
+	CloseCache(name string) error
+	// Close closes all the caches opened by the provider
+	Close() error
+}
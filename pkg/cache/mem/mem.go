@@ -0,0 +1,186 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package mem implements the cache.Provider keeping the entries in memory
+package mem
+
+import (
+	"container/list"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/cache"
+	"github.com/hyperledger/aries-framework-go/pkg/common/clock"
+)
+
+// Provider is the in-memory cache.Provider, the caches are safe for concurrent use
+type Provider struct {
+	maxEntries int
+	caches     map[string]*memCache
+	lock       sync.Mutex
+	clock      clock.Clock
+}
+
+// Opt configures the in-memory cache provider
+type Opt func(p *Provider)
+
+// WithMaxEntries bounds the number of entries of each cache, the least recently used entries are evicted first
+func WithMaxEntries(n int) Opt {
+	return func(p *Provider) {
+		p.maxEntries = n
+	}
+}
+
+// WithClock sets the clock expiring the entries, the system clock by default
+func WithClock(c clock.Clock) Opt {
+	return func(p *Provider) {
+		p.clock = c
+	}
+}
+
+// NewProvider returns the in-memory cache provider, the caches are unbounded unless WithMaxEntries is passed
+func NewProvider(opts ...Opt) *Provider {
+	p := &Provider{caches: make(map[string]*memCache), clock: clock.System()}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p
+}
+
+// OpenCache returns the cache of the name space
+func (p *Provider) OpenCache(name string) (cache.Cache, error) {
+	k := strings.ToLower(name)
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if c, ok := p.caches[k]; ok {
+		return c, nil
+	}
+
+	c := &memCache{
+		maxEntries: p.maxEntries,
+		entries:    make(map[string]*list.Element),
+		lru:        list.New(),
+		clock:      p.clock,
+	}
+	p.caches[k] = c
+
+	return c, nil
+}
+
+// CloseCache drops the entries of the cache of the name space
+func (p *Provider) CloseCache(name string) error {
+	p.lock.Lock()
+	delete(p.caches, strings.ToLower(name))
+	p.lock.Unlock()
+
+	return nil
+}
+
+// Close drops the entries of all the caches
+func (p *Provider) Close() error {
+	p.lock.Lock()
+	p.caches = make(map[string]*memCache)
+	p.lock.Unlock()
+
+	return nil
+}
+
+type memEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+// memCache is a LRU cache whose entries expire
+type memCache struct {
+	maxEntries int
+	entries    map[string]*list.Element
+	lru        *list.List
+	lock       sync.Mutex
+	clock      clock.Clock
+}
+
+// Get returns a copy of the value cached for the key
+func (c *memCache) Get(key string) ([]byte, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, cache.ErrNotFound
+	}
+
+	entry := e.Value.(*memEntry)
+	if !entry.expires.IsZero() && !c.clock.Now().Before(entry.expires) {
+		c.remove(e)
+
+		return nil, cache.ErrNotFound
+	}
+
+	c.lru.MoveToFront(e)
+
+	return copyBytes(entry.value), nil
+}
+
+// Set caches a copy of the value
+func (c *memCache) Set(key string, value []byte, ttl time.Duration) error {
+	entry := &memEntry{key: key, value: copyBytes(value)}
+	if ttl > 0 {
+		entry.expires = c.clock.Now().Add(ttl)
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if e, ok := c.entries[key]; ok {
+		e.Value = entry
+		c.lru.MoveToFront(e)
+
+		return nil
+	}
+
+	c.entries[key] = c.lru.PushFront(entry)
+
+	for c.maxEntries > 0 && c.lru.Len() > c.maxEntries {
+		c.remove(c.lru.Back())
+	}
+
+	return nil
+}
+
+// Delete removes the entry of the key
+func (c *memCache) Delete(key string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if e, ok := c.entries[key]; ok {
+		c.remove(e)
+	}
+
+	return nil
+}
+
+// Len returns the number of entries, including the expired entries not evicted yet
+func (c *memCache) Len() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.lru.Len()
+}
+
+func (c *memCache) remove(e *list.Element) {
+	c.lru.Remove(e)
+	delete(c.entries, e.Value.(*memEntry).key)
+}
+
+func copyBytes(v []byte) []byte {
+	return append([]byte(nil), v...)
+}
@@ -0,0 +1,126 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package mem
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hyperledger/aries-framework-go/pkg/cache"
+	mockclock "github.com/hyperledger/aries-framework-go/pkg/internal/mock/clock"
+)
+
+func TestCache(t *testing.T) {
+	t.Run("test set get delete", func(t *testing.T) {
+		c, err := NewProvider().OpenCache("test")
+		require.NoError(t, err)
+
+		_, err = c.Get("k1")
+		require.Equal(t, cache.ErrNotFound, err)
+
+		require.NoError(t, c.Set("k1", []byte("v1"), 0))
+		v, err := c.Get("k1")
+		require.NoError(t, err)
+		require.Equal(t, []byte("v1"), v)
+
+		require.NoError(t, c.Set("k1", []byte("v2"), 0))
+		v, err = c.Get("k1")
+		require.NoError(t, err)
+		require.Equal(t, []byte("v2"), v)
+
+		require.NoError(t, c.Delete("k1"))
+		require.NoError(t, c.Delete("k1"))
+		_, err = c.Get("k1")
+		require.Equal(t, cache.ErrNotFound, err)
+	})
+
+	t.Run("test entries expire", func(t *testing.T) {
+		clock := &mockclock.Clock{Time: time.Date(2019, time.October, 1, 0, 0, 0, 0, time.UTC)}
+		p := NewProvider(WithClock(clock))
+
+		c, err := p.OpenCache("test")
+		require.NoError(t, err)
+
+		require.NoError(t, c.Set("k1", []byte("v1"), time.Minute))
+		require.NoError(t, c.Set("k2", []byte("v2"), 0))
+
+		clock.Add(time.Minute)
+
+		_, err = c.Get("k1")
+		require.Equal(t, cache.ErrNotFound, err)
+		_, err = c.Get("k2")
+		require.NoError(t, err)
+		require.Equal(t, 1, c.(*memCache).Len())
+	})
+
+	t.Run("test least recently used entries evicted", func(t *testing.T) {
+		c, err := NewProvider(WithMaxEntries(2)).OpenCache("test")
+		require.NoError(t, err)
+
+		require.NoError(t, c.Set("k1", []byte("v1"), 0))
+		require.NoError(t, c.Set("k2", []byte("v2"), 0))
+		_, err = c.Get("k1")
+		require.NoError(t, err)
+		require.NoError(t, c.Set("k3", []byte("v3"), 0))
+
+		_, err = c.Get("k2")
+		require.Equal(t, cache.ErrNotFound, err)
+		_, err = c.Get("k1")
+		require.NoError(t, err)
+		require.Equal(t, 2, c.(*memCache).Len())
+	})
+
+	t.Run("test cached values are not shared with callers", func(t *testing.T) {
+		c, err := NewProvider().OpenCache("test")
+		require.NoError(t, err)
+
+		v := []byte("v1")
+		require.NoError(t, c.Set("k1", v, 0))
+		v[0] = 'x'
+
+		cached, err := c.Get("k1")
+		require.NoError(t, err)
+		require.Equal(t, []byte("v1"), cached)
+
+		cached[0] = 'x'
+		cached, err = c.Get("k1")
+		require.NoError(t, err)
+		require.Equal(t, []byte("v1"), cached)
+	})
+}
+
+func TestProvider(t *testing.T) {
+	p := NewProvider()
+
+	c1, err := p.OpenCache("test")
+	require.NoError(t, err)
+
+	c2, err := p.OpenCache("Test")
+	require.NoError(t, err)
+
+	other, err := p.OpenCache("other")
+	require.NoError(t, err)
+
+	require.NoError(t, c1.Set("k1", []byte("v1"), 0))
+	_, err = c2.Get("k1")
+	require.NoError(t, err)
+	_, err = other.Get("k1")
+	require.Equal(t, cache.ErrNotFound, err)
+
+	require.NoError(t, p.CloseCache("test"))
+
+	c1, err = p.OpenCache("test")
+	require.NoError(t, err)
+	_, err = c1.Get("k1")
+	require.Equal(t, cache.ErrNotFound, err)
+
+	require.NoError(t, other.Set("k1", []byte("v1"), 0))
+	require.NoError(t, p.Close())
+	require.Empty(t, p.caches)
+}
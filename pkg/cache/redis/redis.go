@@ -0,0 +1,348 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package redis implements the cache.Provider keeping the entries in a Redis server, so the agent instances
+// behind a load balancer share the cache. The caches are name spaced by prefixing the keys with the name of
+// the cache.
+package redis
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/cache"
+)
+
+const (
+	defaultDialTimeout = 5 * time.Second
+	defaultTimeout     = 3 * time.Second
+	defaultPoolSize    = 10
+)
+
+// Provider is the Redis cache.Provider, the connections to the server are dialed on demand and the idle
+// connections are kept in a pool, the connections are discarded after a network failure
+type Provider struct {
+	addr        string
+	password    string
+	db          int
+	dialTimeout time.Duration
+	timeout     time.Duration
+	tlsConfig   *tls.Config
+	idle        chan *conn
+	closed      bool
+	lock        sync.RWMutex
+}
+
+// conn is a connection to the server
+type conn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+// Opt configures the Redis cache provider
+type Opt func(p *Provider)
+
+// WithPassword authenticates the connection with the password
+func WithPassword(password string) Opt {
+	return func(p *Provider) {
+		p.password = password
+	}
+}
+
+// WithDatabase selects the database of the server, the database 0 is used by default
+func WithDatabase(db int) Opt {
+	return func(p *Provider) {
+		p.db = db
+	}
+}
+
+// WithDialTimeout sets the timeout of the connection to the server
+func WithDialTimeout(timeout time.Duration) Opt {
+	return func(p *Provider) {
+		p.dialTimeout = timeout
+	}
+}
+
+// WithTimeout sets the deadline of the commands, from sending the command to reading the reply
+func WithTimeout(timeout time.Duration) Opt {
+	return func(p *Provider) {
+		p.timeout = timeout
+	}
+}
+
+// WithPoolSize sets the maximum number of idle connections kept open, the connections above the pool size
+// are closed once their command is done (zero disables the pool)
+func WithPoolSize(size int) Opt {
+	return func(p *Provider) {
+		p.idle = make(chan *conn, size)
+	}
+}
+
+// WithTLSConfig connects to the server with TLS
+func WithTLSConfig(config *tls.Config) Opt {
+	return func(p *Provider) {
+		p.tlsConfig = config
+	}
+}
+
+// NewProvider returns the cache provider of the Redis server at the address (host:port)
+func NewProvider(addr string, opts ...Opt) *Provider {
+	p := &Provider{
+		addr:        addr,
+		dialTimeout: defaultDialTimeout,
+		timeout:     defaultTimeout,
+		idle:        make(chan *conn, defaultPoolSize),
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p
+}
+
+// OpenCache returns the cache of the name space
+func (p *Provider) OpenCache(name string) (cache.Cache, error) {
+	return &redisCache{prov: p, prefix: strings.ToLower(name) + ":"}, nil
+}
+
+// CloseCache is a no-op: the entries are shared with the other agent instances and expire on the server
+func (p *Provider) CloseCache(name string) error {
+	return nil
+}
+
+// Close closes the idle connections to the server, the connections in use are closed once their command is done
+func (p *Provider) Close() error {
+	p.lock.Lock()
+	p.closed = true
+	p.lock.Unlock()
+
+	var err error
+
+	for {
+		select {
+		case c := <-p.idle:
+			if e := c.Close(); e != nil {
+				err = e
+			}
+		default:
+			return err
+		}
+	}
+}
+
+// do sends the command and returns its reply, nil being returned for the null bulk string
+func (p *Provider) do(args ...string) ([]byte, error) {
+	c, err := p.get()
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := p.roundTrip(c, args...)
+	if err != nil {
+		var replyErr replyError
+		if !errors.As(err, &replyErr) {
+			// the state of the connection is unknown after a network failure
+			c.Close() // nolint: errcheck
+			return nil, err
+		}
+	}
+
+	p.put(c)
+
+	return reply, err
+}
+
+// get returns an idle connection or dials a new one
+func (p *Provider) get() (*conn, error) {
+	p.lock.RLock()
+	closed := p.closed
+	p.lock.RUnlock()
+
+	if closed {
+		return nil, errors.New("redis cache provider is closed")
+	}
+
+	select {
+	case c := <-p.idle:
+		return c, nil
+	default:
+		return p.connect()
+	}
+}
+
+// put returns the connection to the pool, it's closed if the pool is full or the provider closed
+func (p *Provider) put(c *conn) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	if !p.closed {
+		select {
+		case p.idle <- c:
+			return
+		default:
+		}
+	}
+
+	c.Close() // nolint: errcheck
+}
+
+func (p *Provider) connect() (*conn, error) {
+	dialer := &net.Dialer{Timeout: p.dialTimeout}
+
+	var (
+		nc  net.Conn
+		err error
+	)
+
+	if p.tlsConfig != nil {
+		nc, err = tls.DialWithDialer(dialer, "tcp", p.addr, p.tlsConfig)
+	} else {
+		nc, err = dialer.Dial("tcp", p.addr)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to redis %s: %w", p.addr, err)
+	}
+
+	c := &conn{Conn: nc, reader: bufio.NewReader(nc)}
+
+	if p.password != "" {
+		if _, err = p.roundTrip(c, "AUTH", p.password); err != nil {
+			c.Close() // nolint: errcheck
+			return nil, fmt.Errorf("redis authentication failed: %w", err)
+		}
+	}
+
+	if p.db != 0 {
+		if _, err = p.roundTrip(c, "SELECT", strconv.Itoa(p.db)); err != nil {
+			c.Close() // nolint: errcheck
+			return nil, fmt.Errorf("failed to select redis database %d: %w", p.db, err)
+		}
+	}
+
+	return c, nil
+}
+
+func (p *Provider) roundTrip(c *conn, args ...string) ([]byte, error) {
+	if p.timeout > 0 {
+		if err := c.SetDeadline(time.Now().Add(p.timeout)); err != nil {
+			return nil, fmt.Errorf("failed to set redis command deadline: %w", err)
+		}
+	}
+
+	var cmd strings.Builder
+
+	fmt.Fprintf(&cmd, "*%d\r\n", len(args))
+
+	for _, arg := range args {
+		fmt.Fprintf(&cmd, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+
+	if _, err := io.WriteString(c, cmd.String()); err != nil {
+		return nil, fmt.Errorf("failed to send redis command: %w", err)
+	}
+
+	return readReply(c.reader)
+}
+
+// replyError is the error replied by the server, the connection is still usable
+type replyError string
+
+func (e replyError) Error() string {
+	return "redis: " + string(e)
+}
+
+// readReply reads a simple string, error, integer or bulk string reply
+func readReply(r *bufio.Reader) ([]byte, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("failed to read redis reply: %w", err)
+	}
+
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("empty redis reply")
+	}
+
+	switch line[0] {
+	case '+', ':':
+		return []byte(line[1:]), nil
+	case '-':
+		return nil, replyError(line[1:])
+	case '$':
+		n, convErr := strconv.Atoi(line[1:])
+		if convErr != nil {
+			return nil, fmt.Errorf("invalid redis bulk string length: %w", convErr)
+		}
+
+		if n < 0 {
+			return nil, nil
+		}
+
+		data := make([]byte, n+2)
+		if _, err = io.ReadFull(r, data); err != nil {
+			return nil, fmt.Errorf("failed to read redis reply: %w", err)
+		}
+
+		return data[:n], nil
+	default:
+		return nil, fmt.Errorf("unsupported redis reply: %q", line)
+	}
+}
+
+// redisCache is the cache of a name space
+type redisCache struct {
+	prov   *Provider
+	prefix string
+}
+
+// Get returns the value cached for the key
+func (c *redisCache) Get(key string) ([]byte, error) {
+	v, err := c.prov.do("GET", c.prefix+key)
+	if err != nil {
+		return nil, err
+	}
+
+	if v == nil {
+		return nil, cache.ErrNotFound
+	}
+
+	return v, nil
+}
+
+// Set caches the value, the ttl is rounded down to the millisecond (1ms at least)
+func (c *redisCache) Set(key string, value []byte, ttl time.Duration) error {
+	args := []string{"SET", c.prefix + key, string(value)}
+
+	if ttl > 0 {
+		ms := int64(ttl / time.Millisecond)
+		if ms == 0 {
+			ms = 1
+		}
+
+		args = append(args, "PX", strconv.FormatInt(ms, 10))
+	}
+
+	_, err := c.prov.do(args...)
+
+	return err
+}
+
+// Delete removes the entry of the key
+func (c *redisCache) Delete(key string) error {
+	_, err := c.prov.do("DEL", c.prefix+key)
+
+	return err
+}
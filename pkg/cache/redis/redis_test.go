@@ -0,0 +1,394 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package redis
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hyperledger/aries-framework-go/pkg/cache"
+)
+
+func TestCache(t *testing.T) {
+	server := newMockServer(t, "")
+	defer server.close()
+
+	p := NewProvider(server.addr())
+	defer func() { require.NoError(t, p.Close()) }()
+
+	c, err := p.OpenCache("Test")
+	require.NoError(t, err)
+
+	t.Run("test set get delete", func(t *testing.T) {
+		_, err = c.Get("k1")
+		require.Equal(t, cache.ErrNotFound, err)
+
+		require.NoError(t, c.Set("k1", []byte("v1\r\nwith line break"), 0))
+		v, e := c.Get("k1")
+		require.NoError(t, e)
+		require.Equal(t, []byte("v1\r\nwith line break"), v)
+		require.Contains(t, server.values(), "test:k1")
+
+		require.NoError(t, c.Delete("k1"))
+		_, err = c.Get("k1")
+		require.Equal(t, cache.ErrNotFound, err)
+	})
+
+	t.Run("test empty value", func(t *testing.T) {
+		require.NoError(t, c.Set("k1", nil, 0))
+		v, e := c.Get("k1")
+		require.NoError(t, e)
+		require.Empty(t, v)
+	})
+
+	t.Run("test ttl", func(t *testing.T) {
+		require.NoError(t, c.Set("k1", []byte("v1"), 1500*time.Microsecond))
+		require.Equal(t, []string{"SET", "test:k1", "v1", "PX", "1"}, server.last())
+	})
+
+	t.Run("test server error", func(t *testing.T) {
+		err = c.Set("fail", []byte("v1"), 0)
+		require.EqualError(t, err, "redis: ERR failure")
+
+		// the connection is still usable
+		_, err = c.Get("fail")
+		require.Equal(t, cache.ErrNotFound, err)
+	})
+
+	t.Run("test reconnect after network failure", func(t *testing.T) {
+		require.NoError(t, c.Set("k1", []byte("v1"), 0))
+		server.dropConnections()
+
+		_, err = c.Get("k1")
+		require.Error(t, err)
+
+		v, e := c.Get("k1")
+		require.NoError(t, e)
+		require.Equal(t, []byte("v1"), v)
+	})
+
+	require.NoError(t, p.CloseCache("test"))
+}
+
+func TestProvider(t *testing.T) {
+	t.Run("test authentication and database selection", func(t *testing.T) {
+		server := newMockServer(t, "secret")
+		defer server.close()
+
+		p := NewProvider(server.addr(), WithPassword("secret"), WithDatabase(2))
+		c, err := p.OpenCache("test")
+		require.NoError(t, err)
+
+		require.NoError(t, c.Set("k1", []byte("v1"), 0))
+		require.Equal(t, []string{"AUTH", "secret"}, server.commands()[0])
+		require.Equal(t, []string{"SELECT", "2"}, server.commands()[1])
+		require.NoError(t, p.Close())
+	})
+
+	t.Run("test authentication failure", func(t *testing.T) {
+		server := newMockServer(t, "secret")
+		defer server.close()
+
+		c, err := NewProvider(server.addr(), WithPassword("wrong")).OpenCache("test")
+		require.NoError(t, err)
+
+		_, err = c.Get("k1")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "redis authentication failed")
+	})
+
+	t.Run("test database selection failure", func(t *testing.T) {
+		server := newMockServer(t, "")
+		defer server.close()
+
+		c, err := NewProvider(server.addr(), WithDatabase(-1)).OpenCache("test")
+		require.NoError(t, err)
+
+		_, err = c.Get("k1")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to select redis database")
+	})
+
+	t.Run("test connection failure", func(t *testing.T) {
+		server := newMockServer(t, "")
+		addr := server.addr()
+		server.close()
+
+		c, err := NewProvider(addr, WithDialTimeout(time.Second)).OpenCache("test")
+		require.NoError(t, err)
+
+		_, err = c.Get("k1")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to connect to redis")
+	})
+}
+
+func TestProvider_Connections(t *testing.T) {
+	t.Run("test command timeout", func(t *testing.T) {
+		server := newMockServer(t, "")
+		defer server.close()
+
+		p := NewProvider(server.addr(), WithTimeout(50*time.Millisecond))
+		c, err := p.OpenCache("test")
+		require.NoError(t, err)
+
+		_, err = c.Get("hang")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to read redis reply")
+
+		require.NoError(t, c.Set("k1", []byte("v1"), 0))
+		require.NoError(t, p.Close())
+	})
+
+	t.Run("test concurrent commands", func(t *testing.T) {
+		server := newMockServer(t, "")
+		defer server.close()
+
+		p := NewProvider(server.addr(), WithPoolSize(2))
+		c, err := p.OpenCache("test")
+		require.NoError(t, err)
+
+		var wg sync.WaitGroup
+
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+
+			go func(i int) {
+				defer wg.Done()
+				require.NoError(t, c.Set(fmt.Sprintf("k%d", i), []byte("v"), 0))
+			}(i)
+		}
+
+		wg.Wait()
+
+		require.Len(t, server.values(), 10)
+		require.True(t, len(p.idle) <= 2)
+		require.NoError(t, p.Close())
+
+		_, err = c.Get("k1")
+		require.EqualError(t, err, "redis cache provider is closed")
+	})
+
+	t.Run("test tls", func(t *testing.T) {
+		ts := httptest.NewTLSServer(http.NotFoundHandler())
+		defer ts.Close()
+
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+
+		server := startMockServer(tls.NewListener(listener, ts.TLS), "")
+		defer server.close()
+
+		clientConfig := ts.Client().Transport.(*http.Transport).TLSClientConfig
+
+		c, err := NewProvider(server.addr(), WithTLSConfig(clientConfig)).OpenCache("test")
+		require.NoError(t, err)
+		require.NoError(t, c.Set("k1", []byte("v1"), 0))
+
+		c, err = NewProvider(server.addr(), WithTLSConfig(&tls.Config{})).OpenCache("test")
+		require.NoError(t, err)
+
+		err = c.Set("k1", []byte("v1"), 0)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to connect to redis")
+	})
+}
+
+func TestReadReply(t *testing.T) {
+	for _, reply := range []string{"", "\r\n", "$x\r\n", "$5\r\nab", "*1\r\n"} {
+		_, err := readReply(bufio.NewReader(strings.NewReader(reply)))
+		require.Error(t, err, reply)
+	}
+
+	v, err := readReply(bufio.NewReader(strings.NewReader(":1\r\n")))
+	require.NoError(t, err)
+	require.Equal(t, []byte("1"), v)
+}
+
+// mockServer implements the commands of the Redis protocol used by the cache, without expiry
+type mockServer struct {
+	listener net.Listener
+	password string
+	lock     sync.Mutex
+	store    map[string]string
+	received [][]string
+	conns    []net.Conn
+}
+
+func newMockServer(t *testing.T, password string) *mockServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	return startMockServer(listener, password)
+}
+
+func startMockServer(listener net.Listener, password string) *mockServer {
+	s := &mockServer{listener: listener, password: password, store: make(map[string]string)}
+
+	go s.serve()
+
+	return s
+}
+
+func (s *mockServer) addr() string {
+	return s.listener.Addr().String()
+}
+
+func (s *mockServer) close() {
+	s.listener.Close() // nolint: errcheck
+	s.dropConnections()
+}
+
+func (s *mockServer) dropConnections() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, conn := range s.conns {
+		conn.Close() // nolint: errcheck
+	}
+
+	s.conns = nil
+}
+
+func (s *mockServer) values() map[string]string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	values := make(map[string]string)
+	for k, v := range s.store {
+		values[k] = v
+	}
+
+	return values
+}
+
+func (s *mockServer) commands() [][]string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return append([][]string(nil), s.received...)
+}
+
+func (s *mockServer) last() []string {
+	commands := s.commands()
+
+	return commands[len(commands)-1]
+}
+
+func (s *mockServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		s.lock.Lock()
+		s.conns = append(s.conns, conn)
+		s.lock.Unlock()
+
+		go s.handle(conn)
+	}
+}
+
+func (s *mockServer) handle(conn net.Conn) {
+	r := bufio.NewReader(conn)
+
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+
+		reply := s.execute(args)
+		if reply == "" {
+			// the command hangs
+			continue
+		}
+
+		if _, err = io.WriteString(conn, reply); err != nil {
+			return
+		}
+	}
+}
+
+func (s *mockServer) execute(args []string) string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.received = append(s.received, args)
+
+	switch strings.ToUpper(args[0]) {
+	case "AUTH":
+		if args[1] != s.password {
+			return "-WRONGPASS invalid password\r\n"
+		}
+	case "SELECT":
+		if db, err := strconv.Atoi(args[1]); err != nil || db < 0 {
+			return "-ERR DB index is out of range\r\n"
+		}
+	case "GET":
+		if args[1] == "test:hang" {
+			return ""
+		}
+
+		v, ok := s.store[args[1]]
+		if !ok {
+			return "$-1\r\n"
+		}
+
+		return fmt.Sprintf("$%d\r\n%s\r\n", len(v), v)
+	case "SET":
+		if strings.HasSuffix(args[1], "fail") {
+			return "-ERR failure\r\n"
+		}
+
+		s.store[args[1]] = args[2]
+	case "DEL":
+		delete(s.store, args[1])
+
+		return ":1\r\n"
+	}
+
+	return "+OK\r\n"
+}
+
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	n, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]string, n)
+
+	for i := range args {
+		arg, e := readReply(r)
+		if e != nil {
+			return nil, e
+		}
+
+		args[i] = string(arg)
+	}
+
+	return args, nil
+}
@@ -7,15 +7,19 @@ SPDX-License-Identifier: Apache-2.0
 package didexchange
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"sync"
+	"time"
 
-	"github.com/google/uuid"
-
+	"github.com/hyperledger/aries-framework-go/pkg/common/clock"
+	"github.com/hyperledger/aries-framework-go/pkg/common/idgen"
+	"github.com/hyperledger/aries-framework-go/pkg/common/log"
 	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/service"
 	"github.com/hyperledger/aries-framework-go/pkg/didcomm/protocol/didexchange"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
 	"github.com/hyperledger/aries-framework-go/pkg/storage"
 	"github.com/hyperledger/aries-framework-go/pkg/wallet"
 )
@@ -25,10 +29,32 @@ const (
 	ConnectionID = didexchange.ConnectionID
 	// InvitationID invitation id is created in invitation request
 	InvitationID = didexchange.InvitationID
+
+	// defaultBufferSize is the default number of events buffered by the client
+	defaultBufferSize = 10
+)
+
+var logger = log.New("aries-framework/didexchange/client")
+
+var (
+	// ErrConnectionNotFound is returned when connection not found
+	ErrConnectionNotFound = errors.New("connection not found")
+	// ErrClientClosed is passed to the Stop function of the action events pending when the client is closed
+	ErrClientClosed = errors.New("didexchange client closed")
+	// ErrRoleDisabled is returned when the action is taken in a role of the DID Exchange protocol the agent
+	// doesn't play
+	ErrRoleDisabled = errors.New("didexchange role disabled")
 )
 
-// ErrConnectionNotFound is returned when connection not found
-var ErrConnectionNotFound = errors.New("connection not found")
+// OverflowPolicy selects what the client does with a new event when its event buffer is full
+type OverflowPolicy int
+
+const (
+	// Block waits until the consumer has received a buffered event, the DID Exchange service is stalled meanwhile
+	Block OverflowPolicy = iota
+	// DropOldest drops the oldest buffered event with a warning, so a slow consumer doesn't stall the service
+	DropOldest
+)
 
 // provider contains dependencies for the DID exchange protocol and is typically created by using aries.Context()
 type provider interface {
@@ -38,24 +64,84 @@ type provider interface {
 	StorageProvider() storage.Provider
 }
 
+// staticConnectionCreator is implemented by the DID Exchange services supporting static connections
+type staticConnectionCreator interface {
+	CreateStaticConnection(conn *didexchange.StaticConnection) (string, error)
+}
+
+// clockProvider is optionally implemented by the provider to replace the system clock, e.g. in the tests
+type clockProvider interface {
+	Clock() clock.Clock
+}
+
+// idGeneratorProvider is optionally implemented by the provider to replace the random UUIDs of the invitations
+type idGeneratorProvider interface {
+	IDGenerator() idgen.Generator
+}
+
+// rolesProvider is optionally implemented by the provider restricting the roles the agent plays in the protocols
+type rolesProvider interface {
+	RoleEnabled(svcName, role string) bool
+}
+
+// myDIDSetter is implemented by the DID Exchange services completing the exchange with a given DID
+type myDIDSetter interface {
+	SetMyDID(connectionID string, doc *did.Doc) error
+}
+
 // Client enable access to didexchange api
 // TODO add support for Accept Exchange Request & Accept Invitation
 //  using events & callback (#198 & #238)
 type Client struct {
 	didexchangeSvc           service.DIDComm
 	wallet                   wallet.Crypto
-	inboundTransportEndpoint string
+	inboundTransportEndpoint func() string
+	svcActionCh              chan service.DIDCommAction
+	svcMsgCh                 chan service.StateMsg
 	actionCh                 chan service.DIDCommAction
 	msgCh                    chan service.StateMsg
+	actionBufferSize         int
+	msgBufferSize            int
+	overflowPolicy           OverflowPolicy
+	done                     chan struct{}
+	listeners                sync.WaitGroup
+	closeOnce                sync.Once
 	actionEvent              chan<- service.DIDCommAction
 	actionEventlock          sync.RWMutex
 	msgEvents                []chan<- service.StateMsg
 	msgEventsLock            sync.RWMutex
 	connectionStore          *didexchange.ConnectionRecorder
+	clock                    clock.Clock
+	ids                      idgen.Generator
+	roles                    rolesProvider
+}
+
+// Opt is a didexchange client option
+type Opt func(c *Client)
+
+// WithActionBufferSize sets the number of action events buffered for the consumer, 10 by default
+func WithActionBufferSize(size int) Opt {
+	return func(c *Client) {
+		c.actionBufferSize = size
+	}
+}
+
+// WithMsgBufferSize sets the number of message events buffered for the consumers, 10 by default
+func WithMsgBufferSize(size int) Opt {
+	return func(c *Client) {
+		c.msgBufferSize = size
+	}
+}
+
+// WithOverflowPolicy sets the policy applied when an event buffer is full, the client blocks by default
+func WithOverflowPolicy(policy OverflowPolicy) Opt {
+	return func(c *Client) {
+		c.overflowPolicy = policy
+	}
 }
 
 // New return new instance of didexchange client
-func New(ctx provider) (*Client, error) {
+func New(ctx provider, opts ...Opt) (*Client, error) {
 	svc, err := ctx.Service(didexchange.DIDExchange)
 	if err != nil {
 		return nil, err
@@ -74,13 +160,40 @@ func New(ctx provider) (*Client, error) {
 	c := &Client{
 		didexchangeSvc:           didexchangeSvc,
 		wallet:                   ctx.CryptoWallet(),
-		inboundTransportEndpoint: ctx.InboundTransportEndpoint(),
-		// TODO channel size - https://github.com/hyperledger/aries-framework-go/issues/246
-		actionCh:        make(chan service.DIDCommAction, 10),
-		msgCh:           make(chan service.StateMsg, 10),
-		connectionStore: didexchange.NewConnectionRecorder(store),
+		inboundTransportEndpoint: ctx.InboundTransportEndpoint,
+		svcActionCh:              make(chan service.DIDCommAction),
+		svcMsgCh:                 make(chan service.StateMsg),
+		actionBufferSize:         defaultBufferSize,
+		msgBufferSize:            defaultBufferSize,
+		done:                     make(chan struct{}),
+		connectionStore:          didexchange.NewConnectionRecorder(store),
+		clock:                    clock.System(),
+		ids:                      idgen.UUID(),
+	}
+
+	if p, ok := ctx.(clockProvider); ok && p.Clock() != nil {
+		c.clock = p.Clock()
+	}
+
+	if p, ok := ctx.(idGeneratorProvider); ok && p.IDGenerator() != nil {
+		c.ids = p.IDGenerator()
 	}
 
+	if p, ok := ctx.(rolesProvider); ok {
+		c.roles = p
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.actionBufferSize < 1 || c.msgBufferSize < 1 {
+		return nil, errors.New("event buffer size must be positive")
+	}
+
+	c.actionCh = make(chan service.DIDCommAction, c.actionBufferSize)
+	c.msgCh = make(chan service.StateMsg, c.msgBufferSize)
+
 	// start listening for action/message events
 	err = c.startServiceEventListener()
 	if err != nil {
@@ -90,18 +203,45 @@ func New(ctx provider) (*Client, error) {
 	return c, nil
 }
 
+// InvitationOpt restricts the exchange requests accepted for the invitation
+type InvitationOpt func(opts *invitationOpts)
+
+type invitationOpts struct {
+	ttl       time.Duration
+	singleUse bool
+}
+
+// WithInvitationTTL rejects the exchange requests received after the ttl has elapsed since the creation
+// of the invitation
+func WithInvitationTTL(ttl time.Duration) InvitationOpt {
+	return func(opts *invitationOpts) {
+		opts.ttl = ttl
+	}
+}
+
+// WithSingleUse accepts a single exchange request for the invitation
+func WithSingleUse() InvitationOpt {
+	return func(opts *invitationOpts) {
+		opts.singleUse = true
+	}
+}
+
 // CreateInvitation create invitation
-func (c *Client) CreateInvitation(label string) (*didexchange.Invitation, error) {
+func (c *Client) CreateInvitation(label string, opts ...InvitationOpt) (*didexchange.Invitation, error) {
+	if err := c.checkRole(didexchange.RoleInviter); err != nil {
+		return nil, err
+	}
+
 	verKey, err := c.wallet.CreateEncryptionKey()
 	if err != nil {
 		return nil, fmt.Errorf("failed CreateSigningKey: %w", err)
 	}
 
 	invitation := &didexchange.Invitation{
-		ID:              uuid.New().String(),
+		ID:              c.ids.NewID(),
 		Label:           label,
 		RecipientKeys:   []string{verKey},
-		ServiceEndpoint: c.inboundTransportEndpoint,
+		ServiceEndpoint: c.inboundTransportEndpoint(),
 		Type:            didexchange.ConnectionInvite,
 	}
 
@@ -110,28 +250,131 @@ func (c *Client) CreateInvitation(label string) (*didexchange.Invitation, error)
 		return nil, fmt.Errorf("failed to save invitation: %w", err)
 	}
 
+	if len(opts) > 0 {
+		invitationOpts := &invitationOpts{}
+		for _, opt := range opts {
+			opt(invitationOpts)
+		}
+
+		usage := &didexchange.InvitationUsage{SingleUse: invitationOpts.singleUse}
+		if invitationOpts.ttl > 0 {
+			usage.ExpiresAt = c.clock.Now().Add(invitationOpts.ttl)
+		}
+
+		err = c.connectionStore.SaveInvitationUsage(verKey, usage)
+		if err != nil {
+			return nil, fmt.Errorf("failed to save invitation usage: %w", err)
+		}
+	}
+
 	return invitation, nil
 }
 
-// HandleInvitation handle incoming invitation
-func (c *Client) HandleInvitation(invitation *didexchange.Invitation) error {
+// AcceptOpt selects how the DID exchange of a connection is completed
+type AcceptOpt func(opts *acceptOpts)
+
+type acceptOpts struct {
+	publicDID *did.Doc
+}
+
+// WithPublicDID completes the exchange with an existing public DID instead of a new peer DID,
+// the keys of the DID must be held by the wallet
+func WithPublicDID(doc *did.Doc) AcceptOpt {
+	return func(opts *acceptOpts) {
+		opts.publicDID = doc
+	}
+}
+
+// AcceptInvitation sets how the DID exchange of the connection is completed. AcceptInvitation is called, for the
+// invitee, with the ID of the invitation before the invitation is handled or, for the inviter, with the thread
+// ID of the exchange request before its action event is continued. The DID backing the connection is recorded
+// and returned by GetConnection. Accepting again with the same options is a no-op.
+func (c *Client) AcceptInvitation(connectionID string, opts ...AcceptOpt) error {
+	accept := &acceptOpts{}
+	for _, opt := range opts {
+		opt(accept)
+	}
+
+	if accept.publicDID == nil {
+		return nil
+	}
+
+	setter, ok := c.didexchangeSvc.(myDIDSetter)
+	if !ok {
+		return errors.New("public DIDs are not supported by the didexchange service")
+	}
+
+	if err := setter.SetMyDID(connectionID, accept.publicDID); err != nil {
+		return fmt.Errorf("failed to set public DID: %w", err)
+	}
+
+	return nil
+}
+
+// HandleInvitation handle incoming invitation, the options are applied with AcceptInvitation
+func (c *Client) HandleInvitation(invitation *didexchange.Invitation, opts ...AcceptOpt) error {
+	return c.HandleInvitationContext(context.Background(), invitation, opts...)
+}
+
+// HandleInvitationContext handle incoming invitation, the context cancels the request sent to the inviter
+func (c *Client) HandleInvitationContext(ctx context.Context, invitation *didexchange.Invitation,
+	opts ...AcceptOpt) error {
+	if err := c.checkRole(didexchange.RoleInvitee); err != nil {
+		return err
+	}
+
 	payload, err := json.Marshal(invitation)
 	if err != nil {
 		return fmt.Errorf("failed marshal invitation: %w", err)
 	}
-	if err = c.didexchangeSvc.Handle(&service.DIDCommMsg{Type: invitation.Type, Payload: payload}); err != nil {
+	if err = c.AcceptInvitation(invitation.ID, opts...); err != nil {
+		return err
+	}
+	if err = c.didexchangeSvc.Handle(ctx, &service.DIDCommMsg{Type: invitation.Type, Payload: payload}); err != nil {
 		return fmt.Errorf("failed from didexchange service handle: %w", err)
 	}
 	return nil
 }
 
-// QueryConnections queries connections matching given parameters
+// checkRole rejects the action taken in a role the agent doesn't play
+func (c *Client) checkRole(role string) error {
+	if c.roles != nil && !c.roles.RoleEnabled(didexchange.DIDExchange, role) {
+		return fmt.Errorf("%s role: %w", role, ErrRoleDisabled)
+	}
+
+	return nil
+}
+
+// QueryConnections queries connections matching given parameters, in connection ID order. The connections are
+// searched by their tags and metadata if the parameters filter on them, all the connections are searched
+// otherwise.
 func (c *Client) QueryConnections(request *QueryConnectionsParams) ([]*ConnectionResult, error) {
-	// TODO sample response, to be implemented as part of #226
-	return []*ConnectionResult{
-		{didexchange.ConnectionRecord{ConnectionID: uuid.New().String()}},
-		{didexchange.ConnectionRecord{ConnectionID: uuid.New().String()}},
-	}, nil
+	ids, err := c.connectionStore.FindConnections(request.Tags, request.Metadata)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query connections: %w", err)
+	}
+
+	results := make([]*ConnectionResult, 0, len(ids))
+
+	for _, id := range ids {
+		result, err := c.GetConnection(id)
+		if errors.Is(err, ErrConnectionNotFound) {
+			// metadata attached to a connection record which has been removed
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if request.State != "" && result.State != request.State {
+			continue
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
 }
 
 // GetConnection fetches single connection record for given id
@@ -143,32 +386,98 @@ func (c *Client) GetConnection(connectionID string) (*ConnectionResult, error) {
 		}
 		return nil, fmt.Errorf("cannot fetch state from store: connectionid=%s err=%s", connectionID, err)
 	}
+	algs, err := c.connectionStore.GetEncryptionAlgs(connectionID)
+	if err != nil {
+		return nil, fmt.Errorf("cannot fetch encryption algorithms from store: connectionid=%s err=%s",
+			connectionID, err)
+	}
 	return &ConnectionResult{
-		didexchange.ConnectionRecord{ConnectionID: connectionID, State: conn.State},
+		didexchange.ConnectionRecord{ConnectionID: connectionID, State: conn.State, EncryptionAlgs: algs,
+			MyDID: conn.MyDID, Tags: conn.Tags, Metadata: conn.Metadata},
 	}, nil
 }
 
-// RemoveConnection removes connection record for given id
+// SaveConnectionMetadata attaches the tags and key/values to the connection, e.g. the customer or the department
+// of the counterparty, replacing the ones previously attached. They are returned with the connection and can be
+// used to query the connections.
+func (c *Client) SaveConnectionMetadata(connectionID string, metadata *didexchange.ConnectionMetadata) error {
+	if _, err := c.GetConnection(connectionID); err != nil {
+		return err
+	}
+
+	if err := c.connectionStore.SaveConnectionMetadata(connectionID, metadata); err != nil {
+		return fmt.Errorf("failed to save connection metadata: %w", err)
+	}
+
+	return nil
+}
+
+// CreateStaticConnection creates a connection to a counterparty known out of band, such as a configured peer or
+// an IoT device, without the DID exchange handshake. The connection ID is returned.
+func (c *Client) CreateStaticConnection(conn *didexchange.StaticConnection) (string, error) {
+	creator, ok := c.didexchangeSvc.(staticConnectionCreator)
+	if !ok {
+		return "", errors.New("static connections are not supported by the didexchange service")
+	}
+
+	connectionID, err := creator.CreateStaticConnection(conn)
+	if err != nil {
+		return "", fmt.Errorf("failed to create static connection: %w", err)
+	}
+
+	return connectionID, nil
+}
+
+// RemoveConnection removes the connection record for given id along with its metadata, ErrConnectionNotFound is
+// returned if the connection doesn't exist
 func (c *Client) RemoveConnection(id string) error {
-	// TODO sample response, to be implemented as part of #226
+	if _, err := c.GetConnection(id); err != nil {
+		return err
+	}
+
+	if err := c.connectionStore.RemoveConnection(id); err != nil {
+		return fmt.Errorf("failed to remove connection %s: %w", id, err)
+	}
+
 	return nil
 }
 
-// startServiceEventListener listens to action and message events from DID Exchange service.
+// startServiceEventListener listens to action and message events from DID Exchange service. The events are
+// buffered by the client, applying the overflow policy, and delivered to the consumers by another goroutine.
 func (c *Client) startServiceEventListener() error {
-	err := c.didexchangeSvc.RegisterActionEvent(c.actionCh)
+	err := c.didexchangeSvc.RegisterActionEvent(c.svcActionCh)
 	if err != nil {
 		return fmt.Errorf("didexchange action event registration failed: %w", err)
 	}
 
 	// register the message event channel
-	err = c.didexchangeSvc.RegisterMsgEvent(c.msgCh)
+	err = c.didexchangeSvc.RegisterMsgEvent(c.svcMsgCh)
 	if err != nil {
 		return fmt.Errorf("didexchange message event registration failed: %w", err)
 	}
 
-	// listen for action event and message events
+	c.listeners.Add(2)
+
+	// buffer the action event and message events
+	go func() {
+		defer c.listeners.Done()
+
+		for {
+			select {
+			case e := <-c.svcActionCh:
+				c.bufferActionEvent(e)
+			case e := <-c.svcMsgCh:
+				c.bufferMessageEvent(e)
+			case <-c.done:
+				return
+			}
+		}
+	}()
+
+	// deliver the buffered events
 	go func() {
+		defer c.listeners.Done()
+
 		for {
 			select {
 			case e := <-c.actionCh:
@@ -179,6 +488,8 @@ func (c *Client) startServiceEventListener() error {
 				// assigned to var as lint fails with : Using a reference for the variable on range scope (scopelint)
 				msg := e
 				c.handleMessageEvent(&msg)
+			case <-c.done:
+				return
 			}
 		}
 	}()
@@ -242,12 +553,73 @@ func (c *Client) UnregisterMsgEvent(ch chan<- service.StateMsg) error {
 	return nil
 }
 
+// bufferActionEvent buffers the action event, the oldest buffered event is dropped and stopped if the buffer
+// is full and the overflow policy is DropOldest
+func (c *Client) bufferActionEvent(e service.DIDCommAction) {
+	if c.overflowPolicy == Block {
+		select {
+		case c.actionCh <- e:
+		case <-c.done:
+			stopAction(e)
+		}
+
+		return
+	}
+
+	for {
+		select {
+		case c.actionCh <- e:
+			return
+		default:
+		}
+
+		select {
+		case dropped := <-c.actionCh:
+			logger.Warnf("action event buffer full, dropped the oldest %s action event", dropped.ProtocolName)
+			stopAction(dropped)
+		default:
+		}
+	}
+}
+
+// bufferMessageEvent buffers the message event, the oldest buffered event is dropped if the buffer is full
+// and the overflow policy is DropOldest
+func (c *Client) bufferMessageEvent(e service.StateMsg) {
+	if c.overflowPolicy == Block {
+		select {
+		case c.msgCh <- e:
+		case <-c.done:
+		}
+
+		return
+	}
+
+	for {
+		select {
+		case c.msgCh <- e:
+			return
+		default:
+		}
+
+		select {
+		case dropped := <-c.msgCh:
+			logger.Warnf("message event buffer full, dropped the oldest %s event of state %s",
+				dropped.ProtocolName, dropped.StateID)
+		default:
+		}
+	}
+}
+
 func (c *Client) handleActionEvent(msg *service.DIDCommAction) {
 	c.actionEventlock.RLock()
 	aEvent := c.actionEvent
-	c.actionEventlock.RLock()
+	c.actionEventlock.RUnlock()
 
-	aEvent <- *msg
+	select {
+	case aEvent <- *msg:
+	case <-c.done:
+		stopAction(*msg)
+	}
 }
 
 func (c *Client) handleMessageEvent(msg *service.StateMsg) {
@@ -256,6 +628,54 @@ func (c *Client) handleMessageEvent(msg *service.StateMsg) {
 	c.msgEventsLock.RUnlock()
 
 	for _, handler := range statusEvents {
-		handler <- *msg
+		select {
+		case handler <- *msg:
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// Close unregisters the event channels from the DID Exchange service and stops the event goroutines.
+// The pending action events are stopped with ErrClientClosed and the pending message events are dropped.
+func (c *Client) Close() error {
+	var err error
+
+	c.closeOnce.Do(func() {
+		if e := c.didexchangeSvc.UnregisterActionEvent(c.svcActionCh); e != nil {
+			err = fmt.Errorf("didexchange action event unregistration failed: %w", e)
+		}
+
+		if e := c.didexchangeSvc.UnregisterMsgEvent(c.svcMsgCh); e != nil && err == nil {
+			err = fmt.Errorf("didexchange message event unregistration failed: %w", e)
+		}
+
+		close(c.done)
+		c.listeners.Wait()
+		c.drain()
+	})
+
+	return err
+}
+
+// drain empties the event buffers and the service channels once the event goroutines are stopped
+func (c *Client) drain() {
+	for {
+		select {
+		case e := <-c.svcActionCh:
+			stopAction(e)
+		case e := <-c.actionCh:
+			stopAction(e)
+		case <-c.svcMsgCh:
+		case <-c.msgCh:
+		default:
+			return
+		}
+	}
+}
+
+func stopAction(e service.DIDCommAction) {
+	if e.Stop != nil {
+		e.Stop(ErrClientClosed)
 	}
 }
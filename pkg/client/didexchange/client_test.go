@@ -6,6 +6,7 @@ SPDX-License-Identifier: Apache-2.0
 package didexchange
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -14,8 +15,11 @@ import (
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/hyperledger/aries-framework-go/pkg/common/idgen"
 	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/service"
 	"github.com/hyperledger/aries-framework-go/pkg/didcomm/protocol/didexchange"
+	diddoc "github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	mockclock "github.com/hyperledger/aries-framework-go/pkg/internal/mock/clock"
 	"github.com/hyperledger/aries-framework-go/pkg/internal/mock/common/did"
 	mockprotocol "github.com/hyperledger/aries-framework-go/pkg/internal/mock/didcomm/protocol"
 	mockprovider "github.com/hyperledger/aries-framework-go/pkg/internal/mock/provider"
@@ -45,6 +49,22 @@ func TestNew(t *testing.T) {
 		require.Error(t, err)
 		require.Contains(t, err.Error(), "cast service to DIDExchange Service failed")
 	})
+
+	t.Run("test event buffer options", func(t *testing.T) {
+		c, err := New(&mockprovider.Provider{StorageProviderValue: mockstore.NewMockStoreProvider(),
+			ServiceValue: &mockprotocol.MockDIDExchangeSvc{}},
+			WithActionBufferSize(5), WithMsgBufferSize(20), WithOverflowPolicy(DropOldest))
+		require.NoError(t, err)
+		require.Equal(t, 5, cap(c.actionCh))
+		require.Equal(t, 20, cap(c.msgCh))
+		require.Equal(t, DropOldest, c.overflowPolicy)
+	})
+
+	t.Run("test error from invalid event buffer size", func(t *testing.T) {
+		_, err := New(&mockprovider.Provider{StorageProviderValue: mockstore.NewMockStoreProvider(),
+			ServiceValue: &mockprotocol.MockDIDExchangeSvc{}}, WithActionBufferSize(0))
+		require.EqualError(t, err, "event buffer size must be positive")
+	})
 }
 
 func TestClient_CreateInvitation(t *testing.T) {
@@ -65,6 +85,43 @@ func TestClient_CreateInvitation(t *testing.T) {
 		require.Equal(t, "endpoint", inviteReq.ServiceEndpoint)
 	})
 
+	t.Run("test invitation ID from ID generator", func(t *testing.T) {
+		svc, err := didexchange.New(&did.MockDIDCreator{}, &mockprotocol.MockProvider{})
+		require.NoError(t, err)
+
+		c, err := New(&mockprovider.Provider{StorageProviderValue: mockstore.NewMockStoreProvider(), ServiceValue: svc,
+			WalletValue:      &mockwallet.CloseableWallet{CreateEncryptionKeyValue: "sample-key"},
+			IDGeneratorValue: idgen.Sequence("invitation")})
+		require.NoError(t, err)
+
+		inviteReq, err := c.CreateInvitation("agent")
+		require.NoError(t, err)
+		require.Equal(t, "invitation-1", inviteReq.ID)
+	})
+
+	t.Run("test invitation usage restrictions", func(t *testing.T) {
+		svc, err := didexchange.New(&did.MockDIDCreator{}, &mockprotocol.MockProvider{})
+		require.NoError(t, err)
+
+		clock := &mockclock.Clock{Time: time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)}
+
+		c, err := New(&mockprovider.Provider{StorageProviderValue: mockstore.NewMockStoreProvider(), ServiceValue: svc,
+			WalletValue: &mockwallet.CloseableWallet{CreateEncryptionKeyValue: "sample-key"}, ClockValue: clock})
+		require.NoError(t, err)
+
+		_, err = c.CreateInvitation("agent", WithInvitationTTL(time.Hour), WithSingleUse())
+		require.NoError(t, err)
+
+		// the invitation expires an hour after its creation by the clock of the client
+		err = c.connectionStore.UseInvitation("sample-key", clock.Now().Add(time.Hour+time.Second))
+		require.True(t, errors.Is(err, didexchange.ErrInvitationExpired))
+
+		require.NoError(t, c.connectionStore.UseInvitation("sample-key", clock.Now().Add(time.Hour)))
+
+		err = c.connectionStore.UseInvitation("sample-key", clock.Now())
+		require.True(t, errors.Is(err, didexchange.ErrInvitationUsed))
+	})
+
 	t.Run("test error from createSigningKey", func(t *testing.T) {
 		svc, err := didexchange.New(&did.MockDIDCreator{}, &mockprotocol.MockProvider{})
 		require.NoError(t, err)
@@ -92,6 +149,56 @@ func TestClient_CreateInvitation(t *testing.T) {
 	})
 }
 
+func TestClient_Roles(t *testing.T) {
+	newClient := func(roles ...string) *Client {
+		c, err := New(&mockRolesProvider{Provider: &mockprovider.Provider{
+			StorageProviderValue: mockstore.NewMockStoreProvider(), ServiceValue: &mockprotocol.MockDIDExchangeSvc{},
+			WalletValue: &mockwallet.CloseableWallet{CreateEncryptionKeyValue: "sample-key"}}, roles: roles})
+		require.NoError(t, err)
+
+		return c
+	}
+
+	t.Run("test invitation not created with the inviter role disabled", func(t *testing.T) {
+		_, err := newClient(didexchange.RoleInvitee).CreateInvitation("agent")
+		require.True(t, errors.Is(err, ErrRoleDisabled))
+		require.Contains(t, err.Error(), "inviter role")
+
+		_, err = newClient(didexchange.RoleInviter).CreateInvitation("agent")
+		require.NoError(t, err)
+	})
+
+	t.Run("test invitation not handled with the invitee role disabled", func(t *testing.T) {
+		invitation := &didexchange.Invitation{ID: "id1", Type: didexchange.ConnectionInvite}
+
+		err := newClient(didexchange.RoleInviter).HandleInvitation(invitation)
+		require.True(t, errors.Is(err, ErrRoleDisabled))
+		require.Contains(t, err.Error(), "invitee role")
+
+		require.NoError(t, newClient(didexchange.RoleInvitee).HandleInvitation(invitation))
+	})
+}
+
+// mockRolesProvider enables the roles of the DID Exchange protocol
+type mockRolesProvider struct {
+	*mockprovider.Provider
+	roles []string
+}
+
+func (p *mockRolesProvider) RoleEnabled(svcName, role string) bool {
+	if svcName != didexchange.DIDExchange {
+		return true
+	}
+
+	for _, r := range p.roles {
+		if r == role {
+			return true
+		}
+	}
+
+	return false
+}
+
 func TestClient_QueryConnectionByID(t *testing.T) {
 	t.Run("test success", func(t *testing.T) {
 		svc, err := didexchange.New(&did.MockDIDCreator{}, &mockprotocol.MockProvider{})
@@ -107,6 +214,22 @@ func TestClient_QueryConnectionByID(t *testing.T) {
 		require.NoError(t, err)
 		require.Equal(t, "complete", result.State)
 		require.Equal(t, "id1", result.ConnectionID)
+		require.Empty(t, result.EncryptionAlgs)
+	})
+
+	t.Run("test success with encryption algorithms", func(t *testing.T) {
+		svc, err := didexchange.New(&did.MockDIDCreator{}, &mockprotocol.MockProvider{})
+		require.NoError(t, err)
+		s := mockstore.MockStore{Store: make(map[string][]byte)}
+		c, err := New(&mockprovider.Provider{
+			ServiceValue:         svc,
+			StorageProviderValue: &mockstore.MockStoreProvider{Store: &s}})
+		require.NoError(t, err)
+		require.NoError(t, s.Put("id1", []byte("complete")))
+		require.NoError(t, didexchange.NewConnectionRecorder(&s).SaveEncryptionAlgs("id1", []string{"C20P"}))
+		result, err := c.GetConnection("id1")
+		require.NoError(t, err)
+		require.Equal(t, []string{"C20P"}, result.EncryptionAlgs)
 	})
 
 	t.Run("test error", func(t *testing.T) {
@@ -143,15 +266,95 @@ func TestClient_QueryConnectionByID(t *testing.T) {
 }
 
 func TestClient_RemoveConnection(t *testing.T) {
-	svc, err := didexchange.New(&did.MockDIDCreator{}, &mockprotocol.MockProvider{})
-	require.NoError(t, err)
-	require.NotNil(t, svc)
+	t.Run("test success", func(t *testing.T) {
+		s := mockstore.MockStore{Store: make(map[string][]byte)}
+		svc, err := didexchange.New(&did.MockDIDCreator{}, &mockprotocol.MockProvider{CustomStore: &s})
+		require.NoError(t, err)
 
-	c, err := New(&mockprovider.Provider{StorageProviderValue: mockstore.NewMockStoreProvider(), ServiceValue: svc})
-	require.NoError(t, err)
+		c, err := New(&mockprovider.Provider{ServiceValue: svc,
+			StorageProviderValue: &mockstore.MockStoreProvider{Store: &s}})
+		require.NoError(t, err)
 
-	err = c.RemoveConnection("sample-id")
-	require.NoError(t, err)
+		connectionID, err := c.CreateStaticConnection(&didexchange.StaticConnection{
+			RecipientKeys: []string{"key1"}, ServiceEndpoint: "http://device:8080"})
+		require.NoError(t, err)
+		require.NoError(t, c.SaveConnectionMetadata(connectionID, &didexchange.ConnectionMetadata{Tags: []string{"vip"}}))
+
+		require.NoError(t, c.RemoveConnection(connectionID))
+
+		_, err = c.GetConnection(connectionID)
+		require.True(t, errors.Is(err, ErrConnectionNotFound))
+
+		results, err := c.QueryConnections(&QueryConnectionsParams{})
+		require.NoError(t, err)
+		require.Empty(t, results)
+		require.Empty(t, s.Store)
+	})
+
+	t.Run("test connection not found", func(t *testing.T) {
+		svc, err := didexchange.New(&did.MockDIDCreator{}, &mockprotocol.MockProvider{})
+		require.NoError(t, err)
+
+		c, err := New(&mockprovider.Provider{StorageProviderValue: mockstore.NewMockStoreProvider(), ServiceValue: svc})
+		require.NoError(t, err)
+
+		err = c.RemoveConnection("sample-id")
+		require.True(t, errors.Is(err, ErrConnectionNotFound))
+	})
+
+	t.Run("test store error", func(t *testing.T) {
+		svc, err := didexchange.New(&did.MockDIDCreator{}, &mockprotocol.MockProvider{})
+		require.NoError(t, err)
+		s := mockstore.MockStore{Store: map[string][]byte{"id1": []byte("completed")},
+			ErrDelete: errors.New("delete error")}
+		c, err := New(&mockprovider.Provider{ServiceValue: svc,
+			StorageProviderValue: &mockstore.MockStoreProvider{Store: &s}})
+		require.NoError(t, err)
+
+		err = c.RemoveConnection("id1")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to remove connection id1")
+	})
+}
+
+func TestClient_CreateStaticConnection(t *testing.T) {
+	t.Run("test success", func(t *testing.T) {
+		svc, err := didexchange.New(&did.MockDIDCreator{}, &mockprotocol.MockProvider{})
+		require.NoError(t, err)
+
+		c, err := New(&mockprovider.Provider{StorageProviderValue: mockstore.NewMockStoreProvider(), ServiceValue: svc})
+		require.NoError(t, err)
+
+		conn := &didexchange.StaticConnection{ConnectionID: "peer1", RecipientKeys: []string{"key1"},
+			ServiceEndpoint: "http://device:8080"}
+		connectionID, err := c.CreateStaticConnection(conn)
+		require.NoError(t, err)
+		require.Equal(t, "peer1", connectionID)
+
+		_, err = c.CreateStaticConnection(conn)
+		require.True(t, errors.Is(err, didexchange.ErrConnectionExists))
+	})
+
+	t.Run("test error from service", func(t *testing.T) {
+		svc, err := didexchange.New(&did.MockDIDCreator{}, &mockprotocol.MockProvider{})
+		require.NoError(t, err)
+
+		c, err := New(&mockprovider.Provider{StorageProviderValue: mockstore.NewMockStoreProvider(), ServiceValue: svc})
+		require.NoError(t, err)
+
+		_, err = c.CreateStaticConnection(&didexchange.StaticConnection{})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to create static connection")
+	})
+
+	t.Run("test static connections not supported", func(t *testing.T) {
+		c, err := New(&mockprovider.Provider{StorageProviderValue: mockstore.NewMockStoreProvider(),
+			ServiceValue: &mockprotocol.MockDIDExchangeSvc{}})
+		require.NoError(t, err)
+
+		_, err = c.CreateStaticConnection(&didexchange.StaticConnection{})
+		require.EqualError(t, err, "static connections are not supported by the didexchange service")
+	})
 }
 
 func TestClient_HandleInvitation(t *testing.T) {
@@ -164,6 +367,7 @@ func TestClient_HandleInvitation(t *testing.T) {
 		inviteReq, err := c.CreateInvitation("agent")
 		require.NoError(t, err)
 		require.NoError(t, c.HandleInvitation(inviteReq))
+		require.NoError(t, c.HandleInvitationContext(context.Background(), inviteReq))
 	})
 
 	t.Run("test error from handle msg", func(t *testing.T) {
@@ -181,22 +385,185 @@ func TestClient_HandleInvitation(t *testing.T) {
 	})
 }
 
+func TestClient_AcceptInvitation(t *testing.T) {
+	publicDID := &diddoc.Doc{Context: []string{diddoc.Context}, ID: "did:example:public", PublicKey: []diddoc.PublicKey{{
+		ID: "did:example:public#key1", Type: "Ed25519VerificationKey2018", Value: []byte("key1")}}}
+
+	otherDID := &diddoc.Doc{Context: []string{diddoc.Context}, ID: "did:example:other", PublicKey: []diddoc.PublicKey{{
+		ID: "did:example:other#key1", Type: "Ed25519VerificationKey2018", Value: []byte("key2")}}}
+	keyHolder := &mockwallet.CloseableWallet{HasKeyValue: true}
+
+	t.Run("test public DID", func(t *testing.T) {
+		s := mockstore.MockStore{Store: make(map[string][]byte)}
+		svc, err := didexchange.New(&did.MockDIDCreator{},
+			&mockprotocol.MockProvider{CustomStore: &s, WalletValue: keyHolder})
+		require.NoError(t, err)
+
+		c, err := New(&mockprovider.Provider{
+			ServiceValue:         svc,
+			StorageProviderValue: &mockstore.MockStoreProvider{Store: &s}})
+		require.NoError(t, err)
+
+		require.NoError(t, c.AcceptInvitation("id1"))
+		require.NoError(t, c.AcceptInvitation("id1", WithPublicDID(publicDID)))
+		require.NoError(t, c.AcceptInvitation("id1", WithPublicDID(publicDID)))
+
+		err = c.AcceptInvitation("id1", WithPublicDID(otherDID))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "already backed by DID did:example:public")
+
+		require.NoError(t, s.Put("id1", []byte("complete")))
+		result, err := c.GetConnection("id1")
+		require.NoError(t, err)
+		require.Equal(t, "did:example:public", result.MyDID)
+	})
+
+	t.Run("test public DID not held by the wallet", func(t *testing.T) {
+		svc, err := didexchange.New(&did.MockDIDCreator{},
+			&mockprotocol.MockProvider{WalletValue: &mockwallet.CloseableWallet{}})
+		require.NoError(t, err)
+
+		c, err := New(&mockprovider.Provider{StorageProviderValue: mockstore.NewMockStoreProvider(), ServiceValue: svc})
+		require.NoError(t, err)
+
+		err = c.AcceptInvitation("id1", WithPublicDID(publicDID))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "the wallet doesn't hold the private key of DID did:example:public")
+	})
+
+	t.Run("test public DID when handling invitation", func(t *testing.T) {
+		svc, err := didexchange.New(&did.MockDIDCreator{}, &mockprotocol.MockProvider{WalletValue: keyHolder})
+		require.NoError(t, err)
+
+		c, err := New(&mockprovider.Provider{StorageProviderValue: mockstore.NewMockStoreProvider(), ServiceValue: svc})
+		require.NoError(t, err)
+
+		invitation := &didexchange.Invitation{ID: "id1", Type: didexchange.ConnectionInvite}
+
+		// the invitation can be handled again with the same DID, e.g. when the request sent to the inviter failed
+		require.NoError(t, c.HandleInvitation(invitation, WithPublicDID(publicDID)))
+		require.NoError(t, c.HandleInvitation(invitation, WithPublicDID(publicDID)))
+
+		err = c.HandleInvitation(invitation, WithPublicDID(otherDID))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to set public DID")
+	})
+
+	t.Run("test public DIDs not supported", func(t *testing.T) {
+		c, err := New(&mockprovider.Provider{StorageProviderValue: mockstore.NewMockStoreProvider(),
+			ServiceValue: &mockprotocol.MockDIDExchangeSvc{}})
+		require.NoError(t, err)
+
+		err = c.AcceptInvitation("id1", WithPublicDID(publicDID))
+		require.EqualError(t, err, "public DIDs are not supported by the didexchange service")
+	})
+}
+
 func TestClient_QueryConnectionsByParams(t *testing.T) {
-	svc, err := didexchange.New(&did.MockDIDCreator{}, &mockprotocol.MockProvider{})
+	s := mockstore.MockStore{Store: make(map[string][]byte)}
+	svc, err := didexchange.New(&did.MockDIDCreator{}, &mockprotocol.MockProvider{CustomStore: &s})
 	require.NoError(t, err)
 	require.NotNil(t, svc)
 
-	c, err := New(&mockprovider.Provider{StorageProviderValue: mockstore.NewMockStoreProvider(),
-		ServiceValue: svc})
+	c, err := New(&mockprovider.Provider{ServiceValue: svc,
+		StorageProviderValue: &mockstore.MockStoreProvider{Store: &s}})
 	require.NoError(t, err)
 
-	results, err := c.QueryConnections(&QueryConnectionsParams{InvitationKey: "sample-inv-key"})
+	results, err := c.QueryConnections(&QueryConnectionsParams{})
 	require.NoError(t, err)
-	require.NotEmpty(t, results)
-	for _, result := range results {
-		require.NotNil(t, result)
-		require.NotNil(t, result.ConnectionID)
-	}
+	require.Empty(t, results)
+
+	connectionID, err := c.CreateStaticConnection(&didexchange.StaticConnection{
+		RecipientKeys: []string{"key1"}, ServiceEndpoint: "http://device:8080"})
+	require.NoError(t, err)
+
+	// a connection which isn't indexed
+	require.NoError(t, s.Put("id1", []byte("requested")))
+
+	results, err = c.QueryConnections(&QueryConnectionsParams{State: "completed"})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.Equal(t, connectionID, results[0].ConnectionID)
+	require.Equal(t, "completed", results[0].State)
+
+	results, err = c.QueryConnections(&QueryConnectionsParams{State: "requested"})
+	require.NoError(t, err)
+	require.Empty(t, results)
+}
+
+func TestClient_ConnectionMetadata(t *testing.T) {
+	t.Run("test query by tags and metadata", func(t *testing.T) {
+		svc, err := didexchange.New(&did.MockDIDCreator{}, &mockprotocol.MockProvider{})
+		require.NoError(t, err)
+		s := mockstore.MockStore{Store: make(map[string][]byte)}
+		c, err := New(&mockprovider.Provider{ServiceValue: svc,
+			StorageProviderValue: &mockstore.MockStoreProvider{Store: &s}})
+		require.NoError(t, err)
+
+		require.NoError(t, s.Put("id1", []byte("completed")))
+		require.NoError(t, s.Put("id2", []byte("requested")))
+
+		require.NoError(t, c.SaveConnectionMetadata("id1", &didexchange.ConnectionMetadata{Tags: []string{"vip"},
+			Metadata: map[string]string{"customer-id": "42"}}))
+		require.NoError(t, c.SaveConnectionMetadata("id2", &didexchange.ConnectionMetadata{Tags: []string{"vip"}}))
+
+		err = c.SaveConnectionMetadata("id3", &didexchange.ConnectionMetadata{Tags: []string{"vip"}})
+		require.True(t, errors.Is(err, ErrConnectionNotFound))
+
+		result, err := c.GetConnection("id1")
+		require.NoError(t, err)
+		require.Equal(t, []string{"vip"}, result.Tags)
+		require.Equal(t, map[string]string{"customer-id": "42"}, result.Metadata)
+
+		results, err := c.QueryConnections(&QueryConnectionsParams{Tags: []string{"vip"}})
+		require.NoError(t, err)
+		require.Len(t, results, 2)
+		require.Equal(t, "id1", results[0].ConnectionID)
+		require.Equal(t, "id2", results[1].ConnectionID)
+
+		results, err = c.QueryConnections(&QueryConnectionsParams{Tags: []string{"vip"}, State: "requested"})
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		require.Equal(t, "id2", results[0].ConnectionID)
+
+		results, err = c.QueryConnections(&QueryConnectionsParams{Metadata: map[string]string{"customer-id": "42"}})
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		require.Equal(t, "id1", results[0].ConnectionID)
+
+		// metadata left by a removed connection
+		delete(s.Store, "id2")
+		results, err = c.QueryConnections(&QueryConnectionsParams{Tags: []string{"vip"}})
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+	})
+
+	t.Run("test store errors", func(t *testing.T) {
+		svc, err := didexchange.New(&did.MockDIDCreator{}, &mockprotocol.MockProvider{})
+		require.NoError(t, err)
+		s := mockstore.MockStore{Store: make(map[string][]byte)}
+		c, err := New(&mockprovider.Provider{ServiceValue: svc,
+			StorageProviderValue: &mockstore.MockStoreProvider{Store: &s}})
+		require.NoError(t, err)
+
+		require.NoError(t, s.Put("id1", []byte("completed")))
+		require.NoError(t, c.SaveConnectionMetadata("id1", &didexchange.ConnectionMetadata{Tags: []string{"vip"}}))
+
+		s.ErrIterate = errors.New("iterate error")
+		_, err = c.QueryConnections(&QueryConnectionsParams{Tags: []string{"vip"}})
+		require.EqualError(t, err, "failed to query connections: iterate error")
+
+		s.ErrIterate = nil
+		s.ErrGet = errors.New("get error")
+		_, err = c.QueryConnections(&QueryConnectionsParams{Tags: []string{"vip"}})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "get error")
+
+		s.ErrGet = nil
+		s.ErrPut = errors.New("put error")
+		err = c.SaveConnectionMetadata("id1", &didexchange.ConnectionMetadata{})
+		require.EqualError(t, err, "failed to save connection metadata: put error")
+	})
 }
 
 func TestServiceEvents(t *testing.T) {
@@ -250,7 +617,7 @@ func TestServiceEvents(t *testing.T) {
 		Payload: request,
 	}
 
-	err = didExSvc.Handle(&msg)
+	err = didExSvc.Handle(context.Background(), &msg)
 	require.NoError(t, err)
 
 	validateState(t, store, id, "responded", 100*time.Millisecond)
@@ -387,3 +754,92 @@ func TestService_MsgEvents(t *testing.T) {
 	err = c.UnregisterMsgEvent(ch1)
 	require.NoError(t, err)
 }
+
+func TestClient_OverflowPolicy(t *testing.T) {
+	t.Run("test drop oldest action event", func(t *testing.T) {
+		c := &Client{actionCh: make(chan service.DIDCommAction, 2), overflowPolicy: DropOldest}
+
+		for _, id := range []string{"1", "2", "3"} {
+			c.bufferActionEvent(service.DIDCommAction{ProtocolName: id})
+		}
+
+		require.Len(t, c.actionCh, 2)
+		require.Equal(t, "2", (<-c.actionCh).ProtocolName)
+		require.Equal(t, "3", (<-c.actionCh).ProtocolName)
+	})
+
+	t.Run("test drop oldest message event", func(t *testing.T) {
+		c := &Client{msgCh: make(chan service.StateMsg, 2), overflowPolicy: DropOldest}
+
+		for _, id := range []string{"1", "2", "3"} {
+			c.bufferMessageEvent(service.StateMsg{StateID: id})
+		}
+
+		require.Len(t, c.msgCh, 2)
+		require.Equal(t, "2", (<-c.msgCh).StateID)
+		require.Equal(t, "3", (<-c.msgCh).StateID)
+	})
+
+	t.Run("test slow consumer doesn't stall the service", func(t *testing.T) {
+		svc := &mockprotocol.MockDIDExchangeSvc{}
+		c, err := New(&mockprovider.Provider{StorageProviderValue: mockstore.NewMockStoreProvider(),
+			ServiceValue: svc}, WithMsgBufferSize(1), WithOverflowPolicy(DropOldest))
+		require.NoError(t, err)
+
+		// the consumer never receives the events
+		require.NoError(t, c.RegisterMsgEvent(make(chan service.StateMsg)))
+
+		done := make(chan struct{})
+		go func() {
+			for i := 0; i < 10; i++ {
+				c.svcMsgCh <- service.StateMsg{StateID: "requested"}
+			}
+			close(done)
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			require.Fail(t, "events blocked by the slow consumer")
+		}
+	})
+}
+
+func TestClient_Close(t *testing.T) {
+	t.Run("test pending action events stopped", func(t *testing.T) {
+		svc, err := didexchange.New(&did.MockDIDCreator{}, &mockprotocol.MockProvider{})
+		require.NoError(t, err)
+
+		c, err := New(&mockprovider.Provider{StorageProviderValue: mockstore.NewMockStoreProvider(), ServiceValue: svc})
+		require.NoError(t, err)
+
+		// the consumer never receives the action events
+		require.NoError(t, c.RegisterActionEvent(make(chan service.DIDCommAction)))
+
+		stopped := make(chan error, 3)
+		for i := 0; i < 3; i++ {
+			c.svcActionCh <- service.DIDCommAction{Stop: func(err error) { stopped <- err }}
+		}
+
+		require.NoError(t, c.Close())
+		require.NoError(t, c.Close())
+
+		for i := 0; i < 3; i++ {
+			require.Equal(t, ErrClientClosed, <-stopped)
+		}
+
+		// the channels are unregistered from the service
+		require.NoError(t, svc.RegisterActionEvent(make(chan service.DIDCommAction)))
+		require.Empty(t, svc.GetMsgEvents())
+	})
+
+	t.Run("test error from unregister", func(t *testing.T) {
+		c, err := New(&mockprovider.Provider{StorageProviderValue: mockstore.NewMockStoreProvider(),
+			ServiceValue: &mockprotocol.MockDIDExchangeSvc{UnregisterMsgEventErr: errors.New("unregister error")}})
+		require.NoError(t, err)
+
+		err = c.Close()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unregister error")
+	})
+}
@@ -35,6 +35,12 @@ type QueryConnectionsParams struct {
 
 	// TheirRole is other party's role
 	TheirRole string `json:"their_role,omitempty"`
+
+	// Tags the connections must all have
+	Tags []string `json:"tags,omitempty"`
+
+	// Metadata key/values the connections must all have
+	Metadata map[string]string `json:"metadata,omitempty"`
 }
 
 // ConnectionResult model
@@ -0,0 +1,30 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package clock provides the current time to the framework: the issuance dates of the credentials, the creation
+// times of the proofs, the expiry of the invitations and the timeouts of the protocol states. The clock is
+// injected through the framework options so the tests get deterministic times.
+package clock
+
+import "time"
+
+// Clock returns the current time
+type Clock interface {
+	Now() time.Time
+}
+
+// Func adapts a function returning the current time to a Clock
+type Func func() time.Time
+
+// Now returns the time returned by the function
+func (f Func) Now() time.Time {
+	return f()
+}
+
+// System returns the clock of the system
+func System() Clock {
+	return Func(time.Now)
+}
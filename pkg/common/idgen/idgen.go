@@ -0,0 +1,153 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package idgen generates the IDs of the framework: the IDs of the messages, of the connections and of the
+// invitations. The generator is injected through the framework options, the random UUIDs are generated by default
+// and the ULIDs or KSUIDs give IDs sortable by creation time. The tests get deterministic IDs with Sequence.
+package idgen
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/big"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/hyperledger/aries-framework-go/pkg/common/clock"
+)
+
+const (
+	crockfordAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+	base62Alphabet    = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+	ulidLength    = 26
+	ksuidLength   = 27
+	ksuidEpoch    = 1400000000
+	ulidEntropy   = 10
+	ksuidEntropy  = 16
+	ulidTimeBytes = 6
+)
+
+// Generator generates unique IDs
+type Generator interface {
+	NewID() string
+}
+
+// Func adapts a function returning a new ID to a Generator
+type Func func() string
+
+// NewID returns the ID returned by the function
+func (f Func) NewID() string {
+	return f()
+}
+
+// UUID returns the generator of the random (version 4) UUIDs
+func UUID() Generator {
+	return Func(func() string {
+		return uuid.New().String()
+	})
+}
+
+type opts struct {
+	clock   clock.Clock
+	entropy io.Reader
+}
+
+// Opt is the option of the ULID and KSUID generators
+type Opt func(opts *opts)
+
+// WithClock sets the clock of the timestamps of the IDs, the system clock by default
+func WithClock(c clock.Clock) Opt {
+	return func(opts *opts) {
+		opts.clock = c
+	}
+}
+
+// WithEntropy sets the source of the random part of the IDs, crypto/rand by default
+func WithEntropy(r io.Reader) Opt {
+	return func(opts *opts) {
+		opts.entropy = r
+	}
+}
+
+func newOpts(options []Opt) *opts {
+	o := &opts{clock: clock.System(), entropy: rand.Reader}
+
+	for _, opt := range options {
+		opt(o)
+	}
+
+	return o
+}
+
+// ULID returns the generator of the ULIDs (https://github.com/ulid/spec): 26 characters encoding a millisecond
+// timestamp and 80 random bits in Crockford's base32, the IDs are sorted by creation time at the millisecond
+func ULID(options ...Opt) Generator {
+	o := newOpts(options)
+
+	return Func(func() string {
+		id := make([]byte, ulidTimeBytes+ulidEntropy)
+
+		ms := uint64(o.clock.Now().UnixNano() / int64(time.Millisecond))
+		timestamp := make([]byte, 8)
+		binary.BigEndian.PutUint64(timestamp, ms)
+		copy(id, timestamp[8-ulidTimeBytes:])
+
+		readEntropy(o.entropy, id[ulidTimeBytes:])
+
+		return encode(id, crockfordAlphabet, ulidLength)
+	})
+}
+
+// KSUID returns the generator of the KSUIDs (https://github.com/segmentio/ksuid): 27 characters encoding a
+// timestamp in seconds and 128 random bits in base62, the IDs are sorted by creation time at the second
+func KSUID(options ...Opt) Generator {
+	o := newOpts(options)
+
+	return Func(func() string {
+		id := make([]byte, 4+ksuidEntropy)
+
+		binary.BigEndian.PutUint32(id, uint32(o.clock.Now().Unix()-ksuidEpoch))
+		readEntropy(o.entropy, id[4:])
+
+		return encode(id, base62Alphabet, ksuidLength)
+	})
+}
+
+// Sequence returns the generator of the deterministic IDs prefix-1, prefix-2... e.g. for the tests
+func Sequence(prefix string) Generator {
+	var counter uint64
+
+	return Func(func() string {
+		return fmt.Sprintf("%s-%d", prefix, atomic.AddUint64(&counter, 1))
+	})
+}
+
+// readEntropy fills the buffer with random bytes, it panics if the random source fails as uuid.New does
+func readEntropy(r io.Reader, b []byte) {
+	if _, err := io.ReadFull(r, b); err != nil {
+		panic(fmt.Sprintf("read ID entropy: %s", err))
+	}
+}
+
+// encode returns the big-endian number in the alphabet, left padded with the first character to the length
+func encode(number []byte, alphabet string, length int) string {
+	n := new(big.Int).SetBytes(number)
+	base := big.NewInt(int64(len(alphabet)))
+	mod := new(big.Int)
+
+	encoded := make([]byte, length)
+	for i := length - 1; i >= 0; i-- {
+		n.DivMod(n, base, mod)
+		encoded[i] = alphabet[mod.Int64()]
+	}
+
+	return string(encoded)
+}
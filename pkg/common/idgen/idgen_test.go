@@ -0,0 +1,129 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package idgen
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	mockclock "github.com/hyperledger/aries-framework-go/pkg/internal/mock/clock"
+)
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, errors.New("entropy failure")
+}
+
+func TestUUID(t *testing.T) {
+	gen := UUID()
+
+	id := gen.NewID()
+	parsed, err := uuid.Parse(id)
+	require.NoError(t, err)
+	require.Equal(t, uuid.Version(4), parsed.Version())
+	require.NotEqual(t, id, gen.NewID())
+}
+
+func TestULID(t *testing.T) {
+	t.Run("test spec vector", func(t *testing.T) {
+		// 1469918176385 ms with the maximal entropy
+		gen := ULID(WithClock(&mockclock.Clock{Time: time.Unix(0, 1469918176385*int64(time.Millisecond))}),
+			WithEntropy(bytes.NewReader(bytes.Repeat([]byte{0xff}, ulidEntropy))))
+
+		require.Equal(t, "01ARYZ6S41ZZZZZZZZZZZZZZZZ", gen.NewID())
+	})
+
+	t.Run("test IDs sorted by time", func(t *testing.T) {
+		now := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+		var ids []string
+		for i := 0; i < 10; i++ {
+			id := ULID(WithClock(&mockclock.Clock{Time: now.Add(time.Duration(i) * time.Millisecond)})).NewID()
+			require.Len(t, id, ulidLength)
+			ids = append(ids, id)
+		}
+
+		require.True(t, sort.StringsAreSorted(ids))
+	})
+
+	t.Run("test random IDs", func(t *testing.T) {
+		gen := ULID(WithClock(&mockclock.Clock{Time: time.Now()}))
+		require.NotEqual(t, gen.NewID(), gen.NewID())
+	})
+
+	t.Run("test entropy failure", func(t *testing.T) {
+		require.Panics(t, func() { ULID(WithEntropy(failingReader{})).NewID() })
+	})
+}
+
+func TestKSUID(t *testing.T) {
+	t.Run("test minimal and maximal IDs", func(t *testing.T) {
+		gen := KSUID(WithClock(&mockclock.Clock{Time: time.Unix(ksuidEpoch, 0)}),
+			WithEntropy(bytes.NewReader(make([]byte, ksuidEntropy))))
+		require.Equal(t, "000000000000000000000000000", gen.NewID())
+
+		gen = KSUID(WithClock(&mockclock.Clock{Time: time.Unix(ksuidEpoch+1<<32-1, 0)}),
+			WithEntropy(bytes.NewReader(bytes.Repeat([]byte{0xff}, ksuidEntropy))))
+		require.Equal(t, "aWgEPTl1tmebfsQzFP4bxwgy80V", gen.NewID())
+	})
+
+	t.Run("test IDs sorted by time", func(t *testing.T) {
+		now := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+		var ids []string
+		for i := 0; i < 10; i++ {
+			id := KSUID(WithClock(&mockclock.Clock{Time: now.Add(time.Duration(i) * time.Second)})).NewID()
+			require.Len(t, id, ksuidLength)
+			ids = append(ids, id)
+		}
+
+		require.True(t, sort.StringsAreSorted(ids))
+	})
+
+	t.Run("test entropy failure", func(t *testing.T) {
+		require.Panics(t, func() { KSUID(WithEntropy(failingReader{})).NewID() })
+	})
+}
+
+func TestSequence(t *testing.T) {
+	gen := Sequence("msg")
+	require.Equal(t, "msg-1", gen.NewID())
+	require.Equal(t, "msg-2", gen.NewID())
+
+	t.Run("test concurrent IDs are unique", func(t *testing.T) {
+		gen := Sequence("id")
+		ids := make(chan string, 100)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 100; i++ {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+				ids <- gen.NewID()
+			}()
+		}
+
+		wg.Wait()
+		close(ids)
+
+		unique := make(map[string]bool)
+		for id := range ids {
+			unique[id] = true
+		}
+
+		require.Len(t, unique, 100)
+	})
+}
@@ -0,0 +1,106 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package log
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+type fieldsKey struct{}
+
+// Fields identify the message being handled, the log lines emitted by a logger returned by WithContext or
+// WithFields are tagged with the fields which are set
+type Fields struct {
+	ConnectionID string
+	ThreadID     string
+	MessageType  string
+}
+
+// NewContext returns a copy of the parent context carrying the fields, the fields which are empty are
+// inherited from the parent context
+func NewContext(parent context.Context, fields Fields) context.Context {
+	inherited := FieldsFromContext(parent)
+
+	if fields.ConnectionID == "" {
+		fields.ConnectionID = inherited.ConnectionID
+	}
+
+	if fields.ThreadID == "" {
+		fields.ThreadID = inherited.ThreadID
+	}
+
+	if fields.MessageType == "" {
+		fields.MessageType = inherited.MessageType
+	}
+
+	return context.WithValue(parent, fieldsKey{}, fields)
+}
+
+// FieldsFromContext returns the fields carried by the context, the fields are empty if the context has none
+func FieldsFromContext(ctx context.Context) Fields {
+	fields, _ := ctx.Value(fieldsKey{}).(Fields)
+
+	return fields
+}
+
+// FieldsFromMessage returns the message type and the thread ID of the DIDComm message, the thread ID being the
+// ID of the message if it doesn't belong to a thread. The fields are empty if the message can't be parsed.
+func FieldsFromMessage(payload []byte) Fields {
+	msg := &struct {
+		ID     string `json:"@id"`
+		Type   string `json:"@type"`
+		Thread *struct {
+			ID string `json:"thid"`
+		} `json:"~thread"`
+	}{}
+
+	if err := json.Unmarshal(payload, msg); err != nil {
+		return Fields{}
+	}
+
+	fields := Fields{ThreadID: msg.ID, MessageType: msg.Type}
+
+	if msg.Thread != nil && msg.Thread.ID != "" {
+		fields.ThreadID = msg.Thread.ID
+	}
+
+	return fields
+}
+
+// WithContext returns a logger of the module tagging the log lines with the fields carried by the context
+func (l *Log) WithContext(ctx context.Context) *Log {
+	return l.WithFields(FieldsFromContext(ctx))
+}
+
+// WithFields returns a logger of the module tagging the log lines with the fields
+func (l *Log) WithFields(fields Fields) *Log {
+	return &Log{module: l.module, prefix: fields.prefix()}
+}
+
+// prefix returns the tag of the log lines, escaped to be used in a format string
+func (f Fields) prefix() string {
+	var tags []string
+
+	for _, field := range []struct{ name, value string }{
+		{"connectionID", f.ConnectionID},
+		{"threadID", f.ThreadID},
+		{"msgType", f.MessageType},
+	} {
+		if field.value != "" {
+			tags = append(tags, fmt.Sprintf("%s=%s", field.name, field.value))
+		}
+	}
+
+	if len(tags) == 0 {
+		return ""
+	}
+
+	return strings.ReplaceAll("["+strings.Join(tags, " ")+"] ", "%", "%%")
+}
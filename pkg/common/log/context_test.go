@@ -0,0 +1,107 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package log
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewContext(t *testing.T) {
+	t.Run("test fields carried by the context", func(t *testing.T) {
+		require.Equal(t, Fields{}, FieldsFromContext(context.Background()))
+
+		ctx := NewContext(context.Background(), Fields{MessageType: "request"})
+		ctx = NewContext(ctx, Fields{ConnectionID: "conn1", ThreadID: "thread1"})
+		require.Equal(t, Fields{ConnectionID: "conn1", ThreadID: "thread1", MessageType: "request"},
+			FieldsFromContext(ctx))
+
+		ctx = NewContext(ctx, Fields{MessageType: "response"})
+		require.Equal(t, "response", FieldsFromContext(ctx).MessageType)
+		require.Equal(t, "conn1", FieldsFromContext(ctx).ConnectionID)
+	})
+}
+
+func TestFieldsFromMessage(t *testing.T) {
+	t.Run("test fields of the message", func(t *testing.T) {
+		require.Equal(t, Fields{ThreadID: "msg1", MessageType: "request"},
+			FieldsFromMessage([]byte(`{"@id":"msg1","@type":"request"}`)))
+		require.Equal(t, Fields{ThreadID: "thread1", MessageType: "response"},
+			FieldsFromMessage([]byte(`{"@id":"msg2","@type":"response","~thread":{"thid":"thread1"}}`)))
+		require.Equal(t, Fields{ThreadID: "msg3"}, FieldsFromMessage([]byte(`{"@id":"msg3","~thread":{}}`)))
+	})
+
+	t.Run("test message which can't be parsed", func(t *testing.T) {
+		require.Equal(t, Fields{}, FieldsFromMessage([]byte("not a message")))
+	})
+}
+
+func TestLog_WithContext(t *testing.T) {
+	defer func() { loggerProviderOnce = sync.Once{} }()
+
+	const module = "sample-module-context"
+
+	recorder := &recordingLogger{}
+	Initialize(&sampleProvider{recorder})
+
+	t.Run("test log lines tagged with the fields", func(t *testing.T) {
+		ctx := NewContext(context.Background(), Fields{ConnectionID: "conn1", ThreadID: "thread1",
+			MessageType: "https://didcomm.org/test/1.0/msg%d"})
+
+		logger := New(module).WithContext(ctx)
+		logger.Infof("handled %s", "message")
+		logger.Warnf("warning")
+		logger.Errorf("error")
+		logger.Debugf("debug")
+
+		require.Equal(t, "[connectionID=conn1 threadID=thread1 msgType=https://didcomm.org/test/1.0/msg%d] "+
+			"handled message", recorder.lines[0])
+		require.Len(t, recorder.lines, 3)
+	})
+
+	t.Run("test only the fields which are set", func(t *testing.T) {
+		recorder.lines = nil
+
+		New(module).WithFields(Fields{ThreadID: "thread1"}).Infof("message")
+		New(module).WithContext(context.Background()).Infof("message")
+
+		require.Equal(t, []string{"[threadID=thread1] message", "message"}, recorder.lines)
+	})
+}
+
+// recordingLogger records the log lines of the enabled levels
+type recordingLogger struct {
+	lines []string
+}
+
+func (l *recordingLogger) Fatalf(msg string, args ...interface{}) {
+	l.lines = append(l.lines, fmt.Sprintf(msg, args...))
+}
+
+func (l *recordingLogger) Panicf(msg string, args ...interface{}) {
+	l.lines = append(l.lines, fmt.Sprintf(msg, args...))
+}
+
+func (l *recordingLogger) Debugf(msg string, args ...interface{}) {
+	l.lines = append(l.lines, fmt.Sprintf(msg, args...))
+}
+
+func (l *recordingLogger) Infof(msg string, args ...interface{}) {
+	l.lines = append(l.lines, fmt.Sprintf(msg, args...))
+}
+
+func (l *recordingLogger) Warnf(msg string, args ...interface{}) {
+	l.lines = append(l.lines, fmt.Sprintf(msg, args...))
+}
+
+func (l *recordingLogger) Errorf(msg string, args ...interface{}) {
+	l.lines = append(l.lines, fmt.Sprintf(msg, args...))
+}
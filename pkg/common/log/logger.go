@@ -24,6 +24,7 @@ const (
 type Log struct {
 	instance Logger
 	module   string
+	prefix   string
 	once     sync.Once
 }
 
@@ -38,33 +39,33 @@ func New(module string) *Log {
 // Fatalf calls Fatalf function of underlying logger
 // should possibly cause system shutdown based on implementation
 func (l *Log) Fatalf(msg string, args ...interface{}) {
-	l.logger().Fatalf(msg, args...)
+	l.logger().Fatalf(l.prefix+msg, args...)
 }
 
 // Panicf calls Panic function of underlying logger
 // should possibly cause panic based on implementation
 func (l *Log) Panicf(msg string, args ...interface{}) {
-	l.logger().Panicf(msg, args...)
+	l.logger().Panicf(l.prefix+msg, args...)
 }
 
 // Debugf calls Debugf function of underlying logger
 func (l *Log) Debugf(msg string, args ...interface{}) {
-	l.logger().Debugf(msg, args...)
+	l.logger().Debugf(l.prefix+msg, args...)
 }
 
 // Infof calls Infof function of underlying logger
 func (l *Log) Infof(msg string, args ...interface{}) {
-	l.logger().Infof(msg, args...)
+	l.logger().Infof(l.prefix+msg, args...)
 }
 
 // Warnf calls Warnf function of underlying logger
 func (l *Log) Warnf(msg string, args ...interface{}) {
-	l.logger().Warnf(msg, args...)
+	l.logger().Warnf(l.prefix+msg, args...)
 }
 
 // Errorf calls Errorf function of underlying logger
 func (l *Log) Errorf(msg string, args ...interface{}) {
-	l.logger().Errorf(msg, args...)
+	l.logger().Errorf(l.prefix+msg, args...)
 }
 
 func (l *Log) logger() Logger {
@@ -0,0 +1,104 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package mem implements the metrics.Provider keeping the metrics in memory, e.g. to expose them through the
+// admin API of the agent or to check them in tests
+package mem
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/hyperledger/aries-framework-go/pkg/common/metrics"
+)
+
+// Provider is the in-memory metrics.Provider
+type Provider struct {
+	lock   sync.Mutex
+	values map[string]float64
+	series map[string][]float64
+}
+
+// NewProvider returns the in-memory metrics provider
+func NewProvider() *Provider {
+	return &Provider{values: make(map[string]float64), series: make(map[string][]float64)}
+}
+
+// Counter returns the counter of the name and labels
+func (p *Provider) Counter(name string, labels ...metrics.Label) metrics.Counter {
+	return &value{prov: p, key: key(name, labels)}
+}
+
+// Gauge returns the gauge of the name and labels
+func (p *Provider) Gauge(name string, labels ...metrics.Label) metrics.Gauge {
+	return &value{prov: p, key: key(name, labels)}
+}
+
+// Histogram returns the histogram of the name and labels
+func (p *Provider) Histogram(name string, labels ...metrics.Label) metrics.Histogram {
+	return &histogram{prov: p, key: key(name, labels)}
+}
+
+// Value returns the value of the counter or gauge of the name and labels, zero if not recorded
+func (p *Provider) Value(name string, labels ...metrics.Label) float64 {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return p.values[key(name, labels)]
+}
+
+// Observations returns the values observed by the histogram of the name and labels in observation order
+func (p *Provider) Observations(name string, labels ...metrics.Label) []float64 {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return append([]float64(nil), p.series[key(name, labels)]...)
+}
+
+// value is a counter or a gauge
+type value struct {
+	prov *Provider
+	key  string
+}
+
+// Set sets the value of the gauge
+func (v *value) Set(value float64) {
+	v.prov.lock.Lock()
+	v.prov.values[v.key] = value
+	v.prov.lock.Unlock()
+}
+
+// Add adds delta to the value
+func (v *value) Add(delta float64) {
+	v.prov.lock.Lock()
+	v.prov.values[v.key] += delta
+	v.prov.lock.Unlock()
+}
+
+type histogram struct {
+	prov *Provider
+	key  string
+}
+
+// Observe records the value
+func (h *histogram) Observe(value float64) {
+	h.prov.lock.Lock()
+	h.prov.series[h.key] = append(h.prov.series[h.key], value)
+	h.prov.lock.Unlock()
+}
+
+// key identifies the metric by its name and labels, the order of the labels doesn't matter
+func key(name string, labels []metrics.Label) string {
+	tags := make([]string, len(labels))
+	for i, l := range labels {
+		tags[i] = l.Name + "=" + l.Value
+	}
+
+	sort.Strings(tags)
+
+	return name + "{" + strings.Join(tags, ",") + "}"
+}
@@ -0,0 +1,56 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package mem
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hyperledger/aries-framework-go/pkg/common/metrics"
+)
+
+func TestProvider(t *testing.T) {
+	a := metrics.Label{Name: "a", Value: "1"}
+	b := metrics.Label{Name: "b", Value: "2"}
+
+	t.Run("test counter", func(t *testing.T) {
+		p := NewProvider()
+
+		p.Counter("count", a, b).Add(1)
+		p.Counter("count", b, a).Add(2)
+		p.Counter("count", a).Add(5)
+
+		require.Equal(t, float64(3), p.Value("count", a, b))
+		require.Equal(t, float64(5), p.Value("count", a))
+		require.Zero(t, p.Value("count"))
+	})
+
+	t.Run("test gauge", func(t *testing.T) {
+		p := NewProvider()
+
+		g := p.Gauge("queued")
+		g.Set(10)
+		g.Add(-3)
+
+		require.Equal(t, float64(7), p.Value("queued"))
+	})
+
+	t.Run("test histogram", func(t *testing.T) {
+		p := NewProvider()
+
+		p.Histogram("duration", a).Observe(0.5)
+		p.Histogram("duration", a).Observe(1.5)
+
+		observations := p.Observations("duration", a)
+		require.Equal(t, []float64{0.5, 1.5}, observations)
+
+		observations[0] = 0
+		require.Equal(t, []float64{0.5, 1.5}, p.Observations("duration", a))
+		require.Empty(t, p.Observations("duration"))
+	})
+}
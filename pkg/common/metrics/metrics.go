@@ -0,0 +1,85 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package metrics defines the metrics recorded by the framework, the metrics are sent to the backend of the
+// Provider registered with the framework. The mem and statsd packages provide the in-memory and StatsD
+// providers, other backends (e.g. OpenTelemetry or Prometheus) are plugged by implementing Provider over
+// their client library.
+package metrics
+
+// Label is a dimension of a metric, e.g. the message type of the inbound messages
+type Label struct {
+	Name  string
+	Value string
+}
+
+// Counter is a metric which only increases, e.g. the number of handled messages
+type Counter interface {
+	// Add increases the counter by delta, delta must not be negative
+	Add(delta float64)
+}
+
+// Gauge is a metric which increases and decreases, e.g. the number of queued messages
+type Gauge interface {
+	// Set sets the value of the gauge
+	Set(value float64)
+
+	// Add adds delta to the value of the gauge, delta may be negative
+	Add(delta float64)
+}
+
+// Histogram is a metric sampling observations, e.g. the handling durations of the messages
+type Histogram interface {
+	// Observe records the value
+	Observe(value float64)
+}
+
+// Provider returns the metrics of the backend, the metrics of the same name and labels are aggregated
+// together by the backend. The providers are safe for concurrent use.
+type Provider interface {
+	// Counter returns the counter of the name and labels
+	Counter(name string, labels ...Label) Counter
+
+	// Gauge returns the gauge of the name and labels
+	Gauge(name string, labels ...Label) Gauge
+
+	// Histogram returns the histogram of the name and labels
+	Histogram(name string, labels ...Label) Histogram
+}
+
+// Names of the metrics recorded by the framework.
+const (
+	// InboundMessages counts the inbound messages handled by the protocol services, labeled with the
+	// service, the message type and the outcome
+	InboundMessages = "didcomm_inbound_messages_total"
+	// InboundSlowMessages counts the inbound messages whose handling exceeded the slow handler threshold, labeled
+	// with the service and the message type
+	InboundSlowMessages = "didcomm_inbound_slow_messages_total"
+	// InboundMessageDuration is the handling duration in seconds of the inbound messages, labeled with the
+	// service and the message type
+	InboundMessageDuration = "didcomm_inbound_message_duration_seconds"
+	// OutboundMessages counts the messages sent by the outbound dispatcher, labeled with the scheme of the
+	// endpoint and the outcome
+	OutboundMessages = "didcomm_outbound_messages_total"
+	// OutboundMessageDuration is the sending duration in seconds of the outbound messages, labeled with the
+	// scheme of the endpoint
+	OutboundMessageDuration = "didcomm_outbound_message_duration_seconds"
+	// TransportEnvelopes counts the envelopes received by the inbound transports, labeled with the transport
+	// and the HTTP status code of the response
+	TransportEnvelopes = "didcomm_transport_envelopes_total"
+	// TransportEnvelopeSize is the size in bytes of the envelopes received by the inbound transports, labeled
+	// with the transport
+	TransportEnvelopeSize = "didcomm_transport_envelope_bytes"
+)
+
+// Outcome returns the outcome label of an operation, "success" or "failure"
+func Outcome(err error) Label {
+	if err != nil {
+		return Label{Name: "outcome", Value: "failure"}
+	}
+
+	return Label{Name: "outcome", Value: "success"}
+}
@@ -0,0 +1,154 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package statsd implements the metrics.Provider sending the metrics to a StatsD server over UDP, the labels are
+// sent as tags in the DogStatsD format supported by the StatsD agents of Datadog and Telegraf
+package statsd
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/aries-framework-go/pkg/common/metrics"
+)
+
+// Provider is the StatsD metrics.Provider, the metrics are sent as they are recorded and are lost if the server
+// can't be reached
+type Provider struct {
+	conn   net.Conn
+	prefix string
+}
+
+// Opt configures the StatsD metrics provider
+type Opt func(p *Provider)
+
+// WithPrefix prefixes the names of the metrics, e.g. with the name of the agent
+func WithPrefix(prefix string) Opt {
+	return func(p *Provider) {
+		p.prefix = prefix
+	}
+}
+
+// NewProvider returns the metrics provider of the StatsD server at the address (host:port)
+func NewProvider(addr string, opts ...Opt) (*Provider, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to statsd %s: %w", addr, err)
+	}
+
+	p := &Provider{conn: conn}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p, nil
+}
+
+// Counter returns the counter of the name and labels
+func (p *Provider) Counter(name string, labels ...metrics.Label) metrics.Counter {
+	return &metric{prov: p, name: p.prefix + sanitize(name), tags: tags(labels)}
+}
+
+// Gauge returns the gauge of the name and labels
+func (p *Provider) Gauge(name string, labels ...metrics.Label) metrics.Gauge {
+	return gaugeMetric{&metric{prov: p, name: p.prefix + sanitize(name), tags: tags(labels)}}
+}
+
+// Histogram returns the histogram of the name and labels
+func (p *Provider) Histogram(name string, labels ...metrics.Label) metrics.Histogram {
+	return &metric{prov: p, name: p.prefix + sanitize(name), tags: tags(labels)}
+}
+
+// Close closes the connection to the server
+func (p *Provider) Close() error {
+	return p.conn.Close()
+}
+
+func (p *Provider) send(lines ...string) {
+	// the metrics are best effort, UDP doesn't report the delivery failures anyway
+	p.conn.Write([]byte(strings.Join(lines, "\n"))) // nolint: errcheck
+}
+
+// metric is a counter, a gauge or a histogram, the type is given by the method called. The gauges are
+// wrapped by gaugeMetric for their Add.
+type metric struct {
+	prov *Provider
+	name string
+	tags string
+}
+
+// Add increases the counter by delta
+func (m *metric) Add(delta float64) {
+	m.prov.send(m.line(format(delta), "c"))
+}
+
+// Set sets the value of the gauge, the negative values are sent as a reset followed by a decrement as
+// StatsD reads the signed values as changes of the gauge
+func (m *metric) Set(value float64) {
+	if value < 0 {
+		m.prov.send(m.line("0", "g"), m.line(format(value), "g"))
+		return
+	}
+
+	m.prov.send(m.line(format(value), "g"))
+}
+
+// Observe records the value of the histogram
+func (m *metric) Observe(value float64) {
+	m.prov.send(m.line(format(value), "h"))
+}
+
+// gaugeMetric adds delta to the value of the gauge
+type gaugeMetric struct {
+	*metric
+}
+
+// Add adds delta to the value of the gauge
+func (g gaugeMetric) Add(delta float64) {
+	value := format(delta)
+	if delta >= 0 {
+		value = "+" + value
+	}
+
+	g.prov.send(g.line(value, "g"))
+}
+
+func (m *metric) line(value, metricType string) string {
+	return m.name + ":" + value + "|" + metricType + m.tags
+}
+
+func format(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// tags formats the labels as DogStatsD tags
+func tags(labels []metrics.Label) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	tags := make([]string, len(labels))
+	for i, l := range labels {
+		tags[i] = sanitize(l.Name) + ":" + sanitize(l.Value)
+	}
+
+	return "|#" + strings.Join(tags, ",")
+}
+
+// sanitize replaces the separators of the StatsD protocol in the names and the tags
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ':', '|', ',', '#', '@', '\n':
+			return '_'
+		default:
+			return r
+		}
+	}, s)
+}
@@ -0,0 +1,90 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package statsd
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hyperledger/aries-framework-go/pkg/common/metrics"
+)
+
+func TestProvider(t *testing.T) {
+	server, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	defer func() { require.NoError(t, server.Close()) }()
+
+	p, err := NewProvider(server.LocalAddr().String(), WithPrefix("agent."))
+	require.NoError(t, err)
+
+	defer func() { require.NoError(t, p.Close()) }()
+
+	msgType := metrics.Label{Name: "msg_type", Value: "https://didcomm.org/trust_ping/1.0/ping"}
+
+	for _, tc := range []struct {
+		name     string
+		record   func()
+		expected string
+	}{
+		{
+			name:     "test counter",
+			record:   func() { p.Counter("messages", msgType, metrics.Outcome(nil)).Add(1) },
+			expected: "agent.messages:1|c|#msg_type:https_//didcomm.org/trust_ping/1.0/ping,outcome:success",
+		},
+		{
+			name:     "test gauge",
+			record:   func() { p.Gauge("queued").Set(2.5) },
+			expected: "agent.queued:2.5|g",
+		},
+		{
+			name:     "test negative gauge",
+			record:   func() { p.Gauge("queued").Set(-2) },
+			expected: "agent.queued:0|g\nagent.queued:-2|g",
+		},
+		{
+			name:     "test gauge increment",
+			record:   func() { p.Gauge("queued").Add(3) },
+			expected: "agent.queued:+3|g",
+		},
+		{
+			name:     "test gauge decrement",
+			record:   func() { p.Gauge("queued").Add(-3) },
+			expected: "agent.queued:-3|g",
+		},
+		{
+			name:     "test histogram",
+			record:   func() { p.Histogram("duration", metrics.Outcome(nil)).Observe(0.25) },
+			expected: "agent.duration:0.25|h|#outcome:success",
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			tc.record()
+			require.Equal(t, tc.expected, receive(t, server))
+		})
+	}
+}
+
+func TestNewProvider(t *testing.T) {
+	_, err := NewProvider("invalid address")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed to connect to statsd")
+}
+
+func receive(t *testing.T, conn net.PacketConn) string {
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+
+	buf := make([]byte, 1024)
+	n, _, err := conn.ReadFrom(buf)
+	require.NoError(t, err)
+
+	return string(buf[:n])
+}
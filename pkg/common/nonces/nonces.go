@@ -0,0 +1,239 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package nonces implements the single-use values shared by the features protecting against replays: the
+// challenges of the presentation requests, the single-use invitations or the IDs of the messages already
+// processed. A nonce is accepted once before its expiry.
+//
+// The nonces are kept in a storage.Store, which must support storage.ConditionalStore so a nonce can't be used
+// twice by concurrent callers or agent instances sharing the store. The records of the nonces expire with the
+// nonces when the store supports storage.ExpiringStore.
+package nonces
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/common/clock"
+	"github.com/hyperledger/aries-framework-go/pkg/storage"
+)
+
+const (
+	keyPrefix = "nonce_"
+	nonceSize = 32
+
+	// maxUpdateAttempts is the number of attempts to burn a nonce updated concurrently
+	maxUpdateAttempts = 10
+)
+
+var (
+	// ErrNotFound is returned when the nonce has not been created by the store
+	ErrNotFound = errors.New("nonce not found")
+	// ErrExpired is returned when the nonce is used after its expiry
+	ErrExpired = errors.New("nonce expired")
+	// ErrUsed is returned when the nonce has already been used, i.e. it is replayed
+	ErrUsed = errors.New("nonce already used")
+	// ErrNotConditional is returned when the nonces are used with a store which isn't a storage.ConditionalStore
+	ErrNotConditional = errors.New("store can't update the nonces atomically")
+)
+
+// record is the stored state of a nonce
+type record struct {
+	Scope     string    `json:"scope,omitempty"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	Used      bool      `json:"used,omitempty"`
+}
+
+// Store creates and burns the nonces
+type Store struct {
+	store  storage.Store
+	clock  clock.Clock
+	random io.Reader
+}
+
+// Opt configures the nonce store
+type Opt func(s *Store)
+
+// WithClock sets the clock used to expire the nonces, the system clock by default
+func WithClock(c clock.Clock) Opt {
+	return func(s *Store) {
+		s.clock = c
+	}
+}
+
+// WithRandom sets the source of the random nonces, crypto/rand by default
+func WithRandom(random io.Reader) Opt {
+	return func(s *Store) {
+		s.random = random
+	}
+}
+
+// New returns the nonce store keeping the nonces in the store
+func New(store storage.Store, opts ...Opt) *Store {
+	s := &Store{store: store, clock: clock.System(), random: rand.Reader}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Create returns a new random nonce for the scope (e.g. the domain of a presentation request), the nonce
+// expires after the ttl unless the ttl is zero
+func (s *Store) Create(scope string, ttl time.Duration) (string, error) {
+	b := make([]byte, nonceSize)
+	if _, err := io.ReadFull(s.random, b); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	nonce := base64.RawURLEncoding.EncodeToString(b)
+
+	bytes, err := json.Marshal(&record{Scope: scope, ExpiresAt: s.expiry(ttl)})
+	if err != nil {
+		return "", err
+	}
+
+	if err = s.put(keyPrefix+nonce, bytes, ttl); err != nil {
+		return "", fmt.Errorf("failed to save nonce: %w", err)
+	}
+
+	return nonce, nil
+}
+
+// Burn validates the nonce and marks it used: the nonce must have been created for the scope, not be expired
+// and not be used yet
+func (s *Store) Burn(scope, nonce string) error {
+	conditional, ok := s.store.(storage.ConditionalStore)
+	if !ok {
+		return ErrNotConditional
+	}
+
+	k := keyPrefix + nonce
+
+	for i := 0; i < maxUpdateAttempts; i++ {
+		current, err := s.store.Get(k)
+		if errors.Is(err, storage.ErrDataNotFound) {
+			return ErrNotFound
+		}
+
+		if err != nil {
+			return err
+		}
+
+		r := &record{}
+		if err = json.Unmarshal(current, r); err != nil {
+			return err
+		}
+
+		if err = s.check(r, scope); err != nil {
+			return err
+		}
+
+		r.Used = true
+
+		bytes, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+
+		err = conditional.PutIf(k, bytes, current)
+		if errors.Is(err, storage.ErrConflict) {
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+
+		return s.rearm(k, bytes, r.ExpiresAt)
+	}
+
+	return fmt.Errorf("failed to burn nonce: %w", storage.ErrConflict)
+}
+
+// Use records a value chosen by the caller (e.g. the ID of a message or the key of a single-use invitation)
+// as a used nonce of the scope, ErrUsed is returned if the value has already been used. The record is kept
+// for the ttl unless the ttl is zero, the value is accepted again once expired.
+func (s *Store) Use(scope, value string, ttl time.Duration) error {
+	conditional, ok := s.store.(storage.ConditionalStore)
+	if !ok {
+		return ErrNotConditional
+	}
+
+	k := keyPrefix + value
+	expiresAt := s.expiry(ttl)
+
+	bytes, err := json.Marshal(&record{Scope: scope, ExpiresAt: expiresAt, Used: true})
+	if err != nil {
+		return err
+	}
+
+	err = conditional.PutIf(k, bytes, nil)
+	if errors.Is(err, storage.ErrConflict) {
+		return fmt.Errorf("%w: %s", ErrUsed, value)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return s.rearm(k, bytes, expiresAt)
+}
+
+func (s *Store) check(r *record, scope string) error {
+	if r.Scope != scope {
+		return fmt.Errorf("%w: nonce issued for scope %s", ErrNotFound, r.Scope)
+	}
+
+	if !r.ExpiresAt.IsZero() && s.clock.Now().After(r.ExpiresAt) {
+		return ErrExpired
+	}
+
+	if r.Used {
+		return ErrUsed
+	}
+
+	return nil
+}
+
+func (s *Store) expiry(ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+
+	return s.clock.Now().Add(ttl)
+}
+
+// put stores the record, which expires with the nonce when supported by the store
+func (s *Store) put(k string, v []byte, ttl time.Duration) error {
+	if expiring, ok := s.store.(storage.ExpiringStore); ok && ttl > 0 {
+		return expiring.PutWithTTL(k, v, ttl)
+	}
+
+	return s.store.Put(k, v)
+}
+
+// rearm sets the expiry of the used record as the puts without ttl don't keep it, the used records are only
+// read to be rejected so rewriting them isn't racy
+func (s *Store) rearm(k string, v []byte, expiresAt time.Time) error {
+	expiring, ok := s.store.(storage.ExpiringStore)
+	if !ok || expiresAt.IsZero() {
+		return nil
+	}
+
+	ttl := expiresAt.Sub(s.clock.Now())
+	if ttl <= 0 {
+		return nil
+	}
+
+	return expiring.PutWithTTL(k, v, ttl)
+}
@@ -0,0 +1,203 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package nonces
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	mockclock "github.com/hyperledger/aries-framework-go/pkg/internal/mock/clock"
+	mockstorage "github.com/hyperledger/aries-framework-go/pkg/internal/mock/storage"
+	"github.com/hyperledger/aries-framework-go/pkg/storage"
+)
+
+func TestStore_Burn(t *testing.T) {
+	t.Run("test nonce burnt once", func(t *testing.T) {
+		s := New(newMockStore())
+
+		nonce, err := s.Create("scope", time.Minute)
+		require.NoError(t, err)
+		require.Len(t, nonce, 43)
+
+		other, err := s.Create("scope", time.Minute)
+		require.NoError(t, err)
+		require.NotEqual(t, nonce, other)
+
+		require.NoError(t, s.Burn("scope", nonce))
+		require.True(t, errors.Is(s.Burn("scope", nonce), ErrUsed))
+		require.NoError(t, s.Burn("scope", other))
+	})
+
+	t.Run("test nonce not created", func(t *testing.T) {
+		s := New(newMockStore())
+		require.True(t, errors.Is(s.Burn("scope", "unknown"), ErrNotFound))
+
+		nonce, err := s.Create("scope", 0)
+		require.NoError(t, err)
+
+		err = s.Burn("other", nonce)
+		require.True(t, errors.Is(err, ErrNotFound))
+		require.Contains(t, err.Error(), "nonce issued for scope scope")
+	})
+
+	t.Run("test nonce expired", func(t *testing.T) {
+		clock := &mockclock.Clock{Time: time.Now()}
+		s := New(newMockStore(), WithClock(clock))
+
+		nonce, err := s.Create("scope", time.Minute)
+		require.NoError(t, err)
+
+		clock.Add(2 * time.Minute)
+		require.True(t, errors.Is(s.Burn("scope", nonce), ErrExpired))
+	})
+
+	t.Run("test records expire with the nonces", func(t *testing.T) {
+		store := newMockStore()
+		s := New(store)
+
+		nonce, err := s.Create("scope", time.Minute)
+		require.NoError(t, err)
+
+		ttl, err := store.TTL(keyPrefix + nonce)
+		require.NoError(t, err)
+		require.True(t, ttl > 0 && ttl <= time.Minute)
+
+		require.NoError(t, s.Burn("scope", nonce))
+
+		ttl, err = store.TTL(keyPrefix + nonce)
+		require.NoError(t, err)
+		require.True(t, ttl > 0 && ttl <= time.Minute)
+
+		nonce, err = s.Create("scope", 0)
+		require.NoError(t, err)
+		require.NoError(t, s.Burn("scope", nonce))
+
+		ttl, err = store.TTL(keyPrefix + nonce)
+		require.NoError(t, err)
+		require.Zero(t, ttl)
+	})
+
+	t.Run("test concurrent burn conflict", func(t *testing.T) {
+		store := newMockStore()
+
+		nonce, err := New(store).Create("scope", time.Minute)
+		require.NoError(t, err)
+
+		err = New(&conflictingStore{MockStore: store}).Burn("scope", nonce)
+		require.True(t, errors.Is(err, ErrUsed))
+	})
+
+	t.Run("test store without optional interfaces", func(t *testing.T) {
+		s := New(&plainStore{store: newMockStore()})
+
+		nonce, err := s.Create("scope", time.Minute)
+		require.NoError(t, err)
+
+		require.True(t, errors.Is(s.Burn("scope", nonce), ErrNotConditional))
+	})
+
+	t.Run("test store errors", func(t *testing.T) {
+		s := New(newMockStore(), WithRandom(bytes.NewReader(nil)))
+		_, err := s.Create("", time.Minute)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to generate nonce")
+
+		s = New(&mockstorage.MockStore{Store: map[string][]byte{}, ErrPut: errors.New("put error")})
+		_, err = s.Create("", time.Minute)
+		require.EqualError(t, err, "failed to save nonce: put error")
+
+		s = New(&mockstorage.MockStore{Store: map[string][]byte{keyPrefix + "n": {}}, ErrGet: errors.New("get error")})
+		require.EqualError(t, s.Burn("", "n"), "get error")
+
+		s = New(&mockstorage.MockStore{Store: map[string][]byte{keyPrefix + "n": []byte("{")}})
+		require.Error(t, s.Burn("", "n"))
+	})
+}
+
+func TestStore_Use(t *testing.T) {
+	t.Run("test value used once", func(t *testing.T) {
+		s := New(newMockStore())
+
+		require.NoError(t, s.Use("messages", "id1", time.Minute))
+		require.NoError(t, s.Use("messages", "id2", 0))
+
+		err := s.Use("messages", "id1", time.Minute)
+		require.True(t, errors.Is(err, ErrUsed))
+		require.Contains(t, err.Error(), "id1")
+
+		require.True(t, errors.Is(s.Use("messages", "id2", 0), ErrUsed))
+	})
+
+	t.Run("test value accepted again once expired", func(t *testing.T) {
+		store := newMockStore()
+		s := New(store)
+
+		require.NoError(t, s.Use("messages", "id1", time.Minute))
+
+		ttl, err := store.TTL(keyPrefix + "id1")
+		require.NoError(t, err)
+		require.True(t, ttl > 0 && ttl <= time.Minute)
+
+		require.NoError(t, s.Use("messages", "id2", time.Millisecond))
+		time.Sleep(2 * time.Millisecond)
+		require.NoError(t, s.Use("messages", "id2", time.Millisecond))
+	})
+
+	t.Run("test store without optional interfaces", func(t *testing.T) {
+		s := New(&plainStore{store: newMockStore()})
+
+		require.True(t, errors.Is(s.Use("messages", "id1", time.Minute), ErrNotConditional))
+	})
+
+	t.Run("test store errors", func(t *testing.T) {
+		s := New(&mockstorage.MockStore{Store: map[string][]byte{}, ErrPut: errors.New("put error")})
+		require.EqualError(t, s.Use("messages", "id1", time.Minute), "put error")
+	})
+}
+
+func newMockStore() *mockstorage.MockStore {
+	return &mockstorage.MockStore{Store: make(map[string][]byte)}
+}
+
+// conflictingStore burns the nonce by another instance before the first conditional update
+type conflictingStore struct {
+	*mockstorage.MockStore
+	conflicted bool
+}
+
+func (s *conflictingStore) PutIf(k string, v, expected []byte) error {
+	if !s.conflicted {
+		s.conflicted = true
+
+		if err := s.MockStore.Put(k, v); err != nil {
+			return err
+		}
+	}
+
+	return s.MockStore.PutIf(k, v, expected)
+}
+
+// plainStore only implements storage.Store
+type plainStore struct {
+	store storage.Store
+}
+
+func (s *plainStore) Put(k string, v []byte) error {
+	return s.store.Put(k, v)
+}
+
+func (s *plainStore) Get(k string) ([]byte, error) {
+	return s.store.Get(k)
+}
+
+func (s *plainStore) Delete(k string) error {
+	return s.store.Delete(k)
+}
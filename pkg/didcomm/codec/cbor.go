@@ -0,0 +1,182 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package codec
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+const (
+	// majorMap is the CBOR major type of the maps (RFC 7049)
+	majorMap = 5
+
+	// maxDepth is the maximum nesting of the decoded arrays, maps and tags
+	maxDepth = 100
+)
+
+// CBOR encodes the messages in CBOR (RFC 7049), the messages are converted through their JSON representation
+// so the JSON tags of the messages apply. The messages are encoded in the canonical CBOR, the content of the
+// tags is decoded as untagged.
+type CBOR struct{}
+
+// Name returns the codec name
+func (CBOR) Name() string {
+	return CBORName
+}
+
+// Marshal encodes the message in CBOR
+func (CBOR) Marshal(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var generic interface{}
+	if err = decoder.Decode(&generic); err != nil {
+		return nil, err
+	}
+
+	generic, err = fromJSON(generic)
+	if err != nil {
+		return nil, err
+	}
+
+	mode, err := cbor.CanonicalEncOptions().EncMode()
+	if err != nil {
+		return nil, err
+	}
+
+	return mode.Marshal(generic)
+}
+
+// Unmarshal decodes the CBOR payload into the message
+func (CBOR) Unmarshal(data []byte, v interface{}) error {
+	mode, err := cbor.DecOptions{
+		DupMapKey:       cbor.DupMapKeyEnforcedAPF,
+		MaxNestedLevels: maxDepth,
+		IndefLength:     cbor.IndefLengthForbidden,
+	}.DecMode()
+	if err != nil {
+		return err
+	}
+
+	decoder := mode.NewDecoder(bytes.NewReader(data))
+
+	var generic interface{}
+	if err = decoder.Decode(&generic); err != nil {
+		// the decoder reads the truncated payloads to the end
+		if errors.Is(err, io.EOF) {
+			return errors.New("cbor: unexpected end of data")
+		}
+
+		return err
+	}
+
+	if decoder.NumBytesRead() != len(data) {
+		return errors.New("cbor: trailing data")
+	}
+
+	generic, err = toJSON(generic)
+	if err != nil {
+		return err
+	}
+
+	jsonBytes, err := json.Marshal(generic)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(jsonBytes, v)
+}
+
+// Accept accepts the CBOR maps
+func (CBOR) Accept(payload []byte) bool {
+	return len(payload) > 0 && payload[0]>>5 == majorMap
+}
+
+// fromJSON converts the JSON numbers to integers when they are integral
+func fromJSON(v interface{}) (interface{}, error) {
+	switch x := v.(type) {
+	case json.Number:
+		if i, err := x.Int64(); err == nil {
+			return i, nil
+		}
+
+		if u, err := strconv.ParseUint(x.String(), 10, 64); err == nil {
+			return u, nil
+		}
+
+		return x.Float64()
+	case []interface{}:
+		for i := range x {
+			item, err := fromJSON(x[i])
+			if err != nil {
+				return nil, err
+			}
+
+			x[i] = item
+		}
+	case map[string]interface{}:
+		for k := range x {
+			item, err := fromJSON(x[k])
+			if err != nil {
+				return nil, err
+			}
+
+			x[k] = item
+		}
+	}
+
+	return v, nil
+}
+
+// toJSON converts the decoded CBOR maps to JSON objects and replaces the tags with their content
+func toJSON(v interface{}) (interface{}, error) {
+	switch x := v.(type) {
+	case cbor.Tag:
+		return toJSON(x.Content)
+	case []interface{}:
+		for i := range x {
+			item, err := toJSON(x[i])
+			if err != nil {
+				return nil, err
+			}
+
+			x[i] = item
+		}
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(x))
+
+		for k, item := range x {
+			key, ok := k.(string)
+			if !ok {
+				return nil, fmt.Errorf("cbor: unsupported map key type %T", k)
+			}
+
+			converted, err := toJSON(item)
+			if err != nil {
+				return nil, err
+			}
+
+			m[key] = converted
+		}
+
+		return m, nil
+	}
+
+	return v, nil
+}
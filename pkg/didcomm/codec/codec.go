@@ -0,0 +1,104 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package codec encodes the DIDComm message payloads packed in the envelopes. JSON is the default codec,
+// the compact codecs such as CBOR reduce the size of the envelopes sent over the constrained transports.
+package codec
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+const (
+	// JSONName is the name of the JSON codec
+	JSONName = "json"
+	// CBORName is the name of the CBOR codec
+	CBORName = "cbor"
+)
+
+// ErrUnknownCodec is returned when the payload isn't encoded with any of the codecs
+var ErrUnknownCodec = errors.New("unknown payload codec")
+
+// Codec encodes the message payloads
+type Codec interface {
+	// Name identifies the codec in the codec negotiation
+	Name() string
+	// Marshal encodes the message, the message is marshalled with its JSON tags
+	Marshal(v interface{}) ([]byte, error)
+	// Unmarshal decodes the payload into the message
+	Unmarshal(data []byte, v interface{}) error
+	// Accept tells whether the payload is encoded with the codec
+	Accept(payload []byte) bool
+}
+
+// JSON is the default codec
+type JSON struct{}
+
+// Name returns the codec name
+func (JSON) Name() string {
+	return JSONName
+}
+
+// Marshal encodes the message in JSON
+func (JSON) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Unmarshal decodes the JSON payload
+func (JSON) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// Accept accepts the JSON objects
+func (JSON) Accept(payload []byte) bool {
+	trimmed := bytes.TrimSpace(payload)
+
+	return len(trimmed) > 0 && trimmed[0] == '{'
+}
+
+// Select returns the first of the codecs preferred by the recipient which is available, JSON if none is
+func Select(preferred []string, available []Codec) Codec {
+	for _, name := range preferred {
+		for _, c := range available {
+			if c.Name() == name {
+				return c
+			}
+		}
+	}
+
+	return JSON{}
+}
+
+// ToJSON converts the payload to JSON with the codec accepting it, the codec name is returned
+// with the JSON payload
+func ToJSON(payload []byte, available []Codec) ([]byte, string, error) {
+	if (JSON{}).Accept(payload) {
+		return payload, JSONName, nil
+	}
+
+	for _, c := range available {
+		if !c.Accept(payload) {
+			continue
+		}
+
+		var v interface{}
+		if err := c.Unmarshal(payload, &v); err != nil {
+			return nil, "", fmt.Errorf("failed to decode %s payload: %w", c.Name(), err)
+		}
+
+		jsonBytes, err := json.Marshal(v)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to convert %s payload to JSON: %w", c.Name(), err)
+		}
+
+		return jsonBytes, c.Name(), nil
+	}
+
+	return nil, "", ErrUnknownCodec
+}
@@ -0,0 +1,160 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package codec
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testMessage struct {
+	ID       string            `json:"@id"`
+	Type     string            `json:"@type"`
+	Count    int               `json:"count"`
+	Negative int64             `json:"negative"`
+	Ratio    float64           `json:"ratio"`
+	Flag     bool              `json:"flag"`
+	Optional *string           `json:"optional"`
+	Tags     []string          `json:"tags"`
+	Attrs    map[string]string `json:"attrs"`
+	Big      uint32            `json:"big"`
+}
+
+func TestCodecs(t *testing.T) {
+	msg := &testMessage{ID: "123", Type: "https://didcomm.org/basicmessage/1.0/message", Count: 300,
+		Negative: -70000, Ratio: 0.5, Flag: true, Tags: []string{"a", "b"},
+		Attrs: map[string]string{"z": "1", "a": "2"}, Big: math.MaxUint32}
+
+	for _, c := range []Codec{JSON{}, CBOR{}} {
+		c := c
+		t.Run("test round trip with "+c.Name(), func(t *testing.T) {
+			payload, err := c.Marshal(msg)
+			require.NoError(t, err)
+			require.True(t, c.Accept(payload))
+
+			decoded := &testMessage{}
+			require.NoError(t, c.Unmarshal(payload, decoded))
+			require.Equal(t, msg, decoded)
+
+			jsonPayload, name, err := ToJSON(payload, []Codec{CBOR{}})
+			require.NoError(t, err)
+			require.Equal(t, c.Name(), name)
+
+			decoded = &testMessage{}
+			require.NoError(t, json.Unmarshal(jsonPayload, decoded))
+			require.Equal(t, msg, decoded)
+		})
+	}
+
+	t.Run("test CBOR payload is smaller", func(t *testing.T) {
+		jsonPayload, err := JSON{}.Marshal(msg)
+		require.NoError(t, err)
+
+		cborPayload, err := CBOR{}.Marshal(msg)
+		require.NoError(t, err)
+		require.True(t, len(cborPayload) < len(jsonPayload))
+		require.False(t, JSON{}.Accept(cborPayload))
+		require.False(t, CBOR{}.Accept(jsonPayload))
+	})
+
+	t.Run("test CBOR encoding is deterministic", func(t *testing.T) {
+		payload, err := CBOR{}.Marshal(map[string]interface{}{"b": 1, "a": []interface{}{nil, false}})
+		require.NoError(t, err)
+		require.Equal(t, "a2616182f6f4616201", hex.EncodeToString(payload))
+	})
+}
+
+func TestCBOR_Unmarshal(t *testing.T) {
+	decode := func(h string) (interface{}, error) {
+		data, err := hex.DecodeString(h)
+		require.NoError(t, err)
+
+		var v interface{}
+
+		return v, CBOR{}.Unmarshal(data, &v)
+	}
+
+	t.Run("test RFC 7049 examples", func(t *testing.T) {
+		for h, expected := range map[string]interface{}{
+			"f93c00":             1.0,
+			"f9c400":             -4.0,
+			"f90001":             5.960464477539063e-8,
+			"fa47c35000":         100000.0,
+			"fb3ff199999999999a": 1.1,
+			"c074323031332d30332d32315432303a30343a30305a": "2013-03-21T20:04:00Z",
+			"4401020304": "AQIDBA==",
+			"f7":         nil,
+			"3903e7":     -1000.0,
+		} {
+			v, err := decode(h)
+			require.NoError(t, err, h)
+			require.Equal(t, expected, v, h)
+		}
+	})
+
+	t.Run("test invalid payloads", func(t *testing.T) {
+		for h, msg := range map[string]string{
+			"":                   "unexpected end of data",
+			"a1":                 "unexpected end of data",
+			"7a0000ffff":         "unexpected end of data",
+			"bf":                 "indefinite-length map isn't allowed",
+			"1c":                 "invalid additional information",
+			"3bffffffffffffffff": "overflows Go's int64",
+			"0000":               "trailing data",
+			"a201020304":         "unsupported map key type",
+			"a2616100616101":     "duplicate map key",
+		} {
+			_, err := decode(h)
+			require.Error(t, err, h)
+			require.Contains(t, err.Error(), msg, h)
+		}
+	})
+
+	t.Run("test maximum nesting depth", func(t *testing.T) {
+		for _, item := range []string{"81", "c6"} {
+			nested := ""
+			for i := 0; i <= maxDepth+1; i++ {
+				nested += item
+			}
+
+			_, err := decode(nested + "00")
+			require.Error(t, err, item)
+			require.Contains(t, err.Error(), "exceeded max nested level", item)
+		}
+	})
+}
+
+func TestSelect(t *testing.T) {
+	available := []Codec{CBOR{}}
+
+	require.Equal(t, CBORName, Select([]string{"msgpack", CBORName}, available).Name())
+	require.Equal(t, JSONName, Select([]string{"msgpack"}, available).Name())
+	require.Equal(t, JSONName, Select(nil, available).Name())
+}
+
+func TestToJSON(t *testing.T) {
+	t.Run("test unknown codec", func(t *testing.T) {
+		_, _, err := ToJSON([]byte{0x01}, []Codec{CBOR{}})
+		require.True(t, errors.Is(err, ErrUnknownCodec))
+	})
+
+	t.Run("test invalid payload", func(t *testing.T) {
+		_, _, err := ToJSON([]byte{0xa1}, []Codec{CBOR{}})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to decode cbor payload")
+	})
+
+	t.Run("test unsupported value", func(t *testing.T) {
+		_, err := CBOR{}.Marshal(make(chan int))
+		require.Error(t, err)
+	})
+}
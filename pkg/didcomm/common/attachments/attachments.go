@@ -0,0 +1,297 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package attachments offloads the large attachment payloads of the messages to an objectstore.Store, so the
+// protocol state keeps only the references and the digests of the payloads instead of growing the storage
+// provider. Every offloaded attachment owns its object, so removing an attachment never removes the payload of
+// another attachment, and the SHA-256 digest of the payload is kept to check the object read back.
+package attachments
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/aries-framework-go/pkg/common/idgen"
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/protocol/decorator"
+	"github.com/hyperledger/aries-framework-go/pkg/objectstore"
+)
+
+const (
+	// LinkScheme prefixes the links of the payloads offloaded to the object store, the links are local to the
+	// agent and are replaced by the payloads when the attachments are inlined
+	LinkScheme = "objectstore:"
+
+	// DefaultThreshold is the default size of the base64 payloads offloaded, in bytes
+	DefaultThreshold = 64 << 10
+
+	attachDecoratorSuffix = "~attach"
+)
+
+var (
+	// ErrDigestMismatch is returned when the payload of the attachment doesn't match its sha256 digest
+	ErrDigestMismatch = errors.New("attachment payload doesn't match its digest")
+	// ErrNoPayload is returned when the attachment has no payload inlined or offloaded
+	ErrNoPayload = errors.New("attachment has no local payload")
+)
+
+// Offloader moves the attachment payloads to the object store and reads them back
+type Offloader struct {
+	store     objectstore.Store
+	threshold int
+	ids       idgen.Generator
+}
+
+// Opt configures the offloader
+type Opt func(o *Offloader)
+
+// WithThreshold offloads the base64 payloads larger than the threshold in bytes, DefaultThreshold by default
+func WithThreshold(threshold int) Opt {
+	return func(o *Offloader) {
+		o.threshold = threshold
+	}
+}
+
+// WithIDGenerator sets the generator of the object keys of the offloaded payloads, random UUIDs by default. The
+// IDs must be valid object keys (see objectstore.CheckKey).
+func WithIDGenerator(g idgen.Generator) Opt {
+	return func(o *Offloader) {
+		o.ids = g
+	}
+}
+
+// New returns the offloader of the object store
+func New(store objectstore.Store, opts ...Opt) *Offloader {
+	o := &Offloader{store: store, threshold: DefaultThreshold, ids: idgen.UUID()}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// Offload moves the base64 payload of the attachment to the object store if larger than the threshold, the
+// payload is replaced by a link to the stored payload and its digest. The JSON payloads are kept inline.
+func (o *Offloader) Offload(a *decorator.Attachment) error {
+	if len(a.Data.Base64) <= o.threshold {
+		return nil
+	}
+
+	payload, err := decode(a.Data.Base64)
+	if err != nil {
+		return fmt.Errorf("invalid attachment %s: %w", a.ID, err)
+	}
+
+	digest := sha256.Sum256(payload)
+	hexDigest := hex.EncodeToString(digest[:])
+
+	if a.Data.SHA256 != "" && !strings.EqualFold(a.Data.SHA256, hexDigest) {
+		return fmt.Errorf("%w: attachment %s", ErrDigestMismatch, a.ID)
+	}
+
+	key := o.ids.NewID()
+
+	if err = o.store.Put(key, payload); err != nil {
+		return fmt.Errorf("failed to offload attachment %s: %w", a.ID, err)
+	}
+
+	a.Data.Base64 = ""
+	a.Data.SHA256 = hexDigest
+	a.Data.Links = append([]string{LinkScheme + key}, a.Data.Links...)
+
+	if a.ByteCount == 0 {
+		a.ByteCount = int64(len(payload))
+	}
+
+	return nil
+}
+
+// Payload returns the payload of the attachment, inlined or read from the object store, checked against the
+// digest of the attachment if any
+func (o *Offloader) Payload(a *decorator.Attachment) ([]byte, error) {
+	var (
+		payload []byte
+		err     error
+	)
+
+	switch key := localKey(a); {
+	case a.Data.Base64 != "":
+		payload, err = decode(a.Data.Base64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid attachment %s: %w", a.ID, err)
+		}
+	case len(a.Data.JSON) > 0:
+		payload = a.Data.JSON
+	case key != "":
+		payload, err = o.store.Get(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read offloaded attachment %s: %w", a.ID, err)
+		}
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrNoPayload, a.ID)
+	}
+
+	if a.Data.SHA256 != "" {
+		digest := sha256.Sum256(payload)
+		if !strings.EqualFold(a.Data.SHA256, hex.EncodeToString(digest[:])) {
+			return nil, fmt.Errorf("%w: attachment %s", ErrDigestMismatch, a.ID)
+		}
+	}
+
+	return payload, nil
+}
+
+// Inline replaces the link to the offloaded payload by the payload, e.g. before the message is sent to the
+// counterparty which can't resolve the links local to the agent
+func (o *Offloader) Inline(a *decorator.Attachment) error {
+	if a.Data.Base64 != "" || localKey(a) == "" {
+		return nil
+	}
+
+	payload, err := o.Payload(a)
+	if err != nil {
+		return err
+	}
+
+	a.Data.Base64 = base64.URLEncoding.EncodeToString(payload)
+
+	links := a.Data.Links[:0]
+
+	for _, link := range a.Data.Links {
+		if !strings.HasPrefix(link, LinkScheme) {
+			links = append(links, link)
+		}
+	}
+
+	a.Data.Links = nil
+	if len(links) > 0 {
+		a.Data.Links = links
+	}
+
+	return nil
+}
+
+// Remove deletes the offloaded payload of the attachment, e.g. once the message holding the attachment is deleted.
+// The attachments which are not offloaded are ignored.
+func (o *Offloader) Remove(a *decorator.Attachment) error {
+	key := localKey(a)
+	if key == "" {
+		return nil
+	}
+
+	if err := o.store.Delete(key); err != nil {
+		return fmt.Errorf("failed to remove offloaded attachment %s: %w", a.ID, err)
+	}
+
+	return nil
+}
+
+// OffloadMessage offloads the attachments of the attachment decorators (e.g. "img~attach") of the JSON message,
+// the decorators hold an attachment or an array of attachments. Messages which are not JSON objects are returned
+// as is.
+func (o *Offloader) OffloadMessage(msg []byte) ([]byte, error) {
+	return processMessage(msg, o.Offload)
+}
+
+// InlineMessage inlines the offloaded attachments of the JSON message
+func (o *Offloader) InlineMessage(msg []byte) ([]byte, error) {
+	return processMessage(msg, o.Inline)
+}
+
+func processMessage(msg []byte, process func(a *decorator.Attachment) error) ([]byte, error) {
+	fields := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(msg, &fields); err != nil {
+		return msg, nil
+	}
+
+	modified := false
+
+	for name, value := range fields {
+		if !strings.HasSuffix(name, attachDecoratorSuffix) {
+			continue
+		}
+
+		processed, err := processDecorator(value, process)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+
+		if processed != nil {
+			fields[name] = processed
+			modified = true
+		}
+	}
+
+	if !modified {
+		return msg, nil
+	}
+
+	return json.Marshal(fields)
+}
+
+// processDecorator processes the attachments of the decorator, nil is returned if the decorator isn't modified
+func processDecorator(value json.RawMessage, process func(a *decorator.Attachment) error) (json.RawMessage, error) {
+	single := len(value) > 0 && value[0] == '{'
+	if single {
+		value = append(append([]byte{'['}, value...), ']')
+	}
+
+	var list []*decorator.Attachment
+	if err := json.Unmarshal(value, &list); err != nil {
+		return nil, err
+	}
+
+	modified := false
+
+	for _, a := range list {
+		payload := a.Data.Base64
+
+		if err := process(a); err != nil {
+			return nil, err
+		}
+
+		modified = modified || payload != a.Data.Base64
+	}
+
+	switch {
+	case !modified:
+		return nil, nil
+	case single:
+		return json.Marshal(list[0])
+	default:
+		return json.Marshal(list)
+	}
+}
+
+// localKey returns the object store key of the offloaded payload, an empty key if the payload isn't offloaded
+func localKey(a *decorator.Attachment) string {
+	for _, link := range a.Data.Links {
+		if strings.HasPrefix(link, LinkScheme) {
+			return strings.TrimPrefix(link, LinkScheme)
+		}
+	}
+
+	return ""
+}
+
+// decode decodes the base64 payload, encoded with or without padding in the URL or the standard alphabet
+func decode(payload string) ([]byte, error) {
+	encoding := base64.URLEncoding
+	if strings.ContainsAny(payload, "+/") {
+		encoding = base64.StdEncoding
+	}
+
+	if !strings.HasSuffix(payload, "=") && len(payload)%4 != 0 {
+		encoding = encoding.WithPadding(base64.NoPadding)
+	}
+
+	return encoding.DecodeString(payload)
+}
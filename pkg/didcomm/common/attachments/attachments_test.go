@@ -0,0 +1,249 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package attachments
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hyperledger/aries-framework-go/pkg/common/idgen"
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/protocol/decorator"
+	"github.com/hyperledger/aries-framework-go/pkg/objectstore"
+)
+
+func TestOffloader(t *testing.T) {
+	payload := []byte(strings.Repeat("large payload ", 10))
+	digest := sha256.Sum256(payload)
+	hexDigest := hex.EncodeToString(digest[:])
+	key := "object-1"
+
+	t.Run("test offload and inline", func(t *testing.T) {
+		store := newMockStore()
+		o := New(store, WithThreshold(64), WithIDGenerator(idgen.Sequence("object")))
+
+		a := &decorator.Attachment{ID: "a1", Data: decorator.AttachmentData{
+			Base64: base64.StdEncoding.EncodeToString(payload), Links: []string{"https://example.com/a1"}}}
+		require.NoError(t, o.Offload(a))
+		require.Empty(t, a.Data.Base64)
+		require.Equal(t, hexDigest, a.Data.SHA256)
+		require.Equal(t, []string{LinkScheme + key, "https://example.com/a1"}, a.Data.Links)
+		require.Equal(t, int64(len(payload)), a.ByteCount)
+		require.Equal(t, payload, store.objects[key])
+
+		p, err := o.Payload(a)
+		require.NoError(t, err)
+		require.Equal(t, payload, p)
+
+		require.NoError(t, o.Inline(a))
+		require.Equal(t, base64.URLEncoding.EncodeToString(payload), a.Data.Base64)
+		require.Equal(t, []string{"https://example.com/a1"}, a.Data.Links)
+
+		// inlining an inlined attachment is a no-op
+		require.NoError(t, o.Inline(a))
+
+		a.Data.Links = []string{LinkScheme + key}
+		a.Data.Base64 = ""
+		require.NoError(t, o.Inline(a))
+		require.Nil(t, a.Data.Links)
+	})
+
+	t.Run("test small and JSON payloads kept inline", func(t *testing.T) {
+		store := newMockStore()
+		o := New(store)
+
+		a := &decorator.Attachment{Data: decorator.AttachmentData{Base64: base64.StdEncoding.EncodeToString(payload)}}
+		require.NoError(t, o.Offload(a))
+		require.NotEmpty(t, a.Data.Base64)
+
+		a = &decorator.Attachment{Data: decorator.AttachmentData{JSON: json.RawMessage(`{"k":"v"}`)}}
+		require.NoError(t, o.Offload(a))
+		require.Empty(t, store.objects)
+
+		p, err := o.Payload(a)
+		require.NoError(t, err)
+		require.Equal(t, `{"k":"v"}`, string(p))
+	})
+
+	t.Run("test the same payload offloaded twice is removed independently", func(t *testing.T) {
+		store := newMockStore()
+		o := New(store, WithThreshold(64), WithIDGenerator(idgen.Sequence("object")))
+
+		a1 := &decorator.Attachment{ID: "a1", Data: decorator.AttachmentData{
+			Base64: base64.StdEncoding.EncodeToString(payload)}}
+		a2 := &decorator.Attachment{ID: "a2", Data: decorator.AttachmentData{
+			Base64: base64.StdEncoding.EncodeToString(payload)}}
+		require.NoError(t, o.Offload(a1))
+		require.NoError(t, o.Offload(a2))
+		require.Len(t, store.objects, 2)
+
+		require.NoError(t, o.Remove(a1))
+		require.Len(t, store.objects, 1)
+
+		_, err := o.Payload(a1)
+		require.True(t, errors.Is(err, objectstore.ErrNotFound))
+
+		p, err := o.Payload(a2)
+		require.NoError(t, err)
+		require.Equal(t, payload, p)
+
+		// the attachments which are not offloaded are ignored
+		require.NoError(t, o.Remove(&decorator.Attachment{Data: decorator.AttachmentData{Base64: "YWJj"}}))
+
+		store.err = errors.New("delete error")
+		err = o.Remove(a2)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "delete error")
+	})
+
+	t.Run("test digest mismatch", func(t *testing.T) {
+		o := New(newMockStore(), WithThreshold(1), WithIDGenerator(idgen.Sequence("object")))
+
+		a := &decorator.Attachment{ID: "a1", Data: decorator.AttachmentData{
+			Base64: base64.RawURLEncoding.EncodeToString(payload), SHA256: "00"}}
+		require.True(t, errors.Is(o.Offload(a), ErrDigestMismatch))
+
+		_, err := o.Payload(a)
+		require.True(t, errors.Is(err, ErrDigestMismatch))
+
+		a.Data.SHA256 = strings.ToUpper(hexDigest)
+		require.NoError(t, o.Offload(a))
+
+		// the offloaded payload is tampered with
+		o.store.(*mockStore).objects[key] = []byte("tampered")
+
+		_, err = o.Payload(a)
+		require.True(t, errors.Is(err, ErrDigestMismatch))
+		require.True(t, errors.Is(o.Inline(a), ErrDigestMismatch))
+	})
+
+	t.Run("test payload errors", func(t *testing.T) {
+		store := newMockStore()
+		o := New(store, WithThreshold(1))
+
+		_, err := o.Payload(&decorator.Attachment{ID: "a1"})
+		require.True(t, errors.Is(err, ErrNoPayload))
+
+		_, err = o.Payload(&decorator.Attachment{Data: decorator.AttachmentData{Links: []string{LinkScheme + key}}})
+		require.True(t, errors.Is(err, objectstore.ErrNotFound))
+
+		_, err = o.Payload(&decorator.Attachment{Data: decorator.AttachmentData{Base64: "!!"}})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid attachment")
+
+		err = o.Offload(&decorator.Attachment{Data: decorator.AttachmentData{Base64: "!!"}})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid attachment")
+
+		store.err = errors.New("put error")
+		err = o.Offload(&decorator.Attachment{Data: decorator.AttachmentData{Base64: "YWJj"}})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "put error")
+	})
+}
+
+func TestOffloader_Message(t *testing.T) {
+	payload := base64.StdEncoding.EncodeToString([]byte(strings.Repeat("large payload ", 10)))
+	o := New(newMockStore(), WithThreshold(64))
+
+	t.Run("test offload and inline message", func(t *testing.T) {
+		msg := []byte(`{"@id":"m1","img~attach":{"@id":"a1","data":{"base64":"` + payload + `"}},` +
+			`"docs~attach":[{"@id":"a2","data":{"base64":"c21hbGw="}},{"@id":"a3","data":{"base64":"` + payload +
+			`"}}],"other":"` + payload + `"}`)
+
+		offloaded, err := o.OffloadMessage(msg)
+		require.NoError(t, err)
+		require.True(t, len(offloaded) < len(msg))
+		require.Contains(t, string(offloaded), `"other":"`+payload)
+		require.Contains(t, string(offloaded), `"c21hbGw="`)
+
+		fields := struct {
+			Img  *decorator.Attachment   `json:"img~attach"`
+			Docs []*decorator.Attachment `json:"docs~attach"`
+		}{}
+		require.NoError(t, json.Unmarshal(offloaded, &fields))
+		require.Empty(t, fields.Img.Data.Base64)
+		require.Len(t, fields.Docs, 2)
+		require.Empty(t, fields.Docs[1].Data.Base64)
+
+		inlined, err := o.InlineMessage(offloaded)
+		require.NoError(t, err)
+
+		var docs struct {
+			Docs []*decorator.Attachment `json:"docs~attach"`
+		}
+		require.NoError(t, json.Unmarshal(inlined, &docs))
+		require.Len(t, docs.Docs, 2)
+		require.Nil(t, docs.Docs[1].Data.Links)
+
+		p, err := o.Payload(docs.Docs[1])
+		require.NoError(t, err)
+		require.Equal(t, strings.Repeat("large payload ", 10), string(p))
+	})
+
+	t.Run("test messages not modified", func(t *testing.T) {
+		for _, msg := range []string{`not JSON`, `{"@id":"m1"}`, `{"img~attach":{"data":{"base64":"c21hbGw="}}}`} {
+			processed, err := o.OffloadMessage([]byte(msg))
+			require.NoError(t, err)
+			require.Equal(t, msg, string(processed))
+		}
+	})
+
+	t.Run("test invalid attachment decorators", func(t *testing.T) {
+		_, err := o.OffloadMessage([]byte(`{"img~attach":"attachment"}`))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "img~attach")
+
+		_, err = o.OffloadMessage([]byte(`{"img~attach":{"data":{"base64":"` + payload + `!"}}}`))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid attachment")
+	})
+}
+
+type mockStore struct {
+	objects map[string][]byte
+	err     error
+}
+
+func newMockStore() *mockStore {
+	return &mockStore{objects: make(map[string][]byte)}
+}
+
+func (s *mockStore) Put(key string, value []byte) error {
+	if s.err != nil {
+		return s.err
+	}
+
+	s.objects[key] = value
+
+	return nil
+}
+
+func (s *mockStore) Get(key string) ([]byte, error) {
+	v, ok := s.objects[key]
+	if !ok {
+		return nil, objectstore.ErrNotFound
+	}
+
+	return v, nil
+}
+
+func (s *mockStore) Delete(key string) error {
+	if s.err != nil {
+		return s.err
+	}
+
+	delete(s.objects, key)
+
+	return nil
+}
@@ -0,0 +1,135 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package attachments
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/protocol/decorator"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+)
+
+// PresentationExchangeFormat is the attachment format of the W3C verifiable presentations submitted per the DIF
+// presentation exchange
+const PresentationExchangeFormat = "dif/presentation-exchange/submission@v1.0"
+
+// ErrUnsupportedFormat is returned when no handler is registered for the format of an attachment
+var ErrUnsupportedFormat = errors.New("unsupported attachment format")
+
+// ErrFormatMismatch is returned when an attachment has no format or a format has no attachment
+var ErrFormatMismatch = errors.New("attachments and formats mismatch")
+
+// Format maps an attachment of a message to its format, as the formats decorator of the protocols exchanging
+// proofs of several formats, e.g. {"attach_id": "1", "format": "hlindy/proof@v2.0"}
+type Format struct {
+	AttachID string `json:"attach_id"`
+	Format   string `json:"format"`
+}
+
+// FormatHandler checks the payload of an attachment of its format and returns the decoded proof, e.g. a
+// *verifiable.Presentation, an Indy proof or an ISO 18013-5 device response
+type FormatHandler func(payload []byte) (interface{}, error)
+
+// FormatRegistry holds the handlers of the attachment formats, the proof formats are plugged by registering
+// their handler without modifying the protocol services
+type FormatRegistry struct {
+	mutex    sync.RWMutex
+	handlers map[string]FormatHandler
+}
+
+// NewFormatRegistry returns a registry without handlers
+func NewFormatRegistry() *FormatRegistry {
+	return &FormatRegistry{handlers: make(map[string]FormatHandler)}
+}
+
+// Register adds or replaces the handler of the format
+func (r *FormatRegistry) Register(format string, handler FormatHandler) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.handlers[format] = handler
+}
+
+// Formats returns the registered formats in lexical order, e.g. to advertise the supported formats
+func (r *FormatRegistry) Formats() []string {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	formats := make([]string, 0, len(r.handlers))
+	for format := range r.handlers {
+		formats = append(formats, format)
+	}
+
+	sort.Strings(formats)
+
+	return formats
+}
+
+// Decode returns the proof decoded from the payload by the handler of the format
+func (r *FormatRegistry) Decode(format string, payload []byte) (interface{}, error) {
+	r.mutex.RLock()
+	handler, ok := r.handlers[format]
+	r.mutex.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFormat, format)
+	}
+
+	return handler(payload)
+}
+
+// DecodeAttachments returns the proofs decoded from the attachments by attachment ID, the payloads are read
+// with the payload function, e.g. Payload of the Offloader. Every attachment must have a format and every format
+// an attachment, ErrFormatMismatch is returned otherwise.
+func (r *FormatRegistry) DecodeAttachments(formats []Format, attachments []decorator.Attachment,
+	payload func(a *decorator.Attachment) ([]byte, error)) (map[string]interface{}, error) {
+	formatOf := make(map[string]string, len(formats))
+	for _, f := range formats {
+		formatOf[f.AttachID] = f.Format
+	}
+
+	proofs := make(map[string]interface{})
+
+	for i := range attachments {
+		a := &attachments[i]
+
+		format, ok := formatOf[a.ID]
+		if !ok {
+			return nil, fmt.Errorf("%w: attachment %s has no format", ErrFormatMismatch, a.ID)
+		}
+
+		data, err := payload(a)
+		if err != nil {
+			return nil, err
+		}
+
+		proof, err := r.Decode(format, data)
+		if err != nil {
+			return nil, fmt.Errorf("decode attachment %s: %w", a.ID, err)
+		}
+
+		proofs[a.ID] = proof
+	}
+
+	for _, f := range formats {
+		if _, ok := proofs[f.AttachID]; !ok {
+			return nil, fmt.Errorf("%w: format of attachment %s has no attachment", ErrFormatMismatch, f.AttachID)
+		}
+	}
+
+	return proofs, nil
+}
+
+// PresentationHandler decodes the W3C verifiable presentations with the options, e.g. to check their proofs
+func PresentationHandler(opts ...verifiable.PresentationOpt) FormatHandler {
+	return func(payload []byte) (interface{}, error) {
+		return verifiable.NewPresentation(payload, opts...)
+	}
+}
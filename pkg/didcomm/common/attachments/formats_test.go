@@ -0,0 +1,104 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package attachments
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/protocol/decorator"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+)
+
+const (
+	indyFormat = "hlindy/proof@v2.0"
+
+	presentation = `{
+  "@context": ["https://www.w3.org/2018/credentials/v1"],
+  "id": "urn:uuid:3978344f-8596-4c3a-a978-8fcaba3903c5",
+  "type": "VerifiablePresentation",
+  "holder": "did:example:ebfeb1f712ebc6f1c276e12ec21"
+}`
+)
+
+func TestFormatRegistry(t *testing.T) {
+	indyHandler := func(payload []byte) (interface{}, error) {
+		return "indy:" + string(payload), nil
+	}
+
+	t.Run("test register and decode", func(t *testing.T) {
+		r := NewFormatRegistry()
+		require.Empty(t, r.Formats())
+
+		r.Register(PresentationExchangeFormat, PresentationHandler())
+		r.Register(indyFormat, indyHandler)
+		require.Equal(t, []string{PresentationExchangeFormat, indyFormat}, r.Formats())
+
+		proof, err := r.Decode(PresentationExchangeFormat, []byte(presentation))
+		require.NoError(t, err)
+
+		vp, ok := proof.(*verifiable.Presentation)
+		require.True(t, ok)
+		require.Equal(t, "did:example:ebfeb1f712ebc6f1c276e12ec21", vp.Holder)
+
+		proof, err = r.Decode(indyFormat, []byte("proof"))
+		require.NoError(t, err)
+		require.Equal(t, "indy:proof", proof)
+
+		_, err = r.Decode("iso/18013-5@v1.0", nil)
+		require.True(t, errors.Is(err, ErrUnsupportedFormat))
+
+		_, err = r.Decode(PresentationExchangeFormat, []byte("{"))
+		require.Error(t, err)
+	})
+
+	t.Run("test decode attachments", func(t *testing.T) {
+		r := NewFormatRegistry()
+		r.Register(PresentationExchangeFormat, PresentationHandler())
+		r.Register(indyFormat, indyHandler)
+
+		attachments := []decorator.Attachment{
+			{ID: "vp", Data: decorator.AttachmentData{JSON: []byte(presentation)}},
+			{ID: "indy", Data: decorator.AttachmentData{
+				Base64: base64.StdEncoding.EncodeToString([]byte("proof"))}},
+		}
+		formats := []Format{{AttachID: "vp", Format: PresentationExchangeFormat}, {AttachID: "indy", Format: indyFormat}}
+
+		o := New(newMockStore())
+
+		proofs, err := r.DecodeAttachments(formats, attachments, o.Payload)
+		require.NoError(t, err)
+		require.Len(t, proofs, 2)
+		require.IsType(t, &verifiable.Presentation{}, proofs["vp"])
+		require.Equal(t, "indy:proof", proofs["indy"])
+
+		other := append(append([]decorator.Attachment{}, attachments...),
+			decorator.Attachment{ID: "other", Data: decorator.AttachmentData{JSON: []byte(`{}`)}})
+
+		_, err = r.DecodeAttachments(append(formats, Format{AttachID: "other", Format: "iso/18013-5@v1.0"}),
+			other, o.Payload)
+		require.True(t, errors.Is(err, ErrUnsupportedFormat))
+		require.Contains(t, err.Error(), "decode attachment other")
+
+		_, err = r.DecodeAttachments(formats, other, o.Payload)
+		require.True(t, errors.Is(err, ErrFormatMismatch))
+		require.Contains(t, err.Error(), "attachment other has no format")
+
+		_, err = r.DecodeAttachments(append(formats, Format{AttachID: "other", Format: indyFormat}), attachments,
+			o.Payload)
+		require.True(t, errors.Is(err, ErrFormatMismatch))
+		require.Contains(t, err.Error(), "format of attachment other has no attachment")
+
+		_, err = r.DecodeAttachments(formats, attachments, func(*decorator.Attachment) ([]byte, error) {
+			return nil, errors.New("payload error")
+		})
+		require.EqualError(t, err, "payload error")
+	})
+}
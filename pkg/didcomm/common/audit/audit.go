@@ -0,0 +1,386 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package audit keeps the audit trail of the outbound protocol messages: the hash of every envelope sent by the
+// agent and the metadata of its message, for the issuers required to prove which messages they sent.
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/common/clock"
+	"github.com/hyperledger/aries-framework-go/pkg/common/idgen"
+	"github.com/hyperledger/aries-framework-go/pkg/storage"
+)
+
+// The records are keyed by timestamp so they are iterated from the oldest, and indexed by thread and by message
+// type so the queries don't scan all the records:
+//
+//	outbound_<timestamp>_<id>              the record
+//	thread_<thread hash>_<timestamp>_<id>  the key of the record, for the queries by thread
+//	type_<type hash>_<timestamp>_<id>      the key of the record, for the queries by message type
+//
+// The thread and the type are hashed so the index keys have the same length, a thread ID isn't the prefix of
+// another one.
+const (
+	// Namespace is the store name space of the audit records
+	Namespace = "audit"
+
+	keyPrefix         = "outbound_"
+	threadIndexPrefix = "thread_"
+	typeIndexPrefix   = "type_"
+)
+
+// ErrQueryNotSupported is returned when the records are queried from a store which isn't a storage.IterableStore
+var ErrQueryNotSupported = errors.New("audit store doesn't support queries")
+
+// errStop stops the iteration of the records
+var errStop = errors.New("stop iteration")
+
+// Record is the audit record of an outbound message
+type Record struct {
+	ID       string `json:"id"`
+	MsgID    string `json:"msgID,omitempty"`
+	MsgType  string `json:"msgType,omitempty"`
+	ThreadID string `json:"thid,omitempty"`
+	// Hash is the hex encoded SHA-256 digest of the packed envelope, as sent to the endpoint. It is empty if the
+	// message couldn't be packed.
+	Hash string `json:"hash,omitempty"`
+	// Endpoint is the endpoint the message was sent to, the last endpoint tried if the message wasn't delivered
+	Endpoint      string    `json:"endpoint,omitempty"`
+	RecipientKeys []string  `json:"recipientKeys,omitempty"`
+	Timestamp     time.Time `json:"timestamp"`
+	// Error is the reason why the message wasn't delivered
+	Error string `json:"error,omitempty"`
+}
+
+// NewRecord returns the audit record of the message sent in the packed envelope, the message type and thread are
+// read from the message. The envelope is nil if the message couldn't be packed.
+func NewRecord(msg interface{}, envelope []byte) (*Record, error) {
+	bytes, err := json.Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal audited message: %w", err)
+	}
+
+	header := &struct {
+		ID     string `json:"@id,omitempty"`
+		Type   string `json:"@type,omitempty"`
+		Thread *struct {
+			ID string `json:"thid,omitempty"`
+		} `json:"~thread,omitempty"`
+	}{}
+
+	// the messages which aren't JSON objects are audited without metadata
+	if json.Unmarshal(bytes, header) != nil {
+		header.ID, header.Type = "", ""
+	}
+
+	record := &Record{MsgID: header.ID, MsgType: header.Type, ThreadID: header.ID}
+
+	if envelope != nil {
+		digest := sha256.Sum256(envelope)
+		record.Hash = hex.EncodeToString(digest[:])
+	}
+
+	if header.Thread != nil && header.Thread.ID != "" {
+		record.ThreadID = header.Thread.ID
+	}
+
+	return record, nil
+}
+
+// Query selects the audit records, the zero values match all the records
+type Query struct {
+	MsgType  string
+	ThreadID string
+	// Since and Until bound the timestamps of the records, both inclusive
+	Since time.Time
+	Until time.Time
+	// Limit is the maximum number of records returned, the oldest records first
+	Limit int
+}
+
+func (q *Query) match(r *Record) bool {
+	return (q.MsgType == "" || q.MsgType == r.MsgType) &&
+		(q.ThreadID == "" || q.ThreadID == r.ThreadID) &&
+		(q.Since.IsZero() || !r.Timestamp.Before(q.Since)) &&
+		(q.Until.IsZero() || !r.Timestamp.After(q.Until))
+}
+
+// Store keeps the audit records
+type Store struct {
+	store     storage.Store
+	retention time.Duration
+	ids       idgen.Generator
+	clock     clock.Clock
+}
+
+// Opt configures the audit store
+type Opt func(s *Store)
+
+// WithRetention removes the records after the duration, the records are expired by the store if it supports
+// storage.ExpiringStore and deleted by Cleanup otherwise. The records are kept forever by default.
+func WithRetention(retention time.Duration) Opt {
+	return func(s *Store) {
+		s.retention = retention
+	}
+}
+
+// WithIDGenerator sets the generator of the IDs of the records, random UUIDs by default
+func WithIDGenerator(g idgen.Generator) Opt {
+	return func(s *Store) {
+		s.ids = g
+	}
+}
+
+// WithClock sets the clock of the timestamps and the retention of the records, the system clock by default
+func WithClock(c clock.Clock) Opt {
+	return func(s *Store) {
+		s.clock = c
+	}
+}
+
+// New returns new audit store opened from the storage provider. The retention requires a store expiring or
+// iterating its records, so the records are removed.
+func New(prov storage.Provider, opts ...Opt) (*Store, error) {
+	store, err := prov.OpenStore(Namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit store: %w", err)
+	}
+
+	s := &Store{store: store, ids: idgen.UUID(), clock: clock.System()}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	_, expiring := store.(storage.ExpiringStore)
+	_, iterable := store.(storage.IterableStore)
+
+	if s.retention > 0 && !expiring && !iterable {
+		return nil, errors.New("audit retention requires a store expiring or iterating its records")
+	}
+
+	return s, nil
+}
+
+// Add stores the record, the ID and the timestamp of the record are set if empty
+func (s *Store) Add(record *Record) error {
+	if record.ID == "" {
+		record.ID = s.ids.NewID()
+	}
+
+	if record.Timestamp.IsZero() {
+		record.Timestamp = s.clock.Now().UTC()
+	}
+
+	bytes, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	k := recordKey(record)
+
+	// the indexes are written first so a record is never stored without its indexes
+	for _, index := range indexKeys(record) {
+		if err = s.put(index, []byte(k)); err != nil {
+			return fmt.Errorf("failed to save audit record index: %w", err)
+		}
+	}
+
+	if err = s.put(k, bytes); err != nil {
+		return fmt.Errorf("failed to save audit record: %w", err)
+	}
+
+	return nil
+}
+
+// put stores the record, expiring with the retention if the store supports it
+func (s *Store) put(k string, v []byte) error {
+	if expiring, ok := s.store.(storage.ExpiringStore); ok && s.retention > 0 {
+		return expiring.PutWithTTL(k, v, s.retention)
+	}
+
+	return s.store.Put(k, v)
+}
+
+// Query returns the records matching the query, the oldest records first. The records are read from the thread
+// index or the message type index if the query selects a thread or a message type.
+func (s *Store) Query(query *Query) ([]*Record, error) {
+	iterable, ok := s.store.(storage.IterableStore)
+	if !ok {
+		return nil, ErrQueryNotSupported
+	}
+
+	prefix, indexed := keyPrefix, true
+
+	switch {
+	case query.ThreadID != "":
+		prefix = indexPrefix(threadIndexPrefix, query.ThreadID)
+	case query.MsgType != "":
+		prefix = indexPrefix(typeIndexPrefix, query.MsgType)
+	default:
+		indexed = false
+	}
+
+	var records []*Record
+
+	err := iterable.Iterate(prefix, func(k string, v []byte) error {
+		if indexed {
+			k = string(v)
+		}
+
+		timestamp := keyTimestamp(k)
+
+		// the keys are ordered by timestamp
+		if !query.Until.IsZero() && timestamp.After(query.Until) {
+			return errStop
+		}
+
+		if s.expired(timestamp) || !query.Since.IsZero() && timestamp.Before(query.Since) {
+			return nil
+		}
+
+		record, err := s.read(k, v, indexed)
+		if err != nil || record == nil || !query.match(record) {
+			return err
+		}
+
+		records = append(records, record)
+
+		if query.Limit > 0 && len(records) == query.Limit {
+			return errStop
+		}
+
+		return nil
+	})
+	if err != nil && !errors.Is(err, errStop) {
+		return nil, fmt.Errorf("failed to query audit records: %w", err)
+	}
+
+	return records, nil
+}
+
+// Cleanup deletes the records older than the retention and returns the number of deleted records, the records
+// of the stores expiring them are skipped as they're removed by the store
+func (s *Store) Cleanup() (int, error) {
+	iterable, ok := s.store.(storage.IterableStore)
+	if !ok || s.retention <= 0 {
+		return 0, nil
+	}
+
+	var expired []*Record
+
+	err := iterable.Iterate(keyPrefix, func(k string, v []byte) error {
+		// the keys are ordered by timestamp, the records following a live record are live
+		if !s.expired(keyTimestamp(k)) {
+			return errStop
+		}
+
+		record, err := s.read(k, v, false)
+		if err != nil {
+			return err
+		}
+
+		expired = append(expired, record)
+
+		return nil
+	})
+	if err != nil && !errors.Is(err, errStop) {
+		return 0, fmt.Errorf("failed to iterate audit records: %w", err)
+	}
+
+	for i, record := range expired {
+		// the record is deleted first so a record is never left without its indexes
+		for _, k := range append([]string{recordKey(record)}, indexKeys(record)...) {
+			if err = s.store.Delete(k); err != nil {
+				return i, fmt.Errorf("failed to delete audit record %s: %w", record.ID, err)
+			}
+		}
+	}
+
+	return len(expired), nil
+}
+
+// read returns the record of the key, v is the record unless it is read from an index. Nil is returned for the
+// indexed records which have been deleted.
+func (s *Store) read(k string, v []byte, indexed bool) (*Record, error) {
+	if indexed {
+		var err error
+
+		v, err = s.store.Get(k)
+		if errors.Is(err, storage.ErrDataNotFound) {
+			return nil, nil
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	record := &Record{}
+	if err := json.Unmarshal(v, record); err != nil {
+		return nil, fmt.Errorf("invalid audit record %s: %w", k, err)
+	}
+
+	return record, nil
+}
+
+func (s *Store) expired(timestamp time.Time) bool {
+	return s.retention > 0 && s.clock.Now().Sub(timestamp) > s.retention
+}
+
+// recordKey returns the key of the record, ordered by timestamp
+func recordKey(r *Record) string {
+	return fmt.Sprintf("%s%s", keyPrefix, keySuffix(r))
+}
+
+// indexKeys returns the keys of the indexes of the record
+func indexKeys(r *Record) []string {
+	var keys []string
+
+	if r.ThreadID != "" {
+		keys = append(keys, indexPrefix(threadIndexPrefix, r.ThreadID)+keySuffix(r))
+	}
+
+	if r.MsgType != "" {
+		keys = append(keys, indexPrefix(typeIndexPrefix, r.MsgType)+keySuffix(r))
+	}
+
+	return keys
+}
+
+func indexPrefix(prefix, value string) string {
+	digest := sha256.Sum256([]byte(value))
+	return prefix + hex.EncodeToString(digest[:]) + "_"
+}
+
+func keySuffix(r *Record) string {
+	return fmt.Sprintf("%020d_%s", r.Timestamp.UnixNano(), r.ID)
+}
+
+// keyTimestamp returns the timestamp of the record key, the zero time if the key is malformed
+func keyTimestamp(k string) time.Time {
+	suffix := strings.TrimPrefix(k, keyPrefix)
+
+	i := strings.Index(suffix, "_")
+	if i < 0 {
+		return time.Time{}
+	}
+
+	nanos, err := strconv.ParseInt(suffix[:i], 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+
+	return time.Unix(0, nanos)
+}
@@ -0,0 +1,300 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hyperledger/aries-framework-go/pkg/common/idgen"
+	mockclock "github.com/hyperledger/aries-framework-go/pkg/internal/mock/clock"
+	mockstorage "github.com/hyperledger/aries-framework-go/pkg/internal/mock/storage"
+	"github.com/hyperledger/aries-framework-go/pkg/storage"
+)
+
+func TestNewRecord(t *testing.T) {
+	t.Run("test message metadata", func(t *testing.T) {
+		record, err := NewRecord(map[string]interface{}{"@id": "id1", "@type": "type1"}, []byte("envelope"))
+		require.NoError(t, err)
+
+		// the envelope is hashed, not the message
+		digest := sha256.Sum256([]byte("envelope"))
+		require.Equal(t, &Record{MsgID: "id1", MsgType: "type1", ThreadID: "id1",
+			Hash: hex.EncodeToString(digest[:])}, record)
+
+		record, err = NewRecord(map[string]interface{}{"@id": "id2", "@type": "type1",
+			"~thread": map[string]interface{}{"thid": "id1"}}, []byte("envelope"))
+		require.NoError(t, err)
+		require.Equal(t, "id1", record.ThreadID)
+		require.Equal(t, "id2", record.MsgID)
+	})
+
+	t.Run("test message without metadata", func(t *testing.T) {
+		record, err := NewRecord([]string{"not", "an", "object"}, []byte("envelope"))
+		require.NoError(t, err)
+		require.Empty(t, record.MsgType)
+		require.Empty(t, record.ThreadID)
+		require.NotEmpty(t, record.Hash)
+	})
+
+	t.Run("test message not packed", func(t *testing.T) {
+		record, err := NewRecord(map[string]interface{}{"@id": "id1"}, nil)
+		require.NoError(t, err)
+		require.Equal(t, "id1", record.MsgID)
+		require.Empty(t, record.Hash)
+	})
+
+	t.Run("test invalid message", func(t *testing.T) {
+		_, err := NewRecord(make(chan int), nil)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to marshal audited message")
+	})
+}
+
+func TestStore(t *testing.T) {
+	t.Run("test add and query", func(t *testing.T) {
+		s, err := New(mockstorage.NewMockStoreProvider())
+		require.NoError(t, err)
+
+		start := time.Date(2019, time.October, 1, 0, 0, 0, 0, time.UTC)
+		for i, r := range []*Record{
+			{MsgType: "type1", ThreadID: "thid1"},
+			{MsgType: "type2", ThreadID: "thid1"},
+			{MsgType: "type1", ThreadID: "thid2"},
+		} {
+			r.Timestamp = start.Add(time.Duration(i) * time.Minute)
+			require.NoError(t, s.Add(r))
+			require.NotEmpty(t, r.ID)
+		}
+
+		records, err := s.Query(&Query{})
+		require.NoError(t, err)
+		require.Len(t, records, 3)
+		require.Equal(t, start, records[0].Timestamp)
+
+		records, err = s.Query(&Query{MsgType: "type1"})
+		require.NoError(t, err)
+		require.Len(t, records, 2)
+
+		records, err = s.Query(&Query{ThreadID: "thid1", MsgType: "type2"})
+		require.NoError(t, err)
+		require.Len(t, records, 1)
+
+		records, err = s.Query(&Query{Since: start.Add(time.Minute), Until: start.Add(time.Minute)})
+		require.NoError(t, err)
+		require.Len(t, records, 1)
+		require.Equal(t, "type2", records[0].MsgType)
+
+		records, err = s.Query(&Query{Limit: 2})
+		require.NoError(t, err)
+		require.Len(t, records, 2)
+		require.Equal(t, "type2", records[1].MsgType)
+
+		records, err = s.Query(&Query{ThreadID: "thid1", Limit: 1})
+		require.NoError(t, err)
+		require.Len(t, records, 1)
+		require.Equal(t, "type1", records[0].MsgType)
+
+		records, err = s.Query(&Query{ThreadID: "thid", MsgType: "type1"})
+		require.NoError(t, err)
+		require.Empty(t, records)
+	})
+
+	t.Run("test indexes", func(t *testing.T) {
+		store := &mockstorage.MockStore{Store: make(map[string][]byte)}
+		s, err := New(&mockstorage.MockStoreProvider{Store: store})
+		require.NoError(t, err)
+
+		require.NoError(t, s.Add(&Record{MsgType: "type1", ThreadID: "thid1"}))
+		require.NoError(t, s.Add(&Record{MsgType: "type2"}))
+
+		threads, types := 0, 0
+
+		for k := range store.Store {
+			switch {
+			case strings.HasPrefix(k, threadIndexPrefix):
+				threads++
+			case strings.HasPrefix(k, typeIndexPrefix):
+				types++
+			}
+		}
+
+		require.Equal(t, 1, threads)
+		require.Equal(t, 2, types)
+
+		// the indexed records which have been deleted are skipped
+		for k := range store.Store {
+			if strings.HasPrefix(k, keyPrefix) {
+				require.NoError(t, store.Delete(k))
+			}
+		}
+
+		records, err := s.Query(&Query{MsgType: "type1"})
+		require.NoError(t, err)
+		require.Empty(t, records)
+	})
+
+	t.Run("test ID generator", func(t *testing.T) {
+		s, err := New(mockstorage.NewMockStoreProvider(), WithIDGenerator(idgen.Sequence("record")))
+		require.NoError(t, err)
+
+		record := &Record{}
+		require.NoError(t, s.Add(record))
+		require.Equal(t, "record-1", record.ID)
+	})
+
+	t.Run("test timestamp set", func(t *testing.T) {
+		s, err := New(mockstorage.NewMockStoreProvider())
+		require.NoError(t, err)
+
+		record := &Record{ID: "id1"}
+		require.NoError(t, s.Add(record))
+		require.Equal(t, "id1", record.ID)
+		require.False(t, record.Timestamp.IsZero())
+	})
+
+	t.Run("test retention", func(t *testing.T) {
+		store := &mockstorage.MockStore{Store: make(map[string][]byte)}
+		s, err := New(&mockstorage.MockStoreProvider{Store: store}, WithRetention(time.Hour))
+		require.NoError(t, err)
+
+		require.NoError(t, s.Add(&Record{ID: "id1"}))
+
+		// the records expire with the retention
+		for k := range store.Store {
+			ttl, e := store.TTL(k)
+			require.NoError(t, e)
+			require.True(t, ttl > 0 && ttl <= time.Hour)
+		}
+
+		// the records older than the retention aren't returned
+		s.clock = &mockclock.Clock{Time: time.Now().Add(2 * time.Hour)}
+
+		records, err := s.Query(&Query{})
+		require.NoError(t, err)
+		require.Empty(t, records)
+
+		// the expiring store removes the records
+		n, err := s.Cleanup()
+		require.NoError(t, err)
+		require.Equal(t, 1, n)
+	})
+
+	t.Run("test cleanup", func(t *testing.T) {
+		store := &mockstorage.MockStore{Store: make(map[string][]byte)}
+		s, err := New(mockstorage.NewMockCustomStoreProvider(&iterableStore{Store: store}), WithRetention(time.Hour))
+		require.NoError(t, err)
+
+		now := time.Now()
+		for i, r := range []*Record{
+			{MsgType: "type1", ThreadID: "thid1"},
+			{MsgType: "type1", ThreadID: "thid2"},
+			{MsgType: "type1"},
+		} {
+			r.Timestamp = now.Add(time.Duration(i-2) * time.Hour)
+			require.NoError(t, s.Add(r))
+		}
+
+		// the records don't expire on a store without expiry
+		for k := range store.Store {
+			ttl, e := store.TTL(k)
+			require.NoError(t, e)
+			require.Zero(t, ttl)
+		}
+
+		n, err := s.Cleanup()
+		require.NoError(t, err)
+		require.Equal(t, 2, n)
+
+		// the expired records are deleted with their indexes
+		require.Len(t, store.Store, 2)
+
+		records, err := s.Query(&Query{MsgType: "type1"})
+		require.NoError(t, err)
+		require.Len(t, records, 1)
+
+		n, err = s.Cleanup()
+		require.NoError(t, err)
+		require.Zero(t, n)
+
+		t.Run("test without retention", func(t *testing.T) {
+			s, err = New(mockstorage.NewMockCustomStoreProvider(&iterableStore{Store: store}))
+			require.NoError(t, err)
+
+			n, err = s.Cleanup()
+			require.NoError(t, err)
+			require.Zero(t, n)
+		})
+
+		t.Run("test delete error", func(t *testing.T) {
+			s, err = New(mockstorage.NewMockCustomStoreProvider(&iterableStore{Store: store}), WithRetention(time.Hour))
+			require.NoError(t, err)
+
+			s.clock = &mockclock.Clock{Time: now.Add(2 * time.Hour)}
+			store.ErrDelete = errors.New("delete error")
+
+			_, err = s.Cleanup()
+			require.Error(t, err)
+			require.Contains(t, err.Error(), "delete error")
+		})
+
+		t.Run("test invalid record", func(t *testing.T) {
+			store.Store[keyPrefix+"1_id"] = []byte("{")
+
+			_, err = s.Cleanup()
+			require.Error(t, err)
+			require.Contains(t, err.Error(), "invalid audit record")
+		})
+	})
+
+	t.Run("test retention without store support", func(t *testing.T) {
+		_, err := New(mockstorage.NewMockCustomStoreProvider(&plainStore{}), WithRetention(time.Hour))
+		require.EqualError(t, err, "audit retention requires a store expiring or iterating its records")
+	})
+
+	t.Run("test store errors", func(t *testing.T) {
+		_, err := New(&mockstorage.MockStoreProvider{ErrOpenStoreHandle: errors.New("open error")})
+		require.EqualError(t, err, "failed to open audit store: open error")
+
+		s, err := New(&mockstorage.MockStoreProvider{Store: &mockstorage.MockStore{Store: make(map[string][]byte),
+			ErrPut: errors.New("put error")}})
+		require.NoError(t, err)
+		require.EqualError(t, s.Add(&Record{}), "failed to save audit record: put error")
+		require.EqualError(t, s.Add(&Record{MsgType: "type1"}), "failed to save audit record index: put error")
+
+		s, err = New(&mockstorage.MockStoreProvider{Store: &mockstorage.MockStore{
+			Store: map[string][]byte{keyPrefix + "1": []byte("{")}}})
+		require.NoError(t, err)
+		_, err = s.Query(&Query{})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid audit record")
+
+		s.store = &plainStore{}
+		_, err = s.Query(&Query{})
+		require.True(t, errors.Is(err, ErrQueryNotSupported))
+	})
+}
+
+// plainStore only implements storage.Store
+type plainStore struct {
+	storage.Store
+}
+
+// iterableStore hides the expiry of the mock store
+type iterableStore struct {
+	storage.Store
+}
+
+func (s *iterableStore) Iterate(prefix string, fn func(k string, v []byte) error) error {
+	return s.Store.(storage.IterableStore).Iterate(prefix, fn)
+}
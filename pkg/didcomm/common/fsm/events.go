@@ -0,0 +1,46 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package fsm
+
+import (
+	"errors"
+
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/service"
+)
+
+// ErrNoActionClient is returned when an action event is emitted and no clients are registered to handle it
+var ErrNoActionClient = errors.New("no clients are registered to handle the message")
+
+// Events registers the action and message event clients of a protocol service and emits the events
+type Events struct {
+	service.Action
+	service.Message
+	// ProtocolName is set on the emitted events
+	ProtocolName string
+}
+
+// SendActionEvent triggers the action event
+func (e *Events) SendActionEvent(action service.DIDCommAction) error {
+	aEvent := e.GetActionEvent()
+	if aEvent == nil {
+		return ErrNoActionClient
+	}
+
+	action.ProtocolName = e.ProtocolName
+	aEvent <- action
+
+	return nil
+}
+
+// SendMsgEvents triggers the message events
+func (e *Events) SendMsgEvents(msg *service.StateMsg) {
+	msg.ProtocolName = e.ProtocolName
+
+	for _, handler := range e.GetMsgEvents() {
+		handler <- *msg
+	}
+}
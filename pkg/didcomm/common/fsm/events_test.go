@@ -0,0 +1,53 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package fsm
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/service"
+)
+
+func TestEvents(t *testing.T) {
+	t.Run("test send action event", func(t *testing.T) {
+		e := &Events{ProtocolName: "protocol"}
+
+		err := e.SendActionEvent(service.DIDCommAction{})
+		require.True(t, errors.Is(err, ErrNoActionClient))
+
+		actionCh := make(chan service.DIDCommAction, 1)
+		require.NoError(t, e.RegisterActionEvent(actionCh))
+
+		require.NoError(t, e.SendActionEvent(service.DIDCommAction{Properties: "properties"}))
+
+		action := <-actionCh
+		require.Equal(t, "protocol", action.ProtocolName)
+		require.Equal(t, "properties", action.Properties)
+	})
+
+	t.Run("test send message events", func(t *testing.T) {
+		e := &Events{ProtocolName: "protocol"}
+
+		e.SendMsgEvents(&service.StateMsg{StateID: "start"})
+
+		msgCh1 := make(chan service.StateMsg, 1)
+		msgCh2 := make(chan service.StateMsg, 1)
+		require.NoError(t, e.RegisterMsgEvent(msgCh1))
+		require.NoError(t, e.RegisterMsgEvent(msgCh2))
+
+		e.SendMsgEvents(&service.StateMsg{Type: service.PostState, StateID: "done"})
+
+		for _, ch := range []chan service.StateMsg{msgCh1, msgCh2} {
+			msg := <-ch
+			require.Equal(t, "protocol", msg.ProtocolName)
+			require.Equal(t, "done", msg.StateID)
+		}
+	})
+}
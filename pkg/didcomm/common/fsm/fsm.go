@@ -0,0 +1,141 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package fsm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/hyperledger/aries-framework-go/pkg/common/idgen"
+	"github.com/hyperledger/aries-framework-go/pkg/storage"
+)
+
+// State of a protocol thread
+type State interface {
+	// Name of this state.
+	Name() string
+	// Whether this state allows transitioning into the next state.
+	CanTransitionTo(next State) bool
+}
+
+// StateFromName returns the protocol state representing the name
+type StateFromName func(name string) (State, error)
+
+// Guard is checked before the thread transitions from the current state to the next state,
+// the transition is rejected if an error is returned
+type Guard func(thid string, current, next State) error
+
+// Machine is the state machine of the protocol threads. The state of every thread is checkpointed in the
+// protocol store under the thread ID, the data of the actions waiting for the clients under the action ID.
+type Machine struct {
+	store     storage.Store
+	initial   State
+	stateFrom StateFromName
+	guards    []Guard
+	ids       idgen.Generator
+}
+
+// Opt is a state machine option
+type Opt func(m *Machine)
+
+// WithGuard adds the guard checked on every state transition
+func WithGuard(guard Guard) Opt {
+	return func(m *Machine) {
+		m.guards = append(m.guards, guard)
+	}
+}
+
+// WithIDGenerator sets the generator of the action IDs, random UUIDs by default
+func WithIDGenerator(g idgen.Generator) Opt {
+	return func(m *Machine) {
+		m.ids = g
+	}
+}
+
+// New returns new state machine checkpointing the threads in the store, new threads are in the initial state
+func New(store storage.Store, initial State, stateFrom StateFromName, opts ...Opt) *Machine {
+	m := &Machine{store: store, initial: initial, stateFrom: stateFrom, ids: idgen.UUID()}
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return m
+}
+
+// Current returns the checkpointed state of the thread, the initial state is returned for new threads
+func (m *Machine) Current(thid string) (State, error) {
+	name, err := m.store.Get(thid)
+	if err != nil {
+		if errors.Is(err, storage.ErrDataNotFound) {
+			return m.initial, nil
+		}
+
+		return nil, fmt.Errorf("cannot fetch state from store: thid=%s err=%w", thid, err)
+	}
+
+	return m.stateFrom(string(name))
+}
+
+// Transition checks the thread can transition to the next state and returns the current state of the thread
+func (m *Machine) Transition(thid string, next State) (State, error) {
+	current, err := m.Current(thid)
+	if err != nil {
+		return nil, err
+	}
+
+	if !current.CanTransitionTo(next) {
+		return nil, fmt.Errorf("invalid state transition: %s -> %s", current.Name(), next.Name())
+	}
+
+	for _, guard := range m.guards {
+		if err := guard(thid, current, next); err != nil {
+			return nil, fmt.Errorf("state transition %s -> %s rejected: %w", current.Name(), next.Name(), err)
+		}
+	}
+
+	return current, nil
+}
+
+// Checkpoint persists the state of the thread
+func (m *Machine) Checkpoint(thid string, state State) error {
+	if err := m.store.Put(thid, []byte(state.Name())); err != nil {
+		return fmt.Errorf("failed to write to store: %w", err)
+	}
+
+	return nil
+}
+
+// SaveAction persists the data of the action waiting for the client and returns the action ID
+func (m *Machine) SaveAction(data interface{}) (string, error) {
+	bytes, err := json.Marshal(data)
+	if err != nil {
+		return "", fmt.Errorf("JSON marshalling of action failed: %w", err)
+	}
+
+	id := m.ids.NewID()
+
+	if err := m.store.Put(id, bytes); err != nil {
+		return "", fmt.Errorf("failed to save action: %w", err)
+	}
+
+	return id, nil
+}
+
+// LoadAction reads the data of the action into the value pointed to by data
+func (m *Machine) LoadAction(id string, data interface{}) error {
+	bytes, err := m.store.Get(id)
+	if err != nil {
+		return fmt.Errorf("action %s doesn't exist in the store: %w", id, err)
+	}
+
+	if err := json.Unmarshal(bytes, data); err != nil {
+		return fmt.Errorf("JSON unmarshalling of action failed: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,159 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package fsm
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hyperledger/aries-framework-go/pkg/common/idgen"
+	mockstorage "github.com/hyperledger/aries-framework-go/pkg/internal/mock/storage"
+)
+
+const (
+	stateNameStart = "start"
+	stateNameDone  = "done"
+)
+
+type start struct{}
+
+func (s *start) Name() string {
+	return stateNameStart
+}
+
+func (s *start) CanTransitionTo(next State) bool {
+	return next.Name() == stateNameDone
+}
+
+type done struct{}
+
+func (s *done) Name() string {
+	return stateNameDone
+}
+
+func (s *done) CanTransitionTo(_ State) bool {
+	return false
+}
+
+func stateFromName(name string) (State, error) {
+	switch name {
+	case stateNameStart:
+		return &start{}, nil
+	case stateNameDone:
+		return &done{}, nil
+	default:
+		return nil, fmt.Errorf("invalid state name %s", name)
+	}
+}
+
+func TestMachine_Transition(t *testing.T) {
+	store := &mockstorage.MockStore{Store: make(map[string][]byte)}
+	m := New(store, &start{}, stateFromName)
+
+	t.Run("test initial state of new thread", func(t *testing.T) {
+		current, err := m.Transition("thid1", &done{})
+		require.NoError(t, err)
+		require.Equal(t, stateNameStart, current.Name())
+	})
+
+	t.Run("test checkpointed state", func(t *testing.T) {
+		require.NoError(t, m.Checkpoint("thid2", &done{}))
+
+		current, err := m.Current("thid2")
+		require.NoError(t, err)
+		require.Equal(t, stateNameDone, current.Name())
+
+		_, err = m.Transition("thid2", &start{})
+		require.EqualError(t, err, "invalid state transition: done -> start")
+	})
+
+	t.Run("test invalid checkpointed state", func(t *testing.T) {
+		require.NoError(t, store.Put("thid3", []byte("unknown")))
+
+		_, err := m.Transition("thid3", &done{})
+		require.EqualError(t, err, "invalid state name unknown")
+	})
+
+	t.Run("test guard", func(t *testing.T) {
+		m := New(store, &start{}, stateFromName, WithGuard(func(thid string, current, next State) error {
+			if thid == "rejected" {
+				return errors.New("guard error")
+			}
+
+			return nil
+		}))
+
+		_, err := m.Transition("accepted", &done{})
+		require.NoError(t, err)
+
+		_, err = m.Transition("rejected", &done{})
+		require.EqualError(t, err, "state transition start -> done rejected: guard error")
+	})
+
+	t.Run("test store errors", func(t *testing.T) {
+		store := &mockstorage.MockStore{Store: make(map[string][]byte), ErrPut: errors.New("put error")}
+		m := New(store, &start{}, stateFromName)
+
+		err := m.Checkpoint("thid1", &done{})
+		require.EqualError(t, err, "failed to write to store: put error")
+
+		store.Store["thid1"] = []byte(stateNameStart)
+		store.ErrGet = errors.New("get error")
+
+		_, err = m.Transition("thid1", &done{})
+		require.EqualError(t, err, "cannot fetch state from store: thid=thid1 err=get error")
+	})
+}
+
+func TestMachine_Action(t *testing.T) {
+	type action struct {
+		ThreadID string
+		Next     string
+	}
+
+	t.Run("test save and load", func(t *testing.T) {
+		m := New(&mockstorage.MockStore{Store: make(map[string][]byte)}, &start{}, stateFromName)
+
+		id, err := m.SaveAction(&action{ThreadID: "thid1", Next: stateNameDone})
+		require.NoError(t, err)
+		require.NotEmpty(t, id)
+
+		loaded := &action{}
+		require.NoError(t, m.LoadAction(id, loaded))
+		require.Equal(t, &action{ThreadID: "thid1", Next: stateNameDone}, loaded)
+
+		err = m.LoadAction("unknown", loaded)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "action unknown doesn't exist in the store")
+
+		m = New(&mockstorage.MockStore{Store: make(map[string][]byte)}, &start{}, stateFromName,
+			WithIDGenerator(idgen.Sequence("action")))
+		id, err = m.SaveAction(&action{ThreadID: "thid1", Next: stateNameDone})
+		require.NoError(t, err)
+		require.Equal(t, "action-1", id)
+	})
+
+	t.Run("test invalid action", func(t *testing.T) {
+		store := &mockstorage.MockStore{Store: map[string][]byte{"id1": []byte("invalid")}}
+		m := New(store, &start{}, stateFromName)
+
+		_, err := m.SaveAction(make(chan int))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "JSON marshalling of action failed")
+
+		err = m.LoadAction("id1", &action{})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "JSON unmarshalling of action failed")
+
+		store.ErrPut = errors.New("put error")
+		_, err = m.SaveAction(&action{})
+		require.EqualError(t, err, "failed to save action: put error")
+	})
+}
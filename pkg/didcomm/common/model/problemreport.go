@@ -0,0 +1,63 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package model
+
+import (
+	"strings"
+
+	"github.com/hyperledger/aries-framework-go/pkg/common/metadata"
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/protocol/decorator"
+)
+
+// ProblemReportMsgType is the message type of the generic problem report, which may be sent for any protocol thread
+const ProblemReportMsgType = metadata.AriesCommunityDID + ";spec/notification/1.0/problem-report"
+
+// ProblemReport problem report struct
+type ProblemReport struct {
+	Type        string             `json:"@type,omitempty"`
+	ID          string             `json:"@id,omitempty"`
+	Description ProblemDescription `json:"description,omitempty"`
+	ExplainLTXT string             `json:"explain_ltxt,omitempty"`
+	Thread      *decorator.Thread  `json:"~thread,omitempty"`
+}
+
+// ProblemDescription holds the problem code and its human readable description
+type ProblemDescription struct {
+	Code string `json:"code,omitempty"`
+	Text string `json:"en,omitempty"`
+}
+
+// Explain returns the explanation of the problem
+func (r *ProblemReport) Explain() string {
+	if r.ExplainLTXT != "" {
+		return r.ExplainLTXT
+	}
+
+	return r.Description.Text
+}
+
+// IsProblemReport checks if the message type is the generic problem report or the problem report of a protocol
+func IsProblemReport(msgType string) bool {
+	return msgType == ProblemReportMsgType ||
+		strings.HasSuffix(msgType, "/problem-report") || strings.HasSuffix(msgType, "/problem_report")
+}
+
+// ProblemReportError is returned when an inbound message is rejected, the problem report explains the
+// rejection to the sender
+type ProblemReportError struct {
+	Report *ProblemReport
+	Err    error
+}
+
+func (e *ProblemReportError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the cause of the rejection
+func (e *ProblemReportError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,38 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package model
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProblemReport(t *testing.T) {
+	t.Run("test explain", func(t *testing.T) {
+		r := &ProblemReport{Description: ProblemDescription{Code: "code", Text: "description"}}
+		require.Equal(t, "description", r.Explain())
+
+		r.ExplainLTXT = "explanation"
+		require.Equal(t, "explanation", r.Explain())
+	})
+
+	t.Run("test problem report message types", func(t *testing.T) {
+		require.True(t, IsProblemReport(ProblemReportMsgType))
+		require.True(t, IsProblemReport("https://didcomm.org/didexchange/1.0/problem_report"))
+		require.True(t, IsProblemReport("https://didcomm.org/issue-credential/1.0/problem-report"))
+		require.False(t, IsProblemReport("https://didcomm.org/didexchange/1.0/request"))
+	})
+	t.Run("test problem report error", func(t *testing.T) {
+		cause := errors.New("rejected")
+		err := &ProblemReportError{Report: &ProblemReport{Type: ProblemReportMsgType}, Err: cause}
+
+		require.EqualError(t, err, "rejected")
+		require.True(t, errors.Is(err, cause))
+	})
+}
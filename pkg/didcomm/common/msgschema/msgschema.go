@@ -0,0 +1,122 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package msgschema
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/xeipuuv/gojsonschema"
+
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/model"
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/protocol/decorator"
+)
+
+// InvalidMessageCode is the code of the problem reports of the messages not valid against their schemas
+const InvalidMessageCode = "invalid-message"
+
+// ErrInvalidMessage is returned when an inbound message is not valid against the schema of its type
+var ErrInvalidMessage = errors.New("invalid message")
+
+// ValidationError lists the violations of the schema of the message type by the message
+type ValidationError struct {
+	MsgType    string
+	Violations []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s message not valid: %s", e.MsgType, strings.Join(e.Violations, "; "))
+}
+
+// Unwrap returns ErrInvalidMessage
+func (e *ValidationError) Unwrap() error {
+	return ErrInvalidMessage
+}
+
+// Registry holds the JSON schemas of the protocol message types the inbound messages are validated against
+// before the protocol state machines consume them
+type Registry struct {
+	mutex   sync.RWMutex
+	schemas map[string]*gojsonschema.Schema
+}
+
+// New returns the registry of the JSON schemas of the message types
+func New(schemas map[string]string) (*Registry, error) {
+	r := &Registry{schemas: make(map[string]*gojsonschema.Schema)}
+
+	for msgType, schema := range schemas {
+		if err := r.Register(msgType, schema); err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+// Register adds or replaces the JSON schema of the message type
+func (r *Registry) Register(msgType, schema string) error {
+	compiled, err := gojsonschema.NewSchema(gojsonschema.NewStringLoader(schema))
+	if err != nil {
+		return fmt.Errorf("load schema of %s: %w", msgType, err)
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.schemas[msgType] = compiled
+
+	return nil
+}
+
+// Validate validates the payload against the schema of the message type, the violations are reported by the
+// ValidationError. The messages of the types without schema are valid.
+func (r *Registry) Validate(msgType string, payload []byte) error {
+	r.mutex.RLock()
+	schema, ok := r.schemas[msgType]
+	r.mutex.RUnlock()
+
+	if !ok {
+		return nil
+	}
+
+	result, err := schema.Validate(gojsonschema.NewBytesLoader(payload))
+	if err != nil {
+		return &ValidationError{MsgType: msgType, Violations: []string{err.Error()}}
+	}
+
+	if result.Valid() {
+		return nil
+	}
+
+	violations := make([]string, len(result.Errors()))
+	for i, desc := range result.Errors() {
+		violations[i] = desc.String()
+	}
+
+	return &ValidationError{MsgType: msgType, Violations: violations}
+}
+
+// ProblemReportError returns the error rejecting the invalid message with the problem report of the type and ID
+// on the thread, the problem report explains the violations to the sender
+func ProblemReportError(reportType, id, thid string, err error) *model.ProblemReportError {
+	report := &model.ProblemReport{
+		Type: reportType,
+		ID:   id,
+		Description: model.ProblemDescription{
+			Code: InvalidMessageCode,
+			Text: err.Error(),
+		},
+	}
+
+	if thid != "" {
+		report.Thread = &decorator.Thread{ID: thid}
+	}
+
+	return &model.ProblemReportError{Report: report, Err: err}
+}
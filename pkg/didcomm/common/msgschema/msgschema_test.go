@@ -0,0 +1,94 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package msgschema
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	offerType   = "https://didcomm.org/issue-credential/1.0/offer-credential"
+	offerSchema = `{
+  "type": "object",
+  "required": ["@id", "offers~attach"],
+  "properties": {
+    "@id": {"type": "string"},
+    "comment": {"type": "string"},
+    "offers~attach": {"type": "array", "minItems": 1}
+  }
+}`
+)
+
+func TestRegistry_Validate(t *testing.T) {
+	registry, err := New(map[string]string{offerType: offerSchema})
+	require.NoError(t, err)
+
+	t.Run("test valid message", func(t *testing.T) {
+		require.NoError(t, registry.Validate(offerType, []byte(`{"@id": "1", "offers~attach": [{}]}`)))
+	})
+
+	t.Run("test message type without schema", func(t *testing.T) {
+		require.NoError(t, registry.Validate("https://didcomm.org/trust_ping/1.0/ping", []byte(`{}`)))
+	})
+
+	t.Run("test invalid message", func(t *testing.T) {
+		err := registry.Validate(offerType, []byte(`{"comment": 1, "offers~attach": []}`))
+		require.Error(t, err)
+		require.True(t, errors.Is(err, ErrInvalidMessage))
+
+		validationErr := &ValidationError{}
+		require.True(t, errors.As(err, &validationErr))
+		require.Equal(t, offerType, validationErr.MsgType)
+		require.Len(t, validationErr.Violations, 3)
+		require.Contains(t, err.Error(), "(root): @id is required")
+	})
+
+	t.Run("test malformed message", func(t *testing.T) {
+		err := registry.Validate(offerType, []byte(`{"@id": `))
+		require.Error(t, err)
+		require.True(t, errors.Is(err, ErrInvalidMessage))
+	})
+
+	t.Run("test register schema", func(t *testing.T) {
+		r, err := New(nil)
+		require.NoError(t, err)
+
+		require.NoError(t, r.Register(offerType, `{"required": ["credential_preview"]}`))
+		require.Error(t, r.Validate(offerType, []byte(`{}`)))
+
+		require.NoError(t, r.Register(offerType, `{}`))
+		require.NoError(t, r.Validate(offerType, []byte(`{}`)))
+	})
+
+	t.Run("test invalid schema", func(t *testing.T) {
+		_, err := New(map[string]string{offerType: "invalid"})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "load schema of "+offerType)
+	})
+}
+
+func TestProblemReportError(t *testing.T) {
+	cause := &ValidationError{MsgType: offerType, Violations: []string{"(root): @id is required"}}
+
+	t.Run("test threaded problem report", func(t *testing.T) {
+		err := ProblemReportError("problem-report", "report-1", "thid", cause)
+		require.Equal(t, "problem-report", err.Report.Type)
+		require.Equal(t, "report-1", err.Report.ID)
+		require.Equal(t, InvalidMessageCode, err.Report.Description.Code)
+		require.Equal(t, cause.Error(), err.Report.Description.Text)
+		require.Equal(t, "thid", err.Report.Thread.ID)
+		require.True(t, errors.Is(err, ErrInvalidMessage))
+	})
+
+	t.Run("test problem report without thread", func(t *testing.T) {
+		err := ProblemReportError("problem-report", "report-2", "", cause)
+		require.Nil(t, err.Report.Thread)
+	})
+}
@@ -104,3 +104,16 @@ func AutoExecuteActionEvent(ch chan DIDCommAction) error {
 
 	return nil
 }
+
+// ProblemReportEvent is implemented by the properties of the message events triggered by the problem reports
+// received for the protocol threads. The stored state of the thread is marked as failed.
+type ProblemReportEvent interface {
+	// Code of the problem
+	Code() string
+
+	// Explain returns the human readable explanation of the problem
+	Explain() string
+
+	// ThreadID of the failed thread
+	ThreadID() string
+}
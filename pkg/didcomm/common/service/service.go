@@ -6,9 +6,16 @@ SPDX-License-Identifier: Apache-2.0
 
 package service
 
-// Handler provides protocol service handle api.
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// Handler provides protocol service handle api. The context is the context of the caller, e.g. of
+// the inbound transport request, and is passed to the outbound sends done while handling the message.
 type Handler interface {
-	Handle(msg *DIDCommMsg) error
+	Handle(ctx context.Context, msg *DIDCommMsg) error
 }
 
 // DIDComm defines service APIs.
@@ -32,6 +39,29 @@ type DIDCommMsg struct {
 	OutboundDestination *Destination
 	// ToVerKeys are recipient keys
 	ToVerKeys []string
+	// EncryptionAlgs are the content encryption algorithms the sender of the inbound message is known to support
+	EncryptionAlgs []string
+	// Metadata is the metadata of the envelope the inbound message was received in
+	Metadata *EnvelopeMetadata
+}
+
+// EnvelopeMetadata is the metadata of the envelope of an inbound message. The keys are taken from the
+// envelope, services should check them against the connection instead of trusting the DIDs of the payload.
+type EnvelopeMetadata struct {
+	// SenderVerKey is the sender key of the envelope, empty for anonymous envelopes
+	SenderVerKey string
+	// RecipientVerKey is the key the envelope was decrypted with
+	RecipientVerKey string
+	// Transport is the inbound transport the envelope was received on, e.g. http
+	Transport string
+	// ReceivedTime is the time the envelope was received by the transport
+	ReceivedTime time.Time
+	// Profile is the profile the envelope was received for in the multi-profile deployments
+	Profile string
+	// Codec is the codec of the payload, the payload is converted to JSON before being handled
+	Codec string
+	// Format is the format of the envelope announced by its protected header, e.g. JWM/1.0
+	Format string
 }
 
 // Destination provides the recipientKeys, routingKeys, and serviceEndpoint populated from Invitation
@@ -39,4 +69,48 @@ type Destination struct {
 	RecipientKeys   []string
 	ServiceEndpoint string
 	RoutingKeys     []string
+	// EncryptionAlgs are the content encryption algorithms supported by the recipient, if known
+	EncryptionAlgs []string
+	// Endpoints are the alternative service endpoints of the recipient, tried when the service endpoint fails
+	Endpoints []Endpoint `json:",omitempty"`
+	// Codecs are the payload codecs supported by the recipient in preference order, JSON if not known
+	Codecs []string `json:",omitempty"`
+	// Compression is the payload compression accepted by the recipient, e.g. DEF, the payloads are sent
+	// uncompressed if empty
+	Compression string `json:",omitempty"`
+}
+
+// Endpoint is a service endpoint of a destination
+type Endpoint struct {
+	URI string
+	// Priority orders the endpoints, the endpoints with the lowest priority are tried first
+	Priority int `json:",omitempty"`
+}
+
+// ServiceEndpoints returns the service endpoint followed by the alternative endpoints by priority
+func (d *Destination) ServiceEndpoints() []string {
+	endpoints := make([]Endpoint, len(d.Endpoints))
+	copy(endpoints, d.Endpoints)
+
+	sort.SliceStable(endpoints, func(i, j int) bool {
+		return endpoints[i].Priority < endpoints[j].Priority
+	})
+
+	var uris []string
+
+	seen := make(map[string]bool)
+
+	if d.ServiceEndpoint != "" {
+		uris = append(uris, d.ServiceEndpoint)
+		seen[d.ServiceEndpoint] = true
+	}
+
+	for _, e := range endpoints {
+		if e.URI != "" && !seen[e.URI] {
+			uris = append(uris, e.URI)
+			seen[e.URI] = true
+		}
+	}
+
+	return uris
 }
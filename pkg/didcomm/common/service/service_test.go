@@ -0,0 +1,27 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDestination_ServiceEndpoints(t *testing.T) {
+	t.Run("test service endpoint only", func(t *testing.T) {
+		require.Equal(t, []string{"http://a"}, (&Destination{ServiceEndpoint: "http://a"}).ServiceEndpoints())
+		require.Empty(t, (&Destination{}).ServiceEndpoints())
+	})
+
+	t.Run("test alternative endpoints by priority", func(t *testing.T) {
+		des := &Destination{ServiceEndpoint: "http://a", Endpoints: []Endpoint{
+			{URI: "http://c", Priority: 2}, {URI: "http://a", Priority: 1}, {URI: "http://b", Priority: 1}, {URI: ""},
+		}}
+		require.Equal(t, []string{"http://a", "http://b", "http://c"}, des.ServiceEndpoints())
+	})
+}
@@ -0,0 +1,335 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package threads
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/common/clock"
+	"github.com/hyperledger/aries-framework-go/pkg/storage"
+)
+
+const (
+	// Namespace is the store name space of the thread records
+	Namespace = "threads"
+
+	keyPattern = "%s_%s"
+	keyPrefix  = "thread"
+	indexKey   = "thread_index"
+)
+
+// Record maps the thread to the connection, the parent thread and the protocol the thread belongs to
+type Record struct {
+	ThreadID string `json:"thid"`
+	// ParentThreadID is the ID of the thread which started this one (~thread.pthid), if any
+	ParentThreadID string    `json:"pthid,omitempty"`
+	ConnectionID   string    `json:"connectionID,omitempty"`
+	Protocol       string    `json:"protocol"`
+	Completed      bool      `json:"completed,omitempty"`
+	Created        time.Time `json:"created"`
+	Updated        time.Time `json:"updated"`
+	// TheirVerKeys are the keys of the counterparty DID, the inbound messages of the thread are sent with
+	TheirVerKeys []string `json:"theirVerKeys,omitempty"`
+}
+
+// Store keeps the thread records of all the protocol services
+type Store struct {
+	store           storage.Store
+	expiry          time.Duration
+	removeCompleted bool
+	clock           clock.Clock
+	lock            sync.Mutex
+}
+
+// Opt is a thread store option, used to configure the cleanup policies
+type Opt func(s *Store)
+
+// WithExpiry removes the threads which have not been updated for the duration
+func WithExpiry(expiry time.Duration) Opt {
+	return func(s *Store) {
+		s.expiry = expiry
+	}
+}
+
+// WithCompletedRemoval removes the threads as soon as they are completed. The records of the connections, i.e. the
+// threads of their own connection, are kept since the inbound messages are verified against their counterparty keys.
+func WithCompletedRemoval() Opt {
+	return func(s *Store) {
+		s.removeCompleted = true
+	}
+}
+
+// WithClock sets the clock of the expiry of the threads
+func WithClock(c clock.Clock) Opt {
+	return func(s *Store) {
+		s.clock = c
+	}
+}
+
+// New returns new thread store opened from the storage provider
+func New(prov storage.Provider, opts ...Opt) (*Store, error) {
+	store, err := prov.OpenStore(Namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open thread store: %w", err)
+	}
+
+	s := &Store{store: store, clock: clock.System()}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s, nil
+}
+
+// Save saves the thread record, the creation time and the counterparty keys of an existing thread are kept
+// unless the keys are set on the record
+func (s *Store) Save(record *Record) error {
+	if record.ThreadID == "" {
+		return errors.New("thread ID is mandatory")
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	r := *record
+	r.Updated = s.clock.Now()
+
+	existing, err := s.get(r.ThreadID)
+
+	switch {
+	case err == nil:
+		r.Created = existing.Created
+
+		if len(r.TheirVerKeys) == 0 {
+			r.TheirVerKeys = existing.TheirVerKeys
+		}
+	case errors.Is(err, storage.ErrDataNotFound):
+		r.Created = r.Updated
+
+		if err := s.addToIndex(r.ThreadID); err != nil {
+			return err
+		}
+	default:
+		return err
+	}
+
+	if r.Completed && s.removeCompleted && r.ConnectionID != r.ThreadID {
+		return s.remove(r.ThreadID)
+	}
+
+	return s.put(&r)
+}
+
+// Get returns the thread record, storage.ErrDataNotFound is returned for unknown, removed and expired threads
+func (s *Store) Get(threadID string) (*Record, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.get(threadID)
+}
+
+// Complete marks the thread as completed
+func (s *Store) Complete(threadID string) error {
+	r, err := s.Get(threadID)
+	if err != nil {
+		return err
+	}
+
+	r.Completed = true
+
+	return s.Save(r)
+}
+
+// Remove removes the thread record
+func (s *Store) Remove(threadID string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.remove(threadID)
+}
+
+// ChildThreads returns the records of the threads started from the parent thread
+func (s *Store) ChildThreads(parentThreadID string) ([]*Record, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	ids, err := s.index()
+	if err != nil {
+		return nil, err
+	}
+
+	var children []*Record
+
+	for _, id := range ids {
+		r, err := s.get(id)
+		if err != nil {
+			if errors.Is(err, storage.ErrDataNotFound) {
+				continue
+			}
+
+			return nil, err
+		}
+
+		if r.ParentThreadID == parentThreadID {
+			children = append(children, r)
+		}
+	}
+
+	return children, nil
+}
+
+// Cleanup removes the expired threads, and the completed ones if completed threads are removed,
+// returns the number of removed threads
+func (s *Store) Cleanup() (int, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	ids, err := s.index()
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		kept    []string
+		removed int
+	)
+
+	for _, id := range ids {
+		r, err := s.read(id)
+		if err != nil {
+			if errors.Is(err, storage.ErrDataNotFound) {
+				// already removed, drop it from the index
+				continue
+			}
+
+			return removed, err
+		}
+
+		if s.expired(r) || (r.Completed && s.removeCompleted) {
+			if err := s.remove(id); err != nil {
+				return removed, fmt.Errorf("failed to remove thread %s: %w", id, err)
+			}
+
+			removed++
+
+			continue
+		}
+
+		kept = append(kept, id)
+	}
+
+	return removed, s.putIndex(kept)
+}
+
+func (s *Store) get(threadID string) (*Record, error) {
+	r, err := s.read(threadID)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.expired(r) {
+		return nil, storage.ErrDataNotFound
+	}
+
+	return r, nil
+}
+
+// read reads the thread record, removed records are stored empty as the store can't delete records
+func (s *Store) read(threadID string) (*Record, error) {
+	bytes, err := s.store.Get(recordKey(threadID))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(bytes) == 0 {
+		return nil, storage.ErrDataNotFound
+	}
+
+	r := &Record{}
+
+	err = json.Unmarshal(bytes, r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal thread record: %w", err)
+	}
+
+	return r, nil
+}
+
+func (s *Store) put(r *Record) error {
+	bytes, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("failed to marshal thread record: %w", err)
+	}
+
+	// the expiring stores drop the expired records by themselves
+	if expiring, ok := s.store.(storage.ExpiringStore); ok && s.expiry > 0 {
+		return expiring.PutWithTTL(recordKey(r.ThreadID), bytes, s.expiry)
+	}
+
+	return s.store.Put(recordKey(r.ThreadID), bytes)
+}
+
+// remove stores the empty record, nil values are rejected by some stores
+func (s *Store) remove(threadID string) error {
+	return s.store.Put(recordKey(threadID), []byte{})
+}
+
+func (s *Store) expired(r *Record) bool {
+	return s.expiry > 0 && s.clock.Now().Sub(r.Updated) > s.expiry
+}
+
+func (s *Store) index() ([]string, error) {
+	bytes, err := s.store.Get(indexKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrDataNotFound) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	var ids []string
+
+	err = json.Unmarshal(bytes, &ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal thread index: %w", err)
+	}
+
+	return ids, nil
+}
+
+func (s *Store) addToIndex(threadID string) error {
+	ids, err := s.index()
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		if id == threadID {
+			return nil
+		}
+	}
+
+	return s.putIndex(append(ids, threadID))
+}
+
+func (s *Store) putIndex(ids []string) error {
+	bytes, err := json.Marshal(ids)
+	if err != nil {
+		return fmt.Errorf("failed to marshal thread index: %w", err)
+	}
+
+	return s.store.Put(indexKey, bytes)
+}
+
+func recordKey(threadID string) string {
+	return fmt.Sprintf(keyPattern, keyPrefix, threadID)
+}
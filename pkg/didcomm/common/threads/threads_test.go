@@ -0,0 +1,281 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package threads
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	mockclock "github.com/hyperledger/aries-framework-go/pkg/internal/mock/clock"
+	mockstorage "github.com/hyperledger/aries-framework-go/pkg/internal/mock/storage"
+	"github.com/hyperledger/aries-framework-go/pkg/storage"
+	"github.com/hyperledger/aries-framework-go/pkg/storage/leveldb"
+)
+
+func TestNew(t *testing.T) {
+	t.Run("test success", func(t *testing.T) {
+		s, err := New(mockstorage.NewMockStoreProvider())
+		require.NoError(t, err)
+		require.NotNil(t, s)
+	})
+
+	t.Run("test open store error", func(t *testing.T) {
+		_, err := New(&mockstorage.MockStoreProvider{ErrOpenStoreHandle: errors.New("open error")})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "open error")
+	})
+}
+
+func TestStore_SaveGet(t *testing.T) {
+	s, clock := newTestStore(t)
+
+	t.Run("test save and get", func(t *testing.T) {
+		require.NoError(t, s.Save(&Record{ThreadID: "thid1", ConnectionID: "conn1", Protocol: "didexchange"}))
+		created := clock.Now()
+
+		clock.Add(time.Minute)
+		require.NoError(t, s.Save(&Record{ThreadID: "thid1", ConnectionID: "conn1", Protocol: "didexchange"}))
+
+		r, err := s.Get("thid1")
+		require.NoError(t, err)
+		require.Equal(t, "conn1", r.ConnectionID)
+		require.Equal(t, "didexchange", r.Protocol)
+		require.Equal(t, created, r.Created)
+		require.Equal(t, clock.Now(), r.Updated)
+	})
+
+	t.Run("test counterparty keys are kept", func(t *testing.T) {
+		require.NoError(t, s.Save(&Record{ThreadID: "thid4", Protocol: "didexchange", TheirVerKeys: []string{"key1"}}))
+		require.NoError(t, s.Save(&Record{ThreadID: "thid4", Protocol: "didexchange"}))
+
+		r, err := s.Get("thid4")
+		require.NoError(t, err)
+		require.Equal(t, []string{"key1"}, r.TheirVerKeys)
+
+		require.NoError(t, s.Save(&Record{ThreadID: "thid4", Protocol: "didexchange", TheirVerKeys: []string{"key2"}}))
+
+		r, err = s.Get("thid4")
+		require.NoError(t, err)
+		require.Equal(t, []string{"key2"}, r.TheirVerKeys)
+	})
+
+	t.Run("test thread not found", func(t *testing.T) {
+		_, err := s.Get("unknown")
+		require.True(t, errors.Is(err, storage.ErrDataNotFound))
+	})
+
+	t.Run("test thread ID is mandatory", func(t *testing.T) {
+		require.EqualError(t, s.Save(&Record{Protocol: "didexchange"}), "thread ID is mandatory")
+	})
+
+	t.Run("test remove", func(t *testing.T) {
+		require.NoError(t, s.Save(&Record{ThreadID: "thid2", Protocol: "didexchange"}))
+		require.NoError(t, s.Remove("thid2"))
+
+		_, err := s.Get("thid2")
+		require.True(t, errors.Is(err, storage.ErrDataNotFound))
+	})
+
+	t.Run("test complete", func(t *testing.T) {
+		require.NoError(t, s.Save(&Record{ThreadID: "thid3", Protocol: "didexchange"}))
+		require.NoError(t, s.Complete("thid3"))
+
+		r, err := s.Get("thid3")
+		require.NoError(t, err)
+		require.True(t, r.Completed)
+
+		require.True(t, errors.Is(s.Complete("unknown"), storage.ErrDataNotFound))
+	})
+
+	t.Run("test store errors", func(t *testing.T) {
+		store := &mockstorage.MockStore{Store: make(map[string][]byte), ErrGet: errors.New("get error")}
+		s, err := New(mockstorage.NewMockCustomStoreProvider(store))
+		require.NoError(t, err)
+
+		require.NoError(t, store.Put(recordKey("thid1"), []byte("{}")))
+		require.EqualError(t, s.Save(&Record{ThreadID: "thid1"}), "get error")
+
+		store.ErrGet = nil
+		require.NoError(t, store.Put(recordKey("thid1"), []byte("invalid")))
+		_, err = s.Get("thid1")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to unmarshal thread record")
+	})
+}
+
+func TestStore_ChildThreads(t *testing.T) {
+	s, _ := newTestStore(t)
+
+	require.NoError(t, s.Save(&Record{ThreadID: "parent", Protocol: "introduce"}))
+	require.NoError(t, s.Save(&Record{ThreadID: "child1", ParentThreadID: "parent", Protocol: "didexchange"}))
+	require.NoError(t, s.Save(&Record{ThreadID: "child2", ParentThreadID: "parent", Protocol: "didexchange"}))
+	require.NoError(t, s.Save(&Record{ThreadID: "other", Protocol: "didexchange"}))
+	require.NoError(t, s.Remove("child2"))
+
+	children, err := s.ChildThreads("parent")
+	require.NoError(t, err)
+	require.Len(t, children, 1)
+	require.Equal(t, "child1", children[0].ThreadID)
+}
+
+func TestStore_Cleanup(t *testing.T) {
+	t.Run("test expiry", func(t *testing.T) {
+		s, clock := newTestStore(t, WithExpiry(time.Hour))
+
+		require.NoError(t, s.Save(&Record{ThreadID: "thid1", Protocol: "didexchange"}))
+		clock.Add(30 * time.Minute)
+		require.NoError(t, s.Save(&Record{ThreadID: "thid2", Protocol: "didexchange"}))
+		clock.Add(45 * time.Minute)
+
+		_, err := s.Get("thid1")
+		require.True(t, errors.Is(err, storage.ErrDataNotFound))
+
+		removed, err := s.Cleanup()
+		require.NoError(t, err)
+		require.Equal(t, 1, removed)
+
+		_, err = s.Get("thid2")
+		require.NoError(t, err)
+
+		ids, err := s.index()
+		require.NoError(t, err)
+		require.Equal(t, []string{"thid2"}, ids)
+	})
+
+	t.Run("test completed removal", func(t *testing.T) {
+		s, _ := newTestStore(t, WithCompletedRemoval())
+
+		require.NoError(t, s.Save(&Record{ThreadID: "thid1", Protocol: "didexchange"}))
+		require.NoError(t, s.Save(&Record{ThreadID: "thid2", Protocol: "didexchange"}))
+		require.NoError(t, s.Complete("thid1"))
+
+		_, err := s.Get("thid1")
+		require.True(t, errors.Is(err, storage.ErrDataNotFound))
+
+		removed, err := s.Cleanup()
+		require.NoError(t, err)
+		require.Equal(t, 0, removed)
+
+		ids, err := s.index()
+		require.NoError(t, err)
+		require.Equal(t, []string{"thid2"}, ids)
+	})
+
+	t.Run("test completed removal keeps the connections", func(t *testing.T) {
+		s, _ := newTestStore(t, WithCompletedRemoval())
+
+		require.NoError(t, s.Save(&Record{ThreadID: "conn1", ConnectionID: "conn1", Protocol: "didexchange",
+			Completed: true, TheirVerKeys: []string{"key1"}}))
+		require.NoError(t, s.Save(&Record{ThreadID: "thid1", ConnectionID: "conn1", Protocol: "didexchange"}))
+		require.NoError(t, s.Complete("thid1"))
+
+		_, err := s.Get("thid1")
+		require.True(t, errors.Is(err, storage.ErrDataNotFound))
+
+		connection, err := s.Get("conn1")
+		require.NoError(t, err)
+		require.Equal(t, []string{"key1"}, connection.TheirVerKeys)
+	})
+
+	t.Run("test records expire from the expiring stores", func(t *testing.T) {
+		prov := mockstorage.NewMockStoreProvider()
+		s, err := New(prov, WithExpiry(time.Hour))
+		require.NoError(t, err)
+
+		require.NoError(t, s.Save(&Record{ThreadID: "thid1", Protocol: "didexchange"}))
+
+		ttl, err := prov.Store.TTL(recordKey("thid1"))
+		require.NoError(t, err)
+		require.True(t, ttl > 0 && ttl <= time.Hour)
+
+		// the index doesn't expire
+		ttl, err = prov.Store.TTL(indexKey)
+		require.NoError(t, err)
+		require.Zero(t, ttl)
+	})
+
+	t.Run("test expiry with the clock", func(t *testing.T) {
+		c := &mockclock.Clock{Time: time.Date(2019, time.October, 1, 0, 0, 0, 0, time.UTC)}
+		s, err := New(mockstorage.NewMockStoreProvider(), WithExpiry(time.Hour), WithClock(c))
+		require.NoError(t, err)
+
+		require.NoError(t, s.Save(&Record{ThreadID: "thid1", Protocol: "didexchange"}))
+
+		record, err := s.Get("thid1")
+		require.NoError(t, err)
+		require.Equal(t, c.Time, record.Updated)
+
+		c.Add(2 * time.Hour)
+
+		_, err = s.Get("thid1")
+		require.True(t, errors.Is(err, storage.ErrDataNotFound))
+	})
+
+	t.Run("test no policies", func(t *testing.T) {
+		s, clock := newTestStore(t)
+
+		require.NoError(t, s.Save(&Record{ThreadID: "thid1", Protocol: "didexchange", Completed: true}))
+		clock.Add(24 * time.Hour)
+
+		removed, err := s.Cleanup()
+		require.NoError(t, err)
+		require.Equal(t, 0, removed)
+
+		_, err = s.Get("thid1")
+		require.NoError(t, err)
+	})
+}
+
+func newTestStore(t *testing.T, opts ...Opt) (*Store, *mockclock.Clock) {
+	clock := &mockclock.Clock{Time: time.Date(2019, time.October, 1, 0, 0, 0, 0, time.UTC)}
+
+	s, err := New(mockstorage.NewMockStoreProvider(), append([]Opt{WithClock(clock)}, opts...)...)
+	require.NoError(t, err)
+
+	return s, clock
+}
+
+func TestStore_LevelDB(t *testing.T) {
+	path, cleanup := setupLevelDB(t)
+	defer cleanup()
+
+	prov, err := leveldb.NewProvider(path)
+	require.NoError(t, err)
+
+	defer func() {
+		require.NoError(t, prov.Close())
+	}()
+
+	s, err := New(prov, WithCompletedRemoval())
+	require.NoError(t, err)
+
+	require.NoError(t, s.Save(&Record{ThreadID: "thid1", Protocol: "didexchange"}))
+	require.NoError(t, s.Complete("thid1"))
+
+	_, err = s.Get("thid1")
+	require.True(t, errors.Is(err, storage.ErrDataNotFound))
+
+	require.NoError(t, s.Save(&Record{ThreadID: "thid2", Protocol: "didexchange"}))
+	require.NoError(t, s.Remove("thid2"))
+
+	_, err = s.Get("thid2")
+	require.True(t, errors.Is(err, storage.ErrDataNotFound))
+}
+
+func setupLevelDB(t testing.TB) (string, func()) {
+	dbPath, err := ioutil.TempDir("", "threads")
+	require.NoError(t, err)
+
+	return dbPath, func() {
+		require.NoError(t, os.RemoveAll(dbPath))
+	}
+}
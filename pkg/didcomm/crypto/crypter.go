@@ -26,6 +26,16 @@ type Crypter interface {
 	Decrypt(envelope []byte, recipientKeyPair KeyPair) ([]byte, error)
 }
 
+// SenderDecrypter is optionally implemented by the crypters of authenticated envelopes
+type SenderDecrypter interface {
+	// DecryptWithSender decrypts the envelope with the recipient key pair as Crypter.Decrypt does
+	// returns:
+	// 		[]byte containing the decrypted payload
+	//		[]byte containing the public key of the sender
+	//		error if decryption failed
+	DecryptWithSender(envelope []byte, recipientKeyPair KeyPair) ([]byte, []byte, error)
+}
+
 // KeyPair represents a private/public key pair each with 32 bytes in size
 type KeyPair struct {
 	// Priv is a private key
@@ -0,0 +1,225 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package fixtures loads envelope test vectors, such as the reference envelopes of the Aries RFCs or the
+// envelopes packed by other Aries implementations, and verifies crypters against them.
+package fixtures
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/btcsuite/btcutil/base58"
+
+	"github.com/hyperledger/aries-framework-go/pkg/common/log"
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/crypto"
+)
+
+var logger = log.New("aries-framework/crypto/fixtures")
+
+// Format is the envelope format of a test vector
+type Format string
+
+const (
+	// LegacyAuthcrypt is the authenticated envelope of Aries RFC 0019
+	LegacyAuthcrypt Format = "legacy-authcrypt"
+	// LegacyAnoncrypt is the anonymous envelope of Aries RFC 0019
+	LegacyAnoncrypt Format = "legacy-anoncrypt"
+	// JWEAuthcrypt is the authenticated JWE envelope
+	JWEAuthcrypt Format = "jwe-authcrypt"
+)
+
+var (
+	// ErrPlaintextMismatch is returned when the decrypted envelope isn't the plaintext of the vector
+	ErrPlaintextMismatch = errors.New("decrypted payload doesn't match the vector plaintext")
+	// ErrSenderMismatch is returned when the sender key of the decrypted envelope isn't the sender of the vector
+	ErrSenderMismatch = errors.New("sender key doesn't match the vector sender")
+	// ErrSenderNotVerified is returned when the vector has a sender but the crypter doesn't return the sender key
+	ErrSenderNotVerified = errors.New("crypter doesn't return the sender key of the vector")
+	// ErrExpectedFailure is returned when a negative vector is decrypted successfully
+	ErrExpectedFailure = errors.New("vector was expected to fail decryption")
+)
+
+// Vector is an envelope test vector, the keys are base58 encoded
+type Vector struct {
+	// Name identifies the vector in the verification results
+	Name string `json:"name"`
+	// Description tells what the vector covers
+	Description string `json:"description,omitempty"`
+	// Format is the envelope format
+	Format Format `json:"format"`
+	// Envelope is the JSON envelope
+	Envelope json.RawMessage `json:"envelope"`
+	// RecipientPub is the public key of the recipient
+	RecipientPub string `json:"recipientPub"`
+	// RecipientPriv is the private key of the recipient
+	RecipientPriv string `json:"recipientPriv"`
+	// SenderPub is the public key of the sender of the authenticated envelopes, if published
+	SenderPub string `json:"senderPub,omitempty"`
+	// Plaintext is the payload of the envelope
+	Plaintext string `json:"plaintext,omitempty"`
+	// ExpectError marks the negative vectors the recipient must fail to decrypt
+	ExpectError bool `json:"expectError,omitempty"`
+}
+
+// RecipientKeyPair returns the key pair of the recipient of the vector
+func (v *Vector) RecipientKeyPair() crypto.KeyPair {
+	return crypto.KeyPair{Pub: base58.Decode(v.RecipientPub), Priv: base58.Decode(v.RecipientPriv)}
+}
+
+// suite is the format of the fixture files
+type suite struct {
+	Source  string   `json:"source,omitempty"`
+	Vectors []Vector `json:"vectors"`
+}
+
+// Load reads the test vectors of a fixture file
+func Load(r io.Reader) ([]Vector, error) {
+	s := &suite{}
+	if err := json.NewDecoder(r).Decode(s); err != nil {
+		return nil, fmt.Errorf("failed to decode test vectors: %w", err)
+	}
+
+	for i, v := range s.Vectors {
+		if v.Name == "" || v.Format == "" || len(v.Envelope) == 0 {
+			return nil, fmt.Errorf("test vector %d must have a name, a format and an envelope", i)
+		}
+	}
+
+	return s.Vectors, nil
+}
+
+// LoadFile reads the test vectors of the fixture file at the path
+func LoadFile(path string) ([]Vector, error) {
+	f, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open test vectors: %w", err)
+	}
+
+	defer func() {
+		if e := f.Close(); e != nil {
+			logger.Warnf("failed to close test vectors file %s: %s", path, e)
+		}
+	}()
+
+	vectors, err := Load(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return vectors, nil
+}
+
+// LoadDir reads the test vectors of the JSON fixture files of the directory
+func LoadDir(dir string) ([]Vector, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var vectors []Vector
+
+	for _, path := range paths {
+		loaded, loadErr := LoadFile(path)
+		if loadErr != nil {
+			return nil, loadErr
+		}
+
+		vectors = append(vectors, loaded...)
+	}
+
+	return vectors, nil
+}
+
+// Filter returns the vectors of the formats
+func Filter(vectors []Vector, formats ...Format) []Vector {
+	var filtered []Vector
+
+	for _, v := range vectors {
+		for _, f := range formats {
+			if v.Format == f {
+				filtered = append(filtered, v)
+				break
+			}
+		}
+	}
+
+	return filtered
+}
+
+// Verify decrypts the envelope of the vector with the crypter and checks the plaintext, and the sender key
+// if the vector has one, in which case the crypter must implement crypto.SenderDecrypter. The negative vectors
+// must fail.
+func Verify(crypter crypto.Crypter, v *Vector) error {
+	payload, sender, err := decrypt(crypter, v)
+
+	if v.ExpectError {
+		if err == nil {
+			return ErrExpectedFailure
+		}
+
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to decrypt envelope: %w", err)
+	}
+
+	if !bytes.Equal(payload, []byte(v.Plaintext)) {
+		return ErrPlaintextMismatch
+	}
+
+	return verifySender(sender, v)
+}
+
+func verifySender(sender []byte, v *Vector) error {
+	if v.SenderPub == "" {
+		return nil
+	}
+
+	if sender == nil {
+		return ErrSenderNotVerified
+	}
+
+	if !bytes.Equal(sender, base58.Decode(v.SenderPub)) {
+		return ErrSenderMismatch
+	}
+
+	return nil
+}
+
+func decrypt(crypter crypto.Crypter, v *Vector) ([]byte, []byte, error) {
+	if d, ok := crypter.(crypto.SenderDecrypter); ok {
+		return d.DecryptWithSender(v.Envelope, v.RecipientKeyPair())
+	}
+
+	payload, err := crypter.Decrypt(v.Envelope, v.RecipientKeyPair())
+
+	return payload, nil, err
+}
+
+// Result is the outcome of the verification of a crypter against a vector
+type Result struct {
+	Vector Vector
+	// Err is nil if the crypter passed the vector
+	Err error
+}
+
+// VerifyAll verifies the crypter against the vectors, see Verify
+func VerifyAll(crypter crypto.Crypter, vectors []Vector) []Result {
+	results := make([]Result, len(vectors))
+
+	for i := range vectors {
+		results[i] = Result{Vector: vectors[i], Err: Verify(crypter, &vectors[i])}
+	}
+
+	return results
+}
@@ -0,0 +1,144 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package fixtures
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/crypto"
+	jwe "github.com/hyperledger/aries-framework-go/pkg/didcomm/crypto/jwe/authcrypt"
+	legacy "github.com/hyperledger/aries-framework-go/pkg/didcomm/crypto/legacy/authcrypt"
+)
+
+func TestLoad(t *testing.T) {
+	t.Run("test load fixture directory", func(t *testing.T) {
+		vectors, err := LoadDir("testdata")
+		require.NoError(t, err)
+		require.Len(t, vectors, 8)
+		require.Len(t, Filter(vectors, LegacyAuthcrypt), 3)
+		require.Len(t, Filter(vectors, LegacyAnoncrypt), 1)
+		require.Len(t, Filter(vectors, JWEAuthcrypt), 4)
+		require.Len(t, Filter(vectors, LegacyAuthcrypt, JWEAuthcrypt), 7)
+	})
+
+	t.Run("test invalid fixtures", func(t *testing.T) {
+		_, err := Load(strings.NewReader("{"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to decode test vectors")
+
+		_, err = Load(strings.NewReader(`{"vectors": [{"name": "v1", "format": "legacy-authcrypt"}]}`))
+		require.EqualError(t, err, "test vector 0 must have a name, a format and an envelope")
+
+		_, err = LoadFile("testdata/missing.json")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to open test vectors")
+
+		_, err = LoadDir("[")
+		require.Error(t, err)
+	})
+}
+
+func TestVerify(t *testing.T) {
+	vectors, loadErr := LoadFile("testdata/legacy_authcrypt.json")
+	require.NoError(t, loadErr)
+
+	t.Run("test legacy crypter passes the reference vectors", func(t *testing.T) {
+		for _, result := range VerifyAll(legacy.New(), vectors) {
+			require.NoError(t, result.Err, result.Vector.Name)
+		}
+	})
+
+	t.Run("test plaintext mismatch", func(t *testing.T) {
+		v := vectors[0]
+		v.Plaintext = "other"
+		require.True(t, errors.Is(Verify(legacy.New(), &v), ErrPlaintextMismatch))
+	})
+
+	t.Run("test negative vector decrypted", func(t *testing.T) {
+		v := vectors[0]
+		v.ExpectError = true
+		require.True(t, errors.Is(Verify(legacy.New(), &v), ErrExpectedFailure))
+	})
+
+	t.Run("test decryption failure", func(t *testing.T) {
+		v := vectors[2]
+		v.ExpectError = false
+		err := Verify(legacy.New(), &v)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to decrypt envelope")
+	})
+
+	t.Run("test legacy crypter fails the anoncrypt vectors", func(t *testing.T) {
+		anoncrypt, err := LoadFile("testdata/legacy_anoncrypt.json")
+		require.NoError(t, err)
+
+		for _, result := range VerifyAll(legacy.New(), anoncrypt) {
+			require.NoError(t, result.Err, result.Vector.Name)
+		}
+
+		v := anoncrypt[0]
+		v.ExpectError = false
+		err = Verify(legacy.New(), &v)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "message format Anoncrypt not supported")
+	})
+
+	t.Run("test sender not returned by the crypter", func(t *testing.T) {
+		v := vectors[0]
+		v.SenderPub = v.RecipientPub
+		require.True(t, errors.Is(Verify(legacy.New(), &v), ErrSenderNotVerified))
+	})
+}
+
+func TestVerifyJWE(t *testing.T) {
+	vectors, loadErr := LoadFile("testdata/jwe_authcrypt.json")
+	require.NoError(t, loadErr)
+
+	crypterOf := func(t *testing.T, v *Vector) *jwe.Crypter {
+		envelope := &jwe.Envelope{}
+		require.NoError(t, json.Unmarshal(v.Envelope, envelope))
+
+		alg, err := envelope.ContentEncryption()
+		require.NoError(t, err)
+
+		crypter, err := jwe.New(alg)
+		require.NoError(t, err)
+
+		return crypter
+	}
+
+	t.Run("test JWE crypter passes the vectors", func(t *testing.T) {
+		for i := range vectors {
+			require.NoError(t, Verify(crypterOf(t, &vectors[i]), &vectors[i]), vectors[i].Name)
+		}
+	})
+
+	t.Run("test sender mismatch", func(t *testing.T) {
+		v := vectors[0]
+		v.SenderPub = v.RecipientPub
+		require.True(t, errors.Is(Verify(crypterOf(t, &v), &v), ErrSenderMismatch))
+	})
+
+	t.Run("test crypter without sender", func(t *testing.T) {
+		v := vectors[0]
+		crypter := &noSenderCrypter{Crypter: crypterOf(t, &v)}
+		require.True(t, errors.Is(Verify(crypter, &v), ErrSenderNotVerified))
+
+		v.SenderPub = ""
+		require.NoError(t, Verify(crypter, &v))
+	})
+}
+
+// noSenderCrypter hides the DecryptWithSender of the crypter
+type noSenderCrypter struct {
+	crypto.Crypter
+}
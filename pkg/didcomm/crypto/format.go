@@ -0,0 +1,59 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package crypto
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/jose"
+)
+
+// Format is the format of an envelope, as announced by the typ or cty header of its protected header
+type Format string
+
+const (
+	// FormatLegacy is the legacy authcrypt envelope of Aries RFC 0019
+	FormatLegacy Format = "JWM/1.0"
+	// FormatAuthcrypt is the JWE authcrypt envelope of the Aries agents (DIDComm v1)
+	FormatAuthcrypt Format = "prs.hyperledger.aries-auth-message"
+	// FormatDIDCommV2 is the encrypted envelope of DIDComm v2
+	FormatDIDCommV2 Format = "application/didcomm-encrypted+json"
+)
+
+// ErrUnsupportedFormat is returned for the envelopes of an unknown format
+var ErrUnsupportedFormat = errors.New("unsupported envelope format")
+
+// EnvelopeFormat returns the format of the JSON envelope read from the typ header of its protected header, or from
+// the cty header if the typ is not a known envelope format. The media types are matched with or without their
+// "application/" prefix.
+func EnvelopeFormat(envelope []byte) (Format, error) {
+	headers, err := jose.ParseProtectedHeaders(envelope)
+	if err != nil {
+		return "", err
+	}
+
+	for _, h := range []string{headers.Type, headers.ContentType} {
+		if format, ok := knownFormat(h); ok {
+			return format, nil
+		}
+	}
+
+	return "", fmt.Errorf("%w: typ %q cty %q", ErrUnsupportedFormat, headers.Type, headers.ContentType)
+}
+
+func knownFormat(mediaType string) (Format, bool) {
+	for _, format := range []Format{FormatLegacy, FormatAuthcrypt, FormatDIDCommV2} {
+		if strings.EqualFold(mediaType, string(format)) ||
+			strings.EqualFold("application/"+mediaType, string(format)) {
+			return format, true
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,56 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package crypto
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/jose"
+)
+
+func TestEnvelopeFormat(t *testing.T) {
+	envelope := func(encoding *base64.Encoding, protected string) []byte {
+		return []byte(fmt.Sprintf(`{"protected":%q}`, encoding.EncodeToString([]byte(protected))))
+	}
+
+	t.Run("test known formats", func(t *testing.T) {
+		for protected, expected := range map[string]Format{
+			`{"typ":"JWM/1.0"}`:                            FormatLegacy,
+			`{"typ":"prs.hyperledger.aries-auth-message"}`: FormatAuthcrypt,
+			`{"typ":"application/didcomm-encrypted+json"}`: FormatDIDCommV2,
+			`{"typ":"didcomm-encrypted+json"}`:             FormatDIDCommV2,
+			`{"typ":"JWE","cty":"didcomm-encrypted+json"}`: FormatDIDCommV2,
+		} {
+			format, err := EnvelopeFormat(envelope(base64.RawURLEncoding, protected))
+			require.NoError(t, err)
+			require.Equal(t, expected, format, protected)
+		}
+
+		// the legacy envelopes encode the protected header with padding
+		format, err := EnvelopeFormat(envelope(base64.URLEncoding, `{"typ":"JWM/1.0"}`))
+		require.NoError(t, err)
+		require.Equal(t, FormatLegacy, format)
+	})
+
+	t.Run("test unsupported format", func(t *testing.T) {
+		_, err := EnvelopeFormat(envelope(base64.RawURLEncoding, `{"typ":"JWE"}`))
+		require.True(t, errors.Is(err, ErrUnsupportedFormat))
+	})
+
+	t.Run("test invalid envelope", func(t *testing.T) {
+		for _, e := range [][]byte{[]byte("invalid"), []byte(`{}`), []byte(`{"protected":"!"}`),
+			envelope(base64.RawURLEncoding, "{")} {
+			_, err := EnvelopeFormat(e)
+			require.True(t, errors.Is(err, jose.ErrInvalidHeaders), string(e))
+		}
+	})
+}
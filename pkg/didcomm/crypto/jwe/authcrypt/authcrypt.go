@@ -9,8 +9,13 @@ package authcrypt
 import (
 	"crypto/rand"
 	"errors"
+	"fmt"
+	"io"
+	"sync"
 
 	chacha "golang.org/x/crypto/chacha20poly1305"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/jose"
 )
 
 // This package deals with Authcrypt encryption for Packing/Unpacking DID Comm exchange
@@ -25,8 +30,9 @@ const C20P = ContentEncryption("C20P") // Chacha20 encryption + Poly1305 authent
 // XC20P XChacha20Poly1305 algorithm
 const XC20P = ContentEncryption("XC20P") // XChacha20 encryption + Poly1305 authenticator cipher (192 bits nonce)
 
-// randReader is a cryptographically secure random number generator.
-// TODO: document usage for tests or find another mechanism.
+// randReader is the cryptographically secure random number generator used by the crypters without
+// a random source set with WithRandSource.
+//
 //nolint:gochecknoglobals
 var randReader = rand.Reader
 
@@ -47,8 +53,93 @@ var errUnsupportedAlg = errors.New("algorithm not supported")
 
 // Crypter represents an Authcrypt Encrypter (Decrypter) that outputs/reads JWE envelopes
 type Crypter struct {
-	alg       ContentEncryption
-	nonceSize int
+	alg                 ContentEncryption
+	nonceSize           int
+	bufPool             *sync.Pool
+	deriveKeyAgreement  bool
+	thumbprintKID       bool
+	secrets             *SharedSecretCache
+	randReader          io.Reader
+	zip                 Compression
+	maxDecompressedSize int64
+}
+
+// Option configures the Crypter
+type Option func(c *Crypter)
+
+// WithBufferPool enables the perf mode of the Crypter: the intermediate buffers used to seal and open
+// payloads are taken from a pool shared by all Encrypt/Decrypt calls of the Crypter instead of being
+// allocated per message. It is meant for agents processing high volumes of messages (eg. mediators).
+func WithBufferPool() Option {
+	return func(c *Crypter) {
+		c.bufPool = &sync.Pool{
+			New: func() interface{} {
+				return new([]byte)
+			},
+		}
+	}
+}
+
+// WithKeyAgreementDerivation makes EncryptToDIDs derive the X25519 recipient keys from the Ed25519 keys
+// of the DID documents which have no keyAgreement keys.
+func WithKeyAgreementDerivation() Option {
+	return func(c *Crypter) {
+		c.deriveKeyAgreement = true
+	}
+}
+
+// WithThumbprintKID sets the RFC 7638 JWK thumbprint of the recipient key as the recipient "kid" header
+// instead of the base58 encoded key, as expected by other JWE stacks. Decrypt accepts both forms.
+func WithThumbprintKID() Option {
+	return func(c *Crypter) {
+		c.thumbprintKID = true
+	}
+}
+
+// WithSharedSecretCache reuses the shared secrets of the static sender and recipient keys kept in the cache,
+// reducing the CPU used by agents exchanging many messages with the same counterparties.
+func WithSharedSecretCache(cache *SharedSecretCache) Option {
+	return func(c *Crypter) {
+		c.secrets = cache
+	}
+}
+
+// WithRandSource sets the random source of the nonces, content encryption keys and ephemeral keys, e.g.
+// a deterministic reader generating reproducible envelopes in golden tests. It must be cryptographically
+// secure in production, the default is crypto/rand.
+func WithRandSource(r io.Reader) Option {
+	return func(c *Crypter) {
+		c.randReader = r
+	}
+}
+
+// random returns the random source of the Crypter
+func (c *Crypter) random() io.Reader {
+	if c.randReader != nil {
+		return c.randReader
+	}
+
+	return randReader
+}
+
+// SupportedAlgs returns the content encryption algorithms supported by the Crypter in preference order
+func SupportedAlgs() []ContentEncryption {
+	return []ContentEncryption{XC20P, C20P}
+}
+
+// SelectAlg returns the most preferred supported content encryption algorithm which is also
+// supported by the counterparty.
+func SelectAlg(counterpartyAlgs []string) (ContentEncryption, error) {
+	for _, alg := range SupportedAlgs() {
+		for _, a := range counterpartyAlgs {
+			if a == string(alg) {
+				return alg, nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("no mutually supported content encryption algorithm in %v: %w",
+		counterpartyAlgs, errUnsupportedAlg)
 }
 
 // Envelope represents a JWE envelope as per the Aries Encryption envelope specs
@@ -61,11 +152,40 @@ type Envelope struct {
 	CipherText string      `json:"ciphertext,omitempty"`
 }
 
+// ContentEncryption returns the content encryption algorithm of the envelope set in the protected headers
+func (e *Envelope) ContentEncryption() (ContentEncryption, error) {
+	headers, err := e.protectedHeaders()
+	if err != nil {
+		return "", err
+	}
+
+	if headers.Encryption == "" {
+		return "", errors.New("content encryption algorithm not set in protected headers")
+	}
+
+	return ContentEncryption(headers.Encryption), nil
+}
+
+// Compression returns the compression of the payload set in the protected headers, empty if not compressed
+func (e *Envelope) Compression() (Compression, error) {
+	headers, err := e.protectedHeaders()
+	if err != nil {
+		return "", err
+	}
+
+	return Compression(headers.Compression), nil
+}
+
+func (e *Envelope) protectedHeaders() (*jose.Headers, error) {
+	return jose.DecodeProtectedHeaders(e.Protected)
+}
+
 // jweHeaders are the Protected JWE headers in a map format
 type jweHeaders struct {
 	Typ string `json:"typ,omitempty"`
 	Alg string `json:"alg,omitempty"`
 	Enc string `json:"enc,omitempty"`
+	Zip string `json:"zip,omitempty"`
 }
 
 // Recipient is a recipient of an envelope including the shared encryption key
@@ -106,7 +226,7 @@ type jwk struct {
 // C20P (chacha20-poly1305 ietf)
 // XC20P (xchacha20-poly1305 ietf)
 // The returned crypter contains all the information required to encrypt payloads.
-func New(alg ContentEncryption) (*Crypter, error) {
+func New(alg ContentEncryption, opts ...Option) (*Crypter, error) {
 	var nonceSize int
 	switch alg {
 	case C20P:
@@ -118,13 +238,40 @@ func New(alg ContentEncryption) (*Crypter, error) {
 	}
 
 	c := &Crypter{
-		alg,
-		nonceSize,
+		alg:       alg,
+		nonceSize: nonceSize,
+	}
+
+	for _, opt := range opts {
+		opt(c)
 	}
 
 	return c, nil
 }
 
+// getBuffer returns an empty buffer with a capacity of at least size bytes
+func (c *Crypter) getBuffer(size int) []byte {
+	if c.bufPool == nil {
+		return make([]byte, 0, size)
+	}
+
+	buf, ok := c.bufPool.Get().(*[]byte)
+	if !ok || cap(*buf) < size {
+		return make([]byte, 0, size)
+	}
+
+	return (*buf)[:0]
+}
+
+// putBuffer returns the buffer to the pool once it's not referenced anymore
+func (c *Crypter) putBuffer(buf []byte) {
+	if c.bufPool == nil {
+		return
+	}
+
+	c.bufPool.Put(&buf)
+}
+
 // IsChachaKeyValid will return true if key size is the same as chacha20poly1305.keySize
 // false otherwise
 func IsChachaKeyValid(key []byte) bool {
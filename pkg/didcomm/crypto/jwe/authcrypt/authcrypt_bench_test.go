@@ -0,0 +1,126 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package authcrypt
+
+import (
+	"bytes"
+	"testing"
+
+	"golang.org/x/crypto/nacl/box"
+
+	jwecrypto "github.com/hyperledger/aries-framework-go/pkg/didcomm/crypto"
+)
+
+type benchPayloadSize struct {
+	name string
+	size int
+}
+
+// benchPayloadSizes returns payload sizes in bytes used by the benchmarks
+func benchPayloadSizes() []benchPayloadSize {
+	return []benchPayloadSize{
+		{"1KB", 1 << 10},
+		{"64KB", 1 << 16},
+	}
+}
+
+func BenchmarkEncrypt(b *testing.B) {
+	for _, opts := range benchCrypterOpts() {
+		for _, ps := range benchPayloadSizes() {
+			b.Run(opts.name+"/"+ps.name, func(b *testing.B) {
+				crypter, sender, recipients := newBenchCrypter(b, opts.opts...)
+				pld := bytes.Repeat([]byte("a"), ps.size)
+
+				b.ReportAllocs()
+				b.SetBytes(int64(ps.size))
+				b.ResetTimer()
+
+				for i := 0; i < b.N; i++ {
+					if _, err := crypter.Encrypt(pld, sender, [][]byte{recipients[0].Pub}); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkDecrypt(b *testing.B) {
+	for _, opts := range benchCrypterOpts() {
+		for _, ps := range benchPayloadSizes() {
+			b.Run(opts.name+"/"+ps.name, func(b *testing.B) {
+				crypter, sender, recipients := newBenchCrypter(b, opts.opts...)
+				enc, err := crypter.Encrypt(bytes.Repeat([]byte("a"), ps.size), sender, [][]byte{recipients[0].Pub})
+				if err != nil {
+					b.Fatal(err)
+				}
+
+				b.ReportAllocs()
+				b.SetBytes(int64(ps.size))
+				b.ResetTimer()
+
+				for i := 0; i < b.N; i++ {
+					if _, err := crypter.Decrypt(enc, recipients[0]); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkEncryptMultipleRecipients(b *testing.B) {
+	crypter, sender, recipients := newBenchCrypter(b, WithBufferPool())
+	pld := bytes.Repeat([]byte("a"), 1<<10)
+
+	var recipientKeys [][]byte
+	for _, r := range recipients {
+		recipientKeys = append(recipientKeys, r.Pub)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := crypter.Encrypt(pld, sender, recipientKeys); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+type benchOpts struct {
+	name string
+	opts []Option
+}
+
+func benchCrypterOpts() []benchOpts {
+	return []benchOpts{
+		{name: "default"},
+		{name: "bufferPool", opts: []Option{WithBufferPool()}},
+	}
+}
+
+func newBenchCrypter(b *testing.B, opts ...Option) (*Crypter, jwecrypto.KeyPair, []jwecrypto.KeyPair) {
+	crypter, err := New(XC20P, opts...)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	var keys []jwecrypto.KeyPair
+
+	const numKeys = 4
+	for i := 0; i < numKeys; i++ {
+		pub, priv, err := box.GenerateKey(randReader)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		keys = append(keys, jwecrypto.KeyPair{Pub: pub[:], Priv: priv[:]})
+	}
+
+	return crypter, keys[0], keys[1:]
+}
@@ -11,6 +11,8 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"io"
+	insecurerand "math/rand"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -18,6 +20,8 @@ import (
 	"golang.org/x/crypto/nacl/box"
 
 	jwecrypto "github.com/hyperledger/aries-framework-go/pkg/didcomm/crypto"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	jsonwebkey "github.com/hyperledger/aries-framework-go/pkg/doc/jwk"
 )
 
 func TestEncrypt(t *testing.T) {
@@ -172,6 +176,36 @@ func TestEncrypt(t *testing.T) {
 		require.NoError(t, e)
 		require.NotEmpty(t, dec)
 		require.EqualValues(t, dec, pld)
+
+		// decrypt with the sender key
+		dec, sender, e := crypter.DecryptWithSender(enc, recipient3Key)
+		require.NoError(t, e)
+		require.EqualValues(t, pld, dec)
+		require.EqualValues(t, sendEcKey.Pub, sender)
+	})
+
+	t.Run("Success test case: Encrypting and decrypting messages with a buffer pool (perf mode)", func(t *testing.T) {
+		crypter, e := New(XC20P, WithBufferPool())
+		require.NoError(t, e)
+		require.NotNil(t, crypter.bufPool)
+
+		plds := [][]byte{[]byte("lorem ipsum"), []byte("lorem ipsum dolor sit amet, consectetur")}
+		decs := make([][]byte, 0, len(plds))
+
+		for _, pld := range plds {
+			enc, e := crypter.Encrypt(pld, sendEcKey, [][]byte{recipient1Key.Pub, recipient2Key.Pub})
+			require.NoError(t, e)
+			require.NotEmpty(t, enc)
+
+			dec, e := crypter.Decrypt(enc, recipient2Key)
+			require.NoError(t, e)
+			require.EqualValues(t, pld, dec)
+
+			decs = append(decs, dec)
+		}
+
+		// the decrypted payloads are not overwritten by the pooled buffers
+		require.EqualValues(t, plds, decs)
 	})
 
 	t.Run("Success test case: Decrypting a message with two Crypter instances to simulate two agents", func(t *testing.T) {
@@ -426,6 +460,99 @@ func prettyPrint(msg []byte) (string, error) {
 	return prettyJSON.String(), nil
 }
 
+func TestSelectAlg(t *testing.T) {
+	t.Run("test most preferred mutual algorithm", func(t *testing.T) {
+		alg, err := SelectAlg([]string{"A256GCM", string(C20P), string(XC20P)})
+		require.NoError(t, err)
+		require.Equal(t, XC20P, alg)
+	})
+
+	t.Run("test single mutual algorithm", func(t *testing.T) {
+		alg, err := SelectAlg([]string{"A256GCM", string(C20P)})
+		require.NoError(t, err)
+		require.Equal(t, C20P, alg)
+	})
+
+	t.Run("test no mutual algorithm", func(t *testing.T) {
+		_, err := SelectAlg([]string{"A256GCM"})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), errUnsupportedAlg.Error())
+	})
+}
+
+func TestEnvelope_ContentEncryption(t *testing.T) {
+	senderPub, senderPriv, err := box.GenerateKey(randReader)
+	require.NoError(t, err)
+
+	recipientPub, _, err := box.GenerateKey(randReader)
+	require.NoError(t, err)
+
+	for _, alg := range SupportedAlgs() {
+		crypter, err := New(alg)
+		require.NoError(t, err)
+
+		enc, err := crypter.Encrypt([]byte("lorem ipsum"),
+			jwecrypto.KeyPair{Priv: senderPriv[:], Pub: senderPub[:]}, [][]byte{recipientPub[:]})
+		require.NoError(t, err)
+
+		jwe := &Envelope{}
+		require.NoError(t, json.Unmarshal(enc, jwe))
+
+		envelopeAlg, err := jwe.ContentEncryption()
+		require.NoError(t, err)
+		require.Equal(t, alg, envelopeAlg)
+	}
+
+	t.Run("test invalid protected headers", func(t *testing.T) {
+		_, err := (&Envelope{Protected: "!"}).ContentEncryption()
+		require.Error(t, err)
+
+		_, err = (&Envelope{Protected: base64.RawURLEncoding.EncodeToString([]byte("{"))}).ContentEncryption()
+		require.Error(t, err)
+
+		_, err = (&Envelope{Protected: base64.RawURLEncoding.EncodeToString([]byte("{}"))}).ContentEncryption()
+		require.EqualError(t, err, "content encryption algorithm not set in protected headers")
+	})
+}
+
+func TestCrypter_WithRandSource(t *testing.T) {
+	senderPub, senderPriv, err := box.GenerateKey(randReader)
+	require.NoError(t, err)
+
+	recipientPub, recipientPriv, err := box.GenerateKey(randReader)
+	require.NoError(t, err)
+
+	sender := jwecrypto.KeyPair{Pub: senderPub[:], Priv: senderPriv[:]}
+
+	encrypt := func(source io.Reader) ([]byte, error) {
+		crypter, e := New(XC20P, WithRandSource(source))
+		require.NoError(t, e)
+
+		return crypter.Encrypt([]byte("payload"), sender, [][]byte{recipientPub[:]})
+	}
+
+	t.Run("test deterministic random source packs the same envelope", func(t *testing.T) {
+		first, e := encrypt(insecurerand.New(insecurerand.NewSource(7283490))) // just a random const
+		require.NoError(t, e)
+
+		second, e := encrypt(insecurerand.New(insecurerand.NewSource(7283490)))
+		require.NoError(t, e)
+		require.Equal(t, first, second)
+
+		crypter, e := New(XC20P)
+		require.NoError(t, e)
+
+		payload, e := crypter.Decrypt(first, jwecrypto.KeyPair{Pub: recipientPub[:], Priv: recipientPriv[:]})
+		require.NoError(t, e)
+		require.Equal(t, []byte("payload"), payload)
+	})
+
+	t.Run("test random source failure", func(t *testing.T) {
+		_, e := encrypt(&badReader{})
+		require.Error(t, e)
+	})
+}
+
 func TestBadCreateCipher(t *testing.T) {
 	_, err := createCipher(0, nil)
 	require.Error(t, err)
@@ -472,3 +599,34 @@ func TestRefEncrypt(t *testing.T) {
 	require.NoError(t, err)
 	require.NotEmpty(t, dec)
 }
+
+func TestThumbprintKID(t *testing.T) {
+	senderPub, senderPriv, err := box.GenerateKey(randReader)
+	require.NoError(t, err)
+	sender := jwecrypto.KeyPair{Priv: senderPriv[:], Pub: senderPub[:]}
+
+	recPub, recPriv, err := box.GenerateKey(randReader)
+	require.NoError(t, err)
+	recipient := jwecrypto.KeyPair{Priv: recPriv[:], Pub: recPub[:]}
+
+	crypter, err := New(XC20P, WithThumbprintKID())
+	require.NoError(t, err)
+
+	enc, err := crypter.Encrypt([]byte("lorem ipsum"), sender, [][]byte{recipient.Pub})
+	require.NoError(t, err)
+
+	envelope := &Envelope{}
+	require.NoError(t, json.Unmarshal(enc, envelope))
+
+	kid, err := jsonwebkey.KeyID(did.X25519KeyAgreementKey2019, recipient.Pub)
+	require.NoError(t, err)
+	require.Equal(t, kid, envelope.Recipients[0].Header.KID)
+
+	// the crypter without the option decrypts thumbprint kids too
+	defCrypter, err := New(XC20P)
+	require.NoError(t, err)
+
+	dec, err := defCrypter.Decrypt(enc, recipient)
+	require.NoError(t, err)
+	require.Equal(t, []byte("lorem ipsum"), dec)
+}
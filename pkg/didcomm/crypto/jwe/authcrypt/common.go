@@ -53,6 +53,25 @@ func (c *Crypter) deriveKEK(alg, apu []byte, privKey, pubKey *[chacha.KeySize]by
 	// ( equivalent to derive an EC key )
 	curve25519.ScalarMult(z, privKey, pubKey)
 
+	return deriveKEKFromZ(alg, apu, z)
+}
+
+// deriveStaticKEK derives the kek from the static keys of the sender and of the recipient as deriveKEK does,
+// the shared secret Z is taken from the shared secret cache if configured
+func (c *Crypter) deriveStaticKEK(alg, apu []byte, privKey, pubKey *[chacha.KeySize]byte) ([]byte, error) {
+	if c.secrets == nil {
+		return c.deriveKEK(alg, apu, privKey, pubKey)
+	}
+
+	if privKey == nil || pubKey == nil {
+		return nil, errInvalidKey
+	}
+
+	return deriveKEKFromZ(alg, apu, c.secrets.secret(privKey, pubKey))
+}
+
+// deriveKEKFromZ derives the kek from the shared secret Z with the Concat KDF
+func deriveKEKFromZ(alg, apu []byte, z *[chacha.KeySize]byte) ([]byte, error) {
 	// inspired by: github.com/square/go-jose/v3@v3.0.0-20190722231519-723929d55157/cipher/ecdh_es.go
 	// -> DeriveECDHES() call
 	// suppPubInfo is the encoded length of the recipient shared key output size in bits
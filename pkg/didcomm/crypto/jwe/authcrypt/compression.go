@@ -0,0 +1,108 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package authcrypt
+
+import (
+	"bytes"
+	"compress/flate"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// Compression represents a compression algorithm of the payload, set in the "zip" protected header
+type Compression string
+
+// DEF DEFLATE compression (RFC 1951), the only "zip" value registered by RFC 7516
+const DEF = Compression("DEF")
+
+// DefaultMaxDecompressedSize is the default maximum size in bytes of the decompressed payloads, the default
+// maximum envelope size of the wallet
+const DefaultMaxDecompressedSize = 10 << 20
+
+// errDecompressedTooLarge is used when a decompressed payload exceeds the maximum size
+var errDecompressedTooLarge = errors.New("decompressed payload exceeds the maximum size")
+
+// WithCompression compresses the payloads before the encryption, e.g. to reduce the size of the envelopes of
+// large JSON-LD credentials. The payloads are sent uncompressed when the compression doesn't reduce their size.
+func WithCompression(zip Compression) Option {
+	return func(c *Crypter) {
+		c.zip = zip
+	}
+}
+
+// WithMaxDecompressedSize sets the maximum size in bytes of the decompressed payloads, the default is
+// DefaultMaxDecompressedSize.
+func WithMaxDecompressedSize(size int64) Option {
+	return func(c *Crypter) {
+		c.maxDecompressedSize = size
+	}
+}
+
+// compress compresses the payload with the compression of the Crypter, the returned compression is empty
+// if the payload is left uncompressed
+func (c *Crypter) compress(payload []byte) ([]byte, Compression, error) {
+	if c.zip == "" {
+		return payload, "", nil
+	}
+
+	if c.zip != DEF {
+		return nil, "", fmt.Errorf("compression %s: %w", c.zip, errUnsupportedAlg)
+	}
+
+	var buf bytes.Buffer
+
+	w, err := flate.NewWriter(&buf, flate.DefaultCompression)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if _, err = w.Write(payload); err != nil {
+		return nil, "", fmt.Errorf("failed to compress payload: %w", err)
+	}
+
+	if err = w.Close(); err != nil {
+		return nil, "", fmt.Errorf("failed to compress payload: %w", err)
+	}
+
+	if buf.Len() >= len(payload) {
+		return payload, "", nil
+	}
+
+	return buf.Bytes(), c.zip, nil
+}
+
+// decompress decompresses the payload compressed with zip, up to the maximum decompressed size
+func (c *Crypter) decompress(payload []byte, zip Compression) ([]byte, error) {
+	var r io.Reader
+
+	switch zip {
+	case "":
+		return payload, nil
+	case DEF:
+		r = flate.NewReader(bytes.NewReader(payload))
+	default:
+		return nil, fmt.Errorf("compression %s: %w", zip, errUnsupportedAlg)
+	}
+
+	maxSize := c.maxDecompressedSize
+	if maxSize <= 0 {
+		maxSize = DefaultMaxDecompressedSize
+	}
+
+	decompressed, err := ioutil.ReadAll(io.LimitReader(r, maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress payload: %w", err)
+	}
+
+	if int64(len(decompressed)) > maxSize {
+		return nil, fmt.Errorf("%w: %d bytes", errDecompressedTooLarge, maxSize)
+	}
+
+	return decompressed, nil
+}
@@ -0,0 +1,126 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package authcrypt
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/nacl/box"
+
+	jwecrypto "github.com/hyperledger/aries-framework-go/pkg/didcomm/crypto"
+)
+
+func TestCrypter_WithCompression(t *testing.T) {
+	senderPub, senderPriv, err := box.GenerateKey(randReader)
+	require.NoError(t, err)
+
+	recipientPub, recipientPriv, err := box.GenerateKey(randReader)
+	require.NoError(t, err)
+
+	sender := jwecrypto.KeyPair{Pub: senderPub[:], Priv: senderPriv[:]}
+	recipient := jwecrypto.KeyPair{Pub: recipientPub[:], Priv: recipientPriv[:]}
+
+	credential := bytes.Repeat([]byte(`{"@context":["https://www.w3.org/2018/credentials/v1"]}`), 100)
+
+	t.Run("test DEF compressed payload", func(t *testing.T) {
+		crypter, e := New(XC20P, WithCompression(DEF))
+		require.NoError(t, e)
+
+		compressed, e := crypter.Encrypt(credential, sender, [][]byte{recipientPub[:]})
+		require.NoError(t, e)
+
+		plain, e := New(XC20P)
+		require.NoError(t, e)
+
+		uncompressed, e := plain.Encrypt(credential, sender, [][]byte{recipientPub[:]})
+		require.NoError(t, e)
+		require.True(t, len(compressed) < len(uncompressed))
+
+		env := &Envelope{}
+		require.NoError(t, json.Unmarshal(compressed, env))
+
+		c, e := env.Compression()
+		require.NoError(t, e)
+		require.Equal(t, DEF, c)
+
+		// the envelopes are decompressed whatever the compression option of the Crypter
+		payload, e := plain.Decrypt(compressed, recipient)
+		require.NoError(t, e)
+		require.Equal(t, credential, payload)
+	})
+
+	t.Run("test payload left uncompressed when not reduced", func(t *testing.T) {
+		crypter, e := New(XC20P, WithCompression(DEF))
+		require.NoError(t, e)
+
+		envelope, e := crypter.Encrypt([]byte("p"), sender, [][]byte{recipientPub[:]})
+		require.NoError(t, e)
+
+		env := &Envelope{}
+		require.NoError(t, json.Unmarshal(envelope, env))
+
+		c, e := env.Compression()
+		require.NoError(t, e)
+		require.Empty(t, c)
+
+		payload, e := crypter.Decrypt(envelope, recipient)
+		require.NoError(t, e)
+		require.Equal(t, []byte("p"), payload)
+	})
+
+	t.Run("test unsupported compression", func(t *testing.T) {
+		crypter, e := New(XC20P, WithCompression("LZ4"))
+		require.NoError(t, e)
+
+		_, e = crypter.Encrypt(credential, sender, [][]byte{recipientPub[:]})
+		require.True(t, errors.Is(e, errUnsupportedAlg))
+	})
+
+	t.Run("test decompressed payload too large", func(t *testing.T) {
+		crypter, e := New(XC20P, WithCompression(DEF))
+		require.NoError(t, e)
+
+		envelope, e := crypter.Encrypt(credential, sender, [][]byte{recipientPub[:]})
+		require.NoError(t, e)
+
+		limited, e := New(XC20P, WithMaxDecompressedSize(int64(len(credential)-1)))
+		require.NoError(t, e)
+
+		_, e = limited.Decrypt(envelope, recipient)
+		require.True(t, errors.Is(e, errDecompressedTooLarge))
+	})
+}
+
+func TestCrypter_Decompress(t *testing.T) {
+	crypter, err := New(XC20P)
+	require.NoError(t, err)
+
+	t.Run("test unsupported compression", func(t *testing.T) {
+		_, err = crypter.decompress([]byte("payload"), "LZ4")
+		require.True(t, errors.Is(err, errUnsupportedAlg))
+	})
+
+	t.Run("test invalid compressed payloads", func(t *testing.T) {
+		_, err = crypter.decompress([]byte("payload"), "GZIP")
+		require.True(t, errors.Is(err, errUnsupportedAlg))
+
+		_, err = crypter.decompress([]byte("payload"), DEF)
+		require.Error(t, err)
+	})
+
+	t.Run("test invalid protected headers", func(t *testing.T) {
+		_, err = (&Envelope{Protected: "!"}).Compression()
+		require.Error(t, err)
+
+		_, err = (&Envelope{Protected: "e30"}).ContentEncryption()
+		require.Error(t, err)
+	})
+}
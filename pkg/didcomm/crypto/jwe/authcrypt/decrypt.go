@@ -17,6 +17,8 @@ import (
 	chacha "golang.org/x/crypto/chacha20poly1305"
 
 	jwecrypto "github.com/hyperledger/aries-framework-go/pkg/didcomm/crypto"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	jsonwebkey "github.com/hyperledger/aries-framework-go/pkg/doc/jwk"
 )
 
 // Decrypt will JWE decode the envelope argument for the recipientPrivKey and validates
@@ -25,26 +27,33 @@ import (
 // encrypted CEK.
 // The current recipient is the one with the sender's encrypted key that successfully
 // decrypts with recipientKeyPair.Priv Key.
-func (c *Crypter) Decrypt(envelope []byte, recipientKeyPair jwecrypto.KeyPair) ([]byte, error) { //nolint:lll,funlen
+func (c *Crypter) Decrypt(envelope []byte, recipientKeyPair jwecrypto.KeyPair) ([]byte, error) {
+	payload, _, err := c.DecryptWithSender(envelope, recipientKeyPair)
+	return payload, err
+}
+
+// DecryptWithSender decrypts the envelope as Decrypt does and returns the public key of the sender
+// along with the payload.
+func (c *Crypter) DecryptWithSender(envelope []byte, recipientKeyPair jwecrypto.KeyPair) ([]byte, []byte, error) { //nolint:lll,funlen
 	if !jwecrypto.IsKeyPairValid(recipientKeyPair) {
-		return nil, errInvalidKeypair
+		return nil, nil, errInvalidKeypair
 	}
 
 	jwe := &Envelope{}
 	err := json.Unmarshal(envelope, jwe)
 	if err != nil {
-		return nil, fmt.Errorf("failed to decrypt message: %w", err)
+		return nil, nil, fmt.Errorf("failed to decrypt message: %w", err)
 	}
 	pubK := new([chacha.KeySize]byte)
 	copy(pubK[:], recipientKeyPair.Pub)
 	recipient, err := c.findRecipient(jwe.Recipients, pubK)
 	if err != nil {
-		return nil, fmt.Errorf("failed to decrypt message: %w", err)
+		return nil, nil, fmt.Errorf("failed to decrypt message: %w", err)
 	}
 
 	senderKey, err := c.decryptSPK(recipientKeyPair, recipient.Header.SPK)
 	if err != nil {
-		return nil, fmt.Errorf("failed to decrypt sender key: %w", err)
+		return nil, nil, fmt.Errorf("failed to decrypt sender key: %w", err)
 	}
 
 	// senderKey must not be empty to proceed
@@ -54,18 +63,23 @@ func (c *Crypter) Decrypt(envelope []byte, recipientKeyPair jwecrypto.KeyPair) (
 
 		sharedKey, er := c.decryptSharedKey(recipientKeyPair, &senderPubKey, recipient)
 		if er != nil {
-			return nil, fmt.Errorf("failed to decrypt shared key: %w", er)
+			return nil, nil, fmt.Errorf("failed to decrypt shared key: %w", er)
 		}
 
 		symOutput, er := c.decryptPayload(sharedKey, jwe)
 		if er != nil {
-			return nil, fmt.Errorf("failed to decrypt message: %w", er)
+			return nil, nil, fmt.Errorf("failed to decrypt message: %w", er)
 		}
 
-		return symOutput, nil
+		payload, er := c.decompressPayload(symOutput, jwe)
+		if er != nil {
+			return nil, nil, fmt.Errorf("failed to decrypt message: %w", er)
+		}
+
+		return payload, senderPubKey[:], nil
 	}
 
-	return nil, errors.New("failed to decrypt message - invalid sender key in envelope")
+	return nil, nil, errors.New("failed to decrypt message - invalid sender key in envelope")
 }
 
 func (c *Crypter) decryptPayload(cek []byte, jwe *Envelope) ([]byte, error) {
@@ -75,27 +89,61 @@ func (c *Crypter) decryptPayload(cek []byte, jwe *Envelope) ([]byte, error) {
 	}
 
 	pldAAD := jwe.Protected + "." + jwe.AAD
-	payload, er := base64.RawURLEncoding.DecodeString(jwe.CipherText)
+	nonce, er := base64.RawURLEncoding.DecodeString(jwe.IV)
 	if er != nil {
 		return nil, er
 	}
-	tag, er := base64.RawURLEncoding.DecodeString(jwe.Tag)
+
+	// decode cipherText and tag into a single preallocated buffer to open it in place
+	cipherTextLen := base64.RawURLEncoding.DecodedLen(len(jwe.CipherText))
+	tagLen := base64.RawURLEncoding.DecodedLen(len(jwe.Tag))
+	buf := c.getBuffer(cipherTextLen + tagLen)[:cipherTextLen+tagLen]
+	defer c.putBuffer(buf)
+
+	n, er := base64.RawURLEncoding.Decode(buf, []byte(jwe.CipherText))
 	if er != nil {
 		return nil, er
 	}
-	nonce, er := base64.RawURLEncoding.DecodeString(jwe.IV)
+	m, er := base64.RawURLEncoding.Decode(buf[n:], []byte(jwe.Tag))
+	if er != nil {
+		return nil, er
+	}
+	buf = buf[:n+m]
+
+	payload, er := crypter.Open(buf[:0], nonce, buf, []byte(pldAAD))
 	if er != nil {
 		return nil, er
 	}
-	payload = append(payload, tag...)
-	return crypter.Open(nil, nonce, payload, []byte(pldAAD))
+
+	if c.bufPool == nil {
+		return payload, nil
+	}
+
+	// the payload shares the memory of buf, it's copied out so buf can be returned to the pool
+	return append([]byte(nil), payload...), nil
+}
+
+// decompressPayload decompresses the decrypted payload if compressed as set in the protected headers
+func (c *Crypter) decompressPayload(payload []byte, jwe *Envelope) ([]byte, error) {
+	zip, err := jwe.Compression()
+	if err != nil {
+		return nil, err
+	}
+
+	return c.decompress(payload, zip)
 }
 
 // findRecipient will loop through jweRecipients and returns the first matching key from recipients
 func (c *Crypter) findRecipient(jweRecipients []Recipient, recipientPubKey *[chacha.KeySize]byte) (*Recipient, error) {
+	// kid may also be the JWK thumbprint of the key, refer WithThumbprintKID()
+	thumbprintKID, err := jsonwebkey.KeyID(did.X25519KeyAgreementKey2019, recipientPubKey[:])
+	if err != nil {
+		return nil, err
+	}
+
 	for _, recipient := range jweRecipients {
 		recipient := recipient // pin!
-		if bytes.Equal(recipientPubKey[:], base58.Decode(recipient.Header.KID)) {
+		if recipient.Header.KID == thumbprintKID || bytes.Equal(recipientPubKey[:], base58.Decode(recipient.Header.KID)) {
 			return &recipient, nil
 		}
 	}
@@ -129,7 +177,7 @@ func (c *Crypter) decryptSharedKey(recipientKp jwecrypto.KeyPair, senderPubKey *
 	copy(privK[:], recipientKp.Priv)
 
 	// derive an ephemeral key for the recipient
-	kek, err := c.deriveKEK([]byte(c.alg), apu, privK, senderPubKey)
+	kek, err := c.deriveStaticKEK([]byte(c.alg), apu, privK, senderPubKey)
 	if err != nil {
 		return nil, err
 	}
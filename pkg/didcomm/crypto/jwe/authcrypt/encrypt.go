@@ -19,6 +19,8 @@ import (
 	"golang.org/x/crypto/poly1305"
 
 	jwecrypto "github.com/hyperledger/aries-framework-go/pkg/didcomm/crypto"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	jsonwebkey "github.com/hyperledger/aries-framework-go/pkg/doc/jwk"
 )
 
 // Encrypt will JWE encode the payload argument for the sender and recipients
@@ -30,10 +32,16 @@ func (c *Crypter) Encrypt(payload []byte, sender jwecrypto.KeyPair, recipients [
 		return nil, fmt.Errorf("failed to encrypt message: %w", err)
 	}
 
+	payload, zip, err := c.compress(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt message: %w", err)
+	}
+
 	headers := jweHeaders{
 		Typ: "prs.hyperledger.aries-auth-message",
 		Alg: "ECDH-SS+" + string(c.alg) + "KW",
 		Enc: string(c.alg),
+		Zip: string(zip),
 	}
 
 	chachaRecipients, err := convertRecipients(recipients)
@@ -54,7 +62,7 @@ func (c *Crypter) Encrypt(payload []byte, sender jwecrypto.KeyPair, recipients [
 
 	// generate a new nonce for this encryption
 	nonce := make([]byte, c.nonceSize)
-	_, err = randReader.Read(nonce)
+	_, err = c.random().Read(nonce)
 	if err != nil {
 		return nil, err
 	}
@@ -63,7 +71,7 @@ func (c *Crypter) Encrypt(payload []byte, sender jwecrypto.KeyPair, recipients [
 	cek := &[chacha.KeySize]byte{}
 
 	// generate a cek for encryption (it will be treated as a symmetric key)
-	_, err = randReader.Read(cek[:])
+	_, err = c.random().Read(cek[:])
 	if err != nil {
 		return nil, err
 	}
@@ -76,10 +84,11 @@ func (c *Crypter) Encrypt(payload []byte, sender jwecrypto.KeyPair, recipients [
 
 	// encrypt payload using generated nonce, payload and its AAD
 	// the output is a []byte containing the cipherText + tag
-	symOutput := crypter.Seal(nil, nonce, payload, []byte(pldAAD))
+	symOutput := crypter.Seal(c.getBuffer(len(payload)+poly1305.TagSize), nonce, payload, []byte(pldAAD))
 
 	tagEncoded := extractTag(symOutput)
 	cipherTextEncoded := extractCipherText(symOutput)
+	c.putBuffer(symOutput)
 
 	// now build, encode recipients and include the encrypted cek (with a recipient's ephemeral key)
 	encRec, err := c.encodeRecipients(cek, chachaRecipients, sender)
@@ -202,7 +211,7 @@ func (c *Crypter) encodeRecipients(sharedSymKey *[chacha.KeySize]byte, recipient
 func (c *Crypter) encodeRecipient(sharedSymKey, recipientKey *[chacha.KeySize]byte, senderKp jwecrypto.KeyPair) (*Recipient, error) { //nolint:lll
 	// generate a random APU value (Agreement PartyUInfo: https://tools.ietf.org/html/rfc7518#section-4.6.1.2)
 	apu := make([]byte, 64)
-	_, err := randReader.Read(apu)
+	_, err := c.random().Read(apu)
 	if err != nil {
 		return nil, err
 	}
@@ -210,7 +219,7 @@ func (c *Crypter) encodeRecipient(sharedSymKey, recipientKey *[chacha.KeySize]by
 	privK := new([chacha.KeySize]byte)
 	copy(privK[:], senderKp.Priv)
 	// derive an ephemeral key for the recipient
-	kek, err := c.deriveKEK([]byte(c.alg), apu, privK, recipientKey)
+	kek, err := c.deriveStaticKEK([]byte(c.alg), apu, privK, recipientKey)
 	if err != nil {
 		return nil, err
 	}
@@ -226,6 +235,15 @@ func (c *Crypter) encodeRecipient(sharedSymKey, recipientKey *[chacha.KeySize]by
 	return c.buildRecipient(sharedKeyCipher, apu, nonce, tag, pubK, recipientKey)
 }
 
+// recipientKID returns the "kid" header of the recipient key
+func (c *Crypter) recipientKID(recipientKey *[chacha.KeySize]byte) (string, error) {
+	if !c.thumbprintKID {
+		return base58.Encode(recipientKey[:]), nil
+	}
+
+	return jsonwebkey.KeyID(did.X25519KeyAgreementKey2019, recipientKey[:])
+}
+
 // buildRecipient will build a proper JSON formatted Recipient
 func (c *Crypter) buildRecipient(key string, apu []byte, nonceEncoded, tagEncoded string, senderPubKey, recipientKey *[chacha.KeySize]byte) (*Recipient, error) { //nolint:lll
 	spkEncoded, err := c.generateSPK(recipientKey, senderPubKey)
@@ -233,11 +251,16 @@ func (c *Crypter) buildRecipient(key string, apu []byte, nonceEncoded, tagEncode
 		return nil, err
 	}
 
+	kid, err := c.recipientKID(recipientKey)
+	if err != nil {
+		return nil, err
+	}
+
 	recipientHeaders := RecipientHeaders{
 		APU: base64.RawURLEncoding.EncodeToString(apu),
 		IV:  nonceEncoded,
 		Tag: tagEncoded,
-		KID: base58.Encode(recipientKey[:]),
+		KID: kid,
 		SPK: spkEncoded,
 	}
 
@@ -263,7 +286,7 @@ func (c *Crypter) encryptSymKey(kek, symKey []byte) (string, string, string, err
 
 	// create a new nonce
 	nonce := make([]byte, c.nonceSize)
-	_, err = randReader.Read(nonce)
+	_, err = c.random().Read(nonce)
 	if err != nil {
 		return "", "", "", err
 	}
@@ -0,0 +1,51 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package authcrypt
+
+import (
+	"fmt"
+
+	jwecrypto "github.com/hyperledger/aries-framework-go/pkg/didcomm/crypto"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+)
+
+// EncryptToDIDs will JWE encode the payload for all the key agreement keys of the recipients DID documents.
+// Refer WithKeyAgreementDerivation() for documents without keyAgreement keys.
+func (c *Crypter) EncryptToDIDs(payload []byte, sender jwecrypto.KeyPair, recipients []*did.Doc) ([]byte, error) {
+	recipientKeys, err := c.keyAgreementKeys(recipients)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt message: %w", err)
+	}
+
+	return c.Encrypt(payload, sender, recipientKeys)
+}
+
+func (c *Crypter) keyAgreementKeys(docs []*did.Doc) ([][]byte, error) {
+	var opts []did.KeyAgreementOpt
+	if c.deriveKeyAgreement {
+		opts = append(opts, did.WithEd25519Derivation())
+	}
+
+	var keys [][]byte
+
+	for _, doc := range docs {
+		pks, err := doc.KeyAgreementKeys(opts...)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(pks) == 0 {
+			return nil, fmt.Errorf("%w - no key agreement key for %s", errRecipientNotFound, doc.ID)
+		}
+
+		for _, pk := range pks {
+			keys = append(keys, pk.Value)
+		}
+	}
+
+	return keys, nil
+}
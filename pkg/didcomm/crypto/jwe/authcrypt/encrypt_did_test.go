@@ -0,0 +1,86 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package authcrypt
+
+import (
+	"testing"
+
+	"github.com/agl/ed25519"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/nacl/box"
+
+	jwecrypto "github.com/hyperledger/aries-framework-go/pkg/didcomm/crypto"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	"github.com/hyperledger/aries-framework-go/pkg/internal/cryptoutil"
+)
+
+func TestEncryptToDIDs(t *testing.T) {
+	senderPub, senderPriv, err := box.GenerateKey(randReader)
+	require.NoError(t, err)
+	sender := jwecrypto.KeyPair{Priv: senderPriv[:], Pub: senderPub[:]}
+
+	edPub, edPriv, err := ed25519.GenerateKey(randReader)
+	require.NoError(t, err)
+
+	recPub, err := cryptoutil.PublicEd25519toCurve25519(edPub[:])
+	require.NoError(t, err)
+	recPriv, err := cryptoutil.SecretEd25519toCurve25519(edPriv[:])
+	require.NoError(t, err)
+	recipient := jwecrypto.KeyPair{Priv: recPriv, Pub: recPub}
+
+	doc := &did.Doc{ID: "did:example:123", PublicKey: []did.PublicKey{{ID: "did:example:123#key-1",
+		Type: did.Ed25519VerificationKey2018, Controller: "did:example:123", Value: edPub[:]}}}
+
+	t.Run("test encrypt with derived key agreement key", func(t *testing.T) {
+		crypter, err := New(XC20P, WithKeyAgreementDerivation())
+		require.NoError(t, err)
+
+		enc, err := crypter.EncryptToDIDs([]byte("lorem ipsum"), sender, []*did.Doc{doc})
+		require.NoError(t, err)
+
+		dec, err := crypter.Decrypt(enc, recipient)
+		require.NoError(t, err)
+		require.Equal(t, []byte("lorem ipsum"), dec)
+	})
+
+	t.Run("test encrypt with key agreement key", func(t *testing.T) {
+		crypter, err := New(XC20P)
+		require.NoError(t, err)
+
+		kaDoc := &did.Doc{ID: "did:example:456", KeyAgreement: []did.VerificationMethod{{PublicKey: did.PublicKey{
+			ID: "did:example:456#key-1", Type: did.X25519KeyAgreementKey2019, Value: recPub}}}}
+
+		enc, err := crypter.EncryptToDIDs([]byte("lorem ipsum"), sender, []*did.Doc{kaDoc})
+		require.NoError(t, err)
+
+		dec, err := crypter.Decrypt(enc, recipient)
+		require.NoError(t, err)
+		require.Equal(t, []byte("lorem ipsum"), dec)
+	})
+
+	t.Run("test no key agreement key without derivation", func(t *testing.T) {
+		crypter, err := New(XC20P)
+		require.NoError(t, err)
+
+		enc, err := crypter.EncryptToDIDs([]byte("lorem ipsum"), sender, []*did.Doc{doc})
+		require.EqualError(t, err,
+			"failed to encrypt message: recipient not found - no key agreement key for did:example:123")
+		require.Empty(t, enc)
+	})
+
+	t.Run("test invalid Ed25519 key", func(t *testing.T) {
+		crypter, err := New(XC20P, WithKeyAgreementDerivation())
+		require.NoError(t, err)
+
+		badDoc := &did.Doc{ID: "did:example:789", PublicKey: []did.PublicKey{{ID: "did:example:789#key-1",
+			Type: did.Ed25519VerificationKey2018, Value: []byte("bad key")}}}
+
+		_, err = crypter.EncryptToDIDs([]byte("lorem ipsum"), sender, []*did.Doc{badDoc})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "derive key agreement key from did:example:789#key-1")
+	})
+}
@@ -23,7 +23,7 @@ func (c *Crypter) generateSPK(recipientPubKey, senderPubKey *[chacha.KeySize]byt
 	}
 
 	// generate ephemeral asymmetric keys
-	epk, esk, err := box.GenerateKey(randReader)
+	epk, esk, err := box.GenerateKey(c.random())
 	if err != nil {
 		return "", err
 	}
@@ -36,7 +36,7 @@ func (c *Crypter) generateSPK(recipientPubKey, senderPubKey *[chacha.KeySize]byt
 
 	// generate a sharedSymKey for encryption
 	sharedSymKey := &[chacha.KeySize]byte{}
-	_, err = randReader.Read(sharedSymKey[:])
+	_, err = c.random().Read(sharedSymKey[:])
 	if err != nil {
 		return "", err
 	}
@@ -92,7 +92,7 @@ func (c *Crypter) buildJWKHeaders(epk *[32]byte, kNonceEncoded, kTagEncoded stri
 func (c *Crypter) encryptSenderJWK(encKey, headers string, senderJWKJSON, sharedSymKey []byte) (string, error) {
 	// create a new nonce
 	nonce := make([]byte, c.nonceSize)
-	_, err := randReader.Read(nonce)
+	_, err := c.random().Read(nonce)
 	if err != nil {
 		return "", err
 	}
@@ -0,0 +1,134 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package authcrypt
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"sync"
+	"time"
+
+	chacha "golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/curve25519"
+
+	"github.com/hyperledger/aries-framework-go/pkg/common/clock"
+)
+
+// SharedSecretCache keeps the X25519 shared secrets (Z) computed between the static keys of the agent and the
+// keys of its counterparties, so the scalar multiplication is done once per key pair instead of once per
+// message. The cache is bounded: the least recently used secrets are evicted first and the secrets expire
+// after the TTL. A cache can be shared by several Crypters.
+type SharedSecretCache struct {
+	size    int
+	ttl     time.Duration
+	clock   clock.Clock
+	lock    sync.Mutex
+	entries map[secretKey]*list.Element
+	lru     *list.List
+}
+
+// secretKey identifies the shared secret by the hash of the private key of the agent and the key of the
+// counterparty, the private key itself isn't kept by the cache
+type secretKey struct {
+	own   [sha256.Size]byte
+	their [chacha.KeySize]byte
+}
+
+type secretEntry struct {
+	key    secretKey
+	secret [chacha.KeySize]byte
+	expiry time.Time
+}
+
+// NewSharedSecretCache returns a cache holding up to size shared secrets, zero ttl disables the expiry
+func NewSharedSecretCache(size int, ttl time.Duration) *SharedSecretCache {
+	return &SharedSecretCache{
+		size:    size,
+		ttl:     ttl,
+		clock:   clock.System(),
+		entries: make(map[secretKey]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// Len returns the number of cached shared secrets
+func (s *SharedSecretCache) Len() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.lru.Len()
+}
+
+// secret returns the shared secret of the private key and of the public key of the counterparty. The secret is
+// computed without holding the lock so the crypters sharing the cache aren't serialized.
+func (s *SharedSecretCache) secret(privKey, pubKey *[chacha.KeySize]byte) *[chacha.KeySize]byte {
+	key := secretKey{own: sha256.Sum256(privKey[:]), their: *pubKey}
+
+	if z, ok := s.get(key); ok {
+		return z
+	}
+
+	entry := &secretEntry{key: key}
+	curve25519.ScalarMult(&entry.secret, privKey, pubKey)
+
+	s.put(entry)
+
+	z := entry.secret
+
+	return &z
+}
+
+// get returns the secret of the key unless missing or expired
+func (s *SharedSecretCache) get(key secretKey) (*[chacha.KeySize]byte, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	e, ok := s.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry := e.Value.(*secretEntry)
+	if s.ttl != 0 && !s.clock.Now().Before(entry.expiry) {
+		s.remove(e)
+
+		return nil, false
+	}
+
+	s.lru.MoveToFront(e)
+
+	z := entry.secret
+
+	return &z, true
+}
+
+// put caches the entry, replacing the entry of the same key computed concurrently
+func (s *SharedSecretCache) put(entry *secretEntry) {
+	if s.size <= 0 {
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	entry.expiry = s.clock.Now().Add(s.ttl)
+
+	if e, ok := s.entries[entry.key]; ok {
+		s.remove(e)
+	}
+
+	s.entries[entry.key] = s.lru.PushFront(entry)
+
+	for s.lru.Len() > s.size {
+		s.remove(s.lru.Back())
+	}
+}
+
+func (s *SharedSecretCache) remove(e *list.Element) {
+	s.lru.Remove(e)
+	delete(s.entries, e.Value.(*secretEntry).key)
+}
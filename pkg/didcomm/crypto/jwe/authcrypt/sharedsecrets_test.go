@@ -0,0 +1,156 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package authcrypt
+
+import (
+	"crypto/sha256"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	chacha "golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/curve25519"
+	"golang.org/x/crypto/nacl/box"
+
+	jwecrypto "github.com/hyperledger/aries-framework-go/pkg/didcomm/crypto"
+	mockclock "github.com/hyperledger/aries-framework-go/pkg/internal/mock/clock"
+)
+
+func TestSharedSecretCache(t *testing.T) {
+	_, ownPriv, err := box.GenerateKey(randReader)
+	require.NoError(t, err)
+
+	newKey := func() *[chacha.KeySize]byte {
+		pub, _, e := box.GenerateKey(randReader)
+		require.NoError(t, e)
+
+		return pub
+	}
+
+	t.Run("test cached secret", func(t *testing.T) {
+		cache := NewSharedSecretCache(10, 0)
+		theirPub := newKey()
+
+		expected := new([chacha.KeySize]byte)
+		curve25519.ScalarMult(expected, ownPriv, theirPub)
+
+		require.Equal(t, expected, cache.secret(ownPriv, theirPub))
+		require.Equal(t, expected, cache.secret(ownPriv, theirPub))
+		require.Equal(t, 1, cache.Len())
+	})
+
+	t.Run("test least recently used secret evicted", func(t *testing.T) {
+		cache := NewSharedSecretCache(2, 0)
+		key1, key2, key3 := newKey(), newKey(), newKey()
+
+		cache.secret(ownPriv, key1)
+		cache.secret(ownPriv, key2)
+		cache.secret(ownPriv, key1)
+		cache.secret(ownPriv, key3)
+		require.Equal(t, 2, cache.Len())
+
+		_, ok := cache.entries[secretKey{own: sha256.Sum256(ownPriv[:]), their: *key2}]
+		require.False(t, ok)
+
+		_, ok = cache.entries[secretKey{own: sha256.Sum256(ownPriv[:]), their: *key1}]
+		require.True(t, ok)
+	})
+
+	t.Run("test expired secret", func(t *testing.T) {
+		clock := &mockclock.Clock{Time: time.Now()}
+		cache := NewSharedSecretCache(2, time.Minute)
+		cache.clock = clock
+		theirPub := newKey()
+
+		cache.secret(ownPriv, theirPub)
+		entry := cache.entries[secretKey{own: sha256.Sum256(ownPriv[:]), their: *theirPub}].Value.(*secretEntry)
+		require.Equal(t, clock.Now().Add(time.Minute), entry.expiry)
+
+		clock.Add(2 * time.Minute)
+		cache.secret(ownPriv, theirPub)
+		entry = cache.entries[secretKey{own: sha256.Sum256(ownPriv[:]), their: *theirPub}].Value.(*secretEntry)
+		require.Equal(t, clock.Now().Add(time.Minute), entry.expiry)
+		require.Equal(t, 1, cache.Len())
+	})
+
+	t.Run("test secrets keyed by the private key", func(t *testing.T) {
+		cache := NewSharedSecretCache(10, 0)
+		theirPub := newKey()
+
+		_, otherPriv, err := box.GenerateKey(randReader)
+		require.NoError(t, err)
+
+		expected := new([chacha.KeySize]byte)
+		curve25519.ScalarMult(expected, otherPriv, theirPub)
+
+		cache.secret(ownPriv, theirPub)
+		require.Equal(t, expected, cache.secret(otherPriv, theirPub))
+		require.Equal(t, 2, cache.Len())
+	})
+
+	t.Run("test concurrent secrets", func(t *testing.T) {
+		cache := NewSharedSecretCache(10, 0)
+		theirPub := newKey()
+
+		expected := new([chacha.KeySize]byte)
+		curve25519.ScalarMult(expected, ownPriv, theirPub)
+
+		var wg sync.WaitGroup
+
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				require.Equal(t, expected, cache.secret(ownPriv, theirPub))
+			}()
+		}
+
+		wg.Wait()
+		require.Equal(t, 1, cache.Len())
+	})
+
+	t.Run("test zero size disables the cache", func(t *testing.T) {
+		cache := NewSharedSecretCache(0, 0)
+		require.NotNil(t, cache.secret(ownPriv, newKey()))
+		require.Equal(t, 0, cache.Len())
+	})
+}
+
+func TestCrypter_SharedSecretCache(t *testing.T) {
+	senderPub, senderPriv, err := box.GenerateKey(randReader)
+	require.NoError(t, err)
+
+	recipientPub, recipientPriv, err := box.GenerateKey(randReader)
+	require.NoError(t, err)
+
+	senderCache := NewSharedSecretCache(10, time.Hour)
+	sender, err := New(XC20P, WithSharedSecretCache(senderCache))
+	require.NoError(t, err)
+
+	recipientCache := NewSharedSecretCache(10, time.Hour)
+	recipient, err := New(XC20P, WithSharedSecretCache(recipientCache))
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		enc, err := sender.Encrypt([]byte("payload"), jwecrypto.KeyPair{Priv: senderPriv[:], Pub: senderPub[:]},
+			[][]byte{recipientPub[:]})
+		require.NoError(t, err)
+
+		dec, err := recipient.Decrypt(enc, jwecrypto.KeyPair{Priv: recipientPriv[:], Pub: recipientPub[:]})
+		require.NoError(t, err)
+		require.Equal(t, []byte("payload"), dec)
+	}
+
+	require.Equal(t, 1, senderCache.Len())
+	require.Equal(t, 1, recipientCache.Len())
+
+	_, err = sender.deriveStaticKEK([]byte(XC20P), nil, nil, recipientPub)
+	require.EqualError(t, err, errInvalidKey.Error())
+}
@@ -14,8 +14,8 @@ import (
 	"io"
 
 	"github.com/hyperledger/aries-framework-go/pkg/didcomm/crypto"
+	"github.com/hyperledger/aries-framework-go/pkg/internal/cryptoutil"
 
-	"github.com/agl/ed25519/extra25519"
 	"golang.org/x/crypto/blake2b"
 	"golang.org/x/crypto/nacl/box"
 )
@@ -44,12 +44,30 @@ type Crypter struct {
 	randSource io.Reader
 }
 
+// Option configures the Crypter
+type Option func(c *Crypter)
+
+// WithRandSource sets the random source of the nonces, content encryption keys and ephemeral keys, e.g.
+// a deterministic reader generating reproducible envelopes in golden tests. It must be cryptographically
+// secure in production, the default is crypto/rand.
+func WithRandSource(source io.Reader) Option {
+	return func(c *Crypter) {
+		c.randSource = source
+	}
+}
+
 // New will create a Crypter that encrypts messages using the legacy Aries format
 // Note: legacy crypter does not support XChacha20Poly1035 (XC20P), only Chacha20Poly1035 (C20P)
-func New() *Crypter {
-	return &Crypter{
+func New(opts ...Option) *Crypter {
+	c := &Crypter{
 		randSource: rand.Reader,
 	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
 }
 
 func (c *Crypter) setRandSource(source io.Reader) {
@@ -104,28 +122,31 @@ func keyToEdKey(keyPair crypto.KeyPair) (*keyPairEd25519, error) {
 }
 
 // publicEd25519toCurve25519 takes an Ed25519 public key and provides the corresponding Curve25519 public key
-//  This function wraps PublicKeyToCurve25519 from Adam Langley's ed25519 repo: https://github.com/agl/ed25519
 func publicEd25519toCurve25519(pub *publicEd25519) (*publicCurve25519, error) {
 	if pub == nil {
 		return nil, errors.New("key is nil")
 	}
-	pkOut := new([CurveKeySize]byte)
-	success := extra25519.PublicKeyToCurve25519(pkOut, (*[ed25519.PublicKeySize]byte)(pub))
-	if !success {
-		return nil, errors.New("failed to convert public key")
+	pkOut, err := cryptoutil.PublicEd25519toCurve25519(pub[:])
+	if err != nil {
+		return nil, err
 	}
-	return (*publicCurve25519)(pkOut), nil
+	pk := publicCurve25519{}
+	copy(pk[:], pkOut)
+	return &pk, nil
 }
 
 // secretEd25519toCurve25519 converts a secret key from Ed25519 to curve25519 format
-//  This function wraps PrivateKeyToCurve25519 from Adam Langley's ed25519 repo: https://github.com/agl/ed25519
 func secretEd25519toCurve25519(priv *privateEd25519) (*privateCurve25519, error) {
 	if priv == nil {
 		return nil, errors.New("key is nil")
 	}
-	sKOut := new([CurveKeySize]byte)
-	extra25519.PrivateKeyToCurve25519(sKOut, (*[ed25519.PrivateKeySize]byte)(priv))
-	return (*privateCurve25519)(sKOut), nil
+	sKOut, err := cryptoutil.SecretEd25519toCurve25519(priv[:])
+	if err != nil {
+		return nil, err
+	}
+	sk := privateCurve25519{}
+	copy(sk[:], sKOut)
+	return &sk, nil
 }
 
 func makeNonce(pub1, pub2 []byte) ([]byte, error) {
@@ -170,8 +170,7 @@ func TestEncrypt(t *testing.T) {
 		source := insecurerand.NewSource(5937493) // just a random const
 		constRand := insecurerand.New(source)
 
-		crypter := New()
-		crypter.setRandSource(constRand)
+		crypter := New(WithRandSource(constRand))
 		require.NotEmpty(t, crypter)
 		enc, err := crypter.Encrypt(nil, *senderKey, [][]byte{recipientKey.Pub})
 		require.NoError(t, err)
@@ -7,6 +7,11 @@ SPDX-License-Identifier: Apache-2.0
 package dispatcher
 
 import (
+	"context"
+
+	"github.com/hyperledger/aries-framework-go/pkg/common/metrics"
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/codec"
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/audit"
 	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/service"
 	"github.com/hyperledger/aries-framework-go/pkg/didcomm/transport"
 	"github.com/hyperledger/aries-framework-go/pkg/wallet"
@@ -19,9 +24,80 @@ type Service interface {
 	Name() string
 }
 
-// Outbound interface
+// ServiceDescriptor describes a registered protocol service
+type ServiceDescriptor struct {
+	// Name of the service
+	Name string `json:"name"`
+	// MessageTypes accepted by the service, empty if the service doesn't describe them
+	MessageTypes []string `json:"messageTypes,omitempty"`
+	// Version of the protocol implemented by the service
+	Version string `json:"version,omitempty"`
+	// Roles of the protocol the service can play, empty if the service doesn't describe them
+	Roles []string `json:"roles,omitempty"`
+}
+
+// Describer is optionally implemented by the protocol services to describe the message types they accept
+// and the version of the protocol they implement
+type Describer interface {
+	MessageTypes() []string
+	Version() string
+}
+
+// RoleDescriber is optionally implemented by the protocol services to describe the roles of the protocol
+// they can play, e.g. the issuer and the holder of the issue-credential protocol
+type RoleDescriber interface {
+	Roles() []string
+}
+
+// MessageRoleResolver is optionally implemented by the protocol services describing their roles, it returns
+// the role played by the agent handling the inbound message type, empty if the message is handled in any role
+// (e.g. the problem reports)
+type MessageRoleResolver interface {
+	MessageRole(msgType string) string
+}
+
+// FormatAcceptor is optionally implemented by the protocol services handling the messages of some envelope formats
+// only, e.g. the services of a protocol version. The inbound messages of the other formats are dispatched to the
+// next service accepting their type.
+type FormatAcceptor interface {
+	AcceptFormat(format string) bool
+}
+
+// AcceptsFormat returns true if the service handles the messages received in the envelopes of the format
+func AcceptsFormat(svc Service, format string) bool {
+	if a, ok := svc.(FormatAcceptor); ok {
+		return a.AcceptFormat(format)
+	}
+
+	return true
+}
+
+// Describe returns the descriptor of the protocol service
+func Describe(svc Service) ServiceDescriptor {
+	descriptor := ServiceDescriptor{Name: svc.Name()}
+
+	if d, ok := svc.(Describer); ok {
+		descriptor.MessageTypes = d.MessageTypes()
+		descriptor.Version = d.Version()
+	}
+
+	if d, ok := svc.(RoleDescriber); ok {
+		descriptor.Roles = d.Roles()
+	}
+
+	return descriptor
+}
+
+// Outbound interface, the context cancels the send and carries the values of the caller (e.g. trace)
+// to the outbound transport
 type Outbound interface {
-	Send(interface{}, string, *service.Destination) error
+	Send(context.Context, interface{}, string, *service.Destination) error
+}
+
+// BatchOutbound is optionally implemented by the outbound dispatchers sending a message to many destinations
+// at once, e.g. the broadcast notifications
+type BatchOutbound interface {
+	SendToMany(context.Context, interface{}, string, []*service.Destination, ...BatchOpt) error
 }
 
 // Provider interface for outbound ctx
@@ -30,5 +106,22 @@ type Provider interface {
 	OutboundTransports() []transport.OutboundTransport
 }
 
+// CodecProvider is optionally implemented by the provider to encode the payloads with the codecs
+// supported by the recipients
+type CodecProvider interface {
+	Codecs() []codec.Codec
+}
+
+// MetricsProvider is optionally implemented by the provider to record the metrics of the outbound messages
+type MetricsProvider interface {
+	MetricsProvider() metrics.Provider
+}
+
+// AuditStoreProvider is optionally implemented by the provider to record the outbound messages in the audit trail,
+// nil if the audit trail isn't enabled
+type AuditStoreProvider interface {
+	AuditStore() *audit.Store
+}
+
 // OutboundCreator method to create new outbound dispatcher service
 type OutboundCreator func(prov Provider) (Outbound, error)
@@ -0,0 +1,187 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package dispatcher
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/codec"
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/service"
+)
+
+// maxConcurrentDeliveries is the number of destinations SendToMany delivers to concurrently
+const maxConcurrentDeliveries = 10
+
+// BatchError is returned by SendToMany when the message couldn't be sent to some of the destinations
+type BatchError struct {
+	// Errs are the errors of the failed destinations by index of the destination
+	Errs map[int]error
+}
+
+func (e *BatchError) Error() string {
+	indexes := make([]int, 0, len(e.Errs))
+	for i := range e.Errs {
+		indexes = append(indexes, i)
+	}
+
+	sort.Ints(indexes)
+
+	errs := make([]string, len(indexes))
+	for i, index := range indexes {
+		errs[i] = fmt.Sprintf("destination %d: %s", index, e.Errs[index])
+	}
+
+	return fmt.Sprintf("failed to send msg to %d destinations: %s", len(errs), strings.Join(errs, "; "))
+}
+
+// batch is the envelope of a destination, or the envelope shared by the destinations of a connection
+type batch struct {
+	recipientKeys []string
+	once          sync.Once
+	packedMsg     []byte
+	err           error
+}
+
+// batchOpts holds the options of SendToMany
+type batchOpts struct {
+	shared bool
+}
+
+// BatchOpt is the option of SendToMany
+type BatchOpt func(opts *batchOpts)
+
+// WithSharedEnvelope option packs the message once for the recipients of all the destinations, e.g. the devices
+// of a single connection. Every recipient can read the keys of the others in the envelope, so the destinations
+// must belong to the same connection; they must also have the same codec, encryption algorithms and compression.
+func WithSharedEnvelope() BatchOpt {
+	return func(opts *batchOpts) {
+		opts.shared = true
+	}
+}
+
+// SendToMany sends the message to the destinations, the deliveries are made concurrently. The message is packed
+// for each destination unless WithSharedEnvelope is passed. The message is recorded in the audit trail for each
+// destination if enabled. A *BatchError is returned if the message couldn't be sent to some of the destinations.
+func (o *OutboundDispatcher) SendToMany(ctx context.Context, msg interface{}, senderVerKey string,
+	destinations []*service.Destination, opts ...BatchOpt) error {
+	batchOf, err := o.batches(destinations, opts...)
+	if err != nil {
+		return err
+	}
+
+	var (
+		wg   sync.WaitGroup
+		lock sync.Mutex
+		errs = make(map[int]error)
+		sem  = make(chan struct{}, maxConcurrentDeliveries)
+	)
+
+	for i, des := range destinations {
+		wg.Add(1)
+
+		sem <- struct{}{}
+
+		go func(i int, des *service.Destination, b *batch) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			var envelope []byte
+
+			endpoint, sendErr := o.deliver(ctx, des, func() ([]byte, error) {
+				b.once.Do(func() {
+					packDes := *des
+					packDes.RecipientKeys = b.recipientKeys
+					b.packedMsg, b.err = o.pack(msg, senderVerKey, &packDes)
+				})
+
+				envelope = b.packedMsg
+
+				return b.packedMsg, b.err
+			})
+			o.record(ctx, msg, envelope, des, endpoint, sendErr)
+
+			if sendErr != nil {
+				lock.Lock()
+				errs[i] = sendErr
+				lock.Unlock()
+			}
+		}(i, des, batchOf[i])
+	}
+
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return &BatchError{Errs: errs}
+	}
+
+	return nil
+}
+
+// batches returns the envelope of each destination
+func (o *OutboundDispatcher) batches(destinations []*service.Destination, opts ...BatchOpt) ([]*batch, error) {
+	options := &batchOpts{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	batchOf := make([]*batch, len(destinations))
+
+	if !options.shared {
+		for i, des := range destinations {
+			batchOf[i] = &batch{recipientKeys: des.RecipientKeys}
+		}
+
+		return batchOf, nil
+	}
+
+	shared := &batch{}
+
+	for i, des := range destinations {
+		if o.batchKey(des) != o.batchKey(destinations[0]) {
+			return nil, fmt.Errorf("destination %d: the destinations of a shared envelope must have the same codec, "+
+				"encryption algorithms and compression", i)
+		}
+
+		shared.recipientKeys = appendUnique(shared.recipientKeys, des.RecipientKeys...)
+		batchOf[i] = shared
+	}
+
+	return batchOf, nil
+}
+
+// batchKey identifies the envelope of the destination by its codec, its encryption algorithms and its compression
+func (o *OutboundDispatcher) batchKey(des *service.Destination) string {
+	algs := append([]string(nil), des.EncryptionAlgs...)
+	sort.Strings(algs)
+
+	return codec.Select(des.Codecs, o.codecs).Name() + "|" + strings.Join(algs, ",") + "|" + des.Compression
+}
+
+func appendUnique(values []string, added ...string) []string {
+	for _, a := range added {
+		found := false
+
+		for _, v := range values {
+			if v == a {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			values = append(values, a)
+		}
+	}
+
+	return values
+}
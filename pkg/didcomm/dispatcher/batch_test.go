@@ -0,0 +1,173 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package dispatcher
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/audit"
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/service"
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/transport"
+	mockstorage "github.com/hyperledger/aries-framework-go/pkg/internal/mock/storage"
+	mockwallet "github.com/hyperledger/aries-framework-go/pkg/internal/mock/wallet"
+	"github.com/hyperledger/aries-framework-go/pkg/wallet"
+)
+
+func TestOutboundDispatcher_SendToMany(t *testing.T) {
+	t.Run("test message packed per destination", func(t *testing.T) {
+		w := &batchWallet{}
+		tr := &batchTransport{sent: make(map[string][]byte)}
+		o := NewOutbound(&provider{walletValue: w, outboundTransportsValue: []transport.OutboundTransport{tr}})
+
+		var _ BatchOutbound = o
+
+		err := o.SendToMany(context.Background(), "data", "sender", []*service.Destination{
+			{ServiceEndpoint: "http://a", RecipientKeys: []string{"keyA"}},
+			{ServiceEndpoint: "http://b", RecipientKeys: []string{"keyB"}},
+			{ServiceEndpoint: "http://c", RecipientKeys: []string{"keyC"}, EncryptionAlgs: []string{"C20P"}},
+		})
+		require.NoError(t, err)
+
+		require.ElementsMatch(t, []string{"keyA", "keyB", "keyC"}, w.packed)
+
+		require.Len(t, tr.sent, 3)
+		require.Equal(t, "keyA", string(tr.sent["http://a"]))
+		require.Equal(t, "keyB", string(tr.sent["http://b"]))
+	})
+
+	t.Run("test message packed once for the destinations of a connection", func(t *testing.T) {
+		w := &batchWallet{}
+		tr := &batchTransport{sent: make(map[string][]byte)}
+		o := NewOutbound(&provider{walletValue: w, outboundTransportsValue: []transport.OutboundTransport{tr}})
+
+		err := o.SendToMany(context.Background(), "data", "sender", []*service.Destination{
+			{ServiceEndpoint: "http://a", RecipientKeys: []string{"keyA"}},
+			{ServiceEndpoint: "http://b", RecipientKeys: []string{"keyB", "keyA"}},
+		}, WithSharedEnvelope())
+		require.NoError(t, err)
+
+		require.Equal(t, []string{"keyA,keyB"}, w.packed)
+		require.Equal(t, tr.sent["http://a"], tr.sent["http://b"])
+
+		err = o.SendToMany(context.Background(), "data", "sender", []*service.Destination{
+			{ServiceEndpoint: "http://a", RecipientKeys: []string{"keyA"}},
+			{ServiceEndpoint: "http://c", RecipientKeys: []string{"keyC"}, EncryptionAlgs: []string{"C20P"}},
+		}, WithSharedEnvelope())
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "destination 1: the destinations of a shared envelope must have the same codec")
+	})
+
+	t.Run("test partial failure", func(t *testing.T) {
+		auditStore, err := audit.New(mockstorage.NewMockStoreProvider())
+		require.NoError(t, err)
+
+		tr := &batchTransport{sent: make(map[string][]byte), failing: map[string]bool{"http://b": true}}
+		o := NewOutbound(&auditProvider{provider: provider{walletValue: &batchWallet{},
+			outboundTransportsValue: []transport.OutboundTransport{tr}}, audit: auditStore})
+
+		err = o.SendToMany(context.Background(), map[string]string{"@id": "id1"}, "", []*service.Destination{
+			{ServiceEndpoint: "http://a", RecipientKeys: []string{"keyA"}},
+			{ServiceEndpoint: "http://b", RecipientKeys: []string{"keyB"}},
+			{ServiceEndpoint: "unknown://c", RecipientKeys: []string{"keyC"}},
+		})
+		require.Error(t, err)
+
+		batchErr := &BatchError{}
+		require.True(t, errors.As(err, &batchErr))
+		require.Len(t, batchErr.Errs, 2)
+		require.Contains(t, batchErr.Errs[1].Error(), "send error")
+		require.Contains(t, batchErr.Errs[2].Error(), "no outbound transport found")
+		require.Contains(t, err.Error(), "failed to send msg to 2 destinations: destination 1: ")
+		require.Contains(t, tr.sent, "http://a")
+
+		records, err := auditStore.Query(&audit.Query{ThreadID: "id1"})
+		require.NoError(t, err)
+		require.Len(t, records, 3)
+
+		hashes := make(map[string]string)
+		for _, record := range records {
+			hashes[record.Endpoint] = record.Hash
+		}
+
+		// the envelope of each destination is hashed, the message isn't packed without a transport
+		digest := sha256.Sum256([]byte("keyB"))
+		require.Equal(t, hex.EncodeToString(digest[:]), hashes["http://b"])
+		require.Empty(t, hashes["unknown://c"])
+	})
+
+	t.Run("test pack failure", func(t *testing.T) {
+		o := NewOutbound(&provider{walletValue: &mockwallet.CloseableWallet{PackErr: fmt.Errorf("pack error")},
+			outboundTransportsValue: []transport.OutboundTransport{&batchTransport{sent: make(map[string][]byte)}}})
+
+		err := o.SendToMany(context.Background(), "data", "", []*service.Destination{
+			{ServiceEndpoint: "http://a"}, {ServiceEndpoint: "http://b"}})
+		require.Error(t, err)
+
+		batchErr := &BatchError{}
+		require.True(t, errors.As(err, &batchErr))
+		require.Len(t, batchErr.Errs, 2)
+		require.Contains(t, batchErr.Errs[0].Error(), "pack error")
+	})
+
+	t.Run("test no destination", func(t *testing.T) {
+		o := NewOutbound(&provider{walletValue: &batchWallet{}})
+		require.NoError(t, o.SendToMany(context.Background(), "data", "", nil))
+	})
+}
+
+// batchWallet records the recipients of the packed envelopes, the envelope is the list of the recipients
+type batchWallet struct {
+	mockwallet.CloseableWallet
+	lock   sync.Mutex
+	packed []string
+}
+
+func (w *batchWallet) PackMessage(envelope *wallet.Envelope) ([]byte, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	keys := append([]string(nil), envelope.ToVerKeys...)
+	sort.Strings(keys)
+
+	w.packed = append(w.packed, strings.Join(keys, ","))
+
+	return []byte(strings.Join(keys, ",")), nil
+}
+
+// batchTransport records the envelopes sent to the endpoints and fails the sends to the failing endpoints
+type batchTransport struct {
+	lock    sync.Mutex
+	failing map[string]bool
+	sent    map[string][]byte
+}
+
+func (b *batchTransport) Send(_ context.Context, data []byte, url string) (string, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.failing[url] {
+		return "", fmt.Errorf("send error")
+	}
+
+	b.sent[url] = data
+
+	return "", nil
+}
+
+func (b *batchTransport) Accept(url string) bool {
+	return strings.HasPrefix(url, "http")
+}
@@ -0,0 +1,181 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package dispatcher
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/common/log"
+	"github.com/hyperledger/aries-framework-go/pkg/common/metrics"
+)
+
+var logger = log.New("aries-framework/dispatcher")
+
+// MessageTypeStats are the statistics of the inbound messages of a message type
+type MessageTypeStats struct {
+	// Count is the number of handled messages
+	Count uint64
+	// Failed is the number of messages whose handling failed
+	Failed uint64
+	// Slow is the number of messages whose handling exceeded the slow handler threshold
+	Slow uint64
+	// TotalDuration is the time spent handling the messages
+	TotalDuration time.Duration
+	// MaxDuration is the longest handling time of a message
+	MaxDuration time.Duration
+}
+
+// Metrics records the inbound messages handled by the protocol services with the metrics providers, and logs the
+// handlers exceeding the slow handler threshold as they block the inbound pipeline. The statistics per message
+// type are also recorded in memory for Snapshot.
+type Metrics struct {
+	slowThreshold time.Duration
+	stats         *statsProvider
+	providers     []metrics.Provider
+}
+
+// NewMetrics returns new inbound message metrics recorded with the providers, zero slowThreshold disables the
+// slow handler detection
+func NewMetrics(slowThreshold time.Duration, providers ...metrics.Provider) *Metrics {
+	stats := &statsProvider{stats: make(map[string]*MessageTypeStats)}
+
+	return &Metrics{
+		slowThreshold: slowThreshold,
+		stats:         stats,
+		providers:     append([]metrics.Provider{stats}, providers...),
+	}
+}
+
+// Observe records the handling of a message of the message type by the service
+func (m *Metrics) Observe(svc, msgType string, duration time.Duration, err error) {
+	slow := m.slowThreshold > 0 && duration > m.slowThreshold
+	if slow {
+		logger.Warnf("slow handler: service %s took %s to handle message type %s", svc, duration, msgType)
+	}
+
+	svcLabel := metrics.Label{Name: "service", Value: svc}
+	msgTypeLabel := metrics.Label{Name: msgTypeLabelName, Value: msgType}
+
+	for _, p := range m.providers {
+		p.Counter(metrics.InboundMessages, svcLabel, msgTypeLabel, metrics.Outcome(err)).Add(1)
+		p.Histogram(metrics.InboundMessageDuration, svcLabel, msgTypeLabel).Observe(duration.Seconds())
+
+		if slow {
+			p.Counter(metrics.InboundSlowMessages, svcLabel, msgTypeLabel).Add(1)
+		}
+	}
+}
+
+// Snapshot returns a copy of the statistics per message type
+func (m *Metrics) Snapshot() map[string]MessageTypeStats {
+	return m.stats.snapshot()
+}
+
+const msgTypeLabelName = "msg_type"
+
+// statsProvider is the metrics provider aggregating the inbound message metrics per message type
+type statsProvider struct {
+	lock  sync.Mutex
+	stats map[string]*MessageTypeStats
+}
+
+func (p *statsProvider) Counter(name string, labels ...metrics.Label) metrics.Counter {
+	return &statsMetric{prov: p, name: name, labels: labels}
+}
+
+func (p *statsProvider) Gauge(name string, labels ...metrics.Label) metrics.Gauge {
+	return &statsMetric{prov: p, name: name, labels: labels}
+}
+
+func (p *statsProvider) Histogram(name string, labels ...metrics.Label) metrics.Histogram {
+	return &statsMetric{prov: p, name: name, labels: labels}
+}
+
+func (p *statsProvider) snapshot() map[string]MessageTypeStats {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	snapshot := make(map[string]MessageTypeStats, len(p.stats))
+	for msgType, s := range p.stats {
+		snapshot[msgType] = *s
+	}
+
+	return snapshot
+}
+
+// statsMetric updates the statistics of the message type of its labels, the metrics other than the inbound
+// message metrics are ignored
+type statsMetric struct {
+	prov   *statsProvider
+	name   string
+	labels []metrics.Label
+}
+
+func (m *statsMetric) Set(float64) {}
+
+func (m *statsMetric) Add(delta float64) {
+	switch m.name {
+	case metrics.InboundMessages:
+		success := metrics.Outcome(nil)
+		failed := m.label(success.Name) != success.Value
+
+		m.update(func(s *MessageTypeStats) {
+			s.Count += uint64(delta)
+
+			if failed {
+				s.Failed += uint64(delta)
+			}
+		})
+	case metrics.InboundSlowMessages:
+		m.update(func(s *MessageTypeStats) {
+			s.Slow += uint64(delta)
+		})
+	}
+}
+
+func (m *statsMetric) Observe(value float64) {
+	if m.name != metrics.InboundMessageDuration {
+		return
+	}
+
+	duration := time.Duration(math.Round(value * float64(time.Second)))
+
+	m.update(func(s *MessageTypeStats) {
+		s.TotalDuration += duration
+
+		if duration > s.MaxDuration {
+			s.MaxDuration = duration
+		}
+	})
+}
+
+func (m *statsMetric) update(fn func(s *MessageTypeStats)) {
+	msgType := m.label(msgTypeLabelName)
+
+	m.prov.lock.Lock()
+	defer m.prov.lock.Unlock()
+
+	s, ok := m.prov.stats[msgType]
+	if !ok {
+		s = &MessageTypeStats{}
+		m.prov.stats[msgType] = s
+	}
+
+	fn(s)
+}
+
+func (m *statsMetric) label(name string) string {
+	for _, l := range m.labels {
+		if l.Name == name {
+			return l.Value
+		}
+	}
+
+	return ""
+}
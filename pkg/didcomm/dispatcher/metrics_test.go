@@ -0,0 +1,67 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package dispatcher
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hyperledger/aries-framework-go/pkg/common/metrics"
+	memmetrics "github.com/hyperledger/aries-framework-go/pkg/common/metrics/mem"
+)
+
+func TestMetrics(t *testing.T) {
+	t.Run("test statistics per message type", func(t *testing.T) {
+		m := NewMetrics(time.Second)
+
+		m.Observe("svc", "type1", 10*time.Millisecond, nil)
+		m.Observe("svc", "type1", 2*time.Second, errors.New("handle error"))
+		m.Observe("svc", "type2", 20*time.Millisecond, nil)
+
+		require.Equal(t, map[string]MessageTypeStats{
+			"type1": {Count: 2, Failed: 1, Slow: 1, TotalDuration: 2010 * time.Millisecond, MaxDuration: 2 * time.Second},
+			"type2": {Count: 1, TotalDuration: 20 * time.Millisecond, MaxDuration: 20 * time.Millisecond},
+		}, m.Snapshot())
+	})
+
+	t.Run("test metrics recorded with the providers", func(t *testing.T) {
+		prov := memmetrics.NewProvider()
+		m := NewMetrics(time.Second, prov)
+
+		m.Observe("svc", "type1", 10*time.Millisecond, nil)
+		m.Observe("svc", "type1", 2*time.Second, errors.New("handle error"))
+
+		svcLabel := metrics.Label{Name: "service", Value: "svc"}
+		typeLabel := metrics.Label{Name: "msg_type", Value: "type1"}
+
+		require.Equal(t, float64(1), prov.Value(metrics.InboundMessages, svcLabel, typeLabel, metrics.Outcome(nil)))
+		require.Equal(t, float64(1), prov.Value(metrics.InboundMessages, svcLabel, typeLabel,
+			metrics.Outcome(errors.New("handle error"))))
+		require.Equal(t, float64(1), prov.Value(metrics.InboundSlowMessages, svcLabel, typeLabel))
+		require.Equal(t, []float64{0.01, 2}, prov.Observations(metrics.InboundMessageDuration, svcLabel, typeLabel))
+		require.Equal(t, uint64(2), m.Snapshot()["type1"].Count)
+	})
+
+	t.Run("test other metrics ignored by the statistics", func(t *testing.T) {
+		m := NewMetrics(0)
+
+		m.stats.Counter(metrics.OutboundMessages).Add(1)
+		m.stats.Gauge(metrics.OutboundMessages).Set(1)
+		m.stats.Histogram(metrics.OutboundMessageDuration).Observe(1)
+		require.Empty(t, m.Snapshot())
+	})
+
+	t.Run("test slow handler detection disabled", func(t *testing.T) {
+		m := NewMetrics(0)
+
+		m.Observe("svc", "type1", time.Hour, nil)
+		require.Equal(t, uint64(0), m.Snapshot()["type1"].Slow)
+	})
+}
@@ -7,46 +7,296 @@ SPDX-License-Identifier: Apache-2.0
 package dispatcher
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
+	"github.com/hyperledger/aries-framework-go/pkg/cache"
+	"github.com/hyperledger/aries-framework-go/pkg/cache/mem"
+	"github.com/hyperledger/aries-framework-go/pkg/common/metrics"
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/codec"
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/audit"
 	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/service"
 	"github.com/hyperledger/aries-framework-go/pkg/didcomm/transport"
 	"github.com/hyperledger/aries-framework-go/pkg/wallet"
 )
 
+// maxLastEndpoints is the number of recipients whose last working endpoint is remembered, the least recently used
+// are forgotten first
+const maxLastEndpoints = 10000
+
+// EndpointsError is returned by Send when the message couldn't be sent to any of the endpoints of the destination
+type EndpointsError struct {
+	// Errs are the errors of the endpoints in the order the endpoints were tried
+	Errs []error
+}
+
+func (e *EndpointsError) Error() string {
+	errs := make([]string, len(e.Errs))
+	for i, err := range e.Errs {
+		errs[i] = err.Error()
+	}
+
+	return strings.Join(errs, "; ")
+}
+
+// Is reports whether the error of an endpoint matches the target
+func (e *EndpointsError) Is(target error) bool {
+	for _, err := range e.Errs {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// As finds the first error of an endpoint that matches the target
+func (e *EndpointsError) As(target interface{}) bool {
+	for _, err := range e.Errs {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
 // OutboundDispatcher dispatch msgs to destination
 type OutboundDispatcher struct {
 	outboundTransports []transport.OutboundTransport
 	wallet             wallet.Pack
+	lastEndpoints      cache.Cache
+	codecs             []codec.Codec
+	metrics            metrics.Provider
+	audit              *audit.Store
 }
 
 // NewOutbound return new dispatcher outbound instance
 func NewOutbound(prov Provider) *OutboundDispatcher {
-	return &OutboundDispatcher{outboundTransports: prov.OutboundTransports(), wallet: prov.PackWallet()}
+	o := &OutboundDispatcher{outboundTransports: prov.OutboundTransports(), wallet: prov.PackWallet()}
+
+	// the in-memory caches can always be opened
+	caches := mem.NewProvider(mem.WithMaxEntries(maxLastEndpoints))
+	o.lastEndpoints, _ = caches.OpenCache("endpoints") // nolint: errcheck
+
+	if p, ok := prov.(CodecProvider); ok {
+		o.codecs = p.Codecs()
+	}
+
+	if p, ok := prov.(MetricsProvider); ok {
+		o.metrics = p.MetricsProvider()
+	}
+
+	if p, ok := prov.(AuditStoreProvider); ok {
+		o.audit = p.AuditStore()
+	}
+
+	return o
 }
 
-// Send msg
-func (o *OutboundDispatcher) Send(msg interface{}, senderVerKey string, des *service.Destination) error {
-	for _, v := range o.outboundTransports {
-		if !v.Accept(des.ServiceEndpoint) {
+// Send msg to the service endpoints of the destination, the endpoint which last worked for the recipient
+// is tried first, then the service endpoint followed by the alternative endpoints by priority. The message is
+// recorded in the audit trail if enabled.
+func (o *OutboundDispatcher) Send(ctx context.Context, msg interface{}, senderVerKey string,
+	des *service.Destination) error {
+	endpoint, envelope, err := o.send(ctx, msg, senderVerKey, des)
+	o.record(ctx, msg, envelope, des, endpoint, err)
+
+	return err
+}
+
+// send sends the message and returns the endpoint it was sent to, the last endpoint tried on failure, and the
+// envelope sent, nil if the message wasn't packed
+func (o *OutboundDispatcher) send(ctx context.Context, msg interface{}, senderVerKey string,
+	des *service.Destination) (string, []byte, error) {
+	var envelope []byte
+
+	endpoint, err := o.deliver(ctx, des, func() ([]byte, error) {
+		var packErr error
+
+		envelope, packErr = o.pack(msg, senderVerKey, des)
+
+		return envelope, packErr
+	})
+
+	return endpoint, envelope, err
+}
+
+// pack packs the message for the recipients of the destination
+func (o *OutboundDispatcher) pack(msg interface{}, senderVerKey string, des *service.Destination) ([]byte, error) {
+	bytes, err := codec.Select(des.Codecs, o.codecs).Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("failed marshal to bytes: %w", err)
+	}
+
+	packedMsg, err := o.wallet.PackMessage(
+		&wallet.Envelope{Message: bytes, FromVerKey: senderVerKey, ToVerKeys: des.RecipientKeys,
+			EncryptionAlgs: des.EncryptionAlgs, Compression: des.Compression})
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack msg: %w", err)
+	}
+
+	return packedMsg, nil
+}
+
+// deliver sends the envelope returned by pack to the endpoints of the destination, the envelope is packed once
+// an outbound transport accepts an endpoint
+func (o *OutboundDispatcher) deliver(ctx context.Context, des *service.Destination,
+	pack func() ([]byte, error)) (string, error) {
+	endpoints := o.orderEndpoints(des)
+
+	var packedMsg []byte
+
+	var errs []error
+
+	for _, endpoint := range endpoints {
+		v := o.outboundTransport(endpoint)
+		if v == nil {
+			errs = append(errs, fmt.Errorf("no outbound transport found for serviceEndpoint: %s", endpoint))
 			continue
 		}
-		bytes, err := json.Marshal(msg)
-		if err != nil {
-			return fmt.Errorf("failed marshal to bytes: %w", err)
-		}
-		packedMsg, err := o.wallet.PackMessage(
-			&wallet.Envelope{Message: bytes, FromVerKey: senderVerKey, ToVerKeys: des.RecipientKeys})
-		if err != nil {
-			return fmt.Errorf("failed to pack msg: %w", err)
+
+		if packedMsg == nil {
+			var err error
+
+			packedMsg, err = pack()
+			if err != nil {
+				return endpoint, err
+			}
 		}
+
 		// TODO should we return respData from send
-		_, err = v.Send(packedMsg, des.ServiceEndpoint)
+		start := time.Now()
+		_, err := v.Send(ctx, packedMsg, endpoint)
+		o.observe(endpoint, time.Since(start), err)
+
 		if err != nil {
-			return fmt.Errorf("failed to send msg using http outbound transport: %w", err)
+			if ctx.Err() != nil || len(endpoints) == 1 {
+				return endpoint, fmt.Errorf("failed to send msg using http outbound transport: %w", err)
+			}
+
+			logger.WithContext(ctx).Warnf("failed to send msg to %s, trying the next endpoint: %s", endpoint, err)
+			errs = append(errs, fmt.Errorf("failed to send msg to %s: %w", endpoint, err))
+
+			continue
+		}
+
+		o.setLastEndpoint(des, endpoint)
+
+		return endpoint, nil
+	}
+
+	return endpoints[len(endpoints)-1], &EndpointsError{Errs: errs}
+}
+
+// record records the message sent in the envelope in the audit trail if enabled, the failures are logged as the
+// message has already been sent
+func (o *OutboundDispatcher) record(ctx context.Context, msg interface{}, envelope []byte, des *service.Destination,
+	endpoint string, sendErr error) {
+	if o.audit == nil {
+		return
+	}
+
+	record, err := audit.NewRecord(msg, envelope)
+	if err != nil {
+		logger.WithContext(ctx).Errorf("failed to audit outbound message: %s", err)
+		return
+	}
+
+	record.Endpoint = endpoint
+	record.RecipientKeys = des.RecipientKeys
+
+	if sendErr != nil {
+		record.Error = sendErr.Error()
+	}
+
+	if err = o.audit.Add(record); err != nil {
+		logger.WithContext(ctx).Errorf("failed to audit outbound message %s: %s", record.MsgID, err)
+	}
+}
+
+// observe records the sending of a message to the endpoint in the metrics if configured
+func (o *OutboundDispatcher) observe(endpoint string, duration time.Duration, err error) {
+	if o.metrics == nil {
+		return
+	}
+
+	scheme := metrics.Label{Name: "scheme", Value: endpointScheme(endpoint)}
+
+	o.metrics.Counter(metrics.OutboundMessages, scheme, metrics.Outcome(err)).Add(1)
+	o.metrics.Histogram(metrics.OutboundMessageDuration, scheme).Observe(duration.Seconds())
+}
+
+// endpointScheme returns the scheme of the endpoint, e.g. https, the endpoints without scheme are labeled unknown
+func endpointScheme(endpoint string) string {
+	i := strings.Index(endpoint, ":")
+	if i <= 0 {
+		return "unknown"
+	}
+
+	return strings.ToLower(endpoint[:i])
+}
+
+func (o *OutboundDispatcher) outboundTransport(endpoint string) transport.OutboundTransport {
+	for _, v := range o.outboundTransports {
+		if v.Accept(endpoint) {
+			return v
+		}
+	}
+
+	return nil
+}
+
+// orderEndpoints returns the endpoints of the destination, the last working endpoint first
+func (o *OutboundDispatcher) orderEndpoints(des *service.Destination) []string {
+	endpoints := des.ServiceEndpoints()
+	if len(endpoints) == 0 {
+		return []string{des.ServiceEndpoint}
+	}
+
+	last, ok := o.LastEndpoint(des)
+	if !ok || last == endpoints[0] {
+		return endpoints
+	}
+
+	ordered := []string{last}
+	found := false
+
+	for _, e := range endpoints {
+		if e == last {
+			found = true
+			continue
 		}
-		return nil
+
+		ordered = append(ordered, e)
+	}
+
+	if !found {
+		// the endpoint has been removed from the destination
+		return endpoints
 	}
-	return fmt.Errorf("no outbound transport found for serviceEndpoint: %s", des.ServiceEndpoint)
+
+	return ordered
+}
+
+// LastEndpoint returns the endpoint which last worked for the recipient of the destination
+func (o *OutboundDispatcher) LastEndpoint(des *service.Destination) (string, bool) {
+	endpoint, err := o.lastEndpoints.Get(recipientKey(des))
+	if err != nil {
+		return "", false
+	}
+
+	return string(endpoint), true
+}
+
+func (o *OutboundDispatcher) setLastEndpoint(des *service.Destination, endpoint string) {
+	o.lastEndpoints.Set(recipientKey(des), []byte(endpoint), 0) // nolint: errcheck
+}
+
+// recipientKey identifies the connection of the destination by the keys of the recipient
+func recipientKey(des *service.Destination) string {
+	return strings.Join(des.RecipientKeys, ",")
 }
@@ -7,14 +7,24 @@ SPDX-License-Identifier: Apache-2.0
 package dispatcher
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/hyperledger/aries-framework-go/pkg/common/metrics"
+	memmetrics "github.com/hyperledger/aries-framework-go/pkg/common/metrics/mem"
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/codec"
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/audit"
 	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/service"
 	"github.com/hyperledger/aries-framework-go/pkg/didcomm/transport"
 	mockdidcomm "github.com/hyperledger/aries-framework-go/pkg/internal/mock/didcomm"
+	mockstorage "github.com/hyperledger/aries-framework-go/pkg/internal/mock/storage"
 	mockwallet "github.com/hyperledger/aries-framework-go/pkg/internal/mock/wallet"
 	"github.com/hyperledger/aries-framework-go/pkg/wallet"
 )
@@ -23,13 +33,13 @@ func TestOutboundDispatcher_Send(t *testing.T) {
 	t.Run("test success", func(t *testing.T) {
 		o := NewOutbound(&provider{walletValue: &mockwallet.CloseableWallet{},
 			outboundTransportsValue: []transport.OutboundTransport{&mockdidcomm.MockOutboundTransport{AcceptValue: true}}})
-		require.NoError(t, o.Send("data", "", &service.Destination{ServiceEndpoint: "url"}))
+		require.NoError(t, o.Send(context.Background(), "data", "", &service.Destination{ServiceEndpoint: "url"}))
 	})
 
 	t.Run("test no outbound transport found", func(t *testing.T) {
 		o := NewOutbound(&provider{walletValue: &mockwallet.CloseableWallet{},
 			outboundTransportsValue: []transport.OutboundTransport{&mockdidcomm.MockOutboundTransport{AcceptValue: false}}})
-		err := o.Send("data", "", &service.Destination{ServiceEndpoint: "url"})
+		err := o.Send(context.Background(), "data", "", &service.Destination{ServiceEndpoint: "url"})
 		require.Error(t, err)
 		require.Contains(t, err.Error(), "no outbound transport found for serviceEndpoint: url")
 	})
@@ -37,7 +47,7 @@ func TestOutboundDispatcher_Send(t *testing.T) {
 	t.Run("test pack msg failure", func(t *testing.T) {
 		o := NewOutbound(&provider{walletValue: &mockwallet.CloseableWallet{PackErr: fmt.Errorf("pack error")},
 			outboundTransportsValue: []transport.OutboundTransport{&mockdidcomm.MockOutboundTransport{AcceptValue: true}}})
-		err := o.Send("data", "", &service.Destination{ServiceEndpoint: "url"})
+		err := o.Send(context.Background(), "data", "", &service.Destination{ServiceEndpoint: "url"})
 		require.Error(t, err)
 		require.Contains(t, err.Error(), "pack error")
 	})
@@ -46,10 +56,259 @@ func TestOutboundDispatcher_Send(t *testing.T) {
 		o := NewOutbound(&provider{walletValue: &mockwallet.CloseableWallet{},
 			outboundTransportsValue: []transport.OutboundTransport{
 				&mockdidcomm.MockOutboundTransport{AcceptValue: true, SendErr: fmt.Errorf("send error")}}})
-		err := o.Send("data", "", &service.Destination{ServiceEndpoint: "url"})
+		err := o.Send(context.Background(), "data", "", &service.Destination{ServiceEndpoint: "url"})
 		require.Error(t, err)
 		require.Contains(t, err.Error(), "send error")
 	})
+
+	t.Run("test encryption algorithms and compression of destination", func(t *testing.T) {
+		w := &packRecorder{}
+		o := NewOutbound(&provider{walletValue: w,
+			outboundTransportsValue: []transport.OutboundTransport{&mockdidcomm.MockOutboundTransport{AcceptValue: true}}})
+		require.NoError(t, o.Send(context.Background(), "data", "", &service.Destination{ServiceEndpoint: "url",
+			EncryptionAlgs: []string{"C20P"}, Compression: "DEF"}))
+		require.Equal(t, []string{"C20P"}, w.envelope.EncryptionAlgs)
+		require.Equal(t, "DEF", w.envelope.Compression)
+	})
+}
+
+func TestOutboundDispatcher_SendFailover(t *testing.T) {
+	des := &service.Destination{RecipientKeys: []string{"key"}, ServiceEndpoint: "http://preferred",
+		Endpoints: []service.Endpoint{{URI: "http://backup2", Priority: 2}, {URI: "http://backup1", Priority: 1}}}
+
+	t.Run("test fall back to the endpoints by priority", func(t *testing.T) {
+		outbound := &endpointTransport{failing: map[string]bool{"http://preferred": true, "http://backup1": true}}
+		o := NewOutbound(&provider{walletValue: &mockwallet.CloseableWallet{},
+			outboundTransportsValue: []transport.OutboundTransport{outbound}})
+
+		require.NoError(t, o.Send(context.Background(), "data", "", des))
+		require.Equal(t, []string{"http://preferred", "http://backup1", "http://backup2"}, outbound.sent)
+
+		endpoint, ok := o.LastEndpoint(des)
+		require.True(t, ok)
+		require.Equal(t, "http://backup2", endpoint)
+
+		// the last working endpoint is tried first
+		outbound.sent = nil
+		require.NoError(t, o.Send(context.Background(), "data", "", des))
+		require.Equal(t, []string{"http://backup2"}, outbound.sent)
+
+		// the endpoints are tried in order when the last working endpoint fails
+		outbound.sent = nil
+		outbound.failing = map[string]bool{"http://backup2": true}
+		require.NoError(t, o.Send(context.Background(), "data", "", des))
+		require.Equal(t, []string{"http://backup2", "http://preferred"}, outbound.sent)
+
+		endpoint, ok = o.LastEndpoint(des)
+		require.True(t, ok)
+		require.Equal(t, "http://preferred", endpoint)
+	})
+
+	t.Run("test skip endpoints without transport", func(t *testing.T) {
+		outbound := &endpointTransport{}
+		o := NewOutbound(&provider{walletValue: &mockwallet.CloseableWallet{},
+			outboundTransportsValue: []transport.OutboundTransport{outbound}})
+
+		require.NoError(t, o.Send(context.Background(), "data", "", &service.Destination{ServiceEndpoint: "ws://preferred",
+			Endpoints: []service.Endpoint{{URI: "http://backup"}}}))
+		require.Equal(t, []string{"http://backup"}, outbound.sent)
+	})
+
+	t.Run("test all endpoints fail", func(t *testing.T) {
+		outbound := &endpointTransport{failing: map[string]bool{"http://preferred": true, "http://backup1": true,
+			"http://backup2": true}}
+		o := NewOutbound(&provider{walletValue: &mockwallet.CloseableWallet{},
+			outboundTransportsValue: []transport.OutboundTransport{outbound}})
+
+		err := o.Send(context.Background(), "data", "", des)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to send msg to http://backup2: send error")
+		require.True(t, errors.Is(err, errSend))
+
+		endpointsErr := &EndpointsError{}
+		require.True(t, errors.As(err, &endpointsErr))
+		require.Len(t, endpointsErr.Errs, 3)
+
+		_, ok := o.LastEndpoint(des)
+		require.False(t, ok)
+	})
+
+	t.Run("test last endpoints are bounded", func(t *testing.T) {
+		outbound := &endpointTransport{failing: map[string]bool{"http://preferred": true}}
+		o := NewOutbound(&provider{walletValue: &mockwallet.CloseableWallet{},
+			outboundTransportsValue: []transport.OutboundTransport{outbound}})
+
+		for i := 0; i <= maxLastEndpoints; i++ {
+			d := *des
+			d.RecipientKeys = []string{fmt.Sprintf("key%d", i)}
+			require.NoError(t, o.Send(context.Background(), "data", "", &d))
+		}
+
+		// the least recently used recipient is forgotten
+		_, ok := o.LastEndpoint(&service.Destination{RecipientKeys: []string{"key0"}})
+		require.False(t, ok)
+
+		endpoint, ok := o.LastEndpoint(&service.Destination{RecipientKeys: []string{fmt.Sprintf("key%d", maxLastEndpoints)}})
+		require.True(t, ok)
+		require.Equal(t, "http://backup1", endpoint)
+	})
+
+	t.Run("test cancelled send doesn't fall back", func(t *testing.T) {
+		outbound := &endpointTransport{failing: map[string]bool{"http://preferred": true}}
+		o := NewOutbound(&provider{walletValue: &mockwallet.CloseableWallet{},
+			outboundTransportsValue: []transport.OutboundTransport{outbound}})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		require.Error(t, o.Send(ctx, "data", "", des))
+		require.Equal(t, []string{"http://preferred"}, outbound.sent)
+	})
+}
+
+// endpointTransport records the endpoints of the sends and fails the sends to the failing endpoints
+var errSend = errors.New("send error")
+
+type endpointTransport struct {
+	failing map[string]bool
+	sent    []string
+}
+
+func (e *endpointTransport) Send(_ context.Context, _ []byte, url string) (string, error) {
+	e.sent = append(e.sent, url)
+
+	if e.failing[url] {
+		return "", errSend
+	}
+
+	return "", nil
+}
+
+func (e *endpointTransport) Accept(url string) bool {
+	return strings.HasPrefix(url, "http")
+}
+
+func TestOutboundDispatcher_SendCodec(t *testing.T) {
+	newDispatcher := func(w wallet.Pack) *OutboundDispatcher {
+		return NewOutbound(&codecProvider{provider: provider{walletValue: w,
+			outboundTransportsValue: []transport.OutboundTransport{&mockdidcomm.MockOutboundTransport{AcceptValue: true}}},
+			codecs: []codec.Codec{codec.CBOR{}}})
+	}
+
+	t.Run("test codec of the destination", func(t *testing.T) {
+		w := &packRecorder{}
+		require.NoError(t, newDispatcher(w).Send(context.Background(), map[string]string{"@type": "type"}, "",
+			&service.Destination{ServiceEndpoint: "url", Codecs: []string{"msgpack", codec.CBORName}}))
+		require.True(t, codec.CBOR{}.Accept(w.envelope.Message))
+	})
+
+	t.Run("test JSON by default", func(t *testing.T) {
+		w := &packRecorder{}
+		require.NoError(t, newDispatcher(w).Send(context.Background(), map[string]string{"@type": "type"}, "",
+			&service.Destination{ServiceEndpoint: "url"}))
+		require.JSONEq(t, `{"@type": "type"}`, string(w.envelope.Message))
+	})
+}
+
+func TestOutboundDispatcher_SendMetrics(t *testing.T) {
+	m := memmetrics.NewProvider()
+	o := NewOutbound(&metricsProvider{provider: provider{walletValue: &mockwallet.CloseableWallet{},
+		outboundTransportsValue: []transport.OutboundTransport{&endpointTransport{failing: map[string]bool{"http://down": true}}}},
+		metrics: m})
+
+	require.NoError(t, o.Send(context.Background(), "data", "", &service.Destination{
+		ServiceEndpoint: "http://down", Endpoints: []service.Endpoint{{URI: "http://up", Priority: 1}}}))
+
+	scheme := metrics.Label{Name: "scheme", Value: "http"}
+	require.Equal(t, float64(1), m.Value(metrics.OutboundMessages, scheme, metrics.Outcome(nil)))
+	require.Equal(t, float64(1), m.Value(metrics.OutboundMessages, scheme, metrics.Outcome(errors.New("failed"))))
+	require.Len(t, m.Observations(metrics.OutboundMessageDuration, scheme), 2)
+
+	require.Equal(t, "https", endpointScheme("HTTPS://up"))
+	require.Equal(t, "unknown", endpointScheme("url"))
+}
+
+func TestOutboundDispatcher_SendAudit(t *testing.T) {
+	auditStore, err := audit.New(mockstorage.NewMockStoreProvider())
+	require.NoError(t, err)
+
+	o := NewOutbound(&auditProvider{provider: provider{
+		walletValue:             &mockwallet.CloseableWallet{PackValue: []byte("envelope")},
+		outboundTransportsValue: []transport.OutboundTransport{&endpointTransport{failing: map[string]bool{"http://down": true}}}},
+		audit: auditStore})
+
+	msg := map[string]interface{}{"@id": "id1", "@type": "type1", "~thread": map[string]interface{}{"thid": "thid1"}}
+
+	require.NoError(t, o.Send(context.Background(), msg, "", &service.Destination{
+		ServiceEndpoint: "http://down", Endpoints: []service.Endpoint{{URI: "http://up", Priority: 1}},
+		RecipientKeys: []string{"key1"}}))
+	require.Error(t, o.Send(context.Background(), msg, "", &service.Destination{ServiceEndpoint: "http://down"}))
+
+	records, err := auditStore.Query(&audit.Query{ThreadID: "thid1"})
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+
+	require.Equal(t, "id1", records[0].MsgID)
+	require.Equal(t, "type1", records[0].MsgType)
+	require.Equal(t, "http://up", records[0].Endpoint)
+	require.Equal(t, []string{"key1"}, records[0].RecipientKeys)
+	require.Empty(t, records[0].Error)
+
+	// the envelope sent is hashed, not the message
+	digest := sha256.Sum256([]byte("envelope"))
+	require.Equal(t, hex.EncodeToString(digest[:]), records[0].Hash)
+
+	require.Equal(t, records[0].Hash, records[1].Hash)
+	require.Equal(t, "http://down", records[1].Endpoint)
+	require.NotEmpty(t, records[1].Error)
+
+	t.Run("test message which can't be audited", func(t *testing.T) {
+		err = o.Send(context.Background(), make(chan int), "", &service.Destination{ServiceEndpoint: "http://up"})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed marshal to bytes")
+
+		records, err = auditStore.Query(&audit.Query{})
+		require.NoError(t, err)
+		require.Len(t, records, 2)
+	})
+}
+
+type auditProvider struct {
+	provider
+	audit *audit.Store
+}
+
+func (p *auditProvider) AuditStore() *audit.Store {
+	return p.audit
+}
+
+type metricsProvider struct {
+	provider
+	metrics metrics.Provider
+}
+
+func (p *metricsProvider) MetricsProvider() metrics.Provider {
+	return p.metrics
+}
+
+type codecProvider struct {
+	provider
+	codecs []codec.Codec
+}
+
+func (p *codecProvider) Codecs() []codec.Codec {
+	return p.codecs
+}
+
+// packRecorder records the packed envelope
+type packRecorder struct {
+	mockwallet.CloseableWallet
+	envelope *wallet.Envelope
+}
+
+func (p *packRecorder) PackMessage(envelope *wallet.Envelope) ([]byte, error) {
+	p.envelope = envelope
+	return nil, nil
 }
 
 type provider struct {
@@ -6,14 +6,39 @@ SPDX-License-Identifier: Apache-2.0
 
 package decorator
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 // Thread thread data
 type Thread struct {
 	ID string `json:"thid,omitempty"`
+	// PID is the ID of the parent thread
+	PID string `json:"pthid,omitempty"`
 }
 
 // Timing keeps expiration time
 type Timing struct {
 	ExpiresTime time.Time `json:"expires_time,omitempty"`
 }
+
+// Attachment is the attachment of a message (Aries RFC 0017), e.g. the value of the "~attach" decorator
+type Attachment struct {
+	ID          string         `json:"@id,omitempty"`
+	MimeType    string         `json:"mime-type,omitempty"`
+	Filename    string         `json:"filename,omitempty"`
+	LastModTime *time.Time     `json:"lastmod_time,omitempty"`
+	ByteCount   int64          `json:"byte_count,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Data        AttachmentData `json:"data"`
+}
+
+// AttachmentData is the content of the attachment, inlined or referenced by links
+type AttachmentData struct {
+	Base64 string          `json:"base64,omitempty"`
+	JSON   json.RawMessage `json:"json,omitempty"`
+	Links  []string        `json:"links,omitempty"`
+	// SHA256 is the hex encoded digest of the content
+	SHA256 string `json:"sha256,omitempty"`
+}
@@ -0,0 +1,121 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package didexchange
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/service"
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/threads"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+)
+
+// connectionIDNamespace is the name space of the UUIDs derived from the DIDs of the parties
+var connectionIDNamespace = uuid.NewSHA1(uuid.NameSpaceURL, []byte(DIDExchangeSpec)) //nolint:gochecknoglobals
+
+// completedConnectionStore records the connections completed by the exchange threads
+type completedConnectionStore interface {
+	SaveCompletedConnection(thid string, record *ConnectionRecord, myDID *did.Doc, theirDID string,
+		destination *service.Destination) error
+}
+
+// ConnectionIDFromDIDs returns the connection ID derived from the DID of the agent and the DID of the counterparty,
+// a name based UUID so the same pair of DIDs always maps to the same connection
+func ConnectionIDFromDIDs(myDID, theirDID string) string {
+	// the DIDs can't contain spaces
+	return uuid.NewSHA1(connectionIDNamespace, []byte(myDID+" "+theirDID)).String()
+}
+
+// ConnectionIDByKey returns the ID of the connection whose counterparty sends its messages with the key, e.g. to
+// identify the connection of an inbound message from its sender key. storage.ErrDataNotFound is returned if the
+// key isn't known.
+func (s *Service) ConnectionIDByKey(theirVerKey string) (string, error) {
+	return s.connectionStore.GetConnectionIDByKey(theirVerKey)
+}
+
+// recordConnection records the connection completed by the exchange thread under the ID derived from the DIDs of
+// the parties and returns the ID, the records of the thread are moved to the connection. The records are
+// overwritten when an exchange between the same DIDs completes again, so a retried exchange doesn't create a
+// duplicate connection. The thread ID is returned if the DIDs of the thread aren't known.
+func (s *Service) recordConnection(thid string) (string, error) {
+	myDID, err := s.connectionStore.GetMyDID(thid)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch my DID: %w", err)
+	}
+
+	theirDID, err := s.connectionStore.GetTheirDID(thid)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch their DID: %w", err)
+	}
+
+	if myDID == nil || theirDID == "" {
+		logger.Warnf("DIDs of the exchange thread %s aren't known, the connection is recorded under the thread", thid)
+
+		return thid, nil
+	}
+
+	connectionID := ConnectionIDFromDIDs(myDID.ID, theirDID)
+
+	destination, err := s.connectionStore.GetDestination(thid)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch destination: %w", err)
+	}
+
+	record, err := s.completedConnection(thid, connectionID)
+	if err != nil {
+		return "", err
+	}
+
+	err = s.connectionStore.SaveCompletedConnection(thid, record, myDID, theirDID, destination)
+	if err != nil {
+		return "", fmt.Errorf("failed to save connection %s: %w", connectionID, err)
+	}
+
+	err = s.threads.Save(&threads.Record{
+		ThreadID:     connectionID,
+		ConnectionID: connectionID,
+		Protocol:     DIDExchange,
+		Completed:    true,
+		TheirVerKeys: destination.RecipientKeys,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to save thread %s: %w", connectionID, err)
+	}
+
+	return connectionID, nil
+}
+
+// completedConnection returns the record of the connection completed by the thread, the encryption algorithms used
+// by the counterparty on the thread are added to the ones known on the connection and the metadata attached to the
+// thread is kept
+func (s *Service) completedConnection(thid, connectionID string) (*ConnectionRecord, error) {
+	algs, err := s.connectionStore.GetEncryptionAlgs(connectionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch encryption algorithms: %w", err)
+	}
+
+	threadAlgs, err := s.connectionStore.GetEncryptionAlgs(thid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch encryption algorithms: %w", err)
+	}
+
+	for _, alg := range threadAlgs {
+		if !contains(algs, alg) {
+			algs = append(algs, alg)
+		}
+	}
+
+	metadata, err := s.connectionStore.GetConnectionMetadata(thid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch connection metadata: %w", err)
+	}
+
+	return &ConnectionRecord{ConnectionID: connectionID, State: stateNameCompleted, EncryptionAlgs: algs,
+		Tags: metadata.Tags, Metadata: metadata.Metadata}, nil
+}
@@ -0,0 +1,218 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package didexchange
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/service"
+	mockdid "github.com/hyperledger/aries-framework-go/pkg/internal/mock/common/did"
+	"github.com/hyperledger/aries-framework-go/pkg/internal/mock/didcomm/protocol"
+	mockstorage "github.com/hyperledger/aries-framework-go/pkg/internal/mock/storage"
+	"github.com/hyperledger/aries-framework-go/pkg/storage"
+)
+
+func TestConnectionIDFromDIDs(t *testing.T) {
+	id := ConnectionIDFromDIDs("did:example:alice", "did:example:bob")
+	require.Equal(t, id, ConnectionIDFromDIDs("did:example:alice", "did:example:bob"))
+	require.NotEqual(t, id, ConnectionIDFromDIDs("did:example:bob", "did:example:alice"))
+	require.NotEqual(t, id, ConnectionIDFromDIDs("did:example:alice", "did:example:carol"))
+}
+
+func TestService_DeterministicConnectionIDs(t *testing.T) {
+	const theirDID = "did:example:bob"
+
+	// completeExchange records the exchange of the thread up to the completion, as on the inviter side
+	completeExchange := func(t *testing.T, svc *Service, thid string) string {
+		require.NoError(t, svc.connectionStore.SaveMyDID(thid, getMockDID()))
+		require.NoError(t, svc.recordEncryptionAlgs(thid, []string{"XC20P"}))
+
+		payload, err := json.Marshal(&Request{
+			Connection: &Connection{DID: theirDID, DIDDoc: getMockDIDPublicKey()}})
+		require.NoError(t, err)
+
+		connectionID, err := svc.saveThread(&message{ThreadID: thid,
+			Msg: &service.DIDCommMsg{Type: ConnectionRequest, Payload: payload}}, &requested{})
+		require.NoError(t, err)
+		require.Equal(t, thid, connectionID)
+
+		require.NoError(t, svc.update(thid, &completed{}))
+		connectionID, err = svc.saveThread(&message{ThreadID: thid,
+			Msg: &service.DIDCommMsg{Type: ConnectionAck, Payload: []byte("{}")}}, &completed{})
+		require.NoError(t, err)
+
+		return connectionID
+	}
+
+	t.Run("test completed connection recorded under the derived ID", func(t *testing.T) {
+		svc, err := New(&mockdid.MockDIDCreator{Doc: getMockDID()}, &deterministicIDsProvider{})
+		require.NoError(t, err)
+		require.True(t, svc.deterministicIDs)
+
+		connectionID := completeExchange(t, svc, "thread1")
+		require.Equal(t, ConnectionIDFromDIDs(getMockDID().ID, theirDID), connectionID)
+
+		conn, err := svc.connectionStore.GetConnection(connectionID)
+		require.NoError(t, err)
+		require.Equal(t, stateNameCompleted, conn.State)
+		require.Equal(t, getMockDID().ID, conn.MyDID)
+
+		destination, err := svc.connectionStore.GetDestination(connectionID)
+		require.NoError(t, err)
+		require.Len(t, destination.RecipientKeys, len(getMockDIDPublicKey().PublicKey))
+		require.Equal(t, "https://localhost:8090", destination.ServiceEndpoint)
+
+		algs, err := svc.connectionStore.GetEncryptionAlgs(connectionID)
+		require.NoError(t, err)
+		require.Equal(t, []string{"XC20P"}, algs)
+
+		thread, err := svc.threads.Get("thread1")
+		require.NoError(t, err)
+		require.True(t, thread.Completed)
+		require.Equal(t, connectionID, thread.ConnectionID)
+
+		thread, err = svc.threads.Get(connectionID)
+		require.NoError(t, err)
+		require.True(t, thread.Completed)
+		require.Len(t, thread.TheirVerKeys, len(getMockDIDPublicKey().PublicKey))
+
+		// the records of the thread are moved to the connection
+		_, err = svc.connectionStore.GetConnection("thread1")
+		require.True(t, errors.Is(err, storage.ErrDataNotFound))
+		_, err = svc.connectionStore.GetDestination("thread1")
+		require.True(t, errors.Is(err, storage.ErrDataNotFound))
+
+		id, err := svc.ConnectionIDByKey(destination.RecipientKeys[0])
+		require.NoError(t, err)
+		require.Equal(t, connectionID, id)
+
+		ids, err := NewConnectionRecorder(svc.store).FindConnections(nil, nil)
+		require.NoError(t, err)
+		require.Equal(t, []string{connectionID}, ids)
+
+		// a retried exchange between the same DIDs completes the same connection
+		require.NoError(t, NewConnectionRecorder(svc.store).SaveConnectionMetadata("thread2",
+			&ConnectionMetadata{Tags: []string{"retried"}}))
+		require.Equal(t, connectionID, completeExchange(t, svc, "thread2"))
+
+		ids, err = NewConnectionRecorder(svc.store).FindConnections([]string{"retried"}, nil)
+		require.NoError(t, err)
+		require.Equal(t, []string{connectionID}, ids)
+	})
+
+	t.Run("test records written in a batch", func(t *testing.T) {
+		store := &mockstorage.MockStore{Store: make(map[string][]byte)}
+		svc, err := New(&mockdid.MockDIDCreator{Doc: getMockDID()}, &deterministicIDsProvider{
+			MockProvider: protocol.MockProvider{CustomStore: store}})
+		require.NoError(t, err)
+
+		require.NoError(t, svc.connectionStore.SaveMyDID("thread1", getMockDID()))
+		require.NoError(t, svc.connectionStore.SaveTheirDID("thread1", theirDID))
+		require.NoError(t, svc.connectionStore.SaveDestination("thread1", &service.Destination{ServiceEndpoint: "url"}))
+
+		store.ErrPut = errors.New("put error")
+		_, err = svc.recordConnection("thread1")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "put error")
+
+		// nothing is written when the batch fails
+		store.ErrPut = nil
+		_, err = svc.connectionStore.GetConnection(ConnectionIDFromDIDs(getMockDID().ID, theirDID))
+		require.True(t, errors.Is(err, storage.ErrDataNotFound))
+	})
+
+	t.Run("test connection recorded under the thread without the option", func(t *testing.T) {
+		svc, err := New(&mockdid.MockDIDCreator{Doc: getMockDID()}, &protocol.MockProvider{})
+		require.NoError(t, err)
+		require.False(t, svc.deterministicIDs)
+
+		require.Equal(t, "thread1", completeExchange(t, svc, "thread1"))
+	})
+
+	t.Run("test connection recorded under the thread if the DIDs aren't known", func(t *testing.T) {
+		svc, err := New(&mockdid.MockDIDCreator{Doc: getMockDID()}, &deterministicIDsProvider{})
+		require.NoError(t, err)
+
+		connectionID, err := svc.saveThread(&message{ThreadID: "thread1",
+			Msg: &service.DIDCommMsg{Type: ConnectionAck, Payload: []byte("{}")}}, &completed{})
+		require.NoError(t, err)
+		require.Equal(t, "thread1", connectionID)
+	})
+
+	t.Run("test store error", func(t *testing.T) {
+		store := &mockStore{
+			get: func(string) ([]byte, error) { return nil, errors.New("get error") },
+		}
+		svc := &Service{connectionStore: NewConnectionRecorder(store)}
+
+		_, err := svc.recordConnection("thread1")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to fetch my DID")
+	})
+
+	t.Run("test static connection", func(t *testing.T) {
+		svc, err := New(&mockdid.MockDIDCreator{Doc: getMockDID()}, &deterministicIDsProvider{})
+		require.NoError(t, err)
+
+		connectionID, err := svc.CreateStaticConnection(&StaticConnection{
+			MyDIDDoc:    getMockDID(),
+			TheirDIDDoc: getMockDIDPublicKey(),
+		})
+		require.NoError(t, err)
+		require.Equal(t, ConnectionIDFromDIDs(getMockDID().ID, getMockDIDPublicKey().ID), connectionID)
+
+		conn, err := svc.connectionStore.GetConnection(connectionID)
+		require.NoError(t, err)
+		require.Equal(t, getMockDID().ID, conn.MyDID)
+
+		theirDID, err := svc.connectionStore.GetTheirDID(connectionID)
+		require.NoError(t, err)
+		require.Equal(t, getMockDIDPublicKey().ID, theirDID)
+	})
+}
+
+// deterministicIDsProvider enables the deterministic connection IDs
+type deterministicIDsProvider struct {
+	protocol.MockProvider
+}
+
+func (p *deterministicIDsProvider) DeterministicConnectionIDs() bool {
+	return true
+}
+
+func TestConnectionRecorder_TheirDID(t *testing.T) {
+	store := &mockStore{
+		get: func(string) ([]byte, error) { return nil, storage.ErrDataNotFound },
+	}
+
+	theirDID, err := NewConnectionRecorder(store).GetTheirDID("conn1")
+	require.NoError(t, err)
+	require.Empty(t, theirDID)
+
+	store.get = func(string) ([]byte, error) { return nil, errors.New("get error") }
+	_, err = NewConnectionRecorder(store).GetTheirDID("conn1")
+	require.EqualError(t, err, "get error")
+}
+
+func TestService_ConnectionIDByKey(t *testing.T) {
+	svc, err := New(&mockdid.MockDIDCreator{Doc: getMockDID()}, &protocol.MockProvider{})
+	require.NoError(t, err)
+
+	_, err = svc.ConnectionIDByKey("key1")
+	require.True(t, errors.Is(err, storage.ErrDataNotFound))
+
+	require.NoError(t, svc.connectionStore.SaveDestination("conn1",
+		&service.Destination{RecipientKeys: []string{"key1"}, ServiceEndpoint: "url"}))
+
+	connectionID, err := svc.ConnectionIDByKey("key1")
+	require.NoError(t, err)
+	require.Equal(t, "conn1", connectionID)
+}
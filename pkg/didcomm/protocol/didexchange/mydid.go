@@ -0,0 +1,163 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package didexchange
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	"github.com/hyperledger/aries-framework-go/pkg/wallet"
+)
+
+// myDIDStore keeps the DIDs of the agent backing the connections
+type myDIDStore interface {
+	SaveMyDID(connectionID string, doc *did.Doc) error
+	GetMyDID(connectionID string) (*did.Doc, error)
+}
+
+// SetMyDID sets the DID the agent completes the DID exchange of the connection with, e.g. an existing public DID,
+// instead of a new peer DID. The DID is set before the agent sends it: for the invitee, under the invitation ID
+// before handling the invitation and, for the inviter, under the thread ID of the exchange request before
+// continuing its action event. The keys of the DID must be held by the wallet. Setting the same DID again is
+// a no-op so a failed exchange can be retried.
+func (s *Service) SetMyDID(connectionID string, doc *did.Doc) error {
+	pubKeys, err := getPublicKeys(doc, supportedPublicKeyType)
+	if err != nil {
+		return fmt.Errorf("DID %s has no %s key: %w", doc.ID, supportedPublicKeyType, err)
+	}
+
+	if err = s.checkKeyHeld(doc.ID, string(pubKeys[0].Value)); err != nil {
+		return err
+	}
+
+	current, err := s.connectionStore.GetMyDID(connectionID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch DID of connection %s: %w", connectionID, err)
+	}
+
+	if current != nil {
+		if current.ID == doc.ID {
+			return nil
+		}
+
+		return fmt.Errorf("connection %s is already backed by DID %s", connectionID, current.ID)
+	}
+
+	return s.connectionStore.SaveMyDID(connectionID, doc)
+}
+
+// checkKeyHeld checks the wallet holds the private key of the DID the agent sends its messages with
+func (s *Service) checkKeyHeld(didID, verKey string) error {
+	if s.keys == nil {
+		return fmt.Errorf("the wallet can't check the key of DID %s", didID)
+	}
+
+	held, err := s.keys.HasKey(verKey)
+	if err != nil {
+		return fmt.Errorf("failed to check the key of DID %s: %w", didID, err)
+	}
+
+	if !held {
+		return fmt.Errorf("the wallet doesn't hold the private key of DID %s", didID)
+	}
+
+	return nil
+}
+
+// keyHolderOf returns the wallet of the provider if it tells whether it holds the keys, nil otherwise
+func keyHolderOf(prov provider) wallet.KeyHolder {
+	p, ok := prov.(cryptoWalletProvider)
+	if !ok {
+		return nil
+	}
+
+	keys, ok := p.CryptoWallet().(wallet.KeyHolder)
+	if !ok {
+		return nil
+	}
+
+	return keys
+}
+
+// MyDID returns the DID of the agent backing the connection, nil if not set or created yet
+func (s *Service) MyDID(connectionID string) (*did.Doc, error) {
+	return s.connectionStore.GetMyDID(connectionID)
+}
+
+// inviteeDIDDoc returns the DID set for the invitation, recorded for the connection thread, otherwise the DID
+// of the connection thread
+func (c *stateContext) inviteeDIDDoc(invitationID, thid string) (*did.Doc, error) {
+	if c.myDIDs == nil || invitationID == "" {
+		return c.myDIDDoc(thid)
+	}
+
+	doc, err := c.myDIDs.GetMyDID(invitationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch DID of invitation %s: %w", invitationID, err)
+	}
+
+	if doc == nil {
+		return c.myDIDDoc(thid)
+	}
+
+	if err = c.myDIDs.SaveMyDID(thid, doc); err != nil {
+		return nil, fmt.Errorf("failed to save DID of connection %s: %w", thid, err)
+	}
+
+	return doc, nil
+}
+
+// myDIDDoc returns the DID set for the connection, otherwise creates a new peer DID recorded for the connection
+func (c *stateContext) myDIDDoc(connectionID string) (*did.Doc, error) {
+	if c.myDIDs != nil {
+		doc, err := c.myDIDs.GetMyDID(connectionID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch DID of connection %s: %w", connectionID, err)
+		}
+
+		if doc != nil {
+			return doc, nil
+		}
+	}
+
+	doc, err := c.didCreator.CreateDID(wallet.WithServiceType(DIDExchangeServiceType))
+	if err != nil {
+		return nil, err
+	}
+
+	if c.myDIDs != nil {
+		if err = c.myDIDs.SaveMyDID(connectionID, doc); err != nil {
+			return nil, fmt.Errorf("failed to save DID of connection %s: %w", connectionID, err)
+		}
+	}
+
+	return doc, nil
+}
+
+// myVerKey returns the key of the DID of the agent backing the connection, the key the messages of the
+// connection are packed with. The key is empty when no DID backs the connection yet.
+func (c *stateContext) myVerKey(connectionID string) (string, error) {
+	if c.myDIDs == nil {
+		return "", nil
+	}
+
+	doc, err := c.myDIDs.GetMyDID(connectionID)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch DID of connection %s: %w", connectionID, err)
+	}
+
+	if doc == nil {
+		return "", nil
+	}
+
+	pubKey, err := getPublicKeys(doc, supportedPublicKeyType)
+	if err != nil {
+		return "", fmt.Errorf("DID %s has no %s key: %w", doc.ID, supportedPublicKeyType, err)
+	}
+
+	return string(pubKey[0].Value), nil
+}
@@ -0,0 +1,180 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package didexchange
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	mockdid "github.com/hyperledger/aries-framework-go/pkg/internal/mock/common/did"
+	"github.com/hyperledger/aries-framework-go/pkg/internal/mock/didcomm/protocol"
+	mockstorage "github.com/hyperledger/aries-framework-go/pkg/internal/mock/storage"
+	mockwallet "github.com/hyperledger/aries-framework-go/pkg/internal/mock/wallet"
+)
+
+func TestService_SetMyDID(t *testing.T) {
+	keyHolder := &mockwallet.CloseableWallet{HasKeyValue: true}
+
+	t.Run("test set public DID", func(t *testing.T) {
+		s, err := New(&mockdid.MockDIDCreator{Doc: getMockDID()}, &protocol.MockProvider{WalletValue: keyHolder})
+		require.NoError(t, err)
+
+		doc, err := s.MyDID("conn1")
+		require.NoError(t, err)
+		require.Nil(t, doc)
+
+		require.NoError(t, s.SetMyDID("conn1", getPublicDID()))
+
+		doc, err = s.MyDID("conn1")
+		require.NoError(t, err)
+		require.Equal(t, getPublicDID().ID, doc.ID)
+
+		// setting the same DID again is a no-op
+		require.NoError(t, s.SetMyDID("conn1", getPublicDID()))
+
+		err = s.SetMyDID("conn1", getMockDID())
+		require.EqualError(t, err, "connection conn1 is already backed by DID did:example:public")
+	})
+
+	t.Run("test DID without supported key", func(t *testing.T) {
+		s, err := New(&mockdid.MockDIDCreator{Doc: getMockDID()}, &protocol.MockProvider{})
+		require.NoError(t, err)
+
+		err = s.SetMyDID("conn1", getMockDIDPublicKey())
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "has no Ed25519VerificationKey2018 key")
+	})
+
+	t.Run("test key not held by the wallet", func(t *testing.T) {
+		s, err := New(&mockdid.MockDIDCreator{Doc: getMockDID()}, &protocol.MockProvider{})
+		require.NoError(t, err)
+
+		err = s.SetMyDID("conn1", getPublicDID())
+		require.EqualError(t, err, "the wallet can't check the key of DID did:example:public")
+
+		s.keys = &mockwallet.CloseableWallet{}
+		err = s.SetMyDID("conn1", getPublicDID())
+		require.EqualError(t, err, "the wallet doesn't hold the private key of DID did:example:public")
+
+		s.keys = &mockwallet.CloseableWallet{HasKeyErr: fmt.Errorf("wallet error")}
+		err = s.SetMyDID("conn1", getPublicDID())
+		require.EqualError(t, err, "failed to check the key of DID did:example:public: wallet error")
+
+		doc, err := s.MyDID("conn1")
+		require.NoError(t, err)
+		require.Nil(t, doc)
+	})
+
+	t.Run("test store error", func(t *testing.T) {
+		s := &Service{connectionStore: NewConnectionRecorder(&mockStore{
+			get: func(string) ([]byte, error) { return nil, fmt.Errorf("get error") },
+		}), keys: keyHolder}
+
+		err := s.SetMyDID("conn1", getPublicDID())
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "get error")
+	})
+}
+
+func TestStateContext_MyDIDDoc(t *testing.T) {
+	t.Run("test public DID is used instead of a new DID", func(t *testing.T) {
+		recorder := NewConnectionRecorder(&mockstorage.MockStore{Store: make(map[string][]byte)})
+		require.NoError(t, recorder.SaveMyDID("thid1", getPublicDID()))
+
+		ctx := &stateContext{outboundDispatcher: newMockOutboundDispatcher(),
+			didCreator: &mockdid.MockDIDCreator{Failure: fmt.Errorf("create DID error")}, myDIDs: recorder}
+
+		_, err := ctx.handleInboundInvitation(&Invitation{ID: "thid1"}, "thid1")
+		require.NoError(t, err)
+
+		// the DID set for the invitation is recorded for the connection thread
+		_, err = ctx.handleInboundInvitation(&Invitation{ID: "thid1"}, "thid2")
+		require.NoError(t, err)
+
+		doc, err := recorder.GetMyDID("thid2")
+		require.NoError(t, err)
+		require.Equal(t, getPublicDID().ID, doc.ID)
+
+		_, err = ctx.handleInboundRequest(&Request{Connection: &Connection{DIDDoc: getMockDID()}}, "thid1")
+		require.NoError(t, err)
+	})
+
+	t.Run("test new DID is recorded", func(t *testing.T) {
+		recorder := NewConnectionRecorder(&mockstorage.MockStore{Store: make(map[string][]byte)})
+		ctx := &stateContext{outboundDispatcher: newMockOutboundDispatcher(),
+			didCreator: &mockdid.MockDIDCreator{Doc: getMockDID()}, myDIDs: recorder}
+
+		doc, err := ctx.myDIDDoc("thid1")
+		require.NoError(t, err)
+		require.Equal(t, getMockDID().ID, doc.ID)
+
+		doc, err = recorder.GetMyDID("thid1")
+		require.NoError(t, err)
+		require.Equal(t, getMockDID().ID, doc.ID)
+	})
+
+	t.Run("test store errors", func(t *testing.T) {
+		ctx := &stateContext{didCreator: &mockdid.MockDIDCreator{Doc: getMockDID()},
+			myDIDs: NewConnectionRecorder(&mockStore{
+				get: func(string) ([]byte, error) { return nil, fmt.Errorf("get error") },
+			})}
+
+		_, err := ctx.myDIDDoc("thid1")
+		require.EqualError(t, err, "failed to fetch DID of connection thid1: get error")
+
+		ctx.myDIDs = NewConnectionRecorder(&mockstorage.MockStore{
+			Store: make(map[string][]byte), ErrPut: fmt.Errorf("put error")})
+
+		_, err = ctx.myDIDDoc("thid1")
+		require.EqualError(t, err, "failed to save DID of connection thid1: put error")
+	})
+}
+
+func TestStateContext_MyVerKey(t *testing.T) {
+	t.Run("test key of the DID backing the connection", func(t *testing.T) {
+		recorder := NewConnectionRecorder(&mockstorage.MockStore{Store: make(map[string][]byte)})
+		require.NoError(t, recorder.SaveMyDID("thid1", getPublicDID()))
+
+		ctx := &stateContext{myDIDs: recorder}
+
+		key, err := ctx.myVerKey("thid1")
+		require.NoError(t, err)
+		require.Equal(t, string(getPublicDID().PublicKey[0].Value), key)
+
+		key, err = ctx.myVerKey("thid2")
+		require.NoError(t, err)
+		require.Empty(t, key)
+	})
+
+	t.Run("test DID without supported key", func(t *testing.T) {
+		recorder := NewConnectionRecorder(&mockstorage.MockStore{Store: make(map[string][]byte)})
+		require.NoError(t, recorder.SaveMyDID("thid1", getMockDIDPublicKey()))
+
+		_, err := (&stateContext{myDIDs: recorder}).myVerKey("thid1")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "has no Ed25519VerificationKey2018 key")
+	})
+
+	t.Run("test store error", func(t *testing.T) {
+		ctx := &stateContext{myDIDs: NewConnectionRecorder(&mockStore{
+			get: func(string) ([]byte, error) { return nil, fmt.Errorf("get error") },
+		})}
+
+		_, err := ctx.myVerKey("thid1")
+		require.EqualError(t, err, "failed to fetch DID of connection thid1: get error")
+	})
+}
+
+func getPublicDID() *did.Doc {
+	doc := getMockDID()
+	doc.ID = "did:example:public"
+
+	return doc
+}
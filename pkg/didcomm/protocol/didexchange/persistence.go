@@ -11,21 +11,100 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/service"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
 	"github.com/hyperledger/aries-framework-go/pkg/storage"
 )
 
 const (
-	keyPattern   = "%s_%s"
-	invKeyPrefix = "inv_"
+	keyPattern      = "%s_%s"
+	invKeyPrefix    = "inv_"
+	invUsagePrefix  = "invusage"
+	encAlgKeyPrefix = "encalg"
+	destKeyPrefix   = "dest"
+	myDIDKeyPrefix  = "mydid"
+	theirDIDPrefix  = "theirdid"
+	metaKeyPrefix   = "connmeta"
+	theirKeyPrefix  = "theirkey"
+	connKeyPrefix   = "conn"
 )
 
+var (
+	// ErrInvitationExpired is returned when an exchange request references an expired invitation
+	ErrInvitationExpired = errors.New("invitation expired")
+	// ErrInvitationUsed is returned when an exchange request references a single-use invitation already used
+	ErrInvitationUsed = errors.New("invitation already used")
+	// ErrNotConditional is returned when a single-use invitation is used from a store which isn't a
+	// storage.ConditionalStore
+	ErrNotConditional = errors.New("single-use invitations require a conditional store")
+	// ErrQueryNotSupported is returned when the connections can't be queried because the store can't be iterated
+	ErrQueryNotSupported = errors.New("connection queries are not supported by the store")
+)
+
+// InvitationUsage restricts the exchange requests accepted for an invitation created by the agent
+type InvitationUsage struct {
+	// ExpiresAt is the time after which the exchange requests are rejected, zero for no expiry
+	ExpiresAt time.Time `json:"expiresAt,omitempty"`
+	// SingleUse accepts a single exchange request for the invitation
+	SingleUse bool `json:"singleUse,omitempty"`
+	// Used marks the single-use invitations an exchange request has been accepted for
+	Used bool `json:"used,omitempty"`
+}
+
 // ConnectionRecord contain info about did exchange connection
 type ConnectionRecord struct {
 	// State of the connection invitation
 	State string
 
 	ConnectionID string
+
+	// EncryptionAlgs are the envelope content encryption algorithms supported by the counterparty
+	EncryptionAlgs []string
+
+	// MyDID is the DID of the agent backing the connection, empty until the DID exchange creates it
+	MyDID string
+
+	// Tags are the tags attached to the connection by the application
+	Tags []string `json:",omitempty"`
+
+	// Metadata are the key/values attached to the connection by the application
+	Metadata map[string]string `json:",omitempty"`
+}
+
+// ConnectionMetadata are the tags and key/values attached to a connection by the application, e.g. the customer
+// or the department of the counterparty
+type ConnectionMetadata struct {
+	Tags     []string          `json:"tags,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// Matches returns true if the connection has all the tags and all the key/values
+func (m *ConnectionMetadata) Matches(tags []string, metadata map[string]string) bool {
+	for _, tag := range tags {
+		found := false
+
+		for _, t := range m.Tags {
+			if t == tag {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	for k, v := range metadata {
+		if value, ok := m.Metadata[k]; !ok || value != v {
+			return false
+		}
+	}
+
+	return true
 }
 
 // NewConnectionRecorder returns new connection record instance
@@ -94,13 +173,532 @@ func (c *ConnectionRecorder) GetInvitation(verKey string) (*Invitation, error) {
 	return result, nil
 }
 
+// SaveInvitationUsage saves the usage restrictions of the invitation with the recipient key verKey
+func (c *ConnectionRecorder) SaveInvitationUsage(verKey string, usage *InvitationUsage) error {
+	k, err := invitationUsageKey(verKey)
+	if err != nil {
+		return err
+	}
+
+	bytes, err := json.Marshal(usage)
+	if err != nil {
+		return err
+	}
+
+	return c.store.Put(k, bytes)
+}
+
+// CheckInvitation checks that an exchange request can be received for the invitation with the recipient key
+// verKey at the given time. ErrInvitationExpired or ErrInvitationUsed is returned if the request must be rejected,
+// invitations without usage restrictions are always accepted.
+func (c *ConnectionRecorder) CheckInvitation(verKey string, now time.Time) error {
+	_, _, _, err := c.invitationUsage(verKey, now)
+
+	return err
+}
+
+// UseInvitation checks the invitation as CheckInvitation does and marks single-use invitations as used, it's
+// called when an exchange request is accepted. The invitation is marked with a conditional put, which requires
+// a storage.ConditionalStore, so concurrent requests can't both use it.
+func (c *ConnectionRecorder) UseInvitation(verKey string, now time.Time) error {
+	k, usage, current, err := c.invitationUsage(verKey, now)
+	if err != nil || usage == nil || !usage.SingleUse {
+		return err
+	}
+
+	conditional, ok := c.store.(storage.ConditionalStore)
+	if !ok {
+		return fmt.Errorf("failed to use single-use invitation %s: %w", verKey, ErrNotConditional)
+	}
+
+	usage.Used = true
+
+	bytes, err := json.Marshal(usage)
+	if err != nil {
+		return err
+	}
+
+	err = conditional.PutIf(k, bytes, current)
+	if errors.Is(err, storage.ErrConflict) {
+		return fmt.Errorf("%w: %s", ErrInvitationUsed, verKey)
+	}
+
+	return err
+}
+
+// invitationUsage returns the store key, the usage restrictions and the stored record of the invitation with the
+// recipient key verKey, nil usage if the invitation has no restrictions. ErrInvitationExpired or ErrInvitationUsed
+// is returned if the exchange requests are rejected at the given time.
+func (c *ConnectionRecorder) invitationUsage(verKey string, now time.Time) (string, *InvitationUsage, []byte, error) {
+	k, err := invitationUsageKey(verKey)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	bytes, err := c.store.Get(k)
+	if errors.Is(err, storage.ErrDataNotFound) {
+		return k, nil, nil, nil
+	}
+
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	usage := &InvitationUsage{}
+
+	err = json.Unmarshal(bytes, usage)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	if !usage.ExpiresAt.IsZero() && now.After(usage.ExpiresAt) {
+		return "", nil, nil, fmt.Errorf("%w: %s", ErrInvitationExpired, verKey)
+	}
+
+	if usage.SingleUse && usage.Used {
+		return "", nil, nil, fmt.Errorf("%w: %s", ErrInvitationUsed, verKey)
+	}
+
+	return k, usage, bytes, nil
+}
+
+// SaveNewConnection saves the new connection with the invitation it was created for and the DID of the agent
+// backing it, the invitation and the DID are optional. The records are written atomically if the store is a
+// storage.BatchStore, otherwise the invitation is written last so a crash can't leave an invitation referencing
+// a connection which hasn't been saved.
+func (c *ConnectionRecorder) SaveNewConnection(record *ConnectionRecord, invitation *Invitation, myDID *did.Doc) error {
+	records, err := newConnectionRecords(record, invitation, myDID)
+	if err != nil {
+		return fmt.Errorf("failed to save new connection: %w", err)
+	}
+
+	return c.putAll(records)
+}
+
+// SaveCompletedConnection saves the connection completed by the exchange thread with the DID of the counterparty
+// and its destination, then deletes the records of the thread so the connection is only recorded under its ID.
+// The records of the connection are written atomically if the store is a storage.BatchStore.
+func (c *ConnectionRecorder) SaveCompletedConnection(thid string, record *ConnectionRecord, myDID *did.Doc,
+	theirDID string, destination *service.Destination) error {
+	records, err := newConnectionRecords(record, nil, myDID)
+	if err != nil {
+		return fmt.Errorf("failed to save completed connection: %w", err)
+	}
+
+	bytes, err := json.Marshal(destination)
+	if err != nil {
+		return fmt.Errorf("failed to save completed connection: %w", err)
+	}
+
+	records = append(records,
+		connectionEntry{key: theirDIDKey(record.ConnectionID), value: []byte(theirDID)},
+		connectionEntry{key: destinationKey(record.ConnectionID), value: bytes})
+
+	for _, key := range destination.RecipientKeys {
+		records = append(records, connectionEntry{key: theirKeyKey(key), value: []byte(record.ConnectionID)})
+	}
+
+	if err = c.putAll(records); err != nil {
+		return err
+	}
+
+	return c.RemoveConnection(thid)
+}
+
+// putAll writes the records atomically if the store is a storage.BatchStore, in order otherwise
+func (c *ConnectionRecorder) putAll(records []connectionEntry) error {
+	if batch, ok := c.store.(storage.BatchStore); ok {
+		values := make(map[string][]byte, len(records))
+		for _, r := range records {
+			values[r.key] = r.value
+		}
+
+		return batch.PutAll(values)
+	}
+
+	for _, r := range records {
+		if err := c.store.Put(r.key, r.value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type connectionEntry struct {
+	key   string
+	value []byte
+}
+
+// newConnectionRecords returns the records of the new connection in the order of the non-atomic writes
+func newConnectionRecords(record *ConnectionRecord, invitation *Invitation,
+	myDID *did.Doc) ([]connectionEntry, error) {
+	if record == nil || record.ConnectionID == "" {
+		return nil, errors.New("connection ID is mandatory")
+	}
+
+	records := []connectionEntry{
+		{key: record.ConnectionID, value: []byte(record.State)},
+		{key: connectionKey(record.ConnectionID), value: []byte(record.ConnectionID)},
+	}
+
+	if len(record.EncryptionAlgs) > 0 {
+		bytes, err := json.Marshal(record.EncryptionAlgs)
+		if err != nil {
+			return nil, err
+		}
+
+		records = append(records, connectionEntry{key: encryptionAlgsKey(record.ConnectionID), value: bytes})
+	}
+
+	if len(record.Tags) > 0 || len(record.Metadata) > 0 {
+		bytes, err := json.Marshal(&ConnectionMetadata{Tags: record.Tags, Metadata: record.Metadata})
+		if err != nil {
+			return nil, err
+		}
+
+		records = append(records, connectionEntry{key: metadataKey(record.ConnectionID), value: bytes})
+	}
+
+	if myDID != nil {
+		bytes, err := myDID.JSONBytes()
+		if err != nil {
+			return nil, err
+		}
+
+		records = append(records, connectionEntry{key: myDIDKey(record.ConnectionID), value: bytes})
+	}
+
+	if invitation != nil {
+		if len(invitation.RecipientKeys) == 0 {
+			return nil, errors.New("invitation has no recipient key")
+		}
+
+		k, err := invitationKey(invitation.RecipientKeys[0])
+		if err != nil {
+			return nil, err
+		}
+
+		bytes, err := json.Marshal(invitation)
+		if err != nil {
+			return nil, err
+		}
+
+		records = append(records, connectionEntry{key: k, value: bytes})
+	}
+
+	return records, nil
+}
+
 // GetConnection return connection record
 func (c *ConnectionRecorder) GetConnection(connectionID string) (*ConnectionRecord, error) {
 	name, err := c.store.Get(connectionID)
 	if err != nil {
 		return nil, err
 	}
-	return &ConnectionRecord{State: string(name)}, nil
+
+	record := &ConnectionRecord{State: string(name)}
+
+	myDID, err := c.GetMyDID(connectionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if myDID != nil {
+		record.MyDID = myDID.ID
+	}
+
+	metadata, err := c.GetConnectionMetadata(connectionID)
+	if err != nil {
+		return nil, err
+	}
+
+	record.Tags = metadata.Tags
+	record.Metadata = metadata.Metadata
+
+	return record, nil
+}
+
+// SaveConnectionMetadata saves the tags and key/values attached to the connection, replacing the previous ones
+func (c *ConnectionRecorder) SaveConnectionMetadata(connectionID string, metadata *ConnectionMetadata) error {
+	bytes, err := json.Marshal(metadata)
+	if err != nil {
+		return err
+	}
+
+	return c.store.Put(metadataKey(connectionID), bytes)
+}
+
+// GetConnectionMetadata returns the tags and key/values attached to the connection, empty if none
+func (c *ConnectionRecorder) GetConnectionMetadata(connectionID string) (*ConnectionMetadata, error) {
+	bytes, err := c.store.Get(metadataKey(connectionID))
+	if err != nil {
+		if errors.Is(err, storage.ErrDataNotFound) {
+			return &ConnectionMetadata{}, nil
+		}
+
+		return nil, err
+	}
+
+	metadata := &ConnectionMetadata{}
+
+	err = json.Unmarshal(bytes, metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	return metadata, nil
+}
+
+// SaveConnectionIndex indexes the connection, the connections are listed from the index by FindConnections
+func (c *ConnectionRecorder) SaveConnectionIndex(connectionID string) error {
+	return c.store.Put(connectionKey(connectionID), []byte(connectionID))
+}
+
+// FindConnections returns the IDs of the connections having all the tags and all the key/values, in ID order.
+// All the connections are returned without tags and key/values. ErrQueryNotSupported is returned if the store
+// isn't a storage.IterableStore.
+func (c *ConnectionRecorder) FindConnections(tags []string, metadata map[string]string) ([]string, error) {
+	iterable, ok := c.store.(storage.IterableStore)
+	if !ok {
+		return nil, ErrQueryNotSupported
+	}
+
+	if len(tags) == 0 && len(metadata) == 0 {
+		return c.connectionIDs(iterable)
+	}
+
+	prefix := metadataKey("")
+
+	var ids []string
+
+	err := iterable.Iterate(prefix, func(k string, v []byte) error {
+		m := &ConnectionMetadata{}
+		if err := json.Unmarshal(v, m); err != nil {
+			return fmt.Errorf("invalid metadata of connection %s: %w", strings.TrimPrefix(k, prefix), err)
+		}
+
+		if m.Matches(tags, metadata) {
+			ids = append(ids, strings.TrimPrefix(k, prefix))
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// connectionIDs returns the IDs of the indexed connections in ID order
+func (c *ConnectionRecorder) connectionIDs(iterable storage.IterableStore) ([]string, error) {
+	var ids []string
+
+	err := iterable.Iterate(connectionKey(""), func(_ string, v []byte) error {
+		ids = append(ids, string(v))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// RemoveConnection deletes the records of the connection: its state, metadata, DIDs, destination, encryption
+// algorithms and the index of the keys of the counterparty. The state is deleted last so a failed removal can be
+// retried.
+func (c *ConnectionRecorder) RemoveConnection(connectionID string) error {
+	keys := []string{
+		metadataKey(connectionID), encryptionAlgsKey(connectionID), myDIDKey(connectionID),
+		theirDIDKey(connectionID),
+	}
+
+	destination, err := c.GetDestination(connectionID)
+
+	switch {
+	case err == nil:
+		for _, key := range destination.RecipientKeys {
+			// the key may have been reused by another connection since
+			if id, e := c.GetConnectionIDByKey(key); e == nil && id == connectionID {
+				keys = append(keys, theirKeyKey(key))
+			}
+		}
+	case !errors.Is(err, storage.ErrDataNotFound):
+		return fmt.Errorf("failed to fetch destination: %w", err)
+	}
+
+	keys = append(keys, destinationKey(connectionID), connectionKey(connectionID), connectionID)
+
+	for _, k := range keys {
+		if err = c.store.Delete(k); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SaveEncryptionAlgs saves the envelope content encryption algorithms supported by the counterparty
+// of the connection, as learned from the inbound envelopes
+func (c *ConnectionRecorder) SaveEncryptionAlgs(connectionID string, algs []string) error {
+	bytes, err := json.Marshal(algs)
+	if err != nil {
+		return err
+	}
+
+	return c.store.Put(encryptionAlgsKey(connectionID), bytes)
+}
+
+// GetEncryptionAlgs returns the envelope content encryption algorithms supported by the counterparty
+// of the connection, nil if not known
+func (c *ConnectionRecorder) GetEncryptionAlgs(connectionID string) ([]string, error) {
+	bytes, err := c.store.Get(encryptionAlgsKey(connectionID))
+	if err != nil {
+		if errors.Is(err, storage.ErrDataNotFound) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	var algs []string
+
+	err = json.Unmarshal(bytes, &algs)
+	if err != nil {
+		return nil, err
+	}
+
+	return algs, nil
+}
+
+// SaveDestination saves the destination of the counterparty of the connection, the recipient keys of the
+// destination are indexed to look the connection up from the keys of the counterparty
+func (c *ConnectionRecorder) SaveDestination(connectionID string, destination *service.Destination) error {
+	bytes, err := json.Marshal(destination)
+	if err != nil {
+		return err
+	}
+
+	if err = c.store.Put(destinationKey(connectionID), bytes); err != nil {
+		return err
+	}
+
+	for _, key := range destination.RecipientKeys {
+		if err = c.store.Put(theirKeyKey(key), []byte(connectionID)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetConnectionIDByKey returns the ID of the connection whose counterparty has the key,
+// storage.ErrDataNotFound is returned if not known
+func (c *ConnectionRecorder) GetConnectionIDByKey(theirVerKey string) (string, error) {
+	bytes, err := c.store.Get(theirKeyKey(theirVerKey))
+	if err != nil {
+		return "", err
+	}
+
+	return string(bytes), nil
+}
+
+// GetDestination returns the destination of the counterparty of the connection,
+// storage.ErrDataNotFound is returned if not known
+func (c *ConnectionRecorder) GetDestination(connectionID string) (*service.Destination, error) {
+	bytes, err := c.store.Get(destinationKey(connectionID))
+	if err != nil {
+		return nil, err
+	}
+
+	destination := &service.Destination{}
+
+	err = json.Unmarshal(bytes, destination)
+	if err != nil {
+		return nil, err
+	}
+
+	return destination, nil
+}
+
+// SaveMyDID saves the DID document of the agent backing the connection
+func (c *ConnectionRecorder) SaveMyDID(connectionID string, doc *did.Doc) error {
+	bytes, err := doc.JSONBytes()
+	if err != nil {
+		return err
+	}
+
+	return c.store.Put(myDIDKey(connectionID), bytes)
+}
+
+// GetMyDID returns the DID document of the agent backing the connection, nil if not known
+func (c *ConnectionRecorder) GetMyDID(connectionID string) (*did.Doc, error) {
+	bytes, err := c.store.Get(myDIDKey(connectionID))
+	if err != nil {
+		if errors.Is(err, storage.ErrDataNotFound) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return did.ParseDocument(bytes)
+}
+
+// SaveTheirDID saves the DID of the counterparty of the connection
+func (c *ConnectionRecorder) SaveTheirDID(connectionID, theirDID string) error {
+	return c.store.Put(theirDIDKey(connectionID), []byte(theirDID))
+}
+
+// GetTheirDID returns the DID of the counterparty of the connection, empty if not known
+func (c *ConnectionRecorder) GetTheirDID(connectionID string) (string, error) {
+	bytes, err := c.store.Get(theirDIDKey(connectionID))
+	if err != nil {
+		if errors.Is(err, storage.ErrDataNotFound) {
+			return "", nil
+		}
+
+		return "", err
+	}
+
+	return string(bytes), nil
+}
+
+// connectionKey computes key for the index of the connection
+func connectionKey(connectionID string) string {
+	return fmt.Sprintf(keyPattern, connKeyPrefix, connectionID)
+}
+
+// myDIDKey computes key for the DID of the agent backing the connection
+func myDIDKey(connectionID string) string {
+	return fmt.Sprintf(keyPattern, myDIDKeyPrefix, connectionID)
+}
+
+// theirDIDKey computes key for the DID of the counterparty of the connection
+func theirDIDKey(connectionID string) string {
+	return fmt.Sprintf(keyPattern, theirDIDPrefix, connectionID)
+}
+
+// metadataKey computes key for the metadata attached to the connection
+func metadataKey(connectionID string) string {
+	return fmt.Sprintf(keyPattern, metaKeyPrefix, connectionID)
+}
+
+// destinationKey computes key for the destination of the connection
+func destinationKey(connectionID string) string {
+	return fmt.Sprintf(keyPattern, destKeyPrefix, connectionID)
+}
+
+// theirKeyKey computes key for the connection of the counterparty key
+func theirKeyKey(verKey string) string {
+	return fmt.Sprintf(keyPattern, theirKeyPrefix, verKey)
+}
+
+// encryptionAlgsKey computes key for the encryption algorithms of the connection
+func encryptionAlgsKey(connectionID string) string {
+	return fmt.Sprintf(keyPattern, encAlgKeyPrefix, connectionID)
 }
 
 // invitationKey computes key for invitation object
@@ -113,6 +711,16 @@ func invitationKey(verKey string) (string, error) {
 	return fmt.Sprintf(keyPattern, invKeyPrefix, storeKey), nil
 }
 
+// invitationUsageKey computes key for the usage restrictions of the invitation
+func invitationUsageKey(verKey string) (string, error) {
+	storeKey, err := computeHash([]byte(verKey))
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf(keyPattern, invUsagePrefix, storeKey), nil
+}
+
 // computeHash will compute the hash for the supplied bytes
 func computeHash(bytes []byte) (string, error) {
 	if len(bytes) == 0 {
@@ -7,11 +7,14 @@ SPDX-License-Identifier: Apache-2.0
 package didexchange
 
 import (
+	"errors"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/service"
 	mockstorage "github.com/hyperledger/aries-framework-go/pkg/internal/mock/storage"
 	"github.com/hyperledger/aries-framework-go/pkg/storage"
 )
@@ -142,3 +145,439 @@ func TestConnectionRecorder_GetConnection(t *testing.T) {
 		require.Contains(t, err.Error(), "get error")
 	})
 }
+
+func TestConnectionRecorder_EncryptionAlgs(t *testing.T) {
+	t.Run("test save and get", func(t *testing.T) {
+		record := NewConnectionRecorder(&mockstorage.MockStore{Store: make(map[string][]byte)})
+
+		algs, err := record.GetEncryptionAlgs("conn1")
+		require.NoError(t, err)
+		require.Empty(t, algs)
+
+		require.NoError(t, record.SaveEncryptionAlgs("conn1", []string{"XC20P", "C20P"}))
+
+		algs, err = record.GetEncryptionAlgs("conn1")
+		require.NoError(t, err)
+		require.Equal(t, []string{"XC20P", "C20P"}, algs)
+	})
+
+	t.Run("test get error", func(t *testing.T) {
+		store := &mockstorage.MockStore{Store: make(map[string][]byte), ErrGet: fmt.Errorf("get error")}
+		record := NewConnectionRecorder(store)
+		require.NoError(t, record.SaveEncryptionAlgs("conn1", []string{"C20P"}))
+		_, err := record.GetEncryptionAlgs("conn1")
+		require.EqualError(t, err, "get error")
+	})
+
+	t.Run("test invalid record", func(t *testing.T) {
+		store := &mockstorage.MockStore{Store: make(map[string][]byte)}
+		record := NewConnectionRecorder(store)
+		require.NoError(t, store.Put(encryptionAlgsKey("conn1"), []byte("invalid")))
+		_, err := record.GetEncryptionAlgs("conn1")
+		require.Error(t, err)
+	})
+}
+
+func TestConnectionRecorder_UseInvitation(t *testing.T) {
+	now := time.Now()
+
+	t.Run("test invitation without usage restrictions", func(t *testing.T) {
+		record := NewConnectionRecorder(&mockstorage.MockStore{Store: make(map[string][]byte)})
+		require.NoError(t, record.UseInvitation("key1", now))
+		require.NoError(t, record.UseInvitation("key1", now))
+	})
+
+	t.Run("test expired invitation", func(t *testing.T) {
+		record := NewConnectionRecorder(&mockstorage.MockStore{Store: make(map[string][]byte)})
+		require.NoError(t, record.SaveInvitationUsage("key1", &InvitationUsage{ExpiresAt: now.Add(time.Minute)}))
+
+		require.NoError(t, record.UseInvitation("key1", now))
+		require.NoError(t, record.UseInvitation("key1", now))
+
+		err := record.UseInvitation("key1", now.Add(2*time.Minute))
+		require.True(t, errors.Is(err, ErrInvitationExpired))
+	})
+
+	t.Run("test single-use invitation", func(t *testing.T) {
+		record := NewConnectionRecorder(&mockstorage.MockStore{Store: make(map[string][]byte)})
+		require.NoError(t, record.SaveInvitationUsage("key1", &InvitationUsage{SingleUse: true}))
+
+		// the check doesn't use the invitation
+		require.NoError(t, record.CheckInvitation("key1", now))
+		require.NoError(t, record.CheckInvitation("key1", now))
+
+		require.NoError(t, record.UseInvitation("key1", now))
+		require.True(t, errors.Is(record.CheckInvitation("key1", now), ErrInvitationUsed))
+
+		err := record.UseInvitation("key1", now)
+		require.True(t, errors.Is(err, ErrInvitationUsed))
+	})
+
+	t.Run("test single-use invitation without conditional store", func(t *testing.T) {
+		mock := &mockstorage.MockStore{Store: make(map[string][]byte)}
+		record := NewConnectionRecorder(&mockStore{put: mock.Put, get: mock.Get})
+		require.NoError(t, record.SaveInvitationUsage("key1", &InvitationUsage{SingleUse: true}))
+
+		err := record.UseInvitation("key1", now)
+		require.True(t, errors.Is(err, ErrNotConditional))
+	})
+
+	t.Run("test single-use invitation used by another instance", func(t *testing.T) {
+		store := &mockstorage.MockStore{Store: make(map[string][]byte)}
+		require.NoError(t, NewConnectionRecorder(store).SaveInvitationUsage("key1",
+			&InvitationUsage{SingleUse: true, ExpiresAt: now.Add(time.Minute)}))
+
+		require.NoError(t, NewConnectionRecorder(store).UseInvitation("key1", now))
+
+		err := NewConnectionRecorder(store).UseInvitation("key1", now)
+		require.True(t, errors.Is(err, ErrInvitationUsed))
+	})
+
+	t.Run("test single-use invitation used concurrently", func(t *testing.T) {
+		store := &mockstorage.MockStore{Store: make(map[string][]byte)}
+		record := NewConnectionRecorder(&usingStore{MockStore: store, record: NewConnectionRecorder(store)})
+		require.NoError(t, record.SaveInvitationUsage("key1", &InvitationUsage{SingleUse: true}))
+
+		err := record.UseInvitation("key1", now)
+		require.True(t, errors.Is(err, ErrInvitationUsed))
+	})
+
+	t.Run("test used single-use invitation", func(t *testing.T) {
+		record := NewConnectionRecorder(&mockstorage.MockStore{Store: make(map[string][]byte)})
+		require.NoError(t, record.SaveInvitationUsage("key1", &InvitationUsage{SingleUse: true, Used: true}))
+
+		err := record.UseInvitation("key1", now)
+		require.True(t, errors.Is(err, ErrInvitationUsed))
+	})
+
+	t.Run("test get error", func(t *testing.T) {
+		store := &mockstorage.MockStore{Store: make(map[string][]byte), ErrGet: fmt.Errorf("get error")}
+		record := NewConnectionRecorder(store)
+		require.NoError(t, record.SaveInvitationUsage("key1", &InvitationUsage{SingleUse: true}))
+		require.EqualError(t, record.UseInvitation("key1", now), "get error")
+	})
+
+	t.Run("test invalid record", func(t *testing.T) {
+		store := &mockstorage.MockStore{Store: make(map[string][]byte)}
+		record := NewConnectionRecorder(store)
+
+		k, err := invitationUsageKey("key1")
+		require.NoError(t, err)
+		require.NoError(t, store.Put(k, []byte("invalid")))
+		require.Error(t, record.UseInvitation("key1", now))
+	})
+
+	t.Run("test invalid key", func(t *testing.T) {
+		record := NewConnectionRecorder(&mockstorage.MockStore{Store: make(map[string][]byte)})
+		require.Error(t, record.SaveInvitationUsage("", &InvitationUsage{}))
+		require.Error(t, record.UseInvitation("", now))
+	})
+}
+
+func TestConnectionRecorder_Destination(t *testing.T) {
+	t.Run("test save and get", func(t *testing.T) {
+		record := NewConnectionRecorder(&mockstorage.MockStore{Store: make(map[string][]byte)})
+
+		_, err := record.GetDestination("conn1")
+		require.True(t, errors.Is(err, storage.ErrDataNotFound))
+
+		destination := &service.Destination{RecipientKeys: []string{"key1"}, ServiceEndpoint: "url"}
+		require.NoError(t, record.SaveDestination("conn1", destination))
+
+		result, err := record.GetDestination("conn1")
+		require.NoError(t, err)
+		require.Equal(t, destination, result)
+	})
+
+	t.Run("test connection by key", func(t *testing.T) {
+		record := NewConnectionRecorder(&mockstorage.MockStore{Store: make(map[string][]byte)})
+
+		_, err := record.GetConnectionIDByKey("key1")
+		require.True(t, errors.Is(err, storage.ErrDataNotFound))
+
+		destination := &service.Destination{RecipientKeys: []string{"key1", "key2"}, ServiceEndpoint: "url"}
+		require.NoError(t, record.SaveDestination("conn1", destination))
+
+		connectionID, err := record.GetConnectionIDByKey("key2")
+		require.NoError(t, err)
+		require.Equal(t, "conn1", connectionID)
+	})
+
+	t.Run("test invalid record", func(t *testing.T) {
+		store := &mockstorage.MockStore{Store: make(map[string][]byte)}
+		record := NewConnectionRecorder(store)
+		require.NoError(t, store.Put(destinationKey("conn1"), []byte("invalid")))
+		_, err := record.GetDestination("conn1")
+		require.Error(t, err)
+	})
+}
+
+func TestConnectionRecorder_MyDID(t *testing.T) {
+	t.Run("test save and get", func(t *testing.T) {
+		store := &mockstorage.MockStore{Store: make(map[string][]byte)}
+		record := NewConnectionRecorder(store)
+
+		doc, err := record.GetMyDID("conn1")
+		require.NoError(t, err)
+		require.Nil(t, doc)
+
+		require.NoError(t, store.Put("conn1", []byte(stateNameCompleted)))
+		require.NoError(t, record.SaveMyDID("conn1", getMockDIDPublicKey()))
+
+		doc, err = record.GetMyDID("conn1")
+		require.NoError(t, err)
+		require.Equal(t, getMockDIDPublicKey().ID, doc.ID)
+		require.Equal(t, getMockDIDPublicKey().PublicKey[0].Value, doc.PublicKey[0].Value)
+
+		connection, err := record.GetConnection("conn1")
+		require.NoError(t, err)
+		require.Equal(t, getMockDIDPublicKey().ID, connection.MyDID)
+	})
+
+	t.Run("test invalid record", func(t *testing.T) {
+		store := &mockstorage.MockStore{Store: make(map[string][]byte)}
+		record := NewConnectionRecorder(store)
+		require.NoError(t, store.Put(myDIDKey("conn1"), []byte("invalid")))
+		_, err := record.GetMyDID("conn1")
+		require.Error(t, err)
+
+		require.NoError(t, store.Put("conn1", []byte(stateNameCompleted)))
+		_, err = record.GetConnection("conn1")
+		require.Error(t, err)
+	})
+}
+
+func TestConnectionRecorder_SaveNewConnection(t *testing.T) {
+	invitation := &Invitation{ID: "inv1", Label: "alice", RecipientKeys: []string{"key1"}}
+
+	t.Run("test save and get", func(t *testing.T) {
+		record := NewConnectionRecorder(&mockstorage.MockStore{Store: make(map[string][]byte)})
+
+		err := record.SaveNewConnection(&ConnectionRecord{
+			ConnectionID:   "conn1",
+			State:          stateNameInvited,
+			EncryptionAlgs: []string{"A256GCM"},
+		}, invitation, getMockDIDPublicKey())
+		require.NoError(t, err)
+
+		connection, err := record.GetConnection("conn1")
+		require.NoError(t, err)
+		require.Equal(t, stateNameInvited, connection.State)
+		require.Equal(t, getMockDIDPublicKey().ID, connection.MyDID)
+
+		algs, err := record.GetEncryptionAlgs("conn1")
+		require.NoError(t, err)
+		require.Equal(t, []string{"A256GCM"}, algs)
+
+		saved, err := record.GetInvitation("key1")
+		require.NoError(t, err)
+		require.Equal(t, invitation, saved)
+	})
+
+	t.Run("test without invitation and DID", func(t *testing.T) {
+		store := &mockstorage.MockStore{Store: make(map[string][]byte)}
+		record := NewConnectionRecorder(store)
+
+		require.NoError(t, record.SaveNewConnection(&ConnectionRecord{ConnectionID: "conn1"}, nil, nil))
+		require.Len(t, store.Store, 2)
+		require.Equal(t, "conn1", string(store.Store[connectionKey("conn1")]))
+	})
+
+	t.Run("test nothing saved on failure", func(t *testing.T) {
+		store := &mockstorage.MockStore{Store: make(map[string][]byte), ErrPut: errors.New("put error")}
+		record := NewConnectionRecorder(store)
+
+		err := record.SaveNewConnection(&ConnectionRecord{ConnectionID: "conn1"}, invitation, getMockDIDPublicKey())
+		require.EqualError(t, err, "put error")
+		require.Empty(t, store.Store)
+	})
+
+	t.Run("test invitation saved last by stores without batch", func(t *testing.T) {
+		store := &orderedStore{store: &mockstorage.MockStore{Store: make(map[string][]byte)}}
+		record := NewConnectionRecorder(store)
+
+		err := record.SaveNewConnection(&ConnectionRecord{ConnectionID: "conn1"}, invitation, getMockDIDPublicKey())
+		require.NoError(t, err)
+
+		k, err := invitationKey("key1")
+		require.NoError(t, err)
+		require.Equal(t, []string{"conn1", connectionKey("conn1"), myDIDKey("conn1"), k}, store.keys)
+
+		store.store.ErrPut = errors.New("put error")
+		err = record.SaveNewConnection(&ConnectionRecord{ConnectionID: "conn2"}, invitation, nil)
+		require.EqualError(t, err, "put error")
+	})
+
+	t.Run("test invalid records", func(t *testing.T) {
+		record := NewConnectionRecorder(&mockstorage.MockStore{Store: make(map[string][]byte)})
+
+		err := record.SaveNewConnection(&ConnectionRecord{}, invitation, nil)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "connection ID is mandatory")
+
+		err = record.SaveNewConnection(&ConnectionRecord{ConnectionID: "conn1"}, &Invitation{}, nil)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invitation has no recipient key")
+
+		err = record.SaveNewConnection(&ConnectionRecord{ConnectionID: "conn1"},
+			&Invitation{RecipientKeys: []string{""}}, nil)
+		require.Error(t, err)
+	})
+}
+
+func TestConnectionRecorder_ConnectionMetadata(t *testing.T) {
+	t.Run("test save, get and find", func(t *testing.T) {
+		record := NewConnectionRecorder(&mockstorage.MockStore{Store: make(map[string][]byte)})
+
+		err := record.SaveNewConnection(&ConnectionRecord{ConnectionID: "conn1", State: stateNameCompleted,
+			Tags: []string{"vip"}, Metadata: map[string]string{"department": "sales"}}, nil, nil)
+		require.NoError(t, err)
+
+		require.NoError(t, record.SaveConnectionMetadata("conn2", &ConnectionMetadata{Tags: []string{"vip", "new"},
+			Metadata: map[string]string{"department": "support", "customer-id": "42"}}))
+		require.NoError(t, record.SaveConnectionMetadata("conn3", &ConnectionMetadata{}))
+		require.NoError(t, record.SaveConnectionIndex("conn2"))
+		require.NoError(t, record.SaveConnectionIndex("conn3"))
+
+		connection, err := record.GetConnection("conn1")
+		require.NoError(t, err)
+		require.Equal(t, []string{"vip"}, connection.Tags)
+		require.Equal(t, map[string]string{"department": "sales"}, connection.Metadata)
+
+		metadata, err := record.GetConnectionMetadata("conn4")
+		require.NoError(t, err)
+		require.Empty(t, metadata.Tags)
+		require.Empty(t, metadata.Metadata)
+
+		ids, err := record.FindConnections([]string{"vip"}, nil)
+		require.NoError(t, err)
+		require.Equal(t, []string{"conn1", "conn2"}, ids)
+
+		ids, err = record.FindConnections([]string{"vip"}, map[string]string{"customer-id": "42"})
+		require.NoError(t, err)
+		require.Equal(t, []string{"conn2"}, ids)
+
+		ids, err = record.FindConnections(nil, map[string]string{"department": "marketing"})
+		require.NoError(t, err)
+		require.Empty(t, ids)
+
+		ids, err = record.FindConnections(nil, nil)
+		require.NoError(t, err)
+		require.Equal(t, []string{"conn1", "conn2", "conn3"}, ids)
+	})
+
+	t.Run("test store errors", func(t *testing.T) {
+		store := &mockstorage.MockStore{Store: make(map[string][]byte)}
+		record := NewConnectionRecorder(store)
+
+		store.Store[metadataKey("conn1")] = []byte("{")
+		_, err := record.GetConnectionMetadata("conn1")
+		require.Error(t, err)
+
+		_, err = record.FindConnections([]string{"vip"}, nil)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid metadata of connection conn1")
+
+		store.ErrGet = errors.New("get error")
+		_, err = record.GetConnectionMetadata("conn1")
+		require.EqualError(t, err, "get error")
+
+		store.ErrIterate = errors.New("iterate error")
+		_, err = record.FindConnections(nil, nil)
+		require.EqualError(t, err, "iterate error")
+
+		_, err = NewConnectionRecorder(&orderedStore{}).FindConnections(nil, nil)
+		require.True(t, errors.Is(err, ErrQueryNotSupported))
+	})
+}
+
+func TestConnectionRecorder_RemoveConnection(t *testing.T) {
+	t.Run("test remove", func(t *testing.T) {
+		store := &mockstorage.MockStore{Store: make(map[string][]byte)}
+		record := NewConnectionRecorder(store)
+
+		err := record.SaveNewConnection(&ConnectionRecord{ConnectionID: "conn1", State: stateNameCompleted,
+			Tags: []string{"vip"}, EncryptionAlgs: []string{"A256GCM"}}, nil, getMockDIDPublicKey())
+		require.NoError(t, err)
+		require.NoError(t, record.SaveTheirDID("conn1", "did:example:123"))
+		require.NoError(t, record.SaveDestination("conn1",
+			&service.Destination{RecipientKeys: []string{"key1", "key2"}, ServiceEndpoint: "url"}))
+
+		// key2 is now used by another connection
+		require.NoError(t, record.SaveDestination("conn2",
+			&service.Destination{RecipientKeys: []string{"key2"}, ServiceEndpoint: "url"}))
+
+		require.NoError(t, record.RemoveConnection("conn1"))
+
+		_, err = record.GetConnection("conn1")
+		require.True(t, errors.Is(err, storage.ErrDataNotFound))
+
+		ids, err := record.FindConnections(nil, nil)
+		require.NoError(t, err)
+		require.Empty(t, ids)
+
+		ids, err = record.FindConnections([]string{"vip"}, nil)
+		require.NoError(t, err)
+		require.Empty(t, ids)
+
+		_, err = record.GetConnectionIDByKey("key1")
+		require.True(t, errors.Is(err, storage.ErrDataNotFound))
+
+		connectionID, err := record.GetConnectionIDByKey("key2")
+		require.NoError(t, err)
+		require.Equal(t, "conn2", connectionID)
+
+		require.Len(t, store.Store, 2)
+
+		// removing again isn't an error
+		require.NoError(t, record.RemoveConnection("conn1"))
+	})
+
+	t.Run("test store errors", func(t *testing.T) {
+		store := &mockstorage.MockStore{Store: make(map[string][]byte)}
+		record := NewConnectionRecorder(store)
+
+		require.NoError(t, store.Put(destinationKey("conn1"), []byte("invalid")))
+		err := record.RemoveConnection("conn1")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to fetch destination")
+
+		require.NoError(t, store.Delete(destinationKey("conn1")))
+		store.ErrDelete = errors.New("delete error")
+		require.EqualError(t, record.RemoveConnection("conn1"), "delete error")
+	})
+}
+
+// orderedStore records the order of the puts, it isn't a storage.BatchStore
+type orderedStore struct {
+	store *mockstorage.MockStore
+	keys  []string
+}
+
+func (s *orderedStore) Put(k string, v []byte) error {
+	s.keys = append(s.keys, k)
+
+	return s.store.Put(k, v)
+}
+
+func (s *orderedStore) Get(k string) ([]byte, error) {
+	return s.store.Get(k)
+}
+
+func (s *orderedStore) Delete(k string) error {
+	return s.store.Delete(k)
+}
+
+// usingStore uses the invitation with another recorder before the conditional puts
+type usingStore struct {
+	*mockstorage.MockStore
+	record *ConnectionRecorder
+}
+
+func (s *usingStore) PutIf(k string, v, expected []byte) error {
+	if err := s.record.UseInvitation("key1", time.Now()); err != nil {
+		return err
+	}
+
+	return s.MockStore.PutIf(k, v, expected)
+}
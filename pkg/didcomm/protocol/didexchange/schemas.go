@@ -0,0 +1,65 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package didexchange
+
+// requestSchema is the JSON schema of the exchange request, the connection carries the DID and the DID document
+// of the invitee
+const requestSchema = `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "type": "object",
+  "required": ["@id", "connection"],
+  "properties": {
+    "@id": {"type": "string", "minLength": 1},
+    "label": {"type": "string"},
+    "connection": {
+      "type": "object",
+      "required": ["did", "did_doc"],
+      "properties": {
+        "did": {"type": "string", "minLength": 1},
+        "did_doc": {"type": "object"}
+      }
+    },
+    "~thread": {"type": "object"}
+  }
+}`
+
+// responseSchema is the JSON schema of the exchange response, the connection of the inviter is carried by the
+// connection~sig decorator and the response is threaded to the request. The signature isn't required as the
+// responses aren't signed yet.
+const responseSchema = `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "type": "object",
+  "required": ["@id", "connection~sig", "~thread"],
+  "properties": {
+    "@id": {"type": "string", "minLength": 1},
+    "connection~sig": {
+      "type": "object",
+      "required": ["sig_data", "signers"],
+      "properties": {
+        "@type": {"type": "string"},
+        "signature": {"type": "string", "minLength": 1},
+        "sig_data": {"type": "string", "minLength": 1},
+        "signers": {"type": "string", "minLength": 1}
+      }
+    },
+    "~thread": {
+      "type": "object",
+      "required": ["thid"],
+      "properties": {
+        "thid": {"type": "string", "minLength": 1}
+      }
+    }
+  }
+}`
+
+// messageSchemas maps the inbound message types validated before the state machine consumes them to their schemas
+func messageSchemas() map[string]string {
+	return map[string]string{
+		ConnectionRequest:  requestSchema,
+		ConnectionResponse: responseSchema,
+	}
+}
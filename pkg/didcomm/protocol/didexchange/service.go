@@ -7,23 +7,33 @@ SPDX-License-Identifier: Apache-2.0
 package didexchange
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"time"
 
-	"github.com/google/uuid"
-
+	"github.com/hyperledger/aries-framework-go/pkg/common/clock"
 	"github.com/hyperledger/aries-framework-go/pkg/common/did"
+	"github.com/hyperledger/aries-framework-go/pkg/common/idgen"
 	"github.com/hyperledger/aries-framework-go/pkg/common/log"
 	"github.com/hyperledger/aries-framework-go/pkg/common/metadata"
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/fsm"
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/model"
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/msgschema"
 	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/service"
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/threads"
 	"github.com/hyperledger/aries-framework-go/pkg/didcomm/dispatcher"
 	"github.com/hyperledger/aries-framework-go/pkg/didcomm/protocol/decorator"
 	"github.com/hyperledger/aries-framework-go/pkg/storage"
+	"github.com/hyperledger/aries-framework-go/pkg/wallet"
 )
 
 var logger = log.New("aries-framework/did-exchange/service")
 
+// ErrReporterMismatch is returned when a problem report isn't sent with a key of the counterparty of the thread
+var ErrReporterMismatch = errors.New("problem report not sent by the counterparty of the thread")
+
 // didCommChMessage type to correlate actionEvent message(go channel) with callback message(internal go channel).
 type didCommChMessage struct {
 	ID  string
@@ -33,8 +43,10 @@ type didCommChMessage struct {
 const (
 	// DIDExchange did exchange protocol
 	DIDExchange = "didexchange"
+	// DIDExchangeVersion is the version of the did-exchange protocol
+	DIDExchangeVersion = "1.0"
 	// DIDExchangeSpec defines the did-exchange spec
-	DIDExchangeSpec = metadata.AriesCommunityDID + ";spec/didexchange/1.0/"
+	DIDExchangeSpec = metadata.AriesCommunityDID + ";spec/didexchange/" + DIDExchangeVersion + "/"
 	// ConnectionInvite defines the did-exchange invite message type.
 	ConnectionInvite = DIDExchangeSpec + "invitation"
 	// ConnectionRequest defines the did-exchange request message type.
@@ -43,12 +55,21 @@ const (
 	ConnectionResponse = DIDExchangeSpec + "response"
 	// ConnectionAck defines the did-exchange ack message type.
 	ConnectionAck = DIDExchangeSpec + "ack"
+	// ConnectionProblemReport defines the did-exchange problem report message type.
+	ConnectionProblemReport = DIDExchangeSpec + "problem_report"
 	// DIDExchangeServiceType is the service type to be used in DID document
 	DIDExchangeServiceType = "did-communication"
+	// RoleInviter is the role of the agent creating the invitation and responding to the exchange request
+	RoleInviter = "inviter"
+	// RoleInvitee is the role of the agent receiving the invitation and sending the exchange request
+	RoleInvitee = "invitee"
 	// ConnectionID connection id is created to retriever connection record from db
 	ConnectionID = "connectionID"
 	// InvitationID invitation id is created in invitation request
 	InvitationID = "invitationID"
+
+	// requestNotAcceptedCode is the problem code of the exchange requests rejected for the invitation usage
+	requestNotAcceptedCode = "request_not_accepted"
 )
 
 // message type to store data for eventing. This is retrieved during callback.
@@ -64,23 +85,74 @@ type provider interface {
 	StorageProvider() storage.Provider
 }
 
+// threadStoreProvider is optionally implemented by the provider to share the thread store between the services
+type threadStoreProvider interface {
+	ThreadStore() *threads.Store
+}
+
+// connectionIDsProvider is optionally implemented by the provider to derive the IDs of the connections from the
+// DIDs of the parties
+type connectionIDsProvider interface {
+	DeterministicConnectionIDs() bool
+}
+
 type connectionStore interface {
 	GetConnection(connectionID string) (*ConnectionRecord, error)
+	SaveEncryptionAlgs(connectionID string, algs []string) error
+	GetEncryptionAlgs(connectionID string) ([]string, error)
+	CheckInvitation(verKey string, now time.Time) error
+	UseInvitation(verKey string, now time.Time) error
+	SaveDestination(connectionID string, destination *service.Destination) error
+	GetDestination(connectionID string) (*service.Destination, error)
+	GetConnectionIDByKey(theirVerKey string) (string, error)
+	SaveTheirDID(connectionID, theirDID string) error
+	GetTheirDID(connectionID string) (string, error)
+	SaveConnectionIndex(connectionID string) error
+	GetConnectionMetadata(connectionID string) (*ConnectionMetadata, error)
+	myDIDStore
+	completedConnectionStore
+}
+
+// clockProvider is optionally implemented by the provider to replace the system clock, e.g. in the tests
+type clockProvider interface {
+	Clock() clock.Clock
+}
+
+// idGeneratorProvider is optionally implemented by the provider to replace the random UUIDs of the messages and
+// connections
+type idGeneratorProvider interface {
+	IDGenerator() idgen.Generator
+}
+
+// cryptoWalletProvider is optionally implemented by the provider to check the keys of the DIDs set for the
+// connections are held by the wallet
+type cryptoWalletProvider interface {
+	CryptoWallet() wallet.Crypto
 }
 
 // Service for DID exchange protocol
 type Service struct {
-	service.Action
-	service.Message
-	ctx             context
+	fsm.Events
+	ctx             stateContext
 	store           storage.Store
+	machine         *fsm.Machine
 	callbackChannel chan didCommChMessage
 	connectionStore connectionStore
+	threads         *threads.Store
+	// deterministicIDs records the completed connections under the ID derived from the DIDs of the parties
+	deterministicIDs bool
+	// schemas validate the inbound messages before the state machine consumes them
+	schemas *msgschema.Registry
+	// keys checks the wallet holds the keys of the DIDs set for the connections, nil if the wallet can't tell
+	keys wallet.KeyHolder
 }
 
-type context struct {
+type stateContext struct {
 	outboundDispatcher dispatcher.Outbound
 	didCreator         did.Creator
+	myDIDs             myDIDStore
+	clock              clock.Clock
+	ids                idgen.Generator
 }
 
 // New return didexchange service
@@ -90,14 +162,55 @@ func New(didMaker did.Creator, prov provider) (*Service, error) {
 		return nil, err
 	}
 
+	svcClock := clock.System()
+	if p, ok := prov.(clockProvider); ok && p.Clock() != nil {
+		svcClock = p.Clock()
+	}
+
+	ids := idgen.UUID()
+	if p, ok := prov.(idGeneratorProvider); ok && p.IDGenerator() != nil {
+		ids = p.IDGenerator()
+	}
+
+	var threadStore *threads.Store
+	if p, ok := prov.(threadStoreProvider); ok && p.ThreadStore() != nil {
+		threadStore = p.ThreadStore()
+	} else {
+		threadStore, err = threads.New(prov.StorageProvider(), threads.WithClock(svcClock))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	connectionStore := NewConnectionRecorder(store)
+
+	schemas, err := msgschema.New(messageSchemas())
+	if err != nil {
+		return nil, err
+	}
+
+	var deterministicIDs bool
+	if p, ok := prov.(connectionIDsProvider); ok {
+		deterministicIDs = p.DeterministicConnectionIDs()
+	}
+
 	svc := &Service{
-		ctx: context{
+		Events: fsm.Events{ProtocolName: DIDExchange},
+		ctx: stateContext{
 			outboundDispatcher: prov.OutboundDispatcher(),
-			didCreator:         didMaker},
-		store: store,
+			didCreator:         didMaker,
+			myDIDs:             connectionStore,
+			clock:              svcClock,
+			ids:                ids},
+		store:   store,
+		machine: newStateMachine(store, fsm.WithIDGenerator(ids)),
 		// TODO channel size - https://github.com/hyperledger/aries-framework-go/issues/246
-		callbackChannel: make(chan didCommChMessage, 10),
-		connectionStore: NewConnectionRecorder(store),
+		callbackChannel:  make(chan didCommChMessage, 10),
+		connectionStore:  connectionStore,
+		threads:          threadStore,
+		deterministicIDs: deterministicIDs,
+		schemas:          schemas,
+		keys:             keyHolderOf(prov),
 	}
 
 	svc.startInternalListener()
@@ -106,55 +219,70 @@ func New(didMaker did.Creator, prov provider) (*Service, error) {
 }
 
 // Handle didexchange msg
-func (s *Service) Handle(msg *service.DIDCommMsg) error {
-	// throw error if there is no action event registered for inbound messages
-	aEvent := s.GetActionEvent()
+func (s *Service) Handle(ctx context.Context, msg *service.DIDCommMsg) error {
+	if !msg.Outbound && model.IsProblemReport(msg.Type) {
+		return s.handleProblemReport(msg)
+	}
 
-	logger.Infof("entered into Handle exchange message : %s", msg.Payload)
+	// throw error if there is no action event registered for inbound messages
+	if !msg.Outbound && s.GetActionEvent() == nil {
+		return fsm.ErrNoActionClient
+	}
 
-	if !msg.Outbound && aEvent == nil {
-		return errors.New("no clients are registered to handle the message")
+	if err := s.validateMessage(msg); err != nil {
+		return err
 	}
 
-	thid, err := threadID(msg)
+	thid, err := s.ctx.threadID(msg)
 	if err != nil {
 		return err
 	}
-	logger.Infof("thread id value for the did exchange msg : %s", thid)
 
-	current, err := s.currentState(thid)
+	// TODO change from thread id to connection id #397
+	ctx = log.NewContext(ctx, log.Fields{ConnectionID: thid, ThreadID: thid, MessageType: msg.Type})
+	msgLogger := logger.WithContext(ctx)
+
+	msgLogger.Infof("entered into Handle exchange message : %s", msg.Payload)
+
+	if !msg.Outbound {
+		if err = s.recordCounterparty(thid, msg); err != nil {
+			return err
+		}
+	}
+
+	next, err := stateFromMsgType(msg.Type)
 	if err != nil {
 		return err
 	}
-	logger.Infof("current state : %s", current.Name())
+	msgLogger.Infof("state will transition to -> %s if the msgType is processed", next.Name())
 
-	next, err := stateFromMsgType(msg.Type)
+	current, err := s.machine.Transition(thid, next)
 	if err != nil {
 		return err
 	}
-	logger.Infof("state will transition to -> %s if the msgType is processed", next.Name())
+	msgLogger.Infof("current state : %s", current.Name())
 
-	if !current.CanTransitionTo(next) {
-		return fmt.Errorf("invalid state transition: %s -> %s", current.Name(), next.Name())
+	if err = s.checkInvitation(msg, thid); err != nil {
+		return err
 	}
 
 	// trigger message events
 	// TODO change from thread id to connection id #397
 	// TODO pass invitation id #397
-	s.sendMsgEvents(&service.StateMsg{
+	s.SendMsgEvents(&service.StateMsg{
 		Type: service.PreState, Msg: msg, StateID: next.Name(), Properties: s.createEventProperties(thid, "")})
-	logger.Infof("sent pre event for state %s", next.Name())
+	msgLogger.Infof("sent pre event for state %s", next.Name())
 
 	// trigger action event based on message type for inbound messages
 	if !msg.Outbound && canTriggerActionEvents(msg.Type) {
-		err = s.sendActionEvent(msg, aEvent, thid, next)
+		err = s.sendActionEvent(msg, thid, next)
 		if err != nil {
 			return fmt.Errorf("send events failed: %w", err)
 		}
 		return nil
 	}
 	// if no action event is triggered, continue the execution
-	return s.handle(&message{Msg: msg, ThreadID: thid, NextStateName: next.Name()})
+	return s.handle(ctx, &message{Msg: msg, ThreadID: thid, NextStateName: next.Name()})
 }
 
 // Name return service name
@@ -162,63 +290,467 @@ func (s *Service) Name() string {
 	return DIDExchange
 }
 
+// MessageTypes returns the message types accepted by the service
+func (s *Service) MessageTypes() []string {
+	return []string{ConnectionInvite, ConnectionRequest, ConnectionResponse, ConnectionAck, ConnectionProblemReport}
+}
+
+// Version returns the version of the did-exchange protocol implemented by the service
+func (s *Service) Version() string {
+	return DIDExchangeVersion
+}
+
+// Roles returns the roles of the did-exchange protocol the service can play
+func (s *Service) Roles() []string {
+	return []string{RoleInviter, RoleInvitee}
+}
+
+// MessageRole returns the role played by the agent handling the inbound message type
+func (s *Service) MessageRole(msgType string) string {
+	switch msgType {
+	case ConnectionRequest, ConnectionAck:
+		return RoleInviter
+	case ConnectionInvite, ConnectionResponse:
+		return RoleInvitee
+	default:
+		return ""
+	}
+}
+
 // Accept msg checks the msg type
 func (s *Service) Accept(msgType string) bool {
-	return msgType == ConnectionInvite ||
-		msgType == ConnectionRequest ||
-		msgType == ConnectionResponse ||
-		msgType == ConnectionAck
+	return contains(s.MessageTypes(), msgType)
+}
+
+// RegisterMessageSchema adds or replaces the JSON schema the inbound messages of the type are validated against
+func (s *Service) RegisterMessageSchema(msgType, schema string) error {
+	return s.schemas.Register(msgType, schema)
 }
 
-func (s *Service) handle(msg *message) error {
-	logger.Infof("entered into private handle didcomm message: %s ", msg)
+// validateMessage rejects the inbound message not valid against the schema of its type with a problem report,
+// instead of failing later when the state machine unmarshals it
+func (s *Service) validateMessage(msg *service.DIDCommMsg) error {
+	if msg.Outbound || s.schemas == nil {
+		return nil
+	}
+
+	err := s.schemas.Validate(msg.Type, msg.Payload)
+	if err == nil {
+		return nil
+	}
+
+	// the problem report isn't threaded if the message doesn't identify its thread
+	thid, _ := s.ctx.threadID(msg)
+
+	logger.Warnf("rejected invalid message of thread %s: %s", thid, err)
+
+	return msgschema.ProblemReportError(ConnectionProblemReport, s.ctx.newID(), thid, err)
+}
+
+// requestInvitationKey returns the recipient key of the envelope of the inbound exchange request, i.e. the key of the
+// invitation the request responds to, empty for the other messages
+func requestInvitationKey(msg *service.DIDCommMsg) string {
+	if msg == nil || msg.Outbound || msg.Type != ConnectionRequest || msg.Metadata == nil {
+		return ""
+	}
+
+	return msg.Metadata.RecipientVerKey
+}
+
+// checkInvitation rejects the inbound exchange request responding to an expired or used invitation, the
+// single-use invitations are only marked as used once the request is accepted
+func (s *Service) checkInvitation(msg *service.DIDCommMsg, thid string) error {
+	verKey := requestInvitationKey(msg)
+	if verKey == "" {
+		return nil
+	}
+
+	err := s.connectionStore.CheckInvitation(verKey, s.ctx.now())
+	if !errors.Is(err, ErrInvitationExpired) && !errors.Is(err, ErrInvitationUsed) {
+		return err
+	}
+
+	logger.Warnf("rejected exchange request %s: %s", thid, err)
+
+	return &model.ProblemReportError{
+		Report: &model.ProblemReport{
+			Type: ConnectionProblemReport,
+			ID:   s.ctx.newID(),
+			Description: model.ProblemDescription{
+				Code: requestNotAcceptedCode,
+				Text: err.Error(),
+			},
+			Thread: &decorator.Thread{ID: thid},
+		},
+		Err: err,
+	}
+}
+
+// handleProblemReport marks the connection as failed and delivers the problem to the message event clients.
+// The inbound reports must be sent with a key of the counterparty if its keys are known.
+func (s *Service) handleProblemReport(msg *service.DIDCommMsg) error {
+	report := &model.ProblemReport{}
+
+	err := json.Unmarshal(msg.Payload, report)
+	if err != nil {
+		return fmt.Errorf("unmarshalling problem report failed: %w", err)
+	}
+
+	if report.Thread == nil || report.Thread.ID == "" {
+		return errors.New("problem report thread is not defined")
+	}
+
+	thid := report.Thread.ID
+
+	if _, err = s.connectionStore.GetConnection(thid); err != nil {
+		return fmt.Errorf("cannot fetch state of problem report thread %s: %w", thid, err)
+	}
+
+	if !msg.Outbound {
+		if err = s.verifyReporter(thid, msg.Metadata); err != nil {
+			return err
+		}
+	}
+
+	if err = s.update(thid, &failed{}); err != nil {
+		return fmt.Errorf("failed to persist state %s %w", stateNameFailed, err)
+	}
+
+	if err = s.threads.Complete(thid); err != nil && !errors.Is(err, storage.ErrDataNotFound) {
+		return fmt.Errorf("failed to complete thread %s %w", thid, err)
+	}
+
+	logger.Warnf("problem reported on connection %s: %s %s", thid, report.Description.Code, report.Explain())
+
+	// TODO change from thread id to connection id #397
+	s.SendMsgEvents(&service.StateMsg{
+		Type: service.PostState, Msg: msg, StateID: stateNameFailed,
+		Properties: &problemReportEvent{
+			didExchangeEvent: didExchangeEvent{connectionID: thid},
+			code:             report.Description.Code,
+			explain:          report.Explain(),
+			threadID:         thid,
+		}})
+
+	return nil
+}
+
+func (s *Service) handle(ctx context.Context, msg *message) error {
+	// TODO change from thread id to connection id #397
+	fields := log.Fields{ConnectionID: msg.ThreadID, ThreadID: msg.ThreadID}
+	if msg.Msg != nil {
+		fields.MessageType = msg.Msg.Type
+	}
+
+	ctx = log.NewContext(ctx, fields)
+	msgLogger := logger.WithContext(ctx)
+
+	msgLogger.Infof("entered into private handle didcomm message: %s ", msg)
 
 	next, err := stateFromName(msg.NextStateName)
 	if err != nil {
 		return fmt.Errorf("invalid state name: %w", err)
 	}
-	logger.Infof("next valid state to transition -> %s ", next.Name())
+	msgLogger.Infof("next valid state to transition -> %s ", next.Name())
+
+	stateCtx, err := s.connectionContext(msg.ThreadID)
+	if err != nil {
+		return err
+	}
 
 	for !isNoOp(next) {
 		// TODO change from thread id to connection id #397
 		// TODO pass invitation id #397
-		s.sendMsgEvents(&service.StateMsg{
+		s.SendMsgEvents(&service.StateMsg{
 			Type: service.PreState, Msg: msg.Msg, StateID: next.Name(),
 			Properties: s.createEventProperties(msg.ThreadID, "")})
-		logger.Infof("sent pre event for state %s", next.Name())
+		msgLogger.Infof("sent pre event for state %s", next.Name())
 
 		var action stateAction
 		var followup state
+		var connectionID string
 
-		followup, action, err = next.Execute(msg.Msg, msg.ThreadID, s.ctx)
+		followup, action, err = next.Execute(msg.Msg, msg.ThreadID, stateCtx)
 		if err != nil {
 			return fmt.Errorf("failed to execute state %s %w", next.Name(), err)
 		}
-		logger.Infof("finish execute next state: %s", next.Name())
+		msgLogger.Infof("finish execute next state: %s", next.Name())
 
 		if err = s.update(msg.ThreadID, next); err != nil {
 			return fmt.Errorf("failed to persist state %s %w", next.Name(), err)
 		}
-		logger.Infof("persisted the connection using %s and updated the state to %s",
+		msgLogger.Infof("persisted the connection using %s and updated the state to %s",
 			msg.ThreadID, next.Name())
 
-		if err := action(); err != nil {
+		connectionID, err = s.saveThread(msg, next)
+		if err != nil {
+			return fmt.Errorf("failed to save thread %s %w", msg.ThreadID, err)
+		}
+
+		if err := action(ctx); err != nil {
 			return fmt.Errorf("failed to execute state action %s %w", next.Name(), err)
 		}
-		logger.Infof("finish execute state action: %s", next.Name())
+		msgLogger.Infof("finish execute state action: %s", next.Name())
 
 		// TODO change from thread id to connection id #397
 		// TODO pass invitation id #397
-		s.sendMsgEvents(&service.StateMsg{
+		s.SendMsgEvents(&service.StateMsg{
 			Type: service.PostState, Msg: msg.Msg, StateID: next.Name(),
-			Properties: s.createEventProperties(msg.ThreadID, "")})
-		logger.Infof("sent post event for state %s", next.Name())
+			Properties: s.createEventProperties(connectionID, "")})
+		msgLogger.Infof("sent post event for state %s", next.Name())
 
 		next = followup
 	}
 	return nil
 }
 
+// saveThread records the thread of the connection in the thread store, along with the keys of the
+// counterparty, and returns the ID of the connection of the thread
+func (s *Service) saveThread(msg *message, current state) (string, error) {
+	connection, err := theirConnection(msg.Msg)
+	if err != nil {
+		return "", err
+	}
+
+	var keys []string
+
+	if connection != nil {
+		destination := prepareDestination(connection.DIDDoc)
+		keys = destination.RecipientKeys
+
+		// TODO change from thread id to connection id #397
+		if err = s.connectionStore.SaveDestination(msg.ThreadID, destination); err != nil {
+			return "", fmt.Errorf("failed to save destination: %w", err)
+		}
+
+		if err = s.connectionStore.SaveTheirDID(msg.ThreadID, theirDID(connection)); err != nil {
+			return "", fmt.Errorf("failed to save their DID: %w", err)
+		}
+	}
+
+	completed := current.Name() == stateNameCompleted
+	connectionID := msg.ThreadID
+
+	if completed && s.deterministicIDs {
+		if connectionID, err = s.recordConnection(msg.ThreadID); err != nil {
+			return "", err
+		}
+	}
+
+	return connectionID, s.threads.Save(&threads.Record{
+		ThreadID:       msg.ThreadID,
+		ParentThreadID: parentThreadID(msg.Msg),
+		ConnectionID:   connectionID,
+		Protocol:       DIDExchange,
+		Completed:      completed,
+		TheirVerKeys:   keys,
+	})
+}
+
+// theirDestination returns the destination of the counterparty DID document sent in the inbound request
+// or response, nil for the other messages
+func theirDestination(msg *service.DIDCommMsg) (*service.Destination, error) {
+	connection, err := theirConnection(msg)
+	if err != nil || connection == nil {
+		return nil, err
+	}
+
+	return prepareDestination(connection.DIDDoc), nil
+}
+
+// theirConnection returns the connection of the counterparty sent in the inbound request or response, nil for
+// the other messages or if the connection has no DID document
+func theirConnection(msg *service.DIDCommMsg) (*Connection, error) {
+	if msg.Outbound {
+		return nil, nil
+	}
+
+	var connection *Connection
+
+	switch msg.Type {
+	case ConnectionRequest:
+		request := &Request{}
+		if err := json.Unmarshal(msg.Payload, request); err != nil {
+			return nil, fmt.Errorf("unmarshalling request failed: %w", err)
+		}
+
+		connection = request.Connection
+	case ConnectionResponse:
+		response := &Response{}
+		if err := json.Unmarshal(msg.Payload, response); err != nil {
+			return nil, fmt.Errorf("unmarshalling response failed: %w", err)
+		}
+
+		if response.ConnectionSignature == nil {
+			return nil, nil
+		}
+
+		var err error
+
+		connection, err = connectionFromSignature(response.ConnectionSignature)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if connection == nil || connection.DIDDoc == nil {
+		return nil, nil
+	}
+
+	return connection, nil
+}
+
+// verifyReporter checks that the problem report was sent with a key of the counterparty of the thread, the
+// reports of the threads whose counterparty keys aren't known yet are accepted
+func (s *Service) verifyReporter(thid string, metadata *service.EnvelopeMetadata) error {
+	keys, err := s.theirVerKeys(thid)
+	if err != nil || len(keys) == 0 {
+		return err
+	}
+
+	if metadata != nil && contains(keys, metadata.SenderVerKey) {
+		return nil
+	}
+
+	return fmt.Errorf("problem report of thread %s: %w", thid, ErrReporterMismatch)
+}
+
+// theirVerKeys returns the keys of the counterparty of the thread, or of the connection of the thread, nil if
+// not known
+func (s *Service) theirVerKeys(thid string) ([]string, error) {
+	thread, err := s.threads.Get(thid)
+	if errors.Is(err, storage.ErrDataNotFound) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch thread %s: %w", thid, err)
+	}
+
+	if len(thread.TheirVerKeys) > 0 || thread.ConnectionID == "" || thread.ConnectionID == thid {
+		return thread.TheirVerKeys, nil
+	}
+
+	connection, err := s.threads.Get(thread.ConnectionID)
+	if errors.Is(err, storage.ErrDataNotFound) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch thread %s: %w", thread.ConnectionID, err)
+	}
+
+	return connection.TheirVerKeys, nil
+}
+
+// connectionOf returns the ID of the connection of the thread, the thread ID until the connection is recorded
+func (s *Service) connectionOf(thid string) (string, error) {
+	thread, err := s.threads.Get(thid)
+	if errors.Is(err, storage.ErrDataNotFound) {
+		return thid, nil
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch thread %s: %w", thid, err)
+	}
+
+	if thread.ConnectionID == "" {
+		return thid, nil
+	}
+
+	return thread.ConnectionID, nil
+}
+
+// theirDID returns the DID of the counterparty connection, the ID of its DID document if not set
+func theirDID(connection *Connection) string {
+	if connection.DID != "" {
+		return connection.DID
+	}
+
+	return connection.DIDDoc.ID
+}
+
+// recordCounterparty records the encryption algorithms used by the counterparty in the inbound message on the
+// connection of the thread, the payload codecs of the counterparty are advertised in its DID document
+func (s *Service) recordCounterparty(thid string, msg *service.DIDCommMsg) error {
+	if len(msg.EncryptionAlgs) == 0 {
+		return nil
+	}
+
+	connectionID, err := s.connectionOf(thid)
+	if err != nil {
+		return err
+	}
+
+	return s.recordEncryptionAlgs(connectionID, msg.EncryptionAlgs)
+}
+
+// recordEncryptionAlgs adds the encryption algorithms used by the counterparty to the algorithms
+// known to be supported on the connection
+func (s *Service) recordEncryptionAlgs(connectionID string, algs []string) error {
+	known, err := s.connectionStore.GetEncryptionAlgs(connectionID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch encryption algorithms: %w", err)
+	}
+
+	updated := known
+
+	for _, alg := range algs {
+		if !contains(updated, alg) {
+			updated = append(updated, alg)
+		}
+	}
+
+	if len(updated) == len(known) {
+		return nil
+	}
+
+	if err := s.connectionStore.SaveEncryptionAlgs(connectionID, updated); err != nil {
+		return fmt.Errorf("failed to save encryption algorithms: %w", err)
+	}
+
+	return nil
+}
+
+// connectionContext returns the state context of the connection of the thread, the outbound messages are packed
+// with the best encryption algorithm supported by the counterparty
+func (s *Service) connectionContext(thid string) (stateContext, error) {
+	connectionID, err := s.connectionOf(thid)
+	if err != nil {
+		return stateContext{}, err
+	}
+
+	algs, err := s.connectionStore.GetEncryptionAlgs(connectionID)
+	if err != nil {
+		return stateContext{}, fmt.Errorf("failed to fetch encryption algorithms: %w", err)
+	}
+
+	ctx := s.ctx
+	if len(algs) > 0 {
+		ctx.outboundDispatcher = &connectionOutbound{Outbound: s.ctx.outboundDispatcher, encryptionAlgs: algs}
+	}
+
+	return ctx, nil
+}
+
+// connectionOutbound sets the encryption algorithms supported by the counterparty on the destinations
+type connectionOutbound struct {
+	dispatcher.Outbound
+	encryptionAlgs []string
+}
+
+// Send msg
+func (o *connectionOutbound) Send(ctx context.Context, msg interface{}, senderVerKey string,
+	des *service.Destination) error {
+	if des != nil && len(des.EncryptionAlgs) == 0 {
+		d := *des
+		d.EncryptionAlgs = o.encryptionAlgs
+		des = &d
+	}
+
+	return o.Outbound.Send(ctx, msg, senderVerKey, des)
+}
+
 func (s *Service) createEventProperties(connectionID, invitationID string) *didExchangeEvent { //nolint: unparam
 	return &didExchangeEvent{connectionID: connectionID, invitationID: invitationID}
 }
@@ -239,32 +771,46 @@ func (ex *didExchangeEvent) InvitationID() string {
 	return ex.invitationID
 }
 
+// problemReportEvent implements didexchange.Event and service.ProblemReportEvent interfaces.
+type problemReportEvent struct {
+	didExchangeEvent
+	code     string
+	explain  string
+	threadID string
+}
+
+// Code returns the problem code.
+func (ev *problemReportEvent) Code() string {
+	return ev.code
+}
+
+// Explain returns the explanation of the problem.
+func (ev *problemReportEvent) Explain() string {
+	return ev.explain
+}
+
+// ThreadID returns the ID of the failed thread.
+func (ev *problemReportEvent) ThreadID() string {
+	return ev.threadID
+}
+
 // sendEvent triggers the action event. This function stores the state of current processing and passes a callback
 // function in the event message.
-func (s *Service) sendActionEvent(msg *service.DIDCommMsg, aEvent chan<- service.DIDCommAction,
-	threadID string, nextState state) error {
-	jsonDoc, err := json.Marshal(&message{
+func (s *Service) sendActionEvent(msg *service.DIDCommMsg, threadID string, nextState state) error {
+	// save the incoming message in the store (to retrieve later when callback events are fired)
+	id, err := s.machine.SaveAction(&message{
 		Msg:           msg,
 		ThreadID:      threadID,
 		NextStateName: nextState.Name(),
 	})
-
-	if err != nil {
-		return fmt.Errorf("JSON marshalling of document failed: %w", err)
-	}
-
-	// save the incoming message in the store (to retrieve later when callback events are fired)
-	id := generateRandomID()
-	err = s.store.Put(id, jsonDoc)
 	if err != nil {
-		return fmt.Errorf("JSON marshalling of document failed: %w", err)
+		return err
 	}
 	// create the message for the channel
 	// TODO change from thread id to connection id #397
 	// TODO pass invitation id #397
 	didCommAction := service.DIDCommAction{
-		ProtocolName: DIDExchange,
-		Message:      msg,
+		Message: msg,
 		Continue: func() {
 			s.processCallback(id, nil)
 		},
@@ -275,21 +821,7 @@ func (s *Service) sendActionEvent(msg *service.DIDCommMsg, aEvent chan<- service
 	}
 
 	// trigger the registered action event
-	aEvent <- didCommAction
-
-	return nil
-}
-
-// sendEvent triggers the message events.
-func (s *Service) sendMsgEvents(msg *service.StateMsg) {
-	msg.ProtocolName = DIDExchange
-
-	// trigger the message events
-	statusEvents := s.GetMsgEvents()
-
-	for _, handler := range statusEvents {
-		handler <- *msg
-	}
+	return s.SendActionEvent(didCommAction)
 }
 
 // startInternalListener listens to messages in gochannel for callback messages from clients.
@@ -320,34 +852,48 @@ func (s *Service) process(msg didCommChMessage) error {
 	}
 
 	// fetch the record
-	jsonDoc, err := s.store.Get(msg.ID)
+	document := &message{}
+
+	err := s.machine.LoadAction(msg.ID, document)
 	if err != nil {
-		return fmt.Errorf("document for the id doesn't exists in the database: %w", err)
+		return err
 	}
 
-	document := &message{}
-	err = json.Unmarshal(jsonDoc, document)
-	if err != nil {
-		return fmt.Errorf("JSON marshalling failed: %w", err)
+	// the exchange request is accepted, the invitation it responds to is used
+	if verKey := requestInvitationKey(document.Msg); verKey != "" {
+		if err = s.connectionStore.UseInvitation(verKey, s.ctx.now()); err != nil {
+			return fmt.Errorf("exchange request %s not accepted: %w", document.ThreadID, err)
+		}
 	}
 
-	// continue the processing
-	err = s.handle(document)
+	// continue the processing, the context of the message handled before the action event is not kept
+	err = s.handle(context.Background(), document)
 	if err != nil {
 		return fmt.Errorf("processing of the message failed: %w", err)
 	}
 	return nil
 }
 
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
 func isNoOp(s state) bool {
 	_, ok := s.(*noOp)
 	return ok
 }
 
-func threadID(didCommMsg *service.DIDCommMsg) (string, error) {
+// threadID returns the ID of the thread of the message, a new thread is started by the inbound invitations
+func (c *stateContext) threadID(didCommMsg *service.DIDCommMsg) (string, error) {
 	var thid string
 	if !didCommMsg.Outbound && didCommMsg.Type == ConnectionInvite {
-		return uuid.New().String(), nil
+		return c.newID(), nil
 	}
 	msg := struct {
 		ID     string           `json:"@id"`
@@ -364,27 +910,37 @@ func threadID(didCommMsg *service.DIDCommMsg) (string, error) {
 	return thid, nil
 }
 
-func (s *Service) currentState(thid string) (state, error) {
-	conn, err := s.connectionStore.GetConnection(thid)
-	if err != nil {
-		if errors.Is(err, storage.ErrDataNotFound) {
-			return &null{}, nil
-		}
-		return nil, fmt.Errorf("cannot fetch state from store: thid=%s err=%s", thid, err)
+// parentThreadID returns the parent thread ID (~thread.pthid) of the message, if any
+func parentThreadID(didCommMsg *service.DIDCommMsg) string {
+	msg := struct {
+		Thread decorator.Thread `json:"~thread,omitempty"`
+	}{}
+
+	if err := json.Unmarshal(didCommMsg.Payload, &msg); err != nil {
+		return ""
 	}
-	return stateFromName(conn.State)
+
+	return msg.Thread.PID
+}
+
+func (s *Service) currentState(thid string) (fsm.State, error) {
+	return s.machine.Current(thid)
 }
 
+// update checkpoints the state of the connection of the thread and indexes the connection
 func (s *Service) update(thid string, state state) error {
-	err := s.store.Put(thid, []byte(state.Name()))
-	if err != nil {
-		return fmt.Errorf("failed to write to store: %s", err)
+	if err := s.machine.Checkpoint(thid, state); err != nil {
+		return err
 	}
-	return nil
+
+	return s.connectionStore.SaveConnectionIndex(thid)
 }
 
-func generateRandomID() string {
-	return uuid.New().String()
+// newStateMachine returns the state machine checkpointing the connection states in the store
+func newStateMachine(store storage.Store, opts ...fsm.Opt) *fsm.Machine {
+	return fsm.New(store, &null{}, func(name string) (fsm.State, error) {
+		return stateFromName(name)
+	}, opts...)
 }
 
 // canTriggerActionEvents checks if the incoming message type matches either ConnectionRequest, ConnectionResponse or
@@ -7,8 +7,11 @@ SPDX-License-Identifier: Apache-2.0
 package didexchange
 
 import (
+	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -17,11 +20,15 @@ import (
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
 
+	"github.com/hyperledger/aries-framework-go/pkg/common/idgen"
 	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/model"
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/msgschema"
 	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/service"
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/threads"
 	"github.com/hyperledger/aries-framework-go/pkg/didcomm/dispatcher"
 	"github.com/hyperledger/aries-framework-go/pkg/didcomm/protocol/decorator"
 	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	mockclock "github.com/hyperledger/aries-framework-go/pkg/internal/mock/clock"
 	mockdid "github.com/hyperledger/aries-framework-go/pkg/internal/mock/common/did"
 	"github.com/hyperledger/aries-framework-go/pkg/internal/mock/didcomm/protocol"
 	mockstorage "github.com/hyperledger/aries-framework-go/pkg/internal/mock/storage"
@@ -45,7 +52,7 @@ func TestService_Name(t *testing.T) {
 // did-exchange flow with role Inviter
 func TestService_Handle_Inviter(t *testing.T) {
 	prov := protocol.MockProvider{}
-	ctx := context{outboundDispatcher: prov.OutboundDispatcher(), didCreator: &mockdid.MockDIDCreator{Doc: getMockDID()}}
+	ctx := stateContext{outboundDispatcher: prov.OutboundDispatcher(), didCreator: &mockdid.MockDIDCreator{Doc: getMockDID()}}
 	newDidDoc, err := ctx.didCreator.CreateDID()
 	require.NoError(t, err)
 
@@ -77,7 +84,7 @@ func TestService_Handle_Inviter(t *testing.T) {
 		})
 	require.NoError(t, err)
 	msg := service.DIDCommMsg{Type: ConnectionRequest, Payload: payloadBytes}
-	err = s.Handle(&msg)
+	err = s.Handle(context.Background(), &msg)
 	require.NoError(t, err)
 
 	select {
@@ -97,7 +104,7 @@ func TestService_Handle_Inviter(t *testing.T) {
 		})
 	require.NoError(t, err)
 	msg = service.DIDCommMsg{Type: ConnectionAck, Payload: payloadBytes}
-	err = s.Handle(&msg)
+	err = s.Handle(context.Background(), &msg)
 	require.NoError(t, err)
 
 	select {
@@ -165,11 +172,15 @@ func TestService_Handle_Invitee(t *testing.T) {
 		},
 	}
 	prov := protocol.MockProvider{}
-	ctx := context{outboundDispatcher: prov.OutboundDispatcher(), didCreator: &mockdid.MockDIDCreator{Doc: getMockDID()}}
+	ctx := stateContext{outboundDispatcher: prov.OutboundDispatcher(), didCreator: &mockdid.MockDIDCreator{Doc: getMockDID()}}
 	newDidDoc, err := ctx.didCreator.CreateDID()
 	require.NoError(t, err)
 
-	s, err := New(&mockdid.MockDIDCreator{Doc: getMockDID()}, &protocol.MockProvider{CustomStore: store})
+	threadStore, err := threads.New(mockstorage.NewMockStoreProvider())
+	require.NoError(t, err)
+
+	s, err := New(&mockdid.MockDIDCreator{Doc: getMockDID()},
+		&protocol.MockProvider{CustomStore: store, ThreadStoreValue: threadStore})
 	require.NoError(t, err)
 	actionCh := make(chan service.DIDCommAction, 10)
 	err = s.RegisterActionEvent(actionCh)
@@ -201,13 +212,16 @@ func TestService_Handle_Invitee(t *testing.T) {
 	)
 	require.NoError(t, err)
 	msg := service.DIDCommMsg{Type: ConnectionInvite, Outbound: false, Payload: payloadBytes}
-	err = s.Handle(&msg)
+	err = s.Handle(context.Background(), &msg)
 	require.NoError(t, err)
 
 	// Alice automatically sends a Request to Bob and is now in REQUESTED state.
 	var thid string
 	var currState string
 	for k, v := range data {
+		if strings.HasPrefix(k, myDIDKeyPrefix) || strings.HasPrefix(k, connKeyPrefix) {
+			continue
+		}
 		thid = k
 		currState = v
 		break
@@ -220,7 +234,7 @@ func TestService_Handle_Invitee(t *testing.T) {
 		DIDDoc: newDidDoc,
 	}
 
-	connectionSignature, err := prepareConnectionSignature(connection)
+	connectionSignature, err := prepareConnectionSignature(connection, time.Now())
 	require.NoError(t, err)
 
 	// Bob replies with a Response
@@ -234,7 +248,7 @@ func TestService_Handle_Invitee(t *testing.T) {
 	)
 	require.NoError(t, err)
 	msg = service.DIDCommMsg{Type: ConnectionResponse, Outbound: false, Payload: payloadBytes}
-	err = s.Handle(&msg)
+	err = s.Handle(context.Background(), &msg)
 	require.NoError(t, err)
 
 	// Alice automatically sends an ACK to Bob
@@ -245,11 +259,18 @@ func TestService_Handle_Invitee(t *testing.T) {
 		require.Fail(t, "didn't receive post event complete")
 	}
 	validateState(t, s, thid, (&completed{}).Name())
+
+	thread, err := threadStore.Get(thid)
+	require.NoError(t, err)
+	require.Equal(t, DIDExchange, thread.Protocol)
+	require.Equal(t, thid, thread.ConnectionID)
+	require.True(t, thread.Completed)
+	require.Len(t, thread.TheirVerKeys, len(newDidDoc.PublicKey))
 }
 
 func TestService_Handle_EdgeCases(t *testing.T) {
 	t.Run("must not start with Response msg", func(t *testing.T) {
-		ctx := context{outboundDispatcher: newMockOutboundDispatcher(),
+		ctx := stateContext{outboundDispatcher: newMockOutboundDispatcher(),
 			didCreator: &mockdid.MockDIDCreator{Doc: getMockDID()}}
 		mockStore, err := mockstorage.NewMockStoreProvider().OpenStore(DIDExchange)
 		require.NoError(t, err)
@@ -261,11 +282,11 @@ func TestService_Handle_EdgeCases(t *testing.T) {
 			},
 		)
 		require.NoError(t, err)
-		err = s.Handle(&service.DIDCommMsg{Type: ConnectionResponse, Payload: response})
+		err = s.Handle(context.Background(), &service.DIDCommMsg{Type: ConnectionResponse, Payload: response})
 		require.Error(t, err)
 	})
 	t.Run("must not start with ACK msg", func(t *testing.T) {
-		ctx := context{outboundDispatcher: newMockOutboundDispatcher(),
+		ctx := stateContext{outboundDispatcher: newMockOutboundDispatcher(),
 			didCreator: &mockdid.MockDIDCreator{Doc: getMockDID()}}
 		mockStore, err := mockstorage.NewMockStoreProvider().OpenStore(DIDExchange)
 		require.NoError(t, err)
@@ -277,12 +298,12 @@ func TestService_Handle_EdgeCases(t *testing.T) {
 			},
 		)
 		require.NoError(t, err)
-		err = s.Handle(&service.DIDCommMsg{Type: ConnectionAck, Payload: ack})
+		err = s.Handle(context.Background(), &service.DIDCommMsg{Type: ConnectionAck, Payload: ack})
 		require.Error(t, err)
 	})
 	t.Run("must not transition to same state", func(t *testing.T) {
 		prov := protocol.MockProvider{}
-		ctx := context{outboundDispatcher: prov.OutboundDispatcher(),
+		ctx := stateContext{outboundDispatcher: prov.OutboundDispatcher(),
 			didCreator: &mockdid.MockDIDCreator{Doc: getMockDID()}}
 		newDidDoc, err := ctx.didCreator.CreateDID()
 		require.NoError(t, err)
@@ -321,7 +342,7 @@ func TestService_Handle_EdgeCases(t *testing.T) {
 			},
 		)
 		require.NoError(t, err)
-		err = s.Handle(&service.DIDCommMsg{Type: ConnectionRequest, Outbound: false, Payload: request})
+		err = s.Handle(context.Background(), &service.DIDCommMsg{Type: ConnectionRequest, Outbound: false, Payload: request})
 		require.NoError(t, err)
 
 		select {
@@ -340,11 +361,11 @@ func TestService_Handle_EdgeCases(t *testing.T) {
 			},
 		)
 		require.NoError(t, err)
-		err = s.Handle(&service.DIDCommMsg{Type: ConnectionResponse, Outbound: false, Payload: response})
+		err = s.Handle(context.Background(), &service.DIDCommMsg{Type: ConnectionResponse, Outbound: false, Payload: response})
 		require.Error(t, err)
 	})
 	t.Run("error when updating store on first state transition", func(t *testing.T) {
-		ctx := context{outboundDispatcher: newMockOutboundDispatcher(),
+		ctx := stateContext{outboundDispatcher: newMockOutboundDispatcher(),
 			didCreator: &mockdid.MockDIDCreator{Doc: getMockDID()}}
 		s := &Service{
 			ctx: ctx,
@@ -365,12 +386,12 @@ func TestService_Handle_EdgeCases(t *testing.T) {
 			},
 		)
 		require.NoError(t, err)
-		err = s.Handle(&service.DIDCommMsg{Type: ConnectionRequest, Outbound: false, Payload: request})
+		err = s.Handle(context.Background(), &service.DIDCommMsg{Type: ConnectionRequest, Outbound: false, Payload: request})
 		require.Error(t, err)
 	})
 	t.Run("error when updating store on followup state transition", func(t *testing.T) {
 		counter := 0
-		ctx := context{outboundDispatcher: newMockOutboundDispatcher(),
+		ctx := stateContext{outboundDispatcher: newMockOutboundDispatcher(),
 			didCreator: &mockdid.MockDIDCreator{Doc: getMockDID()}}
 		s := &Service{
 			ctx: ctx,
@@ -395,12 +416,12 @@ func TestService_Handle_EdgeCases(t *testing.T) {
 			},
 		)
 		require.NoError(t, err)
-		err = s.Handle(&service.DIDCommMsg{Type: ConnectionRequest, Outbound: false, Payload: request})
+		err = s.Handle(context.Background(), &service.DIDCommMsg{Type: ConnectionRequest, Outbound: false, Payload: request})
 		require.Error(t, err)
 	})
 
 	t.Run("error on invalid msg type", func(t *testing.T) {
-		ctx := context{outboundDispatcher: newMockOutboundDispatcher(),
+		ctx := stateContext{outboundDispatcher: newMockOutboundDispatcher(),
 			didCreator: &mockdid.MockDIDCreator{Doc: getMockDID()}}
 		mockStore, err := mockstorage.NewMockStoreProvider().OpenStore(DIDExchange)
 		require.NoError(t, err)
@@ -413,7 +434,7 @@ func TestService_Handle_EdgeCases(t *testing.T) {
 			},
 		)
 		require.NoError(t, err)
-		err = s.Handle(&service.DIDCommMsg{Type: "INVALID", Outbound: false, Payload: request})
+		err = s.Handle(context.Background(), &service.DIDCommMsg{Type: "INVALID", Outbound: false, Payload: request})
 		require.Error(t, err)
 	})
 }
@@ -422,7 +443,7 @@ func TestService_Accept(t *testing.T) {
 	dbstore, err := mockstorage.NewMockStoreProvider().OpenStore(DIDExchange)
 	require.NoError(t, err)
 
-	ctx := context{outboundDispatcher: newMockOutboundDispatcher(), didCreator: &mockdid.MockDIDCreator{Doc: getMockDID()}}
+	ctx := stateContext{outboundDispatcher: newMockOutboundDispatcher(), didCreator: &mockdid.MockDIDCreator{Doc: getMockDID()}}
 	s := &Service{ctx: ctx, store: dbstore}
 
 	resp := s.Accept("did:sov:BzCbsNYhMrjHiqZDTUASHg;spec/didexchange/1.0/invitation")
@@ -437,33 +458,113 @@ func TestService_Accept(t *testing.T) {
 	resp = s.Accept("did:sov:BzCbsNYhMrjHiqZDTUASHg;spec/didexchange/1.0/ack")
 	require.Equal(t, true, resp)
 
+	resp = s.Accept("did:sov:BzCbsNYhMrjHiqZDTUASHg;spec/didexchange/1.0/problem_report")
+	require.Equal(t, true, resp)
+
 	resp = s.Accept("unsupported msg type")
 	require.Equal(t, false, resp)
+
+	for _, msgType := range s.MessageTypes() {
+		require.True(t, s.Accept(msgType))
+	}
+
+	require.Equal(t, "1.0", s.Version())
+	require.Equal(t, []string{RoleInviter, RoleInvitee}, s.Roles())
+
+	require.Equal(t, RoleInviter, s.MessageRole(ConnectionRequest))
+	require.Equal(t, RoleInviter, s.MessageRole(ConnectionAck))
+	require.Equal(t, RoleInvitee, s.MessageRole(ConnectionInvite))
+	require.Equal(t, RoleInvitee, s.MessageRole(ConnectionResponse))
+	require.Empty(t, s.MessageRole(ConnectionProblemReport))
 }
 
 func TestService_threadID(t *testing.T) {
+	ctx := &stateContext{}
+
 	t.Run("returns new thid for ", func(t *testing.T) {
-		thid, err := threadID(&service.DIDCommMsg{Type: ConnectionInvite, Outbound: false})
+		thid, err := ctx.threadID(&service.DIDCommMsg{Type: ConnectionInvite, Outbound: false})
 		require.NoError(t, err)
 		require.NotNil(t, thid)
 	})
+	t.Run("returns thid of the ID generator for invitations", func(t *testing.T) {
+		thid, err := (&stateContext{ids: idgen.Sequence("thread")}).threadID(
+			&service.DIDCommMsg{Type: ConnectionInvite, Outbound: false})
+		require.NoError(t, err)
+		require.Equal(t, "thread-1", thid)
+	})
 	t.Run("returns unmarshall error", func(t *testing.T) {
-		thid, err := threadID(&service.DIDCommMsg{Type: ConnectionRequest, Outbound: true})
+		thid, err := ctx.threadID(&service.DIDCommMsg{Type: ConnectionRequest, Outbound: true})
 		require.Error(t, err)
 		require.Equal(t, "", thid)
 	})
 	msg := []byte(`{"~thread": {"thid": "xyz"}}`)
 	t.Run("returns unmarshall error", func(t *testing.T) {
-		thid, err := threadID(&service.DIDCommMsg{Type: ConnectionRequest, Outbound: true, Payload: msg})
+		thid, err := ctx.threadID(&service.DIDCommMsg{Type: ConnectionRequest, Outbound: true, Payload: msg})
 		require.NoError(t, err)
 		require.Equal(t, "xyz", thid)
 	})
 }
 
+func TestService_theirDestination(t *testing.T) {
+	t.Run("test destination of the request", func(t *testing.T) {
+		payload, err := json.Marshal(&Request{Type: ConnectionRequest, ID: randomString(),
+			Connection: &Connection{DID: "did:example:123", DIDDoc: getMockDIDPublicKey()}})
+		require.NoError(t, err)
+
+		destination, err := theirDestination(&service.DIDCommMsg{Type: ConnectionRequest, Payload: payload})
+		require.NoError(t, err)
+		require.Equal(t, prepareDestination(getMockDIDPublicKey()), destination)
+
+		destination, err = theirDestination(&service.DIDCommMsg{Type: ConnectionRequest, Payload: payload, Outbound: true})
+		require.NoError(t, err)
+		require.Nil(t, destination)
+	})
+
+	t.Run("test messages without destination", func(t *testing.T) {
+		payload, err := json.Marshal(&Response{Type: ConnectionResponse, ID: randomString()})
+		require.NoError(t, err)
+
+		destination, err := theirDestination(&service.DIDCommMsg{Type: ConnectionResponse, Payload: payload})
+		require.NoError(t, err)
+		require.Nil(t, destination)
+
+		destination, err = theirDestination(&service.DIDCommMsg{Type: ConnectionAck, Payload: []byte("{}")})
+		require.NoError(t, err)
+		require.Nil(t, destination)
+	})
+
+	t.Run("test invalid messages", func(t *testing.T) {
+		_, err := theirDestination(&service.DIDCommMsg{Type: ConnectionRequest, Payload: []byte("invalid")})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unmarshalling request failed")
+
+		_, err = theirDestination(&service.DIDCommMsg{Type: ConnectionResponse, Payload: []byte("invalid")})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unmarshalling response failed")
+
+		payload, err := json.Marshal(&Response{Type: ConnectionResponse, ID: randomString(),
+			ConnectionSignature: &ConnectionSignature{SignedData: "!invalid"}})
+		require.NoError(t, err)
+
+		_, err = theirDestination(&service.DIDCommMsg{Type: ConnectionResponse, Payload: payload})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "decode string failed")
+
+		// the signed data without connection
+		payload, err = json.Marshal(&Response{Type: ConnectionResponse, ID: randomString(),
+			ConnectionSignature: &ConnectionSignature{SignedData: base64.URLEncoding.EncodeToString([]byte("1"))}})
+		require.NoError(t, err)
+
+		_, err = theirDestination(&service.DIDCommMsg{Type: ConnectionResponse, Payload: payload})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unmarshalling failed")
+	})
+}
+
 func TestService_currentState(t *testing.T) {
 	t.Run("null state if not found in store", func(t *testing.T) {
 		svc := &Service{
-			connectionStore: NewConnectionRecorder(&mockStore{
+			machine: newStateMachine(&mockStore{
 				get: func(string) ([]byte, error) { return nil, storage.ErrDataNotFound },
 			}),
 		}
@@ -474,7 +575,7 @@ func TestService_currentState(t *testing.T) {
 	t.Run("returns state from store", func(t *testing.T) {
 		expected := &requested{}
 		svc := &Service{
-			connectionStore: NewConnectionRecorder(&mockStore{
+			machine: newStateMachine(&mockStore{
 				get: func(string) ([]byte, error) { return []byte(expected.Name()), nil },
 			}),
 		}
@@ -484,7 +585,7 @@ func TestService_currentState(t *testing.T) {
 	})
 	t.Run("forwards generic error from store", func(t *testing.T) {
 		svc := &Service{
-			connectionStore: NewConnectionRecorder(&mockStore{
+			machine: newStateMachine(&mockStore{
 				get: func(string) ([]byte, error) {
 					return nil, errors.New("test")
 				},
@@ -505,8 +606,436 @@ func TestService_update(t *testing.T) {
 			return nil
 		},
 	}
-	require.NoError(t, (&Service{store: store}).update("123", s))
+	svc := &Service{machine: newStateMachine(store), connectionStore: NewConnectionRecorder(store)}
+	require.NoError(t, svc.update(thid, s))
 	require.Equal(t, s.Name(), string(data[thid]))
+	require.Equal(t, thid, string(data[connectionKey(thid)]))
+}
+
+func TestService_ProblemReport(t *testing.T) {
+	threadStore, err := threads.New(mockstorage.NewMockStoreProvider())
+	require.NoError(t, err)
+
+	svc, err := New(&mockdid.MockDIDCreator{Doc: getMockDID()},
+		&protocol.MockProvider{ThreadStoreValue: threadStore})
+	require.NoError(t, err)
+
+	statusCh := make(chan service.StateMsg, 10)
+	require.NoError(t, svc.RegisterMsgEvent(statusCh))
+
+	report := func(msgType, thid string) *service.DIDCommMsg {
+		payload, e := json.Marshal(&model.ProblemReport{
+			Type:        msgType,
+			ID:          randomString(),
+			Description: model.ProblemDescription{Code: "request_not_accepted", Text: "request rejected"},
+			Thread:      &decorator.Thread{ID: thid},
+		})
+		require.NoError(t, e)
+
+		return &service.DIDCommMsg{Type: msgType, Payload: payload}
+	}
+
+	t.Run("test problem report marks the connection as failed", func(t *testing.T) {
+		for _, msgType := range []string{ConnectionProblemReport, model.ProblemReportMsgType} {
+			thid := randomString()
+			require.NoError(t, svc.update(thid, &requested{}))
+			require.NoError(t, threadStore.Save(&threads.Record{ThreadID: thid, Protocol: DIDExchange}))
+
+			require.NoError(t, svc.Handle(context.Background(), report(msgType, thid)))
+			validateState(t, svc, thid, stateNameFailed)
+
+			thread, e := threadStore.Get(thid)
+			require.NoError(t, e)
+			require.True(t, thread.Completed)
+
+			select {
+			case msg := <-statusCh:
+				require.Equal(t, stateNameFailed, msg.StateID)
+				require.Equal(t, service.PostState, msg.Type)
+
+				event, ok := msg.Properties.(service.ProblemReportEvent)
+				require.True(t, ok)
+				require.Equal(t, "request_not_accepted", event.Code())
+				require.Equal(t, "request rejected", event.Explain())
+				require.Equal(t, thid, event.ThreadID())
+
+				connEvent, ok := msg.Properties.(Event)
+				require.True(t, ok)
+				require.Equal(t, thid, connEvent.ConnectionID())
+			case <-time.After(time.Second):
+				require.Fail(t, "didn't receive problem report event")
+			}
+		}
+	})
+
+	t.Run("test failed state is final", func(t *testing.T) {
+		s := &failed{}
+		require.False(t, s.CanTransitionTo(&null{}))
+		_, _, err := s.Execute(&service.DIDCommMsg{}, "", stateContext{})
+		require.Error(t, err)
+	})
+
+	t.Run("test problem report errors", func(t *testing.T) {
+		err := svc.Handle(context.Background(), &service.DIDCommMsg{Type: ConnectionProblemReport, Payload: []byte("invalid")})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unmarshalling problem report failed")
+
+		err = svc.Handle(context.Background(), &service.DIDCommMsg{Type: ConnectionProblemReport, Payload: []byte("{}")})
+		require.EqualError(t, err, "problem report thread is not defined")
+
+		err = svc.Handle(context.Background(), report(ConnectionProblemReport, "unknown"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "cannot fetch state of problem report thread unknown")
+	})
+
+	t.Run("test problem report sent by the counterparty", func(t *testing.T) {
+		thid := randomString()
+		require.NoError(t, svc.update(thid, &responded{}))
+		require.NoError(t, threadStore.Save(&threads.Record{ThreadID: thid, Protocol: DIDExchange,
+			TheirVerKeys: []string{"theirKey"}}))
+
+		msg := report(ConnectionProblemReport, thid)
+		msg.Metadata = &service.EnvelopeMetadata{SenderVerKey: "otherKey"}
+
+		err := svc.Handle(context.Background(), msg)
+		require.True(t, errors.Is(err, ErrReporterMismatch))
+		validateState(t, svc, thid, stateNameResponded)
+
+		msg.Metadata = nil
+		require.True(t, errors.Is(svc.Handle(context.Background(), msg), ErrReporterMismatch))
+
+		msg.Metadata = &service.EnvelopeMetadata{SenderVerKey: "theirKey"}
+		require.NoError(t, svc.Handle(context.Background(), msg))
+		validateState(t, svc, thid, stateNameFailed)
+		<-statusCh
+
+		// the keys of the connection of the thread
+		connectionThread := randomString()
+		require.NoError(t, svc.update(connectionThread, &requested{}))
+		require.NoError(t, threadStore.Save(&threads.Record{ThreadID: connectionThread, Protocol: DIDExchange,
+			ConnectionID: thid}))
+
+		msg = report(ConnectionProblemReport, connectionThread)
+		msg.Metadata = &service.EnvelopeMetadata{SenderVerKey: "otherKey"}
+		require.True(t, errors.Is(svc.Handle(context.Background(), msg), ErrReporterMismatch))
+	})
+}
+
+func TestService_Threads(t *testing.T) {
+	t.Run("test thread store of the storage provider", func(t *testing.T) {
+		svc, err := New(&mockdid.MockDIDCreator{Doc: getMockDID()}, &storageOnlyProvider{})
+		require.NoError(t, err)
+		require.NotNil(t, svc.threads)
+	})
+
+	t.Run("test parent thread ID", func(t *testing.T) {
+		require.Equal(t, "pthid", parentThreadID(&service.DIDCommMsg{
+			Payload: []byte(`{"~thread": {"thid": "thid", "pthid": "pthid"}}`)}))
+		require.Empty(t, parentThreadID(&service.DIDCommMsg{Payload: []byte(`{"@id": "id"}`)}))
+		require.Empty(t, parentThreadID(&service.DIDCommMsg{Payload: []byte("invalid")}))
+	})
+}
+
+// storageOnlyProvider doesn't provide a shared thread store
+type storageOnlyProvider struct{}
+
+func (p *storageOnlyProvider) OutboundDispatcher() dispatcher.Outbound {
+	return newMockOutboundDispatcher()
+}
+
+func (p *storageOnlyProvider) StorageProvider() storage.Provider {
+	return mockstorage.NewMockStoreProvider()
+}
+
+func TestService_Context(t *testing.T) {
+	type ctxKey struct{}
+
+	svc, err := New(&mockdid.MockDIDCreator{Doc: getMockDID()}, &protocol.MockProvider{})
+	require.NoError(t, err)
+
+	require.NoError(t, svc.RegisterActionEvent(make(chan service.DIDCommAction)))
+
+	outbound := &recordingOutbound{}
+	svc.ctx.outboundDispatcher = outbound
+
+	invitation, err := json.Marshal(&Invitation{
+		Type:            ConnectionInvite,
+		ID:              randomString(),
+		Label:           "Bob",
+		RecipientKeys:   []string{"8HH5gYEeNc3z7PYXmd54d4x6qAfCNrqQqEB3nS7Zfu7K"},
+		ServiceEndpoint: "https://localhost:8090",
+	})
+	require.NoError(t, err)
+
+	ctx := context.WithValue(context.Background(), ctxKey{}, "value")
+
+	require.NoError(t, svc.Handle(ctx, &service.DIDCommMsg{Type: ConnectionInvite, Payload: invitation}))
+	require.NotNil(t, outbound.ctx)
+	require.Equal(t, "value", outbound.ctx.Value(ctxKey{}))
+}
+
+func TestService_EncryptionAlgs(t *testing.T) {
+	t.Run("test record and use encryption algorithms of the counterparty", func(t *testing.T) {
+		threadStore, err := threads.New(mockstorage.NewMockStoreProvider())
+		require.NoError(t, err)
+
+		store := &mockstorage.MockStore{Store: make(map[string][]byte)}
+		outbound := &recordingOutbound{}
+		svc := &Service{ctx: stateContext{outboundDispatcher: outbound}, store: store,
+			connectionStore: NewConnectionRecorder(store), threads: threadStore}
+
+		require.NoError(t, svc.recordEncryptionAlgs("conn1", []string{"C20P"}))
+		require.NoError(t, svc.recordEncryptionAlgs("conn1", []string{"C20P", "XC20P"}))
+
+		algs, err := svc.connectionStore.GetEncryptionAlgs("conn1")
+		require.NoError(t, err)
+		require.Equal(t, []string{"C20P", "XC20P"}, algs)
+
+		ctx, err := svc.connectionContext("conn1")
+		require.NoError(t, err)
+
+		destination := &service.Destination{ServiceEndpoint: "url"}
+		require.NoError(t, ctx.outboundDispatcher.Send(context.Background(), "data", "key", destination))
+		require.Equal(t, []string{"C20P", "XC20P"}, outbound.destination.EncryptionAlgs)
+		require.Empty(t, destination.EncryptionAlgs)
+
+		ctx, err = svc.connectionContext("conn2")
+		require.NoError(t, err)
+		require.Equal(t, outbound, ctx.outboundDispatcher)
+	})
+
+	t.Run("test encryption algorithms recorded on the connection of the thread", func(t *testing.T) {
+		threadStore, err := threads.New(mockstorage.NewMockStoreProvider())
+		require.NoError(t, err)
+
+		store := &mockstorage.MockStore{Store: make(map[string][]byte)}
+		outbound := &recordingOutbound{}
+		svc := &Service{ctx: stateContext{outboundDispatcher: outbound}, store: store,
+			connectionStore: NewConnectionRecorder(store), threads: threadStore}
+
+		require.NoError(t, threadStore.Save(&threads.Record{ThreadID: "thid1", ConnectionID: "conn1"}))
+		require.NoError(t, svc.recordCounterparty("thid1", &service.DIDCommMsg{EncryptionAlgs: []string{"C20P"}}))
+
+		algs, err := svc.connectionStore.GetEncryptionAlgs("conn1")
+		require.NoError(t, err)
+		require.Equal(t, []string{"C20P"}, algs)
+
+		algs, err = svc.connectionStore.GetEncryptionAlgs("thid1")
+		require.NoError(t, err)
+		require.Empty(t, algs)
+
+		// the algorithms of the other threads of the connection are used
+		require.NoError(t, threadStore.Save(&threads.Record{ThreadID: "thid2", ConnectionID: "conn1"}))
+		ctx, err := svc.connectionContext("thid2")
+		require.NoError(t, err)
+		require.NoError(t, ctx.outboundDispatcher.Send(context.Background(), "data", "key",
+			&service.Destination{ServiceEndpoint: "url"}))
+		require.Equal(t, []string{"C20P"}, outbound.destination.EncryptionAlgs)
+
+		// the thread ID until the connection is recorded
+		require.NoError(t, svc.recordCounterparty("thid3", &service.DIDCommMsg{EncryptionAlgs: []string{"XC20P"}}))
+		algs, err = svc.connectionStore.GetEncryptionAlgs("thid3")
+		require.NoError(t, err)
+		require.Equal(t, []string{"XC20P"}, algs)
+	})
+
+	t.Run("test store errors", func(t *testing.T) {
+		threadStore, err := threads.New(mockstorage.NewMockStoreProvider())
+		require.NoError(t, err)
+
+		store := &mockStore{get: func(string) ([]byte, error) { return nil, errors.New("get error") }}
+		svc := &Service{connectionStore: NewConnectionRecorder(store), threads: threadStore}
+
+		err = svc.recordEncryptionAlgs("conn1", []string{"C20P"})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to fetch encryption algorithms")
+
+		_, err = svc.connectionContext("conn1")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to fetch encryption algorithms")
+
+		store.get = func(string) ([]byte, error) { return nil, storage.ErrDataNotFound }
+		store.put = func(string, []byte) error { return errors.New("put error") }
+		err = svc.recordEncryptionAlgs("conn1", []string{"C20P"})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to save encryption algorithms")
+	})
+}
+
+func TestService_InvitationUsage(t *testing.T) {
+	clock := &mockclock.Clock{Time: time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)}
+
+	s, err := New(&mockdid.MockDIDCreator{Doc: getMockDID()}, &protocol.MockProvider{ClockValue: clock})
+	require.NoError(t, err)
+	actions := make(chan service.DIDCommAction, 10)
+	require.NoError(t, s.RegisterActionEvent(actions))
+
+	recorder := NewConnectionRecorder(s.store)
+	require.NoError(t, recorder.SaveInvitationUsage("single-use-key", &InvitationUsage{SingleUse: true}))
+	require.NoError(t, recorder.SaveInvitationUsage("expired-key",
+		&InvitationUsage{ExpiresAt: clock.Now().Add(-time.Minute)}))
+	require.NoError(t, recorder.SaveInvitationUsage("ttl-key",
+		&InvitationUsage{ExpiresAt: clock.Now().Add(time.Hour)}))
+
+	request := func(recipientKey string) (string, *service.DIDCommMsg) {
+		thid := randomString()
+		payload, e := json.Marshal(&Request{Type: ConnectionRequest, ID: thid, Label: "Bob",
+			Connection: &Connection{DID: "did:example:bob", DIDDoc: getMockDIDPublicKey()}})
+		require.NoError(t, e)
+
+		return thid, &service.DIDCommMsg{Type: ConnectionRequest, Payload: payload,
+			Metadata: &service.EnvelopeMetadata{RecipientVerKey: recipientKey}}
+	}
+
+	requireRejected := func(err error, thid string, cause error) {
+		var problem *model.ProblemReportError
+		require.True(t, errors.As(err, &problem))
+		require.True(t, errors.Is(err, cause))
+		require.Equal(t, ConnectionProblemReport, problem.Report.Type)
+		require.Equal(t, requestNotAcceptedCode, problem.Report.Description.Code)
+		require.Equal(t, thid, problem.Report.Thread.ID)
+	}
+
+	t.Run("test single-use invitation", func(t *testing.T) {
+		// the invitation isn't used until a request is accepted
+		_, msg := request("single-use-key")
+		require.NoError(t, s.Handle(context.Background(), msg))
+		_, msg = request("single-use-key")
+		require.NoError(t, s.Handle(context.Background(), msg))
+
+		(<-actions).Continue()
+
+		for i := 0; recorder.CheckInvitation("single-use-key", clock.Now()) == nil; i++ {
+			require.True(t, i < 100, "invitation not used on accept")
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		// the other request fails to use the invitation when accepted
+		(<-actions).Continue()
+
+		thid, msg := request("single-use-key")
+		requireRejected(s.Handle(context.Background(), msg), thid, ErrInvitationUsed)
+	})
+
+	t.Run("test expired invitation", func(t *testing.T) {
+		thid, msg := request("expired-key")
+		requireRejected(s.Handle(context.Background(), msg), thid, ErrInvitationExpired)
+	})
+
+	t.Run("test invitation expired by the clock of the service", func(t *testing.T) {
+		_, msg := request("ttl-key")
+		require.NoError(t, s.Handle(context.Background(), msg))
+
+		clock.Add(2 * time.Hour)
+
+		thid, msg := request("ttl-key")
+		requireRejected(s.Handle(context.Background(), msg), thid, ErrInvitationExpired)
+	})
+
+	t.Run("test invitation without usage restrictions", func(t *testing.T) {
+		_, msg := request("other-key")
+		require.NoError(t, s.Handle(context.Background(), msg))
+
+		_, msg = request("other-key")
+		require.NoError(t, s.Handle(context.Background(), msg))
+	})
+}
+
+func TestService_MessageValidation(t *testing.T) {
+	s, err := New(&mockdid.MockDIDCreator{Doc: getMockDID()}, &protocol.MockProvider{})
+	require.NoError(t, err)
+	require.NoError(t, s.RegisterActionEvent(make(chan service.DIDCommAction, 10)))
+
+	requireInvalid := func(err error, thid, violation string) {
+		var problem *model.ProblemReportError
+		require.True(t, errors.As(err, &problem))
+		require.True(t, errors.Is(err, msgschema.ErrInvalidMessage))
+		require.Equal(t, ConnectionProblemReport, problem.Report.Type)
+		require.Equal(t, msgschema.InvalidMessageCode, problem.Report.Description.Code)
+		require.Contains(t, problem.Report.Description.Text, violation)
+
+		if thid == "" {
+			require.Nil(t, problem.Report.Thread)
+		} else {
+			require.Equal(t, thid, problem.Report.Thread.ID)
+		}
+	}
+
+	t.Run("test request without connection", func(t *testing.T) {
+		thid := randomString()
+		payload, err := json.Marshal(&Request{Type: ConnectionRequest, ID: thid, Label: "Bob"})
+		require.NoError(t, err)
+
+		err = s.Handle(context.Background(), &service.DIDCommMsg{Type: ConnectionRequest, Payload: payload})
+		requireInvalid(err, thid, "connection is required")
+
+		// the rejected request doesn't start a thread
+		validateState(t, s, thid, (&null{}).Name())
+	})
+
+	t.Run("test request with invalid field types", func(t *testing.T) {
+		payload := []byte(`{"@type": "` + ConnectionRequest + `", "@id": "thid-1", "label": 1,
+			"connection": {"did": "did:example:bob", "did_doc": "invalid"}}`)
+
+		err := s.Handle(context.Background(), &service.DIDCommMsg{Type: ConnectionRequest, Payload: payload})
+		requireInvalid(err, "thid-1", "label: Invalid type")
+		requireInvalid(err, "thid-1", "connection.did_doc: Invalid type")
+	})
+
+	t.Run("test response without thread", func(t *testing.T) {
+		id := randomString()
+		payload, err := json.Marshal(&Response{Type: ConnectionResponse, ID: id,
+			ConnectionSignature: &ConnectionSignature{SignVerKey: "key"}})
+		require.NoError(t, err)
+
+		// the problem report is threaded to the response
+		err = s.Handle(context.Background(), &service.DIDCommMsg{Type: ConnectionResponse, Payload: payload})
+		requireInvalid(err, id, "~thread is required")
+		requireInvalid(err, id, "connection~sig: sig_data is required")
+	})
+
+	t.Run("test message not a JSON object", func(t *testing.T) {
+		err := s.Handle(context.Background(), &service.DIDCommMsg{Type: ConnectionRequest, Payload: []byte("[]")})
+		requireInvalid(err, "", "Invalid type")
+	})
+
+	t.Run("test outbound messages aren't validated", func(t *testing.T) {
+		require.NoError(t, s.validateMessage(&service.DIDCommMsg{Type: ConnectionRequest, Payload: []byte("{}"),
+			Outbound: true}))
+	})
+
+	t.Run("test registered message schema", func(t *testing.T) {
+		require.NoError(t, s.RegisterMessageSchema(ConnectionAck, `{"required": ["~thread"]}`))
+
+		err := s.Handle(context.Background(), &service.DIDCommMsg{Type: ConnectionAck,
+			Payload: []byte(`{"@id": "ack-1"}`)})
+		requireInvalid(err, "ack-1", "~thread is required")
+
+		require.Error(t, s.RegisterMessageSchema(ConnectionAck, "invalid"))
+	})
+}
+
+// newConnectionSignature returns the connection signature of a response
+func newConnectionSignature(t *testing.T) *ConnectionSignature {
+	signature, err := prepareConnectionSignature(&Connection{DID: "did:example:alice",
+		DIDDoc: getMockDIDPublicKey()}, time.Now())
+	require.NoError(t, err)
+
+	return signature
+}
+
+// recordingOutbound records the context and the destination of the sent message
+type recordingOutbound struct {
+	ctx         context.Context
+	destination *service.Destination
+}
+
+func (o *recordingOutbound) Send(ctx context.Context, msg interface{}, senderVerKey string,
+	des *service.Destination) error {
+	o.ctx = ctx
+	o.destination = des
+
+	return nil
 }
 
 func newMockOutboundDispatcher() dispatcher.Outbound {
@@ -528,6 +1057,11 @@ func (m *mockStore) Get(k string) ([]byte, error) {
 	return m.get(k)
 }
 
+// Delete removes the record
+func (m *mockStore) Delete(k string) error {
+	return nil
+}
+
 func getMockDID() *did.Doc {
 	return &did.Doc{
 		Context: []string{"https://w3id.org/did/v1"},
@@ -598,8 +1132,9 @@ func TestService_Events(t *testing.T) {
 		id := "done"
 		request, err := json.Marshal(
 			&Request{
-				Type: ConnectionRequest,
-				ID:   id,
+				Type:       ConnectionRequest,
+				ID:         id,
+				Connection: &Connection{DID: "did:example:done", DIDDoc: getMockDIDPublicKey()},
 			},
 		)
 		require.NoError(t, err)
@@ -609,7 +1144,7 @@ func TestService_Events(t *testing.T) {
 			Payload: request,
 		}
 
-		err = svc.Handle(&msg)
+		err = svc.Handle(context.Background(), &msg)
 		require.NoError(t, err)
 	}()
 
@@ -723,7 +1258,7 @@ func handleConnectionResponseEvents(t *testing.T, svc *Service, e *service.DIDCo
 		})
 		require.NoError(t, err)
 
-		id := generateRandomID()
+		id := uuid.New().String()
 		err = svc.store.Put(id, jsonDoc)
 		require.NoError(t, err)
 
@@ -759,7 +1294,7 @@ func handleConnectionAckEvents(t *testing.T, svc *Service, e *service.DIDCommAct
 	require.NoError(t, err)
 
 	if pl.ID == corrupt {
-		id := generateRandomID()
+		id := uuid.New().String()
 		err := svc.store.Put(id, []byte("invalid json"))
 		require.NoError(t, err)
 
@@ -811,7 +1346,7 @@ func validateSuccessCase(t *testing.T, svc *Service) {
 		Payload: invite,
 	}
 
-	err = svc.Handle(&msg)
+	err = svc.Handle(context.Background(), &msg)
 	require.NoError(t, err)
 }
 
@@ -825,9 +1360,10 @@ func validateUserError(t *testing.T, svc *Service) {
 
 	request, err := json.Marshal(
 		&Request{
-			Type:  ConnectionRequest,
-			ID:    id,
-			Label: "test",
+			Type:       ConnectionRequest,
+			ID:         id,
+			Label:      "test",
+			Connection: &Connection{DID: "did:example:test", DIDDoc: getMockDIDPublicKey()},
 		},
 	)
 	require.NoError(t, err)
@@ -837,7 +1373,7 @@ func validateUserError(t *testing.T, svc *Service) {
 		Payload: request,
 	}
 
-	err = svc.Handle(&msg)
+	err = svc.Handle(context.Background(), &msg)
 	require.NoError(t, err)
 }
 
@@ -853,21 +1389,22 @@ func validateStoreError(t *testing.T, svc *Service) {
 	require.NoError(t, err)
 	require.Equal(t, "requested", s.Name())
 
-	request, err := json.Marshal(
-		&Request{
-			Type:  ConnectionResponse,
-			ID:    id,
-			Label: "test",
+	response, err := json.Marshal(
+		&Response{
+			Type:                ConnectionResponse,
+			ID:                  id,
+			ConnectionSignature: newConnectionSignature(t),
+			Thread:              &decorator.Thread{ID: id},
 		},
 	)
 	require.NoError(t, err)
 
 	msg := service.DIDCommMsg{
 		Type:    ConnectionResponse,
-		Payload: request,
+		Payload: response,
 	}
 
-	err = svc.Handle(&msg)
+	err = svc.Handle(context.Background(), &msg)
 	require.NoError(t, err)
 }
 
@@ -897,7 +1434,7 @@ func validateStoreDataCorruptionError(t *testing.T, svc *Service) {
 		Payload: request,
 	}
 
-	err = svc.Handle(&msg)
+	err = svc.Handle(context.Background(), &msg)
 	require.NoError(t, err)
 }
 
@@ -913,21 +1450,22 @@ func validateHandleError(t *testing.T, svc *Service) {
 	require.NoError(t, err)
 	require.Equal(t, "requested", s.Name())
 
-	request, err := json.Marshal(
-		&Request{
-			Type:  ConnectionResponse,
-			ID:    id,
-			Label: "test",
+	response, err := json.Marshal(
+		&Response{
+			Type:                ConnectionResponse,
+			ID:                  id,
+			ConnectionSignature: newConnectionSignature(t),
+			Thread:              &decorator.Thread{ID: id},
 		},
 	)
 	require.NoError(t, err)
 
 	msg := service.DIDCommMsg{
 		Type:    ConnectionResponse,
-		Payload: request,
+		Payload: response,
 	}
 
-	err = svc.Handle(&msg)
+	err = svc.Handle(context.Background(), &msg)
 	require.NoError(t, err)
 }
 
@@ -956,7 +1494,7 @@ func TestService_No_Execution(t *testing.T) {
 		Type: ConnectionResponse,
 	}
 
-	err = svc.Handle(&msg)
+	err = svc.Handle(context.Background(), &msg)
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "no clients are registered to handle the message")
 }
@@ -969,10 +1507,11 @@ func validateState(t *testing.T, svc *Service, id, expected string) {
 
 func TestServiceErrors(t *testing.T) {
 	request, err := json.Marshal(
-		&Request{
-			Type:  ConnectionResponse,
-			ID:    randomString(),
-			Label: "test",
+		&Response{
+			Type:                ConnectionResponse,
+			ID:                  randomString(),
+			ConnectionSignature: newConnectionSignature(t),
+			Thread:              &decorator.Thread{ID: randomString()},
 		},
 	)
 	require.NoError(t, err)
@@ -989,7 +1528,7 @@ func TestServiceErrors(t *testing.T) {
 	require.NoError(t, err)
 
 	// thid error
-	err = svc.Handle(&service.DIDCommMsg{Payload: nil})
+	err = svc.Handle(context.Background(), &service.DIDCommMsg{Payload: nil})
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "cannot unmarshal @id and ~thread: error=")
 
@@ -1004,7 +1543,8 @@ func TestServiceErrors(t *testing.T) {
 	}}
 	svc.store = mockStore
 	svc.connectionStore = NewConnectionRecorder(mockStore)
-	err = svc.Handle(&msg)
+	svc.machine = newStateMachine(mockStore)
+	err = svc.Handle(context.Background(), &msg)
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "cannot fetch state from store")
 
@@ -1012,28 +1552,29 @@ func TestServiceErrors(t *testing.T) {
 	msg.Type = "invalid"
 	svc.store, err = mockstorage.NewMockStoreProvider().OpenStore(DIDExchange)
 	svc.connectionStore = NewConnectionRecorder(svc.store)
+	svc.machine = newStateMachine(svc.store)
 	require.NoError(t, err)
-	err = svc.Handle(&msg)
+	err = svc.Handle(context.Background(), &msg)
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "unrecognized msgType: invalid")
 
 	// test handle - invalid state name
 	msg.Type = ConnectionResponse
 	message := &message{Msg: &msg, ThreadID: randomString()}
-	err = svc.handle(message)
+	err = svc.handle(context.Background(), message)
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "invalid state name:")
 
 	// invalid state name
 	message.NextStateName = stateNameInvited
-	err = svc.handle(message)
+	err = svc.handle(context.Background(), message)
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "failed to execute state invited")
 
 	// empty thread id
 	message.NextStateName = stateNameNull
 	message.ThreadID = ""
-	err = svc.handle(message)
+	err = svc.handle(context.Background(), message)
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "failed to persist state null")
 }
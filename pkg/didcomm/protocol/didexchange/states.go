@@ -8,6 +8,7 @@ package didexchange
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
@@ -15,13 +16,12 @@ import (
 	"strconv"
 	"time"
 
-	"github.com/google/uuid"
-
+	"github.com/hyperledger/aries-framework-go/pkg/common/idgen"
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/fsm"
 	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/model"
 	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/service"
 	"github.com/hyperledger/aries-framework-go/pkg/didcomm/protocol/decorator"
 	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
-	"github.com/hyperledger/aries-framework-go/pkg/wallet"
 )
 
 const (
@@ -31,26 +31,21 @@ const (
 	stateNameRequested = "requested"
 	stateNameResponded = "responded"
 	stateNameCompleted = "completed"
+	stateNameFailed    = "failed"
 	ackStatusOK        = "ok"
 	// Todo:How to find the key type -Issue-439
 	supportedPublicKeyType = "Ed25519VerificationKey2018"
 )
 
-//TODO: This is temporary to move forward with bdd test will be fixed in Issue-353
-var temp string //nolint
-
-// state action for network call
-type stateAction func() error
+// state action for network call, the context is the context of the handled message
+type stateAction func(ctx context.Context) error
 
 // The did-exchange protocol's state.
 type state interface {
-	// Name of this state.
-	Name() string
-	// Whether this state allows transitioning into the next state.
-	CanTransitionTo(next state) bool
+	fsm.State
 	// Executes this state, returning a followup state to be immediately executed as well.
 	// The 'noOp' state should be returned if the state has no followup.
-	Execute(msg *service.DIDCommMsg, thid string, ctx context) (followup state, action stateAction, err error)
+	Execute(msg *service.DIDCommMsg, thid string, ctx stateContext) (followup state, action stateAction, err error)
 }
 
 // Returns the state towards which the protocol will transition to if the msgType is processed.
@@ -85,6 +80,8 @@ func stateFromName(name string) (state, error) {
 		return &responded{}, nil
 	case stateNameCompleted:
 		return &completed{}, nil
+	case stateNameFailed:
+		return &failed{}, nil
 	default:
 		return nil, fmt.Errorf("invalid state name %s", name)
 	}
@@ -97,11 +94,11 @@ func (s *noOp) Name() string {
 	return stateNameNoop
 }
 
-func (s *noOp) CanTransitionTo(_ state) bool {
+func (s *noOp) CanTransitionTo(_ fsm.State) bool {
 	return false
 }
 
-func (s *noOp) Execute(_ *service.DIDCommMsg, thid string, ctx context) (state, stateAction, error) {
+func (s *noOp) Execute(_ *service.DIDCommMsg, thid string, ctx stateContext) (state, stateAction, error) {
 	return nil, nil, errors.New("cannot execute no-op")
 }
 
@@ -113,11 +110,11 @@ func (s *null) Name() string {
 	return stateNameNull
 }
 
-func (s *null) CanTransitionTo(next state) bool {
+func (s *null) CanTransitionTo(next fsm.State) bool {
 	return stateNameInvited == next.Name() || stateNameRequested == next.Name()
 }
 
-func (s *null) Execute(msg *service.DIDCommMsg, thid string, ctx context) (state, stateAction, error) {
+func (s *null) Execute(msg *service.DIDCommMsg, thid string, ctx stateContext) (state, stateAction, error) {
 	return &noOp{}, nil, nil
 }
 
@@ -129,11 +126,11 @@ func (s *invited) Name() string {
 	return stateNameInvited
 }
 
-func (s *invited) CanTransitionTo(next state) bool {
+func (s *invited) CanTransitionTo(next fsm.State) bool {
 	return stateNameRequested == next.Name()
 }
 
-func (s *invited) Execute(msg *service.DIDCommMsg, thid string, ctx context) (state, stateAction, error) {
+func (s *invited) Execute(msg *service.DIDCommMsg, thid string, ctx stateContext) (state, stateAction, error) {
 	if msg.Type != ConnectionInvite {
 		return nil, nil, fmt.Errorf("illegal msg type %s for state %s", msg.Type, s.Name())
 	}
@@ -141,7 +138,7 @@ func (s *invited) Execute(msg *service.DIDCommMsg, thid string, ctx context) (st
 		// illegal
 		return nil, nil, errors.New("outbound invitations are not allowed")
 	}
-	return &requested{}, func() error { return nil }, nil
+	return &requested{}, func(context.Context) error { return nil }, nil
 }
 
 // requested state
@@ -152,11 +149,11 @@ func (s *requested) Name() string {
 	return stateNameRequested
 }
 
-func (s *requested) CanTransitionTo(next state) bool {
+func (s *requested) CanTransitionTo(next fsm.State) bool {
 	return stateNameResponded == next.Name()
 }
 
-func (s *requested) Execute(msg *service.DIDCommMsg, thid string, ctx context) (state, stateAction, error) {
+func (s *requested) Execute(msg *service.DIDCommMsg, thid string, ctx stateContext) (state, stateAction, error) {
 	switch msg.Type {
 	case ConnectionInvite:
 		if msg.Outbound {
@@ -180,7 +177,7 @@ func (s *requested) Execute(msg *service.DIDCommMsg, thid string, ctx context) (
 			}
 			return &noOp{}, action, nil
 		}
-		return &responded{}, func() error { return nil }, nil
+		return &responded{}, func(context.Context) error { return nil }, nil
 	default:
 		return nil, nil, fmt.Errorf("illegal msg type %s for state %s", msg.Type, s.Name())
 	}
@@ -194,11 +191,11 @@ func (s *responded) Name() string {
 	return stateNameResponded
 }
 
-func (s *responded) CanTransitionTo(next state) bool {
+func (s *responded) CanTransitionTo(next fsm.State) bool {
 	return stateNameCompleted == next.Name()
 }
 
-func (s *responded) Execute(msg *service.DIDCommMsg, thid string, ctx context) (state, stateAction, error) {
+func (s *responded) Execute(msg *service.DIDCommMsg, thid string, ctx stateContext) (state, stateAction, error) {
 	switch msg.Type {
 	case ConnectionRequest:
 		if msg.Outbound {
@@ -209,7 +206,7 @@ func (s *responded) Execute(msg *service.DIDCommMsg, thid string, ctx context) (
 		if err != nil {
 			return nil, nil, fmt.Errorf("unmarshalling failed: %s", err)
 		}
-		action, err := ctx.handleInboundRequest(request)
+		action, err := ctx.handleInboundRequest(request, thid)
 		if err != nil {
 			return nil, nil, fmt.Errorf("handle inbound request failed: %s", err)
 		}
@@ -222,7 +219,7 @@ func (s *responded) Execute(msg *service.DIDCommMsg, thid string, ctx context) (
 			}
 			return &noOp{}, action, nil
 		}
-		return &completed{}, func() error { return nil }, nil
+		return &completed{}, func(context.Context) error { return nil }, nil
 	default:
 		return nil, nil, fmt.Errorf("illegal msg type %s for state %s", msg.Type, s.Name())
 	}
@@ -236,11 +233,11 @@ func (s *completed) Name() string {
 	return stateNameCompleted
 }
 
-func (s *completed) CanTransitionTo(next state) bool {
+func (s *completed) CanTransitionTo(next fsm.State) bool {
 	return false
 }
 
-func (s *completed) Execute(msg *service.DIDCommMsg, thid string, ctx context) (state, stateAction, error) {
+func (s *completed) Execute(msg *service.DIDCommMsg, thid string, ctx stateContext) (state, stateAction, error) {
 	switch msg.Type {
 	case ConnectionResponse:
 		if msg.Outbound {
@@ -251,16 +248,16 @@ func (s *completed) Execute(msg *service.DIDCommMsg, thid string, ctx context) (
 		if err != nil {
 			return nil, nil, fmt.Errorf("unmarshalling failed: %s", err)
 		}
-		action, err := ctx.handleInboundResponse(response)
+		action, err := ctx.handleInboundResponse(response, thid)
 		if err != nil {
 			return nil, nil, fmt.Errorf("handle inbound failed: %s", err)
 		}
 		return &noOp{}, action, nil
 	case ConnectionAck:
-		action := func() error { return nil }
+		action := func(context.Context) error { return nil }
 		if msg.Outbound {
 			var err error
-			action, err = ctx.sendOutboundAck(msg)
+			action, err = ctx.sendOutboundAck(msg, thid)
 			if err != nil {
 				return nil, nil, fmt.Errorf("send outbound ack failed: %s", err)
 			}
@@ -271,14 +268,31 @@ func (s *completed) Execute(msg *service.DIDCommMsg, thid string, ctx context) (
 		return nil, nil, fmt.Errorf("illegal msg type %s for state %s", msg.Type, s.Name())
 	}
 }
-func (ctx *context) handleInboundInvitation(invitation *Invitation, thid string) (stateAction, error) {
+
+// failed state, the counterparty reported a problem on the connection
+type failed struct {
+}
+
+func (s *failed) Name() string {
+	return stateNameFailed
+}
+
+func (s *failed) CanTransitionTo(next fsm.State) bool {
+	return false
+}
+
+func (s *failed) Execute(_ *service.DIDCommMsg, thid string, ctx stateContext) (state, stateAction, error) {
+	return nil, nil, errors.New("cannot execute failed state")
+}
+
+func (c *stateContext) handleInboundInvitation(invitation *Invitation, thid string) (stateAction, error) {
 	// create a request from invitation
 	destination := &service.Destination{
 		RecipientKeys:   invitation.RecipientKeys,
 		ServiceEndpoint: invitation.ServiceEndpoint,
 		RoutingKeys:     invitation.RoutingKeys,
 	}
-	newDidDoc, err := ctx.didCreator.CreateDID(wallet.WithServiceType(DIDExchangeServiceType))
+	newDidDoc, err := c.inviteeDIDDoc(invitation.ID, thid)
 	if err != nil {
 		return nil, err
 	}
@@ -287,7 +301,6 @@ func (ctx *context) handleInboundInvitation(invitation *Invitation, thid string)
 		return nil, fmt.Errorf("error while getting public key %s", err)
 	}
 	sendVerKey := string(pubKey[0].Value)
-	temp = sendVerKey
 	// prepare the request :
 	// TODO Service.Handle() is using the ID from the Invitation as the threadID when instead it should be
 	//  using this request's ID. issue-280
@@ -301,13 +314,13 @@ func (ctx *context) handleInboundInvitation(invitation *Invitation, thid string)
 		},
 	}
 	// send the exchange request
-	return func() error {
-		return ctx.outboundDispatcher.Send(request, sendVerKey, destination)
+	return func(ctx context.Context) error {
+		return c.outboundDispatcher.Send(ctx, request, sendVerKey, destination)
 	}, nil
 }
-func (ctx *context) handleInboundRequest(request *Request) (stateAction, error) {
+func (c *stateContext) handleInboundRequest(request *Request, thid string) (stateAction, error) {
 	// create a response from Request
-	newDidDoc, err := ctx.didCreator.CreateDID(wallet.WithServiceType(DIDExchangeServiceType))
+	newDidDoc, err := c.myDIDDoc(thid)
 	if err != nil {
 		return nil, err
 	}
@@ -316,14 +329,14 @@ func (ctx *context) handleInboundRequest(request *Request) (stateAction, error)
 		DIDDoc: newDidDoc,
 	}
 	// prepare connection signature
-	encodedConnectionSignature, err := prepareConnectionSignature(connection)
+	encodedConnectionSignature, err := prepareConnectionSignature(connection, c.now())
 	if err != nil {
 		return nil, err
 	}
 	// prepare the response
 	response := &Response{
 		Type: ConnectionResponse,
-		ID:   uuid.New().String(),
+		ID:   c.newID(),
 		Thread: &decorator.Thread{
 			ID: request.ID,
 		},
@@ -338,11 +351,11 @@ func (ctx *context) handleInboundRequest(request *Request) (stateAction, error)
 	}
 	sendVerKey := string(pubKey[0].Value)
 	// send exchange response
-	return func() error {
-		return ctx.outboundDispatcher.Send(response, sendVerKey, destination)
+	return func(ctx context.Context) error {
+		return c.outboundDispatcher.Send(ctx, response, sendVerKey, destination)
 	}, nil
 }
-func (ctx *context) sendOutboundRequest(msg *service.DIDCommMsg) (stateAction, error) {
+func (c *stateContext) sendOutboundRequest(msg *service.DIDCommMsg) (stateAction, error) {
 	if msg.OutboundDestination == nil {
 		return nil, fmt.Errorf("outboundDestination cannot be empty for outbound Request")
 	}
@@ -363,12 +376,12 @@ func (ctx *context) sendOutboundRequest(msg *service.DIDCommMsg) (stateAction, e
 	}
 	sendVerKey := string(pubKey[0].Value)
 	// send the exchange request
-	return func() error {
-		return ctx.outboundDispatcher.Send(request, sendVerKey, destination)
+	return func(ctx context.Context) error {
+		return c.outboundDispatcher.Send(ctx, request, sendVerKey, destination)
 	}, nil
 }
 
-func (ctx *context) sendOutboundResponse(msg *service.DIDCommMsg) (stateAction, error) {
+func (c *stateContext) sendOutboundResponse(msg *service.DIDCommMsg) (stateAction, error) {
 	if msg.OutboundDestination == nil {
 		return nil, fmt.Errorf("outboundDestination cannot be empty for outbound Request")
 	}
@@ -388,9 +401,9 @@ func (ctx *context) sendOutboundResponse(msg *service.DIDCommMsg) (stateAction,
 	if err != nil {
 		return nil, fmt.Errorf("decoding string failed : %s", err)
 	}
-	if len(sigData) != 0 {
-		// trimming the timestamp and only taking out connection attribute Bytes
-		connBytes = sigData[bytes.IndexRune(sigData, '{'):]
+	// trimming the timestamp and only taking out connection attribute Bytes
+	if i := bytes.IndexRune(sigData, '{'); i >= 0 {
+		connBytes = sigData[i:]
 	}
 
 	connection := &Connection{}
@@ -406,17 +419,38 @@ func (ctx *context) sendOutboundResponse(msg *service.DIDCommMsg) (stateAction,
 	// choose the first public key
 	sendVerKey := string(pubKey[0].Value)
 
-	return func() error {
-		return ctx.outboundDispatcher.Send(response, sendVerKey, destination)
+	return func(ctx context.Context) error {
+		return c.outboundDispatcher.Send(ctx, response, sendVerKey, destination)
 	}, nil
 }
 
 // TODO: Need to figure out how to find the destination for outbound request
 //  https://github.com/hyperledger/aries-framework-go/issues/282
 func prepareDestination(didDoc *did.Doc) *service.Destination {
-	var srvEndPoint string
-	for _, v := range didDoc.Service {
-		srvEndPoint = v.ServiceEndpoint
+	var srvEndPoint, compression string
+
+	var codecs, encryptionAlgs []string
+
+	var endpoints []service.Endpoint
+
+	var best int
+
+	// the service endpoint is the endpoint with the lowest priority, the last one if several
+	for i := range didDoc.Service {
+		priority := servicePriority(&didDoc.Service[i])
+		if i == 0 || priority <= best {
+			srvEndPoint = didDoc.Service[i].ServiceEndpoint
+			compression, _ = didDoc.Service[i].Properties["compression"].(string) // nolint: errcheck
+			codecs = serviceStrings(&didDoc.Service[i], "codecs")
+			encryptionAlgs = serviceStrings(&didDoc.Service[i], "encryptionAlgs")
+			best = priority
+		}
+
+		endpoints = append(endpoints, service.Endpoint{URI: didDoc.Service[i].ServiceEndpoint, Priority: priority})
+	}
+
+	if len(endpoints) < 2 {
+		endpoints = nil
 	}
 
 	pubKey := didDoc.PublicKey
@@ -428,18 +462,55 @@ func prepareDestination(didDoc *did.Doc) *service.Destination {
 	return &service.Destination{
 		RecipientKeys:   recipientKeys,
 		ServiceEndpoint: srvEndPoint,
+		Endpoints:       endpoints,
+		Compression:     compression,
+		Codecs:          codecs,
+		EncryptionAlgs:  encryptionAlgs,
+	}
+}
+
+// serviceStrings returns the string list property of the DID service, e.g. the payload codecs in preference
+// order. The list is a []interface{} once the DID document is unmarshalled, a []string when set by the wallet.
+func serviceStrings(s *did.Service, property string) []string {
+	switch c := s.Properties[property].(type) {
+	case []string:
+		return c
+	case []interface{}:
+		var values []string
+
+		for _, v := range c {
+			if value, ok := v.(string); ok {
+				values = append(values, value)
+			}
+		}
+
+		return values
+	default:
+		return nil
+	}
+}
+
+// servicePriority returns the priority property of the DID service, zero if not set. The priority is a float64
+// once the DID document is unmarshalled, an int when set by the wallet or the DID resolvers.
+func servicePriority(s *did.Service) int {
+	switch p := s.Properties["priority"].(type) {
+	case float64:
+		return int(p)
+	case int:
+		return p
+	default:
+		return 0
 	}
 }
 
 // Encode the connection and convert to Connection Signature as per the spec:
 // https://github.com/hyperledger/aries-rfcs/tree/master/features/0023-did-exchange
-func prepareConnectionSignature(connection *Connection) (*ConnectionSignature, error) {
+func prepareConnectionSignature(connection *Connection, now time.Time) (*ConnectionSignature, error) {
 	connAttributeBytes, err := json.Marshal(connection)
 	if err != nil {
 		return nil, err
 	}
-	now := getEpochTime()
-	timestamp := strconv.FormatInt(now, 10)
+	timestamp := strconv.FormatInt(now.Unix(), 10)
 	connAttributeString := string(connAttributeBytes)
 	concatenateSignData := []byte(timestamp + connAttributeString)
 	pubKey := connection.DIDDoc.PublicKey[0].Value
@@ -452,7 +523,7 @@ func prepareConnectionSignature(connection *Connection) (*ConnectionSignature, e
 		SignVerKey: string(pubKey),
 	}, nil
 }
-func (ctx *context) sendOutboundAck(msg *service.DIDCommMsg) (stateAction, error) {
+func (c *stateContext) sendOutboundAck(msg *service.DIDCommMsg, thid string) (stateAction, error) {
 	ack := &model.Ack{}
 	if msg.OutboundDestination == nil {
 		return nil, fmt.Errorf("outboundDestination cannot be empty for outbound Response")
@@ -467,48 +538,75 @@ func (ctx *context) sendOutboundAck(msg *service.DIDCommMsg) (stateAction, error
 	if err != nil {
 		return nil, err
 	}
-	// TODO : Issue-353
-	sendVerKey := temp
+	sendVerKey, err := c.myVerKey(thid)
+	if err != nil {
+		return nil, err
+	}
 
-	action := func() error {
-		return ctx.outboundDispatcher.Send(ack, sendVerKey, destination)
+	action := func(ctx context.Context) error {
+		return c.outboundDispatcher.Send(ctx, ack, sendVerKey, destination)
 	}
 	return action, nil
 }
-func (ctx *context) handleInboundResponse(response *Response) (stateAction, error) {
+func (c *stateContext) handleInboundResponse(response *Response, thid string) (stateAction, error) {
 	ack := &model.Ack{
 		Type:   ConnectionAck,
-		ID:     uuid.New().String(),
+		ID:     c.newID(),
 		Status: ackStatusOK,
 		Thread: &decorator.Thread{
 			ID: response.Thread.ID,
 		},
 	}
 
+	conn, err := connectionFromSignature(response.ConnectionSignature)
+	if err != nil {
+		return nil, err
+	}
+	dest := prepareDestination(conn.DIDDoc)
+	sendVerKey, err := c.myVerKey(thid)
+	if err != nil {
+		return nil, err
+	}
+	return func(ctx context.Context) error {
+		return c.outboundDispatcher.Send(ctx, ack, sendVerKey, dest)
+	}, nil
+}
+
+// connectionFromSignature returns the connection signed in the connection signature of the response
+func connectionFromSignature(signature *ConnectionSignature) (*Connection, error) {
 	var connBytes []byte
-	sigData, err := base64.URLEncoding.DecodeString(response.ConnectionSignature.SignedData)
+	sigData, err := base64.URLEncoding.DecodeString(signature.SignedData)
 	if err != nil {
 		return nil, fmt.Errorf("decode string failed : %s", err)
 	}
-	if len(sigData) != 0 {
-		// trimming the timestamp and only taking out connection attribute Bytes
-		connBytes = sigData[bytes.IndexRune(sigData, '{'):]
+	// trimming the timestamp and only taking out connection attribute Bytes
+	if i := bytes.IndexRune(sigData, '{'); i >= 0 {
+		connBytes = sigData[i:]
 	}
 	conn := &Connection{}
 	err = json.Unmarshal(connBytes, conn)
 	if err != nil {
 		return nil, fmt.Errorf("unmarshalling failed : %s", err)
 	}
-	dest := prepareDestination(conn.DIDDoc)
-	// TODO : Issue-353
-	sendVerKey := temp
-	return func() error {
-		return ctx.outboundDispatcher.Send(ack, sendVerKey, dest)
-	}, nil
+	return conn, nil
 }
 
-func getEpochTime() int64 {
-	return time.Now().Unix()
+// now returns the current time of the clock of the service
+func (c *stateContext) now() time.Time {
+	if c.clock == nil {
+		return time.Now()
+	}
+
+	return c.clock.Now()
+}
+
+// newID returns a new ID of the ID generator of the service
+func (c *stateContext) newID() string {
+	if c.ids == nil {
+		return idgen.UUID().NewID()
+	}
+
+	return c.ids.NewID()
 }
 func getPublicKeys(didDoc *did.Doc, pubKeyType string) ([]did.PublicKey, error) {
 	var publicKeys []did.PublicKey
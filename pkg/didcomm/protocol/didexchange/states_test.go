@@ -8,10 +8,12 @@ package didexchange
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
@@ -19,6 +21,7 @@ import (
 	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/model"
 	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/service"
 	"github.com/hyperledger/aries-framework-go/pkg/didcomm/protocol/decorator"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
 	mockdid "github.com/hyperledger/aries-framework-go/pkg/internal/mock/common/did"
 	mockdispatcher "github.com/hyperledger/aries-framework-go/pkg/internal/mock/didcomm/dispatcher"
 	"github.com/hyperledger/aries-framework-go/pkg/internal/mock/didcomm/protocol"
@@ -170,14 +173,14 @@ func TestStateFromName(t *testing.T) {
 
 // noOp.Execute() returns nil, error
 func TestNoOpState_Execute(t *testing.T) {
-	followup, _, err := (&noOp{}).Execute(&service.DIDCommMsg{}, "", context{})
+	followup, _, err := (&noOp{}).Execute(&service.DIDCommMsg{}, "", stateContext{})
 	require.Error(t, err)
 	require.Nil(t, followup)
 }
 
 // null.Execute() is a no-op
 func TestNullState_Execute(t *testing.T) {
-	followup, _, err := (&null{}).Execute(&service.DIDCommMsg{}, "", context{})
+	followup, _, err := (&null{}).Execute(&service.DIDCommMsg{}, "", stateContext{})
 	require.NoError(t, err)
 	require.IsType(t, &noOp{}, followup)
 }
@@ -186,17 +189,17 @@ func TestInvitedState_Execute(t *testing.T) {
 	t.Run("rejects msgs other than invitations", func(t *testing.T) {
 		others := []string{ConnectionRequest, ConnectionResponse, ConnectionAck}
 		for _, o := range others {
-			_, _, err := (&invited{}).Execute(&service.DIDCommMsg{Type: o}, "", context{})
+			_, _, err := (&invited{}).Execute(&service.DIDCommMsg{Type: o}, "", stateContext{})
 			require.Error(t, err)
 		}
 	})
 	t.Run("rejects outbound invitations", func(t *testing.T) {
-		_, _, err := (&invited{}).Execute(&service.DIDCommMsg{Type: ConnectionInvite, Outbound: true}, "", context{})
+		_, _, err := (&invited{}).Execute(&service.DIDCommMsg{Type: ConnectionInvite, Outbound: true}, "", stateContext{})
 		require.Error(t, err)
 	})
 	t.Run("followup to 'requested' on inbound invitations", func(t *testing.T) {
 		followup, _, err := (&invited{}).Execute(
-			&service.DIDCommMsg{Type: ConnectionInvite, Outbound: false}, "", context{})
+			&service.DIDCommMsg{Type: ConnectionInvite, Outbound: false}, "", stateContext{})
 		require.NoError(t, err)
 		require.Equal(t, (&requested{}).Name(), followup.Name())
 	})
@@ -204,18 +207,18 @@ func TestInvitedState_Execute(t *testing.T) {
 
 func TestRequestedState_Execute(t *testing.T) {
 	prov := protocol.MockProvider{}
-	ctx := context{outboundDispatcher: prov.OutboundDispatcher(), didCreator: &mockdid.MockDIDCreator{Doc: getMockDID()}}
+	ctx := stateContext{outboundDispatcher: prov.OutboundDispatcher(), didCreator: &mockdid.MockDIDCreator{Doc: getMockDID()}}
 	newDidDoc, err := ctx.didCreator.CreateDID()
 	require.NoError(t, err)
 	t.Run("rejects msgs other than invitations or requests", func(t *testing.T) {
 		others := []string{ConnectionResponse, ConnectionAck}
 		for _, o := range others {
-			_, _, e := (&requested{}).Execute(&service.DIDCommMsg{Type: o}, "", context{})
+			_, _, e := (&requested{}).Execute(&service.DIDCommMsg{Type: o}, "", stateContext{})
 			require.Error(t, e)
 		}
 	})
 	t.Run("rejects outbound invitations", func(t *testing.T) {
-		_, _, e := (&requested{}).Execute(&service.DIDCommMsg{Type: ConnectionInvite, Outbound: true}, "", context{})
+		_, _, e := (&requested{}).Execute(&service.DIDCommMsg{Type: ConnectionInvite, Outbound: true}, "", stateContext{})
 		require.Error(t, e)
 	})
 	// Alice receives an invitation from Bob
@@ -233,7 +236,7 @@ func TestRequestedState_Execute(t *testing.T) {
 	require.NoError(t, err)
 	t.Run("no followup to inbound invitations", func(t *testing.T) {
 		msg := service.DIDCommMsg{Type: ConnectionInvite, Payload: invitationPayloadBytes, Outbound: false}
-		thid, er := threadID(&msg)
+		thid, er := ctx.threadID(&msg)
 		require.NoError(t, er)
 		_, _, e := (&requested{}).Execute(
 			&service.DIDCommMsg{Type: ConnectionInvite, Payload: invitationPayloadBytes, Outbound: false}, thid, ctx)
@@ -262,7 +265,7 @@ func TestRequestedState_Execute(t *testing.T) {
 		require.IsType(t, &noOp{}, followup)
 	})
 	t.Run("err in sendind outbound requests", func(t *testing.T) {
-		ctx2 := context{outboundDispatcher: prov.OutboundDispatcher(),
+		ctx2 := stateContext{outboundDispatcher: prov.OutboundDispatcher(),
 			didCreator: &mockdid.MockDIDCreator{Doc: getMockDIDPublicKey()}}
 		newDidDoc, err := ctx2.didCreator.CreateDID()
 		require.NoError(t, err)
@@ -286,40 +289,40 @@ func TestRequestedState_Execute(t *testing.T) {
 	})
 	t.Run("followup to 'responded' on inbound requests", func(t *testing.T) {
 		followup, _, err := (&requested{}).
-			Execute(&service.DIDCommMsg{Type: ConnectionRequest, Outbound: false}, "", context{})
+			Execute(&service.DIDCommMsg{Type: ConnectionRequest, Outbound: false}, "", stateContext{})
 		require.NoError(t, err)
 		require.Equal(t, (&responded{}).Name(), followup.Name())
 	})
 	t.Run("followup to 'responded' on inbound requests", func(t *testing.T) {
 		followup, _, err := (&requested{}).
-			Execute(&service.DIDCommMsg{Type: ConnectionRequest, Payload: nil, Outbound: true}, "", context{})
+			Execute(&service.DIDCommMsg{Type: ConnectionRequest, Payload: nil, Outbound: true}, "", stateContext{})
 		require.Error(t, err)
 		require.Nil(t, followup)
 	})
 	t.Run("inbound request error", func(t *testing.T) {
 		followup, _, err := (&requested{}).
-			Execute(&service.DIDCommMsg{Type: ConnectionInvite, Payload: nil, Outbound: false}, "", context{})
+			Execute(&service.DIDCommMsg{Type: ConnectionInvite, Payload: nil, Outbound: false}, "", stateContext{})
 		require.Error(t, err)
 		require.Nil(t, followup)
 	})
 	t.Run("create DID error", func(t *testing.T) {
-		ctx2 := context{outboundDispatcher: prov.OutboundDispatcher(),
+		ctx2 := stateContext{outboundDispatcher: prov.OutboundDispatcher(),
 			didCreator: &mockdid.MockDIDCreator{Failure: fmt.Errorf("create DID error")}}
 		didDoc, err := ctx2.didCreator.CreateDID()
 		require.Error(t, err)
 		require.Nil(t, didDoc)
 	})
 	t.Run("handle inbound invitation  error", func(t *testing.T) {
-		ctx2 := context{outboundDispatcher: &mockdispatcher.MockOutbound{SendErr: fmt.Errorf("error")},
+		ctx2 := stateContext{outboundDispatcher: &mockdispatcher.MockOutbound{SendErr: fmt.Errorf("error")},
 			didCreator: &mockdid.MockDIDCreator{Doc: getMockDID()}}
 		followup, action, err := (&requested{}).
 			Execute(&service.DIDCommMsg{Type: ConnectionInvite, Payload: invitationPayloadBytes, Outbound: false}, "", ctx2)
 		require.NoError(t, err)
 		require.NotNil(t, followup)
-		require.Error(t, action())
+		require.Error(t, action(context.Background()))
 	})
 	t.Run("handle inbound invitation public key error", func(t *testing.T) {
-		ctx2 := context{outboundDispatcher: prov.OutboundDispatcher(),
+		ctx2 := stateContext{outboundDispatcher: prov.OutboundDispatcher(),
 			didCreator: &mockdid.MockDIDCreator{Doc: getMockDIDPublicKey()}}
 		followup, _, err := (&requested{}).
 			Execute(&service.DIDCommMsg{Type: ConnectionInvite, Payload: invitationPayloadBytes, Outbound: false}, "", ctx2)
@@ -330,7 +333,7 @@ func TestRequestedState_Execute(t *testing.T) {
 
 func TestRespondedState_Execute(t *testing.T) {
 	prov := protocol.MockProvider{}
-	ctx := context{outboundDispatcher: prov.OutboundDispatcher(), didCreator: &mockdid.MockDIDCreator{Doc: getMockDID()}}
+	ctx := stateContext{outboundDispatcher: prov.OutboundDispatcher(), didCreator: &mockdid.MockDIDCreator{Doc: getMockDID()}}
 	newDidDoc, err := ctx.didCreator.CreateDID()
 	require.NoError(t, err)
 
@@ -338,12 +341,12 @@ func TestRespondedState_Execute(t *testing.T) {
 	t.Run("rejects msgs other than requests and responses", func(t *testing.T) {
 		others := []string{ConnectionInvite, ConnectionAck}
 		for _, o := range others {
-			_, _, e := (&responded{}).Execute(&service.DIDCommMsg{Type: o}, "", context{})
+			_, _, e := (&responded{}).Execute(&service.DIDCommMsg{Type: o}, "", stateContext{})
 			require.Error(t, e)
 		}
 	})
 	t.Run("rejects outbound requests", func(t *testing.T) {
-		_, _, e := (&responded{}).Execute(&service.DIDCommMsg{Type: ConnectionRequest, Outbound: true}, "", context{})
+		_, _, e := (&responded{}).Execute(&service.DIDCommMsg{Type: ConnectionRequest, Outbound: true}, "", stateContext{})
 		require.Error(t, e)
 	})
 	// Prepare did-exchange inbound request
@@ -375,7 +378,7 @@ func TestRespondedState_Execute(t *testing.T) {
 		DID:    newDidDoc.ID,
 		DIDDoc: newDidDoc,
 	}
-	connectionSignature, err := prepareConnectionSignature(connection)
+	connectionSignature, err := prepareConnectionSignature(connection, time.Now())
 	require.NoError(t, err)
 
 	response := &Response{
@@ -396,7 +399,7 @@ func TestRespondedState_Execute(t *testing.T) {
 	})
 
 	t.Run("error for outbound responses", func(t *testing.T) {
-		ctx2 := context{outboundDispatcher: prov.OutboundDispatcher(),
+		ctx2 := stateContext{outboundDispatcher: prov.OutboundDispatcher(),
 			didCreator: &mockdid.MockDIDCreator{Doc: getMockDIDPublicKey()}}
 		newDidDoc, err = ctx2.didCreator.CreateDID()
 		require.NoError(t, err)
@@ -404,7 +407,7 @@ func TestRespondedState_Execute(t *testing.T) {
 			DID:    newDidDoc.ID,
 			DIDDoc: newDidDoc,
 		}
-		connectionSignature, err = prepareConnectionSignature(connection)
+		connectionSignature, err = prepareConnectionSignature(connection, time.Now())
 		require.NoError(t, err)
 
 		response := &Response{
@@ -424,25 +427,25 @@ func TestRespondedState_Execute(t *testing.T) {
 
 	t.Run("no followup for outbound responses error", func(t *testing.T) {
 		followup, _, e := (&responded{}).
-			Execute(&service.DIDCommMsg{Type: ConnectionResponse, Payload: nil, Outbound: true}, "", context{})
+			Execute(&service.DIDCommMsg{Type: ConnectionResponse, Payload: nil, Outbound: true}, "", stateContext{})
 		require.Error(t, e)
 		require.Nil(t, followup)
 	})
 	t.Run("inbound request error", func(t *testing.T) {
 		followup, _, e := (&responded{}).
-			Execute(&service.DIDCommMsg{Type: ConnectionRequest, Payload: nil, Outbound: false}, "", context{})
+			Execute(&service.DIDCommMsg{Type: ConnectionRequest, Payload: nil, Outbound: false}, "", stateContext{})
 		require.Error(t, e)
 		require.Nil(t, followup)
 	})
 	t.Run("handle inbound request  error", func(t *testing.T) {
-		ctx2 := context{outboundDispatcher: &mockdispatcher.MockOutbound{SendErr: fmt.Errorf("error")},
+		ctx2 := stateContext{outboundDispatcher: &mockdispatcher.MockOutbound{SendErr: fmt.Errorf("error")},
 			didCreator: &mockdid.MockDIDCreator{Doc: getMockDID()}}
 		followup, action, e := (&responded{}).
 			Execute(&service.DIDCommMsg{Type: ConnectionRequest, Payload: requestPayloadBytes,
 				Outbound: false, OutboundDestination: outboundDestination}, "", ctx2)
 		require.NoError(t, e)
 		require.NotNil(t, followup)
-		require.Error(t, action())
+		require.Error(t, action(context.Background()))
 	})
 	t.Run("outbound responses unmarshall connection error ", func(t *testing.T) {
 		require.NoError(t, err)
@@ -459,7 +462,7 @@ func TestRespondedState_Execute(t *testing.T) {
 		require.Nil(t, followup)
 	})
 	t.Run("handle inbound request public key error", func(t *testing.T) {
-		ctx2 := context{outboundDispatcher: prov.OutboundDispatcher(),
+		ctx2 := stateContext{outboundDispatcher: prov.OutboundDispatcher(),
 			didCreator: &mockdid.MockDIDCreator{Doc: getMockDIDPublicKey()}}
 		followup, _, err := (&responded{}).
 			Execute(&service.DIDCommMsg{Type: ConnectionRequest, Payload: requestPayloadBytes, Outbound: false}, "", ctx2)
@@ -471,14 +474,14 @@ func TestRespondedState_Execute(t *testing.T) {
 // completed is an end state
 func TestCompletedState_Execute(t *testing.T) {
 	prov := protocol.MockProvider{}
-	ctx := context{outboundDispatcher: prov.OutboundDispatcher(), didCreator: &mockdid.MockDIDCreator{Doc: getMockDID()}}
+	ctx := stateContext{outboundDispatcher: prov.OutboundDispatcher(), didCreator: &mockdid.MockDIDCreator{Doc: getMockDID()}}
 	newDidDoc, err := ctx.didCreator.CreateDID()
 	require.NoError(t, err)
 	outboundDestination := &service.Destination{RecipientKeys: []string{"test", "test2"}, ServiceEndpoint: "xyz"}
 	t.Run("rejects msgs other than responses and acks", func(t *testing.T) {
 		others := []string{ConnectionInvite, ConnectionRequest}
 		for _, o := range others {
-			_, _, err = (&completed{}).Execute(&service.DIDCommMsg{Type: o}, "", context{})
+			_, _, err = (&completed{}).Execute(&service.DIDCommMsg{Type: o}, "", stateContext{})
 			require.Error(t, err)
 		}
 	})
@@ -496,7 +499,7 @@ func TestCompletedState_Execute(t *testing.T) {
 		DID:    newDidDoc.ID,
 		DIDDoc: newDidDoc,
 	}
-	connectionSignature, err := prepareConnectionSignature(connection)
+	connectionSignature, err := prepareConnectionSignature(connection, time.Now())
 	require.NoError(t, err)
 	response := &Response{
 		Type: ConnectionRequest,
@@ -509,7 +512,7 @@ func TestCompletedState_Execute(t *testing.T) {
 	responsePayloadBytes, err := json.Marshal(response)
 
 	t.Run("rejects outbound responses", func(t *testing.T) {
-		_, _, err = (&completed{}).Execute(&service.DIDCommMsg{Type: ConnectionResponse, Outbound: true}, "", context{})
+		_, _, err = (&completed{}).Execute(&service.DIDCommMsg{Type: ConnectionResponse, Outbound: true}, "", stateContext{})
 		require.Error(t, err)
 	})
 	t.Run("no followup for inbound responses", func(t *testing.T) {
@@ -536,12 +539,12 @@ func TestCompletedState_Execute(t *testing.T) {
 	})
 	t.Run("no followup for inbound responses error", func(t *testing.T) {
 		followup, _, err := (&completed{}).Execute(&service.DIDCommMsg{Type: ConnectionResponse, Outbound: false},
-			"", context{})
+			"", stateContext{})
 		require.Error(t, err)
 		require.Nil(t, followup)
 	})
 	t.Run("no followup for inbound acks", func(t *testing.T) {
-		followup, _, err := (&completed{}).Execute(&service.DIDCommMsg{Type: ConnectionAck, Outbound: false}, "", context{})
+		followup, _, err := (&completed{}).Execute(&service.DIDCommMsg{Type: ConnectionAck, Outbound: false}, "", stateContext{})
 		require.NoError(t, err)
 		require.IsType(t, &noOp{}, followup)
 	})
@@ -565,20 +568,20 @@ func TestCompletedState_Execute(t *testing.T) {
 		require.Nil(t, followup)
 	})
 	t.Run("handle inbound response  error", func(t *testing.T) {
-		ctx2 := context{outboundDispatcher: &mockdispatcher.MockOutbound{SendErr: fmt.Errorf("error")},
+		ctx2 := stateContext{outboundDispatcher: &mockdispatcher.MockOutbound{SendErr: fmt.Errorf("error")},
 			didCreator: &mockdid.MockDIDCreator{Doc: getMockDID()}}
 		followup, action, err := (&completed{}).
 			Execute(&service.DIDCommMsg{Type: ConnectionResponse, Payload: responsePayloadBytes,
 				Outbound: false, OutboundDestination: outboundDestination}, "", ctx2)
 		require.NoError(t, err)
 		require.NotNil(t, followup)
-		require.Error(t, action())
+		require.Error(t, action(context.Background()))
 	})
 }
 func TestPrepareConnectionSignature(t *testing.T) {
 	t.Run("prepare connection signature", func(t *testing.T) {
 		prov := protocol.MockProvider{}
-		ctx := context{outboundDispatcher: prov.OutboundDispatcher(), didCreator: &mockdid.MockDIDCreator{Doc: getMockDID()}}
+		ctx := stateContext{outboundDispatcher: prov.OutboundDispatcher(), didCreator: &mockdid.MockDIDCreator{Doc: getMockDID()}}
 		newDidDoc, err := ctx.didCreator.CreateDID()
 		require.NoError(t, err)
 		require.NoError(t, err)
@@ -586,7 +589,7 @@ func TestPrepareConnectionSignature(t *testing.T) {
 			DID:    newDidDoc.ID,
 			DIDDoc: newDidDoc,
 		}
-		connectionSignature, err := prepareConnectionSignature(connection)
+		connectionSignature, err := prepareConnectionSignature(connection, time.Now())
 		require.NoError(t, err)
 		require.NotNil(t, connectionSignature)
 		sigData, err := base64.URLEncoding.DecodeString(connectionSignature.SignedData)
@@ -601,7 +604,7 @@ func TestPrepareConnectionSignature(t *testing.T) {
 
 func TestPrepareDestination(t *testing.T) {
 	prov := protocol.MockProvider{}
-	ctx := context{outboundDispatcher: prov.OutboundDispatcher(), didCreator: &mockdid.MockDIDCreator{Doc: getMockDID()}}
+	ctx := stateContext{outboundDispatcher: prov.OutboundDispatcher(), didCreator: &mockdid.MockDIDCreator{Doc: getMockDID()}}
 	newDidDoc, err := ctx.didCreator.CreateDID()
 	require.NoError(t, err)
 	dest := prepareDestination(newDidDoc)
@@ -609,12 +612,86 @@ func TestPrepareDestination(t *testing.T) {
 	require.Equal(t, dest.ServiceEndpoint, "https://localhost:8090")
 	// 2 Public keys inside the didDoc
 	require.Len(t, dest.RecipientKeys, 3)
+	require.Empty(t, dest.Endpoints)
+
+	t.Run("test multiple services", func(t *testing.T) {
+		doc := getMockDID()
+		doc.Service = []did.Service{
+			{ServiceEndpoint: "https://localhost:8091", Properties: map[string]interface{}{"priority": float64(1)}},
+			{ServiceEndpoint: "https://localhost:8090"},
+		}
+
+		dest := prepareDestination(doc)
+		require.Equal(t, "https://localhost:8090", dest.ServiceEndpoint)
+		require.Equal(t, []service.Endpoint{{URI: "https://localhost:8091", Priority: 1},
+			{URI: "https://localhost:8090"}}, dest.Endpoints)
+		require.Equal(t, []string{"https://localhost:8090", "https://localhost:8091"}, dest.ServiceEndpoints())
+	})
+
+	t.Run("test service endpoint with the lowest priority", func(t *testing.T) {
+		doc := getMockDID()
+		doc.Service = []did.Service{
+			{ServiceEndpoint: "https://localhost:8090", Properties: map[string]interface{}{"priority": float64(0)}},
+			{ServiceEndpoint: "https://localhost:8091", Properties: map[string]interface{}{"priority": float64(1)}},
+		}
+
+		dest := prepareDestination(doc)
+		require.Equal(t, "https://localhost:8090", dest.ServiceEndpoint)
+		require.Equal(t, []string{"https://localhost:8090", "https://localhost:8091"}, dest.ServiceEndpoints())
+	})
+
+	t.Run("test priorities set by the wallet", func(t *testing.T) {
+		doc := getMockDID()
+		doc.Service = []did.Service{
+			{ServiceEndpoint: "ws://localhost:8091", Properties: map[string]interface{}{"priority": 1}},
+			{ServiceEndpoint: "https://localhost:8090", Properties: map[string]interface{}{"priority": 0}},
+		}
+
+		dest := prepareDestination(doc)
+		require.Equal(t, "https://localhost:8090", dest.ServiceEndpoint)
+		require.Equal(t, []string{"https://localhost:8090", "ws://localhost:8091"}, dest.ServiceEndpoints())
+	})
+
+	t.Run("test compression accepted by the service", func(t *testing.T) {
+		doc := getMockDID()
+		doc.Service = []did.Service{
+			{ServiceEndpoint: "https://localhost:8090", Properties: map[string]interface{}{"compression": "DEF"}},
+		}
+
+		require.Equal(t, "DEF", prepareDestination(doc).Compression)
+		require.Empty(t, prepareDestination(getMockDID()).Compression)
+	})
+
+	t.Run("test codecs advertised by the service", func(t *testing.T) {
+		doc := getMockDID()
+		doc.Service = []did.Service{
+			{ServiceEndpoint: "https://localhost:8090", Properties: map[string]interface{}{"codecs": []string{"cbor"}}},
+		}
+
+		require.Equal(t, []string{"cbor"}, prepareDestination(doc).Codecs)
+		require.Empty(t, prepareDestination(getMockDID()).Codecs)
+
+		// the codecs of the unmarshalled DID documents
+		doc.Service[0].Properties["codecs"] = []interface{}{"cbor", 1, "json"}
+		require.Equal(t, []string{"cbor", "json"}, prepareDestination(doc).Codecs)
+	})
+
+	t.Run("test encryption algorithms advertised by the service", func(t *testing.T) {
+		doc := getMockDID()
+		doc.Service = []did.Service{
+			{ServiceEndpoint: "https://localhost:8090", Properties: map[string]interface{}{
+				"encryptionAlgs": []interface{}{"XC20P", "C20P"}}},
+		}
+
+		require.Equal(t, []string{"XC20P", "C20P"}, prepareDestination(doc).EncryptionAlgs)
+		require.Empty(t, prepareDestination(getMockDID()).EncryptionAlgs)
+	})
 }
 
 func TestNewRequestFromInvitation(t *testing.T) {
 	t.Run("successful new request from invitation", func(t *testing.T) {
 		prov := protocol.MockProvider{}
-		ctx := context{outboundDispatcher: prov.OutboundDispatcher(), didCreator: &mockdid.MockDIDCreator{Doc: getMockDID()}}
+		ctx := stateContext{outboundDispatcher: prov.OutboundDispatcher(), didCreator: &mockdid.MockDIDCreator{Doc: getMockDID()}}
 		invitation := &Invitation{
 			Type:            ConnectionInvite,
 			ID:              randomString(),
@@ -626,19 +703,19 @@ func TestNewRequestFromInvitation(t *testing.T) {
 		}
 		invitationBytes, err := json.Marshal(invitation)
 		require.NoError(t, err)
-		thid, err := threadID(&service.DIDCommMsg{Type: ConnectionInvite, Payload: invitationBytes, Outbound: false})
+		thid, err := ctx.threadID(&service.DIDCommMsg{Type: ConnectionInvite, Payload: invitationBytes, Outbound: false})
 		require.NoError(t, err)
 		_, err = ctx.handleInboundInvitation(invitation, thid)
 		require.NoError(t, err)
 	})
 	t.Run("unsuccessful new request from invitation ", func(t *testing.T) {
 		prov := protocol.MockProvider{}
-		ctx := context{outboundDispatcher: prov.OutboundDispatcher(),
+		ctx := stateContext{outboundDispatcher: prov.OutboundDispatcher(),
 			didCreator: &mockdid.MockDIDCreator{Failure: fmt.Errorf("create DID error")}}
 		invitation := &Invitation{}
 		invitationBytes, err := json.Marshal(invitation)
 		require.NoError(t, err)
-		thid, err := threadID(&service.DIDCommMsg{Type: ConnectionInvite, Payload: invitationBytes, Outbound: false})
+		thid, err := ctx.threadID(&service.DIDCommMsg{Type: ConnectionInvite, Payload: invitationBytes, Outbound: false})
 		require.NoError(t, err)
 		_, err = ctx.handleInboundInvitation(invitation, thid)
 		require.Error(t, err)
@@ -649,7 +726,7 @@ func TestNewRequestFromInvitation(t *testing.T) {
 func TestNewResponseFromRequest(t *testing.T) {
 	t.Run("successful new response from request", func(t *testing.T) {
 		prov := protocol.MockProvider{}
-		ctx := context{outboundDispatcher: prov.OutboundDispatcher(), didCreator: &mockdid.MockDIDCreator{Doc: getMockDID()}}
+		ctx := stateContext{outboundDispatcher: prov.OutboundDispatcher(), didCreator: &mockdid.MockDIDCreator{Doc: getMockDID()}}
 		newDidDoc, err := ctx.didCreator.CreateDID()
 		require.NoError(t, err)
 		request := &Request{
@@ -661,15 +738,15 @@ func TestNewResponseFromRequest(t *testing.T) {
 				DIDDoc: newDidDoc,
 			},
 		}
-		_, err = ctx.handleInboundRequest(request)
+		_, err = ctx.handleInboundRequest(request, randomString())
 		require.NoError(t, err)
 	})
 	t.Run("unsuccessful new response from request", func(t *testing.T) {
 		prov := protocol.MockProvider{}
-		ctx := context{outboundDispatcher: prov.OutboundDispatcher(),
+		ctx := stateContext{outboundDispatcher: prov.OutboundDispatcher(),
 			didCreator: &mockdid.MockDIDCreator{Failure: fmt.Errorf("create DID error")}}
 		request := &Request{}
-		_, err := ctx.handleInboundRequest(request)
+		_, err := ctx.handleInboundRequest(request, randomString())
 		require.Error(t, err)
 	})
 }
@@ -677,7 +754,7 @@ func TestNewResponseFromRequest(t *testing.T) {
 func TestGetPublicKey(t *testing.T) {
 	t.Run("successfully getting public key", func(t *testing.T) {
 		prov := protocol.MockProvider{}
-		ctx := context{outboundDispatcher: prov.OutboundDispatcher(), didCreator: &mockdid.MockDIDCreator{Doc: getMockDID()}}
+		ctx := stateContext{outboundDispatcher: prov.OutboundDispatcher(), didCreator: &mockdid.MockDIDCreator{Doc: getMockDID()}}
 		newDidDoc, err := ctx.didCreator.CreateDID()
 		require.NoError(t, err)
 		pubkey, err := getPublicKeys(newDidDoc, supportedPublicKeyType)
@@ -687,7 +764,7 @@ func TestGetPublicKey(t *testing.T) {
 	})
 	t.Run("failed to get public key", func(t *testing.T) {
 		prov := protocol.MockProvider{}
-		ctx := context{outboundDispatcher: prov.OutboundDispatcher(), didCreator: &mockdid.MockDIDCreator{Doc: getMockDID()}}
+		ctx := stateContext{outboundDispatcher: prov.OutboundDispatcher(), didCreator: &mockdid.MockDIDCreator{Doc: getMockDID()}}
 		newDidDoc, err := ctx.didCreator.CreateDID()
 		require.NoError(t, err)
 		pubkey, err := getPublicKeys(newDidDoc, "invalid key")
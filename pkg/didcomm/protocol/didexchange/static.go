@@ -0,0 +1,153 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package didexchange
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/service"
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/threads"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	"github.com/hyperledger/aries-framework-go/pkg/storage"
+)
+
+// ErrConnectionExists is returned when a static connection is created with the ID of an existing connection
+var ErrConnectionExists = errors.New("connection already exists")
+
+// StaticConnection describes a counterparty known out of band, such as a configured peer or an IoT device.
+// The destination of the counterparty is read from its DID document if set, otherwise from the keys and
+// the endpoint.
+type StaticConnection struct {
+	// ConnectionID of the connection, generated if empty. The ID is derived from the DIDs of the parties if the
+	// service derives the connection IDs and both DID documents are set.
+	ConnectionID string
+	// MyDIDDoc is the DID document of the agent backing the connection, optional
+	MyDIDDoc *did.Doc
+	// TheirDIDDoc is the DID document of the counterparty
+	TheirDIDDoc *did.Doc
+	// RecipientKeys are the keys of the counterparty
+	RecipientKeys []string
+	// ServiceEndpoint is the endpoint of the counterparty
+	ServiceEndpoint string
+	// RoutingKeys are the keys of the mediators of the counterparty
+	RoutingKeys []string
+	// EncryptionAlgs are the envelope content encryption algorithms supported by the counterparty, if known
+	EncryptionAlgs []string
+	// Codecs are the payload codecs supported by the counterparty in preference order, read from its DID document
+	// if not set
+	Codecs []string
+	// Compression is the payload compression accepted by the counterparty, read from its DID document if not set
+	Compression string
+}
+
+// CreateStaticConnection creates a completed connection to the counterparty without the DID exchange handshake.
+// The connection is recorded like the connections created by the handshake: the inbound messages are verified
+// against the keys of the counterparty and the outbound messages are packed for its encryption algorithms.
+// ErrConnectionExists is returned if the connection ID is already used.
+func (s *Service) CreateStaticConnection(conn *StaticConnection) (string, error) {
+	destination, err := staticDestination(conn)
+	if err != nil {
+		return "", err
+	}
+
+	connectionID, err := s.staticConnectionID(conn)
+	if err != nil {
+		return "", err
+	}
+
+	if conn.MyDIDDoc != nil {
+		if err = s.connectionStore.SaveMyDID(connectionID, conn.MyDIDDoc); err != nil {
+			return "", fmt.Errorf("failed to save my DID: %w", err)
+		}
+	}
+
+	if conn.TheirDIDDoc != nil {
+		if err = s.connectionStore.SaveTheirDID(connectionID, conn.TheirDIDDoc.ID); err != nil {
+			return "", fmt.Errorf("failed to save their DID: %w", err)
+		}
+	}
+
+	if err = s.connectionStore.SaveDestination(connectionID, destination); err != nil {
+		return "", fmt.Errorf("failed to save destination: %w", err)
+	}
+
+	if len(conn.EncryptionAlgs) > 0 {
+		if err = s.recordEncryptionAlgs(connectionID, conn.EncryptionAlgs); err != nil {
+			return "", err
+		}
+	}
+
+	err = s.threads.Save(&threads.Record{
+		ThreadID:     connectionID,
+		ConnectionID: connectionID,
+		Protocol:     DIDExchange,
+		Completed:    true,
+		TheirVerKeys: destination.RecipientKeys,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to save thread %s: %w", connectionID, err)
+	}
+
+	if err = s.update(connectionID, &completed{}); err != nil {
+		return "", fmt.Errorf("failed to persist state %s: %w", stateNameCompleted, err)
+	}
+
+	return connectionID, nil
+}
+
+// staticDestination returns the destination of the counterparty of the static connection
+func staticDestination(conn *StaticConnection) (*service.Destination, error) {
+	destination := &service.Destination{
+		RecipientKeys:   conn.RecipientKeys,
+		ServiceEndpoint: conn.ServiceEndpoint,
+		RoutingKeys:     conn.RoutingKeys,
+	}
+
+	if conn.TheirDIDDoc != nil {
+		destination = prepareDestination(conn.TheirDIDDoc)
+		destination.RoutingKeys = conn.RoutingKeys
+	}
+
+	if len(conn.Codecs) > 0 {
+		destination.Codecs = conn.Codecs
+	}
+
+	if conn.Compression != "" {
+		destination.Compression = conn.Compression
+	}
+
+	if len(destination.RecipientKeys) == 0 || destination.ServiceEndpoint == "" {
+		return nil, errors.New("recipient keys and service endpoint of the static connection are mandatory")
+	}
+
+	return destination, nil
+}
+
+// staticConnectionID returns the ID of the static connection, ErrConnectionExists is returned if already used
+func (s *Service) staticConnectionID(conn *StaticConnection) (string, error) {
+	connectionID := conn.ConnectionID
+
+	switch {
+	case connectionID != "":
+	case s.deterministicIDs && conn.MyDIDDoc != nil && conn.TheirDIDDoc != nil:
+		connectionID = ConnectionIDFromDIDs(conn.MyDIDDoc.ID, conn.TheirDIDDoc.ID)
+	default:
+		connectionID = s.ctx.newID()
+	}
+
+	_, err := s.connectionStore.GetConnection(connectionID)
+	if err == nil {
+		return "", fmt.Errorf("%w: %s", ErrConnectionExists, connectionID)
+	}
+
+	if !errors.Is(err, storage.ErrDataNotFound) {
+		return "", fmt.Errorf("failed to check connection %s: %w", connectionID, err)
+	}
+
+	return connectionID, nil
+}
@@ -0,0 +1,114 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package didexchange
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	mockdid "github.com/hyperledger/aries-framework-go/pkg/internal/mock/common/did"
+	"github.com/hyperledger/aries-framework-go/pkg/internal/mock/didcomm/protocol"
+	"github.com/hyperledger/aries-framework-go/pkg/storage"
+)
+
+func TestService_CreateStaticConnection(t *testing.T) {
+	t.Run("test connection from keys and endpoint", func(t *testing.T) {
+		svc, err := New(&mockdid.MockDIDCreator{Doc: getMockDID()}, &protocol.MockProvider{})
+		require.NoError(t, err)
+
+		connectionID, err := svc.CreateStaticConnection(&StaticConnection{
+			RecipientKeys:   []string{"key1"},
+			ServiceEndpoint: "http://device:8080",
+			RoutingKeys:     []string{"routing1"},
+			EncryptionAlgs:  []string{"XC20P"},
+		})
+		require.NoError(t, err)
+		require.NotEmpty(t, connectionID)
+
+		conn, err := svc.connectionStore.GetConnection(connectionID)
+		require.NoError(t, err)
+		require.Equal(t, stateNameCompleted, conn.State)
+
+		destination, err := svc.connectionStore.GetDestination(connectionID)
+		require.NoError(t, err)
+		require.Equal(t, []string{"key1"}, destination.RecipientKeys)
+		require.Equal(t, "http://device:8080", destination.ServiceEndpoint)
+		require.Equal(t, []string{"routing1"}, destination.RoutingKeys)
+
+		algs, err := svc.connectionStore.GetEncryptionAlgs(connectionID)
+		require.NoError(t, err)
+		require.Equal(t, []string{"XC20P"}, algs)
+
+		thread, err := svc.threads.Get(connectionID)
+		require.NoError(t, err)
+		require.True(t, thread.Completed)
+		require.Equal(t, connectionID, thread.ConnectionID)
+		require.Equal(t, []string{"key1"}, thread.TheirVerKeys)
+	})
+
+	t.Run("test connection from DID document", func(t *testing.T) {
+		svc, err := New(&mockdid.MockDIDCreator{Doc: getMockDID()}, &protocol.MockProvider{})
+		require.NoError(t, err)
+
+		connectionID, err := svc.CreateStaticConnection(&StaticConnection{
+			ConnectionID: "peer1",
+			TheirDIDDoc:  getMockDIDPublicKey(),
+		})
+		require.NoError(t, err)
+		require.Equal(t, "peer1", connectionID)
+
+		destination, err := svc.connectionStore.GetDestination(connectionID)
+		require.NoError(t, err)
+		require.Len(t, destination.RecipientKeys, len(getMockDIDPublicKey().PublicKey))
+		require.Equal(t, "https://localhost:8090", destination.ServiceEndpoint)
+	})
+
+	t.Run("test duplicate connection ID", func(t *testing.T) {
+		svc, err := New(&mockdid.MockDIDCreator{Doc: getMockDID()}, &protocol.MockProvider{})
+		require.NoError(t, err)
+
+		conn := &StaticConnection{ConnectionID: "peer1", RecipientKeys: []string{"key1"}, ServiceEndpoint: "url1"}
+		_, err = svc.CreateStaticConnection(conn)
+		require.NoError(t, err)
+
+		conn.RecipientKeys = []string{"key2"}
+		_, err = svc.CreateStaticConnection(conn)
+		require.True(t, errors.Is(err, ErrConnectionExists))
+
+		thread, err := svc.threads.Get("peer1")
+		require.NoError(t, err)
+		require.Equal(t, []string{"key1"}, thread.TheirVerKeys)
+	})
+
+	t.Run("test missing destination", func(t *testing.T) {
+		svc, err := New(&mockdid.MockDIDCreator{Doc: getMockDID()}, &protocol.MockProvider{})
+		require.NoError(t, err)
+
+		_, err = svc.CreateStaticConnection(&StaticConnection{RecipientKeys: []string{"key1"}})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "recipient keys and service endpoint of the static connection are mandatory")
+	})
+
+	t.Run("test store error", func(t *testing.T) {
+		store := &mockStore{
+			get: func(string) ([]byte, error) { return nil, storage.ErrDataNotFound },
+			put: func(string, []byte) error { return errors.New("put error") },
+		}
+		svc := &Service{connectionStore: NewConnectionRecorder(store)}
+
+		_, err := svc.CreateStaticConnection(&StaticConnection{RecipientKeys: []string{"key1"}, ServiceEndpoint: "url"})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to save destination")
+
+		store.get = func(string) ([]byte, error) { return nil, errors.New("get error") }
+		_, err = svc.CreateStaticConnection(&StaticConnection{RecipientKeys: []string{"key1"}, ServiceEndpoint: "url"})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to check connection")
+	})
+}
@@ -7,6 +7,8 @@ SPDX-License-Identifier: Apache-2.0
 package introduce
 
 import (
+	"context"
+
 	"github.com/hyperledger/aries-framework-go/pkg/common/metadata"
 	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/service"
 )
@@ -38,7 +40,7 @@ func New() *Service {
 }
 
 // Handle didexchange msg
-func (s *Service) Handle(msg *service.DIDCommMsg) error {
+func (s *Service) Handle(ctx context.Context, msg *service.DIDCommMsg) error {
 	return nil
 }
 
@@ -7,6 +7,7 @@ SPDX-License-Identifier: Apache-2.0
 package introduce
 
 import (
+	"context"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -51,7 +52,7 @@ func TestService_Name(t *testing.T) {
 }
 
 func TestService_Handle(t *testing.T) {
-	require.Nil(t, New().Handle(&service.DIDCommMsg{}))
+	require.Nil(t, New().Handle(context.Background(), &service.DIDCommMsg{}))
 }
 
 func TestService_Accept(t *testing.T) {
@@ -9,6 +9,7 @@ package introduce
 import (
 	"errors"
 
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/fsm"
 	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/service"
 )
 
@@ -31,10 +32,7 @@ const (
 
 // The introduce protocol's state.
 type state interface {
-	// Name of this state.
-	Name() string
-	// Whether this state allows transitioning into the next state.
-	CanTransitionTo(next state) bool
+	fsm.State
 	// Executes this state, returning a followup state to be immediately executed as well.
 	// The 'noOp' state should be returned if the state has no followup.
 	Execute(msg *service.DIDCommMsg) (followup state, err error)
@@ -48,7 +46,7 @@ func (s *noOp) Name() string {
 	return stateNameNoop
 }
 
-func (s *noOp) CanTransitionTo(_ state) bool {
+func (s *noOp) CanTransitionTo(_ fsm.State) bool {
 	return false
 }
 
@@ -64,7 +62,7 @@ func (s *start) Name() string {
 	return stateNameStart
 }
 
-func (s *start) CanTransitionTo(next state) bool {
+func (s *start) CanTransitionTo(next fsm.State) bool {
 	// Introducer can go to arranging or delivering state
 	// Introducee can go to deciding
 	return next.Name() == stateNameArranging || next.Name() == stateNameDelivering || next.Name() == stateNameDeciding
@@ -82,7 +80,7 @@ func (s *done) Name() string {
 	return stateNameDone
 }
 
-func (s *done) CanTransitionTo(next state) bool {
+func (s *done) CanTransitionTo(next fsm.State) bool {
 	// done is the last state there is no possibility for the next state
 	return false
 }
@@ -99,7 +97,7 @@ func (s *arranging) Name() string {
 	return stateNameArranging
 }
 
-func (s *arranging) CanTransitionTo(next state) bool {
+func (s *arranging) CanTransitionTo(next fsm.State) bool {
 	return next.Name() == stateNameArranging || next.Name() == stateNameDone || next.Name() == stateNameAbandoning
 }
 
@@ -115,7 +113,7 @@ func (s *delivering) Name() string {
 	return stateNameDelivering
 }
 
-func (s *delivering) CanTransitionTo(next state) bool {
+func (s *delivering) CanTransitionTo(next fsm.State) bool {
 	return next.Name() == stateNameConfirming || next.Name() == stateNameDone || next.Name() == stateNameAbandoning
 }
 
@@ -131,7 +129,7 @@ func (s *confirming) Name() string {
 	return stateNameConfirming
 }
 
-func (s *confirming) CanTransitionTo(next state) bool {
+func (s *confirming) CanTransitionTo(next fsm.State) bool {
 	return next.Name() == stateNameDone || next.Name() == stateNameAbandoning
 }
 
@@ -147,7 +145,7 @@ func (s *abandoning) Name() string {
 	return stateNameAbandoning
 }
 
-func (s *abandoning) CanTransitionTo(next state) bool {
+func (s *abandoning) CanTransitionTo(next fsm.State) bool {
 	return next.Name() == stateNameDone
 }
 
@@ -163,7 +161,7 @@ func (s *deciding) Name() string {
 	return stateNameDeciding
 }
 
-func (s *deciding) CanTransitionTo(next state) bool {
+func (s *deciding) CanTransitionTo(next fsm.State) bool {
 	return next.Name() == stateNameWaiting || next.Name() == stateNameDone
 }
 
@@ -179,7 +177,7 @@ func (s *waiting) Name() string {
 	return stateNameWaiting
 }
 
-func (s *waiting) CanTransitionTo(next state) bool {
+func (s *waiting) CanTransitionTo(next fsm.State) bool {
 	return next.Name() == stateNameDone
 }
 
@@ -0,0 +1,30 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package route
+
+import "encoding/json"
+
+// Forward is the message forwarded by the mediator to the recipient key
+type Forward struct {
+	Type string          `json:"@type,omitempty"`
+	ID   string          `json:"@id,omitempty"`
+	To   string          `json:"to,omitempty"`
+	Msg  json.RawMessage `json:"msg,omitempty"`
+}
+
+// KeylistUpdate requests the mediator to add or remove the recipient keys routed to the connection
+type KeylistUpdate struct {
+	Type    string   `json:"@type,omitempty"`
+	ID      string   `json:"@id,omitempty"`
+	Updates []Update `json:"updates,omitempty"`
+}
+
+// Update is the update of a recipient key in the keylist update
+type Update struct {
+	RecipientKey string `json:"recipient_key,omitempty"`
+	Action       string `json:"action,omitempty"`
+}
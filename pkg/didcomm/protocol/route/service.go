@@ -0,0 +1,234 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package route
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/hyperledger/aries-framework-go/pkg/common/log"
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/attachments"
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/service"
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/protocol/didexchange"
+	"github.com/hyperledger/aries-framework-go/pkg/storage"
+)
+
+var logger = log.New("aries-framework/route/service")
+
+const (
+	// Route is the name of the route service, registered with aries.WithProtocols to run the agent as a mediator
+	Route = "route"
+	// RouteVersion is the version of the routing protocol
+	RouteVersion = "1.0"
+	// ForwardMsgType defines the forward message type
+	ForwardMsgType = "https://didcomm.org/routing/1.0/forward"
+	// KeylistUpdateMsgType defines the keylist update message type
+	KeylistUpdateMsgType = "https://didcomm.org/coordinatemediation/1.0/keylist-update"
+	// AddAction adds the recipient key of the keylist update
+	AddAction = "add"
+	// RemoveAction removes the recipient key of the keylist update
+	RemoveAction = "remove"
+)
+
+// ConnectionLookup finds the connection of the sender key of an inbound message, implemented by the
+// did-exchange service
+type ConnectionLookup interface {
+	ConnectionIDByKey(theirVerKey string) (string, error)
+}
+
+type provider interface {
+	StorageProvider() storage.Provider
+	Service(id string) (interface{}, error)
+}
+
+// attachmentsProvider is implemented by the providers offloading the large payloads, see aries.WithAttachmentStore
+type attachmentsProvider interface {
+	Attachments() *attachments.Offloader
+}
+
+// Service queues the forward messages for the recipient keys routed to the connections with the mediator,
+// the connections route their keys with keylist updates
+type Service struct {
+	store       *Store
+	services    provider
+	connections ConnectionLookup
+	lock        sync.Mutex
+}
+
+// New returns the route service, the routing state is kept in the store of the provider (see NewStore) and the
+// large forward messages in the attachment store of the provider, if any.
+// The did-exchange service is looked up when the first keylist update is handled, as it's created after
+// the services registered with aries.WithProtocols.
+func New(prov provider, opts ...Opt) (*Service, error) {
+	if p, ok := prov.(attachmentsProvider); ok && p.Attachments() != nil {
+		opts = append([]Opt{WithAttachments(p.Attachments())}, opts...)
+	}
+
+	store, err := NewStore(prov.StorageProvider(), opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Service{store: store, services: prov}, nil
+}
+
+// Handle handles the forward and keylist update messages
+func (s *Service) Handle(ctx context.Context, msg *service.DIDCommMsg) error {
+	if msg.Outbound {
+		return fmt.Errorf("route service doesn't send %s messages", msg.Type)
+	}
+
+	switch msg.Type {
+	case ForwardMsgType:
+		return s.handleForward(ctx, msg)
+	case KeylistUpdateMsgType:
+		return s.handleKeylistUpdate(ctx, msg)
+	default:
+		return fmt.Errorf("unsupported message type %s", msg.Type)
+	}
+}
+
+// Pickup removes and returns the messages queued for the recipient key routed to the connection,
+// ErrKeyNotRouted is returned if the key isn't routed to the connection
+func (s *Service) Pickup(connectionID, recipientKey string) ([][]byte, error) {
+	return s.store.Dequeue(connectionID, recipientKey)
+}
+
+// Metrics returns the counters of the forward messages handled by the service
+func (s *Service) Metrics() Metrics {
+	return s.store.Metrics()
+}
+
+// Name returns the service name
+func (s *Service) Name() string {
+	return Route
+}
+
+// MessageTypes returns the message types accepted by the service
+func (s *Service) MessageTypes() []string {
+	return []string{ForwardMsgType, KeylistUpdateMsgType}
+}
+
+// Version returns the version of the routing protocol implemented by the service
+func (s *Service) Version() string {
+	return RouteVersion
+}
+
+// Accept msg checks the msg type
+func (s *Service) Accept(msgType string) bool {
+	for _, t := range s.MessageTypes() {
+		if t == msgType {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *Service) handleForward(ctx context.Context, msg *service.DIDCommMsg) error {
+	forward := &Forward{}
+	if err := json.Unmarshal(msg.Payload, forward); err != nil {
+		return fmt.Errorf("failed to unmarshal forward message: %w", err)
+	}
+
+	if forward.To == "" || len(forward.Msg) == 0 {
+		return errors.New("forward message requires the recipient key and the message")
+	}
+
+	if _, err := s.store.ConnectionID(forward.To); err != nil {
+		if errors.Is(err, storage.ErrDataNotFound) {
+			return fmt.Errorf("%w: %s", ErrKeyNotRouted, forward.To)
+		}
+
+		return err
+	}
+
+	if err := s.store.Enqueue(forward.To, forward.Msg); err != nil {
+		return err
+	}
+
+	logger.WithContext(ctx).Debugf("queued forward message for %s", forward.To)
+
+	return nil
+}
+
+func (s *Service) handleKeylistUpdate(ctx context.Context, msg *service.DIDCommMsg) error {
+	update := &KeylistUpdate{}
+	if err := json.Unmarshal(msg.Payload, update); err != nil {
+		return fmt.Errorf("failed to unmarshal keylist update: %w", err)
+	}
+
+	connectionID, err := s.connectionID(msg.Metadata)
+	if err != nil {
+		return err
+	}
+
+	for _, u := range update.Updates {
+		switch u.Action {
+		case AddAction:
+			err = s.store.AddKey(connectionID, u.RecipientKey)
+		case RemoveAction:
+			err = s.store.RemoveKey(connectionID, u.RecipientKey)
+		default:
+			err = fmt.Errorf("unsupported keylist update action %s", u.Action)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	logger.WithContext(ctx).Debugf("updated %d recipient keys of connection %s", len(update.Updates), connectionID)
+
+	return nil
+}
+
+// connectionID returns the connection of the sender of the keylist update, the anonymous updates are rejected
+// as the keys couldn't be routed to a connection
+func (s *Service) connectionID(metadata *service.EnvelopeMetadata) (string, error) {
+	if metadata == nil || metadata.SenderVerKey == "" {
+		return "", errors.New("keylist update requires an authenticated sender")
+	}
+
+	connections, err := s.connectionLookup()
+	if err != nil {
+		return "", err
+	}
+
+	connectionID, err := connections.ConnectionIDByKey(metadata.SenderVerKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to find the connection of the keylist update sender: %w", err)
+	}
+
+	return connectionID, nil
+}
+
+func (s *Service) connectionLookup() (ConnectionLookup, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.connections != nil {
+		return s.connections, nil
+	}
+
+	svc, err := s.services.Service(didexchange.DIDExchange)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up did-exchange service: %w", err)
+	}
+
+	connections, ok := svc.(ConnectionLookup)
+	if !ok {
+		return nil, errors.New("did-exchange service doesn't look up the connections by key")
+	}
+
+	s.connections = connections
+
+	return connections, nil
+}
@@ -0,0 +1,201 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package route
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/attachments"
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/service"
+	mockprovider "github.com/hyperledger/aries-framework-go/pkg/internal/mock/provider"
+	mockstorage "github.com/hyperledger/aries-framework-go/pkg/internal/mock/storage"
+	"github.com/hyperledger/aries-framework-go/pkg/storage"
+)
+
+func TestNew(t *testing.T) {
+	t.Run("test success", func(t *testing.T) {
+		svc, err := New(&mockprovider.Provider{StorageProviderValue: mockstorage.NewMockStoreProvider()})
+		require.NoError(t, err)
+		require.Equal(t, Route, svc.Name())
+		require.Equal(t, RouteVersion, svc.Version())
+		require.True(t, svc.Accept(ForwardMsgType))
+		require.True(t, svc.Accept(KeylistUpdateMsgType))
+		require.False(t, svc.Accept("unsupported"))
+	})
+
+	t.Run("test attachment store of the provider", func(t *testing.T) {
+		objects := newObjectStore()
+		svc, err := New(&offloadingProvider{
+			Provider:    &mockprovider.Provider{StorageProviderValue: mockstorage.NewMockStoreProvider()},
+			attachments: attachments.New(objects, attachments.WithThreshold(1)),
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, svc.store.AddKey("conn1", "key1"))
+		require.NoError(t, svc.store.Enqueue("key1", []byte("message")))
+		require.Len(t, objects.objects, 1)
+	})
+
+	t.Run("test store error", func(t *testing.T) {
+		_, err := New(&mockprovider.Provider{StorageProviderValue: &mockstorage.MockStoreProvider{
+			ErrOpenStoreHandle: errors.New("open error")}})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "open error")
+	})
+}
+
+func TestService_Handle(t *testing.T) {
+	connections := &mockLookup{keys: map[string]string{"sender1": "conn1", "sender2": "conn2"}}
+
+	svc, err := New(&mockprovider.Provider{
+		StorageProviderValue: mockstorage.NewMockStoreProvider(),
+		ServiceValue:         connections,
+	})
+	require.NoError(t, err)
+
+	t.Run("test keylist update and forward", func(t *testing.T) {
+		require.NoError(t, svc.Handle(context.Background(), keylistUpdate(t, "sender1",
+			Update{RecipientKey: "key1", Action: AddAction}, Update{RecipientKey: "key2", Action: AddAction})))
+
+		require.NoError(t, svc.Handle(context.Background(), forward(t, "key1", `{"msg":1}`)))
+
+		messages, err := svc.Pickup("conn1", "key1")
+		require.NoError(t, err)
+		require.Equal(t, [][]byte{[]byte(`{"msg":1}`)}, messages)
+
+		// the keys are only picked up by the connection they are routed to
+		_, err = svc.Pickup("conn2", "key1")
+		require.True(t, errors.Is(err, ErrKeyNotRouted))
+
+		require.NoError(t, svc.Handle(context.Background(), keylistUpdate(t, "sender1",
+			Update{RecipientKey: "key2", Action: RemoveAction})))
+
+		err = svc.Handle(context.Background(), forward(t, "key2", `{"msg":2}`))
+		require.True(t, errors.Is(err, ErrKeyNotRouted))
+		require.Equal(t, Metrics{Enqueued: 1}, svc.Metrics())
+	})
+
+	t.Run("test keys routed to another connection", func(t *testing.T) {
+		require.NoError(t, svc.Handle(context.Background(), keylistUpdate(t, "sender1",
+			Update{RecipientKey: "key3", Action: AddAction})))
+
+		err := svc.Handle(context.Background(), keylistUpdate(t, "sender2",
+			Update{RecipientKey: "key3", Action: AddAction}))
+		require.True(t, errors.Is(err, ErrKeyRouted))
+
+		err = svc.Handle(context.Background(), keylistUpdate(t, "sender2",
+			Update{RecipientKey: "key3", Action: RemoveAction}))
+		require.True(t, errors.Is(err, ErrKeyNotRouted))
+	})
+
+	t.Run("test invalid keylist updates", func(t *testing.T) {
+		msg := keylistUpdate(t, "sender1", Update{RecipientKey: "key4", Action: AddAction})
+		msg.Metadata = nil
+		require.EqualError(t, svc.Handle(context.Background(), msg), "keylist update requires an authenticated sender")
+
+		err := svc.Handle(context.Background(), keylistUpdate(t, "unknown",
+			Update{RecipientKey: "key4", Action: AddAction}))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to find the connection of the keylist update sender")
+
+		err = svc.Handle(context.Background(), keylistUpdate(t, "sender1",
+			Update{RecipientKey: "key4", Action: "invalid"}))
+		require.EqualError(t, err, "unsupported keylist update action invalid")
+
+		err = svc.Handle(context.Background(), &service.DIDCommMsg{Type: KeylistUpdateMsgType,
+			Payload: []byte("invalid")})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to unmarshal keylist update")
+	})
+
+	t.Run("test invalid forward messages", func(t *testing.T) {
+		err := svc.Handle(context.Background(), forward(t, "", `{}`))
+		require.EqualError(t, err, "forward message requires the recipient key and the message")
+
+		err = svc.Handle(context.Background(), &service.DIDCommMsg{Type: ForwardMsgType, Payload: []byte("invalid")})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to unmarshal forward message")
+	})
+
+	t.Run("test unsupported messages", func(t *testing.T) {
+		err := svc.Handle(context.Background(), &service.DIDCommMsg{Type: "unsupported"})
+		require.EqualError(t, err, "unsupported message type unsupported")
+
+		err = svc.Handle(context.Background(), &service.DIDCommMsg{Type: ForwardMsgType, Outbound: true})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "route service doesn't send")
+	})
+}
+
+func TestService_ConnectionLookup(t *testing.T) {
+	t.Run("test did-exchange service error", func(t *testing.T) {
+		svc, err := New(&mockprovider.Provider{
+			StorageProviderValue: mockstorage.NewMockStoreProvider(),
+			ServiceErr:           errors.New("service error"),
+		})
+		require.NoError(t, err)
+
+		err = svc.Handle(context.Background(), keylistUpdate(t, "sender1",
+			Update{RecipientKey: "key1", Action: AddAction}))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "service error")
+	})
+
+	t.Run("test did-exchange service without lookup", func(t *testing.T) {
+		svc, err := New(&mockprovider.Provider{
+			StorageProviderValue: mockstorage.NewMockStoreProvider(),
+			ServiceValue:         struct{}{},
+		})
+		require.NoError(t, err)
+
+		err = svc.Handle(context.Background(), keylistUpdate(t, "sender1",
+			Update{RecipientKey: "key1", Action: AddAction}))
+		require.EqualError(t, err, "did-exchange service doesn't look up the connections by key")
+	})
+}
+
+func keylistUpdate(t *testing.T, sender string, updates ...Update) *service.DIDCommMsg {
+	payload, err := json.Marshal(&KeylistUpdate{Type: KeylistUpdateMsgType, ID: "id", Updates: updates})
+	require.NoError(t, err)
+
+	return &service.DIDCommMsg{Type: KeylistUpdateMsgType, Payload: payload,
+		Metadata: &service.EnvelopeMetadata{SenderVerKey: sender}}
+}
+
+func forward(t *testing.T, to, msg string) *service.DIDCommMsg {
+	payload, err := json.Marshal(&Forward{Type: ForwardMsgType, ID: "id", To: to, Msg: json.RawMessage(msg)})
+	require.NoError(t, err)
+
+	return &service.DIDCommMsg{Type: ForwardMsgType, Payload: payload}
+}
+
+type mockLookup struct {
+	keys map[string]string
+}
+
+func (m *mockLookup) ConnectionIDByKey(theirVerKey string) (string, error) {
+	connectionID, ok := m.keys[theirVerKey]
+	if !ok {
+		return "", storage.ErrDataNotFound
+	}
+
+	return connectionID, nil
+}
+
+type offloadingProvider struct {
+	*mockprovider.Provider
+	attachments *attachments.Offloader
+}
+
+func (p *offloadingProvider) Attachments() *attachments.Offloader {
+	return p.attachments
+}
@@ -0,0 +1,581 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package route
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/attachments"
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/protocol/decorator"
+	"github.com/hyperledger/aries-framework-go/pkg/storage"
+)
+
+// The routing state is kept only in the store, so the mediator replicas sharing the store serve the same
+// traffic. The records are partitioned by key:
+//
+//	keylist_<connectionID>  the recipient keys routed to the connection
+//	route_<recipientKey>    the connection the recipient key is routed to
+//	queue_<recipientKey>    the forward messages queued for the recipient key
+//
+// Every record holds a version and is updated with storage.ConditionalStore, the stores without conditional
+// updates are rejected. The name space must not be cached (see storage/cache) as the replicas update the records.
+const (
+	// Namespace is the store name space of the routing state
+	Namespace = "route"
+
+	keyPattern    = "%s_%s"
+	keylistPrefix = "keylist"
+	routePrefix   = "route"
+	queuePrefix   = "queue"
+
+	// maxUpdateAttempts is the number of attempts to update a record modified concurrently by another replica
+	maxUpdateAttempts = 10
+
+	// offloadedPrefix prefixes the queued entries of the messages offloaded to the object store, followed by the
+	// attachment holding the link and the digest of the message. The forward messages are JSON or compact
+	// envelopes, they never start with the prefix.
+	offloadedPrefix = attachments.LinkScheme
+
+	envelopeMimeType = "application/didcomm-envelope-enc"
+)
+
+var (
+	// ErrKeyRouted is returned when the recipient key is already routed to another connection
+	ErrKeyRouted = errors.New("recipient key is routed to another connection")
+	// ErrKeyNotRouted is returned when the recipient key isn't routed to the requesting connection
+	ErrKeyNotRouted = errors.New("recipient key is not routed to the connection")
+	// ErrTooManyKeys is returned when the connection already routes the maximum number of recipient keys
+	ErrTooManyKeys = errors.New("maximum number of recipient keys of the connection exceeded")
+	// ErrMessageTooLarge is returned when the forward message exceeds the maximum message size
+	ErrMessageTooLarge = errors.New("forward message exceeds the maximum size")
+	// ErrQuotaExceeded is returned when the forward message doesn't fit in the queue quota of the recipient key
+	ErrQuotaExceeded = errors.New("queue quota of the recipient key exceeded")
+)
+
+// EvictionPolicy selects the messages dropped when the queue quota of a recipient key is exceeded
+type EvictionPolicy int
+
+const (
+	// RejectNewest rejects the forward messages exceeding the quota, the queued messages are kept
+	RejectNewest EvictionPolicy = iota
+	// EvictOldest drops the oldest queued messages to make room for the new message
+	EvictOldest
+)
+
+// Metrics are the counters of the forward messages handled by the store instance
+type Metrics struct {
+	// Enqueued is the number of queued messages
+	Enqueued uint64
+	// Rejected is the number of messages rejected as too large or exceeding the quota
+	Rejected uint64
+	// Evicted is the number of queued messages dropped by the eviction policy
+	Evicted uint64
+}
+
+type keylistRecord struct {
+	Version uint64   `json:"version"`
+	Keys    []string `json:"keys,omitempty"`
+}
+
+type routeRecord struct {
+	Version      uint64 `json:"version"`
+	ConnectionID string `json:"connectionID,omitempty"`
+}
+
+type queueRecord struct {
+	Version  uint64   `json:"version"`
+	Messages [][]byte `json:"messages,omitempty"`
+}
+
+// Store keeps the recipient key lists of the connections and the forward messages queued for the recipient keys
+type Store struct {
+	// metrics is accessed atomically, kept first for the 64-bit alignment
+	metrics        Metrics
+	store          storage.Store
+	conditional    storage.ConditionalStore
+	maxKeys        int
+	maxMessageSize int
+	maxMessages    int
+	maxQueueSize   int
+	eviction       EvictionPolicy
+	attachments    *attachments.Offloader
+}
+
+// Opt is a routing store option, used to limit the storage used by the queued messages
+type Opt func(s *Store)
+
+// WithMaxKeys limits the number of recipient keys routed to every connection, zero disables the limit
+func WithMaxKeys(n int) Opt {
+	return func(s *Store) {
+		s.maxKeys = n
+	}
+}
+
+// WithMaxMessageSize rejects the forward messages larger than size bytes
+func WithMaxMessageSize(size int) Opt {
+	return func(s *Store) {
+		s.maxMessageSize = size
+	}
+}
+
+// WithQueueQuota limits the number of messages and the total size in bytes of the messages queued
+// for every recipient key, zero disables the limit
+func WithQueueQuota(maxMessages, maxSize int) Opt {
+	return func(s *Store) {
+		s.maxMessages = maxMessages
+		s.maxQueueSize = maxSize
+	}
+}
+
+// WithEvictionPolicy sets the policy applied when the queue quota of a recipient key is exceeded,
+// the new messages are rejected by default
+func WithEvictionPolicy(policy EvictionPolicy) Opt {
+	return func(s *Store) {
+		s.eviction = policy
+	}
+}
+
+// WithAttachments offloads the forward messages larger than the threshold of the offloader to its object store,
+// the queues keep only their links and digests
+func WithAttachments(o *attachments.Offloader) Opt {
+	return func(s *Store) {
+		s.attachments = o
+	}
+}
+
+// NewStore returns new routing store opened from the storage provider, the store must support
+// conditional updates (see storage.ConditionalStore)
+func NewStore(prov storage.Provider, opts ...Opt) (*Store, error) {
+	store, err := prov.OpenStore(Namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open route store: %w", err)
+	}
+
+	conditional, ok := store.(storage.ConditionalStore)
+	if !ok {
+		return nil, errors.New("route store doesn't support conditional updates")
+	}
+
+	s := &Store{store: store, conditional: conditional}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s, nil
+}
+
+// AddKey routes the messages for the recipient key to the connection. ErrKeyRouted is returned if the key
+// is routed to another connection and ErrTooManyKeys if the connection routes the maximum number of keys.
+func (s *Store) AddKey(connectionID, recipientKey string) error {
+	if connectionID == "" || recipientKey == "" {
+		return errors.New("connection ID and recipient key are mandatory")
+	}
+
+	err := s.update(storeKey(routePrefix, recipientKey), &routeRecord{}, func(v interface{}) (bool, error) {
+		r := v.(*routeRecord)
+		if r.ConnectionID == connectionID {
+			return false, nil
+		}
+
+		if r.ConnectionID != "" {
+			return false, fmt.Errorf("%w: %s", ErrKeyRouted, recipientKey)
+		}
+
+		r.ConnectionID = connectionID
+
+		return true, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = s.update(storeKey(keylistPrefix, connectionID), &keylistRecord{}, func(v interface{}) (bool, error) {
+		r := v.(*keylistRecord)
+		for _, k := range r.Keys {
+			if k == recipientKey {
+				return false, nil
+			}
+		}
+
+		if s.maxKeys > 0 && len(r.Keys) >= s.maxKeys {
+			return false, fmt.Errorf("%w: %s", ErrTooManyKeys, connectionID)
+		}
+
+		r.Keys = append(r.Keys, recipientKey)
+
+		return true, nil
+	})
+	if err != nil {
+		// the key claimed above isn't listed for the connection, release it
+		if e := s.releaseKey(connectionID, recipientKey); e != nil {
+			return fmt.Errorf("%w (release of the key failed: %s)", err, e)
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// RemoveKey stops routing the messages for the recipient key to the connection,
+// ErrKeyNotRouted is returned if the key is routed to another connection
+func (s *Store) RemoveKey(connectionID, recipientKey string) error {
+	if err := s.checkRoute(connectionID, recipientKey); err != nil && !errors.Is(err, storage.ErrDataNotFound) {
+		return err
+	}
+
+	err := s.update(storeKey(keylistPrefix, connectionID), &keylistRecord{}, func(v interface{}) (bool, error) {
+		r := v.(*keylistRecord)
+		for i, k := range r.Keys {
+			if k == recipientKey {
+				r.Keys = append(r.Keys[:i], r.Keys[i+1:]...)
+				return true, nil
+			}
+		}
+
+		return false, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.releaseKey(connectionID, recipientKey)
+}
+
+// releaseKey removes the route of the recipient key if it's routed to the connection
+func (s *Store) releaseKey(connectionID, recipientKey string) error {
+	return s.update(storeKey(routePrefix, recipientKey), &routeRecord{}, func(v interface{}) (bool, error) {
+		r := v.(*routeRecord)
+		if r.ConnectionID != connectionID {
+			return false, nil
+		}
+
+		r.ConnectionID = ""
+
+		return true, nil
+	})
+}
+
+// checkRoute returns ErrKeyNotRouted if the recipient key is routed to another connection,
+// storage.ErrDataNotFound is returned for keys which are not routed
+func (s *Store) checkRoute(connectionID, recipientKey string) error {
+	routed, err := s.ConnectionID(recipientKey)
+	if err != nil {
+		return err
+	}
+
+	if routed != connectionID {
+		return fmt.Errorf("%w: %s", ErrKeyNotRouted, recipientKey)
+	}
+
+	return nil
+}
+
+// Keys returns the recipient keys routed to the connection
+func (s *Store) Keys(connectionID string) ([]string, error) {
+	r := &keylistRecord{}
+	if _, err := s.read(storeKey(keylistPrefix, connectionID), r); err != nil {
+		return nil, err
+	}
+
+	return r.Keys, nil
+}
+
+// ConnectionID returns the connection the recipient key is routed to,
+// storage.ErrDataNotFound is returned for keys which are not routed
+func (s *Store) ConnectionID(recipientKey string) (string, error) {
+	r := &routeRecord{}
+	if _, err := s.read(storeKey(routePrefix, recipientKey), r); err != nil {
+		return "", err
+	}
+
+	if r.ConnectionID == "" {
+		return "", storage.ErrDataNotFound
+	}
+
+	return r.ConnectionID, nil
+}
+
+// Enqueue queues the forward message for the recipient key. The message is rejected if it exceeds the
+// maximum message size, or the queue quota of the recipient key unless the oldest messages are evicted.
+func (s *Store) Enqueue(recipientKey string, msg []byte) error {
+	if s.maxMessageSize > 0 && len(msg) > s.maxMessageSize {
+		atomic.AddUint64(&s.metrics.Rejected, 1)
+		return fmt.Errorf("%w: %d bytes for %s", ErrMessageTooLarge, len(msg), recipientKey)
+	}
+
+	entry, err := s.offload(msg)
+	if err != nil {
+		return err
+	}
+
+	var evicted [][]byte
+
+	err = s.update(storeKey(queuePrefix, recipientKey), &queueRecord{}, func(v interface{}) (bool, error) {
+		r := v.(*queueRecord)
+		r.Messages = append(r.Messages, entry)
+
+		evicted = nil
+
+		for s.quotaExceeded(r.Messages) {
+			if s.eviction != EvictOldest || len(r.Messages) == 1 {
+				return false, fmt.Errorf("%w: %s", ErrQuotaExceeded, recipientKey)
+			}
+
+			evicted = append(evicted, r.Messages[0])
+			r.Messages = r.Messages[1:]
+		}
+
+		return true, nil
+	})
+	if err != nil {
+		if errors.Is(err, ErrQuotaExceeded) {
+			atomic.AddUint64(&s.metrics.Rejected, 1)
+		}
+
+		s.release(entry)
+
+		return err
+	}
+
+	s.release(evicted...)
+
+	atomic.AddUint64(&s.metrics.Enqueued, 1)
+	atomic.AddUint64(&s.metrics.Evicted, uint64(len(evicted)))
+
+	return nil
+}
+
+// offload offloads the message to the object store and returns the queue entry of the message, the message
+// itself if it isn't offloaded
+func (s *Store) offload(msg []byte) ([]byte, error) {
+	if s.attachments == nil {
+		return msg, nil
+	}
+
+	a := &decorator.Attachment{MimeType: envelopeMimeType, Data: decorator.AttachmentData{
+		Base64: base64.RawURLEncoding.EncodeToString(msg),
+	}}
+
+	if err := s.attachments.Offload(a); err != nil {
+		return nil, fmt.Errorf("failed to offload forward message: %w", err)
+	}
+
+	// the messages smaller than the threshold are kept in the queue
+	if a.Data.Base64 != "" {
+		return msg, nil
+	}
+
+	raw, err := json.Marshal(a)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal offloaded forward message: %w", err)
+	}
+
+	return append([]byte(offloadedPrefix), raw...), nil
+}
+
+// load returns the message of the queue entry, read from the object store if offloaded
+func (s *Store) load(entry []byte) ([]byte, error) {
+	a, err := offloadedMessage(entry)
+	if err != nil || a == nil {
+		return entry, err
+	}
+
+	if s.attachments == nil {
+		return nil, errors.New("forward message offloaded without attachment store")
+	}
+
+	return s.attachments.Payload(a)
+}
+
+// release removes the offloaded messages of the queue entries from the object store, the failures are logged
+// as the entries are no longer queued
+func (s *Store) release(entries ...[]byte) {
+	for _, entry := range entries {
+		a, err := offloadedMessage(entry)
+		if err == nil && a != nil && s.attachments != nil {
+			err = s.attachments.Remove(a)
+		}
+
+		if err != nil {
+			logger.Warnf("failed to remove offloaded forward message: %s", err)
+		}
+	}
+}
+
+// offloadedMessage returns the attachment of the offloaded message of the queue entry, nil if the message is
+// queued as is
+func offloadedMessage(entry []byte) (*decorator.Attachment, error) {
+	if !bytes.HasPrefix(entry, []byte(offloadedPrefix)) {
+		return nil, nil
+	}
+
+	a := &decorator.Attachment{}
+	if err := json.Unmarshal(entry[len(offloadedPrefix):], a); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal offloaded forward message: %w", err)
+	}
+
+	return a, nil
+}
+
+// entrySize returns the size of the message of the queue entry, the offloaded messages count toward the quota
+func entrySize(entry []byte) int {
+	if a, err := offloadedMessage(entry); err == nil && a != nil {
+		return int(a.ByteCount)
+	}
+
+	return len(entry)
+}
+
+// Metrics returns the counters of the forward messages handled by the store
+func (s *Store) Metrics() Metrics {
+	return Metrics{
+		Enqueued: atomic.LoadUint64(&s.metrics.Enqueued),
+		Rejected: atomic.LoadUint64(&s.metrics.Rejected),
+		Evicted:  atomic.LoadUint64(&s.metrics.Evicted),
+	}
+}
+
+func (s *Store) quotaExceeded(messages [][]byte) bool {
+	if s.maxMessages > 0 && len(messages) > s.maxMessages {
+		return true
+	}
+
+	if s.maxQueueSize <= 0 {
+		return false
+	}
+
+	size := 0
+	for _, msg := range messages {
+		size += entrySize(msg)
+	}
+
+	return size > s.maxQueueSize
+}
+
+// Dequeue removes and returns the messages queued for the recipient key routed to the connection, the messages
+// are returned to a single replica. ErrKeyNotRouted is returned if the key isn't routed to the connection.
+func (s *Store) Dequeue(connectionID, recipientKey string) ([][]byte, error) {
+	if err := s.checkRoute(connectionID, recipientKey); err != nil {
+		if errors.Is(err, storage.ErrDataNotFound) {
+			return nil, fmt.Errorf("%w: %s", ErrKeyNotRouted, recipientKey)
+		}
+
+		return nil, err
+	}
+
+	var entries, messages [][]byte
+
+	err := s.update(storeKey(queuePrefix, recipientKey), &queueRecord{}, func(v interface{}) (bool, error) {
+		r := v.(*queueRecord)
+		entries = r.Messages
+		messages = make([][]byte, len(entries))
+
+		// the offloaded messages are read before the queue is emptied, the queue is kept if they can't be read
+		for i, entry := range entries {
+			msg, err := s.load(entry)
+			if err != nil {
+				return false, fmt.Errorf("failed to read queued forward message: %w", err)
+			}
+
+			messages[i] = msg
+		}
+
+		r.Messages = nil
+
+		return len(entries) > 0, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.release(entries...)
+
+	if len(messages) == 0 {
+		return nil, nil
+	}
+
+	return messages, nil
+}
+
+// update reads the record into v, applies the modification and stores the record with the next version.
+// The update is retried if the record has been modified concurrently.
+func (s *Store) update(key string, v interface{}, modify func(v interface{}) (bool, error)) error {
+	for i := 0; i < maxUpdateAttempts; i++ {
+		current, err := s.read(key, v)
+		if err != nil && !errors.Is(err, storage.ErrDataNotFound) {
+			return err
+		}
+
+		modified, err := modify(v)
+		if err != nil || !modified {
+			return err
+		}
+
+		nextVersion(v)
+
+		bytes, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("failed to marshal route record: %w", err)
+		}
+
+		err = s.conditional.PutIf(key, bytes, current)
+		if errors.Is(err, storage.ErrConflict) {
+			resetRecord(v)
+			continue
+		}
+
+		return err
+	}
+
+	return fmt.Errorf("failed to update route record %s: %w", key, storage.ErrConflict)
+}
+
+// read reads the record into v and returns the stored bytes
+func (s *Store) read(key string, v interface{}) ([]byte, error) {
+	bytes, err := s.store.Get(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(bytes, v); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal route record: %w", err)
+	}
+
+	return bytes, nil
+}
+
+func nextVersion(v interface{}) {
+	switch r := v.(type) {
+	case *keylistRecord:
+		r.Version++
+	case *routeRecord:
+		r.Version++
+	case *queueRecord:
+		r.Version++
+	}
+}
+
+func resetRecord(v interface{}) {
+	switch r := v.(type) {
+	case *keylistRecord:
+		*r = keylistRecord{}
+	case *routeRecord:
+		*r = routeRecord{}
+	case *queueRecord:
+		*r = queueRecord{}
+	}
+}
+
+func storeKey(prefix, id string) string {
+	return fmt.Sprintf(keyPattern, prefix, id)
+}
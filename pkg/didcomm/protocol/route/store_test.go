@@ -0,0 +1,472 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package route
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/attachments"
+	mockstorage "github.com/hyperledger/aries-framework-go/pkg/internal/mock/storage"
+	"github.com/hyperledger/aries-framework-go/pkg/objectstore"
+	"github.com/hyperledger/aries-framework-go/pkg/storage"
+)
+
+func TestNewStore(t *testing.T) {
+	t.Run("test success", func(t *testing.T) {
+		s, err := NewStore(mockstorage.NewMockStoreProvider())
+		require.NoError(t, err)
+		require.NotNil(t, s)
+	})
+
+	t.Run("test open store error", func(t *testing.T) {
+		_, err := NewStore(&mockstorage.MockStoreProvider{ErrOpenStoreHandle: errors.New("open error")})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "open error")
+	})
+
+	t.Run("test store without conditional updates", func(t *testing.T) {
+		_, err := NewStore(mockstorage.NewMockCustomStoreProvider(
+			&struct{ storage.Store }{Store: &mockstorage.MockStore{Store: make(map[string][]byte)}}))
+		require.EqualError(t, err, "route store doesn't support conditional updates")
+	})
+}
+
+func TestStore_Keys(t *testing.T) {
+	s, err := NewStore(mockstorage.NewMockStoreProvider())
+	require.NoError(t, err)
+
+	t.Run("test add and remove keys", func(t *testing.T) {
+		require.NoError(t, s.AddKey("conn1", "key1"))
+		require.NoError(t, s.AddKey("conn1", "key2"))
+		require.NoError(t, s.AddKey("conn1", "key1"))
+
+		keys, err := s.Keys("conn1")
+		require.NoError(t, err)
+		require.Equal(t, []string{"key1", "key2"}, keys)
+
+		connectionID, err := s.ConnectionID("key2")
+		require.NoError(t, err)
+		require.Equal(t, "conn1", connectionID)
+
+		require.NoError(t, s.RemoveKey("conn1", "key2"))
+		require.NoError(t, s.RemoveKey("conn1", "unknown"))
+
+		keys, err = s.Keys("conn1")
+		require.NoError(t, err)
+		require.Equal(t, []string{"key1"}, keys)
+
+		_, err = s.ConnectionID("key2")
+		require.True(t, errors.Is(err, storage.ErrDataNotFound))
+
+		_, err = s.ConnectionID("unknown")
+		require.True(t, errors.Is(err, storage.ErrDataNotFound))
+	})
+
+	t.Run("test key routed to another connection", func(t *testing.T) {
+		require.NoError(t, s.AddKey("conn2", "key3"))
+
+		err := s.AddKey("conn3", "key3")
+		require.True(t, errors.Is(err, ErrKeyRouted))
+
+		// the key is kept for the connection it is routed to
+		err = s.RemoveKey("conn3", "key3")
+		require.True(t, errors.Is(err, ErrKeyNotRouted))
+
+		connectionID, err := s.ConnectionID("key3")
+		require.NoError(t, err)
+		require.Equal(t, "conn2", connectionID)
+	})
+
+	t.Run("test maximum number of keys", func(t *testing.T) {
+		s, err := NewStore(mockstorage.NewMockStoreProvider(), WithMaxKeys(2))
+		require.NoError(t, err)
+
+		require.NoError(t, s.AddKey("conn1", "key1"))
+		require.NoError(t, s.AddKey("conn1", "key2"))
+		require.NoError(t, s.AddKey("conn1", "key2"))
+
+		err = s.AddKey("conn1", "key3")
+		require.True(t, errors.Is(err, ErrTooManyKeys))
+
+		// the key rejected for the connection can be routed to another connection
+		_, err = s.ConnectionID("key3")
+		require.True(t, errors.Is(err, storage.ErrDataNotFound))
+		require.NoError(t, s.AddKey("conn2", "key3"))
+	})
+
+	t.Run("test mandatory fields", func(t *testing.T) {
+		require.EqualError(t, s.AddKey("", "key1"), "connection ID and recipient key are mandatory")
+		require.EqualError(t, s.AddKey("conn1", ""), "connection ID and recipient key are mandatory")
+	})
+
+	t.Run("test store errors", func(t *testing.T) {
+		store := &mockstorage.MockStore{Store: make(map[string][]byte)}
+		s := &Store{store: store, conditional: store}
+
+		require.NoError(t, store.Put(storeKey(keylistPrefix, "conn1"), []byte("invalid")))
+		require.NoError(t, store.Put(storeKey(routePrefix, "key1"), []byte("invalid")))
+
+		_, err := s.Keys("conn1")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to unmarshal route record")
+
+		_, err = s.ConnectionID("key1")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to unmarshal route record")
+
+		require.Error(t, s.AddKey("conn1", "key1"))
+		require.Error(t, s.RemoveKey("conn1", "key1"))
+
+		_, err = s.Dequeue("conn1", "key1")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to unmarshal route record")
+
+		store.ErrGet = errors.New("get error")
+		require.EqualError(t, s.AddKey("conn1", "key1"), "get error")
+	})
+}
+
+func TestStore_Queue(t *testing.T) {
+	t.Run("test enqueue and dequeue", func(t *testing.T) {
+		s, err := NewStore(mockstorage.NewMockStoreProvider())
+		require.NoError(t, err)
+
+		require.NoError(t, s.AddKey("conn1", "key1"))
+
+		messages, err := s.Dequeue("conn1", "key1")
+		require.NoError(t, err)
+		require.Empty(t, messages)
+
+		require.NoError(t, s.Enqueue("key1", []byte("msg1")))
+		require.NoError(t, s.Enqueue("key1", []byte("msg2")))
+		require.NoError(t, s.Enqueue("key2", []byte("msg3")))
+
+		messages, err = s.Dequeue("conn1", "key1")
+		require.NoError(t, err)
+		require.Equal(t, [][]byte{[]byte("msg1"), []byte("msg2")}, messages)
+
+		messages, err = s.Dequeue("conn1", "key1")
+		require.NoError(t, err)
+		require.Empty(t, messages)
+	})
+
+	t.Run("test dequeue by another connection", func(t *testing.T) {
+		s, err := NewStore(mockstorage.NewMockStoreProvider())
+		require.NoError(t, err)
+
+		require.NoError(t, s.AddKey("conn1", "key1"))
+		require.NoError(t, s.Enqueue("key1", []byte("msg1")))
+
+		_, err = s.Dequeue("conn2", "key1")
+		require.True(t, errors.Is(err, ErrKeyNotRouted))
+
+		_, err = s.Dequeue("conn2", "unknown")
+		require.True(t, errors.Is(err, ErrKeyNotRouted))
+
+		messages, err := s.Dequeue("conn1", "key1")
+		require.NoError(t, err)
+		require.Equal(t, [][]byte{[]byte("msg1")}, messages)
+	})
+
+	t.Run("test replicas sharing the store", func(t *testing.T) {
+		prov := mockstorage.NewMockStoreProvider()
+
+		const replicas = 5
+
+		var wg sync.WaitGroup
+
+		for i := 0; i < replicas; i++ {
+			s, err := NewStore(prov)
+			require.NoError(t, err)
+
+			wg.Add(1)
+
+			go func(i int) {
+				defer wg.Done()
+				require.NoError(t, s.Enqueue("key1", []byte(fmt.Sprintf("msg%d", i))))
+			}(i)
+		}
+
+		wg.Wait()
+
+		s, err := NewStore(prov)
+		require.NoError(t, err)
+
+		require.NoError(t, s.AddKey("conn1", "key1"))
+
+		messages, err := s.Dequeue("conn1", "key1")
+		require.NoError(t, err)
+		require.Len(t, messages, replicas)
+
+		var values []string
+		for _, msg := range messages {
+			values = append(values, string(msg))
+		}
+
+		sort.Strings(values)
+		require.Equal(t, []string{"msg0", "msg1", "msg2", "msg3", "msg4"}, values)
+	})
+
+	t.Run("test record modified by another replica", func(t *testing.T) {
+		store := &conflictingStore{MockStore: &mockstorage.MockStore{Store: make(map[string][]byte)}, conflicts: 1}
+		s := &Store{store: store, conditional: store}
+		replica := &Store{store: store.MockStore, conditional: store.MockStore}
+
+		store.concurrent = func() {
+			require.NoError(t, replica.Enqueue("key1", []byte("msg1")))
+		}
+
+		require.NoError(t, s.Enqueue("key1", []byte("msg2")))
+		require.NoError(t, s.AddKey("conn1", "key1"))
+
+		messages, err := s.Dequeue("conn1", "key1")
+		require.NoError(t, err)
+		require.Equal(t, [][]byte{[]byte("msg1"), []byte("msg2")}, messages)
+	})
+
+	t.Run("test update attempts exceeded", func(t *testing.T) {
+		store := &conflictingStore{MockStore: &mockstorage.MockStore{Store: make(map[string][]byte)},
+			conflicts: maxUpdateAttempts}
+		s := &Store{store: store, conditional: store}
+		replica := &Store{store: store.MockStore, conditional: store.MockStore}
+
+		store.concurrent = func() {
+			require.NoError(t, replica.Enqueue("key1", []byte("msg")))
+		}
+
+		err := s.Enqueue("key1", []byte("msg"))
+		require.True(t, errors.Is(err, storage.ErrConflict))
+	})
+
+	t.Run("test store errors", func(t *testing.T) {
+		store := &mockstorage.MockStore{Store: make(map[string][]byte), ErrPut: errors.New("put error")}
+		s := &Store{store: store, conditional: store}
+
+		require.EqualError(t, s.Enqueue("key1", []byte("msg1")), "put error")
+
+		store.Store[storeKey(routePrefix, "key1")] = []byte(`{"connectionID":"conn1"}`)
+		store.ErrGet = errors.New("get error")
+		_, err := s.Dequeue("conn1", "key1")
+		require.EqualError(t, err, "get error")
+	})
+}
+
+func TestStore_Limits(t *testing.T) {
+	t.Run("test maximum message size", func(t *testing.T) {
+		s, err := NewStore(mockstorage.NewMockStoreProvider(), WithMaxMessageSize(4))
+		require.NoError(t, err)
+
+		require.NoError(t, s.AddKey("conn1", "key1"))
+		require.NoError(t, s.Enqueue("key1", []byte("msg1")))
+
+		err = s.Enqueue("key1", []byte("msg10"))
+		require.True(t, errors.Is(err, ErrMessageTooLarge))
+
+		messages, err := s.Dequeue("conn1", "key1")
+		require.NoError(t, err)
+		require.Equal(t, [][]byte{[]byte("msg1")}, messages)
+		require.Equal(t, Metrics{Enqueued: 1, Rejected: 1}, s.Metrics())
+	})
+
+	t.Run("test newest messages rejected", func(t *testing.T) {
+		s, err := NewStore(mockstorage.NewMockStoreProvider(), WithQueueQuota(2, 0))
+		require.NoError(t, err)
+
+		require.NoError(t, s.AddKey("conn1", "key1"))
+		require.NoError(t, s.Enqueue("key1", []byte("msg1")))
+		require.NoError(t, s.Enqueue("key1", []byte("msg2")))
+
+		err = s.Enqueue("key1", []byte("msg3"))
+		require.True(t, errors.Is(err, ErrQuotaExceeded))
+
+		// the quota is per recipient key
+		require.NoError(t, s.Enqueue("key2", []byte("msg3")))
+
+		messages, err := s.Dequeue("conn1", "key1")
+		require.NoError(t, err)
+		require.Equal(t, [][]byte{[]byte("msg1"), []byte("msg2")}, messages)
+		require.Equal(t, Metrics{Enqueued: 3, Rejected: 1}, s.Metrics())
+	})
+
+	t.Run("test oldest messages evicted", func(t *testing.T) {
+		s, err := NewStore(mockstorage.NewMockStoreProvider(), WithQueueQuota(0, 10), WithEvictionPolicy(EvictOldest))
+		require.NoError(t, err)
+
+		require.NoError(t, s.AddKey("conn1", "key1"))
+		require.NoError(t, s.Enqueue("key1", []byte("msg1")))
+		require.NoError(t, s.Enqueue("key1", []byte("msg2")))
+		require.NoError(t, s.Enqueue("key1", []byte("msg3")))
+
+		// a message exceeding the quota on its own is rejected
+		err = s.Enqueue("key1", []byte("message1234"))
+		require.True(t, errors.Is(err, ErrQuotaExceeded))
+
+		messages, err := s.Dequeue("conn1", "key1")
+		require.NoError(t, err)
+		require.Equal(t, [][]byte{[]byte("msg2"), []byte("msg3")}, messages)
+		require.Equal(t, Metrics{Enqueued: 3, Rejected: 1, Evicted: 1}, s.Metrics())
+	})
+}
+
+func TestStore_Attachments(t *testing.T) {
+	large := []byte(strings.Repeat("large message ", 10))
+
+	t.Run("test large messages offloaded", func(t *testing.T) {
+		objects := newObjectStore()
+		s, err := NewStore(mockstorage.NewMockStoreProvider(),
+			WithAttachments(attachments.New(objects, attachments.WithThreshold(64))))
+		require.NoError(t, err)
+
+		require.NoError(t, s.AddKey("conn1", "key1"))
+		require.NoError(t, s.Enqueue("key1", []byte("msg1")))
+		require.NoError(t, s.Enqueue("key1", large))
+		require.NoError(t, s.Enqueue("key1", large))
+
+		// every queued message owns its object, the same message queued twice is stored twice
+		require.Len(t, objects.objects, 2)
+
+		record, err := s.store.Get(storeKey(queuePrefix, "key1"))
+		require.NoError(t, err)
+		require.NotContains(t, string(record), "large message")
+
+		messages, err := s.Dequeue("conn1", "key1")
+		require.NoError(t, err)
+		require.Equal(t, [][]byte{[]byte("msg1"), large, large}, messages)
+		require.Empty(t, objects.objects)
+	})
+
+	t.Run("test offloaded messages count toward the quota", func(t *testing.T) {
+		objects := newObjectStore()
+		s, err := NewStore(mockstorage.NewMockStoreProvider(), WithQueueQuota(0, 2*len(large)),
+			WithEvictionPolicy(EvictOldest), WithAttachments(attachments.New(objects, attachments.WithThreshold(64))))
+		require.NoError(t, err)
+
+		require.NoError(t, s.AddKey("conn1", "key1"))
+		require.NoError(t, s.Enqueue("key1", large))
+		require.NoError(t, s.Enqueue("key1", large))
+		require.NoError(t, s.Enqueue("key1", large))
+
+		// the object of the evicted message is removed
+		require.Len(t, objects.objects, 2)
+		require.Equal(t, Metrics{Enqueued: 3, Evicted: 1}, s.Metrics())
+
+		// a message exceeding the quota on its own is rejected and its object removed
+		err = s.Enqueue("key1", []byte(strings.Repeat(string(large), 3)))
+		require.True(t, errors.Is(err, ErrQuotaExceeded))
+		require.Len(t, objects.objects, 2)
+	})
+
+	t.Run("test offloaded message not found", func(t *testing.T) {
+		objects := newObjectStore()
+		s, err := NewStore(mockstorage.NewMockStoreProvider(),
+			WithAttachments(attachments.New(objects, attachments.WithThreshold(64))))
+		require.NoError(t, err)
+
+		require.NoError(t, s.AddKey("conn1", "key1"))
+		require.NoError(t, s.Enqueue("key1", large))
+
+		saved := objects.objects
+		objects.objects = make(map[string][]byte)
+
+		_, err = s.Dequeue("conn1", "key1")
+		require.Error(t, err)
+		require.True(t, errors.Is(err, objectstore.ErrNotFound))
+
+		// the queue is kept when the offloaded messages can't be read
+		objects.objects = saved
+
+		messages, err := s.Dequeue("conn1", "key1")
+		require.NoError(t, err)
+		require.Equal(t, [][]byte{large}, messages)
+	})
+
+	t.Run("test offload error", func(t *testing.T) {
+		objects := newObjectStore()
+		objects.err = errors.New("put error")
+
+		s, err := NewStore(mockstorage.NewMockStoreProvider(),
+			WithAttachments(attachments.New(objects, attachments.WithThreshold(64))))
+		require.NoError(t, err)
+
+		err = s.Enqueue("key1", large)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to offload forward message")
+	})
+
+	t.Run("test offloaded message without attachment store", func(t *testing.T) {
+		prov := mockstorage.NewMockStoreProvider()
+
+		s, err := NewStore(prov, WithAttachments(attachments.New(newObjectStore(), attachments.WithThreshold(64))))
+		require.NoError(t, err)
+
+		require.NoError(t, s.AddKey("conn1", "key1"))
+		require.NoError(t, s.Enqueue("key1", large))
+
+		s, err = NewStore(prov)
+		require.NoError(t, err)
+
+		_, err = s.Dequeue("conn1", "key1")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "forward message offloaded without attachment store")
+	})
+}
+
+type objectStore struct {
+	objects map[string][]byte
+	err     error
+}
+
+func newObjectStore() *objectStore {
+	return &objectStore{objects: make(map[string][]byte)}
+}
+
+func (s *objectStore) Put(key string, value []byte) error {
+	if s.err != nil {
+		return s.err
+	}
+
+	s.objects[key] = value
+
+	return nil
+}
+
+func (s *objectStore) Get(key string) ([]byte, error) {
+	v, ok := s.objects[key]
+	if !ok {
+		return nil, objectstore.ErrNotFound
+	}
+
+	return v, nil
+}
+
+func (s *objectStore) Delete(key string) error {
+	delete(s.objects, key)
+	return nil
+}
+
+// conflictingStore modifies the record before the conditional put, as done by a concurrent replica
+type conflictingStore struct {
+	*mockstorage.MockStore
+	conflicts  int
+	concurrent func()
+}
+
+func (s *conflictingStore) PutIf(k string, v, expected []byte) error {
+	if s.conflicts > 0 {
+		s.conflicts--
+		s.concurrent()
+	}
+
+	return s.MockStore.PutIf(k, v, expected)
+}
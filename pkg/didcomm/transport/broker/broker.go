@@ -0,0 +1,65 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package broker implements the DIDComm transports over the message brokers such as AMQP or Kafka, e.g. for
+// the server-to-server credential issuance pipelines. The integrators implement the Publisher and Subscriber
+// SPI with the broker client, the outbound transport maps the destination endpoints to the topics (or queues)
+// and the inbound transport consumes the envelopes of a topic. MemoryBroker is an in-memory reference
+// implementation of the SPI.
+package broker
+
+import (
+	"context"
+
+	"github.com/hyperledger/aries-framework-go/pkg/common/log"
+)
+
+var logger = log.New("aries-framework/transport/broker")
+
+const (
+	// transportName is set on the inbound info of the received messages
+	transportName = "broker"
+
+	// DefaultScheme is the URL scheme of the broker endpoints, e.g. broker://issuance-topic
+	DefaultScheme = "broker"
+
+	// ContentTypeHeader is the header of the messages carrying the media type of the envelope
+	ContentTypeHeader = "content-type"
+
+	// envelopeContentType is the media type of the envelopes published by the outbound transport
+	envelopeContentType = "application/didcomm-envelope-enc"
+)
+
+// Message is a message published on a topic of the broker
+type Message struct {
+	// Topic is the topic (or queue) of the message
+	Topic string
+	// Headers are the headers (or properties) of the message
+	Headers map[string]string
+	// Body is the DIDComm envelope
+	Body []byte
+}
+
+// Publisher publishes the messages to the broker, e.g. a Kafka producer or an AMQP channel
+type Publisher interface {
+	// Publish publishes the message on its topic, the context cancels the publishing
+	Publish(ctx context.Context, msg *Message) error
+}
+
+// Handler handles the messages consumed from a topic, the message is acknowledged unless an error is returned
+type Handler func(msg *Message) error
+
+// Subscriber consumes the messages of the broker, e.g. a Kafka consumer group or an AMQP consumer
+type Subscriber interface {
+	// Subscribe calls the handler with the messages of the topic until the subscription is closed
+	Subscribe(topic string, handler Handler) (Subscription, error)
+}
+
+// Subscription is a subscription to a topic
+type Subscription interface {
+	// Close stops consuming the messages and waits for the messages being handled
+	Close() error
+}
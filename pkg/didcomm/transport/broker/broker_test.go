@@ -0,0 +1,243 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package broker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/transport"
+	"github.com/hyperledger/aries-framework-go/pkg/wallet"
+)
+
+// echoWallet unpacks the envelopes into messages of the same content
+type echoWallet struct {
+	unpackErr error
+}
+
+func (w *echoWallet) PackMessage(envelope *wallet.Envelope) ([]byte, error) {
+	return envelope.Message, nil
+}
+
+func (w *echoWallet) UnpackMessage(encMessage []byte) (*wallet.Envelope, error) {
+	if w.unpackErr != nil {
+		return nil, w.unpackErr
+	}
+
+	return &wallet.Envelope{Message: encMessage}, nil
+}
+
+type mockProvider struct {
+	wallet     wallet.Pack
+	handlerErr error
+	maxSize    int64
+	received   chan *wallet.Envelope
+	noHandler  bool
+}
+
+func newMockProvider() *mockProvider {
+	return &mockProvider{wallet: &echoWallet{}, received: make(chan *wallet.Envelope, 10)}
+}
+
+func (p *mockProvider) InboundMessageHandler() transport.InboundMessageHandler {
+	if p.noHandler {
+		return nil
+	}
+
+	return func(ctx context.Context, envelope *wallet.Envelope) error {
+		info, ok := transport.InboundInfoFromContext(ctx)
+		if !ok || info.Transport != transportName {
+			return errors.New("missing inbound info")
+		}
+
+		if p.handlerErr != nil {
+			return p.handlerErr
+		}
+
+		p.received <- envelope
+
+		return nil
+	}
+}
+
+func (p *mockProvider) PackWallet() wallet.Pack {
+	return p.wallet
+}
+
+func (p *mockProvider) MessageSizeLimits() wallet.SizeLimits {
+	return wallet.SizeLimits{MaxEnvelopeSize: p.maxSize}
+}
+
+type mockPublisher struct {
+	published []*Message
+	err       error
+}
+
+func (p *mockPublisher) Publish(ctx context.Context, msg *Message) error {
+	p.published = append(p.published, msg)
+	return p.err
+}
+
+type mockSubscriber struct {
+	err error
+}
+
+func (s *mockSubscriber) Subscribe(topic string, handler Handler) (Subscription, error) {
+	return nil, s.err
+}
+
+func TestOutbound(t *testing.T) {
+	t.Run("test destination mapped to a topic", func(t *testing.T) {
+		publisher := &mockPublisher{}
+		outbound := NewOutbound(publisher, WithDestinationMapping(map[string]string{
+			"https://issuer.example.com": "issuance",
+		}))
+
+		require.True(t, outbound.Accept("https://issuer.example.com"))
+		require.True(t, outbound.Accept("broker://credentials"))
+		require.False(t, outbound.Accept("https://verifier.example.com"))
+		require.False(t, outbound.Accept("broker://"))
+
+		_, err := outbound.Send(context.Background(), []byte("envelope"), "https://issuer.example.com")
+		require.NoError(t, err)
+
+		_, err = outbound.Send(context.Background(), []byte("envelope"), "broker://credentials")
+		require.NoError(t, err)
+
+		require.Len(t, publisher.published, 2)
+		require.Equal(t, "issuance", publisher.published[0].Topic)
+		require.Equal(t, "credentials", publisher.published[1].Topic)
+		require.Equal(t, []byte("envelope"), publisher.published[0].Body)
+		require.Equal(t, envelopeContentType, publisher.published[0].Headers[ContentTypeHeader])
+	})
+
+	t.Run("test custom scheme", func(t *testing.T) {
+		outbound := NewOutbound(&mockPublisher{}, WithOutboundScheme("kafka"))
+		require.True(t, outbound.Accept("kafka://issuance"))
+		require.False(t, outbound.Accept("broker://issuance"))
+	})
+
+	t.Run("test no topic for destination", func(t *testing.T) {
+		_, err := NewOutbound(&mockPublisher{}).Send(context.Background(), []byte("envelope"), "http://localhost")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "no broker topic")
+	})
+
+	t.Run("test publish error", func(t *testing.T) {
+		outbound := NewOutbound(&mockPublisher{err: errors.New("publish error")})
+		_, err := outbound.Send(context.Background(), []byte("envelope"), "broker://issuance")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "publish error")
+	})
+}
+
+func TestInbound(t *testing.T) {
+	t.Run("test envelopes consumed from the topic", func(t *testing.T) {
+		broker := NewMemoryBroker()
+		inbound := NewInbound(broker, "issuance")
+		require.Equal(t, "broker://issuance", inbound.Endpoint())
+
+		prov := newMockProvider()
+		require.NoError(t, inbound.Start(prov))
+		require.Error(t, inbound.Start(prov))
+
+		outbound := NewOutbound(broker)
+		require.True(t, outbound.Accept(inbound.Endpoint()))
+
+		for i := 0; i < 3; i++ {
+			_, err := outbound.Send(context.Background(), []byte("envelope"), inbound.Endpoint())
+			require.NoError(t, err)
+
+			select {
+			case envelope := <-prov.received:
+				require.Equal(t, "envelope", string(envelope.Message))
+			case <-time.After(time.Second):
+				require.Fail(t, "envelope not received")
+			}
+		}
+
+		require.NoError(t, inbound.Stop())
+		require.NoError(t, inbound.Stop())
+	})
+
+	t.Run("test custom scheme", func(t *testing.T) {
+		inbound := NewInbound(NewMemoryBroker(), "issuance", WithInboundScheme("amqp"))
+		require.Equal(t, "amqp://issuance", inbound.Endpoint())
+	})
+
+	t.Run("test start errors", func(t *testing.T) {
+		inbound := NewInbound(NewMemoryBroker(), "issuance")
+		require.Error(t, inbound.Start(nil))
+		require.Error(t, inbound.Start(&mockProvider{noHandler: true}))
+
+		inbound = NewInbound(&mockSubscriber{err: errors.New("subscribe error")}, "issuance")
+		err := inbound.Start(newMockProvider())
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "subscribe error")
+	})
+}
+
+func TestHandler(t *testing.T) {
+	t.Run("test envelope too large acknowledged", func(t *testing.T) {
+		prov := newMockProvider()
+		prov.maxSize = 4
+
+		require.NoError(t, handler(prov)(&Message{Topic: "issuance", Body: []byte("envelope")}))
+		require.Empty(t, prov.received)
+	})
+
+	t.Run("test envelope not unpacked acknowledged", func(t *testing.T) {
+		prov := newMockProvider()
+		prov.wallet = &echoWallet{unpackErr: errors.New("unpack error")}
+
+		require.NoError(t, handler(prov)(&Message{Topic: "issuance", Body: []byte("envelope")}))
+		require.Empty(t, prov.received)
+	})
+
+	t.Run("test message handler error not acknowledged", func(t *testing.T) {
+		prov := newMockProvider()
+		prov.handlerErr = errors.New("handler error")
+
+		err := handler(prov)(&Message{Topic: "issuance", Body: []byte("envelope")})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "handler error")
+	})
+}
+
+func TestMemoryBroker(t *testing.T) {
+	t.Run("test messages queued until consumed", func(t *testing.T) {
+		broker := NewMemoryBroker(WithQueueSize(1))
+		require.NoError(t, broker.Publish(context.Background(), &Message{Topic: "issuance", Body: []byte("first")}))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		err := broker.Publish(ctx, &Message{Topic: "issuance", Body: []byte("second")})
+		require.True(t, errors.Is(err, context.DeadlineExceeded))
+
+		consumed := make(chan *Message, 1)
+		subscription, err := broker.Subscribe("issuance", func(msg *Message) error {
+			consumed <- msg
+			return errors.New("not acknowledged")
+		})
+		require.NoError(t, err)
+
+		select {
+		case msg := <-consumed:
+			require.Equal(t, "first", string(msg.Body))
+		case <-time.After(time.Second):
+			require.Fail(t, "message not consumed")
+		}
+
+		require.NoError(t, subscription.Close())
+		require.NoError(t, subscription.Close())
+	})
+}
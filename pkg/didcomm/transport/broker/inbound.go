@@ -0,0 +1,132 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package broker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/common/log"
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/transport"
+	"github.com/hyperledger/aries-framework-go/pkg/wallet"
+)
+
+// sizeLimitsProvider is optionally implemented by the inbound provider to limit the size of the envelopes
+type sizeLimitsProvider interface {
+	MessageSizeLimits() wallet.SizeLimits
+}
+
+// InboundOpt is an inbound broker transport option
+type InboundOpt func(i *Inbound)
+
+// WithInboundScheme option sets the URL scheme of the endpoint of the transport, e.g. kafka
+func WithInboundScheme(scheme string) InboundOpt {
+	return func(i *Inbound) {
+		i.scheme = scheme
+	}
+}
+
+// Inbound consumes the envelopes published on a topic of the broker
+type Inbound struct {
+	subscriber   Subscriber
+	topic        string
+	scheme       string
+	subscription Subscription
+	lock         sync.Mutex
+}
+
+// NewInbound creates a new inbound transport consuming the envelopes of the topic
+func NewInbound(subscriber Subscriber, topic string, opts ...InboundOpt) *Inbound {
+	i := &Inbound{subscriber: subscriber, topic: topic, scheme: DefaultScheme}
+
+	for _, opt := range opts {
+		opt(i)
+	}
+
+	return i
+}
+
+// Start subscribes to the topic and handles the envelopes consumed
+func (i *Inbound) Start(prov transport.InboundProvider) error {
+	if prov == nil || prov.InboundMessageHandler() == nil {
+		return errors.New("broker inbound transport start failed: message handler function is nil")
+	}
+
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	if i.subscription != nil {
+		return fmt.Errorf("broker inbound transport already consuming topic %s", i.topic)
+	}
+
+	subscription, err := i.subscriber.Subscribe(i.topic, handler(prov))
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to topic %s: %w", i.topic, err)
+	}
+
+	i.subscription = subscription
+
+	return nil
+}
+
+// handler returns the handler of the envelopes consumed. The envelopes which can't be unpacked are
+// acknowledged as redelivering them won't help, the errors of the message handler are returned so the
+// broker can redeliver the envelope.
+func handler(prov transport.InboundProvider) Handler {
+	var maxSize int64
+	if p, ok := prov.(sizeLimitsProvider); ok {
+		maxSize = p.MessageSizeLimits().MaxEnvelopeSize
+	}
+
+	return func(msg *Message) error {
+		received := time.Now()
+
+		if maxSize > 0 && int64(len(msg.Body)) > maxSize {
+			logger.Errorf("dropping envelope of topic %s: %s", msg.Topic, wallet.ErrEnvelopeTooLarge)
+			return nil
+		}
+
+		unpackMsg, err := prov.PackWallet().UnpackMessage(msg.Body)
+		if err != nil {
+			logger.Errorf("failed to unpack msg: %s", err)
+			return nil
+		}
+
+		ctx := transport.WithInboundInfo(context.Background(), &transport.InboundInfo{Transport: transportName,
+			ReceivedTime: received})
+		ctx = log.NewContext(ctx, log.FieldsFromMessage(unpackMsg.Message))
+
+		if err = prov.InboundMessageHandler()(ctx, unpackMsg); err != nil {
+			return fmt.Errorf("incoming msg processing failed: %w", err)
+		}
+
+		return nil
+	}
+}
+
+// Stop closes the subscription to the topic
+func (i *Inbound) Stop() error {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	if i.subscription == nil {
+		return nil
+	}
+
+	err := i.subscription.Close()
+	i.subscription = nil
+
+	return err
+}
+
+// Endpoint returns the broker endpoint of the topic, e.g. broker://issuance-topic
+func (i *Inbound) Endpoint() string {
+	return i.scheme + "://" + i.topic
+}
@@ -0,0 +1,109 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package broker
+
+import (
+	"context"
+	"sync"
+)
+
+// defaultQueueSize is the number of messages a topic of the memory broker holds until consumed
+const defaultQueueSize = 100
+
+// MemoryBrokerOpt is a memory broker option
+type MemoryBrokerOpt func(b *MemoryBroker)
+
+// WithQueueSize option sets the number of messages a topic holds until consumed, the publishers
+// wait once the topic is full
+func WithQueueSize(size int) MemoryBrokerOpt {
+	return func(b *MemoryBroker) {
+		b.queueSize = size
+	}
+}
+
+// MemoryBroker is an in-memory broker, the messages of a topic are queued and consumed by one of the
+// subscribers of the topic. The messages not acknowledged by the handlers are dropped.
+type MemoryBroker struct {
+	queueSize int
+	topics    map[string]chan *Message
+	lock      sync.Mutex
+}
+
+// NewMemoryBroker creates a new in-memory broker
+func NewMemoryBroker(opts ...MemoryBrokerOpt) *MemoryBroker {
+	b := &MemoryBroker{queueSize: defaultQueueSize, topics: make(map[string]chan *Message)}
+
+	for _, opt := range opts {
+		opt(b)
+	}
+
+	return b
+}
+
+// Publish queues the message on its topic
+func (b *MemoryBroker) Publish(ctx context.Context, msg *Message) error {
+	select {
+	case b.queue(msg.Topic) <- msg:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Subscribe consumes the messages of the topic with the handler until the subscription is closed
+func (b *MemoryBroker) Subscribe(topic string, handler Handler) (Subscription, error) {
+	s := &memSubscription{done: make(chan struct{})}
+	queue := b.queue(topic)
+
+	s.wg.Add(1)
+
+	go func() {
+		defer s.wg.Done()
+
+		for {
+			select {
+			case msg := <-queue:
+				if err := handler(msg); err != nil {
+					logger.Warnf("message of topic %s not acknowledged: %s", topic, err)
+				}
+			case <-s.done:
+				return
+			}
+		}
+	}()
+
+	return s, nil
+}
+
+func (b *MemoryBroker) queue(topic string) chan *Message {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	queue, ok := b.topics[topic]
+	if !ok {
+		queue = make(chan *Message, b.queueSize)
+		b.topics[topic] = queue
+	}
+
+	return queue
+}
+
+type memSubscription struct {
+	done chan struct{}
+	once sync.Once
+	wg   sync.WaitGroup
+}
+
+func (s *memSubscription) Close() error {
+	s.once.Do(func() {
+		close(s.done)
+	})
+
+	s.wg.Wait()
+
+	return nil
+}
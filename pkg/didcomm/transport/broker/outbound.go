@@ -0,0 +1,93 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package broker
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// OutboundOpt is an outbound broker transport option
+type OutboundOpt func(o *Outbound)
+
+// WithOutboundScheme option sets the URL scheme of the endpoints accepted by the transport, e.g. kafka
+func WithOutboundScheme(scheme string) OutboundOpt {
+	return func(o *Outbound) {
+		o.scheme = scheme
+	}
+}
+
+// WithDestinationMapping option maps the destination endpoints to the topics of the broker, e.g. the
+// https endpoints of the back-office agents to their issuance topic. The mapped endpoints are accepted
+// by the transport whatever their scheme.
+func WithDestinationMapping(mapping map[string]string) OutboundOpt {
+	return func(o *Outbound) {
+		for endpoint, topic := range mapping {
+			o.topics[endpoint] = topic
+		}
+	}
+}
+
+// Outbound publishes the envelopes on the topics of the broker
+type Outbound struct {
+	publisher Publisher
+	scheme    string
+	topics    map[string]string
+}
+
+// NewOutbound creates a new outbound transport publishing the envelopes with the publisher
+func NewOutbound(publisher Publisher, opts ...OutboundOpt) *Outbound {
+	o := &Outbound{publisher: publisher, scheme: DefaultScheme, topics: make(map[string]string)}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// Send publishes the envelope on the topic of the destination
+func (o *Outbound) Send(ctx context.Context, data []byte, destination string) (string, error) {
+	topic, ok := o.topic(destination)
+	if !ok {
+		return "", fmt.Errorf("no broker topic for destination %s", destination)
+	}
+
+	msg := &Message{
+		Topic:   topic,
+		Headers: map[string]string{ContentTypeHeader: envelopeContentType},
+		Body:    data,
+	}
+
+	if err := o.publisher.Publish(ctx, msg); err != nil {
+		return "", fmt.Errorf("failed to publish envelope on topic %s: %w", topic, err)
+	}
+
+	return "", nil
+}
+
+// Accept accepts the endpoints of the scheme of the transport and the mapped endpoints
+func (o *Outbound) Accept(url string) bool {
+	_, ok := o.topic(url)
+	return ok
+}
+
+// topic returns the topic of the destination, the mapped topic or the topic of the endpoint,
+// e.g. issuance for broker://issuance
+func (o *Outbound) topic(destination string) (string, bool) {
+	if topic, ok := o.topics[destination]; ok {
+		return topic, true
+	}
+
+	prefix := o.scheme + "://"
+	if !strings.HasPrefix(destination, prefix) || len(destination) == len(prefix) {
+		return "", false
+	}
+
+	return strings.TrimPrefix(destination, prefix), true
+}
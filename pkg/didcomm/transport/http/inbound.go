@@ -8,25 +8,47 @@ package http
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/hyperledger/aries-framework-go/pkg/wallet"
 
 	"github.com/hyperledger/aries-framework-go/pkg/common/log"
+	"github.com/hyperledger/aries-framework-go/pkg/common/metrics"
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/model"
 	"github.com/hyperledger/aries-framework-go/pkg/didcomm/transport"
 )
 
 var logger = log.New("aries-framework/transport")
 
+// transportName is set on the inbound info of the received messages
+const transportName = "http"
+
 // provider contains dependencies for the HTTP Handler creation and is typically created by using aries.Context()
 type provider interface {
 	InboundMessageHandler() transport.InboundMessageHandler
 	PackWallet() wallet.Pack
 }
 
+// sizeLimitsProvider is optionally implemented by the provider to limit the size of the inbound envelopes
+type sizeLimitsProvider interface {
+	MessageSizeLimits() wallet.SizeLimits
+}
+
+// metricsProvider is optionally implemented by the provider to record the metrics of the received envelopes
+type metricsProvider interface {
+	MetricsProvider() metrics.Provider
+}
+
 // NewInboundHandler will create a new handler to enforce Did-Comm HTTP transport specs
 // then routes processing to the mandatory 'msgHandler' argument.
 //
@@ -39,12 +61,103 @@ func NewInboundHandler(prov provider) (http.Handler, error) {
 		return nil, errors.New("creation of inbound handler failed")
 	}
 
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		processPOSTRequest(w, r, prov)
+	return observed(prov, func(w http.ResponseWriter, r *http.Request) {
+		processPOSTRequest(w, r, prov, "")
 	}), nil
 }
 
-func processPOSTRequest(w http.ResponseWriter, r *http.Request, prov transport.InboundProvider) {
+// NewProfileInboundHandler creates a handler serving the profiles of a multi-profile deployment on the paths
+// made of the prefix and the profile, e.g. /agent/{profile}. The envelopes are tagged with the profile in the
+// inbound info and handled by the provider of the profile if the provider implements transport.ProfileProvider.
+func NewProfileInboundHandler(prov provider, pathPrefix string) (http.Handler, error) {
+	if prov == nil || prov.InboundMessageHandler() == nil {
+		logger.Errorf("Error creating a new inbound handler: message handler function is nil")
+		return nil, errors.New("creation of inbound handler failed")
+	}
+
+	return observed(prov, func(w http.ResponseWriter, r *http.Request) {
+		profile := strings.TrimPrefix(r.URL.Path, pathPrefix)
+		if !strings.HasPrefix(r.URL.Path, pathPrefix) || profile == "" || strings.Contains(profile, "/") {
+			http.NotFound(w, r)
+			return
+		}
+
+		profileProv, err := profileInboundProvider(prov, profile)
+		if errors.Is(err, transport.ErrProfileNotFound) {
+			http.NotFound(w, r)
+			return
+		}
+
+		if err != nil {
+			logger.Errorf("failed to get provider of profile %s: %s", profile, err)
+			http.Error(w, "failed to get profile", http.StatusInternalServerError)
+
+			return
+		}
+
+		processPOSTRequest(w, r, profileProv, profile)
+	}), nil
+}
+
+// observed records the envelopes received by the handler in the metrics of the provider, if configured
+func observed(prov transport.InboundProvider, handler http.HandlerFunc) http.Handler {
+	p, ok := prov.(metricsProvider)
+	if !ok || p.MetricsProvider() == nil {
+		return handler
+	}
+
+	m := p.MetricsProvider()
+	transportLabel := metrics.Label{Name: "transport", Value: transportName}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		body := &countingReader{ReadCloser: r.Body}
+		r.Body = body
+
+		handler(sw, r)
+
+		m.Counter(metrics.TransportEnvelopes, transportLabel,
+			metrics.Label{Name: "status", Value: strconv.Itoa(sw.status)}).Add(1)
+		m.Histogram(metrics.TransportEnvelopeSize, transportLabel).Observe(float64(body.n))
+	})
+}
+
+// statusWriter records the status code of the response
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// countingReader counts the bytes read from the request body
+type countingReader struct {
+	io.ReadCloser
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.n += int64(n)
+
+	return n, err
+}
+
+// profileInboundProvider returns the provider of the profile, the provider itself if it doesn't serve profiles
+func profileInboundProvider(prov transport.InboundProvider, profile string) (transport.InboundProvider, error) {
+	if p, ok := prov.(transport.ProfileProvider); ok {
+		return p.ProfileInboundProvider(profile)
+	}
+
+	return prov, nil
+}
+
+func processPOSTRequest(w http.ResponseWriter, r *http.Request, prov transport.InboundProvider, profile string) {
+	received := time.Now()
+
 	if valid := validateHTTPMethod(w, r); !valid {
 		return
 	}
@@ -52,30 +165,106 @@ func processPOSTRequest(w http.ResponseWriter, r *http.Request, prov transport.I
 		return
 	}
 
-	body, err := ioutil.ReadAll(r.Body)
+	maxSize := maxEnvelopeSize(prov)
+	if maxSize > 0 && r.ContentLength > maxSize {
+		http.Error(w, wallet.ErrEnvelopeTooLarge.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	body, err := readBody(r, maxSize)
 	if err != nil {
+		if errors.Is(err, wallet.ErrEnvelopeTooLarge) {
+			http.Error(w, wallet.ErrEnvelopeTooLarge.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+
 		logger.Errorf("Error reading request body: %s - returning Code: %d", err, http.StatusInternalServerError)
 		http.Error(w, "Failed to read payload", http.StatusInternalServerError)
 		return
 	}
 	unpackMsg, err := prov.PackWallet().UnpackMessage(body)
 	if err != nil {
+		if errors.Is(err, wallet.ErrEnvelopeTooLarge) || errors.Is(err, wallet.ErrAttachmentTooLarge) {
+			logger.Warnf("rejected msg: %s - returning Code: %d", err, http.StatusRequestEntityTooLarge)
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+
 		logger.Errorf("failed to unpack msg: %s - returning Code: %d", err, http.StatusInternalServerError)
 		http.Error(w, "failed to unpack msg", http.StatusInternalServerError)
 		return
 	}
 
 	messageHandler := prov.InboundMessageHandler()
-	err = messageHandler(unpackMsg)
+	ctx := transport.WithInboundInfo(r.Context(), &transport.InboundInfo{Transport: transportName,
+		ReceivedTime: received, Profile: profile})
+	ctx = log.NewContext(ctx, log.FieldsFromMessage(unpackMsg.Message))
+
+	err = messageHandler(ctx, unpackMsg)
+
+	var rejected *model.ProblemReportError
+	if errors.As(err, &rejected) {
+		logger.WithContext(ctx).Warnf("rejected msg: %s - returning Code: %d", err, http.StatusForbidden)
+		writeProblemReport(w, rejected.Report)
+
+		return
+	}
+
 	if err != nil {
 		// TODO HTTP Response Codes based on errors from service https://github.com/hyperledger/aries-framework-go/issues/271
-		logger.Errorf("incoming msg processing failed: %s", err)
+		logger.WithContext(ctx).Errorf("incoming msg processing failed: %s", err)
 		w.WriteHeader(http.StatusInternalServerError)
 	} else {
 		w.WriteHeader(http.StatusAccepted)
 	}
 }
 
+// writeProblemReport returns the problem report explaining the rejection of the message to the sender
+func writeProblemReport(w http.ResponseWriter, report *model.ProblemReport) {
+	bytes, err := json.Marshal(report)
+	if err != nil {
+		logger.Errorf("failed to marshal problem report: %s", err)
+		w.WriteHeader(http.StatusForbidden)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusForbidden)
+
+	if _, err := w.Write(bytes); err != nil {
+		logger.Errorf("failed to write problem report: %s", err)
+	}
+}
+
+// maxEnvelopeSize returns the maximum inbound envelope size configured by the provider, zero if not limited
+func maxEnvelopeSize(prov transport.InboundProvider) int64 {
+	if p, ok := prov.(sizeLimitsProvider); ok {
+		return p.MessageSizeLimits().MaxEnvelopeSize
+	}
+
+	return 0
+}
+
+// readBody reads the request body up to the maximum size, the body is not limited if maxSize is zero
+func readBody(r *http.Request, maxSize int64) ([]byte, error) {
+	if maxSize <= 0 {
+		return ioutil.ReadAll(r.Body)
+	}
+
+	// read one more byte to detect the body exceeding the limit
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(body)) > maxSize {
+		return nil, wallet.ErrEnvelopeTooLarge
+	}
+
+	return body, nil
+}
+
 // validatePayload validate and get the payload from the request
 func validatePayload(r *http.Request, w http.ResponseWriter) bool {
 	if r.ContentLength == 0 { // empty payload should not be accepted
@@ -101,50 +290,230 @@ func validateHTTPMethod(w http.ResponseWriter, r *http.Request) bool {
 	return true
 }
 
+// Paths of the health check endpoints served on the admin address.
+const (
+	DefaultHealthPath = "/healthz"
+	DefaultReadyPath  = "/readyz"
+)
+
 // Inbound http type.
 type Inbound struct {
-	server *http.Server
+	addr        string
+	server      *http.Server
+	port        string
+	profilePath string
+	adminAddr   string
+	adminServer *http.Server
+	adminPort   string
+	lock        sync.RWMutex
+}
+
+// InboundOpt is a HTTP inbound transport option
+type InboundOpt func(i *Inbound)
+
+// WithProfilePath serves the profiles of a multi-profile deployment on the paths made of the prefix and
+// the profile, e.g. /agent/{profile}, instead of serving a single agent on every path
+func WithProfilePath(pathPrefix string) InboundOpt {
+	return func(i *Inbound) {
+		i.profilePath = pathPrefix
+	}
+}
+
+// WithHealthCheck serves the health check endpoints for the orchestrators probing the agent on a separate
+// admin address, e.g. localhost:8081, the endpoints aren't served by default. The health endpoint
+// (DefaultHealthPath) responds 200 OK while none of the components of the agent is failing, the ready endpoint
+// (DefaultReadyPath) once the agent has started in addition. The endpoints respond 503 Service Unavailable
+// otherwise, without body.
+func WithHealthCheck(adminAddr string) InboundOpt {
+	return func(i *Inbound) {
+		i.adminAddr = adminAddr
+	}
 }
 
 // NewInbound creates a new HTTP inbound transport instance.
-func NewInbound(addr string) (*Inbound, error) {
+func NewInbound(addr string, opts ...InboundOpt) (*Inbound, error) {
 	if addr == "" {
 		return nil, errors.New("http address is mandatory")
 	}
 
-	return &Inbound{server: &http.Server{Addr: addr}}, nil
+	i := &Inbound{addr: addr}
+
+	for _, opt := range opts {
+		opt(i)
+	}
+
+	return i, nil
 }
 
-// Start the http server.
+// Start the http server, the transport can be started again once stopped.
 func (i *Inbound) Start(prov transport.InboundProvider) error {
-	handler, err := NewInboundHandler(prov)
+	var (
+		handler http.Handler
+		err     error
+	)
+
+	if i.profilePath != "" {
+		handler, err = NewProfileInboundHandler(prov, i.profilePath)
+	} else {
+		handler, err = NewInboundHandler(prov)
+	}
+
 	if err != nil {
 		return fmt.Errorf("HTTP server start failed: %w", err)
 	}
 
-	i.server.Handler = handler
+	server, port, err := serve(i.addr, handler)
+	if err != nil {
+		return fmt.Errorf("HTTP server start failed: %w", err)
+	}
+
+	i.lock.Lock()
+	i.server = server
+	i.port = port
+	i.lock.Unlock()
+
+	if i.adminAddr == "" {
+		return nil
+	}
+
+	adminServer, adminPort, err := serve(i.adminAddr, healthCheckHandler(prov))
+	if err != nil {
+		if e := server.Shutdown(context.Background()); e != nil {
+			logger.Errorf("HTTP server shutdown failed: %s", e)
+		}
+
+		return fmt.Errorf("HTTP admin server start failed: %w", err)
+	}
+
+	i.lock.Lock()
+	i.adminServer = adminServer
+	i.adminPort = adminPort
+	i.lock.Unlock()
+
+	return nil
+}
+
+// serve serves the handler on the address, the port is the port the server listens on
+func serve(addr string, handler http.Handler) (*http.Server, string, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, "", err
+	}
+
+	_, port, err := net.SplitHostPort(listener.Addr().String())
+	if err != nil {
+		return nil, "", err
+	}
+
+	server := &http.Server{Addr: addr, Handler: handler}
 
 	go func() {
-		if err := i.server.ListenAndServe(); err != http.ErrServerClosed {
+		if err := server.Serve(listener); err != http.ErrServerClosed {
 			// TODO add panic msg
-			logger.Fatalf("HTTP server start with address [%s] failed, cause:  %s", i.server.Addr, err)
+			logger.Fatalf("HTTP server start with address [%s] failed, cause:  %s", addr, err)
 		}
 	}()
 
-	return nil
+	return server, port, nil
+}
+
+// healthCheckHandler serves the health check endpoints of the admin address
+func healthCheckHandler(prov transport.InboundProvider) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc(DefaultHealthPath, func(w http.ResponseWriter, r *http.Request) {
+		writeStatus(w, r, agentStatus(prov), false)
+	})
+	mux.HandleFunc(DefaultReadyPath, func(w http.ResponseWriter, r *http.Request) {
+		writeStatus(w, r, agentStatus(prov), true)
+	})
+
+	return mux
 }
 
-// Stop the http server.
+// agentStatus returns the status reported by the provider, the agent is ready and healthy if the provider
+// doesn't report its status
+func agentStatus(prov transport.InboundProvider) transport.Status {
+	if p, ok := prov.(transport.StatusProvider); ok {
+		return p.Status()
+	}
+
+	return transport.Status{Ready: true}
+}
+
+// writeStatus responds with the status code of the agent, 200 OK if the agent is healthy (and ready if required)
+func writeStatus(w http.ResponseWriter, r *http.Request, status transport.Status, ready bool) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "HTTP Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !status.Healthy() || (ready && !status.Ready) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// Stop the http server and the admin server.
 func (i *Inbound) Stop() error {
-	if err := i.server.Shutdown(context.Background()); err != nil {
+	i.lock.RLock()
+	server, adminServer := i.server, i.adminServer
+	i.lock.RUnlock()
+
+	if adminServer != nil {
+		if err := adminServer.Shutdown(context.Background()); err != nil {
+			return fmt.Errorf("HTTP admin server shutdown failed: %w", err)
+		}
+	}
+
+	if server == nil {
+		return nil
+	}
+
+	if err := server.Shutdown(context.Background()); err != nil {
 		return fmt.Errorf("HTTP server shutdown failed: %w", err)
 	}
 
 	return nil
 }
 
-// Endpoint provides the http connection details.
+// AdminEndpoint provides the http connection details of the health check endpoints, empty if not served.
+func (i *Inbound) AdminEndpoint() string {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+
+	if i.adminAddr == "" {
+		return ""
+	}
+
+	if host, _, err := net.SplitHostPort(i.adminAddr); err == nil && i.adminPort != "" {
+		return "http://" + net.JoinHostPort(host, i.adminPort)
+	}
+
+	return "http://" + i.adminAddr
+}
+
+// Endpoint provides the http connection details. The port is the port the server listens on once started,
+// e.g. the port picked by the system for the addresses with the port 0.
 func (i *Inbound) Endpoint() string {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+
 	// return http prefix as framework only supports http
-	return "http://" + i.server.Addr
+	if host, _, err := net.SplitHostPort(i.addr); err == nil && i.port != "" {
+		return "http://" + net.JoinHostPort(host, i.port)
+	}
+
+	return "http://" + i.addr
+}
+
+// ProfileEndpoint provides the http connection details of the profile, the endpoint if profiles aren't served.
+func (i *Inbound) ProfileEndpoint(profile string) string {
+	if i.profilePath == "" {
+		return i.Endpoint()
+	}
+
+	return i.Endpoint() + i.profilePath + profile
 }
@@ -8,18 +8,27 @@ package http
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net"
 	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/hyperledger/aries-framework-go/pkg/common/metrics"
+	memmetrics "github.com/hyperledger/aries-framework-go/pkg/common/metrics/mem"
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/model"
 	"github.com/hyperledger/aries-framework-go/pkg/didcomm/transport"
 	mockwallet "github.com/hyperledger/aries-framework-go/pkg/internal/mock/wallet"
 	"github.com/hyperledger/aries-framework-go/pkg/wallet"
@@ -30,7 +39,7 @@ type mockProvider struct {
 }
 
 func (p *mockProvider) InboundMessageHandler() transport.InboundMessageHandler {
-	return func(envelope *wallet.Envelope) error {
+	return func(ctx context.Context, envelope *wallet.Envelope) error {
 		logger.Debugf("Envelope received is %s", envelope)
 		return nil
 	}
@@ -158,6 +167,45 @@ func TestInboundTransport(t *testing.T) {
 		require.Contains(t, err.Error(), "http address is mandatory")
 	})
 
+	t.Run("test inbound transport - restart on the port picked by the system", func(t *testing.T) {
+		inbound, err := NewInbound("localhost:0")
+		require.NoError(t, err)
+		require.Equal(t, "http://localhost:0", inbound.Endpoint())
+		require.NoError(t, inbound.Stop())
+
+		packWalletValue := &mockwallet.CloseableWallet{UnpackValue: &wallet.Envelope{Message: []byte("data")}}
+
+		for i := 0; i < 2; i++ {
+			err = inbound.Start(&mockProvider{packWalletValue: packWalletValue})
+			require.NoError(t, err)
+			require.NotEqual(t, "http://localhost:0", inbound.Endpoint())
+
+			resp, e := http.Post(inbound.Endpoint(), commContentType, bytes.NewBuffer([]byte("success")))
+			require.NoError(t, e)
+			require.Equal(t, http.StatusAccepted, resp.StatusCode)
+			require.NoError(t, resp.Body.Close())
+
+			require.NoError(t, inbound.Stop())
+		}
+	})
+
+	t.Run("test inbound transport - address in use", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "localhost:0")
+		require.NoError(t, err)
+
+		defer func() {
+			require.NoError(t, listener.Close())
+		}()
+
+		inbound, err := NewInbound(listener.Addr().String())
+		require.NoError(t, err)
+
+		packWalletValue := &mockwallet.CloseableWallet{UnpackValue: &wallet.Envelope{Message: []byte("data")}}
+		err = inbound.Start(&mockProvider{packWalletValue: packWalletValue})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "HTTP server start failed")
+	})
+
 	t.Run("test inbound transport - invoke endpoint", func(t *testing.T) {
 		// initiate inbound with port
 		inbound, err := NewInbound(":26604")
@@ -189,6 +237,108 @@ func TestInboundTransport(t *testing.T) {
 	})
 }
 
+type mockStatusProvider struct {
+	mockProvider
+	status transport.Status
+	lock   sync.Mutex
+}
+
+func (p *mockStatusProvider) Status() transport.Status {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return p.status
+}
+
+func (p *mockStatusProvider) setStatus(status transport.Status) {
+	p.lock.Lock()
+	p.status = status
+	p.lock.Unlock()
+}
+
+func TestInboundTransportHealthCheck(t *testing.T) {
+	get := func(url string) int {
+		resp, err := http.Get(url) // nolint: gosec
+		require.NoError(t, err)
+
+		defer func() { require.NoError(t, resp.Body.Close()) }()
+
+		body, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.Empty(t, body)
+
+		return resp.StatusCode
+	}
+
+	packWalletValue := &mockwallet.CloseableWallet{UnpackValue: &wallet.Envelope{Message: []byte("data")}}
+
+	t.Run("test health and readiness of the agent", func(t *testing.T) {
+		inbound, err := NewInbound("localhost:0", WithHealthCheck("localhost:0"))
+		require.NoError(t, err)
+
+		prov := &mockStatusProvider{mockProvider: mockProvider{packWalletValue: packWalletValue}}
+		require.NoError(t, inbound.Start(prov))
+
+		defer func() { require.NoError(t, inbound.Stop()) }()
+
+		require.NotEqual(t, inbound.Endpoint(), inbound.AdminEndpoint())
+		require.Equal(t, http.StatusOK, get(inbound.AdminEndpoint()+DefaultHealthPath))
+		require.Equal(t, http.StatusServiceUnavailable, get(inbound.AdminEndpoint()+DefaultReadyPath))
+
+		prov.setStatus(transport.Status{Ready: true})
+		require.Equal(t, http.StatusOK, get(inbound.AdminEndpoint()+DefaultReadyPath))
+
+		prov.setStatus(transport.Status{Ready: true, Checks: map[string]string{"storage": "unavailable"}})
+		require.Equal(t, http.StatusServiceUnavailable, get(inbound.AdminEndpoint()+DefaultHealthPath))
+		require.Equal(t, http.StatusServiceUnavailable, get(inbound.AdminEndpoint()+DefaultReadyPath))
+
+		resp, err := http.Post(inbound.AdminEndpoint()+DefaultHealthPath, commContentType, bytes.NewBufferString("data"))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+		require.NoError(t, resp.Body.Close())
+
+		// the inbound address doesn't serve the health check endpoints
+		resp, err = http.Get(inbound.Endpoint() + DefaultHealthPath)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+		require.NoError(t, resp.Body.Close())
+
+		resp, err = http.Post(inbound.Endpoint(), commContentType, bytes.NewBufferString("data"))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusAccepted, resp.StatusCode)
+		require.NoError(t, resp.Body.Close())
+	})
+
+	t.Run("test agent without status", func(t *testing.T) {
+		inbound, err := NewInbound("localhost:0", WithHealthCheck("localhost:0"))
+		require.NoError(t, err)
+		require.NoError(t, inbound.Start(&mockProvider{packWalletValue: packWalletValue}))
+
+		defer func() { require.NoError(t, inbound.Stop()) }()
+
+		require.Equal(t, http.StatusOK, get(inbound.AdminEndpoint()+DefaultReadyPath))
+	})
+
+	t.Run("test health check disabled by default", func(t *testing.T) {
+		inbound, err := NewInbound("localhost:0")
+		require.NoError(t, err)
+		require.NoError(t, inbound.Start(&mockProvider{packWalletValue: packWalletValue}))
+
+		defer func() { require.NoError(t, inbound.Stop()) }()
+
+		require.Empty(t, inbound.AdminEndpoint())
+	})
+
+	t.Run("test admin address failure", func(t *testing.T) {
+		inbound, err := NewInbound("localhost:0", WithHealthCheck("invalid-address"))
+		require.NoError(t, err)
+
+		err = inbound.Start(&mockProvider{packWalletValue: packWalletValue})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "HTTP admin server start failed")
+	})
+}
+
 func listenFor(host string, d time.Duration) error {
 	timeout := time.After(d)
 	for {
@@ -207,3 +357,226 @@ func listenFor(host string, d time.Duration) error {
 		}
 	}
 }
+
+type mockSizeLimitsProvider struct {
+	mockProvider
+	limits wallet.SizeLimits
+}
+
+func (p *mockSizeLimitsProvider) MessageSizeLimits() wallet.SizeLimits {
+	return p.limits
+}
+
+func TestInboundHandlerSizeLimits(t *testing.T) {
+	mockWallet := &mockwallet.CloseableWallet{UnpackValue: &wallet.Envelope{Message: []byte("data")}}
+	inHandler, err := NewInboundHandler(&mockSizeLimitsProvider{mockProvider: mockProvider{packWalletValue: mockWallet},
+		limits: wallet.SizeLimits{MaxEnvelopeSize: 10}})
+	require.NoError(t, err)
+
+	post := func(body io.Reader) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/", body)
+		req.Header.Set("Content-type", commContentType)
+		rec := httptest.NewRecorder()
+		inHandler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("test envelope within limit", func(t *testing.T) {
+		require.Equal(t, http.StatusAccepted, post(bytes.NewBufferString("0123456789")).Code)
+	})
+
+	t.Run("test envelope exceeding limit", func(t *testing.T) {
+		rec := post(bytes.NewBufferString("0123456789a"))
+		require.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+		require.Contains(t, rec.Body.String(), "envelope exceeds the maximum size")
+	})
+
+	t.Run("test envelope of unknown length exceeding limit", func(t *testing.T) {
+		rec := post(ioutil.NopCloser(strings.NewReader("0123456789a")))
+		require.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	})
+
+	t.Run("test attachment exceeding limit", func(t *testing.T) {
+		mockWallet.UnpackValue = nil
+		mockWallet.UnpackErr = fmt.Errorf("unpack: %w", wallet.ErrAttachmentTooLarge)
+		rec := post(bytes.NewBufferString("data"))
+		require.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+		require.Contains(t, rec.Body.String(), "attachment exceeds the maximum size")
+	})
+}
+
+type mockInfoProvider struct {
+	mockProvider
+	info *transport.InboundInfo
+}
+
+func (p *mockInfoProvider) InboundMessageHandler() transport.InboundMessageHandler {
+	return func(ctx context.Context, envelope *wallet.Envelope) error {
+		p.info, _ = transport.InboundInfoFromContext(ctx)
+		return nil
+	}
+}
+
+func TestInboundHandlerInboundInfo(t *testing.T) {
+	mockWallet := &mockwallet.CloseableWallet{UnpackValue: &wallet.Envelope{Message: []byte("data")}}
+	prov := &mockInfoProvider{mockProvider: mockProvider{packWalletValue: mockWallet}}
+	inHandler, err := NewInboundHandler(prov)
+	require.NoError(t, err)
+
+	before := time.Now()
+
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString("data"))
+	req.Header.Set("Content-type", commContentType)
+	rec := httptest.NewRecorder()
+	inHandler.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusAccepted, rec.Code)
+
+	require.NotNil(t, prov.info)
+	require.Equal(t, "http", prov.info.Transport)
+	require.False(t, prov.info.ReceivedTime.Before(before))
+}
+
+type mockMetricsProvider struct {
+	mockProvider
+	metrics *memmetrics.Provider
+}
+
+func (p *mockMetricsProvider) MetricsProvider() metrics.Provider {
+	return p.metrics
+}
+
+func TestInboundHandlerMetrics(t *testing.T) {
+	mockWallet := &mockwallet.CloseableWallet{UnpackValue: &wallet.Envelope{Message: []byte("data")}}
+	prov := &mockMetricsProvider{mockProvider: mockProvider{packWalletValue: mockWallet},
+		metrics: memmetrics.NewProvider()}
+	inHandler, err := NewInboundHandler(prov)
+	require.NoError(t, err)
+
+	post := func(contentType string) {
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString("data"))
+		req.Header.Set("Content-type", contentType)
+		inHandler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	post(commContentType)
+	post(commContentType)
+	post("text/plain")
+
+	transportLabel := metrics.Label{Name: "transport", Value: "http"}
+	require.Equal(t, float64(2), prov.metrics.Value(metrics.TransportEnvelopes, transportLabel,
+		metrics.Label{Name: "status", Value: "202"}))
+	require.Equal(t, float64(1), prov.metrics.Value(metrics.TransportEnvelopes, transportLabel,
+		metrics.Label{Name: "status", Value: "415"}))
+	require.Equal(t, []float64{4, 4, 0}, prov.metrics.Observations(metrics.TransportEnvelopeSize, transportLabel))
+}
+
+type mockRejectingProvider struct {
+	mockProvider
+}
+
+func (p *mockRejectingProvider) InboundMessageHandler() transport.InboundMessageHandler {
+	return func(ctx context.Context, envelope *wallet.Envelope) error {
+		return &model.ProblemReportError{Report: &model.ProblemReport{Type: model.ProblemReportMsgType,
+			Description: model.ProblemDescription{Code: "sender-mismatch"}}, Err: errors.New("rejected")}
+	}
+}
+
+func TestInboundHandlerProblemReport(t *testing.T) {
+	mockWallet := &mockwallet.CloseableWallet{UnpackValue: &wallet.Envelope{Message: []byte("data")}}
+	inHandler, err := NewInboundHandler(&mockRejectingProvider{mockProvider: mockProvider{packWalletValue: mockWallet}})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString("data"))
+	req.Header.Set("Content-type", commContentType)
+	rec := httptest.NewRecorder()
+	inHandler.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusForbidden, rec.Code)
+
+	report := &model.ProblemReport{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), report))
+	require.Equal(t, "sender-mismatch", report.Description.Code)
+}
+
+type mockProfileProvider struct {
+	mockInfoProvider
+	profiles map[string]transport.InboundProvider
+	err      error
+}
+
+func (p *mockProfileProvider) ProfileInboundProvider(profile string) (transport.InboundProvider, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+
+	prov, ok := p.profiles[profile]
+	if !ok {
+		return nil, transport.ErrProfileNotFound
+	}
+
+	return prov, nil
+}
+
+func TestProfileInboundHandler(t *testing.T) {
+	mockWallet := &mockwallet.CloseableWallet{UnpackValue: &wallet.Envelope{Message: []byte("data")}}
+
+	post := func(handler http.Handler, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString("data"))
+		req.Header.Set("Content-type", commContentType)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("test envelopes routed to the profile provider", func(t *testing.T) {
+		tenant1 := &mockInfoProvider{mockProvider: mockProvider{packWalletValue: mockWallet}}
+		prov := &mockProfileProvider{mockInfoProvider: mockInfoProvider{mockProvider: mockProvider{}},
+			profiles: map[string]transport.InboundProvider{"tenant1": tenant1}}
+
+		handler, err := NewProfileInboundHandler(prov, "/agent/")
+		require.NoError(t, err)
+
+		require.Equal(t, http.StatusAccepted, post(handler, "/agent/tenant1").Code)
+		require.NotNil(t, tenant1.info)
+		require.Equal(t, "tenant1", tenant1.info.Profile)
+		require.Nil(t, prov.info)
+
+		require.Equal(t, http.StatusNotFound, post(handler, "/agent/tenant2").Code)
+		require.Equal(t, http.StatusNotFound, post(handler, "/agent/").Code)
+		require.Equal(t, http.StatusNotFound, post(handler, "/agent/tenant1/x").Code)
+		require.Equal(t, http.StatusNotFound, post(handler, "/other/tenant1").Code)
+	})
+
+	t.Run("test envelopes tagged with the profile", func(t *testing.T) {
+		prov := &mockInfoProvider{mockProvider: mockProvider{packWalletValue: mockWallet}}
+
+		handler, err := NewProfileInboundHandler(prov, "/agent/")
+		require.NoError(t, err)
+
+		require.Equal(t, http.StatusAccepted, post(handler, "/agent/tenant1").Code)
+		require.Equal(t, "tenant1", prov.info.Profile)
+	})
+
+	t.Run("test profile provider error", func(t *testing.T) {
+		prov := &mockProfileProvider{err: errors.New("profile error")}
+
+		handler, err := NewProfileInboundHandler(prov, "/agent/")
+		require.NoError(t, err)
+		require.Equal(t, http.StatusInternalServerError, post(handler, "/agent/tenant1").Code)
+	})
+
+	t.Run("test nil provider", func(t *testing.T) {
+		_, err := NewProfileInboundHandler(nil, "/agent/")
+		require.Error(t, err)
+	})
+
+	t.Run("test inbound transport with profile path", func(t *testing.T) {
+		inbound, err := NewInbound("example.com:26605", WithProfilePath("/agent/"))
+		require.NoError(t, err)
+		require.Equal(t, "http://example.com:26605/agent/tenant1", inbound.ProfileEndpoint("tenant1"))
+		require.Implements(t, (*transport.ProfileEndpointer)(nil), inbound)
+
+		inbound, err = NewInbound("example.com:26605")
+		require.NoError(t, err)
+		require.Equal(t, "http://example.com:26605", inbound.ProfileEndpoint("tenant1"))
+	})
+}
@@ -8,10 +8,14 @@ package http
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"net/url"
 	"strings"
 	"time"
 )
@@ -21,12 +25,37 @@ import (
 const (
 	commContentType = "application/didcomm-envelope-enc"
 	httpScheme      = "http"
+
+	// dialTimeout is the connect timeout of the dialer of the keep-alive option, as in http.DefaultTransport
+	dialTimeout = 30 * time.Second
 )
 
 // outboundCommHTTPOpts holds options for the HTTP transport implementation of CommTransport
 // it has an http.Client instance
 type outboundCommHTTPOpts struct {
-	client *http.Client
+	client       *http.Client
+	pool         *ConnectionPool
+	keepAlive    time.Duration
+	noKeepAlives bool
+	http2        bool
+	proxy        func(*http.Request) (*url.URL, error)
+	caBundle     []byte
+	destinations map[string]*tls.Config
+	unsafe       bool
+}
+
+// ConnectionPool limits the connections kept open to the agents, the zero fields keep the defaults
+// of the HTTP client transport
+type ConnectionPool struct {
+	// MaxIdleConns is the maximum number of idle connections to all the agents
+	MaxIdleConns int
+	// MaxIdleConnsPerHost is the maximum number of idle connections to an agent, e.g. a mediator receiving
+	// most of the messages needs more than the default http.DefaultMaxIdleConnsPerHost
+	MaxIdleConnsPerHost int
+	// MaxConnsPerHost is the maximum number of connections to an agent, including the connections in use
+	MaxConnsPerHost int
+	// IdleConnTimeout is the time an idle connection is kept open
+	IdleConnTimeout time.Duration
 }
 
 // OutboundHTTPOpt is an outbound HTTP transport option
@@ -57,6 +86,83 @@ func WithOutboundTLSConfig(tlsConfig *tls.Config) OutboundHTTPOpt {
 	}
 }
 
+// WithOutboundConnectionPool option sets the connection pool limits of the HTTP client transport, the
+// connections are reused to send the messages to the same agents
+func WithOutboundConnectionPool(pool ConnectionPool) OutboundHTTPOpt {
+	return func(opts *outboundCommHTTPOpts) {
+		opts.pool = &pool
+	}
+}
+
+// WithOutboundKeepAlive option sets the TCP keep-alive period of the connections to the agents, the period
+// must be positive
+func WithOutboundKeepAlive(keepAlive time.Duration) OutboundHTTPOpt {
+	return func(opts *outboundCommHTTPOpts) {
+		opts.keepAlive = keepAlive
+	}
+}
+
+// WithOutboundDisableKeepAlives option closes the connections to the agents after each message instead of
+// reusing them
+func WithOutboundDisableKeepAlives() OutboundHTTPOpt {
+	return func(opts *outboundCommHTTPOpts) {
+		opts.noKeepAlives = true
+	}
+}
+
+// WithOutboundHTTP2 option sends the messages over HTTP/2 to the agents supporting it, the messages
+// to the same agent are multiplexed over a single TLS connection
+func WithOutboundHTTP2() OutboundHTTPOpt {
+	return func(opts *outboundCommHTTPOpts) {
+		opts.http2 = true
+	}
+}
+
+// WithOutboundProxy option sends the messages through the HTTP or HTTPS proxy of the URL,
+// e.g. http://proxy.example.com:3128
+func WithOutboundProxy(proxyURL *url.URL) OutboundHTTPOpt {
+	return func(opts *outboundCommHTTPOpts) {
+		opts.proxy = http.ProxyURL(proxyURL)
+	}
+}
+
+// WithOutboundProxyFromEnvironment option sends the messages through the proxies of the HTTP_PROXY, HTTPS_PROXY
+// and NO_PROXY environment variables, the clients of WithOutboundTLSConfig don't use them otherwise
+func WithOutboundProxyFromEnvironment() OutboundHTTPOpt {
+	return func(opts *outboundCommHTTPOpts) {
+		opts.proxy = http.ProxyFromEnvironment
+	}
+}
+
+// WithOutboundCABundle option trusts the CA certificates of the PEM bundle in addition to the system roots,
+// e.g. the CA of a TLS inspecting proxy. The option conflicts with the RootCAs of the client TLS config.
+func WithOutboundCABundle(pemCerts []byte) OutboundHTTPOpt {
+	return func(opts *outboundCommHTTPOpts) {
+		opts.caBundle = pemCerts
+	}
+}
+
+// WithDestinationTLSConfig option sets the TLS config of the messages sent to the host, e.g. a partner agent
+// requiring a client certificate. The host matches the host and port of the endpoints, e.g.
+// agent.example.com:8443, or their host only. The config is used as is, without the CA bundle.
+func WithDestinationTLSConfig(host string, tlsConfig *tls.Config) OutboundHTTPOpt {
+	return func(opts *outboundCommHTTPOpts) {
+		if opts.destinations == nil {
+			opts.destinations = make(map[string]*tls.Config)
+		}
+
+		opts.destinations[host] = tlsConfig
+	}
+}
+
+// WithUnsafeInsecureSkipVerify option allows the TLS configs skipping the verification of the agents
+// certificates, the transport can then be intercepted. Only use it for tests.
+func WithUnsafeInsecureSkipVerify() OutboundHTTPOpt {
+	return func(opts *outboundCommHTTPOpts) {
+		opts.unsafe = true
+	}
+}
+
 // OutboundHTTPClient represents the Outbound HTTP transport instance
 type OutboundHTTPClient struct {
 	client *http.Client
@@ -75,33 +181,206 @@ func NewOutbound(opts ...OutboundHTTPOpt) (*OutboundHTTPClient, error) {
 		return nil, errors.New("creation of outbound transport requires an HTTP client")
 	}
 
+	client := clOpts.client
+
+	if err := checkInsecureSkipVerify(client.Transport, clOpts); err != nil {
+		return nil, err
+	}
+
+	if clOpts.keepAlive < 0 {
+		return nil, fmt.Errorf("invalid keep-alive period %s", clOpts.keepAlive)
+	}
+
+	if clOpts.pool != nil || clOpts.keepAlive != 0 || clOpts.noKeepAlives || clOpts.http2 || clOpts.proxy != nil ||
+		clOpts.caBundle != nil || len(clOpts.destinations) > 0 {
+		tr, err := configureTransport(client.Transport, clOpts)
+		if err != nil {
+			return nil, err
+		}
+
+		// copy the client to leave the client of the option untouched
+		c := *client
+		c.Transport = tr
+
+		if len(clOpts.destinations) > 0 {
+			c.Transport = newDestinationTransport(tr, clOpts.destinations)
+		}
+
+		client = &c
+	}
+
 	cs := &OutboundHTTPClient{
-		client: clOpts.client,
+		client: client,
 	}
 	return cs, nil
 }
 
+// configureTransport returns a copy of the client transport with the connection options
+func configureTransport(rt http.RoundTripper, opts *outboundCommHTTPOpts) (*http.Transport, error) {
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+
+	t, ok := rt.(*http.Transport)
+	if !ok {
+		return nil, fmt.Errorf("connection options require an *http.Transport, got %T", rt)
+	}
+
+	tr := t.Clone()
+
+	if opts.pool != nil {
+		if opts.pool.MaxIdleConns != 0 {
+			tr.MaxIdleConns = opts.pool.MaxIdleConns
+		}
+
+		if opts.pool.MaxIdleConnsPerHost != 0 {
+			tr.MaxIdleConnsPerHost = opts.pool.MaxIdleConnsPerHost
+		}
+
+		if opts.pool.MaxConnsPerHost != 0 {
+			tr.MaxConnsPerHost = opts.pool.MaxConnsPerHost
+		}
+
+		if opts.pool.IdleConnTimeout != 0 {
+			tr.IdleConnTimeout = opts.pool.IdleConnTimeout
+		}
+	}
+
+	if opts.keepAlive != 0 {
+		tr.DialContext = (&net.Dialer{Timeout: dialTimeout, KeepAlive: opts.keepAlive}).DialContext
+	}
+
+	if opts.noKeepAlives {
+		tr.DisableKeepAlives = true
+	}
+
+	if opts.http2 {
+		// a transport with a custom TLS config or dialer only uses HTTP/2 when forced
+		tr.ForceAttemptHTTP2 = true
+	}
+
+	if opts.proxy != nil {
+		tr.Proxy = opts.proxy
+	}
+
+	if opts.caBundle != nil {
+		if err := addCABundle(tr, opts.caBundle); err != nil {
+			return nil, err
+		}
+	}
+
+	return tr, nil
+}
+
+// addCABundle sets the root CAs of the transport TLS config to the system roots and the CAs of the bundle
+func addCABundle(tr *http.Transport, pemCerts []byte) error {
+	if tr.TLSClientConfig != nil && tr.TLSClientConfig.RootCAs != nil {
+		return errors.New("CA bundle conflicts with the RootCAs of the TLS config")
+	}
+
+	roots, err := x509.SystemCertPool()
+	if err != nil {
+		logger.Warnf("system root CAs not available, only the CA bundle is trusted: %v", err)
+
+		roots = x509.NewCertPool()
+	}
+
+	if !roots.AppendCertsFromPEM(pemCerts) {
+		return errors.New("no certificate found in the CA bundle")
+	}
+
+	tlsConfig := &tls.Config{}
+	if tr.TLSClientConfig != nil {
+		tlsConfig = tr.TLSClientConfig.Clone()
+	}
+
+	tlsConfig.RootCAs = roots
+	tr.TLSClientConfig = tlsConfig
+
+	return nil
+}
+
+// checkInsecureSkipVerify rejects the TLS configs skipping the certificates verification unless allowed
+func checkInsecureSkipVerify(rt http.RoundTripper, opts *outboundCommHTTPOpts) error {
+	if opts.unsafe {
+		return nil
+	}
+
+	if t, ok := rt.(*http.Transport); ok && t.TLSClientConfig != nil && t.TLSClientConfig.InsecureSkipVerify {
+		return errors.New("TLS config skipping certificates verification requires the unsafe option")
+	}
+
+	for host, tlsConfig := range opts.destinations {
+		if tlsConfig != nil && tlsConfig.InsecureSkipVerify {
+			return fmt.Errorf("TLS config of %s skipping certificates verification requires the unsafe option", host)
+		}
+	}
+
+	return nil
+}
+
+// destinationTransport sends the requests with the transport of the TLS config of their destination host
+type destinationTransport struct {
+	defaultTransport http.RoundTripper
+	hosts            map[string]http.RoundTripper
+}
+
+// newDestinationTransport returns the transport using copies of the transport with the TLS configs of the hosts
+func newDestinationTransport(tr *http.Transport, destinations map[string]*tls.Config) *destinationTransport {
+	d := &destinationTransport{defaultTransport: tr, hosts: make(map[string]http.RoundTripper, len(destinations))}
+
+	for host, tlsConfig := range destinations {
+		hostTransport := tr.Clone()
+		hostTransport.TLSClientConfig = tlsConfig.Clone()
+		d.hosts[host] = hostTransport
+	}
+
+	return d
+}
+
+// RoundTrip sends the request with the transport of the host and port of the request, or of its host
+func (d *destinationTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if rt, ok := d.hosts[req.URL.Host]; ok {
+		return rt.RoundTrip(req)
+	}
+
+	if rt, ok := d.hosts[req.URL.Hostname()]; ok {
+		return rt.RoundTrip(req)
+	}
+
+	return d.defaultTransport.RoundTrip(req)
+}
+
 // Send sends a2a exchange data via HTTP (client side)
-func (cs *OutboundHTTPClient) Send(data []byte, url string) (string, error) {
-	resp, err := cs.client.Post(url, commContentType, bytes.NewBuffer(data))
+func (cs *OutboundHTTPClient) Send(ctx context.Context, data []byte, url string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(data))
+	if err != nil {
+		return "", fmt.Errorf("creating POST request failed: %w", err)
+	}
+
+	req.Header.Set("Content-Type", commContentType)
+
+	resp, err := cs.client.Do(req)
 	if err != nil {
-		logger.Errorf("posting DID envelope to agent failed [%s, %v]", url, err)
+		logger.WithContext(ctx).Errorf("posting DID envelope to agent failed [%s, %v]", url, err)
 		return "", err
 	}
 
 	var respData string
 	if resp != nil {
-		isStatusSuccess := resp.StatusCode == http.StatusAccepted || resp.StatusCode == http.StatusOK
-		if !isStatusSuccess {
-			return "", fmt.Errorf("received unsuccessful POST HTTP status from agent [%s, %v]", url, resp.Status)
-		}
-		// handle response
+		// the body is closed whatever the status so the connection is released
 		defer func() {
 			e := resp.Body.Close()
 			if e != nil {
-				logger.Errorf("closing response body failed: %v", e)
+				logger.WithContext(ctx).Errorf("closing response body failed: %v", e)
 			}
 		}()
+
+		isStatusSuccess := resp.StatusCode == http.StatusAccepted || resp.StatusCode == http.StatusOK
+		if !isStatusSuccess {
+			return "", fmt.Errorf("received unsuccessful POST HTTP status from agent [%s, %v]", url, resp.Status)
+		}
+		// handle response
 		buf := new(bytes.Buffer)
 		_, e := buf.ReadFrom(resp.Body)
 		if e != nil {
@@ -7,10 +7,19 @@
 package http
 
 import (
+	"context"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/pem"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -68,25 +77,273 @@ func TestOutboundHTTPTransport(t *testing.T) {
 
 	// test Outbound transport's api
 	// first with an empty url
-	r, e := ot.Send([]byte("Hello World"), "")
+	r, e := ot.Send(context.Background(), []byte("Hello World"), "")
 	require.Error(t, e)
 	require.Empty(t, r)
 
 	// now try a bad url
-	r, e = ot.Send([]byte("Hello World"), "https://badurl")
+	r, e = ot.Send(context.Background(), []byte("Hello World"), "https://badurl")
 	require.Error(t, e)
 	require.Empty(t, r)
 
 	// and try with a 'bad' payload with a valid url..
-	r, e = ot.Send([]byte("bad"), serverURL)
+	r, e = ot.Send(context.Background(), []byte("bad"), serverURL)
 	require.Error(t, e)
 	require.Empty(t, r)
 
 	// finally using a valid url
-	r, e = ot.Send([]byte("Hello World"), serverURL)
+	r, e = ot.Send(context.Background(), []byte("Hello World"), serverURL)
 	require.NoError(t, e)
 	require.NotEmpty(t, r)
 
 	require.True(t, ot.Accept("http://example.com"))
 	require.False(t, ot.Accept("123:22"))
 }
+
+func TestOutboundHTTPTransport_Context(t *testing.T) {
+	server := httptest.NewServer(mockHTTPHandler{})
+	defer server.Close()
+
+	ot, err := NewOutbound(WithOutboundHTTPClient(server.Client()))
+	require.NoError(t, err)
+
+	t.Run("test send", func(t *testing.T) {
+		r, e := ot.Send(context.Background(), []byte("Hello World"), server.URL)
+		require.NoError(t, e)
+		require.NotEmpty(t, r)
+	})
+
+	t.Run("test send cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		r, e := ot.Send(ctx, []byte("Hello World"), server.URL)
+		require.Error(t, e)
+		require.True(t, errors.Is(e, context.Canceled))
+		require.Empty(t, r)
+	})
+
+	t.Run("test invalid url", func(t *testing.T) {
+		_, e := ot.Send(context.Background(), []byte("Hello World"), "%invalid")
+		require.Error(t, e)
+		require.Contains(t, e.Error(), "creating POST request failed")
+	})
+}
+
+func TestOutboundHTTPTransport_UnsuccessfulStatus(t *testing.T) {
+	body := &closeRecorder{Reader: strings.NewReader("error")}
+	client := &http.Client{Transport: roundTripperFunc(func(*http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusInternalServerError, Status: "500 Internal Server Error",
+			Body: body}, nil
+	})}
+
+	ot, err := NewOutbound(WithOutboundHTTPClient(client))
+	require.NoError(t, err)
+
+	_, err = ot.Send(context.Background(), []byte("Hello World"), "http://localhost")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "received unsuccessful POST HTTP status from agent")
+	require.True(t, body.closed)
+}
+
+func TestOutboundHTTPTransport_ConnectionOptions(t *testing.T) {
+	t.Run("test connection pool and keep-alive", func(t *testing.T) {
+		client := &http.Client{}
+		ot, err := NewOutbound(WithOutboundHTTPClient(client), WithOutboundKeepAlive(time.Minute),
+			WithOutboundConnectionPool(ConnectionPool{MaxIdleConnsPerHost: 50, MaxConnsPerHost: 100,
+				IdleConnTimeout: time.Minute}))
+		require.NoError(t, err)
+
+		tr, ok := ot.client.Transport.(*http.Transport)
+		require.True(t, ok)
+		require.Equal(t, 50, tr.MaxIdleConnsPerHost)
+		require.Equal(t, 100, tr.MaxConnsPerHost)
+		require.Equal(t, time.Minute, tr.IdleConnTimeout)
+		require.Equal(t, http.DefaultTransport.(*http.Transport).MaxIdleConns, tr.MaxIdleConns)
+		require.False(t, tr.DisableKeepAlives)
+		require.NotNil(t, tr.DialContext)
+
+		// the client of the option is left untouched
+		require.Nil(t, client.Transport)
+	})
+
+	t.Run("test keep-alives disabled", func(t *testing.T) {
+		ot, err := NewOutbound(WithOutboundHTTPClient(&http.Client{}), WithOutboundDisableKeepAlives())
+		require.NoError(t, err)
+		require.True(t, ot.client.Transport.(*http.Transport).DisableKeepAlives)
+	})
+
+	t.Run("test negative keep-alive period", func(t *testing.T) {
+		_, err := NewOutbound(WithOutboundHTTPClient(&http.Client{}), WithOutboundKeepAlive(-1))
+		require.EqualError(t, err, "invalid keep-alive period -1ns")
+	})
+
+	t.Run("test HTTP/2", func(t *testing.T) {
+		server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, e := w.Write([]byte(r.Proto))
+			require.NoError(t, e)
+		}))
+		server.EnableHTTP2 = true
+		server.StartTLS()
+
+		defer server.Close()
+
+		cp := x509.NewCertPool()
+		cp.AddCert(server.Certificate())
+
+		ot, err := NewOutbound(WithOutboundTLSConfig(&tls.Config{RootCAs: cp}), WithOutboundHTTP2())
+		require.NoError(t, err)
+
+		r, err := ot.Send(context.Background(), []byte("Hello World"), server.URL)
+		require.NoError(t, err)
+		require.Equal(t, "HTTP/2.0", r)
+
+		ot, err = NewOutbound(WithOutboundTLSConfig(&tls.Config{RootCAs: cp}))
+		require.NoError(t, err)
+
+		r, err = ot.Send(context.Background(), []byte("Hello World"), server.URL)
+		require.NoError(t, err)
+		require.Equal(t, "HTTP/1.1", r)
+	})
+
+	t.Run("test unsupported client transport", func(t *testing.T) {
+		_, err := NewOutbound(WithOutboundHTTPClient(&http.Client{Transport: &mockRoundTripper{}}),
+			WithOutboundHTTP2())
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "connection options require an *http.Transport")
+	})
+}
+
+func TestOutboundHTTPTransport_NetworkOptions(t *testing.T) {
+	newTLSServer := func() (*httptest.Server, []byte) {
+		server := httptest.NewTLSServer(mockHTTPHandler{})
+		return server, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	}
+
+	t.Run("test proxy", func(t *testing.T) {
+		proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, e := w.Write([]byte("proxied to " + r.Host))
+			require.NoError(t, e)
+		}))
+		defer proxy.Close()
+
+		proxyURL, err := url.Parse(proxy.URL)
+		require.NoError(t, err)
+
+		ot, err := NewOutbound(WithOutboundHTTPClient(&http.Client{}), WithOutboundProxy(proxyURL))
+		require.NoError(t, err)
+
+		r, err := ot.Send(context.Background(), []byte("Hello World"), "http://agent.example.com/inbound")
+		require.NoError(t, err)
+		require.Equal(t, "proxied to agent.example.com", r)
+
+		ot, err = NewOutbound(WithOutboundTLSConfig(&tls.Config{}), WithOutboundProxyFromEnvironment())
+		require.NoError(t, err)
+		require.NotNil(t, ot.client.Transport.(*http.Transport).Proxy)
+	})
+
+	t.Run("test CA bundle", func(t *testing.T) {
+		server, caBundle := newTLSServer()
+		defer server.Close()
+
+		ot, err := NewOutbound(WithOutboundHTTPClient(&http.Client{}), WithOutboundCABundle(caBundle))
+		require.NoError(t, err)
+
+		r, err := ot.Send(context.Background(), []byte("Hello World"), server.URL)
+		require.NoError(t, err)
+		require.Equal(t, "success", r)
+
+		ot, err = NewOutbound(WithOutboundHTTPClient(&http.Client{}))
+		require.NoError(t, err)
+
+		_, err = ot.Send(context.Background(), []byte("Hello World"), server.URL)
+		require.Error(t, err)
+
+		_, err = NewOutbound(WithOutboundHTTPClient(&http.Client{}), WithOutboundCABundle([]byte("not PEM")))
+		require.EqualError(t, err, "no certificate found in the CA bundle")
+
+		_, err = NewOutbound(WithOutboundTLSConfig(&tls.Config{RootCAs: x509.NewCertPool()}),
+			WithOutboundCABundle(caBundle))
+		require.EqualError(t, err, "CA bundle conflicts with the RootCAs of the TLS config")
+	})
+
+	t.Run("test destination TLS config", func(t *testing.T) {
+		server1, _ := newTLSServer()
+		defer server1.Close()
+
+		server2, _ := newTLSServer()
+		defer server2.Close()
+
+		roots1 := x509.NewCertPool()
+		roots1.AddCert(server1.Certificate())
+
+		ot, err := NewOutbound(WithOutboundHTTPClient(&http.Client{}),
+			WithDestinationTLSConfig(server1.Listener.Addr().String(), &tls.Config{RootCAs: roots1}))
+		require.NoError(t, err)
+
+		r, err := ot.Send(context.Background(), []byte("Hello World"), server1.URL)
+		require.NoError(t, err)
+		require.Equal(t, "success", r)
+
+		_, err = ot.Send(context.Background(), []byte("Hello World"), server2.URL)
+		require.Error(t, err)
+
+		// the host matches all the ports
+		roots := x509.NewCertPool()
+		roots.AddCert(server1.Certificate())
+		roots.AddCert(server2.Certificate())
+
+		ot, err = NewOutbound(WithOutboundHTTPClient(&http.Client{}),
+			WithDestinationTLSConfig("127.0.0.1", &tls.Config{RootCAs: roots}))
+		require.NoError(t, err)
+
+		for _, serverURL := range []string{server1.URL, server2.URL} {
+			_, err = ot.Send(context.Background(), []byte("Hello World"), serverURL)
+			require.NoError(t, err)
+		}
+	})
+
+	t.Run("test insecure skip verify requires the unsafe option", func(t *testing.T) {
+		server, _ := newTLSServer()
+		defer server.Close()
+
+		_, err := NewOutbound(WithOutboundTLSConfig(&tls.Config{InsecureSkipVerify: true})) //nolint:gosec
+		require.EqualError(t, err, "TLS config skipping certificates verification requires the unsafe option")
+
+		_, err = NewOutbound(WithOutboundHTTPClient(&http.Client{}),
+			WithDestinationTLSConfig("agent.example.com", &tls.Config{InsecureSkipVerify: true})) //nolint:gosec
+		require.EqualError(t, err,
+			"TLS config of agent.example.com skipping certificates verification requires the unsafe option")
+
+		ot, err := NewOutbound(WithOutboundTLSConfig(&tls.Config{InsecureSkipVerify: true}), //nolint:gosec
+			WithUnsafeInsecureSkipVerify())
+		require.NoError(t, err)
+
+		r, err := ot.Send(context.Background(), []byte("Hello World"), server.URL)
+		require.NoError(t, err)
+		require.Equal(t, "success", r)
+	})
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// closeRecorder records the closing of the response body
+type closeRecorder struct {
+	io.Reader
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
+
+type mockRoundTripper struct{}
+
+func (m *mockRoundTripper) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, errors.New("not implemented")
+}
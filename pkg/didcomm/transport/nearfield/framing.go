@@ -0,0 +1,171 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package nearfield
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// headerSize is the size of the frame header: the session ID, the message ID, the chunk index and the chunk count
+const headerSize = 12
+
+var (
+	// ErrMTUTooSmall is returned when the MTU of the link can't carry the frame header and a payload byte
+	ErrMTUTooSmall = errors.New("link MTU too small")
+	// ErrMessageTooLarge is returned when a message exceeds the chunk count or the configured maximum size
+	ErrMessageTooLarge = errors.New("message too large")
+	// ErrInvalidFrame is returned when a received frame doesn't continue the message being received
+	ErrInvalidFrame = errors.New("invalid frame")
+)
+
+// header is the header of the frames of a message chunk
+type header struct {
+	session uint32
+	message uint32
+	index   uint16
+	count   uint16
+}
+
+func (h *header) encode(chunk []byte) []byte {
+	frame := make([]byte, headerSize+len(chunk))
+	binary.BigEndian.PutUint32(frame[0:4], h.session)
+	binary.BigEndian.PutUint32(frame[4:8], h.message)
+	binary.BigEndian.PutUint16(frame[8:10], h.index)
+	binary.BigEndian.PutUint16(frame[10:12], h.count)
+	copy(frame[headerSize:], chunk)
+
+	return frame
+}
+
+func decodeHeader(frame []byte) (*header, error) {
+	if len(frame) < headerSize {
+		return nil, fmt.Errorf("%w: frame shorter than the header", ErrInvalidFrame)
+	}
+
+	h := &header{
+		session: binary.BigEndian.Uint32(frame[0:4]),
+		message: binary.BigEndian.Uint32(frame[4:8]),
+		index:   binary.BigEndian.Uint16(frame[8:10]),
+		count:   binary.BigEndian.Uint16(frame[10:12]),
+	}
+
+	if h.count == 0 || h.index >= h.count {
+		return nil, fmt.Errorf("%w: chunk %d of %d", ErrInvalidFrame, h.index, h.count)
+	}
+
+	return h, nil
+}
+
+// Session sends and receives the messages over a link, the messages are split into frames fitting the MTU.
+// The frames carry the session ID so the stale frames of a previous session of the link are rejected.
+type Session struct {
+	// ID identifies the session, the receiving side adopts the ID of the first frame if zero
+	ID             uint32
+	link           Link
+	messageID      uint32
+	maxMessageSize int64
+}
+
+// NewSession returns a session over the link, the received messages larger than maxMessageSize are
+// rejected (zero doesn't limit the messages)
+func NewSession(link Link, id uint32, maxMessageSize int64) *Session {
+	return &Session{ID: id, link: link, maxMessageSize: maxMessageSize}
+}
+
+// WriteMessage sends the message in chunks fitting the MTU of the link
+func (s *Session) WriteMessage(ctx context.Context, msg []byte) error {
+	chunkSize := s.link.MTU() - headerSize
+	if chunkSize <= 0 {
+		return fmt.Errorf("%w: %d bytes", ErrMTUTooSmall, s.link.MTU())
+	}
+
+	count := (len(msg) + chunkSize - 1) / chunkSize
+	if count == 0 {
+		count = 1
+	}
+
+	if count > math.MaxUint16 {
+		return fmt.Errorf("%w: %d chunks of %d bytes", ErrMessageTooLarge, count, chunkSize)
+	}
+
+	s.messageID++
+
+	for i := 0; i < count; i++ {
+		end := (i + 1) * chunkSize
+		if end > len(msg) {
+			end = len(msg)
+		}
+
+		h := &header{session: s.ID, message: s.messageID, index: uint16(i), count: uint16(count)}
+
+		if err := s.link.WriteFrame(ctx, h.encode(msg[i*chunkSize:end])); err != nil {
+			return fmt.Errorf("failed to write frame %d of %d: %w", i, count, err)
+		}
+	}
+
+	return nil
+}
+
+// ReadMessage receives the chunks of the next message and returns the reassembled message
+func (s *Session) ReadMessage(ctx context.Context) ([]byte, error) {
+	var (
+		msg   []byte
+		first *header
+	)
+
+	for next := uint16(0); ; next++ {
+		frame, err := s.link.ReadFrame(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		h, err := decodeHeader(frame)
+		if err != nil {
+			return nil, err
+		}
+
+		if first == nil {
+			if err = s.start(h); err != nil {
+				return nil, err
+			}
+
+			first = h
+		}
+
+		if h.session != first.session || h.message != first.message || h.count != first.count || h.index != next {
+			return nil, fmt.Errorf("%w: chunk %d of message %d received instead of chunk %d of message %d",
+				ErrInvalidFrame, h.index, h.message, next, first.message)
+		}
+
+		msg = append(msg, frame[headerSize:]...)
+
+		if s.maxMessageSize > 0 && int64(len(msg)) > s.maxMessageSize {
+			return nil, fmt.Errorf("%w: exceeds %d bytes", ErrMessageTooLarge, s.maxMessageSize)
+		}
+
+		if h.index == h.count-1 {
+			return msg, nil
+		}
+	}
+}
+
+// start checks the session of the first frame of a message, the session ID is adopted if not set yet
+func (s *Session) start(h *header) error {
+	if s.ID == 0 {
+		s.ID = h.session
+	}
+
+	if h.session != s.ID {
+		return fmt.Errorf("%w: frame of session %d received on session %d", ErrInvalidFrame, h.session, s.ID)
+	}
+
+	return nil
+}
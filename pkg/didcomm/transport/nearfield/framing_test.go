@@ -0,0 +1,177 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package nearfield
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// frameLink records the written frames and returns the queued frames
+type frameLink struct {
+	mtu      int
+	written  [][]byte
+	frames   [][]byte
+	writeErr error
+}
+
+func (l *frameLink) MTU() int {
+	return l.mtu
+}
+
+func (l *frameLink) WriteFrame(ctx context.Context, frame []byte) error {
+	if l.writeErr != nil {
+		return l.writeErr
+	}
+
+	l.written = append(l.written, frame)
+
+	return nil
+}
+
+func (l *frameLink) ReadFrame(ctx context.Context) ([]byte, error) {
+	if len(l.frames) == 0 {
+		return nil, ErrClosed
+	}
+
+	frame := l.frames[0]
+	l.frames = l.frames[1:]
+
+	return frame, nil
+}
+
+func (l *frameLink) Close() error {
+	return nil
+}
+
+func TestSession(t *testing.T) {
+	t.Run("test message split into frames fitting the MTU", func(t *testing.T) {
+		link := &frameLink{mtu: headerSize + 4}
+		msg := []byte("near-field message")
+
+		require.NoError(t, NewSession(link, 7, 0).WriteMessage(context.Background(), msg))
+		require.Len(t, link.written, 5)
+
+		for _, frame := range link.written {
+			require.True(t, len(frame) <= link.mtu)
+		}
+
+		link.frames = link.written
+		received, err := NewSession(link, 0, 0).ReadMessage(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, msg, received)
+	})
+
+	t.Run("test empty message", func(t *testing.T) {
+		link := &frameLink{mtu: 20}
+
+		require.NoError(t, NewSession(link, 1, 0).WriteMessage(context.Background(), nil))
+		require.Len(t, link.written, 1)
+
+		link.frames = link.written
+		received, err := NewSession(link, 0, 0).ReadMessage(context.Background())
+		require.NoError(t, err)
+		require.Empty(t, received)
+	})
+
+	t.Run("test MTU too small", func(t *testing.T) {
+		err := NewSession(&frameLink{mtu: headerSize}, 1, 0).WriteMessage(context.Background(), []byte("msg"))
+		require.True(t, errors.Is(err, ErrMTUTooSmall))
+	})
+
+	t.Run("test message exceeding the chunk count", func(t *testing.T) {
+		msg := make([]byte, 1<<16+1)
+		err := NewSession(&frameLink{mtu: headerSize + 1}, 1, 0).WriteMessage(context.Background(), msg)
+		require.True(t, errors.Is(err, ErrMessageTooLarge))
+	})
+
+	t.Run("test write error", func(t *testing.T) {
+		link := &frameLink{mtu: 20, writeErr: errors.New("write error")}
+		err := NewSession(link, 1, 0).WriteMessage(context.Background(), []byte("msg"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "write error")
+	})
+
+	t.Run("test message exceeding the maximum size", func(t *testing.T) {
+		link := &frameLink{mtu: headerSize + 2}
+		require.NoError(t, NewSession(link, 1, 0).WriteMessage(context.Background(), []byte("12345")))
+
+		link.frames = link.written
+		_, err := NewSession(link, 0, 4).ReadMessage(context.Background())
+		require.True(t, errors.Is(err, ErrMessageTooLarge))
+	})
+
+	t.Run("test consecutive messages", func(t *testing.T) {
+		link := &frameLink{mtu: headerSize + 3}
+		s := NewSession(link, 9, 0)
+		require.NoError(t, s.WriteMessage(context.Background(), []byte("first")))
+		require.NoError(t, s.WriteMessage(context.Background(), []byte("second")))
+
+		link.frames = link.written
+		r := NewSession(link, 0, 0)
+
+		received, err := r.ReadMessage(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, "first", string(received))
+
+		received, err = r.ReadMessage(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, "second", string(received))
+		require.Equal(t, uint32(9), r.ID)
+	})
+}
+
+func TestSession_InvalidFrames(t *testing.T) {
+	encode := func(h header, chunk string) []byte {
+		return h.encode([]byte(chunk))
+	}
+
+	tests := []struct {
+		name   string
+		frames [][]byte
+	}{
+		{name: "frame shorter than the header", frames: [][]byte{[]byte("short")}},
+		{name: "zero chunk count", frames: [][]byte{encode(header{session: 1, message: 1}, "a")}},
+		{name: "chunk index out of range", frames: [][]byte{encode(header{session: 1, message: 1, index: 2, count: 2}, "a")}},
+		{name: "first chunk missing", frames: [][]byte{encode(header{session: 1, message: 1, index: 1, count: 2}, "a")}},
+		{name: "chunk of another message", frames: [][]byte{
+			encode(header{session: 1, message: 1, index: 0, count: 2}, "a"),
+			encode(header{session: 1, message: 2, index: 1, count: 2}, "b"),
+		}},
+		{name: "chunk of another session", frames: [][]byte{
+			encode(header{session: 1, message: 1, index: 0, count: 2}, "a"),
+			encode(header{session: 2, message: 1, index: 1, count: 2}, "b"),
+		}},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run("test "+tc.name, func(t *testing.T) {
+			_, err := NewSession(&frameLink{mtu: 20, frames: tc.frames}, 0, 0).ReadMessage(context.Background())
+			require.True(t, errors.Is(err, ErrInvalidFrame))
+		})
+	}
+
+	t.Run("test frame of a stale session", func(t *testing.T) {
+		link := &frameLink{mtu: 20, frames: [][]byte{encode(header{session: 3, message: 1, count: 1}, "a")}}
+		_, err := NewSession(link, 4, 0).ReadMessage(context.Background())
+		require.True(t, errors.Is(err, ErrInvalidFrame))
+	})
+
+	t.Run("test link closed while reading", func(t *testing.T) {
+		link := &frameLink{mtu: 20, frames: [][]byte{encode(header{session: 1, message: 1, count: 2}, "a")}}
+		_, err := NewSession(link, 0, 0).ReadMessage(context.Background())
+		require.True(t, errors.Is(err, ErrClosed))
+	})
+
+	require.True(t, bytes.Equal(encode(header{session: 1, count: 1}, "")[:headerSize],
+		[]byte{0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 1}))
+}
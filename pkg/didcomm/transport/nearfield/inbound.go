@@ -0,0 +1,166 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package nearfield
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/common/log"
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/transport"
+	"github.com/hyperledger/aries-framework-go/pkg/wallet"
+)
+
+// sizeLimitsProvider is optionally implemented by the inbound provider to limit the size of the envelopes
+type sizeLimitsProvider interface {
+	MessageSizeLimits() wallet.SizeLimits
+}
+
+// InboundOpt is an inbound near-field transport option
+type InboundOpt func(i *Inbound)
+
+// WithInboundScheme option sets the URL scheme of the endpoint of the transport, e.g. ble
+func WithInboundScheme(scheme string) InboundOpt {
+	return func(i *Inbound) {
+		i.scheme = scheme
+	}
+}
+
+// Inbound receives the envelopes sent by the near-field peers over the links accepted by the listener
+type Inbound struct {
+	listener Listener
+	scheme   string
+	links    map[Link]struct{}
+	lock     sync.Mutex
+	wg       sync.WaitGroup
+}
+
+// NewInbound creates a new inbound transport accepting the links of the listener
+func NewInbound(listener Listener, opts ...InboundOpt) *Inbound {
+	i := &Inbound{listener: listener, scheme: DefaultScheme, links: make(map[Link]struct{})}
+
+	for _, opt := range opts {
+		opt(i)
+	}
+
+	return i
+}
+
+// Start accepts the links and handles the envelopes received over them
+func (i *Inbound) Start(prov transport.InboundProvider) error {
+	if prov == nil || prov.InboundMessageHandler() == nil {
+		return errors.New("near-field inbound transport start failed: message handler function is nil")
+	}
+
+	var maxSize int64
+	if p, ok := prov.(sizeLimitsProvider); ok {
+		maxSize = p.MessageSizeLimits().MaxEnvelopeSize
+	}
+
+	i.wg.Add(1)
+
+	go func() {
+		defer i.wg.Done()
+
+		for {
+			link, err := i.listener.Accept()
+			if err != nil {
+				if !errors.Is(err, ErrClosed) {
+					logger.Errorf("near-field listener %s failed: %s", i.listener.Address(), err)
+				}
+
+				return
+			}
+
+			i.serve(link, prov, maxSize)
+		}
+	}()
+
+	return nil
+}
+
+// serve handles the envelopes received over the link until the link is closed
+func (i *Inbound) serve(link Link, prov transport.InboundProvider, maxSize int64) {
+	i.lock.Lock()
+	i.links[link] = struct{}{}
+	i.lock.Unlock()
+
+	i.wg.Add(1)
+
+	go func() {
+		defer i.wg.Done()
+		defer i.closeLink(link)
+
+		session := NewSession(link, 0, maxSize)
+
+		for {
+			msg, err := session.ReadMessage(context.Background())
+			if err != nil {
+				if !errors.Is(err, ErrClosed) {
+					logger.Warnf("closing near-field link: %s", err)
+				}
+
+				return
+			}
+
+			handle(prov, msg)
+		}
+	}()
+}
+
+func (i *Inbound) closeLink(link Link) {
+	i.lock.Lock()
+	delete(i.links, link)
+	i.lock.Unlock()
+
+	if err := link.Close(); err != nil {
+		logger.Warnf("failed to close near-field link: %s", err)
+	}
+}
+
+// handle unpacks the envelope and handles the message, the errors are logged as the link has no response
+func handle(prov transport.InboundProvider, envelope []byte) {
+	received := time.Now()
+
+	unpackMsg, err := prov.PackWallet().UnpackMessage(envelope)
+	if err != nil {
+		logger.Errorf("failed to unpack msg: %s", err)
+		return
+	}
+
+	ctx := transport.WithInboundInfo(context.Background(), &transport.InboundInfo{Transport: transportName,
+		ReceivedTime: received})
+	ctx = log.NewContext(ctx, log.FieldsFromMessage(unpackMsg.Message))
+
+	if err = prov.InboundMessageHandler()(ctx, unpackMsg); err != nil {
+		logger.WithContext(ctx).Errorf("incoming msg processing failed: %s", err)
+	}
+}
+
+// Stop stops accepting the links, closes the open links and waits for the messages being handled
+func (i *Inbound) Stop() error {
+	err := i.listener.Close()
+
+	i.lock.Lock()
+	for link := range i.links {
+		if e := link.Close(); e != nil {
+			logger.Warnf("failed to close near-field link: %s", e)
+		}
+	}
+	i.lock.Unlock()
+
+	i.wg.Wait()
+
+	return err
+}
+
+// Endpoint returns the near-field endpoint of the listener, e.g. nearfield://device-address
+func (i *Inbound) Endpoint() string {
+	return i.scheme + "://" + i.listener.Address()
+}
@@ -0,0 +1,158 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package nearfield
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Medium is an in-memory near-field medium, the links of the medium deliver the frames between the
+// dialers and the listeners of the same process. It's the reference implementation of the SPI.
+type Medium struct {
+	mtu       int
+	listeners map[string]*memListener
+	lock      sync.Mutex
+}
+
+// NewMedium creates an in-memory medium with links of the MTU
+func NewMedium(mtu int) *Medium {
+	return &Medium{mtu: mtu, listeners: make(map[string]*memListener)}
+}
+
+// Listen returns a listener accepting the links dialed to the address
+func (m *Medium) Listen(address string) (Listener, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if _, ok := m.listeners[address]; ok {
+		return nil, fmt.Errorf("near-field address %s already in use", address)
+	}
+
+	l := &memListener{medium: m, address: address, links: make(chan Link), closed: make(chan struct{})}
+	m.listeners[address] = l
+
+	return l, nil
+}
+
+// Dial opens a link to the listener of the address, ErrNotInRange is returned if nothing listens on the address
+func (m *Medium) Dial(ctx context.Context, address string) (Link, error) {
+	m.lock.Lock()
+	l, ok := m.listeners[address]
+	m.lock.Unlock()
+
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotInRange, address)
+	}
+
+	local, remote := newMemLinks(m.mtu)
+
+	select {
+	case l.links <- remote:
+		return local, nil
+	case <-l.closed:
+		return nil, fmt.Errorf("%w: %s", ErrNotInRange, address)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (m *Medium) remove(address string) {
+	m.lock.Lock()
+	delete(m.listeners, address)
+	m.lock.Unlock()
+}
+
+type memListener struct {
+	medium  *Medium
+	address string
+	links   chan Link
+	closed  chan struct{}
+	once    sync.Once
+}
+
+func (l *memListener) Accept() (Link, error) {
+	select {
+	case link := <-l.links:
+		return link, nil
+	case <-l.closed:
+		return nil, ErrClosed
+	}
+}
+
+func (l *memListener) Close() error {
+	l.once.Do(func() {
+		l.medium.remove(l.address)
+		close(l.closed)
+	})
+
+	return nil
+}
+
+func (l *memListener) Address() string {
+	return l.address
+}
+
+// memLink is one end of an in-memory link, closing either end closes the link
+type memLink struct {
+	mtu    int
+	in     <-chan []byte
+	out    chan<- []byte
+	closed chan struct{}
+	once   *sync.Once
+}
+
+func newMemLinks(mtu int) (*memLink, *memLink) {
+	a, b := make(chan []byte), make(chan []byte)
+	closed := make(chan struct{})
+	once := &sync.Once{}
+
+	return &memLink{mtu: mtu, in: a, out: b, closed: closed, once: once},
+		&memLink{mtu: mtu, in: b, out: a, closed: closed, once: once}
+}
+
+func (l *memLink) MTU() int {
+	return l.mtu
+}
+
+func (l *memLink) WriteFrame(ctx context.Context, frame []byte) error {
+	if len(frame) > l.mtu {
+		return fmt.Errorf("frame of %d bytes exceeds the MTU of %d bytes", len(frame), l.mtu)
+	}
+
+	f := make([]byte, len(frame))
+	copy(f, frame)
+
+	select {
+	case l.out <- f:
+		return nil
+	case <-l.closed:
+		return ErrClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (l *memLink) ReadFrame(ctx context.Context) ([]byte, error) {
+	select {
+	case frame := <-l.in:
+		return frame, nil
+	case <-l.closed:
+		return nil, ErrClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (l *memLink) Close() error {
+	l.once.Do(func() {
+		close(l.closed)
+	})
+
+	return nil
+}
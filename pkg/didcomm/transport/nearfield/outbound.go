@@ -0,0 +1,89 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package nearfield
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// OutboundOpt is an outbound near-field transport option
+type OutboundOpt func(o *Outbound)
+
+// WithOutboundScheme option sets the URL scheme of the endpoints accepted by the transport, e.g. ble
+func WithOutboundScheme(scheme string) OutboundOpt {
+	return func(o *Outbound) {
+		o.scheme = scheme
+	}
+}
+
+// Outbound sends the envelopes to the near-field peers, a link is dialed per envelope
+type Outbound struct {
+	dialer Dialer
+	scheme string
+}
+
+// NewOutbound creates a new outbound transport dialing the peers with the dialer
+func NewOutbound(dialer Dialer, opts ...OutboundOpt) *Outbound {
+	o := &Outbound{dialer: dialer, scheme: DefaultScheme}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// Send sends the envelope to the peer of the near-field endpoint, e.g. nearfield://device-address
+func (o *Outbound) Send(ctx context.Context, data []byte, destination string) (string, error) {
+	address := strings.TrimPrefix(destination, o.scheme+"://")
+
+	link, err := o.dialer.Dial(ctx, address)
+	if err != nil {
+		return "", fmt.Errorf("failed to dial %s: %w", address, err)
+	}
+
+	defer func() {
+		if e := link.Close(); e != nil {
+			logger.Warnf("failed to close link to %s: %s", address, e)
+		}
+	}()
+
+	id, err := newSessionID()
+	if err != nil {
+		return "", err
+	}
+
+	if err = NewSession(link, id, 0).WriteMessage(ctx, data); err != nil {
+		return "", fmt.Errorf("failed to send envelope to %s: %w", address, err)
+	}
+
+	return "", nil
+}
+
+// Accept accepts the endpoints of the scheme of the transport
+func (o *Outbound) Accept(url string) bool {
+	return strings.HasPrefix(url, o.scheme+"://")
+}
+
+// newSessionID returns a random non-zero session ID
+func newSessionID() (uint32, error) {
+	var b [4]byte
+
+	for {
+		if _, err := rand.Read(b[:]); err != nil {
+			return 0, fmt.Errorf("failed to generate session ID: %w", err)
+		}
+
+		if id := binary.BigEndian.Uint32(b[:]); id != 0 {
+			return id, nil
+		}
+	}
+}
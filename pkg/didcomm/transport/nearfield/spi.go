@@ -0,0 +1,64 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package nearfield implements the DIDComm transports over the non-IP links such as Bluetooth Low Energy
+// or NFC. The integrators implement the Link, Dialer and Listener SPI with the platform APIs, the transports
+// split the envelopes into frames fitting the MTU of the links. Medium is an in-memory reference implementation
+// of the SPI, e.g. to test the agents exchanging messages over a near-field link.
+package nearfield
+
+import (
+	"context"
+	"errors"
+
+	"github.com/hyperledger/aries-framework-go/pkg/common/log"
+)
+
+var logger = log.New("aries-framework/transport/nearfield")
+
+const (
+	// transportName is set on the inbound info of the received messages
+	transportName = "nearfield"
+
+	// DefaultScheme is the URL scheme of the near-field endpoints, e.g. nearfield://device-address
+	DefaultScheme = "nearfield"
+)
+
+var (
+	// ErrClosed is returned by the links and the listeners once closed
+	ErrClosed = errors.New("near-field link closed")
+	// ErrNotInRange is returned by the dialers when no peer listens on the address
+	ErrNotInRange = errors.New("near-field peer not in range")
+)
+
+// Link is a reliable and ordered frame connection to a peer, e.g. a BLE GATT characteristic pair
+// or an NFC peer-to-peer exchange. The link delivers the frames as written, without splitting them.
+type Link interface {
+	// MTU returns the maximum size in bytes of the frames of the link
+	MTU() int
+	// WriteFrame sends the frame to the peer, the context cancels the write
+	WriteFrame(ctx context.Context, frame []byte) error
+	// ReadFrame returns the next frame received from the peer, ErrClosed once the link is closed
+	ReadFrame(ctx context.Context) ([]byte, error)
+	// Close closes the link
+	Close() error
+}
+
+// Dialer opens the links to the peers, implemented by the outbound side of the platform integration
+type Dialer interface {
+	// Dial opens a link to the peer at the address, e.g. a BLE device address
+	Dial(ctx context.Context, address string) (Link, error)
+}
+
+// Listener accepts the links opened by the peers, implemented by the inbound side of the platform integration
+type Listener interface {
+	// Accept waits for the next link, ErrClosed is returned once the listener is closed
+	Accept() (Link, error)
+	// Close stops accepting the links
+	Close() error
+	// Address is the address the peers dial
+	Address() string
+}
@@ -0,0 +1,252 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package nearfield
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/transport"
+	"github.com/hyperledger/aries-framework-go/pkg/wallet"
+)
+
+// echoWallet unpacks the envelopes into messages of the same content
+type echoWallet struct {
+	unpackErr error
+}
+
+func (w *echoWallet) PackMessage(envelope *wallet.Envelope) ([]byte, error) {
+	return envelope.Message, nil
+}
+
+func (w *echoWallet) UnpackMessage(encMessage []byte) (*wallet.Envelope, error) {
+	if w.unpackErr != nil {
+		return nil, w.unpackErr
+	}
+
+	return &wallet.Envelope{Message: encMessage}, nil
+}
+
+type mockProvider struct {
+	wallet   wallet.Pack
+	handler  transport.InboundMessageHandler
+	maxSize  int64
+	received chan *wallet.Envelope
+}
+
+func newMockProvider() *mockProvider {
+	p := &mockProvider{wallet: &echoWallet{}, received: make(chan *wallet.Envelope, 10)}
+	p.handler = func(ctx context.Context, envelope *wallet.Envelope) error {
+		info, ok := transport.InboundInfoFromContext(ctx)
+		if !ok || info.Transport != transportName {
+			return errors.New("missing inbound info")
+		}
+
+		p.received <- envelope
+
+		return nil
+	}
+
+	return p
+}
+
+func (p *mockProvider) InboundMessageHandler() transport.InboundMessageHandler {
+	return p.handler
+}
+
+func (p *mockProvider) PackWallet() wallet.Pack {
+	return p.wallet
+}
+
+func (p *mockProvider) MessageSizeLimits() wallet.SizeLimits {
+	return wallet.SizeLimits{MaxEnvelopeSize: p.maxSize}
+}
+
+func TestTransport(t *testing.T) {
+	t.Run("test envelopes sent over the medium", func(t *testing.T) {
+		medium := NewMedium(headerSize + 16)
+		listener, err := medium.Listen("device-1")
+		require.NoError(t, err)
+
+		inbound := NewInbound(listener)
+		require.Equal(t, "nearfield://device-1", inbound.Endpoint())
+
+		prov := newMockProvider()
+		require.NoError(t, inbound.Start(prov))
+
+		outbound := NewOutbound(medium)
+		require.True(t, outbound.Accept(inbound.Endpoint()))
+		require.False(t, outbound.Accept("http://device-1"))
+
+		msg := strings.Repeat("envelope-", 20)
+
+		for i := 0; i < 3; i++ {
+			_, err = outbound.Send(context.Background(), []byte(msg), inbound.Endpoint())
+			require.NoError(t, err)
+
+			select {
+			case envelope := <-prov.received:
+				require.Equal(t, msg, string(envelope.Message))
+			case <-time.After(time.Second):
+				require.Fail(t, "envelope not received")
+			}
+		}
+
+		require.NoError(t, inbound.Stop())
+
+		_, err = outbound.Send(context.Background(), []byte(msg), inbound.Endpoint())
+		require.True(t, errors.Is(err, ErrNotInRange))
+	})
+
+	t.Run("test custom scheme", func(t *testing.T) {
+		medium := NewMedium(64)
+		listener, err := medium.Listen("device-2")
+		require.NoError(t, err)
+
+		inbound := NewInbound(listener, WithInboundScheme("ble"))
+		require.Equal(t, "ble://device-2", inbound.Endpoint())
+
+		outbound := NewOutbound(medium, WithOutboundScheme("ble"))
+		require.True(t, outbound.Accept(inbound.Endpoint()))
+		require.False(t, outbound.Accept("nearfield://device-2"))
+		require.NoError(t, inbound.Stop())
+	})
+
+	t.Run("test start without a message handler", func(t *testing.T) {
+		medium := NewMedium(64)
+		listener, err := medium.Listen("device-3")
+		require.NoError(t, err)
+
+		prov := newMockProvider()
+		prov.handler = nil
+
+		inbound := NewInbound(listener)
+		require.Error(t, inbound.Start(prov))
+		require.Error(t, inbound.Start(nil))
+		require.NoError(t, inbound.Stop())
+	})
+
+	t.Run("test envelopes not handled", func(t *testing.T) {
+		medium := NewMedium(headerSize + 4)
+		listener, err := medium.Listen("device-4")
+		require.NoError(t, err)
+
+		prov := newMockProvider()
+		prov.maxSize = 8
+		prov.wallet = &echoWallet{unpackErr: errors.New("unpack error")}
+
+		inbound := NewInbound(listener)
+		require.NoError(t, inbound.Start(prov))
+
+		outbound := NewOutbound(medium)
+
+		// the link is closed by the inbound transport when the envelope exceeds the maximum size
+		_, err = outbound.Send(context.Background(), []byte("envelope too large"), inbound.Endpoint())
+		require.Error(t, err)
+
+		_, err = outbound.Send(context.Background(), []byte("envelope"), inbound.Endpoint())
+		require.NoError(t, err)
+
+		require.NoError(t, inbound.Stop())
+		require.Empty(t, prov.received)
+	})
+
+	t.Run("test send with the MTU too small", func(t *testing.T) {
+		medium := NewMedium(headerSize)
+		listener, err := medium.Listen("device-5")
+		require.NoError(t, err)
+
+		inbound := NewInbound(listener)
+		require.NoError(t, inbound.Start(newMockProvider()))
+
+		_, err = NewOutbound(medium).Send(context.Background(), []byte("msg"), inbound.Endpoint())
+		require.True(t, errors.Is(err, ErrMTUTooSmall))
+		require.NoError(t, inbound.Stop())
+	})
+}
+
+func TestMedium(t *testing.T) {
+	t.Run("test address in use", func(t *testing.T) {
+		medium := NewMedium(64)
+		listener, err := medium.Listen("device")
+		require.NoError(t, err)
+
+		_, err = medium.Listen("device")
+		require.Error(t, err)
+
+		require.NoError(t, listener.Close())
+		require.NoError(t, listener.Close())
+
+		_, err = listener.Accept()
+		require.True(t, errors.Is(err, ErrClosed))
+
+		_, err = medium.Listen("device")
+		require.NoError(t, err)
+	})
+
+	t.Run("test link", func(t *testing.T) {
+		medium := NewMedium(8)
+		listener, err := medium.Listen("device")
+		require.NoError(t, err)
+
+		accepted := make(chan Link)
+
+		go func() {
+			link, e := listener.Accept()
+			if e == nil {
+				accepted <- link
+			}
+		}()
+
+		local, err := medium.Dial(context.Background(), "device")
+		require.NoError(t, err)
+
+		remote := <-accepted
+		require.Equal(t, 8, remote.MTU())
+
+		go func() {
+			require.NoError(t, local.WriteFrame(context.Background(), []byte("frame")))
+		}()
+
+		frame, err := remote.ReadFrame(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, "frame", string(frame))
+
+		require.Error(t, local.WriteFrame(context.Background(), []byte("frame larger than the MTU")))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err = remote.ReadFrame(ctx)
+		require.True(t, errors.Is(err, context.Canceled))
+		require.True(t, errors.Is(local.WriteFrame(ctx, []byte("frame")), context.Canceled))
+
+		require.NoError(t, local.Close())
+		require.NoError(t, remote.Close())
+
+		_, err = remote.ReadFrame(context.Background())
+		require.True(t, errors.Is(err, ErrClosed))
+		require.True(t, errors.Is(local.WriteFrame(context.Background(), []byte("frame")), ErrClosed))
+	})
+
+	t.Run("test dial cancelled", func(t *testing.T) {
+		medium := NewMedium(8)
+		_, err := medium.Listen("device")
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err = medium.Dial(ctx, "device")
+		require.True(t, errors.Is(err, context.Canceled))
+	})
+}
@@ -6,20 +6,26 @@ SPDX-License-Identifier: Apache-2.0
 
 package transport
 
-import "github.com/hyperledger/aries-framework-go/pkg/wallet"
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/wallet"
+)
 
 // OutboundTransport interface definition for transport layer
 // This is the client side of the agent
 type OutboundTransport interface {
-	// Send send a2a exchange data
-	Send(data []byte, destination string) (string, error)
+	// Send send a2a exchange data, the context cancels the send
+	Send(ctx context.Context, data []byte, destination string) (string, error)
 	// Accept url
 	Accept(string) bool
 }
 
 // InboundMessageHandler handles the inbound requests. The transport will unpack the payload prior to the
-// message handle invocation.
-type InboundMessageHandler func(envelope *wallet.Envelope) error
+// message handle invocation, the context is the context of the inbound request.
+type InboundMessageHandler func(ctx context.Context, envelope *wallet.Envelope) error
 
 // InboundProvider contains dependencies for starting the inbound transport.
 // It is typically created by using aries.Context().
@@ -28,6 +34,22 @@ type InboundProvider interface {
 	PackWallet() wallet.Pack
 }
 
+// ErrProfileNotFound is returned by the ProfileProvider for the profiles not served by the agent
+var ErrProfileNotFound = errors.New("profile not found")
+
+// ProfileProvider is optionally implemented by the InboundProvider of the multi-profile deployments. The envelopes
+// received for a profile are unpacked and handled by the provider of the profile, i.e. with its wallet and services.
+type ProfileProvider interface {
+	ProfileInboundProvider(profile string) (InboundProvider, error)
+}
+
+// ProfileEndpointer is optionally implemented by the inbound transports serving the profiles of the multi-profile
+// deployments, e.g. on a path per profile
+type ProfileEndpointer interface {
+	// ProfileEndpoint returns the endpoint the envelopes of the profile are received on
+	ProfileEndpoint(profile string) string
+}
+
 // InboundTransport interface definition for inbound transport layer
 type InboundTransport interface {
 	// starts the inbound transport
@@ -39,3 +61,46 @@ type InboundTransport interface {
 	// returns the endpoint
 	Endpoint() string
 }
+
+// InboundInfo describes how an inbound message was received
+type InboundInfo struct {
+	// Transport is the name of the inbound transport, e.g. http
+	Transport string
+	// ReceivedTime is the time the message was received
+	ReceivedTime time.Time
+	// Profile is the profile the message was received for, empty if the transport doesn't serve profiles
+	Profile string
+}
+
+type inboundInfoKey struct{}
+
+// WithInboundInfo returns a copy of the inbound request context carrying the inbound info
+func WithInboundInfo(ctx context.Context, info *InboundInfo) context.Context {
+	return context.WithValue(ctx, inboundInfoKey{}, info)
+}
+
+// InboundInfoFromContext returns the inbound info carried by the context, if any
+func InboundInfoFromContext(ctx context.Context) (*InboundInfo, bool) {
+	info, ok := ctx.Value(inboundInfoKey{}).(*InboundInfo)
+	return info, ok
+}
+
+// Status is the status of the agent reported by the health check endpoints of the inbound transports
+type Status struct {
+	// Ready is set once the agent has started, until it is closed
+	Ready bool `json:"ready"`
+	// Checks are the states of the failing components of the agent by component, empty if the agent is healthy.
+	// The states don't carry the errors of the components, the errors are logged by the agent.
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// Healthy returns true if none of the components of the agent is failing
+func (s *Status) Healthy() bool {
+	return len(s.Checks) == 0
+}
+
+// StatusProvider is optionally implemented by the InboundProvider to report the status of the agent on the
+// health check endpoints of the inbound transports
+type StatusProvider interface {
+	Status() Status
+}
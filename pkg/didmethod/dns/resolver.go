@@ -0,0 +1,294 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package dns resolves the did:dns DIDs from the DNS records of the domain, so the agents can advertise
+// their DIDComm endpoints and keys in DNS instead of a central configuration:
+//
+//	_didcomm.example.com. TXT "v=didcomm1 key=<base58 Ed25519 key> endpoint=https://agent.example.com priority=1"
+//	_didcomm._tcp.example.com. SRV 0 5 443 agent2.example.com.
+//
+// The DID document of did:dns:example.com has a DIDComm service per endpoint of the TXT and SRV records, the
+// service priority is the priority of the record (lowest first). The keys of the TXT records are only added
+// when the lookup is a SecureLookup: a spoofed DNS answer would otherwise swap the keys of the DID.
+package dns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/btcsuite/btcutil/base58"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	"github.com/hyperledger/aries-framework-go/pkg/framework/didresolver"
+)
+
+const (
+	// Method is the DID method resolved by the resolver
+	Method = "dns"
+
+	txtVersion     = "didcomm1"
+	recordName     = "_didcomm"
+	srvService     = "didcomm"
+	srvProto       = "tcp"
+	defaultScheme  = "https"
+	defaultTimeout = 5 * time.Second
+
+	serviceType = "did-communication"
+	keyType     = "Ed25519VerificationKey2018"
+)
+
+// Lookup looks up the DNS records of the domains, implemented by net.Resolver
+type Lookup interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+	LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+}
+
+// SecureLookup is optionally implemented by the lookups validating the DNSSEC signatures of the answers, e.g. by
+// querying a validating resolver over a trusted path and checking the authenticated data flag
+type SecureLookup interface {
+	// LookupSecureTXT returns the TXT records of the name, an error if the answer isn't DNSSEC validated
+	LookupSecureTXT(ctx context.Context, name string) ([]string, error)
+}
+
+// resolverOpts holds the options of the DNS resolver
+type resolverOpts struct {
+	lookup  Lookup
+	timeout time.Duration
+	scheme  string
+}
+
+// ResolverOpt is the DNS resolver option
+type ResolverOpt func(opts *resolverOpts)
+
+// WithLookup option sets the DNS lookup, e.g. a net.Resolver using a specific name server
+func WithLookup(lookup Lookup) ResolverOpt {
+	return func(opts *resolverOpts) {
+		opts.lookup = lookup
+	}
+}
+
+// WithTimeout option sets the timeout of the DNS lookups of a DID resolution
+func WithTimeout(timeout time.Duration) ResolverOpt {
+	return func(opts *resolverOpts) {
+		opts.timeout = timeout
+	}
+}
+
+// WithSRVScheme option sets the URL scheme of the endpoints of the SRV records, https by default
+func WithSRVScheme(scheme string) ResolverOpt {
+	return func(opts *resolverOpts) {
+		opts.scheme = scheme
+	}
+}
+
+// DIDResolver resolves the did:dns DIDs from the DNS records of the domains
+type DIDResolver struct {
+	lookup  Lookup
+	timeout time.Duration
+	scheme  string
+}
+
+// New creates new DNS DID resolver
+func New(opts ...ResolverOpt) *DIDResolver {
+	resolverOpts := &resolverOpts{lookup: net.DefaultResolver, timeout: defaultTimeout, scheme: defaultScheme}
+	for _, opt := range opts {
+		opt(resolverOpts)
+	}
+
+	return &DIDResolver{lookup: resolverOpts.lookup, timeout: resolverOpts.timeout, scheme: resolverOpts.scheme}
+}
+
+// Read implements didresolver.DidMethod.Read interface (https://w3c-ccg.github.io/did-resolution/#resolving-input)
+func (r *DIDResolver) Read(ctx context.Context, didID string, _ ...didresolver.ResolveOpt) ([]byte, error) {
+	domain := strings.TrimPrefix(didID, "did:"+Method+":")
+	if domain == didID || domain == "" {
+		return nil, fmt.Errorf("invalid did:dns DID: %s", didID)
+	}
+
+	doc, err := r.Discover(ctx, domain)
+	if err != nil {
+		return nil, err
+	}
+
+	jsonDoc, err := doc.JSONBytes()
+	if err != nil {
+		return nil, fmt.Errorf("JSON marshalling of document failed: %w", err)
+	}
+
+	return jsonDoc, nil
+}
+
+// Accept did method
+func (r *DIDResolver) Accept(method string) bool {
+	return method == Method
+}
+
+// endpoint is an endpoint advertised by a DNS record
+type endpoint struct {
+	uri      string
+	priority int
+}
+
+// Discover returns the DID document of the keys and endpoints advertised in the DNS records of the domain,
+// didresolver.ErrNotFound is returned if the domain has no DIDComm records. The lookups are bounded by the timeout
+// of the resolver and the context.
+func (r *DIDResolver) Discover(ctx context.Context, domain string) (*did.Doc, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	keys, endpoints, err := r.lookupTXT(ctx, domain)
+	if err != nil {
+		return nil, err
+	}
+
+	srvEndpoints, err := r.lookupSRV(ctx, domain)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoints = append(endpoints, srvEndpoints...)
+
+	if len(keys) == 0 && len(endpoints) == 0 {
+		return nil, didresolver.ErrNotFound
+	}
+
+	sort.SliceStable(endpoints, func(i, j int) bool {
+		return endpoints[i].priority < endpoints[j].priority
+	})
+
+	didID := "did:" + Method + ":" + domain
+	doc := &did.Doc{Context: []string{did.Context}, ID: didID}
+
+	for i, k := range keys {
+		doc.PublicKey = append(doc.PublicKey, did.PublicKey{ID: fmt.Sprintf("%s#keys-%d", didID, i+1),
+			Type: keyType, Controller: didID, Value: k})
+	}
+
+	for i, e := range endpoints {
+		doc.Service = append(doc.Service, did.Service{ID: fmt.Sprintf("%s#endpoint-%d", didID, i+1),
+			Type: serviceType, ServiceEndpoint: e.uri, Properties: map[string]interface{}{"priority": e.priority}})
+	}
+
+	return doc, nil
+}
+
+// lookupTXT returns the keys and endpoints of the DIDComm TXT records of the domain, the keys are only returned
+// from a secure lookup
+func (r *DIDResolver) lookupTXT(ctx context.Context, domain string) ([][]byte, []endpoint, error) {
+	secure, validated := r.lookup.(SecureLookup)
+
+	var records []string
+
+	var err error
+
+	if validated {
+		records, err = secure.LookupSecureTXT(ctx, recordName+"."+domain)
+	} else {
+		records, err = r.lookup.LookupTXT(ctx, recordName+"."+domain)
+	}
+
+	if err != nil {
+		if isNotFound(err) {
+			return nil, nil, nil
+		}
+
+		return nil, nil, fmt.Errorf("failed to look up TXT records of %s: %w", domain, err)
+	}
+
+	var keys [][]byte
+
+	var endpoints []endpoint
+
+	for _, record := range records {
+		attrs := parseTXT(record)
+		if attrs["v"] == nil || attrs["v"][0] != txtVersion {
+			continue
+		}
+
+		priority := 0
+
+		if p := attrs["priority"]; p != nil {
+			priority, err = strconv.Atoi(p[0])
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid priority in TXT record of %s: %w", domain, err)
+			}
+		}
+
+		if validated {
+			if keys, err = appendKeys(keys, attrs["key"]); err != nil {
+				return nil, nil, fmt.Errorf("invalid key in TXT record of %s: %w", domain, err)
+			}
+		}
+
+		for _, e := range attrs["endpoint"] {
+			endpoints = append(endpoints, endpoint{uri: e, priority: priority})
+		}
+	}
+
+	return keys, endpoints, nil
+}
+
+// appendKeys appends the base58 keys of a TXT record to the keys
+func appendKeys(keys [][]byte, encoded []string) ([][]byte, error) {
+	for _, k := range encoded {
+		key := base58.Decode(k)
+		if len(key) == 0 {
+			return nil, errors.New(k)
+		}
+
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+// lookupSRV returns the endpoints of the DIDComm SRV records of the domain
+func (r *DIDResolver) lookupSRV(ctx context.Context, domain string) ([]endpoint, error) {
+	_, records, err := r.lookup.LookupSRV(ctx, srvService, srvProto, domain)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("failed to look up SRV records of %s: %w", domain, err)
+	}
+
+	endpoints := make([]endpoint, len(records))
+
+	for i, srv := range records {
+		host := strings.TrimSuffix(srv.Target, ".")
+		uri := fmt.Sprintf("%s://%s", r.scheme, net.JoinHostPort(host, strconv.Itoa(int(srv.Port))))
+		endpoints[i] = endpoint{uri: uri, priority: int(srv.Priority)}
+	}
+
+	return endpoints, nil
+}
+
+// parseTXT parses the space separated name=value attributes of a TXT record
+func parseTXT(record string) map[string][]string {
+	attrs := make(map[string][]string)
+
+	for _, field := range strings.Fields(record) {
+		parts := strings.SplitN(field, "=", 2)
+		if len(parts) == 2 {
+			attrs[parts[0]] = append(attrs[parts[0]], parts[1])
+		}
+	}
+
+	return attrs
+}
+
+func isNotFound(err error) bool {
+	var dnsErr *net.DNSError
+
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
@@ -0,0 +1,161 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package dns
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcutil/base58"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	"github.com/hyperledger/aries-framework-go/pkg/framework/didresolver"
+)
+
+const testKey = "B12NYF8RrR3h41TDCTJojY59usg3mbtbjnFs7Eud1Y6u"
+
+func TestDIDResolver_Read(t *testing.T) {
+	t.Run("test resolve TXT and SRV records", func(t *testing.T) {
+		lookup := &mockLookup{
+			txt: map[string][]string{"_didcomm.example.com": {
+				"v=didcomm1 key=" + testKey + " endpoint=https://agent.example.com/didcomm priority=1",
+				"v=spf1 include:example.com",
+			}},
+			srv: map[string][]*net.SRV{"example.com": {{Target: "agent2.example.com.", Port: 8443, Priority: 0}}},
+		}
+
+		r := New(WithLookup(&secureLookup{mockLookup: lookup}), WithTimeout(time.Second))
+		require.True(t, r.Accept("dns"))
+		require.False(t, r.Accept("peer"))
+
+		bytes, err := r.Read(context.Background(), "did:dns:example.com")
+		require.NoError(t, err)
+
+		doc, err := did.ParseDocument(bytes)
+		require.NoError(t, err)
+		require.Equal(t, "did:dns:example.com", doc.ID)
+		require.Len(t, doc.PublicKey, 1)
+		require.Equal(t, base58.Decode(testKey), doc.PublicKey[0].Value)
+		require.Len(t, doc.Service, 2)
+		require.Equal(t, "https://agent2.example.com:8443", doc.Service[0].ServiceEndpoint)
+		require.Equal(t, "https://agent.example.com/didcomm", doc.Service[1].ServiceEndpoint)
+		require.Equal(t, float64(1), doc.Service[1].Properties["priority"])
+	})
+
+	t.Run("test keys of unvalidated TXT records are ignored", func(t *testing.T) {
+		lookup := &mockLookup{txt: map[string][]string{"_didcomm.example.com": {
+			"v=didcomm1 key=" + testKey + " endpoint=https://agent.example.com/didcomm",
+		}}}
+
+		doc, err := New(WithLookup(lookup)).Discover(context.Background(), "example.com")
+		require.NoError(t, err)
+		require.Empty(t, doc.PublicKey)
+		require.Len(t, doc.Service, 1)
+		require.Equal(t, "https://agent.example.com/didcomm", doc.Service[0].ServiceEndpoint)
+
+		_, err = New(WithLookup(&secureLookup{mockLookup: lookup, err: errors.New("answer not validated")})).
+			Discover(context.Background(), "example.com")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "answer not validated")
+	})
+
+	t.Run("test resolve with the DID resolver", func(t *testing.T) {
+		lookup := &mockLookup{srv: map[string][]*net.SRV{"example.com": {{Target: "agent.example.com.", Port: 80}}}}
+
+		resolver := didresolver.New(didresolver.WithDidMethod(New(WithLookup(lookup), WithSRVScheme("http"))))
+
+		doc, err := resolver.Resolve("did:dns:example.com")
+		require.NoError(t, err)
+		require.Len(t, doc.Service, 1)
+		require.Equal(t, "http://agent.example.com:80", doc.Service[0].ServiceEndpoint)
+	})
+
+	t.Run("test no DIDComm records", func(t *testing.T) {
+		_, err := New(WithLookup(&mockLookup{})).Read(context.Background(), "did:dns:example.com")
+		require.True(t, errors.Is(err, didresolver.ErrNotFound))
+	})
+
+	t.Run("test invalid DID", func(t *testing.T) {
+		_, err := New(WithLookup(&mockLookup{})).Read(context.Background(), "did:peer:123")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid did:dns DID")
+	})
+
+	t.Run("test lookup failures", func(t *testing.T) {
+		_, err := New(WithLookup(&mockLookup{err: errors.New("lookup error")})).Read(context.Background(), "did:dns:example.com")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to look up TXT records of example.com: lookup error")
+
+		_, err = New(WithLookup(&mockLookup{srvErr: errors.New("lookup error")})).Read(context.Background(), "did:dns:example.com")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to look up SRV records of example.com: lookup error")
+	})
+
+	t.Run("test invalid TXT records", func(t *testing.T) {
+		_, err := New(WithLookup(&mockLookup{txt: map[string][]string{
+			"_didcomm.example.com": {"v=didcomm1 endpoint=https://agent.example.com priority=high"}}})).
+			Read(context.Background(), "did:dns:example.com")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid priority in TXT record of example.com")
+
+		_, err = New(WithLookup(&secureLookup{mockLookup: &mockLookup{txt: map[string][]string{
+			"_didcomm.example.com": {"v=didcomm1 key=0OIl"}}}})).Read(context.Background(), "did:dns:example.com")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid key in TXT record of example.com")
+	})
+}
+
+type mockLookup struct {
+	txt    map[string][]string
+	srv    map[string][]*net.SRV
+	err    error
+	srvErr error
+}
+
+func (m *mockLookup) LookupTXT(_ context.Context, name string) ([]string, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	records, ok := m.txt[name]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+	}
+
+	return records, nil
+}
+
+func (m *mockLookup) LookupSRV(_ context.Context, service, proto, name string) (string, []*net.SRV, error) {
+	if m.srvErr != nil {
+		return "", nil, m.srvErr
+	}
+
+	records, ok := m.srv[name]
+	if !ok {
+		return "", nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+	}
+
+	return "_" + service + "._" + proto + "." + name, records, nil
+}
+
+// secureLookup validates the answers of the mock lookup
+type secureLookup struct {
+	*mockLookup
+	err error
+}
+
+func (s *secureLookup) LookupSecureTXT(ctx context.Context, name string) ([]string, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	return s.LookupTXT(ctx, name)
+}
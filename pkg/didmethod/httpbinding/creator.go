@@ -0,0 +1,66 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package httpbinding
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	"github.com/hyperledger/aries-framework-go/pkg/framework/vdr"
+)
+
+const didLDJSON = "application/did+ld+json"
+
+// Create implements vdr.VDR.Create interface. The DID document is uploaded to the web server
+// at the same location it is resolved from.
+func (res *DIDResolver) Create(doc *did.Doc, _ ...vdr.CreateOpt) (*did.Doc, error) {
+	if doc == nil || doc.ID == "" {
+		return nil, errors.New("DID and document are mandatory")
+	}
+
+	reqURL, err := url.ParseRequestURI(res.endpointURL)
+	if err != nil {
+		return nil, fmt.Errorf("url parse request uri failed: %w", err)
+	}
+
+	reqURL.Path = path.Join(reqURL.Path, doc.ID)
+
+	jsonDoc, err := doc.JSONBytes()
+	if err != nil {
+		return nil, fmt.Errorf("JSON marshalling of document failed: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPut, reqURL.String(), bytes.NewReader(jsonDoc))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP Put request: %w", err)
+	}
+
+	req.Header.Set("Content-type", didLDJSON)
+
+	resp, err := res.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP Put request failed: %w", err)
+	}
+
+	defer func() {
+		e := resp.Body.Close()
+		if e != nil {
+			logger.Errorf("Failed to close response body: %v", e)
+		}
+	}()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated, http.StatusNoContent:
+		return doc, nil
+	default:
+		return nil, fmt.Errorf("unsupported response from DID upload [%v]", resp.StatusCode)
+	}
+}
@@ -0,0 +1,72 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+package httpbinding
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+)
+
+func TestCreate_DIDDoc(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		require.Equal(t, http.MethodPut, req.Method)
+		require.Equal(t, "/did:example:334455", req.URL.String())
+		require.Equal(t, didLDJSON, req.Header.Get("Content-type"))
+
+		body, err := ioutil.ReadAll(req.Body)
+		require.NoError(t, err)
+		require.Contains(t, string(body), "did:example:334455")
+
+		res.WriteHeader(http.StatusCreated)
+	}))
+	defer testServer.Close()
+
+	resolver, err := New(testServer.URL)
+	require.NoError(t, err)
+
+	doc, err := resolver.Create(&did.Doc{Context: []string{did.Context}, ID: "did:example:334455"})
+	require.NoError(t, err)
+	require.Equal(t, "did:example:334455", doc.ID)
+}
+
+func TestCreate_Failed(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.WriteHeader(http.StatusForbidden)
+	}))
+	defer testServer.Close()
+
+	resolver, err := New(testServer.URL)
+	require.NoError(t, err)
+
+	t.Run("test missing document", func(t *testing.T) {
+		doc, err := resolver.Create(nil)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "DID and document are mandatory")
+		require.Nil(t, doc)
+	})
+
+	t.Run("test upload rejected", func(t *testing.T) {
+		doc, err := resolver.Create(&did.Doc{Context: []string{did.Context}, ID: "did:example:334455"})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unsupported response from DID upload [403]")
+		require.Nil(t, doc)
+	})
+
+	t.Run("test HTTP put failed", func(t *testing.T) {
+		resolver, err := New("http://localhost:1")
+		require.NoError(t, err)
+
+		doc, err := resolver.Create(&did.Doc{Context: []string{did.Context}, ID: "did:example:334455"})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "HTTP Put request failed")
+		require.Nil(t, doc)
+	})
+}
@@ -6,6 +6,7 @@ SPDX-License-Identifier: Apache-2.0
 package httpbinding
 
 import (
+	"context"
 	"crypto/tls"
 	"fmt"
 	"io/ioutil"
@@ -46,8 +47,13 @@ func WithTLSConfig(tlsConfig *tls.Config) ResolverOpt {
 }
 
 // resolveDID makes DID resolution via HTTP
-func (res *DIDResolver) resolveDID(uri string) ([]byte, error) {
-	resp, err := res.client.Get(uri)
+func (res *DIDResolver) resolveDID(ctx context.Context, uri string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP request creation failed: %w", err)
+	}
+
+	resp, err := res.client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("HTTP Get request failed: %w", err)
 	}
@@ -70,6 +76,8 @@ func (res *DIDResolver) resolveDID(uri string) ([]byte, error) {
 		return gotBody, nil
 	} else if notExistentDID(resp) {
 		return nil, fmt.Errorf("DID does not exist: %w", err)
+	} else if resp.StatusCode == http.StatusGone {
+		return nil, didresolver.ErrDeactivated
 	}
 
 	return nil, fmt.Errorf("unsupported response from DID resolver [%v]", resp.StatusCode)
@@ -106,7 +114,7 @@ func New(endpointURL string, opts ...ResolverOpt) (*DIDResolver, error) {
 }
 
 // Read implements didresolver.DidMethod.Read interface (https://w3c-ccg.github.io/did-resolution/#resolving-input)
-func (res *DIDResolver) Read(did string, _ ...didresolver.ResolveOpt) ([]byte, error) {
+func (res *DIDResolver) Read(ctx context.Context, did string, _ ...didresolver.ResolveOpt) ([]byte, error) {
 	reqURL, err := url.ParseRequestURI(res.endpointURL)
 	if err != nil {
 		return nil, fmt.Errorf("url parse request uri failed: %w", err)
@@ -114,7 +122,7 @@ func (res *DIDResolver) Read(did string, _ ...didresolver.ResolveOpt) ([]byte, e
 
 	reqURL.Path = path.Join(reqURL.Path, did)
 
-	return res.resolveDID(reqURL.String())
+	return res.resolveDID(ctx, reqURL.String())
 }
 
 // Accept did method - attempt to resolve any method
@@ -5,12 +5,16 @@ SPDX-License-Identifier: Apache-2.0
 package httpbinding
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/require"
+
+	"github.com/hyperledger/aries-framework-go/pkg/framework/didresolver"
 )
 
 func TestWithOutboundOpts(t *testing.T) {
@@ -65,7 +69,7 @@ func TestRead_DIDDoc(t *testing.T) {
 
 	resolver, err := New(testServer.URL)
 	require.NoError(t, err)
-	gotDocument, err := resolver.Read("did:example:334455")
+	gotDocument, err := resolver.Read(context.Background(), "did:example:334455")
 	require.NoError(t, err)
 	require.Equal(t, []byte("did doc body"), gotDocument)
 }
@@ -82,11 +86,38 @@ func TestRead_DIDDocWithBasePath(t *testing.T) {
 
 	resolver, err := New(testServer.URL + "/document")
 	require.NoError(t, err)
-	gotDocument, err := resolver.Read("did:example:334455")
+	gotDocument, err := resolver.Read(context.Background(), "did:example:334455")
 	require.NoError(t, err)
 	require.Equal(t, []byte("did doc body"), gotDocument)
 }
 
+func TestRead_DIDDeactivated(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.WriteHeader(http.StatusGone)
+	}))
+	defer func() { testServer.Close() }()
+
+	resolver, err := New(testServer.URL)
+	require.NoError(t, err)
+	_, err = resolver.Read(context.Background(), "did:example:334455")
+	require.True(t, errors.Is(err, didresolver.ErrDeactivated))
+}
+
+func TestRead_ContextCancelled(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.WriteHeader(http.StatusOK)
+	}))
+	defer func() { testServer.Close() }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	resolver, err := New(testServer.URL)
+	require.NoError(t, err)
+	_, err = resolver.Read(ctx, "did:example:334455")
+	require.True(t, errors.Is(err, context.Canceled))
+}
+
 func TestRead_DIDDocWithBasePathWithSlashes(t *testing.T) {
 	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
 		require.Equal(t, "/document/did:example:334455", req.URL.String())
@@ -99,7 +130,7 @@ func TestRead_DIDDocWithBasePathWithSlashes(t *testing.T) {
 
 	resolver, err := New(testServer.URL + "/document/")
 	require.NoError(t, err)
-	gotDocument, err := resolver.Read("did:example:334455")
+	gotDocument, err := resolver.Read(context.Background(), "did:example:334455")
 	require.NoError(t, err)
 	require.Equal(t, []byte("did doc body"), gotDocument)
 }
@@ -115,7 +146,7 @@ func TestRead_DIDDocNotFound(t *testing.T) {
 
 	resolver, err := New(testServer.URL)
 	require.NoError(t, err)
-	_, err = resolver.Read("did:example:334455")
+	_, err = resolver.Read(context.Background(), "did:example:334455")
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "DID does not exist")
 }
@@ -128,7 +159,7 @@ func TestRead_UnsupportedStatus(t *testing.T) {
 
 	resolver, err := New(testServer.URL)
 	require.NoError(t, err)
-	_, err = resolver.Read("did:example:334455")
+	_, err = resolver.Read(context.Background(), "did:example:334455")
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "unsupported response from DID resolver")
 }
@@ -142,7 +173,7 @@ func TestRead_HTTPGetFailed(t *testing.T) {
 
 	resolver, err := New(testServer.URL)
 	require.NoError(t, err)
-	_, err = resolver.Read("did:example:334455")
+	_, err = resolver.Read(context.Background(), "did:example:334455")
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "HTTP Get request failed")
 }
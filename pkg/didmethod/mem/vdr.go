@@ -0,0 +1,238 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package mem implements a DID method keeping the DID documents in memory, for the protocol tests and the
+// sample apps exercising the public DID flows without network or ledger. The VDR is both the vdr.VDR and the
+// didresolver.DidMethod of the method:
+//
+//	v := mem.New()
+//	framework, err := aries.New(aries.WithVDR(v),
+//	    aries.WithDIDResolver(didresolver.New(didresolver.WithDidMethod(v))))
+//
+// The documents are copied in and out of the VDR, and the whole registry is saved with Snapshot and rolled back
+// with Restore, e.g. between the cases of a test.
+package mem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/hyperledger/aries-framework-go/pkg/common/clock"
+	"github.com/hyperledger/aries-framework-go/pkg/common/idgen"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	"github.com/hyperledger/aries-framework-go/pkg/framework/didresolver"
+	"github.com/hyperledger/aries-framework-go/pkg/framework/vdr"
+)
+
+// DefaultMethod is the default DID method of the VDR
+const DefaultMethod = "mem"
+
+var (
+	// ErrExists is returned when a DID document is created for a DID already registered
+	ErrExists = errors.New("DID already exists")
+	// ErrDeactivated is returned when a deactivated DID is read or updated
+	ErrDeactivated = didresolver.ErrDeactivated
+)
+
+// record is the registered state of a DID
+type record struct {
+	doc         []byte
+	deactivated bool
+}
+
+// Snapshot is the saved state of the VDR
+type Snapshot struct {
+	records map[string]record
+}
+
+// VDR registers and resolves the DID documents of the method
+type VDR struct {
+	method  string
+	clock   clock.Clock
+	ids     idgen.Generator
+	lock    sync.RWMutex
+	records map[string]record
+}
+
+// Opt configures the in-memory VDR
+type Opt func(v *VDR)
+
+// WithMethod sets the DID method served by the VDR, DefaultMethod by default
+func WithMethod(method string) Opt {
+	return func(v *VDR) {
+		v.method = method
+	}
+}
+
+// WithClock sets the clock of the created and updated times of the documents, the system clock by default
+func WithClock(c clock.Clock) Opt {
+	return func(v *VDR) {
+		v.clock = c
+	}
+}
+
+// WithIDGenerator sets the generator of the random DIDs, random UUIDs by default
+func WithIDGenerator(g idgen.Generator) Opt {
+	return func(v *VDR) {
+		v.ids = g
+	}
+}
+
+// New returns new empty in-memory VDR
+func New(opts ...Opt) *VDR {
+	v := &VDR{method: DefaultMethod, clock: clock.System(), ids: idgen.UUID(), records: make(map[string]record)}
+
+	for _, opt := range opts {
+		opt(v)
+	}
+
+	return v
+}
+
+// Accept accepts the DID method of the VDR
+func (v *VDR) Accept(method string) bool {
+	return method == v.method
+}
+
+// Create registers the DID document, a random DID of the method is assigned to the documents without ID
+func (v *VDR) Create(doc *did.Doc, _ ...vdr.CreateOpt) (*did.Doc, error) {
+	if doc == nil {
+		return nil, errors.New("DID document is mandatory")
+	}
+
+	created := *doc
+	if created.ID == "" {
+		created.ID = fmt.Sprintf("did:%s:%s", v.method, v.ids.NewID())
+	}
+
+	if created.Created == nil {
+		now := v.clock.Now().UTC()
+		created.Created = &now
+	}
+
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	if _, ok := v.records[created.ID]; ok {
+		return nil, fmt.Errorf("%w: %s", ErrExists, created.ID)
+	}
+
+	return v.put(&created)
+}
+
+// Update replaces the document of a registered DID
+func (v *VDR) Update(doc *did.Doc) (*did.Doc, error) {
+	if doc == nil {
+		return nil, errors.New("DID document is mandatory")
+	}
+
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	if err := v.check(doc.ID); err != nil {
+		return nil, err
+	}
+
+	updated := *doc
+	now := v.clock.Now().UTC()
+	updated.Updated = &now
+
+	return v.put(&updated)
+}
+
+// Deactivate deactivates the DID, the DID is no longer resolved and can't be registered again
+func (v *VDR) Deactivate(id string) error {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	if err := v.check(id); err != nil {
+		return err
+	}
+
+	r := v.records[id]
+	r.deactivated = true
+	v.records[id] = r
+
+	return nil
+}
+
+// Read returns the document of the DID, didresolver.ErrNotFound if the DID isn't registered and ErrDeactivated
+// if it is deactivated. The versions of the documents aren't kept, the current document is returned.
+func (v *VDR) Read(_ context.Context, id string, _ ...didresolver.ResolveOpt) ([]byte, error) {
+	v.lock.RLock()
+	defer v.lock.RUnlock()
+
+	if err := v.check(id); err != nil {
+		return nil, err
+	}
+
+	r := v.records[id]
+
+	return append([]byte(nil), r.doc...), nil
+}
+
+// Snapshot saves the state of the VDR
+func (v *VDR) Snapshot() *Snapshot {
+	v.lock.RLock()
+	defer v.lock.RUnlock()
+
+	return &Snapshot{records: copyRecords(v.records)}
+}
+
+// Restore rolls the VDR back to the snapshot, the snapshot may be restored several times
+func (v *VDR) Restore(s *Snapshot) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	v.records = copyRecords(s.records)
+}
+
+func (v *VDR) check(id string) error {
+	r, ok := v.records[id]
+	if !ok {
+		return fmt.Errorf("%w: %s", didresolver.ErrNotFound, id)
+	}
+
+	if r.deactivated {
+		return fmt.Errorf("%w: %s", ErrDeactivated, id)
+	}
+
+	return nil
+}
+
+// put stores the document and returns the copy of the stored document, called with the lock held
+func (v *VDR) put(doc *did.Doc) (*did.Doc, error) {
+	if !strings.HasPrefix(doc.ID, "did:"+v.method+":") {
+		return nil, fmt.Errorf("DID %s isn't a DID of the method %s", doc.ID, v.method)
+	}
+
+	bytes, err := doc.JSONBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	stored, err := did.ParseDocument(bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DID document: %w", err)
+	}
+
+	v.records[doc.ID] = record{doc: bytes}
+
+	return stored, nil
+}
+
+// copyRecords returns the copy of the records, the documents are never modified and are shared by the copies
+func copyRecords(records map[string]record) map[string]record {
+	c := make(map[string]record, len(records))
+	for id, r := range records {
+		c[id] = r
+	}
+
+	return c
+}
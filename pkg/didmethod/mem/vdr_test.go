@@ -0,0 +1,177 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package mem
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hyperledger/aries-framework-go/pkg/common/idgen"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	"github.com/hyperledger/aries-framework-go/pkg/framework/didresolver"
+	"github.com/hyperledger/aries-framework-go/pkg/framework/vdr"
+	mockclock "github.com/hyperledger/aries-framework-go/pkg/internal/mock/clock"
+)
+
+func TestVDR(t *testing.T) {
+	clock := &mockclock.Clock{Time: time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)}
+	v := New(WithClock(clock))
+
+	require.True(t, v.Accept(DefaultMethod))
+	require.False(t, v.Accept("peer"))
+
+	t.Run("test create and resolve", func(t *testing.T) {
+		doc, err := v.Create(newDoc(""))
+		require.NoError(t, err)
+		require.True(t, strings.HasPrefix(doc.ID, "did:mem:"))
+		require.Equal(t, clock.Now(), *doc.Created)
+		require.Len(t, doc.PublicKey, 1)
+
+		resolver := didresolver.New(didresolver.WithDidMethod(v))
+
+		resolved, err := resolver.Resolve(doc.ID)
+		require.NoError(t, err)
+		require.Equal(t, doc.ID, resolved.ID)
+		require.Equal(t, doc.PublicKey[0].Value, resolved.PublicKey[0].Value)
+
+		_, err = resolver.Resolve("did:mem:unknown")
+		require.Equal(t, didresolver.ErrNotFound, err)
+
+		_, err = v.Create(doc)
+		require.True(t, errors.Is(err, ErrExists))
+	})
+
+	t.Run("test create through the registry", func(t *testing.T) {
+		doc, err := vdr.New(vdr.WithVDR(v)).Create(DefaultMethod, newDoc("did:mem:registry"))
+		require.NoError(t, err)
+		require.Equal(t, "did:mem:registry", doc.ID)
+	})
+
+	t.Run("test update and deactivate", func(t *testing.T) {
+		doc, err := v.Create(newDoc("did:mem:update"))
+		require.NoError(t, err)
+
+		clock.Add(time.Hour)
+		doc.Service = []did.Service{{ID: "did:mem:update#agent", Type: "did-communication",
+			ServiceEndpoint: "https://example.com"}}
+
+		updated, err := v.Update(doc)
+		require.NoError(t, err)
+		require.Equal(t, clock.Now(), *updated.Updated)
+
+		bytes, err := v.Read(context.Background(), doc.ID)
+		require.NoError(t, err)
+
+		resolved, err := did.ParseDocument(bytes)
+		require.NoError(t, err)
+		require.Len(t, resolved.Service, 1)
+
+		require.NoError(t, v.Deactivate(doc.ID))
+
+		_, err = v.Read(context.Background(), doc.ID)
+		require.True(t, errors.Is(err, didresolver.ErrDeactivated))
+
+		_, err = v.Update(doc)
+		require.True(t, errors.Is(err, ErrDeactivated))
+		require.True(t, errors.Is(v.Deactivate(doc.ID), ErrDeactivated))
+
+		_, err = v.Create(doc)
+		require.True(t, errors.Is(err, ErrExists))
+
+		_, err = v.Update(newDoc("did:mem:unknown"))
+		require.True(t, errors.Is(err, didresolver.ErrNotFound))
+	})
+
+	t.Run("test snapshot and restore", func(t *testing.T) {
+		doc, err := v.Create(newDoc("did:mem:snapshot"))
+		require.NoError(t, err)
+
+		snapshot := v.Snapshot()
+
+		_, err = v.Create(newDoc("did:mem:after"))
+		require.NoError(t, err)
+
+		doc.Service = []did.Service{{ID: "did:mem:snapshot#agent", Type: "did-communication",
+			ServiceEndpoint: "https://example.com"}}
+		_, err = v.Update(doc)
+		require.NoError(t, err)
+		require.NoError(t, v.Deactivate("did:mem:registry"))
+
+		for i := 0; i < 2; i++ {
+			v.Restore(snapshot)
+
+			_, err = v.Read(context.Background(), "did:mem:after")
+			require.True(t, errors.Is(err, didresolver.ErrNotFound))
+
+			_, err = v.Read(context.Background(), "did:mem:registry")
+			require.NoError(t, err)
+
+			bytes, e := v.Read(context.Background(), "did:mem:snapshot")
+			require.NoError(t, e)
+
+			resolved, e := did.ParseDocument(bytes)
+			require.NoError(t, e)
+			require.Empty(t, resolved.Service)
+
+			// the restored VDR doesn't modify the snapshot
+			_, err = v.Create(newDoc("did:mem:after"))
+			require.NoError(t, err)
+		}
+	})
+
+	t.Run("test invalid documents", func(t *testing.T) {
+		_, err := v.Create(nil)
+		require.Error(t, err)
+
+		_, err = v.Update(nil)
+		require.Error(t, err)
+
+		_, err = v.Create(newDoc("did:peer:123"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "isn't a DID of the method mem")
+
+		doc := newDoc("did:mem:invalid")
+		doc.Context = nil
+
+		_, err = v.Create(doc)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid DID document")
+
+		_, err = v.Read(context.Background(), "did:mem:invalid")
+		require.True(t, errors.Is(err, didresolver.ErrNotFound))
+	})
+
+	t.Run("test method", func(t *testing.T) {
+		doc, err := New(WithMethod("example")).Create(newDoc(""))
+		require.NoError(t, err)
+		require.True(t, strings.HasPrefix(doc.ID, "did:example:"))
+	})
+
+	t.Run("test ID generator", func(t *testing.T) {
+		doc, err := New(WithIDGenerator(idgen.Sequence("id"))).Create(newDoc(""))
+		require.NoError(t, err)
+		require.Equal(t, "did:mem:id-1", doc.ID)
+	})
+}
+
+func newDoc(id string) *did.Doc {
+	return &did.Doc{
+		Context: []string{"https://w3id.org/did/v1"},
+		ID:      id,
+		PublicKey: []did.PublicKey{{
+			ID:         id + "#key-1",
+			Type:       "Ed25519VerificationKey2018",
+			Controller: id,
+			Value:      []byte("public key"),
+		}},
+	}
+}
@@ -7,9 +7,12 @@ SPDX-License-Identifier: Apache-2.0
 package peer
 
 import (
+	"context"
 	"fmt"
 
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
 	"github.com/hyperledger/aries-framework-go/pkg/framework/didresolver"
+	"github.com/hyperledger/aries-framework-go/pkg/framework/vdr"
 )
 
 // DIDResolver resolver
@@ -23,7 +26,7 @@ func NewDIDResolver(store *DIDStore) *DIDResolver {
 }
 
 // Read implements didresolver.DidMethod.Read interface (https://w3c-ccg.github.io/did-resolution/#resolving-input)
-func (resl *DIDResolver) Read(did string, _ ...didresolver.ResolveOpt) ([]byte, error) {
+func (resl *DIDResolver) Read(_ context.Context, did string, _ ...didresolver.ResolveOpt) ([]byte, error) {
 	// get the document from the store
 	doc, err := resl.store.Get(did)
 	if err != nil {
@@ -43,6 +46,15 @@ func (resl *DIDResolver) Read(did string, _ ...didresolver.ResolveOpt) ([]byte,
 	return jsonDoc, nil
 }
 
+// Create implements vdr.VDR.Create interface, peer DID documents are saved in the local store
+func (resl *DIDResolver) Create(doc *did.Doc, _ ...vdr.CreateOpt) (*did.Doc, error) {
+	if err := resl.store.Put(doc, nil); err != nil {
+		return nil, fmt.Errorf("failed to save peer DID document: %w", err)
+	}
+
+	return doc, nil
+}
+
 // Accept did method
 func (resl *DIDResolver) Accept(method string) bool {
 	return method == "peer"
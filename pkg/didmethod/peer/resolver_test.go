@@ -7,6 +7,7 @@ SPDX-License-Identifier: Apache-2.0
 package peer
 
 import (
+	"context"
 	"encoding/json"
 	"testing"
 
@@ -14,6 +15,7 @@ import (
 
 	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
 	"github.com/hyperledger/aries-framework-go/pkg/framework/didresolver"
+	"github.com/hyperledger/aries-framework-go/pkg/framework/vdr"
 	"github.com/hyperledger/aries-framework-go/pkg/internal/mock/storage"
 )
 
@@ -71,31 +73,31 @@ func TestPeerDIDResolver(t *testing.T) {
 	dbstore, err := prov.OpenStore(StoreNamespace)
 	require.NoError(t, err)
 
-	context := []string{"https://w3id.org/did/v1"}
+	didContext := []string{"https://w3id.org/did/v1"}
 
 	// save did document
 	store := NewDIDStore(dbstore)
-	err = store.Put(&did.Doc{Context: context, ID: peerDID}, nil)
+	err = store.Put(&did.Doc{Context: didContext, ID: peerDID}, nil)
 	require.NoError(t, err)
 
 	resl := NewDIDResolver(store)
-	doc, err := resl.Read(peerDID)
+	doc, err := resl.Read(context.Background(), peerDID)
 	require.NoError(t, err)
 
-	document := &did.Doc{Context: context}
+	document := &did.Doc{Context: didContext}
 	err = json.Unmarshal(doc, document)
 	require.NoError(t, err)
 	require.Equal(t, peerDID, document.ID)
 
 	// empty DID
-	_, err = resl.Read("")
+	_, err = resl.Read(context.Background(), "")
 	require.Error(t, err)
 
 	// missing DID
 	// TODO this test should assert that didresolver.ErrNotFound is returned.
 	//      that is currently impossible since the underlying store returns a
 	//      generic error when the object is not found.
-	_, err = resl.Read("did:peer:789")
+	_, err = resl.Read(context.Background(), "did:peer:789")
 	require.Error(t, err)
 }
 
@@ -119,3 +121,29 @@ func TestWithDIDResolveAPI(t *testing.T) {
 	_, err = r.Resolve("did:peer:789")
 	require.Error(t, err)
 }
+
+func TestPeerDIDResolver_Create(t *testing.T) {
+	prov := storage.NewMockStoreProvider()
+	dbstore, err := prov.OpenStore(StoreNamespace)
+	require.NoError(t, err)
+
+	resl := NewDIDResolver(NewDIDStore(dbstore))
+
+	t.Run("test create through vdr registry", func(t *testing.T) {
+		registry := vdr.New(vdr.WithVDR(resl))
+		doc, err := registry.Create("peer", &did.Doc{Context: []string{"https://w3id.org/did/v1"}, ID: peerDID})
+		require.NoError(t, err)
+		require.Equal(t, peerDID, doc.ID)
+
+		docBytes, err := resl.Read(context.Background(), peerDID)
+		require.NoError(t, err)
+		require.Contains(t, string(docBytes), peerDID)
+	})
+
+	t.Run("test create with missing DID", func(t *testing.T) {
+		doc, err := resl.Create(&did.Doc{})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to save peer DID document")
+		require.Nil(t, doc)
+	})
+}
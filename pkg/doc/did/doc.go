@@ -12,6 +12,7 @@ import (
 	"encoding/pem"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/btcsuite/btcutil/base58"
@@ -21,8 +22,11 @@ import (
 )
 
 const (
-	// Context of the DID document
-	Context            = "https://w3id.org/did/v1"
+	// Context of the DID document as defined by the DID v1 draft
+	Context = "https://w3id.org/did/v1"
+	// ContextDIDCore of the DID document as defined by W3C DID Core
+	ContextDIDCore = "https://www.w3.org/ns/did/v1"
+
 	jsonldType         = "type"
 	jsonldID           = "id"
 	jsonldServicePoint = "serviceEndpoint"
@@ -45,17 +49,25 @@ const (
   ],
   "properties": {
     "@context": {
-      "type": "array",
-      "items": [
+      "oneOf": [
         {
           "type": "string",
-          "pattern": "^https://w3id.org/did/v1$"
+          "pattern": "^https://(w3id.org/did/v1|www.w3.org/ns/did/v1)$"
+        },
+        {
+          "type": "array",
+          "items": [
+            {
+              "type": "string",
+              "pattern": "^https://(w3id.org/did/v1|www.w3.org/ns/did/v1)$"
+            }
+          ],
+          "additionalItems": {
+            "type": "string",
+            "format": "uri"
+          }
         }
-      ],
-      "additionalItems": {
-        "type": "string",
-        "format": "uri"
-      }
+      ]
     },
     "id": {
       "type": "string"
@@ -66,6 +78,12 @@ const (
         "$ref": "#/definitions/publicKey"
       }
     },
+    "verificationMethod": {
+      "type": "array",
+      "items": {
+        "$ref": "#/definitions/publicKey"
+      }
+    },
     "authentication": {
       "type": "array",
       "items": {
@@ -79,6 +97,18 @@ const (
         ]
       }
     },
+    "assertionMethod": {
+      "$ref": "#/definitions/verificationRelationship"
+    },
+    "keyAgreement": {
+      "$ref": "#/definitions/verificationRelationship"
+    },
+    "capabilityInvocation": {
+      "$ref": "#/definitions/verificationRelationship"
+    },
+    "capabilityDelegation": {
+      "$ref": "#/definitions/verificationRelationship"
+    },
     "service": {
       "type": "array",
       "items": {
@@ -99,6 +129,19 @@ const (
     }
   },
   "definitions": {
+    "verificationRelationship": {
+      "type": "array",
+      "items": {
+        "oneOf": [
+          {
+            "$ref": "#/definitions/publicKey"
+          },
+          {
+            "type": "string"
+          }
+        ]
+      }
+    },
 	"proof": {
       "type": "object",
       "required": [ "type", "creator", "created", "proofValue"],
@@ -172,6 +215,23 @@ const (
 
 var schemaLoader = gojsonschema.NewStringLoader(schema) //nolint:gochecknoglobals
 
+// VerificationRelationship defines how the verification method may be used by the DID subject.
+// The values are the names of the DID document properties as well as the proof purposes.
+type VerificationRelationship string
+
+const (
+	// Authentication relationship is used to authenticate as the DID subject
+	Authentication VerificationRelationship = "authentication"
+	// AssertionMethod relationship is used to issue verifiable credentials
+	AssertionMethod VerificationRelationship = "assertionMethod"
+	// KeyAgreement relationship is used to establish secure communication (encryption)
+	KeyAgreement VerificationRelationship = "keyAgreement"
+	// CapabilityInvocation relationship is used to invoke authorization capabilities
+	CapabilityInvocation VerificationRelationship = "capabilityInvocation"
+	// CapabilityDelegation relationship is used to delegate authorization capabilities
+	CapabilityDelegation VerificationRelationship = "capabilityDelegation"
+)
+
 // Doc DID Document definition
 type Doc struct {
 	Context        []string
@@ -179,9 +239,14 @@ type Doc struct {
 	PublicKey      []PublicKey
 	Service        []Service
 	Authentication []VerificationMethod
-	Created        *time.Time
-	Updated        *time.Time
-	Proof          []Proof
+	// omitempty keeps the JSON encoding of the struct (used by peer DID genesis) stable
+	AssertionMethod      []VerificationMethod `json:",omitempty"`
+	KeyAgreement         []VerificationMethod `json:",omitempty"`
+	CapabilityInvocation []VerificationMethod `json:",omitempty"`
+	CapabilityDelegation []VerificationMethod `json:",omitempty"`
+	Created              *time.Time
+	Updated              *time.Time
+	Proof                []Proof
 }
 
 // PublicKey DID doc public key
@@ -200,20 +265,27 @@ type Service struct {
 	Properties      map[string]interface{}
 }
 
-// VerificationMethod authentication verification method
+// VerificationMethod verification method of a verification relationship
 type VerificationMethod struct {
 	PublicKey PublicKey
+	// Referenced is true if the method refers to a key of the public keys instead of embedding it
+	Referenced bool `json:",omitempty"`
 }
 
 type rawDoc struct {
-	Context        []string                 `json:"@context,omitempty"`
-	ID             string                   `json:"id,omitempty"`
-	PublicKey      []map[string]interface{} `json:"publicKey,omitempty"`
-	Service        []map[string]interface{} `json:"service,omitempty"`
-	Authentication []interface{}            `json:"authentication,omitempty"`
-	Created        *time.Time               `json:"created,omitempty"`
-	Updated        *time.Time               `json:"updated,omitempty"`
-	Proof          []interface{}            `json:"proof,omitempty"`
+	Context              interface{}              `json:"@context,omitempty"`
+	ID                   string                   `json:"id,omitempty"`
+	PublicKey            []map[string]interface{} `json:"publicKey,omitempty"`
+	VerificationMethod   []map[string]interface{} `json:"verificationMethod,omitempty"`
+	Service              []map[string]interface{} `json:"service,omitempty"`
+	Authentication       []interface{}            `json:"authentication,omitempty"`
+	AssertionMethod      []interface{}            `json:"assertionMethod,omitempty"`
+	KeyAgreement         []interface{}            `json:"keyAgreement,omitempty"`
+	CapabilityInvocation []interface{}            `json:"capabilityInvocation,omitempty"`
+	CapabilityDelegation []interface{}            `json:"capabilityDelegation,omitempty"`
+	Created              *time.Time               `json:"created,omitempty"`
+	Updated              *time.Time               `json:"updated,omitempty"`
+	Proof                []interface{}            `json:"proof,omitempty"`
 }
 
 // Proof is cryptographic proof of the integrity of the DID Document
@@ -226,7 +298,10 @@ type Proof struct {
 	Nonce      []byte
 }
 
-// ParseDocument creates an instance of DIDDocument by reading a JSON document from bytes
+// ParseDocument creates an instance of DIDDocument by reading a JSON document from bytes.
+// Both https://w3id.org/did/v1 and https://www.w3.org/ns/did/v1 contexts are supported: keys
+// from publicKey and verificationMethod are merged and relative key references are resolved
+// against the DID.
 func ParseDocument(data []byte) (*Doc, error) {
 	// validate did document
 	if err := validate(data); err != nil {
@@ -239,29 +314,59 @@ func ParseDocument(data []byte) (*Doc, error) {
 		return nil, fmt.Errorf("JSON marshalling of did doc bytes bytes failed: %w", err)
 	}
 
-	publicKeys, err := populatePublicKeys(raw.PublicKey)
+	publicKeys, err := populatePublicKeys(raw.ID, append(raw.PublicKey, raw.VerificationMethod...))
 	if err != nil {
 		return nil, fmt.Errorf("populate public keys failed: %w", err)
 	}
-	authPKs, err := populateAuthentications(raw.Authentication, publicKeys)
+	authPKs, err := populateVerificationMethods(raw.ID, Authentication, raw.Authentication, publicKeys)
 	if err != nil {
 		return nil, fmt.Errorf("populate authentications failed: %w", err)
 	}
 
-	proofs, err := populateProofs(raw.Proof)
-	if err != nil {
-		return nil, fmt.Errorf("populate proofs failed: %w", err)
-	}
-
-	return &Doc{Context: raw.Context,
+	doc := &Doc{Context: populateContext(raw.Context),
 		ID:             raw.ID,
 		PublicKey:      publicKeys,
-		Service:        populateServices(raw.Service),
+		Service:        populateServices(raw.ID, raw.Service),
 		Authentication: authPKs,
 		Created:        raw.Created,
 		Updated:        raw.Updated,
-		Proof:          proofs,
-	}, nil
+	}
+
+	if err := populateRelationships(doc, raw, publicKeys); err != nil {
+		return nil, err
+	}
+
+	doc.Proof, err = populateProofs(raw.Proof)
+	if err != nil {
+		return nil, fmt.Errorf("populate proofs failed: %w", err)
+	}
+
+	return doc, nil
+}
+
+// populateRelationships populates verification relationships other than authentication
+func populateRelationships(doc *Doc, raw *rawDoc, pks []PublicKey) error {
+	relationships := []struct {
+		relationship VerificationRelationship
+		raw          []interface{}
+		vms          *[]VerificationMethod
+	}{
+		{AssertionMethod, raw.AssertionMethod, &doc.AssertionMethod},
+		{KeyAgreement, raw.KeyAgreement, &doc.KeyAgreement},
+		{CapabilityInvocation, raw.CapabilityInvocation, &doc.CapabilityInvocation},
+		{CapabilityDelegation, raw.CapabilityDelegation, &doc.CapabilityDelegation},
+	}
+
+	for _, r := range relationships {
+		vms, err := populateVerificationMethods(raw.ID, r.relationship, r.raw, pks)
+		if err != nil {
+			return fmt.Errorf("populate %s failed: %w", r.relationship, err)
+		}
+
+		*r.vms = vms
+	}
+
+	return nil
 }
 
 func populateProofs(rawProofs []interface{}) ([]Proof, error) {
@@ -302,11 +407,37 @@ func populateProofs(rawProofs []interface{}) ([]Proof, error) {
 	return proofs, nil
 }
 
-func populateServices(rawServices []map[string]interface{}) []Service {
+// populateContext converts the context which may be a single string or an array of strings
+func populateContext(rawContext interface{}) []string {
+	switch ctx := rawContext.(type) {
+	case string:
+		return []string{ctx}
+	case []interface{}:
+		context := make([]string, 0, len(ctx))
+		for _, c := range ctx {
+			context = append(context, stringEntry(c))
+		}
+
+		return context
+	default:
+		return nil
+	}
+}
+
+// absoluteID resolves relative DID URL (e.g. "#keys-1") against the DID
+func absoluteID(did, id string) string {
+	if strings.HasPrefix(id, "#") {
+		return did + id
+	}
+
+	return id
+}
+
+func populateServices(didID string, rawServices []map[string]interface{}) []Service {
 	services := make([]Service, 0, len(rawServices))
 	for _, rawService := range rawServices {
-		service := Service{ID: stringEntry(rawService[jsonldID]), Type: stringEntry(rawService[jsonldType]),
-			ServiceEndpoint: stringEntry(rawService[jsonldServicePoint])}
+		service := Service{ID: absoluteID(didID, stringEntry(rawService[jsonldID])),
+			Type: stringEntry(rawService[jsonldType]), ServiceEndpoint: stringEntry(rawService[jsonldServicePoint])}
 		delete(rawService, jsonldID)
 		delete(rawService, jsonldType)
 		delete(rawService, jsonldServicePoint)
@@ -316,47 +447,49 @@ func populateServices(rawServices []map[string]interface{}) []Service {
 	return services
 }
 
-func populateAuthentications(rawAuthentications []interface{}, pks []PublicKey) ([]VerificationMethod, error) {
+func populateVerificationMethods(didID string, relationship VerificationRelationship, rawVMs []interface{},
+	pks []PublicKey) ([]VerificationMethod, error) {
 	var vms []VerificationMethod
-	for _, rawAuthentication := range rawAuthentications {
-		valueString, ok := rawAuthentication.(string)
+	for _, rawVM := range rawVMs {
+		valueString, ok := rawVM.(string)
 		if ok {
+			valueString = absoluteID(didID, valueString)
 			keyExist := false
 			for _, pk := range pks {
 				if pk.ID == valueString {
-					vms = append(vms, VerificationMethod{pk})
+					vms = append(vms, VerificationMethod{PublicKey: pk, Referenced: true})
 					keyExist = true
 					break
 				}
 			}
 			if !keyExist {
-				return nil, fmt.Errorf("authentication key %s not exist in did doc public key", valueString)
+				return nil, fmt.Errorf("%s key %s not exist in did doc public key", relationship, valueString)
 			}
 			continue
 		}
 
-		valuePK, ok := rawAuthentication.(map[string]interface{})
+		valuePK, ok := rawVM.(map[string]interface{})
 		if !ok {
-			return nil, errors.New("rawAuthentication is not map[string]interface{}")
+			return nil, fmt.Errorf("raw %s is not map[string]interface{}", relationship)
 		}
-		pk, err := populatePublicKeys([]map[string]interface{}{valuePK})
+		pk, err := populatePublicKeys(didID, []map[string]interface{}{valuePK})
 		if err != nil {
 			return nil, err
 		}
-		vms = append(vms, VerificationMethod{pk[0]})
+		vms = append(vms, VerificationMethod{PublicKey: pk[0]})
 	}
 	return vms, nil
 }
 
-func populatePublicKeys(rawPKs []map[string]interface{}) ([]PublicKey, error) {
+func populatePublicKeys(didID string, rawPKs []map[string]interface{}) ([]PublicKey, error) {
 	var publicKeys []PublicKey
 	for _, rawPK := range rawPKs {
 		decodeValue, err := decodePK(rawPK)
 		if err != nil {
 			return nil, err
 		}
-		publicKeys = append(publicKeys, PublicKey{ID: stringEntry(rawPK[jsonldID]), Type: stringEntry(rawPK[jsonldType]),
-			Controller: stringEntry(rawPK[jsonldController]), Value: decodeValue})
+		publicKeys = append(publicKeys, PublicKey{ID: absoluteID(didID, stringEntry(rawPK[jsonldID])),
+			Type: stringEntry(rawPK[jsonldType]), Controller: stringEntry(rawPK[jsonldController]), Value: decodeValue})
 	}
 	return publicKeys, nil
 }
@@ -413,14 +546,18 @@ func stringEntry(entry interface{}) string {
 // JSONBytes converts document to json bytes
 func (doc *Doc) JSONBytes() ([]byte, error) {
 	raw := &rawDoc{
-		Context:        doc.Context,
-		ID:             doc.ID,
-		PublicKey:      populateRawPublicKeys(doc.PublicKey),
-		Authentication: populateRawAuthentications(doc.Authentication),
-		Service:        populateRawServices(doc.Service),
-		Created:        doc.Created,
-		Proof:          populateRawProofs(doc.Proof),
-		Updated:        doc.Updated,
+		Context:              doc.Context,
+		ID:                   doc.ID,
+		PublicKey:            populateRawPublicKeys(doc.PublicKey),
+		Authentication:       populateRawVerificationMethods(doc.Authentication),
+		AssertionMethod:      populateRawVerificationMethods(doc.AssertionMethod),
+		KeyAgreement:         populateRawVerificationMethods(doc.KeyAgreement),
+		CapabilityInvocation: populateRawVerificationMethods(doc.CapabilityInvocation),
+		CapabilityDelegation: populateRawVerificationMethods(doc.CapabilityDelegation),
+		Service:              populateRawServices(doc.Service),
+		Created:              doc.Created,
+		Proof:                populateRawProofs(doc.Proof),
+		Updated:              doc.Updated,
 	}
 
 	byteDoc, err := json.Marshal(raw)
@@ -449,6 +586,39 @@ func (doc *Doc) VerifyProof() error {
 // ErrProofNotFound is returned when proof is not found
 var ErrProofNotFound = errors.New("proof not found")
 
+// ErrProofPurposeMismatch is returned when key is not authorized for the proof purpose
+var ErrProofPurposeMismatch = errors.New("key is not authorized for the proof purpose")
+
+// VerificationMethods returns the verification methods of the relationship
+func (doc *Doc) VerificationMethods(relationship VerificationRelationship) []VerificationMethod {
+	switch relationship {
+	case Authentication:
+		return doc.Authentication
+	case AssertionMethod:
+		return doc.AssertionMethod
+	case KeyAgreement:
+		return doc.KeyAgreement
+	case CapabilityInvocation:
+		return doc.CapabilityInvocation
+	case CapabilityDelegation:
+		return doc.CapabilityDelegation
+	default:
+		return nil
+	}
+}
+
+// ValidateProofPurpose checks that the key is authorized by the DID subject for the proof purpose,
+// e.g. a credential proof with "assertionMethod" purpose must be created by an assertion method key.
+func (doc *Doc) ValidateProofPurpose(proofPurpose, keyID string) error {
+	for _, vm := range doc.VerificationMethods(VerificationRelationship(proofPurpose)) {
+		if absoluteID(doc.ID, vm.PublicKey.ID) == absoluteID(doc.ID, keyID) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("validate %s proof purpose of key %s: %w", proofPurpose, keyID, ErrProofPurposeMismatch)
+}
+
 // didKeyResolver implements public key resolution for DID public keys
 type didKeyResolver struct {
 	PubKeys []PublicKey
@@ -505,14 +675,19 @@ func populateRawPublicKey(pk PublicKey) map[string]interface{} {
 	return rawPK
 }
 
-func populateRawAuthentications(vms []VerificationMethod) []interface{} {
-	var rawAuthentications []interface{}
+func populateRawVerificationMethods(vms []VerificationMethod) []interface{} {
+	var rawVMs []interface{}
 
 	for _, vm := range vms {
-		rawAuthentications = append(rawAuthentications, populateRawPublicKey(vm.PublicKey))
+		if vm.Referenced {
+			rawVMs = append(rawVMs, vm.PublicKey.ID)
+			continue
+		}
+
+		rawVMs = append(rawVMs, populateRawPublicKey(vm.PublicKey))
 	}
 
-	return rawAuthentications
+	return rawVMs
 }
 
 func populateRawProofs(proofs []Proof) []interface{} {
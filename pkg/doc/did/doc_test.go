@@ -14,6 +14,7 @@ import (
 	"encoding/json"
 	"encoding/pem"
 	"errors"
+	"strings"
 	"testing"
 	"time"
 
@@ -99,7 +100,7 @@ func TestValid(t *testing.T) {
 			ID:         "did:example:123456789abcdefghi#keys-1",
 			Controller: "did:example:123456789abcdefghi",
 			Type:       "Secp256k1VerificationKey2018",
-			Value:      base58.Decode("H3C2AVvLMv6gmMNam3uVAjZpfkcJCwDwnZn6z3wXmqPV")}},
+			Value:      base58.Decode("H3C2AVvLMv6gmMNam3uVAjZpfkcJCwDwnZn6z3wXmqPV")}, Referenced: true},
 		{PublicKey: PublicKey{
 			ID:         "did:example:123456789abcdefghs#key3",
 			Controller: "did:example:123456789abcdefghs",
@@ -204,6 +205,83 @@ func TestPopulateAuthentications(t *testing.T) {
 	})
 }
 
+func TestVerificationRelationships(t *testing.T) {
+	doc, err := ParseDocument([]byte(docWithVerificationRelationships))
+	require.NoError(t, err)
+
+	t.Run("test referenced and embedded methods", func(t *testing.T) {
+		require.Len(t, doc.AssertionMethod, 1)
+		require.True(t, doc.AssertionMethod[0].Referenced)
+		require.Equal(t, "did:example:123456789abcdefghi#keys-1", doc.AssertionMethod[0].PublicKey.ID)
+
+		require.Len(t, doc.KeyAgreement, 1)
+		require.False(t, doc.KeyAgreement[0].Referenced)
+		require.Equal(t, "did:example:123456789abcdefghi#keys-2", doc.KeyAgreement[0].PublicKey.ID)
+		require.Equal(t, "X25519KeyAgreementKey2019", doc.KeyAgreement[0].PublicKey.Type)
+
+		require.Len(t, doc.CapabilityInvocation, 1)
+		require.Len(t, doc.CapabilityDelegation, 1)
+	})
+
+	t.Run("test verification methods by relationship", func(t *testing.T) {
+		require.Equal(t, doc.Authentication, doc.VerificationMethods(Authentication))
+		require.Equal(t, doc.AssertionMethod, doc.VerificationMethods(AssertionMethod))
+		require.Equal(t, doc.KeyAgreement, doc.VerificationMethods(KeyAgreement))
+		require.Equal(t, doc.CapabilityInvocation, doc.VerificationMethods(CapabilityInvocation))
+		require.Equal(t, doc.CapabilityDelegation, doc.VerificationMethods(CapabilityDelegation))
+		require.Nil(t, doc.VerificationMethods("unknown"))
+	})
+
+	t.Run("test validate proof purpose", func(t *testing.T) {
+		require.NoError(t, doc.ValidateProofPurpose("assertionMethod", "did:example:123456789abcdefghi#keys-1"))
+		require.NoError(t, doc.ValidateProofPurpose("capabilityInvocation", "#keys-1"))
+
+		err := doc.ValidateProofPurpose("capabilityDelegation", "did:example:123456789abcdefghi#keys-2")
+		require.Error(t, err)
+		require.True(t, errors.Is(err, ErrProofPurposeMismatch))
+
+		err = doc.ValidateProofPurpose("unknown", "did:example:123456789abcdefghi#keys-1")
+		require.True(t, errors.Is(err, ErrProofPurposeMismatch))
+	})
+
+	t.Run("test JSON conversion keeps referenced form", func(t *testing.T) {
+		byteDoc, err := doc.JSONBytes()
+		require.NoError(t, err)
+
+		raw := &rawDoc{}
+		require.NoError(t, json.Unmarshal(byteDoc, &raw))
+		require.Equal(t, "did:example:123456789abcdefghi#keys-1", raw.AssertionMethod[0])
+		require.IsType(t, map[string]interface{}{}, raw.KeyAgreement[0])
+
+		doc2, err := ParseDocument(byteDoc)
+		require.NoError(t, err)
+		require.Equal(t, doc, doc2)
+	})
+
+	t.Run("test referenced key not exist", func(t *testing.T) {
+		raw := &rawDoc{}
+		require.NoError(t, json.Unmarshal([]byte(docWithVerificationRelationships), &raw))
+		raw.CapabilityDelegation[0] = "#key4"
+		bytes, err := json.Marshal(raw)
+		require.NoError(t, err)
+		_, err = ParseDocument(bytes)
+		require.Error(t, err)
+		require.Contains(t, err.Error(),
+			"capabilityDelegation key did:example:123456789abcdefghi#key4 not exist in did doc public key")
+	})
+
+	t.Run("test invalid relationship entry", func(t *testing.T) {
+		raw := &rawDoc{}
+		require.NoError(t, json.Unmarshal([]byte(docWithVerificationRelationships), &raw))
+		raw.KeyAgreement[0] = 1
+		bytes, err := json.Marshal(raw)
+		require.NoError(t, err)
+		err = validate(bytes)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "keyAgreement.0: Must validate one and only one schema (oneOf)")
+	})
+}
+
 func TestPublicKeys(t *testing.T) {
 	t.Run("test failed to decode PEM block", func(t *testing.T) {
 		raw := &rawDoc{}
@@ -256,10 +334,79 @@ func TestValidateDidDocContext(t *testing.T) {
 		require.NoError(t, err)
 		err = validate(bytes)
 		require.Error(t, err)
-		require.Contains(t, err.Error(), "Does not match pattern '^https://w3id.org/did/v1$'")
+		require.Contains(t, err.Error(), "Does not match pattern '^https://(w3id.org/did/v1|www.w3.org/ns/did/v1)$'")
+	})
+
+	t.Run("test did doc with single string context", func(t *testing.T) {
+		raw := &rawDoc{}
+		require.NoError(t, json.Unmarshal([]byte(validDoc), &raw))
+		raw.Context = ContextDIDCore
+		bytes, err := json.Marshal(raw)
+		require.NoError(t, err)
+		doc, err := ParseDocument(bytes)
+		require.NoError(t, err)
+		require.Equal(t, []string{ContextDIDCore}, doc.Context)
 	})
 }
 
+func TestParseDocumentContextVersions(t *testing.T) {
+	const didCoreDoc = `{
+  "@context": ["https://www.w3.org/ns/did/v1"],
+  "id": "did:example:123456789abcdefghi",
+  "verificationMethod": [
+    {
+      "id": "#keys-1",
+      "type": "Ed25519VerificationKey2018",
+      "controller": "did:example:123456789abcdefghi",
+      "publicKeyBase58": "H3C2AVvLMv6gmMNam3uVAjZpfkcJCwDwnZn6z3wXmqPV"
+    }
+  ],
+  "publicKey": [
+    {
+      "id": "did:example:123456789abcdefghi#keys-2",
+      "type": "Secp256k1VerificationKey2018",
+      "controller": "did:example:123456789abcdefghi",
+      "publicKeyHex": "02b97c30de767f084ce3080168ee293053ba33b235d7116a3263d29f1450936b71"
+    }
+  ],
+  "authentication": ["#keys-1", "did:example:123456789abcdefghi#keys-2"],
+  "service": [
+    {
+      "id": "#did-communication",
+      "type": "did-communication",
+      "serviceEndpoint": "https://agent.example.com/"
+    }
+  ]
+}`
+
+	doc, err := ParseDocument([]byte(didCoreDoc))
+	require.NoError(t, err)
+	require.Equal(t, []string{ContextDIDCore}, doc.Context)
+
+	// verificationMethod and publicKey are merged, relative ids are resolved against the DID
+	require.Len(t, doc.PublicKey, 2)
+	require.Equal(t, "did:example:123456789abcdefghi#keys-2", doc.PublicKey[0].ID)
+	require.Equal(t, "did:example:123456789abcdefghi#keys-1", doc.PublicKey[1].ID)
+
+	require.Len(t, doc.Authentication, 2)
+	require.Equal(t, doc.PublicKey[1], doc.Authentication[0].PublicKey)
+	require.Equal(t, doc.PublicKey[0], doc.Authentication[1].PublicKey)
+
+	require.Equal(t, "did:example:123456789abcdefghi#did-communication", doc.Service[0].ID)
+
+	// both context versions produce the same internal model
+	w3idDoc, err := ParseDocument([]byte(strings.Replace(didCoreDoc, ContextDIDCore, Context, 1)))
+	require.NoError(t, err)
+	require.Equal(t, []string{Context}, w3idDoc.Context)
+	require.Equal(t, doc.PublicKey, w3idDoc.PublicKey)
+	require.Equal(t, doc.Authentication, w3idDoc.Authentication)
+
+	// relative reference to a missing key
+	_, err = ParseDocument([]byte(strings.Replace(didCoreDoc, `["#keys-1"`, `["#keys-3"`, 1)))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "authentication key did:example:123456789abcdefghi#keys-3 not exist")
+}
+
 func TestValidateDidDocID(t *testing.T) {
 	t.Run("test did doc with empty id", func(t *testing.T) {
 		raw := &rawDoc{}
@@ -749,3 +896,27 @@ const docWithInvalidUpdated = `{
 	"updated": "2019-9-23T14:16:59.261024-04:00",
 	"id": "did:method:abc"
 }`
+
+const docWithVerificationRelationships = `{
+  "@context": ["https://www.w3.org/ns/did/v1"],
+  "id": "did:example:123456789abcdefghi",
+  "publicKey": [
+    {
+      "id": "did:example:123456789abcdefghi#keys-1",
+      "type": "Ed25519VerificationKey2018",
+      "controller": "did:example:123456789abcdefghi",
+      "publicKeyBase58": "H3C2AVvLMv6gmMNam3uVAjZpfkcJCwDwnZn6z3wXmqPV"
+    }
+  ],
+  "assertionMethod": ["did:example:123456789abcdefghi#keys-1"],
+  "keyAgreement": [
+    {
+      "id": "did:example:123456789abcdefghi#keys-2",
+      "type": "X25519KeyAgreementKey2019",
+      "controller": "did:example:123456789abcdefghi",
+      "publicKeyBase58": "JhNWeSVLMYccCk7iopQW4guaSJTojqpMEELgSLhKwRr"
+    }
+  ],
+  "capabilityInvocation": ["#keys-1"],
+  "capabilityDelegation": ["did:example:123456789abcdefghi#keys-1"]
+}`
@@ -0,0 +1,71 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package did
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/aries-framework-go/pkg/internal/cryptoutil"
+)
+
+const (
+	// Ed25519VerificationKey2018 is the type of Ed25519 verification keys
+	Ed25519VerificationKey2018 = "Ed25519VerificationKey2018"
+	// X25519KeyAgreementKey2019 is the type of X25519 key agreement keys
+	X25519KeyAgreementKey2019 = "X25519KeyAgreementKey2019"
+)
+
+// keyAgreementOpts holds the options for key agreement keys lookup
+type keyAgreementOpts struct {
+	deriveFromEd25519 bool
+}
+
+// KeyAgreementOpt is a key agreement keys lookup option
+type KeyAgreementOpt func(opts *keyAgreementOpts)
+
+// WithEd25519Derivation derives X25519 key agreement keys from the Ed25519 public keys
+// of the document when it has no keyAgreement keys
+func WithEd25519Derivation() KeyAgreementOpt {
+	return func(opts *keyAgreementOpts) {
+		opts.deriveFromEd25519 = true
+	}
+}
+
+// KeyAgreementKeys returns the keys to encrypt messages for the DID subject.
+// Derived keys keep the ID and controller of the Ed25519 key they were derived from.
+func (doc *Doc) KeyAgreementKeys(opts ...KeyAgreementOpt) ([]PublicKey, error) {
+	kaOpts := &keyAgreementOpts{}
+	for _, opt := range opts {
+		opt(kaOpts)
+	}
+
+	if len(doc.KeyAgreement) > 0 || !kaOpts.deriveFromEd25519 {
+		keys := make([]PublicKey, 0, len(doc.KeyAgreement))
+		for _, vm := range doc.KeyAgreement {
+			keys = append(keys, vm.PublicKey)
+		}
+
+		return keys, nil
+	}
+
+	var keys []PublicKey
+
+	for _, pk := range doc.PublicKey {
+		if pk.Type != Ed25519VerificationKey2018 {
+			continue
+		}
+
+		x25519, err := cryptoutil.PublicEd25519toCurve25519(pk.Value)
+		if err != nil {
+			return nil, fmt.Errorf("derive key agreement key from %s: %w", pk.ID, err)
+		}
+
+		keys = append(keys, PublicKey{ID: pk.ID, Type: X25519KeyAgreementKey2019, Controller: pk.Controller,
+			Value: x25519})
+	}
+
+	return keys, nil
+}
@@ -0,0 +1,65 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package did
+
+import (
+	"crypto/rand"
+	"testing"
+
+	"github.com/agl/ed25519"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDoc_KeyAgreementKeys(t *testing.T) {
+	edPub, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	edKey := PublicKey{ID: "did:example:123#key-1", Type: Ed25519VerificationKey2018,
+		Controller: "did:example:123", Value: edPub[:]}
+	rsaKey := PublicKey{ID: "did:example:123#key-2", Type: "RsaVerificationKey2018",
+		Controller: "did:example:123", Value: []byte("rsa")}
+	kaKey := PublicKey{ID: "did:example:123#key-3", Type: X25519KeyAgreementKey2019,
+		Controller: "did:example:123", Value: []byte("x25519")}
+
+	t.Run("test key agreement keys", func(t *testing.T) {
+		doc := &Doc{PublicKey: []PublicKey{edKey}, KeyAgreement: []VerificationMethod{{PublicKey: kaKey}}}
+
+		keys, err := doc.KeyAgreementKeys(WithEd25519Derivation())
+		require.NoError(t, err)
+		require.Equal(t, []PublicKey{kaKey}, keys)
+	})
+
+	t.Run("test no derivation by default", func(t *testing.T) {
+		doc := &Doc{PublicKey: []PublicKey{edKey}}
+
+		keys, err := doc.KeyAgreementKeys()
+		require.NoError(t, err)
+		require.Empty(t, keys)
+	})
+
+	t.Run("test derivation from Ed25519 keys", func(t *testing.T) {
+		doc := &Doc{PublicKey: []PublicKey{edKey, rsaKey}}
+
+		keys, err := doc.KeyAgreementKeys(WithEd25519Derivation())
+		require.NoError(t, err)
+		require.Len(t, keys, 1)
+		require.Equal(t, edKey.ID, keys[0].ID)
+		require.Equal(t, edKey.Controller, keys[0].Controller)
+		require.Equal(t, X25519KeyAgreementKey2019, keys[0].Type)
+		require.Len(t, keys[0].Value, 32)
+		require.NotEqual(t, edKey.Value, keys[0].Value)
+	})
+
+	t.Run("test derivation from invalid Ed25519 key", func(t *testing.T) {
+		doc := &Doc{PublicKey: []PublicKey{{ID: "did:example:123#key-4", Type: Ed25519VerificationKey2018,
+			Value: []byte("short")}}}
+
+		keys, err := doc.KeyAgreementKeys(WithEd25519Derivation())
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid Ed25519 public key size 5")
+		require.Nil(t, keys)
+	})
+}
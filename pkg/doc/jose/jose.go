@@ -0,0 +1,122 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package jose reads the protected header of the JWE envelopes without decrypting them, e.g. to route the
+// envelopes, to label their metrics or to reject the unsupported algorithms before any key lookup.
+package jose
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrInvalidHeaders is returned when the protected header can't be decoded
+	ErrInvalidHeaders = errors.New("invalid protected header")
+	// ErrUnsupportedType is returned when the typ header isn't allowed
+	ErrUnsupportedType = errors.New("unsupported envelope type")
+	// ErrUnsupportedAlg is returned when the alg header isn't allowed
+	ErrUnsupportedAlg = errors.New("unsupported key management algorithm")
+	// ErrUnsupportedEnc is returned when the enc header isn't allowed
+	ErrUnsupportedEnc = errors.New("unsupported content encryption algorithm")
+)
+
+// Headers is the protected header of a JWE
+type Headers struct {
+	// Type is the media type of the envelope
+	Type string `json:"typ,omitempty"`
+	// ContentType is the media type of the payload
+	ContentType string `json:"cty,omitempty"`
+	// Algorithm is the key management algorithm
+	Algorithm string `json:"alg,omitempty"`
+	// Encryption is the content encryption algorithm
+	Encryption string `json:"enc,omitempty"`
+	// Compression is the compression of the payload, empty if not compressed
+	Compression string `json:"zip,omitempty"`
+	// KeyID identifies the key of the single recipient envelopes
+	KeyID string `json:"kid,omitempty"`
+}
+
+// ParseProtectedHeaders returns the protected header of the JWE in the JSON serialization, the envelope isn't
+// decrypted
+func ParseProtectedHeaders(envelope []byte) (*Headers, error) {
+	e := &struct {
+		Protected string `json:"protected,omitempty"`
+	}{}
+
+	if err := json.Unmarshal(envelope, e); err != nil {
+		return nil, fmt.Errorf("%w: invalid envelope: %s", ErrInvalidHeaders, err)
+	}
+
+	return DecodeProtectedHeaders(e.Protected)
+}
+
+// DecodeProtectedHeaders decodes the base64url encoded protected header, with or without padding
+func DecodeProtectedHeaders(protected string) (*Headers, error) {
+	if protected == "" {
+		return nil, fmt.Errorf("%w: missing protected header", ErrInvalidHeaders)
+	}
+
+	bytes, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(protected, "="))
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to decode: %s", ErrInvalidHeaders, err)
+	}
+
+	headers := &Headers{}
+	if err = json.Unmarshal(bytes, headers); err != nil {
+		return nil, fmt.Errorf("%w: failed to unmarshal: %s", ErrInvalidHeaders, err)
+	}
+
+	return headers, nil
+}
+
+// Policy lists the allowed header values, the empty lists allow any value
+type Policy struct {
+	Types       []string
+	Algorithms  []string
+	Encryptions []string
+}
+
+// Validate checks the headers against the policy, the algorithms are compared case sensitively as required by
+// RFC 7516 while the media types are compared case insensitively
+func (h *Headers) Validate(policy *Policy) error {
+	if len(policy.Types) > 0 && !containsFold(policy.Types, h.Type) {
+		return fmt.Errorf("%w: %q", ErrUnsupportedType, h.Type)
+	}
+
+	if len(policy.Algorithms) > 0 && !contains(policy.Algorithms, h.Algorithm) {
+		return fmt.Errorf("%w: %q", ErrUnsupportedAlg, h.Algorithm)
+	}
+
+	if len(policy.Encryptions) > 0 && !contains(policy.Encryptions, h.Encryption) {
+		return fmt.Errorf("%w: %q", ErrUnsupportedEnc, h.Encryption)
+	}
+
+	return nil
+}
+
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsFold(values []string, v string) bool {
+	for _, value := range values {
+		if strings.EqualFold(value, v) {
+			return true
+		}
+	}
+
+	return false
+}
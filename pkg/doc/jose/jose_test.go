@@ -0,0 +1,67 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package jose
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseProtectedHeaders(t *testing.T) {
+	const protected = `{"typ":"prs.hyperledger.aries-auth-message","alg":"ECDH-SS+XC20PKW","enc":"XC20P","zip":"DEF"}`
+
+	t.Run("test parse", func(t *testing.T) {
+		expected := &Headers{Type: "prs.hyperledger.aries-auth-message", Algorithm: "ECDH-SS+XC20PKW",
+			Encryption: "XC20P", Compression: "DEF"}
+
+		for _, encoding := range []*base64.Encoding{base64.RawURLEncoding, base64.URLEncoding} {
+			envelope := fmt.Sprintf(`{"protected":%q,"ciphertext":"abc"}`,
+				encoding.EncodeToString([]byte(protected)))
+
+			headers, err := ParseProtectedHeaders([]byte(envelope))
+			require.NoError(t, err)
+			require.Equal(t, expected, headers)
+		}
+	})
+
+	t.Run("test invalid headers", func(t *testing.T) {
+		for _, envelope := range []string{
+			"invalid",
+			`{}`,
+			`{"protected":"!"}`,
+			fmt.Sprintf(`{"protected":%q}`, base64.RawURLEncoding.EncodeToString([]byte("{"))),
+		} {
+			_, err := ParseProtectedHeaders([]byte(envelope))
+			require.True(t, errors.Is(err, ErrInvalidHeaders), envelope)
+		}
+	})
+}
+
+func TestHeaders_Validate(t *testing.T) {
+	headers := &Headers{Type: "prs.hyperledger.aries-auth-message", Algorithm: "ECDH-SS+XC20PKW", Encryption: "XC20P"}
+
+	require.NoError(t, headers.Validate(&Policy{}))
+	require.NoError(t, headers.Validate(&Policy{
+		Types:       []string{"JWM/1.0", "PRS.hyperledger.aries-auth-message"},
+		Algorithms:  []string{"ECDH-SS+XC20PKW"},
+		Encryptions: []string{"C20P", "XC20P"},
+	}))
+
+	err := headers.Validate(&Policy{Types: []string{"JWM/1.0"}})
+	require.True(t, errors.Is(err, ErrUnsupportedType))
+
+	err = headers.Validate(&Policy{Algorithms: []string{"ecdh-ss+xc20pkw"}})
+	require.True(t, errors.Is(err, ErrUnsupportedAlg))
+
+	err = headers.Validate(&Policy{Encryptions: []string{"A256GCM"}})
+	require.True(t, errors.Is(err, ErrUnsupportedEnc))
+	require.EqualError(t, err, `unsupported content encryption algorithm: "XC20P"`)
+}
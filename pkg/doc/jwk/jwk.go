@@ -0,0 +1,130 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package jwk
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+)
+
+const (
+	// KeyTypeOKP is the key type of octet key pairs (RFC 8037)
+	KeyTypeOKP = "OKP"
+	// KeyTypeEC is the key type of elliptic curve keys
+	KeyTypeEC = "EC"
+	// KeyTypeRSA is the key type of RSA keys
+	KeyTypeRSA = "RSA"
+	// KeyTypeOct is the key type of symmetric keys
+	KeyTypeOct = "oct"
+
+	// CurveEd25519 is the Ed25519 signing curve
+	CurveEd25519 = "Ed25519"
+	// CurveX25519 is the X25519 key agreement curve
+	CurveX25519 = "X25519"
+)
+
+// JWK is a public JSON Web Key with the members used for thumbprint computation
+type JWK struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	K   string `json:"k,omitempty"`
+}
+
+// FromPublicKey returns the JWK of the raw public key of the DID key type,
+// Ed25519VerificationKey2018 and X25519KeyAgreementKey2019 are supported.
+func FromPublicKey(keyType string, pub []byte) (*JWK, error) {
+	const okpKeySize = 32
+
+	var crv string
+
+	switch keyType {
+	case did.Ed25519VerificationKey2018:
+		crv = CurveEd25519
+	case did.X25519KeyAgreementKey2019:
+		crv = CurveX25519
+	default:
+		return nil, fmt.Errorf("key type %s not supported", keyType)
+	}
+
+	if len(pub) != okpKeySize {
+		return nil, fmt.Errorf("invalid %s public key size %d", crv, len(pub))
+	}
+
+	return &JWK{Kty: KeyTypeOKP, Crv: crv, X: base64.RawURLEncoding.EncodeToString(pub)}, nil
+}
+
+// Thumbprint computes the RFC 7638 SHA-256 thumbprint of the key
+func (k *JWK) Thumbprint() ([]byte, error) {
+	// the required members in lexicographic order, encoding/json sorts map keys
+	var members map[string]string
+
+	switch k.Kty {
+	case KeyTypeOKP:
+		members = map[string]string{"crv": k.Crv, "kty": k.Kty, "x": k.X}
+	case KeyTypeEC:
+		members = map[string]string{"crv": k.Crv, "kty": k.Kty, "x": k.X, "y": k.Y}
+	case KeyTypeRSA:
+		members = map[string]string{"e": k.E, "kty": k.Kty, "n": k.N}
+	case KeyTypeOct:
+		members = map[string]string{"k": k.K, "kty": k.Kty}
+	default:
+		return nil, fmt.Errorf("key type %s not supported", k.Kty)
+	}
+
+	for name, value := range members {
+		if value == "" {
+			return nil, fmt.Errorf("missing required member %s", name)
+		}
+	}
+
+	data, err := json.Marshal(members)
+	if err != nil {
+		return nil, err
+	}
+
+	digest := sha256.Sum256(data)
+
+	return digest[:], nil
+}
+
+// KeyID returns the base64url encoded thumbprint of the key, used as "kid" of the key
+func (k *JWK) KeyID() (string, error) {
+	thumbprint, err := k.Thumbprint()
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(thumbprint), nil
+}
+
+// KeyID returns the thumbprint "kid" of the raw public key of the DID key type.
+// The same kid is produced for the key whether used in JWE recipients, JWS headers or DID documents.
+func KeyID(keyType string, pub []byte) (string, error) {
+	k, err := FromPublicKey(keyType, pub)
+	if err != nil {
+		return "", err
+	}
+
+	return k.KeyID()
+}
+
+// DIDKeyID returns the thumbprint "kid" of the DID document public key
+func DIDKeyID(pk *did.PublicKey) (string, error) {
+	if pk == nil {
+		return "", errors.New("public key is nil")
+	}
+
+	return KeyID(pk.Type, pk.Value)
+}
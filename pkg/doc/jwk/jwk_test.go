@@ -0,0 +1,78 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package jwk
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+)
+
+func TestJWK_KeyID(t *testing.T) {
+	t.Run("test RFC 7638 RSA example", func(t *testing.T) {
+		k := &JWK{
+			Kty: KeyTypeRSA,
+			E:   "AQAB",
+			N: "0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPeb" +
+				"WKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368Q" +
+				"QMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2" +
+				"NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw",
+		}
+
+		kid, err := k.KeyID()
+		require.NoError(t, err)
+		require.Equal(t, "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs", kid)
+	})
+
+	t.Run("test RFC 8037 Ed25519 example", func(t *testing.T) {
+		pub, err := base64.RawURLEncoding.DecodeString("11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo")
+		require.NoError(t, err)
+
+		kid, err := KeyID(did.Ed25519VerificationKey2018, pub)
+		require.NoError(t, err)
+		require.Equal(t, "kPrK_qmxVWaYVA9wwBF6Iuo3vVzz7TxHCTwXBygrS4k", kid)
+
+		didKID, err := DIDKeyID(&did.PublicKey{Type: did.Ed25519VerificationKey2018, Value: pub})
+		require.NoError(t, err)
+		require.Equal(t, kid, didKID)
+	})
+
+	t.Run("test X25519 key", func(t *testing.T) {
+		k, err := FromPublicKey(did.X25519KeyAgreementKey2019, make([]byte, 32))
+		require.NoError(t, err)
+		require.Equal(t, &JWK{Kty: KeyTypeOKP, Crv: CurveX25519, X: "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA"}, k)
+	})
+
+	t.Run("test EC and oct keys", func(t *testing.T) {
+		kid, err := (&JWK{Kty: KeyTypeEC, Crv: "P-256", X: "x", Y: "y"}).KeyID()
+		require.NoError(t, err)
+		require.NotEmpty(t, kid)
+
+		kid, err = (&JWK{Kty: KeyTypeOct, K: "k"}).KeyID()
+		require.NoError(t, err)
+		require.NotEmpty(t, kid)
+	})
+
+	t.Run("test errors", func(t *testing.T) {
+		_, err := KeyID("RsaVerificationKey2018", make([]byte, 32))
+		require.EqualError(t, err, "key type RsaVerificationKey2018 not supported")
+
+		_, err = KeyID(did.Ed25519VerificationKey2018, []byte("short"))
+		require.EqualError(t, err, "invalid Ed25519 public key size 5")
+
+		_, err = (&JWK{Kty: "unknown"}).KeyID()
+		require.EqualError(t, err, "key type unknown not supported")
+
+		_, err = (&JWK{Kty: KeyTypeEC, Crv: "P-256", X: "x"}).KeyID()
+		require.EqualError(t, err, "missing required member y")
+
+		_, err = DIDKeyID(nil)
+		require.EqualError(t, err, "public key is nil")
+	})
+}
@@ -0,0 +1,134 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package ed25519ph implements the Ed25519ph pre-hashed variant of Ed25519 (RFC 8032, section 5.1) with an
+// empty context. The message is digested with SHA-512 before being signed, so large payloads (e.g. attachments)
+// are signed without being loaded in memory: the digest is computed as the payload is read.
+package ed25519ph
+
+import (
+	"crypto/ed25519"
+	"crypto/sha512"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/agl/ed25519/edwards25519"
+)
+
+// dom2 is the prefix of the hashes of Ed25519ph: the phflag is 1 and the context is empty
+const dom2 = "SigEd25519 no Ed25519 collisions\x01\x00"
+
+// Digest returns the SHA-512 digest of the message read from the reader, the digest signed by Sign
+func Digest(r io.Reader) ([]byte, error) {
+	h := sha512.New()
+
+	if _, err := io.Copy(h, r); err != nil {
+		return nil, fmt.Errorf("failed to digest message: %w", err)
+	}
+
+	return h.Sum(nil), nil
+}
+
+// Sign signs the SHA-512 digest of a message with the Ed25519 private key
+func Sign(privKey, digest []byte) ([]byte, error) {
+	if len(privKey) != ed25519.PrivateKeySize {
+		return nil, errors.New("ed25519ph: bad private key length")
+	}
+
+	if len(digest) != sha512.Size {
+		return nil, errors.New("ed25519ph: bad digest length")
+	}
+
+	h := sha512.Sum512(privKey[:32])
+
+	var expandedSecretKey [32]byte
+
+	copy(expandedSecretKey[:], h[:32])
+	expandedSecretKey[0] &= 248
+	expandedSecretKey[31] &= 63
+	expandedSecretKey[31] |= 64
+
+	var r [32]byte
+
+	reducedHash(&r, h[32:], digest)
+
+	var R edwards25519.ExtendedGroupElement
+
+	edwards25519.GeScalarMultBase(&R, &r)
+
+	var encodedR [32]byte
+
+	R.ToBytes(&encodedR)
+
+	var k [32]byte
+
+	reducedHash(&k, encodedR[:], privKey[32:], digest)
+
+	var s [32]byte
+
+	edwards25519.ScMulAdd(&s, &k, &expandedSecretKey, &r)
+
+	return append(encodedR[:], s[:]...), nil
+}
+
+// Verify verifies the signature of the SHA-512 digest of a message with the Ed25519 public key
+func Verify(pubKey, digest, signature []byte) error {
+	if len(pubKey) != ed25519.PublicKeySize {
+		return errors.New("ed25519ph: bad public key length")
+	}
+
+	if len(digest) != sha512.Size || len(signature) != ed25519.SignatureSize || signature[63]&224 != 0 {
+		return errors.New("signature doesn't match")
+	}
+
+	var publicKey [32]byte
+
+	copy(publicKey[:], pubKey)
+
+	var A edwards25519.ExtendedGroupElement
+	if !A.FromBytes(&publicKey) {
+		return errors.New("ed25519ph: invalid public key")
+	}
+
+	edwards25519.FeNeg(&A.X, &A.X)
+	edwards25519.FeNeg(&A.T, &A.T)
+
+	var k, s [32]byte
+
+	reducedHash(&k, signature[:32], pubKey, digest)
+	copy(s[:], signature[32:])
+
+	var R edwards25519.ProjectiveGroupElement
+
+	edwards25519.GeDoubleScalarMultVartime(&R, &k, &A, &s)
+
+	var checkR [32]byte
+
+	R.ToBytes(&checkR)
+
+	if subtle.ConstantTimeCompare(signature[:32], checkR[:]) != 1 {
+		return errors.New("signature doesn't match")
+	}
+
+	return nil
+}
+
+// reducedHash computes the SHA-512 hash of the dom2 prefix followed by the parts, reduced modulo the group order
+func reducedHash(out *[32]byte, parts ...[]byte) {
+	h := sha512.New()
+	h.Write([]byte(dom2)) // nolint: errcheck
+
+	for _, p := range parts {
+		h.Write(p) // nolint: errcheck
+	}
+
+	var digest [64]byte
+
+	h.Sum(digest[:0])
+	edwards25519.ScReduce(out, &digest)
+}
@@ -0,0 +1,102 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ed25519ph
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSign(t *testing.T) {
+	t.Run("test RFC 8032 vector", func(t *testing.T) {
+		// the Ed25519ph test vector of RFC 8032, section 7.3
+		seed, err := hex.DecodeString("833fe62409237b9d62ec77587520911e9a759cec1d19755b7da901b96dca3d42")
+		require.NoError(t, err)
+
+		privKey := ed25519.NewKeyFromSeed(seed)
+		require.Equal(t, "ec172b93ad5e563bf4932c70e1245034c35467ef2efd4d64ebf819683467e2bf",
+			hex.EncodeToString(privKey[32:]))
+
+		digest, err := Digest(bytes.NewBufferString("abc"))
+		require.NoError(t, err)
+
+		signature, err := Sign(privKey, digest)
+		require.NoError(t, err)
+		require.Equal(t, "98a70222f0b8121aa9d30f813d683f809e462b469c7ff87639499bb94e6dae41"+
+			"31f85042463c2a355a2003d062adf5aaa10b8c61e636062aaad11c2a26083406", hex.EncodeToString(signature))
+
+		require.NoError(t, Verify(privKey[32:], digest, signature))
+	})
+
+	t.Run("test sign and verify", func(t *testing.T) {
+		pubKey, privKey, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+
+		digest := sha512.Sum512([]byte("large payload"))
+
+		signature, err := Sign(privKey, digest[:])
+		require.NoError(t, err)
+		require.NoError(t, Verify(pubKey, digest[:], signature))
+
+		// the pre-hashed signature isn't a plain Ed25519 signature of the digest
+		require.False(t, ed25519.Verify(pubKey, digest[:], signature))
+
+		other := sha512.Sum512([]byte("other payload"))
+		require.EqualError(t, Verify(pubKey, other[:], signature), "signature doesn't match")
+
+		signature[0] ^= 1
+		require.EqualError(t, Verify(pubKey, digest[:], signature), "signature doesn't match")
+	})
+
+	t.Run("test invalid arguments", func(t *testing.T) {
+		pubKey, privKey, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+
+		digest := sha512.Sum512([]byte("payload"))
+
+		_, err = Sign(privKey[:10], digest[:])
+		require.EqualError(t, err, "ed25519ph: bad private key length")
+
+		_, err = Sign(privKey, digest[:10])
+		require.EqualError(t, err, "ed25519ph: bad digest length")
+
+		signature, err := Sign(privKey, digest[:])
+		require.NoError(t, err)
+
+		require.EqualError(t, Verify(pubKey[:10], digest[:], signature), "ed25519ph: bad public key length")
+		require.Error(t, Verify(pubKey, digest[:10], signature))
+		require.Error(t, Verify(pubKey, digest[:], signature[:10]))
+
+		invalidKey := bytes.Repeat([]byte{0xff}, ed25519.PublicKeySize)
+		require.Error(t, Verify(invalidKey, digest[:], signature))
+	})
+}
+
+func TestDigest(t *testing.T) {
+	digest, err := Digest(bytes.NewReader(make([]byte, 1<<20)))
+	require.NoError(t, err)
+
+	expected := sha512.Sum512(make([]byte, 1<<20))
+	require.Equal(t, expected[:], digest)
+
+	_, err = Digest(&failingReader{})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed to digest message")
+}
+
+type failingReader struct{}
+
+func (r *failingReader) Read([]byte) (int, error) {
+	return 0, errors.New("read error")
+}
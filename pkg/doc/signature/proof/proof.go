@@ -23,6 +23,8 @@ const (
 	jsonldNonce = "nonce"
 	// jsonldProofValue is key for proof value
 	jsonldProofValue = "proofValue"
+	// jsonldProofPurpose is key for the proof purpose
+	jsonldProofPurpose = "proofPurpose"
 )
 
 // Proof is cryptographic proof of the integrity of the DID Document
@@ -33,6 +35,8 @@ type Proof struct {
 	ProofValue []byte
 	Domain     string
 	Nonce      []byte
+	// ProofPurpose is the verification relationship of the creator key, e.g. assertionMethod, optional
+	ProofPurpose string
 }
 
 // NewProof creates new proof
@@ -55,12 +59,13 @@ func NewProof(emap map[string]interface{}) (*Proof, error) {
 	}
 
 	return &Proof{
-		Type:       stringEntry(emap[jsonldType]),
-		Created:    &timeValue,
-		Creator:    stringEntry(emap[jsonldCreator]),
-		ProofValue: proofValue,
-		Domain:     stringEntry(emap[jsonldDomain]),
-		Nonce:      nonce,
+		Type:         stringEntry(emap[jsonldType]),
+		Created:      &timeValue,
+		Creator:      stringEntry(emap[jsonldCreator]),
+		ProofValue:   proofValue,
+		Domain:       stringEntry(emap[jsonldDomain]),
+		Nonce:        nonce,
+		ProofPurpose: stringEntry(emap[jsonldProofPurpose]),
 	}, nil
 }
 
@@ -84,5 +89,9 @@ func (p *Proof) JSONLdObject() map[string]interface{} {
 	emap[jsonldDomain] = p.Domain
 	emap[jsonldNonce] = base64.RawURLEncoding.EncodeToString(p.Nonce)
 
+	if p.ProofPurpose != "" {
+		emap[jsonldProofPurpose] = p.ProofPurpose
+	}
+
 	return emap
 }
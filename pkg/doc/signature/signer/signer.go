@@ -11,6 +11,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/hyperledger/aries-framework-go/pkg/common/clock"
 	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/ed25519signature2018"
 	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/proof"
 )
@@ -40,12 +41,14 @@ type DocumentSigner struct {
 
 // Context holds signing options and private key
 type Context struct {
-	SignatureType string     // required
-	Creator       string     // required
-	Signer        signer     // required
-	Created       *time.Time // optional
-	Domain        string     // optional
-	Nonce         []byte     // optional
+	SignatureType string      // required
+	Creator       string      // required
+	Signer        signer      // required
+	Created       *time.Time  // optional
+	Clock         clock.Clock // optional, the clock of the creation time if Created is not set
+	Domain        string      // optional
+	Nonce         []byte      // optional
+	ProofPurpose  string      // optional, e.g. assertionMethod
 }
 
 // New returns new instance of document verifier
@@ -90,16 +93,22 @@ func (signer *DocumentSigner) signObject(context *Context, jsonLdObject map[stri
 
 	created := context.Created
 	if created == nil {
-		now := time.Now()
+		c := context.Clock
+		if c == nil {
+			c = clock.System()
+		}
+
+		now := c.Now()
 		created = &now
 	}
 
 	p := proof.Proof{
-		Type:    context.SignatureType,
-		Creator: context.Creator,
-		Created: created,
-		Domain:  context.Domain,
-		Nonce:   context.Nonce,
+		Type:         context.SignatureType,
+		Creator:      context.Creator,
+		Created:      created,
+		Domain:       context.Domain,
+		Nonce:        context.Nonce,
+		ProofPurpose: context.ProofPurpose,
 	}
 
 	message, err := proof.CreateVerifyHash(suite, jsonLdObject, p.JSONLdObject())
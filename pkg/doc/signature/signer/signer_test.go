@@ -8,10 +8,14 @@ package signer
 import (
 	"crypto/ed25519"
 	"crypto/rand"
+	"encoding/json"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
+
+	mockclock "github.com/hyperledger/aries-framework-go/pkg/internal/mock/clock"
 )
 
 const signatureType = "Ed25519Signature2018"
@@ -25,6 +29,18 @@ func TestDocumentSigner_Sign(t *testing.T) {
 	require.NotNil(t, signedDoc)
 }
 
+func TestDocumentSigner_SignWithClock(t *testing.T) {
+	context := getSignatureContext()
+	context.Clock = &mockclock.Clock{Time: time.Date(2020, time.January, 1, 12, 0, 0, 0, time.UTC)}
+
+	signedDoc, err := New().Sign(context, []byte(validDoc))
+	require.NoError(t, err)
+
+	var doc map[string]interface{}
+	require.NoError(t, json.Unmarshal(signedDoc, &doc))
+	require.Equal(t, "2020-01-01T12:00:00Z", doc["proof"].([]interface{})[0].(map[string]interface{})["created"])
+}
+
 func TestDocumentSigner_SignErrors(t *testing.T) {
 	context := getSignatureContext()
 	s := New()
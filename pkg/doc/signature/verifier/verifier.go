@@ -69,28 +69,59 @@ func (dv *DocumentVerifier) verifyObject(jsonLdObject map[string]interface{}) er
 	}
 
 	for _, p := range proofs {
-		publicKey, err := dv.pkResolver.Resolve(p.Creator)
-		if err != nil {
+		if err := dv.verifyProof(jsonLdObject, p); err != nil {
 			return err
 		}
+	}
 
-		suite, err := dv.getSignatureSuite(p.Type)
-		if err != nil {
-			return err
-		}
+	return nil
+}
 
-		message, err := proof.CreateVerifyHash(suite, jsonLdObject, p.JSONLdObject())
-		if err != nil {
-			return err
-		}
+// ProofResult is the outcome of the verification of a proof of the document, Err is nil if the proof is verified
+type ProofResult struct {
+	Proof *proof.Proof
+	Err   error
+}
 
-		err = suite.Verify(publicKey, message, p.ProofValue)
-		if err != nil {
-			return err
-		}
+// VerifyProofs verifies each proof of the document and returns the result of every proof, unlike Verify
+// it doesn't stop at the first proof failing. An error is returned if the proofs can't be read.
+func (dv *DocumentVerifier) VerifyProofs(jsonLdDoc []byte) ([]ProofResult, error) {
+	var jsonLdObject map[string]interface{}
+	if err := json.Unmarshal(jsonLdDoc, &jsonLdObject); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal json ld document: %w", err)
 	}
 
-	return nil
+	proofs, err := proof.GetProofs(jsonLdObject)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]ProofResult, len(proofs))
+	for i, p := range proofs {
+		results[i] = ProofResult{Proof: p, Err: dv.verifyProof(jsonLdObject, p)}
+	}
+
+	return results, nil
+}
+
+// verifyProof verifies the proof of the JSON LD object
+func (dv *DocumentVerifier) verifyProof(jsonLdObject map[string]interface{}, p *proof.Proof) error {
+	suite, err := dv.getSignatureSuite(p.Type)
+	if err != nil {
+		return err
+	}
+
+	publicKey, err := dv.pkResolver.Resolve(p.Creator)
+	if err != nil {
+		return err
+	}
+
+	message, err := proof.CreateVerifyHash(suite, jsonLdObject, p.JSONLdObject())
+	if err != nil {
+		return err
+	}
+
+	return suite.Verify(publicKey, message, p.ProofValue)
 }
 
 // getSignatureSuite returns signature suite based on signature type
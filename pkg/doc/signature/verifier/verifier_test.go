@@ -74,6 +74,37 @@ func TestVerifyObject(t *testing.T) {
 	require.Contains(t, err.Error(), "signature doesn't match")
 }
 
+func TestVerifyProofs(t *testing.T) {
+	jsonLdObject, tkr := getDefaultSignedDocObject()
+
+	// add a second proof with an invalid signature
+	proofs, err := proof.GetProofs(jsonLdObject)
+	require.NoError(t, err)
+	invalid := *proofs[0]
+	invalid.ProofValue = []byte("invalid")
+	require.NoError(t, proof.AddProof(jsonLdObject, &invalid))
+
+	docBytes, err := json.Marshal(jsonLdObject)
+	require.NoError(t, err)
+
+	v := New(tkr)
+	results, err := v.VerifyProofs(docBytes)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	require.NoError(t, results[0].Err)
+	require.Equal(t, "key-1", results[0].Proof.Creator)
+	require.Error(t, results[1].Err)
+	require.Contains(t, results[1].Err.Error(), "signature doesn't match")
+
+	_, err = v.VerifyProofs([]byte("not json"))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed to unmarshal json ld document")
+
+	_, err = v.VerifyProofs([]byte(validDoc))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "proof not found")
+}
+
 func getDefaultSignedDoc() ([]byte, keyResolver) {
 	pubKey, privKey, err := ed25519.GenerateKey(rand.Reader)
 	if err != nil {
@@ -0,0 +1,158 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package verifiable
+
+import (
+	"errors"
+	"time"
+)
+
+// CredentialBuilder constructs a Verifiable Credential field by field, e.g.
+//
+//	vc, err := NewCredentialBuilder().
+//		AddType("UniversityDegreeCredential").
+//		SetIssuer(Issuer{ID: "did:example:76e12ec712ebc6f1c221ebfeb1f"}).
+//		AddSubject(map[string]interface{}{"id": "did:example:ebfeb1f712ebc6f1c276e12ec21"}).
+//		Build()
+//
+// The credential is validated by Build, the builder is not safe for concurrent use.
+type CredentialBuilder struct {
+	vc        Credential
+	types     []string
+	subjects  []Subject
+	evidences []Evidence
+}
+
+// NewCredentialBuilder returns the builder of a credential with the base context and type
+func NewCredentialBuilder() *CredentialBuilder {
+	return &CredentialBuilder{
+		vc:    Credential{Context: []interface{}{vcContext}},
+		types: []string{vcType},
+	}
+}
+
+// SetID sets the ID of the credential
+func (b *CredentialBuilder) SetID(id string) *CredentialBuilder {
+	b.vc.ID = id
+	return b
+}
+
+// AddContext adds a JSON-LD context to the base context, e.g. the context of the credential type
+func (b *CredentialBuilder) AddContext(context string) *CredentialBuilder {
+	b.vc.Context = append(b.vc.Context, context)
+	return b
+}
+
+// AddType adds a type to the VerifiableCredential type
+func (b *CredentialBuilder) AddType(credentialType string) *CredentialBuilder {
+	b.types = append(b.types, credentialType)
+	return b
+}
+
+// SetIssuer sets the issuer of the credential
+func (b *CredentialBuilder) SetIssuer(issuer Issuer) *CredentialBuilder {
+	b.vc.Issuer = issuer
+	return b
+}
+
+// AddSubject adds a credentialSubject, e.g. a map of the claims about the subject. The credentials of several
+// subjects are built with the subjects as an array.
+func (b *CredentialBuilder) AddSubject(subject Subject) *CredentialBuilder {
+	b.subjects = append(b.subjects, subject)
+	return b
+}
+
+// SetIssued sets the issuanceDate of the credential, the current time of the clock of the Build options by default
+func (b *CredentialBuilder) SetIssued(issued time.Time) *CredentialBuilder {
+	b.vc.Issued = &issued
+	return b
+}
+
+// SetExpiry sets the expirationDate of the credential
+func (b *CredentialBuilder) SetExpiry(expired time.Time) *CredentialBuilder {
+	b.vc.Expired = &expired
+	return b
+}
+
+// AddSchema adds a credentialSchema, the credential is validated against it by Build
+func (b *CredentialBuilder) AddSchema(schema CredentialSchema) *CredentialBuilder {
+	b.vc.Schemas = append(b.vc.Schemas, schema)
+	return b
+}
+
+// SetStatus sets the credentialStatus of the credential
+func (b *CredentialBuilder) SetStatus(status *CredentialStatus) *CredentialBuilder {
+	b.vc.Status = status
+	return b
+}
+
+// AddTermsOfUse adds terms of use of the credential
+func (b *CredentialBuilder) AddTermsOfUse(terms TermsOfUse) *CredentialBuilder {
+	b.vc.TermsOfUse = append(b.vc.TermsOfUse, terms)
+	return b
+}
+
+// AddEvidence adds an evidence of the claims of the credential, the evidences are built as an array
+func (b *CredentialBuilder) AddEvidence(evidence Evidence) *CredentialBuilder {
+	b.evidences = append(b.evidences, evidence)
+	return b
+}
+
+// SetRefreshService sets the refreshService of the credential
+func (b *CredentialBuilder) SetRefreshService(service *RefreshService) *CredentialBuilder {
+	b.vc.RefreshService = service
+	return b
+}
+
+// SetCustomField sets a top-level field not modeled by Credential
+func (b *CredentialBuilder) SetCustomField(name string, value interface{}) *CredentialBuilder {
+	if b.vc.CustomFields == nil {
+		b.vc.CustomFields = make(CustomFields)
+	}
+
+	b.vc.CustomFields[name] = value
+
+	return b
+}
+
+// Build returns the credential decoded by NewCredential with the options, so the built credential is validated
+// as the credentials received. The issuanceDate is set at the current time of the clock of the options if not set.
+// The credential must then be signed, e.g. with GenerateProof.
+func (b *CredentialBuilder) Build(opts ...CredentialOpt) (*Credential, error) {
+	if len(b.subjects) == 0 {
+		return nil, errors.New("credential subject is not defined")
+	}
+
+	vc := b.vc
+	vc.Type = append([]string(nil), b.types...)
+
+	if len(b.evidences) > 0 {
+		vc.Evidence = append([]Evidence(nil), b.evidences...)
+	}
+
+	if len(b.subjects) == 1 {
+		vc.Subject = b.subjects[0]
+	} else {
+		vc.Subject = append([]Subject(nil), b.subjects...)
+	}
+
+	if vc.Issued == nil {
+		crOpts := defaultCredentialOpts()
+		for _, opt := range opts {
+			opt(crOpts)
+		}
+
+		issued := crOpts.clock.Now().UTC().Truncate(time.Second)
+		vc.Issued = &issued
+	}
+
+	vcBytes, err := vc.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+
+	return NewCredential(vcBytes, opts...)
+}
@@ -0,0 +1,127 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package verifiable
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	mockclock "github.com/hyperledger/aries-framework-go/pkg/internal/mock/clock"
+)
+
+func TestCredentialBuilder(t *testing.T) {
+	issued := time.Date(2010, time.January, 1, 19, 23, 24, 0, time.UTC)
+	expired := issued.AddDate(10, 0, 0)
+
+	newBuilder := func() *CredentialBuilder {
+		return NewCredentialBuilder().
+			SetID("http://example.edu/credentials/1872").
+			AddContext("https://www.w3.org/2018/credentials/examples/v1").
+			AddType("UniversityDegreeCredential").
+			SetIssuer(Issuer{ID: "did:example:76e12ec712ebc6f1c221ebfeb1f", Name: "Example University"}).
+			AddSubject(map[string]interface{}{
+				"id":     "did:example:ebfeb1f712ebc6f1c276e12ec21",
+				"degree": map[string]interface{}{"type": "BachelorDegree", "university": "MIT"},
+			}).
+			SetIssued(issued).
+			SetExpiry(expired)
+	}
+
+	t.Run("test build", func(t *testing.T) {
+		vc, err := newBuilder().
+			SetStatus(&CredentialStatus{ID: "https://example.edu/status/24", Type: CredentialStatusList2017}).
+			AddTermsOfUse(TermsOfUse{Types: []string{"IssuerPolicy"},
+				ID: "http://example.com/policies/credential/4"}).
+			AddEvidence(Evidence{Types: []string{"DocumentVerification"},
+				CustomFields: CustomFields{"evidenceDocument": "DriversLicense"}}).
+			SetRefreshService(&RefreshService{ID: "https://example.edu/refresh/3732", Type: "ManualRefreshService2018"}).
+			SetCustomField("referenceNumber", 83294847.0).
+			Build(WithNoCustomSchemaCheck())
+		require.NoError(t, err)
+
+		require.Equal(t, "http://example.edu/credentials/1872", vc.ID)
+		require.Equal(t, []interface{}{vcContext, "https://www.w3.org/2018/credentials/examples/v1"}, vc.Context)
+		require.Equal(t, []string{vcType, "UniversityDegreeCredential"}, vc.Types())
+		require.Equal(t, Issuer{ID: "did:example:76e12ec712ebc6f1c221ebfeb1f", Name: "Example University"}, vc.Issuer)
+		require.Equal(t, issued, *vc.Issued)
+		require.Equal(t, expired, *vc.Expired)
+		require.Equal(t, CredentialStatusList2017, vc.Status.Type)
+		require.Equal(t, []TermsOfUse{{Types: []string{"IssuerPolicy"},
+			ID: "http://example.com/policies/credential/4"}}, vc.TermsOfUse)
+		require.Equal(t, "ManualRefreshService2018", vc.RefreshService.Type)
+		require.Equal(t, CustomFields{"referenceNumber": 83294847.0}, vc.CustomFields)
+
+		require.Equal(t, []Evidence{{Types: []string{"DocumentVerification"},
+			CustomFields: CustomFields{"evidenceDocument": "DriversLicense"}}}, vc.Evidence)
+
+		subjectID, err := vc.SubjectID()
+		require.NoError(t, err)
+		require.Equal(t, "did:example:ebfeb1f712ebc6f1c276e12ec21", subjectID)
+	})
+
+	t.Run("test build several subjects", func(t *testing.T) {
+		vc, err := newBuilder().
+			AddSubject(map[string]interface{}{"id": "did:example:c276e12ec21ebfeb1f712ebc6f1"}).
+			Build(WithNoCustomSchemaCheck())
+		require.NoError(t, err)
+
+		vcBytes, err := vc.MarshalJSON()
+		require.NoError(t, err)
+		require.Contains(t, string(vcBytes), `"credentialSubject":[{`)
+	})
+
+	t.Run("test issuance date of the clock", func(t *testing.T) {
+		now := time.Date(2020, time.January, 1, 12, 0, 0, 500, time.UTC)
+
+		vc, err := NewCredentialBuilder().
+			AddType("UniversityDegreeCredential").
+			SetIssuer(Issuer{ID: "did:example:76e12ec712ebc6f1c221ebfeb1f"}).
+			AddSubject(map[string]interface{}{"id": "did:example:ebfeb1f712ebc6f1c276e12ec21"}).
+			Build(WithNoCustomSchemaCheck(), WithClock(&mockclock.Clock{Time: now}))
+		require.NoError(t, err)
+		require.Equal(t, now.Truncate(time.Second), *vc.Issued)
+	})
+
+	t.Run("test build validated against the schemas", func(t *testing.T) {
+		registry := WithSchemaRegistry(NewEmbeddedSchemaRegistry(map[string][]byte{
+			degreeSchemaID: []byte(degreeSchema),
+		}))
+
+		_, err := newBuilder().
+			AddSchema(CredentialSchema{ID: degreeSchemaID, Type: jsonSchema2018Type}).
+			Build(registry)
+		require.Error(t, err)
+
+		validationErr := &ValidationError{}
+		require.True(t, errors.As(err, &validationErr))
+		require.Equal(t, "credentialSubject.degree", validationErr.Violations[0].Field)
+	})
+
+	t.Run("test invalid credentials", func(t *testing.T) {
+		_, err := NewCredentialBuilder().
+			SetIssuer(Issuer{ID: "did:example:76e12ec712ebc6f1c221ebfeb1f"}).
+			Build(WithNoCustomSchemaCheck())
+		require.EqualError(t, err, "credential subject is not defined")
+
+		_, err = NewCredentialBuilder().
+			AddType("UniversityDegreeCredential").
+			AddSubject(map[string]interface{}{"id": "did:example:ebfeb1f712ebc6f1c276e12ec21"}).
+			Build(WithNoCustomSchemaCheck())
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "issuer: Does not match format 'uri'")
+
+		// the credentials have a type in addition to VerifiableCredential
+		_, err = NewCredentialBuilder().
+			SetIssuer(Issuer{ID: "did:example:76e12ec712ebc6f1c221ebfeb1f"}).
+			AddSubject(map[string]interface{}{"id": "did:example:ebfeb1f712ebc6f1c276e12ec21"}).
+			Build(WithNoCustomSchemaCheck())
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "type: Array must have at least 2 items")
+	})
+}
@@ -0,0 +1,74 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package verifiable
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/common/nonces"
+	"github.com/hyperledger/aries-framework-go/pkg/storage"
+)
+
+// ChallengeNamespace is the store name space of the presentation request challenges
+const ChallengeNamespace = "challenge"
+
+var (
+	// ErrChallengeNotFound is returned when the challenge has not been issued by the challenge store
+	ErrChallengeNotFound = errors.New("presentation challenge not found")
+	// ErrChallengeExpired is returned when the challenge is used after its expiry
+	ErrChallengeExpired = errors.New("presentation challenge expired")
+	// ErrChallengeUsed is returned when the challenge has already been used, i.e. the presentation is replayed
+	ErrChallengeUsed = errors.New("presentation challenge already used")
+)
+
+// ChallengeStore issues the challenges of the presentation requests and accepts every challenge once
+// before its expiry, so the verifier rejects the replayed presentations. The challenges are the nonces of
+// the domains of the requests. The underlying store must support storage.ConditionalStore, it may then be
+// shared by several verifier instances.
+type ChallengeStore struct {
+	nonces *nonces.Store
+	ttl    time.Duration
+}
+
+// NewChallengeStore returns new challenge store, the issued challenges expire after the TTL
+func NewChallengeStore(p storage.Provider, ttl time.Duration) (*ChallengeStore, error) {
+	store, err := p.OpenStore(ChallengeNamespace)
+	if err != nil {
+		return nil, fmt.Errorf("open challenge store: %w", err)
+	}
+
+	return &ChallengeStore{nonces: nonces.New(store), ttl: ttl}, nil
+}
+
+// NewRequest returns a presentation request for the domain with a new random challenge
+func (s *ChallengeStore) NewRequest(domain string) (*PresentationRequest, error) {
+	challenge, err := s.nonces.Create(domain, s.ttl)
+	if err != nil {
+		return nil, fmt.Errorf("new challenge: %w", err)
+	}
+
+	return &PresentationRequest{Challenge: challenge, Domain: domain}, nil
+}
+
+// Use marks the challenge of the request used, the challenge must have been issued for the domain
+// of the request, not be expired and not be used yet
+func (s *ChallengeStore) Use(request *PresentationRequest) error {
+	err := s.nonces.Burn(request.Domain, request.Challenge)
+
+	switch {
+	case errors.Is(err, nonces.ErrNotFound):
+		return fmt.Errorf("%w: %s", ErrChallengeNotFound, err)
+	case errors.Is(err, nonces.ErrExpired):
+		return ErrChallengeExpired
+	case errors.Is(err, nonces.ErrUsed):
+		return ErrChallengeUsed
+	default:
+		return err
+	}
+}
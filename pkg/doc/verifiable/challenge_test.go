@@ -0,0 +1,106 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package verifiable
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hyperledger/aries-framework-go/pkg/common/nonces"
+	mockclock "github.com/hyperledger/aries-framework-go/pkg/internal/mock/clock"
+	mockstorage "github.com/hyperledger/aries-framework-go/pkg/internal/mock/storage"
+)
+
+func TestChallengeStore(t *testing.T) {
+	newStore := func() *ChallengeStore {
+		s, err := NewChallengeStore(mockstorage.NewMockStoreProvider(), time.Minute)
+		require.NoError(t, err)
+
+		return s
+	}
+
+	t.Run("test challenge used once", func(t *testing.T) {
+		s := newStore()
+
+		request, err := s.NewRequest("verifier.example.com")
+		require.NoError(t, err)
+		require.Equal(t, "verifier.example.com", request.Domain)
+		require.Len(t, request.Challenge, 43)
+
+		other, err := s.NewRequest("verifier.example.com")
+		require.NoError(t, err)
+		require.NotEqual(t, request.Challenge, other.Challenge)
+
+		require.NoError(t, s.Use(request))
+		require.True(t, errors.Is(s.Use(request), ErrChallengeUsed))
+		require.NoError(t, s.Use(other))
+	})
+
+	t.Run("test challenge not issued", func(t *testing.T) {
+		s := newStore()
+		require.True(t, errors.Is(s.Use(&PresentationRequest{Challenge: "unknown"}), ErrChallengeNotFound))
+
+		request, err := s.NewRequest("verifier.example.com")
+		require.NoError(t, err)
+
+		err = s.Use(&PresentationRequest{Challenge: request.Challenge, Domain: "other.example.com"})
+		require.True(t, errors.Is(err, ErrChallengeNotFound))
+		require.Contains(t, err.Error(), "nonce issued for scope verifier.example.com")
+	})
+
+	t.Run("test challenge expired", func(t *testing.T) {
+		s := newStore()
+		clock := &mockclock.Clock{Time: time.Now()}
+		s.nonces = nonces.New(mockstorage.NewMockStoreProvider().Store, nonces.WithClock(clock))
+
+		request, err := s.NewRequest("verifier.example.com")
+		require.NoError(t, err)
+
+		clock.Add(2 * time.Minute)
+		require.True(t, errors.Is(s.Use(request), ErrChallengeExpired))
+	})
+
+	t.Run("test store errors", func(t *testing.T) {
+		_, err := NewChallengeStore(&mockstorage.MockStoreProvider{ErrOpenStoreHandle: errors.New("open error")},
+			time.Minute)
+		require.EqualError(t, err, "open challenge store: open error")
+
+		s := newStore()
+		s.nonces = nonces.New(&mockstorage.MockStore{Store: map[string][]byte{}, ErrPut: errors.New("put error")})
+		_, err = s.NewRequest("")
+		require.EqualError(t, err, "new challenge: failed to save nonce: put error")
+	})
+}
+
+func TestNewPresentation_ChallengeStore(t *testing.T) {
+	s, err := NewChallengeStore(mockstorage.NewMockStoreProvider(), time.Minute)
+	require.NoError(t, err)
+
+	request, err := s.NewRequest("verifier.example.com")
+	require.NoError(t, err)
+
+	holder := newTestHolder(t)
+
+	vpBytes, err := holder.presentation(t, request).MarshalJSON()
+	require.NoError(t, err)
+
+	_, err = NewPresentation(vpBytes, WithPresentationProofCheck(holder), WithPresentationRequest(request),
+		WithChallengeStore(s))
+	require.NoError(t, err)
+
+	// the replayed presentation is rejected
+	_, err = NewPresentation(vpBytes, WithPresentationProofCheck(holder), WithPresentationRequest(request),
+		WithChallengeStore(s))
+	require.True(t, errors.Is(err, ErrChallengeUsed))
+	require.Contains(t, err.Error(), "verifiable presentation challenge")
+
+	_, err = NewPresentation(vpBytes, WithPresentationProofCheck(holder), WithChallengeStore(s))
+	require.EqualError(t, err, "challenge check requires the presentation request")
+}
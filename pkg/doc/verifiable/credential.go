@@ -6,15 +6,20 @@ SPDX-License-Identifier: Apache-2.0
 package verifiable
 
 import (
+	"bytes"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"strings"
 	"time"
 
+	"github.com/piprate/json-gold/ld"
 	"github.com/xeipuuv/gojsonschema"
 
+	"github.com/hyperledger/aries-framework-go/pkg/common/clock"
 	"github.com/hyperledger/aries-framework-go/pkg/common/log"
 )
 
@@ -109,15 +114,17 @@ const defaultSchema = `{
       "$ref": "#/definitions/timestamp"
     },
     "proof": {
-      "type": "object",
-      "required": [
-        "type"
-      ],
-      "properties": {
-        "type": {
-          "type": "string"
+      "anyOf": [
+        {
+          "$ref": "#/definitions/proof"
+        },
+        {
+          "type": "array",
+          "items": {
+            "$ref": "#/definitions/proof"
+          }
         }
-      }
+      ]
     },
     "expirationDate": {
       "$ref": "#/definitions/timestamp"
@@ -129,13 +136,27 @@ const defaultSchema = `{
       "$ref": "#/definitions/typedIDs"
     },
     "evidence": {
-      "$ref": "#/definitions/typedIDs"
+      "$ref": "#/definitions/typedObjects"
+    },
+    "termsOfUse": {
+      "$ref": "#/definitions/typedObjects"
     },
     "refreshService": {
       "$ref": "#/definitions/typedID"
     }
   },
   "definitions": {
+    "proof": {
+      "type": "object",
+      "required": [
+        "type"
+      ],
+      "properties": {
+        "type": {
+          "type": "string"
+        }
+      }
+    },
     "timestamp": {
       "type": "string",
       "pattern": "\\d{4}-[01]\\d-[0-3]\\dT[0-2]\\d:[0-5]\\d:[0-5]\\dZ"
@@ -178,6 +199,44 @@ const defaultSchema = `{
           }
         }
       ]
+    },
+    "typedObject": {
+      "type": "object",
+      "required": [
+        "type"
+      ],
+      "properties": {
+        "id": {
+          "type": "string",
+          "format": "uri"
+        },
+        "type": {
+          "anyOf": [
+            {
+              "type": "string"
+            },
+            {
+              "type": "array",
+              "items": {
+                "type": "string"
+              }
+            }
+          ]
+        }
+      }
+    },
+    "typedObjects": {
+      "anyOf": [
+        {
+          "$ref": "#/definitions/typedObject"
+        },
+        {
+          "type": "array",
+          "items": {
+            "$ref": "#/definitions/typedObject"
+          }
+        }
+      ]
     }
   }
 }
@@ -188,12 +247,14 @@ const jsonSchema2018Type = "JsonSchemaValidator2018"
 //nolint:gochecknoglobals
 var defaultSchemaLoader = gojsonschema.NewStringLoader(defaultSchema)
 
+// defaultSchemaRegistry is shared by the credentials decoded without a schema registry, so the custom schemas are
+// downloaded once
+//nolint:gochecknoglobals
+var defaultSchemaRegistry = NewCachingSchemaRegistry(NewHTTPSchemaRegistry(&http.Client{}), defaultSchemaCacheSize)
+
 // Proof defines embedded proof of Verifiable Credential
 type Proof interface{}
 
-// Evidence defines evidence of Verifiable Credential
-type Evidence interface{}
-
 type typedID struct {
 	ID   string `json:"id,omitempty"`
 	Type string `json:"type,omitempty"`
@@ -209,7 +270,14 @@ type Issuer struct {
 type Subject interface{}
 
 // CredentialStatus defines status of Verifiable Credential
-type CredentialStatus typedID
+type CredentialStatus struct {
+	ID   string `json:"id,omitempty"`
+	Type string `json:"type,omitempty"`
+	// RevocationListIndex is the index of the credential in the revocation list of RevocationList2020Status
+	RevocationListIndex string `json:"revocationListIndex,omitempty"`
+	// RevocationListCredential is the URL of the revocation list credential of RevocationList2020Status
+	RevocationListCredential string `json:"revocationListCredential,omitempty"`
+}
 
 // CredentialSchema defines a link to data schema which enforces a specific structure of Verifiable Credential.
 type CredentialSchema typedID
@@ -217,9 +285,6 @@ type CredentialSchema typedID
 // RefreshService provides a way to automatic refresh of expired Verifiable Credential
 type RefreshService typedID
 
-// TermsOfUse represents terms of use of Verifiable Credential by Issuer or Verifiable Presentation by Holder.
-type TermsOfUse typedID
-
 // Credential Verifiable Credential definition
 type Credential struct {
 	Context        []interface{}
@@ -232,9 +297,16 @@ type Credential struct {
 	Proof          *Proof
 	Status         *CredentialStatus
 	Schemas        []CredentialSchema
-	Evidence       *Evidence
+	Evidence       []Evidence
 	TermsOfUse     []TermsOfUse
 	RefreshService *RefreshService
+	CustomFields   CustomFields
+
+	// singleEvidence marshals the evidence as an object rather than an array, as decoded
+	singleEvidence bool
+
+	// canonicalMarshal makes MarshalJSON produce canonical JSON
+	canonicalMarshal bool
 }
 
 // rawCredential is a basic verifiable credential
@@ -249,9 +321,19 @@ type rawCredential struct {
 	Status         *CredentialStatus `json:"credentialStatus,omitempty"`
 	Issuer         interface{}       `json:"issuer,omitempty"`
 	Schema         interface{}       `json:"credentialSchema,omitempty"`
-	Evidence       *Evidence         `json:"evidence,omitempty"`
-	TermsOfUse     []TermsOfUse      `json:"termsOfUse,omitempty"`
+	Evidence       *evidenceList     `json:"evidence,omitempty"`
+	TermsOfUse     termsOfUseList    `json:"termsOfUse,omitempty"`
 	RefreshService *RefreshService   `json:"refreshService,omitempty"`
+
+	CustomFields CustomFields `json:"-"`
+}
+
+// modeledCredentialFields are the JSON fields of rawCredential
+//
+//nolint:gochecknoglobals
+var modeledCredentialFields = []string{
+	"@context", "id", "type", "credentialSubject", "issuanceDate", "expirationDate", "proof",
+	"credentialStatus", "issuer", "credentialSchema", "evidence", "termsOfUse", "refreshService",
 }
 
 type typeSingle struct {
@@ -310,14 +392,21 @@ const (
 )
 
 // credentialOpts holds options for the Verifiable Credential decoding
-// it has a http.Client instance initialized with default parameters
+// it has a HTTP schema registry initialized with default parameters
 type credentialOpts struct {
-	schemaDownloadClient   *http.Client
+	schemaRegistry         SchemaRegistry
 	disabledCustomSchema   bool
+	schemaDrafts           map[string]JSONSchemaDraft
 	decoders               []CredentialDecoder
 	template               CredentialTemplate
 	issuerPublicKeyFetcher PublicKeyFetcher
 	jwtDecoding            jwtDecoding
+	canonicalMarshal       bool
+	clock                  clock.Clock
+	proofChecker           *ProofChecker
+	statusVerifiers        []StatusVerifier
+	subjectDecoder         SubjectDecoder
+	jsonldLoader           ld.DocumentLoader
 }
 
 // CredentialOpt is the Verifiable Credential decoding option
@@ -325,9 +414,26 @@ type CredentialOpt func(opts *credentialOpts)
 
 // WithSchemaDownloadClient option is for definition of HTTP(s) client used during decoding of Verifiable Credential.
 // If custom credentialSchema is defined in Verifiable Credential, the client downloads from the specified URL.
+// The schemas are cached by the option, WithSchemaRegistry shares a CachingSchemaRegistry across the options.
 func WithSchemaDownloadClient(client *http.Client) CredentialOpt {
 	return func(opts *credentialOpts) {
-		opts.schemaDownloadClient = client
+		opts.schemaRegistry = NewCachingSchemaRegistry(NewHTTPSchemaRegistry(client), defaultSchemaCacheSize)
+	}
+}
+
+// WithClock option is for definition of the clock of the issuance dates of the credentials issued from templates,
+// the system clock by default.
+func WithClock(c clock.Clock) CredentialOpt {
+	return func(opts *credentialOpts) {
+		opts.clock = c
+	}
+}
+
+// WithSchemaRegistry option is for definition of the registry the custom credentialSchema and the schemas
+// it references are loaded from, instead of downloading them from their URL.
+func WithSchemaRegistry(registry SchemaRegistry) CredentialOpt {
+	return func(opts *credentialOpts) {
+		opts.schemaRegistry = registry
 	}
 }
 
@@ -353,6 +459,14 @@ func WithTemplate(template CredentialTemplate) CredentialOpt {
 	}
 }
 
+// WithCanonicalMarshal option makes MarshalJSON of the decoded Verifiable Credential produce canonical JSON,
+// see MarshalCanonicalJSON.
+func WithCanonicalMarshal() CredentialOpt {
+	return func(opts *credentialOpts) {
+		opts.canonicalMarshal = true
+	}
+}
+
 // WithJWSDecoding indicates that Verifiable Credential should be decoded from JWS using
 // the public key fetcher.
 func WithJWSDecoding(fetcher PublicKeyFetcher) CredentialOpt {
@@ -437,18 +551,46 @@ func NewCredential(vcData []byte, opts ...CredentialOpt) (*Credential, error) {
 		return nil, err
 	}
 
+	if crOpts.jsonldLoader != nil {
+		if err = validateJSONLD(vcDataDecoded, crOpts.jsonldLoader); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = checkProofs(vcDataDecoded, crOpts); err != nil {
+		return nil, err
+	}
+
+	if err = checkStatus(vcDataDecoded, raw.Status, crOpts); err != nil {
+		return nil, err
+	}
+
 	cred := crOpts.template()
 	cred.Context = raw.Context
 	cred.ID = raw.ID
-	cred.Subject = raw.Subject
 	cred.Issued = raw.Issued
 	cred.Expired = raw.Expired
 	cred.Proof = raw.Proof
 	cred.Status = raw.Status
 	cred.Schemas = schemas
-	cred.Evidence = raw.Evidence
 	cred.RefreshService = raw.RefreshService
 	cred.TermsOfUse = raw.TermsOfUse
+	cred.canonicalMarshal = crOpts.canonicalMarshal
+
+	if raw.Evidence != nil {
+		cred.Evidence = raw.Evidence.evidences
+		cred.singleEvidence = raw.Evidence.single
+	}
+
+	cred.Subject, err = decodeSubject(vcDataDecoded, raw.Subject, crOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	cred.CustomFields, err = decodeCustomFields(vcDataDecoded, modeledCredentialFields)
+	if err != nil {
+		return nil, err
+	}
 
 	for _, decoder := range crOpts.decoders {
 		err = decoder(vcDataDecoded, cred)
@@ -460,6 +602,9 @@ func NewCredential(vcData []byte, opts ...CredentialOpt) (*Credential, error) {
 	return cred, nil
 }
 
+// decodeRaw decodes the credential in JSON form, or in JWT form with the JWS or the unsecured JWT decoding. The
+// JWT credentials are detected by their compact serialization, so they are rejected with an explicit error if
+// the JWT decoding isn't configured.
 func decodeRaw(vcData []byte, crOpts *credentialOpts) ([]byte, *rawCredential, error) {
 	switch crOpts.jwtDecoding {
 	case jwsDecoding:
@@ -477,7 +622,15 @@ func decodeRaw(vcData []byte, crOpts *credentialOpts) ([]byte, *rawCredential, e
 		return rawBytes, rawCred, nil
 	}
 
-	// unmarshal VC from JSON
+	if isJWT(vcData) {
+		return nil, nil, errors.New("JWT encoded verifiable credential requires JWS or unsecured JWT decoding")
+	}
+
+	return decodeRawJSON(vcData)
+}
+
+// decodeRawJSON unmarshals the credential from JSON
+func decodeRawJSON(vcData []byte) ([]byte, *rawCredential, error) {
 	raw := &rawCredential{}
 	err := json.Unmarshal(vcData, raw)
 	if err != nil {
@@ -486,6 +639,23 @@ func decodeRaw(vcData []byte, crOpts *credentialOpts) ([]byte, *rawCredential, e
 	return vcData, raw, nil
 }
 
+// isJWT returns true if the data has the compact serialization of a JWT: base64url encoded header, claims and
+// signature separated by dots, the signature being empty for the unsecured JWTs
+func isJWT(data []byte) bool {
+	parts := strings.Split(string(bytes.TrimSpace(data)), ".")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" {
+		return false
+	}
+
+	for _, part := range parts {
+		if _, err := base64.RawURLEncoding.DecodeString(part); err != nil {
+			return false
+		}
+	}
+
+	return true
+}
+
 func loadCredentialSchemas(raw *rawCredential, vcDataDecoded []byte) ([]CredentialSchema, error) {
 	if raw.Schema != nil {
 		schemas, err := decodeCredentialSchema(vcDataDecoded)
@@ -499,11 +669,12 @@ func loadCredentialSchemas(raw *rawCredential, vcDataDecoded []byte) ([]Credenti
 
 func defaultCredentialOpts() *credentialOpts {
 	return &credentialOpts{
-		schemaDownloadClient: &http.Client{},
+		schemaRegistry:       defaultSchemaRegistry,
 		disabledCustomSchema: false,
 		decoders:             []CredentialDecoder{decodeIssuer, decodeType},
 		template:             func() *Credential { return &Credential{} },
 		jwtDecoding:          noJwtDecoding,
+		clock:                clock.System(),
 	}
 }
 
@@ -533,56 +704,102 @@ func issuerToSerialize(vc *Credential) interface{} {
 func validate(data []byte, schemas []CredentialSchema, opts *credentialOpts) error {
 	// Validate that the Verifiable Credential conforms to the serialization of the Verifiable Credential data model
 	// (https://w3c.github.io/vc-data-model/#example-1-a-simple-example-of-a-verifiable-credential)
-	schemaLoader, err := getSchemaLoader(schemas, opts)
+	schema, err := getSchema(schemas, opts)
 	if err != nil {
 		return err
 	}
 
 	loader := gojsonschema.NewStringLoader(string(data))
-	result, err := gojsonschema.Validate(schemaLoader, loader)
+	result, err := schema.Validate(loader)
 	if err != nil {
 		return fmt.Errorf("validation of verifiable credential failed: %w", err)
 	}
 
 	if !result.Valid() {
-		errMsg := describeSchemaValidationError(result)
-		return errors.New(errMsg)
+		return newValidationError(result)
 	}
 
 	return nil
 }
 
-func describeSchemaValidationError(result *gojsonschema.Result) string {
-	errMsg := "verifiable credential is not valid:\n"
-	for _, desc := range result.Errors() {
-		errMsg += fmt.Sprintf("- %s\n", desc)
+// SchemaViolation is a violation of the credential schema by a field of the Verifiable Credential
+type SchemaViolation struct {
+	// Field is the path of the field, e.g. credentialSubject.degree.type, (root) for the credential itself
+	Field string
+	// Constraint is the failed constraint, e.g. required, invalid_type, format
+	Constraint string
+	// Description describes the violation
+	Description string
+}
+
+// ValidationError is returned when the Verifiable Credential doesn't conform to the credential schema or uses
+// terms not defined by its JSON-LD context, or the Verifiable Presentation doesn't conform to the presentation schema
+type ValidationError struct {
+	Violations []SchemaViolation
+	// document is the kind of the validated document, the verifiable credential by default
+	document string
+}
+
+func (e *ValidationError) Error() string {
+	document := e.document
+	if document == "" {
+		document = "verifiable credential"
 	}
+
+	errMsg := document + " is not valid:\n"
+	for _, v := range e.Violations {
+		errMsg += fmt.Sprintf("- %s: %s\n", v.Field, v.Description)
+	}
+
 	return errMsg
 }
 
-func getSchemaLoader(schemas []CredentialSchema, opts *credentialOpts) (gojsonschema.JSONLoader, error) {
+func newValidationError(result *gojsonschema.Result) *ValidationError {
+	violations := make([]SchemaViolation, len(result.Errors()))
+	for i, desc := range result.Errors() {
+		violations[i] = SchemaViolation{Field: desc.Field(), Constraint: desc.Type(), Description: desc.Description()}
+	}
+
+	return &ValidationError{Violations: violations}
+}
+
+func getSchema(schemas []CredentialSchema, opts *credentialOpts) (*gojsonschema.Schema, error) {
 	if opts.disabledCustomSchema {
-		return defaultSchemaLoader, nil
+		return defaultCredentialSchema()
 	}
 
 	for _, schema := range schemas {
 		switch schema.Type {
 		case jsonSchema2018Type:
-			customSchemaData, err := loadCredentialSchema(schema.ID, opts.schemaDownloadClient)
+			customSchemaData, err := opts.schemaRegistry.Get(schema.ID)
 			if err != nil {
 				return nil, fmt.Errorf("loading custom credential schema from %s failed: %w", schema.ID, err)
 			}
-			return gojsonschema.NewBytesLoader(customSchemaData), nil
+
+			customSchema, err := compileCredentialSchema(schema.ID, customSchemaData, opts)
+			if err != nil {
+				return nil, fmt.Errorf("compiling custom credential schema from %s failed: %w", schema.ID, err)
+			}
+
+			return customSchema, nil
 		default:
 			logger.Warnf("unsupported credential schema: %s. Using default schema for validation", schema.Type)
 		}
 	}
 
 	// If no custom schema is chosen, use default one
-	return defaultSchemaLoader, nil
+	return defaultCredentialSchema()
+}
+
+func defaultCredentialSchema() (*gojsonschema.Schema, error) {
+	schema, err := gojsonschema.NewSchema(defaultSchemaLoader)
+	if err != nil {
+		return nil, fmt.Errorf("validation of verifiable credential failed: %w", err)
+	}
+
+	return schema, nil
 }
 
-// todo cache credential schema (https://github.com/hyperledger/aries-framework-go/issues/185)
 func loadCredentialSchema(url string, client *http.Client) ([]byte, error) {
 	resp, err := client.Get(url)
 	if err != nil {
@@ -644,8 +861,24 @@ func (vc *Credential) SubjectID() (string, error) {
 		}
 		return subjectIDFn(subject[0])
 
-	default:
+	case []interface{}, nil:
 		return "", errors.New("subject of unknown structure")
+
+	default:
+		// the subjects decoded into typed structs
+		typedSubject := &struct {
+			ID string `json:"id,omitempty"`
+		}{}
+
+		if err := vc.DecodeSubject(typedSubject); err != nil {
+			return "", errors.New("subject of unknown structure")
+		}
+
+		if typedSubject.ID == "" {
+			return "", errors.New("subject id is not defined")
+		}
+
+		return typedSubject.ID, nil
 	}
 }
 
@@ -661,7 +894,7 @@ func (vc *Credential) Types() []string {
 }
 
 func (vc *Credential) raw() *rawCredential {
-	return &rawCredential{
+	raw := &rawCredential{
 		Context:        vc.Context,
 		ID:             vc.ID,
 		Type:           vc.Type,
@@ -671,11 +904,29 @@ func (vc *Credential) raw() *rawCredential {
 		Proof:          vc.Proof,
 		Status:         vc.Status,
 		Issuer:         issuerToSerialize(vc),
-		Schema:         vc.Schemas,
-		Evidence:       vc.Evidence,
 		RefreshService: vc.RefreshService,
 		TermsOfUse:     vc.TermsOfUse,
+		CustomFields:   vc.CustomFields,
 	}
+
+	// a nil slice in the interface would be marshalled as null
+	if len(vc.Schemas) > 0 {
+		raw.Schema = vc.Schemas
+	}
+
+	if len(vc.Evidence) > 0 {
+		raw.Evidence = &evidenceList{evidences: vc.Evidence, single: vc.singleEvidence}
+	}
+
+	return raw
+}
+
+// MarshalJSON marshals the raw credential with the custom fields appended to the modeled fields,
+// the custom fields named as a modeled field are ignored
+func (raw *rawCredential) MarshalJSON() ([]byte, error) {
+	type modeledCredential rawCredential
+
+	return marshalWithCustomFields((*modeledCredential)(raw), raw.CustomFields, modeledCredentialFields)
 }
 
 func (raw *rawCredential) marshalJSON() ([]byte, error) {
@@ -689,6 +940,10 @@ func (raw *rawCredential) marshalJSON() ([]byte, error) {
 
 // MarshalJSON converts Verifiable Credential to JSON bytes
 func (vc *Credential) MarshalJSON() ([]byte, error) {
+	if vc.canonicalMarshal {
+		return vc.MarshalCanonicalJSON()
+	}
+
 	byteCred, err := json.Marshal(vc.raw())
 	if err != nil {
 		return nil, fmt.Errorf("JSON marshalling of verifiable credential failed: %w", err)
@@ -696,3 +951,41 @@ func (vc *Credential) MarshalJSON() ([]byte, error) {
 
 	return byteCred, nil
 }
+
+// MarshalCanonicalJSON converts Verifiable Credential to canonical JSON bytes: the object keys are sorted,
+// there is no insignificant whitespace and the HTML characters are not escaped, so the digests and signatures
+// computed over the serialized credential are reproducible.
+func (vc *Credential) MarshalCanonicalJSON() ([]byte, error) {
+	byteCred, err := json.Marshal(vc.raw())
+	if err != nil {
+		return nil, fmt.Errorf("JSON marshalling of verifiable credential failed: %w", err)
+	}
+
+	canonical, err := canonicalJSON(byteCred)
+	if err != nil {
+		return nil, fmt.Errorf("canonical JSON marshalling of verifiable credential failed: %w", err)
+	}
+
+	return canonical, nil
+}
+
+// canonicalJSON re-encodes the JSON with sorted object keys, the numbers are kept as is
+func canonicalJSON(data []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var doc interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	buf := &bytes.Buffer{}
+	encoder := json.NewEncoder(buf)
+	encoder.SetEscapeHTML(false)
+
+	if err := encoder.Encode(doc); err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
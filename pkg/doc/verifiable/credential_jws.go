@@ -6,6 +6,8 @@ SPDX-License-Identifier: Apache-2.0
 package verifiable
 
 import (
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
 
 	"github.com/square/go-jose/v3"
@@ -63,6 +65,34 @@ func (jcc *JWTCredClaims) MarshalJWS(signatureAlg JWSAlgorithm, privateKey inter
 	return jws, nil
 }
 
+// MarshalJWSWithSigner serializes JWT into signed form (JWS) signed by the signer, e.g. with a key of the wallet
+// (see NewKeySigner). The signer must produce the signature of the algorithm over the JWS signing input.
+func (jcc *JWTCredClaims) MarshalJWSWithSigner(signatureAlg JWSAlgorithm, s Signer, keyID string) (string, error) {
+	headers, err := json.Marshal(map[string]string{
+		"alg": string(signatureAlg.Jose()),
+		"kid": keyID,
+		"typ": "JWT",
+	})
+	if err != nil {
+		return "", err
+	}
+
+	claims, err := json.Marshal(jcc)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal JWT claims: %w", err)
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(headers) + "." +
+		base64.RawURLEncoding.EncodeToString(claims)
+
+	signature, err := s.Sign([]byte(signingInput))
+	if err != nil {
+		return "", fmt.Errorf("failed to sign JWT: %w", err)
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
 // credJWSDecoder parses and verifies signature of serialized JWT. To verify the signature,
 // Public Key Fetcher is used.
 type credJWSDecoder struct {
@@ -9,6 +9,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/square/go-jose/v3/jwt"
 )
@@ -106,6 +107,10 @@ func decodeCredJWT(rawJWT []byte, credClaimsDecoder jwtCredClaimsDecoder) ([]byt
 		return nil, nil, fmt.Errorf("failed to decode raw Verifiable Credential JWT claims: %w", err)
 	}
 
+	if err = credClaims.checkCredentialClaims(); err != nil {
+		return nil, nil, err
+	}
+
 	// Apply VC-related claims from JWT.
 	credClaims.refineCredFromJWTClaims()
 	// Complement original "vc" JSON claim with data refined from JWT claims.
@@ -128,26 +133,75 @@ func (jcc *JWTCredClaims) refineCredFromJWTClaims() {
 		refineVCIssuerFromJWTClaims(raw, iss)
 	}
 
+	// nbf represents issuanceDate, iat is only used by the issuers not setting nbf
 	if nbf := jcc.NotBefore; nbf != nil {
 		nbfTime := nbf.Time().UTC()
 		raw.Issued = &nbfTime
+	} else if iat := jcc.IssuedAt; iat != nil {
+		iatTime := iat.Time().UTC()
+		raw.Issued = &iatTime
 	}
 
 	if jti := jcc.ID; jti != "" {
 		raw.ID = jcc.ID
 	}
 
-	if iat := jcc.IssuedAt; iat != nil {
-		iatTime := iat.Time().UTC()
-		raw.Issued = &iatTime
-	}
-
 	if exp := jcc.Expiry; exp != nil {
 		expTime := exp.Time().UTC()
 		raw.Expired = &expTime
 	}
 }
 
+// checkCredentialClaims checks the registered JWT claims represent the same values as the fields of the "vc"
+// claim they map to, if both are set: jti the id, iss the issuer, nbf the issuanceDate and exp the expirationDate
+func (jcc *JWTCredClaims) checkCredentialClaims() error {
+	raw := jcc.Credential
+	if jcc.Claims == nil || raw == nil {
+		return nil
+	}
+
+	if jcc.ID != "" && raw.ID != "" && jcc.ID != raw.ID {
+		return fmt.Errorf("JWT claim jti %s doesn't match the id %s of the credential", jcc.ID, raw.ID)
+	}
+
+	if issuerID := rawIssuerID(raw.Issuer); jcc.Issuer != "" && issuerID != "" && jcc.Issuer != issuerID {
+		return fmt.Errorf("JWT claim iss %s doesn't match the issuer %s of the credential", jcc.Issuer, issuerID)
+	}
+
+	if !sameTime(jcc.NotBefore, raw.Issued) {
+		return errors.New("JWT claim nbf doesn't match the issuanceDate of the credential")
+	}
+
+	if !sameTime(jcc.Expiry, raw.Expired) {
+		return errors.New("JWT claim exp doesn't match the expirationDate of the credential")
+	}
+
+	return nil
+}
+
+// sameTime returns true if the time of the claim is the time of the field at the second precision of the claims,
+// or if one of them is not set
+func sameTime(claim *jwt.NumericDate, field *time.Time) bool {
+	if claim == nil || field == nil {
+		return true
+	}
+
+	return claim.Time().Unix() == field.Unix()
+}
+
+// rawIssuerID returns the ID of the issuer, defined either as a string or as an object
+func rawIssuerID(issuer interface{}) string {
+	switch i := issuer.(type) {
+	case string:
+		return i
+	case map[string]interface{}:
+		id, _ := i["id"].(string)
+		return id
+	}
+
+	return ""
+}
+
 func refineVCIssuerFromJWTClaims(raw *rawCredential, iss string) {
 	// Issuer of Verifiable Credential could be either string (id) or struct (with "id" field).
 	switch issuer := raw.Issuer.(type) {
@@ -244,3 +244,108 @@ func createUnsecuredJWT(t *testing.T, cred []byte, minimize bool) []byte {
 
 	return []byte(vcJWT)
 }
+
+func TestCredential_MarshalJWSWithSigner(t *testing.T) {
+	issuer := newTestHolder(t)
+
+	vc, err := NewCredential([]byte(jwtTestCredential))
+	require.NoError(t, err)
+
+	jwtClaims, err := vc.JWTClaims(true)
+	require.NoError(t, err)
+
+	t.Run("test signed and verified", func(t *testing.T) {
+		jws, err := jwtClaims.MarshalJWSWithSigner(EdDSA, issuer, vc.Issuer.ID+"#keys-"+keyID)
+		require.NoError(t, err)
+
+		vcFromJWT, err := NewCredential([]byte(jws), WithJWSDecoding(func(issuerID, kid string) (interface{}, error) {
+			require.Equal(t, vc.Issuer.ID, issuerID)
+			require.Equal(t, vc.Issuer.ID+"#keys-"+keyID, kid)
+
+			return issuer.pub, nil
+		}))
+		require.NoError(t, err)
+		require.Equal(t, vc, vcFromJWT)
+
+		_, err = NewCredential([]byte(jws), WithJWSDecoding(func(issuerID, kid string) (interface{}, error) {
+			return newTestHolder(t).pub, nil
+		}))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "JWT signature verification failed")
+	})
+
+	t.Run("test signing error", func(t *testing.T) {
+		_, err := jwtClaims.MarshalJWSWithSigner(EdDSA, &failingSigner{}, keyID)
+		require.EqualError(t, err, "failed to sign JWT: sign error")
+	})
+}
+
+func TestNewCredential_JWTClaimsMapping(t *testing.T) {
+	vc, err := NewCredential([]byte(jwtTestCredential))
+	require.NoError(t, err)
+
+	decode := func(jwtClaims *JWTCredClaims) (*Credential, error) {
+		unsecuredJWT, err := jwtClaims.MarshalUnsecuredJWT()
+		require.NoError(t, err)
+
+		return NewCredential([]byte(unsecuredJWT), WithUnsecuredJWTDecoding())
+	}
+
+	t.Run("test JWT detected", func(t *testing.T) {
+		jwtClaims, err := vc.JWTClaims(false)
+		require.NoError(t, err)
+
+		unsecuredJWT, err := jwtClaims.MarshalUnsecuredJWT()
+		require.NoError(t, err)
+
+		_, err = NewCredential([]byte(unsecuredJWT))
+		require.EqualError(t, err, "JWT encoded verifiable credential requires JWS or unsecured JWT decoding")
+	})
+
+	t.Run("test nbf takes precedence over iat", func(t *testing.T) {
+		jwtClaims, err := vc.JWTClaims(true)
+		require.NoError(t, err)
+
+		jwtClaims.IssuedAt = jwt.NewNumericDate(vc.Issued.Add(time.Hour))
+
+		decoded, err := decode(jwtClaims)
+		require.NoError(t, err)
+		require.Equal(t, vc.Issued, decoded.Issued)
+
+		jwtClaims.NotBefore = nil
+
+		decoded, err = decode(jwtClaims)
+		require.NoError(t, err)
+		require.Equal(t, vc.Issued.Add(time.Hour), *decoded.Issued)
+	})
+
+	t.Run("test claims conflicting with the credential", func(t *testing.T) {
+		for claim, update := range map[string]func(claims *jwt.Claims){
+			"jti": func(claims *jwt.Claims) { claims.ID = "http://example.edu/credentials/other" },
+			"iss": func(claims *jwt.Claims) { claims.Issuer = "did:example:other" },
+			"nbf": func(claims *jwt.Claims) { claims.NotBefore = jwt.NewNumericDate(time.Now()) },
+			"exp": func(claims *jwt.Claims) { claims.Expiry = jwt.NewNumericDate(time.Now()) },
+		} {
+			withID := *vc
+			withID.ID = "http://example.edu/credentials/1872"
+
+			jwtClaims, err := withID.JWTClaims(false)
+			require.NoError(t, err)
+
+			_, err = decode(jwtClaims)
+			require.NoError(t, err)
+
+			update(jwtClaims.Claims)
+
+			_, err = decode(jwtClaims)
+			require.Error(t, err, claim)
+			require.Contains(t, err.Error(), "JWT claim "+claim+" ", claim)
+		}
+	})
+}
+
+type failingSigner struct{}
+
+func (s *failingSigner) Sign([]byte) ([]byte, error) {
+	return nil, errors.New("sign error")
+}
@@ -0,0 +1,139 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package verifiable
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/common/clock"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/signer"
+)
+
+const (
+	// Ed25519Signature2018 is the type of the Ed25519 linked data proofs
+	Ed25519Signature2018 = "Ed25519Signature2018"
+
+	credentialProofKey = "proof"
+)
+
+// ProofOptions holds the issuer signing settings of the credential proof
+type ProofOptions struct {
+	// SignatureType is the type of the linked data proof, Ed25519Signature2018 by default
+	SignatureType string
+	// Creator is the ID of the issuer key, e.g. DID public key ID
+	Creator string
+	// Created is the creation time of the proof, the current time by default
+	Created *time.Time
+	// Clock is the clock of the creation time if Created is not set, the system clock by default
+	Clock clock.Clock
+	// Domain restricts the proof to the domain, optional
+	Domain string
+	// Nonce is the challenge of the proof, optional
+	Nonce []byte
+	// ProofPurpose is the verification relationship of the issuer key, assertionMethod by default
+	ProofPurpose string
+}
+
+// MessageSigner signs messages with the keys it holds, e.g. wallet.Crypto
+type MessageSigner interface {
+	SignMessage(message []byte, fromVerKey string) ([]byte, error)
+}
+
+// keySigner signs with a key of the wallet
+type keySigner struct {
+	wallet MessageSigner
+	verKey string
+}
+
+// NewKeySigner returns the Signer signing with the key of the wallet identified by its verification key
+func NewKeySigner(wallet MessageSigner, verKey string) Signer {
+	return &keySigner{wallet: wallet, verKey: verKey}
+}
+
+// Sign signs the document with the key of the wallet
+func (s *keySigner) Sign(doc []byte) ([]byte, error) {
+	return s.wallet.SignMessage(doc, s.verKey)
+}
+
+// GenerateProof signs the credential by the issuer and adds the linked data proof to the proofs of the
+// credential, the default options apply if opts is nil. Only Ed25519Signature2018 proofs are supported.
+func (vc *Credential) GenerateProof(s Signer, opts *ProofOptions) error {
+	if vc.Issuer.ID == "" {
+		return errors.New("issuer of the verifiable credential is not defined")
+	}
+
+	if s == nil {
+		return errors.New("signer is missing")
+	}
+
+	if opts == nil {
+		opts = &ProofOptions{}
+	}
+
+	vcBytes, err := vc.MarshalJSON()
+	if err != nil {
+		return err
+	}
+
+	vcBytes, err = withProofList(vcBytes)
+	if err != nil {
+		return err
+	}
+
+	signatureType := opts.SignatureType
+	if signatureType == "" {
+		signatureType = Ed25519Signature2018
+	}
+
+	proofPurpose := opts.ProofPurpose
+	if proofPurpose == "" {
+		proofPurpose = string(did.AssertionMethod)
+	}
+
+	signed, err := signer.New().Sign(&signer.Context{
+		SignatureType: signatureType,
+		Creator:       opts.Creator,
+		Signer:        s,
+		Created:       opts.Created,
+		Clock:         opts.Clock,
+		Domain:        opts.Domain,
+		Nonce:         opts.Nonce,
+		ProofPurpose:  proofPurpose,
+	}, vcBytes)
+	if err != nil {
+		return fmt.Errorf("failed to add linked data proof to verifiable credential: %w", err)
+	}
+
+	raw := &rawCredential{}
+	if err := json.Unmarshal(signed, raw); err != nil {
+		return fmt.Errorf("JSON unmarshalling of signed verifiable credential failed: %w", err)
+	}
+
+	vc.Proof = raw.Proof
+
+	return nil
+}
+
+// withProofList turns the single proof of the credential into a list of proofs, so another proof can be added
+func withProofList(vcBytes []byte) ([]byte, error) {
+	vcMap := make(map[string]interface{})
+	if err := json.Unmarshal(vcBytes, &vcMap); err != nil {
+		return nil, err
+	}
+
+	p, ok := vcMap[credentialProofKey].(map[string]interface{})
+	if !ok {
+		return vcBytes, nil
+	}
+
+	vcMap[credentialProofKey] = []interface{}{p}
+
+	return json.Marshal(vcMap)
+}
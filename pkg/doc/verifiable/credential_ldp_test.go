@@ -0,0 +1,120 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package verifiable
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/verifier"
+	mockclock "github.com/hyperledger/aries-framework-go/pkg/internal/mock/clock"
+	mockwallet "github.com/hyperledger/aries-framework-go/pkg/internal/mock/wallet"
+)
+
+func TestCredential_GenerateProof(t *testing.T) {
+	issuer := newTestHolder(t)
+
+	t.Run("test proof generated and verified", func(t *testing.T) {
+		vc, err := NewCredential([]byte(validCredential), WithNoCustomSchemaCheck())
+		require.NoError(t, err)
+
+		vc.Proof = nil
+
+		err = vc.GenerateProof(issuer, &ProofOptions{Creator: holderKey})
+		require.NoError(t, err)
+		require.NotNil(t, vc.Proof)
+
+		vcBytes, err := vc.MarshalJSON()
+		require.NoError(t, err)
+		require.NoError(t, verifier.New(issuer).Verify(vcBytes))
+	})
+
+	t.Run("test proof added to the proofs of the credential", func(t *testing.T) {
+		vc, err := NewCredential([]byte(validCredential), WithNoCustomSchemaCheck())
+		require.NoError(t, err)
+
+		err = vc.GenerateProof(issuer, &ProofOptions{SignatureType: Ed25519Signature2018, Creator: holderKey,
+			Domain: "example.com", Nonce: []byte("challenge")})
+		require.NoError(t, err)
+
+		proofs, ok := (*vc.Proof).([]interface{})
+		require.True(t, ok)
+		require.Len(t, proofs, 2)
+		require.Equal(t, "RsaSignature2018", proofs[0].(map[string]interface{})["type"])
+		require.Equal(t, Ed25519Signature2018, proofs[1].(map[string]interface{})["type"])
+		require.Equal(t, "example.com", proofs[1].(map[string]interface{})["domain"])
+	})
+
+	t.Run("test proof created by the clock", func(t *testing.T) {
+		vc, err := NewCredential([]byte(validCredential), WithNoCustomSchemaCheck())
+		require.NoError(t, err)
+
+		vc.Proof = nil
+
+		err = vc.GenerateProof(issuer, &ProofOptions{Creator: holderKey,
+			Clock: &mockclock.Clock{Time: time.Date(2020, time.January, 1, 12, 0, 0, 0, time.UTC)}})
+		require.NoError(t, err)
+
+		proofs, ok := (*vc.Proof).([]interface{})
+		require.True(t, ok)
+		require.Equal(t, "2020-01-01T12:00:00Z", proofs[0].(map[string]interface{})["created"])
+	})
+
+	t.Run("test signed with the key of the wallet", func(t *testing.T) {
+		vc, err := NewCredential([]byte(validCredential), WithNoCustomSchemaCheck())
+		require.NoError(t, err)
+
+		vc.Proof = nil
+
+		err = vc.GenerateProof(NewKeySigner(&mockwallet.CloseableWallet{SignMessageValue: []byte("signature")},
+			"verKey"), &ProofOptions{Creator: holderKey})
+		require.NoError(t, err)
+
+		proofs, ok := (*vc.Proof).([]interface{})
+		require.True(t, ok)
+		require.Equal(t, "c2lnbmF0dXJl", proofs[0].(map[string]interface{})["proofValue"])
+
+		err = vc.GenerateProof(NewKeySigner(&mockwallet.CloseableWallet{SignMessageErr: errors.New("sign error")},
+			"verKey"), &ProofOptions{Creator: holderKey})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "sign error")
+	})
+
+	t.Run("test invalid options", func(t *testing.T) {
+		vc, err := NewCredential([]byte(validCredential), WithNoCustomSchemaCheck())
+		require.NoError(t, err)
+
+		err = vc.GenerateProof(nil, &ProofOptions{Creator: holderKey})
+		require.EqualError(t, err, "signer is missing")
+
+		err = vc.GenerateProof(issuer, &ProofOptions{})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "creator is missing")
+
+		err = vc.GenerateProof(issuer, nil)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "creator is missing")
+
+		err = vc.GenerateProof(issuer, &ProofOptions{SignatureType: "Unknown", Creator: holderKey})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to add linked data proof to verifiable credential")
+
+		vc.Issuer.ID = ""
+		err = vc.GenerateProof(issuer, &ProofOptions{Creator: holderKey})
+		require.EqualError(t, err, "issuer of the verifiable credential is not defined")
+	})
+}
+
+func TestNewKeySigner(t *testing.T) {
+	s := NewKeySigner(&mockwallet.CloseableWallet{SignMessageValue: []byte("signature")}, "verKey")
+
+	signature, err := s.Sign([]byte("doc"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("signature"), signature)
+}
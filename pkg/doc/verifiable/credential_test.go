@@ -10,6 +10,7 @@ import (
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -565,6 +566,131 @@ func TestJSONConversionCompositeIssuer(t *testing.T) {
 	require.Equal(t, vc, cred2)
 }
 
+func TestValidationError(t *testing.T) {
+	raw := make(map[string]interface{})
+	require.NoError(t, json.Unmarshal([]byte(validCredential), &raw))
+
+	delete(raw, "credentialSubject")
+	raw["credentialStatus"].(map[string]interface{})["id"] = "invalid URI"
+
+	data, err := json.Marshal(raw)
+	require.NoError(t, err)
+
+	_, err = NewCredential(data)
+	require.Error(t, err)
+
+	var validationErr *ValidationError
+	require.True(t, errors.As(err, &validationErr))
+	require.ElementsMatch(t, []SchemaViolation{
+		{Field: "(root)", Constraint: "required", Description: "credentialSubject is required"},
+		{Field: "credentialStatus.id", Constraint: "format", Description: "Does not match format 'uri'"},
+	}, validationErr.Violations)
+
+	require.Contains(t, err.Error(), "verifiable credential is not valid:\n")
+	require.Contains(t, err.Error(), "- (root): credentialSubject is required\n")
+	require.Contains(t, err.Error(), "- credentialStatus.id: Does not match format 'uri'\n")
+}
+
+func TestCustomFields(t *testing.T) {
+	raw := make(map[string]interface{})
+	require.NoError(t, json.Unmarshal([]byte(validCredential), &raw))
+
+	raw["referenceNumber"] = 83294847.0
+	raw["extension"] = map[string]interface{}{"name": "Example"}
+
+	data, err := json.Marshal(raw)
+	require.NoError(t, err)
+
+	vc, err := NewCredential(data)
+	require.NoError(t, err)
+	require.Equal(t, CustomFields{"referenceNumber": 83294847.0, "extension": map[string]interface{}{"name": "Example"}},
+		vc.CustomFields)
+
+	t.Run("test custom fields re-emitted", func(t *testing.T) {
+		byteCred, err := vc.MarshalJSON()
+		require.NoError(t, err)
+
+		roundTrip := make(map[string]interface{})
+		require.NoError(t, json.Unmarshal(byteCred, &roundTrip))
+		require.Equal(t, 83294847.0, roundTrip["referenceNumber"])
+		require.Equal(t, map[string]interface{}{"name": "Example"}, roundTrip["extension"])
+
+		cred2, err := NewCredential(byteCred)
+		require.NoError(t, err)
+		require.Equal(t, vc, cred2)
+	})
+
+	t.Run("test custom field named as modeled field ignored", func(t *testing.T) {
+		byteCred, err := (&Credential{ID: "http://example.edu/credentials/1872",
+			CustomFields: CustomFields{"id": "other", "a": "b"}}).MarshalJSON()
+		require.NoError(t, err)
+		require.True(t, strings.HasPrefix(string(byteCred), `{"id":"http://example.edu/credentials/1872",`))
+		require.True(t, strings.HasSuffix(string(byteCred), `,"a":"b"}`))
+		require.NotContains(t, string(byteCred), "other")
+
+		byteCred, err = (&rawCredential{CustomFields: CustomFields{"a": "b"}}).MarshalJSON()
+		require.NoError(t, err)
+		require.Equal(t, `{"a":"b"}`, string(byteCred))
+	})
+
+	t.Run("test custom fields in JWT claims", func(t *testing.T) {
+		claims, err := vc.JWTClaims(false)
+		require.NoError(t, err)
+
+		jwtData, err := json.Marshal(claims)
+		require.NoError(t, err)
+		require.Contains(t, string(jwtData), `"referenceNumber":83294847`)
+	})
+
+	t.Run("test no custom fields", func(t *testing.T) {
+		cred, err := NewCredential([]byte(validCredential))
+		require.NoError(t, err)
+		require.Nil(t, cred.CustomFields)
+
+		_, err = decodeCustomFields([]byte("["), modeledCredentialFields)
+		require.Error(t, err)
+	})
+}
+
+func TestCanonicalMarshal(t *testing.T) {
+	vc, err := NewCredential([]byte(validCredential), WithCanonicalMarshal())
+	require.NoError(t, err)
+
+	vc.Subject.(map[string]interface{})["note"] = "<b>&</b>"
+
+	byteCred, err := vc.MarshalJSON()
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(string(byteCred), `{"@context":[`))
+	require.Contains(t, string(byteCred), `"note":"<b>&</b>"`)
+	require.NotContains(t, string(byteCred), "\n")
+
+	// keys are sorted at every level
+	require.True(t, strings.Index(string(byteCred), `"credentialStatus"`) <
+		strings.Index(string(byteCred), `"credentialSubject"`))
+	require.True(t, strings.Index(string(byteCred), `"issuanceDate"`) < strings.Index(string(byteCred), `"issuer"`))
+	require.True(t, strings.Index(string(byteCred), `"degree"`) < strings.Index(string(byteCred), `"id":"did:example`))
+
+	// serialization is stable and doesn't depend on the option for an equal credential
+	canonical, err := vc.MarshalCanonicalJSON()
+	require.NoError(t, err)
+	require.Equal(t, byteCred, canonical)
+
+	cred2, err := NewCredential(byteCred)
+	require.NoError(t, err)
+
+	canonical, err = cred2.MarshalCanonicalJSON()
+	require.NoError(t, err)
+	require.Equal(t, byteCred, canonical)
+
+	// numbers are kept as is
+	canonical, err = canonicalJSON([]byte(`{"b": 1.50, "a": 12345678901234567890}`))
+	require.NoError(t, err)
+	require.Equal(t, `{"a":12345678901234567890,"b":1.50}`, string(canonical))
+
+	_, err = canonicalJSON([]byte("{"))
+	require.Error(t, err)
+}
+
 func TestWithHttpClient(t *testing.T) {
 	client := &http.Client{}
 	credentialOpt := WithSchemaDownloadClient(client)
@@ -572,7 +698,16 @@ func TestWithHttpClient(t *testing.T) {
 
 	opts := &credentialOpts{}
 	credentialOpt(opts)
-	require.NotNil(t, opts.schemaDownloadClient)
+	require.IsType(t, &CachingSchemaRegistry{}, opts.schemaRegistry)
+	require.Equal(t, NewHTTPSchemaRegistry(client), opts.schemaRegistry.(*CachingSchemaRegistry).registry)
+}
+
+func TestWithSchemaRegistry(t *testing.T) {
+	registry := NewEmbeddedSchemaRegistry(nil)
+
+	opts := &credentialOpts{}
+	WithSchemaRegistry(registry)(opts)
+	require.Equal(t, registry, opts.schemaRegistry)
 }
 
 func TestWithDisabledExternalSchemaCheck(t *testing.T) {
@@ -725,7 +860,7 @@ func TestDownloadCustomSchema(t *testing.T) {
 func TestDefaultCredentialOpts(t *testing.T) {
 	opts := defaultCredentialOpts()
 	require.NotNil(t, opts)
-	require.NotNil(t, opts.schemaDownloadClient)
+	require.Equal(t, defaultSchemaRegistry, opts.schemaRegistry)
 	require.False(t, opts.disabledCustomSchema)
 	require.NotNil(t, opts.template)
 	require.NotEmpty(t, opts.decoders)
@@ -0,0 +1,67 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package verifiable
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// CustomFields are the top-level fields of the Verifiable Credential or Presentation not modeled by Credential
+// or Presentation, they are preserved to re-emit them when the credential or presentation is marshalled
+type CustomFields map[string]interface{}
+
+// marshalWithCustomFields marshals the modeled fields with the custom fields appended, the custom fields named
+// as a modeled field are ignored
+func marshalWithCustomFields(modeled interface{}, custom CustomFields, modeledFields []string) ([]byte, error) {
+	data, err := json.Marshal(modeled)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make(map[string]interface{}, len(custom))
+
+	for k, v := range custom {
+		if !containsString(modeledFields, k) {
+			fields[k] = v
+		}
+	}
+
+	if len(fields) == 0 {
+		return data, nil
+	}
+
+	customData, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	if bytes.Equal(data, []byte("{}")) {
+		return customData, nil
+	}
+
+	// append the custom fields object members to the modeled fields object
+	return append(append(data[:len(data)-1], ','), customData[1:]...), nil
+}
+
+// decodeCustomFields returns the top-level fields of the JSON object which aren't modeled fields
+func decodeCustomFields(data []byte, modeledFields []string) (CustomFields, error) {
+	fields := make(map[string]interface{})
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("JSON unmarshalling of custom fields failed: %w", err)
+	}
+
+	for _, k := range modeledFields {
+		delete(fields, k)
+	}
+
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	return fields, nil
+}
@@ -0,0 +1,236 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package verifiable
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/piprate/json-gold/ld"
+)
+
+// undefinedTermConstraint is the constraint of the violations of the JSON-LD validation
+const undefinedTermConstraint = "jsonld_undefined_term"
+
+// defaultJSONLDLoader is shared by the credentials validated with the default loader, so the remote contexts are
+// downloaded once
+//nolint:gochecknoglobals
+var defaultJSONLDLoader = CachingJSONLDLoader()
+
+// WithJSONLDValidation option enables the JSON-LD processing of the Verifiable Credential: the credential is
+// expanded against its @context using the loader of the remote contexts, the fields whose terms are not defined
+// by the contexts are reported by the ValidationError. The default loader is a shared CachingJSONLDLoader.
+//
+// The JSON-LD processor doesn't support the @protected keyword of JSON-LD 1.1, so it is dropped from the
+// contexts: the terms protected by a context and redefined by a following one aren't reported.
+func WithJSONLDValidation(loader ld.DocumentLoader) CredentialOpt {
+	return func(opts *credentialOpts) {
+		if loader == nil {
+			loader = defaultJSONLDLoader
+		}
+
+		opts.jsonldLoader = loader
+	}
+}
+
+// JSONLDLoader is the JSON-LD document loader caching the remote contexts once downloaded, it is safe for
+// concurrent loads
+type JSONLDLoader struct {
+	mutex  sync.Mutex
+	loader *ld.CachingDocumentLoader
+}
+
+// CachingJSONLDLoader returns the JSON-LD document loader caching the remote contexts, it is preloaded with the
+// W3C Verifiable Credentials context so the credentials are processed offline. Other contexts are cached with
+// AddDocument.
+func CachingJSONLDLoader() *JSONLDLoader {
+	loader := ld.NewCachingDocumentLoader(ld.NewDefaultDocumentLoader(nil))
+
+	var vcContextDoc interface{}
+
+	// the embedded context is valid JSON
+	_ = json.Unmarshal([]byte(credentialsV1Context), &vcContextDoc) //nolint:errcheck
+
+	loader.AddDocument(vcContext, vcContextDoc)
+
+	return &JSONLDLoader{loader: loader}
+}
+
+// LoadDocument returns the cached document of the URL, the document is downloaded and cached if missing
+func (l *JSONLDLoader) LoadDocument(u string) (*ld.RemoteDocument, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return l.loader.LoadDocument(u)
+}
+
+// AddDocument caches the document of the URL
+func (l *JSONLDLoader) AddDocument(u string, doc interface{}) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.loader.AddDocument(u, doc)
+}
+
+// validateJSONLD expands the credential and compacts it back against its @context, the fields lost on the way
+// are the terms the contexts don't define
+func validateJSONLD(data []byte, loader ld.DocumentLoader) error {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("JSON unmarshalling of verifiable credential failed: %w", err)
+	}
+
+	proc := ld.NewJsonLdProcessor()
+	options := jsonldOptions(loader)
+
+	// the contexts embedded in the credential don't go through the loader
+	doc = withoutProtected(doc).(map[string]interface{})
+
+	expanded, err := proc.Expand(doc, options)
+	if err != nil {
+		return fmt.Errorf("JSON-LD expansion of verifiable credential failed: %w", err)
+	}
+
+	compacted, err := proc.Compact(expanded, map[string]interface{}{"@context": doc["@context"]}, options)
+	if err != nil {
+		return fmt.Errorf("JSON-LD compaction of verifiable credential failed: %w", err)
+	}
+
+	var undefined []string
+
+	collectUndefinedTerms("", doc, compacted, &undefined)
+
+	if len(undefined) == 0 {
+		return nil
+	}
+
+	sort.Strings(undefined)
+
+	violations := make([]SchemaViolation, len(undefined))
+	for i, field := range undefined {
+		violations[i] = SchemaViolation{
+			Field:       field,
+			Constraint:  undefinedTermConstraint,
+			Description: "term is not defined by the JSON-LD context",
+		}
+	}
+
+	return &ValidationError{Violations: violations}
+}
+
+// jsonldOptions returns the options of the JSON-LD 1.1 processing of the credentials, the remote contexts are
+// loaded by the loader without their @protected keyword
+func jsonldOptions(loader ld.DocumentLoader) *ld.JsonLdOptions {
+	options := ld.NewJsonLdOptions("")
+	options.ProcessingMode = ld.JsonLd_1_1
+	options.DocumentLoader = &unprotectedContextLoader{next: loader}
+
+	return options
+}
+
+// collectUndefinedTerms adds the paths of the fields of the original document missing in the compacted one
+func collectUndefinedTerms(path string, original, compacted interface{}, undefined *[]string) {
+	switch o := original.(type) {
+	case map[string]interface{}:
+		c, ok := singleValue(compacted).(map[string]interface{})
+		if !ok {
+			return
+		}
+
+		for k, v := range o {
+			if strings.HasPrefix(k, "@") {
+				continue
+			}
+
+			field := k
+			if path != "" {
+				field = path + "." + k
+			}
+
+			cv, ok := c[k]
+			if !ok {
+				*undefined = append(*undefined, field)
+				continue
+			}
+
+			collectUndefinedTerms(field, v, cv, undefined)
+		}
+
+	case []interface{}:
+		c, ok := compacted.([]interface{})
+		if !ok {
+			c = []interface{}{compacted}
+		}
+
+		if len(c) != len(o) {
+			return
+		}
+
+		for i := range o {
+			collectUndefinedTerms(path, o[i], c[i], undefined)
+		}
+	}
+}
+
+// singleValue unwraps the value of the single element array as the compaction does
+func singleValue(v interface{}) interface{} {
+	if values, ok := v.([]interface{}); ok && len(values) == 1 {
+		return values[0]
+	}
+
+	return v
+}
+
+// unprotectedContextLoader drops the @protected keyword of JSON-LD 1.1 from the loaded contexts, the JSON-LD
+// processor doesn't support it and fails on the W3C contexts otherwise. The protection of the terms against their
+// redefinition is not checked.
+type unprotectedContextLoader struct {
+	next ld.DocumentLoader
+}
+
+func (l *unprotectedContextLoader) LoadDocument(u string) (*ld.RemoteDocument, error) {
+	doc, err := l.next.LoadDocument(u)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ld.RemoteDocument{
+		DocumentURL: doc.DocumentURL,
+		Document:    withoutProtected(doc.Document),
+		ContextURL:  doc.ContextURL,
+	}, nil
+}
+
+// withoutProtected returns a copy of the JSON document without the @protected members
+func withoutProtected(doc interface{}) interface{} {
+	switch d := doc.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(d))
+
+		for k, v := range d {
+			if k != "@protected" {
+				m[k] = withoutProtected(v)
+			}
+		}
+
+		return m
+
+	case []interface{}:
+		a := make([]interface{}, len(d))
+
+		for i, v := range d {
+			a[i] = withoutProtected(v)
+		}
+
+		return a
+	}
+
+	return doc
+}
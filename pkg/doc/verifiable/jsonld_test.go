@@ -0,0 +1,158 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package verifiable
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/piprate/json-gold/ld"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	examplesContextURL = "https://www.w3.org/2018/credentials/examples/v1"
+
+	examplesContext = `{
+  "@context": {
+    "@version": 1.1,
+    "@protected": true,
+    "ex": "https://example.org/examples#",
+    "schema": "http://schema.org/",
+    "UniversityDegreeCredential": "ex:UniversityDegreeCredential",
+    "BachelorDegree": "ex:BachelorDegree",
+    "degree": "ex:degree",
+    "university": "ex:university",
+    "name": "schema:name"
+  }
+}`
+
+	jsonldCredential = `{
+  "@context": [
+    "https://www.w3.org/2018/credentials/v1",
+    "https://www.w3.org/2018/credentials/examples/v1"
+  ],
+  "id": "http://example.edu/credentials/1872",
+  "type": ["VerifiableCredential", "UniversityDegreeCredential"],
+  "credentialSubject": {
+    "id": "did:example:ebfeb1f712ebc6f1c276e12ec21",
+    "degree": {"type": "BachelorDegree", "university": "MIT"},
+    "name": "Jayden Doe"
+  },
+  "issuer": "did:example:76e12ec712ebc6f1c221ebfeb1f",
+  "issuanceDate": "2010-01-01T19:23:24Z",
+  "proof": {
+    "type": "Ed25519Signature2018",
+    "created": "2018-06-18T21:19:10Z",
+    "proofPurpose": "assertionMethod",
+    "verificationMethod": "https://example.com/jdoe/keys/1",
+    "jws": "eyJhbGciOiJFZERTQSIsImI2NCI6ZmFsc2UsImNyaXQiOlsiYjY0Il19..c2lnbmF0dXJl"
+  }
+}`
+)
+
+type failingLoader struct{}
+
+func (failingLoader) LoadDocument(u string) (*ld.RemoteDocument, error) {
+	return nil, errors.New("offline")
+}
+
+func examplesLoader(t *testing.T) *JSONLDLoader {
+	loader := CachingJSONLDLoader()
+
+	var doc interface{}
+	require.NoError(t, json.Unmarshal([]byte(examplesContext), &doc))
+	loader.AddDocument(examplesContextURL, doc)
+
+	return loader
+}
+
+func TestWithJSONLDValidation(t *testing.T) {
+	t.Run("test valid credential", func(t *testing.T) {
+		vc, err := NewCredential([]byte(jsonldCredential), WithJSONLDValidation(examplesLoader(t)))
+		require.NoError(t, err)
+		require.Equal(t, "http://example.edu/credentials/1872", vc.ID)
+	})
+
+	t.Run("test undefined terms", func(t *testing.T) {
+		vcData := strings.Replace(jsonldCredential, `"university": "MIT"`,
+			`"university": "MIT", "degreeLevel": "BSc"`, 1)
+		vcData = strings.Replace(vcData, `"jws"`, `"proofNote": "signed", "jws"`, 1)
+		vcData = strings.Replace(vcData, `"issuer"`, `"issuerNote": "test", "issuer"`, 1)
+
+		_, err := NewCredential([]byte(vcData), WithJSONLDValidation(examplesLoader(t)))
+		require.Error(t, err)
+
+		validationErr := &ValidationError{}
+		require.True(t, errors.As(err, &validationErr))
+		require.Len(t, validationErr.Violations, 3)
+		require.Equal(t, "credentialSubject.degree.degreeLevel", validationErr.Violations[0].Field)
+		require.Equal(t, "issuerNote", validationErr.Violations[1].Field)
+		require.Equal(t, "proof.proofNote", validationErr.Violations[2].Field)
+		require.Equal(t, undefinedTermConstraint, validationErr.Violations[0].Constraint)
+	})
+
+	t.Run("test undefined terms accepted without JSON-LD validation", func(t *testing.T) {
+		vcData := strings.Replace(jsonldCredential, `"university": "MIT"`,
+			`"university": "MIT", "degreeLevel": "BSc"`, 1)
+
+		_, err := NewCredential([]byte(vcData))
+		require.NoError(t, err)
+	})
+
+	t.Run("test embedded context", func(t *testing.T) {
+		vcData := strings.Replace(jsonldCredential, `"https://www.w3.org/2018/credentials/examples/v1"`,
+			`{"@protected": true, "ex": "https://example.org/examples#", "UniversityDegreeCredential": "ex:Degree",
+			"degree": "ex:degree", "university": "ex:university", "name": "ex:name"}`, 1)
+
+		_, err := NewCredential([]byte(vcData), WithJSONLDValidation(CachingJSONLDLoader()))
+		require.NoError(t, err)
+	})
+
+	t.Run("test context loading error", func(t *testing.T) {
+		_, err := NewCredential([]byte(jsonldCredential), WithJSONLDValidation(failingLoader{}))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "JSON-LD expansion of verifiable credential failed")
+	})
+
+	t.Run("test default loader", func(t *testing.T) {
+		opts := &credentialOpts{}
+		WithJSONLDValidation(nil)(opts)
+		require.NotNil(t, opts.jsonldLoader)
+
+		doc, err := opts.jsonldLoader.LoadDocument(vcContext)
+		require.NoError(t, err)
+		require.NotNil(t, doc.Document)
+
+		// the default loader is shared
+		other := &credentialOpts{}
+		WithJSONLDValidation(nil)(other)
+		require.Equal(t, opts.jsonldLoader, other.jsonldLoader)
+	})
+
+	t.Run("test concurrent loads", func(t *testing.T) {
+		loader := examplesLoader(t)
+
+		var wg sync.WaitGroup
+
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				_, err := NewCredential([]byte(jsonldCredential), WithJSONLDValidation(loader))
+				require.NoError(t, err)
+			}()
+		}
+
+		wg.Wait()
+	})
+}
@@ -0,0 +1,238 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package verifiable
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/common/clock"
+)
+
+// LintRule identifies the check of the linter raising a warning
+type LintRule string
+
+const (
+	// LintMissingField warns about a recommended field not set
+	LintMissingField LintRule = "missing-field"
+	// LintDeprecatedContext warns about a JSON-LD context replaced by a newer one
+	LintDeprecatedContext LintRule = "deprecated-context"
+	// LintSuspiciousDates warns about dates not parsable, in the future, expired or spanning a long period
+	LintSuspiciousDates LintRule = "suspicious-dates"
+	// LintOversizedSubject warns about a credentialSubject larger than the maximum size
+	LintOversizedSubject LintRule = "oversized-subject"
+)
+
+const (
+	defaultMaxSubjectSize = 16 * 1024
+	defaultMaxValidity    = 10 * 365 * 24 * time.Hour
+)
+
+// deprecatedContexts maps the deprecated JSON-LD contexts to the contexts replacing them
+var deprecatedContexts = map[string]string{ //nolint:gochecknoglobals
+	"https://w3id.org/credentials/v1": vcContext,
+	"https://w3id.org/security/v1":    "https://w3id.org/security/v2",
+}
+
+// LintWarning is a non-fatal issue of a Verifiable Credential or Presentation
+type LintWarning struct {
+	Rule LintRule
+	// Field is the path of the field, e.g. credentialSubject.id
+	Field   string
+	Message string
+}
+
+type lintOpts struct {
+	clock          clock.Clock
+	maxSubjectSize int
+	maxValidity    time.Duration
+}
+
+// LintOpt is the linter option
+type LintOpt func(opts *lintOpts)
+
+// WithLintClock sets the clock the dates are compared to, the system clock by default
+func WithLintClock(c clock.Clock) LintOpt {
+	return func(opts *lintOpts) {
+		opts.clock = c
+	}
+}
+
+// WithMaxSubjectSize sets the maximum size in bytes of the JSON credentialSubject, 16 KiB by default
+func WithMaxSubjectSize(size int) LintOpt {
+	return func(opts *lintOpts) {
+		opts.maxSubjectSize = size
+	}
+}
+
+// WithMaxValidity sets the longest validity period not reported as suspicious, 10 years by default
+func WithMaxValidity(validity time.Duration) LintOpt {
+	return func(opts *lintOpts) {
+		opts.maxValidity = validity
+	}
+}
+
+// Lint checks the Verifiable Credential against the good practices before its issuance and returns the warnings,
+// an error is only returned if the credential is not a JSON object. The credential is not validated, NewCredential
+// rejects the invalid credentials.
+func Lint(vcBytes []byte, opts ...LintOpt) ([]LintWarning, error) {
+	var vc map[string]interface{}
+	if err := json.Unmarshal(vcBytes, &vc); err != nil {
+		return nil, fmt.Errorf("JSON unmarshalling of verifiable credential failed: %w", err)
+	}
+
+	return lintCredential(vc, "", newLintOpts(opts)), nil
+}
+
+// LintPresentation checks the Verifiable Presentation and its embedded credentials in JSON form against the good
+// practices and returns the warnings, the fields of the credentials are prefixed by verifiableCredential.<index>.
+func LintPresentation(vpBytes []byte, opts ...LintOpt) ([]LintWarning, error) {
+	var vp map[string]interface{}
+	if err := json.Unmarshal(vpBytes, &vp); err != nil {
+		return nil, fmt.Errorf("JSON unmarshalling of verifiable presentation failed: %w", err)
+	}
+
+	lOpts := newLintOpts(opts)
+
+	var warnings []LintWarning
+	warnings = append(warnings, lintMissingFields(vp, "", "id", "holder", "proof")...)
+	warnings = append(warnings, lintContexts(vp, "")...)
+
+	credentials, _ := vp["verifiableCredential"].([]interface{})
+	for i, c := range credentials {
+		// the credentials in JWT form are left to the JWT tooling
+		if vc, ok := c.(map[string]interface{}); ok {
+			warnings = append(warnings, lintCredential(vc, fmt.Sprintf("verifiableCredential.%d.", i), lOpts)...)
+		}
+	}
+
+	return warnings, nil
+}
+
+func newLintOpts(opts []LintOpt) *lintOpts {
+	lOpts := &lintOpts{
+		clock:          clock.System(),
+		maxSubjectSize: defaultMaxSubjectSize,
+		maxValidity:    defaultMaxValidity,
+	}
+
+	for _, opt := range opts {
+		opt(lOpts)
+	}
+
+	return lOpts
+}
+
+func lintCredential(vc map[string]interface{}, prefix string, opts *lintOpts) []LintWarning {
+	var warnings []LintWarning
+	warnings = append(warnings, lintMissingFields(vc, prefix, "id", "expirationDate")...)
+
+	if subject, ok := vc["credentialSubject"].(map[string]interface{}); ok {
+		warnings = append(warnings, lintMissingFields(subject, prefix+"credentialSubject.", "id")...)
+	}
+
+	warnings = append(warnings, lintContexts(vc, prefix)...)
+	warnings = append(warnings, lintDates(vc, prefix, opts)...)
+
+	if subject, ok := vc["credentialSubject"]; ok {
+		subjectBytes, err := json.Marshal(subject)
+		if err == nil && len(subjectBytes) > opts.maxSubjectSize {
+			warnings = append(warnings, LintWarning{Rule: LintOversizedSubject, Field: prefix + "credentialSubject",
+				Message: fmt.Sprintf("credentialSubject of %d bytes exceeds %d bytes", len(subjectBytes),
+					opts.maxSubjectSize)})
+		}
+	}
+
+	return warnings
+}
+
+func lintMissingFields(doc map[string]interface{}, prefix string, fields ...string) []LintWarning {
+	var warnings []LintWarning
+
+	for _, field := range fields {
+		if _, ok := doc[field]; !ok {
+			warnings = append(warnings, LintWarning{Rule: LintMissingField, Field: prefix + field,
+				Message: fmt.Sprintf("recommended field %s is not set", field)})
+		}
+	}
+
+	return warnings
+}
+
+func lintContexts(doc map[string]interface{}, prefix string) []LintWarning {
+	var warnings []LintWarning
+
+	contexts, _ := doc["@context"].([]interface{})
+	for i, c := range contexts {
+		context, ok := c.(string)
+		if !ok {
+			continue
+		}
+
+		if replacement, ok := deprecatedContexts[context]; ok {
+			warnings = append(warnings, LintWarning{Rule: LintDeprecatedContext,
+				Field:   fmt.Sprintf("%s@context.%d", prefix, i),
+				Message: fmt.Sprintf("context %s is deprecated, use %s", context, replacement)})
+		}
+	}
+
+	return warnings
+}
+
+func lintDates(vc map[string]interface{}, prefix string, opts *lintOpts) []LintWarning {
+	var warnings []LintWarning
+
+	warning := func(field, format string, args ...interface{}) {
+		warnings = append(warnings, LintWarning{Rule: LintSuspiciousDates, Field: prefix + field,
+			Message: fmt.Sprintf(format, args...)})
+	}
+
+	parse := func(field string) *time.Time {
+		value, ok := vc[field]
+		if !ok {
+			return nil
+		}
+
+		s, _ := value.(string)
+
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			warning(field, "%s %v is not a RFC3339 date", field, value)
+			return nil
+		}
+
+		return &t
+	}
+
+	now := opts.clock.Now()
+	issued, expired := parse("issuanceDate"), parse("expirationDate")
+
+	if issued != nil && issued.After(now) {
+		warning("issuanceDate", "issuanceDate %s is in the future", issued.Format(time.RFC3339))
+	}
+
+	if expired == nil {
+		return warnings
+	}
+
+	if !expired.After(now) {
+		warning("expirationDate", "expirationDate %s has passed", expired.Format(time.RFC3339))
+	}
+
+	if issued == nil {
+		return warnings
+	}
+
+	switch validity := expired.Sub(*issued); {
+	case validity <= 0:
+		warning("expirationDate", "expirationDate is not after issuanceDate")
+	case validity > opts.maxValidity:
+		warning("expirationDate", "validity period of %s exceeds %s", validity, opts.maxValidity)
+	}
+
+	return warnings
+}
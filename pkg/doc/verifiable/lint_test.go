@@ -0,0 +1,103 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package verifiable
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	mockclock "github.com/hyperledger/aries-framework-go/pkg/internal/mock/clock"
+)
+
+func TestLint(t *testing.T) {
+	now := &mockclock.Clock{Time: time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)}
+
+	t.Run("test credential following the good practices", func(t *testing.T) {
+		warnings, err := Lint([]byte(`{
+			"@context": ["https://www.w3.org/2018/credentials/v1"],
+			"id": "http://example.edu/credentials/1872",
+			"type": "VerifiableCredential",
+			"credentialSubject": {"id": "did:example:ebfeb1f712ebc6f1c276e12ec21"},
+			"issuer": "did:example:76e12ec712ebc6f1c221ebfeb1f",
+			"issuanceDate": "2019-01-01T00:00:00Z",
+			"expirationDate": "2021-01-01T00:00:00Z"
+		}`), WithLintClock(now))
+		require.NoError(t, err)
+		require.Empty(t, warnings)
+	})
+
+	t.Run("test warnings", func(t *testing.T) {
+		warnings, err := Lint([]byte(`{
+			"@context": ["https://www.w3.org/2018/credentials/v1", "https://w3id.org/security/v1"],
+			"type": "VerifiableCredential",
+			"credentialSubject": {"name": "`+strings.Repeat("a", 100)+`"},
+			"issuer": "did:example:76e12ec712ebc6f1c221ebfeb1f",
+			"issuanceDate": "2021-01-01T00:00:00Z",
+			"expirationDate": "2019-01-01T00:00:00Z"
+		}`), WithLintClock(now), WithMaxSubjectSize(64))
+		require.NoError(t, err)
+		require.Equal(t, []LintWarning{
+			{Rule: LintMissingField, Field: "id", Message: "recommended field id is not set"},
+			{Rule: LintMissingField, Field: "credentialSubject.id", Message: "recommended field id is not set"},
+			{Rule: LintDeprecatedContext, Field: "@context.1",
+				Message: "context https://w3id.org/security/v1 is deprecated, use https://w3id.org/security/v2"},
+			{Rule: LintSuspiciousDates, Field: "issuanceDate",
+				Message: "issuanceDate 2021-01-01T00:00:00Z is in the future"},
+			{Rule: LintSuspiciousDates, Field: "expirationDate",
+				Message: "expirationDate 2019-01-01T00:00:00Z has passed"},
+			{Rule: LintSuspiciousDates, Field: "expirationDate", Message: "expirationDate is not after issuanceDate"},
+			{Rule: LintOversizedSubject, Field: "credentialSubject",
+				Message: "credentialSubject of 111 bytes exceeds 64 bytes"},
+		}, warnings)
+	})
+
+	t.Run("test suspicious dates", func(t *testing.T) {
+		warnings, err := Lint([]byte(`{"id": "urn:uuid:1", "issuanceDate": "2019-01-01T00:00:00Z",
+			"expirationDate": "2119-01-01T00:00:00Z"}`), WithLintClock(now))
+		require.NoError(t, err)
+		require.Len(t, warnings, 1)
+		require.Equal(t, LintSuspiciousDates, warnings[0].Rule)
+		require.Contains(t, warnings[0].Message, "validity period of")
+
+		warnings, err = Lint([]byte(`{"id": "urn:uuid:1", "issuanceDate": "yesterday",
+			"expirationDate": "2021-01-01T00:00:00Z"}`), WithLintClock(now), WithMaxValidity(time.Hour))
+		require.NoError(t, err)
+		require.Equal(t, []LintWarning{{Rule: LintSuspiciousDates, Field: "issuanceDate",
+			Message: "issuanceDate yesterday is not a RFC3339 date"}}, warnings)
+	})
+
+	t.Run("test invalid credential", func(t *testing.T) {
+		_, err := Lint([]byte("["))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "JSON unmarshalling of verifiable credential failed")
+	})
+}
+
+func TestLintPresentation(t *testing.T) {
+	warnings, err := LintPresentation([]byte(`{
+		"@context": ["https://w3id.org/credentials/v1"],
+		"type": "VerifiablePresentation",
+		"verifiableCredential": ["eyJhbGciOiJub25lIn0.e30.", {"id": "urn:uuid:1",
+			"credentialSubject": {"id": "did:example:ebfeb1f712ebc6f1c276e12ec21"}}]
+	}`))
+	require.NoError(t, err)
+	require.Equal(t, []LintWarning{
+		{Rule: LintMissingField, Field: "id", Message: "recommended field id is not set"},
+		{Rule: LintMissingField, Field: "holder", Message: "recommended field holder is not set"},
+		{Rule: LintMissingField, Field: "proof", Message: "recommended field proof is not set"},
+		{Rule: LintDeprecatedContext, Field: "@context.0",
+			Message: "context https://w3id.org/credentials/v1 is deprecated, use " + vcContext},
+		{Rule: LintMissingField, Field: "verifiableCredential.1.expirationDate",
+			Message: "recommended field expirationDate is not set"},
+	}, warnings)
+
+	_, err = LintPresentation([]byte("["))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "JSON unmarshalling of verifiable presentation failed")
+}
@@ -0,0 +1,178 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package verifiable
+
+import (
+	"fmt"
+	"time"
+)
+
+// PolicyDecision is the outcome of evaluating a verifier Policy.
+type PolicyDecision int
+
+const (
+	// PolicyUndecided means the policy could not conclude and the presentation needs a manual decision.
+	PolicyUndecided PolicyDecision = iota
+
+	// PolicyAccept means all the credentials satisfy the policy.
+	PolicyAccept
+
+	// PolicyReject means at least one credential violates the policy.
+	PolicyReject
+)
+
+// RevocationChecker checks if the credential referenced by the status was revoked.
+type RevocationChecker func(status *CredentialStatus) (bool, error)
+
+// Policy defines verifier-side rules applied to the credentials of a received presentation.
+// Zero values of the fields disable the corresponding rule.
+type Policy struct {
+	// AcceptedIssuers is a list of trusted issuer IDs. Empty list accepts any issuer.
+	AcceptedIssuers []string
+
+	// RequiredTypes lists the types every credential must have.
+	RequiredTypes []string
+
+	// MaxCredentialAge is a maximum allowed time elapsed since the credential issuance.
+	MaxCredentialAge time.Duration
+
+	// RevocationRequired demands every credential to have a status which was checked for revocation.
+	RevocationRequired bool
+
+	// RevocationChecker is used to check credential status. If not defined and revocation
+	// is required, the policy cannot make a conclusive decision.
+	RevocationChecker RevocationChecker
+}
+
+// PolicyResult holds the decision made by the Policy along with the reason of rejection or indecision.
+type PolicyResult struct {
+	Decision PolicyDecision
+	Reason   string
+}
+
+// Evaluate applies the policy to the credentials of the presentation. Rejection takes precedence over
+// indecision, so a single violating credential rejects the whole presentation.
+func (p *Policy) Evaluate(credentials ...*Credential) *PolicyResult {
+	if len(credentials) == 0 {
+		return &PolicyResult{Decision: PolicyReject, Reason: "no credentials presented"}
+	}
+
+	var undecided *PolicyResult
+
+	for _, vc := range credentials {
+		result := p.evaluateCredential(vc)
+
+		switch result.Decision {
+		case PolicyReject:
+			return result
+		case PolicyUndecided:
+			if undecided == nil {
+				undecided = result
+			}
+		}
+	}
+
+	if undecided != nil {
+		return undecided
+	}
+
+	return &PolicyResult{Decision: PolicyAccept}
+}
+
+func (p *Policy) evaluateCredential(vc *Credential) *PolicyResult {
+	if reason := p.checkIssuer(vc); reason != "" {
+		return &PolicyResult{Decision: PolicyReject, Reason: reason}
+	}
+
+	if reason := p.checkTypes(vc); reason != "" {
+		return &PolicyResult{Decision: PolicyReject, Reason: reason}
+	}
+
+	if reason := p.checkAge(vc); reason != "" {
+		return &PolicyResult{Decision: PolicyReject, Reason: reason}
+	}
+
+	return p.checkRevocation(vc)
+}
+
+func (p *Policy) checkIssuer(vc *Credential) string {
+	if len(p.AcceptedIssuers) == 0 {
+		return ""
+	}
+
+	for _, issuer := range p.AcceptedIssuers {
+		if issuer == vc.Issuer.ID {
+			return ""
+		}
+	}
+
+	return fmt.Sprintf("issuer %s of credential %s is not accepted", vc.Issuer.ID, vc.ID)
+}
+
+func (p *Policy) checkTypes(vc *Credential) string {
+	types := vc.Types()
+
+	for _, required := range p.RequiredTypes {
+		if !containsString(types, required) {
+			return fmt.Sprintf("credential %s is not of required type %s", vc.ID, required)
+		}
+	}
+
+	return ""
+}
+
+func (p *Policy) checkAge(vc *Credential) string {
+	if p.MaxCredentialAge == 0 {
+		return ""
+	}
+
+	if vc.Issued == nil {
+		return fmt.Sprintf("credential %s has no issuance date", vc.ID)
+	}
+
+	if time.Since(*vc.Issued) > p.MaxCredentialAge {
+		return fmt.Sprintf("credential %s is older than %s", vc.ID, p.MaxCredentialAge)
+	}
+
+	return ""
+}
+
+func (p *Policy) checkRevocation(vc *Credential) *PolicyResult {
+	if !p.RevocationRequired {
+		return &PolicyResult{Decision: PolicyAccept}
+	}
+
+	if vc.Status == nil {
+		return &PolicyResult{Decision: PolicyReject, Reason: fmt.Sprintf("credential %s has no status", vc.ID)}
+	}
+
+	if p.RevocationChecker == nil {
+		return &PolicyResult{Decision: PolicyUndecided,
+			Reason: fmt.Sprintf("no revocation checker to check status of credential %s", vc.ID)}
+	}
+
+	revoked, err := p.RevocationChecker(vc.Status)
+	if err != nil {
+		return &PolicyResult{Decision: PolicyUndecided,
+			Reason: fmt.Sprintf("revocation check of credential %s failed: %s", vc.ID, err)}
+	}
+
+	if revoked {
+		return &PolicyResult{Decision: PolicyReject, Reason: fmt.Sprintf("credential %s is revoked", vc.ID)}
+	}
+
+	return &PolicyResult{Decision: PolicyAccept}
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,119 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package verifiable
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicy_Evaluate(t *testing.T) {
+	issued := time.Now().Add(-time.Hour)
+	newVC := func() *Credential {
+		return &Credential{
+			ID:     "http://example.edu/credentials/1872",
+			Type:   []string{"VerifiableCredential", "UniversityDegreeCredential"},
+			Issuer: Issuer{ID: "did:example:76e12ec712ebc6f1c221ebfeb1f"},
+			Issued: &issued,
+			Status: &CredentialStatus{ID: "https://example.edu/status/24", Type: "CredentialStatusList2017"},
+		}
+	}
+
+	t.Run("empty policy accepts any credential", func(t *testing.T) {
+		p := &Policy{}
+		require.Equal(t, PolicyAccept, p.Evaluate(newVC()).Decision)
+	})
+
+	t.Run("no credentials presented", func(t *testing.T) {
+		p := &Policy{}
+		result := p.Evaluate()
+		require.Equal(t, PolicyReject, result.Decision)
+		require.Equal(t, "no credentials presented", result.Reason)
+	})
+
+	t.Run("accepted issuers", func(t *testing.T) {
+		p := &Policy{AcceptedIssuers: []string{"did:example:76e12ec712ebc6f1c221ebfeb1f"}}
+		require.Equal(t, PolicyAccept, p.Evaluate(newVC()).Decision)
+
+		p.AcceptedIssuers = []string{"did:example:other"}
+		result := p.Evaluate(newVC())
+		require.Equal(t, PolicyReject, result.Decision)
+		require.Contains(t, result.Reason, "is not accepted")
+	})
+
+	t.Run("required types", func(t *testing.T) {
+		p := &Policy{RequiredTypes: []string{"UniversityDegreeCredential"}}
+		require.Equal(t, PolicyAccept, p.Evaluate(newVC()).Decision)
+
+		p.RequiredTypes = []string{"DriverLicenseCredential"}
+		result := p.Evaluate(newVC())
+		require.Equal(t, PolicyReject, result.Decision)
+		require.Contains(t, result.Reason, "is not of required type DriverLicenseCredential")
+	})
+
+	t.Run("max credential age", func(t *testing.T) {
+		p := &Policy{MaxCredentialAge: 24 * time.Hour}
+		require.Equal(t, PolicyAccept, p.Evaluate(newVC()).Decision)
+
+		p.MaxCredentialAge = time.Minute
+		result := p.Evaluate(newVC())
+		require.Equal(t, PolicyReject, result.Decision)
+		require.Contains(t, result.Reason, "is older than")
+
+		vc := newVC()
+		vc.Issued = nil
+		result = p.Evaluate(vc)
+		require.Equal(t, PolicyReject, result.Decision)
+		require.Contains(t, result.Reason, "has no issuance date")
+	})
+
+	t.Run("revocation required", func(t *testing.T) {
+		p := &Policy{RevocationRequired: true}
+
+		// no checker defined - policy is not conclusive
+		result := p.Evaluate(newVC())
+		require.Equal(t, PolicyUndecided, result.Decision)
+		require.Contains(t, result.Reason, "no revocation checker")
+
+		vc := newVC()
+		vc.Status = nil
+		result = p.Evaluate(vc)
+		require.Equal(t, PolicyReject, result.Decision)
+		require.Contains(t, result.Reason, "has no status")
+
+		p.RevocationChecker = func(status *CredentialStatus) (bool, error) {
+			return false, nil
+		}
+		require.Equal(t, PolicyAccept, p.Evaluate(newVC()).Decision)
+
+		p.RevocationChecker = func(status *CredentialStatus) (bool, error) {
+			return true, nil
+		}
+		result = p.Evaluate(newVC())
+		require.Equal(t, PolicyReject, result.Decision)
+		require.Contains(t, result.Reason, "is revoked")
+
+		p.RevocationChecker = func(status *CredentialStatus) (bool, error) {
+			return false, errors.New("status list is not available")
+		}
+		result = p.Evaluate(newVC())
+		require.Equal(t, PolicyUndecided, result.Decision)
+		require.Contains(t, result.Reason, "status list is not available")
+	})
+
+	t.Run("rejection takes precedence over indecision", func(t *testing.T) {
+		p := &Policy{RevocationRequired: true}
+
+		vcWithoutStatus := newVC()
+		vcWithoutStatus.Status = nil
+
+		result := p.Evaluate(newVC(), vcWithoutStatus)
+		require.Equal(t, PolicyReject, result.Decision)
+	})
+}
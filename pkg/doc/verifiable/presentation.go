@@ -0,0 +1,565 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package verifiable
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/xeipuuv/gojsonschema"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/proof"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/signer"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/verifier"
+)
+
+const (
+	vcContext            = "https://www.w3.org/2018/credentials/v1"
+	vpType               = "VerifiablePresentation"
+	presentationProofKey = "proof"
+)
+
+// defaultPresentationSchema is the JSON schema of the serialization of the Verifiable Presentation data model
+const defaultPresentationSchema = `{
+  "required": [
+    "@context",
+    "type"
+  ],
+  "properties": {
+    "@context": {
+      "type": "array",
+      "items": [
+        {
+          "type": "string",
+          "pattern": "^https://www.w3.org/2018/credentials/v1$"
+        }
+      ],
+      "uniqueItems": true,
+      "additionalItems": {
+        "oneOf": [
+          {
+            "type": "object"
+          },
+          {
+            "type": "string"
+          }
+        ]
+      }
+    },
+    "id": {
+      "type": "string",
+      "format": "uri"
+    },
+    "type": {
+      "oneOf": [
+        {
+          "type": "array",
+          "items": [
+            {
+              "type": "string",
+              "pattern": "^VerifiablePresentation$"
+            }
+          ],
+          "additionalItems": {
+            "type": "string"
+          }
+        },
+        {
+          "type": "string",
+          "pattern": "^VerifiablePresentation$"
+        }
+      ]
+    },
+    "verifiableCredential": {
+      "type": "array",
+      "items": {
+        "oneOf": [
+          {
+            "type": "object"
+          },
+          {
+            "type": "string"
+          }
+        ]
+      }
+    },
+    "holder": {
+      "type": "string",
+      "format": "uri"
+    },
+    "proof": {
+      "anyOf": [
+        {
+          "$ref": "#/definitions/proof"
+        },
+        {
+          "type": "array",
+          "items": {
+            "$ref": "#/definitions/proof"
+          }
+        }
+      ]
+    }
+  },
+  "definitions": {
+    "proof": {
+      "type": "object",
+      "required": [
+        "type"
+      ],
+      "properties": {
+        "type": {
+          "type": "string"
+        }
+      }
+    }
+  }
+}
+`
+
+//nolint:gochecknoglobals
+var defaultPresentationSchemaLoader = gojsonschema.NewStringLoader(defaultPresentationSchema)
+
+// Presentation Verifiable Presentation definition
+type Presentation struct {
+	Context []interface{}
+	ID      string
+	Type    interface{}
+	// Credentials are the embedded Verifiable Credentials in JSON (map) or JWT (string) form
+	Credentials  []interface{}
+	Holder       string
+	Proof        interface{}
+	CustomFields CustomFields
+}
+
+// rawPresentation is a basic verifiable presentation
+type rawPresentation struct {
+	Context     []interface{} `json:"@context,omitempty"`
+	ID          string        `json:"id,omitempty"`
+	Type        interface{}   `json:"type,omitempty"`
+	Credentials []interface{} `json:"verifiableCredential,omitempty"`
+	Holder      string        `json:"holder,omitempty"`
+	Proof       interface{}   `json:"proof,omitempty"`
+
+	CustomFields CustomFields `json:"-"`
+}
+
+// modeledPresentationFields are the JSON fields of rawPresentation
+//
+//nolint:gochecknoglobals
+var modeledPresentationFields = []string{"@context", "id", "type", "verifiableCredential", "holder", "proof"}
+
+// MarshalJSON marshals the raw presentation with the custom fields appended to the modeled fields,
+// the custom fields named as a modeled field are ignored
+func (raw *rawPresentation) MarshalJSON() ([]byte, error) {
+	type modeledPresentation rawPresentation
+
+	return marshalWithCustomFields((*modeledPresentation)(raw), raw.CustomFields, modeledPresentationFields)
+}
+
+// KeyResolver resolves the public keys of the presentation proofs
+type KeyResolver interface {
+	// Resolve will return public key bytes
+	Resolve(id string) ([]byte, error)
+}
+
+// PresentationRequest holds the parameters of the verifier's request the presentation proof is bound to.
+// The challenge is carried as proof nonce.
+type PresentationRequest struct {
+	Challenge string
+	Domain    string
+}
+
+// Signer signs the canonical document digest with the holder key
+type Signer interface {
+	// Sign will sign document and return signature
+	Sign(doc []byte) ([]byte, error)
+}
+
+// LinkedDataProofContext holds the holder signing settings of the presentation proof
+type LinkedDataProofContext struct {
+	SignatureType string
+	// Creator is the ID of the holder key, e.g. DID public key ID
+	Creator string
+	Signer  Signer
+	Created *time.Time
+}
+
+// presentationOpts holds options for the Verifiable Presentation decoding
+type presentationOpts struct {
+	keyResolver        KeyResolver
+	request            *PresentationRequest
+	challenges         *ChallengeStore
+	holderBinding      bool
+	subjectIsHolder    bool
+	credentialDecoding []CredentialOpt
+}
+
+// PresentationOpt is the Verifiable Presentation decoding option
+type PresentationOpt func(opts *presentationOpts)
+
+// WithPresentationProofCheck verifies the presentation proofs using the key resolver.
+func WithPresentationProofCheck(resolver KeyResolver) PresentationOpt {
+	return func(opts *presentationOpts) {
+		opts.keyResolver = resolver
+	}
+}
+
+// WithPresentationRequest checks the presentation proofs are bound to the challenge and domain of the request.
+func WithPresentationRequest(request *PresentationRequest) PresentationOpt {
+	return func(opts *presentationOpts) {
+		opts.request = request
+	}
+}
+
+// WithChallengeStore checks the challenge of the presentation request has been issued by the challenge store
+// and uses it once the presentation proofs are verified, so a replayed presentation is rejected.
+// The option requires WithPresentationProofCheck and WithPresentationRequest.
+func WithChallengeStore(store *ChallengeStore) PresentationOpt {
+	return func(opts *presentationOpts) {
+		opts.challenges = store
+	}
+}
+
+// WithHolderBinding checks the presentation proofs are created by keys of the holder DID.
+func WithHolderBinding() PresentationOpt {
+	return func(opts *presentationOpts) {
+		opts.holderBinding = true
+	}
+}
+
+// WithSubjectHolderCheck checks the subject of every embedded credential is the presentation holder.
+// The credentials are decoded using the credential options.
+func WithSubjectHolderCheck(credentialOpts ...CredentialOpt) PresentationOpt {
+	return func(opts *presentationOpts) {
+		opts.subjectIsHolder = true
+		opts.credentialDecoding = credentialOpts
+	}
+}
+
+// NewPresentation creates an instance of Verifiable Presentation by reading a JSON document from bytes.
+// The proofs, holder binding and credential subjects are checked according to the options, the request,
+// challenge and holder checks require WithPresentationProofCheck.
+func NewPresentation(vpData []byte, opts ...PresentationOpt) (*Presentation, error) {
+	vpOpts := &presentationOpts{}
+	for _, opt := range opts {
+		opt(vpOpts)
+	}
+
+	raw := &rawPresentation{}
+
+	err := json.Unmarshal(vpData, raw)
+	if err != nil {
+		return nil, fmt.Errorf("JSON unmarshalling of verifiable presentation failed: %w", err)
+	}
+
+	vp := &Presentation{
+		Context:     raw.Context,
+		ID:          raw.ID,
+		Type:        raw.Type,
+		Credentials: raw.Credentials,
+		Holder:      raw.Holder,
+		Proof:       raw.Proof,
+	}
+
+	vp.CustomFields, err = decodeCustomFields(vpData, modeledPresentationFields)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validatePresentation(vp); err != nil {
+		return nil, err
+	}
+
+	if err := validatePresentationSchema(vpData); err != nil {
+		return nil, err
+	}
+
+	if err := checkPresentationProofs(vpData, vp, vpOpts); err != nil {
+		return nil, err
+	}
+
+	if vpOpts.subjectIsHolder {
+		if err := vp.checkSubjectsAreHolder(vpOpts.credentialDecoding); err != nil {
+			return nil, err
+		}
+	}
+
+	if vpOpts.challenges != nil {
+		if vpOpts.request == nil {
+			return nil, errors.New("challenge check requires the presentation request")
+		}
+
+		if err := vpOpts.challenges.Use(vpOpts.request); err != nil {
+			return nil, fmt.Errorf("verifiable presentation challenge: %w", err)
+		}
+	}
+
+	return vp, nil
+}
+
+// NewPresentationOf creates a Verifiable Presentation of the credentials for the holder.
+func NewPresentationOf(holder string, credentials ...*Credential) (*Presentation, error) {
+	vp := &Presentation{
+		Context: []interface{}{vcContext},
+		Type:    vpType,
+		Holder:  holder,
+	}
+
+	for _, vc := range credentials {
+		vcBytes, err := vc.MarshalJSON()
+		if err != nil {
+			return nil, err
+		}
+
+		var vcMap map[string]interface{}
+		if err := json.Unmarshal(vcBytes, &vcMap); err != nil {
+			return nil, fmt.Errorf("JSON unmarshalling of verifiable credential failed: %w", err)
+		}
+
+		vp.Credentials = append(vp.Credentials, vcMap)
+	}
+
+	return vp, nil
+}
+
+// AddLinkedDataProof signs the presentation by the holder and binds the proof to the verifier's request.
+func (vp *Presentation) AddLinkedDataProof(ctx *LinkedDataProofContext, request *PresentationRequest) error {
+	if vp.Holder == "" {
+		return errors.New("holder of the verifiable presentation is not defined")
+	}
+
+	if controllerOfKey(ctx.Creator) != vp.Holder {
+		return fmt.Errorf("creator key %s is not bound to holder %s", ctx.Creator, vp.Holder)
+	}
+
+	vpBytes, err := vp.MarshalJSON()
+	if err != nil {
+		return err
+	}
+
+	signCtx := &signer.Context{
+		SignatureType: ctx.SignatureType,
+		Creator:       ctx.Creator,
+		Signer:        ctx.Signer,
+		Created:       ctx.Created,
+	}
+
+	if request != nil {
+		signCtx.Domain = request.Domain
+		signCtx.Nonce = []byte(request.Challenge)
+	}
+
+	signed, err := signer.New().Sign(signCtx, vpBytes)
+	if err != nil {
+		return fmt.Errorf("failed to add linked data proof to verifiable presentation: %w", err)
+	}
+
+	raw := &rawPresentation{}
+	if err := json.Unmarshal(signed, raw); err != nil {
+		return fmt.Errorf("JSON unmarshalling of signed verifiable presentation failed: %w", err)
+	}
+
+	vp.Proof = raw.Proof
+
+	return nil
+}
+
+// DecodeCredentials decodes the embedded Verifiable Credentials, credentials in JWT form
+// require the JWT decoding options.
+func (vp *Presentation) DecodeCredentials(opts ...CredentialOpt) ([]*Credential, error) {
+	credentials := make([]*Credential, 0, len(vp.Credentials))
+
+	for i, c := range vp.Credentials {
+		var vcBytes []byte
+
+		switch vc := c.(type) {
+		case string:
+			vcBytes = []byte(vc)
+		default:
+			b, err := json.Marshal(vc)
+			if err != nil {
+				return nil, fmt.Errorf("JSON marshalling of credential %d failed: %w", i, err)
+			}
+
+			vcBytes = b
+		}
+
+		vc, err := NewCredential(vcBytes, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("decode credential %d of verifiable presentation: %w", i, err)
+		}
+
+		credentials = append(credentials, vc)
+	}
+
+	return credentials, nil
+}
+
+// MarshalJSON converts Verifiable Presentation to JSON bytes
+func (vp *Presentation) MarshalJSON() ([]byte, error) {
+	byteVP, err := json.Marshal(&rawPresentation{
+		Context:      vp.Context,
+		ID:           vp.ID,
+		Type:         vp.Type,
+		Credentials:  vp.Credentials,
+		Holder:       vp.Holder,
+		Proof:        vp.Proof,
+		CustomFields: vp.CustomFields,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("JSON marshalling of verifiable presentation failed: %w", err)
+	}
+
+	return byteVP, nil
+}
+
+// Types returns a list containing types of minimum one string type
+func (vp *Presentation) Types() []string {
+	switch t := vp.Type.(type) {
+	case string:
+		return []string{t}
+	case []interface{}:
+		types := make([]string, 0, len(t))
+
+		for _, v := range t {
+			if s, ok := v.(string); ok {
+				types = append(types, s)
+			}
+		}
+
+		return types
+	case []string:
+		return t
+	}
+
+	return []string{}
+}
+
+func (vp *Presentation) checkSubjectsAreHolder(opts []CredentialOpt) error {
+	credentials, err := vp.DecodeCredentials(opts...)
+	if err != nil {
+		return err
+	}
+
+	for _, vc := range credentials {
+		subjectID, err := vc.SubjectID()
+		if err != nil {
+			return fmt.Errorf("subject of credential %s: %w", vc.ID, err)
+		}
+
+		if subjectID != vp.Holder {
+			return fmt.Errorf("subject %s of credential %s is not the presentation holder", subjectID, vc.ID)
+		}
+	}
+
+	return nil
+}
+
+func validatePresentation(vp *Presentation) error {
+	if len(vp.Context) == 0 || vp.Context[0] != vcContext {
+		return fmt.Errorf("verifiable presentation is not valid: first @context must be %s", vcContext)
+	}
+
+	types := vp.Types()
+	if len(types) == 0 || types[0] != vpType {
+		return fmt.Errorf("verifiable presentation is not valid: first type must be %s", vpType)
+	}
+
+	return nil
+}
+
+// validatePresentationSchema validates the presentation against the schema of the Verifiable Presentation data model,
+// the violations are returned as ValidationError
+func validatePresentationSchema(vpData []byte) error {
+	schema, err := gojsonschema.NewSchema(defaultPresentationSchemaLoader)
+	if err != nil {
+		return fmt.Errorf("validation of verifiable presentation failed: %w", err)
+	}
+
+	result, err := schema.Validate(gojsonschema.NewBytesLoader(vpData))
+	if err != nil {
+		return fmt.Errorf("validation of verifiable presentation failed: %w", err)
+	}
+
+	if !result.Valid() {
+		validationErr := newValidationError(result)
+		validationErr.document = "verifiable presentation"
+
+		return validationErr
+	}
+
+	return nil
+}
+
+func checkPresentationProofs(vpData []byte, vp *Presentation, opts *presentationOpts) error {
+	if opts.keyResolver == nil {
+		// the challenge and holder of an unverified proof can't be trusted
+		if opts.request != nil || opts.challenges != nil || opts.holderBinding || opts.subjectIsHolder {
+			return errors.New("presentation request, challenge and holder checks require the proof check")
+		}
+
+		return nil
+	}
+
+	var vpMap map[string]interface{}
+	if err := json.Unmarshal(vpData, &vpMap); err != nil {
+		return fmt.Errorf("JSON unmarshalling of verifiable presentation failed: %w", err)
+	}
+
+	// the proofs are an array as created by the document signer
+	if p, ok := vpMap[presentationProofKey].(map[string]interface{}); ok {
+		vpMap[presentationProofKey] = []interface{}{p}
+	}
+
+	vpBytes, err := json.Marshal(vpMap)
+	if err != nil {
+		return err
+	}
+
+	if err := verifier.New(opts.keyResolver).Verify(vpBytes); err != nil {
+		return fmt.Errorf("verifiable presentation proof verification failed: %w", err)
+	}
+
+	proofs, err := proof.GetProofs(vpMap)
+	if err != nil {
+		return fmt.Errorf("verifiable presentation proof: %w", err)
+	}
+
+	for _, p := range proofs {
+		if err := checkPresentationProof(p, vp, opts); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func checkPresentationProof(p *proof.Proof, vp *Presentation, opts *presentationOpts) error {
+	if opts.holderBinding && (vp.Holder == "" || controllerOfKey(p.Creator) != vp.Holder) {
+		return fmt.Errorf("proof creator %s is not bound to holder %s", p.Creator, vp.Holder)
+	}
+
+	if opts.request != nil {
+		if string(p.Nonce) != opts.request.Challenge {
+			return errors.New("verifiable presentation proof challenge does not match the request")
+		}
+
+		if p.Domain != opts.request.Domain {
+			return errors.New("verifiable presentation proof domain does not match the request")
+		}
+	}
+
+	return nil
+}
+
+// controllerOfKey returns the DID of the DID public key ID
+func controllerOfKey(keyID string) string {
+	return strings.SplitN(keyID, "#", 2)[0]
+}
@@ -0,0 +1,273 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package verifiable
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/signer"
+)
+
+const (
+	holderDID = "did:example:ebfeb1f712ebc6f1c276e12ec21"
+	holderKey = holderDID + "#keys-1"
+)
+
+func TestNewPresentation(t *testing.T) {
+	holder := newTestHolder(t)
+	vp := holder.presentation(t, &PresentationRequest{Challenge: "c0ae1c8e", Domain: "verifier.example.com"})
+
+	vpBytes, err := vp.MarshalJSON()
+	require.NoError(t, err)
+
+	t.Run("test decode presentation", func(t *testing.T) {
+		decoded, err := NewPresentation(vpBytes)
+		require.NoError(t, err)
+		require.Equal(t, holderDID, decoded.Holder)
+		require.Equal(t, []string{vpType}, decoded.Types())
+		require.Len(t, decoded.Credentials, 1)
+	})
+
+	t.Run("test verify presentation bound to request and holder", func(t *testing.T) {
+		decoded, err := NewPresentation(vpBytes,
+			WithPresentationProofCheck(holder),
+			WithPresentationRequest(&PresentationRequest{Challenge: "c0ae1c8e", Domain: "verifier.example.com"}),
+			WithHolderBinding(),
+			WithSubjectHolderCheck(WithNoCustomSchemaCheck()))
+		require.NoError(t, err)
+
+		credentials, err := decoded.DecodeCredentials(WithNoCustomSchemaCheck())
+		require.NoError(t, err)
+		require.Equal(t, "http://example.edu/credentials/1872", credentials[0].ID)
+	})
+
+	t.Run("test request mismatch", func(t *testing.T) {
+		_, err := NewPresentation(vpBytes, WithPresentationProofCheck(holder),
+			WithPresentationRequest(&PresentationRequest{Challenge: "other", Domain: "verifier.example.com"}))
+		require.EqualError(t, err, "verifiable presentation proof challenge does not match the request")
+
+		_, err = NewPresentation(vpBytes, WithPresentationProofCheck(holder),
+			WithPresentationRequest(&PresentationRequest{Challenge: "c0ae1c8e", Domain: "other.example.com"}))
+		require.EqualError(t, err, "verifiable presentation proof domain does not match the request")
+	})
+
+	t.Run("test tampered presentation", func(t *testing.T) {
+		tampered := *vp
+		tampered.ID = "urn:uuid:tampered"
+		tamperedBytes, err := tampered.MarshalJSON()
+		require.NoError(t, err)
+
+		_, err = NewPresentation(tamperedBytes, WithPresentationProofCheck(holder))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "verifiable presentation proof verification failed")
+	})
+
+	t.Run("test holder binding violation", func(t *testing.T) {
+		other := *vp
+		other.Holder = "did:example:other"
+		other.Proof = nil
+		otherBytes, err := other.MarshalJSON()
+		require.NoError(t, err)
+
+		otherBytes = holder.sign(t, otherBytes)
+
+		_, err = NewPresentation(otherBytes, WithPresentationProofCheck(holder), WithHolderBinding())
+		require.EqualError(t, err, "proof creator "+holderKey+" is not bound to holder did:example:other")
+
+		_, err = NewPresentation(otherBytes, WithPresentationProofCheck(holder),
+			WithSubjectHolderCheck(WithNoCustomSchemaCheck()))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "is not the presentation holder")
+	})
+
+	t.Run("test missing proof", func(t *testing.T) {
+		unsigned := *vp
+		unsigned.Proof = nil
+		unsignedBytes, err := unsigned.MarshalJSON()
+		require.NoError(t, err)
+
+		_, err = NewPresentation(unsignedBytes, WithPresentationProofCheck(holder), WithHolderBinding())
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "verifiable presentation proof verification failed")
+	})
+
+	t.Run("test checks without the proof check", func(t *testing.T) {
+		unsigned := *vp
+		unsigned.Proof = nil
+		unsignedBytes, err := unsigned.MarshalJSON()
+		require.NoError(t, err)
+
+		for _, opt := range []PresentationOpt{
+			WithPresentationRequest(&PresentationRequest{Challenge: "c0ae1c8e", Domain: "verifier.example.com"}),
+			WithHolderBinding(),
+			WithSubjectHolderCheck(WithNoCustomSchemaCheck()),
+		} {
+			_, err = NewPresentation(unsignedBytes, opt)
+			require.EqualError(t, err, "presentation request, challenge and holder checks require the proof check")
+		}
+	})
+
+	t.Run("test invalid presentation", func(t *testing.T) {
+		_, err := NewPresentation([]byte("{"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "JSON unmarshalling of verifiable presentation failed")
+
+		_, err = NewPresentation([]byte(`{"@context":["https://www.w3.org/2018/credentials/v1"],
+			"type":"VerifiableCredential"}`))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "first type must be VerifiablePresentation")
+
+		_, err = NewPresentation([]byte(`{"type":"VerifiablePresentation"}`))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "first @context must be")
+
+		_, err = NewPresentation(holder.sign(t, []byte(`{"@context":["https://www.w3.org/2018/credentials/v1"],
+			"type":["VerifiablePresentation"],"verifiableCredential":[{"id":"invalid"}]}`)),
+			WithPresentationProofCheck(holder), WithSubjectHolderCheck(WithNoCustomSchemaCheck()))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "decode credential 0 of verifiable presentation")
+	})
+
+	t.Run("test presentation not valid against the schema", func(t *testing.T) {
+		_, err := NewPresentation([]byte(`{"@context":["https://www.w3.org/2018/credentials/v1"],
+			"type":"VerifiablePresentation","verifiableCredential":[1],"proof":{"created":"2020-01-01T00:00:00Z"}}`))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "verifiable presentation is not valid")
+
+		validationErr := &ValidationError{}
+		require.True(t, errors.As(err, &validationErr))
+
+		fields := make([]string, len(validationErr.Violations))
+		for i, v := range validationErr.Violations {
+			fields[i] = v.Field
+		}
+
+		require.Contains(t, fields, "verifiableCredential.0")
+		require.Contains(t, fields, "proof")
+	})
+}
+
+func TestPresentation_CustomFields(t *testing.T) {
+	holder := newTestHolder(t)
+
+	vc, err := NewCredential([]byte(validCredential), WithNoCustomSchemaCheck())
+	require.NoError(t, err)
+
+	vp, err := NewPresentationOf(holderDID, vc)
+	require.NoError(t, err)
+
+	vp.CustomFields = CustomFields{"referenceNumber": 83294847.0, "holder": "ignored"}
+
+	require.NoError(t, vp.AddLinkedDataProof(&LinkedDataProofContext{SignatureType: "Ed25519Signature2018",
+		Creator: holderKey, Signer: holder}, nil))
+
+	vpBytes, err := vp.MarshalJSON()
+	require.NoError(t, err)
+
+	t.Run("test custom fields round-trip", func(t *testing.T) {
+		decoded, err := NewPresentation(vpBytes, WithPresentationProofCheck(holder))
+		require.NoError(t, err)
+		require.Equal(t, CustomFields{"referenceNumber": 83294847.0}, decoded.CustomFields)
+		require.Equal(t, holderDID, decoded.Holder)
+
+		decodedBytes, err := decoded.MarshalJSON()
+		require.NoError(t, err)
+		require.JSONEq(t, string(vpBytes), string(decodedBytes))
+	})
+
+	t.Run("test no custom fields", func(t *testing.T) {
+		decoded, err := NewPresentation([]byte(`{"@context":["https://www.w3.org/2018/credentials/v1"],
+			"type":"VerifiablePresentation"}`))
+		require.NoError(t, err)
+		require.Nil(t, decoded.CustomFields)
+	})
+}
+
+func TestPresentation_AddLinkedDataProof(t *testing.T) {
+	holder := newTestHolder(t)
+
+	vc, err := NewCredential([]byte(validCredential), WithNoCustomSchemaCheck())
+	require.NoError(t, err)
+
+	t.Run("test creator is not holder key", func(t *testing.T) {
+		vp, err := NewPresentationOf(holderDID, vc)
+		require.NoError(t, err)
+
+		err = vp.AddLinkedDataProof(&LinkedDataProofContext{SignatureType: "Ed25519Signature2018",
+			Creator: "did:example:other#keys-1", Signer: holder}, nil)
+		require.EqualError(t, err, "creator key did:example:other#keys-1 is not bound to holder "+holderDID)
+	})
+
+	t.Run("test no holder", func(t *testing.T) {
+		vp, err := NewPresentationOf("", vc)
+		require.NoError(t, err)
+
+		err = vp.AddLinkedDataProof(&LinkedDataProofContext{}, nil)
+		require.EqualError(t, err, "holder of the verifiable presentation is not defined")
+	})
+
+	t.Run("test signing error", func(t *testing.T) {
+		vp, err := NewPresentationOf(holderDID, vc)
+		require.NoError(t, err)
+
+		err = vp.AddLinkedDataProof(&LinkedDataProofContext{SignatureType: "Unknown", Creator: holderKey,
+			Signer: holder}, nil)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to add linked data proof to verifiable presentation")
+	})
+}
+
+type testHolder struct {
+	pub  ed25519.PublicKey
+	priv ed25519.PrivateKey
+}
+
+func newTestHolder(t *testing.T) *testHolder {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	return &testHolder{pub: pub, priv: priv}
+}
+
+func (h *testHolder) presentation(t *testing.T, request *PresentationRequest) *Presentation {
+	vc, err := NewCredential([]byte(validCredential), WithNoCustomSchemaCheck())
+	require.NoError(t, err)
+
+	vp, err := NewPresentationOf(holderDID, vc)
+	require.NoError(t, err)
+
+	err = vp.AddLinkedDataProof(&LinkedDataProofContext{SignatureType: "Ed25519Signature2018",
+		Creator: holderKey, Signer: h}, request)
+	require.NoError(t, err)
+
+	return vp
+}
+
+// sign adds the holder proof to the presentation document without the holder binding of AddLinkedDataProof
+func (h *testHolder) sign(t *testing.T, vpBytes []byte) []byte {
+	signed, err := signer.New().Sign(&signer.Context{SignatureType: "Ed25519Signature2018", Creator: holderKey,
+		Signer: h}, vpBytes)
+	require.NoError(t, err)
+
+	return signed
+}
+
+func (h *testHolder) Sign(doc []byte) ([]byte, error) {
+	return ed25519.Sign(h.priv, doc), nil
+}
+
+func (h *testHolder) Resolve(id string) ([]byte, error) {
+	if id != holderKey {
+		return nil, errors.New("key not found")
+	}
+
+	return h.pub, nil
+}
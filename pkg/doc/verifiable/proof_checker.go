@@ -0,0 +1,218 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package verifiable
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/verifier"
+	"github.com/hyperledger/aries-framework-go/pkg/framework/didresolver"
+)
+
+// DIDResolver resolves the DIDs of the proof creators, e.g. the DID resolver of the framework
+type DIDResolver interface {
+	Resolve(did string, opts ...didresolver.ResolveOpt) (*did.Doc, error)
+}
+
+// didKeyResolver resolves the public keys of the proofs from the DID documents of their controllers
+type didKeyResolver struct {
+	resolver DIDResolver
+}
+
+// NewDIDKeyResolver returns the KeyResolver resolving the keys, e.g. did:example:123#keys-1, from the DID
+// documents of their controllers
+func NewDIDKeyResolver(resolver DIDResolver) KeyResolver {
+	return &didKeyResolver{resolver: resolver}
+}
+
+// Resolve returns the value of the public key of the DID document
+func (r *didKeyResolver) Resolve(keyID string) ([]byte, error) {
+	didID := controllerOfKey(keyID)
+
+	doc, err := r.resolver.Resolve(didID)
+	if err != nil {
+		return nil, fmt.Errorf("resolve DID %s: %w", didID, err)
+	}
+
+	for _, key := range doc.PublicKey {
+		// the key IDs may be relative to the DID, e.g. #keys-1
+		if key.ID == keyID || (strings.HasPrefix(key.ID, "#") && didID+key.ID == keyID) {
+			return key.Value, nil
+		}
+	}
+
+	return nil, fmt.Errorf("public key %s not found in the DID document: %w", keyID, did.ErrKeyNotFound)
+}
+
+// ProofResult is the verification result of a linked data proof
+type ProofResult struct {
+	Type string
+	// Creator is the ID of the public key of the proof, i.e. its verification method
+	Creator string
+	Created *time.Time
+	// ProofPurpose is the verification relationship of the creator key, e.g. assertionMethod
+	ProofPurpose string
+	// Err is the cause of the verification failure, nil if the proof is verified
+	Err error
+}
+
+// VerificationResult holds the results of the proofs of a document, the document is verified if all its
+// proofs are verified
+type VerificationResult struct {
+	Verified bool
+	Proofs   []ProofResult
+}
+
+// ProofVerificationError is returned when a proof of the credential is not verified
+type ProofVerificationError struct {
+	Result *VerificationResult
+}
+
+func (e *ProofVerificationError) Error() string {
+	errMsg := "verifiable credential proof verification failed:"
+
+	for i, p := range e.Result.Proofs {
+		if p.Err != nil {
+			errMsg += fmt.Sprintf("\n- proof %d %s by %s: %s", i, p.Type, p.Creator, p.Err)
+		}
+	}
+
+	return errMsg
+}
+
+// ProofChecker verifies the linked data proofs of the documents: the public keys of the proofs are resolved,
+// the documents canonicalized and the signatures verified. Only Ed25519Signature2018 proofs are supported.
+type ProofChecker struct {
+	verifier *verifier.DocumentVerifier
+	// resolver resolves the DID documents of the issuers, nil if the keys aren't resolved from DID documents
+	resolver DIDResolver
+}
+
+// NewProofChecker returns the ProofChecker resolving the public keys with the key resolver. The keys of the
+// credential proofs are checked against the assertionMethod of the issuer DID documents if the key resolver
+// is a DID key resolver, see NewDIDKeyResolver.
+func NewProofChecker(resolver KeyResolver) *ProofChecker {
+	c := &ProofChecker{verifier: verifier.New(resolver)}
+
+	if r, ok := resolver.(*didKeyResolver); ok {
+		c.resolver = r.resolver
+	}
+
+	return c
+}
+
+// NewDIDProofChecker returns the ProofChecker resolving the public keys from the DID documents of their
+// controllers, e.g. with the DID resolver of the framework
+func NewDIDProofChecker(resolver DIDResolver) *ProofChecker {
+	return NewProofChecker(NewDIDKeyResolver(resolver))
+}
+
+// Check verifies the proofs of the JSON-LD document and returns their results, an error is returned if the
+// document has no proofs or if they can't be read
+func (c *ProofChecker) Check(doc []byte) (*VerificationResult, error) {
+	// a single proof may be embedded as an object
+	doc, err := withProofList(doc)
+	if err != nil {
+		return nil, fmt.Errorf("JSON unmarshalling of linked data document failed: %w", err)
+	}
+
+	results, err := c.verifier.VerifyProofs(doc)
+	if err != nil {
+		return nil, fmt.Errorf("read linked data proofs: %w", err)
+	}
+
+	if len(results) == 0 {
+		return nil, errors.New("linked data proof not found")
+	}
+
+	result := &VerificationResult{Verified: true, Proofs: make([]ProofResult, len(results))}
+
+	for i, r := range results {
+		result.Proofs[i] = ProofResult{
+			Type:         r.Proof.Type,
+			Creator:      r.Proof.Creator,
+			Created:      r.Proof.Created,
+			ProofPurpose: r.Proof.ProofPurpose,
+			Err:          r.Err,
+		}
+
+		if r.Err != nil {
+			result.Verified = false
+		}
+	}
+
+	return result, nil
+}
+
+// WithProofChecker option verifies the linked data proofs of the credentials in JSON form with the checker, the
+// credentials without proofs are rejected. The proofs must be created for the assertionMethod purpose by a key of
+// the issuer, listed in the assertionMethod of the issuer DID document if the checker resolves DID documents. The
+// proofs failing the verification are reported by ProofVerificationError.
+func WithProofChecker(checker *ProofChecker) CredentialOpt {
+	return func(opts *credentialOpts) {
+		opts.proofChecker = checker
+	}
+}
+
+// checkProofs verifies the proofs of the credential in JSON form
+func checkProofs(vcData []byte, opts *credentialOpts) error {
+	if opts.proofChecker == nil || opts.jwtDecoding != noJwtDecoding {
+		return nil
+	}
+
+	result, err := opts.proofChecker.Check(vcData)
+	if err != nil {
+		return fmt.Errorf("verifiable credential proof verification failed: %w", err)
+	}
+
+	if !result.Verified {
+		return &ProofVerificationError{Result: result}
+	}
+
+	issuerID, _, err := issuerFromBytes(vcData)
+	if err != nil {
+		return err
+	}
+
+	for i := range result.Proofs {
+		result.Proofs[i].Err = opts.proofChecker.checkAssertion(issuerID, &result.Proofs[i])
+		if result.Proofs[i].Err != nil {
+			result.Verified = false
+		}
+	}
+
+	if !result.Verified {
+		return &ProofVerificationError{Result: result}
+	}
+
+	return nil
+}
+
+// checkAssertion checks that the proof was created by a key of the issuer for the assertionMethod purpose
+func (c *ProofChecker) checkAssertion(issuerID string, p *ProofResult) error {
+	if controllerOfKey(p.Creator) != issuerID {
+		return fmt.Errorf("proof creator %s is not a key of issuer %s", p.Creator, issuerID)
+	}
+
+	if p.ProofPurpose != string(did.AssertionMethod) {
+		return fmt.Errorf("proof purpose %q is not %s", p.ProofPurpose, did.AssertionMethod)
+	}
+
+	if c.resolver == nil {
+		return nil
+	}
+
+	doc, err := c.resolver.Resolve(issuerID)
+	if err != nil {
+		return fmt.Errorf("resolve DID %s: %w", issuerID, err)
+	}
+
+	return doc.ValidateProofPurpose(p.ProofPurpose, p.Creator)
+}
@@ -0,0 +1,188 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package verifiable
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	"github.com/hyperledger/aries-framework-go/pkg/framework/didresolver"
+)
+
+func TestWithProofChecker(t *testing.T) {
+	issuer := newTestHolder(t)
+
+	vc, err := NewCredential([]byte(validCredential), WithNoCustomSchemaCheck())
+	require.NoError(t, err)
+
+	issuerKey := vc.Issuer.ID + "#keys-1"
+	issuerDoc := func(keyID string) *did.Doc {
+		key := did.PublicKey{ID: keyID, Value: issuer.pub}
+
+		return &did.Doc{ID: vc.Issuer.ID, PublicKey: []did.PublicKey{key},
+			AssertionMethod: []did.VerificationMethod{{PublicKey: key, Referenced: true}}}
+	}
+
+	resolver := &mockDIDResolver{docs: map[string]*did.Doc{vc.Issuer.ID: issuerDoc(issuerKey)}}
+	checker := NewDIDProofChecker(resolver)
+
+	vc.Proof = nil
+	require.NoError(t, vc.GenerateProof(issuer, &ProofOptions{Creator: issuerKey}))
+
+	vcBytes, err := vc.MarshalJSON()
+	require.NoError(t, err)
+
+	t.Run("test credential proof verified", func(t *testing.T) {
+		decoded, err := NewCredential(vcBytes, WithNoCustomSchemaCheck(), WithProofChecker(checker))
+		require.NoError(t, err)
+		require.Equal(t, vc.ID, decoded.ID)
+
+		result, err := checker.Check(vcBytes)
+		require.NoError(t, err)
+		require.True(t, result.Verified)
+		require.Len(t, result.Proofs, 1)
+		require.Equal(t, Ed25519Signature2018, result.Proofs[0].Type)
+		require.Equal(t, issuerKey, result.Proofs[0].Creator)
+		require.Equal(t, "assertionMethod", result.Proofs[0].ProofPurpose)
+		require.NotNil(t, result.Proofs[0].Created)
+		require.NoError(t, result.Proofs[0].Err)
+	})
+
+	t.Run("test key relative to the DID", func(t *testing.T) {
+		relative := NewDIDProofChecker(&mockDIDResolver{docs: map[string]*did.Doc{
+			vc.Issuer.ID: issuerDoc("#keys-1"),
+		}})
+
+		_, err := NewCredential(vcBytes, WithNoCustomSchemaCheck(), WithProofChecker(relative))
+		require.NoError(t, err)
+	})
+
+	t.Run("test proof not created by the issuer", func(t *testing.T) {
+		holder := *vc
+		holder.Proof = nil
+		require.NoError(t, holder.GenerateProof(issuer, &ProofOptions{Creator: holderKey}))
+
+		holderBytes, err := holder.MarshalJSON()
+		require.NoError(t, err)
+
+		resolver.docs[holderDID] = &did.Doc{ID: holderDID,
+			PublicKey: []did.PublicKey{{ID: holderKey, Value: issuer.pub}}}
+		defer delete(resolver.docs, holderDID)
+
+		_, err = NewCredential(holderBytes, WithNoCustomSchemaCheck(), WithProofChecker(checker))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "proof creator "+holderKey+" is not a key of issuer "+vc.Issuer.ID)
+	})
+
+	t.Run("test proof purpose", func(t *testing.T) {
+		authentication := *vc
+		authentication.Proof = nil
+		require.NoError(t, authentication.GenerateProof(issuer,
+			&ProofOptions{Creator: issuerKey, ProofPurpose: "authentication"}))
+
+		authenticationBytes, err := authentication.MarshalJSON()
+		require.NoError(t, err)
+
+		_, err = NewCredential(authenticationBytes, WithNoCustomSchemaCheck(), WithProofChecker(checker))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), `proof purpose "authentication" is not assertionMethod`)
+
+		// the key must be an assertion method of the issuer
+		notAsserting := NewDIDProofChecker(&mockDIDResolver{docs: map[string]*did.Doc{
+			vc.Issuer.ID: {ID: vc.Issuer.ID, PublicKey: []did.PublicKey{{ID: issuerKey, Value: issuer.pub}}},
+		}})
+
+		_, err = NewCredential(vcBytes, WithNoCustomSchemaCheck(), WithProofChecker(notAsserting))
+
+		verificationErr := &ProofVerificationError{}
+		require.True(t, errors.As(err, &verificationErr))
+		require.True(t, errors.Is(verificationErr.Result.Proofs[0].Err, did.ErrProofPurposeMismatch))
+
+		// the keys of the other key resolvers are only bound to the issuer
+		_, err = NewCredential(vcBytes, WithNoCustomSchemaCheck(),
+			WithProofChecker(NewProofChecker(staticKeyResolver(issuer.pub))))
+		require.NoError(t, err)
+	})
+
+	t.Run("test tampered credential", func(t *testing.T) {
+		tampered := *vc
+		tampered.ID = "http://example.edu/credentials/tampered"
+		tamperedBytes, err := tampered.MarshalJSON()
+		require.NoError(t, err)
+
+		_, err = NewCredential(tamperedBytes, WithNoCustomSchemaCheck(), WithProofChecker(checker))
+		require.Error(t, err)
+
+		verificationErr := &ProofVerificationError{}
+		require.True(t, errors.As(err, &verificationErr))
+		require.False(t, verificationErr.Result.Verified)
+		require.Error(t, verificationErr.Result.Proofs[0].Err)
+		require.Contains(t, err.Error(), "proof 0 Ed25519Signature2018 by "+issuerKey)
+	})
+
+	t.Run("test unsupported proof and unresolved key", func(t *testing.T) {
+		// validCredential has a single RsaSignature2018 proof, another proof is added by an unknown key
+		other, err := NewCredential([]byte(validCredential), WithNoCustomSchemaCheck())
+		require.NoError(t, err)
+		require.NoError(t, other.GenerateProof(issuer, &ProofOptions{Creator: "did:example:unknown#keys-1"}))
+
+		otherBytes, err := other.MarshalJSON()
+		require.NoError(t, err)
+
+		result, err := checker.Check(otherBytes)
+		require.NoError(t, err)
+		require.False(t, result.Verified)
+		require.Len(t, result.Proofs, 2)
+		require.EqualError(t, result.Proofs[0].Err, "signature type RsaSignature2018 not supported")
+		require.Contains(t, result.Proofs[1].Err.Error(), "resolve DID did:example:unknown")
+
+		resolver.docs["did:example:unknown"] = &did.Doc{ID: "did:example:unknown"}
+		defer delete(resolver.docs, "did:example:unknown")
+
+		result, err = checker.Check(otherBytes)
+		require.NoError(t, err)
+		require.True(t, errors.Is(result.Proofs[1].Err, did.ErrKeyNotFound))
+	})
+
+	t.Run("test credential without proof", func(t *testing.T) {
+		unsigned := *vc
+		unsigned.Proof = nil
+		unsignedBytes, err := unsigned.MarshalJSON()
+		require.NoError(t, err)
+
+		_, err = NewCredential(unsignedBytes, WithNoCustomSchemaCheck(), WithProofChecker(checker))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "proof not found")
+
+		_, err = checker.Check([]byte(`{"proof": []}`))
+		require.EqualError(t, err, "linked data proof not found")
+
+		_, err = checker.Check([]byte("{"))
+		require.Error(t, err)
+	})
+}
+
+type staticKeyResolver []byte
+
+func (k staticKeyResolver) Resolve(string) ([]byte, error) {
+	return k, nil
+}
+
+type mockDIDResolver struct {
+	docs map[string]*did.Doc
+}
+
+func (r *mockDIDResolver) Resolve(didID string, _ ...didresolver.ResolveOpt) (*did.Doc, error) {
+	doc, ok := r.docs[didID]
+	if !ok {
+		return nil, didresolver.ErrNotFound
+	}
+
+	return doc, nil
+}
@@ -0,0 +1,402 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package verifiable
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// JSONSchemaDraft is the draft of the JSON Schema specification a credential schema is written in
+type JSONSchemaDraft int
+
+const (
+	// DetectDraft detects the draft from the $schema keyword of the credential schema, the schemas
+	// without $schema are validated with the keywords of the drafts 4, 6 and 7
+	DetectDraft JSONSchemaDraft = iota
+	// Draft4 is the JSON Schema draft 4
+	Draft4
+	// Draft6 is the JSON Schema draft 6
+	Draft6
+	// Draft7 is the JSON Schema draft 7
+	Draft7
+	// Draft201909 is the JSON Schema draft 2019-09
+	Draft201909
+	// Draft202012 is the JSON Schema draft 2020-12
+	Draft202012
+)
+
+const (
+	draft4SchemaURL      = "http://json-schema.org/draft-04/schema"
+	draft6SchemaURL      = "http://json-schema.org/draft-06/schema"
+	draft7SchemaURL      = "http://json-schema.org/draft-07/schema"
+	draft201909SchemaURL = "https://json-schema.org/draft/2019-09/schema"
+	draft202012SchemaURL = "https://json-schema.org/draft/2020-12/schema"
+
+	// maxSchemaRefs limits the number of remote schemas referenced by a credential schema
+	maxSchemaRefs = 32
+)
+
+// The validator implements the drafts 4, 6 and 7. The schemas of the drafts 2019-09 and 2020-12 are translated
+// to draft 7: $ref is evaluated along with its sibling keywords, prefixItems and the 2020-12 items become items
+// and additionalItems, dependentRequired and dependentSchemas become dependencies. The schemas using the
+// keywords without draft 7 equivalent are rejected rather than partially validated.
+//
+//nolint:gochecknoglobals
+var unsupportedKeywords = []string{
+	"unevaluatedProperties", "unevaluatedItems", "minContains", "maxContains",
+	"$anchor", "$dynamicRef", "$dynamicAnchor", "$recursiveRef", "$recursiveAnchor",
+}
+
+// keywords with a schema or an array of schemas value
+//
+//nolint:gochecknoglobals
+var subschemaKeywords = []string{
+	"items", "prefixItems", "additionalItems", "contains", "additionalProperties", "propertyNames",
+	"not", "if", "then", "else", "allOf", "anyOf", "oneOf",
+}
+
+// keywords with a map of schemas value
+//
+//nolint:gochecknoglobals
+var subschemaMapKeywords = []string{"properties", "patternProperties", "definitions", "$defs", "dependentSchemas"}
+
+// WithJSONSchemaDraft option sets the JSON Schema draft of the credential schema with the given ID,
+// by default the draft is detected from the $schema keyword of the credential schema.
+func WithJSONSchemaDraft(schemaID string, draft JSONSchemaDraft) CredentialOpt {
+	return func(opts *credentialOpts) {
+		if opts.schemaDrafts == nil {
+			opts.schemaDrafts = make(map[string]JSONSchemaDraft)
+		}
+
+		opts.schemaDrafts[schemaID] = draft
+	}
+}
+
+// compileCredentialSchema compiles the credential schema with the given ID. The remote schemas referenced
+// with $ref are loaded from the schema registry. The format keywords are asserted.
+func compileCredentialSchema(id string, data []byte, opts *credentialOpts) (*gojsonschema.Schema, error) {
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("unmarshalling credential schema failed: %w", err)
+	}
+
+	loader := gojsonschema.NewSchemaLoader()
+
+	doc, err := prepareSchema(doc, opts.schemaDrafts[id], loader)
+	if err != nil {
+		return nil, err
+	}
+
+	refs := &schemaRefs{loader: loader, registry: opts.schemaRegistry, opts: opts,
+		loaded: map[string]bool{baseURL(id): true}}
+
+	if err = refs.load(doc); err != nil {
+		return nil, err
+	}
+
+	return loader.Compile(gojsonschema.NewGoLoader(doc))
+}
+
+// prepareSchema configures the loader for the draft of the schema and translates the schemas of the drafts
+// 2019-09 and 2020-12 to draft 7
+func prepareSchema(doc interface{}, draft JSONSchemaDraft, loader *gojsonschema.SchemaLoader) (interface{}, error) {
+	if draft == DetectDraft {
+		draft = detectDraft(doc)
+	}
+
+	switch draft {
+	case Draft4:
+		loader.AutoDetect, loader.Draft = false, gojsonschema.Draft4
+	case Draft6:
+		loader.AutoDetect, loader.Draft = false, gojsonschema.Draft6
+	case Draft7:
+		loader.AutoDetect, loader.Draft = false, gojsonschema.Draft7
+	case Draft201909, Draft202012:
+		loader.AutoDetect, loader.Draft = false, gojsonschema.Draft7
+
+		return translateSchemaOf(doc, draft)
+	}
+
+	return doc, nil
+}
+
+// detectDraft returns the draft of the $schema keyword of the schema
+func detectDraft(doc interface{}) JSONSchemaDraft {
+	m, ok := doc.(map[string]interface{})
+	if !ok {
+		return DetectDraft
+	}
+
+	schema, ok := m["$schema"].(string)
+	if !ok {
+		return DetectDraft
+	}
+
+	switch withoutScheme(strings.TrimSuffix(schema, "#")) {
+	case withoutScheme(draft4SchemaURL):
+		return Draft4
+	case withoutScheme(draft6SchemaURL):
+		return Draft6
+	case withoutScheme(draft7SchemaURL):
+		return Draft7
+	case withoutScheme(draft201909SchemaURL):
+		return Draft201909
+	case withoutScheme(draft202012SchemaURL):
+		return Draft202012
+	}
+
+	return DetectDraft
+}
+
+// withoutScheme returns the URL without http or https scheme, the meta-schemas are referenced with both
+func withoutScheme(url string) string {
+	return strings.TrimPrefix(strings.TrimPrefix(url, "https://"), "http://")
+}
+
+// translateSchema translates the schema of the draft 2019-09 or 2020-12 to draft 7
+func translateSchema(doc interface{}, draft JSONSchemaDraft) (interface{}, error) {
+	schema, ok := doc.(map[string]interface{})
+	if !ok {
+		return doc, nil
+	}
+
+	for _, keyword := range unsupportedKeywords {
+		if _, ok := schema[keyword]; ok {
+			return nil, fmt.Errorf("unsupported JSON Schema keyword: %s", keyword)
+		}
+	}
+
+	translated := make(map[string]interface{}, len(schema))
+
+	for k, v := range schema {
+		var err error
+
+		switch {
+		case containsString(subschemaKeywords, k):
+			translated[k], err = translateSubschemas(v, draft)
+		case containsString(subschemaMapKeywords, k):
+			translated[k], err = translateSubschemaMap(v, draft)
+		default:
+			translated[k] = v
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	translateItems(translated, draft)
+	translateDependencies(translated)
+
+	return translateRef(translated), nil
+}
+
+func translateSubschemas(v interface{}, draft JSONSchemaDraft) (interface{}, error) {
+	list, ok := v.([]interface{})
+	if !ok {
+		return translateSchema(v, draft)
+	}
+
+	translated := make([]interface{}, len(list))
+
+	for i, s := range list {
+		var err error
+
+		translated[i], err = translateSchema(s, draft)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return translated, nil
+}
+
+func translateSubschemaMap(v interface{}, draft JSONSchemaDraft) (interface{}, error) {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return v, nil
+	}
+
+	translated := make(map[string]interface{}, len(m))
+
+	for k, s := range m {
+		var err error
+
+		translated[k], err = translateSchema(s, draft)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return translated, nil
+}
+
+// translateItems replaces the 2020-12 prefixItems and items with the draft 7 items and additionalItems
+func translateItems(schema map[string]interface{}, draft JSONSchemaDraft) {
+	if draft != Draft202012 {
+		return
+	}
+
+	items, hasItems := schema["items"]
+	prefixItems, hasPrefixItems := schema["prefixItems"]
+
+	if !hasPrefixItems {
+		return
+	}
+
+	delete(schema, "prefixItems")
+	schema["items"] = prefixItems
+
+	if hasItems {
+		schema["additionalItems"] = items
+	}
+}
+
+// translateDependencies replaces dependentRequired and dependentSchemas with the draft 7 dependencies
+func translateDependencies(schema map[string]interface{}) {
+	dependencies := make(map[string]interface{})
+
+	for _, keyword := range []string{"dependentRequired", "dependentSchemas"} {
+		if m, ok := schema[keyword].(map[string]interface{}); ok {
+			for k, v := range m {
+				dependencies[k] = v
+			}
+
+			delete(schema, keyword)
+		}
+	}
+
+	if len(dependencies) > 0 {
+		schema["dependencies"] = dependencies
+	}
+}
+
+// translateRef moves $ref into allOf when it has sibling keywords, which draft 7 ignores
+func translateRef(schema map[string]interface{}) map[string]interface{} {
+	ref, ok := schema["$ref"]
+	if !ok || len(schema) == 1 {
+		return schema
+	}
+
+	delete(schema, "$ref")
+
+	allOf, _ := schema["allOf"].([]interface{}) //nolint:errcheck
+	schema["allOf"] = append(allOf, map[string]interface{}{"$ref": ref})
+
+	return schema
+}
+
+// schemaRefs loads the remote schemas referenced by a credential schema from the schema registry
+type schemaRefs struct {
+	loader   *gojsonschema.SchemaLoader
+	registry SchemaRegistry
+	opts     *credentialOpts
+	loaded   map[string]bool
+}
+
+// load downloads and adds to the loader the remote schemas referenced by the schema
+func (r *schemaRefs) load(doc interface{}) error {
+	switch v := doc.(type) {
+	case map[string]interface{}:
+		if ref, ok := v["$ref"].(string); ok {
+			if err := r.loadRef(baseURL(ref)); err != nil {
+				return err
+			}
+		}
+
+		for _, s := range v {
+			if err := r.load(s); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, s := range v {
+			if err := r.load(s); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (r *schemaRefs) loadRef(id string) error {
+	if r.loaded[id] || !isAbsoluteURI(id) {
+		return nil
+	}
+
+	if len(r.loaded) > maxSchemaRefs {
+		return fmt.Errorf("credential schema references more than %d schemas", maxSchemaRefs)
+	}
+
+	r.loaded[id] = true
+
+	data, err := r.registry.Get(id)
+	if err != nil {
+		return fmt.Errorf("loading referenced schema from %s failed: %w", id, err)
+	}
+
+	var doc interface{}
+	if err = json.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("unmarshalling referenced schema from %s failed: %w", id, err)
+	}
+
+	doc, err = translateSchemaOf(doc, r.opts.schemaDrafts[id])
+	if err != nil {
+		return err
+	}
+
+	if err = r.load(doc); err != nil {
+		return err
+	}
+
+	return r.loader.AddSchema(id, gojsonschema.NewGoLoader(doc))
+}
+
+// translateSchemaOf translates the referenced schema of the draft 2019-09 or 2020-12 to draft 7
+func translateSchemaOf(doc interface{}, draft JSONSchemaDraft) (interface{}, error) {
+	if draft == DetectDraft {
+		draft = detectDraft(doc)
+	}
+
+	if draft != Draft201909 && draft != Draft202012 {
+		return doc, nil
+	}
+
+	translated, err := translateSchema(doc, draft)
+	if err != nil {
+		return nil, err
+	}
+
+	if m, ok := translated.(map[string]interface{}); ok {
+		m["$schema"] = draft7SchemaURL + "#"
+	}
+
+	return translated, nil
+}
+
+// isAbsoluteURI reports whether the reference is an absolute URI, the relative references are resolved
+// by the validator within the referencing schema
+func isAbsoluteURI(ref string) bool {
+	u, err := url.Parse(ref)
+
+	return err == nil && u.IsAbs()
+}
+
+// baseURL returns the URL without fragment
+func baseURL(ref string) string {
+	if i := strings.Index(ref, "#"); i >= 0 {
+		return ref[:i]
+	}
+
+	return ref
+}
@@ -0,0 +1,149 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package verifiable
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/cache"
+	"github.com/hyperledger/aries-framework-go/pkg/cache/mem"
+)
+
+// defaultSchemaCacheSize is the number of schemas cached by the default registry of NewCredential
+const defaultSchemaCacheSize = 100
+
+// CachingSchemaRegistry caches the schemas of a schema registry, typically the HTTP registry, so the schemas
+// are downloaded once rather than on every NewCredential. The schemas are kept in a cache.Cache, by default
+// a bounded in-memory cache evicting the least recently used schemas first. The simultaneous requests of the
+// same schema share a single download. The registry is safe for concurrent use and meant to be shared by the
+// goroutines decoding credentials.
+type CachingSchemaRegistry struct {
+	registry SchemaRegistry
+	cache    cache.Cache
+	ttl      time.Duration
+	lock     sync.Mutex
+	inflight map[string]*schemaCall
+	metrics  SchemaCacheMetrics
+}
+
+// SchemaCacheMetrics are the counters of the schema requests served by the cache
+type SchemaCacheMetrics struct {
+	// Hits is the number of schemas served from the cache
+	Hits uint64
+	// Misses is the number of schemas loaded from the registry
+	Misses uint64
+	// Shared is the number of requests which waited for the download of the same schema by another request
+	Shared uint64
+}
+
+// schemaCall is a schema download in progress
+type schemaCall struct {
+	done   chan struct{}
+	schema []byte
+	err    error
+}
+
+// NewCachingSchemaRegistry returns a registry caching up to size schemas of the registry in memory,
+// the schemas aren't cached if the size isn't positive
+func NewCachingSchemaRegistry(registry SchemaRegistry, size int) *CachingSchemaRegistry {
+	var schemas cache.Cache
+
+	if size > 0 {
+		// the in-memory caches can't fail to open
+		schemas, _ = mem.NewProvider(mem.WithMaxEntries(size)).OpenCache("schemas") // nolint: errcheck
+	}
+
+	return NewCachingSchemaRegistryWithCache(registry, schemas, 0)
+}
+
+// NewCachingSchemaRegistryWithCache returns a registry caching the schemas of the registry in the cache,
+// the cached schemas expire after the ttl unless the ttl is zero
+func NewCachingSchemaRegistryWithCache(registry SchemaRegistry, schemas cache.Cache,
+	ttl time.Duration) *CachingSchemaRegistry {
+	return &CachingSchemaRegistry{
+		registry: registry,
+		cache:    schemas,
+		ttl:      ttl,
+		inflight: make(map[string]*schemaCall),
+	}
+}
+
+// Get returns the cached schema or loads it from the registry, the failed loads are not cached.
+// The cache errors aren't returned: the schema is loaded from the registry if it can't be read from the cache.
+func (r *CachingSchemaRegistry) Get(id string) ([]byte, error) {
+	r.lock.Lock()
+
+	if r.cache != nil {
+		if schema, err := r.cache.Get(id); err == nil {
+			r.metrics.Hits++
+			r.lock.Unlock()
+
+			return schema, nil
+		}
+	}
+
+	if call, ok := r.inflight[id]; ok {
+		r.metrics.Shared++
+		r.lock.Unlock()
+
+		<-call.done
+
+		return call.schema, call.err
+	}
+
+	call := &schemaCall{done: make(chan struct{})}
+	r.inflight[id] = call
+	r.metrics.Misses++
+	r.lock.Unlock()
+
+	r.load(id, call)
+
+	return call.schema, call.err
+}
+
+// load loads the schema from the registry and releases the requests waiting for it, they are released with an
+// error if the registry panics
+func (r *CachingSchemaRegistry) load(id string, call *schemaCall) {
+	call.err = fmt.Errorf("failed to load schema %s", id)
+
+	defer func() {
+		r.lock.Lock()
+
+		if call.err == nil && r.cache != nil {
+			r.cache.Set(id, call.schema, r.ttl) // nolint: errcheck
+		}
+
+		delete(r.inflight, id)
+		r.lock.Unlock()
+		close(call.done)
+	}()
+
+	call.schema, call.err = r.registry.Get(id)
+}
+
+// Len returns the number of cached schemas, or -1 if the cache can't count its entries
+func (r *CachingSchemaRegistry) Len() int {
+	if r.cache == nil {
+		return 0
+	}
+
+	if counter, ok := r.cache.(interface{ Len() int }); ok {
+		return counter.Len()
+	}
+
+	return -1
+}
+
+// Metrics returns the counters of the schema requests
+func (r *CachingSchemaRegistry) Metrics() SchemaCacheMetrics {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return r.metrics
+}
@@ -0,0 +1,228 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package verifiable
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	cachespi "github.com/hyperledger/aries-framework-go/pkg/cache"
+	"github.com/hyperledger/aries-framework-go/pkg/cache/mem"
+)
+
+// countingRegistry counts the schema loads, the loads block until release is closed
+type countingRegistry struct {
+	loads   int32
+	release chan struct{}
+	err     error
+	panics  bool
+}
+
+func (r *countingRegistry) Get(id string) ([]byte, error) {
+	atomic.AddInt32(&r.loads, 1)
+
+	if r.release != nil {
+		<-r.release
+	}
+
+	if r.panics {
+		panic("registry failure")
+	}
+
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	return []byte(id), nil
+}
+
+func TestCachingSchemaRegistry(t *testing.T) {
+	t.Run("test cached schema", func(t *testing.T) {
+		registry := &countingRegistry{}
+		cache := NewCachingSchemaRegistry(registry, 10)
+
+		for i := 0; i < 3; i++ {
+			schema, err := cache.Get("urn:schema:1")
+			require.NoError(t, err)
+			require.Equal(t, []byte("urn:schema:1"), schema)
+		}
+
+		require.Equal(t, int32(1), registry.loads)
+		require.Equal(t, SchemaCacheMetrics{Hits: 2, Misses: 1}, cache.Metrics())
+		require.Equal(t, 1, cache.Len())
+	})
+
+	t.Run("test least recently used schema evicted", func(t *testing.T) {
+		registry := &countingRegistry{}
+		cache := NewCachingSchemaRegistry(registry, 2)
+
+		for _, id := range []string{"urn:schema:1", "urn:schema:2", "urn:schema:1", "urn:schema:3", "urn:schema:2"} {
+			_, err := cache.Get(id)
+			require.NoError(t, err)
+		}
+
+		require.Equal(t, int32(4), registry.loads)
+		require.Equal(t, 2, cache.Len())
+
+		_, err := cache.cache.Get("urn:schema:3")
+		require.NoError(t, err)
+		_, err = cache.cache.Get("urn:schema:1")
+		require.True(t, errors.Is(err, cachespi.ErrNotFound))
+	})
+
+	t.Run("test failed load not cached", func(t *testing.T) {
+		registry := &countingRegistry{err: ErrSchemaNotFound}
+		cache := NewCachingSchemaRegistry(registry, 10)
+
+		for i := 0; i < 2; i++ {
+			_, err := cache.Get("urn:schema:1")
+			require.True(t, errors.Is(err, ErrSchemaNotFound))
+		}
+
+		require.Equal(t, int32(2), registry.loads)
+		require.Equal(t, 0, cache.Len())
+	})
+
+	t.Run("test simultaneous requests share the download", func(t *testing.T) {
+		registry := &countingRegistry{release: make(chan struct{})}
+		cache := NewCachingSchemaRegistry(registry, 10)
+
+		const requests = 10
+
+		var wg sync.WaitGroup
+
+		wg.Add(requests)
+
+		for i := 0; i < requests; i++ {
+			go func() {
+				defer wg.Done()
+
+				schema, err := cache.Get("urn:schema:1")
+				require.NoError(t, err)
+				require.Equal(t, []byte("urn:schema:1"), schema)
+			}()
+		}
+
+		// wait for all the requests to be in flight or waiting for the download
+		for m := cache.Metrics(); m.Misses+m.Shared < requests; m = cache.Metrics() {
+			time.Sleep(time.Millisecond)
+		}
+
+		close(registry.release)
+		wg.Wait()
+
+		require.Equal(t, int32(1), registry.loads)
+		require.Equal(t, SchemaCacheMetrics{Misses: 1, Shared: requests - 1}, cache.Metrics())
+	})
+
+	t.Run("test waiting requests released if the registry panics", func(t *testing.T) {
+		registry := &countingRegistry{release: make(chan struct{}), panics: true}
+		cache := NewCachingSchemaRegistry(registry, 10)
+
+		panicked := make(chan interface{})
+
+		go func() {
+			defer func() {
+				panicked <- recover()
+			}()
+
+			_, _ = cache.Get("urn:schema:1") //nolint:errcheck
+		}()
+
+		for cache.Metrics().Misses == 0 {
+			time.Sleep(time.Millisecond)
+		}
+
+		waiter := make(chan error)
+
+		go func() {
+			_, err := cache.Get("urn:schema:1")
+			waiter <- err
+		}()
+
+		for cache.Metrics().Shared == 0 {
+			time.Sleep(time.Millisecond)
+		}
+
+		close(registry.release)
+
+		require.Equal(t, "registry failure", <-panicked)
+		require.EqualError(t, <-waiter, "failed to load schema urn:schema:1")
+		require.Equal(t, 0, cache.Len())
+	})
+
+	t.Run("test zero size disables the cache", func(t *testing.T) {
+		registry := &countingRegistry{}
+		cache := NewCachingSchemaRegistry(registry, 0)
+
+		for i := 0; i < 2; i++ {
+			_, err := cache.Get("urn:schema:1")
+			require.NoError(t, err)
+		}
+
+		require.Equal(t, int32(2), registry.loads)
+		require.Equal(t, 0, cache.Len())
+	})
+}
+
+func TestCachingSchemaRegistryWithCache(t *testing.T) {
+	schemas, err := mem.NewProvider().OpenCache("schemas")
+	require.NoError(t, err)
+
+	registry := &countingRegistry{}
+	cache := NewCachingSchemaRegistryWithCache(registry, &notCountingCache{Cache: schemas}, time.Hour)
+
+	for i := 0; i < 2; i++ {
+		schema, e := cache.Get("urn:schema:1")
+		require.NoError(t, e)
+		require.Equal(t, []byte("urn:schema:1"), schema)
+	}
+
+	require.Equal(t, int32(1), registry.loads)
+	require.Equal(t, -1, cache.Len())
+
+	schema, err := schemas.Get("urn:schema:1")
+	require.NoError(t, err)
+	require.Equal(t, []byte("urn:schema:1"), schema)
+}
+
+// notCountingCache hides the Len of the cache
+type notCountingCache struct {
+	cachespi.Cache
+}
+
+func TestNewCredentialWithCachingSchemaRegistry(t *testing.T) {
+	cache := NewCachingSchemaRegistry(NewEmbeddedSchemaRegistry(map[string][]byte{
+		"urn:schema:default": []byte(defaultSchema),
+	}), 10)
+
+	raw := `{"@context": ["https://www.w3.org/2018/credentials/v1"], "type": "VerifiableCredential",
+  "issuer": "did:example:1", "issuanceDate": "2010-01-01T19:23:24Z", "credentialSubject": {"id": "did:example:2"},
+  "credentialSchema": {"id": "urn:schema:default", "type": "JsonSchemaValidator2018"}}`
+
+	var wg sync.WaitGroup
+
+	wg.Add(5)
+
+	for i := 0; i < 5; i++ {
+		go func() {
+			defer wg.Done()
+
+			_, err := NewCredential([]byte(raw), WithSchemaRegistry(cache))
+			require.NoError(t, err)
+		}()
+	}
+
+	wg.Wait()
+
+	require.Equal(t, uint64(1), cache.Metrics().Misses)
+}
@@ -0,0 +1,100 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package verifiable
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/hyperledger/aries-framework-go/pkg/storage"
+)
+
+// SchemaRegistryNamespace is the store name space of the storage backed schema registry
+const SchemaRegistryNamespace = "credentialschema"
+
+// ErrSchemaNotFound is returned when the schema registry has no schema with the given ID
+var ErrSchemaNotFound = errors.New("credential schema not found")
+
+// SchemaRegistry is the source of the credential schemas and the schemas they reference. The registry
+// decides which schemas are trusted, e.g. a governance framework may restrict the schemas to a curated set.
+type SchemaRegistry interface {
+	// Get returns the JSON schema with the given ID
+	Get(id string) ([]byte, error)
+}
+
+// HTTPSchemaRegistry downloads the schemas from their ID URL, see CachingSchemaRegistry to cache the schemas
+type HTTPSchemaRegistry struct {
+	client *http.Client
+}
+
+// NewHTTPSchemaRegistry returns new HTTP schema registry downloading the schemas with the client
+func NewHTTPSchemaRegistry(client *http.Client) *HTTPSchemaRegistry {
+	return &HTTPSchemaRegistry{client: client}
+}
+
+// Get downloads the schema from the ID URL
+func (r *HTTPSchemaRegistry) Get(id string) ([]byte, error) {
+	return loadCredentialSchema(id, r.client)
+}
+
+// StoreSchemaRegistry serves the schemas saved in the store
+type StoreSchemaRegistry struct {
+	store storage.Store
+}
+
+// NewStoreSchemaRegistry returns new schema registry backed by the store of the storage provider
+func NewStoreSchemaRegistry(p storage.Provider) (*StoreSchemaRegistry, error) {
+	store, err := p.OpenStore(SchemaRegistryNamespace)
+	if err != nil {
+		return nil, fmt.Errorf("open schema registry store: %w", err)
+	}
+
+	return &StoreSchemaRegistry{store: store}, nil
+}
+
+// Put saves the schema with the given ID
+func (r *StoreSchemaRegistry) Put(id string, schema []byte) error {
+	return r.store.Put(id, schema)
+}
+
+// Get returns the saved schema with the given ID
+func (r *StoreSchemaRegistry) Get(id string) ([]byte, error) {
+	schema, err := r.store.Get(id)
+	if errors.Is(err, storage.ErrDataNotFound) {
+		return nil, ErrSchemaNotFound
+	}
+
+	return schema, err
+}
+
+// EmbeddedSchemaRegistry serves the schemas embedded in the application
+type EmbeddedSchemaRegistry struct {
+	schemas map[string][]byte
+}
+
+// NewEmbeddedSchemaRegistry returns new schema registry serving the given schemas by ID
+func NewEmbeddedSchemaRegistry(schemas map[string][]byte) *EmbeddedSchemaRegistry {
+	return &EmbeddedSchemaRegistry{schemas: schemas}
+}
+
+// WithEmbeddedSchemas option is for validating the credentials against the schemas registered by ID ahead of
+// time, e.g. in air-gapped deployments: the credentialSchema of JsonSchemaValidator2018 type and the schemas it
+// references are only loaded from the given schemas, never downloaded.
+func WithEmbeddedSchemas(schemas map[string][]byte) CredentialOpt {
+	return WithSchemaRegistry(NewEmbeddedSchemaRegistry(schemas))
+}
+
+// Get returns the embedded schema with the given ID
+func (r *EmbeddedSchemaRegistry) Get(id string) ([]byte, error) {
+	schema, ok := r.schemas[id]
+	if !ok {
+		return nil, ErrSchemaNotFound
+	}
+
+	return schema, nil
+}
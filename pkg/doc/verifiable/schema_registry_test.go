@@ -0,0 +1,164 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package verifiable
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	mockstorage "github.com/hyperledger/aries-framework-go/pkg/internal/mock/storage"
+)
+
+func TestHTTPSchemaRegistry(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/schema" {
+			res.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		_, err := res.Write([]byte(defaultSchema))
+		require.NoError(t, err)
+	}))
+
+	defer testServer.Close()
+
+	registry := NewHTTPSchemaRegistry(&http.Client{})
+
+	schema, err := registry.Get(testServer.URL + "/schema")
+	require.NoError(t, err)
+	require.Equal(t, []byte(defaultSchema), schema)
+
+	_, err = registry.Get(testServer.URL + "/missing")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "credential schema endpoint HTTP failure [404]")
+}
+
+func TestStoreSchemaRegistry(t *testing.T) {
+	t.Run("test put and get", func(t *testing.T) {
+		registry, err := NewStoreSchemaRegistry(mockstorage.NewMockStoreProvider())
+		require.NoError(t, err)
+
+		require.NoError(t, registry.Put("urn:schema:1", []byte(defaultSchema)))
+
+		schema, err := registry.Get("urn:schema:1")
+		require.NoError(t, err)
+		require.Equal(t, []byte(defaultSchema), schema)
+
+		_, err = registry.Get("urn:schema:2")
+		require.True(t, errors.Is(err, ErrSchemaNotFound))
+	})
+
+	t.Run("test open store error", func(t *testing.T) {
+		_, err := NewStoreSchemaRegistry(&mockstorage.MockStoreProvider{ErrOpenStoreHandle: errors.New("open error")})
+		require.EqualError(t, err, "open schema registry store: open error")
+	})
+}
+
+func TestEmbeddedSchemaRegistry(t *testing.T) {
+	registry := NewEmbeddedSchemaRegistry(map[string][]byte{"urn:schema:1": []byte(defaultSchema)})
+
+	schema, err := registry.Get("urn:schema:1")
+	require.NoError(t, err)
+	require.Equal(t, []byte(defaultSchema), schema)
+
+	_, err = registry.Get("urn:schema:2")
+	require.True(t, errors.Is(err, ErrSchemaNotFound))
+}
+
+func TestWithEmbeddedSchemas(t *testing.T) {
+	const schemaID = "https://example.com/schemas/reference.json"
+
+	schemas := map[string][]byte{
+		"https://example.com/schemas/credential.json": []byte(defaultSchema),
+		schemaID: []byte(`{
+  "allOf": [{"$ref": "https://example.com/schemas/credential.json"}],
+  "required": ["referenceNumber"]
+}`),
+	}
+
+	raw := make(map[string]interface{})
+	require.NoError(t, json.Unmarshal([]byte(validCredential), &raw))
+	raw["credentialSchema"] = map[string]interface{}{"id": schemaID, "type": jsonSchema2018Type}
+
+	vcBytes, err := json.Marshal(raw)
+	require.NoError(t, err)
+
+	t.Run("test schemas not downloaded", func(t *testing.T) {
+		_, err := NewCredential(vcBytes, WithEmbeddedSchemas(schemas))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "referenceNumber is required")
+
+		raw["referenceNumber"] = 83294847
+		valid, err := json.Marshal(raw)
+		require.NoError(t, err)
+
+		_, err = NewCredential(valid, WithEmbeddedSchemas(schemas))
+		require.NoError(t, err)
+	})
+
+	t.Run("test schema not embedded", func(t *testing.T) {
+		_, err := NewCredential(vcBytes, WithEmbeddedSchemas(nil))
+		require.True(t, errors.Is(err, ErrSchemaNotFound))
+	})
+}
+
+func TestNewCredentialWithSchemaRegistry(t *testing.T) {
+	registry := NewEmbeddedSchemaRegistry(map[string][]byte{
+		"urn:schema:default": []byte(defaultSchema),
+		"urn:schema:reference": []byte(`{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "$ref": "urn:schema:default",
+  "required": ["referenceNumber"]
+}`),
+		"urn:schema:invalid-ref": []byte(`{"$ref": "urn:schema:missing"}`),
+	})
+
+	newCredential := func(schemaID string, fields map[string]interface{}) error {
+		raw := make(map[string]interface{})
+		require.NoError(t, json.Unmarshal([]byte(validCredential), &raw))
+
+		raw["credentialSchema"] = map[string]interface{}{"id": schemaID, "type": jsonSchema2018Type}
+		for k, v := range fields {
+			raw[k] = v
+		}
+
+		bytes, err := json.Marshal(raw)
+		require.NoError(t, err)
+
+		_, err = NewCredential(bytes, WithSchemaRegistry(registry))
+
+		return err
+	}
+
+	t.Run("test schema and referenced schema loaded from registry", func(t *testing.T) {
+		require.NoError(t, newCredential("urn:schema:reference", map[string]interface{}{"referenceNumber": 83294847}))
+
+		err := newCredential("urn:schema:reference", map[string]interface{}{"issuer": nil})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "referenceNumber is required")
+		require.Contains(t, err.Error(), "issuer")
+	})
+
+	t.Run("test schema not found", func(t *testing.T) {
+		err := newCredential("urn:schema:missing", nil)
+		require.Error(t, err)
+		require.True(t, errors.Is(err, ErrSchemaNotFound))
+		require.Contains(t, err.Error(), "loading custom credential schema from urn:schema:missing failed")
+	})
+
+	t.Run("test referenced schema not found", func(t *testing.T) {
+		err := newCredential("urn:schema:invalid-ref", nil)
+		require.Error(t, err)
+		require.True(t, errors.Is(err, ErrSchemaNotFound))
+		require.Contains(t, err.Error(), "loading referenced schema from urn:schema:missing failed")
+	})
+}
@@ -0,0 +1,200 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package verifiable
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectDraft(t *testing.T) {
+	for schema, draft := range map[string]JSONSchemaDraft{
+		"http://json-schema.org/draft-04/schema#":       Draft4,
+		"http://json-schema.org/draft-06/schema#":       Draft6,
+		"http://json-schema.org/draft-07/schema":        Draft7,
+		"https://json-schema.org/draft/2019-09/schema":  Draft201909,
+		"https://json-schema.org/draft/2020-12/schema":  Draft202012,
+		"https://json-schema.org/draft/2020-12/schema#": Draft202012,
+		"https://example.com/schema":                    DetectDraft,
+	} {
+		require.Equal(t, draft, detectDraft(map[string]interface{}{"$schema": schema}), schema)
+	}
+
+	require.Equal(t, DetectDraft, detectDraft(map[string]interface{}{}))
+	require.Equal(t, DetectDraft, detectDraft(true))
+}
+
+func TestTranslateSchema(t *testing.T) {
+	translate := func(schema string, draft JSONSchemaDraft) (map[string]interface{}, error) {
+		var doc interface{}
+		require.NoError(t, json.Unmarshal([]byte(schema), &doc))
+
+		translated, err := translateSchema(doc, draft)
+		if err != nil {
+			return nil, err
+		}
+
+		return translated.(map[string]interface{}), nil
+	}
+
+	t.Run("test prefixItems and items", func(t *testing.T) {
+		schema, err := translate(`{"properties": {"a": {"prefixItems": [{"type": "string"}], "items": false}}}`,
+			Draft202012)
+		require.NoError(t, err)
+		require.Equal(t, map[string]interface{}{"items": []interface{}{map[string]interface{}{"type": "string"}},
+			"additionalItems": false}, schema["properties"].(map[string]interface{})["a"])
+
+		schema, err = translate(`{"items": [{"type": "string"}], "additionalItems": false}`, Draft201909)
+		require.NoError(t, err)
+		require.Equal(t, false, schema["additionalItems"])
+	})
+
+	t.Run("test dependentRequired and dependentSchemas", func(t *testing.T) {
+		schema, err := translate(`{"dependentRequired": {"a": ["b"]}, "dependentSchemas": {"c": {"required": ["d"]}}}`,
+			Draft202012)
+		require.NoError(t, err)
+		require.Equal(t, map[string]interface{}{
+			"a": []interface{}{"b"},
+			"c": map[string]interface{}{"required": []interface{}{"d"}},
+		}, schema["dependencies"])
+		require.NotContains(t, schema, "dependentRequired")
+		require.NotContains(t, schema, "dependentSchemas")
+	})
+
+	t.Run("test $ref with sibling keywords", func(t *testing.T) {
+		schema, err := translate(`{"$ref": "#/$defs/a", "required": ["b"], "allOf": [{"type": "object"}]}`,
+			Draft202012)
+		require.NoError(t, err)
+		require.NotContains(t, schema, "$ref")
+		require.Equal(t, []interface{}{map[string]interface{}{"type": "object"},
+			map[string]interface{}{"$ref": "#/$defs/a"}}, schema["allOf"])
+
+		schema, err = translate(`{"$ref": "#/$defs/a"}`, Draft202012)
+		require.NoError(t, err)
+		require.Equal(t, "#/$defs/a", schema["$ref"])
+	})
+
+	t.Run("test values are not translated", func(t *testing.T) {
+		schema, err := translate(`{"const": {"prefixItems": [], "unevaluatedProperties": false}}`, Draft202012)
+		require.NoError(t, err)
+		require.Equal(t, map[string]interface{}{"prefixItems": []interface{}{}, "unevaluatedProperties": false},
+			schema["const"])
+	})
+
+	t.Run("test unsupported keyword", func(t *testing.T) {
+		_, err := translate(`{"properties": {"a": {"unevaluatedProperties": false}}}`, Draft202012)
+		require.EqualError(t, err, "unsupported JSON Schema keyword: unevaluatedProperties")
+
+		_, err = translate(`{"anyOf": [{"$dynamicRef": "#meta"}]}`, Draft202012)
+		require.EqualError(t, err, "unsupported JSON Schema keyword: $dynamicRef")
+	})
+}
+
+func TestCustomCredentialSchemaDrafts(t *testing.T) {
+	mux := http.NewServeMux()
+	testServer := httptest.NewServer(mux)
+
+	defer testServer.Close()
+
+	serve := func(path, schema string) {
+		mux.HandleFunc(path, func(res http.ResponseWriter, req *http.Request) {
+			_, err := res.Write([]byte(schema))
+			require.NoError(t, err)
+		})
+	}
+
+	// the 2020-12 schema requires the fields of the default schema, referenced remotely,
+	// along with a reference number
+	serve("/default", defaultSchema)
+	serve("/2020-12", `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "$ref": "`+testServer.URL+`/default",
+  "required": ["referenceNumber"],
+  "properties": {
+    "referenceNumber": {"$ref": "#/$defs/referenceNumber"},
+    "referenceDate": {"format": "date-time"}
+  },
+  "dependentRequired": {"referenceNumber": ["referenceIssuer"]},
+  "$defs": {"referenceNumber": {"type": "integer"}}
+}`)
+	serve("/unsupported", `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "unevaluatedProperties": false
+}`)
+	serve("/invalid-ref", `{"$ref": "`+testServer.URL+`/missing"}`)
+
+	newCredential := func(schemaPath string, fields map[string]interface{}, opts ...CredentialOpt) error {
+		raw := make(map[string]interface{})
+		require.NoError(t, json.Unmarshal([]byte(validCredential), &raw))
+
+		raw["credentialSchema"] = map[string]interface{}{"id": testServer.URL + schemaPath, "type": jsonSchema2018Type}
+		for k, v := range fields {
+			raw[k] = v
+		}
+
+		bytes, err := json.Marshal(raw)
+		require.NoError(t, err)
+
+		_, err = NewCredential(bytes, append(opts, WithSchemaDownloadClient(&http.Client{}))...)
+
+		return err
+	}
+
+	t.Run("test valid credential", func(t *testing.T) {
+		require.NoError(t, newCredential("/2020-12", map[string]interface{}{
+			"referenceNumber": 83294847, "referenceIssuer": "did:example:123"}))
+	})
+
+	t.Run("test 2020-12 keywords validated", func(t *testing.T) {
+		err := newCredential("/2020-12", nil)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "referenceNumber is required")
+
+		err = newCredential("/2020-12", map[string]interface{}{"referenceNumber": "abc"})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "Invalid type")
+
+		err = newCredential("/2020-12", map[string]interface{}{"referenceNumber": 83294847})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "referenceIssuer")
+	})
+
+	t.Run("test remote $ref validated", func(t *testing.T) {
+		err := newCredential("/2020-12", map[string]interface{}{
+			"referenceNumber": 83294847, "referenceIssuer": "did:example:123", "issuer": nil})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "issuer")
+	})
+
+	t.Run("test format asserted", func(t *testing.T) {
+		err := newCredential("/2020-12", map[string]interface{}{
+			"referenceNumber": 83294847, "referenceIssuer": "did:example:123", "referenceDate": "yesterday"})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "date-time")
+	})
+
+	t.Run("test draft configured for the schema", func(t *testing.T) {
+		// validated as draft 7, the sibling keywords of $ref are ignored
+		require.NoError(t, newCredential("/2020-12", nil, WithJSONSchemaDraft(testServer.URL+"/2020-12", Draft7)))
+	})
+
+	t.Run("test unsupported keyword", func(t *testing.T) {
+		err := newCredential("/unsupported", nil)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unsupported JSON Schema keyword: unevaluatedProperties")
+	})
+
+	t.Run("test referenced schema not found", func(t *testing.T) {
+		err := newCredential("/invalid-ref", nil)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "loading referenced schema")
+	})
+}
@@ -0,0 +1,252 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package verifiable
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	// CredentialStatusList2017 is the type of the statuses published as status documents
+	CredentialStatusList2017 = "CredentialStatusList2017"
+	// RevocationList2020Status is the type of the statuses kept in a revocation list credential
+	RevocationList2020Status = "RevocationList2020Status"
+
+	revokedStatus = "Revoked"
+
+	// maxStatusSize is the maximum size in bytes of the fetched status documents and revocation list credentials
+	maxStatusSize = 1 << 20
+	// maxRevocationListSize is the maximum size in bytes of the decompressed revocation lists, 8M credentials
+	maxRevocationListSize = 1 << 20
+)
+
+// ErrCredentialRevoked is returned when the status of the credential says it's revoked
+var ErrCredentialRevoked = errors.New("verifiable credential is revoked")
+
+// StatusVerifier checks the statuses of the credentials, e.g. their revocation
+type StatusVerifier interface {
+	// Accept returns true if the verifier checks the statuses of the type
+	Accept(statusType string) bool
+	// Revoked returns true if the credential of the issuer referenced by the status was revoked
+	Revoked(status *CredentialStatus, issuerID string) (bool, error)
+}
+
+// WithStatusCheck option checks the status of the credentials with the first verifier accepting its type, the
+// credentials without status are accepted. The revoked credentials are rejected with ErrCredentialRevoked.
+func WithStatusCheck(verifiers ...StatusVerifier) CredentialOpt {
+	return func(opts *credentialOpts) {
+		opts.statusVerifiers = append(opts.statusVerifiers, verifiers...)
+	}
+}
+
+// checkStatus checks the status of the credential in JSON form with the status verifiers
+func checkStatus(vcData []byte, status *CredentialStatus, opts *credentialOpts) error {
+	if status == nil || len(opts.statusVerifiers) == 0 {
+		return nil
+	}
+
+	issuerID, _, err := issuerFromBytes(vcData)
+	if err != nil {
+		return err
+	}
+
+	for _, v := range opts.statusVerifiers {
+		if !v.Accept(status.Type) {
+			continue
+		}
+
+		revoked, e := v.Revoked(status, issuerID)
+		if e != nil {
+			return fmt.Errorf("check credential status %s: %w", status.ID, e)
+		}
+
+		if revoked {
+			return ErrCredentialRevoked
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("credential status type %s is not supported", status.Type)
+}
+
+// HTTPStatusVerifier fetches the statuses of the credentials with an HTTP client: the status documents of
+// CredentialStatusList2017 and the revocation list credentials of RevocationList2020Status. The revocation list
+// credentials must be issued by the issuer of the credential, their proofs are verified with the proof checker.
+type HTTPStatusVerifier struct {
+	client  *http.Client
+	checker *ProofChecker
+}
+
+// NewHTTPStatusVerifier returns new HTTP status verifier fetching the statuses with the client and verifying
+// the proofs of the revocation list credentials with the checker
+func NewHTTPStatusVerifier(client *http.Client, checker *ProofChecker) *HTTPStatusVerifier {
+	return &HTTPStatusVerifier{client: client, checker: checker}
+}
+
+// Accept accepts CredentialStatusList2017 and RevocationList2020Status
+func (v *HTTPStatusVerifier) Accept(statusType string) bool {
+	return statusType == CredentialStatusList2017 || statusType == RevocationList2020Status
+}
+
+// Revoked fetches the status and returns true if the credential of the issuer was revoked
+func (v *HTTPStatusVerifier) Revoked(status *CredentialStatus, issuerID string) (bool, error) {
+	switch status.Type {
+	case CredentialStatusList2017:
+		return v.revokedByStatusList(status)
+	case RevocationList2020Status:
+		return v.revokedByRevocationList(status, issuerID)
+	default:
+		return false, fmt.Errorf("credential status type %s is not supported", status.Type)
+	}
+}
+
+// revokedByStatusList reads the currentStatus of the status document
+func (v *HTTPStatusVerifier) revokedByStatusList(status *CredentialStatus) (bool, error) {
+	statusDoc := &struct {
+		CurrentStatus string `json:"currentStatus"`
+	}{}
+
+	statusBytes, err := v.get(status.ID)
+	if err != nil {
+		return false, err
+	}
+
+	if err = json.Unmarshal(statusBytes, statusDoc); err != nil {
+		return false, fmt.Errorf("JSON unmarshalling of credential status failed: %w", err)
+	}
+
+	return statusDoc.CurrentStatus == revokedStatus, nil
+}
+
+// revokedByRevocationList reads the bit of the credential in the encoded list of the revocation list credential
+func (v *HTTPStatusVerifier) revokedByRevocationList(status *CredentialStatus, issuerID string) (bool, error) {
+	index, err := strconv.Atoi(status.RevocationListIndex)
+	if err != nil || index < 0 {
+		return false, fmt.Errorf("invalid revocationListIndex %s", status.RevocationListIndex)
+	}
+
+	encodedList, err := v.revocationList(status.RevocationListCredential, issuerID)
+	if err != nil {
+		return false, err
+	}
+
+	list, err := decodeRevocationList(encodedList)
+	if err != nil {
+		return false, err
+	}
+
+	if index >= len(list)*8 {
+		return false, fmt.Errorf("revocationListIndex %d is out of the revocation list", index)
+	}
+
+	// the bits are ordered from the most significant bit of the first byte
+	return list[index/8]&(1<<(7-uint(index%8))) != 0, nil
+}
+
+// revocationList fetches the revocation list credential, verifies its proofs and its issuer and returns its
+// encoded list
+func (v *HTTPStatusVerifier) revocationList(url, issuerID string) (string, error) {
+	if v.checker == nil {
+		return "", errors.New("proof checker of the revocation list credentials is required")
+	}
+
+	vcBytes, err := v.get(url)
+	if err != nil {
+		return "", err
+	}
+
+	listVC, err := NewCredential(vcBytes, WithNoCustomSchemaCheck(), WithProofChecker(v.checker))
+	if err != nil {
+		return "", fmt.Errorf("revocation list credential: %w", err)
+	}
+
+	if listVC.Issuer.ID != issuerID {
+		return "", fmt.Errorf("revocation list credential issuer %s is not the credential issuer %s",
+			listVC.Issuer.ID, issuerID)
+	}
+
+	subject := &struct {
+		Subject struct {
+			EncodedList string `json:"encodedList"`
+		} `json:"credentialSubject"`
+	}{}
+
+	if err = json.Unmarshal(vcBytes, subject); err != nil {
+		return "", fmt.Errorf("JSON unmarshalling of revocation list credential failed: %w", err)
+	}
+
+	return subject.Subject.EncodedList, nil
+}
+
+// get fetches the document of the URL, up to maxStatusSize bytes
+func (v *HTTPStatusVerifier) get(url string) ([]byte, error) {
+	resp, err := v.client.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP request failed: %w", err)
+	}
+
+	defer func() {
+		if e := resp.Body.Close(); e != nil {
+			logger.Errorf("closing response body failed [%v]", e)
+		}
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("credential status endpoint HTTP failure [%v]", resp.StatusCode)
+	}
+
+	doc, err := readLimited(resp.Body, maxStatusSize)
+	if err != nil {
+		return nil, fmt.Errorf("read credential status: %w", err)
+	}
+
+	return doc, nil
+}
+
+// decodeRevocationList decodes the base64url encoded and GZIP compressed bitstring of the revocation list
+func decodeRevocationList(encodedList string) ([]byte, error) {
+	compressed, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(encodedList, "="))
+	if err != nil {
+		return nil, fmt.Errorf("decode revocation list: %w", err)
+	}
+
+	r, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, fmt.Errorf("decompress revocation list: %w", err)
+	}
+
+	list, err := readLimited(r, maxRevocationListSize)
+	if err != nil {
+		return nil, fmt.Errorf("decompress revocation list: %w", err)
+	}
+
+	return list, nil
+}
+
+// readLimited reads r up to maxSize bytes, an error is returned if r is larger
+func readLimited(r io.Reader, maxSize int64) ([]byte, error) {
+	data, err := ioutil.ReadAll(io.LimitReader(r, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(data)) > maxSize {
+		return nil, fmt.Errorf("exceeds the maximum size of %d bytes", maxSize)
+	}
+
+	return data, nil
+}
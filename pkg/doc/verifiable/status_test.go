@@ -0,0 +1,300 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package verifiable
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithStatusCheck(t *testing.T) {
+	issuer := newTestHolder(t)
+
+	// the credentials 1 and 10 of the revocation list are revoked
+	listVC := revocationListCredential(t, issuer, "", []byte{0x40, 0x20})
+	otherIssuerVC := revocationListCredential(t, issuer, "did:example:other", []byte{0x40, 0x20})
+
+	unsignedVC := &rawCredential{}
+	require.NoError(t, json.Unmarshal(listVC, unsignedVC))
+	unsignedVC.Proof = nil
+
+	unsigned, e := json.Marshal(unsignedVC)
+	require.NoError(t, e)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/status/24":
+			fmt.Fprint(w, `{"id": "https://example.edu/status/24", "currentStatus": "Suspended"}`)
+		case "/status/25":
+			fmt.Fprint(w, `{"id": "https://example.edu/status/25", "currentStatus": "Revoked"}`)
+		case "/revocation-list":
+			_, e := w.Write(listVC)
+			require.NoError(t, e)
+		case "/other-issuer":
+			_, e := w.Write(otherIssuerVC)
+			require.NoError(t, e)
+		case "/unsigned":
+			_, e := w.Write(unsigned)
+			require.NoError(t, e)
+		case "/large":
+			_, e := w.Write(bytes.Repeat([]byte(" "), maxStatusSize+1))
+			require.NoError(t, e)
+		case "/invalid":
+			fmt.Fprint(w, `not JSON`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	checker := NewProofChecker(staticKeyResolver(issuer.pub))
+	statusCheck := WithStatusCheck(NewHTTPStatusVerifier(server.Client(), checker))
+
+	credentialWithStatus := func(status *CredentialStatus) []byte {
+		raw := &rawCredential{}
+		require.NoError(t, json.Unmarshal([]byte(validCredential), &raw))
+		raw.Status = status
+
+		vcBytes, err := json.Marshal(raw)
+		require.NoError(t, err)
+
+		return vcBytes
+	}
+
+	t.Run("test CredentialStatusList2017", func(t *testing.T) {
+		vc, err := NewCredential(credentialWithStatus(&CredentialStatus{
+			ID: server.URL + "/status/24", Type: CredentialStatusList2017,
+		}), WithNoCustomSchemaCheck(), statusCheck)
+		require.NoError(t, err)
+		require.Equal(t, server.URL+"/status/24", vc.Status.ID)
+
+		_, err = NewCredential(credentialWithStatus(&CredentialStatus{
+			ID: server.URL + "/status/25", Type: CredentialStatusList2017,
+		}), WithNoCustomSchemaCheck(), statusCheck)
+		require.True(t, errors.Is(err, ErrCredentialRevoked))
+	})
+
+	t.Run("test RevocationList2020Status", func(t *testing.T) {
+		revocationListStatus := func(index string) *CredentialStatus {
+			return &CredentialStatus{
+				ID:                       server.URL + "/revocation-list#" + index,
+				Type:                     RevocationList2020Status,
+				RevocationListIndex:      index,
+				RevocationListCredential: server.URL + "/revocation-list",
+			}
+		}
+
+		for _, index := range []string{"0", "2", "9", "15"} {
+			vc, err := NewCredential(credentialWithStatus(revocationListStatus(index)),
+				WithNoCustomSchemaCheck(), statusCheck)
+			require.NoError(t, err)
+			require.Equal(t, index, vc.Status.RevocationListIndex)
+		}
+
+		for _, index := range []string{"1", "10"} {
+			_, err := NewCredential(credentialWithStatus(revocationListStatus(index)),
+				WithNoCustomSchemaCheck(), statusCheck)
+			require.True(t, errors.Is(err, ErrCredentialRevoked))
+		}
+
+		_, err := NewCredential(credentialWithStatus(revocationListStatus("16")),
+			WithNoCustomSchemaCheck(), statusCheck)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "revocationListIndex 16 is out of the revocation list")
+
+		_, err = NewCredential(credentialWithStatus(revocationListStatus("-1")),
+			WithNoCustomSchemaCheck(), statusCheck)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid revocationListIndex -1")
+	})
+
+	t.Run("test revocation list credential not trusted", func(t *testing.T) {
+		listStatus := func(path string) *CredentialStatus {
+			return &CredentialStatus{
+				ID:                       server.URL + path + "#0",
+				Type:                     RevocationList2020Status,
+				RevocationListIndex:      "0",
+				RevocationListCredential: server.URL + path,
+			}
+		}
+
+		_, err := NewCredential(credentialWithStatus(listStatus("/other-issuer")),
+			WithNoCustomSchemaCheck(), statusCheck)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "revocation list credential issuer did:example:other is not the credential issuer")
+
+		_, err = NewCredential(credentialWithStatus(listStatus("/unsigned")),
+			WithNoCustomSchemaCheck(), statusCheck)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "proof not found")
+
+		_, err = NewCredential(credentialWithStatus(listStatus("/revocation-list")),
+			WithNoCustomSchemaCheck(), WithStatusCheck(NewHTTPStatusVerifier(server.Client(),
+				NewProofChecker(staticKeyResolver(newTestHolder(t).pub)))))
+		require.Error(t, err)
+
+		verificationErr := &ProofVerificationError{}
+		require.True(t, errors.As(err, &verificationErr))
+
+		_, err = NewCredential(credentialWithStatus(listStatus("/revocation-list")),
+			WithNoCustomSchemaCheck(), WithStatusCheck(NewHTTPStatusVerifier(server.Client(), nil)))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "proof checker of the revocation list credentials is required")
+	})
+
+	t.Run("test credential without status", func(t *testing.T) {
+		raw := &rawCredential{}
+		require.NoError(t, json.Unmarshal([]byte(validCredential), &raw))
+		raw.Status = nil
+
+		vcBytes, err := json.Marshal(raw)
+		require.NoError(t, err)
+
+		_, err = NewCredential(vcBytes, WithNoCustomSchemaCheck(), statusCheck)
+		require.NoError(t, err)
+	})
+
+	t.Run("test status type not supported", func(t *testing.T) {
+		_, err := NewCredential(credentialWithStatus(&CredentialStatus{
+			ID: server.URL + "/status/24", Type: "CustomStatus",
+		}), WithNoCustomSchemaCheck(), statusCheck)
+		require.EqualError(t, err, "credential status type CustomStatus is not supported")
+	})
+
+	t.Run("test status not fetched", func(t *testing.T) {
+		_, err := NewCredential(credentialWithStatus(&CredentialStatus{
+			ID: server.URL + "/status/26", Type: CredentialStatusList2017,
+		}), WithNoCustomSchemaCheck(), statusCheck)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "credential status endpoint HTTP failure [404]")
+
+		_, err = NewCredential(credentialWithStatus(&CredentialStatus{
+			ID: server.URL + "/invalid", Type: CredentialStatusList2017,
+		}), WithNoCustomSchemaCheck(), statusCheck)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "JSON unmarshalling of credential status failed")
+
+		_, err = NewCredential(credentialWithStatus(&CredentialStatus{
+			ID: "http://localhost:1/status/24", Type: CredentialStatusList2017,
+		}), WithNoCustomSchemaCheck(), statusCheck)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "HTTP request failed")
+
+		_, err = NewCredential(credentialWithStatus(&CredentialStatus{
+			ID: server.URL + "/large", Type: CredentialStatusList2017,
+		}), WithNoCustomSchemaCheck(), statusCheck)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "exceeds the maximum size")
+	})
+
+	t.Run("test custom status verifier", func(t *testing.T) {
+		verifier := &mockStatusVerifier{statusType: "CustomStatus", revoked: true}
+
+		_, err := NewCredential(credentialWithStatus(&CredentialStatus{
+			ID: "https://example.edu/status/24", Type: "CustomStatus",
+		}), WithNoCustomSchemaCheck(), statusCheck, WithStatusCheck(verifier))
+		require.True(t, errors.Is(err, ErrCredentialRevoked))
+
+		verifier.err = errors.New("status unavailable")
+
+		_, err = NewCredential(credentialWithStatus(&CredentialStatus{
+			ID: "https://example.edu/status/24", Type: "CustomStatus",
+		}), WithNoCustomSchemaCheck(), WithStatusCheck(verifier))
+		require.EqualError(t, err, "check credential status https://example.edu/status/24: status unavailable")
+	})
+}
+
+func TestDecodeRevocationList(t *testing.T) {
+	t.Run("test padded encoding", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		w := gzip.NewWriter(&buf)
+		_, err := w.Write([]byte{0x01})
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+
+		list, err := decodeRevocationList(base64.URLEncoding.EncodeToString(buf.Bytes()))
+		require.NoError(t, err)
+		require.Equal(t, []byte{0x01}, list)
+	})
+
+	t.Run("test invalid encoded list", func(t *testing.T) {
+		_, err := decodeRevocationList("not base64!")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "decode revocation list")
+
+		_, err = decodeRevocationList(base64.RawURLEncoding.EncodeToString([]byte("not gzip")))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "decompress revocation list")
+	})
+
+	t.Run("test decompressed list too large", func(t *testing.T) {
+		_, err := decodeRevocationList(encodeRevocationList(t, make([]byte, maxRevocationListSize+1)))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "exceeds the maximum size")
+	})
+}
+
+func encodeRevocationList(t *testing.T, list []byte) string {
+	var buf bytes.Buffer
+
+	w := gzip.NewWriter(&buf)
+	_, err := w.Write(list)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	return base64.RawURLEncoding.EncodeToString(buf.Bytes())
+}
+
+// revocationListCredential returns the revocation list credential of the list signed by the holder, the issuer
+// of validCredential is the issuer of the list if issuerID is empty
+func revocationListCredential(t *testing.T, signer *testHolder, issuerID string, list []byte) []byte {
+	vc, err := NewCredential([]byte(validCredential), WithNoCustomSchemaCheck())
+	require.NoError(t, err)
+
+	if issuerID != "" {
+		vc.Issuer.ID = issuerID
+	}
+
+	vc.ID = "https://example.edu/revocation-list"
+	vc.Subject = map[string]interface{}{
+		"id":          "https://example.edu/revocation-list#list",
+		"type":        "RevocationList2020",
+		"encodedList": encodeRevocationList(t, list),
+	}
+	vc.Status = nil
+	vc.Proof = nil
+
+	require.NoError(t, vc.GenerateProof(signer, &ProofOptions{Creator: vc.Issuer.ID + "#keys-1"}))
+
+	vcBytes, err := vc.MarshalJSON()
+	require.NoError(t, err)
+
+	return vcBytes
+}
+
+type mockStatusVerifier struct {
+	statusType string
+	revoked    bool
+	err        error
+}
+
+func (v *mockStatusVerifier) Accept(statusType string) bool {
+	return statusType == v.statusType
+}
+
+func (v *mockStatusVerifier) Revoked(*CredentialStatus, string) (bool, error) {
+	return v.revoked, v.err
+}
@@ -0,0 +1,46 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package verifiable
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// SubjectDecoder decodes the credentialSubject in JSON form into the Subject of the credential, e.g. into a
+// strongly typed struct
+type SubjectDecoder func(subject json.RawMessage) (interface{}, error)
+
+// WithSubjectDecoder option decodes the credentialSubject of the credential with the decoder, instead of
+// leaving it as generic JSON values. The typed subjects are then returned by DecodeSubject as is.
+func WithSubjectDecoder(decoder SubjectDecoder) CredentialOpt {
+	return func(opts *credentialOpts) {
+		opts.subjectDecoder = decoder
+	}
+}
+
+// decodeSubject returns the Subject decoded from the credentialSubject JSON by the subject decoder of the
+// options, the generic Subject is returned if there is no decoder
+func decodeSubject(vcData []byte, subject Subject, opts *credentialOpts) (Subject, error) {
+	if opts.subjectDecoder == nil || subject == nil {
+		return subject, nil
+	}
+
+	raw := &struct {
+		Subject json.RawMessage `json:"credentialSubject"`
+	}{}
+
+	if err := json.Unmarshal(vcData, raw); err != nil {
+		return nil, fmt.Errorf("JSON unmarshalling of credential subject failed: %w", err)
+	}
+
+	decoded, err := opts.subjectDecoder(raw.Subject)
+	if err != nil {
+		return nil, fmt.Errorf("decode credential subject: %w", err)
+	}
+
+	return decoded, nil
+}
@@ -0,0 +1,92 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package verifiable
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithSubjectDecoder(t *testing.T) {
+	degreeDecoder := func(subject json.RawMessage) (interface{}, error) {
+		degree := &UniversityDegreeSubject{}
+		err := json.Unmarshal(subject, degree)
+
+		return degree, err
+	}
+
+	t.Run("test typed subject", func(t *testing.T) {
+		vc, err := NewCredential([]byte(validCredential), WithSubjectDecoder(degreeDecoder))
+		require.NoError(t, err)
+
+		subject, ok := vc.Subject.(*UniversityDegreeSubject)
+		require.True(t, ok)
+		require.Equal(t, "did:example:ebfeb1f712ebc6f1c276e12ec21", subject.ID)
+		require.Equal(t, "BachelorDegree", subject.Degree.Type)
+
+		// the typed subject is returned as is
+		decoded := &UniversityDegreeSubject{}
+		require.NoError(t, vc.DecodeSubject(decoded))
+		require.Equal(t, subject, decoded)
+
+		subjectID, err := vc.SubjectID()
+		require.NoError(t, err)
+		require.Equal(t, subject.ID, subjectID)
+
+		// the typed subject is marshalled back
+		vcBytes, err := vc.MarshalJSON()
+		require.NoError(t, err)
+
+		vc, err = NewCredential(vcBytes)
+		require.NoError(t, err)
+		require.NoError(t, vc.DecodeSubject(decoded))
+		require.Equal(t, subject, decoded)
+	})
+
+	t.Run("test raw subject", func(t *testing.T) {
+		vc, err := NewCredential([]byte(validCredential),
+			WithSubjectDecoder(func(subject json.RawMessage) (interface{}, error) {
+				return subject, nil
+			}))
+		require.NoError(t, err)
+		require.IsType(t, json.RawMessage{}, vc.Subject)
+
+		degree := UniversityDegreeSubject{}
+		require.NoError(t, vc.DecodeSubject(&degree))
+		require.Equal(t, "Jayden Doe", degree.Name)
+
+		subjectID, err := vc.SubjectID()
+		require.NoError(t, err)
+		require.Equal(t, degree.ID, subjectID)
+	})
+
+	t.Run("test subject decoder error", func(t *testing.T) {
+		_, err := NewCredential([]byte(validCredential),
+			WithSubjectDecoder(func(json.RawMessage) (interface{}, error) {
+				return nil, errors.New("unsupported subject")
+			}))
+		require.EqualError(t, err, "decode credential subject: unsupported subject")
+	})
+}
+
+func TestCredential_SubjectID_TypedSubject(t *testing.T) {
+	t.Run("test subject without id", func(t *testing.T) {
+		vc := &Credential{Subject: &UniversityDegreeSubject{Name: "Jayden Doe"}}
+
+		_, err := vc.SubjectID()
+		require.EqualError(t, err, "subject id is not defined")
+	})
+
+	t.Run("test subject not an object", func(t *testing.T) {
+		vc := &Credential{Subject: "did:example:ebfeb1f712ebc6f1c276e12ec21"}
+
+		_, err := vc.SubjectID()
+		require.EqualError(t, err, "subject of unknown structure")
+	})
+}
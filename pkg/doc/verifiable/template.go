@@ -0,0 +1,150 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package verifiable
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+const vcType = "VerifiableCredential"
+
+// IssuanceTemplate defines once the credentials issued many times by an issuer, e.g. the degrees of a university,
+// the credentials are then issued by supplying the subject data only
+type IssuanceTemplate struct {
+	// Context are the JSON-LD contexts of the credentials in addition to the base context
+	Context []string
+	// Types are the types of the credentials in addition to VerifiableCredential
+	Types []string
+	// Schemas are the credential schemas the credentials are validated against
+	Schemas []CredentialSchema
+	Issuer  Issuer
+	// SubjectFields maps the fields of the subject data to the paths of the credentialSubject fields, the nested
+	// fields being separated by dots, e.g. "degreeName" to "degree.name". The subject data can only contain the
+	// mapped fields, or any field if no field is mapped.
+	SubjectFields map[string]string
+	// RequiredFields are the fields the subject data must contain
+	RequiredFields []string
+	// Validity is the validity period of the credentials, the credentials don't expire if zero
+	Validity time.Duration
+}
+
+// Issue creates the credential of the subject from the subject data, the credential is validated against the
+// template and against its schemas, the schemas are loaded using the options. The credential is issued at the
+// current time of the clock of the options and must then be signed, e.g. with GenerateProof.
+func (t *IssuanceTemplate) Issue(subjectID string, data map[string]interface{},
+	opts ...CredentialOpt) (*Credential, error) {
+	if t.Issuer.ID == "" {
+		return nil, errors.New("issuer of the credential template is not defined")
+	}
+
+	subject, err := t.subject(subjectID, data)
+	if err != nil {
+		return nil, err
+	}
+
+	crOpts := defaultCredentialOpts()
+	for _, opt := range opts {
+		opt(crOpts)
+	}
+
+	issued := crOpts.clock.Now().UTC().Truncate(time.Second)
+
+	vc := &Credential{
+		Context: []interface{}{vcContext},
+		Type:    append([]string{vcType}, t.Types...),
+		Subject: subject,
+		Issuer:  t.Issuer,
+		Issued:  &issued,
+		Schemas: t.Schemas,
+	}
+
+	for _, c := range t.Context {
+		vc.Context = append(vc.Context, c)
+	}
+
+	if t.Validity > 0 {
+		expired := issued.Add(t.Validity)
+		vc.Expired = &expired
+	}
+
+	vcBytes, err := vc.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validate(vcBytes, vc.Schemas, crOpts); err != nil {
+		return nil, err
+	}
+
+	return vc, nil
+}
+
+// subject maps the subject data to the credentialSubject
+func (t *IssuanceTemplate) subject(subjectID string, data map[string]interface{}) (map[string]interface{}, error) {
+	for _, field := range t.RequiredFields {
+		if data[field] == nil {
+			return nil, fmt.Errorf("required subject field %s is missing", field)
+		}
+	}
+
+	subject := make(map[string]interface{})
+	if subjectID != "" {
+		subject["id"] = subjectID
+	}
+
+	// the fields are mapped in order so the conflicting paths are reported consistently
+	fields := make([]string, 0, len(data))
+	for field := range data {
+		fields = append(fields, field)
+	}
+
+	sort.Strings(fields)
+
+	for _, field := range fields {
+		path := field
+
+		if len(t.SubjectFields) > 0 {
+			var ok bool
+			if path, ok = t.SubjectFields[field]; !ok {
+				return nil, fmt.Errorf("subject field %s is not defined by the credential template", field)
+			}
+		}
+
+		if err := setField(subject, strings.Split(path, "."), data[field]); err != nil {
+			return nil, fmt.Errorf("subject field %s: %w", field, err)
+		}
+	}
+
+	return subject, nil
+}
+
+// setField sets the value of the nested field, creating its parent objects
+func setField(object map[string]interface{}, path []string, value interface{}) error {
+	for _, name := range path[:len(path)-1] {
+		child, ok := object[name]
+		if !ok {
+			child = make(map[string]interface{})
+			object[name] = child
+		}
+
+		if object, ok = child.(map[string]interface{}); !ok {
+			return fmt.Errorf("%s is not an object", name)
+		}
+	}
+
+	name := path[len(path)-1]
+	if _, ok := object[name]; ok {
+		return fmt.Errorf("%s is already set", name)
+	}
+
+	object[name] = value
+
+	return nil
+}
@@ -0,0 +1,169 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package verifiable
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	mockclock "github.com/hyperledger/aries-framework-go/pkg/internal/mock/clock"
+)
+
+const degreeSchemaID = "https://example.edu/schemas/degree.json"
+
+const degreeSchema = `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "type": "object",
+  "required": ["credentialSubject"],
+  "properties": {
+    "credentialSubject": {
+      "type": "object",
+      "required": ["id", "degree"],
+      "properties": {
+        "degree": {
+          "type": "object",
+          "required": ["type", "name"],
+          "properties": {
+            "type": {"type": "string", "enum": ["BachelorDegree", "MasterDegree"]},
+            "name": {"type": "string"}
+          }
+        }
+      }
+    }
+  }
+}`
+
+func TestIssuanceTemplate_Issue(t *testing.T) {
+	template := &IssuanceTemplate{
+		Context: []string{"https://www.w3.org/2018/credentials/examples/v1"},
+		Types:   []string{"UniversityDegreeCredential"},
+		Schemas: []CredentialSchema{{ID: degreeSchemaID, Type: jsonSchema2018Type}},
+		Issuer:  Issuer{ID: "did:example:76e12ec712ebc6f1c221ebfeb1f", Name: "Example University"},
+		SubjectFields: map[string]string{
+			"name":       "name",
+			"degreeType": "degree.type",
+			"degreeName": "degree.name",
+		},
+		RequiredFields: []string{"degreeType"},
+		Validity:       24 * time.Hour,
+	}
+	registry := WithSchemaRegistry(NewEmbeddedSchemaRegistry(map[string][]byte{degreeSchemaID: []byte(degreeSchema)}))
+
+	t.Run("test issue", func(t *testing.T) {
+		vc, err := template.Issue("did:example:ebfeb1f712ebc6f1c276e12ec21", map[string]interface{}{
+			"name":       "Jayden Doe",
+			"degreeType": "BachelorDegree",
+			"degreeName": "Bachelor of Science and Arts",
+		}, registry)
+		require.NoError(t, err)
+
+		require.Equal(t, []interface{}{vcContext, "https://www.w3.org/2018/credentials/examples/v1"}, vc.Context)
+		require.Equal(t, []string{vcType, "UniversityDegreeCredential"}, vc.Types())
+		require.Equal(t, template.Issuer, vc.Issuer)
+		require.Equal(t, template.Schemas, vc.Schemas)
+		require.Equal(t, vc.Issued.Add(24*time.Hour), *vc.Expired)
+		require.Equal(t, map[string]interface{}{
+			"id":   "did:example:ebfeb1f712ebc6f1c276e12ec21",
+			"name": "Jayden Doe",
+			"degree": map[string]interface{}{
+				"type": "BachelorDegree",
+				"name": "Bachelor of Science and Arts",
+			},
+		}, vc.Subject)
+
+		// the issued credential can be signed and decoded
+		vcBytes, err := vc.MarshalJSON()
+		require.NoError(t, err)
+
+		decoded, err := NewCredential(vcBytes, registry)
+		require.NoError(t, err)
+		require.Equal(t, vc.Issued, decoded.Issued)
+	})
+
+	t.Run("test issue with the clock", func(t *testing.T) {
+		now := time.Date(2020, time.January, 1, 12, 0, 0, 500, time.UTC)
+
+		vc, err := template.Issue("did:example:ebfeb1f712ebc6f1c276e12ec21", map[string]interface{}{
+			"degreeType": "BachelorDegree",
+			"degreeName": "Bachelor of Science and Arts",
+		}, registry, WithClock(&mockclock.Clock{Time: now}))
+		require.NoError(t, err)
+		require.Equal(t, now.Truncate(time.Second), *vc.Issued)
+		require.Equal(t, now.Truncate(time.Second).Add(24*time.Hour), *vc.Expired)
+	})
+
+	t.Run("test subject data not valid against the template", func(t *testing.T) {
+		_, err := template.Issue("did:example:ebfeb1f712ebc6f1c276e12ec21", map[string]interface{}{
+			"degreeName": "Bachelor of Science and Arts",
+		}, registry)
+		require.EqualError(t, err, "required subject field degreeType is missing")
+
+		_, err = template.Issue("did:example:ebfeb1f712ebc6f1c276e12ec21", map[string]interface{}{
+			"degreeType": "BachelorDegree",
+			"spouse":     "did:example:c276e12ec21ebfeb1f712ebc6f1",
+		}, registry)
+		require.EqualError(t, err, "subject field spouse is not defined by the credential template")
+	})
+
+	t.Run("test subject data not valid against the schema", func(t *testing.T) {
+		_, err := template.Issue("did:example:ebfeb1f712ebc6f1c276e12ec21", map[string]interface{}{
+			"degreeType": "PhD",
+			"degreeName": "Doctor of Philosophy",
+		}, registry)
+		require.Error(t, err)
+
+		validationErr := &ValidationError{}
+		require.True(t, errors.As(err, &validationErr))
+		require.Equal(t, "credentialSubject.degree.type", validationErr.Violations[0].Field)
+
+		_, err = template.Issue("", map[string]interface{}{
+			"degreeType": "BachelorDegree",
+			"degreeName": "Bachelor of Science and Arts",
+		}, registry)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "credentialSubject: id is required")
+	})
+
+	t.Run("test conflicting subject fields", func(t *testing.T) {
+		conflicting := &IssuanceTemplate{Issuer: template.Issuer}
+
+		_, err := conflicting.Issue("did:example:ebfeb1f712ebc6f1c276e12ec21", map[string]interface{}{
+			"degree":      "BachelorDegree",
+			"degree.name": "Bachelor of Science and Arts",
+		}, WithNoCustomSchemaCheck())
+		require.EqualError(t, err, "subject field degree.name: degree is not an object")
+
+		conflicting.SubjectFields = map[string]string{"name": "name", "fullName": "name"}
+
+		_, err = conflicting.Issue("did:example:ebfeb1f712ebc6f1c276e12ec21", map[string]interface{}{
+			"name":     "Jayden",
+			"fullName": "Jayden Doe",
+		}, WithNoCustomSchemaCheck())
+		require.EqualError(t, err, "subject field name: name is already set")
+	})
+
+	t.Run("test template without schemas", func(t *testing.T) {
+		noSchemas := *template
+		noSchemas.Schemas = nil
+
+		vc, err := noSchemas.Issue("did:example:ebfeb1f712ebc6f1c276e12ec21", map[string]interface{}{
+			"degreeType": "BachelorDegree",
+		})
+		require.NoError(t, err)
+
+		vcBytes, err := vc.MarshalJSON()
+		require.NoError(t, err)
+		require.NotContains(t, string(vcBytes), "credentialSchema")
+	})
+
+	t.Run("test template without issuer", func(t *testing.T) {
+		_, err := (&IssuanceTemplate{}).Issue("did:example:ebfeb1f712ebc6f1c276e12ec21", nil)
+		require.EqualError(t, err, "issuer of the credential template is not defined")
+	})
+}
@@ -0,0 +1,198 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package verifiable
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Evidence is an evidence of the claims of the Verifiable Credential, e.g. the verification of a document of
+// the subject by the issuer
+type Evidence struct {
+	ID    string
+	Types []string
+	// CustomFields are the fields of the evidence besides id and type, e.g. the verifier or the evidenceDocument
+	CustomFields CustomFields
+}
+
+// TermsOfUse represents terms of use of Verifiable Credential by Issuer or Verifiable Presentation by Holder.
+type TermsOfUse struct {
+	ID    string
+	Types []string
+	// CustomFields are the fields of the terms besides id and type, e.g. the profile or the prohibitions
+	CustomFields CustomFields
+}
+
+// rawTypedObject is the JSON of the evidences and the terms of use
+type rawTypedObject struct {
+	ID   string      `json:"id,omitempty"`
+	Type interface{} `json:"type,omitempty"`
+}
+
+//nolint:gochecknoglobals
+var modeledTypedObjectFields = []string{"id", "type"}
+
+// MarshalJSON marshals the evidence with its custom fields, a single type is marshalled as a string
+func (e Evidence) MarshalJSON() ([]byte, error) {
+	return marshalWithCustomFields(&rawTypedObject{ID: e.ID, Type: encodeTypes(e.Types)}, e.CustomFields,
+		modeledTypedObjectFields)
+}
+
+// UnmarshalJSON unmarshals the evidence, its type is required
+func (e *Evidence) UnmarshalJSON(data []byte) error {
+	raw, custom, err := unmarshalTypedObject(data)
+	if err != nil {
+		return fmt.Errorf("evidence: %w", err)
+	}
+
+	types, err := decodeTypes(raw.Type)
+	if err != nil {
+		return fmt.Errorf("evidence: %w", err)
+	}
+
+	e.ID = raw.ID
+	e.Types = types
+	e.CustomFields = custom
+
+	return nil
+}
+
+// MarshalJSON marshals the terms of use with their custom fields, a single type is marshalled as a string
+func (t TermsOfUse) MarshalJSON() ([]byte, error) {
+	return marshalWithCustomFields(&rawTypedObject{ID: t.ID, Type: encodeTypes(t.Types)}, t.CustomFields,
+		modeledTypedObjectFields)
+}
+
+// UnmarshalJSON unmarshals the terms of use, their type is required
+func (t *TermsOfUse) UnmarshalJSON(data []byte) error {
+	raw, custom, err := unmarshalTypedObject(data)
+	if err != nil {
+		return fmt.Errorf("terms of use: %w", err)
+	}
+
+	types, err := decodeTypes(raw.Type)
+	if err != nil {
+		return fmt.Errorf("terms of use: %w", err)
+	}
+
+	t.ID = raw.ID
+	t.Types = types
+	t.CustomFields = custom
+
+	return nil
+}
+
+// encodeTypes returns the type to marshal, a single type as a string
+func encodeTypes(types []string) interface{} {
+	switch len(types) {
+	case 0:
+		return nil
+	case 1:
+		return types[0]
+	default:
+		return types
+	}
+}
+
+// decodeTypes returns the types of the decoded type, a string or an array of strings
+func decodeTypes(decoded interface{}) ([]string, error) {
+	switch t := decoded.(type) {
+	case string:
+		return []string{t}, nil
+	case []interface{}:
+		types := make([]string, len(t))
+
+		for i, v := range t {
+			s, ok := v.(string)
+			if !ok {
+				return nil, errors.New("type is not a string")
+			}
+
+			types[i] = s
+		}
+
+		return types, nil
+	default:
+		return nil, errors.New("type is not a string or an array of strings")
+	}
+}
+
+func unmarshalTypedObject(data []byte) (*rawTypedObject, CustomFields, error) {
+	raw := &rawTypedObject{}
+	if err := json.Unmarshal(data, raw); err != nil {
+		return nil, nil, err
+	}
+
+	if raw.Type == nil {
+		return nil, nil, errors.New("type is required")
+	}
+
+	custom, err := decodeCustomFields(data, modeledTypedObjectFields)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return raw, custom, nil
+}
+
+// termsOfUseList is the termsOfUse of the credential JSON, a single object or an array
+type termsOfUseList []TermsOfUse
+
+func (l *termsOfUseList) UnmarshalJSON(data []byte) error {
+	if isJSONArray(data) {
+		return json.Unmarshal(data, (*[]TermsOfUse)(l))
+	}
+
+	single := TermsOfUse{}
+	if err := json.Unmarshal(data, &single); err != nil {
+		return err
+	}
+
+	*l = termsOfUseList{single}
+
+	return nil
+}
+
+// evidenceList is the evidence of the credential JSON, a single object or an array. A single evidence decoded
+// from an object is marshalled back as an object.
+type evidenceList struct {
+	evidences []Evidence
+	single    bool
+}
+
+func (l *evidenceList) MarshalJSON() ([]byte, error) {
+	if l.single && len(l.evidences) == 1 {
+		return json.Marshal(l.evidences[0])
+	}
+
+	return json.Marshal(l.evidences)
+}
+
+func (l *evidenceList) UnmarshalJSON(data []byte) error {
+	if isJSONArray(data) {
+		l.single = false
+		return json.Unmarshal(data, &l.evidences)
+	}
+
+	single := Evidence{}
+	if err := json.Unmarshal(data, &single); err != nil {
+		return err
+	}
+
+	l.evidences = []Evidence{single}
+	l.single = true
+
+	return nil
+}
+
+func isJSONArray(data []byte) bool {
+	data = bytes.TrimSpace(data)
+	return len(data) > 0 && data[0] == '['
+}
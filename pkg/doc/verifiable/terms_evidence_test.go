@@ -0,0 +1,125 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package verifiable
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCredential_Evidence(t *testing.T) {
+	t.Run("test evidences", func(t *testing.T) {
+		vc, err := NewCredential([]byte(validCredential))
+		require.NoError(t, err)
+
+		require.Len(t, vc.Evidence, 2)
+		require.Equal(t, "https://example.edu/evidence/f2aeec97-fc0d-42bf-8ca7-0548192d4231", vc.Evidence[0].ID)
+		require.Equal(t, []string{"DocumentVerification"}, vc.Evidence[0].Types)
+		require.Equal(t, "DriversLicense", vc.Evidence[0].CustomFields["evidenceDocument"])
+		require.Equal(t, []string{"SupportingActivity"}, vc.Evidence[1].Types)
+	})
+
+	t.Run("test single evidence", func(t *testing.T) {
+		vc, err := NewCredential([]byte(jsonldCredential[:len(jsonldCredential)-1] +
+			`,"evidence": {"type": "DocumentVerification", "verifier": "did:v"}}`), WithNoCustomSchemaCheck())
+		require.NoError(t, err)
+		require.Equal(t, []Evidence{{Types: []string{"DocumentVerification"},
+			CustomFields: CustomFields{"verifier": "did:v"}}}, vc.Evidence)
+
+		// the single evidence is marshalled back as an object
+		vcBytes, err := vc.MarshalJSON()
+		require.NoError(t, err)
+		require.Contains(t, string(vcBytes), `"evidence":{"type":"DocumentVerification","verifier":"did:v"}`)
+
+		vc.singleEvidence = false
+		vcBytes, err = vc.MarshalJSON()
+		require.NoError(t, err)
+		require.Contains(t, string(vcBytes), `"evidence":[{"type":"DocumentVerification","verifier":"did:v"}]`)
+
+		vcBytes, err = (&Credential{}).MarshalJSON()
+		require.NoError(t, err)
+		require.NotContains(t, string(vcBytes), "evidence")
+	})
+
+	t.Run("test evidence marshalling", func(t *testing.T) {
+		data, err := json.Marshal(Evidence{ID: "https://example.edu/evidence/1",
+			Types: []string{"DocumentVerification", "Supporting"}, CustomFields: CustomFields{"verifier": "did:v"}})
+		require.NoError(t, err)
+		require.JSONEq(t, `{"id": "https://example.edu/evidence/1", "type": ["DocumentVerification", "Supporting"],
+			"verifier": "did:v"}`, string(data))
+
+		data, err = json.Marshal(Evidence{Types: []string{"DocumentVerification"}})
+		require.NoError(t, err)
+		require.JSONEq(t, `{"type": "DocumentVerification"}`, string(data))
+	})
+
+	t.Run("test invalid evidences", func(t *testing.T) {
+		for _, evidence := range []string{
+			`{"id": "https://example.edu/evidence/1"}`,
+			`[{"type": 1}]`,
+			`{"type": ["DocumentVerification", 1]}`,
+			`"evidence"`,
+		} {
+			require.Error(t, json.Unmarshal([]byte(evidence), &evidenceList{}))
+		}
+
+		vcData := strings.Replace(validCredential, `"type": ["DocumentVerification"],`, "", 1)
+		_, err := NewCredential([]byte(vcData))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "evidence: type is required")
+	})
+}
+
+func TestCredential_TermsOfUse(t *testing.T) {
+	t.Run("test custom fields of terms of use", func(t *testing.T) {
+		vc, err := NewCredential([]byte(validCredential))
+		require.NoError(t, err)
+		require.Len(t, vc.TermsOfUse, 1)
+
+		terms := vc.TermsOfUse[0]
+		require.Equal(t, []string{"IssuerPolicy"}, terms.Types)
+		require.Equal(t, "http://example.com/policies/credential/4", terms.ID)
+		require.Equal(t, "http://example.com/profiles/credential", terms.CustomFields["profile"])
+		require.Len(t, terms.CustomFields["prohibition"], 1)
+
+		vcBytes, err := vc.MarshalJSON()
+		require.NoError(t, err)
+		require.Contains(t, string(vcBytes), `"profile":"http://example.com/profiles/credential"`)
+	})
+
+	t.Run("test single terms of use", func(t *testing.T) {
+		vc, err := NewCredential([]byte(jsonldCredential[:len(jsonldCredential)-1] +
+			`,"termsOfUse": {"type": "IssuerPolicy", "id": "http://example.com/policies/credential/4"}}`))
+		require.NoError(t, err)
+		require.Equal(t, []TermsOfUse{{Types: []string{"IssuerPolicy"},
+			ID: "http://example.com/policies/credential/4"}}, vc.TermsOfUse)
+	})
+
+	t.Run("test invalid terms of use", func(t *testing.T) {
+		_, err := NewCredential([]byte(jsonldCredential[:len(jsonldCredential)-1] +
+			`,"termsOfUse": [{"id": "http://example.com/policies/credential/4"}]}`))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "terms of use: type is required")
+
+		terms := &TermsOfUse{}
+		require.NoError(t, json.Unmarshal([]byte(`{"type": ["IssuerPolicy", "Prohibition"]}`), terms))
+		require.Equal(t, []string{"IssuerPolicy", "Prohibition"}, terms.Types)
+		require.Error(t, json.Unmarshal([]byte(`{"type": ["IssuerPolicy", 1]}`), terms))
+		require.Error(t, json.Unmarshal([]byte(`{"type": 1}`), terms))
+		require.Error(t, json.Unmarshal([]byte(`[]`), terms))
+
+		data, err := json.Marshal(TermsOfUse{Types: []string{"IssuerPolicy"}})
+		require.NoError(t, err)
+		require.JSONEq(t, `{"type": "IssuerPolicy"}`, string(data))
+
+		list := &termsOfUseList{}
+		require.Error(t, json.Unmarshal([]byte(`{"id": "http://example.com/policies/credential/4"}`), list))
+	})
+}
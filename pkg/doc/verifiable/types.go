@@ -0,0 +1,143 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package verifiable
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/piprate/json-gold/ld"
+)
+
+// CredentialType is a type of the Verifiable Credential
+type CredentialType struct {
+	// Term is the type as defined in the credential, e.g. UniversityDegreeCredential
+	Term string
+	// IRI is the type expanded with the @context of the credential, the term if the context doesn't define it
+	IRI string
+}
+
+// CredentialTypeOf returns the types of the Verifiable Credential JSON without decoding the credential,
+// e.g. to route the incoming credentials to their handlers. The types are expanded by the JSON-LD processor with
+// the remote contexts of the loader, the shared CachingJSONLDLoader if nil.
+func CredentialTypeOf(data []byte, loader ld.DocumentLoader) ([]CredentialType, error) {
+	raw := &struct {
+		Context interface{} `json:"@context,omitempty"`
+		Type    interface{} `json:"type,omitempty"`
+	}{}
+
+	if err := json.Unmarshal(data, raw); err != nil {
+		return nil, fmt.Errorf("JSON unmarshalling of verifiable credential failed: %w", err)
+	}
+
+	var terms []string
+
+	switch t := raw.Type.(type) {
+	case string:
+		terms = []string{t}
+	case []interface{}:
+		for _, v := range t {
+			term, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("verifiable credential type is not a string: %v", v)
+			}
+
+			terms = append(terms, term)
+		}
+	default:
+		return nil, fmt.Errorf("verifiable credential type is not valid: %v", raw.Type)
+	}
+
+	return expandTypes(terms, raw.Context, loader)
+}
+
+// MatchesType reports whether the Verifiable Credential is of the type given either as a term or as an IRI, the
+// type and the types of the credential are expanded with the @context of the credential and the remote contexts
+// of the loader, the shared CachingJSONLDLoader if nil. The credential doesn't match if its contexts can't be
+// loaded.
+func MatchesType(vc *Credential, credentialType string, loader ld.DocumentLoader) bool {
+	types, err := expandTypes(append([]string{credentialType}, vc.Types()...), vc.Context, loader)
+	if err != nil {
+		logger.Warnf("failed to expand the types of verifiable credential %s: %s", vc.ID, err)
+		return false
+	}
+
+	for _, t := range types[1:] {
+		if t.IRI == types[0].IRI {
+			return true
+		}
+	}
+
+	return false
+}
+
+// expandTypes expands the type terms with the JSON-LD context
+func expandTypes(terms []string, context interface{}, loader ld.DocumentLoader) ([]CredentialType, error) {
+	if loader == nil {
+		loader = defaultJSONLDLoader
+	}
+
+	values := make([]interface{}, len(terms))
+	for i, term := range terms {
+		values[i] = term
+	}
+
+	doc := map[string]interface{}{"@type": values}
+	if context != nil {
+		// the contexts embedded in the credential don't go through the loader
+		doc["@context"] = withoutProtected(context)
+	}
+
+	expanded, err := ld.NewJsonLdProcessor().Expand(doc, jsonldOptions(loader))
+	if err != nil {
+		return nil, fmt.Errorf("JSON-LD expansion of verifiable credential types failed: %w", err)
+	}
+
+	iris, err := expandedTypes(expanded)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(iris) != len(terms) {
+		return nil, errors.New("JSON-LD expansion of verifiable credential types is invalid")
+	}
+
+	types := make([]CredentialType, len(terms))
+	for i, term := range terms {
+		types[i] = CredentialType{Term: term, IRI: iris[i]}
+	}
+
+	return types, nil
+}
+
+// expandedTypes returns the IRIs of the expanded JSON-LD document holding the types
+func expandedTypes(expanded []interface{}) ([]string, error) {
+	if len(expanded) != 1 {
+		return nil, errors.New("JSON-LD expansion of verifiable credential types is invalid")
+	}
+
+	node, ok := expanded[0].(map[string]interface{})
+	if !ok {
+		return nil, errors.New("JSON-LD expansion of verifiable credential types is invalid")
+	}
+
+	values, ok := node["@type"].([]interface{})
+	if !ok {
+		return nil, errors.New("JSON-LD expansion of verifiable credential types is invalid")
+	}
+
+	iris := make([]string, len(values))
+
+	for i, v := range values {
+		if iris[i], ok = v.(string); !ok {
+			return nil, errors.New("JSON-LD expansion of verifiable credential types is invalid")
+		}
+	}
+
+	return iris, nil
+}
@@ -0,0 +1,119 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package verifiable
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCredentialTypeOf(t *testing.T) {
+	t.Run("test types expanded with known contexts", func(t *testing.T) {
+		types, err := CredentialTypeOf([]byte(validCredential), examplesLoader(t))
+		require.NoError(t, err)
+		require.Equal(t, []CredentialType{
+			{Term: "VerifiableCredential", IRI: "https://www.w3.org/2018/credentials#VerifiableCredential"},
+			{Term: "UniversityDegreeCredential", IRI: "https://example.org/examples#UniversityDegreeCredential"},
+		}, types)
+	})
+
+	t.Run("test types expanded with inline context", func(t *testing.T) {
+		types, err := CredentialTypeOf([]byte(`{
+  "@context": [
+    "https://www.w3.org/2018/credentials/v1",
+    {
+      "ex": "https://example.com/vocab#",
+      "AlumniCredential": {"@id": "ex:AlumniCredential"},
+      "MembershipCredential": "https://example.com/membership#MembershipCredential"
+    }
+  ],
+  "type": ["AlumniCredential", "MembershipCredential", "ex:Other", "https://example.com/full#Type", "Unknown"]
+}`), nil)
+		require.NoError(t, err)
+		require.Equal(t, []CredentialType{
+			{Term: "AlumniCredential", IRI: "https://example.com/vocab#AlumniCredential"},
+			{Term: "MembershipCredential", IRI: "https://example.com/membership#MembershipCredential"},
+			{Term: "ex:Other", IRI: "https://example.com/vocab#Other"},
+			{Term: "https://example.com/full#Type", IRI: "https://example.com/full#Type"},
+			{Term: "Unknown", IRI: "Unknown"},
+		}, types)
+	})
+
+	t.Run("test single type and context", func(t *testing.T) {
+		types, err := CredentialTypeOf([]byte(`{"@context": "https://w3id.org/citizenship/v1",
+  "type": "PermanentResidentCard"}`), citizenshipLoader(t))
+		require.NoError(t, err)
+		require.Equal(t, []CredentialType{
+			{Term: "PermanentResidentCard", IRI: "https://w3id.org/citizenship#PermanentResidentCard"},
+		}, types)
+	})
+
+	t.Run("test invalid credential", func(t *testing.T) {
+		_, err := CredentialTypeOf([]byte("{"), nil)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "JSON unmarshalling of verifiable credential failed")
+
+		_, err = CredentialTypeOf([]byte(`{"type": [1]}`), nil)
+		require.EqualError(t, err, "verifiable credential type is not a string: 1")
+
+		_, err = CredentialTypeOf([]byte(`{}`), nil)
+		require.EqualError(t, err, "verifiable credential type is not valid: <nil>")
+	})
+
+	t.Run("test context loading error", func(t *testing.T) {
+		_, err := CredentialTypeOf([]byte(validCredential), failingLoader{})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "JSON-LD expansion of verifiable credential types failed")
+	})
+}
+
+func TestMatchesType(t *testing.T) {
+	vc, err := NewCredential([]byte(validCredential))
+	require.NoError(t, err)
+
+	loader := examplesLoader(t)
+
+	require.True(t, MatchesType(vc, "UniversityDegreeCredential", loader))
+	require.True(t, MatchesType(vc, "VerifiableCredential", loader))
+	require.True(t, MatchesType(vc, "https://example.org/examples#UniversityDegreeCredential", loader))
+	require.True(t, MatchesType(vc, "https://www.w3.org/2018/credentials#VerifiableCredential", loader))
+	require.False(t, MatchesType(vc, "AlumniCredential", loader))
+	require.False(t, MatchesType(vc, "https://example.com/vocab#UniversityDegreeCredential", loader))
+
+	// the credential doesn't match if its contexts can't be loaded
+	require.False(t, MatchesType(vc, "UniversityDegreeCredential", failingLoader{}))
+
+	vc = &Credential{
+		Context: []interface{}{map[string]interface{}{"ex": "https://example.com/vocab#"}},
+		Type:    []string{"ex:AlumniCredential"},
+	}
+	require.True(t, MatchesType(vc, "https://example.com/vocab#AlumniCredential", nil))
+	require.True(t, MatchesType(vc, "ex:AlumniCredential", nil))
+	require.False(t, MatchesType(vc, "AlumniCredential", nil))
+
+	// the term is expanded with the context of the credential
+	vc = &Credential{
+		Context: []interface{}{vcContext, map[string]interface{}{
+			"UniversityDegreeCredential": "https://example.com/vocab#UniversityDegreeCredential"}},
+		Type: []string{"VerifiableCredential", "UniversityDegreeCredential"},
+	}
+	require.True(t, MatchesType(vc, "UniversityDegreeCredential", nil))
+	require.False(t, MatchesType(vc, "https://example.org/examples#UniversityDegreeCredential", nil))
+}
+
+func citizenshipLoader(t *testing.T) *JSONLDLoader {
+	loader := CachingJSONLDLoader()
+
+	var doc interface{}
+	require.NoError(t, json.Unmarshal([]byte(`{"@context": {"@version": 1.1, "@protected": true,
+		"PermanentResidentCard": "https://w3id.org/citizenship#PermanentResidentCard"}}`), &doc))
+	loader.AddDocument("https://w3id.org/citizenship/v1", doc)
+
+	return loader
+}
@@ -0,0 +1,199 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package verifiable
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+const (
+	// PermanentResidentCardType is the credential type of the citizenship vocabulary
+	// (https://w3id.org/citizenship/v1)
+	PermanentResidentCardType = "PermanentResidentCard"
+	// VaccinationCertificateType is the credential type of the vaccination vocabulary
+	// (https://w3id.org/vaccination/v1)
+	VaccinationCertificateType = "VaccinationCertificate"
+)
+
+// Person is the schema.org Person credential subject (https://schema.org/Person)
+type Person struct {
+	ID          string      `json:"id,omitempty"`
+	Type        interface{} `json:"type,omitempty"`
+	Name        string      `json:"name,omitempty"`
+	GivenName   string      `json:"givenName,omitempty"`
+	FamilyName  string      `json:"familyName,omitempty"`
+	Gender      string      `json:"gender,omitempty"`
+	BirthDate   string      `json:"birthDate,omitempty"`
+	Email       string      `json:"email,omitempty"`
+	Nationality string      `json:"nationality,omitempty"`
+}
+
+// PermanentResident is the credential subject of the permanent resident card of the citizenship vocabulary
+type PermanentResident struct {
+	ID                     string      `json:"id,omitempty"`
+	Type                   interface{} `json:"type,omitempty"`
+	GivenName              string      `json:"givenName,omitempty"`
+	FamilyName             string      `json:"familyName,omitempty"`
+	Gender                 string      `json:"gender,omitempty"`
+	Image                  string      `json:"image,omitempty"`
+	ResidentSince          string      `json:"residentSince,omitempty"`
+	LPRCategory            string      `json:"lprCategory,omitempty"`
+	LPRNumber              string      `json:"lprNumber,omitempty"`
+	CommuterClassification string      `json:"commuterClassification,omitempty"`
+	BirthCountry           string      `json:"birthCountry,omitempty"`
+	BirthDate              string      `json:"birthDate,omitempty"`
+}
+
+// VaccinationEvent is the credential subject of the vaccination certificate of the vaccination vocabulary
+type VaccinationEvent struct {
+	ID                   string            `json:"id,omitempty"`
+	Type                 interface{}       `json:"type,omitempty"`
+	BatchNumber          string            `json:"batchNumber,omitempty"`
+	AdministeringCentre  string            `json:"administeringCentre,omitempty"`
+	HealthProfessional   string            `json:"healthProfessional,omitempty"`
+	CountryOfVaccination string            `json:"countryOfVaccination,omitempty"`
+	DateOfVaccination    string            `json:"dateOfVaccination,omitempty"`
+	NextVaccinationDate  string            `json:"nextVaccinationDate,omitempty"`
+	Order                string            `json:"order,omitempty"`
+	Recipient            *VaccineRecipient `json:"recipient,omitempty"`
+	Vaccine              *Vaccine          `json:"vaccine,omitempty"`
+}
+
+// VaccineRecipient is the person vaccinated
+type VaccineRecipient struct {
+	Type       interface{} `json:"type,omitempty"`
+	GivenName  string      `json:"givenName,omitempty"`
+	FamilyName string      `json:"familyName,omitempty"`
+	Gender     string      `json:"gender,omitempty"`
+	BirthDate  string      `json:"birthDate,omitempty"`
+}
+
+// Vaccine is the vaccine administered
+type Vaccine struct {
+	Type                         interface{} `json:"type,omitempty"`
+	Disease                      string      `json:"disease,omitempty"`
+	ATCCode                      string      `json:"atcCode,omitempty"`
+	MedicinalProductName         string      `json:"medicinalProductName,omitempty"`
+	MarketingAuthorizationHolder string      `json:"marketingAuthorizationHolder,omitempty"`
+}
+
+// DecodeCredentialFields returns the decoder unmarshalling the Verifiable Credential JSON into each of the fields,
+// e.g. a struct with the credentialSubject of a known vocabulary. The decoder is applied with WithDecoders
+// along with the other decoders.
+func DecodeCredentialFields(fields ...interface{}) CredentialDecoder {
+	return func(dataJSON []byte, _ *Credential) error {
+		for _, f := range fields {
+			if err := json.Unmarshal(dataJSON, f); err != nil {
+				return fmt.Errorf("JSON unmarshalling of Verifiable Credential fields failed: %w", err)
+			}
+		}
+
+		return nil
+	}
+}
+
+// DecodeSubject unmarshals the subject of the Verifiable Credential into the subject given,
+// a slice is expected for the credentials with several subjects. The subjects decoded into the type of the
+// subject given, e.g. by WithSubjectDecoder, are assigned and the subjects in JSON form unmarshalled without
+// re-marshalling them.
+func (vc *Credential) DecodeSubject(subject interface{}) error {
+	if vc.Subject == nil {
+		return errors.New("no subject is defined")
+	}
+
+	if assignSubject(vc.Subject, subject) {
+		return nil
+	}
+
+	data, ok := vc.Subject.(json.RawMessage)
+	if !ok {
+		var err error
+		if data, err = json.Marshal(vc.Subject); err != nil {
+			return fmt.Errorf("JSON marshalling of Verifiable Credential subject failed: %w", err)
+		}
+	}
+
+	if err := json.Unmarshal(data, subject); err != nil {
+		return fmt.Errorf("JSON unmarshalling of Verifiable Credential subject failed: %w", err)
+	}
+
+	return nil
+}
+
+// assignSubject sets the value pointed by the target to the subject, or to the value pointed by the subject,
+// if the types match
+func assignSubject(subject, target interface{}) bool {
+	targetValue := reflect.ValueOf(target)
+	if targetValue.Kind() != reflect.Ptr || targetValue.IsNil() {
+		return false
+	}
+
+	value := reflect.ValueOf(subject)
+	if value.Kind() == reflect.Ptr && !value.IsNil() && value.Type() == targetValue.Type() {
+		value = value.Elem()
+	}
+
+	if value.Type() != targetValue.Elem().Type() {
+		return false
+	}
+
+	targetValue.Elem().Set(value)
+
+	return true
+}
+
+// PersonSubject returns the schema.org Person subject of the Verifiable Credential
+func (vc *Credential) PersonSubject() (*Person, error) {
+	person := &Person{}
+
+	if err := vc.DecodeSubject(person); err != nil {
+		return nil, err
+	}
+
+	return person, nil
+}
+
+// PermanentResidentSubject returns the subject of the permanent resident card
+func (vc *Credential) PermanentResidentSubject() (*PermanentResident, error) {
+	if err := vc.requireType(PermanentResidentCardType); err != nil {
+		return nil, err
+	}
+
+	resident := &PermanentResident{}
+
+	if err := vc.DecodeSubject(resident); err != nil {
+		return nil, err
+	}
+
+	return resident, nil
+}
+
+// VaccinationEventSubject returns the subject of the vaccination certificate
+func (vc *Credential) VaccinationEventSubject() (*VaccinationEvent, error) {
+	if err := vc.requireType(VaccinationCertificateType); err != nil {
+		return nil, err
+	}
+
+	event := &VaccinationEvent{}
+
+	if err := vc.DecodeSubject(event); err != nil {
+		return nil, err
+	}
+
+	return event, nil
+}
+
+func (vc *Credential) requireType(credentialType string) error {
+	if !containsString(vc.Types(), credentialType) {
+		return fmt.Errorf("verifiable credential is not of %s type", credentialType)
+	}
+
+	return nil
+}
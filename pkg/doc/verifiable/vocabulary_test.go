@@ -0,0 +1,143 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package verifiable
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const permanentResidentCard = `{
+  "@context": ["https://www.w3.org/2018/credentials/v1", "https://w3id.org/citizenship/v1"],
+  "id": "https://issuer.oidp.uscis.gov/credentials/83627465",
+  "type": ["VerifiableCredential", "PermanentResidentCard"],
+  "issuer": "did:example:28394728934792387",
+  "issuanceDate": "2019-12-03T12:19:52Z",
+  "credentialSubject": {
+    "id": "did:example:b34ca6cd37bbf23",
+    "type": ["PermanentResident", "Person"],
+    "givenName": "JOHN",
+    "familyName": "SMITH",
+    "gender": "Male",
+    "residentSince": "2015-01-01",
+    "lprCategory": "C09",
+    "lprNumber": "999-999-999",
+    "commuterClassification": "C1",
+    "birthCountry": "Bahamas",
+    "birthDate": "1958-07-17"
+  }
+}`
+
+const vaccinationCertificate = `{
+  "@context": ["https://www.w3.org/2018/credentials/v1", "https://w3id.org/vaccination/v1"],
+  "type": ["VerifiableCredential", "VaccinationCertificate"],
+  "issuer": "did:example:28394728934792387",
+  "issuanceDate": "2019-12-03T12:19:52Z",
+  "credentialSubject": {
+    "type": "VaccinationEvent",
+    "batchNumber": "1183738569",
+    "countryOfVaccination": "NZ",
+    "dateOfVaccination": "2019-12-03T12:19:52Z",
+    "order": "1/3",
+    "recipient": {"type": "VaccineRecipient", "givenName": "JOHN", "familyName": "SMITH", "birthDate": "1958-07-17"},
+    "vaccine": {"type": "Vaccine", "disease": "COVID-19", "atcCode": "J07BX03"}
+  }
+}`
+
+func TestCredential_DecodeSubject(t *testing.T) {
+	t.Run("test single subject", func(t *testing.T) {
+		vc, err := NewCredential([]byte(validCredential))
+		require.NoError(t, err)
+
+		subject := &UniversityDegreeSubject{}
+		require.NoError(t, vc.DecodeSubject(subject))
+		require.Equal(t, "MIT", subject.Degree.University)
+
+		person, err := vc.PersonSubject()
+		require.NoError(t, err)
+		require.Equal(t, "did:example:ebfeb1f712ebc6f1c276e12ec21", person.ID)
+		require.Equal(t, "Jayden Doe", person.Name)
+	})
+
+	t.Run("test several subjects", func(t *testing.T) {
+		vc := &Credential{Subject: []map[string]interface{}{{"id": "did:example:1"}, {"id": "did:example:2"}}}
+
+		var subjects []Person
+		require.NoError(t, vc.DecodeSubject(&subjects))
+		require.Len(t, subjects, 2)
+		require.Equal(t, "did:example:2", subjects[1].ID)
+
+		_, err := vc.PersonSubject()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "JSON unmarshalling of Verifiable Credential subject failed")
+	})
+
+	t.Run("test no subject", func(t *testing.T) {
+		_, err := (&Credential{}).PersonSubject()
+		require.EqualError(t, err, "no subject is defined")
+	})
+}
+
+func TestCredential_PermanentResidentSubject(t *testing.T) {
+	vc, err := NewCredential([]byte(permanentResidentCard))
+	require.NoError(t, err)
+
+	resident, err := vc.PermanentResidentSubject()
+	require.NoError(t, err)
+	require.Equal(t, &PermanentResident{
+		ID:                     "did:example:b34ca6cd37bbf23",
+		Type:                   []interface{}{"PermanentResident", "Person"},
+		GivenName:              "JOHN",
+		FamilyName:             "SMITH",
+		Gender:                 "Male",
+		ResidentSince:          "2015-01-01",
+		LPRCategory:            "C09",
+		LPRNumber:              "999-999-999",
+		CommuterClassification: "C1",
+		BirthCountry:           "Bahamas",
+		BirthDate:              "1958-07-17",
+	}, resident)
+
+	_, err = vc.VaccinationEventSubject()
+	require.EqualError(t, err, "verifiable credential is not of VaccinationCertificate type")
+}
+
+func TestCredential_VaccinationEventSubject(t *testing.T) {
+	vc, err := NewCredential([]byte(vaccinationCertificate))
+	require.NoError(t, err)
+
+	event, err := vc.VaccinationEventSubject()
+	require.NoError(t, err)
+	require.Equal(t, "1183738569", event.BatchNumber)
+	require.Equal(t, "1/3", event.Order)
+	require.Equal(t, "SMITH", event.Recipient.FamilyName)
+	require.Equal(t, "COVID-19", event.Vaccine.Disease)
+
+	_, err = vc.PermanentResidentSubject()
+	require.EqualError(t, err, "verifiable credential is not of PermanentResidentCard type")
+}
+
+func TestDecodeCredentialFields(t *testing.T) {
+	prc := &struct {
+		Subject *PermanentResident `json:"credentialSubject"`
+	}{}
+	extra := &struct {
+		ID string `json:"id"`
+	}{}
+
+	_, err := NewCredential([]byte(permanentResidentCard), WithDecoders([]CredentialDecoder{
+		DecodeCredentialFields(prc, extra),
+	}))
+	require.NoError(t, err)
+	require.Equal(t, "999-999-999", prc.Subject.LPRNumber)
+	require.Equal(t, "https://issuer.oidp.uscis.gov/credentials/83627465", extra.ID)
+
+	err = DecodeCredentialFields(prc)([]byte("{"), &Credential{})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "JSON unmarshalling of Verifiable Credential fields failed")
+}
@@ -0,0 +1,255 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package zcap
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/common/idgen"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/signer"
+)
+
+// Context of the authorization capability (zcap-ld) documents
+const Context = "https://w3id.org/security/v2"
+
+// ProofPurposeDelegation is the proof purpose of the delegated capability proofs
+const ProofPurposeDelegation = "capabilityDelegation"
+
+const (
+	// CaveatExpireAtTime restricts the capability to be invoked before the expiry time
+	CaveatExpireAtTime = "ExpireAtTime"
+	// CaveatRestrictAction restricts the capability to the listed actions
+	CaveatRestrictAction = "RestrictAction"
+)
+
+// Capability is an authorization capability (zcap-ld) to invoke actions on the invocation target,
+// e.g. an EDV vault or a remote KMS keystore.
+type Capability struct {
+	Context string `json:"@context"`
+	ID      string `json:"id"`
+	// ParentCapability is the ID of the capability this one was delegated from, empty for the root capability
+	ParentCapability string `json:"parentCapability,omitempty"`
+	InvocationTarget string `json:"invocationTarget"`
+	// Controller is the DID allowed to invoke and delegate the capability
+	Controller    string                   `json:"controller"`
+	AllowedAction []string                 `json:"allowedAction,omitempty"`
+	Caveats       []Caveat                 `json:"caveat,omitempty"`
+	Proof         []map[string]interface{} `json:"proof,omitempty"`
+}
+
+// Caveat restricts the use of the capability and all the capabilities delegated from it
+type Caveat struct {
+	Type string `json:"type"`
+	// Expires is the expiry time of ExpireAtTime caveat
+	Expires *time.Time `json:"expires,omitempty"`
+	// Actions are the allowed actions of RestrictAction caveat
+	Actions []string `json:"actions,omitempty"`
+	// Value holds the data of custom caveat types
+	Value interface{} `json:"value,omitempty"`
+}
+
+// capabilityOpts holds the options for capability creation
+type capabilityOpts struct {
+	id               string
+	invocationTarget string
+	controller       string
+	allowedActions   []string
+	caveats          []Caveat
+	ids              idgen.Generator
+}
+
+// CapabilityOpt is a capability creation option
+type CapabilityOpt func(opts *capabilityOpts)
+
+// WithID sets the capability ID, a random urn:uuid is used by default
+func WithID(id string) CapabilityOpt {
+	return func(opts *capabilityOpts) {
+		opts.id = id
+	}
+}
+
+// WithIDGenerator sets the generator of the IDs of the delegated capabilities, random urn:uuid URNs by default
+func WithIDGenerator(g idgen.Generator) CapabilityOpt {
+	return func(opts *capabilityOpts) {
+		opts.ids = g
+	}
+}
+
+// WithInvocationTarget sets the invocation target of the root capability
+func WithInvocationTarget(target string) CapabilityOpt {
+	return func(opts *capabilityOpts) {
+		opts.invocationTarget = target
+	}
+}
+
+// WithController sets the DID allowed to invoke and delegate the capability
+func WithController(controller string) CapabilityOpt {
+	return func(opts *capabilityOpts) {
+		opts.controller = controller
+	}
+}
+
+// WithAllowedActions restricts the actions of the capability
+func WithAllowedActions(actions ...string) CapabilityOpt {
+	return func(opts *capabilityOpts) {
+		opts.allowedActions = actions
+	}
+}
+
+// WithCaveats adds caveats to the capability
+func WithCaveats(caveats ...Caveat) CapabilityOpt {
+	return func(opts *capabilityOpts) {
+		opts.caveats = append(opts.caveats, caveats...)
+	}
+}
+
+// NewCapability creates a root capability of the invocation target.
+// The root capability is not signed, its authority comes from the invocation target itself.
+func NewCapability(opts ...CapabilityOpt) (*Capability, error) {
+	capOpts := newCapabilityOpts(opts)
+
+	if capOpts.invocationTarget == "" {
+		return nil, errors.New("invocation target is mandatory")
+	}
+
+	if capOpts.controller == "" {
+		return nil, errors.New("controller is mandatory")
+	}
+
+	if capOpts.id == "" {
+		capOpts.id = capOpts.invocationTarget
+	}
+
+	return &Capability{
+		Context:          Context,
+		ID:               capOpts.id,
+		InvocationTarget: capOpts.invocationTarget,
+		Controller:       capOpts.controller,
+		AllowedAction:    capOpts.allowedActions,
+		Caveats:          capOpts.caveats,
+	}, nil
+}
+
+// Delegate creates a capability delegated from the parent capability and signs it with the key
+// of the parent capability controller, the proof purpose is set to capabilityDelegation.
+func Delegate(parent *Capability, signCtx *signer.Context, opts ...CapabilityOpt) (*Capability, error) {
+	if parent == nil {
+		return nil, errors.New("parent capability is mandatory")
+	}
+
+	capOpts := newCapabilityOpts(opts)
+
+	if capOpts.controller == "" {
+		return nil, errors.New("controller is mandatory")
+	}
+
+	if capOpts.invocationTarget != "" && capOpts.invocationTarget != parent.InvocationTarget {
+		return nil, errors.New("invocation target of the delegated capability must match the parent capability")
+	}
+
+	if !isSubset(capOpts.allowedActions, parent.AllowedAction) {
+		return nil, errors.New("allowed actions of the delegated capability exceed the parent capability")
+	}
+
+	allowedActions := capOpts.allowedActions
+	if len(allowedActions) == 0 {
+		allowedActions = parent.AllowedAction
+	}
+
+	if capOpts.id == "" {
+		capOpts.id = capOpts.ids.NewID()
+	}
+
+	c := &Capability{
+		Context:          Context,
+		ID:               capOpts.id,
+		ParentCapability: parent.ID,
+		InvocationTarget: parent.InvocationTarget,
+		Controller:       capOpts.controller,
+		AllowedAction:    allowedActions,
+		Caveats:          capOpts.caveats,
+	}
+
+	unsigned, err := c.JSONBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	delegationCtx := *signCtx
+	delegationCtx.ProofPurpose = ProofPurposeDelegation
+
+	signed, err := signer.New().Sign(&delegationCtx, unsigned)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign delegated capability: %w", err)
+	}
+
+	return ParseCapability(signed)
+}
+
+// ParseCapability parses the JSON bytes of the capability
+func ParseCapability(data []byte) (*Capability, error) {
+	c := &Capability{}
+
+	err := json.Unmarshal(data, c)
+	if err != nil {
+		return nil, fmt.Errorf("JSON unmarshalling of capability failed: %w", err)
+	}
+
+	return c, nil
+}
+
+// JSONBytes converts the capability to JSON bytes
+func (c *Capability) JSONBytes() ([]byte, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return nil, fmt.Errorf("JSON marshalling of capability failed: %w", err)
+	}
+
+	return data, nil
+}
+
+func newCapabilityOpts(opts []CapabilityOpt) *capabilityOpts {
+	capOpts := &capabilityOpts{ids: idgen.Func(func() string {
+		return "urn:uuid:" + idgen.UUID().NewID()
+	})}
+	for _, opt := range opts {
+		opt(capOpts)
+	}
+
+	return capOpts
+}
+
+// isSubset checks that all the actions are allowed, no allowed actions means all actions are allowed
+func isSubset(actions, allowed []string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+
+	if len(actions) == 0 {
+		return true
+	}
+
+	for _, a := range actions {
+		if !contains(allowed, a) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,162 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package zcap
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hyperledger/aries-framework-go/pkg/common/idgen"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/signer"
+)
+
+const (
+	vaultURL    = "https://edv.example.com/vaults/z4sRgBJJLnYy"
+	alice       = "did:example:alice"
+	bob         = "did:example:bob"
+	carol       = "did:example:carol"
+	aliceKey    = alice + "#key-1"
+	bobKey      = bob + "#key-1"
+	signingType = "Ed25519Signature2018"
+)
+
+func TestNewCapability(t *testing.T) {
+	t.Run("test root capability", func(t *testing.T) {
+		c, err := NewCapability(WithInvocationTarget(vaultURL), WithController(alice),
+			WithAllowedActions("read", "write"))
+		require.NoError(t, err)
+		require.Equal(t, Context, c.Context)
+		require.Equal(t, vaultURL, c.ID)
+		require.Empty(t, c.ParentCapability)
+		require.Equal(t, alice, c.Controller)
+		require.Equal(t, []string{"read", "write"}, c.AllowedAction)
+	})
+
+	t.Run("test missing invocation target", func(t *testing.T) {
+		c, err := NewCapability(WithController(alice))
+		require.EqualError(t, err, "invocation target is mandatory")
+		require.Nil(t, c)
+	})
+
+	t.Run("test missing controller", func(t *testing.T) {
+		c, err := NewCapability(WithInvocationTarget(vaultURL))
+		require.EqualError(t, err, "controller is mandatory")
+		require.Nil(t, c)
+	})
+}
+
+func TestDelegate(t *testing.T) {
+	keys := newTestKeys(t, aliceKey)
+
+	root, err := NewCapability(WithInvocationTarget(vaultURL), WithController(alice),
+		WithAllowedActions("read", "write"))
+	require.NoError(t, err)
+
+	t.Run("test delegate capability", func(t *testing.T) {
+		c, err := Delegate(root, keys.signCtx(aliceKey), WithController(bob), WithAllowedActions("read"),
+			WithCaveats(Caveat{Type: CaveatRestrictAction, Actions: []string{"read"}}))
+		require.NoError(t, err)
+		require.True(t, strings.HasPrefix(c.ID, "urn:uuid:"))
+		require.Equal(t, root.ID, c.ParentCapability)
+		require.Equal(t, vaultURL, c.InvocationTarget)
+		require.Equal(t, bob, c.Controller)
+		require.Equal(t, []string{"read"}, c.AllowedAction)
+		require.Len(t, c.Caveats, 1)
+		require.Len(t, c.Proof, 1)
+		require.Equal(t, aliceKey, c.Proof[0]["creator"])
+		require.Equal(t, ProofPurposeDelegation, c.Proof[0]["proofPurpose"])
+
+		data, err := c.JSONBytes()
+		require.NoError(t, err)
+
+		parsed, err := ParseCapability(data)
+		require.NoError(t, err)
+		require.Equal(t, c, parsed)
+	})
+
+	t.Run("test inherit allowed actions", func(t *testing.T) {
+		c, err := Delegate(root, keys.signCtx(aliceKey), WithController(bob))
+		require.NoError(t, err)
+		require.Equal(t, root.AllowedAction, c.AllowedAction)
+	})
+
+	t.Run("test ID generator", func(t *testing.T) {
+		c, err := Delegate(root, keys.signCtx(aliceKey), WithController(bob), WithIDGenerator(idgen.Sequence("cap")))
+		require.NoError(t, err)
+		require.Equal(t, "cap-1", c.ID)
+	})
+
+	t.Run("test delegate errors", func(t *testing.T) {
+		_, err := Delegate(nil, keys.signCtx(aliceKey), WithController(bob))
+		require.EqualError(t, err, "parent capability is mandatory")
+
+		_, err = Delegate(root, keys.signCtx(aliceKey))
+		require.EqualError(t, err, "controller is mandatory")
+
+		_, err = Delegate(root, keys.signCtx(aliceKey), WithController(bob), WithInvocationTarget("https://other"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invocation target of the delegated capability must match")
+
+		_, err = Delegate(root, keys.signCtx(aliceKey), WithController(bob), WithAllowedActions("delete"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "allowed actions of the delegated capability exceed the parent capability")
+
+		_, err = Delegate(root, &signer.Context{SignatureType: signingType}, WithController(bob))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to sign delegated capability")
+	})
+
+	t.Run("test parse invalid capability", func(t *testing.T) {
+		_, err := ParseCapability([]byte("{"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "JSON unmarshalling of capability failed")
+	})
+}
+
+type testKeys struct {
+	pub  map[string]ed25519.PublicKey
+	priv map[string]ed25519.PrivateKey
+}
+
+func newTestKeys(t *testing.T, ids ...string) *testKeys {
+	keys := &testKeys{pub: make(map[string]ed25519.PublicKey), priv: make(map[string]ed25519.PrivateKey)}
+
+	for _, id := range ids {
+		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+
+		keys.pub[id] = pub
+		keys.priv[id] = priv
+	}
+
+	return keys
+}
+
+func (k *testKeys) signCtx(id string) *signer.Context {
+	return &signer.Context{SignatureType: signingType, Creator: id, Signer: &testSigner{k.priv[id]}}
+}
+
+func (k *testKeys) Resolve(id string) ([]byte, error) {
+	pub, ok := k.pub[id]
+	if !ok {
+		return nil, errors.New("key not found")
+	}
+
+	return pub, nil
+}
+
+type testSigner struct {
+	privateKey ed25519.PrivateKey
+}
+
+func (s *testSigner) Sign(doc []byte) ([]byte, error) {
+	return ed25519.Sign(s.privateKey, doc), nil
+}
@@ -0,0 +1,252 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package zcap
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/common/clock"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/proof"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/verifier"
+)
+
+const defaultMaxChainLength = 10
+
+// ErrUnauthorized is returned when the invocation is not authorized by the capability
+var ErrUnauthorized = errors.New("invocation not authorized")
+
+// KeyResolver resolves the public keys of the delegation proofs
+type KeyResolver interface {
+	// Resolve will return public key bytes
+	Resolve(id string) ([]byte, error)
+}
+
+// CapabilityResolver resolves the parent capabilities of the delegation chain by ID
+type CapabilityResolver interface {
+	Resolve(id string) (*Capability, error)
+}
+
+// CaveatVerifier verifies custom caveat types
+type CaveatVerifier interface {
+	// Verify checks the invocation satisfies the caveat
+	Verify(caveat *Caveat, invocation *Invocation) error
+	// Accept registers this verifier with the given caveat type
+	Accept(caveatType string) bool
+}
+
+// Invocation is an (authenticated) attempt to invoke the action on the invocation target
+type Invocation struct {
+	// Invoker is the DID of the authenticated invoker
+	Invoker string
+	Target  string
+	Action  string
+	// Time of the invocation, the current time of the verifier clock is used by default
+	Time *time.Time
+}
+
+// Verifier verifies the capability delegation chain and authorizes the invocations
+type Verifier struct {
+	keyResolver    KeyResolver
+	capResolver    CapabilityResolver
+	caveats        []CaveatVerifier
+	maxChainLength int
+	clock          clock.Clock
+}
+
+// VerifierOpt is a verifier instance option
+type VerifierOpt func(v *Verifier)
+
+// WithCaveatVerifier adds verifier of custom caveat type
+func WithCaveatVerifier(cv CaveatVerifier) VerifierOpt {
+	return func(v *Verifier) {
+		v.caveats = append(v.caveats, cv)
+	}
+}
+
+// WithMaxChainLength limits the length of the delegation chain
+func WithMaxChainLength(length int) VerifierOpt {
+	return func(v *Verifier) {
+		v.maxChainLength = length
+	}
+}
+
+// WithClock sets the clock of the invocations without time, the system clock by default
+func WithClock(c clock.Clock) VerifierOpt {
+	return func(v *Verifier) {
+		v.clock = c
+	}
+}
+
+// NewVerifier returns new instance of capability verifier
+func NewVerifier(keyResolver KeyResolver, capResolver CapabilityResolver, opts ...VerifierOpt) *Verifier {
+	v := &Verifier{keyResolver: keyResolver, capResolver: capResolver, maxChainLength: defaultMaxChainLength,
+		clock: clock.System()}
+	for _, opt := range opts {
+		opt(v)
+	}
+
+	return v
+}
+
+// Verify checks the delegation chain of the capability and that the invocation satisfies
+// the capability and the caveats of the whole chain.
+func (v *Verifier) Verify(c *Capability, invocation *Invocation) error {
+	chain, err := v.VerifyChain(c)
+	if err != nil {
+		return err
+	}
+
+	// the trusted root replaces the capability if it's the root
+	c = chain[len(chain)-1]
+
+	if invocation.Invoker != c.Controller {
+		return fmt.Errorf("%w: invoker %s is not the capability controller", ErrUnauthorized, invocation.Invoker)
+	}
+
+	if invocation.Target != c.InvocationTarget {
+		return fmt.Errorf("%w: capability not valid for target %s", ErrUnauthorized, invocation.Target)
+	}
+
+	if len(c.AllowedAction) > 0 && !contains(c.AllowedAction, invocation.Action) {
+		return fmt.Errorf("%w: action %s not allowed", ErrUnauthorized, invocation.Action)
+	}
+
+	inv := *invocation
+	if inv.Time == nil {
+		now := v.clock.Now()
+		inv.Time = &now
+	}
+
+	for _, link := range chain {
+		for i := range link.Caveats {
+			if err := v.verifyCaveat(&link.Caveats[i], &inv); err != nil {
+				return fmt.Errorf("%w: caveat of capability %s: %s", ErrUnauthorized, link.ID, err.Error())
+			}
+		}
+	}
+
+	return nil
+}
+
+// VerifyChain verifies the delegation proofs of the capability up to the root capability
+// and returns the chain starting from the root. The root is resolved by the capability resolver,
+// a root capability presented by the caller is replaced by the trusted one.
+func (v *Verifier) VerifyChain(c *Capability) ([]*Capability, error) {
+	if c.ParentCapability == "" {
+		root, err := v.trustedRoot(c.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		return []*Capability{root}, nil
+	}
+
+	chain := []*Capability{c}
+
+	for c.ParentCapability != "" {
+		if len(chain) > v.maxChainLength {
+			return nil, fmt.Errorf("delegation chain exceeds max length %d", v.maxChainLength)
+		}
+
+		parent, err := v.capResolver.Resolve(c.ParentCapability)
+		if err != nil {
+			return nil, fmt.Errorf("resolve parent capability %s: %w", c.ParentCapability, err)
+		}
+
+		if err := v.verifyDelegation(parent, c); err != nil {
+			return nil, fmt.Errorf("verify delegation of capability %s: %w", c.ID, err)
+		}
+
+		chain = append([]*Capability{parent}, chain...)
+		c = parent
+	}
+
+	if c.ID != c.InvocationTarget {
+		return nil, fmt.Errorf("root capability %s does not match invocation target", c.ID)
+	}
+
+	return chain, nil
+}
+
+// trustedRoot returns the root capability of the ID resolved by the capability resolver
+func (v *Verifier) trustedRoot(id string) (*Capability, error) {
+	root, err := v.capResolver.Resolve(id)
+	if err != nil {
+		return nil, fmt.Errorf("root capability %s not trusted: %w", id, err)
+	}
+
+	if root.ParentCapability != "" || root.ID != root.InvocationTarget {
+		return nil, fmt.Errorf("root capability %s does not match invocation target", id)
+	}
+
+	return root, nil
+}
+
+func (v *Verifier) verifyDelegation(parent, c *Capability) error {
+	if c.InvocationTarget != parent.InvocationTarget {
+		return errors.New("invocation target does not match the parent capability")
+	}
+
+	if len(parent.AllowedAction) > 0 &&
+		(len(c.AllowedAction) == 0 || !isSubset(c.AllowedAction, parent.AllowedAction)) {
+		return errors.New("allowed actions exceed the parent capability")
+	}
+
+	if len(c.Proof) == 0 {
+		return proof.ErrProofNotFound
+	}
+
+	for _, p := range c.Proof {
+		creator, ok := p["creator"].(string)
+		if !ok || controllerOf(creator) != parent.Controller {
+			return fmt.Errorf("proof creator is not the controller %s of the parent capability", parent.Controller)
+		}
+
+		if p["proofPurpose"] != ProofPurposeDelegation {
+			return fmt.Errorf("proof purpose is not %s", ProofPurposeDelegation)
+		}
+	}
+
+	data, err := c.JSONBytes()
+	if err != nil {
+		return err
+	}
+
+	return verifier.New(v.keyResolver).Verify(data)
+}
+
+func (v *Verifier) verifyCaveat(caveat *Caveat, invocation *Invocation) error {
+	switch caveat.Type {
+	case CaveatExpireAtTime:
+		if caveat.Expires == nil || !invocation.Time.Before(*caveat.Expires) {
+			return errors.New("capability expired")
+		}
+
+		return nil
+	case CaveatRestrictAction:
+		if !contains(caveat.Actions, invocation.Action) {
+			return fmt.Errorf("action %s restricted", invocation.Action)
+		}
+
+		return nil
+	}
+
+	for _, cv := range v.caveats {
+		if cv.Accept(caveat.Type) {
+			return cv.Verify(caveat, invocation)
+		}
+	}
+
+	return fmt.Errorf("caveat type %s not supported", caveat.Type)
+}
+
+// controllerOf returns the DID of the key ID
+func controllerOf(keyID string) string {
+	return strings.SplitN(keyID, "#", 2)[0]
+}
@@ -0,0 +1,197 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package zcap
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifier_Verify(t *testing.T) {
+	keys := newTestKeys(t, aliceKey, bobKey)
+	caps := mockCapabilityStore{}
+
+	root, err := NewCapability(WithInvocationTarget(vaultURL), WithController(alice),
+		WithAllowedActions("read", "write"))
+	require.NoError(t, err)
+	caps[root.ID] = root
+
+	expires := time.Now().Add(time.Hour)
+	toBob, err := Delegate(root, keys.signCtx(aliceKey), WithController(bob),
+		WithCaveats(Caveat{Type: CaveatExpireAtTime, Expires: &expires}))
+	require.NoError(t, err)
+	caps[toBob.ID] = toBob
+
+	toCarol, err := Delegate(toBob, keys.signCtx(bobKey), WithController(carol), WithAllowedActions("read"))
+	require.NoError(t, err)
+	caps[toCarol.ID] = toCarol
+
+	v := NewVerifier(keys, caps)
+
+	t.Run("test authorized invocations", func(t *testing.T) {
+		require.NoError(t, v.Verify(root, &Invocation{Invoker: alice, Target: vaultURL, Action: "write"}))
+		require.NoError(t, v.Verify(toBob, &Invocation{Invoker: bob, Target: vaultURL, Action: "write"}))
+		require.NoError(t, v.Verify(toCarol, &Invocation{Invoker: carol, Target: vaultURL, Action: "read"}))
+
+		chain, err := v.VerifyChain(toCarol)
+		require.NoError(t, err)
+		require.Equal(t, []*Capability{root, toBob, toCarol}, chain)
+	})
+
+	t.Run("test unauthorized invocations", func(t *testing.T) {
+		err := v.Verify(toCarol, &Invocation{Invoker: bob, Target: vaultURL, Action: "read"})
+		require.True(t, errors.Is(err, ErrUnauthorized))
+		require.Contains(t, err.Error(), "invoker did:example:bob is not the capability controller")
+
+		err = v.Verify(toCarol, &Invocation{Invoker: carol, Target: "https://other", Action: "read"})
+		require.True(t, errors.Is(err, ErrUnauthorized))
+
+		err = v.Verify(toCarol, &Invocation{Invoker: carol, Target: vaultURL, Action: "write"})
+		require.True(t, errors.Is(err, ErrUnauthorized))
+		require.Contains(t, err.Error(), "action write not allowed")
+	})
+
+	t.Run("test caveats of the chain", func(t *testing.T) {
+		later := expires.Add(time.Minute)
+		err := v.Verify(toCarol, &Invocation{Invoker: carol, Target: vaultURL, Action: "read", Time: &later})
+		require.True(t, errors.Is(err, ErrUnauthorized))
+		require.Contains(t, err.Error(), "capability expired")
+
+		restricted, err := Delegate(root, keys.signCtx(aliceKey), WithController(bob),
+			WithCaveats(Caveat{Type: CaveatRestrictAction, Actions: []string{"read"}}))
+		require.NoError(t, err)
+
+		require.NoError(t, v.Verify(restricted, &Invocation{Invoker: bob, Target: vaultURL, Action: "read"}))
+		err = v.Verify(restricted, &Invocation{Invoker: bob, Target: vaultURL, Action: "write"})
+		require.Contains(t, err.Error(), "action write restricted")
+	})
+
+	t.Run("test custom caveat", func(t *testing.T) {
+		custom, err := Delegate(root, keys.signCtx(aliceKey), WithController(bob),
+			WithCaveats(Caveat{Type: "MaxUses", Value: 1.0}))
+		require.NoError(t, err)
+
+		err = v.Verify(custom, &Invocation{Invoker: bob, Target: vaultURL, Action: "read"})
+		require.Contains(t, err.Error(), "caveat type MaxUses not supported")
+
+		cv := NewVerifier(keys, caps, WithCaveatVerifier(&mockCaveatVerifier{caveatType: "MaxUses"}))
+		require.NoError(t, cv.Verify(custom, &Invocation{Invoker: bob, Target: vaultURL, Action: "read"}))
+
+		cv = NewVerifier(keys, caps, WithCaveatVerifier(&mockCaveatVerifier{caveatType: "MaxUses",
+			err: errors.New("used up")}))
+		err = cv.Verify(custom, &Invocation{Invoker: bob, Target: vaultURL, Action: "read"})
+		require.Contains(t, err.Error(), "used up")
+	})
+
+	t.Run("test invalid delegation chain", func(t *testing.T) {
+		_, err := NewVerifier(keys, caps, WithMaxChainLength(1)).VerifyChain(toCarol)
+		require.Contains(t, err.Error(), "delegation chain exceeds max length 1")
+
+		_, err = NewVerifier(keys, mockCapabilityStore{}).VerifyChain(toBob)
+		require.Contains(t, err.Error(), "resolve parent capability")
+
+		tampered := *toCarol
+		tampered.AllowedAction = []string{"read", "write"}
+		_, err = v.VerifyChain(&tampered)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "verify delegation of capability")
+
+		tampered = *toCarol
+		tampered.AllowedAction = nil
+		_, err = v.VerifyChain(&tampered)
+		require.Contains(t, err.Error(), "allowed actions exceed the parent capability")
+
+		tampered = *toCarol
+		tampered.Proof = nil
+		_, err = v.VerifyChain(&tampered)
+		require.Contains(t, err.Error(), "proof not found")
+
+		tampered = *toCarol
+		tampered.Proof = []map[string]interface{}{{}}
+		for k, val := range toCarol.Proof[0] {
+			tampered.Proof[0][k] = val
+		}
+		tampered.Proof[0]["proofPurpose"] = "assertionMethod"
+		_, err = v.VerifyChain(&tampered)
+		require.Contains(t, err.Error(), "proof purpose is not capabilityDelegation")
+
+		tampered = *toCarol
+		tampered.InvocationTarget = "https://other"
+		_, err = v.VerifyChain(&tampered)
+		require.Contains(t, err.Error(), "invocation target does not match the parent capability")
+
+		// delegation of bob's capability signed by alice
+		forged, err := Delegate(toBob, keys.signCtx(aliceKey), WithController(carol))
+		require.NoError(t, err)
+		_, err = v.VerifyChain(forged)
+		require.Contains(t, err.Error(), "proof creator is not the controller did:example:bob")
+
+		fakeRoot := *root
+		fakeRoot.ID = "https://edv.example.com/other"
+		_, err = v.VerifyChain(&fakeRoot)
+		require.Contains(t, err.Error(), "root capability https://edv.example.com/other not trusted")
+
+		caps[fakeRoot.ID] = &fakeRoot
+		defer delete(caps, fakeRoot.ID)
+		_, err = v.VerifyChain(&fakeRoot)
+		require.Contains(t, err.Error(), "does not match invocation target")
+	})
+
+	t.Run("test forged root", func(t *testing.T) {
+		// a root of the target self-issued by bob, the verifier only trusts the root controlled by alice
+		forgedRoot, err := NewCapability(WithInvocationTarget(vaultURL), WithController(bob),
+			WithAllowedActions("read", "write"))
+		require.NoError(t, err)
+
+		err = v.Verify(forgedRoot, &Invocation{Invoker: bob, Target: vaultURL, Action: "write"})
+		require.True(t, errors.Is(err, ErrUnauthorized))
+		require.Contains(t, err.Error(), "invoker did:example:bob is not the capability controller")
+
+		chain, err := v.VerifyChain(forgedRoot)
+		require.NoError(t, err)
+		require.Equal(t, []*Capability{root}, chain)
+
+		// the delegations of the forged root are checked against the trusted root
+		fromForged, err := Delegate(forgedRoot, keys.signCtx(bobKey), WithController(carol))
+		require.NoError(t, err)
+		err = v.Verify(fromForged, &Invocation{Invoker: carol, Target: vaultURL, Action: "write"})
+		require.Contains(t, err.Error(), "proof creator is not the controller did:example:alice")
+
+		// the roots of the targets unknown to the verifier are rejected
+		otherRoot, err := NewCapability(WithInvocationTarget("https://edv.example.com/other"), WithController(bob))
+		require.NoError(t, err)
+		err = v.Verify(otherRoot, &Invocation{Invoker: bob, Target: "https://edv.example.com/other", Action: "read"})
+		require.Contains(t, err.Error(), "not trusted")
+	})
+}
+
+type mockCapabilityStore map[string]*Capability
+
+func (m mockCapabilityStore) Resolve(id string) (*Capability, error) {
+	c, ok := m[id]
+	if !ok {
+		return nil, fmt.Errorf("capability %s not found", id)
+	}
+
+	return c, nil
+}
+
+type mockCaveatVerifier struct {
+	caveatType string
+	err        error
+}
+
+func (m *mockCaveatVerifier) Verify(caveat *Caveat, invocation *Invocation) error {
+	return m.err
+}
+
+func (m *mockCaveatVerifier) Accept(caveatType string) bool {
+	return caveatType == m.caveatType
+}
@@ -0,0 +1,20 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package api
+
+const (
+	// FeatureDIDCommV2 enables the experimental DIDComm v2 envelopes and protocols
+	FeatureDIDCommV2 = "didcomm-v2"
+	// FeatureBBSPlus enables the experimental BBS+ signatures
+	FeatureBBSPlus = "bbs-plus"
+)
+
+// FeatureProvider is optionally implemented by the providers of the protocol services, the experimental
+// protocols and algorithms are only used if their feature flag is enabled
+type FeatureProvider interface {
+	FeatureEnabled(flag string) bool
+}
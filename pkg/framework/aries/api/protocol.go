@@ -17,6 +17,10 @@ import (
 // ErrSvcNotFound is returned when service not found
 var ErrSvcNotFound = errors.New("service not found")
 
+// ErrDuplicateSvc is returned when protocol services with the same name or accepting the same message type
+// are registered
+var ErrDuplicateSvc = errors.New("duplicate protocol service")
+
 // Provider interface for protocol ctx
 type Provider interface {
 	OutboundDispatcher() dispatcher.Outbound
@@ -29,3 +33,12 @@ type Provider interface {
 
 // ProtocolSvcCreator method to create new protocol service
 type ProtocolSvcCreator func(prv Provider) (dispatcher.Service, error)
+
+// ProtocolSvc registers a protocol service with its descriptor. The descriptor takes precedence over the
+// description of the created service (see dispatcher.Describer), its name must be the name of the service.
+type ProtocolSvc struct {
+	Descriptor dispatcher.ServiceDescriptor
+	Create     ProtocolSvcCreator
+	// Feature is the feature flag of the experimental protocols, the service is only loaded if it's enabled
+	Feature string
+}
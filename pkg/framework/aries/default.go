@@ -9,7 +9,12 @@ package aries
 import (
 	"fmt"
 
+	"github.com/hyperledger/aries-framework-go/pkg/cache/mem"
+	"github.com/hyperledger/aries-framework-go/pkg/common/clock"
 	"github.com/hyperledger/aries-framework-go/pkg/common/did"
+	"github.com/hyperledger/aries-framework-go/pkg/common/metrics"
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/audit"
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/threads"
 	"github.com/hyperledger/aries-framework-go/pkg/didcomm/dispatcher"
 	"github.com/hyperledger/aries-framework-go/pkg/didcomm/protocol/didexchange"
 	didcommtrans "github.com/hyperledger/aries-framework-go/pkg/didcomm/transport"
@@ -18,11 +23,16 @@ import (
 	"github.com/hyperledger/aries-framework-go/pkg/framework/aries/api"
 	"github.com/hyperledger/aries-framework-go/pkg/framework/aries/factory/transport"
 	"github.com/hyperledger/aries-framework-go/pkg/framework/didresolver"
+	"github.com/hyperledger/aries-framework-go/pkg/framework/vdr"
 	"github.com/hyperledger/aries-framework-go/pkg/storage"
+	"github.com/hyperledger/aries-framework-go/pkg/storage/cache"
 	"github.com/hyperledger/aries-framework-go/pkg/storage/leveldb"
 	"github.com/hyperledger/aries-framework-go/pkg/wallet"
 )
 
+// defaultCacheEntries bounds the number of connection records and peer DID documents cached per store by default
+const defaultCacheEntries = 10000
+
 // TODO handle the test scenario better (make dbPath constant).
 //nolint:gochecknoglobals
 var (
@@ -47,8 +57,30 @@ func didResolverProvider(dbprov storage.Provider) (DIDResolver, error) {
 	return resl, nil
 }
 
-func storeProvider() (storage.Provider, error) {
-	storeProv, err := leveldb.NewProvider(dbPath)
+// vdrRegistryProvider provides default VDR registry, injected VDRs take precedence over peer VDR.
+func vdrRegistryProvider(dbprov storage.Provider, vdrs []vdr.VDR) (*vdr.Registry, error) {
+	dbstore, err := dbprov.OpenStore(peer.StoreNamespace)
+	if err != nil {
+		return nil, fmt.Errorf("storage initialization failed : %w", err)
+	}
+
+	opts := make([]vdr.Opt, 0, len(vdrs)+1)
+	for _, v := range vdrs {
+		opts = append(opts, vdr.WithVDR(v))
+	}
+
+	opts = append(opts, vdr.WithVDR(peer.NewDIDResolver(peer.NewDIDStore(dbstore))))
+
+	return vdr.New(opts...), nil
+}
+
+func storeProvider(c clock.Clock) (storage.Provider, error) {
+	var opts []leveldb.Opt
+	if c != nil {
+		opts = append(opts, leveldb.WithClock(c))
+	}
+
+	storeProv, err := leveldb.NewProvider(dbPath, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("leveldb provider initialization failed : %w", err)
 	}
@@ -72,13 +104,21 @@ func defFrameworkOpts(frameworkOpts *Aries) error {
 	}
 
 	if frameworkOpts.storeProvider == nil {
-		storeProv, err := storeProvider()
+		storeProv, err := storeProvider(frameworkOpts.clock)
 		if err != nil {
 			return fmt.Errorf("resolver initialization failed : %w", err)
 		}
 		frameworkOpts.storeProvider = storeProv
 	}
 
+	if frameworkOpts.cacheProvider == nil {
+		frameworkOpts.cacheProvider = mem.NewProvider(mem.WithMaxEntries(defaultCacheEntries))
+	}
+
+	// cache connection records and peer DID documents read for every inbound message
+	frameworkOpts.storeProvider = cache.NewProviderWithCache(frameworkOpts.storeProvider, frameworkOpts.cacheProvider,
+		didexchange.DIDExchange, peer.StoreNamespace)
+
 	if frameworkOpts.inboundTransport == nil {
 		inbound, err := inboundTransport()
 		if err != nil {
@@ -95,6 +135,34 @@ func defFrameworkOpts(frameworkOpts *Aries) error {
 		frameworkOpts.didResolver = resolver
 	}
 
+	registry, err := vdrRegistryProvider(frameworkOpts.storeProvider, frameworkOpts.vdrs)
+	if err != nil {
+		return fmt.Errorf("vdr registry initialization failed : %w", err)
+	}
+	frameworkOpts.vdrRegistry = registry
+
+	threadStoreOpts := frameworkOpts.threadStoreOpts
+	if frameworkOpts.clock != nil {
+		threadStoreOpts = append([]threads.Opt{threads.WithClock(frameworkOpts.clock)}, threadStoreOpts...)
+	}
+
+	threadStore, err := threads.New(frameworkOpts.storeProvider, threadStoreOpts...)
+	if err != nil {
+		return fmt.Errorf("thread store initialization failed : %w", err)
+	}
+	frameworkOpts.threadStore = threadStore
+
+	if err = setDefaultAuditStore(frameworkOpts); err != nil {
+		return err
+	}
+
+	setDefaultMessageMetrics(frameworkOpts)
+
+	if frameworkOpts.sizeLimits == nil {
+		limits := wallet.DefaultSizeLimits()
+		frameworkOpts.sizeLimits = &limits
+	}
+
 	if frameworkOpts.walletCreator == nil {
 		frameworkOpts.walletCreator = func(provider api.Provider) (api.CloseableWallet, error) {
 			return wallet.New(provider)
@@ -106,11 +174,52 @@ func defFrameworkOpts(frameworkOpts *Aries) error {
 	newExchangeSvc := func(prv api.Provider) (dispatcher.Service, error) {
 		return didexchange.New(did.NewLocalDIDCreator(prv), prv)
 	}
-	frameworkOpts.protocolSvcCreators = append(frameworkOpts.protocolSvcCreators, newExchangeSvc)
+	frameworkOpts.protocolSvcs = append(frameworkOpts.protocolSvcs, api.ProtocolSvc{Create: newExchangeSvc})
+
+	return nil
+}
+
+// setDefaultAuditStore opens the audit store if the audit trail is enabled, the records are identified by the
+// framework ID generator and timestamped by the framework clock if set
+func setDefaultAuditStore(frameworkOpts *Aries) error {
+	if frameworkOpts.auditOpts == nil {
+		return nil
+	}
+
+	auditOpts := frameworkOpts.auditOpts
+	if frameworkOpts.idGenerator != nil {
+		auditOpts = append([]audit.Opt{audit.WithIDGenerator(frameworkOpts.idGenerator)}, auditOpts...)
+	}
+
+	if frameworkOpts.clock != nil {
+		auditOpts = append([]audit.Opt{audit.WithClock(frameworkOpts.clock)}, auditOpts...)
+	}
+
+	auditStore, err := audit.New(frameworkOpts.storeProvider, auditOpts...)
+	if err != nil {
+		return fmt.Errorf("audit store initialization failed : %w", err)
+	}
+
+	frameworkOpts.auditStore = auditStore
 
 	return nil
 }
 
+// setDefaultMessageMetrics creates the inbound message metrics if enabled, they are recorded with the metrics
+// provider if set
+func setDefaultMessageMetrics(frameworkOpts *Aries) {
+	if frameworkOpts.slowThreshold == nil {
+		return
+	}
+
+	var providers []metrics.Provider
+	if frameworkOpts.metricsProvider != nil {
+		providers = append(providers, frameworkOpts.metricsProvider)
+	}
+
+	frameworkOpts.metrics = dispatcher.NewMetrics(*frameworkOpts.slowThreshold, providers...)
+}
+
 func setDefaultOutboundDispatcher(frameworkOpts *Aries) {
 	if frameworkOpts.outboundDispatcherCreator == nil {
 		frameworkOpts.outboundDispatcherCreator = func(prv dispatcher.Provider) (dispatcher.Outbound, error) {
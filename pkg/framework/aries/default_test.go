@@ -13,6 +13,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/hyperledger/aries-framework-go/pkg/internal/mock/storage"
+	"github.com/hyperledger/aries-framework-go/pkg/storage/cache"
 )
 
 func TestDefaultFramework(t *testing.T) {
@@ -25,6 +26,7 @@ func TestDefaultFramework(t *testing.T) {
 
 		err := defFrameworkOpts(aries)
 		require.NoError(t, err)
+		require.IsType(t, &cache.Provider{}, aries.storeProvider)
 	})
 
 	t.Run("test default framework - store provider error", func(t *testing.T) {
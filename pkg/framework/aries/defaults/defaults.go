@@ -25,10 +25,11 @@ func WithStorePath(storePath string) aries.Option {
 	}
 }
 
-// WithInboundHTTPAddr return new default inbound transport.
-func WithInboundHTTPAddr(addr string) aries.Option {
+// WithInboundHTTPAddr return new default inbound transport, configured with the HTTP inbound options
+// (e.g. http.WithHealthCheck).
+func WithInboundHTTPAddr(addr string, inboundOpts ...http.InboundOpt) aries.Option {
 	return func(opts *aries.Aries) error {
-		inbound, err := http.NewInbound(addr)
+		inbound, err := http.NewInbound(addr, inboundOpts...)
 		if err != nil {
 			return fmt.Errorf("http inbound transport initialization failed : %w", err)
 		}
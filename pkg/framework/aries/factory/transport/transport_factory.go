@@ -15,15 +15,18 @@ import (
 
 // ProviderFactory represents the default transport provider factory.
 type ProviderFactory struct {
+	opts []httptransport.OutboundHTTPOpt
 }
 
-// NewProviderFactory returns the default transport provider factory.
-func NewProviderFactory() *ProviderFactory {
-	f := ProviderFactory{}
+// NewProviderFactory returns the default transport provider factory, the outbound HTTP transport is created
+// with the options, e.g. the proxy and the TLS configs of the network.
+func NewProviderFactory(opts ...httptransport.OutboundHTTPOpt) *ProviderFactory {
+	f := ProviderFactory{opts: opts}
 	return &f
 }
 
 // CreateOutboundTransport returns a new default implementation of outbound transport provider
 func (f *ProviderFactory) CreateOutboundTransport() (transport.OutboundTransport, error) {
-	return httptransport.NewOutbound(httptransport.WithOutboundHTTPClient(&http.Client{}))
+	opts := append([]httptransport.OutboundHTTPOpt{httptransport.WithOutboundHTTPClient(&http.Client{})}, f.opts...)
+	return httptransport.NewOutbound(opts...)
 }
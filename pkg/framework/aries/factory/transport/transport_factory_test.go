@@ -8,8 +8,11 @@ package transport
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
+
+	httptransport "github.com/hyperledger/aries-framework-go/pkg/didcomm/transport/http"
 )
 
 func TestNewProviderFactory(t *testing.T) {
@@ -18,3 +21,14 @@ func TestNewProviderFactory(t *testing.T) {
 	require.NoError(t, err)
 	require.NotEmpty(t, ot)
 }
+
+func TestNewProviderFactory_Options(t *testing.T) {
+	f := NewProviderFactory(httptransport.WithOutboundTimeout(time.Second))
+	ot, err := f.CreateOutboundTransport()
+	require.NoError(t, err)
+	require.NotEmpty(t, ot)
+
+	f = NewProviderFactory(httptransport.WithOutboundCABundle([]byte("not PEM")))
+	_, err = f.CreateOutboundTransport()
+	require.EqualError(t, err, "no certificate found in the CA bundle")
+}
@@ -7,17 +7,45 @@ SPDX-License-Identifier: Apache-2.0
 package aries
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"sync/atomic"
+	"time"
 
+	"github.com/hyperledger/aries-framework-go/pkg/cache"
+	"github.com/hyperledger/aries-framework-go/pkg/common/clock"
+	"github.com/hyperledger/aries-framework-go/pkg/common/idgen"
+	"github.com/hyperledger/aries-framework-go/pkg/common/log"
+	"github.com/hyperledger/aries-framework-go/pkg/common/metrics"
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/codec"
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/attachments"
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/audit"
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/threads"
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/crypto/jwe/authcrypt"
 	"github.com/hyperledger/aries-framework-go/pkg/didcomm/dispatcher"
 	"github.com/hyperledger/aries-framework-go/pkg/didcomm/transport"
 	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
 	"github.com/hyperledger/aries-framework-go/pkg/framework/aries/api"
 	"github.com/hyperledger/aries-framework-go/pkg/framework/context"
 	"github.com/hyperledger/aries-framework-go/pkg/framework/didresolver"
+	"github.com/hyperledger/aries-framework-go/pkg/framework/maintenance"
+	"github.com/hyperledger/aries-framework-go/pkg/framework/vdr"
+	"github.com/hyperledger/aries-framework-go/pkg/objectstore"
 	"github.com/hyperledger/aries-framework-go/pkg/storage"
+	"github.com/hyperledger/aries-framework-go/pkg/storage/migration"
+	"github.com/hyperledger/aries-framework-go/pkg/wallet"
 )
 
+const (
+	// statusStoreName is the name space of the store read by the status checks
+	statusStoreName = "status"
+	// statusUnavailable is the state of the failing components reported in the status
+	statusUnavailable = "unavailable"
+)
+
+var logger = log.New("aries-framework/framework")
+
 // DIDResolver interface for DID resolver.
 type DIDResolver interface {
 	Resolve(did string, opts ...didresolver.ResolveOpt) (*did.Doc, error)
@@ -27,14 +55,53 @@ type DIDResolver interface {
 type Aries struct {
 	transport                 api.TransportProviderFactory
 	didResolver               DIDResolver
+	vdrs                      []vdr.VDR
+	vdrRegistry               *vdr.Registry
 	storeProvider             storage.Provider
-	protocolSvcCreators       []api.ProtocolSvcCreator
+	cacheProvider             cache.Provider
+	protocolSvcs              []api.ProtocolSvc
 	services                  []dispatcher.Service
+	descriptors               []dispatcher.ServiceDescriptor
+	protocolRoles             map[string][]string
 	inboundTransport          transport.InboundTransport
+	inboundTransports         []transport.InboundTransport
 	walletCreator             api.WalletCreator
 	wallet                    api.CloseableWallet
 	outboundDispatcherCreator dispatcher.OutboundCreator
 	outboundDispatcher        dispatcher.Outbound
+	sizeLimits                *wallet.SizeLimits
+	walletRateLimits          wallet.RateLimits
+	walletKeyBackup           *wallet.KeyBackupConfig
+	threadStoreOpts           []threads.Opt
+	threadStore               *threads.Store
+	auditOpts                 []audit.Opt
+	auditStore                *audit.Store
+	attachments               *attachments.Offloader
+	attachmentFormats         *attachments.FormatRegistry
+	verifySender              bool
+	deterministicConnIDs      bool
+	featureFlags              []string
+	clock                     clock.Clock
+	idGenerator               idgen.Generator
+	sharedSecretCache         *authcrypt.SharedSecretCache
+	metrics                   *dispatcher.Metrics
+	slowThreshold             *time.Duration
+	metricsProvider           metrics.Provider
+	randSource                io.Reader
+	envelopeCompression       authcrypt.Compression
+	codecs                    []codec.Codec
+	migrations                map[string][]migration.Step
+	maintenanceInterval       time.Duration
+	maintenance               *maintenance.Scheduler
+	profileOpts               []profileOpts
+	profiles                  map[string]*Aries
+	started                   int32
+}
+
+// profileOpts are the options of a profile served by the framework
+type profileOpts struct {
+	name string
+	opts []Option
 }
 
 // Option configures the framework.
@@ -59,6 +126,12 @@ func New(opts ...Option) (*Aries, error) {
 		return nil, fmt.Errorf("default option initialization failed: %w", err)
 	}
 
+	// Upgrade the records of the stores before they are used
+	err = migrateStores(frameworkOpts)
+	if err != nil {
+		return nil, err
+	}
+
 	// TODO: https://github.com/hyperledger/aries-framework-go/issues/212
 	//  Define clear relationship between framework and context.
 	//  Details - The code creates context without protocolServices. The protocolServicesCreators are dependent
@@ -91,9 +164,27 @@ func New(opts ...Option) (*Aries, error) {
 		return nil, err
 	}
 
+	// Schedule the storage maintenance
+	err = startMaintenance(frameworkOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	atomic.StoreInt32(&frameworkOpts.started, 1)
+
 	return frameworkOpts, nil
 }
 
+// WithInboundTransports injects inbound transports started along with the inbound transport, e.g. a WebSocket
+// transport along with the HTTP transport. The endpoints of all the transports are advertised as services of the
+// DIDs created by the wallet, in order of priority: the inbound transport first, then these transports in order.
+func WithInboundTransports(inboundTransports ...transport.InboundTransport) Option {
+	return func(opts *Aries) error {
+		opts.inboundTransports = append(opts.inboundTransports, inboundTransports...)
+		return nil
+	}
+}
+
 // WithTransportProviderFactory injects a protocol provider factory interface to Aries
 func WithTransportProviderFactory(transportProv api.TransportProviderFactory) Option {
 	return func(opts *Aries) error {
@@ -102,6 +193,23 @@ func WithTransportProviderFactory(transportProv api.TransportProviderFactory) Op
 	}
 }
 
+// WithProfile serves the profile, e.g. a tenant with its own wallet, storage and protocol services, on the inbound
+// transport of the framework, e.g. the HTTP transport created with http.WithProfilePath. The profile framework is
+// created with the options on startup, its DIDs and invitations advertise the endpoint of the profile. The profiles
+// need their own storage, e.g. WithStoreProvider. The profile is returned by Profile and closed along with the
+// framework.
+func WithProfile(name string, opts ...Option) Option {
+	return func(a *Aries) error {
+		if name == "" {
+			return errors.New("profile name is empty")
+		}
+
+		a.profileOpts = append(a.profileOpts, profileOpts{name: name, opts: opts})
+
+		return nil
+	}
+}
+
 // WithInboundTransport injects a inbound transport to the Aries framework
 func WithInboundTransport(inboundTransport transport.InboundTransport) Option {
 	return func(opts *Aries) error {
@@ -118,6 +226,14 @@ func WithDIDResolver(didResolver DIDResolver) Option {
 	}
 }
 
+// WithVDR injects a VDR service to the Aries framework, used to publish DIDs of its method
+func WithVDR(v vdr.VDR) Option {
+	return func(opts *Aries) error {
+		opts.vdrs = append(opts.vdrs, v)
+		return nil
+	}
+}
+
 // WithStoreProvider injects a storage provider to the Aries framework
 func WithStoreProvider(prov storage.Provider) Option {
 	return func(opts *Aries) error {
@@ -126,10 +242,47 @@ func WithStoreProvider(prov storage.Provider) Option {
 	}
 }
 
+// WithCacheProvider injects the cache provider caching the connection records and the peer DID documents
+// of the Aries framework, the least recently used records are evicted from a bounded in-memory cache by default
+func WithCacheProvider(prov cache.Provider) Option {
+	return func(opts *Aries) error {
+		opts.cacheProvider = prov
+		return nil
+	}
+}
+
 // WithProtocols injects a protocol service to the Aries framework
 func WithProtocols(protocolSvcCreator ...api.ProtocolSvcCreator) Option {
 	return func(opts *Aries) error {
-		opts.protocolSvcCreators = append(opts.protocolSvcCreators, protocolSvcCreator...)
+		for _, create := range protocolSvcCreator {
+			opts.protocolSvcs = append(opts.protocolSvcs, api.ProtocolSvc{Create: create})
+		}
+
+		return nil
+	}
+}
+
+// WithDescribedProtocols injects protocol services with their descriptors to the Aries framework, the
+// descriptors are exposed as the capabilities of the agent
+func WithDescribedProtocols(protocolSvc ...api.ProtocolSvc) Option {
+	return func(opts *Aries) error {
+		opts.protocolSvcs = append(opts.protocolSvcs, protocolSvc...)
+		return nil
+	}
+}
+
+// WithProtocolRoles restricts the roles the agent plays in the protocol of the service (e.g. the verifier role
+// of the present-proof protocol), the inbound messages handled in the other roles are answered with a problem
+// report and the clients reject the actions taken in the other roles. The roles must be supported by the service,
+// the roles of the protocols without restrictions are all enabled.
+func WithProtocolRoles(svcName string, roles ...string) Option {
+	return func(opts *Aries) error {
+		if opts.protocolRoles == nil {
+			opts.protocolRoles = make(map[string][]string)
+		}
+
+		opts.protocolRoles[svcName] = roles
+
 		return nil
 	}
 }
@@ -150,11 +303,292 @@ func WithWallet(w api.WalletCreator) Option {
 	}
 }
 
+// WithMessageSizeLimits sets the maximum sizes in bytes of the envelopes and the message attachments,
+// zero disables the limit. Inbound messages exceeding the limits are rejected before decryption.
+func WithMessageSizeLimits(maxEnvelopeSize, maxAttachmentSize int64) Option {
+	return func(opts *Aries) error {
+		opts.sizeLimits = &wallet.SizeLimits{MaxEnvelopeSize: maxEnvelopeSize, MaxAttachmentSize: maxAttachmentSize}
+		return nil
+	}
+}
+
+// WithWalletRateLimits limits the signing operations of the wallet, the signing is locked out with an increasing
+// duration after repeated authentication failures, i.e. signing with keys the wallet doesn't hold. The lockouts
+// are notified to the channels registered with the RegisterLockoutEvent of the wallet.
+func WithWalletRateLimits(limits wallet.RateLimits) Option {
+	return func(opts *Aries) error {
+		opts.walletRateLimits = limits
+		return nil
+	}
+}
+
+// WithWalletKeyBackup backs up the private keys created or imported by the wallet to the store, e.g. a store
+// replicated off the device, to recover them after the loss of the device. The keys are envelope encrypted: each
+// key with a random data key encrypted by the encrypter, e.g. wallet.PublicKeyEncrypter to an offline recovery
+// key or wallet.KMSKeyWrapper to a cloud KMS key. A key isn't created if its backup fails. The keys are restored
+// with the RestoreKeys of the wallet.
+func WithWalletKeyBackup(encrypter wallet.KeyEncrypter, store storage.Store) Option {
+	return func(opts *Aries) error {
+		if encrypter == nil || store == nil {
+			return errors.New("key backup encrypter and store are required")
+		}
+
+		opts.walletKeyBackup = &wallet.KeyBackupConfig{Encrypter: encrypter, Store: store}
+
+		return nil
+	}
+}
+
+// WithDeterministicConnectionIDs derives the IDs of the connections completed by the DID exchange from the DIDs of
+// the parties, so an exchange retried between the same DIDs (e.g. by another instance of a clustered agent) doesn't
+// create a duplicate connection.
+func WithDeterministicConnectionIDs() Option {
+	return func(opts *Aries) error {
+		opts.deterministicConnIDs = true
+		return nil
+	}
+}
+
+// WithThreadCleanup sets the cleanup policies of the thread store shared by the protocol services.
+func WithThreadCleanup(policies ...threads.Opt) Option {
+	return func(opts *Aries) error {
+		opts.threadStoreOpts = append(opts.threadStoreOpts, policies...)
+		return nil
+	}
+}
+
+// WithSenderVerification rejects the inbound messages not sent with the keys of the counterparty of the thread.
+func WithSenderVerification() Option {
+	return func(opts *Aries) error {
+		opts.verifySender = true
+		return nil
+	}
+}
+
+// WithSharedSecretCache caches up to size shared secrets computed by the wallet to pack and unpack the messages
+// exchanged with the counterparties, the secrets expire after the ttl (zero disables the expiry).
+func WithSharedSecretCache(size int, ttl time.Duration) Option {
+	return func(opts *Aries) error {
+		opts.sharedSecretCache = authcrypt.NewSharedSecretCache(size, ttl)
+		return nil
+	}
+}
+
+// WithRandSource sets the random source of the wallet key generation and crypters. It must be cryptographically
+// secure in production, a deterministic reader makes the keys and envelopes reproducible in tests.
+func WithRandSource(r io.Reader) Option {
+	return func(opts *Aries) error {
+		opts.randSource = r
+		return nil
+	}
+}
+
+// WithEnvelopeCompression compresses the payloads packed by the wallet with the compression, e.g. authcrypt.DEF
+// to reduce the size of the envelopes of large JSON-LD credentials. The compression is advertised in the services
+// of the DID documents created by the wallet, the payloads are compressed only for the counterparties advertising
+// the same compression. The compressed envelopes are decompressed on unpack whatever the option, up to the
+// maximum envelope size.
+func WithEnvelopeCompression(zip authcrypt.Compression) Option {
+	return func(opts *Aries) error {
+		opts.envelopeCompression = zip
+		return nil
+	}
+}
+
+// WithMigrations registers the migration steps of the records of the store namespace, the steps not applied yet
+// to the database are applied on startup in version order. Refer to the migration package.
+func WithMigrations(namespace string, steps ...migration.Step) Option {
+	return func(opts *Aries) error {
+		if opts.migrations == nil {
+			opts.migrations = make(map[string][]migration.Step)
+		}
+
+		opts.migrations[namespace] = append(opts.migrations[namespace], steps...)
+
+		return nil
+	}
+}
+
+// WithAttachmentStore offloads the large payloads kept by the protocol services to the object store, e.g. the
+// forward messages queued by the route service, the protocol state keeps only their links and digests.
+func WithAttachmentStore(store objectstore.Store, opts ...attachments.Opt) Option {
+	return func(a *Aries) error {
+		a.attachments = attachments.New(store, opts...)
+		return nil
+	}
+}
+
+// WithAttachmentFormat registers the handler of the attachment format, e.g. a proof format such as the Indy proofs
+// or the ISO 18013-5 device responses. The formats are decoded by the protocol services with the AttachmentFormats
+// registry of the context.
+func WithAttachmentFormat(format string, handler attachments.FormatHandler) Option {
+	return func(a *Aries) error {
+		if a.attachmentFormats == nil {
+			a.attachmentFormats = attachments.NewFormatRegistry()
+		}
+
+		a.attachmentFormats.Register(format, handler)
+
+		return nil
+	}
+}
+
+// WithAuditTrail records the hash and the metadata of every outbound message in the audit store, the records
+// are queried with the AuditStore of the context.
+func WithAuditTrail(opts ...audit.Opt) Option {
+	return func(a *Aries) error {
+		a.auditOpts = append([]audit.Opt{}, opts...)
+		return nil
+	}
+}
+
+// WithMaintenanceSchedule runs the storage maintenance at the interval: the expired and completed threads are
+// purged as configured by WithThreadCleanup, then the stores are compacted if the storage provider supports it.
+func WithMaintenanceSchedule(interval time.Duration) Option {
+	return func(opts *Aries) error {
+		if interval <= 0 {
+			return errors.New("maintenance interval must be positive")
+		}
+
+		opts.maintenanceInterval = interval
+
+		return nil
+	}
+}
+
+// WithCodecs sets the payload codecs available in addition to JSON, e.g. codec.CBOR to reduce the size of
+// the messages sent over the constrained transports. The codecs are advertised in the services of the DIDs
+// created by the wallet, the messages of a connection are encoded with the first codec advertised by the
+// counterparty which is available, in JSON if none is.
+func WithCodecs(codecs ...codec.Codec) Option {
+	return func(opts *Aries) error {
+		opts.codecs = codecs
+		return nil
+	}
+}
+
+// WithMessageMetrics records the counts and handling durations of the inbound messages per message type,
+// the handlers taking longer than slowThreshold are logged (zero disables the slow handler detection). The
+// messages are also recorded with the provider of WithMetricsProvider.
+func WithMessageMetrics(slowThreshold time.Duration) Option {
+	return func(opts *Aries) error {
+		opts.slowThreshold = &slowThreshold
+		return nil
+	}
+}
+
+// WithMetricsProvider records the metrics of the inbound and outbound messages and of the transports with the
+// metrics provider, e.g. statsd.NewProvider to send the metrics to a StatsD server.
+func WithMetricsProvider(prov metrics.Provider) Option {
+	return func(opts *Aries) error {
+		opts.metricsProvider = prov
+		return nil
+	}
+}
+
+// WithFeatureFlags enables the feature flags of the experimental protocols and algorithms, e.g.
+// api.FeatureDIDCommV2. The protocol services registered with a feature flag are only loaded if it's enabled.
+func WithFeatureFlags(flags ...string) Option {
+	return func(opts *Aries) error {
+		for _, flag := range flags {
+			if flag == "" {
+				return errors.New("feature flag is empty")
+			}
+		}
+
+		opts.featureFlags = append(opts.featureFlags, flags...)
+
+		return nil
+	}
+}
+
+// WithClock sets the clock of the framework, used by the protocol services and the thread store instead of the
+// system clock, e.g. to get deterministic times in the tests.
+func WithClock(c clock.Clock) Option {
+	return func(opts *Aries) error {
+		if c == nil {
+			return errors.New("clock is nil")
+		}
+
+		opts.clock = c
+
+		return nil
+	}
+}
+
+// WithIDGenerator sets the generator of the IDs of the messages, connections and invitations created by the
+// protocol services and clients, e.g. to get IDs sortable by creation time or deterministic IDs in the tests.
+// The random UUIDs are generated by default.
+func WithIDGenerator(g idgen.Generator) Option {
+	return func(opts *Aries) error {
+		if g == nil {
+			return errors.New("ID generator is nil")
+		}
+
+		opts.idGenerator = g
+
+		return nil
+	}
+}
+
+// FeatureEnabled returns true if the feature flag is enabled
+func (a *Aries) FeatureEnabled(flag string) bool {
+	return contains(a.featureFlags, flag)
+}
+
+// Status returns the status of the framework reported by the health check endpoints of the inbound transports:
+// the framework is ready once started until closed, and healthy while its storage is available.
+func (a *Aries) Status() transport.Status {
+	status := transport.Status{Ready: atomic.LoadInt32(&a.started) == 1}
+
+	if err := checkStorage(a.storeProvider); err != nil {
+		logger.Errorf("storage health check failed: %s", err)
+
+		status.Checks = map[string]string{"storage": statusUnavailable}
+	}
+
+	return status
+}
+
+// checkStorage reads a record to check the storage is available
+func checkStorage(prov storage.Provider) error {
+	if prov == nil {
+		return errors.New("storage provider is not configured")
+	}
+
+	store, err := prov.OpenStore(statusStoreName)
+	if err != nil {
+		return err
+	}
+
+	_, err = store.Get(statusStoreName)
+	if err != nil && !errors.Is(err, storage.ErrDataNotFound) {
+		return err
+	}
+
+	return nil
+}
+
+// Profile returns the framework of the profile served with WithProfile, nil if the profile isn't served.
+func (a *Aries) Profile(name string) *Aries {
+	return a.profiles[name]
+}
+
 // DIDResolver returns the framework configured DID Resolver.
 func (a *Aries) DIDResolver() DIDResolver {
 	return a.didResolver
 }
 
+// Metrics returns the metrics of the inbound messages, nil if not enabled with WithMessageMetrics.
+func (a *Aries) Metrics() *dispatcher.Metrics {
+	return a.metrics
+}
+
+// VDRRegistry returns the framework configured VDR registry.
+func (a *Aries) VDRRegistry() *vdr.Registry {
+	return a.vdrRegistry
+}
+
 // Context provides handle to framework context
 func (a *Aries) Context() (*context.Provider, error) {
 	ot, err := a.transport.CreateOutboundTransport()
@@ -164,14 +598,59 @@ func (a *Aries) Context() (*context.Provider, error) {
 	return context.New(
 		context.WithOutboundDispatcher(a.outboundDispatcher),
 		context.WithOutboundTransport(ot), context.WithProtocolServices(a.services...),
+		context.WithServiceDescriptors(a.descriptors...), withProtocolRoles(a.protocolRoles),
 		// TODO configure inbound external endpoints
 		context.WithWallet(a.wallet), context.WithInboundTransportEndpoint(a.inboundTransport.Endpoint()),
-		context.WithStorageProvider(a.storeProvider),
+		context.WithInboundTransports(a.allInboundTransports()...),
+		context.WithStorageProvider(a.storeProvider), context.WithVDRRegistry(a.vdrRegistry),
+		context.WithMessageSizeLimits(*a.sizeLimits), context.WithThreadStore(a.threadStore),
+		withSenderVerification(a.verifySender), context.WithMetricsProvider(a.metricsProvider),
+		context.WithStatus(a.Status), context.WithAuditStore(a.auditStore), context.WithAttachments(a.attachments),
+		context.WithAttachmentFormats(a.attachmentFormats),
+		context.WithFeatureFlags(a.featureFlags...), context.WithClock(a.clock),
+		context.WithIDGenerator(a.idGenerator),
 	)
 }
 
+// withDeterministicConnectionIDs derives the connection IDs in the context if enabled
+func withDeterministicConnectionIDs(enabled bool) context.ProviderOption {
+	if enabled {
+		return context.WithDeterministicConnectionIDs()
+	}
+
+	return func(*context.Provider) error { return nil }
+}
+
+// withProtocolRoles restricts the protocol roles of the context
+func withProtocolRoles(protocolRoles map[string][]string) context.ProviderOption {
+	return func(prov *context.Provider) error {
+		for name, roles := range protocolRoles {
+			if err := context.WithProtocolRoles(name, roles...)(prov); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
+
+// withSenderVerification enables the sender verification of the context if configured
+func withSenderVerification(enabled bool) context.ProviderOption {
+	if enabled {
+		return context.WithSenderVerification()
+	}
+
+	return func(*context.Provider) error { return nil }
+}
+
 // Close frees resources being maintained by the framework.
 func (a *Aries) Close() error {
+	atomic.StoreInt32(&a.started, 0)
+
+	if a.maintenance != nil {
+		a.maintenance.Stop()
+	}
+
 	if a.wallet != nil {
 		err := a.wallet.Close()
 		if err != nil {
@@ -184,18 +663,89 @@ func (a *Aries) Close() error {
 			return fmt.Errorf("failed to close the store: %w", err)
 		}
 	}
+	if a.cacheProvider != nil {
+		err := a.cacheProvider.Close()
+		if err != nil {
+			return fmt.Errorf("failed to close the cache: %w", err)
+		}
+	}
 
-	if a.inboundTransport != nil {
-		if err := a.inboundTransport.Stop(); err != nil {
+	for _, inbound := range a.allInboundTransports() {
+		if err := inbound.Stop(); err != nil {
 			return fmt.Errorf("inbound transport close failed: %w", err)
 		}
 	}
+
+	for name, profile := range a.profiles {
+		if err := profile.Close(); err != nil {
+			return fmt.Errorf("failed to close profile %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// allInboundTransports returns the inbound transport followed by the additional inbound transports
+func (a *Aries) allInboundTransports() []transport.InboundTransport {
+	var transports []transport.InboundTransport
+
+	if a.inboundTransport != nil {
+		transports = append(transports, a.inboundTransport)
+	}
+
+	return append(transports, a.inboundTransports...)
+}
+
+func migrateStores(frameworkOpts *Aries) error {
+	if len(frameworkOpts.migrations) == 0 {
+		return nil
+	}
+
+	migrator, err := migration.New(frameworkOpts.storeProvider)
+	if err != nil {
+		return fmt.Errorf("create migrator failed: %w", err)
+	}
+
+	for namespace, steps := range frameworkOpts.migrations {
+		if err = migrator.Register(namespace, steps...); err != nil {
+			return fmt.Errorf("register migrations failed: %w", err)
+		}
+	}
+
+	if err = migrator.Migrate(); err != nil {
+		return fmt.Errorf("store migration failed: %w", err)
+	}
+
+	return nil
+}
+
+func startMaintenance(frameworkOpts *Aries) error {
+	if frameworkOpts.maintenanceInterval == 0 {
+		return nil
+	}
+
+	ctx, err := context.New(context.WithStorageProvider(frameworkOpts.storeProvider),
+		context.WithThreadStore(frameworkOpts.threadStore))
+	if err != nil {
+		return fmt.Errorf("context creation failed: %w", err)
+	}
+
+	frameworkOpts.maintenance = maintenance.NewScheduler(ctx, frameworkOpts.maintenanceInterval)
+	frameworkOpts.maintenance.Start()
+
 	return nil
 }
 
 func createWallet(frameworkOpts *Aries) error {
 	ctx, err := context.New(context.WithInboundTransportEndpoint(frameworkOpts.inboundTransport.Endpoint()),
-		context.WithStorageProvider(frameworkOpts.storeProvider))
+		context.WithInboundTransports(frameworkOpts.allInboundTransports()...),
+		context.WithStorageProvider(frameworkOpts.storeProvider), context.WithVDRRegistry(frameworkOpts.vdrRegistry),
+		context.WithMessageSizeLimits(*frameworkOpts.sizeLimits),
+		context.WithWalletRateLimits(frameworkOpts.walletRateLimits),
+		context.WithWalletKeyBackup(frameworkOpts.walletKeyBackup),
+		context.WithSharedSecretCache(frameworkOpts.sharedSecretCache),
+		context.WithRandSource(frameworkOpts.randSource),
+		context.WithEnvelopeCompression(frameworkOpts.envelopeCompression), context.WithCodecs(frameworkOpts.codecs...))
 	if err != nil {
 		return fmt.Errorf("create context failed: %w", err)
 	}
@@ -211,7 +761,9 @@ func createOutboundDispatcher(frameworkOpts *Aries) error {
 	if err != nil {
 		return fmt.Errorf("outbound transport initialization failed: %w", err)
 	}
-	ctx, err := context.New(context.WithWallet(frameworkOpts.wallet), context.WithOutboundTransport(ot))
+	ctx, err := context.New(context.WithWallet(frameworkOpts.wallet), context.WithOutboundTransport(ot),
+		context.WithCodecs(frameworkOpts.codecs...), context.WithMetricsProvider(frameworkOpts.metricsProvider),
+		context.WithAuditStore(frameworkOpts.auditStore))
 	if err != nil {
 		return fmt.Errorf("context creation failed: %w", err)
 	}
@@ -223,31 +775,256 @@ func createOutboundDispatcher(frameworkOpts *Aries) error {
 }
 
 func startInboundTransport(frameworkOpts *Aries) error {
+	if err := createProfiles(frameworkOpts); err != nil {
+		return err
+	}
+
 	ctx, err := context.New(context.WithWallet(frameworkOpts.wallet),
 		context.WithInboundTransportEndpoint(frameworkOpts.inboundTransport.Endpoint()),
-		context.WithProtocolServices(frameworkOpts.services...))
+		context.WithInboundTransports(frameworkOpts.allInboundTransports()...),
+		context.WithProtocolServices(frameworkOpts.services...),
+		context.WithServiceDescriptors(frameworkOpts.descriptors...), withProtocolRoles(frameworkOpts.protocolRoles),
+		context.WithMessageSizeLimits(*frameworkOpts.sizeLimits), context.WithThreadStore(frameworkOpts.threadStore),
+		withSenderVerification(frameworkOpts.verifySender), context.WithMetrics(frameworkOpts.metrics),
+		context.WithMetricsProvider(frameworkOpts.metricsProvider), context.WithCodecs(frameworkOpts.codecs...),
+		context.WithStatus(frameworkOpts.Status), context.WithFeatureFlags(frameworkOpts.featureFlags...),
+		context.WithClock(frameworkOpts.clock), context.WithIDGenerator(frameworkOpts.idGenerator),
+		withProfiles(frameworkOpts.profiles))
 	if err != nil {
 		return fmt.Errorf("context creation failed: %w", err)
 	}
-	// Start the inbound transport
-	if err = frameworkOpts.inboundTransport.Start(ctx); err != nil {
-		return fmt.Errorf("inbound transport start failed: %w", err)
+	// Start the inbound transports, the started transports are stopped if a transport fails to start
+	inbounds := frameworkOpts.allInboundTransports()
+
+	for i, inbound := range inbounds {
+		if err = inbound.Start(ctx); err != nil {
+			for _, started := range inbounds[:i] {
+				if e := started.Stop(); e != nil {
+					return fmt.Errorf("stop err: %v inbound transport start failed: %w", e, err)
+				}
+			}
+
+			return fmt.Errorf("inbound transport start failed: %w", err)
+		}
 	}
+
 	return nil
 }
 
+// createProfiles creates the frameworks of the profiles served on the inbound transport, the profiles receive
+// their envelopes through the inbound transport of the framework
+func createProfiles(frameworkOpts *Aries) error {
+	if len(frameworkOpts.profileOpts) == 0 {
+		return nil
+	}
+
+	endpointer, ok := frameworkOpts.inboundTransport.(transport.ProfileEndpointer)
+	if !ok {
+		return errors.New("inbound transport doesn't serve profiles")
+	}
+
+	frameworkOpts.profiles = make(map[string]*Aries)
+
+	for _, p := range frameworkOpts.profileOpts {
+		endpoint := endpointer.ProfileEndpoint(p.name)
+		if endpoint == frameworkOpts.inboundTransport.Endpoint() {
+			return fmt.Errorf("inbound transport doesn't serve profile %s on its own endpoint", p.name)
+		}
+
+		opts := append(append([]Option{}, p.opts...), WithInboundTransport(&profileInbound{endpoint: endpoint}))
+
+		profile, err := New(opts...)
+		if err != nil {
+			closeProfiles(frameworkOpts.profiles)
+
+			return fmt.Errorf("failed to create profile %s: %w", p.name, err)
+		}
+
+		frameworkOpts.profiles[p.name] = profile
+	}
+
+	return nil
+}
+
+// closeProfiles closes the profiles created before a profile failed
+func closeProfiles(profiles map[string]*Aries) {
+	for name, profile := range profiles {
+		if err := profile.Close(); err != nil {
+			logger.Warnf("failed to close profile %s: %s", name, err)
+		}
+	}
+}
+
+// withProfiles serves the profiles with the inbound transports of the context
+func withProfiles(profiles map[string]*Aries) context.ProviderOption {
+	return func(prov *context.Provider) error {
+		for name, profile := range profiles {
+			ctx, err := profile.Context()
+			if err != nil {
+				return fmt.Errorf("failed to create context of profile %s: %w", name, err)
+			}
+
+			if err = context.WithProfile(name, ctx)(prov); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
+
+// profileInbound is the inbound transport of a profile, the envelopes of the profile are received by the inbound
+// transport of the framework serving the profile
+type profileInbound struct {
+	endpoint string
+}
+
+func (p *profileInbound) Start(transport.InboundProvider) error {
+	return nil
+}
+
+func (p *profileInbound) Stop() error {
+	return nil
+}
+
+func (p *profileInbound) Endpoint() string {
+	return p.endpoint
+}
+
 func loadServices(frameworkOpts *Aries) error {
 	ctx, err := context.New(context.WithOutboundDispatcher(frameworkOpts.outboundDispatcher),
-		context.WithWallet(frameworkOpts.wallet), context.WithStorageProvider(frameworkOpts.storeProvider))
+		context.WithWallet(frameworkOpts.wallet), context.WithStorageProvider(frameworkOpts.storeProvider),
+		context.WithThreadStore(frameworkOpts.threadStore), context.WithAttachments(frameworkOpts.attachments),
+		context.WithAttachmentFormats(frameworkOpts.attachmentFormats),
+		withDeterministicConnectionIDs(frameworkOpts.deterministicConnIDs),
+		context.WithFeatureFlags(frameworkOpts.featureFlags...), context.WithClock(frameworkOpts.clock),
+		context.WithIDGenerator(frameworkOpts.idGenerator))
 	if err != nil {
 		return fmt.Errorf("create context failed: %w", err)
 	}
-	for _, v := range frameworkOpts.protocolSvcCreators {
-		svc, svcErr := v(ctx)
+
+	var protocolSvcs []api.ProtocolSvc
+
+	var registered []dispatcher.ServiceDescriptor
+
+	for _, v := range frameworkOpts.protocolSvcs {
+		if v.Feature == "" || frameworkOpts.FeatureEnabled(v.Feature) {
+			protocolSvcs = append(protocolSvcs, v)
+			registered = append(registered, v.Descriptor)
+		}
+	}
+
+	// the services registered with their descriptors are checked before any service is created, the other ones
+	// as soon as they are described so no other service is created
+	if err = checkDuplicateServices(registered); err != nil {
+		return err
+	}
+
+	for _, v := range protocolSvcs {
+		svc, svcErr := v.Create(ctx)
 		if svcErr != nil {
 			return fmt.Errorf("new protocol service failed: %w", svcErr)
 		}
+
+		descriptor, descErr := describe(v.Descriptor, svc)
+		if descErr != nil {
+			return descErr
+		}
+
+		if err = checkDuplicateServices(append(frameworkOpts.descriptors, descriptor)); err != nil {
+			return err
+		}
+
 		frameworkOpts.services = append(frameworkOpts.services, svc)
+		frameworkOpts.descriptors = append(frameworkOpts.descriptors, descriptor)
+	}
+
+	return checkProtocolRoles(frameworkOpts.protocolRoles, frameworkOpts.descriptors)
+}
+
+// describe completes the descriptor the service has been registered with by the description of the service
+func describe(registered dispatcher.ServiceDescriptor, svc dispatcher.Service) (dispatcher.ServiceDescriptor, error) {
+	described := dispatcher.Describe(svc)
+
+	if registered.Name == "" {
+		registered.Name = described.Name
+	}
+
+	if registered.Name != described.Name {
+		return registered, fmt.Errorf("protocol service %s registered with descriptor %s", described.Name,
+			registered.Name)
 	}
+
+	if registered.Version == "" {
+		registered.Version = described.Version
+	}
+
+	if len(registered.MessageTypes) == 0 {
+		registered.MessageTypes = described.MessageTypes
+	}
+
+	if len(registered.Roles) == 0 {
+		registered.Roles = described.Roles
+	}
+
+	return registered, nil
+}
+
+// checkProtocolRoles checks the configured roles are supported by the protocol services
+func checkProtocolRoles(protocolRoles map[string][]string, descriptors []dispatcher.ServiceDescriptor) error {
+	supported := make(map[string][]string)
+	for _, descriptor := range descriptors {
+		supported[descriptor.Name] = descriptor.Roles
+	}
+
+	for name, roles := range protocolRoles {
+		svcRoles, ok := supported[name]
+		if !ok {
+			return fmt.Errorf("roles configured for unknown protocol service %s", name)
+		}
+
+		for _, role := range roles {
+			if !contains(svcRoles, role) {
+				return fmt.Errorf("role %s isn't supported by protocol service %s", role, name)
+			}
+		}
+	}
+
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkDuplicateServices checks the names and the message types of the protocol services are unique, the names
+// not known yet are not checked
+func checkDuplicateServices(descriptors []dispatcher.ServiceDescriptor) error {
+	names := make(map[string]bool)
+	msgTypes := make(map[string]string)
+
+	for _, descriptor := range descriptors {
+		if descriptor.Name != "" && names[descriptor.Name] {
+			return fmt.Errorf("%w: %s registered twice", api.ErrDuplicateSvc, descriptor.Name)
+		}
+
+		names[descriptor.Name] = true
+
+		for _, msgType := range descriptor.MessageTypes {
+			if other, ok := msgTypes[msgType]; ok {
+				return fmt.Errorf("%w: message type %s accepted by %s and %s", api.ErrDuplicateSvc, msgType,
+					other, descriptor.Name)
+			}
+
+			msgTypes[msgType] = descriptor.Name
+		}
+	}
+
 	return nil
 }
@@ -7,30 +7,53 @@ SPDX-License-Identifier: Apache-2.0
 package aries
 
 import (
+	"context"
+	"crypto/rand"
 	"errors"
 	"fmt"
 	"io/ioutil"
+	insecurerand "math/rand"
 	"net"
 	"net/http"
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
-
+	"golang.org/x/crypto/nacl/box"
+
+	"github.com/hyperledger/aries-framework-go/pkg/cache/mem"
+	"github.com/hyperledger/aries-framework-go/pkg/common/idgen"
+	"github.com/hyperledger/aries-framework-go/pkg/common/metrics"
+	memmetrics "github.com/hyperledger/aries-framework-go/pkg/common/metrics/mem"
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/codec"
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/attachments"
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/audit"
 	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/service"
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/threads"
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/crypto/jwe/authcrypt"
 	"github.com/hyperledger/aries-framework-go/pkg/didcomm/dispatcher"
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/protocol/decorator"
 	"github.com/hyperledger/aries-framework-go/pkg/didcomm/protocol/didexchange"
 	"github.com/hyperledger/aries-framework-go/pkg/didcomm/transport"
 	"github.com/hyperledger/aries-framework-go/pkg/didmethod/peer"
 	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
 	"github.com/hyperledger/aries-framework-go/pkg/framework/aries/api"
+	ariescontext "github.com/hyperledger/aries-framework-go/pkg/framework/context"
 	"github.com/hyperledger/aries-framework-go/pkg/framework/didresolver"
+	"github.com/hyperledger/aries-framework-go/pkg/framework/vdr"
+	mockclock "github.com/hyperledger/aries-framework-go/pkg/internal/mock/clock"
 	"github.com/hyperledger/aries-framework-go/pkg/internal/mock/didcomm"
 	mockdispatcher "github.com/hyperledger/aries-framework-go/pkg/internal/mock/didcomm/dispatcher"
 	"github.com/hyperledger/aries-framework-go/pkg/internal/mock/didcomm/protocol"
+	mockstorage "github.com/hyperledger/aries-framework-go/pkg/internal/mock/storage"
 	mockwallet "github.com/hyperledger/aries-framework-go/pkg/internal/mock/wallet"
+	"github.com/hyperledger/aries-framework-go/pkg/objectstore/fs"
+	"github.com/hyperledger/aries-framework-go/pkg/storage"
 	"github.com/hyperledger/aries-framework-go/pkg/storage/leveldb"
+	"github.com/hyperledger/aries-framework-go/pkg/storage/migration"
+	"github.com/hyperledger/aries-framework-go/pkg/wallet"
 )
 
 //nolint:lll
@@ -92,7 +115,7 @@ func TestFramework(t *testing.T) {
 		ctx, err := aries.Context()
 		require.NoError(t, err)
 
-		e := ctx.OutboundDispatcher().Send([]byte("Hello World"), "", &service.Destination{ServiceEndpoint: serverURL})
+		e := ctx.OutboundDispatcher().Send(context.Background(), []byte("Hello World"), "", &service.Destination{ServiceEndpoint: serverURL})
 		require.NoError(t, e)
 	})
 
@@ -111,7 +134,7 @@ func TestFramework(t *testing.T) {
 		ctx, err := aries.Context()
 		require.NoError(t, err)
 
-		e := ctx.OutboundDispatcher().Send([]byte("Hello World"), "", &service.Destination{})
+		e := ctx.OutboundDispatcher().Send(context.Background(), []byte("Hello World"), "", &service.Destination{})
 		require.NoError(t, e)
 	})
 
@@ -193,6 +216,36 @@ func TestFramework(t *testing.T) {
 		require.NoError(t, err)
 	})
 
+	t.Run("test VDR registry - publish public DIDs", func(t *testing.T) {
+		v := &mockVDR{method: "example"}
+		aries, err := New(WithVDR(v), WithInboundTransport(&mockInboundTransport{}))
+		require.NoError(t, err)
+		require.NotNil(t, aries.VDRRegistry())
+
+		ctx, err := aries.Context()
+		require.NoError(t, err)
+
+		// user provided VDR
+		publicDoc, err := ctx.DIDWallet().CreateDID("example", wallet.WithPublic())
+		require.NoError(t, err)
+		require.Equal(t, publicDoc, v.createdDoc)
+
+		// default peer VDR
+		peerDoc, err := ctx.DIDWallet().CreateDID("peer", wallet.WithPublic())
+		require.NoError(t, err)
+
+		resolvedDoc, err := aries.DIDResolver().Resolve(peerDoc.ID)
+		require.NoError(t, err)
+		require.Equal(t, peerDoc.ID, resolvedDoc.ID)
+
+		_, err = ctx.DIDWallet().CreateDID("unknown", wallet.WithPublic())
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "did method unknown not supported for vdr")
+
+		err = aries.Close()
+		require.NoError(t, err)
+	})
+
 	t.Run("test protocol svc - with default protocol", func(t *testing.T) {
 		aries, err := New(WithInboundTransport(&mockInboundTransport{}))
 		require.NoError(t, err)
@@ -263,6 +316,125 @@ func TestFramework(t *testing.T) {
 		require.Contains(t, err.Error(), "error creating the protocol")
 	})
 
+	t.Run("test new with described protocol service", func(t *testing.T) {
+		path, cleanup := generateTempDir(t)
+		defer cleanup()
+		dbPath = path
+
+		mockSvcCreator := func(prv api.Provider) (dispatcher.Service, error) {
+			return &protocol.MockDIDExchangeSvc{ProtocolName: "mockProtocolSvc"}, nil
+		}
+
+		aries, err := New(WithDescribedProtocols(api.ProtocolSvc{
+			Descriptor: dispatcher.ServiceDescriptor{
+				Version: "1.0", Roles: []string{"issuer"}, MessageTypes: []string{"mock-type"}},
+			Create: mockSvcCreator,
+		}), WithInboundTransport(&mockInboundTransport{}))
+		require.NoError(t, err)
+
+		prov, err := aries.Context()
+		require.NoError(t, err)
+
+		descriptors := prov.Services()
+		require.Len(t, descriptors, 2)
+		require.Equal(t, dispatcher.ServiceDescriptor{Name: "mockProtocolSvc", Version: "1.0",
+			Roles: []string{"issuer"}, MessageTypes: []string{"mock-type"}}, descriptors[0])
+		require.Equal(t, didexchange.DIDExchange, descriptors[1].Name)
+		require.Equal(t, []string{didexchange.RoleInviter, didexchange.RoleInvitee}, descriptors[1].Roles)
+
+		require.NoError(t, aries.Close())
+	})
+
+	t.Run("test invalid protocol service registrations", func(t *testing.T) {
+		mockSvcCreator := func(prv api.Provider) (dispatcher.Service, error) {
+			return &protocol.MockDIDExchangeSvc{ProtocolName: "mockProtocolSvc"}, nil
+		}
+
+		// the framework isn't closed when the creation fails, every creation uses its own store
+		path, cleanup := generateTempDir(t)
+		defer cleanup()
+		dbPath = path
+
+		_, err := New(WithProtocols(mockSvcCreator, mockSvcCreator), WithInboundTransport(&mockInboundTransport{}))
+		require.True(t, errors.Is(err, api.ErrDuplicateSvc))
+		require.Contains(t, err.Error(), "mockProtocolSvc registered twice")
+
+		path, cleanup = generateTempDir(t)
+		defer cleanup()
+		dbPath = path
+
+		_, err = New(WithDescribedProtocols(api.ProtocolSvc{
+			Descriptor: dispatcher.ServiceDescriptor{MessageTypes: []string{didexchange.ConnectionRequest}},
+			Create:     mockSvcCreator,
+		}), WithInboundTransport(&mockInboundTransport{}))
+		require.True(t, errors.Is(err, api.ErrDuplicateSvc))
+		require.Contains(t, err.Error(), "accepted by mockProtocolSvc and didexchange")
+
+		path, cleanup = generateTempDir(t)
+		defer cleanup()
+		dbPath = path
+
+		_, err = New(WithDescribedProtocols(api.ProtocolSvc{
+			Descriptor: dispatcher.ServiceDescriptor{Name: "otherSvc"},
+			Create:     mockSvcCreator,
+		}), WithInboundTransport(&mockInboundTransport{}))
+		require.EqualError(t, err, "protocol service mockProtocolSvc registered with descriptor otherSvc")
+
+		path, cleanup = generateTempDir(t)
+		defer cleanup()
+		dbPath = path
+
+		// the duplicate registered descriptors are rejected before any service is created
+		created := 0
+		countingCreator := func(prv api.Provider) (dispatcher.Service, error) {
+			created++
+			return mockSvcCreator(prv)
+		}
+
+		_, err = New(WithDescribedProtocols(api.ProtocolSvc{
+			Descriptor: dispatcher.ServiceDescriptor{Name: "mockProtocolSvc"},
+			Create:     countingCreator,
+		}, api.ProtocolSvc{
+			Descriptor: dispatcher.ServiceDescriptor{Name: "mockProtocolSvc"},
+			Create:     countingCreator,
+		}), WithInboundTransport(&mockInboundTransport{}))
+		require.True(t, errors.Is(err, api.ErrDuplicateSvc))
+		require.Zero(t, created)
+	})
+
+	t.Run("test protocol roles", func(t *testing.T) {
+		path, cleanup := generateTempDir(t)
+		defer cleanup()
+		dbPath = path
+
+		aries, err := New(WithProtocolRoles(didexchange.DIDExchange, didexchange.RoleInvitee),
+			WithInboundTransport(&mockInboundTransport{}))
+		require.NoError(t, err)
+
+		prov, err := aries.Context()
+		require.NoError(t, err)
+
+		// the exchange requests are answered with a problem report as the agent isn't an inviter
+		err = prov.InboundMessageHandler()(context.Background(), &wallet.Envelope{
+			Message: []byte(fmt.Sprintf(`{"@type": "%s", "@id": "thid1"}`, didexchange.ConnectionRequest))})
+		require.True(t, errors.Is(err, ariescontext.ErrRoleDisabled))
+		require.NoError(t, aries.Close())
+
+		path, cleanup = generateTempDir(t)
+		defer cleanup()
+		dbPath = path
+
+		_, err = New(WithProtocolRoles(didexchange.DIDExchange, "issuer"), WithInboundTransport(&mockInboundTransport{}))
+		require.EqualError(t, err, "role issuer isn't supported by protocol service didexchange")
+
+		path, cleanup = generateTempDir(t)
+		defer cleanup()
+		dbPath = path
+
+		_, err = New(WithProtocolRoles("unknown", "issuer"), WithInboundTransport(&mockInboundTransport{}))
+		require.EqualError(t, err, "roles configured for unknown protocol service unknown")
+	})
+
 	t.Run("test Inbound transport - with options", func(t *testing.T) {
 		path, cleanup := generateTempDir(t)
 		defer cleanup()
@@ -316,6 +488,52 @@ func TestFramework(t *testing.T) {
 		require.Contains(t, err.Error(), "inbound transport close failed")
 	})
 
+	t.Run("test profiles served on the inbound transport", func(t *testing.T) {
+		inbound := &mockProfileInboundTransport{mockInboundTransport: mockInboundTransport{endpoint: "http://agent"}}
+
+		aries, err := New(WithInboundTransport(inbound), WithStoreProvider(mockstorage.NewMockStoreProvider()),
+			WithProfile("tenant1", WithStoreProvider(mockstorage.NewMockStoreProvider())))
+		require.NoError(t, err)
+
+		profile := aries.Profile("tenant1")
+		require.NotNil(t, profile)
+		require.Nil(t, aries.Profile("tenant2"))
+
+		// the profile advertises its endpoint
+		ctx, err := profile.Context()
+		require.NoError(t, err)
+		require.Equal(t, "http://agent/agent/tenant1", ctx.InboundTransportEndpoint())
+
+		// the envelopes of the profile are dispatched by the provider of the profile
+		profiles, ok := inbound.prov.(transport.ProfileProvider)
+		require.True(t, ok)
+
+		profileProv, err := profiles.ProfileInboundProvider("tenant1")
+		require.NoError(t, err)
+		require.NotNil(t, profileProv.InboundMessageHandler())
+
+		_, err = profiles.ProfileInboundProvider("tenant2")
+		require.True(t, errors.Is(err, transport.ErrProfileNotFound))
+
+		require.NoError(t, aries.Close())
+	})
+
+	t.Run("test profiles errors", func(t *testing.T) {
+		_, err := New(WithProfile(""))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "profile name is empty")
+
+		_, err = New(WithInboundTransport(&mockInboundTransport{}),
+			WithStoreProvider(mockstorage.NewMockStoreProvider()), WithProfile("tenant1"))
+		require.EqualError(t, err, "inbound transport doesn't serve profiles")
+
+		_, err = New(WithInboundTransport(&mockProfileInboundTransport{}),
+			WithStoreProvider(mockstorage.NewMockStoreProvider()), WithProfile("tenant1",
+				WithStoreProvider(mockstorage.NewMockStoreProvider()), WithFeatureFlags("")))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to create profile tenant1")
+	})
+
 	t.Run("test wallet svc - with user provided wallet", func(t *testing.T) {
 		path, cleanup := generateTempDir(t)
 		defer cleanup()
@@ -348,6 +566,450 @@ func TestFramework(t *testing.T) {
 		require.Error(t, err)
 		require.Contains(t, err.Error(), "error from wallet")
 	})
+
+	t.Run("test message size limits", func(t *testing.T) {
+		aries, err := New(WithInboundTransport(&mockInboundTransport{}),
+			WithStoreProvider(mockstorage.NewMockStoreProvider()))
+		require.NoError(t, err)
+
+		ctx, err := aries.Context()
+		require.NoError(t, err)
+		require.Equal(t, wallet.DefaultSizeLimits(), ctx.MessageSizeLimits())
+		require.NoError(t, aries.Close())
+
+		aries, err = New(WithInboundTransport(&mockInboundTransport{}),
+			WithStoreProvider(mockstorage.NewMockStoreProvider()), WithMessageSizeLimits(1024, 0))
+		require.NoError(t, err)
+
+		ctx, err = aries.Context()
+		require.NoError(t, err)
+		require.Equal(t, wallet.SizeLimits{MaxEnvelopeSize: 1024}, ctx.MessageSizeLimits())
+		require.NoError(t, aries.Close())
+	})
+
+	t.Run("test deterministic connection IDs", func(t *testing.T) {
+		aries, err := New(WithInboundTransport(&mockInboundTransport{}),
+			WithStoreProvider(mockstorage.NewMockStoreProvider()), WithDeterministicConnectionIDs())
+		require.NoError(t, err)
+
+		ctx, err := aries.Context()
+		require.NoError(t, err)
+
+		svc, err := ctx.Service(didexchange.DIDExchange)
+		require.NoError(t, err)
+
+		myDID, theirDID := &did.Doc{ID: "did:example:alice"}, &did.Doc{ID: "did:example:bob",
+			Service:   []did.Service{{ServiceEndpoint: "http://bob"}},
+			PublicKey: []did.PublicKey{{ID: "did:example:bob#key1", Value: []byte("key1")}}}
+
+		connectionID, err := svc.(*didexchange.Service).CreateStaticConnection(&didexchange.StaticConnection{
+			MyDIDDoc: myDID, TheirDIDDoc: theirDID})
+		require.NoError(t, err)
+		require.Equal(t, didexchange.ConnectionIDFromDIDs(myDID.ID, theirDID.ID), connectionID)
+		require.NoError(t, aries.Close())
+	})
+
+	t.Run("test feature flags", func(t *testing.T) {
+		mockSvcCreator := func(prv api.Provider) (dispatcher.Service, error) {
+			require.True(t, prv.(api.FeatureProvider).FeatureEnabled(api.FeatureDIDCommV2))
+			return &protocol.MockDIDExchangeSvc{ProtocolName: "mockProtocolV2"}, nil
+		}
+		experimental := api.ProtocolSvc{Create: mockSvcCreator, Feature: api.FeatureDIDCommV2}
+
+		aries, err := New(WithInboundTransport(&mockInboundTransport{}),
+			WithStoreProvider(mockstorage.NewMockStoreProvider()), WithDescribedProtocols(experimental))
+		require.NoError(t, err)
+
+		ctx, err := aries.Context()
+		require.NoError(t, err)
+
+		_, err = ctx.Service("mockProtocolV2")
+		require.Error(t, err)
+		require.NoError(t, aries.Close())
+
+		aries, err = New(WithInboundTransport(&mockInboundTransport{}),
+			WithStoreProvider(mockstorage.NewMockStoreProvider()), WithDescribedProtocols(experimental),
+			WithFeatureFlags(api.FeatureDIDCommV2, api.FeatureBBSPlus), WithFeatureFlags(api.FeatureDIDCommV2))
+		require.NoError(t, err)
+
+		ctx, err = aries.Context()
+		require.NoError(t, err)
+
+		_, err = ctx.Service("mockProtocolV2")
+		require.NoError(t, err)
+		require.True(t, ctx.FeatureEnabled(api.FeatureBBSPlus))
+		require.Equal(t, []string{api.FeatureBBSPlus, api.FeatureDIDCommV2}, ctx.FeatureFlags())
+		require.NoError(t, aries.Close())
+
+		_, err = New(WithFeatureFlags(""))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "feature flag is empty")
+	})
+
+	t.Run("test clock", func(t *testing.T) {
+		c := &mockclock.Clock{Time: time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)}
+
+		aries, err := New(WithInboundTransport(&mockInboundTransport{}),
+			WithStoreProvider(mockstorage.NewMockStoreProvider()), WithClock(c),
+			WithThreadCleanup(threads.WithExpiry(time.Hour)))
+		require.NoError(t, err)
+
+		ctx, err := aries.Context()
+		require.NoError(t, err)
+		require.Equal(t, c.Time, ctx.Clock().Now())
+
+		// the threads expire by the clock of the framework
+		require.NoError(t, ctx.ThreadStore().Save(&threads.Record{ThreadID: "thid1", Protocol: "didexchange"}))
+		c.Add(2 * time.Hour)
+
+		_, err = ctx.ThreadStore().Get("thid1")
+		require.Error(t, err)
+		require.NoError(t, aries.Close())
+
+		_, err = New(WithClock(nil))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "clock is nil")
+	})
+
+	t.Run("test ID generator", func(t *testing.T) {
+		aries, err := New(WithInboundTransport(&mockInboundTransport{}),
+			WithStoreProvider(mockstorage.NewMockStoreProvider()), WithIDGenerator(idgen.Sequence("id")))
+		require.NoError(t, err)
+
+		ctx, err := aries.Context()
+		require.NoError(t, err)
+		require.Equal(t, "id-1", ctx.IDGenerator().NewID())
+		require.NoError(t, aries.Close())
+
+		_, err = New(WithIDGenerator(nil))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "ID generator is nil")
+	})
+
+	t.Run("test wallet rate limits", func(t *testing.T) {
+		aries, err := New(WithInboundTransport(&mockInboundTransport{}),
+			WithStoreProvider(mockstorage.NewMockStoreProvider()),
+			WithWalletRateLimits(wallet.RateLimits{SigningRate: 1}))
+		require.NoError(t, err)
+
+		verKey, err := aries.wallet.CreateSigningKey()
+		require.NoError(t, err)
+
+		_, err = aries.wallet.SignMessage([]byte("hello"), verKey)
+		require.NoError(t, err)
+
+		_, err = aries.wallet.SignMessage([]byte("hello"), verKey)
+		require.True(t, errors.Is(err, wallet.ErrRateLimited))
+		require.NoError(t, aries.Close())
+	})
+
+	t.Run("test wallet key backup", func(t *testing.T) {
+		recoveryPub, _, err := box.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+
+		encrypter, err := wallet.PublicKeyEncrypter(recoveryPub[:], rand.Reader)
+		require.NoError(t, err)
+
+		backups := &mockstorage.MockStore{Store: make(map[string][]byte)}
+
+		aries, err := New(WithInboundTransport(&mockInboundTransport{}),
+			WithStoreProvider(mockstorage.NewMockStoreProvider()), WithWalletKeyBackup(encrypter, backups))
+		require.NoError(t, err)
+
+		verKey, err := aries.wallet.CreateSigningKey()
+		require.NoError(t, err)
+		require.Contains(t, backups.Store, verKey)
+		require.NoError(t, aries.Close())
+
+		_, err = New(WithWalletKeyBackup(nil, backups))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "key backup encrypter and store are required")
+	})
+
+	t.Run("test sender verification", func(t *testing.T) {
+		aries, err := New(WithInboundTransport(&mockInboundTransport{}),
+			WithStoreProvider(mockstorage.NewMockStoreProvider()), WithSenderVerification())
+		require.NoError(t, err)
+		require.True(t, aries.verifySender)
+
+		ctx, err := aries.Context()
+		require.NoError(t, err)
+		require.NotNil(t, ctx.InboundMessageHandler())
+		require.NoError(t, aries.Close())
+	})
+
+	t.Run("test shared secret cache", func(t *testing.T) {
+		aries, err := New(WithInboundTransport(&mockInboundTransport{}),
+			WithStoreProvider(mockstorage.NewMockStoreProvider()), WithSharedSecretCache(100, time.Hour))
+		require.NoError(t, err)
+		require.NotNil(t, aries.sharedSecretCache)
+		require.NoError(t, aries.Close())
+	})
+
+	t.Run("test payload codecs", func(t *testing.T) {
+		aries, err := New(WithInboundTransport(&mockInboundTransport{}),
+			WithStoreProvider(mockstorage.NewMockStoreProvider()), WithCodecs(codec.CBOR{}))
+		require.NoError(t, err)
+		require.Equal(t, []codec.Codec{codec.CBOR{}}, aries.codecs)
+		require.NoError(t, aries.Close())
+	})
+
+	t.Run("test random source", func(t *testing.T) {
+		aries, err := New(WithInboundTransport(&mockInboundTransport{}),
+			WithStoreProvider(mockstorage.NewMockStoreProvider()),
+			WithRandSource(insecurerand.New(insecurerand.NewSource(1320485)))) // just a random const
+		require.NoError(t, err)
+		require.NotNil(t, aries.randSource)
+		require.NoError(t, aries.Close())
+	})
+
+	t.Run("test multiple inbound transports", func(t *testing.T) {
+		httpInbound := &mockInboundTransport{endpoint: "http://localhost:8080"}
+		wsInbound := &mockInboundTransport{endpoint: "ws://localhost:8081"}
+
+		aries, err := New(WithInboundTransport(httpInbound), WithInboundTransports(wsInbound),
+			WithStoreProvider(mockstorage.NewMockStoreProvider()))
+		require.NoError(t, err)
+		require.True(t, httpInbound.started)
+		require.True(t, wsInbound.started)
+
+		ctx, err := aries.Context()
+		require.NoError(t, err)
+		require.Equal(t, []string{"http://localhost:8080", "ws://localhost:8081"}, ctx.InboundTransportEndpoints())
+
+		doc, err := ctx.DIDWallet().CreateDID("peer", wallet.WithServiceType("did-communication"))
+		require.NoError(t, err)
+		require.Len(t, doc.Service, 2)
+		require.Equal(t, "ws://localhost:8081", doc.Service[1].ServiceEndpoint)
+
+		// the transport restarted on another port
+		wsInbound.endpoint = "ws://localhost:9091"
+
+		doc, err = ctx.DIDWallet().CreateDID("peer", wallet.WithServiceType("did-communication"))
+		require.NoError(t, err)
+		require.Equal(t, "ws://localhost:9091", doc.Service[1].ServiceEndpoint)
+
+		require.NoError(t, aries.Close())
+		require.False(t, httpInbound.started)
+		require.False(t, wsInbound.started)
+	})
+
+	t.Run("test inbound transports start failure", func(t *testing.T) {
+		httpInbound := &mockInboundTransport{}
+
+		_, err := New(WithInboundTransport(httpInbound),
+			WithInboundTransports(&mockInboundTransport{startError: errors.New("start error")}),
+			WithStoreProvider(mockstorage.NewMockStoreProvider()))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "start error")
+		require.False(t, httpInbound.started)
+
+		_, err = New(WithInboundTransport(&mockInboundTransport{stopError: errors.New("stop error")}),
+			WithInboundTransports(&mockInboundTransport{startError: errors.New("start error")}),
+			WithStoreProvider(mockstorage.NewMockStoreProvider()))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "stop error")
+	})
+
+	t.Run("test store migrations", func(t *testing.T) {
+		var applied bool
+
+		storeProv := mockstorage.NewMockStoreProvider()
+		aries, err := New(WithInboundTransport(&mockInboundTransport{}), WithStoreProvider(storeProv),
+			WithMigrations("didexchange", migration.Step{Version: 1, Migrate: func(store storage.Store) error {
+				applied = true
+				return nil
+			}}))
+		require.NoError(t, err)
+		require.True(t, applied)
+		require.NoError(t, aries.Close())
+
+		_, err = New(WithInboundTransport(&mockInboundTransport{}), WithStoreProvider(storeProv),
+			WithMigrations("didexchange", migration.Step{Version: 0}))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "register migrations failed")
+
+		_, err = New(WithInboundTransport(&mockInboundTransport{}), WithStoreProvider(storeProv),
+			WithMigrations("credentials", migration.Step{Version: 1, Migrate: func(store storage.Store) error {
+				return errors.New("migration error")
+			}}))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "migration error")
+	})
+
+	t.Run("test envelope compression", func(t *testing.T) {
+		aries, err := New(WithInboundTransport(&mockInboundTransport{}),
+			WithStoreProvider(mockstorage.NewMockStoreProvider()), WithEnvelopeCompression(authcrypt.DEF))
+		require.NoError(t, err)
+		require.Equal(t, authcrypt.DEF, aries.envelopeCompression)
+		require.NoError(t, aries.Close())
+	})
+
+	t.Run("test message metrics", func(t *testing.T) {
+		aries, err := New(WithInboundTransport(&mockInboundTransport{}),
+			WithStoreProvider(mockstorage.NewMockStoreProvider()), WithMessageMetrics(time.Second))
+		require.NoError(t, err)
+		require.NotNil(t, aries.Metrics())
+		require.NoError(t, aries.Close())
+
+		// the message metrics are recorded with the metrics provider
+		m := memmetrics.NewProvider()
+		aries, err = New(WithInboundTransport(&mockInboundTransport{}), WithMetricsProvider(m),
+			WithStoreProvider(mockstorage.NewMockStoreProvider()), WithMessageMetrics(0))
+		require.NoError(t, err)
+
+		aries.Metrics().Observe("svc", "type", time.Millisecond, nil)
+		require.Equal(t, float64(1), m.Value(metrics.InboundMessages, metrics.Label{Name: "service", Value: "svc"},
+			metrics.Label{Name: "msg_type", Value: "type"}, metrics.Outcome(nil)))
+		require.NoError(t, aries.Close())
+	})
+
+	t.Run("test metrics provider", func(t *testing.T) {
+		m := memmetrics.NewProvider()
+		aries, err := New(WithInboundTransport(&mockInboundTransport{}),
+			WithStoreProvider(mockstorage.NewMockStoreProvider()), WithMetricsProvider(m))
+		require.NoError(t, err)
+
+		ctx, err := aries.Context()
+		require.NoError(t, err)
+		require.Equal(t, m, ctx.MetricsProvider())
+		require.NoError(t, aries.Close())
+	})
+
+	t.Run("test status", func(t *testing.T) {
+		storeProvider := mockstorage.NewMockStoreProvider()
+		aries, err := New(WithInboundTransport(&mockInboundTransport{}), WithStoreProvider(storeProvider))
+		require.NoError(t, err)
+
+		ctx, err := aries.Context()
+		require.NoError(t, err)
+		require.Equal(t, transport.Status{Ready: true}, ctx.Status())
+
+		storeProvider.Store.ErrGet = errors.New("storage failure")
+		require.NoError(t, storeProvider.Store.Put(statusStoreName, []byte("status")))
+		require.Equal(t, map[string]string{"storage": statusUnavailable}, aries.Status().Checks)
+
+		require.NoError(t, aries.Close())
+		require.False(t, aries.Status().Ready)
+
+		storeProvider.ErrOpenStoreHandle = errors.New("open failure")
+		require.Equal(t, map[string]string{"storage": statusUnavailable}, aries.Status().Checks)
+
+		require.Equal(t, "storage provider is not configured", checkStorage(nil).Error())
+	})
+
+	t.Run("test thread store", func(t *testing.T) {
+		aries, err := New(WithInboundTransport(&mockInboundTransport{}),
+			WithStoreProvider(mockstorage.NewMockStoreProvider()), WithThreadCleanup(threads.WithCompletedRemoval()))
+		require.NoError(t, err)
+
+		ctx, err := aries.Context()
+		require.NoError(t, err)
+		require.NotNil(t, ctx.ThreadStore())
+
+		require.NoError(t, ctx.ThreadStore().Save(&threads.Record{ThreadID: "thid", Protocol: "test"}))
+		require.NoError(t, ctx.ThreadStore().Complete("thid"))
+		_, err = ctx.ThreadStore().Get("thid")
+		require.Error(t, err)
+		require.NoError(t, aries.Close())
+	})
+
+	t.Run("test cache provider", func(t *testing.T) {
+		caches := mem.NewProvider()
+		aries, err := New(WithInboundTransport(&mockInboundTransport{}),
+			WithStoreProvider(mockstorage.NewMockStoreProvider()), WithCacheProvider(caches))
+		require.NoError(t, err)
+		require.Equal(t, caches, aries.cacheProvider)
+
+		store, err := aries.storeProvider.OpenStore(peer.StoreNamespace)
+		require.NoError(t, err)
+		require.NoError(t, store.Put("k1", []byte("v1")))
+
+		records, err := caches.OpenCache(peer.StoreNamespace)
+		require.NoError(t, err)
+		_, err = records.Get("k1")
+		require.NoError(t, err)
+
+		require.NoError(t, aries.Close())
+	})
+
+	t.Run("test maintenance schedule", func(t *testing.T) {
+		aries, err := New(WithInboundTransport(&mockInboundTransport{}),
+			WithStoreProvider(mockstorage.NewMockStoreProvider()), WithMaintenanceSchedule(time.Hour))
+		require.NoError(t, err)
+		require.NotNil(t, aries.maintenance)
+		require.NoError(t, aries.Close())
+
+		_, err = New(WithInboundTransport(&mockInboundTransport{}),
+			WithStoreProvider(mockstorage.NewMockStoreProvider()), WithMaintenanceSchedule(0))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "maintenance interval must be positive")
+	})
+
+	t.Run("test audit trail", func(t *testing.T) {
+		aries, err := New(WithInboundTransport(&mockInboundTransport{}),
+			WithStoreProvider(mockstorage.NewMockStoreProvider()), WithAuditTrail(audit.WithRetention(time.Hour)))
+		require.NoError(t, err)
+
+		ctx, err := aries.Context()
+		require.NoError(t, err)
+		require.NotNil(t, ctx.AuditStore())
+		require.NoError(t, aries.Close())
+
+		aries, err = New(WithInboundTransport(&mockInboundTransport{}),
+			WithStoreProvider(mockstorage.NewMockStoreProvider()))
+		require.NoError(t, err)
+
+		ctx, err = aries.Context()
+		require.NoError(t, err)
+		require.Nil(t, ctx.AuditStore())
+		require.Nil(t, ctx.Attachments())
+		require.Nil(t, ctx.AttachmentFormats())
+		require.NoError(t, aries.Close())
+	})
+
+	t.Run("test attachment store", func(t *testing.T) {
+		dir, cleanup := generateTempDir(t)
+		defer cleanup()
+
+		objects, err := fs.NewStore(dir)
+		require.NoError(t, err)
+
+		aries, err := New(WithInboundTransport(&mockInboundTransport{}),
+			WithStoreProvider(mockstorage.NewMockStoreProvider()), WithAttachmentStore(objects, attachments.WithThreshold(1)))
+		require.NoError(t, err)
+
+		ctx, err := aries.Context()
+		require.NoError(t, err)
+		require.NotNil(t, ctx.Attachments())
+		a := &decorator.Attachment{Data: decorator.AttachmentData{Base64: "YWJj"}}
+		require.NoError(t, ctx.Attachments().Offload(a))
+		require.Len(t, a.Data.Links, 1)
+
+		// the payload is stored under the key of the link
+		payload, err := objects.Get(strings.TrimPrefix(a.Data.Links[0], attachments.LinkScheme))
+		require.NoError(t, err)
+		require.Equal(t, "abc", string(payload))
+		require.NoError(t, aries.Close())
+	})
+
+	t.Run("test attachment formats", func(t *testing.T) {
+		aries, err := New(WithInboundTransport(&mockInboundTransport{}),
+			WithStoreProvider(mockstorage.NewMockStoreProvider()),
+			WithAttachmentFormat("hlindy/proof@v2.0", func(payload []byte) (interface{}, error) {
+				return "indy:" + string(payload), nil
+			}))
+		require.NoError(t, err)
+
+		ctx, err := aries.Context()
+		require.NoError(t, err)
+		require.Equal(t, []string{"hlindy/proof@v2.0"}, ctx.AttachmentFormats().Formats())
+
+		proof, err := ctx.AttachmentFormats().Decode("hlindy/proof@v2.0", []byte("proof"))
+		require.NoError(t, err)
+		require.Equal(t, "indy:proof", proof)
+		require.NoError(t, aries.Close())
+	})
 }
 
 type mockTransportProviderFactory struct {
@@ -367,7 +1029,7 @@ type mockDidMethod struct {
 	acceptFunc func(method string) bool
 }
 
-func (m mockDidMethod) Read(id string, opts ...didresolver.ResolveOpt) ([]byte, error) {
+func (m mockDidMethod) Read(_ context.Context, id string, opts ...didresolver.ResolveOpt) ([]byte, error) {
 	return m.readValue, m.readErr
 }
 
@@ -375,6 +1037,20 @@ func (m mockDidMethod) Accept(method string) bool {
 	return m.acceptFunc(method)
 }
 
+type mockVDR struct {
+	method     string
+	createdDoc *did.Doc
+}
+
+func (m *mockVDR) Create(doc *did.Doc, opts ...vdr.CreateOpt) (*did.Doc, error) {
+	m.createdDoc = doc
+	return doc, nil
+}
+
+func (m *mockVDR) Accept(method string) bool {
+	return method == m.method
+}
+
 func generateTempDir(t testing.TB) (string, func()) {
 	path, err := ioutil.TempDir("", "db")
 	if err != nil {
@@ -426,12 +1102,15 @@ func getServerPort(server net.Listener) int {
 type mockInboundTransport struct {
 	startError error
 	stopError  error
+	endpoint   string
+	started    bool
 }
 
 func (m *mockInboundTransport) Start(prov transport.InboundProvider) error {
 	if m.startError != nil {
 		return m.startError
 	}
+	m.started = true
 	return nil
 }
 
@@ -439,9 +1118,24 @@ func (m *mockInboundTransport) Stop() error {
 	if m.stopError != nil {
 		return m.stopError
 	}
+	m.started = false
 	return nil
 }
 
 func (m *mockInboundTransport) Endpoint() string {
-	return ""
+	return m.endpoint
+}
+
+type mockProfileInboundTransport struct {
+	mockInboundTransport
+	prov transport.InboundProvider
+}
+
+func (m *mockProfileInboundTransport) Start(prov transport.InboundProvider) error {
+	m.prov = prov
+	return m.mockInboundTransport.Start(prov)
+}
+
+func (m *mockProfileInboundTransport) ProfileEndpoint(profile string) string {
+	return m.endpoint + "/agent/" + profile
 }
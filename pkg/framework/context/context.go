@@ -7,25 +7,82 @@ SPDX-License-Identifier: Apache-2.0
 package context
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"sort"
+	"time"
 
+	"github.com/hyperledger/aries-framework-go/pkg/common/clock"
+	"github.com/hyperledger/aries-framework-go/pkg/common/idgen"
+	"github.com/hyperledger/aries-framework-go/pkg/common/log"
+	"github.com/hyperledger/aries-framework-go/pkg/common/metrics"
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/codec"
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/attachments"
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/audit"
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/model"
 	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/service"
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/threads"
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/crypto/jwe/authcrypt"
 	"github.com/hyperledger/aries-framework-go/pkg/didcomm/dispatcher"
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/protocol/decorator"
 	"github.com/hyperledger/aries-framework-go/pkg/didcomm/transport"
 	"github.com/hyperledger/aries-framework-go/pkg/framework/aries/api"
+	"github.com/hyperledger/aries-framework-go/pkg/framework/vdr"
 	"github.com/hyperledger/aries-framework-go/pkg/storage"
 	"github.com/hyperledger/aries-framework-go/pkg/wallet"
 )
 
+var logger = log.New("aries-framework/context")
+
+// ErrSenderMismatch is returned when the sender key of an inbound message isn't one of the keys of the
+// counterparty of the thread
+var ErrSenderMismatch = errors.New("sender key doesn't match the keys of the thread counterparty")
+
+// ErrRoleDisabled is returned when an inbound message is handled in a role of the protocol the agent doesn't play
+var ErrRoleDisabled = errors.New("protocol role disabled")
+
+const (
+	// senderMismatchCode is the code of the problem report of the messages rejected by the sender verification
+	senderMismatchCode = "sender-mismatch"
+	// roleDisabledCode is the code of the problem report of the messages handled in a disabled role
+	roleDisabledCode = "role-disabled"
+)
+
 // Provider supplies the framework configuration to client objects.
 type Provider struct {
 	outboundDispatcher       dispatcher.Outbound
 	services                 []dispatcher.Service
+	descriptors              map[string]dispatcher.ServiceDescriptor
+	roles                    map[string][]string
 	storeProvider            storage.Provider
 	wallet                   wallet.Wallet
 	inboundTransportEndpoint string
+	inboundTransports        []transport.InboundTransport
 	outboundTransport        transport.OutboundTransport
+	vdrRegistry              vdr.Creator
+	sizeLimits               wallet.SizeLimits
+	walletRateLimits         wallet.RateLimits
+	walletKeyBackup          *wallet.KeyBackupConfig
+	threadStore              *threads.Store
+	auditStore               *audit.Store
+	attachments              *attachments.Offloader
+	attachmentFormats        *attachments.FormatRegistry
+	verifySender             bool
+	deterministicConnIDs     bool
+	featureFlags             map[string]bool
+	clock                    clock.Clock
+	idGenerator              idgen.Generator
+	sharedSecretCache        *authcrypt.SharedSecretCache
+	metrics                  *dispatcher.Metrics
+	metricsProvider          metrics.Provider
+	status                   func() transport.Status
+	profiles                 map[string]*Provider
+	randSource               io.Reader
+	envelopeCompression      authcrypt.Compression
+	codecs                   []codec.Codec
 }
 
 // New instantiated new context provider
@@ -38,6 +95,11 @@ func New(opts ...ProviderOption) (*Provider, error) {
 		}
 	}
 
+	// the inbound messages are recorded with the metrics provider unless the metrics are set
+	if ctxProvider.metrics == nil && ctxProvider.metricsProvider != nil {
+		ctxProvider.metrics = dispatcher.NewMetrics(0, ctxProvider.metricsProvider)
+	}
+
 	return &ctxProvider, nil
 }
 
@@ -61,6 +123,21 @@ func (p *Provider) Service(id string) (interface{}, error) {
 	return nil, api.ErrSvcNotFound
 }
 
+// Services returns the descriptors of the registered protocol services
+func (p *Provider) Services() []dispatcher.ServiceDescriptor {
+	descriptors := make([]dispatcher.ServiceDescriptor, len(p.services))
+	for i, svc := range p.services {
+		if descriptor, ok := p.descriptors[svc.Name()]; ok {
+			descriptors[i] = descriptor
+			continue
+		}
+
+		descriptors[i] = dispatcher.Describe(svc)
+	}
+
+	return descriptors
+}
+
 // CryptoWallet returns the crypto wallet service
 func (p *Provider) CryptoWallet() wallet.Crypto {
 	return p.wallet
@@ -76,38 +153,440 @@ func (p *Provider) DIDWallet() wallet.DIDCreator {
 	return p.wallet
 }
 
-// InboundTransportEndpoint returns the inbound transport endpoint
+// InboundTransportEndpoint returns the inbound transport endpoint, the current endpoint of the first inbound
+// transport if the inbound transports are set
 func (p *Provider) InboundTransportEndpoint() string {
+	if len(p.inboundTransports) > 0 {
+		return p.inboundTransports[0].Endpoint()
+	}
+
 	return p.inboundTransportEndpoint
 }
 
+// InboundTransportEndpoints returns the current endpoints of the inbound transports in order of priority, the
+// endpoints are read from the transports so they are up to date if a transport restarts on another port
+func (p *Provider) InboundTransportEndpoints() []string {
+	if len(p.inboundTransports) == 0 {
+		if p.inboundTransportEndpoint == "" {
+			return nil
+		}
+
+		return []string{p.inboundTransportEndpoint}
+	}
+
+	var endpoints []string
+
+	seen := make(map[string]bool)
+
+	for _, t := range p.inboundTransports {
+		if endpoint := t.Endpoint(); endpoint != "" && !seen[endpoint] {
+			seen[endpoint] = true
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+
+	return endpoints
+}
+
 // InboundMessageHandler return inbound message handler
 func (p *Provider) InboundMessageHandler() transport.InboundMessageHandler {
-	return func(envelope *wallet.Envelope) error {
+	return func(ctx context.Context, envelope *wallet.Envelope) error {
+		payload, codecName, err := codec.ToJSON(envelope.Message, p.codecs)
+		if err != nil {
+			return fmt.Errorf("invalid payload data format: %w", err)
+		}
+
 		// get the message type from the payload and dispatch based on the services
 		msgType := &struct {
 			Type string `json:"@type,omitempty"`
 		}{}
-		err := json.Unmarshal(envelope.Message, msgType)
+		err = json.Unmarshal(payload, msgType)
 		if err != nil {
 			return fmt.Errorf("invalid payload data format: %w", err)
 		}
 
-		// find the service which accepts the message type
+		msg := &service.DIDCommMsg{Type: msgType.Type, Payload: payload,
+			ToVerKeys: envelope.ToVerKeys, EncryptionAlgs: envelope.EncryptionAlgs,
+			Metadata: envelopeMetadata(ctx, envelope)}
+		msg.Metadata.Codec = codecName
+
+		// the log lines emitted while handling the message are tagged with its type and thread
+		ctx = log.NewContext(ctx, log.FieldsFromMessage(payload))
+
+		if p.verifySender {
+			if err := p.verifySenderKey(ctx, payload, msg.Metadata); err != nil {
+				return err
+			}
+		}
+
+		// find the service which accepts the message type and the envelope format
 		for _, svc := range p.services {
-			if svc.Accept(msgType.Type) {
-				return svc.Handle(&service.DIDCommMsg{Type: msgType.Type, Payload: envelope.Message, ToVerKeys: envelope.ToVerKeys})
+			if svc.Accept(msgType.Type) && dispatcher.AcceptsFormat(svc, msg.Metadata.Format) {
+				if err := p.checkRole(ctx, svc, msgType.Type, payload); err != nil {
+					return err
+				}
+
+				return p.handle(ctx, svc, msg)
 			}
 		}
+
+		// the generic problem reports are handled by the service owning the thread
+		if msgType.Type == model.ProblemReportMsgType {
+			svc, err := p.threadService(payload)
+			if err != nil {
+				return fmt.Errorf("no message handlers found for the problem report: %w", err)
+			}
+
+			return p.handle(ctx, svc, msg)
+		}
+
 		return fmt.Errorf("no message handlers found for the message type: %s", msgType.Type)
 	}
 }
 
+// handle dispatches the message to the service, the handling is recorded in the metrics if configured
+func (p *Provider) handle(ctx context.Context, svc dispatcher.Service, msg *service.DIDCommMsg) error {
+	if p.metrics == nil {
+		return svc.Handle(ctx, msg)
+	}
+
+	start := time.Now()
+	err := svc.Handle(ctx, msg)
+	p.metrics.Observe(svc.Name(), msg.Type, time.Since(start), err)
+
+	return err
+}
+
+// envelopeMetadata returns the metadata of the envelope and of the inbound transport carried by the context
+func envelopeMetadata(ctx context.Context, envelope *wallet.Envelope) *service.EnvelopeMetadata {
+	metadata := &service.EnvelopeMetadata{SenderVerKey: envelope.FromVerKey, Format: string(envelope.Format)}
+
+	if len(envelope.ToVerKeys) > 0 {
+		metadata.RecipientVerKey = envelope.ToVerKeys[0]
+	}
+
+	if info, ok := transport.InboundInfoFromContext(ctx); ok {
+		metadata.Transport = info.Transport
+		metadata.ReceivedTime = info.ReceivedTime
+		metadata.Profile = info.Profile
+	}
+
+	return metadata
+}
+
+// verifySenderKey checks the sender key of the envelope against the keys of the counterparty stored for the
+// thread of the message, or for the connection of the thread. The messages of unknown threads and of the
+// threads whose counterparty keys aren't known yet are accepted.
+func (p *Provider) verifySenderKey(ctx context.Context, payload []byte, metadata *service.EnvelopeMetadata) error {
+	if p.threadStore == nil {
+		return errors.New("thread store is not configured")
+	}
+
+	thid, err := threadID(payload)
+	if err != nil {
+		return err
+	}
+
+	keys, err := p.theirVerKeys(thid)
+	if err != nil || len(keys) == 0 {
+		return err
+	}
+
+	for _, key := range keys {
+		if key == metadata.SenderVerKey {
+			return nil
+		}
+	}
+
+	logger.WithContext(ctx).Warnf("rejected message of thread %s sent with key %s", thid, metadata.SenderVerKey)
+
+	return &model.ProblemReportError{
+		Report: &model.ProblemReport{
+			Type: model.ProblemReportMsgType,
+			ID:   p.IDGenerator().NewID(),
+			Description: model.ProblemDescription{
+				Code: senderMismatchCode,
+				Text: "the message was not sent by the counterparty of the thread",
+			},
+			Thread: &decorator.Thread{ID: thid},
+		},
+		Err: fmt.Errorf("thread %s: %w", thid, ErrSenderMismatch),
+	}
+}
+
+// checkRole rejects the message if the agent handles it in a role of the protocol it doesn't play, the roles
+// of the protocols which aren't configured are all enabled
+func (p *Provider) checkRole(ctx context.Context, svc dispatcher.Service, msgType string, payload []byte) error {
+	resolver, ok := svc.(dispatcher.MessageRoleResolver)
+	if !ok {
+		return nil
+	}
+
+	role := resolver.MessageRole(msgType)
+	if role == "" || p.RoleEnabled(svc.Name(), role) {
+		return nil
+	}
+
+	thid, err := threadID(payload)
+	if err != nil {
+		return err
+	}
+
+	logger.WithContext(ctx).Warnf("rejected message %s of thread %s handled in disabled role %s", msgType, thid, role)
+
+	return &model.ProblemReportError{
+		Report: &model.ProblemReport{
+			Type: model.ProblemReportMsgType,
+			ID:   p.IDGenerator().NewID(),
+			Description: model.ProblemDescription{
+				Code: roleDisabledCode,
+				Text: fmt.Sprintf("the agent doesn't play the %s role of the %s protocol", role, svc.Name()),
+			},
+			Thread: &decorator.Thread{ID: thid},
+		},
+		Err: fmt.Errorf("%s role of %s: %w", role, svc.Name(), ErrRoleDisabled),
+	}
+}
+
+// RoleEnabled returns true if the agent plays the role of the protocol of the service, the roles of the protocols
+// which aren't configured are all enabled
+func (p *Provider) RoleEnabled(svcName, role string) bool {
+	enabled, ok := p.roles[svcName]
+	if !ok {
+		return true
+	}
+
+	for _, r := range enabled {
+		if r == role {
+			return true
+		}
+	}
+
+	return false
+}
+
+// threadID returns the ID of the thread of the message, the ID of the message if it starts a thread
+func threadID(payload []byte) (string, error) {
+	msg := &struct {
+		ID     string            `json:"@id,omitempty"`
+		Thread *decorator.Thread `json:"~thread,omitempty"`
+	}{}
+
+	err := json.Unmarshal(payload, msg)
+	if err != nil {
+		return "", fmt.Errorf("invalid payload data format: %w", err)
+	}
+
+	if msg.Thread != nil && msg.Thread.ID != "" {
+		return msg.Thread.ID, nil
+	}
+
+	return msg.ID, nil
+}
+
+// theirVerKeys returns the counterparty keys of the thread, or of the connection of the thread
+func (p *Provider) theirVerKeys(thid string) ([]string, error) {
+	thread, err := p.thread(thid)
+	if err != nil || thread == nil {
+		return nil, err
+	}
+
+	if len(thread.TheirVerKeys) > 0 || thread.ConnectionID == "" || thread.ConnectionID == thid {
+		return thread.TheirVerKeys, nil
+	}
+
+	connection, err := p.thread(thread.ConnectionID)
+	if err != nil || connection == nil {
+		return nil, err
+	}
+
+	return connection.TheirVerKeys, nil
+}
+
+// thread returns the thread record, nil for unknown threads
+func (p *Provider) thread(thid string) (*threads.Record, error) {
+	if thid == "" {
+		return nil, nil
+	}
+
+	thread, err := p.threadStore.Get(thid)
+	if err != nil {
+		if errors.Is(err, storage.ErrDataNotFound) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("failed to fetch thread %s: %w", thid, err)
+	}
+
+	return thread, nil
+}
+
+// threadService returns the protocol service owning the thread of the message
+func (p *Provider) threadService(payload []byte) (dispatcher.Service, error) {
+	if p.threadStore == nil {
+		return nil, errors.New("thread store is not configured")
+	}
+
+	msg := &struct {
+		Thread decorator.Thread `json:"~thread,omitempty"`
+	}{}
+
+	err := json.Unmarshal(payload, msg)
+	if err != nil {
+		return nil, fmt.Errorf("invalid payload data format: %w", err)
+	}
+
+	thread, err := p.threadStore.Get(msg.Thread.ID)
+	if err != nil {
+		return nil, fmt.Errorf("thread %s: %w", msg.Thread.ID, err)
+	}
+
+	for _, svc := range p.services {
+		if svc.Name() == thread.Protocol {
+			return svc, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no service for protocol %s of thread %s", thread.Protocol, msg.Thread.ID)
+}
+
 // StorageProvider return storage provider
 func (p *Provider) StorageProvider() storage.Provider {
 	return p.storeProvider
 }
 
+// VDRRegistry returns the registry publishing DID documents
+func (p *Provider) VDRRegistry() vdr.Creator {
+	return p.vdrRegistry
+}
+
+// MessageSizeLimits returns the maximum sizes of the messages
+func (p *Provider) MessageSizeLimits() wallet.SizeLimits {
+	return p.sizeLimits
+}
+
+// WalletRateLimits returns the limits of the wallet signing operations
+func (p *Provider) WalletRateLimits() wallet.RateLimits {
+	return p.walletRateLimits
+}
+
+// WalletKeyBackup returns the backup of the wallet private keys, nil if the keys are not backed up
+func (p *Provider) WalletKeyBackup() *wallet.KeyBackupConfig {
+	return p.walletKeyBackup
+}
+
+// DeterministicConnectionIDs returns true if the DID exchange derives the connection IDs from the DIDs of the parties
+func (p *Provider) DeterministicConnectionIDs() bool {
+	return p.deterministicConnIDs
+}
+
+// FeatureEnabled returns true if the feature flag of the experimental protocols or algorithms is enabled
+func (p *Provider) FeatureEnabled(flag string) bool {
+	return p.featureFlags[flag]
+}
+
+// FeatureFlags returns the enabled feature flags in alphabetical order
+func (p *Provider) FeatureFlags() []string {
+	flags := make([]string, 0, len(p.featureFlags))
+	for flag := range p.featureFlags {
+		flags = append(flags, flag)
+	}
+
+	sort.Strings(flags)
+
+	return flags
+}
+
+// Clock returns the clock of the framework, the system clock by default
+func (p *Provider) Clock() clock.Clock {
+	if p.clock == nil {
+		return clock.System()
+	}
+
+	return p.clock
+}
+
+// IDGenerator returns the generator of the IDs of the messages, connections and invitations, the random UUIDs
+// by default
+func (p *Provider) IDGenerator() idgen.Generator {
+	if p.idGenerator == nil {
+		return idgen.UUID()
+	}
+
+	return p.idGenerator
+}
+
+// SharedSecretCache returns the cache of the shared secrets computed by the wallet crypters
+func (p *Provider) SharedSecretCache() *authcrypt.SharedSecretCache {
+	return p.sharedSecretCache
+}
+
+// Codecs returns the payload codecs available in addition to JSON
+func (p *Provider) Codecs() []codec.Codec {
+	return p.codecs
+}
+
+// RandSource returns the random source of the wallet, nil if the wallet uses crypto/rand
+func (p *Provider) RandSource() io.Reader {
+	return p.randSource
+}
+
+// EnvelopeCompression returns the compression of the payloads packed by the wallet, empty if not compressed
+func (p *Provider) EnvelopeCompression() authcrypt.Compression {
+	return p.envelopeCompression
+}
+
+// Metrics returns the metrics of the inbound messages
+func (p *Provider) Metrics() *dispatcher.Metrics {
+	return p.metrics
+}
+
+// MetricsProvider returns the provider of the metrics recorded by the framework, nil if not configured
+func (p *Provider) MetricsProvider() metrics.Provider {
+	return p.metricsProvider
+}
+
+// Status returns the status of the agent, the agent is ready and healthy if its status isn't configured
+func (p *Provider) Status() transport.Status {
+	if p.status == nil {
+		return transport.Status{Ready: true}
+	}
+
+	return p.status()
+}
+
+// ThreadStore returns the thread store shared by the protocol services
+func (p *Provider) ThreadStore() *threads.Store {
+	return p.threadStore
+}
+
+// Attachments returns the offloader of the large attachment payloads, nil if the payloads aren't offloaded
+func (p *Provider) Attachments() *attachments.Offloader {
+	return p.attachments
+}
+
+// AttachmentFormats returns the registry of the attachment formats the protocol services decode the proofs with,
+// nil if not set
+func (p *Provider) AttachmentFormats() *attachments.FormatRegistry {
+	return p.attachmentFormats
+}
+
+// AuditStore returns the audit trail of the outbound messages, nil if the messages aren't audited
+func (p *Provider) AuditStore() *audit.Store {
+	return p.auditStore
+}
+
+// ProfileInboundProvider returns the provider of the profile, the inbound transports serving the profiles
+// unpack and dispatch the envelopes received for the profile with it
+func (p *Provider) ProfileInboundProvider(profile string) (transport.InboundProvider, error) {
+	prov, ok := p.profiles[profile]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", transport.ErrProfileNotFound, profile)
+	}
+
+	return prov, nil
+}
+
 // ProviderOption configures the framework.
 type ProviderOption func(opts *Provider) error
 
@@ -135,6 +614,19 @@ func WithProtocolServices(services ...dispatcher.Service) ProviderOption {
 	}
 }
 
+// WithServiceDescriptors sets the descriptors the protocol services have been registered with, they take
+// precedence over the descriptions of the services
+func WithServiceDescriptors(descriptors ...dispatcher.ServiceDescriptor) ProviderOption {
+	return func(opts *Provider) error {
+		opts.descriptors = make(map[string]dispatcher.ServiceDescriptor, len(descriptors))
+		for _, descriptor := range descriptors {
+			opts.descriptors[descriptor.Name] = descriptor
+		}
+
+		return nil
+	}
+}
+
 // WithWallet injects a wallet service into the context
 func WithWallet(w wallet.Wallet) ProviderOption {
 	return func(opts *Provider) error {
@@ -151,6 +643,14 @@ func WithInboundTransportEndpoint(endpoint string) ProviderOption {
 	}
 }
 
+// WithInboundTransports injects the inbound transports of the agent into the context, in order of priority
+func WithInboundTransports(transports ...transport.InboundTransport) ProviderOption {
+	return func(opts *Provider) error {
+		opts.inboundTransports = transports
+		return nil
+	}
+}
+
 // WithStorageProvider injects a storage provider into the context
 func WithStorageProvider(s storage.Provider) ProviderOption {
 	return func(opts *Provider) error {
@@ -158,3 +658,206 @@ func WithStorageProvider(s storage.Provider) ProviderOption {
 		return nil
 	}
 }
+
+// WithVDRRegistry injects a VDR registry into the context
+func WithVDRRegistry(r vdr.Creator) ProviderOption {
+	return func(opts *Provider) error {
+		opts.vdrRegistry = r
+		return nil
+	}
+}
+
+// WithWalletRateLimits injects the limits of the wallet signing operations into the context
+func WithWalletRateLimits(limits wallet.RateLimits) ProviderOption {
+	return func(opts *Provider) error {
+		opts.walletRateLimits = limits
+		return nil
+	}
+}
+
+// WithWalletKeyBackup injects the backup of the wallet private keys into the context
+func WithWalletKeyBackup(backup *wallet.KeyBackupConfig) ProviderOption {
+	return func(opts *Provider) error {
+		opts.walletKeyBackup = backup
+		return nil
+	}
+}
+
+// WithDeterministicConnectionIDs makes the DID exchange derive the connection IDs from the DIDs of the parties
+func WithDeterministicConnectionIDs() ProviderOption {
+	return func(opts *Provider) error {
+		opts.deterministicConnIDs = true
+		return nil
+	}
+}
+
+// WithFeatureFlags enables the feature flags of the experimental protocols or algorithms
+func WithFeatureFlags(flags ...string) ProviderOption {
+	return func(opts *Provider) error {
+		if opts.featureFlags == nil {
+			opts.featureFlags = make(map[string]bool, len(flags))
+		}
+
+		for _, flag := range flags {
+			if flag == "" {
+				return errors.New("feature flag is empty")
+			}
+
+			opts.featureFlags[flag] = true
+		}
+
+		return nil
+	}
+}
+
+// WithClock injects the clock of the framework into the context
+func WithClock(c clock.Clock) ProviderOption {
+	return func(opts *Provider) error {
+		opts.clock = c
+		return nil
+	}
+}
+
+// WithIDGenerator injects the generator of the IDs of the framework into the context
+func WithIDGenerator(g idgen.Generator) ProviderOption {
+	return func(opts *Provider) error {
+		opts.idGenerator = g
+		return nil
+	}
+}
+
+// WithMessageSizeLimits injects the message size limits into the context
+func WithMessageSizeLimits(limits wallet.SizeLimits) ProviderOption {
+	return func(opts *Provider) error {
+		opts.sizeLimits = limits
+		return nil
+	}
+}
+
+// WithSharedSecretCache injects the cache of the shared secrets computed by the wallet crypters into the context
+func WithSharedSecretCache(cache *authcrypt.SharedSecretCache) ProviderOption {
+	return func(opts *Provider) error {
+		opts.sharedSecretCache = cache
+		return nil
+	}
+}
+
+// WithCodecs injects the payload codecs available in addition to JSON into the context
+func WithCodecs(codecs ...codec.Codec) ProviderOption {
+	return func(opts *Provider) error {
+		opts.codecs = codecs
+		return nil
+	}
+}
+
+// WithRandSource injects the random source of the wallet key generation and crypters into the context
+func WithRandSource(r io.Reader) ProviderOption {
+	return func(opts *Provider) error {
+		opts.randSource = r
+		return nil
+	}
+}
+
+// WithEnvelopeCompression injects the compression of the payloads packed by the wallet into the context
+func WithEnvelopeCompression(zip authcrypt.Compression) ProviderOption {
+	return func(opts *Provider) error {
+		opts.envelopeCompression = zip
+		return nil
+	}
+}
+
+// WithMetrics records the inbound messages handled by the protocol services in the metrics, which record them
+// with their own providers. The inbound messages are recorded with the provider of WithMetricsProvider otherwise.
+func WithMetrics(m *dispatcher.Metrics) ProviderOption {
+	return func(opts *Provider) error {
+		opts.metrics = m
+		return nil
+	}
+}
+
+// WithMetricsProvider records the metrics of the inbound and outbound messages with the metrics provider
+func WithMetricsProvider(prov metrics.Provider) ProviderOption {
+	return func(opts *Provider) error {
+		opts.metricsProvider = prov
+		return nil
+	}
+}
+
+// WithStatus injects the status of the agent reported by the health check endpoints of the inbound transports
+func WithStatus(status func() transport.Status) ProviderOption {
+	return func(opts *Provider) error {
+		opts.status = status
+		return nil
+	}
+}
+
+// WithSenderVerification rejects the inbound messages whose sender key doesn't match the keys of the counterparty
+// of the thread, a problem report is returned in the ProblemReportError of the inbound message handler
+func WithSenderVerification() ProviderOption {
+	return func(opts *Provider) error {
+		opts.verifySender = true
+		return nil
+	}
+}
+
+// WithProtocolRoles restricts the roles the agent plays in the protocol of the service, the inbound messages
+// handled in the other roles are rejected and a problem report is returned in the ProblemReportError of the
+// inbound message handler. The roles of the protocols without restrictions are all enabled.
+func WithProtocolRoles(svcName string, roles ...string) ProviderOption {
+	return func(opts *Provider) error {
+		if opts.roles == nil {
+			opts.roles = make(map[string][]string)
+		}
+
+		opts.roles[svcName] = roles
+
+		return nil
+	}
+}
+
+// WithThreadStore injects the thread store shared by the protocol services into the context
+func WithThreadStore(s *threads.Store) ProviderOption {
+	return func(opts *Provider) error {
+		opts.threadStore = s
+		return nil
+	}
+}
+
+// WithAttachments injects the offloader of the large attachment payloads kept by the protocol services
+func WithAttachments(o *attachments.Offloader) ProviderOption {
+	return func(opts *Provider) error {
+		opts.attachments = o
+		return nil
+	}
+}
+
+// WithAttachmentFormats injects the registry of the attachment formats, e.g. the proof formats of the protocols
+// exchanging proofs
+func WithAttachmentFormats(r *attachments.FormatRegistry) ProviderOption {
+	return func(opts *Provider) error {
+		opts.attachmentFormats = r
+		return nil
+	}
+}
+
+// WithAuditStore injects the audit trail of the outbound messages into the context
+func WithAuditStore(s *audit.Store) ProviderOption {
+	return func(opts *Provider) error {
+		opts.auditStore = s
+		return nil
+	}
+}
+
+// WithProfile serves the profile with the provider, e.g. a tenant with its own wallet and protocol services.
+// The envelopes received by the inbound transports for the profile are dispatched by the provider of the profile.
+func WithProfile(profile string, prov *Provider) ProviderOption {
+	return func(opts *Provider) error {
+		if opts.profiles == nil {
+			opts.profiles = make(map[string]*Provider)
+		}
+
+		opts.profiles[profile] = prov
+
+		return nil
+	}
+}
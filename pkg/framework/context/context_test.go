@@ -7,15 +7,31 @@ SPDX-License-Identifier: Apache-2.0
 package context
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"testing"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
 
+	"github.com/hyperledger/aries-framework-go/pkg/common/idgen"
+	"github.com/hyperledger/aries-framework-go/pkg/common/metrics"
+	memmetrics "github.com/hyperledger/aries-framework-go/pkg/common/metrics/mem"
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/codec"
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/model"
 	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/service"
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/threads"
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/crypto"
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/crypto/jwe/authcrypt"
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/dispatcher"
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/transport"
 	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	"github.com/hyperledger/aries-framework-go/pkg/framework/vdr"
+	mockclock "github.com/hyperledger/aries-framework-go/pkg/internal/mock/clock"
 	mockdidcomm "github.com/hyperledger/aries-framework-go/pkg/internal/mock/didcomm"
 	mockdispatcher "github.com/hyperledger/aries-framework-go/pkg/internal/mock/didcomm/dispatcher"
 	"github.com/hyperledger/aries-framework-go/pkg/internal/mock/didcomm/protocol"
@@ -34,7 +50,7 @@ func TestNewProvider(t *testing.T) {
 	t.Run("test new with outbound transport", func(t *testing.T) {
 		prov, err := New(WithOutboundDispatcher(&mockdispatcher.MockOutbound{}))
 		require.NoError(t, err)
-		require.NoError(t, prov.OutboundDispatcher().Send(nil, "", nil))
+		require.NoError(t, prov.OutboundDispatcher().Send(context.Background(), nil, "", nil))
 	})
 
 	t.Run("test error return from options", func(t *testing.T) {
@@ -60,6 +76,31 @@ func TestNewProvider(t *testing.T) {
 		require.Error(t, err)
 	})
 
+	t.Run("test protocol service descriptors", func(t *testing.T) {
+		prov, err := New(WithProtocolServices(
+			&protocol.MockDIDExchangeSvc{ProtocolName: "mockProtocolSvc"},
+			&describedService{MockDIDExchangeSvc: protocol.MockDIDExchangeSvc{ProtocolName: "describedSvc"}},
+		))
+		require.NoError(t, err)
+
+		require.Equal(t, []dispatcher.ServiceDescriptor{
+			{Name: "mockProtocolSvc"},
+			{Name: "describedSvc", MessageTypes: []string{"type1", "type2"}, Version: "1.0", Roles: []string{"role1"}},
+		}, prov.Services())
+
+		// the descriptors of the registration take precedence
+		prov, err = New(WithProtocolServices(
+			&protocol.MockDIDExchangeSvc{ProtocolName: "mockProtocolSvc"},
+			&describedService{MockDIDExchangeSvc: protocol.MockDIDExchangeSvc{ProtocolName: "describedSvc"}},
+		), WithServiceDescriptors(dispatcher.ServiceDescriptor{Name: "mockProtocolSvc", Version: "2.0"}))
+		require.NoError(t, err)
+
+		require.Equal(t, []dispatcher.ServiceDescriptor{
+			{Name: "mockProtocolSvc", Version: "2.0"},
+			{Name: "describedSvc", MessageTypes: []string{"type1", "type2"}, Version: "1.0", Roles: []string{"role1"}},
+		}, prov.Services())
+	})
+
 	t.Run("test inbound message handlers/dispatchers", func(t *testing.T) {
 		ctx, err := New(WithProtocolServices(&protocol.MockDIDExchangeSvc{
 			ProtocolName: "mockProtocolSvc",
@@ -91,7 +132,7 @@ func TestNewProvider(t *testing.T) {
 		inboundHandler := ctx.InboundMessageHandler()
 
 		// valid json and message type
-		err = inboundHandler(&wallet.Envelope{Message: []byte(`
+		err = inboundHandler(context.Background(), &wallet.Envelope{Message: []byte(`
 		{
 			"@id": "5678876542345",
 			"@type": "valid-message-type"
@@ -99,17 +140,17 @@ func TestNewProvider(t *testing.T) {
 		require.NoError(t, err)
 
 		// invalid json
-		err = inboundHandler(&wallet.Envelope{Message: []byte("invalid json")})
+		err = inboundHandler(context.Background(), &wallet.Envelope{Message: []byte("invalid json")})
 		require.Error(t, err)
 		require.Contains(t, err.Error(), "invalid payload data format")
 
 		// invalid json
-		err = inboundHandler(&wallet.Envelope{Message: []byte("invalid json")})
+		err = inboundHandler(context.Background(), &wallet.Envelope{Message: []byte("invalid json")})
 		require.Error(t, err)
 		require.Contains(t, err.Error(), "invalid payload data format")
 
 		// no handlers
-		err = inboundHandler(&wallet.Envelope{Message: []byte(`
+		err = inboundHandler(context.Background(), &wallet.Envelope{Message: []byte(`
 		{
 			"@type": "invalid-message-type",
 			"label": "Bob"
@@ -118,7 +159,7 @@ func TestNewProvider(t *testing.T) {
 		require.Contains(t, err.Error(), "no message handlers found for the message type: invalid-message-type")
 
 		// valid json, message type but service handlers returns error
-		err = inboundHandler(&wallet.Envelope{Message: []byte(`
+		err = inboundHandler(context.Background(), &wallet.Envelope{Message: []byte(`
 		{
 			"label": "Carol",
 			"@type": "valid-message-type"
@@ -127,6 +168,392 @@ func TestNewProvider(t *testing.T) {
 		require.Contains(t, err.Error(), "error handling the message")
 	})
 
+	t.Run("test problem report routed to the service owning the thread", func(t *testing.T) {
+		threadStore, err := threads.New(storage.NewMockStoreProvider())
+		require.NoError(t, err)
+		require.NoError(t, threadStore.Save(&threads.Record{ThreadID: "thid1", Protocol: "mockProtocolSvc"}))
+		require.NoError(t, threadStore.Save(&threads.Record{ThreadID: "thid2", Protocol: "unknownSvc"}))
+
+		var handled *service.DIDCommMsg
+
+		ctx, err := New(WithThreadStore(threadStore), WithProtocolServices(&protocol.MockDIDExchangeSvc{
+			ProtocolName: "mockProtocolSvc",
+			AcceptFunc: func(msgType string) bool {
+				return false
+			},
+			HandleFunc: func(msg service.DIDCommMsg) error {
+				handled = &msg
+				return nil
+			},
+		}))
+		require.NoError(t, err)
+
+		inboundHandler := ctx.InboundMessageHandler()
+
+		report := func(thid string) *wallet.Envelope {
+			return &wallet.Envelope{Message: []byte(fmt.Sprintf(`{"@type": "%s", "~thread": {"thid": "%s"}}`,
+				model.ProblemReportMsgType, thid))}
+		}
+
+		require.NoError(t, inboundHandler(context.Background(), report("thid1")))
+		require.NotNil(t, handled)
+		require.Equal(t, model.ProblemReportMsgType, handled.Type)
+
+		err = inboundHandler(context.Background(), report("thid2"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "no service for protocol unknownSvc")
+
+		err = inboundHandler(context.Background(), report("thid3"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "no message handlers found for the problem report")
+
+		ctx, err = New()
+		require.NoError(t, err)
+		err = ctx.InboundMessageHandler()(context.Background(), report("thid1"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "thread store is not configured")
+	})
+
+	t.Run("test inbound message handler envelope metadata", func(t *testing.T) {
+		var handled *service.DIDCommMsg
+
+		ctx, err := New(WithProtocolServices(&protocol.MockDIDExchangeSvc{
+			ProtocolName: "mockProtocolSvc",
+			AcceptFunc: func(msgType string) bool {
+				return true
+			},
+			HandleFunc: func(msg service.DIDCommMsg) error {
+				handled = &msg
+				return nil
+			},
+		}))
+		require.NoError(t, err)
+
+		received := time.Now()
+		inboundCtx := transport.WithInboundInfo(context.Background(),
+			&transport.InboundInfo{Transport: "http", ReceivedTime: received, Profile: "tenant1"})
+
+		err = ctx.InboundMessageHandler()(inboundCtx, &wallet.Envelope{Message: []byte(`{"@type": "type"}`),
+			FromVerKey: "senderKey", ToVerKeys: []string{"recipientKey"}})
+		require.NoError(t, err)
+		require.Equal(t, &service.EnvelopeMetadata{SenderVerKey: "senderKey", RecipientVerKey: "recipientKey",
+			Transport: "http", ReceivedTime: received, Profile: "tenant1", Codec: codec.JSONName}, handled.Metadata)
+
+		err = ctx.InboundMessageHandler()(context.Background(), &wallet.Envelope{Message: []byte(`{"@type": "type"}`)})
+		require.NoError(t, err)
+		require.Equal(t, &service.EnvelopeMetadata{Codec: codec.JSONName}, handled.Metadata)
+	})
+
+	t.Run("test inbound message handler envelope formats", func(t *testing.T) {
+		var handledBy string
+
+		newService := func(name, format string) *formatService {
+			return &formatService{format: format, MockDIDExchangeSvc: &protocol.MockDIDExchangeSvc{
+				ProtocolName: name,
+				AcceptFunc: func(msgType string) bool {
+					return msgType == "type"
+				},
+				HandleFunc: func(msg service.DIDCommMsg) error {
+					handledBy = name
+					return nil
+				},
+			}}
+		}
+
+		ctx, err := New(WithProtocolServices(newService("v1", string(crypto.FormatAuthcrypt)),
+			newService("v2", string(crypto.FormatDIDCommV2))))
+		require.NoError(t, err)
+
+		for format, expected := range map[crypto.Format]string{
+			crypto.FormatAuthcrypt: "v1", crypto.FormatDIDCommV2: "v2"} {
+			err = ctx.InboundMessageHandler()(context.Background(), &wallet.Envelope{
+				Message: []byte(`{"@type": "type"}`), Format: format})
+			require.NoError(t, err)
+			require.Equal(t, expected, handledBy)
+		}
+
+		err = ctx.InboundMessageHandler()(context.Background(), &wallet.Envelope{
+			Message: []byte(`{"@type": "type"}`), Format: crypto.FormatLegacy})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "no message handlers found for the message type: type")
+	})
+
+	t.Run("test inbound message handler payload codecs", func(t *testing.T) {
+		var handled *service.DIDCommMsg
+
+		svc := &protocol.MockDIDExchangeSvc{
+			ProtocolName: "mockProtocolSvc",
+			AcceptFunc: func(msgType string) bool {
+				return msgType == "type"
+			},
+			HandleFunc: func(msg service.DIDCommMsg) error {
+				handled = &msg
+				return nil
+			},
+		}
+
+		payload, err := codec.CBOR{}.Marshal(map[string]string{"@id": "123", "@type": "type"})
+		require.NoError(t, err)
+
+		ctx, err := New(WithProtocolServices(svc), WithCodecs(codec.CBOR{}))
+		require.NoError(t, err)
+		require.Equal(t, []codec.Codec{codec.CBOR{}}, ctx.Codecs())
+
+		err = ctx.InboundMessageHandler()(context.Background(), &wallet.Envelope{Message: payload})
+		require.NoError(t, err)
+		require.Equal(t, codec.CBORName, handled.Metadata.Codec)
+		require.JSONEq(t, `{"@id": "123", "@type": "type"}`, string(handled.Payload))
+
+		// the codec isn't available
+		ctx, err = New(WithProtocolServices(svc))
+		require.NoError(t, err)
+
+		err = ctx.InboundMessageHandler()(context.Background(), &wallet.Envelope{Message: payload})
+		require.Error(t, err)
+		require.True(t, errors.Is(err, codec.ErrUnknownCodec))
+	})
+
+	t.Run("test sender verification", func(t *testing.T) {
+		threadStore, err := threads.New(storage.NewMockStoreProvider())
+		require.NoError(t, err)
+		require.NoError(t, threadStore.Save(&threads.Record{ThreadID: "conn1", ConnectionID: "conn1",
+			Protocol: "mockProtocolSvc", TheirVerKeys: []string{"theirKey"}}))
+		require.NoError(t, threadStore.Save(&threads.Record{ThreadID: "thid1", ConnectionID: "conn1",
+			Protocol: "mockProtocolSvc"}))
+		require.NoError(t, threadStore.Save(&threads.Record{ThreadID: "thid2", ConnectionID: "conn2",
+			Protocol: "mockProtocolSvc"}))
+
+		handled := 0
+
+		ctx, err := New(WithThreadStore(threadStore), WithSenderVerification(),
+			WithProtocolServices(&protocol.MockDIDExchangeSvc{
+				ProtocolName: "mockProtocolSvc",
+				AcceptFunc: func(msgType string) bool {
+					return true
+				},
+				HandleFunc: func(msg service.DIDCommMsg) error {
+					handled++
+					return nil
+				},
+			}))
+		require.NoError(t, err)
+
+		inboundHandler := ctx.InboundMessageHandler()
+
+		envelope := func(thid, senderKey string) *wallet.Envelope {
+			return &wallet.Envelope{Message: []byte(fmt.Sprintf(`{"@type": "type", "~thread": {"thid": "%s"}}`,
+				thid)), FromVerKey: senderKey}
+		}
+
+		// the keys of the connection thread and of the threads of the connection are checked
+		require.NoError(t, inboundHandler(context.Background(), envelope("conn1", "theirKey")))
+		require.NoError(t, inboundHandler(context.Background(), envelope("thid1", "theirKey")))
+
+		// unknown threads and threads without known keys are accepted
+		require.NoError(t, inboundHandler(context.Background(), envelope("unknown", "otherKey")))
+		require.NoError(t, inboundHandler(context.Background(), envelope("thid2", "otherKey")))
+		require.NoError(t, inboundHandler(context.Background(), &wallet.Envelope{
+			Message: []byte(`{"@type": "type", "@id": "thid3"}`)}))
+		require.Equal(t, 5, handled)
+
+		err = inboundHandler(context.Background(), envelope("thid1", "otherKey"))
+		require.True(t, errors.Is(err, ErrSenderMismatch))
+
+		var rejected *model.ProblemReportError
+		require.True(t, errors.As(err, &rejected))
+		require.Equal(t, model.ProblemReportMsgType, rejected.Report.Type)
+		require.Equal(t, senderMismatchCode, rejected.Report.Description.Code)
+		require.Equal(t, "thid1", rejected.Report.Thread.ID)
+
+		err = inboundHandler(context.Background(), &wallet.Envelope{
+			Message: []byte(`{"@type": "type", "@id": "conn1"}`)})
+		require.True(t, errors.Is(err, ErrSenderMismatch))
+		require.Equal(t, 5, handled)
+	})
+
+	t.Run("test sender verification errors", func(t *testing.T) {
+		ctx, err := New(WithSenderVerification())
+		require.NoError(t, err)
+
+		err = ctx.InboundMessageHandler()(context.Background(), &wallet.Envelope{Message: []byte(`{"@type": "type"}`)})
+		require.EqualError(t, err, "thread store is not configured")
+
+		store := &storage.MockStore{Store: map[string][]byte{"thread_thid1": []byte("invalid")}}
+		threadStore, err := threads.New(storage.NewMockCustomStoreProvider(store))
+		require.NoError(t, err)
+
+		ctx, err = New(WithThreadStore(threadStore), WithSenderVerification())
+		require.NoError(t, err)
+
+		err = ctx.InboundMessageHandler()(context.Background(), &wallet.Envelope{
+			Message: []byte(`{"@type": "type", "@id": "thid1"}`)})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to fetch thread thid1")
+	})
+
+	t.Run("test protocol roles", func(t *testing.T) {
+		handled := 0
+		svc := &roleService{describedService{MockDIDExchangeSvc: protocol.MockDIDExchangeSvc{
+			ProtocolName: "roleSvc",
+			AcceptFunc: func(msgType string) bool {
+				return true
+			},
+			HandleFunc: func(msg service.DIDCommMsg) error {
+				handled++
+				return nil
+			},
+		}}}
+
+		ctx, err := New(WithProtocolServices(svc), WithProtocolRoles("roleSvc", "verifier"))
+		require.NoError(t, err)
+
+		inboundHandler := ctx.InboundMessageHandler()
+
+		envelope := func(msgType string) *wallet.Envelope {
+			return &wallet.Envelope{Message: []byte(fmt.Sprintf(`{"@type": "%s", "~thread": {"thid": "thid1"}}`,
+				msgType))}
+		}
+
+		require.NoError(t, inboundHandler(context.Background(), envelope("presentation")))
+		require.NoError(t, inboundHandler(context.Background(), envelope("problem-report")))
+		require.Equal(t, 2, handled)
+
+		err = inboundHandler(context.Background(), envelope("request-presentation"))
+		require.True(t, errors.Is(err, ErrRoleDisabled))
+		require.Contains(t, err.Error(), "prover role of roleSvc")
+
+		var rejected *model.ProblemReportError
+		require.True(t, errors.As(err, &rejected))
+		require.Equal(t, roleDisabledCode, rejected.Report.Description.Code)
+		require.Equal(t, "thid1", rejected.Report.Thread.ID)
+		require.Equal(t, 2, handled)
+		require.True(t, ctx.RoleEnabled("roleSvc", "verifier"))
+		require.False(t, ctx.RoleEnabled("roleSvc", "prover"))
+
+		// the roles of the other protocols are all enabled
+		ctx, err = New(WithProtocolServices(svc), WithProtocolRoles("otherSvc", "verifier"))
+		require.NoError(t, err)
+		require.NoError(t, ctx.InboundMessageHandler()(context.Background(), envelope("request-presentation")))
+		require.Equal(t, 3, handled)
+		require.True(t, ctx.RoleEnabled("roleSvc", "prover"))
+	})
+
+	t.Run("test new with shared secret cache", func(t *testing.T) {
+		cache := authcrypt.NewSharedSecretCache(10, 0)
+		prov, err := New(WithSharedSecretCache(cache))
+		require.NoError(t, err)
+		require.Equal(t, cache, prov.SharedSecretCache())
+	})
+
+	t.Run("test new with inbound transports", func(t *testing.T) {
+		prov, err := New(WithInboundTransportEndpoint("http://localhost:8080"))
+		require.NoError(t, err)
+		require.Equal(t, []string{"http://localhost:8080"}, prov.InboundTransportEndpoints())
+
+		prov, err = New()
+		require.NoError(t, err)
+		require.Empty(t, prov.InboundTransportEndpoints())
+
+		ws := &mockInboundTransport{endpoint: "ws://localhost:8081"}
+		prov, err = New(WithInboundTransportEndpoint("http://localhost:8080"), WithInboundTransports(
+			&mockInboundTransport{endpoint: "http://localhost:8080"}, ws, &mockInboundTransport{},
+			&mockInboundTransport{endpoint: "ws://localhost:8081"}))
+		require.NoError(t, err)
+		require.Equal(t, "http://localhost:8080", prov.InboundTransportEndpoint())
+		require.Equal(t, []string{"http://localhost:8080", "ws://localhost:8081"}, prov.InboundTransportEndpoints())
+
+		ws.endpoint = "ws://localhost:9091"
+		require.Equal(t, []string{"http://localhost:8080", "ws://localhost:9091", "ws://localhost:8081"},
+			prov.InboundTransportEndpoints())
+	})
+
+	t.Run("test new with random source", func(t *testing.T) {
+		source := bytes.NewReader([]byte("random"))
+		prov, err := New(WithRandSource(source))
+		require.NoError(t, err)
+		require.Equal(t, source, prov.RandSource())
+	})
+
+	t.Run("test new with envelope compression", func(t *testing.T) {
+		prov, err := New(WithEnvelopeCompression(authcrypt.DEF))
+		require.NoError(t, err)
+		require.Equal(t, authcrypt.DEF, prov.EnvelopeCompression())
+	})
+
+	t.Run("test inbound message metrics", func(t *testing.T) {
+		metrics := dispatcher.NewMetrics(0)
+
+		ctx, err := New(WithMetrics(metrics), WithProtocolServices(&protocol.MockDIDExchangeSvc{
+			ProtocolName: "mockProtocolSvc",
+			AcceptFunc: func(msgType string) bool {
+				return true
+			},
+			HandleFunc: func(msg service.DIDCommMsg) error {
+				if msg.Type == "failing" {
+					return errors.New("handle error")
+				}
+
+				return nil
+			},
+		}))
+		require.NoError(t, err)
+		require.Equal(t, metrics, ctx.Metrics())
+
+		inboundHandler := ctx.InboundMessageHandler()
+		require.NoError(t, inboundHandler(context.Background(), &wallet.Envelope{Message: []byte(`{"@type": "type"}`)}))
+		require.NoError(t, inboundHandler(context.Background(), &wallet.Envelope{Message: []byte(`{"@type": "type"}`)}))
+		require.Error(t, inboundHandler(context.Background(), &wallet.Envelope{Message: []byte(`{"@type": "failing"}`)}))
+
+		snapshot := metrics.Snapshot()
+		require.Equal(t, uint64(2), snapshot["type"].Count)
+		require.Equal(t, uint64(1), snapshot["failing"].Count)
+		require.Equal(t, uint64(1), snapshot["failing"].Failed)
+	})
+
+	t.Run("test inbound message metrics provider", func(t *testing.T) {
+		m := memmetrics.NewProvider()
+
+		ctx, err := New(WithMetricsProvider(m), WithProtocolServices(&protocol.MockDIDExchangeSvc{
+			ProtocolName: "mockProtocolSvc",
+			AcceptFunc: func(msgType string) bool {
+				return true
+			},
+			HandleFunc: func(msg service.DIDCommMsg) error {
+				if msg.Type == "failing" {
+					return errors.New("handle error")
+				}
+
+				return nil
+			},
+		}))
+		require.NoError(t, err)
+		require.Equal(t, m, ctx.MetricsProvider())
+
+		inboundHandler := ctx.InboundMessageHandler()
+		require.NoError(t, inboundHandler(context.Background(), &wallet.Envelope{Message: []byte(`{"@type": "type"}`)}))
+		require.Error(t, inboundHandler(context.Background(), &wallet.Envelope{Message: []byte(`{"@type": "failing"}`)}))
+
+		svcLabel := metrics.Label{Name: "service", Value: "mockProtocolSvc"}
+		typeLabel := metrics.Label{Name: "msg_type", Value: "type"}
+		failingLabel := metrics.Label{Name: "msg_type", Value: "failing"}
+
+		require.Equal(t, float64(1), m.Value(metrics.InboundMessages, svcLabel, typeLabel, metrics.Outcome(nil)))
+		require.Equal(t, float64(1), m.Value(metrics.InboundMessages, svcLabel, failingLabel,
+			metrics.Outcome(errors.New("handle error"))))
+		require.Len(t, m.Observations(metrics.InboundMessageDuration, svcLabel, typeLabel), 1)
+	})
+
+	t.Run("test status", func(t *testing.T) {
+		prov, err := New()
+		require.NoError(t, err)
+		require.Equal(t, transport.Status{Ready: true}, prov.Status())
+
+		status := transport.Status{Checks: map[string]string{"storage": "failure"}}
+		prov, err = New(WithStatus(func() transport.Status { return status }))
+		require.NoError(t, err)
+		require.Equal(t, status, prov.Status())
+	})
+
 	t.Run("test new with wallet service", func(t *testing.T) {
 		prov, err := New(WithWallet(&mockwallet.CloseableWallet{
 			SignMessageValue: []byte("mockValue"), PackValue: []byte("data")}))
@@ -168,11 +595,156 @@ func TestNewProvider(t *testing.T) {
 		require.Equal(t, s, prov.StorageProvider())
 	})
 
+	t.Run("test new with vdr registry", func(t *testing.T) {
+		r := vdr.New()
+		prov, err := New(WithVDRRegistry(r))
+		require.NoError(t, err)
+		require.Equal(t, r, prov.VDRRegistry())
+	})
+
+	t.Run("test new with message size limits", func(t *testing.T) {
+		limits := wallet.SizeLimits{MaxEnvelopeSize: 1024, MaxAttachmentSize: 512}
+		prov, err := New(WithMessageSizeLimits(limits))
+		require.NoError(t, err)
+		require.Equal(t, limits, prov.MessageSizeLimits())
+	})
+
+	t.Run("test new with wallet rate limits", func(t *testing.T) {
+		limits := wallet.RateLimits{SigningRate: 10, LockoutThreshold: 3, LockoutDuration: time.Minute}
+		prov, err := New(WithWalletRateLimits(limits))
+		require.NoError(t, err)
+		require.Equal(t, limits, prov.WalletRateLimits())
+	})
+
+	t.Run("test new with deterministic connection IDs", func(t *testing.T) {
+		prov, err := New()
+		require.NoError(t, err)
+		require.False(t, prov.DeterministicConnectionIDs())
+
+		prov, err = New(WithDeterministicConnectionIDs())
+		require.NoError(t, err)
+		require.True(t, prov.DeterministicConnectionIDs())
+	})
+
+	t.Run("test new with feature flags", func(t *testing.T) {
+		prov, err := New(WithFeatureFlags("didcomm-v2"), WithFeatureFlags("bbs-plus"))
+		require.NoError(t, err)
+		require.True(t, prov.FeatureEnabled("didcomm-v2"))
+		require.False(t, prov.FeatureEnabled("other"))
+		require.Equal(t, []string{"bbs-plus", "didcomm-v2"}, prov.FeatureFlags())
+
+		_, err = New(WithFeatureFlags(""))
+		require.Error(t, err)
+	})
+
+	t.Run("test new with clock", func(t *testing.T) {
+		prov, err := New()
+		require.NoError(t, err)
+		require.NotNil(t, prov.Clock())
+
+		c := &mockclock.Clock{Time: time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)}
+		prov, err = New(WithClock(c))
+		require.NoError(t, err)
+		require.Equal(t, c.Time, prov.Clock().Now())
+	})
+
+	t.Run("test new with ID generator", func(t *testing.T) {
+		prov, err := New()
+		require.NoError(t, err)
+		_, err = uuid.Parse(prov.IDGenerator().NewID())
+		require.NoError(t, err)
+
+		prov, err = New(WithIDGenerator(idgen.Sequence("id")))
+		require.NoError(t, err)
+		require.Equal(t, "id-1", prov.IDGenerator().NewID())
+	})
+
+	t.Run("test new with thread store", func(t *testing.T) {
+		threadStore, err := threads.New(storage.NewMockStoreProvider())
+		require.NoError(t, err)
+		prov, err := New(WithThreadStore(threadStore))
+		require.NoError(t, err)
+		require.Equal(t, threadStore, prov.ThreadStore())
+	})
+
+	t.Run("test new with profiles", func(t *testing.T) {
+		tenant1, err := New(WithInboundTransportEndpoint("http://agent/tenant1"))
+		require.NoError(t, err)
+		prov, err := New(WithProfile("tenant1", tenant1))
+		require.NoError(t, err)
+
+		p, err := prov.ProfileInboundProvider("tenant1")
+		require.NoError(t, err)
+		require.Equal(t, tenant1, p)
+
+		_, err = prov.ProfileInboundProvider("tenant2")
+		require.True(t, errors.Is(err, transport.ErrProfileNotFound))
+	})
+
 	t.Run("test new with outbound transport service", func(t *testing.T) {
 		prov, err := New(WithOutboundTransport(&mockdidcomm.MockOutboundTransport{ExpectedResponse: "data"}))
 		require.NoError(t, err)
-		r, err := prov.OutboundTransports()[0].Send([]byte("data"), "url")
+		r, err := prov.OutboundTransports()[0].Send(context.Background(), []byte("data"), "url")
 		require.NoError(t, err)
 		require.Equal(t, "data", r)
 	})
 }
+
+// describedService describes the message types it accepts
+type describedService struct {
+	protocol.MockDIDExchangeSvc
+}
+
+func (s *describedService) MessageTypes() []string {
+	return []string{"type1", "type2"}
+}
+
+func (s *describedService) Version() string {
+	return "1.0"
+}
+
+func (s *describedService) Roles() []string {
+	return []string{"role1"}
+}
+
+// roleService plays the verifier role handling the presentations and the prover role handling the requests
+type roleService struct {
+	describedService
+}
+
+func (s *roleService) MessageRole(msgType string) string {
+	switch msgType {
+	case "presentation":
+		return "verifier"
+	case "request-presentation":
+		return "prover"
+	default:
+		return ""
+	}
+}
+
+type mockInboundTransport struct {
+	endpoint string
+}
+
+func (m *mockInboundTransport) Start(prov transport.InboundProvider) error {
+	return nil
+}
+
+func (m *mockInboundTransport) Stop() error {
+	return nil
+}
+
+func (m *mockInboundTransport) Endpoint() string {
+	return m.endpoint
+}
+
+// formatService handles the messages received in the envelopes of a format only
+type formatService struct {
+	*protocol.MockDIDExchangeSvc
+	format string
+}
+
+func (s *formatService) AcceptFormat(format string) bool {
+	return format == s.format
+}
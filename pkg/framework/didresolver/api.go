@@ -6,8 +6,11 @@ SPDX-License-Identifier: Apache-2.0
 package didresolver
 
 import (
+	"context"
 	"errors"
 	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/cache"
 )
 
 // ResultType input option can be used to request a certain type of result.
@@ -23,12 +26,20 @@ const (
 // ErrNotFound is returned when a DID resolver does not find the DID.
 var ErrNotFound = errors.New("DID not found")
 
+// ErrTimeout is the error of the DID method drivers which haven't resolved the DID within their timeout.
+var ErrTimeout = errors.New("DID resolution timed out")
+
+// ErrDeactivated is returned when a DID resolver reports the DID deactivated, the DID isn't resolved by the
+// next DID methods.
+var ErrDeactivated = errors.New("DID deactivated")
+
 // DidMethod resolves a DID into a result type (default: DidDocumentResult).
 // See the DID resolution spec: https://w3c-ccg.github.io/did-resolution.
 type DidMethod interface {
 	// Read implements the 'DID Resolution' algorithm defined in
 	// https://w3c-ccg.github.io/did-resolution/#resolving.
-	Read(did string, opts ...ResolveOpt) ([]byte, error)
+	// The read is abandoned when the context is done, e.g. on the timeout of the DID method.
+	Read(ctx context.Context, did string, opts ...ResolveOpt) ([]byte, error)
 	// Accept registers this DID method resolver with the given method.
 	Accept(method string) bool
 }
@@ -75,15 +86,36 @@ func WithNoCache(noCache bool) ResolveOpt {
 // didResolverOpts holds the options for resolver instance
 type didResolverOpts struct {
 	didMethods []DidMethod
+	timeouts   []time.Duration
+	cache      cache.Cache
+	cacheTTL   time.Duration
 }
 
 // Opt is a resolver instance option
 type Opt func(opts *didResolverOpts)
 
 // WithDidMethod to add did method
-// DID methods are checked in the order added
+// DID methods are checked in the order added, the DID is resolved with the next DID method accepting the method
+// of the DID if a DID method fails, e.g. a local registry then a native driver then a universal resolver.
+// The resolution stops at the DID method reporting the DID deactivated.
 func WithDidMethod(method DidMethod) Opt {
+	return WithDidMethodTimeout(method, 0)
+}
+
+// WithDidMethodTimeout adds the did method with a resolution timeout, the next DID method is tried if the
+// DID isn't resolved within the timeout. Zero disables the timeout.
+func WithDidMethodTimeout(method DidMethod, timeout time.Duration) Opt {
 	return func(opts *didResolverOpts) {
 		opts.didMethods = append(opts.didMethods, method)
+		opts.timeouts = append(opts.timeouts, timeout)
+	}
+}
+
+// WithCache caches the resolved DID documents in the cache for the ttl (zero for no expiry),
+// the documents of a specific version and the resolutions with the no-cache option aren't cached
+func WithCache(c cache.Cache, ttl time.Duration) Opt {
+	return func(opts *didResolverOpts) {
+		opts.cache = c
+		opts.cacheTTL = ttl
 	}
 }
@@ -49,3 +49,10 @@ func TestWithDidMethod(t *testing.T) {
 	opt(resolverOpts)
 	require.True(t, len(resolverOpts.didMethods) == 1)
 }
+
+func TestWithCache(t *testing.T) {
+	opt := WithCache(nil, time.Minute)
+	resolverOpts := &didResolverOpts{}
+	opt(resolverOpts)
+	require.Equal(t, time.Minute, resolverOpts.cacheTTL)
+}
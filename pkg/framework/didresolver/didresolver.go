@@ -6,16 +6,55 @@ SPDX-License-Identifier: Apache-2.0
 package didresolver
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
+	"github.com/hyperledger/aries-framework-go/pkg/cache"
 	diddoc "github.com/hyperledger/aries-framework-go/pkg/doc/did"
 )
 
 // DIDResolver did resolver
 type DIDResolver struct {
-	didMethods []DidMethod
+	drivers  []*driver
+	cache    cache.Cache
+	cacheTTL time.Duration
+}
+
+// driver is a DID method with its resolution timeout
+type driver struct {
+	method  DidMethod
+	timeout time.Duration
+}
+
+// ResolutionError is returned when none of the DID methods accepting the method of the DID resolved it,
+// it lists the DID methods attempted in order
+type ResolutionError struct {
+	DID      string
+	Attempts []Attempt
+}
+
+// Attempt is the failed resolution of a DID method
+type Attempt struct {
+	// Driver is the type of the DID method, e.g. *httpbinding.DIDResolver
+	Driver string
+	Err    error
+}
+
+func (e *ResolutionError) Error() string {
+	attempts := make([]string, len(e.Attempts))
+	for i, a := range e.Attempts {
+		attempts[i] = fmt.Sprintf("%s: %v", a.Driver, a.Err)
+	}
+
+	return fmt.Sprintf("did method read failed for %s: %s", e.DID, strings.Join(attempts, "; "))
+}
+
+// Unwrap returns the error of the last DID method attempted
+func (e *ResolutionError) Unwrap() error {
+	return e.Attempts[len(e.Attempts)-1].Err
 }
 
 // New return new instance of did resolver
@@ -25,7 +64,17 @@ func New(opts ...Opt) *DIDResolver {
 	for _, opt := range opts {
 		opt(resolverOpts)
 	}
-	return &DIDResolver{didMethods: resolverOpts.didMethods}
+
+	drivers := make([]*driver, len(resolverOpts.didMethods))
+	for i, method := range resolverOpts.didMethods {
+		drivers[i] = &driver{method: method, timeout: resolverOpts.timeouts[i]}
+	}
+
+	return &DIDResolver{
+		drivers:  drivers,
+		cache:    resolverOpts.cache,
+		cacheTTL: resolverOpts.cacheTTL,
+	}
 }
 
 // Resolve did document
@@ -46,44 +95,124 @@ func (r *DIDResolver) Resolve(did string, opts ...ResolveOpt) (*diddoc.Doc, erro
 	// Determine if the input DID method is supported by the DID Resolver
 	didMethod := didParts[1]
 	// resolve did method
-	method, err := r.resolveDidMethod(didMethod)
+	drivers, err := r.resolveDidMethods(didMethod)
 	if err != nil {
 		return nil, err
 	}
 
-	// Obtain the DID Document
-	didDocBytes, err := method.Read(did, opts...)
-	if err != nil {
-		if err == ErrNotFound {
-			return nil, err
-		}
-		return nil, fmt.Errorf("did method read failed failed: %w", err)
+	if resolveOpts.resultType == ResolutionResult {
+		// TODO Support resolution-result
+		return nil, errors.New("result type 'resolution-result' not supported")
 	}
 
-	if len(didDocBytes) == 0 {
-		return nil, ErrNotFound
+	if r.cacheable(resolveOpts) {
+		if didDocBytes, e := r.cache.Get(did); e == nil {
+			return diddoc.ParseDocument(didDocBytes)
+		}
 	}
 
-	// Validate that the output DID Document conforms to the serialization of the DID Document data model
-	didDoc, err := diddoc.ParseDocument(didDocBytes)
+	// Obtain the DID Document
+	didDoc, didDocBytes, err := r.read(drivers, did, opts...)
 	if err != nil {
 		return nil, err
 	}
 
-	if resolveOpts.resultType == ResolutionResult {
-		// TODO Support resolution-result
-		return nil, errors.New("result type 'resolution-result' not supported")
+	if r.cacheable(resolveOpts) {
+		// the cache is best effort, the document is read again if it couldn't be cached
+		r.cache.Set(did, didDocBytes, r.cacheTTL) // nolint: errcheck
 	}
 
 	return didDoc, nil
 }
 
-// resolveDidMethod resolve did method
-func (r *DIDResolver) resolveDidMethod(method string) (DidMethod, error) {
-	for _, v := range r.didMethods {
-		if v.Accept(method) {
-			return v, nil
+// read resolves the DID with the first DID method returning a valid document, ErrNotFound is returned if none
+// of the DID methods found the DID and a ResolutionError if a DID method failed. The DID methods after the one
+// reporting the DID deactivated aren't tried, they could return a stale document.
+func (r *DIDResolver) read(drivers []*driver, did string, opts ...ResolveOpt) (*diddoc.Doc, []byte, error) {
+	resolutionErr := &ResolutionError{DID: did}
+	notFound := true
+
+	for _, d := range drivers {
+		didDocBytes, err := d.read(context.Background(), did, opts...)
+		if err == nil && len(didDocBytes) == 0 {
+			err = ErrNotFound
 		}
+
+		var didDoc *diddoc.Doc
+
+		if err == nil {
+			// Validate that the output DID Document conforms to the serialization of the DID Document data model
+			didDoc, err = diddoc.ParseDocument(didDocBytes)
+		}
+
+		if err == nil {
+			return didDoc, didDocBytes, nil
+		}
+
+		notFound = notFound && errors.Is(err, ErrNotFound)
+		resolutionErr.Attempts = append(resolutionErr.Attempts, Attempt{Driver: fmt.Sprintf("%T", d.method), Err: err})
+
+		if errors.Is(err, ErrDeactivated) {
+			return nil, nil, resolutionErr
+		}
+	}
+
+	if notFound {
+		return nil, nil, ErrNotFound
+	}
+
+	return nil, nil, resolutionErr
+}
+
+// read reads the DID with the DID method within the timeout of the driver, the read is cancelled on timeout
+func (d *driver) read(ctx context.Context, did string, opts ...ResolveOpt) ([]byte, error) {
+	if d.timeout <= 0 {
+		return d.method.Read(ctx, did, opts...)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+
+	type result struct {
+		didDocBytes []byte
+		err         error
 	}
-	return nil, fmt.Errorf("did method %s not supported", method)
+
+	// the result of the DID method finishing after the timeout is dropped, the DID method returns once the
+	// context is cancelled
+	results := make(chan result, 1)
+
+	go func() {
+		didDocBytes, err := d.method.Read(ctx, did, opts...)
+		results <- result{didDocBytes: didDocBytes, err: err}
+	}()
+
+	select {
+	case res := <-results:
+		return res.didDocBytes, res.err
+	case <-ctx.Done():
+		return nil, fmt.Errorf("%w after %s", ErrTimeout, d.timeout)
+	}
+}
+
+// cacheable returns true if the resolution with the options can be served from the cache
+func (r *DIDResolver) cacheable(opts *resolveOpts) bool {
+	return r.cache != nil && !opts.noCache && opts.versionID == nil && opts.versionTime == ""
+}
+
+// resolveDidMethods returns the DID methods accepting the method, in the order added
+func (r *DIDResolver) resolveDidMethods(method string) ([]*driver, error) {
+	var drivers []*driver
+
+	for _, d := range r.drivers {
+		if d.method.Accept(method) {
+			drivers = append(drivers, d)
+		}
+	}
+
+	if len(drivers) == 0 {
+		return nil, fmt.Errorf("did method %s not supported", method)
+	}
+
+	return drivers, nil
 }
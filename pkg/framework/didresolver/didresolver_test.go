@@ -6,10 +6,16 @@ SPDX-License-Identifier: Apache-2.0
 package didresolver
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
+
+	"github.com/hyperledger/aries-framework-go/pkg/cache"
+	"github.com/hyperledger/aries-framework-go/pkg/cache/mem"
 )
 
 //nolint:lll
@@ -60,8 +66,9 @@ const doc = `{
 }`
 
 func TestNew(t *testing.T) {
-	r := New(WithDidMethod(nil))
-	require.True(t, len(r.didMethods) == 1)
+	r := New(WithDidMethod(nil), WithDidMethodTimeout(nil, time.Second))
+	require.Len(t, r.drivers, 2)
+	require.Equal(t, time.Second, r.drivers[1].timeout)
 }
 
 func TestResolve(t *testing.T) {
@@ -133,17 +140,155 @@ func TestResolve(t *testing.T) {
 		}}), WithDidMethod(mockDidMethod{readValue: []byte("did2"), acceptFunc: func(method string) bool {
 			return true
 		}}))
-		didMethod, err := r.resolveDidMethod("did1")
+		drivers, err := r.resolveDidMethods("did1")
 		require.NoError(t, err)
-		v, err := didMethod.Read("")
+		require.Len(t, drivers, 2)
+		v, err := drivers[0].method.Read(context.Background(), "")
 		require.NoError(t, err)
 		require.Equal(t, "did1", string(v))
-		didMethod, err = r.resolveDidMethod("did2")
+		drivers, err = r.resolveDidMethods("did2")
 		require.NoError(t, err)
-		v, err = didMethod.Read("")
+		require.Len(t, drivers, 1)
+		v, err = drivers[0].method.Read(context.Background(), "")
 		require.NoError(t, err)
 		require.Equal(t, "did2", string(v))
 	})
+
+	t.Run("test fallback to the next did method", func(t *testing.T) {
+		accept := func(method string) bool { return true }
+		native := &countingDidMethod{mockDidMethod: mockDidMethod{readValue: []byte(doc), acceptFunc: accept}}
+
+		r := New(WithDidMethod(mockDidMethod{readErr: ErrNotFound, acceptFunc: accept}),
+			WithDidMethod(mockDidMethod{readValue: []byte("invalid"), acceptFunc: accept}),
+			WithDidMethod(mockDidMethod{readErr: errors.New("read error"), acceptFunc: accept}),
+			WithDidMethod(native),
+			WithDidMethod(mockDidMethod{readErr: errors.New("not attempted"), acceptFunc: accept}))
+
+		didDoc, err := r.Resolve("did:example:1234")
+		require.NoError(t, err)
+		require.Equal(t, "did:example:21tDAKCERh95uGgKbJNHYp", didDoc.ID)
+		require.Equal(t, 1, native.reads)
+	})
+
+	t.Run("test all did methods failed", func(t *testing.T) {
+		accept := func(method string) bool { return true }
+
+		r := New(WithDidMethod(mockDidMethod{readErr: ErrNotFound, acceptFunc: accept}),
+			WithDidMethod(mockDidMethod{acceptFunc: accept}))
+		_, err := r.Resolve("did:example:1234")
+		require.Equal(t, ErrNotFound, err)
+
+		r = New(WithDidMethod(mockDidMethod{readErr: ErrNotFound, acceptFunc: accept}),
+			WithDidMethod(mockDidMethod{readErr: errors.New("read error"), acceptFunc: accept}))
+		_, err = r.Resolve("did:example:1234")
+		require.EqualError(t, err, "did method read failed for did:example:1234: "+
+			"didresolver.mockDidMethod: DID not found; didresolver.mockDidMethod: read error")
+
+		resolutionErr := &ResolutionError{}
+		require.True(t, errors.As(err, &resolutionErr))
+		require.Len(t, resolutionErr.Attempts, 2)
+		require.Equal(t, ErrNotFound, resolutionErr.Attempts[0].Err)
+		require.EqualError(t, errors.Unwrap(err), "read error")
+	})
+
+	t.Run("test did method timeout", func(t *testing.T) {
+		accept := func(method string) bool { return true }
+		slow := &slowDidMethod{mockDidMethod: mockDidMethod{readValue: []byte(doc), acceptFunc: accept},
+			release: make(chan struct{}), cancelled: make(chan struct{})}
+		defer close(slow.release)
+
+		r := New(WithDidMethodTimeout(slow, 10*time.Millisecond),
+			WithDidMethodTimeout(mockDidMethod{readErr: errors.New("read error"), acceptFunc: accept}, time.Second))
+		_, err := r.Resolve("did:example:1234")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "*didresolver.slowDidMethod: DID resolution timed out after 10ms")
+
+		resolutionErr := &ResolutionError{}
+		require.True(t, errors.As(err, &resolutionErr))
+		require.True(t, errors.Is(resolutionErr.Attempts[0].Err, ErrTimeout))
+
+		// the read of the slow did method is cancelled
+		select {
+		case <-slow.cancelled:
+		case <-time.After(time.Second):
+			require.Fail(t, "read not cancelled")
+		}
+
+		r = New(WithDidMethodTimeout(mockDidMethod{readValue: []byte(doc), acceptFunc: accept}, time.Second))
+		_, err = r.Resolve("did:example:1234")
+		require.NoError(t, err)
+	})
+
+	t.Run("test deactivated did isn't resolved by the next did methods", func(t *testing.T) {
+		accept := func(method string) bool { return true }
+		r := New(WithDidMethod(mockDidMethod{readErr: ErrDeactivated, acceptFunc: accept}),
+			WithDidMethod(mockDidMethod{readValue: []byte(doc), acceptFunc: accept}))
+
+		_, err := r.Resolve("did:example:1234")
+		require.True(t, errors.Is(err, ErrDeactivated))
+
+		resolutionErr := &ResolutionError{}
+		require.True(t, errors.As(err, &resolutionErr))
+		require.Len(t, resolutionErr.Attempts, 1)
+	})
+
+	t.Run("test resolved documents are cached", func(t *testing.T) {
+		method := &countingDidMethod{mockDidMethod: mockDidMethod{readValue: []byte(doc),
+			acceptFunc: func(method string) bool { return true }}}
+
+		c, err := mem.NewProvider().OpenCache("did")
+		require.NoError(t, err)
+
+		r := New(WithDidMethod(method), WithCache(c, time.Hour))
+
+		for i := 0; i < 2; i++ {
+			didDoc, e := r.Resolve("did:example:1234")
+			require.NoError(t, e)
+			require.Equal(t, "did:example:21tDAKCERh95uGgKbJNHYp", didDoc.ID)
+		}
+
+		require.Equal(t, 1, method.reads)
+
+		// the cache is bypassed
+		_, err = r.Resolve("did:example:1234", WithNoCache(true))
+		require.NoError(t, err)
+		_, err = r.Resolve("did:example:1234", WithVersionID("1"))
+		require.NoError(t, err)
+		require.Equal(t, 3, method.reads)
+
+		// invalid documents aren't cached
+		method.readValue = []byte("invalid")
+		_, err = r.Resolve("did:example:5678")
+		require.Error(t, err)
+		_, err = c.Get("did:example:5678")
+		require.Equal(t, cache.ErrNotFound, err)
+	})
+}
+
+type slowDidMethod struct {
+	mockDidMethod
+	release   chan struct{}
+	cancelled chan struct{}
+}
+
+func (m *slowDidMethod) Read(ctx context.Context, did string, opts ...ResolveOpt) ([]byte, error) {
+	select {
+	case <-m.release:
+		return m.mockDidMethod.Read(ctx, did, opts...)
+	case <-ctx.Done():
+		close(m.cancelled)
+		return nil, ctx.Err()
+	}
+}
+
+type countingDidMethod struct {
+	mockDidMethod
+	reads int
+}
+
+func (m *countingDidMethod) Read(ctx context.Context, did string, opts ...ResolveOpt) ([]byte, error) {
+	m.reads++
+	return m.mockDidMethod.Read(ctx, did, opts...)
 }
 
 type mockDidMethod struct {
@@ -152,7 +297,7 @@ type mockDidMethod struct {
 	acceptFunc func(method string) bool
 }
 
-func (m mockDidMethod) Read(did string, opts ...ResolveOpt) ([]byte, error) {
+func (m mockDidMethod) Read(_ context.Context, did string, opts ...ResolveOpt) ([]byte, error) {
 	return m.readValue, m.readErr
 }
 
@@ -0,0 +1,145 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package maintenance runs the storage maintenance operations keeping the long-running agents (e.g. mediators)
+// healthy: the expired transient records are purged, including the expired records of the storage providers
+// expiring the records lazily, then the stores are compacted if the storage provider supports it. The operations
+// are run on demand, e.g. by the controller admin API, or on a schedule.
+package maintenance
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/common/log"
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/audit"
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/threads"
+	"github.com/hyperledger/aries-framework-go/pkg/storage"
+)
+
+var logger = log.New("aries-framework/maintenance")
+
+// provider contains the stores maintained and is typically created by using aries.Context()
+type provider interface {
+	StorageProvider() storage.Provider
+	ThreadStore() *threads.Store
+}
+
+// auditStoreProvider is implemented by the providers keeping an audit trail
+type auditStoreProvider interface {
+	AuditStore() *audit.Store
+}
+
+// Report is the outcome of a maintenance run
+type Report struct {
+	// PurgedThreads is the number of expired or completed thread records purged
+	PurgedThreads int `json:"purgedThreads"`
+	// PurgedRecords is the number of expired records purged from the stores
+	PurgedRecords int `json:"purgedRecords"`
+	// PurgedAuditRecords is the number of audit records deleted past their retention
+	PurgedAuditRecords int `json:"purgedAuditRecords"`
+	// Compacted is true if the stores have been compacted
+	Compacted bool `json:"compacted"`
+	// Duration is the duration of the run
+	Duration time.Duration `json:"duration"`
+}
+
+// Run purges the expired transient records and compacts the stores
+func Run(prov provider) (*Report, error) {
+	start := time.Now()
+	report := &Report{}
+
+	if ts := prov.ThreadStore(); ts != nil {
+		purged, err := ts.Cleanup()
+		if err != nil {
+			return nil, fmt.Errorf("failed to purge threads: %w", err)
+		}
+
+		report.PurgedThreads = purged
+	}
+
+	if p, ok := prov.(auditStoreProvider); ok && p.AuditStore() != nil {
+		purged, err := p.AuditStore().Cleanup()
+		if err != nil {
+			return nil, fmt.Errorf("failed to purge audit records: %w", err)
+		}
+
+		report.PurgedAuditRecords = purged
+	}
+
+	if expiring, ok := prov.StorageProvider().(storage.ExpiringProvider); ok {
+		purged, err := expiring.PurgeExpired()
+		if err != nil {
+			return nil, fmt.Errorf("failed to purge expired records: %w", err)
+		}
+
+		report.PurgedRecords = purged
+	}
+
+	if compactable, ok := prov.StorageProvider().(storage.CompactableProvider); ok {
+		if err := compactable.Compact(); err != nil {
+			return nil, fmt.Errorf("failed to compact stores: %w", err)
+		}
+
+		report.Compacted = true
+	}
+
+	report.Duration = time.Since(start)
+
+	return report, nil
+}
+
+// Scheduler runs the maintenance periodically
+type Scheduler struct {
+	prov     provider
+	interval time.Duration
+	done     chan struct{}
+	once     sync.Once
+	wg       sync.WaitGroup
+}
+
+// NewScheduler returns a scheduler running the maintenance of the stores of the provider at the interval
+func NewScheduler(prov provider, interval time.Duration) *Scheduler {
+	return &Scheduler{prov: prov, interval: interval, done: make(chan struct{})}
+}
+
+// Start starts running the maintenance at the interval, the first run happens after the interval
+func (s *Scheduler) Start() {
+	s.wg.Add(1)
+
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				report, err := Run(s.prov)
+				if err != nil {
+					logger.Errorf("scheduled maintenance failed: %s", err)
+					continue
+				}
+
+				logger.Infof("scheduled maintenance purged %d threads and %d records in %s",
+					report.PurgedThreads, report.PurgedRecords, report.Duration)
+			case <-s.done:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the scheduler and waits for the maintenance being run
+func (s *Scheduler) Stop() {
+	s.once.Do(func() {
+		close(s.done)
+	})
+
+	s.wg.Wait()
+}
@@ -0,0 +1,234 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package maintenance
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/audit"
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/threads"
+	mockstorage "github.com/hyperledger/aries-framework-go/pkg/internal/mock/storage"
+	"github.com/hyperledger/aries-framework-go/pkg/storage"
+)
+
+func TestRun(t *testing.T) {
+	t.Run("test expired threads are purged and stores compacted", func(t *testing.T) {
+		prov := newMockProvider(t, threads.WithExpiry(time.Nanosecond))
+		require.NoError(t, prov.threadStore.Save(&threads.Record{ThreadID: "thid1", Protocol: "didexchange"}))
+		require.NoError(t, prov.threadStore.Save(&threads.Record{ThreadID: "thid2", Protocol: "didexchange"}))
+		time.Sleep(time.Millisecond)
+
+		report, err := Run(prov)
+		require.NoError(t, err)
+		require.Equal(t, 2, report.PurgedThreads)
+		require.Equal(t, 3, report.PurgedRecords)
+		require.True(t, report.Compacted)
+		require.True(t, prov.storageProvider.compacted)
+	})
+
+	t.Run("test storage provider not compactable", func(t *testing.T) {
+		report, err := Run(&mockProvider{store: mockstorage.NewMockStoreProvider()})
+		require.NoError(t, err)
+		require.Zero(t, report.PurgedThreads)
+		require.False(t, report.Compacted)
+	})
+
+	t.Run("test purge error", func(t *testing.T) {
+		prov := newMockProvider(t)
+		require.NoError(t, prov.threadStore.Save(&threads.Record{ThreadID: "thid1", Protocol: "didexchange"}))
+		prov.mockStore.Store.ErrGet = errors.New("get error")
+
+		_, err := Run(prov)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to purge threads")
+		require.False(t, prov.storageProvider.compacted)
+	})
+
+	t.Run("test purge expired records error", func(t *testing.T) {
+		prov := newMockProvider(t)
+		prov.storageProvider.purgeErr = errors.New("purge error")
+
+		_, err := Run(prov)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to purge expired records: purge error")
+	})
+
+	t.Run("test expired audit records are deleted", func(t *testing.T) {
+		prov := newMockProvider(t)
+
+		auditStore, err := audit.New(&iterableProvider{Provider: prov.mockStore}, audit.WithRetention(time.Hour))
+		require.NoError(t, err)
+
+		require.NoError(t, auditStore.Add(&audit.Record{Timestamp: time.Now().Add(-2 * time.Hour)}))
+		require.NoError(t, auditStore.Add(&audit.Record{}))
+
+		report, err := Run(&auditProvider{mockProvider: prov, audit: auditStore})
+		require.NoError(t, err)
+		require.Equal(t, 1, report.PurgedAuditRecords)
+
+		records, err := auditStore.Query(&audit.Query{})
+		require.NoError(t, err)
+		require.Len(t, records, 1)
+
+		require.NoError(t, auditStore.Add(&audit.Record{Timestamp: time.Now().Add(-2 * time.Hour)}))
+		prov.mockStore.Store.ErrDelete = errors.New("delete error")
+
+		_, err = Run(&auditProvider{mockProvider: prov, audit: auditStore})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to purge audit records")
+	})
+
+	t.Run("test compaction error", func(t *testing.T) {
+		prov := newMockProvider(t)
+		prov.storageProvider.err = errors.New("compact error")
+
+		_, err := Run(prov)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to compact stores: compact error")
+	})
+}
+
+func TestScheduler(t *testing.T) {
+	t.Run("test maintenance is run at the interval", func(t *testing.T) {
+		prov := newMockProvider(t)
+
+		s := NewScheduler(prov, time.Millisecond)
+		s.Start()
+
+		for i := 0; i < 100 && !prov.storageProvider.isCompacted(); i++ {
+			time.Sleep(5 * time.Millisecond)
+		}
+
+		s.Stop()
+		require.True(t, prov.storageProvider.isCompacted())
+
+		// stop is idempotent
+		s.Stop()
+	})
+
+	t.Run("test failed maintenance doesn't stop the scheduler", func(t *testing.T) {
+		prov := newMockProvider(t)
+		prov.storageProvider.err = errors.New("compact error")
+
+		s := NewScheduler(prov, time.Millisecond)
+		s.Start()
+		time.Sleep(10 * time.Millisecond)
+		s.Stop()
+
+		require.True(t, prov.storageProvider.isCompacted())
+	})
+}
+
+type mockProvider struct {
+	store           storage.Provider
+	mockStore       *mockstorage.MockStoreProvider
+	storageProvider *compactableProvider
+	threadStore     *threads.Store
+}
+
+func newMockProvider(t *testing.T, opts ...threads.Opt) *mockProvider {
+	mockStore := mockstorage.NewMockStoreProvider()
+	storageProvider := &compactableProvider{Provider: mockStore, purged: 3}
+
+	threadStore, err := threads.New(&notExpiringProvider{Provider: storageProvider}, opts...)
+	require.NoError(t, err)
+
+	return &mockProvider{
+		store:           storageProvider,
+		mockStore:       mockStore,
+		storageProvider: storageProvider,
+		threadStore:     threadStore,
+	}
+}
+
+func (p *mockProvider) StorageProvider() storage.Provider {
+	return p.store
+}
+
+func (p *mockProvider) ThreadStore() *threads.Store {
+	return p.threadStore
+}
+
+type compactableProvider struct {
+	storage.Provider
+	lock      sync.Mutex
+	compacted bool
+	err       error
+	purged    int
+	purgeErr  error
+}
+
+func (p *compactableProvider) PurgeExpired() (int, error) {
+	return p.purged, p.purgeErr
+}
+
+func (p *compactableProvider) Compact() error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.compacted = true
+
+	return p.err
+}
+
+func (p *compactableProvider) isCompacted() bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return p.compacted
+}
+
+// notExpiringProvider hides the expiry of the records so the threads are expired by the thread store
+type notExpiringProvider struct {
+	storage.Provider
+}
+
+func (p *notExpiringProvider) OpenStore(name string) (storage.Store, error) {
+	store, err := p.Provider.OpenStore(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return struct{ storage.Store }{store}, nil
+}
+
+type auditProvider struct {
+	*mockProvider
+	audit *audit.Store
+}
+
+func (p *auditProvider) AuditStore() *audit.Store {
+	return p.audit
+}
+
+// iterableProvider hides the expiry of the records but not their iteration, so the audit records are deleted by
+// the audit store
+type iterableProvider struct {
+	storage.Provider
+}
+
+func (p *iterableProvider) OpenStore(name string) (storage.Store, error) {
+	store, err := p.Provider.OpenStore(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &iterableStore{Store: store}, nil
+}
+
+type iterableStore struct {
+	storage.Store
+}
+
+func (s *iterableStore) Iterate(prefix string, fn func(k string, v []byte) error) error {
+	return s.Store.(storage.IterableStore).Iterate(prefix, fn)
+}
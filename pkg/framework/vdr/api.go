@@ -0,0 +1,81 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vdr
+
+import (
+	"errors"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+)
+
+// ErrNilChannel is returned when registering a nil event channel.
+var ErrNilChannel = errors.New("event channel is nil")
+
+// VDR publishes DID documents to a verifiable data registry (ledger, sidetree node, web server etc).
+type VDR interface {
+	// Create anchors the DID document in the registry and returns the published document,
+	// which may differ from the input (e.g. the registry may assign the DID).
+	Create(doc *did.Doc, opts ...CreateOpt) (*did.Doc, error)
+	// Accept registers this VDR with the given DID method.
+	Accept(method string) bool
+}
+
+// Creator publishes DID documents through the VDR of the DID method, implemented by Registry
+type Creator interface {
+	Create(method string, doc *did.Doc, opts ...CreateOpt) (*did.Doc, error)
+}
+
+// CreateOpts holds the options for DID document creation
+type CreateOpts struct {
+	// Options are VDR specific settings, e.g. a ledger role or a sidetree recovery key
+	Options map[string]interface{}
+}
+
+// CreateOpt is a DID document creation option
+type CreateOpt func(opts *CreateOpts)
+
+// WithOption sets VDR specific creation option
+func WithOption(name string, value interface{}) CreateOpt {
+	return func(opts *CreateOpts) {
+		opts.Options[name] = value
+	}
+}
+
+// NewCreateOpts applies the creation options
+func NewCreateOpts(opts ...CreateOpt) *CreateOpts {
+	createOpts := &CreateOpts{Options: make(map[string]interface{})}
+	for _, opt := range opts {
+		opt(createOpts)
+	}
+
+	return createOpts
+}
+
+// CreateEvent is sent to the registered channels once a DID document publishing is finished.
+type CreateEvent struct {
+	// Method is the DID method of the VDR
+	Method string
+	// Doc is the published DID document, nil if publishing failed
+	Doc *did.Doc
+	// Err is the publishing error
+	Err error
+}
+
+// registryOpts holds the options for registry instance
+type registryOpts struct {
+	vdrs []VDR
+}
+
+// Opt is a registry instance option
+type Opt func(opts *registryOpts)
+
+// WithVDR adds VDR to the registry
+// VDRs are checked in the order added
+func WithVDR(v VDR) Opt {
+	return func(opts *registryOpts) {
+		opts.vdrs = append(opts.vdrs, v)
+	}
+}
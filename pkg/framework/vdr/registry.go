@@ -0,0 +1,116 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vdr
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/hyperledger/aries-framework-go/pkg/common/log"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+)
+
+var logger = log.New("aries-framework/vdr")
+
+// Registry publishes DID documents through the VDR of the DID method
+type Registry struct {
+	vdrs   []VDR
+	mu     sync.RWMutex
+	events []chan<- CreateEvent
+}
+
+// New return new instance of VDR registry
+func New(opts ...Opt) *Registry {
+	regOpts := &registryOpts{}
+	// Apply options
+	for _, opt := range opts {
+		opt(regOpts)
+	}
+
+	return &Registry{vdrs: regOpts.vdrs}
+}
+
+// Create publishes the DID document through the VDR of the method.
+// The result is also sent to the registered event channels.
+func (r *Registry) Create(method string, doc *did.Doc, opts ...CreateOpt) (*did.Doc, error) {
+	published, err := r.create(method, doc, opts...)
+
+	r.sendEvent(CreateEvent{Method: method, Doc: published, Err: err})
+
+	return published, err
+}
+
+func (r *Registry) create(method string, doc *did.Doc, opts ...CreateOpt) (*did.Doc, error) {
+	if doc == nil {
+		return nil, errors.New("DID document is mandatory")
+	}
+
+	v, err := r.resolveVDR(method)
+	if err != nil {
+		return nil, err
+	}
+
+	published, err := v.Create(doc, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to publish DID document %s: %w", doc.ID, err)
+	}
+
+	return published, nil
+}
+
+// RegisterCreateEvent registers the channel to receive the results of DID document publishing, the events
+// are dropped when the channel is not ready.
+func (r *Registry) RegisterCreateEvent(ch chan<- CreateEvent) error {
+	if ch == nil {
+		return ErrNilChannel
+	}
+
+	r.mu.Lock()
+	r.events = append(r.events, ch)
+	r.mu.Unlock()
+
+	return nil
+}
+
+// UnregisterCreateEvent unregisters the channel. Refer RegisterCreateEvent().
+func (r *Registry) UnregisterCreateEvent(ch chan<- CreateEvent) error {
+	r.mu.Lock()
+	for i := 0; i < len(r.events); i++ {
+		if r.events[i] == ch {
+			r.events = append(r.events[:i], r.events[i+1:]...)
+			i--
+		}
+	}
+	r.mu.Unlock()
+
+	return nil
+}
+
+func (r *Registry) sendEvent(event CreateEvent) {
+	r.mu.RLock()
+	events := append(r.events[:0:0], r.events...)
+	r.mu.RUnlock()
+
+	for _, ch := range events {
+		select {
+		case ch <- event:
+		default:
+			logger.Warnf("dropped DID create event of method %s, the channel is not ready", event.Method)
+		}
+	}
+}
+
+// resolveVDR resolve VDR of the did method
+func (r *Registry) resolveVDR(method string) (VDR, error) {
+	for _, v := range r.vdrs {
+		if v.Accept(method) {
+			return v, nil
+		}
+	}
+
+	return nil, fmt.Errorf("did method %s not supported for vdr", method)
+}
@@ -0,0 +1,109 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vdr
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+)
+
+func TestRegistry_Create(t *testing.T) {
+	t.Run("test did method not supported", func(t *testing.T) {
+		registry := New(WithVDR(&mockVDR{acceptValue: false}))
+		doc, err := registry.Create("example", &did.Doc{ID: "did:example:1"})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "did method example not supported for vdr")
+		require.Nil(t, doc)
+	})
+
+	t.Run("test nil document", func(t *testing.T) {
+		registry := New(WithVDR(&mockVDR{acceptValue: true}))
+		doc, err := registry.Create("example", nil)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "DID document is mandatory")
+		require.Nil(t, doc)
+	})
+
+	t.Run("test vdr create error", func(t *testing.T) {
+		registry := New(WithVDR(&mockVDR{acceptValue: true, createErr: errors.New("ledger unavailable")}))
+		doc, err := registry.Create("example", &did.Doc{ID: "did:example:1"})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to publish DID document did:example:1: ledger unavailable")
+		require.Nil(t, doc)
+	})
+
+	t.Run("test vdr create success with options", func(t *testing.T) {
+		v := &mockVDR{acceptValue: true}
+		registry := New(WithVDR(&mockVDR{acceptValue: false}), WithVDR(v))
+		doc, err := registry.Create("example", &did.Doc{ID: "did:example:1"}, WithOption("role", "ENDORSER"))
+		require.NoError(t, err)
+		require.Equal(t, "did:example:1", doc.ID)
+		require.Equal(t, "ENDORSER", v.createOpts.Options["role"])
+	})
+}
+
+func TestRegistry_CreateEvents(t *testing.T) {
+	registry := New(WithVDR(&mockVDR{acceptValue: true}))
+
+	require.Equal(t, ErrNilChannel, registry.RegisterCreateEvent(nil))
+
+	events := make(chan CreateEvent, 2)
+	require.NoError(t, registry.RegisterCreateEvent(events))
+
+	_, err := registry.Create("example", &did.Doc{ID: "did:example:1"})
+	require.NoError(t, err)
+
+	event := <-events
+	require.NoError(t, event.Err)
+	require.Equal(t, "example", event.Method)
+	require.Equal(t, "did:example:1", event.Doc.ID)
+
+	_, err = registry.Create("example", nil)
+	require.Error(t, err)
+
+	event = <-events
+	require.Error(t, event.Err)
+	require.Nil(t, event.Doc)
+
+	// the event is dropped instead of blocking the publishing when the channel is not ready
+	blocked := make(chan CreateEvent)
+	require.NoError(t, registry.RegisterCreateEvent(blocked))
+
+	_, err = registry.Create("example", &did.Doc{ID: "did:example:1"})
+	require.NoError(t, err)
+	<-events
+
+	require.NoError(t, registry.UnregisterCreateEvent(blocked))
+	require.NoError(t, registry.UnregisterCreateEvent(events))
+
+	_, err = registry.Create("example", &did.Doc{ID: "did:example:1"})
+	require.NoError(t, err)
+	require.Empty(t, events)
+}
+
+type mockVDR struct {
+	acceptValue bool
+	createErr   error
+	createOpts  *CreateOpts
+}
+
+func (m *mockVDR) Create(doc *did.Doc, opts ...CreateOpt) (*did.Doc, error) {
+	m.createOpts = NewCreateOpts(opts...)
+
+	if m.createErr != nil {
+		return nil, m.createErr
+	}
+
+	return doc, nil
+}
+
+func (m *mockVDR) Accept(method string) bool {
+	return m.acceptValue
+}
@@ -0,0 +1,52 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package cryptoutil
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/agl/ed25519"
+	"github.com/agl/ed25519/extra25519"
+)
+
+// Curve25519KeySize is the size of public and private Curve25519 keys in bytes
+const Curve25519KeySize = 32
+
+// PublicEd25519toCurve25519 converts an Ed25519 public key to the corresponding Curve25519 (X25519) public key
+// This function wraps PublicKeyToCurve25519 from Adam Langley's ed25519 repo: https://github.com/agl/ed25519
+func PublicEd25519toCurve25519(pub []byte) ([]byte, error) {
+	if len(pub) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid Ed25519 public key size %d", len(pub))
+	}
+
+	edPub := new([ed25519.PublicKeySize]byte)
+	copy(edPub[:], pub)
+
+	pkOut := new([Curve25519KeySize]byte)
+	if !extra25519.PublicKeyToCurve25519(pkOut, edPub) {
+		return nil, errors.New("failed to convert public key")
+	}
+
+	return pkOut[:], nil
+}
+
+// SecretEd25519toCurve25519 converts an Ed25519 private key to the corresponding Curve25519 (X25519) private key
+// This function wraps PrivateKeyToCurve25519 from Adam Langley's ed25519 repo: https://github.com/agl/ed25519
+func SecretEd25519toCurve25519(priv []byte) ([]byte, error) {
+	if len(priv) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("invalid Ed25519 private key size %d", len(priv))
+	}
+
+	edPriv := new([ed25519.PrivateKeySize]byte)
+	copy(edPriv[:], priv)
+
+	sKOut := new([Curve25519KeySize]byte)
+	extra25519.PrivateKeyToCurve25519(sKOut, edPriv)
+
+	return sKOut[:], nil
+}
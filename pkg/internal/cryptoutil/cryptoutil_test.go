@@ -0,0 +1,50 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package cryptoutil
+
+import (
+	"crypto/rand"
+	"testing"
+
+	"github.com/agl/ed25519"
+	"github.com/btcsuite/btcutil/base58"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/curve25519"
+)
+
+func TestEd25519toCurve25519(t *testing.T) {
+	t.Run("test converted keys are a key pair", func(t *testing.T) {
+		edPub, edPriv, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+
+		pub, err := PublicEd25519toCurve25519(edPub[:])
+		require.NoError(t, err)
+		require.Len(t, pub, Curve25519KeySize)
+
+		priv, err := SecretEd25519toCurve25519(edPriv[:])
+		require.NoError(t, err)
+		require.Len(t, priv, Curve25519KeySize)
+
+		var sk, pk [Curve25519KeySize]byte
+		copy(sk[:], priv)
+		curve25519.ScalarBaseMult(&pk, &sk)
+		require.Equal(t, pub, pk[:])
+	})
+
+	t.Run("test invalid key sizes", func(t *testing.T) {
+		_, err := PublicEd25519toCurve25519([]byte("short"))
+		require.EqualError(t, err, "invalid Ed25519 public key size 5")
+
+		_, err = SecretEd25519toCurve25519([]byte("short"))
+		require.EqualError(t, err, "invalid Ed25519 private key size 5")
+	})
+
+	t.Run("test public key not on the curve", func(t *testing.T) {
+		_, err := PublicEd25519toCurve25519(base58.Decode("6ZAQ7QpmR9EqhJdwx1jQsjq6nnpehwVqUbhVxiEiYEV7"))
+		require.EqualError(t, err, "failed to convert public key")
+	})
+}
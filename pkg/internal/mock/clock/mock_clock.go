@@ -0,0 +1,34 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock mock clock advanced by the tests
+type Clock struct {
+	mutex sync.Mutex
+	Time  time.Time
+}
+
+// Now returns the time of the clock
+func (c *Clock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.Time
+}
+
+// Add advances the clock by d
+func (c *Clock) Add(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.Time = c.Time.Add(d)
+}
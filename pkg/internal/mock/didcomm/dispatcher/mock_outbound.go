@@ -6,6 +6,8 @@ SPDX-License-Identifier: Apache-2.0
 package dispatcher
 
 import (
+	"context"
+
 	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/service"
 )
 
@@ -15,6 +17,6 @@ type MockOutbound struct {
 }
 
 // Send msg
-func (m *MockOutbound) Send(msg interface{}, senderVerKey string, des *service.Destination) error {
+func (m *MockOutbound) Send(ctx context.Context, msg interface{}, senderVerKey string, des *service.Destination) error {
 	return m.SendErr
 }
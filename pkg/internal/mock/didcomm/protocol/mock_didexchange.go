@@ -7,11 +7,17 @@ SPDX-License-Identifier: Apache-2.0
 package protocol
 
 import (
+	"context"
+
+	"github.com/hyperledger/aries-framework-go/pkg/common/clock"
+	"github.com/hyperledger/aries-framework-go/pkg/common/idgen"
 	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/service"
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/threads"
 	"github.com/hyperledger/aries-framework-go/pkg/didcomm/dispatcher"
 	mockdispatcher "github.com/hyperledger/aries-framework-go/pkg/internal/mock/didcomm/dispatcher"
 	mockstore "github.com/hyperledger/aries-framework-go/pkg/internal/mock/storage"
 	"github.com/hyperledger/aries-framework-go/pkg/storage"
+	"github.com/hyperledger/aries-framework-go/pkg/wallet"
 )
 
 // MockDIDExchangeSvc mock did exchange service
@@ -26,7 +32,7 @@ type MockDIDExchangeSvc struct {
 }
 
 // Handle msg
-func (m *MockDIDExchangeSvc) Handle(msg *service.DIDCommMsg) error {
+func (m *MockDIDExchangeSvc) Handle(ctx context.Context, msg *service.DIDCommMsg) error {
 	if m.HandleFunc != nil {
 		return m.HandleFunc(*msg)
 	}
@@ -83,7 +89,11 @@ func (m *MockDIDExchangeSvc) UnregisterMsgEvent(ch chan<- service.StateMsg) erro
 
 // MockProvider is provider for DIDExchange Service
 type MockProvider struct {
-	CustomStore storage.Store
+	CustomStore      storage.Store
+	ThreadStoreValue *threads.Store
+	ClockValue       clock.Clock
+	IDGeneratorValue idgen.Generator
+	WalletValue      wallet.Crypto
 }
 
 // OutboundDispatcher is mock outbound dispatcher for DID exchange service
@@ -91,6 +101,11 @@ func (p *MockProvider) OutboundDispatcher() dispatcher.Outbound {
 	return &mockdispatcher.MockOutbound{}
 }
 
+// CryptoWallet is mock wallet for DID exchange service
+func (p *MockProvider) CryptoWallet() wallet.Crypto {
+	return p.WalletValue
+}
+
 // StorageProvider is mock storage provider for DID exchange service
 func (p *MockProvider) StorageProvider() storage.Provider {
 	if p.CustomStore != nil {
@@ -98,3 +113,27 @@ func (p *MockProvider) StorageProvider() storage.Provider {
 	}
 	return mockstore.NewMockStoreProvider()
 }
+
+// ThreadStore is mock thread store for DID exchange service, the threads are kept apart from the custom store
+func (p *MockProvider) ThreadStore() *threads.Store {
+	if p.ThreadStoreValue != nil {
+		return p.ThreadStoreValue
+	}
+
+	s, err := threads.New(mockstore.NewMockStoreProvider())
+	if err != nil {
+		panic(err)
+	}
+
+	return s
+}
+
+// Clock is mock clock for DID exchange service, the system clock is used if not set
+func (p *MockProvider) Clock() clock.Clock {
+	return p.ClockValue
+}
+
+// IDGenerator is mock ID generator for DID exchange service, the random UUIDs are used if not set
+func (p *MockProvider) IDGenerator() idgen.Generator {
+	return p.IDGeneratorValue
+}
@@ -7,6 +7,8 @@ SPDX-License-Identifier: Apache-2.0
 package provider
 
 import (
+	"github.com/hyperledger/aries-framework-go/pkg/common/clock"
+	"github.com/hyperledger/aries-framework-go/pkg/common/idgen"
 	"github.com/hyperledger/aries-framework-go/pkg/storage"
 	"github.com/hyperledger/aries-framework-go/pkg/wallet"
 )
@@ -18,6 +20,8 @@ type Provider struct {
 	WalletValue          wallet.Crypto
 	InboundEndpointValue string
 	StorageProviderValue storage.Provider
+	ClockValue           clock.Clock
+	IDGeneratorValue     idgen.Generator
 }
 
 // Service return service
@@ -39,3 +43,13 @@ func (p *Provider) InboundTransportEndpoint() string {
 func (p *Provider) StorageProvider() storage.Provider {
 	return p.StorageProviderValue
 }
+
+// Clock returns the clock, the system clock is used if not set
+func (p *Provider) Clock() clock.Clock {
+	return p.ClockValue
+}
+
+// IDGenerator returns the ID generator, the random UUIDs are used if not set
+func (p *Provider) IDGenerator() idgen.Generator {
+	return p.IDGeneratorValue
+}
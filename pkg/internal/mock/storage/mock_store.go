@@ -6,8 +6,11 @@ SPDX-License-Identifier: Apache-2.0
 package storage
 
 import (
-	"errors"
+	"bytes"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/hyperledger/aries-framework-go/pkg/storage"
 )
@@ -51,33 +54,179 @@ func (s *MockStoreProvider) CloseStore(name string) error {
 
 // MockStore mock store.
 type MockStore struct {
-	Store  map[string][]byte
-	lock   sync.RWMutex
-	ErrPut error
-	ErrGet error
+	Store      map[string][]byte
+	expiries   map[string]time.Time
+	lock       sync.RWMutex
+	ErrPut     error
+	ErrGet     error
+	ErrIterate error
+	ErrDelete  error
 }
 
 // Put stores the key and the record
 func (s *MockStore) Put(k string, v []byte) error {
 	if k == "" {
-		return errors.New("key is mandatory")
+		return storage.ErrKeyRequired
 	}
 	s.lock.Lock()
 	s.Store[k] = v
+	delete(s.expiries, k)
 	s.lock.Unlock()
 
 	return s.ErrPut
 }
 
+// Delete removes the record
+func (s *MockStore) Delete(k string) error {
+	if k == "" {
+		return storage.ErrKeyRequired
+	}
+
+	if s.ErrDelete != nil {
+		return s.ErrDelete
+	}
+
+	s.lock.Lock()
+	delete(s.Store, k)
+	delete(s.expiries, k)
+	s.lock.Unlock()
+
+	return nil
+}
+
+// PutWithTTL stores the key and the record expiring after the ttl
+func (s *MockStore) PutWithTTL(k string, v []byte, ttl time.Duration) error {
+	if k == "" {
+		return storage.ErrKeyRequired
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.expiries == nil {
+		s.expiries = make(map[string]time.Time)
+	}
+
+	s.Store[k] = v
+	s.expiries[k] = time.Now().Add(ttl)
+
+	return s.ErrPut
+}
+
+// PutAll stores the records atomically, none of the records is stored if ErrPut is set
+func (s *MockStore) PutAll(records map[string][]byte) error {
+	for k := range records {
+		if k == "" {
+			return storage.ErrKeyRequired
+		}
+	}
+
+	if s.ErrPut != nil {
+		return s.ErrPut
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for k, v := range records {
+		s.Store[k] = v
+		delete(s.expiries, k)
+	}
+
+	return nil
+}
+
+// PutIf stores the record if the current record is the expected one
+func (s *MockStore) PutIf(k string, v, expected []byte) error {
+	if k == "" {
+		return storage.ErrKeyRequired
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	current := s.Store[k]
+	if s.expired(k) {
+		current = nil
+	}
+
+	if !bytes.Equal(current, expected) {
+		return storage.ErrConflict
+	}
+
+	s.Store[k] = v
+	delete(s.expiries, k)
+
+	return s.ErrPut
+}
+
 // Get fetches the record based on key
 func (s *MockStore) Get(k string) ([]byte, error) {
 	s.lock.RLock()
 	defer s.lock.RUnlock()
 
 	val, ok := s.Store[k]
-	if !ok {
+	if !ok || s.expired(k) {
 		return nil, storage.ErrDataNotFound
 	}
 
 	return val, s.ErrGet
 }
+
+// Iterate calls fn with the records whose key starts with the prefix in key order
+func (s *MockStore) Iterate(prefix string, fn func(k string, v []byte) error) error {
+	if s.ErrIterate != nil {
+		return s.ErrIterate
+	}
+
+	s.lock.RLock()
+
+	var keys []string
+
+	records := make(map[string][]byte)
+
+	for k, v := range s.Store {
+		if strings.HasPrefix(k, prefix) && !s.expired(k) {
+			keys = append(keys, k)
+			records[k] = v
+		}
+	}
+
+	s.lock.RUnlock()
+
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if err := fn(k, records[k]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// TTL returns the remaining time to live of the record
+func (s *MockStore) TTL(k string) (time.Duration, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if _, ok := s.Store[k]; !ok || s.expired(k) {
+		return 0, storage.ErrDataNotFound
+	}
+
+	if expiry, ok := s.expiries[k]; ok {
+		if ttl := time.Until(expiry); ttl > 0 {
+			return ttl, nil
+		}
+
+		return 0, storage.ErrDataNotFound
+	}
+
+	return 0, nil
+}
+
+func (s *MockStore) expired(k string) bool {
+	expiry, ok := s.expiries[k]
+
+	return ok && !time.Now().Before(expiry)
+}
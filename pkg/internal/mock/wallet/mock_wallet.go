@@ -23,7 +23,11 @@ type CloseableWallet struct {
 	PackErr                  error
 	UnpackValue              *wallet.Envelope
 	UnpackErr                error
+	DecryptValue             *wallet.DecryptedMessage
+	DecryptErr               error
 	MockDID                  *did.Doc
+	HasKeyValue              bool
+	HasKeyErr                error
 }
 
 // Close previously-opened wallet, removing it if so configured.
@@ -47,8 +51,8 @@ func (m *CloseableWallet) SignMessage(message []byte, fromVerKey string) ([]byte
 }
 
 // DecryptMessage decrypt message
-func (m *CloseableWallet) DecryptMessage(encMessage []byte, toVerKey string) ([]byte, string, error) {
-	return nil, "", nil
+func (m *CloseableWallet) DecryptMessage(encMessage []byte, toVerKey string) (*wallet.DecryptedMessage, error) {
+	return m.DecryptValue, m.DecryptErr
 }
 
 // PackMessage Pack a message for one or more recipients.
@@ -65,3 +69,8 @@ func (m *CloseableWallet) UnpackMessage(encMessage []byte) (*wallet.Envelope, er
 func (m *CloseableWallet) CreateDID(method string, opts ...wallet.DocOpts) (*did.Doc, error) {
 	return m.MockDID, nil
 }
+
+// HasKey returns true if the wallet holds the key pair of the verification key
+func (m *CloseableWallet) HasKey(verKey string) (bool, error) {
+	return m.HasKeyValue, m.HasKeyErr
+}
@@ -0,0 +1,100 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package fs implements the objectstore.Store keeping every object in a file of a directory.
+package fs
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/hyperledger/aries-framework-go/pkg/objectstore"
+)
+
+const (
+	dirPerm  = 0700
+	filePerm = 0600
+)
+
+// Store is the file system objectstore.Store, the objects are named after their keys
+type Store struct {
+	dir string
+}
+
+// NewStore returns the object store keeping the objects in the directory, the directory is created if needed
+func NewStore(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, dirPerm); err != nil {
+		return nil, fmt.Errorf("failed to create object store directory: %w", err)
+	}
+
+	return &Store{dir: dir}, nil
+}
+
+// Put writes the object to a temporary file renamed once written, so the object is never read partially written
+func (s *Store) Put(key string, value []byte) error {
+	if err := objectstore.CheckKey(key); err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(s.dir, ".tmp-")
+	if err != nil {
+		return fmt.Errorf("failed to create object file: %w", err)
+	}
+
+	defer os.Remove(tmp.Name()) // nolint: errcheck
+
+	_, err = tmp.Write(value)
+	if e := tmp.Close(); err == nil {
+		err = e
+	}
+
+	if err == nil {
+		err = os.Chmod(tmp.Name(), filePerm)
+	}
+
+	if err == nil {
+		err = os.Rename(tmp.Name(), filepath.Join(s.dir, key))
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to write object %s: %w", key, err)
+	}
+
+	return nil
+}
+
+// Get reads the object
+func (s *Store) Get(key string) ([]byte, error) {
+	if err := objectstore.CheckKey(key); err != nil {
+		return nil, err
+	}
+
+	value, err := ioutil.ReadFile(filepath.Join(s.dir, key)) // nolint: gosec
+	if os.IsNotExist(err) {
+		return nil, objectstore.ErrNotFound
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read object %s: %w", key, err)
+	}
+
+	return value, nil
+}
+
+// Delete removes the file of the object
+func (s *Store) Delete(key string) error {
+	if err := objectstore.CheckKey(key); err != nil {
+		return err
+	}
+
+	if err := os.Remove(filepath.Join(s.dir, key)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete object %s: %w", key, err)
+	}
+
+	return nil
+}
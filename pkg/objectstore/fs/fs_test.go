@@ -0,0 +1,85 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package fs
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hyperledger/aries-framework-go/pkg/objectstore"
+)
+
+func TestStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "objectstore")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir) // nolint: errcheck
+
+	s, err := NewStore(filepath.Join(dir, "objects"))
+	require.NoError(t, err)
+
+	t.Run("test put get delete", func(t *testing.T) {
+		_, err = s.Get("k1")
+		require.Equal(t, objectstore.ErrNotFound, err)
+
+		require.NoError(t, s.Put("k1", []byte("v1")))
+		require.NoError(t, s.Put("k1", []byte("v2")))
+
+		v, e := s.Get("k1")
+		require.NoError(t, e)
+		require.Equal(t, []byte("v2"), v)
+
+		files, e := ioutil.ReadDir(filepath.Join(dir, "objects"))
+		require.NoError(t, e)
+		require.Len(t, files, 1)
+		require.Equal(t, os.FileMode(filePerm), files[0].Mode())
+
+		require.NoError(t, s.Delete("k1"))
+		require.NoError(t, s.Delete("k1"))
+
+		_, err = s.Get("k1")
+		require.Equal(t, objectstore.ErrNotFound, err)
+	})
+
+	t.Run("test invalid key", func(t *testing.T) {
+		require.True(t, errors.Is(s.Put("../k1", nil), objectstore.ErrInvalidKey))
+
+		_, err = s.Get("")
+		require.True(t, errors.Is(err, objectstore.ErrInvalidKey))
+		require.True(t, errors.Is(s.Delete(".."), objectstore.ErrInvalidKey))
+	})
+
+	t.Run("test file errors", func(t *testing.T) {
+		require.NoError(t, os.Mkdir(filepath.Join(dir, "objects", "k2"), dirPerm))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "objects", "k2", "k"), nil, filePerm))
+
+		err = s.Put("k2", []byte("v2"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to write object k2")
+
+		_, err = s.Get("k2")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to read object k2")
+
+		err = s.Delete("k2")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to delete object k2")
+
+		_, err = NewStore(filepath.Join(dir, "objects", "k2", "k"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to create object store directory")
+
+		err = (&Store{dir: filepath.Join(dir, "missing")}).Put("k1", nil)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to create object file")
+	})
+}
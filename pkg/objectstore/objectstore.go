@@ -0,0 +1,43 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package objectstore defines the store of the large binary objects kept out of the storage.Provider, like the
+// payloads of the message attachments, so the deployment picks where the objects are kept: on the file system
+// (see objectstore/fs) or in an S3 compatible object store (see objectstore/s3).
+package objectstore
+
+import "errors"
+
+// ErrNotFound is returned when no object is stored for the key
+var ErrNotFound = errors.New("object not found")
+
+// ErrInvalidKey is returned when the key is empty or contains characters other than [A-Za-z0-9._-]
+var ErrInvalidKey = errors.New("invalid object key")
+
+// Store keeps the objects by key
+type Store interface {
+	// Put stores the object, replacing the object stored for the key if any
+	Put(key string, value []byte) error
+	// Get returns the object stored for the key or ErrNotFound
+	Get(key string) ([]byte, error)
+	// Delete removes the object, deleting a key not stored isn't an error
+	Delete(key string) error
+}
+
+// CheckKey returns ErrInvalidKey if the key can't be used as a file name or an URL path segment as is
+func CheckKey(key string) error {
+	if key == "" || key == "." || key == ".." {
+		return ErrInvalidKey
+	}
+
+	for _, c := range key {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '.' || c == '_' || c == '-') {
+			return ErrInvalidKey
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,23 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package objectstore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckKey(t *testing.T) {
+	for _, key := range []string{"k1", "2b7e1516.bin", "attachment_1-a"} {
+		require.NoError(t, CheckKey(key), key)
+	}
+
+	for _, key := range []string{"", ".", "..", "a/b", `a\b`, "a b", "a%2Fb", "é"} {
+		require.Equal(t, ErrInvalidKey, CheckKey(key), key)
+	}
+}
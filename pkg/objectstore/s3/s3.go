@@ -0,0 +1,262 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package s3 implements the objectstore.Store keeping the objects in a bucket of an S3 compatible object store
+// (AWS S3, MinIO, Ceph...). The requests are sent path-style (endpoint/bucket/key) and signed with the AWS
+// signature version 4 when credentials are configured.
+package s3
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/common/clock"
+	"github.com/hyperledger/aries-framework-go/pkg/objectstore"
+)
+
+const (
+	defaultRegion = "us-east-1"
+	service       = "s3"
+	algorithm     = "AWS4-HMAC-SHA256"
+	dateFormat    = "20060102T150405Z"
+
+	// maxErrorBodySize is the maximum size of the error responses read into the errors
+	maxErrorBodySize = 1024
+
+	defaultTimeout       = 30 * time.Second
+	defaultMaxObjectSize = 64 << 20
+)
+
+// Store is the S3 objectstore.Store of a bucket
+type Store struct {
+	endpoint  string
+	bucket    string
+	region    string
+	accessKey string
+	secretKey string
+	client    *http.Client
+	maxSize   int64
+	clock     clock.Clock
+}
+
+// Opt configures the S3 object store
+type Opt func(s *Store)
+
+// WithRegion sets the region the requests are signed for, us-east-1 by default
+func WithRegion(region string) Opt {
+	return func(s *Store) {
+		s.region = region
+	}
+}
+
+// WithCredentials signs the requests with the access key, the requests are anonymous by default
+func WithCredentials(accessKey, secretKey string) Opt {
+	return func(s *Store) {
+		s.accessKey = accessKey
+		s.secretKey = secretKey
+	}
+}
+
+// WithHTTPClient sets the HTTP client of the requests, a client with a 30 seconds timeout by default
+func WithHTTPClient(client *http.Client) Opt {
+	return func(s *Store) {
+		s.client = client
+	}
+}
+
+// WithMaxObjectSize limits the size of the objects stored and read, 64 MiB by default
+func WithMaxObjectSize(size int64) Opt {
+	return func(s *Store) {
+		s.maxSize = size
+	}
+}
+
+// WithClock sets the clock of the request signatures, the system clock by default
+func WithClock(c clock.Clock) Opt {
+	return func(s *Store) {
+		s.clock = c
+	}
+}
+
+// NewStore returns the object store of the bucket served at the endpoint (e.g. https://s3.eu-west-1.amazonaws.com)
+func NewStore(endpoint, bucket string, opts ...Opt) *Store {
+	s := &Store{
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		bucket:   bucket,
+		region:   defaultRegion,
+		client:   &http.Client{Timeout: defaultTimeout},
+		maxSize:  defaultMaxObjectSize,
+		clock:    clock.System(),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Put uploads the object
+func (s *Store) Put(key string, value []byte) error {
+	if int64(len(value)) > s.maxSize {
+		return fmt.Errorf("object %s exceeds the maximum size of %d bytes", key, s.maxSize)
+	}
+
+	resp, err := s.do(http.MethodPut, key, value)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close() // nolint: errcheck
+
+	if resp.StatusCode != http.StatusOK {
+		return responseError(resp, "put", key)
+	}
+
+	return nil
+}
+
+// Get downloads the object
+func (s *Store) Get(key string) ([]byte, error) {
+	resp, err := s.do(http.MethodGet, key, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close() // nolint: errcheck
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, objectstore.ErrNotFound
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError(resp, "get", key)
+	}
+
+	// one more byte than the maximum size is read to detect the larger objects
+	value, err := ioutil.ReadAll(io.LimitReader(resp.Body, s.maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read object %s: %w", key, err)
+	}
+
+	if int64(len(value)) > s.maxSize {
+		return nil, fmt.Errorf("object %s exceeds the maximum size of %d bytes", key, s.maxSize)
+	}
+
+	return value, nil
+}
+
+// Delete removes the object, S3 doesn't report the deletion of the keys not stored
+func (s *Store) Delete(key string) error {
+	resp, err := s.do(http.MethodDelete, key, nil)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close() // nolint: errcheck
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK &&
+		resp.StatusCode != http.StatusNotFound {
+		return responseError(resp, "delete", key)
+	}
+
+	return nil
+}
+
+func (s *Store) do(method, key string, body []byte) (*http.Response, error) {
+	if err := objectstore.CheckKey(key); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(method, s.endpoint+"/"+url.PathEscape(s.bucket)+"/"+key, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create object store request: %w", err)
+	}
+
+	if s.accessKey != "" {
+		s.sign(req, body)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("object store request failed: %w", err)
+	}
+
+	return resp, nil
+}
+
+// sign adds the AWS signature version 4 of the request, all the headers of the request are signed
+func (s *Store) sign(req *http.Request, body []byte) {
+	now := s.clock.Now().UTC()
+	payloadHash := sha256.Sum256(body)
+
+	req.Header.Set("X-Amz-Date", now.Format(dateFormat))
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(payloadHash[:]))
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.Join(values, ",")
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(headers[name]) + "\n")
+	}
+
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.Query().Encode(),
+		canonicalHeaders.String(),
+		signedHeaders,
+		req.Header.Get("X-Amz-Content-Sha256"),
+	}, "\n")
+
+	scope := strings.Join([]string{now.Format("20060102"), s.region, service, "aws4_request"}, "/")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{algorithm, now.Format(dateFormat), scope, hex.EncodeToString(requestHash[:])},
+		"\n")
+
+	key := []byte("AWS4" + s.secretKey)
+	for _, part := range []string{now.Format("20060102"), s.region, service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		algorithm, s.accessKey, scope, signedHeaders, hex.EncodeToString(hmacSHA256(key, stringToSign))))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data)) // nolint: errcheck
+
+	return mac.Sum(nil)
+}
+
+func responseError(resp *http.Response, op, key string) error {
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize)) // nolint: errcheck
+
+	return fmt.Errorf("failed to %s object %s: %s: %s", op, key, resp.Status, strings.TrimSpace(string(body)))
+}
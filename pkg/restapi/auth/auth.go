@@ -0,0 +1,188 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/hyperledger/aries-framework-go/pkg/common/log"
+	"github.com/hyperledger/aries-framework-go/pkg/internal/common/support"
+	"github.com/hyperledger/aries-framework-go/pkg/restapi/operation"
+)
+
+var logger = log.New("aries-framework/rest/auth")
+
+// ErrNoCredentials is returned by the authenticators when the request doesn't carry their credentials, the next
+// authenticator is then tried
+var ErrNoCredentials = errors.New("no credentials")
+
+// Principal is the authenticated caller of the controller REST API
+type Principal struct {
+	ID string
+	// Scopes are the permissions granted to the principal, checked against the route policies
+	Scopes []string
+}
+
+// HasScopes returns true if the principal is granted all the scopes
+func (p *Principal) HasScopes(scopes ...string) bool {
+	for _, scope := range scopes {
+		granted := false
+
+		for _, s := range p.Scopes {
+			if s == scope {
+				granted = true
+				break
+			}
+		}
+
+		if !granted {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Authenticator authenticates the caller of a request
+type Authenticator interface {
+	// Authenticate returns the principal of the request, ErrNoCredentials if the request doesn't carry the
+	// credentials of the authenticator
+	Authenticate(r *http.Request) (*Principal, error)
+}
+
+type principalKey struct{}
+
+// PrincipalFromContext returns the principal authenticated for the request of the context, nil if none
+func PrincipalFromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
+
+type route struct {
+	path   string
+	method string
+}
+
+type policy struct {
+	public bool
+	scopes []string
+}
+
+// Opt represents an authorization option
+type Opt func(m *Middleware)
+
+// WithRoutePolicy requires the principals calling the route to be granted all the scopes, the path is the route
+// template of the REST handler, e.g. /connections/{id}. The other routes only require an authenticated principal.
+func WithRoutePolicy(path, method string, scopes ...string) Opt {
+	return func(m *Middleware) {
+		m.policies[route{path: path, method: method}] = policy{scopes: scopes}
+	}
+}
+
+// WithPublicRoute serves the route without authentication, e.g. the features of the agent
+func WithPublicRoute(path, method string) Opt {
+	return func(m *Middleware) {
+		m.policies[route{path: path, method: method}] = policy{public: true}
+	}
+}
+
+// Middleware authenticates and authorizes the requests of the controller REST API
+type Middleware struct {
+	authenticators []Authenticator
+	policies       map[route]policy
+}
+
+// New returns the middleware authenticating the requests with the first authenticator finding its credentials
+func New(authenticators []Authenticator, opts ...Opt) (*Middleware, error) {
+	if len(authenticators) == 0 {
+		return nil, errors.New("no authenticator")
+	}
+
+	m := &Middleware{authenticators: authenticators, policies: make(map[route]policy)}
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return m, nil
+}
+
+// Wrap returns the handler authorizing the requests before calling the handler
+func (m *Middleware) Wrap(h operation.Handler) operation.Handler {
+	p := m.policies[route{path: h.Path(), method: h.Method()}]
+	if p.public {
+		return h
+	}
+
+	handle := h.Handle()
+
+	return support.NewHTTPHandler(h.Path(), h.Method(), func(rw http.ResponseWriter, req *http.Request) {
+		principal, err := m.authenticate(req)
+		if err != nil {
+			logger.Warnf("unauthenticated request %s %s: %s", req.Method, req.URL.Path, err)
+			writeError(rw, http.StatusUnauthorized, err)
+
+			return
+		}
+
+		if !principal.HasScopes(p.scopes...) {
+			writeError(rw, http.StatusForbidden, fmt.Errorf("principal %s is not authorized", principal.ID))
+			return
+		}
+
+		handle(rw, req.WithContext(context.WithValue(req.Context(), principalKey{}, principal)))
+	})
+}
+
+// WrapAll wraps the handlers
+func (m *Middleware) WrapAll(handlers []operation.Handler) []operation.Handler {
+	wrapped := make([]operation.Handler, len(handlers))
+	for i, h := range handlers {
+		wrapped[i] = m.Wrap(h)
+	}
+
+	return wrapped
+}
+
+func (m *Middleware) authenticate(req *http.Request) (*Principal, error) {
+	for _, a := range m.authenticators {
+		principal, err := a.Authenticate(req)
+		if errors.Is(err, ErrNoCredentials) {
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		return principal, nil
+	}
+
+	return nil, errors.New("authentication required")
+}
+
+type errorResponse struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func writeError(rw http.ResponseWriter, status int, err error) {
+	rw.Header().Set("Content-Type", "application/json")
+
+	if status == http.StatusUnauthorized {
+		rw.Header().Set("WWW-Authenticate", "Bearer")
+	}
+
+	rw.WriteHeader(status)
+
+	if e := json.NewEncoder(rw).Encode(errorResponse{Code: status, Message: err.Error()}); e != nil {
+		logger.Errorf("Unable to send response, %s", e)
+	}
+}
@@ -0,0 +1,134 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hyperledger/aries-framework-go/pkg/internal/common/support"
+	"github.com/hyperledger/aries-framework-go/pkg/restapi/operation"
+)
+
+func TestMiddleware(t *testing.T) {
+	admin := &Principal{ID: "admin", Scopes: []string{"connections:read", "connections:write"}}
+	reader := &Principal{ID: "reader", Scopes: []string{"connections:read"}}
+
+	m, err := New([]Authenticator{NewAPIKey(map[string]*Principal{"admin-key": admin, "reader-key": reader})},
+		WithRoutePolicy("/connections/{id}/remove", http.MethodPost, "connections:write"),
+		WithRoutePolicy("/connections", http.MethodGet, "connections:read"),
+		WithPublicRoute("/features", http.MethodGet))
+	require.NoError(t, err)
+
+	handler := func(path, method string) operation.Handler {
+		return support.NewHTTPHandler(path, method, func(rw http.ResponseWriter, req *http.Request) {
+			principal := PrincipalFromContext(req.Context())
+			if principal == nil {
+				fmt.Fprint(rw, "anonymous")
+				return
+			}
+
+			fmt.Fprint(rw, principal.ID)
+		})
+	}
+
+	serve := func(h operation.Handler, apiKey string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(h.Method(), h.Path(), nil)
+		if apiKey != "" {
+			req.Header.Set(APIKeyHeader, apiKey)
+		}
+
+		rr := httptest.NewRecorder()
+		m.Wrap(h).Handle()(rr, req)
+
+		return rr
+	}
+
+	t.Run("test authorized requests", func(t *testing.T) {
+		rr := serve(handler("/connections/{id}/remove", http.MethodPost), "admin-key")
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Equal(t, "admin", rr.Body.String())
+
+		rr = serve(handler("/connections", http.MethodGet), "reader-key")
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Equal(t, "reader", rr.Body.String())
+
+		// the routes without policy only require an authenticated principal
+		rr = serve(handler("/connections/{id}", http.MethodGet), "reader-key")
+		require.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("test public route", func(t *testing.T) {
+		rr := serve(handler("/features", http.MethodGet), "")
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Equal(t, "anonymous", rr.Body.String())
+	})
+
+	t.Run("test unauthenticated requests", func(t *testing.T) {
+		rr := serve(handler("/connections", http.MethodGet), "")
+		require.Equal(t, http.StatusUnauthorized, rr.Code)
+		require.Equal(t, "Bearer", rr.Header().Get("WWW-Authenticate"))
+
+		response := errorResponse{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+		require.Equal(t, errorResponse{Code: http.StatusUnauthorized, Message: "authentication required"}, response)
+
+		rr = serve(handler("/connections", http.MethodGet), "unknown-key")
+		require.Equal(t, http.StatusUnauthorized, rr.Code)
+		require.Contains(t, rr.Body.String(), "invalid API key")
+	})
+
+	t.Run("test forbidden request", func(t *testing.T) {
+		rr := serve(handler("/connections/{id}/remove", http.MethodPost), "reader-key")
+		require.Equal(t, http.StatusForbidden, rr.Code)
+		require.Contains(t, rr.Body.String(), "principal reader is not authorized")
+	})
+
+	t.Run("test wrap all", func(t *testing.T) {
+		handlers := m.WrapAll([]operation.Handler{handler("/features", http.MethodGet),
+			handler("/connections", http.MethodGet)})
+		require.Len(t, handlers, 2)
+		require.Equal(t, "/connections", handlers[1].Path())
+		require.Equal(t, http.MethodGet, handlers[1].Method())
+	})
+
+	t.Run("test authenticators tried in order", func(t *testing.T) {
+		failing := &mockAuthenticator{err: errors.New("invalid token")}
+		chained, err := New([]Authenticator{&mockAuthenticator{err: ErrNoCredentials}, failing})
+		require.NoError(t, err)
+
+		_, err = chained.authenticate(httptest.NewRequest(http.MethodGet, "/connections", nil))
+		require.EqualError(t, err, "invalid token")
+
+		failing.err = nil
+		failing.principal = admin
+
+		principal, err := chained.authenticate(httptest.NewRequest(http.MethodGet, "/connections", nil))
+		require.NoError(t, err)
+		require.Equal(t, admin, principal)
+	})
+
+	t.Run("test no authenticator", func(t *testing.T) {
+		_, err := New(nil)
+		require.EqualError(t, err, "no authenticator")
+	})
+}
+
+type mockAuthenticator struct {
+	principal *Principal
+	err       error
+}
+
+func (a *mockAuthenticator) Authenticate(*http.Request) (*Principal, error) {
+	return a.principal, a.err
+}
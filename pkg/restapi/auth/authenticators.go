@@ -0,0 +1,305 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/common/clock"
+)
+
+// APIKeyHeader is the header carrying the API keys
+const APIKeyHeader = "X-API-Key"
+
+type apiKeyAuthenticator struct {
+	keys map[string]*Principal
+}
+
+// NewAPIKey returns the authenticator of the API keys sent in the X-API-Key header, the keys are mapped to
+// their principals
+func NewAPIKey(keys map[string]*Principal) Authenticator {
+	return &apiKeyAuthenticator{keys: keys}
+}
+
+func (a *apiKeyAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	key := r.Header.Get(APIKeyHeader)
+	if key == "" {
+		return nil, ErrNoCredentials
+	}
+
+	var principal *Principal
+
+	// all the keys are compared in constant time so the timing doesn't leak them
+	for k, p := range a.keys {
+		if subtle.ConstantTimeCompare([]byte(k), []byte(key)) == 1 {
+			principal = p
+		}
+	}
+
+	if principal == nil {
+		return nil, errors.New("invalid API key")
+	}
+
+	return principal, nil
+}
+
+const (
+	defaultIntrospectionTimeout  = 10 * time.Second
+	defaultIntrospectionCacheTTL = time.Minute
+	maxCachedTokens              = 1000
+)
+
+type introspectionOpts struct {
+	client       *http.Client
+	clientID     string
+	clientSecret string
+	cacheTTL     time.Duration
+	clock        clock.Clock
+}
+
+// IntrospectionOpt represents an OAuth2 token introspection option
+type IntrospectionOpt func(opts *introspectionOpts)
+
+// WithIntrospectionClient sets the HTTP client of the introspection requests, a client with a 10 seconds timeout
+// by default
+func WithIntrospectionClient(client *http.Client) IntrospectionOpt {
+	return func(opts *introspectionOpts) {
+		opts.client = client
+	}
+}
+
+// WithClientCredentials sets the credentials the agent authenticates with to the introspection endpoint
+func WithClientCredentials(clientID, clientSecret string) IntrospectionOpt {
+	return func(opts *introspectionOpts) {
+		opts.clientID = clientID
+		opts.clientSecret = clientSecret
+	}
+}
+
+// WithIntrospectionCacheTTL sets how long the active tokens are cached before being introspected again, one minute
+// by default. The tokens are never cached past their expiry and a zero TTL disables the cache.
+func WithIntrospectionCacheTTL(ttl time.Duration) IntrospectionOpt {
+	return func(opts *introspectionOpts) {
+		opts.cacheTTL = ttl
+	}
+}
+
+// WithIntrospectionClock sets the clock expiring the cached tokens, the system clock by default
+func WithIntrospectionClock(c clock.Clock) IntrospectionOpt {
+	return func(opts *introspectionOpts) {
+		opts.clock = c
+	}
+}
+
+type cachedToken struct {
+	principal *Principal
+	expiry    time.Time
+}
+
+type introspectionAuthenticator struct {
+	endpoint string
+	opts     *introspectionOpts
+	mutex    sync.Mutex
+	// the active tokens keyed by their hash so the cache doesn't hold them
+	cache map[[sha256.Size]byte]*cachedToken
+}
+
+// NewOAuth2Introspection returns the authenticator of the OAuth2 bearer tokens, the tokens are checked by the
+// introspection endpoint of the authorization server (RFC 7662) and their scopes granted to the principals.
+// The active tokens are cached so the endpoint isn't called on every request.
+func NewOAuth2Introspection(endpoint string, opts ...IntrospectionOpt) Authenticator {
+	iOpts := &introspectionOpts{
+		client:   &http.Client{Timeout: defaultIntrospectionTimeout},
+		cacheTTL: defaultIntrospectionCacheTTL,
+		clock:    clock.System(),
+	}
+	for _, opt := range opts {
+		opt(iOpts)
+	}
+
+	return &introspectionAuthenticator{
+		endpoint: endpoint,
+		opts:     iOpts,
+		cache:    make(map[[sha256.Size]byte]*cachedToken),
+	}
+}
+
+func (a *introspectionAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	const bearerPrefix = "Bearer "
+
+	authorization := r.Header.Get("Authorization")
+	if !strings.HasPrefix(authorization, bearerPrefix) {
+		return nil, ErrNoCredentials
+	}
+
+	token := strings.TrimPrefix(authorization, bearerPrefix)
+	hash := sha256.Sum256([]byte(token))
+
+	if principal := a.cached(hash); principal != nil {
+		return principal, nil
+	}
+
+	principal, expiry, err := a.introspect(token)
+	if err != nil {
+		return nil, err
+	}
+
+	a.cacheToken(hash, principal, expiry)
+
+	return principal, nil
+}
+
+func (a *introspectionAuthenticator) cached(hash [sha256.Size]byte) *Principal {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	cached, ok := a.cache[hash]
+	if !ok {
+		return nil
+	}
+
+	if !a.opts.clock.Now().Before(cached.expiry) {
+		delete(a.cache, hash)
+
+		return nil
+	}
+
+	return cached.principal
+}
+
+func (a *introspectionAuthenticator) cacheToken(hash [sha256.Size]byte, principal *Principal, expiry time.Time) {
+	if a.opts.cacheTTL <= 0 {
+		return
+	}
+
+	now := a.opts.clock.Now()
+
+	if ttlExpiry := now.Add(a.opts.cacheTTL); expiry.IsZero() || ttlExpiry.Before(expiry) {
+		expiry = ttlExpiry
+	}
+
+	if !now.Before(expiry) {
+		return
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if len(a.cache) >= maxCachedTokens {
+		for h, cached := range a.cache {
+			if !now.Before(cached.expiry) {
+				delete(a.cache, h)
+			}
+		}
+	}
+
+	// the cache is bounded, the tokens are introspected again when it is full of live tokens
+	if len(a.cache) >= maxCachedTokens {
+		return
+	}
+
+	a.cache[hash] = &cachedToken{principal: principal, expiry: expiry}
+}
+
+// introspect returns the principal of the active token and its expiry, zero if the token has none
+func (a *introspectionAuthenticator) introspect(token string) (*Principal, time.Time, error) {
+	req, err := http.NewRequest(http.MethodPost, a.endpoint, strings.NewReader(url.Values{
+		"token":           {token},
+		"token_type_hint": {"access_token"},
+	}.Encode()))
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("create introspection request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	if a.opts.clientID != "" {
+		req.SetBasicAuth(a.opts.clientID, a.opts.clientSecret)
+	}
+
+	resp, err := a.opts.client.Do(req)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("token introspection failed: %w", err)
+	}
+
+	defer func() {
+		if e := resp.Body.Close(); e != nil {
+			logger.Errorf("closing response body failed [%v]", e)
+		}
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, time.Time{}, fmt.Errorf("token introspection HTTP failure [%v]", resp.StatusCode)
+	}
+
+	introspection := &struct {
+		Active   bool   `json:"active"`
+		Scope    string `json:"scope"`
+		Subject  string `json:"sub"`
+		ClientID string `json:"client_id"`
+		Expiry   int64  `json:"exp"`
+	}{}
+
+	if err := json.NewDecoder(resp.Body).Decode(introspection); err != nil {
+		return nil, time.Time{}, fmt.Errorf("JSON unmarshalling of token introspection failed: %w", err)
+	}
+
+	if !introspection.Active {
+		return nil, time.Time{}, errors.New("inactive bearer token")
+	}
+
+	principal := &Principal{ID: introspection.Subject, Scopes: strings.Fields(introspection.Scope)}
+	if principal.ID == "" {
+		principal.ID = introspection.ClientID
+	}
+
+	var expiry time.Time
+	if introspection.Expiry != 0 {
+		expiry = time.Unix(introspection.Expiry, 0)
+	}
+
+	return principal, expiry, nil
+}
+
+type mtlsAuthenticator struct {
+	subjects map[string]*Principal
+}
+
+// NewMTLS returns the authenticator of the TLS client certificates, the subject common names of the certificates
+// are mapped to their principals. The certificates must be verified by the TLS server, e.g. with
+// tls.RequireAndVerifyClientCert and the client CAs.
+func NewMTLS(subjects map[string]*Principal) Authenticator {
+	return &mtlsAuthenticator{subjects: subjects}
+}
+
+func (a *mtlsAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		return nil, ErrNoCredentials
+	}
+
+	if len(r.TLS.VerifiedChains) == 0 {
+		return nil, errors.New("client certificate not verified")
+	}
+
+	subject := r.TLS.VerifiedChains[0][0].Subject.CommonName
+
+	principal, ok := a.subjects[subject]
+	if !ok {
+		return nil, fmt.Errorf("client certificate subject %s not authorized", subject)
+	}
+
+	return principal, nil
+}
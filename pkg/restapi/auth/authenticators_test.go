@@ -0,0 +1,247 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package auth
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hyperledger/aries-framework-go/pkg/common/clock"
+)
+
+func TestOAuth2Introspection(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if id, secret, ok := r.BasicAuth(); !ok || id != "agent" || secret != "agent-secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch r.FormValue("token") {
+		case "user-token":
+			fmt.Fprint(w, `{"active": true, "scope": "connections:read connections:write", "sub": "alice"}`)
+		case "client-token":
+			fmt.Fprint(w, `{"active": true, "client_id": "monitoring"}`)
+		case "invalid-response":
+			fmt.Fprint(w, `not JSON`)
+		default:
+			fmt.Fprint(w, `{"active": false}`)
+		}
+	}))
+	defer server.Close()
+
+	authenticator := NewOAuth2Introspection(server.URL, WithIntrospectionClient(server.Client()),
+		WithClientCredentials("agent", "agent-secret"))
+
+	request := func(authorization string) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/connections", nil)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+
+		return req
+	}
+
+	t.Run("test active tokens", func(t *testing.T) {
+		principal, err := authenticator.Authenticate(request("Bearer user-token"))
+		require.NoError(t, err)
+		require.Equal(t, &Principal{ID: "alice", Scopes: []string{"connections:read", "connections:write"}},
+			principal)
+
+		principal, err = authenticator.Authenticate(request("Bearer client-token"))
+		require.NoError(t, err)
+		require.Equal(t, "monitoring", principal.ID)
+		require.Empty(t, principal.Scopes)
+	})
+
+	t.Run("test no bearer token", func(t *testing.T) {
+		_, err := authenticator.Authenticate(request(""))
+		require.True(t, errors.Is(err, ErrNoCredentials))
+
+		_, err = authenticator.Authenticate(request("Basic YWxpY2U6c2VjcmV0"))
+		require.True(t, errors.Is(err, ErrNoCredentials))
+	})
+
+	t.Run("test invalid tokens", func(t *testing.T) {
+		_, err := authenticator.Authenticate(request("Bearer expired-token"))
+		require.EqualError(t, err, "inactive bearer token")
+
+		_, err = authenticator.Authenticate(request("Bearer invalid-response"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "JSON unmarshalling of token introspection failed")
+	})
+
+	t.Run("test introspection failures", func(t *testing.T) {
+		_, err := NewOAuth2Introspection(server.URL, WithIntrospectionClient(server.Client())).
+			Authenticate(request("Bearer user-token"))
+		require.EqualError(t, err, "token introspection HTTP failure [401]")
+
+		_, err = NewOAuth2Introspection("http://localhost:1/introspect").Authenticate(request("Bearer user-token"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "token introspection failed")
+
+		_, err = NewOAuth2Introspection("%").Authenticate(request("Bearer user-token"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "create introspection request")
+	})
+}
+
+func TestOAuth2Introspection_Cache(t *testing.T) {
+	now := time.Now()
+
+	var calls int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+
+		switch r.FormValue("token") {
+		case "expiring-token":
+			fmt.Fprintf(w, `{"active": true, "sub": "bob", "exp": %d}`, now.Add(10*time.Second).Unix())
+		case "user-token":
+			fmt.Fprint(w, `{"active": true, "sub": "alice"}`)
+		default:
+			fmt.Fprint(w, `{"active": false}`)
+		}
+	}))
+	defer server.Close()
+
+	request := func(token string) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/connections", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		return req
+	}
+
+	authenticate := func(t *testing.T, authenticator Authenticator, token string) {
+		principal, err := authenticator.Authenticate(request(token))
+		require.NoError(t, err)
+		require.NotNil(t, principal)
+	}
+
+	t.Run("test active tokens are cached until the TTL", func(t *testing.T) {
+		atomic.StoreInt32(&calls, 0)
+
+		current := now
+		authenticator := NewOAuth2Introspection(server.URL, WithIntrospectionCacheTTL(time.Minute),
+			WithIntrospectionClock(clock.Func(func() time.Time { return current })))
+
+		authenticate(t, authenticator, "user-token")
+		authenticate(t, authenticator, "user-token")
+		require.EqualValues(t, 1, atomic.LoadInt32(&calls))
+
+		current = now.Add(time.Minute)
+
+		authenticate(t, authenticator, "user-token")
+		require.EqualValues(t, 2, atomic.LoadInt32(&calls))
+	})
+
+	t.Run("test active tokens are not cached past their expiry", func(t *testing.T) {
+		atomic.StoreInt32(&calls, 0)
+
+		current := now
+		authenticator := NewOAuth2Introspection(server.URL,
+			WithIntrospectionClock(clock.Func(func() time.Time { return current })))
+
+		authenticate(t, authenticator, "expiring-token")
+		authenticate(t, authenticator, "expiring-token")
+		require.EqualValues(t, 1, atomic.LoadInt32(&calls))
+
+		current = now.Add(10 * time.Second)
+
+		authenticate(t, authenticator, "expiring-token")
+		require.EqualValues(t, 2, atomic.LoadInt32(&calls))
+	})
+
+	t.Run("test inactive tokens are not cached", func(t *testing.T) {
+		atomic.StoreInt32(&calls, 0)
+
+		authenticator := NewOAuth2Introspection(server.URL)
+
+		for i := 0; i < 2; i++ {
+			_, err := authenticator.Authenticate(request("revoked-token"))
+			require.EqualError(t, err, "inactive bearer token")
+		}
+
+		require.EqualValues(t, 2, atomic.LoadInt32(&calls))
+	})
+
+	t.Run("test cache disabled", func(t *testing.T) {
+		atomic.StoreInt32(&calls, 0)
+
+		authenticator := NewOAuth2Introspection(server.URL, WithIntrospectionCacheTTL(0))
+
+		authenticate(t, authenticator, "user-token")
+		authenticate(t, authenticator, "user-token")
+		require.EqualValues(t, 2, atomic.LoadInt32(&calls))
+	})
+
+	t.Run("test cache size is bounded", func(t *testing.T) {
+		authenticator, ok := NewOAuth2Introspection(server.URL).(*introspectionAuthenticator)
+		require.True(t, ok)
+
+		for i := 0; i < maxCachedTokens+1; i++ {
+			authenticator.cacheToken([32]byte{byte(i), byte(i >> 8)}, &Principal{ID: "alice"}, time.Time{})
+		}
+
+		require.Len(t, authenticator.cache, maxCachedTokens)
+	})
+}
+
+func TestMTLS(t *testing.T) {
+	operator := &Principal{ID: "operator", Scopes: []string{"connections:read"}}
+	authenticator := NewMTLS(map[string]*Principal{"operator.example.com": operator})
+
+	request := func(cn string, verified bool) *http.Request {
+		cert := &x509.Certificate{Subject: pkix.Name{CommonName: cn}}
+
+		req := httptest.NewRequest(http.MethodGet, "/connections", nil)
+		req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}
+
+		if verified {
+			req.TLS.VerifiedChains = [][]*x509.Certificate{{cert}}
+		}
+
+		return req
+	}
+
+	t.Run("test verified client certificate", func(t *testing.T) {
+		principal, err := authenticator.Authenticate(request("operator.example.com", true))
+		require.NoError(t, err)
+		require.Equal(t, operator, principal)
+	})
+
+	t.Run("test no client certificate", func(t *testing.T) {
+		_, err := authenticator.Authenticate(httptest.NewRequest(http.MethodGet, "/connections", nil))
+		require.True(t, errors.Is(err, ErrNoCredentials))
+	})
+
+	t.Run("test client certificate not authorized", func(t *testing.T) {
+		_, err := authenticator.Authenticate(request("operator.example.com", false))
+		require.EqualError(t, err, "client certificate not verified")
+
+		_, err = authenticator.Authenticate(request("intruder.example.com", true))
+		require.EqualError(t, err, "client certificate subject intruder.example.com not authorized")
+	})
+}
+
+func TestPrincipal_HasScopes(t *testing.T) {
+	p := &Principal{ID: "alice", Scopes: []string{"connections:read", "connections:write"}}
+
+	require.True(t, p.HasScopes())
+	require.True(t, p.HasScopes("connections:read"))
+	require.True(t, p.HasScopes("connections:write", "connections:read"))
+	require.False(t, p.HasScopes("connections:read", "audit:read"))
+}
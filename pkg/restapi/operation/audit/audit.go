@@ -0,0 +1,144 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package audit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/common/log"
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/audit"
+	"github.com/hyperledger/aries-framework-go/pkg/internal/common/support"
+	"github.com/hyperledger/aries-framework-go/pkg/restapi/operation"
+	"github.com/hyperledger/aries-framework-go/pkg/restapi/operation/audit/models"
+)
+
+var logger = log.New("aries-framework/rest/audit")
+
+const (
+	outboundPath = "/audit/outbound"
+
+	// InvalidRequestErrorCode is the error code of the invalid queries
+	InvalidRequestErrorCode = int32(iota + 1)
+	// AuditDisabledErrorCode is the error code returned when the outbound messages aren't audited
+	AuditDisabledErrorCode
+	// QueryErrorCode is the error code of the audit store failures
+	QueryErrorCode
+)
+
+// errAuditDisabled is returned when the agent doesn't audit the outbound messages
+var errAuditDisabled = errors.New("outbound messages aren't audited by the agent")
+
+// provider contains the audit store of the agent and is typically created by using aries.Context()
+type provider interface {
+	AuditStore() *audit.Store
+}
+
+// Operation is controller REST service controller for the audit trail of the agent
+type Operation struct {
+	ctx      provider
+	handlers []operation.Handler
+}
+
+// New returns new audit rest client instance
+func New(ctx provider) *Operation {
+	o := &Operation{ctx: ctx}
+	o.handlers = []operation.Handler{
+		support.NewHTTPHandler(outboundPath, http.MethodGet, o.QueryOutboundMessages),
+	}
+
+	return o
+}
+
+// QueryOutboundMessages swagger:route GET /audit/outbound audit queryOutboundMessages
+//
+// Queries the audit trail of the outbound messages.
+//
+// Responses:
+//
+//	default: genericError
+//	    200: queryOutboundMessagesResponse
+func (o *Operation) QueryOutboundMessages(rw http.ResponseWriter, req *http.Request) {
+	store := o.ctx.AuditStore()
+	if store == nil {
+		writeError(rw, http.StatusNotFound, AuditDisabledErrorCode, errAuditDisabled)
+		return
+	}
+
+	query, err := parseQuery(req.URL.Query())
+	if err != nil {
+		writeError(rw, http.StatusBadRequest, InvalidRequestErrorCode, err)
+		return
+	}
+
+	records, err := store.Query(query)
+	if err != nil {
+		writeError(rw, http.StatusInternalServerError, QueryErrorCode, err)
+		return
+	}
+
+	response := models.QueryOutboundMessagesResponse{}
+	response.Body.Results = records
+
+	writeResponse(rw, response)
+}
+
+// GetRESTHandlers get all controller API handler available for the audit trail
+func (o *Operation) GetRESTHandlers() []operation.Handler {
+	return o.handlers
+}
+
+func parseQuery(vals url.Values) (*audit.Query, error) {
+	query := &audit.Query{MsgType: vals.Get("msgType"), ThreadID: vals.Get("thid")}
+
+	var err error
+
+	if v := vals.Get("since"); v != "" {
+		if query.Since, err = time.Parse(time.RFC3339, v); err != nil {
+			return nil, fmt.Errorf("invalid since: %w", err)
+		}
+	}
+
+	if v := vals.Get("until"); v != "" {
+		if query.Until, err = time.Parse(time.RFC3339, v); err != nil {
+			return nil, fmt.Errorf("invalid until: %w", err)
+		}
+	}
+
+	if v := vals.Get("limit"); v != "" {
+		if query.Limit, err = strconv.Atoi(v); err != nil || query.Limit < 0 {
+			return nil, fmt.Errorf("invalid limit: %s", v)
+		}
+	}
+
+	return query, nil
+}
+
+func writeError(rw http.ResponseWriter, status int, code int32, err error) {
+	rw.WriteHeader(status)
+
+	errResponse := models.GenericError{}
+	errResponse.Body.Code = code
+	errResponse.Body.Message = err.Error()
+
+	writeResponse(rw, errResponse)
+}
+
+// writeResponse writes interface value to response
+func writeResponse(rw io.Writer, v interface{}) {
+	err := json.NewEncoder(rw).Encode(v)
+	// as of now, just log errors for writing response
+	if err != nil {
+		logger.Errorf("Unable to send response, %s", err)
+	}
+}
@@ -0,0 +1,114 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package audit
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/audit"
+	mockstorage "github.com/hyperledger/aries-framework-go/pkg/internal/mock/storage"
+	"github.com/hyperledger/aries-framework-go/pkg/restapi/operation/audit/models"
+)
+
+type mockProvider struct {
+	auditStore *audit.Store
+}
+
+func (p *mockProvider) AuditStore() *audit.Store {
+	return p.auditStore
+}
+
+func TestOperation_QueryOutboundMessages(t *testing.T) {
+	prov := mockstorage.NewMockStoreProvider()
+	store, err := audit.New(prov)
+	require.NoError(t, err)
+
+	sent := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	for i, msgType := range []string{"request", "response", "request"} {
+		require.NoError(t, store.Add(&audit.Record{MsgType: msgType, ThreadID: "thid",
+			Hash: "hash", Timestamp: sent.Add(time.Duration(i) * time.Hour)}))
+	}
+
+	handlers := New(&mockProvider{auditStore: store}).GetRESTHandlers()
+	require.Len(t, handlers, 1)
+	require.Equal(t, outboundPath, handlers[0].Path())
+	require.Equal(t, http.MethodGet, handlers[0].Method())
+
+	query := func(target string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		handlers[0].Handle().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, target, nil))
+
+		return rr
+	}
+
+	t.Run("test query all records", func(t *testing.T) {
+		rr := query(outboundPath)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		response := models.QueryOutboundMessagesResponse{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+		require.Len(t, response.Body.Results, 3)
+	})
+
+	t.Run("test query records", func(t *testing.T) {
+		rr := query(outboundPath + "?msgType=request&thid=thid&since=2020-01-01T01:00:00Z&until=2020-01-01T02:00:00Z")
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		response := models.QueryOutboundMessagesResponse{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+		require.Len(t, response.Body.Results, 1)
+		require.Equal(t, sent.Add(2*time.Hour), response.Body.Results[0].Timestamp)
+
+		rr = query(outboundPath + "?limit=2")
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+		require.Len(t, response.Body.Results, 2)
+	})
+
+	t.Run("test invalid query", func(t *testing.T) {
+		for _, q := range []string{"?since=yesterday", "?until=1", "?limit=x", "?limit=-1"} {
+			rr := query(outboundPath + q)
+			require.Equal(t, http.StatusBadRequest, rr.Code, q)
+
+			response := models.GenericError{}
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+			require.Equal(t, InvalidRequestErrorCode, response.Body.Code)
+		}
+	})
+
+	t.Run("test query error", func(t *testing.T) {
+		prov.Store.ErrIterate = errors.New("iterate error")
+		defer func() { prov.Store.ErrIterate = nil }()
+
+		rr := query(outboundPath)
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+
+		response := models.GenericError{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+		require.Equal(t, QueryErrorCode, response.Body.Code)
+		require.Contains(t, response.Body.Message, "iterate error")
+	})
+
+	t.Run("test audit disabled", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		New(&mockProvider{}).QueryOutboundMessages(rr, httptest.NewRequest(http.MethodGet, outboundPath, nil))
+		require.Equal(t, http.StatusNotFound, rr.Code)
+
+		response := models.GenericError{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+		require.Equal(t, AuditDisabledErrorCode, response.Body.Code)
+	})
+}
@@ -0,0 +1,65 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package models
+
+import "github.com/hyperledger/aries-framework-go/pkg/didcomm/common/audit"
+
+// QueryOutboundMessages model
+//
+// This is used for querying the audit trail of the outbound messages.
+//
+// swagger:parameters queryOutboundMessages
+type QueryOutboundMessages struct {
+	// Type of the messages
+	//
+	// in: query
+	MsgType string `json:"msgType"`
+
+	// Thread ID of the messages
+	//
+	// in: query
+	ThreadID string `json:"thid"`
+
+	// Messages sent since the time, RFC3339 formatted
+	//
+	// in: query
+	Since string `json:"since"`
+
+	// Messages sent until the time, RFC3339 formatted
+	//
+	// in: query
+	Until string `json:"until"`
+
+	// Maximum number of records returned
+	//
+	// in: query
+	Limit string `json:"limit"`
+}
+
+// QueryOutboundMessagesResponse model
+//
+// This is used for returning the audit records of the outbound messages, the oldest records first.
+//
+// swagger:response queryOutboundMessagesResponse
+type QueryOutboundMessagesResponse struct {
+
+	// in: body
+	Body struct {
+		Results []*audit.Record `json:"results"`
+	} `json:"body"`
+}
+
+// A GenericError is the default error message that is generated.
+//
+// swagger:response genericError
+type GenericError struct {
+	// in: body
+	Body struct {
+		Code    int32  `json:"code"`
+		Message string `json:"message"`
+	} `json:"body"`
+}
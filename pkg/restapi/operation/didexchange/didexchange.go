@@ -13,12 +13,13 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"sync"
 	"time"
 
-	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 
 	"github.com/hyperledger/aries-framework-go/pkg/client/didexchange"
+	"github.com/hyperledger/aries-framework-go/pkg/common/idgen"
 	"github.com/hyperledger/aries-framework-go/pkg/common/log"
 	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/service"
 	"github.com/hyperledger/aries-framework-go/pkg/didcomm/dispatcher"
@@ -26,6 +27,7 @@ import (
 	"github.com/hyperledger/aries-framework-go/pkg/internal/common/support"
 	"github.com/hyperledger/aries-framework-go/pkg/restapi/operation"
 	"github.com/hyperledger/aries-framework-go/pkg/restapi/operation/didexchange/models"
+	"github.com/hyperledger/aries-framework-go/pkg/restapi/webhook"
 	"github.com/hyperledger/aries-framework-go/pkg/storage"
 	"github.com/hyperledger/aries-framework-go/pkg/wallet"
 )
@@ -51,8 +53,23 @@ type provider interface {
 	StorageProvider() storage.Provider
 }
 
+// idGeneratorProvider is optionally implemented by the provider to replace the random UUIDs
+type idGeneratorProvider interface {
+	IDGenerator() idgen.Generator
+}
+
+// Opt configures the DID Exchange rest client protocol instance
+type Opt func(o *Operation)
+
+// WithEventEncoder sets the encoder of the emitted event payloads
+func WithEventEncoder(encoder *webhook.Encoder) Opt {
+	return func(o *Operation) {
+		o.eventEncoder = encoder
+	}
+}
+
 // New returns new DID Exchange rest client protocol instance
-func New(ctx provider) (*Operation, error) {
+func New(ctx provider, opts ...Opt) (*Operation, error) {
 	didExchange, err := didexchange.New(ctx)
 	if err != nil {
 		return nil, err
@@ -75,7 +92,24 @@ func New(ctx provider) (*Operation, error) {
 		// TODO channel size - https://github.com/hyperledger/aries-framework-go/issues/246
 		actionCh: make(chan service.DIDCommAction, 10),
 		msgCh:    make(chan service.StateMsg, 10),
+		ids:      idgen.UUID(),
+	}
+
+	if p, ok := ctx.(idGeneratorProvider); ok && p.IDGenerator() != nil {
+		svc.ids = p.IDGenerator()
+	}
+
+	for _, opt := range opts {
+		opt(svc)
+	}
+
+	if svc.eventEncoder == nil {
+		svc.eventEncoder, err = webhook.NewEncoder(webhook.DefaultFormatVersion)
+		if err != nil {
+			return nil, err
+		}
 	}
+
 	svc.registerHandler()
 
 	err = svc.startClientEventListener()
@@ -94,6 +128,10 @@ type Operation struct {
 	handlers []operation.Handler
 	actionCh chan service.DIDCommAction
 	msgCh    chan service.StateMsg
+
+	eventEncoder *webhook.Encoder
+	closeOnce    sync.Once
+	ids          idgen.Generator
 }
 
 // CreateInvitation swagger:route POST /connections/create-invitation did-exchange createInvitation
@@ -139,7 +177,7 @@ func (c *Operation) ReceiveInvitation(rw http.ResponseWriter, req *http.Request)
 		return
 	}
 
-	err = c.service.Handle(&service.DIDCommMsg{Type: request.Params.Type, Payload: payload})
+	err = c.service.Handle(req.Context(), &service.DIDCommMsg{Type: request.Params.Type, Payload: payload})
 	if err != nil {
 		c.writeGenericError(rw, err)
 		return
@@ -265,7 +303,7 @@ func (c *Operation) AcceptExchangeRequest(rw http.ResponseWriter, req *http.Requ
 
 	// TODO returning sample response below, Accept Exchange Request to be added using events & callback (#198 & #238)
 	result := &models.ExchangeResponse{
-		ConnectionID: uuid.New().String(), CreatedTime: time.Now(),
+		ConnectionID: c.ids.NewID(), CreatedTime: time.Now(),
 	}
 
 	response := models.AcceptExchangeResult{Result: result}
@@ -382,10 +420,32 @@ func (c *Operation) startClientEventListener() error {
 	go func() {
 		for e := range c.msgCh {
 			// TODO https://github.com/hyperledger/aries-framework-go/issues/200 - Webhook integration
-			// for now, log the messages
-			logger.Infof("message event received : type=%s", e.Type)
+			// for now, log the event payloads
+			payload, err := c.eventEncoder.Marshal(e)
+			if err != nil {
+				logger.Errorf("failed to encode message event: %s", err)
+				continue
+			}
+
+			logger.Infof("message event received : %s", payload)
 		}
 	}()
 
 	return nil
 }
+
+// Close closes the DID Exchange client, which stops the event listeners of the operation
+func (c *Operation) Close() error {
+	err := c.client.Close()
+
+	c.closeOnce.Do(func() {
+		close(c.actionCh)
+		close(c.msgCh)
+	})
+
+	if err != nil {
+		return fmt.Errorf("failed to close didexchange client: %w", err)
+	}
+
+	return nil
+}
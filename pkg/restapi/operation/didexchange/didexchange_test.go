@@ -8,6 +8,7 @@ package didexchange
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -21,6 +22,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/hyperledger/aries-framework-go/pkg/client/didexchange"
+	"github.com/hyperledger/aries-framework-go/pkg/common/idgen"
 	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/service"
 	didexsvc "github.com/hyperledger/aries-framework-go/pkg/didcomm/protocol/didexchange"
 	"github.com/hyperledger/aries-framework-go/pkg/internal/mock/common/did"
@@ -30,6 +32,7 @@ import (
 	mockwallet "github.com/hyperledger/aries-framework-go/pkg/internal/mock/wallet"
 	"github.com/hyperledger/aries-framework-go/pkg/restapi/operation"
 	"github.com/hyperledger/aries-framework-go/pkg/restapi/operation/didexchange/models"
+	"github.com/hyperledger/aries-framework-go/pkg/restapi/webhook"
 	"github.com/hyperledger/aries-framework-go/pkg/storage"
 )
 
@@ -43,6 +46,21 @@ func TestOperation_GetAPIHandlers(t *testing.T) {
 	require.NotEmpty(t, handlers)
 }
 
+func TestNew_EventEncoder(t *testing.T) {
+	svc, err := New(&mockprovider.Provider{StorageProviderValue: mockstore.NewMockStoreProvider(),
+		ServiceValue: &protocol.MockDIDExchangeSvc{}})
+	require.NoError(t, err)
+	require.Equal(t, webhook.DefaultFormatVersion, svc.eventEncoder.Version())
+
+	encoder, err := webhook.NewEncoder(webhook.FormatV1)
+	require.NoError(t, err)
+
+	svc, err = New(&mockprovider.Provider{StorageProviderValue: mockstore.NewMockStoreProvider(),
+		ServiceValue: &protocol.MockDIDExchangeSvc{}}, WithEventEncoder(encoder))
+	require.NoError(t, err)
+	require.Equal(t, encoder, svc.eventEncoder)
+}
+
 func TestNew_Fail(t *testing.T) {
 	svc, err := New(&mockprovider.Provider{ServiceErr: errors.New("test-error")})
 	require.Error(t, err)
@@ -110,7 +128,7 @@ func TestOperation_QueryConnectionByID(t *testing.T) {
 func TestOperation_QueryConnectionByParams(t *testing.T) {
 	handler := getHandler(t, connections, nil)
 	buf, err := getResponseFromHandler(handler, nil,
-		operationID+"?invitation_key=3nPvih&alias=sample&state=completed&initiator=test")
+		operationID+"?invitation_key=3nPvih&alias=sample&state=complete&initiator=test")
 	require.NoError(t, err)
 
 	response := models.QueryConnectionsResponse{}
@@ -120,11 +138,8 @@ func TestOperation_QueryConnectionByParams(t *testing.T) {
 	// verify response
 	require.NotEmpty(t, response)
 	require.NotEmpty(t, response.Body)
-	require.NotEmpty(t, response.Body.Results)
-	for _, result := range response.Body.Results {
-		require.NotNil(t, result)
-		require.NotNil(t, result.ConnectionID)
-	}
+	require.Len(t, response.Body.Results, 1)
+	require.Equal(t, "1234", response.Body.Results[0].ConnectionID)
 }
 
 func TestOperation_ReceiveInvitationFailure(t *testing.T) {
@@ -206,11 +221,37 @@ func TestOperation_AcceptExchangeRequest(t *testing.T) {
 	require.NotEmpty(t, response.Result.CreatedTime)
 }
 
+func TestOperation_AcceptExchangeRequestIDGenerator(t *testing.T) {
+	svc, err := New(&mockprovider.Provider{
+		ServiceValue:         &protocol.MockDIDExchangeSvc{},
+		StorageProviderValue: mockstore.NewMockStoreProvider(),
+		IDGeneratorValue:     idgen.Sequence("conn")})
+	require.NoError(t, err)
+
+	var handler operation.Handler
+	for _, h := range svc.GetRESTHandlers() {
+		if h.Path() == acceptExchangeRequest {
+			handler = h
+		}
+	}
+
+	buf, err := getResponseFromHandler(handler, bytes.NewBuffer([]byte("test-id")), operationID+"/4444/accept-request")
+	require.NoError(t, err)
+
+	response := models.AcceptExchangeResult{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &response))
+	require.Equal(t, "conn-1", response.Result.ConnectionID)
+}
+
 func TestOperation_RemoveConnection(t *testing.T) {
 	handler := getHandler(t, removeConnection, nil)
-	buf, err := getResponseFromHandler(handler, bytes.NewBuffer([]byte("test-id")), operationID+"/5555/remove")
+	buf, err := getResponseFromHandler(handler, bytes.NewBuffer([]byte("test-id")), operationID+"/1234/remove")
 	require.NoError(t, err)
 	require.Empty(t, buf.Bytes())
+
+	buf, err = getResponseFromHandler(handler, bytes.NewBuffer([]byte("test-id")), operationID+"/5555/remove")
+	require.NoError(t, err)
+	require.Contains(t, buf.String(), "connection not found")
 }
 
 func TestOperation_WriteGenericError(t *testing.T) {
@@ -275,6 +316,7 @@ func getResponseFromHandler(handler operation.Handler, requestBody io.Reader, pa
 func getHandler(t *testing.T, lookup string, handleErr error) operation.Handler {
 	s := mockstore.MockStore{Store: make(map[string][]byte)}
 	require.NoError(t, s.Put("1234", []byte("complete")))
+	require.NoError(t, s.Put("conn_1234", []byte("1234")))
 	svc, err := New(&mockprovider.Provider{
 		ServiceValue: &protocol.MockDIDExchangeSvc{
 			ProtocolName: "mockProtocolSvc",
@@ -343,7 +385,7 @@ func TestServiceEvents(t *testing.T) {
 		Payload: request,
 	}
 
-	err = didExSvc.Handle(&msg)
+	err = didExSvc.Handle(context.Background(), &msg)
 	require.NoError(t, err)
 
 	validateState(t, store, id, "responded", 100*time.Millisecond)
@@ -387,3 +429,24 @@ func TestOperationEventError(t *testing.T) {
 	err = client.UnregisterActionEvent(aCh)
 	require.NoError(t, err)
 }
+
+func TestOperation_Close(t *testing.T) {
+	t.Run("test success", func(t *testing.T) {
+		op, err := New(&mockprovider.Provider{StorageProviderValue: mockstore.NewMockStoreProvider(),
+			ServiceValue: &protocol.MockDIDExchangeSvc{}})
+		require.NoError(t, err)
+
+		require.NoError(t, op.Close())
+		require.NoError(t, op.Close())
+	})
+
+	t.Run("test error from client close", func(t *testing.T) {
+		op, err := New(&mockprovider.Provider{StorageProviderValue: mockstore.NewMockStoreProvider(),
+			ServiceValue: &protocol.MockDIDExchangeSvc{UnregisterActionEventErr: errors.New("unregister error")}})
+		require.NoError(t, err)
+
+		err = op.Close()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to close didexchange client")
+	})
+}
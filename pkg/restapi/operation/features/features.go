@@ -0,0 +1,81 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package features
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/hyperledger/aries-framework-go/pkg/common/log"
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/dispatcher"
+	"github.com/hyperledger/aries-framework-go/pkg/internal/common/support"
+	"github.com/hyperledger/aries-framework-go/pkg/restapi/operation"
+	"github.com/hyperledger/aries-framework-go/pkg/restapi/operation/features/models"
+)
+
+var logger = log.New("aries-framework/rest/features")
+
+const featuresPath = "/features"
+
+// provider contains the protocol services of the agent and is typically created by using aries.Context()
+type provider interface {
+	Services() []dispatcher.ServiceDescriptor
+}
+
+// featureFlagsProvider is optionally implemented by the providers reporting the enabled feature flags of the
+// experimental protocols and algorithms
+type featureFlagsProvider interface {
+	FeatureFlags() []string
+}
+
+// Operation is controller REST service controller for the features of the agent
+type Operation struct {
+	ctx      provider
+	handlers []operation.Handler
+}
+
+// New returns new features rest client instance
+func New(ctx provider) *Operation {
+	o := &Operation{ctx: ctx}
+	o.handlers = []operation.Handler{
+		support.NewHTTPHandler(featuresPath, http.MethodGet, o.Features),
+	}
+
+	return o
+}
+
+// Features swagger:route GET /features features features
+//
+// Lists the protocol services registered in the agent and the enabled feature flags....
+//
+// Responses:
+//        200: featuresResponse
+func (o *Operation) Features(rw http.ResponseWriter, req *http.Request) {
+	response := models.FeaturesResponse{}
+	response.Body.Services = o.ctx.Services()
+
+	if p, ok := o.ctx.(featureFlagsProvider); ok {
+		response.Body.Features = p.FeatureFlags()
+	}
+
+	writeResponse(rw, response)
+}
+
+// GetRESTHandlers get all controller API handler available for the features
+func (o *Operation) GetRESTHandlers() []operation.Handler {
+	return o.handlers
+}
+
+// writeResponse writes interface value to response
+func writeResponse(rw io.Writer, v interface{}) {
+	err := json.NewEncoder(rw).Encode(v)
+	// as of now, just log errors for writing response
+	if err != nil {
+		logger.Errorf("Unable to send response, %s", err)
+	}
+}
@@ -0,0 +1,68 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package features
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/dispatcher"
+	"github.com/hyperledger/aries-framework-go/pkg/restapi/operation/features/models"
+)
+
+type mockProvider struct {
+	services []dispatcher.ServiceDescriptor
+}
+
+func (p *mockProvider) Services() []dispatcher.ServiceDescriptor {
+	return p.services
+}
+
+func TestOperation_Features(t *testing.T) {
+	services := []dispatcher.ServiceDescriptor{
+		{Name: "didexchange", MessageTypes: []string{"type1", "type2"}, Version: "1.0"},
+		{Name: "introduce"},
+	}
+
+	handlers := New(&mockProvider{services: services}).GetRESTHandlers()
+	require.Len(t, handlers, 1)
+	require.Equal(t, featuresPath, handlers[0].Path())
+	require.Equal(t, http.MethodGet, handlers[0].Method())
+
+	rr := httptest.NewRecorder()
+	handlers[0].Handle().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, featuresPath, nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	response := models.FeaturesResponse{}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	require.Equal(t, services, response.Body.Services)
+}
+
+type featuresProvider struct {
+	mockProvider
+	flags []string
+}
+
+func (p *featuresProvider) FeatureFlags() []string {
+	return p.flags
+}
+
+func TestOperation_FeatureFlags(t *testing.T) {
+	handlers := New(&featuresProvider{flags: []string{"didcomm-v2"}}).GetRESTHandlers()
+
+	rr := httptest.NewRecorder()
+	handlers[0].Handle().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, featuresPath, nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	response := models.FeaturesResponse{}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	require.Equal(t, []string{"didcomm-v2"}, response.Body.Features)
+}
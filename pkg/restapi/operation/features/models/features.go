@@ -0,0 +1,24 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package models
+
+import "github.com/hyperledger/aries-framework-go/pkg/didcomm/dispatcher"
+
+// FeaturesResponse model
+//
+// This is used for returning the protocol services registered in the agent
+//
+// swagger:response featuresResponse
+type FeaturesResponse struct {
+
+	// in: body
+	Body struct {
+		Services []dispatcher.ServiceDescriptor `json:"services"`
+		// Features are the enabled feature flags of the experimental protocols and algorithms
+		Features []string `json:"features,omitempty"`
+	} `json:"body"`
+}
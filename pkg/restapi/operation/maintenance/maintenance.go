@@ -0,0 +1,86 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package maintenance
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/hyperledger/aries-framework-go/pkg/common/log"
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/threads"
+	"github.com/hyperledger/aries-framework-go/pkg/framework/maintenance"
+	"github.com/hyperledger/aries-framework-go/pkg/internal/common/support"
+	"github.com/hyperledger/aries-framework-go/pkg/restapi/operation"
+	"github.com/hyperledger/aries-framework-go/pkg/restapi/operation/maintenance/models"
+	"github.com/hyperledger/aries-framework-go/pkg/storage"
+)
+
+var logger = log.New("aries-framework/rest/maintenance")
+
+const maintenancePath = "/admin/maintenance"
+
+// provider contains the stores of the agent and is typically created by using aries.Context()
+type provider interface {
+	StorageProvider() storage.Provider
+	ThreadStore() *threads.Store
+}
+
+// Operation is controller REST service controller for the storage maintenance of the agent
+type Operation struct {
+	ctx      provider
+	handlers []operation.Handler
+}
+
+// New returns new maintenance rest client instance
+func New(ctx provider) *Operation {
+	o := &Operation{ctx: ctx}
+	o.handlers = []operation.Handler{
+		support.NewHTTPHandler(maintenancePath, http.MethodPost, o.Maintain),
+	}
+
+	return o
+}
+
+// Maintain swagger:route POST /admin/maintenance maintenance maintain
+//
+// Purges the expired transient records and compacts the stores....
+//
+// Responses:
+//
+//	default: genericError
+//	    200: maintenanceResponse
+func (o *Operation) Maintain(rw http.ResponseWriter, req *http.Request) {
+	report, err := maintenance.Run(o.ctx)
+	if err != nil {
+		rw.WriteHeader(http.StatusInternalServerError)
+
+		errResponse := models.GenericError{}
+		errResponse.Body.Code = 1
+		errResponse.Body.Message = err.Error()
+
+		writeResponse(rw, errResponse)
+
+		return
+	}
+
+	writeResponse(rw, models.MaintenanceResponse{Body: report})
+}
+
+// GetRESTHandlers get all controller API handler available for the maintenance
+func (o *Operation) GetRESTHandlers() []operation.Handler {
+	return o.handlers
+}
+
+// writeResponse writes interface value to response
+func writeResponse(rw io.Writer, v interface{}) {
+	err := json.NewEncoder(rw).Encode(v)
+	// as of now, just log errors for writing response
+	if err != nil {
+		logger.Errorf("Unable to send response, %s", err)
+	}
+}
@@ -0,0 +1,72 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package maintenance
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/threads"
+	mockstorage "github.com/hyperledger/aries-framework-go/pkg/internal/mock/storage"
+	"github.com/hyperledger/aries-framework-go/pkg/restapi/operation/maintenance/models"
+	"github.com/hyperledger/aries-framework-go/pkg/storage"
+)
+
+type mockProvider struct {
+	store       *mockstorage.MockStoreProvider
+	threadStore *threads.Store
+}
+
+func (p *mockProvider) StorageProvider() storage.Provider {
+	return p.store
+}
+
+func (p *mockProvider) ThreadStore() *threads.Store {
+	return p.threadStore
+}
+
+func TestOperation_Maintain(t *testing.T) {
+	store := mockstorage.NewMockStoreProvider()
+	threadStore, err := threads.New(store, threads.WithCompletedRemoval())
+	require.NoError(t, err)
+
+	require.NoError(t, threadStore.Save(&threads.Record{ThreadID: "thid", Protocol: "didexchange"}))
+
+	handlers := New(&mockProvider{store: store, threadStore: threadStore}).GetRESTHandlers()
+	require.Len(t, handlers, 1)
+	require.Equal(t, maintenancePath, handlers[0].Path())
+	require.Equal(t, http.MethodPost, handlers[0].Method())
+
+	t.Run("test maintenance report", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		handlers[0].Handle().ServeHTTP(rr, httptest.NewRequest(http.MethodPost, maintenancePath, nil))
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		response := models.MaintenanceResponse{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+		require.NotNil(t, response.Body)
+		require.Zero(t, response.Body.PurgedThreads)
+		require.False(t, response.Body.Compacted)
+	})
+
+	t.Run("test maintenance error", func(t *testing.T) {
+		store.Store.ErrGet = errors.New("get error")
+
+		rr := httptest.NewRecorder()
+		handlers[0].Handle().ServeHTTP(rr, httptest.NewRequest(http.MethodPost, maintenancePath, nil))
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+
+		response := models.GenericError{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+		require.Contains(t, response.Body.Message, "get error")
+	})
+}
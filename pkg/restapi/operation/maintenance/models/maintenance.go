@@ -0,0 +1,31 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package models
+
+import "github.com/hyperledger/aries-framework-go/pkg/framework/maintenance"
+
+// MaintenanceResponse model
+//
+// This is used for returning the report of the storage maintenance.
+//
+// swagger:response maintenanceResponse
+type MaintenanceResponse struct {
+
+	// in: body
+	Body *maintenance.Report `json:"body"`
+}
+
+// A GenericError is the default error message that is generated.
+//
+// swagger:response genericError
+type GenericError struct {
+	// in: body
+	Body struct {
+		Code    int32  `json:"code"`
+		Message string `json:"message"`
+	} `json:"body"`
+}
@@ -7,34 +7,100 @@ SPDX-License-Identifier: Apache-2.0
 package restapi
 
 import (
+	"fmt"
+	"io"
+
 	"github.com/hyperledger/aries-framework-go/pkg/framework/context"
+	"github.com/hyperledger/aries-framework-go/pkg/restapi/auth"
 	"github.com/hyperledger/aries-framework-go/pkg/restapi/operation"
+	"github.com/hyperledger/aries-framework-go/pkg/restapi/operation/audit"
 	"github.com/hyperledger/aries-framework-go/pkg/restapi/operation/didexchange"
+	"github.com/hyperledger/aries-framework-go/pkg/restapi/operation/features"
+	"github.com/hyperledger/aries-framework-go/pkg/restapi/operation/maintenance"
+	"github.com/hyperledger/aries-framework-go/pkg/restapi/webhook"
 )
 
+type allOpts struct {
+	eventFormatVersion string
+	auth               *auth.Middleware
+}
+
+// Opt represents a controller REST API option.
+type Opt func(opts *allOpts)
+
+// WithEventFormatVersion sets the format version of the emitted event payloads,
+// see webhook package for the supported versions.
+func WithEventFormatVersion(version string) Opt {
+	return func(opts *allOpts) {
+		opts.eventFormatVersion = version
+	}
+}
+
+// WithAuth authenticates and authorizes the requests of the REST API operations with the middleware.
+func WithAuth(middleware *auth.Middleware) Opt {
+	return func(opts *allOpts) {
+		opts.auth = middleware
+	}
+}
+
 // New returns new controller REST API instance.
 //
 // TODO: Allow customized operations.
-func New(ctx *context.Provider) (*Controller, error) {
+func New(ctx *context.Provider, opts ...Opt) (*Controller, error) {
+	restAPIOpts := &allOpts{eventFormatVersion: webhook.DefaultFormatVersion}
+	for _, opt := range opts {
+		opt(restAPIOpts)
+	}
+
+	eventEncoder, err := webhook.NewEncoder(restAPIOpts.eventFormatVersion)
+	if err != nil {
+		return nil, err
+	}
+
 	var allHandlers []operation.Handler
 
 	// Add DID Exchange Rest Handlers
-	exchange, err := didexchange.New(ctx)
+	exchange, err := didexchange.New(ctx, didexchange.WithEventEncoder(eventEncoder))
 	if err != nil {
 		return nil, err
 	}
 
 	allHandlers = append(allHandlers, exchange.GetRESTHandlers()...)
 
-	return &Controller{handlers: allHandlers}, nil
+	// Add features Rest Handlers
+	allHandlers = append(allHandlers, features.New(ctx).GetRESTHandlers()...)
+
+	// Add audit Rest Handlers
+	allHandlers = append(allHandlers, audit.New(ctx).GetRESTHandlers()...)
+
+	// Add maintenance Rest Handlers
+	allHandlers = append(allHandlers, maintenance.New(ctx).GetRESTHandlers()...)
+
+	if restAPIOpts.auth != nil {
+		allHandlers = restAPIOpts.auth.WrapAll(allHandlers)
+	}
+
+	return &Controller{handlers: allHandlers, closers: []io.Closer{exchange}}, nil
 }
 
 // Controller contains handlers for controller REST API
 type Controller struct {
 	handlers []operation.Handler
+	closers  []io.Closer
 }
 
 // GetOperations returns all controller REST API endpoints
 func (c *Controller) GetOperations() []operation.Handler {
 	return c.handlers
 }
+
+// Close releases the resources of the REST API operations
+func (c *Controller) Close() error {
+	for _, closer := range c.closers {
+		if err := closer.Close(); err != nil {
+			return fmt.Errorf("failed to close controller REST API: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -8,6 +8,8 @@ package restapi
 
 import (
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"testing"
 
@@ -17,6 +19,8 @@ import (
 	"github.com/hyperledger/aries-framework-go/pkg/framework/aries/api"
 	"github.com/hyperledger/aries-framework-go/pkg/framework/aries/defaults"
 	"github.com/hyperledger/aries-framework-go/pkg/framework/context"
+	"github.com/hyperledger/aries-framework-go/pkg/restapi/auth"
+	"github.com/hyperledger/aries-framework-go/pkg/restapi/webhook"
 )
 
 func TestNew_Failure(t *testing.T) {
@@ -49,6 +53,74 @@ func TestNew_Success(t *testing.T) {
 	require.NotNil(t, controller)
 
 	require.NotEmpty(t, controller.GetOperations())
+	require.NoError(t, controller.Close())
+}
+
+func TestNew_EventFormatVersion(t *testing.T) {
+	path, cleanup := generateTempDir(t)
+	defer cleanup()
+	framework, err := aries.New(defaults.WithStorePath(path), defaults.WithInboundHTTPAddr(":26509"))
+	require.NoError(t, err)
+	require.NotNil(t, framework)
+
+	defer func() {
+		e := framework.Close()
+		if e != nil {
+			t.Fatal(e)
+		}
+	}()
+
+	ctx, err := framework.Context()
+	require.NoError(t, err)
+
+	t.Run("test supported version", func(t *testing.T) {
+		controller, err := New(ctx, WithEventFormatVersion(webhook.FormatV1))
+		require.NoError(t, err)
+		require.NotNil(t, controller)
+	})
+
+	t.Run("test unsupported version", func(t *testing.T) {
+		controller, err := New(ctx, WithEventFormatVersion("0.1"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unsupported event format version")
+		require.Nil(t, controller)
+	})
+}
+
+func TestNew_Auth(t *testing.T) {
+	path, cleanup := generateTempDir(t)
+	defer cleanup()
+	framework, err := aries.New(defaults.WithStorePath(path), defaults.WithInboundHTTPAddr(":26510"))
+	require.NoError(t, err)
+	require.NotNil(t, framework)
+
+	defer func() {
+		e := framework.Close()
+		if e != nil {
+			t.Fatal(e)
+		}
+	}()
+
+	ctx, err := framework.Context()
+	require.NoError(t, err)
+
+	middleware, err := auth.New([]auth.Authenticator{auth.NewAPIKey(map[string]*auth.Principal{
+		"secret": {ID: "admin"},
+	})})
+	require.NoError(t, err)
+
+	controller, err := New(ctx, WithAuth(middleware))
+	require.NoError(t, err)
+
+	defer func() {
+		require.NoError(t, controller.Close())
+	}()
+
+	for _, handler := range controller.GetOperations() {
+		rr := httptest.NewRecorder()
+		handler.Handle()(rr, httptest.NewRequest(handler.Method(), handler.Path(), nil))
+		require.Equal(t, http.StatusUnauthorized, rr.Code, handler.Path())
+	}
 }
 
 func generateTempDir(t testing.TB) (string, func()) {
@@ -0,0 +1,144 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package webhook defines versioned payloads of the events emitted by the controller REST API
+// to external consumers. Every payload carries its format version, and the JSON schema of each
+// version is kept stable so consumers are not broken when protocols evolve.
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/model"
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/service"
+)
+
+const (
+	// FormatV1 is the first version of the event payload format.
+	FormatV1 = "1.0"
+
+	// DefaultFormatVersion is the payload format version used if none is requested.
+	DefaultFormatVersion = FormatV1
+)
+
+// Event topics.
+const (
+	// ConnectionsTopic is the topic of connection state events.
+	ConnectionsTopic = "connections"
+
+	// ProblemReportTopic is the topic of problem report events.
+	ProblemReportTopic = "problem_report"
+)
+
+const (
+	didExchangeProtocol = "didexchange"
+	preStateType        = "pre_state"
+	postStateType       = "post_state"
+)
+
+// Payload is the envelope of every emitted event.
+type Payload struct {
+	FormatVersion string      `json:"formatVersion"`
+	Topic         string      `json:"topic"`
+	Message       interface{} `json:"message"`
+}
+
+// ConnectionMessageV1 is the message of a connection state event in format version 1.0.
+type ConnectionMessageV1 struct {
+	Protocol     string `json:"protocol"`
+	Type         string `json:"type"`
+	State        string `json:"state"`
+	ConnectionID string `json:"connectionID,omitempty"`
+	InvitationID string `json:"invitationID,omitempty"`
+	MessageType  string `json:"messageType,omitempty"`
+}
+
+// ProblemReportMessageV1 is the message of a problem report event in format version 1.0.
+type ProblemReportMessageV1 struct {
+	Protocol     string          `json:"protocol"`
+	ConnectionID string          `json:"connectionID,omitempty"`
+	Report       json.RawMessage `json:"report"`
+}
+
+// connectionEvent is implemented by properties of the connection related events.
+type connectionEvent interface {
+	ConnectionID() string
+	InvitationID() string
+}
+
+// Encoder encodes the state events into the payloads of the requested format version.
+type Encoder struct {
+	version string
+}
+
+// NewEncoder returns new encoder of the payload format version.
+func NewEncoder(version string) (*Encoder, error) {
+	if _, ok := schemas[version]; !ok {
+		return nil, fmt.Errorf("unsupported event format version: %s", version)
+	}
+
+	return &Encoder{version: version}, nil
+}
+
+// Version returns the payload format version of the encoder.
+func (e *Encoder) Version() string {
+	return e.version
+}
+
+// Encode converts the state event to the payload.
+func (e *Encoder) Encode(msg service.StateMsg) (*Payload, error) {
+	if msg.ProtocolName != didExchangeProtocol {
+		return nil, fmt.Errorf("unsupported event protocol: %s", msg.ProtocolName)
+	}
+
+	var connectionID, invitationID string
+
+	if props, ok := msg.Properties.(connectionEvent); ok {
+		connectionID = props.ConnectionID()
+		invitationID = props.InvitationID()
+	}
+
+	if msg.Msg != nil && model.IsProblemReport(msg.Msg.Type) {
+		return &Payload{
+			FormatVersion: e.version,
+			Topic:         ProblemReportTopic,
+			Message: &ProblemReportMessageV1{
+				Protocol:     msg.ProtocolName,
+				ConnectionID: connectionID,
+				Report:       msg.Msg.Payload,
+			},
+		}, nil
+	}
+
+	stateMsg := &ConnectionMessageV1{
+		Protocol:     msg.ProtocolName,
+		Type:         preStateType,
+		State:        msg.StateID,
+		ConnectionID: connectionID,
+		InvitationID: invitationID,
+	}
+
+	if msg.Type == service.PostState {
+		stateMsg.Type = postStateType
+	}
+
+	if msg.Msg != nil {
+		stateMsg.MessageType = msg.Msg.Type
+	}
+
+	return &Payload{FormatVersion: e.version, Topic: ConnectionsTopic, Message: stateMsg}, nil
+}
+
+// Marshal encodes the state event and marshals the payload to JSON.
+func (e *Encoder) Marshal(msg service.StateMsg) ([]byte, error) {
+	payload, err := e.Encode(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(payload)
+}
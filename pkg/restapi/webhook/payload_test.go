@@ -0,0 +1,145 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package webhook
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xeipuuv/gojsonschema"
+
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/service"
+)
+
+func TestNewEncoder(t *testing.T) {
+	t.Run("test supported version", func(t *testing.T) {
+		e, err := NewEncoder(DefaultFormatVersion)
+		require.NoError(t, err)
+		require.Equal(t, FormatV1, e.Version())
+	})
+
+	t.Run("test unsupported version", func(t *testing.T) {
+		e, err := NewEncoder("0.1")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unsupported event format version: 0.1")
+		require.Nil(t, e)
+	})
+}
+
+func TestEncoder_Encode(t *testing.T) {
+	e, err := NewEncoder(FormatV1)
+	require.NoError(t, err)
+
+	t.Run("test connection state event", func(t *testing.T) {
+		payload, err := e.Encode(service.StateMsg{
+			ProtocolName: didExchangeProtocol,
+			Type:         service.PostState,
+			StateID:      "completed",
+			Msg:          &service.DIDCommMsg{Type: "https://didcomm.org/didexchange/1.0/response"},
+			Properties:   &mockEvent{connectionID: "conn-1", invitationID: "inv-1"},
+		})
+		require.NoError(t, err)
+		require.Equal(t, ConnectionsTopic, payload.Topic)
+		require.Equal(t, &ConnectionMessageV1{
+			Protocol:     didExchangeProtocol,
+			Type:         postStateType,
+			State:        "completed",
+			ConnectionID: "conn-1",
+			InvitationID: "inv-1",
+			MessageType:  "https://didcomm.org/didexchange/1.0/response",
+		}, payload.Message)
+
+		payload, err = e.Encode(service.StateMsg{ProtocolName: didExchangeProtocol, Type: service.PreState,
+			StateID: "requested"})
+		require.NoError(t, err)
+		require.Equal(t, preStateType, payload.Message.(*ConnectionMessageV1).Type)
+		require.Empty(t, payload.Message.(*ConnectionMessageV1).ConnectionID)
+	})
+
+	t.Run("test problem report event", func(t *testing.T) {
+		payload, err := e.Encode(service.StateMsg{
+			ProtocolName: didExchangeProtocol,
+			Type:         service.PostState,
+			StateID:      "abandoned",
+			Msg: &service.DIDCommMsg{Type: "https://didcomm.org/didexchange/1.0/problem_report",
+				Payload: []byte(`{"problem-code":"request_not_accepted"}`)},
+			Properties: &mockEvent{connectionID: "conn-1"},
+		})
+		require.NoError(t, err)
+		require.Equal(t, ProblemReportTopic, payload.Topic)
+		require.Equal(t, "conn-1", payload.Message.(*ProblemReportMessageV1).ConnectionID)
+	})
+
+	t.Run("test unsupported protocol", func(t *testing.T) {
+		_, err := e.Encode(service.StateMsg{ProtocolName: "introduce"})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unsupported event protocol: introduce")
+
+		_, err = e.Marshal(service.StateMsg{ProtocolName: "introduce"})
+		require.Error(t, err)
+	})
+}
+
+func TestPayloadsMatchSchemas(t *testing.T) {
+	e, err := NewEncoder(FormatV1)
+	require.NoError(t, err)
+
+	tests := []struct {
+		topic string
+		msg   service.StateMsg
+	}{
+		{
+			topic: ConnectionsTopic,
+			msg: service.StateMsg{ProtocolName: didExchangeProtocol, Type: service.PostState, StateID: "invited",
+				Msg:        &service.DIDCommMsg{Type: "https://didcomm.org/didexchange/1.0/invitation"},
+				Properties: &mockEvent{connectionID: "conn-1"}},
+		},
+		{
+			topic: ProblemReportTopic,
+			msg: service.StateMsg{ProtocolName: didExchangeProtocol, Type: service.PostState,
+				Msg: &service.DIDCommMsg{Type: "https://didcomm.org/didexchange/1.0/problem_report",
+					Payload: []byte(`{"explain":"bad request"}`)}},
+		},
+	}
+
+	for _, tc := range tests {
+		data, err := e.Marshal(tc.msg)
+		require.NoError(t, err)
+
+		schema, err := Schema(FormatV1, tc.topic)
+		require.NoError(t, err)
+
+		result, err := gojsonschema.Validate(gojsonschema.NewStringLoader(schema), gojsonschema.NewBytesLoader(data))
+		require.NoError(t, err)
+		require.True(t, result.Valid(), "payload of topic %s doesn't match schema: %v", tc.topic, result.Errors())
+	}
+}
+
+func TestSchema(t *testing.T) {
+	require.Equal(t, []string{FormatV1}, Versions())
+
+	_, err := Schema("0.1", ConnectionsTopic)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "unsupported event format version")
+
+	_, err = Schema(FormatV1, "credentials")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "no schema for topic credentials")
+}
+
+type mockEvent struct {
+	connectionID string
+	invitationID string
+}
+
+func (m *mockEvent) ConnectionID() string {
+	return m.connectionID
+}
+
+func (m *mockEvent) InvitationID() string {
+	return m.invitationID
+}
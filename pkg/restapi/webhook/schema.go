@@ -0,0 +1,94 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package webhook
+
+import (
+	"fmt"
+	"sort"
+)
+
+// schemas holds the JSON schemas of the payloads by format version and topic.
+// A released version must never change, a new version has to be added instead.
+// TODO add credential state topic once issue-credential protocol is supported.
+var schemas = map[string]map[string]string{ //nolint:gochecknoglobals
+	FormatV1: {
+		ConnectionsTopic:   connectionsSchemaV1,
+		ProblemReportTopic: problemReportSchemaV1,
+	},
+}
+
+// Schema returns the JSON schema of the payload of the topic in the format version.
+func Schema(version, topic string) (string, error) {
+	topics, ok := schemas[version]
+	if !ok {
+		return "", fmt.Errorf("unsupported event format version: %s", version)
+	}
+
+	schema, ok := topics[topic]
+	if !ok {
+		return "", fmt.Errorf("no schema for topic %s in event format version %s", topic, version)
+	}
+
+	return schema, nil
+}
+
+// Versions returns all supported payload format versions.
+func Versions() []string {
+	versions := make([]string, 0, len(schemas))
+	for v := range schemas {
+		versions = append(versions, v)
+	}
+
+	sort.Strings(versions)
+
+	return versions
+}
+
+const connectionsSchemaV1 = `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "$id": "https://hyperledger.github.io/aries-framework-go/schemas/events/1.0/connections.json",
+  "title": "Connection state event",
+  "type": "object",
+  "required": ["formatVersion", "topic", "message"],
+  "properties": {
+    "formatVersion": {"const": "1.0"},
+    "topic": {"const": "connections"},
+    "message": {
+      "type": "object",
+      "required": ["protocol", "type", "state"],
+      "properties": {
+        "protocol": {"type": "string"},
+        "type": {"enum": ["pre_state", "post_state"]},
+        "state": {"type": "string"},
+        "connectionID": {"type": "string"},
+        "invitationID": {"type": "string"},
+        "messageType": {"type": "string"}
+      }
+    }
+  }
+}`
+
+const problemReportSchemaV1 = `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "$id": "https://hyperledger.github.io/aries-framework-go/schemas/events/1.0/problem_report.json",
+  "title": "Problem report event",
+  "type": "object",
+  "required": ["formatVersion", "topic", "message"],
+  "properties": {
+    "formatVersion": {"const": "1.0"},
+    "topic": {"const": "problem_report"},
+    "message": {
+      "type": "object",
+      "required": ["protocol", "report"],
+      "properties": {
+        "protocol": {"type": "string"},
+        "connectionID": {"type": "string"},
+        "report": {"type": "object"}
+      }
+    }
+  }
+}`
@@ -0,0 +1,462 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package revocation manages the revocation of the credentials on the issuer side: the credentials are assigned
+// an index in the revocation list of the issuer when issued, and the revocation list credential is regenerated
+// whenever a credential is revoked or unrevoked so it can be published at the URL referenced by the statuses.
+package revocation
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/hyperledger/aries-framework-go/pkg/common/clock"
+	"github.com/hyperledger/aries-framework-go/pkg/common/log"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+	"github.com/hyperledger/aries-framework-go/pkg/storage"
+)
+
+const (
+	// ListCredentialType is the type of the revocation list credentials
+	ListCredentialType = "RevocationList2020Credential"
+	// ListContext is the JSON-LD context of the revocation list credentials
+	ListContext = "https://w3id.org/vc-revocation-list-2020/v1"
+
+	// DefaultListSize is the number of entries of the revocation lists, the 16KB minimum of the revocation list
+	// specification keeping the revoked credentials among enough credentials
+	DefaultListSize = 16 * 1024 * 8
+
+	listType = "RevocationList2020"
+
+	listKey           = "revocation_list"
+	listCredentialKey = "revocation_list_credential"
+	credentialPrefix  = "revocation_index_"
+)
+
+var (
+	// ErrListFull is returned when all the entries of the revocation list are assigned
+	ErrListFull = errors.New("revocation list is full")
+	// ErrNotAssigned is returned when the credential has no entry in the revocation list
+	ErrNotAssigned = errors.New("credential has no revocation list entry")
+	// ErrNilChannel is returned when registering a nil event channel
+	ErrNilChannel = errors.New("event channel is nil")
+)
+
+var logger = log.New("aries-framework/revocation")
+
+// PublishEvent is sent to the registered channels when the revocation list credential is regenerated, the
+// credential must then be published at the URL of the revocation list. The events aren't sent to the channels
+// which aren't ready to receive them, the next event carrying the whole revocation list again. The events are sent
+// in the order of the updates of the revocation list.
+type PublishEvent struct {
+	// CredentialID is the ID of the revoked or unrevoked credential
+	CredentialID string
+	// Revoked is the new status of the credential
+	Revoked bool
+	// ListCredential is the regenerated revocation list credential
+	ListCredential *verifiable.Credential
+}
+
+// Opt configures the manager
+type Opt func(m *Manager)
+
+// WithListSize sets the number of entries of the revocation list, DefaultListSize by default. The size is rounded
+// up to a multiple of 8 and only applies to new revocation lists.
+func WithListSize(size int) Opt {
+	return func(m *Manager) {
+		m.size = size
+	}
+}
+
+// WithClock sets the clock of the issuance dates of the revocation list credentials, the system clock by default
+func WithClock(c clock.Clock) Opt {
+	return func(m *Manager) {
+		m.clock = c
+	}
+}
+
+// WithSigner signs the regenerated revocation list credentials with the signer and the proof options, e.g. the
+// creator of the issuer key. The credentials are unsigned otherwise.
+func WithSigner(s verifiable.Signer, opts *verifiable.ProofOptions) Opt {
+	return func(m *Manager) {
+		m.signer = s
+		m.proofOpts = opts
+	}
+}
+
+// Manager assigns the revocation list entries of the issued credentials and revokes them. The entries are kept
+// in the store, which must have a single writer: the store must not be shared by several managers, the manager
+// serializing the updates of the revocation list. The records of an update are written atomically if the store
+// is a storage.BatchStore.
+type Manager struct {
+	store     storage.Store
+	listURL   string
+	issuer    verifiable.Issuer
+	size      int
+	clock     clock.Clock
+	signer    verifiable.Signer
+	proofOpts *verifiable.ProofOptions
+	// listLock guards the revocation list and the indexes
+	listLock sync.Mutex
+	// eventsLock guards the registered event channels
+	eventsLock sync.RWMutex
+	events     []chan<- PublishEvent
+}
+
+// revocationList is the stored revocation list
+type revocationList struct {
+	// NextIndex is the index of the next assigned entry
+	NextIndex int `json:"nextIndex"`
+	// Bits are the statuses of the entries, from the most significant bit of the first byte
+	Bits []byte `json:"bits"`
+}
+
+// New returns the manager of the revocation list of the issuer published at the URL, the revocation list is
+// created in the store if missing
+func New(store storage.Store, listURL string, issuer verifiable.Issuer, opts ...Opt) (*Manager, error) {
+	if listURL == "" || issuer.ID == "" {
+		return nil, errors.New("revocation list URL and issuer are required")
+	}
+
+	m := &Manager{store: store, listURL: listURL, issuer: issuer, size: DefaultListSize, clock: clock.System()}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	if m.size <= 0 {
+		return nil, fmt.Errorf("invalid revocation list size %d", m.size)
+	}
+
+	if m.signer != nil && m.proofOpts == nil {
+		return nil, errors.New("proof options of the signer are required")
+	}
+
+	_, err := m.getList()
+	if err == nil {
+		return m, nil
+	}
+
+	if !errors.Is(err, storage.ErrDataNotFound) {
+		return nil, err
+	}
+
+	list := &revocationList{Bits: make([]byte, (m.size+7)/8)}
+
+	if _, err = m.regenerate(list); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// Assign assigns the next entry of the revocation list to the credential and returns the status to set in the
+// credential before signing it. The status of a credential already assigned is returned again.
+func (m *Manager) Assign(credentialID string) (*verifiable.CredentialStatus, error) {
+	if credentialID == "" {
+		return nil, errors.New("credential ID is required")
+	}
+
+	m.listLock.Lock()
+	defer m.listLock.Unlock()
+
+	index, err := m.index(credentialID)
+	if err == nil {
+		return m.status(index), nil
+	}
+
+	if !errors.Is(err, ErrNotAssigned) {
+		return nil, err
+	}
+
+	list, err := m.getList()
+	if err != nil {
+		return nil, err
+	}
+
+	index = list.NextIndex
+	if index >= len(list.Bits)*8 {
+		return nil, ErrListFull
+	}
+
+	list.NextIndex++
+
+	listBytes, err := json.Marshal(list)
+	if err != nil {
+		return nil, err
+	}
+
+	// without batch, the list is stored first so a crash leaves an unused entry rather than an entry assigned twice
+	err = m.putAll(
+		record{key: listKey, value: listBytes},
+		record{key: credentialPrefix + credentialID, value: []byte(strconv.Itoa(index))})
+	if err != nil {
+		return nil, fmt.Errorf("failed to store revocation list index of %s: %w", credentialID, err)
+	}
+
+	return m.status(index), nil
+}
+
+// Revoke revokes the credential and returns the regenerated revocation list credential, which is also sent to
+// the registered channels
+func (m *Manager) Revoke(credentialID string) (*verifiable.Credential, error) {
+	return m.setRevoked(credentialID, true)
+}
+
+// Unrevoke reinstates the revoked credential and returns the regenerated revocation list credential, which is
+// also sent to the registered channels
+func (m *Manager) Unrevoke(credentialID string) (*verifiable.Credential, error) {
+	return m.setRevoked(credentialID, false)
+}
+
+// Revoked returns true if the credential is revoked
+func (m *Manager) Revoked(credentialID string) (bool, error) {
+	m.listLock.Lock()
+	defer m.listLock.Unlock()
+
+	index, err := m.index(credentialID)
+	if err != nil {
+		return false, err
+	}
+
+	list, err := m.getList()
+	if err != nil {
+		return false, err
+	}
+
+	return list.Bits[index/8]&bitMask(index) != 0, nil
+}
+
+// ListCredential returns the last generated revocation list credential, e.g. to serve it at the URL of the
+// revocation list
+func (m *Manager) ListCredential() ([]byte, error) {
+	vcBytes, err := m.store.Get(listCredentialKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get revocation list credential: %w", err)
+	}
+
+	return vcBytes, nil
+}
+
+// RegisterPublishEvent registers the channel to receive the regenerated revocation list credentials
+func (m *Manager) RegisterPublishEvent(ch chan<- PublishEvent) error {
+	if ch == nil {
+		return ErrNilChannel
+	}
+
+	m.eventsLock.Lock()
+	m.events = append(m.events, ch)
+	m.eventsLock.Unlock()
+
+	return nil
+}
+
+// UnregisterPublishEvent unregisters the channel. Refer RegisterPublishEvent().
+func (m *Manager) UnregisterPublishEvent(ch chan<- PublishEvent) error {
+	m.eventsLock.Lock()
+	for i := 0; i < len(m.events); i++ {
+		if m.events[i] == ch {
+			m.events = append(m.events[:i], m.events[i+1:]...)
+			i--
+		}
+	}
+	m.eventsLock.Unlock()
+
+	return nil
+}
+
+func (m *Manager) setRevoked(credentialID string, revoked bool) (*verifiable.Credential, error) {
+	m.listLock.Lock()
+	defer m.listLock.Unlock()
+
+	vc, err := m.updateList(credentialID, revoked)
+	if err != nil {
+		return nil, err
+	}
+
+	// the event is sent before the list lock is released so the channels receive the list credentials in the
+	// order of the updates, a newer list is never followed by an older one. The sends don't block.
+	m.sendEvent(PublishEvent{CredentialID: credentialID, Revoked: revoked, ListCredential: vc})
+
+	return vc, nil
+}
+
+// updateList sets the status of the credential in the revocation list and regenerates the list credential, the
+// list lock must be held
+func (m *Manager) updateList(credentialID string, revoked bool) (*verifiable.Credential, error) {
+	index, err := m.index(credentialID)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := m.getList()
+	if err != nil {
+		return nil, err
+	}
+
+	if revoked {
+		list.Bits[index/8] |= bitMask(index)
+	} else {
+		list.Bits[index/8] &^= bitMask(index)
+	}
+
+	return m.regenerate(list)
+}
+
+// regenerate stores the revocation list and its regenerated credential
+func (m *Manager) regenerate(list *revocationList) (*verifiable.Credential, error) {
+	encodedList, err := EncodeList(list.Bits)
+	if err != nil {
+		return nil, err
+	}
+
+	vc, err := verifiable.NewCredentialBuilder().
+		SetID(m.listURL).
+		AddContext(ListContext).
+		AddType(ListCredentialType).
+		SetIssuer(m.issuer).
+		AddSubject(map[string]interface{}{
+			"id":          m.listURL + "#list",
+			"type":        listType,
+			"encodedList": encodedList,
+		}).
+		Build(verifiable.WithClock(m.clock))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build revocation list credential: %w", err)
+	}
+
+	if m.signer != nil {
+		if err = vc.GenerateProof(m.signer, m.proofOpts); err != nil {
+			return nil, fmt.Errorf("failed to sign revocation list credential: %w", err)
+		}
+	}
+
+	vcBytes, err := vc.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+
+	listBytes, err := json.Marshal(list)
+	if err != nil {
+		return nil, err
+	}
+
+	err = m.putAll(record{key: listKey, value: listBytes}, record{key: listCredentialKey, value: vcBytes})
+	if err != nil {
+		return nil, fmt.Errorf("failed to store revocation list: %w", err)
+	}
+
+	return vc, nil
+}
+
+func (m *Manager) status(index int) *verifiable.CredentialStatus {
+	return &verifiable.CredentialStatus{
+		ID:                       m.listURL + "#" + strconv.Itoa(index),
+		Type:                     verifiable.RevocationList2020Status,
+		RevocationListIndex:      strconv.Itoa(index),
+		RevocationListCredential: m.listURL,
+	}
+}
+
+func (m *Manager) index(credentialID string) (int, error) {
+	indexBytes, err := m.store.Get(credentialPrefix + credentialID)
+	if errors.Is(err, storage.ErrDataNotFound) {
+		return 0, fmt.Errorf("%w: %s", ErrNotAssigned, credentialID)
+	}
+
+	if err != nil {
+		return 0, fmt.Errorf("failed to get revocation list index of %s: %w", credentialID, err)
+	}
+
+	index, err := strconv.Atoi(string(indexBytes))
+	if err != nil {
+		return 0, fmt.Errorf("invalid revocation list index of %s: %w", credentialID, err)
+	}
+
+	return index, nil
+}
+
+func (m *Manager) getList() (*revocationList, error) {
+	listBytes, err := m.store.Get(listKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get revocation list: %w", err)
+	}
+
+	list := &revocationList{}
+	if err := json.Unmarshal(listBytes, list); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal revocation list: %w", err)
+	}
+
+	return list, nil
+}
+
+type record struct {
+	key   string
+	value []byte
+}
+
+// putAll stores the records atomically if the store is a storage.BatchStore, in order otherwise
+func (m *Manager) putAll(records ...record) error {
+	if batch, ok := m.store.(storage.BatchStore); ok {
+		values := make(map[string][]byte, len(records))
+		for _, r := range records {
+			values[r.key] = r.value
+		}
+
+		return batch.PutAll(values)
+	}
+
+	for _, r := range records {
+		if err := m.store.Put(r.key, r.value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *Manager) sendEvent(event PublishEvent) {
+	m.eventsLock.RLock()
+	events := append(m.events[:0:0], m.events...)
+	m.eventsLock.RUnlock()
+
+	for _, ch := range events {
+		select {
+		case ch <- event:
+		default:
+			logger.Warnf("dropped revocation list event of %s, the channel is not ready", event.CredentialID)
+		}
+	}
+}
+
+// bitMask returns the mask of the entry in its byte, the bits are ordered from the most significant bit
+func bitMask(index int) byte {
+	return 1 << (7 - uint(index%8))
+}
+
+// EncodeList returns the encodedList of the revocation list credentials: the GZIP compressed and base64url
+// encoded bitstring
+func EncodeList(bits []byte) (string, error) {
+	var buf bytes.Buffer
+
+	w := gzip.NewWriter(&buf)
+
+	if _, err := w.Write(bits); err != nil {
+		return "", fmt.Errorf("compress revocation list: %w", err)
+	}
+
+	if err := w.Close(); err != nil {
+		return "", fmt.Errorf("compress revocation list: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(buf.Bytes()), nil
+}
@@ -0,0 +1,339 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package revocation
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+	mockclock "github.com/hyperledger/aries-framework-go/pkg/internal/mock/clock"
+	mockstorage "github.com/hyperledger/aries-framework-go/pkg/internal/mock/storage"
+	"github.com/hyperledger/aries-framework-go/pkg/storage"
+)
+
+const listURL = "https://example.edu/credentials/status/3"
+
+//nolint:gochecknoglobals
+var issuer = verifiable.Issuer{ID: "did:example:76e12ec712ebc6f1c221ebfeb1f"}
+
+type ed25519Signer ed25519.PrivateKey
+
+func (s ed25519Signer) Sign(doc []byte) ([]byte, error) {
+	return ed25519.Sign(ed25519.PrivateKey(s), doc), nil
+}
+
+type staticKeyResolver []byte
+
+func (k staticKeyResolver) Resolve(string) ([]byte, error) {
+	return k, nil
+}
+
+func newStore() *mockstorage.MockStore {
+	return &mockstorage.MockStore{Store: make(map[string][]byte)}
+}
+
+func TestManager(t *testing.T) {
+	t.Run("test assign, revoke and unrevoke", func(t *testing.T) {
+		issued := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+		m, err := New(newStore(), listURL, issuer, WithListSize(16), WithClock(&mockclock.Clock{Time: issued}))
+		require.NoError(t, err)
+
+		events := make(chan PublishEvent, 2)
+		require.NoError(t, m.RegisterPublishEvent(events))
+
+		status, err := m.Assign("urn:uuid:1")
+		require.NoError(t, err)
+		require.Equal(t, &verifiable.CredentialStatus{
+			ID:                       listURL + "#0",
+			Type:                     verifiable.RevocationList2020Status,
+			RevocationListIndex:      "0",
+			RevocationListCredential: listURL,
+		}, status)
+
+		status, err = m.Assign("urn:uuid:2")
+		require.NoError(t, err)
+		require.Equal(t, "1", status.RevocationListIndex)
+
+		// the status of an assigned credential is kept
+		status, err = m.Assign("urn:uuid:1")
+		require.NoError(t, err)
+		require.Equal(t, "0", status.RevocationListIndex)
+
+		vc, err := m.Revoke("urn:uuid:2")
+		require.NoError(t, err)
+		require.Equal(t, listURL, vc.ID)
+		require.Equal(t, []string{"VerifiableCredential", ListCredentialType}, vc.Types())
+		require.Equal(t, issued, *vc.Issued)
+
+		event := <-events
+		require.Equal(t, "urn:uuid:2", event.CredentialID)
+		require.True(t, event.Revoked)
+		require.Equal(t, vc, event.ListCredential)
+
+		revoked, err := m.Revoked("urn:uuid:2")
+		require.NoError(t, err)
+		require.True(t, revoked)
+
+		revoked, err = m.Revoked("urn:uuid:1")
+		require.NoError(t, err)
+		require.False(t, revoked)
+
+		_, err = m.Unrevoke("urn:uuid:2")
+		require.NoError(t, err)
+		require.False(t, (<-events).Revoked)
+
+		revoked, err = m.Revoked("urn:uuid:2")
+		require.NoError(t, err)
+		require.False(t, revoked)
+
+		require.NoError(t, m.UnregisterPublishEvent(events))
+		_, err = m.Revoke("urn:uuid:2")
+		require.NoError(t, err)
+		require.Empty(t, events)
+
+		require.Equal(t, ErrNilChannel, m.RegisterPublishEvent(nil))
+	})
+
+	t.Run("test events not blocking the updates", func(t *testing.T) {
+		m, err := New(newStore(), listURL, issuer, WithListSize(16))
+		require.NoError(t, err)
+
+		events := make(chan PublishEvent)
+		require.NoError(t, m.RegisterPublishEvent(events))
+
+		_, err = m.Assign("urn:uuid:1")
+		require.NoError(t, err)
+
+		// nobody receives the events
+		_, err = m.Revoke("urn:uuid:1")
+		require.NoError(t, err)
+
+		revoked, err := m.Revoked("urn:uuid:1")
+		require.NoError(t, err)
+		require.True(t, revoked)
+	})
+
+	t.Run("test events sent in list order", func(t *testing.T) {
+		const count = 8
+
+		m, err := New(newStore(), listURL, issuer, WithListSize(16))
+		require.NoError(t, err)
+
+		events := make(chan PublishEvent, count)
+		require.NoError(t, m.RegisterPublishEvent(events))
+
+		for i := 0; i < count; i++ {
+			_, err = m.Assign("urn:uuid:" + strconv.Itoa(i))
+			require.NoError(t, err)
+		}
+
+		var wg sync.WaitGroup
+
+		for i := 0; i < count; i++ {
+			wg.Add(1)
+
+			go func(i int) {
+				defer wg.Done()
+
+				_, err := m.Revoke("urn:uuid:" + strconv.Itoa(i))
+				require.NoError(t, err)
+			}(i)
+		}
+
+		wg.Wait()
+
+		// every event carries one more revoked credential than the previous one
+		for i := 1; i <= count; i++ {
+			require.Equal(t, i, revokedCount(t, (<-events).ListCredential))
+		}
+	})
+
+	t.Run("test store without batch", func(t *testing.T) {
+		store := newStore()
+		m, err := New(struct{ storage.Store }{store}, listURL, issuer, WithListSize(16))
+		require.NoError(t, err)
+
+		status, err := m.Assign("urn:uuid:1")
+		require.NoError(t, err)
+		require.Equal(t, "0", status.RevocationListIndex)
+		require.Contains(t, store.Store, credentialPrefix+"urn:uuid:1")
+
+		store.ErrPut = errors.New("put error")
+		_, err = m.Assign("urn:uuid:2")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "put error")
+	})
+
+	t.Run("test revocation checked by the holders", func(t *testing.T) {
+		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+
+		m, err := New(newStore(), listURL, issuer, WithSigner(ed25519Signer(priv),
+			&verifiable.ProofOptions{Creator: issuer.ID + "#keys-1"}))
+		require.NoError(t, err)
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			vcBytes, err := m.ListCredential()
+			require.NoError(t, err)
+
+			_, err = w.Write(vcBytes)
+			require.NoError(t, err)
+		}))
+		defer server.Close()
+
+		status, err := m.Assign("urn:uuid:1")
+		require.NoError(t, err)
+
+		status.RevocationListCredential = server.URL
+		verifier := verifiable.NewHTTPStatusVerifier(server.Client(),
+			verifiable.NewProofChecker(staticKeyResolver(pub)))
+
+		revoked, err := verifier.Revoked(status, issuer.ID)
+		require.NoError(t, err)
+		require.False(t, revoked)
+
+		_, err = m.Revoke("urn:uuid:1")
+		require.NoError(t, err)
+
+		revoked, err = verifier.Revoked(status, issuer.ID)
+		require.NoError(t, err)
+		require.True(t, revoked)
+
+		_, err = verifier.Revoked(status, "did:example:other")
+		require.Error(t, err)
+	})
+
+	t.Run("test signed revocation list credential", func(t *testing.T) {
+		_, priv, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+
+		m, err := New(newStore(), listURL, issuer, WithSigner(ed25519Signer(priv),
+			&verifiable.ProofOptions{Creator: issuer.ID + "#keys-1"}))
+		require.NoError(t, err)
+
+		_, err = m.Assign("urn:uuid:1")
+		require.NoError(t, err)
+
+		vc, err := m.Revoke("urn:uuid:1")
+		require.NoError(t, err)
+		require.NotEmpty(t, vc.Proof)
+	})
+
+	t.Run("test existing revocation list", func(t *testing.T) {
+		store := newStore()
+
+		m, err := New(store, listURL, issuer, WithListSize(8))
+		require.NoError(t, err)
+
+		_, err = m.Assign("urn:uuid:1")
+		require.NoError(t, err)
+
+		m, err = New(store, listURL, issuer)
+		require.NoError(t, err)
+
+		status, err := m.Assign("urn:uuid:2")
+		require.NoError(t, err)
+		require.Equal(t, "1", status.RevocationListIndex)
+	})
+
+	t.Run("test full revocation list", func(t *testing.T) {
+		m, err := New(newStore(), listURL, issuer, WithListSize(1))
+		require.NoError(t, err)
+
+		for i := 0; i < 8; i++ {
+			_, err = m.Assign(string(rune('a' + i)))
+			require.NoError(t, err)
+		}
+
+		_, err = m.Assign("i")
+		require.Equal(t, ErrListFull, err)
+	})
+
+	t.Run("test errors", func(t *testing.T) {
+		_, err := New(newStore(), "", issuer)
+		require.EqualError(t, err, "revocation list URL and issuer are required")
+
+		_, err = New(newStore(), listURL, issuer, WithListSize(0))
+		require.EqualError(t, err, "invalid revocation list size 0")
+
+		_, err = New(newStore(), listURL, issuer, WithSigner(ed25519Signer(nil), nil))
+		require.EqualError(t, err, "proof options of the signer are required")
+
+		_, err = New(&mockstorage.MockStore{Store: map[string][]byte{listKey: []byte("{")}}, listURL, issuer)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to unmarshal revocation list")
+
+		_, err = New(&mockstorage.MockStore{Store: map[string][]byte{listKey: nil}, ErrGet: errors.New("get error")},
+			listURL, issuer)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "get error")
+
+		_, err = New(&mockstorage.MockStore{Store: make(map[string][]byte), ErrPut: errors.New("put error")},
+			listURL, issuer)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to store revocation list")
+
+		m, err := New(newStore(), listURL, issuer)
+		require.NoError(t, err)
+
+		_, err = m.Assign("")
+		require.EqualError(t, err, "credential ID is required")
+
+		_, err = m.Revoke("urn:uuid:unknown")
+		require.True(t, errors.Is(err, ErrNotAssigned))
+
+		_, err = m.Revoked("urn:uuid:unknown")
+		require.True(t, errors.Is(err, ErrNotAssigned))
+	})
+}
+
+// revokedCount returns the number of revoked credentials of the revocation list credential
+func revokedCount(t *testing.T, vc *verifiable.Credential) int {
+	vcBytes, err := vc.MarshalJSON()
+	require.NoError(t, err)
+
+	raw := struct {
+		Subject struct {
+			EncodedList string `json:"encodedList"`
+		} `json:"credentialSubject"`
+	}{}
+	require.NoError(t, json.Unmarshal(vcBytes, &raw))
+
+	compressed, err := base64.RawURLEncoding.DecodeString(raw.Subject.EncodedList)
+	require.NoError(t, err)
+
+	r, err := gzip.NewReader(bytes.NewReader(compressed))
+	require.NoError(t, err)
+
+	bits, err := ioutil.ReadAll(r)
+	require.NoError(t, err)
+
+	count := 0
+
+	for i := 0; i < len(bits)*8; i++ {
+		if bits[i/8]&bitMask(i) != 0 {
+			count++
+		}
+	}
+
+	return count
+}
@@ -0,0 +1,368 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package cache
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/cache"
+	"github.com/hyperledger/aries-framework-go/pkg/cache/mem"
+	"github.com/hyperledger/aries-framework-go/pkg/storage"
+)
+
+// Provider is a storage.Provider keeping a copy of the records of the underlying stores in a cache.
+// Records are cached on read and write (write-through), so the hot path of the protocols (eg. reading
+// connection records or DID documents for every inbound message) doesn't hit the underlying storage.
+// All the handles opened for the same name space share the same cache, so an update done through one
+// of them invalidates the record for the others.
+type Provider struct {
+	provider   storage.Provider
+	caches     cache.Provider
+	namespaces map[string]bool
+	stores     map[string]*cachedStore
+	lock       sync.RWMutex
+}
+
+// NewProvider returns a provider caching the records in memory in front of the given provider. If name spaces
+// are passed, only the stores of those name spaces are cached, otherwise all the stores are cached.
+func NewProvider(prov storage.Provider, namespaces ...string) *Provider {
+	return NewProviderWithCache(prov, mem.NewProvider(), namespaces...)
+}
+
+// NewProviderWithCache returns a provider caching the records in the caches of the cache provider in front of
+// the given provider. The cache of a store is opened under the name space of the store.
+func NewProviderWithCache(prov storage.Provider, caches cache.Provider, namespaces ...string) *Provider {
+	p := &Provider{
+		provider:   prov,
+		caches:     caches,
+		namespaces: make(map[string]bool),
+		stores:     make(map[string]*cachedStore),
+	}
+
+	for _, ns := range namespaces {
+		p.namespaces[strings.ToLower(ns)] = true
+	}
+
+	return p
+}
+
+// OpenStore opens and returns a store for given name space.
+func (p *Provider) OpenStore(name string) (storage.Store, error) {
+	k := strings.ToLower(name)
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if s, ok := p.stores[k]; ok {
+		return s.expose(), nil
+	}
+
+	store, err := p.provider.OpenStore(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(p.namespaces) > 0 && !p.namespaces[k] || !cacheable(store) {
+		return store, nil
+	}
+
+	records, err := p.caches.OpenCache(k)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open cache of store %s: %w", name, err)
+	}
+
+	s := newCachedStore(store, records)
+	p.stores[k] = s
+
+	return s.expose(), nil
+}
+
+// CloseStore closes store of given name space and drops its cache.
+func (p *Provider) CloseStore(name string) error {
+	k := strings.ToLower(name)
+
+	p.lock.Lock()
+	s, cached := p.stores[k]
+	delete(p.stores, k)
+	p.lock.Unlock()
+
+	if cached {
+		s.close()
+
+		if err := p.caches.CloseCache(k); err != nil {
+			return fmt.Errorf("failed to close cache of store %s: %w", name, err)
+		}
+	}
+
+	return p.provider.CloseStore(name)
+}
+
+// Close closes all stores created under this store provider and drops their caches.
+func (p *Provider) Close() error {
+	p.lock.Lock()
+	stores := p.stores
+	p.stores = make(map[string]*cachedStore)
+	p.lock.Unlock()
+
+	for k, s := range stores {
+		s.close()
+
+		if err := p.caches.CloseCache(k); err != nil {
+			return fmt.Errorf("failed to close cache of store %s: %w", k, err)
+		}
+	}
+
+	return p.provider.Close()
+}
+
+// Compact compacts the stores of the underlying provider if it supports the compaction
+func (p *Provider) Compact() error {
+	if compactable, ok := p.provider.(storage.CompactableProvider); ok {
+		return compactable.Compact()
+	}
+
+	return nil
+}
+
+// PurgeExpired deletes the expired records of the underlying provider if it expires the records lazily
+func (p *Provider) PurgeExpired() (int, error) {
+	if expiring, ok := p.provider.(storage.ExpiringProvider); ok {
+		return expiring.PurgeExpired()
+	}
+
+	return 0, nil
+}
+
+// Invalidate removes the cached record of the given name space. It is meant for records updated
+// without going through this provider.
+func (p *Provider) Invalidate(namespace, k string) {
+	p.lock.RLock()
+	s, ok := p.stores[strings.ToLower(namespace)]
+	p.lock.RUnlock()
+
+	if ok {
+		s.invalidate(k)
+	}
+}
+
+// optionalStore is a store implementing all the optional interfaces of the storage package
+type optionalStore interface {
+	storage.ConditionalStore
+	storage.BatchStore
+	storage.ExpiringStore
+	storage.IterableStore
+}
+
+// cacheable returns true if the store implements all the optional interfaces or none of them. The stores
+// implementing only some of them aren't cached, so the callers checking an interface (e.g.
+// storage.ConditionalStore for atomic updates) always see the interfaces of the underlying store.
+func cacheable(store storage.Store) bool {
+	if _, ok := store.(optionalStore); ok {
+		return true
+	}
+
+	_, conditional := store.(storage.ConditionalStore)
+	_, batch := store.(storage.BatchStore)
+	_, expiring := store.(storage.ExpiringStore)
+	_, iterable := store.(storage.IterableStore)
+
+	return !conditional && !batch && !expiring && !iterable
+}
+
+// cachedStore is a write-through cache of storage.Store. The lock guards the state of the cache only, it is
+// never held during the I/O of the underlying store: the records written are dropped from the cache until the
+// write is done, and the records read on cache miss are only cached if no write overlapped the read.
+type cachedStore struct {
+	store    storage.Store
+	optional optionalStore
+	records  cache.Cache
+	lock     sync.RWMutex
+	closed   bool
+	// writes counts the writes started, pending the writes in progress
+	writes  uint64
+	pending int
+}
+
+func newCachedStore(store storage.Store, records cache.Cache) *cachedStore {
+	s := &cachedStore{store: store, records: records}
+	s.optional, _ = store.(optionalStore) // nolint: errcheck
+
+	return s
+}
+
+// optionalCachedStore is the cached store of the underlying stores implementing the optional interfaces
+type optionalCachedStore struct{ *cachedStore }
+
+// PutIf stores the record in the underlying store if the current record is the expected one. On conflict the
+// record has been modified by another instance sharing the store, the cached record is dropped.
+func (s optionalCachedStore) PutIf(k string, v, expected []byte) error {
+	return s.write(map[string][]byte{k: v}, 0, func() error {
+		return s.optional.PutIf(k, v, expected)
+	})
+}
+
+// PutAll stores the records atomically in the underlying store, then caches them
+func (s optionalCachedStore) PutAll(records map[string][]byte) error {
+	return s.write(records, 0, func() error {
+		return s.optional.PutAll(records)
+	})
+}
+
+// PutWithTTL stores the key and the record expiring after the ttl, the record is cached for the ttl
+func (s optionalCachedStore) PutWithTTL(k string, v []byte, ttl time.Duration) error {
+	return s.write(map[string][]byte{k: v}, ttl, func() error {
+		return s.optional.PutWithTTL(k, v, ttl)
+	})
+}
+
+// TTL returns the remaining time to live of the record of the underlying store
+func (s optionalCachedStore) TTL(k string) (time.Duration, error) {
+	return s.optional.TTL(k)
+}
+
+// Iterate calls fn with the records of the underlying store whose key starts with the prefix in key order
+func (s optionalCachedStore) Iterate(prefix string, fn func(k string, v []byte) error) error {
+	return s.optional.Iterate(prefix, fn)
+}
+
+// expose returns the store with the optional interfaces of the underlying store, see cacheable
+func (s *cachedStore) expose() storage.Store {
+	if s.optional != nil {
+		return optionalCachedStore{s}
+	}
+
+	return s
+}
+
+// Put stores the key and the record
+func (s *cachedStore) Put(k string, v []byte) error {
+	return s.write(map[string][]byte{k: v}, 0, func() error {
+		return s.store.Put(k, v)
+	})
+}
+
+// Delete removes the record from the underlying store and from the cache
+func (s *cachedStore) Delete(k string) error {
+	return s.write(map[string][]byte{k: nil}, 0, func() error {
+		return s.store.Delete(k)
+	})
+}
+
+// write runs the write of the records to the underlying store, fn, without holding the lock. The records are
+// dropped from the cache before the write so a failed write doesn't leave a stale record in the cache. They are
+// cached for the ttl once written if no other write overlapped, in which case the order of the writes in the
+// store isn't known and the records are dropped again. The nil records are deleted.
+func (s *cachedStore) write(records map[string][]byte, ttl time.Duration, fn func() error) error {
+	s.lock.Lock()
+
+	if s.closed {
+		s.lock.Unlock()
+
+		return storage.ErrStoreClosed
+	}
+
+	for k := range records {
+		if err := s.records.Delete(k); err != nil {
+			s.lock.Unlock()
+
+			return fmt.Errorf("failed to invalidate cached record: %w", err)
+		}
+	}
+
+	s.writes++
+	seq, alone := s.writes, s.pending == 0
+	s.pending++
+	s.lock.Unlock()
+
+	err := fn()
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.pending--
+
+	for k, v := range records {
+		// the cache is best effort, the record is read from the store if it couldn't be cached
+		if err != nil || v == nil || !alone || seq != s.writes {
+			s.records.Delete(k) // nolint: errcheck
+		} else {
+			s.records.Set(k, v, ttl) // nolint: errcheck
+		}
+	}
+
+	return err
+}
+
+// Get fetches the record based on key
+func (s *cachedStore) Get(k string) ([]byte, error) {
+	s.lock.RLock()
+	closed := s.closed
+	v, err := s.records.Get(k)
+	seq, idle := s.writes, s.pending == 0
+	s.lock.RUnlock()
+
+	if closed {
+		return nil, storage.ErrStoreClosed
+	}
+
+	if err == nil {
+		return v, nil
+	}
+
+	v, err = s.store.Get(k)
+	if err != nil {
+		return nil, err
+	}
+
+	// only the records read while no write was in progress are cached, a concurrent write may have been
+	// read half way or be cached already
+	if idle {
+		s.cache(k, v, seq)
+	}
+
+	return v, nil
+}
+
+// cache caches the record read from the store unless a write started since the read, the records of the
+// expiring stores are cached for their remaining time to live
+func (s *cachedStore) cache(k string, v []byte, seq uint64) {
+	var ttl time.Duration
+
+	if s.optional != nil {
+		var err error
+
+		ttl, err = s.optional.TTL(k)
+		if err != nil {
+			return
+		}
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if !s.closed && s.writes == seq {
+		s.records.Set(k, v, ttl) // nolint: errcheck
+	}
+}
+
+// close closes the store, the records aren't served from the cache anymore
+func (s *cachedStore) close() {
+	s.lock.Lock()
+	s.closed = true
+	s.lock.Unlock()
+}
+
+// invalidate drops the record updated without going through the store, as a write would
+func (s *cachedStore) invalidate(k string) {
+	s.lock.Lock()
+	s.writes++
+	s.records.Delete(k) // nolint: errcheck
+	s.lock.Unlock()
+}
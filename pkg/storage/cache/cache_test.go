@@ -0,0 +1,588 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package cache
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	cachespi "github.com/hyperledger/aries-framework-go/pkg/cache"
+	"github.com/hyperledger/aries-framework-go/pkg/cache/mem"
+	mockstorage "github.com/hyperledger/aries-framework-go/pkg/internal/mock/storage"
+	"github.com/hyperledger/aries-framework-go/pkg/storage"
+	"github.com/hyperledger/aries-framework-go/pkg/storage/conformance"
+	"github.com/hyperledger/aries-framework-go/pkg/storage/leveldb"
+)
+
+func TestCachedStore(t *testing.T) {
+	t.Run("test records are served from cache", func(t *testing.T) {
+		mockProv := mockstorage.NewMockStoreProvider()
+		prov := NewProvider(mockProv)
+
+		store, err := prov.OpenStore("test")
+		require.NoError(t, err)
+
+		require.NoError(t, store.Put("k1", []byte("v1")))
+		require.Equal(t, []byte("v1"), mockProv.Store.Store["k1"])
+
+		// remove the record behind the cache
+		delete(mockProv.Store.Store, "k1")
+
+		v, err := store.Get("k1")
+		require.NoError(t, err)
+		require.Equal(t, []byte("v1"), v)
+	})
+
+	t.Run("test records are cached on read", func(t *testing.T) {
+		mockProv := mockstorage.NewMockStoreProvider()
+		mockProv.Store.Store["k1"] = []byte("v1")
+		prov := NewProvider(mockProv)
+
+		store, err := prov.OpenStore("test")
+		require.NoError(t, err)
+
+		v, err := store.Get("k1")
+		require.NoError(t, err)
+		require.Equal(t, []byte("v1"), v)
+
+		mockProv.Store.Store["k1"] = []byte("v2")
+
+		v, err = store.Get("k1")
+		require.NoError(t, err)
+		require.Equal(t, []byte("v1"), v)
+
+		// invalidate the record updated behind the cache
+		prov.Invalidate("TEST", "k1")
+
+		v, err = store.Get("k1")
+		require.NoError(t, err)
+		require.Equal(t, []byte("v2"), v)
+	})
+
+	t.Run("test record not found", func(t *testing.T) {
+		prov := NewProvider(mockstorage.NewMockStoreProvider())
+
+		store, err := prov.OpenStore("test")
+		require.NoError(t, err)
+
+		_, err = store.Get("k1")
+		require.True(t, errors.Is(err, storage.ErrDataNotFound))
+	})
+
+	t.Run("test failed write invalidates the record", func(t *testing.T) {
+		mockProv := mockstorage.NewMockCustomStoreProvider(&mockstorage.MockStore{Store: make(map[string][]byte)})
+		prov := NewProvider(mockProv)
+
+		store, err := prov.OpenStore("test")
+		require.NoError(t, err)
+		require.NoError(t, store.Put("k1", []byte("v1")))
+
+		mockStore, ok := mockProv.Custom.(*mockstorage.MockStore)
+		require.True(t, ok)
+		mockStore.ErrPut = errors.New("put error")
+
+		err = store.Put("k1", []byte("v2"))
+		require.EqualError(t, err, "put error")
+
+		mockStore.ErrPut = nil
+		delete(mockStore.Store, "k1")
+
+		_, err = store.Get("k1")
+		require.True(t, errors.Is(err, storage.ErrDataNotFound))
+	})
+
+	t.Run("test cached records are not shared with callers", func(t *testing.T) {
+		prov := NewProvider(mockstorage.NewMockStoreProvider())
+
+		store, err := prov.OpenStore("test")
+		require.NoError(t, err)
+
+		v := []byte("v1")
+		require.NoError(t, store.Put("k1", v))
+		v[0] = 'x'
+
+		cached, err := store.Get("k1")
+		require.NoError(t, err)
+		require.Equal(t, []byte("v1"), cached)
+	})
+}
+
+func TestCachedStore_Iterate(t *testing.T) {
+	t.Run("test records of the underlying store are iterated", func(t *testing.T) {
+		mockProv := mockstorage.NewMockStoreProvider()
+		mockProv.Store.Store["k1"] = []byte("v1")
+		mockProv.Store.Store["k2"] = []byte("v2")
+		mockProv.Store.Store["x1"] = []byte("x1")
+
+		store, err := NewProvider(mockProv).OpenStore("test")
+		require.NoError(t, err)
+
+		iterable, ok := store.(storage.IterableStore)
+		require.True(t, ok)
+
+		records := make(map[string]string)
+		require.NoError(t, iterable.Iterate("k", func(k string, v []byte) error {
+			records[k] = string(v)
+			return nil
+		}))
+		require.Equal(t, map[string]string{"k1": "v1", "k2": "v2"}, records)
+	})
+
+	t.Run("test underlying store not iterable", func(t *testing.T) {
+		store, err := NewProvider(mockstorage.NewMockCustomStoreProvider(&notIterableStore{})).OpenStore("test")
+		require.NoError(t, err)
+
+		_, ok := store.(storage.IterableStore)
+		require.False(t, ok)
+	})
+}
+
+type notIterableStore struct {
+	storage.Store
+}
+
+func TestCachedStore_PutIf(t *testing.T) {
+	t.Run("test conditional put through the underlying store", func(t *testing.T) {
+		mockProv := mockstorage.NewMockStoreProvider()
+
+		store, err := NewProvider(mockProv).OpenStore("test")
+		require.NoError(t, err)
+
+		conditional, ok := store.(storage.ConditionalStore)
+		require.True(t, ok)
+
+		require.NoError(t, conditional.PutIf("k1", []byte("v1"), nil))
+		require.True(t, errors.Is(conditional.PutIf("k1", []byte("v2"), nil), storage.ErrConflict))
+
+		v, err := store.Get("k1")
+		require.NoError(t, err)
+		require.Equal(t, []byte("v1"), v)
+
+		// updated by another instance sharing the underlying store, the cached record is stale
+		require.NoError(t, mockProv.Store.Put("k1", []byte("v3")))
+
+		err = conditional.PutIf("k1", []byte("v2"), []byte("v1"))
+		require.True(t, errors.Is(err, storage.ErrConflict))
+
+		v, err = store.Get("k1")
+		require.NoError(t, err)
+		require.Equal(t, []byte("v3"), v)
+
+		require.NoError(t, conditional.PutIf("k1", []byte("v4"), []byte("v3")))
+
+		v, err = store.Get("k1")
+		require.NoError(t, err)
+		require.Equal(t, []byte("v4"), v)
+	})
+
+	t.Run("test closed store", func(t *testing.T) {
+		prov := NewProvider(mockstorage.NewMockStoreProvider())
+
+		store, err := prov.OpenStore("test")
+		require.NoError(t, err)
+		require.NoError(t, prov.CloseStore("test"))
+
+		err = store.(storage.ConditionalStore).PutIf("k1", []byte("v1"), nil)
+		require.True(t, errors.Is(err, storage.ErrStoreClosed))
+	})
+
+	t.Run("test underlying store not conditional", func(t *testing.T) {
+		store, err := NewProvider(mockstorage.NewMockCustomStoreProvider(&notIterableStore{
+			Store: &mockstorage.MockStore{Store: make(map[string][]byte)},
+		})).OpenStore("test")
+		require.NoError(t, err)
+
+		for _, ok := range []bool{
+			isConditional(store), isBatch(store), isExpiring(store), isIterable(store),
+		} {
+			require.False(t, ok)
+		}
+	})
+}
+
+func TestCachedStore_Expose(t *testing.T) {
+	mock := &mockstorage.MockStore{Store: make(map[string][]byte)}
+
+	stores := []storage.Store{
+		mock,
+		struct{ storage.Store }{mock},
+		struct {
+			storage.Store
+			storage.ConditionalStore
+		}{mock, mock},
+		struct {
+			storage.Store
+			storage.BatchStore
+			storage.IterableStore
+		}{mock, mock, mock},
+		struct {
+			storage.Store
+			storage.ExpiringStore
+		}{mock, mock},
+		struct {
+			storage.Store
+			storage.ConditionalStore
+			storage.ExpiringStore
+			storage.IterableStore
+		}{mock, mock, mock, mock},
+	}
+
+	for i, underlying := range stores {
+		store, err := NewProvider(mockstorage.NewMockCustomStoreProvider(underlying)).OpenStore("test")
+		require.NoError(t, err)
+
+		require.Equal(t, isConditional(underlying), isConditional(store))
+		require.Equal(t, isBatch(underlying), isBatch(store))
+		require.Equal(t, isExpiring(underlying), isExpiring(store))
+		require.Equal(t, isIterable(underlying), isIterable(store))
+
+		// the stores implementing only some of the optional interfaces aren't cached
+		require.Equal(t, i < 2, store != underlying)
+	}
+}
+
+func TestCachedStore_Concurrency(t *testing.T) {
+	t.Run("test concurrent reads and writes", func(t *testing.T) {
+		store, err := NewProvider(mockstorage.NewMockStoreProvider()).OpenStore("test")
+		require.NoError(t, err)
+
+		var wg sync.WaitGroup
+
+		for i := 0; i < 8; i++ {
+			wg.Add(2)
+
+			go func(i int) {
+				defer wg.Done()
+
+				require.NoError(t, store.Put("k1", []byte(strconv.Itoa(i))))
+			}(i)
+
+			go func() {
+				defer wg.Done()
+
+				_, err := store.Get("k1")
+				if err != nil {
+					require.True(t, errors.Is(err, storage.ErrDataNotFound))
+				}
+			}()
+		}
+
+		wg.Wait()
+
+		require.NoError(t, store.Put("k1", []byte("v1")))
+
+		v, err := store.Get("k1")
+		require.NoError(t, err)
+		require.Equal(t, []byte("v1"), v)
+	})
+
+	t.Run("test slow writes don't block the cached reads", func(t *testing.T) {
+		underlying := &slowStore{Store: &mockstorage.MockStore{Store: make(map[string][]byte)},
+			release: make(chan struct{})}
+
+		store, err := NewProvider(mockstorage.NewMockCustomStoreProvider(underlying)).OpenStore("test")
+		require.NoError(t, err)
+
+		close(underlying.release)
+		require.NoError(t, store.Put("k1", []byte("v1")))
+
+		underlying.release = make(chan struct{})
+		done := make(chan error)
+
+		go func() {
+			done <- store.Put("k2", []byte("v2"))
+		}()
+
+		v, err := store.Get("k1")
+		require.NoError(t, err)
+		require.Equal(t, []byte("v1"), v)
+
+		close(underlying.release)
+		require.NoError(t, <-done)
+	})
+}
+
+// slowStore blocks the puts until released
+type slowStore struct {
+	storage.Store
+	release chan struct{}
+}
+
+func (s *slowStore) Put(k string, v []byte) error {
+	<-s.release
+
+	return s.Store.Put(k, v)
+}
+
+func isConditional(s storage.Store) bool {
+	_, ok := s.(storage.ConditionalStore)
+	return ok
+}
+
+func isBatch(s storage.Store) bool {
+	_, ok := s.(storage.BatchStore)
+	return ok
+}
+
+func isExpiring(s storage.Store) bool {
+	_, ok := s.(storage.ExpiringStore)
+	return ok
+}
+
+func isIterable(s storage.Store) bool {
+	_, ok := s.(storage.IterableStore)
+	return ok
+}
+
+func TestCachedStore_Expiry(t *testing.T) {
+	t.Run("test records expire from the cache", func(t *testing.T) {
+		mockProv := mockstorage.NewMockStoreProvider()
+
+		store, err := NewProvider(mockProv).OpenStore("test")
+		require.NoError(t, err)
+
+		expiring, ok := store.(storage.ExpiringStore)
+		require.True(t, ok)
+
+		require.NoError(t, expiring.PutWithTTL("k1", []byte("v1"), 20*time.Millisecond))
+		// put without the cache, the record is cached on read for its remaining ttl
+		require.NoError(t, mockProv.Store.PutWithTTL("k2", []byte("v2"), 20*time.Millisecond))
+
+		for _, k := range []string{"k1", "k2"} {
+			_, err = store.Get(k)
+			require.NoError(t, err)
+
+			ttl, e := expiring.TTL(k)
+			require.NoError(t, e)
+			require.True(t, ttl > 0)
+		}
+
+		time.Sleep(30 * time.Millisecond)
+
+		for _, k := range []string{"k1", "k2"} {
+			_, err = store.Get(k)
+			require.True(t, errors.Is(err, storage.ErrDataNotFound))
+		}
+	})
+
+	t.Run("test failed write", func(t *testing.T) {
+		mockProv := mockstorage.NewMockStoreProvider()
+
+		store, err := NewProvider(mockProv).OpenStore("test")
+		require.NoError(t, err)
+
+		mockProv.Store.ErrPut = errors.New("put error")
+
+		err = store.(storage.ExpiringStore).PutWithTTL("k1", []byte("v1"), time.Minute)
+		require.EqualError(t, err, "put error")
+	})
+
+	t.Run("test underlying store can't expire its records", func(t *testing.T) {
+		store, err := NewProvider(mockstorage.NewMockCustomStoreProvider(&notIterableStore{
+			Store: &mockstorage.MockStore{Store: map[string][]byte{"k1": []byte("v1")}},
+		})).OpenStore("test")
+		require.NoError(t, err)
+
+		_, ok := store.(storage.ExpiringStore)
+		require.False(t, ok)
+
+		v, err := store.Get("k1")
+		require.NoError(t, err)
+		require.Equal(t, []byte("v1"), v)
+	})
+
+	t.Run("test purge expired records", func(t *testing.T) {
+		purged, err := NewProvider(mockstorage.NewMockStoreProvider()).PurgeExpired()
+		require.NoError(t, err)
+		require.Zero(t, purged)
+
+		purged, err = NewProvider(&expiringProvider{Provider: mockstorage.NewMockStoreProvider(), purged: 2}).
+			PurgeExpired()
+		require.NoError(t, err)
+		require.Equal(t, 2, purged)
+	})
+}
+
+func TestConformance(t *testing.T) {
+	path, err := ioutil.TempDir("", "db")
+	require.NoError(t, err)
+
+	defer func() { require.NoError(t, os.RemoveAll(path)) }()
+
+	prov, err := leveldb.NewProvider(path)
+	require.NoError(t, err)
+
+	conformance.Run(t, NewProvider(prov))
+}
+
+type expiringProvider struct {
+	storage.Provider
+	purged int
+}
+
+func (p *expiringProvider) PurgeExpired() (int, error) {
+	return p.purged, nil
+}
+
+func TestProvider(t *testing.T) {
+	t.Run("test stores of the same name space share the cache", func(t *testing.T) {
+		prov := NewProvider(mockstorage.NewMockStoreProvider())
+
+		store1, err := prov.OpenStore("test")
+		require.NoError(t, err)
+
+		store2, err := prov.OpenStore("Test")
+		require.NoError(t, err)
+
+		require.NoError(t, store1.Put("k1", []byte("v1")))
+		_, err = store2.Get("k1")
+		require.NoError(t, err)
+
+		// update through the other handle
+		require.NoError(t, store1.Put("k1", []byte("v2")))
+
+		v, err := store2.Get("k1")
+		require.NoError(t, err)
+		require.Equal(t, []byte("v2"), v)
+	})
+
+	t.Run("test only configured name spaces are cached", func(t *testing.T) {
+		mockProv := mockstorage.NewMockStoreProvider()
+		prov := NewProvider(mockProv, "cached")
+
+		store, err := prov.OpenStore("cached")
+		require.NoError(t, err)
+		require.NotEqual(t, mockProv.Store, store)
+
+		store, err = prov.OpenStore("other")
+		require.NoError(t, err)
+		require.Equal(t, mockProv.Store, store)
+	})
+
+	t.Run("test open store error", func(t *testing.T) {
+		mockProv := mockstorage.NewMockStoreProvider()
+		mockProv.ErrOpenStoreHandle = errors.New("open error")
+
+		_, err := NewProvider(mockProv).OpenStore("test")
+		require.EqualError(t, err, "open error")
+	})
+
+	t.Run("test close drops the cache", func(t *testing.T) {
+		mockProv := mockstorage.NewMockStoreProvider()
+		prov := NewProvider(mockProv)
+
+		store, err := prov.OpenStore("test")
+		require.NoError(t, err)
+		require.NoError(t, store.Put("k1", []byte("v1")))
+
+		require.NoError(t, prov.CloseStore("test"))
+		require.Empty(t, prov.stores)
+
+		_, err = prov.OpenStore("test")
+		require.NoError(t, err)
+		require.NoError(t, prov.Close())
+		require.Empty(t, prov.stores)
+
+		// nothing to invalidate
+		prov.Invalidate("test", "k1")
+	})
+
+	t.Run("test records cached in the cache provider", func(t *testing.T) {
+		caches := mem.NewProvider()
+		prov := NewProviderWithCache(mockstorage.NewMockStoreProvider(), caches)
+
+		store, err := prov.OpenStore("Test")
+		require.NoError(t, err)
+		require.NoError(t, store.Put("k1", []byte("v1")))
+
+		records, err := caches.OpenCache("test")
+		require.NoError(t, err)
+
+		v, err := records.Get("k1")
+		require.NoError(t, err)
+		require.Equal(t, []byte("v1"), v)
+
+		require.NoError(t, prov.CloseStore("test"))
+
+		records, err = caches.OpenCache("test")
+		require.NoError(t, err)
+
+		_, err = records.Get("k1")
+		require.Equal(t, cachespi.ErrNotFound, err)
+	})
+
+	t.Run("test open cache error", func(t *testing.T) {
+		_, err := NewProviderWithCache(mockstorage.NewMockStoreProvider(),
+			&failingCacheProvider{err: errors.New("cache error")}).OpenStore("test")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "cache error")
+	})
+
+	t.Run("test close cache error", func(t *testing.T) {
+		caches := &failingCacheProvider{Provider: mem.NewProvider()}
+		prov := NewProviderWithCache(mockstorage.NewMockStoreProvider(), caches)
+
+		_, err := prov.OpenStore("test")
+		require.NoError(t, err)
+
+		caches.closeErr = errors.New("cache error")
+		require.Error(t, prov.CloseStore("test"))
+
+		_, err = prov.OpenStore("test")
+		require.NoError(t, err)
+		require.Error(t, prov.Close())
+	})
+
+	t.Run("test compaction is delegated", func(t *testing.T) {
+		compactable := &compactableProvider{Provider: mockstorage.NewMockStoreProvider()}
+		require.NoError(t, NewProvider(compactable).Compact())
+		require.True(t, compactable.compacted)
+
+		compactable.err = errors.New("compact error")
+		require.EqualError(t, NewProvider(compactable).Compact(), "compact error")
+	})
+
+	t.Run("test compaction not supported by the underlying provider", func(t *testing.T) {
+		require.NoError(t, NewProvider(mockstorage.NewMockStoreProvider()).Compact())
+	})
+}
+
+type compactableProvider struct {
+	storage.Provider
+	compacted bool
+	err       error
+}
+
+func (p *compactableProvider) Compact() error {
+	p.compacted = true
+	return p.err
+}
+
+type failingCacheProvider struct {
+	cachespi.Provider
+	err      error
+	closeErr error
+}
+
+func (p *failingCacheProvider) OpenCache(name string) (cachespi.Cache, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+
+	return p.Provider.OpenCache(name)
+}
+
+func (p *failingCacheProvider) CloseCache(name string) error {
+	return p.closeErr
+}
@@ -0,0 +1,283 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package conformance checks that a storage provider honors the contract of the storage interfaces, in particular
+// the errors of the stores. A new storage provider runs the checks in its tests:
+//
+//	func TestConformance(t *testing.T) {
+//	    conformance.Run(t, NewProvider(...))
+//	}
+//
+// The stores must implement the optional interfaces (ConditionalStore, BatchStore, IterableStore, ExpiringStore):
+// a provider wrapping other providers must not hide them.
+package conformance
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hyperledger/aries-framework-go/pkg/storage"
+)
+
+// Run checks the provider, the provider is closed at the end
+func Run(t *testing.T, prov storage.Provider) {
+	t.Run("test put get", func(t *testing.T) {
+		testPutGet(t, prov)
+	})
+
+	t.Run("test delete", func(t *testing.T) {
+		testDelete(t, prov)
+	})
+
+	t.Run("test key required", func(t *testing.T) {
+		testKeyRequired(t, prov)
+	})
+
+	t.Run("test name spaces", func(t *testing.T) {
+		testNameSpaces(t, prov)
+	})
+
+	t.Run("test conditional put", func(t *testing.T) {
+		testConditionalPut(t, prov)
+	})
+
+	t.Run("test batch put", func(t *testing.T) {
+		testBatchPut(t, prov)
+	})
+
+	t.Run("test iterate", func(t *testing.T) {
+		testIterate(t, prov)
+	})
+
+	t.Run("test expiry", func(t *testing.T) {
+		testExpiry(t, prov)
+	})
+
+	t.Run("test close store", func(t *testing.T) {
+		testCloseStore(t, prov)
+	})
+
+	t.Run("test close", func(t *testing.T) {
+		testClose(t, prov)
+	})
+}
+
+func testPutGet(t *testing.T, prov storage.Provider) {
+	store := openStore(t, prov, "conformance_putget")
+
+	_, err := store.Get("k1")
+	requireErr(t, storage.ErrDataNotFound, err)
+
+	require.NoError(t, store.Put("k1", []byte("v1")))
+
+	v, err := store.Get("k1")
+	require.NoError(t, err)
+	require.Equal(t, []byte("v1"), v)
+
+	require.NoError(t, store.Put("k1", []byte("v2")))
+
+	v, err = store.Get("k1")
+	require.NoError(t, err)
+	require.Equal(t, []byte("v2"), v)
+
+	// the same store is opened for the name space
+	v, err = openStore(t, prov, "conformance_putget").Get("k1")
+	require.NoError(t, err)
+	require.Equal(t, []byte("v2"), v)
+}
+
+func testDelete(t *testing.T, prov storage.Provider) {
+	store := openStore(t, prov, "conformance_delete")
+
+	require.NoError(t, store.Put("k1", []byte("v1")))
+	require.NoError(t, store.Put("k2", []byte("v2")))
+	require.NoError(t, store.Delete("k1"))
+
+	_, err := store.Get("k1")
+	requireErr(t, storage.ErrDataNotFound, err)
+
+	_, err = store.Get("k2")
+	require.NoError(t, err)
+
+	// deleting a missing record isn't an error
+	require.NoError(t, store.Delete("k1"))
+
+	if expiring, ok := store.(storage.ExpiringStore); ok {
+		require.NoError(t, expiring.PutWithTTL("k3", []byte("v3"), time.Minute))
+		require.NoError(t, store.Delete("k3"))
+
+		_, err = expiring.TTL("k3")
+		requireErr(t, storage.ErrDataNotFound, err)
+	}
+}
+
+func testKeyRequired(t *testing.T, prov storage.Provider) {
+	store := openStore(t, prov, "conformance_key")
+
+	requireErr(t, storage.ErrKeyRequired, store.Put("", []byte("v1")))
+	requireErr(t, storage.ErrKeyRequired, store.Delete(""))
+
+	_, err := store.Get("")
+	requireErr(t, storage.ErrKeyRequired, err)
+
+	if conditional, ok := store.(storage.ConditionalStore); ok {
+		requireErr(t, storage.ErrKeyRequired, conditional.PutIf("", []byte("v1"), nil))
+	}
+
+	if expiring, ok := store.(storage.ExpiringStore); ok {
+		requireErr(t, storage.ErrKeyRequired, expiring.PutWithTTL("", []byte("v1"), time.Minute))
+	}
+}
+
+func testNameSpaces(t *testing.T, prov storage.Provider) {
+	store1 := openStore(t, prov, "conformance_ns1")
+	store2 := openStore(t, prov, "conformance_ns2")
+
+	require.NoError(t, store1.Put("k1", []byte("v1")))
+
+	_, err := store2.Get("k1")
+	requireErr(t, storage.ErrDataNotFound, err)
+}
+
+func testConditionalPut(t *testing.T, prov storage.Provider) {
+	conditional, ok := openStore(t, prov, "conformance_putif").(storage.ConditionalStore)
+	require.True(t, ok, "the store doesn't implement storage.ConditionalStore")
+
+	require.NoError(t, conditional.PutIf("k1", []byte("v1"), nil))
+	requireErr(t, storage.ErrConflict, conditional.PutIf("k1", []byte("v2"), nil))
+	requireErr(t, storage.ErrConflict, conditional.PutIf("k1", []byte("v2"), []byte("v0")))
+	require.NoError(t, conditional.PutIf("k1", []byte("v2"), []byte("v1")))
+}
+
+func testBatchPut(t *testing.T, prov storage.Provider) {
+	store := openStore(t, prov, "conformance_batch")
+
+	batch, ok := store.(storage.BatchStore)
+	require.True(t, ok, "the store doesn't implement storage.BatchStore")
+
+	require.NoError(t, store.Put("k1", []byte("v0")))
+	require.NoError(t, batch.PutAll(map[string][]byte{"k1": []byte("v1"), "k2": []byte("v2")}))
+
+	for k, expected := range map[string]string{"k1": "v1", "k2": "v2"} {
+		v, err := store.Get(k)
+		require.NoError(t, err)
+		require.Equal(t, []byte(expected), v)
+	}
+
+	// none of the records is stored if one of them is invalid
+	requireErr(t, storage.ErrKeyRequired, batch.PutAll(map[string][]byte{"k3": []byte("v3"), "": []byte("v")}))
+
+	_, err := store.Get("k3")
+	requireErr(t, storage.ErrDataNotFound, err)
+}
+
+func testIterate(t *testing.T, prov storage.Provider) {
+	store := openStore(t, prov, "conformance_iterate")
+
+	iterable, ok := store.(storage.IterableStore)
+	require.True(t, ok, "the store doesn't implement storage.IterableStore")
+
+	for _, k := range []string{"b_2", "a_1", "b_1", "c_1"} {
+		require.NoError(t, store.Put(k, []byte("v_"+k)))
+	}
+
+	var keys []string
+
+	err := iterable.Iterate("b_", func(k string, v []byte) error {
+		require.Equal(t, []byte("v_"+k), v)
+
+		keys = append(keys, k)
+
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"b_1", "b_2"}, keys)
+
+	errStop := errors.New("stop")
+
+	err = iterable.Iterate("", func(k string, v []byte) error {
+		return errStop
+	})
+	requireErr(t, errStop, err)
+}
+
+func testExpiry(t *testing.T, prov storage.Provider) {
+	store := openStore(t, prov, "conformance_expiry")
+
+	expiring, ok := store.(storage.ExpiringStore)
+	require.True(t, ok, "the store doesn't implement storage.ExpiringStore")
+
+	const ttl = 50 * time.Millisecond
+
+	require.NoError(t, expiring.PutWithTTL("k1", []byte("v1"), ttl))
+	require.NoError(t, store.Put("k2", []byte("v2")))
+
+	remaining, err := expiring.TTL("k1")
+	require.NoError(t, err)
+	require.True(t, remaining > 0 && remaining <= ttl, fmt.Sprintf("unexpected ttl %s", remaining))
+
+	remaining, err = expiring.TTL("k2")
+	require.NoError(t, err)
+	require.Zero(t, remaining)
+
+	time.Sleep(ttl)
+
+	_, err = store.Get("k1")
+	requireErr(t, storage.ErrDataNotFound, err)
+
+	_, err = expiring.TTL("k1")
+	requireErr(t, storage.ErrDataNotFound, err)
+
+	_, err = store.Get("k2")
+	require.NoError(t, err)
+}
+
+func testCloseStore(t *testing.T, prov storage.Provider) {
+	store := openStore(t, prov, "conformance_closestore")
+	require.NoError(t, store.Put("k1", []byte("v1")))
+
+	require.NoError(t, prov.CloseStore("conformance_closestore"))
+
+	_, err := store.Get("k1")
+	requireErr(t, storage.ErrStoreClosed, err)
+	requireErr(t, storage.ErrStoreClosed, store.Put("k1", []byte("v2")))
+	requireErr(t, storage.ErrStoreClosed, store.Delete("k1"))
+
+	// closing a store which isn't opened isn't an error
+	require.NoError(t, prov.CloseStore("conformance_closestore"))
+
+	// the records are kept
+	v, err := openStore(t, prov, "conformance_closestore").Get("k1")
+	require.NoError(t, err)
+	require.Equal(t, []byte("v1"), v)
+}
+
+func testClose(t *testing.T, prov storage.Provider) {
+	store := openStore(t, prov, "conformance_close")
+	require.NoError(t, store.Put("k1", []byte("v1")))
+
+	require.NoError(t, prov.Close())
+
+	_, err := store.Get("k1")
+	requireErr(t, storage.ErrStoreClosed, err)
+	requireErr(t, storage.ErrStoreClosed, store.Put("k1", []byte("v2")))
+}
+
+func openStore(t *testing.T, prov storage.Provider, name string) storage.Store {
+	store, err := prov.OpenStore(name)
+	require.NoError(t, err)
+	require.NotNil(t, store)
+
+	return store
+}
+
+func requireErr(t *testing.T, expected, err error) {
+	require.True(t, errors.Is(err, expected), fmt.Sprintf("expected error %q, got %v", expected, err))
+}
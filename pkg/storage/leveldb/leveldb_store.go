@@ -7,28 +7,69 @@ SPDX-License-Identifier: Apache-2.0
 package leveldb
 
 import (
+	"bytes"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
 
+	"github.com/hyperledger/aries-framework-go/pkg/common/clock"
 	"github.com/hyperledger/aries-framework-go/pkg/storage"
 )
 
-const pathPattern = "%s-%s"
+const (
+	pathPattern = "%s-%s"
+
+	// internalPrefix prefixes the keys of the records of the store itself, which aren't iterated
+	internalPrefix = "\x00"
+	// expiryPrefix prefixes the keys of the index of the records put with a ttl, used to purge them
+	expiryPrefix = internalPrefix + "expiry\x00"
+	// formatKey is the key of the format of the records, the stores written before the records had a header
+	// have no format
+	formatKey = internalPrefix + "format"
+
+	// recordFormat is the current format: a header byte, followed by the expiry time if the record expires,
+	// followed by the value
+	recordFormat    = 1
+	headerNoExpiry  = 0
+	headerExpiry    = 1
+	expiryTimeBytes = 8
+)
+
+var errValueRequired = errors.New("value is mandatory")
 
 // Provider leveldb implementation of storage.Provider interface
 type Provider struct {
 	dbPath string
 	dbs    map[string]*leveldbStore
 	lock   sync.RWMutex
+	clock  clock.Clock
+}
+
+// Opt configures the leveldb provider
+type Opt func(p *Provider)
+
+// WithClock sets the clock expiring the records put with a ttl, the system clock by default
+func WithClock(c clock.Clock) Opt {
+	return func(p *Provider) {
+		p.clock = c
+	}
 }
 
 // NewProvider instantiates Provider
-func NewProvider(dbPath string) (*Provider, error) {
-	return &Provider{dbs: make(map[string]*leveldbStore), dbPath: dbPath}, nil
+func NewProvider(dbPath string, opts ...Opt) (*Provider, error) {
+	p := &Provider{dbs: make(map[string]*leveldbStore), dbPath: dbPath, clock: clock.System()}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p, nil
 }
 
 // OpenStore opens and returns a store for given name space.
@@ -59,7 +100,11 @@ func (p *Provider) newLeveldbStore(name string) (*leveldbStore, error) {
 		return nil, err
 	}
 
-	store := &leveldbStore{db}
+	store := &leveldbStore{db: db, clock: p.clock}
+	if err = store.upgrade(); err != nil {
+		return nil, fmt.Errorf("failed to upgrade store %s: %w", name, err)
+	}
+
 	p.dbs[strings.ToLower(name)] = store
 	return store, nil
 }
@@ -98,31 +143,390 @@ func (p *Provider) CloseStore(name string) error {
 	return nil
 }
 
+// Compact compacts the stores opened by the provider, the space of the overwritten records is reclaimed
+func (p *Provider) Compact() error {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	for name, store := range p.dbs {
+		if err := store.db.CompactRange(util.Range{}); err != nil {
+			return fmt.Errorf("failed to compact store %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// PurgeExpired deletes the expired records of the stores opened by the provider
+func (p *Provider) PurgeExpired() (int, error) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	purged := 0
+
+	for name, store := range p.dbs {
+		n, err := store.purgeExpired()
+		purged += n
+
+		if err != nil {
+			return purged, fmt.Errorf("failed to purge expired records of store %s: %w", name, err)
+		}
+	}
+
+	return purged, nil
+}
+
+// leveldbStore is a leveldb store, the expiry time of the records put with a ttl is stored in the header of the
+// record so the record is read once, and indexed under the expiry key of the record. The expired records are
+// filtered on read and deleted by PurgeExpired.
 type leveldbStore struct {
-	db *leveldb.DB
+	db    *leveldb.DB
+	lock  sync.Mutex
+	clock clock.Clock
 }
 
 // Put stores the key and the record
 func (s *leveldbStore) Put(k string, v []byte) error {
-	if k == "" || v == nil {
-		return errors.New("key and value are mandatory")
+	if err := checkRecord(k, v); err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.put(k, v, 0)
+}
+
+// PutWithTTL stores the key and the record expiring after the ttl
+func (s *leveldbStore) PutWithTTL(k string, v []byte, ttl time.Duration) error {
+	if err := checkRecord(k, v); err != nil {
+		return err
+	}
+
+	if ttl <= 0 {
+		return errors.New("ttl must be positive")
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.put(k, v, ttl)
+}
+
+// put writes the record and its expiry index atomically, the index is deleted if there is no ttl
+func (s *leveldbStore) put(k string, v []byte, ttl time.Duration) error {
+	var expiry time.Time
+	if ttl > 0 {
+		expiry = s.clock.Now().Add(ttl)
+	}
+
+	batch := new(leveldb.Batch)
+	putRecord(batch, k, v, expiry)
+
+	return wrapErr(s.db.Write(batch, nil))
+}
+
+// putRecord adds the record and its expiry index to the batch
+func putRecord(batch *leveldb.Batch, k string, v []byte, expiry time.Time) {
+	batch.Put([]byte(k), encodeRecord(v, expiry))
+
+	if expiry.IsZero() {
+		batch.Delete(expiryKey(k))
+		return
+	}
+
+	batch.Put(expiryKey(k), encodeTime(expiry))
+}
+
+// PutAll stores the records atomically, the expiry of the records is removed
+func (s *leveldbStore) PutAll(records map[string][]byte) error {
+	batch := new(leveldb.Batch)
+
+	for k, v := range records {
+		if err := checkRecord(k, v); err != nil {
+			return err
+		}
+
+		putRecord(batch, k, v, time.Time{})
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return wrapErr(s.db.Write(batch, nil))
+}
+
+// Delete removes the record and its expiry index
+func (s *leveldbStore) Delete(k string) error {
+	if k == "" {
+		return storage.ErrKeyRequired
 	}
 
-	return s.db.Put([]byte(k), v, nil)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	batch := new(leveldb.Batch)
+	batch.Delete([]byte(k))
+	batch.Delete(expiryKey(k))
+
+	return wrapErr(s.db.Write(batch, nil))
+}
+
+// PutIf stores the record if the current record is the expected one
+func (s *leveldbStore) PutIf(k string, v, expected []byte) error {
+	if err := checkRecord(k, v); err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	current, err := s.Get(k)
+	if err != nil && !errors.Is(err, storage.ErrDataNotFound) {
+		return err
+	}
+
+	if !bytes.Equal(current, expected) {
+		return storage.ErrConflict
+	}
+
+	return s.put(k, v, 0)
 }
 
 // Get fetches the record based on key
 func (s *leveldbStore) Get(k string) ([]byte, error) {
+	v, _, err := s.get(k)
+	return v, err
+}
+
+// get reads the record and its expiry time, the zero time if the record doesn't expire
+func (s *leveldbStore) get(k string) ([]byte, time.Time, error) {
 	if k == "" {
-		return nil, errors.New("key is mandatory")
+		return nil, time.Time{}, storage.ErrKeyRequired
 	}
 
 	data, err := s.db.Get([]byte(k), nil)
 	if err != nil {
-		if strings.Contains(err.Error(), "not found") {
-			return nil, storage.ErrDataNotFound
+		return nil, time.Time{}, wrapErr(err)
+	}
+
+	v, expiry, err := decodeRecord(data)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("record %s: %w", k, err)
+	}
+
+	if s.expired(expiry) {
+		return nil, time.Time{}, storage.ErrDataNotFound
+	}
+
+	return v, expiry, nil
+}
+
+// Iterate calls fn with the records whose key starts with the prefix in key order
+func (s *leveldbStore) Iterate(prefix string, fn func(k string, v []byte) error) error {
+	iter := s.db.NewIterator(util.BytesPrefix([]byte(prefix)), nil)
+	defer iter.Release()
+
+	for iter.Next() {
+		k := string(iter.Key())
+		if strings.HasPrefix(k, internalPrefix) {
+			continue
 		}
-		return nil, err
+
+		v, expiry, err := decodeRecord(iter.Value())
+		if err != nil {
+			return fmt.Errorf("record %s: %w", k, err)
+		}
+
+		if s.expired(expiry) {
+			continue
+		}
+
+		// the iterator reuses its buffers, the value is copied so fn can keep it
+		if err = fn(k, append([]byte(nil), v...)); err != nil {
+			return err
+		}
+	}
+
+	return wrapErr(iter.Error())
+}
+
+// TTL returns the remaining time to live of the record
+func (s *leveldbStore) TTL(k string) (time.Duration, error) {
+	_, expiry, err := s.get(k)
+	if err != nil || expiry.IsZero() {
+		return 0, err
+	}
+
+	ttl := expiry.Sub(s.clock.Now())
+	if ttl <= 0 {
+		return 0, storage.ErrDataNotFound
+	}
+
+	return ttl, nil
+}
+
+// expired returns true if the expiry time of a record put with a ttl has elapsed
+func (s *leveldbStore) expired(expiry time.Time) bool {
+	return !expiry.IsZero() && !s.clock.Now().Before(expiry)
+}
+
+// upgrade adds the header to the records written before the records had a header. The records are rewritten
+// in a single batch with the format, so a crash can't leave the store with records of both formats.
+func (s *leveldbStore) upgrade() error {
+	_, err := s.db.Get([]byte(formatKey), nil)
+	if err == nil || !errors.Is(err, leveldb.ErrNotFound) {
+		return wrapErr(err)
+	}
+
+	batch := new(leveldb.Batch)
+	iter := s.db.NewIterator(nil, nil)
+
+	for iter.Next() {
+		k := string(iter.Key())
+		if strings.HasPrefix(k, internalPrefix) {
+			continue
+		}
+
+		var expiry time.Time
+
+		index, e := s.db.Get(expiryKey(k), nil)
+		if e == nil {
+			expiry = decodeTime(index)
+		} else if !errors.Is(e, leveldb.ErrNotFound) {
+			iter.Release()
+			return wrapErr(e)
+		}
+
+		batch.Put([]byte(k), encodeRecord(iter.Value(), expiry))
+	}
+
+	iter.Release()
+
+	if err = iter.Error(); err != nil {
+		return wrapErr(err)
+	}
+
+	batch.Put([]byte(formatKey), []byte{recordFormat})
+
+	return wrapErr(s.db.Write(batch, nil))
+}
+
+// purgeExpired deletes the expired records, the records put again since they expired are kept
+func (s *leveldbStore) purgeExpired() (int, error) {
+	var keys []string
+
+	iter := s.db.NewIterator(util.BytesPrefix([]byte(expiryPrefix)), nil)
+
+	for iter.Next() {
+		k := strings.TrimPrefix(string(iter.Key()), expiryPrefix)
+		if s.expired(decodeTime(iter.Value())) {
+			keys = append(keys, k)
+		}
+	}
+
+	iter.Release()
+
+	if err := iter.Error(); err != nil {
+		return 0, wrapErr(err)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	purged := 0
+
+	for _, k := range keys {
+		_, _, err := s.get(k)
+		if err == nil {
+			continue
+		}
+
+		if !errors.Is(err, storage.ErrDataNotFound) {
+			return purged, err
+		}
+
+		batch := new(leveldb.Batch)
+		batch.Delete([]byte(k))
+		batch.Delete(expiryKey(k))
+
+		if err = s.db.Write(batch, nil); err != nil {
+			return purged, wrapErr(err)
+		}
+
+		purged++
+	}
+
+	return purged, nil
+}
+
+// checkRecord checks the key and the value of the record to put
+func checkRecord(k string, v []byte) error {
+	if k == "" {
+		return storage.ErrKeyRequired
+	}
+
+	if v == nil {
+		return errValueRequired
+	}
+
+	return nil
+}
+
+// wrapErr maps the leveldb errors to the storage errors
+func wrapErr(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, leveldb.ErrNotFound):
+		return storage.ErrDataNotFound
+	case errors.Is(err, leveldb.ErrClosed):
+		return fmt.Errorf("leveldb: %w", storage.ErrStoreClosed)
+	default:
+		return fmt.Errorf("leveldb: %w", err)
 	}
-	return data, nil
+}
+
+func expiryKey(k string) []byte {
+	return []byte(expiryPrefix + k)
+}
+
+// encodeRecord prefixes the value with the header of the record
+func encodeRecord(v []byte, expiry time.Time) []byte {
+	if expiry.IsZero() {
+		return append([]byte{headerNoExpiry}, v...)
+	}
+
+	return append(append([]byte{headerExpiry}, encodeTime(expiry)...), v...)
+}
+
+// decodeRecord returns the value and the expiry time of the record, the zero time if it doesn't expire
+func decodeRecord(data []byte) ([]byte, time.Time, error) {
+	if len(data) == 0 {
+		return nil, time.Time{}, errors.New("missing record header")
+	}
+
+	switch data[0] {
+	case headerNoExpiry:
+		return data[1:], time.Time{}, nil
+	case headerExpiry:
+		if len(data) < 1+expiryTimeBytes {
+			return nil, time.Time{}, errors.New("truncated record expiry")
+		}
+
+		return data[1+expiryTimeBytes:], decodeTime(data[1 : 1+expiryTimeBytes]), nil
+	default:
+		return nil, time.Time{}, fmt.Errorf("unknown record header %d", data[0])
+	}
+}
+
+func encodeTime(t time.Time) []byte {
+	b := make([]byte, expiryTimeBytes)
+	binary.BigEndian.PutUint64(b, uint64(t.UnixNano()))
+
+	return b
+}
+
+func decodeTime(b []byte) time.Time {
+	return time.Unix(0, int64(binary.BigEndian.Uint64(b)))
 }
@@ -7,14 +7,20 @@ SPDX-License-Identifier: Apache-2.0
 package leveldb
 
 import (
+	"errors"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
+	"github.com/syndtr/goleveldb/leveldb"
 
+	mockclock "github.com/hyperledger/aries-framework-go/pkg/internal/mock/clock"
 	"github.com/hyperledger/aries-framework-go/pkg/storage"
+	"github.com/hyperledger/aries-framework-go/pkg/storage/conformance"
 )
 
 func setupLevelDB(t testing.TB) (string, func()) {
@@ -128,6 +134,76 @@ func TestLevelDBStore(t *testing.T) {
 		require.Len(t, prov.dbs, 2)
 	})
 
+	t.Run("Test Leveldb store conditional put", func(t *testing.T) {
+		prov, err := NewProvider(path)
+		require.NoError(t, err)
+		store, err := prov.OpenStore("conditional")
+		require.NoError(t, err)
+
+		conditional, ok := store.(storage.ConditionalStore)
+		require.True(t, ok)
+
+		const key = "did:example:123"
+
+		// missing record
+		require.NoError(t, conditional.PutIf(key, []byte("value1"), nil))
+		require.Equal(t, storage.ErrConflict, conditional.PutIf(key, []byte("value2"), nil))
+
+		// modified record
+		require.NoError(t, conditional.PutIf(key, []byte("value2"), []byte("value1")))
+		require.Equal(t, storage.ErrConflict, conditional.PutIf(key, []byte("value3"), []byte("value1")))
+
+		doc, err := store.Get(key)
+		require.NoError(t, err)
+		require.Equal(t, []byte("value2"), doc)
+
+		// nil value
+		require.Error(t, conditional.PutIf(key, nil, []byte("value2")))
+
+		err = prov.Close()
+		require.NoError(t, err)
+
+		// try to put after provider is closed
+		require.Error(t, conditional.PutIf(key, []byte("value3"), []byte("value2")))
+	})
+
+	t.Run("Test Leveldb store iterate", func(t *testing.T) {
+		prov, err := NewProvider(path)
+		require.NoError(t, err)
+		store, err := prov.OpenStore("iterate")
+		require.NoError(t, err)
+
+		for _, k := range []string{"conn_2", "conn_1", "invitation_1"} {
+			require.NoError(t, store.Put(k, []byte("value-"+k)))
+		}
+
+		iterable, ok := store.(storage.IterableStore)
+		require.True(t, ok)
+
+		var keys []string
+
+		err = iterable.Iterate("conn_", func(k string, v []byte) error {
+			require.Equal(t, "value-"+k, string(v))
+			keys = append(keys, k)
+
+			// the records can be updated while iterated
+			return store.Put(k, []byte("updated"))
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{"conn_1", "conn_2"}, keys)
+
+		doc, err := store.Get("conn_1")
+		require.NoError(t, err)
+		require.Equal(t, []byte("updated"), doc)
+
+		err = iterable.Iterate("", func(k string, v []byte) error {
+			return errors.New("iterate error")
+		})
+		require.EqualError(t, err, "iterate error")
+
+		require.NoError(t, prov.Close())
+	})
+
 	t.Run("Test Leveldb store failures", func(t *testing.T) {
 		// pass file instead of directory for leveldb
 		file, err := ioutil.TempFile("", "leveldb.txt*-sample")
@@ -207,6 +283,149 @@ func TestLevelDBStore(t *testing.T) {
 		err = prov.Close()
 		require.NoError(t, err)
 	})
+
+	t.Run("Test Leveldb records expiry", func(t *testing.T) {
+		clock := &mockclock.Clock{Time: time.Now()}
+		prov, err := NewProvider(path, WithClock(clock))
+		require.NoError(t, err)
+
+		store, err := prov.OpenStore("expiry")
+		require.NoError(t, err)
+
+		expiring, ok := store.(storage.ExpiringStore)
+		require.True(t, ok)
+
+		require.NoError(t, expiring.PutWithTTL("k1", []byte("v1"), time.Minute))
+		require.NoError(t, expiring.PutWithTTL("k2", []byte("v2"), time.Hour))
+		require.NoError(t, store.Put("k3", []byte("v3")))
+
+		ttl, err := expiring.TTL("k1")
+		require.NoError(t, err)
+		require.Equal(t, time.Minute, ttl)
+		ttl, err = expiring.TTL("k3")
+		require.NoError(t, err)
+		require.Zero(t, ttl)
+
+		clock.Add(time.Minute)
+
+		_, err = store.Get("k1")
+		require.True(t, errors.Is(err, storage.ErrDataNotFound))
+		_, err = expiring.TTL("k1")
+		require.True(t, errors.Is(err, storage.ErrDataNotFound))
+
+		var keys []string
+
+		require.NoError(t, store.(storage.IterableStore).Iterate("", func(k string, v []byte) error {
+			keys = append(keys, k)
+			return nil
+		}))
+		require.Equal(t, []string{"k2", "k3"}, keys)
+
+		// putting the record without ttl removes its expiry
+		require.NoError(t, store.Put("k2", []byte("v2")))
+		clock.Add(time.Hour)
+
+		v, err := store.Get("k2")
+		require.NoError(t, err)
+		require.Equal(t, []byte("v2"), v)
+
+		require.NoError(t, expiring.PutWithTTL("k4", []byte("v4"), time.Minute))
+
+		purged, err := prov.PurgeExpired()
+		require.NoError(t, err)
+		require.Equal(t, 1, purged)
+
+		purged, err = prov.PurgeExpired()
+		require.NoError(t, err)
+		require.Zero(t, purged)
+
+		_, err = store.(*leveldbStore).db.Get([]byte("k1"), nil)
+		require.Error(t, err)
+
+		require.Error(t, expiring.PutWithTTL("k5", []byte("v5"), 0))
+		require.Error(t, expiring.PutWithTTL("", []byte("v5"), time.Minute))
+
+		require.NoError(t, prov.Close())
+	})
+
+	t.Run("Test Leveldb upgrade of records without header", func(t *testing.T) {
+		db, err := leveldb.OpenFile(fmt.Sprintf(pathPattern, path, "legacy"), nil)
+		require.NoError(t, err)
+
+		expiry := time.Now().Add(time.Hour)
+		require.NoError(t, db.Put([]byte("k1"), []byte("v1"), nil))
+		require.NoError(t, db.Put([]byte("k2"), []byte("v2"), nil))
+		require.NoError(t, db.Put(expiryKey("k2"), encodeTime(expiry), nil))
+		require.NoError(t, db.Put(expiryKey("k3"), encodeTime(expiry), nil))
+		require.NoError(t, db.Close())
+
+		prov, err := NewProvider(path)
+		require.NoError(t, err)
+
+		store, err := prov.OpenStore("legacy")
+		require.NoError(t, err)
+
+		v, err := store.Get("k1")
+		require.NoError(t, err)
+		require.Equal(t, []byte("v1"), v)
+
+		v, err = store.Get("k2")
+		require.NoError(t, err)
+		require.Equal(t, []byte("v2"), v)
+
+		ttl, err := store.(storage.ExpiringStore).TTL("k2")
+		require.NoError(t, err)
+		require.True(t, ttl > 0 && ttl <= time.Hour)
+
+		// the records are upgraded once
+		require.NoError(t, prov.CloseStore("legacy"))
+
+		store, err = prov.OpenStore("legacy")
+		require.NoError(t, err)
+
+		v, err = store.Get("k1")
+		require.NoError(t, err)
+		require.Equal(t, []byte("v1"), v)
+
+		require.NoError(t, store.(*leveldbStore).db.Put([]byte("k4"), []byte{9}, nil))
+		_, err = store.Get("k4")
+		require.EqualError(t, err, "record k4: unknown record header 9")
+
+		require.NoError(t, prov.Close())
+	})
+
+	t.Run("Test Leveldb compaction", func(t *testing.T) {
+		prov, err := NewProvider(path)
+		require.NoError(t, err)
+
+		store, err := prov.OpenStore("compact")
+		require.NoError(t, err)
+
+		for i := 0; i < 10; i++ {
+			require.NoError(t, store.Put("key", []byte(strings.Repeat("v", i))))
+		}
+
+		require.NoError(t, prov.Compact())
+
+		doc, err := store.Get("key")
+		require.NoError(t, err)
+		require.Equal(t, []byte(strings.Repeat("v", 9)), doc)
+
+		require.NoError(t, prov.Close())
+
+		// nothing to compact
+		require.NoError(t, prov.Compact())
+	})
+}
+
+func TestConformance(t *testing.T) {
+	path, cleanup := setupLevelDB(t)
+	defer cleanup()
+
+	prov, err := NewProvider(path)
+	require.NoError(t, err)
+
+	conformance.Run(t, prov)
 }
 
 func cleanupFile(t *testing.T, file *os.File) {
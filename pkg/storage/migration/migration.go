@@ -0,0 +1,216 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package migration upgrades the records of the stores to the current record formats. The migration steps are
+// registered per store namespace with increasing versions, the steps not applied yet are applied in order on
+// startup and the version of each namespace is recorded in the migrations meta store.
+package migration
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/common/log"
+	"github.com/hyperledger/aries-framework-go/pkg/storage"
+)
+
+var logger = log.New("aries-framework/storage/migration")
+
+// MetaStoreName is the namespace of the store recording the versions of the namespaces
+const MetaStoreName = "migrations"
+
+// ErrVersionAhead is returned when the recorded version of a namespace is newer than its latest step, e.g. the
+// database was upgraded by a newer version of the agent
+var ErrVersionAhead = errors.New("namespace version ahead of the migration steps")
+
+// Step upgrades the records of a namespace to the version of the step. The steps must be idempotent: a step is
+// applied again on the next startup if the agent stops before the version is recorded.
+type Step struct {
+	// Version is the version of the namespace once the step applied, starting at 1
+	Version int
+	// Description describes the changes of the record format
+	Description string
+	// Migrate upgrades the records of the store of the namespace
+	Migrate func(store storage.Store) error
+}
+
+// record is the record of the version of a namespace in the meta store
+type record struct {
+	Version     int       `json:"version"`
+	Description string    `json:"description,omitempty"`
+	MigratedAt  time.Time `json:"migratedAt"`
+}
+
+// Migrator applies the migration steps to the stores of the provider
+type Migrator struct {
+	provider storage.Provider
+	meta     storage.Store
+	steps    map[string][]Step
+	lock     sync.Mutex
+}
+
+// New returns a migrator of the stores of the provider
+func New(provider storage.Provider) (*Migrator, error) {
+	meta, err := provider.OpenStore(MetaStoreName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open migrations store: %w", err)
+	}
+
+	return &Migrator{provider: provider, meta: meta, steps: make(map[string][]Step)}, nil
+}
+
+// Register registers the migration steps of the namespace, the versions must be positive and unique
+func (m *Migrator) Register(namespace string, steps ...Step) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	registered := append(append([]Step(nil), m.steps[namespace]...), steps...)
+
+	sort.SliceStable(registered, func(i, j int) bool {
+		return registered[i].Version < registered[j].Version
+	})
+
+	for i, step := range registered {
+		if step.Version < 1 || step.Migrate == nil {
+			return fmt.Errorf("invalid migration step %d of namespace %s", step.Version, namespace)
+		}
+
+		if i > 0 && registered[i-1].Version == step.Version {
+			return fmt.Errorf("duplicate migration step %d of namespace %s", step.Version, namespace)
+		}
+	}
+
+	m.steps[namespace] = registered
+
+	return nil
+}
+
+// Version returns the version of the namespace recorded in the meta store, zero if never migrated
+func (m *Migrator) Version(namespace string) (int, error) {
+	r, _, err := m.record(namespace)
+	if err != nil {
+		return 0, err
+	}
+
+	return r.Version, nil
+}
+
+// Migrate applies the steps not applied yet to the namespaces, in namespace order then version order
+func (m *Migrator) Migrate() error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	namespaces := make([]string, 0, len(m.steps))
+	for namespace := range m.steps {
+		namespaces = append(namespaces, namespace)
+	}
+
+	sort.Strings(namespaces)
+
+	for _, namespace := range namespaces {
+		if err := m.migrate(namespace, m.steps[namespace]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *Migrator) migrate(namespace string, steps []Step) error {
+	current, raw, err := m.record(namespace)
+	if err != nil {
+		return err
+	}
+
+	if latest := steps[len(steps)-1].Version; current.Version > latest {
+		return fmt.Errorf("%w: %s at version %d, latest step %d", ErrVersionAhead, namespace, current.Version, latest)
+	}
+
+	var store storage.Store
+
+	for _, step := range steps {
+		if step.Version <= current.Version {
+			continue
+		}
+
+		if store == nil {
+			store, err = m.provider.OpenStore(namespace)
+			if err != nil {
+				return fmt.Errorf("failed to open store %s: %w", namespace, err)
+			}
+		}
+
+		logger.Infof("migrating %s to version %d: %s", namespace, step.Version, step.Description)
+
+		if err = step.Migrate(store); err != nil {
+			return fmt.Errorf("migration of %s to version %d failed: %w", namespace, step.Version, err)
+		}
+
+		current = &record{Version: step.Version, Description: step.Description, MigratedAt: time.Now()}
+
+		raw, err = m.save(namespace, current, raw)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// record returns the version record of the namespace and its raw bytes, nil if never migrated
+func (m *Migrator) record(namespace string) (*record, []byte, error) {
+	raw, err := m.meta.Get(namespace)
+	if errors.Is(err, storage.ErrDataNotFound) {
+		return &record{}, nil, nil
+	}
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get version of %s: %w", namespace, err)
+	}
+
+	r := &record{}
+	if err = json.Unmarshal(raw, r); err != nil {
+		return nil, nil, fmt.Errorf("failed to unmarshal version of %s: %w", namespace, err)
+	}
+
+	return r, raw, nil
+}
+
+// save records the version of the namespace. The meta stores supporting the conditional puts reject the record
+// if another instance sharing the database migrated the namespace meanwhile.
+func (m *Migrator) save(namespace string, r *record, previous []byte) ([]byte, error) {
+	raw, err := json.Marshal(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal version of %s: %w", namespace, err)
+	}
+
+	if conditional, ok := m.meta.(storage.ConditionalStore); ok {
+		err = conditional.PutIf(namespace, raw, previous)
+	} else {
+		err = m.meta.Put(namespace, raw)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to save version %d of %s: %w", r.Version, namespace, err)
+	}
+
+	return raw, nil
+}
+
+// ForEach calls fn with the records of the store whose key starts with the prefix, e.g. to rewrite the records
+// of a step in the new format. The store must implement storage.IterableStore.
+func ForEach(store storage.Store, prefix string, fn func(k string, v []byte) error) error {
+	iterable, ok := store.(storage.IterableStore)
+	if !ok {
+		return fmt.Errorf("store %T can't iterate its records", store)
+	}
+
+	return iterable.Iterate(prefix, fn)
+}
@@ -0,0 +1,232 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package migration
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	mockstorage "github.com/hyperledger/aries-framework-go/pkg/internal/mock/storage"
+	"github.com/hyperledger/aries-framework-go/pkg/storage"
+)
+
+// namespaceProvider opens a mock store per namespace
+type namespaceProvider struct {
+	stores  map[string]*mockstorage.MockStore
+	openErr error
+}
+
+func newNamespaceProvider() *namespaceProvider {
+	return &namespaceProvider{stores: make(map[string]*mockstorage.MockStore)}
+}
+
+func (p *namespaceProvider) OpenStore(name string) (storage.Store, error) {
+	if p.openErr != nil {
+		return nil, p.openErr
+	}
+
+	if _, ok := p.stores[name]; !ok {
+		p.stores[name] = &mockstorage.MockStore{Store: make(map[string][]byte)}
+	}
+
+	return p.stores[name], nil
+}
+
+func (p *namespaceProvider) CloseStore(name string) error {
+	return nil
+}
+
+func (p *namespaceProvider) Close() error {
+	return nil
+}
+
+// renameField is a migration step renaming the field of the JSON records of the prefix
+func renameField(prefix, from, to string) func(store storage.Store) error {
+	return func(store storage.Store) error {
+		return ForEach(store, prefix, func(k string, v []byte) error {
+			r := make(map[string]interface{})
+			if err := json.Unmarshal(v, &r); err != nil {
+				return err
+			}
+
+			if value, ok := r[from]; ok {
+				r[to] = value
+				delete(r, from)
+			}
+
+			raw, err := json.Marshal(r)
+			if err != nil {
+				return err
+			}
+
+			return store.Put(k, raw)
+		})
+	}
+}
+
+func TestMigrator(t *testing.T) {
+	t.Run("test steps applied in version order", func(t *testing.T) {
+		prov := newNamespaceProvider()
+		store, err := prov.OpenStore("didexchange")
+		require.NoError(t, err)
+		require.NoError(t, store.Put("conn_1", []byte(`{"theirDid":"did:example:1"}`)))
+
+		m, err := New(prov)
+		require.NoError(t, err)
+
+		var applied []int
+
+		require.NoError(t, m.Register("didexchange",
+			Step{Version: 2, Description: "rename theirDID", Migrate: func(s storage.Store) error {
+				applied = append(applied, 2)
+				return renameField("conn_", "theirDID", "TheirDID")(s)
+			}},
+			Step{Version: 1, Description: "rename theirDid", Migrate: func(s storage.Store) error {
+				applied = append(applied, 1)
+				return renameField("conn_", "theirDid", "theirDID")(s)
+			}}))
+
+		require.NoError(t, m.Migrate())
+		require.Equal(t, []int{1, 2}, applied)
+
+		v, err := store.Get("conn_1")
+		require.NoError(t, err)
+		require.JSONEq(t, `{"TheirDID":"did:example:1"}`, string(v))
+
+		version, err := m.Version("didexchange")
+		require.NoError(t, err)
+		require.Equal(t, 2, version)
+
+		// the steps already applied are skipped, e.g. on the next startup
+		m, err = New(prov)
+		require.NoError(t, err)
+
+		require.NoError(t, m.Register("didexchange", Step{Version: 1, Migrate: func(s storage.Store) error {
+			return errors.New("applied again")
+		}}, Step{Version: 3, Migrate: func(s storage.Store) error {
+			applied = append(applied, 3)
+			return nil
+		}}))
+		require.NoError(t, m.Migrate())
+		require.Equal(t, []int{1, 2, 3}, applied)
+	})
+
+	t.Run("test namespace never migrated", func(t *testing.T) {
+		m, err := New(newNamespaceProvider())
+		require.NoError(t, err)
+
+		version, err := m.Version("didexchange")
+		require.NoError(t, err)
+		require.Zero(t, version)
+	})
+
+	t.Run("test version recorded up to the failed step", func(t *testing.T) {
+		m, err := New(newNamespaceProvider())
+		require.NoError(t, err)
+
+		require.NoError(t, m.Register("credentials",
+			Step{Version: 1, Migrate: func(s storage.Store) error { return nil }},
+			Step{Version: 2, Migrate: func(s storage.Store) error { return errors.New("step error") }}))
+
+		err = m.Migrate()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "step error")
+
+		version, err := m.Version("credentials")
+		require.NoError(t, err)
+		require.Equal(t, 1, version)
+	})
+
+	t.Run("test version ahead of the steps", func(t *testing.T) {
+		prov := newNamespaceProvider()
+
+		m, err := New(prov)
+		require.NoError(t, err)
+		require.NoError(t, m.Register("credentials", Step{Version: 2, Migrate: func(s storage.Store) error {
+			return nil
+		}}))
+		require.NoError(t, m.Migrate())
+
+		m, err = New(prov)
+		require.NoError(t, err)
+		require.NoError(t, m.Register("credentials", Step{Version: 1, Migrate: func(s storage.Store) error {
+			return nil
+		}}))
+		require.True(t, errors.Is(m.Migrate(), ErrVersionAhead))
+	})
+
+	t.Run("test invalid steps", func(t *testing.T) {
+		m, err := New(newNamespaceProvider())
+		require.NoError(t, err)
+
+		noop := func(s storage.Store) error { return nil }
+
+		require.Error(t, m.Register("ns", Step{Version: 0, Migrate: noop}))
+		require.Error(t, m.Register("ns", Step{Version: 1}))
+		require.Error(t, m.Register("ns", Step{Version: 1, Migrate: noop}, Step{Version: 1, Migrate: noop}))
+
+		require.NoError(t, m.Register("ns", Step{Version: 1, Migrate: noop}))
+		require.Error(t, m.Register("ns", Step{Version: 1, Migrate: noop}))
+	})
+
+	t.Run("test store errors", func(t *testing.T) {
+		_, err := New(&namespaceProvider{openErr: errors.New("open error")})
+		require.Error(t, err)
+
+		prov := newNamespaceProvider()
+		m, err := New(prov)
+		require.NoError(t, err)
+
+		require.NoError(t, m.Register("ns", Step{Version: 1, Migrate: func(s storage.Store) error { return nil }}))
+
+		prov.stores[MetaStoreName].Store["ns"] = []byte("{")
+		require.Error(t, m.Migrate())
+
+		prov.stores[MetaStoreName].ErrGet = errors.New("get error")
+		_, err = m.Version("ns")
+		require.Error(t, err)
+
+		prov.stores[MetaStoreName].ErrGet = nil
+		delete(prov.stores[MetaStoreName].Store, "ns")
+		prov.openErr = errors.New("open error")
+		require.Error(t, m.Migrate())
+
+		prov.openErr = nil
+		prov.stores[MetaStoreName].ErrPut = errors.New("put error")
+		require.Error(t, m.Migrate())
+	})
+
+	t.Run("test concurrent migration rejected", func(t *testing.T) {
+		prov := newNamespaceProvider()
+		m, err := New(prov)
+		require.NoError(t, err)
+
+		require.NoError(t, m.Register("ns", Step{Version: 1, Migrate: func(s storage.Store) error {
+			// another instance records the version meanwhile
+			return prov.stores[MetaStoreName].Put("ns", []byte(`{"version":1}`))
+		}}))
+
+		err = m.Migrate()
+		require.True(t, errors.Is(err, storage.ErrConflict))
+	})
+}
+
+func TestForEach(t *testing.T) {
+	t.Run("test store not iterable", func(t *testing.T) {
+		err := ForEach(&struct{ storage.Store }{}, "", func(k string, v []byte) error { return nil })
+		require.Error(t, err)
+	})
+
+	t.Run("test iterate error", func(t *testing.T) {
+		err := ForEach(&mockstorage.MockStore{ErrIterate: errors.New("iterate error")}, "",
+			func(k string, v []byte) error { return nil })
+		require.EqualError(t, err, "iterate error")
+	})
+}
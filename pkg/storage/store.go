@@ -6,11 +6,23 @@ SPDX-License-Identifier: Apache-2.0
 
 package storage
 
-import "errors"
+import (
+	"errors"
+	"time"
+)
 
 // ErrDataNotFound is returned when data not found
 var ErrDataNotFound = errors.New("data not found")
 
+// ErrStoreClosed is returned by the operations of a store which has been closed
+var ErrStoreClosed = errors.New("store closed")
+
+// ErrKeyRequired is returned when the key is empty
+var ErrKeyRequired = errors.New("key is mandatory")
+
+// ErrConflict is returned by the conditional put when the record has been modified since it was read
+var ErrConflict = errors.New("record has been modified")
+
 // Provider storage provider interface
 type Provider interface {
 	// OpenStore opens a store with given name space and returns the handle
@@ -23,11 +35,66 @@ type Provider interface {
 	Close() error
 }
 
-// Store is the storage interface
+// CompactableProvider is optionally implemented by the storage providers able to reclaim the space of the
+// overwritten records, e.g. the compaction of the LevelDB stores or the VACUUM of the SQL databases
+type CompactableProvider interface {
+	// Compact compacts the stores opened by the provider
+	Compact() error
+}
+
+// ExpiringProvider is optionally implemented by the storage providers whose stores expire the records lazily:
+// the expired records aren't returned anymore but still use space until they are purged, typically by the
+// storage maintenance
+type ExpiringProvider interface {
+	// PurgeExpired deletes the expired records of the stores opened by the provider, returns the number of
+	// deleted records
+	PurgeExpired() (int, error)
+}
+
+// Store is the storage interface. The errors of the stores wrap ErrDataNotFound, ErrKeyRequired and ErrStoreClosed
+// so the callers check them with errors.Is rather than matching the messages of the backends, see the conformance
+// package for the tests of the contract.
 type Store interface {
 	// Put stores the key and the record
 	Put(k string, v []byte) error
 
 	// Get fetches the record based on key
 	Get(k string) ([]byte, error)
+
+	// Delete removes the record, deleting a missing record isn't an error
+	Delete(k string) error
+}
+
+// ConditionalStore is optionally implemented by the stores supporting optimistic concurrency, it allows
+// multiple instances sharing the store to update the same records
+type ConditionalStore interface {
+	// PutIf stores the record if the current record is the expected one, nil expected record matches
+	// missing records. ErrConflict is returned if the record has been modified.
+	PutIf(k string, v, expected []byte) error
+}
+
+// IterableStore is optionally implemented by the stores able to list their records, e.g. to migrate the records
+// to a new format
+type IterableStore interface {
+	// Iterate calls fn with the records whose key starts with the prefix in key order, the iteration stops at the
+	// first error returned by fn. The records put by fn may not be iterated.
+	Iterate(prefix string, fn func(k string, v []byte) error) error
+}
+
+// BatchStore is optionally implemented by the stores able to put several records atomically, e.g. the records
+// referencing each other which must not be left dangling by a crash
+type BatchStore interface {
+	// PutAll stores the records atomically: either all the records are stored or none
+	PutAll(records map[string][]byte) error
+}
+
+// ExpiringStore is optionally implemented by the stores able to expire their records, e.g. for the transient
+// protocol states or the nonces which are only kept for a limited time
+type ExpiringStore interface {
+	// PutWithTTL stores the key and the record, the record expires after the ttl: ErrDataNotFound is returned
+	// once expired. Putting the record again without ttl removes its expiry.
+	PutWithTTL(k string, v []byte, ttl time.Duration) error
+
+	// TTL returns the remaining time to live of the record, zero if the record doesn't expire
+	TTL(k string) (time.Duration, error)
 }
@@ -8,8 +8,11 @@ package wallet
 
 import (
 	"errors"
+	"io"
 
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/crypto"
 	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	"github.com/hyperledger/aries-framework-go/pkg/framework/vdr"
 )
 
 // Wallet interface
@@ -63,12 +66,24 @@ type Crypto interface {
 	//
 	// toVerKey:The verification key of the recipient.
 	//
-	// []byte: Decrypted message content
+	// Returns:
 	//
-	// string: The sender verification key
+	// DecryptedMessage: Decrypted message content, sender verification key and authentication mode
 	//
 	// error: error
-	DecryptMessage(encMessage []byte, toVerKey string) ([]byte, string, error)
+	DecryptMessage(encMessage []byte, toVerKey string) (*DecryptedMessage, error)
+}
+
+// PreHashSigner is implemented by the wallets signing pre-hashed messages with Ed25519ph (RFC 8032), so large
+// payloads are signed without being loaded in memory. The signatures are verified with ed25519ph.Verify.
+type PreHashSigner interface {
+	// SignDigest signs the SHA-512 digest of a message using the private key associated with a given
+	// verification key.
+	SignDigest(digest []byte, fromVerKey string) ([]byte, error)
+
+	// SignStream signs the message read from the reader using the private key associated with a given
+	// verification key, the message is digested as it is read.
+	SignStream(r io.Reader, fromVerKey string) ([]byte, error)
 }
 
 // Pack provide methods to pack and unpack msg
@@ -124,11 +139,42 @@ type Envelope struct {
 	FromVerKey string
 	// TODO add key type - issue #272
 	ToVerKeys []string
+	// EncryptionAlgs are the content encryption algorithms supported by the recipients, the best
+	// mutually supported algorithm is used to pack the message. Unpacked envelopes hold the algorithm
+	// used by the sender.
+	EncryptionAlgs []string
+	// Compression is the payload compression accepted by the recipients, the payload is compressed only if
+	// the wallet compresses the payloads with the same compression
+	Compression string
+	// Format is the format of the unpacked envelopes
+	Format crypto.Format
+}
+
+// AuthMode tells whether the sender of an envelope is authenticated
+type AuthMode int
+
+const (
+	// AnonCrypt envelopes don't authenticate the sender
+	AnonCrypt AuthMode = iota
+	// AuthCrypt envelopes authenticate the sender with the sender verification key
+	AuthCrypt
+)
+
+// DecryptedMessage is the message decrypted from an envelope
+type DecryptedMessage struct {
+	Message []byte
+	// SenderVerKey is the sender verification key of authcrypted envelopes
+	SenderVerKey string
+	// Mode tells whether the sender is authenticated, envelopes of crypters not exposing the sender
+	// key are reported as anoncrypted
+	Mode AuthMode
 }
 
 // createDIDOpts holds the options for creating DID
 type createDIDOpts struct {
 	serviceType string
+	public      bool
+	vdrOpts     []vdr.CreateOpt
 }
 
 // DocOpts is a create DID option
@@ -141,5 +187,13 @@ func WithServiceType(serviceType string) DocOpts {
 	}
 }
 
+// WithPublic publishes the created DID document through the VDR registry of the DID method
+func WithPublic(vdrOpts ...vdr.CreateOpt) DocOpts {
+	return func(opts *createDIDOpts) {
+		opts.public = true
+		opts.vdrOpts = append(opts.vdrOpts, vdrOpts...)
+	}
+}
+
 // ErrKeyNotFound is returned when key not found
 var ErrKeyNotFound = errors.New("key not found")
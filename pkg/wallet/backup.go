@@ -0,0 +1,348 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package wallet
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/btcsuite/btcutil/base58"
+	"golang.org/x/crypto/curve25519"
+	"golang.org/x/crypto/nacl/box"
+
+	"github.com/hyperledger/aries-framework-go/pkg/common/clock"
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/crypto"
+	"github.com/hyperledger/aries-framework-go/pkg/storage"
+)
+
+const (
+	dataKeySize  = 32
+	boxNonceSize = 24
+)
+
+// ErrBackupKeyMismatch is returned when a key backup is restored with the key encryption key of other backups
+var ErrBackupKeyMismatch = errors.New("key backup encrypted with another key")
+
+// KeyEncrypter encrypts the data keys of the key backups with a key encryption key held outside of the device,
+// e.g. the public key of an offline recovery key pair or a key of a cloud KMS
+type KeyEncrypter interface {
+	// KeyEncryptionKeyID identifies the key encryption key, it is recorded in the backups
+	KeyEncryptionKeyID() string
+	// EncryptKey encrypts the data key
+	EncryptKey(dataKey []byte) ([]byte, error)
+}
+
+// KeyDecrypter decrypts the data keys of the key backups to restore the keys
+type KeyDecrypter interface {
+	// DecryptKey decrypts the data key encrypted with the key encryption key, ErrBackupKeyMismatch is returned if
+	// the decrypter doesn't hold the key encryption key
+	DecryptKey(kekID string, encryptedKey []byte) ([]byte, error)
+}
+
+// KMSClient encrypts and decrypts with the keys held by a cloud KMS, e.g. an adapter of the AWS KMS
+// or Google Cloud KMS clients
+type KMSClient interface {
+	Encrypt(keyID string, plaintext []byte) ([]byte, error)
+	Decrypt(keyID string, ciphertext []byte) ([]byte, error)
+}
+
+// KeyBackupConfig configures the automatic backup of the private keys created or imported by the wallet
+type KeyBackupConfig struct {
+	// Encrypter encrypts the data keys of the backups
+	Encrypter KeyEncrypter
+	// Store receives the backups keyed by key ID, e.g. a store replicated off the device. The store is dedicated
+	// to the backups to restore them all.
+	Store storage.Store
+}
+
+// KeyBackup is the envelope encrypted backup of a private key: the private key is encrypted with a random data
+// key (AES-256-GCM) and the data key is encrypted with the key encryption key
+type KeyBackup struct {
+	KeyID        string    `json:"kid"`
+	Type         KeyType   `json:"type"`
+	KEKID        string    `json:"kek"`
+	EncryptedKey []byte    `json:"encryptedKey"`
+	Nonce        []byte    `json:"nonce"`
+	Ciphertext   []byte    `json:"ciphertext"`
+	Created      time.Time `json:"created"`
+}
+
+// KeyRestorer is implemented by the wallets restoring their keys from the key backups, e.g. on a new device
+type KeyRestorer interface {
+	// RestoreKeys restores the keys backed up in the store, the IDs of the restored keys are returned
+	RestoreKeys(store storage.Store, decrypter KeyDecrypter) ([]string, error)
+}
+
+// NewKeyBackup encrypts the private key of the key type with a new data key encrypted by the encrypter, the backup
+// is created at the time of the clock
+func NewKeyBackup(kid string, keyType KeyType, priv []byte, encrypter KeyEncrypter, random io.Reader,
+	c clock.Clock) (*KeyBackup, error) {
+	dataKey := make([]byte, dataKeySize)
+	if _, err := io.ReadFull(random, dataKey); err != nil {
+		return nil, fmt.Errorf("failed to generate data key: %w", err)
+	}
+
+	gcm, err := newGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = io.ReadFull(random, nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	encryptedKey, err := encrypter.EncryptKey(dataKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt data key: %w", err)
+	}
+
+	return &KeyBackup{
+		KeyID:        kid,
+		Type:         keyType,
+		KEKID:        encrypter.KeyEncryptionKeyID(),
+		EncryptedKey: encryptedKey,
+		Nonce:        nonce,
+		// the key ID is authenticated so the private key can't be restored under another key ID
+		Ciphertext: gcm.Seal(nil, nonce, priv, []byte(kid)),
+		Created:    c.Now(),
+	}, nil
+}
+
+// Open decrypts the private key of the backup
+func (b *KeyBackup) Open(decrypter KeyDecrypter) ([]byte, error) {
+	dataKey, err := decrypter.DecryptKey(b.KEKID, b.EncryptedKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt data key of %s: %w", b.KeyID, err)
+	}
+
+	gcm, err := newGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(b.Nonce) != gcm.NonceSize() {
+		return nil, fmt.Errorf("invalid nonce size %d of %s", len(b.Nonce), b.KeyID)
+	}
+
+	priv, err := gcm.Open(nil, b.Nonce, b.Ciphertext, []byte(b.KeyID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt private key of %s: %w", b.KeyID, err)
+	}
+
+	return priv, nil
+}
+
+func newGCM(dataKey []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(dataKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid data key: %w", err)
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// publicKeyEncrypter encrypts the data keys to an X25519 public key with an ephemeral key pair
+type publicKeyEncrypter struct {
+	pub    [x25519KeySize]byte
+	random io.Reader
+}
+
+// PublicKeyEncrypter encrypts the data keys to the X25519 public key of a recovery key pair, the private key is
+// kept offline and only needed by PrivateKeyDecrypter to restore the keys
+func PublicKeyEncrypter(pub []byte, random io.Reader) (KeyEncrypter, error) {
+	if len(pub) != x25519KeySize {
+		return nil, fmt.Errorf("%w: X25519 public key has invalid size %d", ErrInvalidKey, len(pub))
+	}
+
+	e := &publicKeyEncrypter{random: random}
+	copy(e.pub[:], pub)
+
+	return e, nil
+}
+
+func (e *publicKeyEncrypter) KeyEncryptionKeyID() string {
+	return base58.Encode(e.pub[:])
+}
+
+// EncryptKey returns the ephemeral public key, the nonce and the data key sealed to the public key
+func (e *publicKeyEncrypter) EncryptKey(dataKey []byte) ([]byte, error) {
+	epk, esk, err := box.GenerateKey(e.random)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate ephemeral key: %w", err)
+	}
+
+	var nonce [boxNonceSize]byte
+	if _, err = io.ReadFull(e.random, nonce[:]); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	out := append(append([]byte{}, epk[:]...), nonce[:]...)
+
+	return box.Seal(out, dataKey, &nonce, &e.pub, esk), nil
+}
+
+// privateKeyDecrypter decrypts the data keys encrypted by publicKeyEncrypter
+type privateKeyDecrypter struct {
+	pub  [x25519KeySize]byte
+	priv [x25519KeySize]byte
+}
+
+// PrivateKeyDecrypter decrypts the data keys encrypted to the public key of the X25519 recovery key pair
+func PrivateKeyDecrypter(priv []byte) (KeyDecrypter, error) {
+	if len(priv) != x25519KeySize {
+		return nil, fmt.Errorf("%w: X25519 private key has invalid size %d", ErrInvalidKey, len(priv))
+	}
+
+	d := &privateKeyDecrypter{}
+	copy(d.priv[:], priv)
+	curve25519.ScalarBaseMult(&d.pub, &d.priv)
+
+	return d, nil
+}
+
+func (d *privateKeyDecrypter) DecryptKey(kekID string, encryptedKey []byte) ([]byte, error) {
+	if kekID != base58.Encode(d.pub[:]) {
+		return nil, fmt.Errorf("%w: %s", ErrBackupKeyMismatch, kekID)
+	}
+
+	if len(encryptedKey) < x25519KeySize+boxNonceSize {
+		return nil, errors.New("encrypted data key too short")
+	}
+
+	var (
+		epk   [x25519KeySize]byte
+		nonce [boxNonceSize]byte
+	)
+
+	copy(epk[:], encryptedKey)
+	copy(nonce[:], encryptedKey[x25519KeySize:])
+
+	dataKey, ok := box.Open(nil, encryptedKey[x25519KeySize+boxNonceSize:], &nonce, &epk, &d.priv)
+	if !ok {
+		return nil, errors.New("failed to open encrypted data key")
+	}
+
+	return dataKey, nil
+}
+
+// KMSKeyWrapper encrypts and decrypts the data keys with the key of the cloud KMS
+type KMSKeyWrapper struct {
+	Client KMSClient
+	// KeyID is the ID of the key of the KMS, e.g. the ARN of an AWS KMS key
+	KeyID string
+}
+
+// KeyEncryptionKeyID returns the ID of the key of the KMS
+func (w *KMSKeyWrapper) KeyEncryptionKeyID() string {
+	return w.KeyID
+}
+
+// EncryptKey encrypts the data key with the key of the KMS
+func (w *KMSKeyWrapper) EncryptKey(dataKey []byte) ([]byte, error) {
+	return w.Client.Encrypt(w.KeyID, dataKey)
+}
+
+// DecryptKey decrypts the data key with the key of the KMS
+func (w *KMSKeyWrapper) DecryptKey(kekID string, encryptedKey []byte) ([]byte, error) {
+	if kekID != w.KeyID {
+		return nil, fmt.Errorf("%w: %s", ErrBackupKeyMismatch, kekID)
+	}
+
+	return w.Client.Decrypt(w.KeyID, encryptedKey)
+}
+
+// backupKey backs up the private key if the backup is configured
+func (w *BaseWallet) backupKey(kid string, keyType KeyType, keyPair *crypto.KeyPair) error {
+	if w.backup == nil {
+		return nil
+	}
+
+	backup, err := NewKeyBackup(kid, keyType, keyPair.Priv, w.backup.Encrypter, w.random, w.clock)
+	if err != nil {
+		return fmt.Errorf("failed to back up key: %w", err)
+	}
+
+	bytes, err := json.Marshal(backup)
+	if err != nil {
+		return fmt.Errorf("failed to marshal key backup: %w", err)
+	}
+
+	if err = w.backup.Store.Put(kid, bytes); err != nil {
+		return fmt.Errorf("failed to store key backup: %w", err)
+	}
+
+	return nil
+}
+
+// RestoreKeys restores the keys backed up in the store, the keys already held by the wallet are skipped.
+// The store must be iterable.
+func (w *BaseWallet) RestoreKeys(store storage.Store, decrypter KeyDecrypter) ([]string, error) {
+	iterable, ok := store.(storage.IterableStore)
+	if !ok {
+		return nil, errors.New("key backup store is not iterable")
+	}
+
+	var restored []string
+
+	err := iterable.Iterate("", func(k string, v []byte) error {
+		backup := &KeyBackup{}
+		if err := json.Unmarshal(v, backup); err != nil {
+			return fmt.Errorf("failed to unmarshal key backup %s: %w", k, err)
+		}
+
+		ok, err := w.restoreKey(backup, decrypter)
+		if err != nil {
+			return err
+		}
+
+		if ok {
+			restored = append(restored, backup.KeyID)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore keys: %w", err)
+	}
+
+	return restored, nil
+}
+
+// restoreKey saves the key of the backup unless already held, the restored key is not backed up again
+func (w *BaseWallet) restoreKey(backup *KeyBackup, decrypter KeyDecrypter) (bool, error) {
+	_, err := w.store.Get(backup.KeyID)
+	if err == nil {
+		return false, nil
+	}
+
+	if !errors.Is(err, storage.ErrDataNotFound) {
+		return false, fmt.Errorf("failed to check key: %w", err)
+	}
+
+	priv, err := backup.Open(decrypter)
+	if err != nil {
+		return false, err
+	}
+
+	keyPair, err := importedKeyPair(backup.Type, priv, nil)
+	if err != nil {
+		return false, err
+	}
+
+	if base58.Encode(keyPair.Pub) != backup.KeyID {
+		return false, fmt.Errorf("%w: restored key doesn't match key ID %s", ErrInvalidKey, backup.KeyID)
+	}
+
+	return true, w.storeKey(keyPair, &KeyInfo{ID: backup.KeyID, Type: backup.Type, Usage: keyUsage(backup.Type),
+		Created: backup.Created})
+}
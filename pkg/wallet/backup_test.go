@@ -0,0 +1,212 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package wallet
+
+import (
+	"crypto/rand"
+	"errors"
+	"testing"
+
+	"github.com/btcsuite/btcutil/base58"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/nacl/box"
+
+	"github.com/hyperledger/aries-framework-go/pkg/common/clock"
+	mockstorage "github.com/hyperledger/aries-framework-go/pkg/internal/mock/storage"
+)
+
+type mockKeyBackupProvider struct {
+	*mockProvider
+	backup *KeyBackupConfig
+}
+
+func (p *mockKeyBackupProvider) WalletKeyBackup() *KeyBackupConfig {
+	return p.backup
+}
+
+// mockKMSClient keeps the plaintexts and returns random handles as ciphertexts
+type mockKMSClient struct {
+	plaintexts map[string][]byte
+	errEnc     error
+}
+
+func (c *mockKMSClient) Encrypt(keyID string, plaintext []byte) ([]byte, error) {
+	if c.errEnc != nil {
+		return nil, c.errEnc
+	}
+
+	handle := make([]byte, 16)
+	if _, err := rand.Read(handle); err != nil {
+		return nil, err
+	}
+
+	c.plaintexts[keyID+string(handle)] = plaintext
+
+	return handle, nil
+}
+
+func (c *mockKMSClient) Decrypt(keyID string, ciphertext []byte) ([]byte, error) {
+	plaintext, ok := c.plaintexts[keyID+string(ciphertext)]
+	if !ok {
+		return nil, errors.New("invalid ciphertext")
+	}
+
+	return plaintext, nil
+}
+
+func TestBaseWallet_KeyBackup(t *testing.T) {
+	recoveryPub, recoveryPriv, err := box.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	encrypter, err := PublicKeyEncrypter(recoveryPub[:], rand.Reader)
+	require.NoError(t, err)
+
+	decrypter, err := PrivateKeyDecrypter(recoveryPriv[:])
+	require.NoError(t, err)
+
+	newWallet := func(t *testing.T, backup *KeyBackupConfig) *BaseWallet {
+		w, err := New(&mockKeyBackupProvider{
+			mockProvider: newMockWalletProvider(&mockstorage.MockStoreProvider{
+				Store: &mockstorage.MockStore{Store: make(map[string][]byte)}}),
+			backup: backup,
+		})
+		require.NoError(t, err)
+
+		return w
+	}
+
+	t.Run("test backup and restore on another device", func(t *testing.T) {
+		backups := &mockstorage.MockStore{Store: make(map[string][]byte)}
+		w := newWallet(t, &KeyBackupConfig{Encrypter: encrypter, Store: backups})
+
+		signingKey, err := w.CreateSigningKey()
+		require.NoError(t, err)
+
+		encryptionKey, err := w.CreateEncryptionKey()
+		require.NoError(t, err)
+
+		require.Len(t, backups.Store, 2)
+		require.Contains(t, backups.Store, signingKey)
+		require.Contains(t, backups.Store, encryptionKey)
+
+		signature, err := w.SignMessage([]byte("message"), signingKey)
+		require.NoError(t, err)
+
+		restoredWallet := newWallet(t, nil)
+
+		restored, err := restoredWallet.RestoreKeys(backups, decrypter)
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{signingKey, encryptionKey}, restored)
+
+		restoredSignature, err := restoredWallet.SignMessage([]byte("message"), signingKey)
+		require.NoError(t, err)
+		require.Equal(t, signature, restoredSignature)
+
+		keys, err := restoredWallet.ListKeys(KeyFilter{Usage: Encryption})
+		require.NoError(t, err)
+		require.Len(t, keys, 1)
+		require.Equal(t, encryptionKey, keys[0].ID)
+
+		// the keys already held are skipped
+		restored, err = restoredWallet.RestoreKeys(backups, decrypter)
+		require.NoError(t, err)
+		require.Empty(t, restored)
+	})
+
+	t.Run("test backup to KMS", func(t *testing.T) {
+		backups := &mockstorage.MockStore{Store: make(map[string][]byte)}
+		kms := &KMSKeyWrapper{Client: &mockKMSClient{plaintexts: make(map[string][]byte)},
+			KeyID: "arn:aws:kms:key/1"}
+		w := newWallet(t, &KeyBackupConfig{Encrypter: kms, Store: backups})
+
+		kid, err := w.CreateSigningKey()
+		require.NoError(t, err)
+
+		restored, err := newWallet(t, nil).RestoreKeys(backups, kms)
+		require.NoError(t, err)
+		require.Equal(t, []string{kid}, restored)
+
+		_, err = newWallet(t, nil).RestoreKeys(backups, decrypter)
+		require.True(t, errors.Is(err, ErrBackupKeyMismatch))
+	})
+
+	t.Run("test key not created if backup fails", func(t *testing.T) {
+		kms := &KMSKeyWrapper{Client: &mockKMSClient{errEnc: errors.New("KMS unavailable")}, KeyID: "key"}
+		w := newWallet(t, &KeyBackupConfig{Encrypter: kms,
+			Store: &mockstorage.MockStore{Store: make(map[string][]byte)}})
+
+		_, err := w.CreateSigningKey()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "KMS unavailable")
+
+		keys, err := w.ListKeys(KeyFilter{})
+		require.NoError(t, err)
+		require.Empty(t, keys)
+
+		w = newWallet(t, &KeyBackupConfig{Encrypter: encrypter,
+			Store: &mockstorage.MockStore{Store: make(map[string][]byte), ErrPut: errors.New("store error")}})
+
+		_, err = w.CreateEncryptionKey()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to store key backup")
+	})
+
+	t.Run("test restore errors", func(t *testing.T) {
+		w := newWallet(t, nil)
+
+		_, err := w.RestoreKeys(&mockstorage.MockStore{Store: map[string][]byte{"kid": []byte("{")}}, decrypter)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to unmarshal key backup kid")
+
+		_, err = w.RestoreKeys(&mockstorage.MockStore{ErrIterate: errors.New("iterate error")}, decrypter)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "iterate error")
+
+		// the backup of another key ID
+		_, priv, err := box.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+
+		backup, err := NewKeyBackup("kid", X25519, priv[:], encrypter, rand.Reader, clock.System())
+		require.NoError(t, err)
+
+		_, err = w.restoreKey(backup, decrypter)
+		require.True(t, errors.Is(err, ErrInvalidKey))
+
+		// the backup renamed to another key ID
+		backup.KeyID = base58.Encode(recoveryPub[:])
+		_, err = w.restoreKey(backup, decrypter)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to decrypt private key")
+	})
+}
+
+func TestKeyEncrypters(t *testing.T) {
+	t.Run("test invalid keys", func(t *testing.T) {
+		_, err := PublicKeyEncrypter([]byte("short"), rand.Reader)
+		require.True(t, errors.Is(err, ErrInvalidKey))
+
+		_, err = PrivateKeyDecrypter([]byte("short"))
+		require.True(t, errors.Is(err, ErrInvalidKey))
+	})
+
+	t.Run("test invalid encrypted data key", func(t *testing.T) {
+		pub, priv, err := box.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+
+		decrypter, err := PrivateKeyDecrypter(priv[:])
+		require.NoError(t, err)
+
+		kekID := base58.Encode(pub[:])
+
+		_, err = decrypter.DecryptKey(kekID, []byte("short"))
+		require.Error(t, err)
+
+		_, err = decrypter.DecryptKey(kekID, make([]byte, 100))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to open encrypted data key")
+	})
+}
@@ -0,0 +1,288 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package wallet
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/btcsuite/btcutil/base58"
+	"golang.org/x/crypto/curve25519"
+
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/crypto"
+	"github.com/hyperledger/aries-framework-go/pkg/storage"
+)
+
+// KeyType is the type of a key held by the wallet
+type KeyType string
+
+const (
+	// Ed25519 signing keys, the private key is the 64 bytes key or the 32 bytes seed
+	Ed25519 KeyType = "Ed25519"
+	// X25519 encryption keys
+	X25519 KeyType = "X25519"
+
+	x25519KeySize = 32
+
+	// keyInfoPrefix prefixes the store keys of the key metadata, the underscore is not a base58 character so
+	// they can't collide with the key IDs
+	keyInfoPrefix = "_key_"
+)
+
+// KeyUsage is what a key is used for
+type KeyUsage string
+
+const (
+	// Signing keys sign messages
+	Signing KeyUsage = "signing"
+	// Encryption keys pack and unpack envelopes
+	Encryption KeyUsage = "encryption"
+)
+
+// KeyInfo is the metadata of a key held by the wallet
+type KeyInfo struct {
+	// ID is the key ID, i.e. the base58 public key
+	ID string `json:"id"`
+	// Type is the key type
+	Type KeyType `json:"type"`
+	// Usage is what the key is used for
+	Usage KeyUsage `json:"usage"`
+	// Created is the time the key was created or imported
+	Created time.Time `json:"created"`
+}
+
+// KeyFilter selects the listed keys, the zero fields match any key
+type KeyFilter struct {
+	Type  KeyType
+	Usage KeyUsage
+}
+
+// KeyLister is implemented by the wallets listing their keys
+type KeyLister interface {
+	// ListKeys returns the metadata of the keys matching the filter, in creation order
+	ListKeys(filter KeyFilter) ([]KeyInfo, error)
+}
+
+// KeyHolder is implemented by the wallets telling whether they hold the private key of a verification key
+type KeyHolder interface {
+	// HasKey returns true if the wallet holds the key pair of the verification key
+	HasKey(verKey string) (bool, error)
+}
+
+// HasKey returns true if the wallet holds the key pair of the verification key
+func (w *BaseWallet) HasKey(verKey string) (bool, error) {
+	_, err := w.getKey(verKey)
+	if errors.Is(err, ErrKeyNotFound) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+var (
+	// ErrKeyExists is returned when an imported key is already held by the wallet
+	ErrKeyExists = errors.New("key already exists")
+	// ErrInvalidKey is returned when an imported key is malformed or its public key doesn't match the private key
+	ErrInvalidKey = errors.New("invalid key")
+	// ErrListNotSupported is returned when the keys are listed from a store which isn't a storage.IterableStore
+	ErrListNotSupported = errors.New("wallet store can't list the keys")
+)
+
+// KeyImporter is implemented by the wallets importing the keys generated elsewhere, e.g. in a HSM or another wallet
+type KeyImporter interface {
+	// ImportKey imports the key pair of the key type, the public key is derived from the private key if nil.
+	// The key ID is returned, i.e. the base58 public key used as the verification key.
+	ImportKey(keyType KeyType, priv, pub []byte, opts ...ImportKeyOpt) (string, error)
+}
+
+type importKeyOpts struct {
+	overwrite bool
+}
+
+// ImportKeyOpt is an import key option
+type ImportKeyOpt func(opts *importKeyOpts)
+
+// WithOverwrite replaces the key held by the wallet with the same key ID, the import fails by default
+func WithOverwrite() ImportKeyOpt {
+	return func(opts *importKeyOpts) {
+		opts.overwrite = true
+	}
+}
+
+// ImportKey imports the key pair of the key type to sign and decrypt with it
+func (w *BaseWallet) ImportKey(keyType KeyType, priv, pub []byte, opts ...ImportKeyOpt) (string, error) {
+	importOpts := &importKeyOpts{}
+	for _, opt := range opts {
+		opt(importOpts)
+	}
+
+	keyPair, err := importedKeyPair(keyType, priv, pub)
+	if err != nil {
+		return "", err
+	}
+
+	kid := base58.Encode(keyPair.Pub)
+
+	if !importOpts.overwrite {
+		_, err = w.store.Get(kid)
+		if err == nil {
+			return "", fmt.Errorf("%w: %s", ErrKeyExists, kid)
+		}
+
+		if !errors.Is(err, storage.ErrDataNotFound) {
+			return "", fmt.Errorf("failed to check key: %w", err)
+		}
+	}
+
+	if err = w.saveKey(kid, keyType, keyPair); err != nil {
+		return "", err
+	}
+
+	return kid, nil
+}
+
+// ListKeys returns the metadata of the keys matching the filter, the keys created before the wallet
+// kept the key metadata are not listed. ErrListNotSupported is returned if the store isn't a storage.IterableStore.
+func (w *BaseWallet) ListKeys(filter KeyFilter) ([]KeyInfo, error) {
+	iterable, ok := w.store.(storage.IterableStore)
+	if !ok {
+		return nil, ErrListNotSupported
+	}
+
+	var keys []KeyInfo
+
+	err := iterable.Iterate(keyInfoPrefix, func(k string, v []byte) error {
+		info := KeyInfo{}
+		if err := json.Unmarshal(v, &info); err != nil {
+			return fmt.Errorf("invalid metadata of key %s: %w", strings.TrimPrefix(k, keyInfoPrefix), err)
+		}
+
+		if (filter.Type == "" || filter.Type == info.Type) && (filter.Usage == "" || filter.Usage == info.Usage) {
+			keys = append(keys, info)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list keys: %w", err)
+	}
+
+	// the keys are iterated in ID order
+	sort.SliceStable(keys, func(i, j int) bool {
+		return keys[i].Created.Before(keys[j].Created)
+	})
+
+	return keys, nil
+}
+
+// saveKey backs up and saves the key pair of the key type with its metadata, the key is not saved if its backup
+// fails
+func (w *BaseWallet) saveKey(kid string, keyType KeyType, keyPair *crypto.KeyPair) error {
+	if err := w.backupKey(kid, keyType, keyPair); err != nil {
+		return err
+	}
+
+	return w.storeKey(keyPair, &KeyInfo{ID: kid, Type: keyType, Usage: keyUsage(keyType), Created: w.clock.Now()})
+}
+
+// storeKey saves the key pair and its metadata, they are written atomically if the store is a storage.BatchStore
+func (w *BaseWallet) storeKey(keyPair *crypto.KeyPair, keyInfo *KeyInfo) error {
+	kid := keyInfo.ID
+
+	info, err := json.Marshal(keyInfo)
+	if err != nil {
+		return fmt.Errorf("failed to marshal key metadata: %w", err)
+	}
+
+	if batch, ok := w.store.(storage.BatchStore); ok {
+		keyBytes, marshalErr := json.Marshal(keyPair)
+		if marshalErr != nil {
+			return fmt.Errorf("failed to marshal key: %w", marshalErr)
+		}
+
+		if err = batch.PutAll(map[string][]byte{kid: keyBytes, keyInfoKey(kid): info}); err != nil {
+			return fmt.Errorf("failed to save key: %w", err)
+		}
+
+		return nil
+	}
+
+	if err = w.persistKey(kid, keyPair); err != nil {
+		return err
+	}
+
+	if err = w.store.Put(keyInfoKey(kid), info); err != nil {
+		return fmt.Errorf("failed to save key metadata: %w", err)
+	}
+
+	return nil
+}
+
+func keyInfoKey(kid string) string {
+	return keyInfoPrefix + kid
+}
+
+func keyUsage(keyType KeyType) KeyUsage {
+	if keyType == Ed25519 {
+		return Signing
+	}
+
+	return Encryption
+}
+
+// importedKeyPair validates the key pair of the key type, the public key is derived if nil
+func importedKeyPair(keyType KeyType, priv, pub []byte) (*crypto.KeyPair, error) {
+	var keyPair *crypto.KeyPair
+
+	switch keyType {
+	case Ed25519:
+		var privKey ed25519.PrivateKey
+
+		switch len(priv) {
+		case ed25519.SeedSize:
+			privKey = ed25519.NewKeyFromSeed(priv)
+		case ed25519.PrivateKeySize:
+			privKey = ed25519.NewKeyFromSeed(priv[:ed25519.SeedSize])
+			if !bytes.Equal(privKey, priv) {
+				return nil, fmt.Errorf("%w: Ed25519 private key doesn't match its seed", ErrInvalidKey)
+			}
+		default:
+			return nil, fmt.Errorf("%w: Ed25519 private key has invalid size %d", ErrInvalidKey, len(priv))
+		}
+
+		keyPair = &crypto.KeyPair{Priv: privKey, Pub: privKey.Public().(ed25519.PublicKey)}
+	case X25519:
+		if len(priv) != x25519KeySize {
+			return nil, fmt.Errorf("%w: X25519 private key has invalid size %d", ErrInvalidKey, len(priv))
+		}
+
+		var derived, scalar [x25519KeySize]byte
+
+		copy(scalar[:], priv)
+		curve25519.ScalarBaseMult(&derived, &scalar)
+
+		keyPair = &crypto.KeyPair{Priv: priv, Pub: derived[:]}
+	default:
+		return nil, fmt.Errorf("%w: unsupported key type %s", ErrInvalidKey, keyType)
+	}
+
+	if pub != nil && !bytes.Equal(pub, keyPair.Pub) {
+		return nil, fmt.Errorf("%w: public key doesn't match the private key", ErrInvalidKey)
+	}
+
+	return keyPair, nil
+}
@@ -0,0 +1,92 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package wallet
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	// DefaultMaxEnvelopeSize is the default maximum size of the packed envelope in bytes
+	DefaultMaxEnvelopeSize = 10 << 20
+	// DefaultMaxAttachmentSize is the default maximum size of a message attachment in bytes
+	DefaultMaxAttachmentSize = 5 << 20
+
+	attachDecoratorSuffix = "~attach"
+)
+
+// ErrEnvelopeTooLarge is returned when the envelope exceeds the maximum envelope size
+var ErrEnvelopeTooLarge = errors.New("envelope exceeds the maximum size")
+
+// ErrAttachmentTooLarge is returned when an attachment of the message exceeds the maximum attachment size
+var ErrAttachmentTooLarge = errors.New("attachment exceeds the maximum size")
+
+// SizeLimits are the maximum sizes of the messages in bytes, zero value disables the limit
+type SizeLimits struct {
+	MaxEnvelopeSize   int64
+	MaxAttachmentSize int64
+}
+
+// DefaultSizeLimits returns the default message size limits
+func DefaultSizeLimits() SizeLimits {
+	return SizeLimits{MaxEnvelopeSize: DefaultMaxEnvelopeSize, MaxAttachmentSize: DefaultMaxAttachmentSize}
+}
+
+// CheckEnvelope checks the size of the packed envelope
+func (l SizeLimits) CheckEnvelope(envelope []byte) error {
+	if l.MaxEnvelopeSize > 0 && int64(len(envelope)) > l.MaxEnvelopeSize {
+		return fmt.Errorf("%w: %d bytes, maximum %d", ErrEnvelopeTooLarge, len(envelope), l.MaxEnvelopeSize)
+	}
+
+	return nil
+}
+
+// CheckAttachments checks the size of each attachment of the attachment decorators (e.g. "img~attach") of the
+// plain JSON message, the decorators are an attachment or a list of attachments. Messages which are not JSON
+// objects are not checked.
+func (l SizeLimits) CheckAttachments(message []byte) error {
+	if l.MaxAttachmentSize <= 0 {
+		return nil
+	}
+
+	fields := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(message, &fields); err != nil {
+		return nil
+	}
+
+	for name, value := range fields {
+		if !strings.HasSuffix(name, attachDecoratorSuffix) {
+			continue
+		}
+
+		if err := l.checkDecorator(name, value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkDecorator checks the size of each attachment of the attachment decorator
+func (l SizeLimits) checkDecorator(name string, value json.RawMessage) error {
+	var attachments []json.RawMessage
+	if err := json.Unmarshal(value, &attachments); err != nil {
+		attachments = []json.RawMessage{value}
+	}
+
+	for i, attachment := range attachments {
+		if int64(len(attachment)) > l.MaxAttachmentSize {
+			return fmt.Errorf("%w: %s[%d] has %d bytes, maximum %d", ErrAttachmentTooLarge, name, i,
+				len(attachment), l.MaxAttachmentSize)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,70 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package wallet
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSizeLimits_CheckEnvelope(t *testing.T) {
+	t.Run("test default limits", func(t *testing.T) {
+		limits := DefaultSizeLimits()
+		require.EqualValues(t, DefaultMaxEnvelopeSize, limits.MaxEnvelopeSize)
+		require.EqualValues(t, DefaultMaxAttachmentSize, limits.MaxAttachmentSize)
+	})
+
+	t.Run("test envelope within limit", func(t *testing.T) {
+		require.NoError(t, SizeLimits{MaxEnvelopeSize: 4}.CheckEnvelope([]byte("data")))
+	})
+
+	t.Run("test envelope exceeding limit", func(t *testing.T) {
+		err := SizeLimits{MaxEnvelopeSize: 3}.CheckEnvelope([]byte("data"))
+		require.Error(t, err)
+		require.True(t, errors.Is(err, ErrEnvelopeTooLarge))
+		require.Contains(t, err.Error(), "4 bytes, maximum 3")
+	})
+
+	t.Run("test envelope limit disabled", func(t *testing.T) {
+		require.NoError(t, SizeLimits{}.CheckEnvelope([]byte("data")))
+	})
+}
+
+func TestSizeLimits_CheckAttachments(t *testing.T) {
+	msg := []byte(`{"@type":"https://didcomm.org/test/1.0/msg","content":"0123456789","img~attach":{"data":"abc"}}`)
+
+	t.Run("test attachment within limit", func(t *testing.T) {
+		require.NoError(t, SizeLimits{MaxAttachmentSize: 16}.CheckAttachments(msg))
+	})
+
+	t.Run("test attachment exceeding limit", func(t *testing.T) {
+		err := SizeLimits{MaxAttachmentSize: 10}.CheckAttachments(msg)
+		require.Error(t, err)
+		require.True(t, errors.Is(err, ErrAttachmentTooLarge))
+		require.Contains(t, err.Error(), "img~attach")
+	})
+
+	t.Run("test each attachment of the list checked", func(t *testing.T) {
+		list := []byte(`{"@type":"https://didcomm.org/test/1.0/msg","img~attach":[{"data":"abc"},{"data":"def"}]}`)
+		require.NoError(t, SizeLimits{MaxAttachmentSize: 16}.CheckAttachments(list))
+
+		err := SizeLimits{MaxAttachmentSize: 10}.CheckAttachments(list)
+		require.Error(t, err)
+		require.True(t, errors.Is(err, ErrAttachmentTooLarge))
+		require.Contains(t, err.Error(), "img~attach[0]")
+	})
+
+	t.Run("test attachment limit disabled", func(t *testing.T) {
+		require.NoError(t, SizeLimits{}.CheckAttachments(msg))
+	})
+
+	t.Run("test message not JSON object", func(t *testing.T) {
+		require.NoError(t, SizeLimits{MaxAttachmentSize: 1}.CheckAttachments([]byte("msg1")))
+	})
+}
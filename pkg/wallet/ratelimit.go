@@ -0,0 +1,187 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/common/clock"
+)
+
+var (
+	// ErrRateLimited is returned when a signing operation exceeds the signing rate of the wallet
+	ErrRateLimited = errors.New("wallet signing rate exceeded")
+	// ErrLockedOut is returned when the signing operations are locked out after too many authentication failures
+	ErrLockedOut = errors.New("wallet signing locked out")
+	// ErrNilChannel is returned when registering a nil event channel
+	ErrNilChannel = errors.New("event channel is nil")
+)
+
+// RateLimits limit the signing operations of the wallet, the zero value disables the limits
+type RateLimits struct {
+	// SigningRate is the maximum number of signing operations per second, on average, zero disables the rate limit
+	SigningRate float64
+	// SigningBurst is the number of signing operations allowed at once, 1 if not set
+	SigningBurst int
+	// LockoutThreshold is the number of consecutive authentication failures, i.e. signing operations with keys
+	// not held by the wallet, locking the signing out. Zero disables the lockout.
+	LockoutThreshold int
+	// LockoutDuration is the duration of the first lockout, doubled by every new lockout until the lockouts
+	// stop for MaxLockoutDuration
+	LockoutDuration time.Duration
+	// MaxLockoutDuration caps the lockout duration, LockoutDuration if not set
+	MaxLockoutDuration time.Duration
+}
+
+// LockoutEvent is sent to the registered channels when the signing operations are locked out
+type LockoutEvent struct {
+	// Until is the end of the lockout
+	Until time.Time
+	// Lockouts is the number of consecutive lockouts, the duration of the lockout doubles with every lockout
+	Lockouts int
+}
+
+// rateLimiter is the token bucket of the signing operations, it also locks the signing out after repeated
+// authentication failures
+type rateLimiter struct {
+	limits      RateLimits
+	clock       clock.Clock
+	lock        sync.Mutex
+	tokens      float64
+	refilled    time.Time
+	failures    int
+	lastFailed  time.Time
+	lockouts    int
+	lockedUntil time.Time
+	events      []chan<- LockoutEvent
+}
+
+func newRateLimiter(limits RateLimits, c clock.Clock) *rateLimiter {
+	if limits.SigningBurst <= 0 {
+		limits.SigningBurst = 1
+	}
+
+	if limits.MaxLockoutDuration < limits.LockoutDuration {
+		limits.MaxLockoutDuration = limits.LockoutDuration
+	}
+
+	return &rateLimiter{limits: limits, clock: c, tokens: float64(limits.SigningBurst), refilled: c.Now()}
+}
+
+// allow consumes a token of the bucket, the operations are rejected while the signing is locked out
+func (l *rateLimiter) allow() error {
+	if l == nil {
+		return nil
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := l.clock.Now()
+
+	if now.Before(l.lockedUntil) {
+		return fmt.Errorf("%w until %s", ErrLockedOut, l.lockedUntil.Format(time.RFC3339))
+	}
+
+	if l.limits.SigningRate <= 0 {
+		return nil
+	}
+
+	l.tokens += now.Sub(l.refilled).Seconds() * l.limits.SigningRate
+	if burst := float64(l.limits.SigningBurst); l.tokens > burst {
+		l.tokens = burst
+	}
+
+	l.refilled = now
+
+	if l.tokens < 1 {
+		return ErrRateLimited
+	}
+
+	l.tokens--
+
+	return nil
+}
+
+// succeeded resets the consecutive authentication failures
+func (l *rateLimiter) succeeded() {
+	if l == nil {
+		return
+	}
+
+	l.lock.Lock()
+	l.failures = 0
+	l.lock.Unlock()
+}
+
+// failed records an authentication failure, the signing is locked out after LockoutThreshold consecutive failures.
+// The lockout event is dropped for the channels not ready to receive it.
+func (l *rateLimiter) failed() {
+	if l == nil || l.limits.LockoutThreshold <= 0 {
+		return
+	}
+
+	l.lock.Lock()
+
+	now := l.clock.Now()
+
+	// the lockouts escalate until no authentication fails for the maximum lockout duration
+	if now.Sub(l.lastFailed) > l.limits.MaxLockoutDuration {
+		l.lockouts = 0
+	}
+
+	l.failures++
+	l.lastFailed = now
+
+	if l.failures < l.limits.LockoutThreshold {
+		l.lock.Unlock()
+
+		return
+	}
+
+	duration := l.limits.LockoutDuration << uint(l.lockouts)
+	if duration > l.limits.MaxLockoutDuration || duration <= 0 {
+		duration = l.limits.MaxLockoutDuration
+	}
+
+	l.lockouts++
+	l.failures = 0
+	l.lockedUntil = now.Add(duration)
+
+	event := LockoutEvent{Until: l.lockedUntil, Lockouts: l.lockouts}
+	events := append(l.events[:0:0], l.events...)
+
+	l.lock.Unlock()
+
+	for _, ch := range events {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+func (l *rateLimiter) register(ch chan<- LockoutEvent) {
+	l.lock.Lock()
+	l.events = append(l.events, ch)
+	l.lock.Unlock()
+}
+
+func (l *rateLimiter) unregister(ch chan<- LockoutEvent) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	for i := 0; i < len(l.events); i++ {
+		if l.events[i] == ch {
+			l.events = append(l.events[:i], l.events[i+1:]...)
+			i--
+		}
+	}
+}
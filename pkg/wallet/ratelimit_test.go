@@ -0,0 +1,162 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package wallet
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hyperledger/aries-framework-go/pkg/common/clock"
+	mockstorage "github.com/hyperledger/aries-framework-go/pkg/internal/mock/storage"
+)
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+	c := clock.Func(func() time.Time { return now })
+
+	t.Run("test signing rate", func(t *testing.T) {
+		l := newRateLimiter(RateLimits{SigningRate: 2, SigningBurst: 2, LockoutThreshold: 1}, c)
+
+		require.NoError(t, l.allow())
+		require.NoError(t, l.allow())
+		require.Equal(t, ErrRateLimited, l.allow())
+
+		// the rate limited operations don't lock the signing out
+		require.Equal(t, ErrRateLimited, l.allow())
+
+		now = now.Add(500 * time.Millisecond)
+		require.NoError(t, l.allow())
+		require.Equal(t, ErrRateLimited, l.allow())
+
+		// the burst isn't exceeded after a long pause
+		now = now.Add(time.Hour)
+		require.NoError(t, l.allow())
+		require.NoError(t, l.allow())
+		require.Equal(t, ErrRateLimited, l.allow())
+	})
+
+	t.Run("test limits disabled", func(t *testing.T) {
+		l := newRateLimiter(RateLimits{}, c)
+
+		for i := 0; i < 10; i++ {
+			require.NoError(t, l.allow())
+			l.failed()
+		}
+
+		var nilLimiter *rateLimiter
+		require.NoError(t, nilLimiter.allow())
+		nilLimiter.failed()
+		nilLimiter.succeeded()
+	})
+
+	t.Run("test lockout backoff", func(t *testing.T) {
+		l := newRateLimiter(RateLimits{LockoutThreshold: 2, LockoutDuration: time.Minute,
+			MaxLockoutDuration: 3 * time.Minute}, c)
+
+		events := make(chan LockoutEvent, 10)
+		l.register(events)
+
+		lockout := func() time.Time {
+			require.NoError(t, l.allow())
+			l.failed()
+			require.NoError(t, l.allow())
+			l.failed()
+
+			event := <-events
+
+			// the signing is locked out until the end of the lockout
+			err := l.allow()
+			require.True(t, errors.Is(err, ErrLockedOut))
+			require.Contains(t, err.Error(), event.Until.Format(time.RFC3339))
+
+			now = event.Until
+
+			return event.Until
+		}
+
+		start := now
+		require.Equal(t, start.Add(time.Minute), lockout())
+		require.Equal(t, start.Add(3*time.Minute), lockout())
+		require.Equal(t, start.Add(6*time.Minute), lockout())
+
+		// the lockout duration is reset after a quiet period
+		now = now.Add(time.Hour)
+		start = now
+		require.Equal(t, start.Add(time.Minute), lockout())
+
+		// the consecutive failures are reset by a successful operation
+		l.failed()
+		l.succeeded()
+		l.failed()
+		require.NoError(t, l.allow())
+
+		l.unregister(events)
+		l.failed()
+		require.True(t, errors.Is(l.allow(), ErrLockedOut))
+		require.Empty(t, events)
+	})
+
+	t.Run("test lockout event not blocking", func(t *testing.T) {
+		l := newRateLimiter(RateLimits{LockoutThreshold: 1, LockoutDuration: time.Minute}, c)
+
+		events := make(chan LockoutEvent)
+		l.register(events)
+
+		l.failed()
+		require.True(t, errors.Is(l.allow(), ErrLockedOut))
+	})
+}
+
+func TestBaseWallet_RateLimits(t *testing.T) {
+	w, err := New(&rateLimitedProvider{
+		mockProvider: newMockWalletProvider(mockstorage.NewMockStoreProvider()),
+		limits:       RateLimits{SigningRate: 1, LockoutThreshold: 2, LockoutDuration: time.Hour},
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, ErrNilChannel, w.RegisterLockoutEvent(nil))
+
+	events := make(chan LockoutEvent, 1)
+	require.NoError(t, w.RegisterLockoutEvent(events))
+
+	verKey, err := w.CreateSigningKey()
+	require.NoError(t, err)
+
+	_, err = w.SignMessage([]byte("hello"), verKey)
+	require.NoError(t, err)
+
+	_, err = w.SignDigest(make([]byte, 64), verKey)
+	require.True(t, errors.Is(err, ErrRateLimited))
+
+	w.limiter.limits.SigningRate = 0
+
+	for i := 0; i < 2; i++ {
+		_, err = w.SignMessage([]byte("hello"), "unknown")
+		require.True(t, errors.Is(err, ErrKeyNotFound))
+	}
+
+	event := <-events
+	require.Equal(t, 1, event.Lockouts)
+
+	_, err = w.SignStream(bytes.NewBufferString("hello"), verKey)
+	require.True(t, errors.Is(err, ErrLockedOut))
+
+	require.NoError(t, w.UnregisterLockoutEvent(events))
+}
+
+type rateLimitedProvider struct {
+	*mockProvider
+	limits RateLimits
+}
+
+func (p *rateLimitedProvider) WalletRateLimits() RateLimits {
+	return p.limits
+}
@@ -9,23 +9,32 @@ package wallet
 import (
 	"crypto/ed25519"
 	"crypto/rand"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"time"
+	"io"
+	"strings"
 
 	"github.com/btcsuite/btcutil/base58"
 	"golang.org/x/crypto/nacl/box"
 
+	"github.com/hyperledger/aries-framework-go/pkg/common/clock"
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/codec"
 	"github.com/hyperledger/aries-framework-go/pkg/didcomm/crypto"
 	"github.com/hyperledger/aries-framework-go/pkg/didcomm/crypto/jwe/authcrypt"
+	legacy "github.com/hyperledger/aries-framework-go/pkg/didcomm/crypto/legacy/authcrypt"
 	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/jose"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/ed25519ph"
 	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/ed25519signature2018"
+	"github.com/hyperledger/aries-framework-go/pkg/framework/vdr"
 	"github.com/hyperledger/aries-framework-go/pkg/storage"
 )
 
 const (
 	storageName  = "basewallet"
+	defaultAlg   = authcrypt.XC20P
 	didFormat    = "did:%s:%s"
 	didPKID      = "%s#keys-%d"
 	didServiceID = "%s#endpoint-%d"
@@ -37,18 +46,95 @@ type provider interface {
 	InboundTransportEndpoint() string
 }
 
+// vdrRegistryProvider is optionally implemented by the provider to publish public DIDs
+type vdrRegistryProvider interface {
+	VDRRegistry() vdr.Creator
+}
+
+// sizeLimitsProvider is optionally implemented by the provider to configure the message size limits
+type sizeLimitsProvider interface {
+	MessageSizeLimits() SizeLimits
+}
+
+// sharedSecretCacheProvider is optionally implemented by the provider to cache the shared secrets computed
+// by the crypters
+type sharedSecretCacheProvider interface {
+	SharedSecretCache() *authcrypt.SharedSecretCache
+}
+
+// randSourceProvider is optionally implemented by the provider to set the random source of the key
+// generation and the crypters, e.g. a deterministic reader in tests
+type randSourceProvider interface {
+	RandSource() io.Reader
+}
+
+// rateLimitsProvider is optionally implemented by the provider to limit the signing operations
+type rateLimitsProvider interface {
+	WalletRateLimits() RateLimits
+}
+
+// clockProvider is optionally implemented by the provider to set the clock of the wallet
+type clockProvider interface {
+	Clock() clock.Clock
+}
+
+// compressionProvider is optionally implemented by the provider to compress the payloads of the envelopes
+type compressionProvider interface {
+	EnvelopeCompression() authcrypt.Compression
+}
+
+// codecsProvider is optionally implemented by the provider to advertise the payload codecs accepted by the agent
+// in the DID documents
+type codecsProvider interface {
+	Codecs() []codec.Codec
+}
+
+// inboundTransportEndpointsProvider is optionally implemented by the provider to advertise the endpoints of all
+// the inbound transports in the DID documents, the endpoints are read when the DIDs are created
+type inboundTransportEndpointsProvider interface {
+	InboundTransportEndpoints() []string
+}
+
+// keyBackupProvider is optionally implemented by the provider to back up the private keys created or imported
+// by the wallet
+type keyBackupProvider interface {
+	WalletKeyBackup() *KeyBackupConfig
+}
+
 // BaseWallet wallet implementation
 type BaseWallet struct {
-	store                    storage.Store
-	crypter                  crypto.Crypter
-	inboundTransportEndpoint string
+	store                     storage.Store
+	crypters                  map[authcrypt.ContentEncryption]crypto.Crypter
+	compression               authcrypt.Compression
+	compressingCrypters       map[authcrypt.ContentEncryption]crypto.Crypter
+	codecs                    []string
+	legacyCrypter             crypto.Crypter
+	inboundTransportEndpoints func() []string
+	vdrRegistry               vdr.Creator
+	sizeLimits                SizeLimits
+	random                    io.Reader
+	limiter                   *rateLimiter
+	clock                     clock.Clock
+	backup                    *KeyBackupConfig
 }
 
 // New return new instance of wallet implementation
 func New(ctx provider) (*BaseWallet, error) {
-	crypter, err := authcrypt.New(authcrypt.XC20P)
+	var sizeLimits SizeLimits
+	if p, ok := ctx.(sizeLimitsProvider); ok {
+		sizeLimits = p.MessageSizeLimits()
+	}
+
+	opts, random := crypterOptions(ctx, sizeLimits)
+
+	crypters, err := newCrypters(opts...)
 	if err != nil {
-		return nil, fmt.Errorf("new authcrypt failed: %w", err)
+		return nil, err
+	}
+
+	compression, compressingCrypters, err := newCompressingCrypters(ctx, opts)
+	if err != nil {
+		return nil, err
 	}
 
 	store, err := ctx.StorageProvider().OpenStore(storageName)
@@ -56,18 +142,133 @@ func New(ctx provider) (*BaseWallet, error) {
 		return nil, fmt.Errorf("failed to OpenStore for '%s', cause: %w", storageName, err)
 	}
 
-	return &BaseWallet{store: store, crypter: crypter, inboundTransportEndpoint: ctx.InboundTransportEndpoint()}, nil
+	endpoint := ctx.InboundTransportEndpoint()
+
+	w := &BaseWallet{store: store, crypters: crypters,
+		compression: compression, compressingCrypters: compressingCrypters,
+		legacyCrypter:             legacy.New(legacy.WithRandSource(random)),
+		inboundTransportEndpoints: func() []string { return []string{endpoint} }, random: random,
+		sizeLimits: sizeLimits}
+
+	w.setDIDProviders(ctx)
+
+	if p, ok := ctx.(keyBackupProvider); ok {
+		w.backup = p.WalletKeyBackup()
+	}
+
+	var limits RateLimits
+	if p, ok := ctx.(rateLimitsProvider); ok {
+		limits = p.WalletRateLimits()
+	}
+
+	w.clock = clock.System()
+	if p, ok := ctx.(clockProvider); ok && p.Clock() != nil {
+		w.clock = p.Clock()
+	}
+
+	w.limiter = newRateLimiter(limits, w.clock)
+
+	return w, nil
+}
+
+// setDIDProviders sets the optional providers of the DIDs created by the wallet
+func (w *BaseWallet) setDIDProviders(ctx provider) {
+	if p, ok := ctx.(inboundTransportEndpointsProvider); ok {
+		w.inboundTransportEndpoints = p.InboundTransportEndpoints
+	}
+
+	if p, ok := ctx.(vdrRegistryProvider); ok {
+		w.vdrRegistry = p.VDRRegistry()
+	}
+
+	if p, ok := ctx.(codecsProvider); ok {
+		for _, c := range p.Codecs() {
+			w.codecs = append(w.codecs, c.Name())
+		}
+	}
+}
+
+// newCompressingCrypters returns the compression of the provider and the crypters compressing the payloads
+// with it, nil if the payloads are not compressed
+func newCompressingCrypters(ctx provider,
+	opts []authcrypt.Option) (authcrypt.Compression, map[authcrypt.ContentEncryption]crypto.Crypter, error) {
+	p, ok := ctx.(compressionProvider)
+	if !ok || p.EnvelopeCompression() == "" {
+		return "", nil, nil
+	}
+
+	crypters, err := newCrypters(append(opts, authcrypt.WithCompression(p.EnvelopeCompression()))...)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return p.EnvelopeCompression(), crypters, nil
+}
+
+// crypterOptions returns the options of the crypters and the random source of the wallet. The decompressed
+// payloads are bounded by the maximum envelope size.
+func crypterOptions(ctx provider, limits SizeLimits) ([]authcrypt.Option, io.Reader) {
+	var opts []authcrypt.Option
+	if p, ok := ctx.(sharedSecretCacheProvider); ok && p.SharedSecretCache() != nil {
+		opts = append(opts, authcrypt.WithSharedSecretCache(p.SharedSecretCache()))
+	}
+
+	random := rand.Reader
+	if p, ok := ctx.(randSourceProvider); ok && p.RandSource() != nil {
+		random = p.RandSource()
+		opts = append(opts, authcrypt.WithRandSource(random))
+	}
+
+	if limits.MaxEnvelopeSize > 0 {
+		opts = append(opts, authcrypt.WithMaxDecompressedSize(limits.MaxEnvelopeSize))
+	}
+
+	return opts, random
+}
+
+// newCrypters returns a crypter per supported content encryption algorithm
+func newCrypters(opts ...authcrypt.Option) (map[authcrypt.ContentEncryption]crypto.Crypter, error) {
+	crypters := make(map[authcrypt.ContentEncryption]crypto.Crypter)
+
+	for _, alg := range authcrypt.SupportedAlgs() {
+		crypter, err := authcrypt.New(alg, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("new authcrypt failed: %w", err)
+		}
+
+		crypters[alg] = crypter
+	}
+
+	return crypters, nil
+}
+
+// RegisterLockoutEvent registers the channel to receive the lockouts of the signing operations.
+func (w *BaseWallet) RegisterLockoutEvent(ch chan<- LockoutEvent) error {
+	if ch == nil {
+		return ErrNilChannel
+	}
+
+	w.limiter.register(ch)
+
+	return nil
+}
+
+// UnregisterLockoutEvent unregisters the channel. Refer RegisterLockoutEvent().
+func (w *BaseWallet) UnregisterLockoutEvent(ch chan<- LockoutEvent) error {
+	w.limiter.unregister(ch)
+
+	return nil
 }
 
 // CreateEncryptionKey create a new public/private encryption keypair.
 func (w *BaseWallet) CreateEncryptionKey() (string, error) {
-	pub, priv, err := box.GenerateKey(rand.Reader)
+	pub, priv, err := box.GenerateKey(w.random)
 	if err != nil {
 		return "", fmt.Errorf("failed to GenerateKey: %w", err)
 	}
 	base58Pub := base58.Encode(pub[:])
 	// TODO - need to encrypt the priv before putting them in the store.
-	if err := w.persistKey(base58Pub, &crypto.KeyPair{Pub: pub[:], Priv: priv[:]}); err != nil {
+	if err := w.saveKey(base58Pub, X25519, &crypto.KeyPair{Pub: pub[:], Priv: priv[:]}); err != nil {
 		return "", err
 	}
 	return base58Pub, nil
@@ -75,30 +276,107 @@ func (w *BaseWallet) CreateEncryptionKey() (string, error) {
 
 // CreateSigningKey create a new public/private signing keypair.
 func (w *BaseWallet) CreateSigningKey() (string, error) {
-	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	pub, priv, err := ed25519.GenerateKey(w.random)
 	if err != nil {
 		return "", fmt.Errorf("failed to GenerateKey: %w", err)
 	}
 	base58Pub := base58.Encode(pub[:])
 	// TODO - need to encrypt the priv before putting them in the store.
-	if err := w.persistKey(base58Pub, &crypto.KeyPair{Pub: pub[:], Priv: priv[:]}); err != nil {
+	if err := w.saveKey(base58Pub, Ed25519, &crypto.KeyPair{Pub: pub[:], Priv: priv[:]}); err != nil {
 		return "", err
 	}
 	return base58Pub, nil
 }
 
+// signingKey returns the key pair of the signing operation within the rate limits, the signing is locked out
+// after repeated authentication failures, i.e. signing with keys the wallet doesn't hold
+func (w *BaseWallet) signingKey(verKey string) (*crypto.KeyPair, error) {
+	if err := w.limiter.allow(); err != nil {
+		return nil, err
+	}
+
+	keyPair, err := w.getKey(verKey)
+	if err != nil {
+		if errors.Is(err, ErrKeyNotFound) {
+			w.limiter.failed()
+		}
+
+		return nil, fmt.Errorf("failed to get key: %w", err)
+	}
+
+	w.limiter.succeeded()
+
+	return keyPair, nil
+}
+
 // SignMessage sign a message using the private key associated with a given verification key.
 func (w *BaseWallet) SignMessage(message []byte, fromVerKey string) ([]byte, error) {
-	keyPair, err := w.getKey(fromVerKey)
+	keyPair, err := w.signingKey(fromVerKey)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get key: %w", err)
+		return nil, err
 	}
 	return ed25519signature2018.New().Sign(keyPair.Priv, message)
 }
 
-// DecryptMessage decrypt message
-func (w *BaseWallet) DecryptMessage(encMessage []byte, toVerKey string) ([]byte, string, error) {
-	return nil, "", fmt.Errorf("not implemented")
+// SignDigest signs the SHA-512 digest of a message with Ed25519ph using the private key associated with a given
+// verification key
+func (w *BaseWallet) SignDigest(digest []byte, fromVerKey string) ([]byte, error) {
+	keyPair, err := w.signingKey(fromVerKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return ed25519ph.Sign(keyPair.Priv, digest)
+}
+
+// SignStream signs the message read from the reader with Ed25519ph using the private key associated with a given
+// verification key
+func (w *BaseWallet) SignStream(r io.Reader, fromVerKey string) ([]byte, error) {
+	keyPair, err := w.signingKey(fromVerKey)
+	if err != nil {
+		return nil, err
+	}
+
+	digest, err := ed25519ph.Digest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return ed25519ph.Sign(keyPair.Priv, digest)
+}
+
+// DecryptMessage decrypts the envelope with the key pair of the recipient verification key, the envelope is
+// decrypted by the crypter of its format like in UnpackMessage
+func (w *BaseWallet) DecryptMessage(encMessage []byte, toVerKey string) (*DecryptedMessage, error) {
+	_, _, crypter, recipientKIDs, err := w.unpacker(encMessage)
+	if err != nil {
+		return nil, err
+	}
+
+	if !contains(recipientKIDs, toVerKey) {
+		return nil, fmt.Errorf("%s is not a recipient key of the envelope", toVerKey)
+	}
+
+	recipientKeyPair, err := w.getKey(toVerKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed from getKey: %w", err)
+	}
+
+	bytes, senderKey, err := decrypt(crypter, encMessage, recipientKeyPair)
+	if err != nil {
+		return nil, fmt.Errorf("failed from decrypt: %w", err)
+	}
+
+	if err = w.sizeLimits.CheckAttachments(bytes); err != nil {
+		return nil, err
+	}
+
+	mode := AnonCrypt
+	if senderKey != "" {
+		mode = AuthCrypt
+	}
+
+	return &DecryptedMessage{Message: bytes, SenderVerKey: senderKey, Mode: mode}, nil
 }
 
 // PackMessage Pack a message for one or more recipients.
@@ -106,6 +384,10 @@ func (w *BaseWallet) PackMessage(envelope *Envelope) ([]byte, error) {
 	if envelope == nil {
 		return nil, errors.New("envelope argument is nil")
 	}
+
+	if err := w.sizeLimits.CheckAttachments(envelope.Message); err != nil {
+		return nil, err
+	}
 	// get keypair from db
 	senderKeyPair, err := w.getKey(envelope.FromVerKey)
 	if err != nil {
@@ -121,23 +403,35 @@ func (w *BaseWallet) PackMessage(envelope *Envelope) ([]byte, error) {
 		// create 32 byte key
 		recipients = append(recipients, verKeyBytes)
 	}
+
+	crypter, err := w.negotiateCrypter(envelope.EncryptionAlgs, envelope.Compression)
+	if err != nil {
+		return nil, err
+	}
+
 	// encrypt message
-	bytes, err := w.crypter.Encrypt(envelope.Message, *senderKeyPair, recipients)
+	bytes, err := crypter.Encrypt(envelope.Message, *senderKeyPair, recipients)
 	if err != nil {
 		return nil, fmt.Errorf("failed from encrypt: %w", err)
 	}
+
+	if err := w.sizeLimits.CheckEnvelope(bytes); err != nil {
+		return nil, err
+	}
+
 	return bytes, nil
 }
 
-// UnpackMessage Unpack a message.
+// UnpackMessage Unpack a message. The envelope is unpacked by the crypter of its format, read from its
+// protected header.
 func (w *BaseWallet) UnpackMessage(encMessage []byte) (*Envelope, error) {
-	var e authcrypt.Envelope
-	if err := json.Unmarshal(encMessage, &e); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal encMessage: %w", err)
+	format, alg, crypter, recipientKIDs, err := w.unpacker(encMessage)
+	if err != nil {
+		return nil, err
 	}
+
 	var keysNotFound []string
-	for _, v := range e.Recipients {
-		recipVKeyB58 := v.Header.KID
+	for _, recipVKeyB58 := range recipientKIDs {
 		// get keypair from db
 		recipientKeyPair, err := w.getKey(recipVKeyB58)
 		if err != nil {
@@ -147,22 +441,184 @@ func (w *BaseWallet) UnpackMessage(encMessage []byte) (*Envelope, error) {
 			}
 			return nil, fmt.Errorf("failed from getKey: %w", err)
 		}
-		bytes, err := w.crypter.Decrypt(encMessage, *recipientKeyPair)
+		bytes, senderKey, err := decrypt(crypter, encMessage, recipientKeyPair)
 		if err != nil {
 			return nil, fmt.Errorf("failed from decrypt: %w", err)
 		}
-		return &Envelope{Message: bytes, ToVerKeys: []string{recipVKeyB58}}, nil
+		if err := w.sizeLimits.CheckAttachments(bytes); err != nil {
+			return nil, err
+		}
+		return &Envelope{Message: bytes, FromVerKey: senderKey, ToVerKeys: []string{recipVKeyB58},
+			EncryptionAlgs: alg, Format: format}, nil
 	}
 	return nil, fmt.Errorf("no corresponding recipient key found in {%s}", keysNotFound)
 }
 
+// unpacker returns the format, the content encryption algorithm, the crypter and the recipient keys of the
+// envelope. The size of the envelope is checked before any decryption attempt.
+func (w *BaseWallet) unpacker(encMessage []byte) (crypto.Format, []string, crypto.Crypter, []string, error) {
+	if err := w.sizeLimits.CheckEnvelope(encMessage); err != nil {
+		return "", nil, nil, nil, err
+	}
+
+	var e authcrypt.Envelope
+	if err := json.Unmarshal(encMessage, &e); err != nil {
+		return "", nil, nil, nil, fmt.Errorf("failed to unmarshal encMessage: %w", err)
+	}
+
+	format, err := crypto.EnvelopeFormat(encMessage)
+	if err != nil {
+		return "", nil, nil, nil, err
+	}
+
+	alg, crypter, recipientKIDs, err := w.unpackerOf(format, &e)
+	if err != nil {
+		return "", nil, nil, nil, err
+	}
+
+	return format, alg, crypter, recipientKIDs, nil
+}
+
+// unpackerOf returns the content encryption algorithm, the crypter and the recipient keys of the envelope of
+// the format
+func (w *BaseWallet) unpackerOf(format crypto.Format, e *authcrypt.Envelope) ([]string, crypto.Crypter,
+	[]string, error) {
+	switch format {
+	case crypto.FormatAuthcrypt:
+		// the unsupported algorithms are rejected before any key lookup
+		if err := checkContentEncryption(e.Protected); err != nil {
+			return nil, nil, nil, err
+		}
+
+		alg, crypter := w.crypterOf(e)
+
+		kids := make([]string, len(e.Recipients))
+		for i, r := range e.Recipients {
+			kids[i] = r.Header.KID
+		}
+
+		return alg, crypter, kids, nil
+	case crypto.FormatLegacy:
+		kids, err := legacyRecipientKIDs(e.Protected)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		return nil, w.legacyCrypter, kids, nil
+	default:
+		return nil, nil, nil, fmt.Errorf("%w: %s", crypto.ErrUnsupportedFormat, format)
+	}
+}
+
+// checkContentEncryption checks the content encryption algorithm of the protected header is supported
+func checkContentEncryption(protected string) error {
+	headers, err := jose.DecodeProtectedHeaders(protected)
+	if err != nil {
+		return err
+	}
+
+	var encs []string
+	for _, alg := range authcrypt.SupportedAlgs() {
+		encs = append(encs, string(alg))
+	}
+
+	return headers.Validate(&jose.Policy{Encryptions: encs})
+}
+
+// legacyRecipientKIDs returns the recipient keys of the legacy envelope, listed in its protected header
+func legacyRecipientKIDs(protected string) ([]string, error) {
+	bytes, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(protected, "="))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode protected header: %w", err)
+	}
+
+	headers := &struct {
+		Recipients []struct {
+			Header struct {
+				KID string `json:"kid,omitempty"`
+			} `json:"header,omitempty"`
+		} `json:"recipients,omitempty"`
+	}{}
+
+	if err = json.Unmarshal(bytes, headers); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal protected header: %w", err)
+	}
+
+	kids := make([]string, len(headers.Recipients))
+	for i, r := range headers.Recipients {
+		kids[i] = r.Header.KID
+	}
+
+	return kids, nil
+}
+
+// decrypt decrypts the envelope, the base58 sender key is returned if the crypter exposes it
+func decrypt(crypter crypto.Crypter, encMessage []byte, recipientKeyPair *crypto.KeyPair) ([]byte, string, error) {
+	if d, ok := crypter.(crypto.SenderDecrypter); ok {
+		bytes, senderKey, err := d.DecryptWithSender(encMessage, *recipientKeyPair)
+		if err != nil {
+			return nil, "", err
+		}
+
+		return bytes, base58.Encode(senderKey), nil
+	}
+
+	bytes, err := crypter.Decrypt(encMessage, *recipientKeyPair)
+
+	return bytes, "", err
+}
+
+// negotiateCrypter returns the crypter of the best content encryption algorithm supported by the recipients,
+// the default crypter is used if the recipients algorithms are not known. The payloads are compressed only
+// if the recipients accept the compression of the wallet.
+func (w *BaseWallet) negotiateCrypter(recipientAlgs []string, compression string) (crypto.Crypter, error) {
+	alg := defaultAlg
+
+	if len(recipientAlgs) > 0 {
+		var err error
+
+		alg, err = authcrypt.SelectAlg(recipientAlgs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to negotiate encryption algorithm: %w", err)
+		}
+	}
+
+	if w.compression != "" && compression == string(w.compression) {
+		if crypter, ok := w.compressingCrypters[alg]; ok {
+			return crypter, nil
+		}
+	}
+
+	crypter, ok := w.crypters[alg]
+	if !ok {
+		return nil, fmt.Errorf("no crypter of encryption algorithm %s", alg)
+	}
+
+	return crypter, nil
+}
+
+// crypterOf returns the crypter of the content encryption algorithm used by the sender of the envelope,
+// the default crypter is used if the algorithm is not known
+func (w *BaseWallet) crypterOf(e *authcrypt.Envelope) ([]string, crypto.Crypter) {
+	alg, err := e.ContentEncryption()
+	if err != nil {
+		return nil, w.crypters[defaultAlg]
+	}
+
+	if crypter, ok := w.crypters[alg]; ok {
+		return []string{string(alg)}, crypter
+	}
+
+	return []string{string(alg)}, w.crypters[defaultAlg]
+}
+
 // Close wallet
 func (w *BaseWallet) Close() error {
 	return nil
 }
 
 // CreateDID returns new DID Document
-// TODO write the DID Doc to the chosen DID method.
+// The DID Doc is written to the VDR of the DID method if public option is set.
 func (w *BaseWallet) CreateDID(method string, opts ...DocOpts) (*did.Doc, error) {
 	docOpts := &createDIDOpts{}
 	// Apply options
@@ -192,27 +648,76 @@ func (w *BaseWallet) CreateDID(method string, opts ...DocOpts) (*did.Doc, error)
 	// Service model to be included only if service type is provided through opts
 	var service []did.Service
 	if docOpts.serviceType != "" {
-		// Service endpoints
-		service = []did.Service{
-			{
-				ID:              fmt.Sprintf(didServiceID, id, 1),
-				Type:            docOpts.serviceType,
-				ServiceEndpoint: w.inboundTransportEndpoint,
-			},
-		}
+		service = w.services(id, docOpts.serviceType)
 	}
 
 	// Created time
-	createdTime := time.Now()
+	createdTime := w.clock.Now()
 
-	return &did.Doc{
+	doc := &did.Doc{
 		Context:   []string{did.Context},
 		ID:        id,
 		PublicKey: []did.PublicKey{pubKey},
 		Service:   service,
 		Created:   &createdTime,
 		Updated:   &createdTime,
-	}, nil
+	}
+
+	if !docOpts.public {
+		return doc, nil
+	}
+
+	if w.vdrRegistry == nil {
+		return nil, errors.New("failed to publish DID: VDR registry is not configured")
+	}
+
+	doc, err = w.vdrRegistry.Create(method, doc, docOpts.vdrOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to publish DID: %w", err)
+	}
+
+	return doc, nil
+}
+
+// services returns a service per inbound transport endpoint, the priority of the services is the order of the
+// endpoints when the agent has several endpoints. The services advertise the envelope encryption algorithms and
+// the payload compression of the wallet, and the payload codecs accepted by the agent.
+func (w *BaseWallet) services(id, serviceType string) []did.Service {
+	endpoints := w.inboundTransportEndpoints()
+
+	services := make([]did.Service, len(endpoints))
+
+	var encryptionAlgs []string
+	for _, alg := range authcrypt.SupportedAlgs() {
+		encryptionAlgs = append(encryptionAlgs, string(alg))
+	}
+
+	for i, endpoint := range endpoints {
+		services[i] = did.Service{
+			ID:              fmt.Sprintf(didServiceID, id, i+1),
+			Type:            serviceType,
+			ServiceEndpoint: endpoint,
+		}
+
+		// the counterparties pack the envelopes they send to the agent with the best algorithm they support
+		services[i].Properties = map[string]interface{}{"encryptionAlgs": encryptionAlgs}
+
+		if len(endpoints) > 1 {
+			services[i].Properties["priority"] = i
+		}
+
+		// the counterparties compress the payloads they send to the agent if they use the same compression
+		if w.compression != "" {
+			services[i].Properties["compression"] = string(w.compression)
+		}
+
+		// the counterparties encode the payloads they send to the agent with the first codec they support
+		if len(w.codecs) > 0 {
+			services[i].Properties["codecs"] = w.codecs
+		}
+	}
+
+	return services
 }
 
 // persistKey save key in storage
@@ -243,3 +748,13 @@ func (w *BaseWallet) getKey(verkey string) (*crypto.KeyPair, error) {
 	}
 	return &key, nil
 }
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
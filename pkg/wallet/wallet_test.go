@@ -7,19 +7,33 @@ SPDX-License-Identifier: Apache-2.0
 package wallet
 
 import (
+	"bytes"
+	"crypto/ed25519"
 	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	insecurerand "math/rand"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/btcsuite/btcutil/base58"
 	"github.com/stretchr/testify/require"
 	"golang.org/x/crypto/nacl/box"
 
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/codec"
 	"github.com/hyperledger/aries-framework-go/pkg/didcomm/crypto"
 	"github.com/hyperledger/aries-framework-go/pkg/didcomm/crypto/jwe/authcrypt"
+	legacy "github.com/hyperledger/aries-framework-go/pkg/didcomm/crypto/legacy/authcrypt"
 	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/jose"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/ed25519ph"
 	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/ed25519signature2018"
+	"github.com/hyperledger/aries-framework-go/pkg/framework/vdr"
 	"github.com/hyperledger/aries-framework-go/pkg/internal/mock/didcomm"
 	mockstorage "github.com/hyperledger/aries-framework-go/pkg/internal/mock/storage"
 	"github.com/hyperledger/aries-framework-go/pkg/storage"
@@ -87,9 +101,10 @@ func TestBaseWallet_UnpackMessage(t *testing.T) {
 
 		crypter, err := authcrypt.New(authcrypt.XC20P)
 		require.NoError(t, err)
-		w.crypter = crypter
+		w.crypters = allAlgs(crypter)
 
 		packMsg, err := json.Marshal(authcrypt.Envelope{
+			Protected:  base64.RawURLEncoding.EncodeToString([]byte(`{"typ":"prs.hyperledger.aries-auth-message","enc":"XC20P"}`)),
 			Recipients: []authcrypt.Recipient{{Header: authcrypt.RecipientHeaders{KID: "key1"}}}})
 		require.NoError(t, err)
 		_, err = w.UnpackMessage(packMsg)
@@ -115,7 +130,7 @@ func TestBaseWallet_UnpackMessage(t *testing.T) {
 
 		crypter, err := authcrypt.New(authcrypt.XC20P)
 		require.NoError(t, err)
-		w.crypter = crypter
+		w.crypters = allAlgs(crypter)
 
 		pub1, priv1, err := box.GenerateKey(rand.Reader)
 		require.NoError(t, err)
@@ -153,7 +168,7 @@ func TestBaseWallet_UnpackMessage(t *testing.T) {
 		mockCrypter := &didcomm.MockAuthCrypt{DecryptValue: decryptValue,
 			EncryptValue: e}
 
-		w.crypter = mockCrypter
+		w.crypters = allAlgs(mockCrypter)
 
 		pub1, priv1, err := box.GenerateKey(rand.Reader)
 		require.NoError(t, err)
@@ -177,6 +192,77 @@ func TestBaseWallet_UnpackMessage(t *testing.T) {
 	})
 }
 
+func TestBaseWallet_UnpackMessageFormats(t *testing.T) {
+	w, err := New(newMockWalletProvider(&mockstorage.MockStoreProvider{Store: &mockstorage.MockStore{
+		Store: make(map[string][]byte),
+	}}))
+	require.NoError(t, err)
+
+	t.Run("test legacy envelope", func(t *testing.T) {
+		senderPub, senderPriv, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+
+		recipientPub, recipientPriv, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+		require.NoError(t, w.persistKey(base58.Encode(recipientPub), &crypto.KeyPair{Pub: recipientPub,
+			Priv: recipientPriv}))
+
+		packMsg, err := legacy.New().Encrypt([]byte("msg1"), crypto.KeyPair{Pub: senderPub, Priv: senderPriv},
+			[][]byte{recipientPub})
+		require.NoError(t, err)
+
+		unpackMsg, err := w.UnpackMessage(packMsg)
+		require.NoError(t, err)
+		require.Equal(t, []byte("msg1"), unpackMsg.Message)
+		require.Equal(t, []string{base58.Encode(recipientPub)}, unpackMsg.ToVerKeys)
+		require.Equal(t, crypto.FormatLegacy, unpackMsg.Format)
+	})
+
+	t.Run("test unsupported format", func(t *testing.T) {
+		for _, protected := range []string{`{"typ":"application/didcomm-encrypted+json"}`, `{"typ":"unknown"}`} {
+			packMsg, err := json.Marshal(authcrypt.Envelope{
+				Protected:  base64.RawURLEncoding.EncodeToString([]byte(protected)),
+				Recipients: []authcrypt.Recipient{{Header: authcrypt.RecipientHeaders{KID: "key1"}}}})
+			require.NoError(t, err)
+
+			_, err = w.UnpackMessage(packMsg)
+			require.True(t, errors.Is(err, crypto.ErrUnsupportedFormat), protected)
+		}
+	})
+
+	t.Run("test unsupported content encryption", func(t *testing.T) {
+		protected := `{"typ":"prs.hyperledger.aries-auth-message","enc":"A256GCM"}`
+		packMsg, err := json.Marshal(authcrypt.Envelope{
+			Protected:  base64.RawURLEncoding.EncodeToString([]byte(protected)),
+			Recipients: []authcrypt.Recipient{{Header: authcrypt.RecipientHeaders{KID: "key1"}}}})
+		require.NoError(t, err)
+
+		_, err = w.UnpackMessage(packMsg)
+		require.True(t, errors.Is(err, jose.ErrUnsupportedEnc))
+	})
+
+	t.Run("test invalid legacy header", func(t *testing.T) {
+		_, err := legacyRecipientKIDs("invalid!")
+		require.Error(t, err)
+
+		_, err = legacyRecipientKIDs(base64.URLEncoding.EncodeToString([]byte("{")))
+		require.Error(t, err)
+	})
+
+	t.Run("test legacy header with or without padding", func(t *testing.T) {
+		// 52 bytes, so the padded encoding ends with "=="
+		protected := []byte(`{"recipients":[{"header":{"kid":"key1"}}],"x":"abc"}`)
+
+		for _, encoded := range []string{
+			base64.URLEncoding.EncodeToString(protected), base64.RawURLEncoding.EncodeToString(protected),
+		} {
+			kids, err := legacyRecipientKIDs(encoded)
+			require.NoError(t, err)
+			require.Equal(t, []string{"key1"}, kids)
+		}
+	})
+}
+
 func TestBaseWallet_PackMessage(t *testing.T) {
 	t.Run("test success", func(t *testing.T) {
 		w, err := New(newMockWalletProvider(&mockstorage.MockStoreProvider{Store: &mockstorage.MockStore{
@@ -186,7 +272,7 @@ func TestBaseWallet_PackMessage(t *testing.T) {
 
 		crypter, err := authcrypt.New(authcrypt.XC20P)
 		require.NoError(t, err)
-		w.crypter = crypter
+		w.crypters = allAlgs(crypter)
 
 		pub1, priv1, err := box.GenerateKey(rand.Reader)
 		require.NoError(t, err)
@@ -208,6 +294,7 @@ func TestBaseWallet_PackMessage(t *testing.T) {
 		require.NoError(t, err)
 		require.Equal(t, []byte("msg1"), unpackMsg.Message)
 		require.Equal(t, []string{base58.Encode(pub2[:])}, unpackMsg.ToVerKeys)
+		require.Equal(t, base58FromVerKey, unpackMsg.FromVerKey)
 	})
 
 	t.Run("test envelope is nil", func(t *testing.T) {
@@ -228,7 +315,7 @@ func TestBaseWallet_PackMessage(t *testing.T) {
 
 		crypter, err := authcrypt.New(authcrypt.XC20P)
 		require.NoError(t, err)
-		w.crypter = crypter
+		w.crypters = allAlgs(crypter)
 
 		_, err = w.PackMessage(&Envelope{Message: []byte("msg1"),
 			FromVerKey: "key1",
@@ -246,7 +333,7 @@ func TestBaseWallet_PackMessage(t *testing.T) {
 			return nil, fmt.Errorf("encrypt error")
 		}
 
-		w.crypter = &didcomm.MockAuthCrypt{EncryptValue: encryptValue}
+		w.crypters = allAlgs(&didcomm.MockAuthCrypt{EncryptValue: encryptValue})
 
 		require.NoError(t, err)
 
@@ -269,6 +356,285 @@ func TestBaseWallet_PackMessage(t *testing.T) {
 	})
 }
 
+func TestBaseWallet_EncryptionAlgs(t *testing.T) {
+	w, err := New(newMockWalletProvider(&mockstorage.MockStoreProvider{Store: &mockstorage.MockStore{
+		Store: make(map[string][]byte),
+	}}))
+	require.NoError(t, err)
+
+	pub1, priv1, err := box.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	require.NoError(t, w.persistKey(base58.Encode(pub1[:]), &crypto.KeyPair{Pub: pub1[:], Priv: priv1[:]}))
+
+	pub2, priv2, err := box.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	require.NoError(t, w.persistKey(base58.Encode(pub2[:]), &crypto.KeyPair{Pub: pub2[:], Priv: priv2[:]}))
+
+	pack := func(algs []string) ([]byte, error) {
+		return w.PackMessage(&Envelope{Message: []byte("msg1"), FromVerKey: base58.Encode(pub1[:]),
+			ToVerKeys: []string{base58.Encode(pub2[:])}, EncryptionAlgs: algs})
+	}
+
+	t.Run("test default algorithm", func(t *testing.T) {
+		packMsg, err := pack(nil)
+		require.NoError(t, err)
+
+		unpackMsg, err := w.UnpackMessage(packMsg)
+		require.NoError(t, err)
+		require.Equal(t, []string{string(authcrypt.XC20P)}, unpackMsg.EncryptionAlgs)
+	})
+
+	t.Run("test best mutually supported algorithm", func(t *testing.T) {
+		packMsg, err := pack([]string{"A256GCM", string(authcrypt.C20P)})
+		require.NoError(t, err)
+
+		unpackMsg, err := w.UnpackMessage(packMsg)
+		require.NoError(t, err)
+		require.Equal(t, []byte("msg1"), unpackMsg.Message)
+		require.Equal(t, []string{string(authcrypt.C20P)}, unpackMsg.EncryptionAlgs)
+	})
+
+	t.Run("test no mutually supported algorithm", func(t *testing.T) {
+		_, err := pack([]string{"A256GCM"})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to negotiate encryption algorithm")
+	})
+}
+
+func TestBaseWallet_SharedSecretCache(t *testing.T) {
+	cache := authcrypt.NewSharedSecretCache(10, time.Hour)
+	w, err := New(&mockSharedSecretCacheProvider{mockProvider: newMockWalletProvider(&mockstorage.MockStoreProvider{
+		Store: &mockstorage.MockStore{Store: make(map[string][]byte)}}), cache: cache})
+	require.NoError(t, err)
+
+	pub1, priv1, err := box.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	require.NoError(t, w.persistKey(base58.Encode(pub1[:]), &crypto.KeyPair{Pub: pub1[:], Priv: priv1[:]}))
+
+	pub2, priv2, err := box.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	require.NoError(t, w.persistKey(base58.Encode(pub2[:]), &crypto.KeyPair{Pub: pub2[:], Priv: priv2[:]}))
+
+	for i := 0; i < 3; i++ {
+		packMsg, err := w.PackMessage(&Envelope{Message: []byte("msg1"), FromVerKey: base58.Encode(pub1[:]),
+			ToVerKeys: []string{base58.Encode(pub2[:])}})
+		require.NoError(t, err)
+
+		unpackMsg, err := w.UnpackMessage(packMsg)
+		require.NoError(t, err)
+		require.Equal(t, []byte("msg1"), unpackMsg.Message)
+	}
+
+	// the secrets of the sender and of the recipient keys, both held by the wallet
+	require.Equal(t, 2, cache.Len())
+}
+
+func TestBaseWallet_Codecs(t *testing.T) {
+	w, err := New(&mockCodecsProvider{mockProvider: newMockWalletProvider(&mockstorage.MockStoreProvider{
+		Store: &mockstorage.MockStore{Store: make(map[string][]byte)}}), codecs: []codec.Codec{codec.CBOR{}}})
+	require.NoError(t, err)
+
+	services := w.services("did:peer:123", "did-communication")
+	require.NotEmpty(t, services)
+	require.Equal(t, []string{codec.CBORName}, services[0].Properties["codecs"])
+	require.Nil(t, services[0].Properties["compression"])
+}
+
+func TestBaseWallet_EnvelopeCompression(t *testing.T) {
+	w, err := New(&mockCompressionProvider{mockProvider: newMockWalletProvider(&mockstorage.MockStoreProvider{
+		Store: &mockstorage.MockStore{Store: make(map[string][]byte)}}), zip: authcrypt.DEF})
+	require.NoError(t, err)
+
+	fromKey, err := w.CreateEncryptionKey()
+	require.NoError(t, err)
+
+	toKey, err := w.CreateEncryptionKey()
+	require.NoError(t, err)
+
+	msg := []byte(strings.Repeat(`{"@type":"https://didcomm.org/issue-credential/1.0/issue-credential"}`, 50))
+
+	compressionOf := func(packed []byte) authcrypt.Compression {
+		var e authcrypt.Envelope
+		require.NoError(t, json.Unmarshal(packed, &e))
+
+		zip, e2 := e.Compression()
+		require.NoError(t, e2)
+
+		return zip
+	}
+
+	t.Run("test payload compressed when accepted by the recipients", func(t *testing.T) {
+		packed, e := w.PackMessage(&Envelope{Message: msg, FromVerKey: fromKey, ToVerKeys: []string{toKey},
+			Compression: string(authcrypt.DEF)})
+		require.NoError(t, e)
+		require.Equal(t, authcrypt.DEF, compressionOf(packed))
+
+		unpacked, e := w.UnpackMessage(packed)
+		require.NoError(t, e)
+		require.Equal(t, msg, unpacked.Message)
+	})
+
+	t.Run("test payload uncompressed when not accepted by the recipients", func(t *testing.T) {
+		packed, e := w.PackMessage(&Envelope{Message: msg, FromVerKey: fromKey, ToVerKeys: []string{toKey}})
+		require.NoError(t, e)
+		require.Empty(t, compressionOf(packed))
+
+		packed, e = w.PackMessage(&Envelope{Message: msg, FromVerKey: fromKey, ToVerKeys: []string{toKey},
+			Compression: "LZ4"})
+		require.NoError(t, e)
+		require.Empty(t, compressionOf(packed))
+	})
+
+	t.Run("test compression advertised by the DID services", func(t *testing.T) {
+		services := w.services("did:peer:123", "did-communication")
+		require.NotEmpty(t, services)
+		require.Equal(t, "DEF", services[0].Properties["compression"])
+	})
+
+	t.Run("test decompressed payload bounded by the maximum envelope size", func(t *testing.T) {
+		limited, e := New(&mockCompressionProvider{mockProvider: newMockWalletProvider(&mockstorage.MockStoreProvider{
+			Store: &mockstorage.MockStore{Store: make(map[string][]byte)}}),
+			limits: SizeLimits{MaxEnvelopeSize: int64(len(msg) - 1)}})
+		require.NoError(t, e)
+
+		key, e := limited.CreateEncryptionKey()
+		require.NoError(t, e)
+
+		packed, e := w.PackMessage(&Envelope{Message: msg, FromVerKey: fromKey, ToVerKeys: []string{key},
+			Compression: string(authcrypt.DEF)})
+		require.NoError(t, e)
+		require.True(t, len(packed) < len(msg))
+
+		_, e = limited.UnpackMessage(packed)
+		require.Error(t, e)
+		require.Contains(t, e.Error(), "decompressed payload exceeds the maximum size")
+	})
+}
+
+func TestBaseWallet_RandSource(t *testing.T) {
+	newWallet := func() *BaseWallet {
+		w, err := New(&mockRandSourceProvider{mockProvider: newMockWalletProvider(&mockstorage.MockStoreProvider{
+			Store: &mockstorage.MockStore{Store: make(map[string][]byte)}}),
+			source: insecurerand.New(insecurerand.NewSource(8234802))}) // just a random const
+		require.NoError(t, err)
+
+		return w
+	}
+
+	t.Run("test deterministic random source generates the same keys", func(t *testing.T) {
+		w1, w2 := newWallet(), newWallet()
+
+		for _, w := range []*BaseWallet{w1, w2} {
+			_, err := w.CreateSigningKey()
+			require.NoError(t, err)
+		}
+
+		signingKeys1, err := w1.ListKeys(KeyFilter{})
+		require.NoError(t, err)
+
+		signingKeys2, err := w2.ListKeys(KeyFilter{})
+		require.NoError(t, err)
+		require.Equal(t, signingKeys1[0].ID, signingKeys2[0].ID)
+
+		encKey1, err := w1.CreateEncryptionKey()
+		require.NoError(t, err)
+
+		encKey2, err := w2.CreateEncryptionKey()
+		require.NoError(t, err)
+		require.Equal(t, encKey1, encKey2)
+	})
+
+	t.Run("test deterministic random source packs the same envelope", func(t *testing.T) {
+		w1, w2 := newWallet(), newWallet()
+
+		var packed [][]byte
+
+		for _, w := range []*BaseWallet{w1, w2} {
+			fromKey, err := w.CreateEncryptionKey()
+			require.NoError(t, err)
+
+			toKey, err := w.CreateEncryptionKey()
+			require.NoError(t, err)
+
+			msg, err := w.PackMessage(&Envelope{Message: []byte("msg1"), FromVerKey: fromKey, ToVerKeys: []string{toKey}})
+			require.NoError(t, err)
+
+			packed = append(packed, msg)
+		}
+
+		require.Equal(t, packed[0], packed[1])
+
+		unpacked, err := w2.UnpackMessage(packed[0])
+		require.NoError(t, err)
+		require.Equal(t, []byte("msg1"), unpacked.Message)
+	})
+}
+
+func TestBaseWallet_SizeLimits(t *testing.T) {
+	w, err := New(&mockSizeLimitsProvider{mockProvider: newMockWalletProvider(&mockstorage.MockStoreProvider{
+		Store: &mockstorage.MockStore{Store: make(map[string][]byte)}}),
+		limits: SizeLimits{MaxEnvelopeSize: 4096, MaxAttachmentSize: 8}})
+	require.NoError(t, err)
+
+	crypter, err := authcrypt.New(authcrypt.XC20P)
+	require.NoError(t, err)
+	w.crypters = allAlgs(crypter)
+
+	pub1, priv1, err := box.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	require.NoError(t, w.persistKey(base58.Encode(pub1[:]), &crypto.KeyPair{Pub: pub1[:], Priv: priv1[:]}))
+
+	pub2, priv2, err := box.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	require.NoError(t, w.persistKey(base58.Encode(pub2[:]), &crypto.KeyPair{Pub: pub2[:], Priv: priv2[:]}))
+
+	pack := func(msg []byte) ([]byte, error) {
+		return w.PackMessage(&Envelope{Message: msg, FromVerKey: base58.Encode(pub1[:]),
+			ToVerKeys: []string{base58.Encode(pub2[:])}})
+	}
+
+	t.Run("test success", func(t *testing.T) {
+		packMsg, err := pack([]byte(`{"doc~attach":"abc"}`))
+		require.NoError(t, err)
+
+		_, err = w.UnpackMessage(packMsg)
+		require.NoError(t, err)
+	})
+
+	t.Run("test pack attachment exceeding limit", func(t *testing.T) {
+		_, err := pack([]byte(`{"doc~attach":"0123456789"}`))
+		require.True(t, errors.Is(err, ErrAttachmentTooLarge))
+	})
+
+	t.Run("test pack envelope exceeding limit", func(t *testing.T) {
+		_, err := pack(make([]byte, 4096))
+		require.True(t, errors.Is(err, ErrEnvelopeTooLarge))
+	})
+
+	t.Run("test unpack envelope exceeding limit", func(t *testing.T) {
+		_, err := w.UnpackMessage(make([]byte, 4097))
+		require.True(t, errors.Is(err, ErrEnvelopeTooLarge))
+	})
+
+	t.Run("test unpack attachment exceeding limit", func(t *testing.T) {
+		w.sizeLimits.MaxAttachmentSize = 0
+		packMsg, err := pack([]byte(`{"doc~attach":"0123456789"}`))
+		require.NoError(t, err)
+
+		w.sizeLimits.MaxAttachmentSize = 8
+		_, err = w.UnpackMessage(packMsg)
+		require.True(t, errors.Is(err, ErrAttachmentTooLarge))
+
+		_, err = w.DecryptMessage(packMsg, base58.Encode(pub2[:]))
+		require.True(t, errors.Is(err, ErrAttachmentTooLarge))
+	})
+
+	t.Run("test decrypt envelope exceeding limit", func(t *testing.T) {
+		_, err := w.DecryptMessage(make([]byte, 4097), base58.Encode(pub2[:]))
+		require.True(t, errors.Is(err, ErrEnvelopeTooLarge))
+	})
+}
+
 func TestBaseWallet_SignMessage(t *testing.T) {
 	t.Run("test key not found", func(t *testing.T) {
 		w, err := New(newMockWalletProvider(&mockstorage.MockStoreProvider{
@@ -302,12 +668,137 @@ func TestBaseWallet_SignMessage(t *testing.T) {
 	})
 }
 
+func TestBaseWallet_SignStream(t *testing.T) {
+	w, err := New(newMockWalletProvider(&mockstorage.MockStoreProvider{
+		Store: &mockstorage.MockStore{
+			Store: make(map[string][]byte),
+		}}))
+	require.NoError(t, err)
+
+	var _ PreHashSigner = w
+
+	t.Run("test key not found", func(t *testing.T) {
+		_, err = w.SignStream(bytes.NewBufferString("hello"), "")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "key not found")
+
+		_, err = w.SignDigest(make([]byte, sha512.Size), "")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "key not found")
+	})
+
+	t.Run("test success", func(t *testing.T) {
+		fromVerKey, e := w.CreateSigningKey()
+		require.NoError(t, e)
+
+		payload := bytes.Repeat([]byte("attachment"), 1<<16)
+
+		signature, e := w.SignStream(bytes.NewReader(payload), fromVerKey)
+		require.NoError(t, e)
+
+		digest := sha512.Sum512(payload)
+		require.NoError(t, ed25519ph.Verify(base58.Decode(fromVerKey), digest[:], signature))
+
+		// the signature of the digest is the signature of the stream
+		digestSignature, e := w.SignDigest(digest[:], fromVerKey)
+		require.NoError(t, e)
+		require.Equal(t, signature, digestSignature)
+	})
+
+	t.Run("test invalid digest", func(t *testing.T) {
+		fromVerKey, e := w.CreateSigningKey()
+		require.NoError(t, e)
+
+		_, e = w.SignDigest([]byte("not a digest"), fromVerKey)
+		require.EqualError(t, e, "ed25519ph: bad digest length")
+	})
+}
+
 func TestBaseWallet_DecryptMessage(t *testing.T) {
-	t.Run("test error not implemented", func(t *testing.T) {
-		w, err := New(newMockWalletProvider(&mockstorage.MockStoreProvider{}))
+	newWallet := func(t *testing.T) (*BaseWallet, string, string) {
+		w, err := New(newMockWalletProvider(&mockstorage.MockStoreProvider{Store: &mockstorage.MockStore{
+			Store: make(map[string][]byte),
+		}}))
+		require.NoError(t, err)
+
+		crypter, err := authcrypt.New(authcrypt.XC20P)
+		require.NoError(t, err)
+		w.crypters = allAlgs(crypter)
+
+		pub1, priv1, err := box.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+		base58FromVerKey := base58.Encode(pub1[:])
+		require.NoError(t, w.persistKey(base58FromVerKey, &crypto.KeyPair{Pub: pub1[:], Priv: priv1[:]}))
+
+		pub2, priv2, err := box.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+		base58ToVerKey := base58.Encode(pub2[:])
+		require.NoError(t, w.persistKey(base58ToVerKey, &crypto.KeyPair{Pub: pub2[:], Priv: priv2[:]}))
+
+		return w, base58FromVerKey, base58ToVerKey
+	}
+
+	t.Run("test authcrypted envelope", func(t *testing.T) {
+		w, fromVerKey, toVerKey := newWallet(t)
+
+		packMsg, err := w.PackMessage(&Envelope{Message: []byte("msg1"), FromVerKey: fromVerKey,
+			ToVerKeys: []string{toVerKey}})
+		require.NoError(t, err)
+
+		decrypted, err := w.DecryptMessage(packMsg, toVerKey)
 		require.NoError(t, err)
-		_, _, err = w.DecryptMessage(nil, "")
+		require.Equal(t, &DecryptedMessage{Message: []byte("msg1"), SenderVerKey: fromVerKey, Mode: AuthCrypt},
+			decrypted)
+	})
+
+	t.Run("test crypter not exposing the sender", func(t *testing.T) {
+		w, fromVerKey, toVerKey := newWallet(t)
+
+		packMsg, err := w.PackMessage(&Envelope{Message: []byte("msg1"), FromVerKey: fromVerKey,
+			ToVerKeys: []string{toVerKey}})
+		require.NoError(t, err)
+
+		w.crypters = allAlgs(&didcomm.MockAuthCrypt{
+			DecryptValue: func(envelope []byte, recipientKeyPair crypto.KeyPair) ([]byte, error) {
+				return []byte("msg1"), nil
+			}})
+
+		decrypted, err := w.DecryptMessage(packMsg, toVerKey)
+		require.NoError(t, err)
+		require.Equal(t, &DecryptedMessage{Message: []byte("msg1"), Mode: AnonCrypt}, decrypted)
+	})
+
+	t.Run("test errors", func(t *testing.T) {
+		w, fromVerKey, toVerKey := newWallet(t)
+
+		_, err := w.DecryptMessage([]byte("invalid"), toVerKey)
 		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to unmarshal encMessage")
+
+		packMsg, err := w.PackMessage(&Envelope{Message: []byte("msg1"), FromVerKey: fromVerKey,
+			ToVerKeys: []string{toVerKey}})
+		require.NoError(t, err)
+
+		_, err = w.DecryptMessage(packMsg, "unknown")
+		require.EqualError(t, err, "unknown is not a recipient key of the envelope")
+
+		// the sender key isn't a recipient of the envelope
+		_, err = w.DecryptMessage(packMsg, fromVerKey)
+		require.EqualError(t, err, fromVerKey+" is not a recipient key of the envelope")
+
+		// the recipient key isn't held by the wallet
+		pub, _, err := box.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+
+		packMsg, err = w.PackMessage(&Envelope{Message: []byte("msg1"), FromVerKey: fromVerKey,
+			ToVerKeys: []string{base58.Encode(pub[:])}})
+		require.NoError(t, err)
+
+		_, err = w.DecryptMessage(packMsg, base58.Encode(pub[:]))
+		require.True(t, errors.Is(err, ErrKeyNotFound))
+
+		_, err = w.DecryptMessage([]byte(`{"protected": "e30"}`), toVerKey)
+		require.True(t, errors.Is(err, crypto.ErrUnsupportedFormat))
 	})
 }
 
@@ -368,6 +859,34 @@ func TestBaseWallet_NewDID(t *testing.T) {
 		}
 	})
 
+	t.Run("create new DID with a service per inbound transport endpoint", func(t *testing.T) {
+		prov := &mockEndpointsProvider{mockProvider: newMockWalletProvider(storeProvider),
+			endpoints: []string{"http://localhost:8080", "ws://localhost:8081"}}
+		w, err := New(prov)
+		require.NoError(t, err)
+
+		didDoc, err := w.CreateDID(method, WithServiceType(serviceTypeDIDComm))
+		require.NoError(t, err)
+		require.Len(t, didDoc.Service, 2)
+
+		for i, service := range didDoc.Service {
+			require.Equal(t, fmt.Sprintf(didServiceID, didDoc.ID, i+1), service.ID)
+			require.Equal(t, serviceTypeDIDComm, service.Type)
+			require.Equal(t, prov.endpoints[i], service.ServiceEndpoint)
+			require.Equal(t, i, service.Properties["priority"])
+		}
+
+		// the endpoints are read when the DIDs are created, e.g. once a transport restarted on another port
+		prov.endpoints = []string{"http://localhost:9090"}
+
+		didDoc, err = w.CreateDID(method, WithServiceType(serviceTypeDIDComm))
+		require.NoError(t, err)
+		require.Len(t, didDoc.Service, 1)
+		require.Equal(t, "http://localhost:9090", didDoc.Service[0].ServiceEndpoint)
+		require.Nil(t, didDoc.Service[0].Properties["priority"])
+		require.Equal(t, []string{"XC20P", "C20P"}, didDoc.Service[0].Properties["encryptionAlgs"])
+	})
+
 	t.Run("create new DID without service type", func(t *testing.T) {
 		w, err := New(newMockWalletProvider(storeProvider))
 		require.NoError(t, err)
@@ -380,6 +899,130 @@ func TestBaseWallet_NewDID(t *testing.T) {
 		// verify services
 		require.Empty(t, didDoc.Service)
 	})
+
+	t.Run("create new public DID", func(t *testing.T) {
+		registry := vdr.New(vdr.WithVDR(&mockVDR{}))
+		events := make(chan vdr.CreateEvent, 1)
+		require.NoError(t, registry.RegisterCreateEvent(events))
+
+		w, err := New(&mockVDRProvider{mockProvider: newMockWalletProvider(storeProvider), registry: registry})
+		require.NoError(t, err)
+		didDoc, err := w.CreateDID(method, WithPublic(vdr.WithOption("role", "ENDORSER")))
+		require.NoError(t, err)
+
+		verifyDID(t, didDoc)
+
+		event := <-events
+		require.NoError(t, event.Err)
+		require.Equal(t, didDoc.ID, event.Doc.ID)
+	})
+
+	t.Run("create new public DID - publishing failed", func(t *testing.T) {
+		registry := vdr.New(vdr.WithVDR(&mockVDR{createErr: errors.New("ledger unavailable")}))
+
+		w, err := New(&mockVDRProvider{mockProvider: newMockWalletProvider(storeProvider), registry: registry})
+		require.NoError(t, err)
+		didDoc, err := w.CreateDID(method, WithPublic())
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to publish DID")
+		require.Contains(t, err.Error(), "ledger unavailable")
+		require.Nil(t, didDoc)
+	})
+
+	t.Run("create new public DID - VDR registry not configured", func(t *testing.T) {
+		w, err := New(newMockWalletProvider(storeProvider))
+		require.NoError(t, err)
+		didDoc, err := w.CreateDID(method, WithPublic())
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "VDR registry is not configured")
+		require.Nil(t, didDoc)
+	})
+}
+
+type mockSharedSecretCacheProvider struct {
+	*mockProvider
+	cache *authcrypt.SharedSecretCache
+}
+
+func (m *mockSharedSecretCacheProvider) SharedSecretCache() *authcrypt.SharedSecretCache {
+	return m.cache
+}
+
+type mockEndpointsProvider struct {
+	*mockProvider
+	endpoints []string
+}
+
+func (m *mockEndpointsProvider) InboundTransportEndpoints() []string {
+	return m.endpoints
+}
+
+type mockCodecsProvider struct {
+	*mockProvider
+	codecs []codec.Codec
+}
+
+func (m *mockCodecsProvider) Codecs() []codec.Codec {
+	return m.codecs
+}
+
+type mockCompressionProvider struct {
+	*mockProvider
+	zip    authcrypt.Compression
+	limits SizeLimits
+}
+
+func (m *mockCompressionProvider) EnvelopeCompression() authcrypt.Compression {
+	return m.zip
+}
+
+func (m *mockCompressionProvider) MessageSizeLimits() SizeLimits {
+	return m.limits
+}
+
+type mockRandSourceProvider struct {
+	*mockProvider
+	source io.Reader
+}
+
+func (m *mockRandSourceProvider) RandSource() io.Reader {
+	return m.source
+}
+
+// mockVDRProvider mocks provider for wallet with VDR registry
+type mockSizeLimitsProvider struct {
+	*mockProvider
+	limits SizeLimits
+}
+
+func (m *mockSizeLimitsProvider) MessageSizeLimits() SizeLimits {
+	return m.limits
+}
+
+type mockVDRProvider struct {
+	*mockProvider
+	registry vdr.Creator
+}
+
+func (m *mockVDRProvider) VDRRegistry() vdr.Creator {
+	return m.registry
+}
+
+// mockVDR accepts any DID method
+type mockVDR struct {
+	createErr error
+}
+
+func (m *mockVDR) Create(doc *did.Doc, opts ...vdr.CreateOpt) (*did.Doc, error) {
+	if m.createErr != nil {
+		return nil, m.createErr
+	}
+
+	return doc, nil
+}
+
+func (m *mockVDR) Accept(method string) bool {
+	return true
 }
 
 func newMockWalletProvider(storagePvdr *mockstorage.MockStoreProvider) *mockProvider {
@@ -398,3 +1041,223 @@ func (m *mockProvider) StorageProvider() storage.Provider {
 func (m *mockProvider) InboundTransportEndpoint() string {
 	return serviceEndpoint
 }
+
+func TestBaseWallet_ImportKey(t *testing.T) {
+	newWallet := func(t *testing.T, store *mockstorage.MockStore) *BaseWallet {
+		w, err := New(newMockWalletProvider(&mockstorage.MockStoreProvider{Store: store}))
+		require.NoError(t, err)
+
+		return w
+	}
+
+	t.Run("test import signing key", func(t *testing.T) {
+		w := newWallet(t, &mockstorage.MockStore{Store: make(map[string][]byte)})
+
+		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+
+		kid, err := w.ImportKey(Ed25519, priv, pub)
+		require.NoError(t, err)
+		require.Equal(t, base58.Encode(pub), kid)
+
+		signature, err := w.SignMessage([]byte("hello"), kid)
+		require.NoError(t, err)
+		require.NoError(t, ed25519signature2018.New().Verify(pub, []byte("hello"), signature))
+
+		_, err = w.ImportKey(Ed25519, priv.Seed(), nil)
+		require.True(t, errors.Is(err, ErrKeyExists))
+
+		seedKID, err := w.ImportKey(Ed25519, priv.Seed(), nil, WithOverwrite())
+		require.NoError(t, err)
+		require.Equal(t, kid, seedKID)
+	})
+
+	t.Run("test import encryption key", func(t *testing.T) {
+		w := newWallet(t, &mockstorage.MockStore{Store: make(map[string][]byte)})
+
+		pub, priv, err := box.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+
+		kid, err := w.ImportKey(X25519, priv[:], nil)
+		require.NoError(t, err)
+		require.Equal(t, base58.Encode(pub[:]), kid)
+
+		sender, err := w.CreateEncryptionKey()
+		require.NoError(t, err)
+
+		packed, err := w.PackMessage(&Envelope{Message: []byte("msg"), FromVerKey: sender, ToVerKeys: []string{kid}})
+		require.NoError(t, err)
+
+		unpacked, err := w.UnpackMessage(packed)
+		require.NoError(t, err)
+		require.Equal(t, []byte("msg"), unpacked.Message)
+	})
+
+	t.Run("test invalid keys", func(t *testing.T) {
+		w := newWallet(t, &mockstorage.MockStore{Store: make(map[string][]byte)})
+
+		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+
+		_, err = w.ImportKey(Ed25519, priv[:10], nil)
+		require.True(t, errors.Is(err, ErrInvalidKey))
+
+		tampered := append([]byte{}, priv...)
+		tampered[ed25519.PrivateKeySize-1]++
+		_, err = w.ImportKey(Ed25519, tampered, nil)
+		require.EqualError(t, err, "invalid key: Ed25519 private key doesn't match its seed")
+
+		_, err = w.ImportKey(Ed25519, priv, []byte("other"))
+		require.EqualError(t, err, "invalid key: public key doesn't match the private key")
+
+		_, err = w.ImportKey(X25519, priv, pub)
+		require.True(t, errors.Is(err, ErrInvalidKey))
+
+		_, err = w.ImportKey("RSA", priv, pub)
+		require.EqualError(t, err, "invalid key: unsupported key type RSA")
+	})
+
+	t.Run("test store errors", func(t *testing.T) {
+		_, priv, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+
+		w := newWallet(t, &mockstorage.MockStore{Store: make(map[string][]byte), ErrPut: errors.New("put error")})
+		_, err = w.ImportKey(Ed25519, priv, nil)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "put error")
+
+		w = newWallet(t, &mockstorage.MockStore{Store: make(map[string][]byte), ErrGet: errors.New("get error")})
+		_, err = w.ImportKey(Ed25519, priv, nil)
+		require.NoError(t, err)
+		_, err = w.ImportKey(Ed25519, priv, nil)
+		require.EqualError(t, err, "failed to check key: get error")
+	})
+}
+
+func TestBaseWallet_HasKey(t *testing.T) {
+	store := &mockstorage.MockStore{Store: make(map[string][]byte)}
+	w, err := New(newMockWalletProvider(&mockstorage.MockStoreProvider{Store: store}))
+	require.NoError(t, err)
+
+	signKey, err := w.CreateSigningKey()
+	require.NoError(t, err)
+
+	held, err := w.HasKey(signKey)
+	require.NoError(t, err)
+	require.True(t, held)
+
+	held, err = w.HasKey("unknown")
+	require.NoError(t, err)
+	require.False(t, held)
+
+	store.ErrGet = fmt.Errorf("get error")
+	_, err = w.HasKey(signKey)
+	require.EqualError(t, err, "get error")
+}
+
+func TestBaseWallet_ListKeys(t *testing.T) {
+	t.Run("test list created and imported keys", func(t *testing.T) {
+		w, err := New(newMockWalletProvider(&mockstorage.MockStoreProvider{
+			Store: &mockstorage.MockStore{Store: make(map[string][]byte)}}))
+		require.NoError(t, err)
+
+		keys, err := w.ListKeys(KeyFilter{})
+		require.NoError(t, err)
+		require.Empty(t, keys)
+
+		before := time.Now()
+
+		encKey, err := w.CreateEncryptionKey()
+		require.NoError(t, err)
+		signKey, err := w.CreateSigningKey()
+		require.NoError(t, err)
+
+		_, priv, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+		importedKey, err := w.ImportKey(Ed25519, priv, nil)
+		require.NoError(t, err)
+
+		keys, err = w.ListKeys(KeyFilter{})
+		require.NoError(t, err)
+		require.Len(t, keys, 3)
+		require.Equal(t, KeyInfo{ID: encKey, Type: X25519, Usage: Encryption, Created: keys[0].Created}, keys[0])
+		require.Equal(t, KeyInfo{ID: signKey, Type: Ed25519, Usage: Signing, Created: keys[1].Created}, keys[1])
+		require.Equal(t, importedKey, keys[2].ID)
+		require.False(t, keys[0].Created.Before(before))
+
+		keys, err = w.ListKeys(KeyFilter{Usage: Signing})
+		require.NoError(t, err)
+		require.Len(t, keys, 2)
+
+		keys, err = w.ListKeys(KeyFilter{Type: X25519, Usage: Encryption})
+		require.NoError(t, err)
+		require.Len(t, keys, 1)
+		require.Equal(t, encKey, keys[0].ID)
+
+		_, err = w.ImportKey(Ed25519, priv, nil, WithOverwrite())
+		require.NoError(t, err)
+		keys, err = w.ListKeys(KeyFilter{})
+		require.NoError(t, err)
+		require.Len(t, keys, 3)
+	})
+
+	t.Run("test key saved atomically with its metadata", func(t *testing.T) {
+		store := &mockstorage.MockStore{Store: make(map[string][]byte)}
+		w, err := New(newMockWalletProvider(&mockstorage.MockStoreProvider{Store: store}))
+		require.NoError(t, err)
+
+		store.ErrPut = errors.New("put error")
+		_, err = w.CreateSigningKey()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to save key: put error")
+		require.Empty(t, store.Store)
+	})
+
+	t.Run("test key saved to a store without batches", func(t *testing.T) {
+		store := &plainStore{Store: &mockstorage.MockStore{Store: make(map[string][]byte)}}
+		w, err := New(newMockWalletProvider(mockstorage.NewMockCustomStoreProvider(store)))
+		require.NoError(t, err)
+
+		signKey, err := w.CreateSigningKey()
+		require.NoError(t, err)
+
+		_, err = w.getKey(signKey)
+		require.NoError(t, err)
+
+		_, err = store.Get(keyInfoKey(signKey))
+		require.NoError(t, err)
+
+		_, err = w.ListKeys(KeyFilter{})
+		require.True(t, errors.Is(err, ErrListNotSupported))
+	})
+
+	t.Run("test key metadata errors", func(t *testing.T) {
+		store := &mockstorage.MockStore{Store: make(map[string][]byte)}
+		w, err := New(newMockWalletProvider(&mockstorage.MockStoreProvider{Store: store}))
+		require.NoError(t, err)
+
+		require.NoError(t, store.Put(keyInfoKey("key1"), []byte("invalid")))
+		_, err = w.ListKeys(KeyFilter{})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid metadata of key key1")
+
+		store.ErrIterate = errors.New("iterate error")
+		_, err = w.ListKeys(KeyFilter{})
+		require.EqualError(t, err, "failed to list keys: iterate error")
+	})
+}
+
+// plainStore hides the optional interfaces of the store
+type plainStore struct {
+	storage.Store
+}
+
+// allAlgs returns the crypter as the crypter of all the supported encryption algorithms
+func allAlgs(crypter crypto.Crypter) map[authcrypt.ContentEncryption]crypto.Crypter {
+	crypters := make(map[authcrypt.ContentEncryption]crypto.Crypter)
+	for _, alg := range authcrypt.SupportedAlgs() {
+		crypters[alg] = crypter
+	}
+
+	return crypters
+}
@@ -59,6 +59,7 @@ func FeatureContext(s *godog.Suite) {
 	// Context is shared between tests
 	NewAgentSteps(context).RegisterSteps(s)
 	NewDIDExchangeSteps(context).RegisterSteps(s)
+	NewInteropSteps(context).RegisterSteps(s)
 
 }
 
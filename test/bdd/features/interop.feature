@@ -0,0 +1,41 @@
+#
+# Copyright SecureKey Technologies Inc. All Rights Reserved.
+#
+# SPDX-License-Identifier: Apache-2.0
+#
+# Interop scenarios run third party agents in docker containers and are not part of @all.
+# Run with: go test -run @interop
+# INTEROP_GENESIS_URL, INTEROP_MEDIATOR_INVITATION and INTEROP_NETWORK configure the third party agents.
+
+@interop
+Feature: DID exchange with third party agents
+
+  @interop_inviter
+  Scenario Outline: did exchange with <type> as inviter
+    Given interop agent "<agent>" of type "<type>" is running on inbound port "<inbound>" and controller port "<controller>"
+    And   "Bob" agent is running on "localhost" port "random"
+    And   "Bob" registers to receive notification for post state event "completed"
+    And   interop agent "<agent>" creates invitation
+    And   "Bob" receives invitation from "<agent>"
+    And   "Bob" waits for post state event "completed"
+    And   interop agent "<agent>" waits for connection state "<state>"
+
+    Examples:
+      | agent       | type  | inbound | controller | state    |
+      | AcaPyAlice  | acapy | 8020    | 8021       | active   |
+      | AFJAlice    | afj   | 8030    | 8031       | complete |
+
+  @interop_invitee
+  Scenario Outline: did exchange with <type> as invitee
+    Given "Alice" agent is running on "localhost" port "random"
+    And   "Alice" registers to receive notification for post state event "completed"
+    And   interop agent "<agent>" of type "<type>" is running on inbound port "<inbound>" and controller port "<controller>"
+    And   "Alice" creates invitation
+    And   interop agent "<agent>" receives invitation from "Alice"
+    And   "Alice" waits for post state event "completed"
+    And   interop agent "<agent>" waits for connection state "<state>"
+
+    Examples:
+      | agent     | type  | inbound | controller | state    |
+      | AcaPyBob  | acapy | 8040    | 8041       | active   |
+      | AFJBob    | afj   | 8050    | 8051       | complete |
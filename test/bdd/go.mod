@@ -10,7 +10,7 @@ require (
 	github.com/DATA-DOG/godog v0.7.13
 	github.com/hyperledger/aries-framework-go v0.0.0
 	github.com/sirupsen/logrus v1.3.0
-
+	github.com/stretchr/testify v1.3.0
 )
 
 replace github.com/hyperledger/aries-framework-go => ../..
@@ -0,0 +1,121 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package interop
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+// ACAPyImage is the default docker image of the ACA-Py agent.
+const ACAPyImage = "bcgovimages/aries-cloudagent:py36-1.15-0_0.6.0"
+
+// NewACAPy returns an ACA-Py agent driven through its admin API.
+func NewACAPy(name string, opts ...Option) *Agent {
+	config := newConfig(name, ACAPyImage, opts)
+
+	return &Agent{
+		Controller: &acapyController{url: config.controllerURL()},
+		container: &Container{
+			Name:    config.Name,
+			Image:   config.Image,
+			Network: config.Network,
+			Ports:   map[int]int{config.InboundPort: config.InboundPort, config.ControllerPort: config.ControllerPort},
+			Env:     config.Env,
+			Args:    acapyArgs(config),
+		},
+		config: config,
+	}
+}
+
+func acapyArgs(c *Config) []string {
+	args := []string{"start",
+		"--label", c.Name,
+		"--inbound-transport", "http", "0.0.0.0", strconv.Itoa(c.InboundPort),
+		"--outbound-transport", "http",
+		"--admin", "0.0.0.0", strconv.Itoa(c.ControllerPort),
+		"--admin-insecure-mode",
+		"--endpoint", c.Endpoint,
+		"--auto-accept-invites",
+		"--auto-accept-requests",
+		"--auto-ping-connection",
+	}
+
+	switch {
+	case c.GenesisURL != "":
+		args = append(args, "--genesis-url", c.GenesisURL)
+	case c.GenesisFile != "":
+		args = append(args, "--genesis-file", c.GenesisFile)
+	default:
+		args = append(args, "--no-ledger")
+	}
+
+	if c.MediatorInvitation != "" {
+		args = append(args, "--mediator-invitation", c.MediatorInvitation)
+	}
+
+	if c.OpenMediation {
+		args = append(args, "--open-mediation")
+	}
+
+	return append(args, c.ExtraArgs...)
+}
+
+// acapyController drives ACA-Py through the admin API.
+type acapyController struct {
+	url string
+}
+
+type acapyConnection struct {
+	ConnectionID string          `json:"connection_id"`
+	State        string          `json:"state"`
+	Invitation   json.RawMessage `json:"invitation,omitempty"`
+}
+
+func (c *acapyController) CreateInvitation() (json.RawMessage, string, error) {
+	var conn acapyConnection
+	if err := doJSON(http.MethodPost, c.url+"/connections/create-invitation", nil, &conn); err != nil {
+		return nil, "", err
+	}
+
+	return conn.Invitation, conn.ConnectionID, nil
+}
+
+func (c *acapyController) ReceiveInvitation(invitation json.RawMessage) (string, error) {
+	var conn acapyConnection
+	if err := doJSON(http.MethodPost, c.url+"/connections/receive-invitation", invitation, &conn); err != nil {
+		return "", err
+	}
+
+	return conn.ConnectionID, nil
+}
+
+func (c *acapyController) ConnectionState(connectionID string) (string, error) {
+	var conn acapyConnection
+	if err := doJSON(http.MethodGet, c.url+"/connections/"+connectionID, nil, &conn); err != nil {
+		return "", err
+	}
+
+	return conn.State, nil
+}
+
+// Command maps to the admin API: operations on an existing record are sent
+// to /{topic}/records/{id}/{operation}, others to /{topic}/{operation}.
+func (c *acapyController) Command(topic, operation, id string, data interface{}) (json.RawMessage, error) {
+	url := c.url + "/" + topic + "/" + operation
+	if id != "" {
+		url = c.url + "/" + topic + "/records/" + id + "/" + operation
+	}
+
+	var resp json.RawMessage
+	if err := doJSON(http.MethodPost, url, data, &resp); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
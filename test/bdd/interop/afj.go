@@ -0,0 +1,138 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package interop
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// AFJImage is the default docker image of the AFJ agent. AFJ is a library, so it is
+// run wrapped into the Aries Agent Test Harness backchannel.
+const AFJImage = "afj-agent-backchannel"
+
+// NewAFJ returns an AFJ agent driven through the test harness backchannel API.
+// Mediation is set up through the backchannel once the agent is started.
+func NewAFJ(name string, opts ...Option) *Agent {
+	config := newConfig(name, AFJImage, opts)
+
+	env := map[string]string{
+		"AGENT_NAME":            config.Name,
+		"AGENT_PUBLIC_ENDPOINT": config.Endpoint,
+	}
+
+	if config.GenesisURL != "" {
+		env["GENESIS_URL"] = config.GenesisURL
+	}
+
+	if config.GenesisFile != "" {
+		env["GENESIS_FILE"] = config.GenesisFile
+	}
+
+	for k, v := range config.Env {
+		env[k] = v
+	}
+
+	args := append([]string{"-p", strconv.Itoa(config.ControllerPort), "-i", "false"}, config.ExtraArgs...)
+
+	controller := &backchannelController{url: config.controllerURL()}
+
+	agent := &Agent{
+		Controller: controller,
+		container: &Container{
+			Name:    config.Name,
+			Image:   config.Image,
+			Network: config.Network,
+			Ports:   map[int]int{config.InboundPort: config.InboundPort, config.ControllerPort: config.ControllerPort},
+			Env:     env,
+			Args:    args,
+		},
+		config: config,
+	}
+
+	if config.MediatorInvitation != "" {
+		agent.setup = func() error {
+			return controller.requestMediation(config.MediatorInvitation)
+		}
+	}
+
+	return agent
+}
+
+// backchannelController drives an agent through the Aries Agent Test Harness backchannel API.
+type backchannelController struct {
+	url string
+}
+
+type backchannelCommand struct {
+	ID   string      `json:"id,omitempty"`
+	Data interface{} `json:"data,omitempty"`
+}
+
+type backchannelConnection struct {
+	ConnectionID string          `json:"connection_id"`
+	State        string          `json:"state"`
+	Invitation   json.RawMessage `json:"invitation,omitempty"`
+}
+
+func (c *backchannelController) CreateInvitation() (json.RawMessage, string, error) {
+	var conn backchannelConnection
+	if err := c.command("connection", "create-invitation", "", nil, &conn); err != nil {
+		return nil, "", err
+	}
+
+	return conn.Invitation, conn.ConnectionID, nil
+}
+
+func (c *backchannelController) ReceiveInvitation(invitation json.RawMessage) (string, error) {
+	var conn backchannelConnection
+	if err := c.command("connection", "receive-invitation", "", invitation, &conn); err != nil {
+		return "", err
+	}
+
+	return conn.ConnectionID, nil
+}
+
+func (c *backchannelController) ConnectionState(connectionID string) (string, error) {
+	var conn backchannelConnection
+	if err := doJSON(http.MethodGet, c.url+"/agent/command/connection/"+connectionID, nil, &conn); err != nil {
+		return "", err
+	}
+
+	return conn.State, nil
+}
+
+func (c *backchannelController) Command(topic, operation, id string, data interface{}) (json.RawMessage, error) {
+	var resp json.RawMessage
+	if err := c.command(topic, operation, id, data, &resp); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func (c *backchannelController) command(topic, operation, id string, data, resp interface{}) error {
+	url := fmt.Sprintf("%s/agent/command/%s/%s", c.url, topic, operation)
+
+	return doJSON(http.MethodPost, url, &backchannelCommand{ID: id, Data: data}, resp)
+}
+
+// requestMediation connects to the mediator using the invitation and requests mediation.
+func (c *backchannelController) requestMediation(invitation string) error {
+	connectionID, err := c.ReceiveInvitation(json.RawMessage(invitation))
+	if err != nil {
+		return fmt.Errorf("failed to receive mediator invitation: %w", err)
+	}
+
+	if _, err := c.Command("mediation-coordination", "send-request", connectionID, nil); err != nil {
+		return fmt.Errorf("failed to request mediation: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,247 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package interop runs third party Aries agents (ACA-Py, AFJ) in docker containers
+// and drives them from Go tests, so the framework can be exercised against other
+// implementations of the same protocols.
+//
+// Only the didexchange protocol is implemented by the framework so far. Controllers
+// expose a generic command interface, so issue-credential and present-proof suites
+// can be built on top of the same agents once the protocols are available.
+package interop
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Connection states reported by the third party agents.
+const (
+	StateInvitation = "invitation"
+	StateRequest    = "request"
+	StateResponse   = "response"
+	StateActive     = "active"
+	StateCompleted  = "completed"
+)
+
+const defaultStartTimeout = 60 * time.Second
+
+// Controller drives a running third party agent.
+type Controller interface {
+	// CreateInvitation creates a connection invitation, returns the invitation and the connection id.
+	CreateInvitation() (json.RawMessage, string, error)
+	// ReceiveInvitation accepts the invitation and returns the connection id.
+	ReceiveInvitation(invitation json.RawMessage) (string, error)
+	// ConnectionState returns the current state of the connection.
+	ConnectionState(connectionID string) (string, error)
+	// Command sends a generic command to the agent, e.g. an issue-credential or present-proof operation.
+	Command(topic, operation, id string, data interface{}) (json.RawMessage, error)
+}
+
+// Config holds the settings shared by all third party agents.
+type Config struct {
+	Name    string
+	Image   string
+	Network string
+	// Host is an address the agent is reachable at from the test
+	Host string
+	// Endpoint is the DIDComm endpoint the agent advertises
+	Endpoint           string
+	InboundPort        int
+	ControllerPort     int
+	GenesisURL         string
+	GenesisFile        string
+	MediatorInvitation string
+	OpenMediation      bool
+	ExtraArgs          []string
+	Env                map[string]string
+	StartTimeout       time.Duration
+}
+
+// Option configures a third party agent.
+type Option func(c *Config)
+
+// WithImage overrides the default docker image of the agent.
+func WithImage(image string) Option {
+	return func(c *Config) {
+		c.Image = image
+	}
+}
+
+// WithNetwork attaches the agent container to the docker network.
+func WithNetwork(network string) Option {
+	return func(c *Config) {
+		c.Network = network
+	}
+}
+
+// WithPorts sets the DIDComm inbound port and the controller (admin/backchannel) port.
+func WithPorts(inbound, controller int) Option {
+	return func(c *Config) {
+		c.InboundPort = inbound
+		c.ControllerPort = controller
+	}
+}
+
+// WithEndpoint sets the DIDComm endpoint advertised by the agent.
+func WithEndpoint(endpoint string) Option {
+	return func(c *Config) {
+		c.Endpoint = endpoint
+	}
+}
+
+// WithGenesisURL configures the ledger genesis transactions URL.
+func WithGenesisURL(url string) Option {
+	return func(c *Config) {
+		c.GenesisURL = url
+	}
+}
+
+// WithGenesisFile configures the path (inside the container) of the ledger genesis transactions file.
+func WithGenesisFile(path string) Option {
+	return func(c *Config) {
+		c.GenesisFile = path
+	}
+}
+
+// WithMediatorInvitation makes the agent connect to a mediator using the invitation.
+func WithMediatorInvitation(invitation string) Option {
+	return func(c *Config) {
+		c.MediatorInvitation = invitation
+	}
+}
+
+// WithOpenMediation makes the agent grant mediation requests, so it can act as a mediator.
+func WithOpenMediation() Option {
+	return func(c *Config) {
+		c.OpenMediation = true
+	}
+}
+
+// WithArgs appends extra command line arguments of the agent.
+func WithArgs(args ...string) Option {
+	return func(c *Config) {
+		c.ExtraArgs = append(c.ExtraArgs, args...)
+	}
+}
+
+// WithEnv sets an environment variable of the agent container.
+func WithEnv(key, value string) Option {
+	return func(c *Config) {
+		c.Env[key] = value
+	}
+}
+
+// WithStartTimeout sets how long to wait for the agent controller to become available.
+func WithStartTimeout(timeout time.Duration) Option {
+	return func(c *Config) {
+		c.StartTimeout = timeout
+	}
+}
+
+// Agent is a third party agent running in a docker container.
+type Agent struct {
+	Controller
+	container *Container
+	config    *Config
+	// setup is called once the controller is available
+	setup func() error
+}
+
+// Start runs the agent container and waits for its controller.
+func (a *Agent) Start() error {
+	if err := a.container.Start(); err != nil {
+		return err
+	}
+
+	if err := waitForHTTP(a.config.controllerURL(), a.config.StartTimeout); err != nil {
+		logs, e := a.container.Logs()
+		if e == nil {
+			logger.Errorf("agent %s failed to start: %s", a.config.Name, logs)
+		}
+
+		return fmt.Errorf("failed to start agent %s: %w", a.config.Name, err)
+	}
+
+	if a.setup != nil {
+		if err := a.setup(); err != nil {
+			return fmt.Errorf("failed to set up agent %s: %w", a.config.Name, err)
+		}
+	}
+
+	logger.Infof("Agent %s started from image %s", a.config.Name, a.config.Image)
+
+	return nil
+}
+
+// WaitForConnectionState polls the connection until it reaches one of the states.
+func (a *Agent) WaitForConnectionState(connectionID string, timeout time.Duration, states ...string) error {
+	const pollInterval = 200 * time.Millisecond
+
+	deadline := time.Now().Add(timeout)
+
+	for {
+		state, err := a.ConnectionState(connectionID)
+		if err != nil {
+			return err
+		}
+
+		for _, s := range states {
+			if state == s {
+				return nil
+			}
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timeout waiting for connection %s state %v, current state %s", connectionID, states, state)
+		}
+
+		time.Sleep(pollInterval)
+	}
+}
+
+// Stop removes the agent container.
+func (a *Agent) Stop() error {
+	return a.container.Stop()
+}
+
+// Container returns the container of the agent.
+func (a *Agent) Container() *Container {
+	return a.container
+}
+
+func (c *Config) controllerURL() string {
+	return "http://" + c.Host + ":" + strconv.Itoa(c.ControllerPort)
+}
+
+func newConfig(name, image string, opts []Option) *Config {
+	const (
+		defaultInboundPort    = 8020
+		defaultControllerPort = 8021
+	)
+
+	c := &Config{
+		Name:           name,
+		Image:          image,
+		Host:           "localhost",
+		InboundPort:    defaultInboundPort,
+		ControllerPort: defaultControllerPort,
+		Env:            map[string]string{},
+		StartTimeout:   defaultStartTimeout,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.Endpoint == "" {
+		c.Endpoint = fmt.Sprintf("http://%s:%d", c.Host, c.InboundPort)
+	}
+
+	return c
+}
@@ -0,0 +1,116 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package interop
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+)
+
+const dockerCmd = "docker"
+
+// Container describes a docker container running a third party agent.
+type Container struct {
+	Name    string
+	Image   string
+	Network string
+	// Ports maps host ports to container ports
+	Ports map[int]int
+	Env   map[string]string
+	Args  []string
+}
+
+// Start runs the container in detached mode.
+func (c *Container) Start() error {
+	out, err := exec.Command(dockerCmd, c.runArgs()...).CombinedOutput() // nolint: gosec
+	if err != nil {
+		return fmt.Errorf("failed to start container %s: %s: %w", c.Name, strings.TrimSpace(string(out)), err)
+	}
+
+	return nil
+}
+
+// Stop removes the container.
+func (c *Container) Stop() error {
+	out, err := exec.Command(dockerCmd, "rm", "-f", c.Name).CombinedOutput() // nolint: gosec
+	if err != nil {
+		return fmt.Errorf("failed to stop container %s: %s: %w", c.Name, strings.TrimSpace(string(out)), err)
+	}
+
+	return nil
+}
+
+// Logs returns the container output, useful to diagnose failed interop scenarios.
+func (c *Container) Logs() (string, error) {
+	out, err := exec.Command(dockerCmd, "logs", c.Name).CombinedOutput() // nolint: gosec
+	if err != nil {
+		return "", fmt.Errorf("failed to get logs of container %s: %w", c.Name, err)
+	}
+
+	return string(out), nil
+}
+
+func (c *Container) runArgs() []string {
+	args := []string{"run", "-d", "--rm", "--name", c.Name}
+
+	if c.Network != "" {
+		args = append(args, "--network", c.Network)
+	}
+
+	hostPorts := make([]int, 0, len(c.Ports))
+	for hostPort := range c.Ports {
+		hostPorts = append(hostPorts, hostPort)
+	}
+
+	sort.Ints(hostPorts)
+
+	for _, hostPort := range hostPorts {
+		args = append(args, "-p", fmt.Sprintf("%d:%d", hostPort, c.Ports[hostPort]))
+	}
+
+	keys := make([]string, 0, len(c.Env))
+	for k := range c.Env {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		args = append(args, "-e", k+"="+c.Env[k])
+	}
+
+	args = append(args, c.Image)
+
+	return append(args, c.Args...)
+}
+
+// waitForHTTP polls the url until it responds or the timeout expires.
+func waitForHTTP(url string, timeout time.Duration) error {
+	const pollInterval = 500 * time.Millisecond
+
+	deadline := time.Now().Add(timeout)
+
+	for time.Now().Before(deadline) {
+		resp, err := http.Get(url) // nolint: gosec
+		if err == nil {
+			if e := resp.Body.Close(); e != nil {
+				return e
+			}
+
+			return nil
+		}
+
+		time.Sleep(pollInterval)
+	}
+
+	return errors.New("timeout: agent controller is not available at " + url)
+}
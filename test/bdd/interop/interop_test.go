@@ -0,0 +1,188 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package interop
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestContainer_runArgs(t *testing.T) {
+	c := &Container{
+		Name:    "acapy",
+		Image:   "image",
+		Network: "aries",
+		Ports:   map[int]int{9021: 8021, 9020: 8020},
+		Env:     map[string]string{"B": "2", "A": "1"},
+		Args:    []string{"start"},
+	}
+
+	require.Equal(t, []string{"run", "-d", "--rm", "--name", "acapy", "--network", "aries",
+		"-p", "9020:8020", "-p", "9021:8021", "-e", "A=1", "-e", "B=2", "image", "start"}, c.runArgs())
+}
+
+func TestNewACAPy(t *testing.T) {
+	t.Run("test default ledger config", func(t *testing.T) {
+		agent := NewACAPy("acapy")
+		require.Equal(t, ACAPyImage, agent.Container().Image)
+		require.Contains(t, agent.Container().Args, "--no-ledger")
+		require.Contains(t, agent.Container().Args, "http://localhost:8020")
+	})
+
+	t.Run("test genesis and mediation config", func(t *testing.T) {
+		agent := NewACAPy("acapy", WithGenesisURL("http://ledger/genesis"), WithOpenMediation(),
+			WithMediatorInvitation("http://mediator?c_i=abc"), WithPorts(9020, 9021), WithArgs("--debug"))
+		args := agent.Container().Args
+		require.NotContains(t, args, "--no-ledger")
+		require.Subset(t, args, []string{"--genesis-url", "http://ledger/genesis", "--open-mediation",
+			"--mediator-invitation", "http://mediator?c_i=abc", "--debug", "9020", "9021"})
+		require.Equal(t, "--debug", args[len(args)-1])
+	})
+}
+
+func TestNewAFJ(t *testing.T) {
+	agent := NewAFJ("afj", WithGenesisFile("/genesis.txn"), WithEnv("LOG_LEVEL", "debug"))
+	require.Equal(t, AFJImage, agent.Container().Image)
+	require.Equal(t, "/genesis.txn", agent.Container().Env["GENESIS_FILE"])
+	require.Equal(t, "debug", agent.Container().Env["LOG_LEVEL"])
+	require.Equal(t, "http://localhost:8020", agent.Container().Env["AGENT_PUBLIC_ENDPOINT"])
+	require.Nil(t, agent.setup)
+
+	agent = NewAFJ("afj", WithMediatorInvitation(`{"@type":"invitation"}`))
+	require.NotNil(t, agent.setup)
+}
+
+func TestACAPyController(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/connections/create-invitation":
+			_, _ = w.Write([]byte(`{"connection_id":"c1","invitation":{"@id":"i1"}}`))
+		case "/connections/receive-invitation":
+			body, err := ioutil.ReadAll(r.Body)
+			require.NoError(t, err)
+			require.JSONEq(t, `{"@id":"i1"}`, string(body))
+			_, _ = w.Write([]byte(`{"connection_id":"c2","state":"request"}`))
+		case "/connections/c2":
+			_, _ = w.Write([]byte(`{"connection_id":"c2","state":"active"}`))
+		case "/issue-credential/records/r1/send-offer":
+			_, _ = w.Write([]byte(`{"state":"offer_sent"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	agent := NewACAPy("acapy", withServer(t, server.URL))
+
+	invitation, connID, err := agent.CreateInvitation()
+	require.NoError(t, err)
+	require.Equal(t, "c1", connID)
+	require.JSONEq(t, `{"@id":"i1"}`, string(invitation))
+
+	connID, err = agent.ReceiveInvitation(invitation)
+	require.NoError(t, err)
+	require.Equal(t, "c2", connID)
+
+	require.NoError(t, agent.WaitForConnectionState(connID, time.Second, StateActive))
+
+	resp, err := agent.Command("issue-credential", "send-offer", "r1", nil)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"state":"offer_sent"}`, string(resp))
+
+	_, err = agent.Command("present-proof", "send-request", "", nil)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed with status 404")
+}
+
+func TestBackchannelController(t *testing.T) {
+	states := []string{"request", "response", "complete"}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/agent/command/connection/create-invitation":
+			_, _ = w.Write([]byte(`{"connection_id":"c1","invitation":{"@id":"i1"}}`))
+		case "/agent/command/connection/receive-invitation":
+			var cmd map[string]json.RawMessage
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&cmd))
+			require.JSONEq(t, `{"@id":"i1"}`, string(cmd["data"]))
+			_, _ = w.Write([]byte(`{"connection_id":"c2","state":"invitation"}`))
+		case "/agent/command/connection/c2":
+			state := states[0]
+			if len(states) > 1 {
+				states = states[1:]
+			}
+			_, _ = w.Write([]byte(`{"connection_id":"c2","state":"` + state + `"}`))
+		case "/agent/command/mediation-coordination/send-request":
+			var cmd map[string]json.RawMessage
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&cmd))
+			require.JSONEq(t, `"c2"`, string(cmd["id"]))
+			_, _ = w.Write([]byte(`{"state":"requested"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	agent := NewAFJ("afj", withServer(t, server.URL), WithMediatorInvitation(`{"@id":"i1"}`))
+
+	t.Run("test connection", func(t *testing.T) {
+		invitation, connID, err := agent.CreateInvitation()
+		require.NoError(t, err)
+		require.Equal(t, "c1", connID)
+
+		connID, err = agent.ReceiveInvitation(invitation)
+		require.NoError(t, err)
+
+		require.NoError(t, agent.WaitForConnectionState(connID, time.Second, StateCompleted, "complete"))
+
+		err = agent.WaitForConnectionState(connID, 10*time.Millisecond, StateActive)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "timeout waiting for connection c2")
+	})
+
+	t.Run("test mediation setup", func(t *testing.T) {
+		require.NoError(t, agent.setup())
+	})
+
+	t.Run("test unknown command", func(t *testing.T) {
+		_, err := agent.Command("issue-credential", "send-offer", "", nil)
+		require.Error(t, err)
+	})
+}
+
+func TestWaitForHTTP(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	require.NoError(t, waitForHTTP(server.URL, time.Second))
+
+	server.Close()
+
+	err := waitForHTTP(server.URL, 10*time.Millisecond)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "is not available")
+}
+
+// withServer points the agent controller to the test server.
+func withServer(t *testing.T, serverURL string) Option {
+	u, err := url.Parse(serverURL)
+	require.NoError(t, err)
+
+	port, err := strconv.Atoi(u.Port())
+	require.NoError(t, err)
+
+	return func(c *Config) {
+		c.Host = u.Hostname()
+		c.ControllerPort = port
+	}
+}
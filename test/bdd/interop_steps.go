@@ -0,0 +1,148 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package bdd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/DATA-DOG/godog"
+
+	didexchange2 "github.com/hyperledger/aries-framework-go/pkg/didcomm/protocol/didexchange"
+	"github.com/hyperledger/aries-framework-go/test/interop"
+)
+
+const (
+	interopConnectionTimeout = 30 * time.Second
+
+	// environment variables to configure the ledger and the mediator of the interop agents
+	interopGenesisURLEnv         = "INTEROP_GENESIS_URL"
+	interopMediatorInvitationEnv = "INTEROP_MEDIATOR_INVITATION"
+	interopNetworkEnv            = "INTEROP_NETWORK"
+)
+
+// InteropSteps drive third party agents running in containers
+type InteropSteps struct {
+	bddContext    *Context
+	agents        map[string]*interop.Agent
+	connectionIDs map[string]string
+}
+
+// NewInteropSteps
+func NewInteropSteps(context *Context) *InteropSteps {
+	return &InteropSteps{bddContext: context, agents: make(map[string]*interop.Agent),
+		connectionIDs: make(map[string]string)}
+}
+
+func (i *InteropSteps) startAgent(agentID, agentType, inboundPort, controllerPort string) error {
+	inbound, err := strconv.Atoi(inboundPort)
+	if err != nil {
+		return fmt.Errorf("invalid inbound port: %w", err)
+	}
+
+	controller, err := strconv.Atoi(controllerPort)
+	if err != nil {
+		return fmt.Errorf("invalid controller port: %w", err)
+	}
+
+	opts := []interop.Option{interop.WithPorts(inbound, controller)}
+
+	if url := os.Getenv(interopGenesisURLEnv); url != "" {
+		opts = append(opts, interop.WithGenesisURL(url))
+	}
+
+	if invitation := os.Getenv(interopMediatorInvitationEnv); invitation != "" {
+		opts = append(opts, interop.WithMediatorInvitation(invitation))
+	}
+
+	if network := os.Getenv(interopNetworkEnv); network != "" {
+		opts = append(opts, interop.WithNetwork(network))
+	}
+
+	var agent *interop.Agent
+
+	switch agentType {
+	case "acapy":
+		agent = interop.NewACAPy(agentID, opts...)
+	case "afj":
+		agent = interop.NewAFJ(agentID, opts...)
+	default:
+		return fmt.Errorf("unsupported interop agent type %s", agentType)
+	}
+
+	if err := agent.Start(); err != nil {
+		return err
+	}
+
+	i.agents[agentID] = agent
+
+	return nil
+}
+
+func (i *InteropSteps) createInvitation(agentID string) error {
+	invitation, connectionID, err := i.agents[agentID].CreateInvitation()
+	if err != nil {
+		return fmt.Errorf("failed to create invitation: %w", err)
+	}
+
+	inv := &didexchange2.Invitation{}
+	if err := json.Unmarshal(invitation, inv); err != nil {
+		return fmt.Errorf("failed to unmarshal invitation: %w", err)
+	}
+
+	i.bddContext.Invitations[agentID] = inv
+	i.connectionIDs[agentID] = connectionID
+
+	logger.Infof("Interop agent %s create invitation %s", agentID, invitation)
+
+	return nil
+}
+
+func (i *InteropSteps) receiveInvitation(inviteeAgentID, inviterAgentID string) error {
+	invitation, err := json.Marshal(i.bddContext.Invitations[inviterAgentID])
+	if err != nil {
+		return fmt.Errorf("failed to marshal invitation: %w", err)
+	}
+
+	connectionID, err := i.agents[inviteeAgentID].ReceiveInvitation(invitation)
+	if err != nil {
+		return fmt.Errorf("failed to receive invitation: %w", err)
+	}
+
+	i.connectionIDs[inviteeAgentID] = connectionID
+
+	logger.Infof("Interop agent %s receive invitation from Agent %s", inviteeAgentID, inviterAgentID)
+
+	return nil
+}
+
+func (i *InteropSteps) waitForConnectionState(agentID, state string) error {
+	return i.agents[agentID].WaitForConnectionState(i.connectionIDs[agentID], interopConnectionTimeout, state)
+}
+
+func (i *InteropSteps) stopAgents(interface{}, error) {
+	for agentID, agent := range i.agents {
+		if err := agent.Stop(); err != nil {
+			logger.Errorf("failed to stop interop agent %s: %s", agentID, err)
+		}
+
+		delete(i.agents, agentID)
+	}
+}
+
+// RegisterSteps registers interop steps
+func (i *InteropSteps) RegisterSteps(s *godog.Suite) {
+	s.AfterScenario(i.stopAgents)
+	s.Step(`^interop agent "([^"]*)" of type "([^"]*)" is running on inbound port "([^"]*)" and controller port "([^"]*)"$`, //nolint:lll
+		i.startAgent)
+	s.Step(`^interop agent "([^"]*)" creates invitation$`, i.createInvitation)
+	s.Step(`^interop agent "([^"]*)" receives invitation from "([^"]*)"$`, i.receiveInvitation)
+	s.Step(`^interop agent "([^"]*)" waits for connection state "([^"]*)"$`, i.waitForConnectionState)
+}
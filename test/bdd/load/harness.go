@@ -0,0 +1,291 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package load provides a harness to validate performance oriented changes of the framework. It spins up
+// N in-process agents (in-memory storage, in-process transport) and drives concurrent DID exchanges between
+// them, reporting latency and throughput.
+//
+// Usage:
+//	h, err := load.New(&load.Config{Agents: 10, Exchanges: 1000, Concurrency: 50})
+//	defer h.Close()
+//	report, err := h.Run()
+//	fmt.Println(report)
+package load
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/client/didexchange"
+	"github.com/hyperledger/aries-framework-go/pkg/common/log"
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/service"
+	didexsvc "github.com/hyperledger/aries-framework-go/pkg/didcomm/protocol/didexchange"
+	"github.com/hyperledger/aries-framework-go/pkg/framework/aries"
+)
+
+var logger = log.New("aries-framework/test/load")
+
+const (
+	defaultConcurrency = 10
+	defaultTimeout     = 10 * time.Second
+	// events are buffered so slow reporting doesn't throttle the protocol
+	eventsBufferSize = 1000
+	stateCompleted   = "completed"
+)
+
+// Config of the load run.
+type Config struct {
+	// Agents is a number of in-process agents (minimum 2). Exchanges are spread over pairs of agents.
+	Agents int
+	// Exchanges is a total number of DID exchanges to drive.
+	Exchanges int
+	// Concurrency is a maximum number of exchanges in progress at the same time.
+	Concurrency int
+	// Timeout is a maximum duration of a single exchange.
+	Timeout time.Duration
+}
+
+// Harness drives DID exchanges between in-process agents.
+type Harness struct {
+	config  Config
+	agents  []*agent
+	tracker *tracker
+}
+
+type agent struct {
+	name   string
+	aries  *aries.Aries
+	client *didexchange.Client
+}
+
+// New creates the in-process agents.
+func New(cfg *Config) (*Harness, error) {
+	if cfg.Agents < 2 {
+		return nil, errors.New("at least 2 agents are required")
+	}
+
+	if cfg.Exchanges <= 0 {
+		return nil, errors.New("number of exchanges must be positive")
+	}
+
+	h := &Harness{config: *cfg, tracker: newTracker()}
+
+	if h.config.Concurrency <= 0 {
+		h.config.Concurrency = defaultConcurrency
+	}
+
+	if h.config.Timeout <= 0 {
+		h.config.Timeout = defaultTimeout
+	}
+
+	n := newNetwork()
+	factory := &transportProviderFactory{outbound: &outboundTransport{network: n}}
+
+	for i := 0; i < cfg.Agents; i++ {
+		a, err := h.newAgent(fmt.Sprintf("agent-%d", i), n, factory)
+		if err != nil {
+			if e := h.Close(); e != nil {
+				return nil, fmt.Errorf("close err: %v failed to create agent: %w", e, err)
+			}
+
+			return nil, fmt.Errorf("failed to create agent: %w", err)
+		}
+
+		h.agents = append(h.agents, a)
+	}
+
+	return h, nil
+}
+
+func (h *Harness) newAgent(name string, n *network, factory *transportProviderFactory) (*agent, error) {
+	framework, err := aries.New(
+		aries.WithInboundTransport(newInboundTransport(n, name)),
+		aries.WithTransportProviderFactory(factory),
+		aries.WithStoreProvider(newMemProvider()))
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, err := framework.Context()
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := didexchange.New(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	actionCh := make(chan service.DIDCommAction, eventsBufferSize)
+	if err = client.RegisterActionEvent(actionCh); err != nil {
+		return nil, err
+	}
+
+	go func() {
+		if err := service.AutoExecuteActionEvent(actionCh); err != nil {
+			logger.Errorf("auto execute action event failed: %s", err)
+		}
+	}()
+
+	msgCh := make(chan service.StateMsg, eventsBufferSize)
+	if err = client.RegisterMsgEvent(msgCh); err != nil {
+		return nil, err
+	}
+
+	go func() {
+		for msg := range msgCh {
+			h.tracker.handle(name, msg)
+		}
+	}()
+
+	return &agent{name: name, aries: framework, client: client}, nil
+}
+
+// Run drives the configured number of DID exchanges and returns the report.
+func (h *Harness) Run() (*Report, error) {
+	var (
+		wg      sync.WaitGroup
+		sem     = make(chan struct{}, h.config.Concurrency)
+		results = make(chan *result, h.config.Exchanges)
+		n       = len(h.agents)
+	)
+
+	start := time.Now()
+
+	for i := 0; i < h.config.Exchanges; i++ {
+		inviter, invitee := h.agents[(2*i)%n], h.agents[(2*i+1)%n]
+
+		sem <- struct{}{}
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			results <- h.exchange(inviter, invitee)
+
+			<-sem
+		}()
+	}
+
+	wg.Wait()
+	close(results)
+
+	return newReport(results, time.Since(start)), nil
+}
+
+// exchange runs a single DID exchange and waits for the invitee to complete it.
+func (h *Harness) exchange(inviter, invitee *agent) *result {
+	invitation, err := inviter.client.CreateInvitation(inviter.name)
+	if err != nil {
+		return &result{err: fmt.Errorf("create invitation: %w", err)}
+	}
+
+	done := h.tracker.expect(invitee.name, invitation.ID)
+	defer h.tracker.forget(invitee.name, invitation.ID)
+
+	start := time.Now()
+
+	if err = invitee.client.HandleInvitation(invitation); err != nil {
+		return &result{err: fmt.Errorf("handle invitation: %w", err)}
+	}
+
+	select {
+	case <-done:
+		return &result{latency: time.Since(start)}
+	case <-time.After(h.config.Timeout):
+		return &result{err: fmt.Errorf("exchange timed out after %s", h.config.Timeout)}
+	}
+}
+
+// Close stops the agents.
+func (h *Harness) Close() error {
+	var errs []error
+
+	for _, a := range h.agents {
+		if err := a.aries.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", a.name, err))
+		}
+	}
+
+	h.agents = nil
+
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to close agents: %v", errs)
+	}
+
+	return nil
+}
+
+// tracker correlates the state events of the invitees with the exchanges in progress. The invitee refers
+// to the exchange by a connection ID, which is mapped to the invitation ID when the invitation is handled.
+type tracker struct {
+	// agent name + invitation ID -> completion channel
+	pending map[string]chan struct{}
+	// agent name + connection ID -> invitation ID
+	connections map[string]string
+	lock        sync.Mutex
+}
+
+func newTracker() *tracker {
+	return &tracker{pending: make(map[string]chan struct{}), connections: make(map[string]string)}
+}
+
+func (t *tracker) expect(agentName, invitationID string) <-chan struct{} {
+	done := make(chan struct{})
+
+	t.lock.Lock()
+	t.pending[agentName+invitationID] = done
+	t.lock.Unlock()
+
+	return done
+}
+
+func (t *tracker) forget(agentName, invitationID string) {
+	t.lock.Lock()
+	delete(t.pending, agentName+invitationID)
+	t.lock.Unlock()
+}
+
+func (t *tracker) handle(agentName string, msg service.StateMsg) {
+	props, ok := msg.Properties.(didexsvc.Event)
+	if !ok {
+		return
+	}
+
+	connKey := agentName + props.ConnectionID()
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if msg.Msg != nil && msg.Msg.Type == didexsvc.ConnectionInvite {
+		invitation := &didexsvc.Invitation{}
+		if err := json.Unmarshal(msg.Msg.Payload, invitation); err == nil {
+			t.connections[connKey] = invitation.ID
+		}
+
+		return
+	}
+
+	if msg.Type != service.PostState || msg.StateID != stateCompleted {
+		return
+	}
+
+	invitationID, ok := t.connections[connKey]
+	if !ok {
+		return
+	}
+
+	delete(t.connections, connKey)
+
+	if done, ok := t.pending[agentName+invitationID]; ok {
+		close(done)
+		delete(t.pending, agentName+invitationID)
+	}
+}
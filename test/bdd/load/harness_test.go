@@ -0,0 +1,74 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package load
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHarness(t *testing.T) {
+	t.Run("test invalid config", func(t *testing.T) {
+		_, err := New(&Config{Agents: 1, Exchanges: 1})
+		require.EqualError(t, err, "at least 2 agents are required")
+
+		_, err = New(&Config{Agents: 2})
+		require.EqualError(t, err, "number of exchanges must be positive")
+	})
+
+	t.Run("test run exchanges", func(t *testing.T) {
+		h, err := New(&Config{Agents: 4, Exchanges: 20, Concurrency: 4, Timeout: 2 * time.Second})
+		require.NoError(t, err)
+
+		defer func() {
+			require.NoError(t, h.Close())
+		}()
+
+		report, err := h.Run()
+		require.NoError(t, err)
+		t.Log(report)
+
+		require.Equal(t, 20, report.Exchanges)
+		require.Zero(t, report.Failed, report.Errors)
+		require.Equal(t, 20, report.Completed)
+		require.Empty(t, report.Errors)
+		require.True(t, report.Throughput > 0)
+		require.True(t, report.MinLatency > 0)
+		require.True(t, report.MinLatency <= report.MeanLatency)
+		require.True(t, report.P50Latency <= report.P99Latency)
+		require.True(t, report.P99Latency <= report.MaxLatency)
+		require.True(t, report.MaxLatency < 2*time.Second)
+	})
+}
+
+func TestReport(t *testing.T) {
+	results := make(chan *result, 4)
+	results <- &result{latency: 3 * time.Millisecond}
+	results <- &result{latency: time.Millisecond}
+	results <- &result{latency: 2 * time.Millisecond}
+	results <- &result{err: errTest("timeout")}
+	close(results)
+
+	r := newReport(results, time.Second)
+	require.Equal(t, 4, r.Exchanges)
+	require.Equal(t, 3, r.Completed)
+	require.Equal(t, 1, r.Failed)
+	require.Equal(t, 3.0, r.Throughput)
+	require.Equal(t, time.Millisecond, r.MinLatency)
+	require.Equal(t, 2*time.Millisecond, r.MeanLatency)
+	require.Equal(t, 2*time.Millisecond, r.P50Latency)
+	require.Equal(t, 3*time.Millisecond, r.P99Latency)
+	require.Equal(t, 3*time.Millisecond, r.MaxLatency)
+	require.Equal(t, 1, r.Errors["timeout"])
+	require.Contains(t, r.String(), "error (1): timeout")
+}
+
+type errTest string
+
+func (e errTest) Error() string { return string(e) }
@@ -0,0 +1,111 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package load
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// result of a single exchange
+type result struct {
+	latency time.Duration
+	err     error
+}
+
+// Report contains the latency and throughput figures of the load run.
+type Report struct {
+	Exchanges int
+	Completed int
+	Failed    int
+	Duration  time.Duration
+	// Throughput is a number of completed exchanges per second
+	Throughput float64
+
+	MinLatency  time.Duration
+	MeanLatency time.Duration
+	P50Latency  time.Duration
+	P95Latency  time.Duration
+	P99Latency  time.Duration
+	MaxLatency  time.Duration
+
+	// Errors counts the failed exchanges per error message
+	Errors map[string]int
+}
+
+func newReport(results <-chan *result, duration time.Duration) *Report {
+	r := &Report{Duration: duration, Errors: make(map[string]int)}
+
+	var latencies []time.Duration
+
+	for res := range results {
+		r.Exchanges++
+
+		if res.err != nil {
+			r.Failed++
+			r.Errors[res.err.Error()]++
+
+			continue
+		}
+
+		r.Completed++
+
+		latencies = append(latencies, res.latency)
+	}
+
+	if duration > 0 {
+		r.Throughput = float64(r.Completed) / duration.Seconds()
+	}
+
+	if len(latencies) == 0 {
+		return r
+	}
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	var total time.Duration
+	for _, l := range latencies {
+		total += l
+	}
+
+	r.MinLatency = latencies[0]
+	r.MaxLatency = latencies[len(latencies)-1]
+	r.MeanLatency = total / time.Duration(len(latencies))
+	r.P50Latency = percentile(latencies, 50)
+	r.P95Latency = percentile(latencies, 95)
+	r.P99Latency = percentile(latencies, 99)
+
+	return r
+}
+
+// percentile of the sorted latencies
+func percentile(sorted []time.Duration, p int) time.Duration {
+	i := (len(sorted)*p+99)/100 - 1
+	if i < 0 {
+		i = 0
+	}
+
+	return sorted[i]
+}
+
+// String formats the report to be printed by the load tests.
+func (r *Report) String() string {
+	b := &strings.Builder{}
+
+	fmt.Fprintf(b, "exchanges: %d, completed: %d, failed: %d, duration: %s, throughput: %.2f exchanges/s\n",
+		r.Exchanges, r.Completed, r.Failed, r.Duration, r.Throughput)
+	fmt.Fprintf(b, "latency min: %s, mean: %s, p50: %s, p95: %s, p99: %s, max: %s\n",
+		r.MinLatency, r.MeanLatency, r.P50Latency, r.P95Latency, r.P99Latency, r.MaxLatency)
+
+	for msg, count := range r.Errors {
+		fmt.Fprintf(b, "error (%d): %s\n", count, msg)
+	}
+
+	return b.String()
+}
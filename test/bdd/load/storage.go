@@ -0,0 +1,96 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package load
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/hyperledger/aries-framework-go/pkg/storage"
+)
+
+// memProvider is an in-memory implementation of storage.Provider, so the agents don't need any disk I/O
+type memProvider struct {
+	stores map[string]*memStore
+	lock   sync.Mutex
+}
+
+func newMemProvider() *memProvider {
+	return &memProvider{stores: make(map[string]*memStore)}
+}
+
+// OpenStore opens and returns a store for given name space.
+func (p *memProvider) OpenStore(name string) (storage.Store, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	store, ok := p.stores[name]
+	if !ok {
+		store = &memStore{records: make(map[string][]byte)}
+		p.stores[name] = store
+	}
+
+	return store, nil
+}
+
+// CloseStore closes store of given name space
+func (p *memProvider) CloseStore(name string) error {
+	p.lock.Lock()
+	delete(p.stores, name)
+	p.lock.Unlock()
+
+	return nil
+}
+
+// Close closes all stores created under this store provider
+func (p *memProvider) Close() error {
+	p.lock.Lock()
+	p.stores = make(map[string]*memStore)
+	p.lock.Unlock()
+
+	return nil
+}
+
+type memStore struct {
+	records map[string][]byte
+	lock    sync.RWMutex
+}
+
+// Put stores the key and the record
+func (s *memStore) Put(k string, v []byte) error {
+	if k == "" || v == nil {
+		return errors.New("key and value are mandatory")
+	}
+
+	s.lock.Lock()
+	s.records[k] = v
+	s.lock.Unlock()
+
+	return nil
+}
+
+// Get fetches the record based on key
+func (s *memStore) Get(k string) ([]byte, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	v, ok := s.records[k]
+	if !ok {
+		return nil, storage.ErrDataNotFound
+	}
+
+	return v, nil
+}
+
+// Delete removes the record
+func (s *memStore) Delete(k string) error {
+	s.lock.Lock()
+	delete(s.records, k)
+	s.lock.Unlock()
+
+	return nil
+}
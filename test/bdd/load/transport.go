@@ -0,0 +1,119 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package load
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/transport"
+)
+
+const inprocScheme = "inproc://"
+
+// network routes the packed messages between the in-process agents
+type network struct {
+	agents map[string]transport.InboundProvider
+	lock   sync.RWMutex
+}
+
+func newNetwork() *network {
+	return &network{agents: make(map[string]transport.InboundProvider)}
+}
+
+func (n *network) register(endpoint string, prov transport.InboundProvider) {
+	n.lock.Lock()
+	n.agents[endpoint] = prov
+	n.lock.Unlock()
+}
+
+func (n *network) unregister(endpoint string) {
+	n.lock.Lock()
+	delete(n.agents, endpoint)
+	n.lock.Unlock()
+}
+
+func (n *network) agent(endpoint string) (transport.InboundProvider, error) {
+	n.lock.RLock()
+	defer n.lock.RUnlock()
+
+	prov, ok := n.agents[endpoint]
+	if !ok {
+		return nil, fmt.Errorf("no agent is listening on %s", endpoint)
+	}
+
+	return prov, nil
+}
+
+// inboundTransport is the in-process implementation of transport.InboundTransport
+type inboundTransport struct {
+	network  *network
+	endpoint string
+}
+
+func newInboundTransport(n *network, name string) *inboundTransport {
+	return &inboundTransport{network: n, endpoint: inprocScheme + name}
+}
+
+// Start registers the agent in the in-process network.
+func (i *inboundTransport) Start(prov transport.InboundProvider) error {
+	i.network.register(i.endpoint, prov)
+	return nil
+}
+
+// Stop unregisters the agent from the in-process network.
+func (i *inboundTransport) Stop() error {
+	i.network.unregister(i.endpoint)
+	return nil
+}
+
+// Endpoint returns the in-process endpoint of the agent.
+func (i *inboundTransport) Endpoint() string {
+	return i.endpoint
+}
+
+// outboundTransport is the in-process implementation of transport.OutboundTransport. Like the HTTP
+// transport, the message is unpacked and handled by the recipient agent before Send returns.
+type outboundTransport struct {
+	network *network
+}
+
+// Send delivers the packed message to the agent listening on the destination.
+func (o *outboundTransport) Send(ctx context.Context, data []byte, destination string) (string, error) {
+	prov, err := o.network.agent(destination)
+	if err != nil {
+		return "", err
+	}
+
+	envelope, err := prov.PackWallet().UnpackMessage(data)
+	if err != nil {
+		return "", fmt.Errorf("failed to unpack msg: %w", err)
+	}
+
+	if err := prov.InboundMessageHandler()(ctx, envelope); err != nil {
+		return "", fmt.Errorf("incoming msg processing failed: %w", err)
+	}
+
+	return "", nil
+}
+
+// Accept url
+func (o *outboundTransport) Accept(url string) bool {
+	return strings.HasPrefix(url, inprocScheme)
+}
+
+// transportProviderFactory provides the in-process outbound transport to the framework
+type transportProviderFactory struct {
+	outbound *outboundTransport
+}
+
+// CreateOutboundTransport returns the in-process outbound transport.
+func (f *transportProviderFactory) CreateOutboundTransport() (transport.OutboundTransport, error) {
+	return f.outbound, nil
+}